@@ -5,14 +5,17 @@ import (
 	"backend/internal/auth"
 	"backend/internal/config"
 	"backend/internal/controllers"
+	"backend/internal/errorreport"
 	"backend/internal/middleware"
 	"backend/internal/services"
 	"backend/internal/whatsapp"
 	"backend/internal/whatsapp/commands"
 	whatsappclient "backend/pkg/whatsapp"
 	"context"
+	"flag"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/gin-contrib/cors"
@@ -42,18 +45,127 @@ func main() {
 		log.Warn().Msg("Error loading .env file, using environment variables")
 	}
 
+	// Load the settings saved by first-run setup, which the environment overrides
+	if err := config.LoadSettings(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load settings")
+	}
+
+	// Load the keys stored credentials and tokens are encrypted with, from the KMS when one is configured
+	if err := services.InitSecrets(context.Background(), config.LoadSecretsConfig()); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load secret keys")
+	}
+
+	// Report panics and server errors to Sentry when SENTRY_DSN is set
+	errorReportingConfig := config.LoadErrorReportingConfig()
+	if err := errorreport.Init(errorReportingConfig); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize error reporting, errors won't be reported")
+	} else if errorreport.Enabled() {
+		log.Info().
+			Str("environment", errorReportingConfig.Environment).
+			Str("release", errorReportingConfig.Release).
+			Msg("Reporting errors to Sentry")
+	}
+	defer errorreport.Flush(2 * time.Second)
+
+	// Single-user mode serves one local user without Clerk, for self-hosted personal servers
+	singleUser := flag.Bool("single-user", os.Getenv("SINGLE_USER") == "true", "serve a single local user without Clerk authentication")
+	flag.Parse()
+
 	// Initialize database
 	db.InitDB()
 
+	// Let services look up users and check their access through middleware
+	services.SetAccessLookups(services.AccessLookups{
+		CanEditNote:       middleware.CanEditNote,
+		CanEditChapter:    middleware.CanEditChapter,
+		User:              middleware.GetUserCached,
+		SingleUser:        middleware.SingleUserEnabled,
+		OrgRoleFromClerk:  middleware.OrgRoleFromClerk,
+		ValidClerkOrgRole: middleware.ValidClerkOrgRole,
+	})
+
+	// Seed the default system prompts from the built-in ones once the tables exist
+	go func() {
+		<-db.Migrated()
+		if err := services.NewSystemPromptService().Seed(); err != nil {
+			log.Error().Err(err).Msg("Failed to seed system prompts")
+		}
+	}()
+
+	// Re-encrypt stored secrets that aren't encrypted with the current key version
+	go func() {
+		<-db.Migrated()
+		report, err := services.NewSecretsService().Rotate(context.Background())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to rotate stored secrets")
+		} else if report.Rotated+report.Failed > 0 {
+			log.Info().Int("rotated", report.Rotated).Int("failed", report.Failed).Msg("Rotated stored secrets to the current key")
+		}
+	}()
+
+	// Start background job workers
+	services.GetJobQueue().Start(context.Background())
+
+	// Start scheduled GitHub syncs
+	go services.NewGitHubSyncJob(services.NewGitHubSyncService(), time.Minute).Start(context.Background())
+
+	// Start scheduled Jira/Linear task syncs
+	go services.NewTaskSyncJob(services.NewTaskSyncService(), time.Minute).Start(context.Background())
+
+	// Close ended iterations and roll their open tasks over
+	go services.NewIterationJob(services.NewIterationService(), 15*time.Minute).Start(context.Background())
+
+	// Repair chapters and notes whose organization drifted from their notebook's
+	go services.NewOrgConsistencyJob(services.NewOrgConsistencyService(), 6*time.Hour).Start(context.Background())
+
+	// Slug notes and chapters created before slugs and renumber duplicated slugs
+	go services.NewSlugRepairJob(services.NewSlugService(), time.Hour).Start(context.Background())
+
+	// Archive organization notes left untouched for longer than their retention policy allows
+	go services.NewRetentionJob(services.NewRetentionService(), 6*time.Hour).Start(context.Background())
+
+	// Suggest links between the notes of meetings with overlapping attendees and topics
+	go services.NewLinkSuggestionJob(services.NewLinkSuggestionService(), 6*time.Hour).Start(context.Background())
+
+	// Poll subscribed RSS and Atom feeds for new entries
+	go services.NewFeedPollJob(services.NewFeedSubscriptionService(), time.Minute).Start(context.Background())
+
+	// Check notebooks for broken external links and links to deleted notes
+	go services.NewLinkCheckJob(services.NewLinkCheckService(), time.Hour).Start(context.Background())
+
 	// Initialize calendar OAuth
 	auth.InitCalendarOAuth()
 
-	// Initialize Clerk SDK
-	clerkSecretKey := os.Getenv("CLERK_SECRET_KEY")
-	if clerkSecretKey == "" {
-		log.Fatal().Msg("CLERK_SECRET_KEY environment variable is required")
+	// Initialize Clerk SDK, or the local user in single-user mode
+	authMiddleware := []gin.HandlerFunc{middleware.ClerkMiddleware(), middleware.RequireAuth()}
+	if *singleUser {
+		name := os.Getenv("SINGLE_USER_NAME")
+		if name == "" {
+			name = "Me"
+		}
+		email := os.Getenv("SINGLE_USER_EMAIL")
+		if email == "" {
+			email = "me@localhost"
+		}
+		middleware.EnableSingleUser(name, email)
+
+		token := os.Getenv("SINGLE_USER_TOKEN")
+		authMiddleware = []gin.HandlerFunc{middleware.SingleUserAuth(token)}
+		if token == "" {
+			log.Warn().Msg("Single-user mode without SINGLE_USER_TOKEN: anyone who can reach the server has full access")
+		}
+		log.Info().Str("user_id", middleware.LocalUserID).Msg("Running in single-user mode, Clerk authentication is disabled")
+	} else {
+		clerkSecretKey := os.Getenv("CLERK_SECRET_KEY")
+		if clerkSecretKey == "" {
+			// A fresh self-hosted server starts without it so it can be configured through POST /setup
+			if os.Getenv(config.SetupCompletedKey) == "true" {
+				log.Fatal().Msg("CLERK_SECRET_KEY environment variable is required")
+			}
+			log.Warn().Msg("CLERK_SECRET_KEY is not set, complete first-run setup with POST /setup")
+		}
+		clerk.SetKey(clerkSecretKey)
 	}
-	clerk.SetKey(clerkSecretKey)
 
 	// Initialize WhatsApp services
 	whatsappConfig, err := config.LoadWhatsAppConfig()
@@ -83,6 +195,8 @@ func main() {
 		commandRegistry.Register(commands.NewCreateCommand(whatsappContextService))
 		commandRegistry.Register(commands.NewDeleteEntityCommand(whatsappContextService))
 		commandRegistry.Register(commands.NewLinkOrganizationCommand(whatsappContextService, whatsappAuthService))
+		commandRegistry.Register(commands.NewSummarizeCommand(whatsappContextService))
+		commandRegistry.Register(commands.NewReadLaterCommand())
 		commandRegistry.Register(commands.NewCancelCommand(whatsappContextService))
 
 		// Initialize message processor
@@ -126,9 +240,14 @@ func main() {
 
 	r := gin.Default()
 
-	// Performance monitoring middleware
+	// Request IDs and contextual logging, error reporting, then performance monitoring
+	r.Use(middleware.RequestLogger())
+	r.Use(middleware.ErrorReporting())
 	r.Use(middleware.ResponseTimeMiddleware())
 
+	// Gzip large JSON and text responses for clients that accept it
+	r.Use(middleware.Compression())
+
 	// CORS configuration - read from environment variable
 	corsOrigins := os.Getenv("CORS_ORIGINS")
 	allowedOrigins := []string{"http://localhost:5173"} // Default for local development
@@ -145,29 +264,57 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length", "X-Response-Time"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Setup-Token", middleware.RequestIDHeader, services.PublicConsentHeader},
+		ExposeHeaders:    []string{"Content-Length", "X-Response-Time", middleware.RequestIDHeader},
 		AllowCredentials: true,
+		// Notebook chat widgets are embedded on the authors' own websites
+		AllowOriginWithContextFunc: controllers.AllowNotebookWidgetOrigin,
 	}))
 
 	// Public routes (no authentication required)
 	public := r.Group("/")
 	{
-		// Calendar OAuth callback routes - public for OAuth flow
+		// Calendar and Drive OAuth callback routes - public for OAuth flow
 		public.GET("/api/calendar/google/callback", auth.GoogleCalendarCallback)
 		public.GET("/api/calendar/microsoft/callback", auth.MicrosoftCalendarCallback)
+		public.GET("/api/drive/google/callback", auth.GoogleDriveCallback)
 
 		// Public content routes
 		public.GET("/public/:notebookId", controllers.GetPublicNotebook)
 		public.GET("/public/:notebookId/:chapterId", controllers.GetPublicChapter)
 		public.GET("/public/:notebookId/:chapterId/:noteId", controllers.GetPublicNote)
+		public.GET("/public/:notebookId/snapshots", controllers.GetPublicNotebookSnapshots)
+		public.GET("/public/:notebookId/snapshots/:name", controllers.GetPublicNotebookSnapshot)
+		public.GET("/public/:notebookId/privacy", controllers.GetPublicNotebookPrivacy)
 		public.GET("/public/user/:email", controllers.GetPublicUserProfile)
+
+		// Reader questions about published notebooks (5 questions per minute per IP) and feedback on
+		// public notes (10 per minute per IP). Both POST routes share the :id wildcard, as gin requires.
+		public.POST("/public/:id/ask", middleware.NewPublicRateLimiter(5).Middleware(), controllers.AskPublicNotebook)
+		public.POST("/public/:id/feedback", middleware.NewPublicRateLimiter(10).Middleware(), controllers.SubmitPublicNoteFeedback)
+
+		// Chat widget authors embed on their own websites, answered like reader questions
+		public.GET("/public/:notebookId/widget-config", controllers.GetNotebookWidgetConfig)
+		public.POST("/public/:id/widget-chat", middleware.NewPublicRateLimiter(5).Middleware(), controllers.NotebookWidgetChat)
+
+		// Read-only task boards shared by link. Password attempts are limited to 10 per minute per IP.
+		public.GET("/public/boards/:token", controllers.GetPublicTaskBoard)
+		public.POST("/public/boards/:token", middleware.NewPublicRateLimiter(10).Middleware(), controllers.UnlockPublicTaskBoard)
+
+		// Public scheduling links. Booking requests are limited to 5 per minute per IP, on top of the cap on
+		// each link's pending requests.
+		public.GET("/book/:slug", controllers.GetBookingPage)
+		public.POST("/book/:slug", middleware.NewPublicRateLimiter(5).Middleware(), controllers.RequestBooking)
+
+		// First-run setup for self-hosted servers
+		public.GET("/setup/status", controllers.GetSetupStatus)
+		public.POST("/setup", controllers.RunSetup)
 	}
 
-	// Protected routes (authentication required via Clerk)
+	// Protected routes (authentication required via Clerk, or the local user in single-user mode)
 	protected := r.Group("/")
-	protected.Use(middleware.ClerkMiddleware())
-	protected.Use(middleware.RequireAuth())
+	protected.Use(authMiddleware...)
+	protected.Use(middleware.RequestCache())
 	{
 		// Auth routes
 		protected.GET("/auth/user", auth.GetCurrentUser)
@@ -181,6 +328,33 @@ func main() {
 		protected.GET("/settings/ai-credentials", auth.GetAICredentials)
 		protected.POST("/settings/ai-credentials", auth.SetAICredential)
 		protected.DELETE("/settings/ai-credentials", auth.DeleteAICredential)
+		protected.GET("/settings/ai-models", auth.GetAISettings)
+		protected.PUT("/settings/ai-models", auth.UpdateAISettings)
+		protected.GET("/settings/ai-tools", auth.GetAIToolSettings)
+		protected.PUT("/settings/ai-tools", auth.UpdateAIToolSettings)
+
+		// GitHub sync routes
+		protected.GET("/settings/github", controllers.GetGitHubIntegration)
+		protected.PUT("/settings/github", controllers.SaveGitHubIntegration)
+		protected.DELETE("/settings/github", controllers.DeleteGitHubIntegration)
+		protected.POST("/settings/github/sync", controllers.SyncGitHubIntegration)
+
+		// Automation API key routes
+		protected.GET("/settings/automation-keys", controllers.ListAutomationKeys)
+		protected.POST("/settings/automation-keys", controllers.CreateAutomationKey)
+		protected.DELETE("/settings/automation-keys/:keyId", controllers.RevokeAutomationKey)
+
+		// Connected session routes
+		protected.GET("/settings/sessions", controllers.ListSessions)
+		protected.DELETE("/settings/sessions/:id", controllers.RevokeSession)
+
+		// Timezone settings
+		protected.GET("/settings/timezone", controllers.GetTimezoneSettings)
+		protected.PUT("/settings/timezone", controllers.UpdateTimezoneSettings)
+
+		// Meeting filing settings
+		protected.GET("/settings/meeting-filing", controllers.GetMeetingFilingSettings)
+		protected.PUT("/settings/meeting-filing", controllers.UpdateMeetingFilingSettings)
 
 		// Organization management routes
 		protected.POST("/organizations", controllers.CreateOrganization)
@@ -194,6 +368,7 @@ func main() {
 		protected.GET("/organizations/:orgId/invitations", middleware.RequireOrgMembership(), controllers.ListInvitations)
 		protected.DELETE("/organizations/:orgId/invitations/:invitationId", middleware.RequireOrgAdmin(), controllers.RevokeInvitation)
 		protected.GET("/organizations/:orgId/members", middleware.RequireOrgMembership(), controllers.ListMembers)
+		protected.POST("/organizations/:orgId/members/import", middleware.RequireOrgAdmin(), controllers.ImportMembers)
 		protected.PUT("/organizations/:orgId/members/:userId", middleware.RequireOrgAdmin(), controllers.UpdateMemberRole)
 		protected.DELETE("/organizations/:orgId/members/:userId", middleware.RequireOrgAdmin(), controllers.RemoveMember)
 
@@ -202,6 +377,106 @@ func main() {
 		protected.POST("/organizations/:orgId/api-credentials", middleware.RequireOrgAdmin(), controllers.SetOrgAPICredential)
 		protected.DELETE("/organizations/:orgId/api-credentials", middleware.RequireOrgAdmin(), controllers.DeleteOrgAPICredential)
 
+		// Organization AI model and tool settings
+		protected.GET("/organizations/:orgId/ai-models", middleware.RequireOrgMembership(), controllers.GetOrgAISettings)
+		protected.PUT("/organizations/:orgId/ai-models", middleware.RequireOrgAdmin(), controllers.UpdateOrgAISettings)
+		protected.GET("/organizations/:orgId/ai-tools", middleware.RequireOrgMembership(), controllers.GetOrgAIToolSettings)
+		protected.PUT("/organizations/:orgId/ai-tools", middleware.RequireOrgAdmin(), controllers.UpdateOrgAIToolSettings)
+
+		// Organization link embed settings
+		protected.GET("/organizations/:orgId/embed-settings", middleware.RequireOrgMembership(), controllers.GetOrgEmbedSettings)
+		protected.PUT("/organizations/:orgId/embed-settings", middleware.RequireOrgAdmin(), controllers.UpdateOrgEmbedSettings)
+
+		// Organization GitHub sync routes
+		protected.GET("/organizations/:orgId/github", middleware.RequireOrgMembership(), controllers.GetGitHubIntegration)
+		protected.PUT("/organizations/:orgId/github", middleware.RequireOrgAdmin(), controllers.SaveGitHubIntegration)
+		protected.DELETE("/organizations/:orgId/github", middleware.RequireOrgAdmin(), controllers.DeleteGitHubIntegration)
+		protected.POST("/organizations/:orgId/github/sync", middleware.RequireOrgAdmin(), controllers.SyncGitHubIntegration)
+
+		// Organization publishing settings
+		protected.GET("/organizations/:orgId/publishing-settings", middleware.RequireOrgMembership(), controllers.GetOrgPublishingSettings)
+		protected.PUT("/organizations/:orgId/publishing-settings", middleware.RequireOrgAdmin(), controllers.UpdateOrgPublishingSettings)
+
+		// Organization retention policy and legal holds
+		protected.GET("/organizations/:orgId/retention-policy", middleware.RequireOrgAdmin(), controllers.GetOrgRetentionPolicy)
+		protected.PUT("/organizations/:orgId/retention-policy", middleware.RequireOrgAdmin(), controllers.UpdateOrgRetentionPolicy)
+		protected.GET("/organizations/:orgId/legal-holds", middleware.RequireOrgAdmin(), controllers.ListOrgLegalHolds)
+		protected.PUT("/organizations/:orgId/legal-holds/:notebookId", middleware.RequireOrgAdmin(), controllers.PlaceLegalHold)
+		protected.DELETE("/organizations/:orgId/legal-holds/:notebookId", middleware.RequireOrgAdmin(), controllers.ReleaseLegalHold)
+
+		// Organization transcript redaction policy
+		protected.GET("/organizations/:orgId/redaction-policy", middleware.RequireOrgMembership(), controllers.GetOrgRedactionPolicy)
+		protected.PUT("/organizations/:orgId/redaction-policy", middleware.RequireOrgAdmin(), controllers.UpdateOrgRedactionPolicy)
+
+		// Organization style guide, checked by note linting
+		protected.GET("/organizations/:orgId/style-guide", middleware.RequireOrgMembership(), controllers.GetOrgStyleGuide)
+		protected.PUT("/organizations/:orgId/style-guide", middleware.RequireOrgAdmin(), controllers.UpdateOrgStyleGuide)
+
+		// Organization glossary, found in notes and linked to each term's canonical note
+		protected.GET("/organizations/:orgId/glossary", middleware.RequireOrgMembership(), controllers.ListGlossary)
+		protected.POST("/organizations/:orgId/glossary", middleware.RequireOrgAdmin(), controllers.CreateGlossaryTerm)
+		protected.PUT("/organizations/:orgId/glossary/:termId", middleware.RequireOrgAdmin(), controllers.UpdateGlossaryTerm)
+		protected.DELETE("/organizations/:orgId/glossary/:termId", middleware.RequireOrgAdmin(), controllers.DeleteGlossaryTerm)
+		protected.GET("/organizations/:orgId/glossary/:termId/occurrences", middleware.RequireOrgMembership(), controllers.GetGlossaryTermOccurrences)
+
+		// Organization meeting transcription provider
+		protected.GET("/organizations/:orgId/transcription-settings", middleware.RequireOrgMembership(), controllers.GetOrgTranscriptionSettings)
+		protected.PUT("/organizations/:orgId/transcription-settings", middleware.RequireOrgAdmin(), controllers.UpdateOrgTranscriptionSettings)
+
+		// Organization meeting bot name, join message and consent notice
+		protected.GET("/organizations/:orgId/bot-settings", middleware.RequireOrgMembership(), controllers.GetOrgBotSettings)
+		protected.PUT("/organizations/:orgId/bot-settings", middleware.RequireOrgAdmin(), controllers.UpdateOrgBotSettings)
+		protected.GET("/organizations/:orgId/bot-settings/preview", middleware.RequireOrgMembership(), controllers.PreviewOrgBotSettings)
+
+		// Notebooks created for members joining the organization
+		protected.GET("/organizations/:orgId/onboarding-settings", middleware.RequireOrgMembership(), controllers.GetOrgOnboardingSettings)
+		protected.PUT("/organizations/:orgId/onboarding-settings", middleware.RequireOrgAdmin(), controllers.UpdateOrgOnboardingSettings)
+
+		// WhatsApp groups linked to the organization
+		protected.GET("/organizations/:orgId/whatsapp-groups", middleware.RequireOrgAdmin(), controllers.ListWhatsAppGroups)
+		protected.POST("/organizations/:orgId/whatsapp-groups", middleware.RequireOrgAdmin(), controllers.LinkWhatsAppGroup)
+		protected.PUT("/organizations/:orgId/whatsapp-groups/:groupId", middleware.RequireOrgAdmin(), controllers.UpdateWhatsAppGroup)
+		protected.DELETE("/organizations/:orgId/whatsapp-groups/:groupId", middleware.RequireOrgAdmin(), controllers.UnlinkWhatsAppGroup)
+
+		// Organization consistency repair
+		protected.POST("/organizations/:orgId/consistency/repair", middleware.RequireOrgAdmin(), controllers.RepairOrgConsistency)
+		protected.GET("/organizations/:orgId/moderation-policy", middleware.RequireOrgMembership(), controllers.GetOrgModerationPolicy)
+		protected.PUT("/organizations/:orgId/moderation-policy", middleware.RequireOrgAdmin(), controllers.UpdateOrgModerationPolicy)
+		protected.GET("/organizations/:orgId/moderation-findings", middleware.RequireOrgAdmin(), controllers.ListModerationFindings)
+		protected.PUT("/organizations/:orgId/moderation-findings/:findingId", middleware.RequireOrgAdmin(), controllers.ReviewModerationFinding)
+
+		// Organization system prompt routes, overriding the default prompts
+		protected.GET("/organizations/:orgId/system-prompts/:name", middleware.RequireOrgAdmin(), controllers.GetSystemPrompt)
+		protected.POST("/organizations/:orgId/system-prompts/:name/versions", middleware.RequireOrgAdmin(), controllers.CreateSystemPromptVersion)
+		protected.POST("/organizations/:orgId/system-prompts/:name/versions/:version/activate", middleware.RequireOrgAdmin(), controllers.ActivateSystemPromptVersion)
+		protected.POST("/organizations/:orgId/system-prompts/:name/preview", middleware.RequireOrgAdmin(), controllers.PreviewSystemPrompt)
+		protected.DELETE("/organizations/:orgId/system-prompts/:name", middleware.RequireOrgAdmin(), controllers.ResetOrgSystemPrompt)
+
+		// Default system prompt routes for server admins
+		protected.GET("/admin/system-prompts/:name", middleware.RequirePlatformAdmin(), controllers.GetSystemPrompt)
+		protected.POST("/admin/system-prompts/:name/versions", middleware.RequirePlatformAdmin(), controllers.CreateSystemPromptVersion)
+		protected.POST("/admin/system-prompts/:name/versions/:version/activate", middleware.RequirePlatformAdmin(), controllers.ActivateSystemPromptVersion)
+		protected.POST("/admin/system-prompts/:name/preview", middleware.RequirePlatformAdmin(), controllers.PreviewSystemPrompt)
+
+		// Whole workspace backups for moving a self-hosted server
+		protected.POST("/admin/backup", middleware.RequirePlatformAdmin(), controllers.CreateBackup)
+		protected.POST("/admin/restore", middleware.RequirePlatformAdmin(), controllers.RestoreBackup)
+
+		// Health of external dependencies, for degraded-mode banners
+		protected.GET("/api/system/status", controllers.GetSystemStatus)
+
+		// Organization content analytics
+		protected.GET("/organizations/:orgId/content-analytics", middleware.RequireOrgAdmin(), controllers.GetOrgContentAnalytics)
+
+		// Organization knowledge gaps reports
+		protected.POST("/organizations/:orgId/knowledge-report", middleware.RequireOrgAdmin(), controllers.RequestKnowledgeReport)
+		protected.GET("/organizations/:orgId/knowledge-report/:reportId", middleware.RequireOrgAdmin(), controllers.GetKnowledgeReport)
+
+		// Organization automation API key routes
+		protected.GET("/organizations/:orgId/automation-keys", middleware.RequireOrgAdmin(), controllers.ListAutomationKeys)
+		protected.POST("/organizations/:orgId/automation-keys", middleware.RequireOrgAdmin(), controllers.CreateAutomationKey)
+		protected.DELETE("/organizations/:orgId/automation-keys/:keyId", middleware.RequireOrgAdmin(), controllers.RevokeAutomationKey)
+
 		// User invitations routes
 		protected.GET("/user/invitations", controllers.ListUserInvitations)
 		protected.POST("/user/invitations/:invitationId/accept", controllers.AcceptInvitation)
@@ -209,6 +484,9 @@ func main() {
 
 		// Chat/AI routes
 		protected.POST("/api/chat", controllers.ChatHandler)
+		protected.GET("/api/chat/runs/:id", controllers.GetChatRun)
+		protected.POST("/api/chat/runs/:id/cancel", controllers.CancelChatRun)
+		protected.POST("/api/chat/:conversationId/save-as-note", controllers.SaveChatAsNote)
 		protected.POST("/api/generate", controllers.GenerateHandler)
 		protected.GET("/api/dump", controllers.DumpHandler)
 
@@ -219,6 +497,38 @@ func main() {
 		protected.GET("/notebook/:id", controllers.GetNotebookById)
 		protected.PUT("/notebook/:id", controllers.UpdateNotebook)
 		protected.DELETE("/notebook/:id", controllers.DeleteNotebook)
+		protected.GET("/notebook/:id/resolve", controllers.ResolveNoteLink)
+
+		// Notebook snapshot routes
+		protected.POST("/notebook/:id/snapshots", controllers.CreateNotebookSnapshot)
+		protected.GET("/notebook/:id/snapshots", controllers.ListNotebookSnapshots)
+		protected.GET("/notebook/:id/snapshots/:name", controllers.GetNotebookSnapshot)
+		protected.DELETE("/notebook/:id/snapshots/:name", controllers.DeleteNotebookSnapshot)
+
+		// Notebook encryption routes
+		protected.PUT("/notebook/:id/encryption", controllers.EnableNotebookEncryption)
+		protected.DELETE("/notebook/:id/encryption", controllers.DisableNotebookEncryption)
+		protected.GET("/notebook/:id/encryption/envelope", controllers.GetNotebookKeyEnvelope)
+		protected.PUT("/notebook/:id/encryption/envelopes/:userId", controllers.SaveNotebookKeyEnvelope)
+		protected.GET("/notebook/:id/export/encrypted", controllers.ExportEncryptedNotebook)
+		protected.GET("/notebook/:id/export.pdf", controllers.ExportNotebookPDF)
+		protected.GET("/notebook/:id/export.docx", controllers.ExportNotebookDocx)
+		protected.GET("/notebook/:id/notes.csv", controllers.ExportNotebookNotesCSV)
+
+		// Broken link and stale reference report
+		protected.GET("/notebook/:id/link-report", controllers.GetNotebookLinkReport)
+		protected.POST("/notebook/:id/link-report/check", controllers.CheckNotebookLinks)
+		protected.POST("/notebook/:id/link-report/issues/:issueId/update-url", controllers.UpdateLinkIssueURL)
+		protected.POST("/notebook/:id/link-report/issues/:issueId/remove-link", controllers.RemoveLinkIssueLink)
+		protected.POST("/notebook/:id/link-report/issues/:issueId/restore-target", controllers.RestoreLinkIssueTarget)
+
+		// Note view routes
+		protected.POST("/notebook/:id/views", controllers.CreateNoteView)
+		protected.GET("/notebook/:id/views", controllers.ListNoteViews)
+		protected.GET("/views/:viewId", controllers.GetNoteView)
+		protected.PUT("/views/:viewId", controllers.UpdateNoteView)
+		protected.DELETE("/views/:viewId", controllers.DeleteNoteView)
+		protected.GET("/views/:viewId/notes", controllers.QueryNoteView)
 
 		// Chapter routes
 		protected.POST("/chapter", controllers.CreateChapter)
@@ -226,6 +536,8 @@ func main() {
 		protected.GET("/chapter/:id", controllers.GetChapterById)
 		protected.PUT("/chapter/:id", controllers.UpdateChapter)
 		protected.PATCH("/chapter/:id/move", controllers.MoveChapter)
+		protected.GET("/chapter/:id/settings", controllers.GetChapterSettings)
+		protected.PUT("/chapter/:id/settings", controllers.UpdateChapterSettings)
 		protected.DELETE("/chapter/:id", controllers.DeleteChapter)
 
 		// Note routes
@@ -234,18 +546,121 @@ func main() {
 		protected.PUT("/note/:id", controllers.UpdateNote)
 		protected.PATCH("/note/:id/move", controllers.MoveNote)
 		protected.DELETE("/note/:id", controllers.DeleteNote)
+		protected.GET("/note/:id/export.pdf", controllers.ExportNotePDF)
+		protected.GET("/note/:id/export.docx", controllers.ExportNoteDocx)
+		protected.POST("/note/:id/email", controllers.EmailNote)
+		protected.GET("/note/:id/emails", controllers.GetNoteEmails)
 		protected.POST("/note/:id/generate-video", controllers.GenerateNoteVideo)
 		protected.DELETE("/note/:id/video", controllers.DeleteNoteVideo)
+		protected.GET("/note/:id/rendered", controllers.GetRenderedNote)
+		protected.GET("/note/:id/embedded-in", controllers.GetNoteEmbeddedIn)
+		protected.GET("/note/:id/block-refs", controllers.GetNoteBlockReferences)
+		protected.GET("/blocks/resolve", controllers.ResolveBlockRef)
+
+		// Note lock routes
+		protected.POST("/note/:id/lock", controllers.LockNote)
+		protected.DELETE("/note/:id/lock", controllers.RemoveNoteLock)
+		protected.POST("/note/:id/unlock", controllers.UnlockNote)
+		protected.POST("/note/:id/relock", controllers.RelockNote)
+
+		// Note property routes
+		protected.GET("/notes/search", controllers.SearchNotes)
+		protected.GET("/note/:id/properties", controllers.GetNoteProperties)
+		protected.PUT("/note/:id/properties/:key", controllers.SetNoteProperty)
+		protected.DELETE("/note/:id/properties/:key", controllers.DeleteNoteProperty)
+
+		// Note lifecycle routes
+		protected.PATCH("/note/:id/status", controllers.ChangeNoteStatus)
+		protected.POST("/note/:id/review", controllers.RequestNoteReview)
+		protected.POST("/note/:id/review/approve", controllers.ApproveNoteReview)
+		protected.POST("/note/:id/review/reject", controllers.RejectNoteReview)
+		protected.GET("/note/:id/reviews", controllers.GetNoteReviews)
+
+		// Pending revisions of published notes in organizations that require approval
+		protected.GET("/note/:id/revisions", controllers.GetNoteRevisions)
+		protected.POST("/note/:id/revision/publish", controllers.PublishNoteRevision)
+		protected.DELETE("/note/:id/revision", controllers.DiscardNoteRevision)
+
+		// Note translation routes
+		protected.POST("/note/:id/translate", controllers.TranslateNote)
+
+		// Note style checks
+		protected.POST("/note/:id/lint", controllers.LintNote)
+
+		// Glossary terms used in a note
+		protected.GET("/note/:id/glossary", controllers.GetNoteGlossary)
+
+		// Highlights and margin notes on clipped notes
+		protected.GET("/note/:id/annotations", controllers.GetNoteAnnotations)
+		protected.POST("/note/:id/annotations", controllers.CreateNoteAnnotation)
+		protected.PUT("/note/:id/annotations/:annotationId", controllers.UpdateNoteAnnotation)
+		protected.DELETE("/note/:id/annotations/:annotationId", controllers.DeleteNoteAnnotation)
+		protected.POST("/notebook/:id/highlights/digest", controllers.CreateHighlightsDigest)
+
+		// Sources cited by notes, and notebook bibliographies
+		protected.GET("/note/:id/sources", controllers.GetNoteSources)
+		protected.POST("/note/:id/sources", controllers.CreateNoteSource)
+		protected.PUT("/note/:id/sources/:sourceId", controllers.UpdateNoteSource)
+		protected.DELETE("/note/:id/sources/:sourceId", controllers.DeleteNoteSource)
+		protected.GET("/sources/lookup", controllers.LookupSource)
+		protected.GET("/notebook/:id/bibliography", controllers.GetNotebookBibliography)
 
 		// Note link routes
 		protected.POST("/api/notes/links", controllers.CreateNoteLink)
 		protected.GET("/api/notes/links", controllers.GetAllLinks)
 		protected.GET("/api/notes/:id/links", controllers.GetNoteLinksByNoteID)
+		protected.GET("/api/notes/:id/backlinks", controllers.GetNoteBacklinks)
 		protected.PUT("/api/notes/links/:id", controllers.UpdateNoteLink)
 		protected.DELETE("/api/notes/links/:id", controllers.DeleteNoteLink)
 
+		// Overview of every workspace the user belongs to
+		protected.GET("/api/workspaces/overview", controllers.GetWorkspacesOverview)
+
+		// Review mode, resurfacing notes the user hasn't seen in a while
+		protected.GET("/api/review/next", controllers.GetNextReviewNote)
+		protected.POST("/api/review/:noteId/done", controllers.CompleteNoteReview)
+		protected.PUT("/api/review/:noteId/favorite", controllers.SetNoteFavorite)
+
+		// Link preview routes
+		protected.POST("/api/link-preview", controllers.GetLinkPreview)
+
+		// Import routes
+		protected.POST("/api/import/bookmarks", controllers.ImportBookmarks)
+
+		// Reading list routes
+		protected.GET("/api/reading-list", controllers.ListReadingList)
+		protected.POST("/api/reading-list", controllers.SaveToReadingList)
+		protected.GET("/api/reading-list/:id", controllers.GetReadingListItem)
+		protected.PUT("/api/reading-list/:id/read", controllers.SetReadingListItemRead)
+		protected.DELETE("/api/reading-list/:id", controllers.DeleteReadingListItem)
+		protected.POST("/api/reading-list/:id/highlights", controllers.AddReadingListHighlight)
+		protected.DELETE("/api/reading-list/:id/highlights/:highlightId", controllers.DeleteReadingListHighlight)
+		protected.POST("/api/reading-list/:id/convert", controllers.ConvertReadingListItem)
+
+		// Feed subscription routes
+		protected.GET("/api/feeds", controllers.ListFeedSubscriptions)
+		protected.POST("/api/feeds", controllers.SubscribeToFeed)
+		protected.PUT("/api/feeds/:id", controllers.UpdateFeedSubscription)
+		protected.DELETE("/api/feeds/:id", controllers.UnsubscribeFromFeed)
+		protected.GET("/api/feeds/:id/entries", controllers.ListFeedEntries)
+		protected.POST("/api/feeds/:id/poll", controllers.PollFeed)
+
 		// Graph visualization routes
 		protected.GET("/api/graph/data", controllers.GetGraphData)
+		protected.GET("/api/graph/clusters", controllers.GetGraphClusters)
+		protected.GET("/api/graph/clusters/:clusterId", controllers.GetGraphCluster)
+		protected.GET("/api/graph/neighborhood/:noteId", controllers.GetGraphNeighborhood)
+		protected.GET("/api/graph/changes", controllers.GetGraphChanges)
+
+		// Suggested links waiting for review
+		protected.GET("/api/link-suggestions/pending", controllers.GetPendingLinkSuggestions)
+		protected.POST("/api/link-suggestions/:id/accept", controllers.AcceptLinkSuggestion)
+		protected.POST("/api/link-suggestions/:id/dismiss", controllers.DismissLinkSuggestion)
+
+		// Meeting filing: generated meeting notes suggested to be filed in project chapters
+		protected.GET("/api/meeting-filing/pending", controllers.GetPendingMeetingFilings)
+		protected.POST("/api/meeting-filing/:id/accept", controllers.AcceptMeetingFiling)
+		protected.POST("/api/meeting-filing/:id/dismiss", controllers.DismissMeetingFiling)
 
 		// Task management routes
 		// Note-associated task routes
@@ -257,17 +672,54 @@ func main() {
 		protected.GET("/kanban/:boardId", controllers.GetTaskBoard)
 		protected.PUT("/kanban/:boardId", controllers.UpdateTaskBoard)
 		protected.DELETE("/kanban/:boardId", controllers.DeleteTaskBoard)
+		protected.GET("/kanban/:boardId/timeline", controllers.GetBoardTimeline)
+		protected.GET("/kanban/:boardId/export.csv", controllers.ExportTaskBoardCSV)
+		protected.POST("/kanban/:boardId/publish", controllers.PublishTaskBoard)
+		protected.GET("/kanban/:boardId/publish", controllers.GetTaskBoardShare)
+		protected.DELETE("/kanban/:boardId/publish", controllers.RevokeTaskBoardShare)
+		protected.POST("/kanban/:boardId/iterations", controllers.CreateIteration)
+		protected.GET("/kanban/:boardId/iterations", controllers.GetIterations)
+		protected.PUT("/kanban/:boardId/iterations/:id", controllers.UpdateIteration)
+		protected.DELETE("/kanban/:boardId/iterations/:id", controllers.DeleteIteration)
+		protected.POST("/kanban/:boardId/iterations/:id/close", controllers.CloseIteration)
+		protected.GET("/kanban/:boardId/iterations/:id/burndown", controllers.GetIterationBurndown)
 		protected.GET("/user/kanban", controllers.GetUserTaskBoards)
 
+		// Writing stats and streaks
+		protected.GET("/user/stats", controllers.GetUserStats)
+
+		// Goal routes
+		protected.GET("/goals", controllers.ListGoals)
+		protected.POST("/goals", controllers.CreateGoal)
+		protected.GET("/goals/weekly-summary", controllers.GetGoalsWeeklySummary)
+		protected.PUT("/goals/:id", controllers.UpdateGoal)
+		protected.DELETE("/goals/:id", controllers.DeleteGoal)
+		protected.PUT("/goals/:id/progress", controllers.UpdateGoalProgress)
+
 		// Task routes
 		protected.POST("/kanban/:boardId/tasks", controllers.CreateTask)
 		protected.PUT("/tasks/:taskId", controllers.UpdateTask)
 		protected.DELETE("/tasks/:taskId", controllers.DeleteTask)
+		protected.PUT("/tasks/:taskId/block-ref", controllers.SetTaskBlockRef)
+		protected.POST("/tasks/:taskId/dependencies", controllers.AddTaskDependency)
+		protected.DELETE("/tasks/:taskId/dependencies/:dependsOnId", controllers.RemoveTaskDependency)
+		protected.PUT("/tasks/:taskId/iteration", controllers.SetTaskIteration)
 
 		// Task assignment routes
 		protected.POST("/tasks/:taskId/assign", controllers.AssignTaskToUsers)
 		protected.DELETE("/tasks/:taskId/assign/:userId", controllers.UnassignUserFromTask)
 
+		// Task comment routes
+		protected.GET("/tasks/:taskId/comments", controllers.GetTaskComments)
+		protected.POST("/tasks/:taskId/comments", controllers.CreateTaskComment)
+
+		// Jira/Linear task sync routes
+		protected.GET("/kanban/:boardId/sync", controllers.GetTaskSyncIntegration)
+		protected.PUT("/kanban/:boardId/sync", controllers.SaveTaskSyncIntegration)
+		protected.DELETE("/kanban/:boardId/sync", controllers.DeleteTaskSyncIntegration)
+		protected.GET("/kanban/:boardId/sync/status", controllers.GetTaskSyncStatus)
+		protected.POST("/kanban/:boardId/sync/run", controllers.RunTaskSync)
+
 		// Organization member routes for task assignment
 		protected.GET("/organization/:orgId/members", controllers.GetOrganizationMembers)
 
@@ -282,12 +734,32 @@ func main() {
 		protected.POST("/notebook/:id/publish", controllers.PublishNotebook)
 		protected.PUT("/notebook/:id/published-notes", controllers.UpdatePublishedNotes)
 		protected.POST("/notebook/:id/unpublish", controllers.UnpublishNotebook)
+		protected.GET("/notebook/:id/qa-settings", controllers.GetNotebookQASettings)
+		protected.PUT("/notebook/:id/qa-settings", controllers.UpdateNotebookQASettings)
+		protected.GET("/notebook/:id/feedback", controllers.GetNotebookFeedback)
+
+		// What published notebooks record about their readers
+		protected.GET("/notebook/:id/privacy", controllers.GetNotebookPrivacySettings)
+		protected.PUT("/notebook/:id/privacy", controllers.UpdateNotebookPrivacySettings)
+
+		// Languages published notebooks are offered in, with notes shown in their translations
+		protected.GET("/notebook/:id/languages", controllers.GetNotebookLanguages)
+		protected.PUT("/notebook/:id/languages", controllers.UpdateNotebookLanguages)
+
+		// Edits readers suggest to public notes, reviewed by the notebook's authors
+		protected.POST("/note/:id/suggestions", controllers.SubmitNoteSuggestion)
+		protected.GET("/notebook/:id/suggestions", controllers.ListNotebookSuggestions)
+		protected.GET("/suggestions/:id", controllers.GetNoteSuggestion)
+		protected.POST("/suggestions/:id/accept", controllers.AcceptNoteSuggestion)
+		protected.POST("/suggestions/:id/reject", controllers.RejectNoteSuggestion)
 		protected.PATCH("/note/:id/publish", controllers.PublishNote)
 
 		// Meeting routes
 		protected.POST("/meeting/start", controllers.StartMeetingRecording)
 		protected.GET("/meetings", controllers.GetUserMeetings)
 		protected.GET("/meeting/:id/transcript", controllers.GetMeetingTranscript)
+		protected.GET("/meeting/:id/redaction", controllers.GetMeetingRedaction)
+		protected.GET("/meeting/:id/video", controllers.GetMeetingVideo)
 		protected.POST("/meetings/backfill-videos", controllers.BackfillVideoURLs)
 
 		// Calendar routes
@@ -299,6 +771,32 @@ func main() {
 		protected.POST("/api/calendars/:id/sync", controllers.SyncCalendarEvents)
 		protected.POST("/api/calendar-events/:eventId/schedule-bot", controllers.ScheduleBotForEvent)
 		protected.DELETE("/api/calendar-events/:eventId/cancel-bot", controllers.CancelBotForEvent)
+		protected.POST("/api/calendar-events/:eventId/link-note", controllers.LinkNoteToEvent)
+		protected.DELETE("/api/calendar-events/:eventId/link-note", controllers.UnlinkNoteFromEvent)
+
+		// Recurring meeting series routes
+		protected.GET("/api/meeting-series", controllers.ListMeetingSeries)
+		protected.GET("/api/meeting-series/:seriesId", controllers.GetMeetingSeriesTimeline)
+		protected.POST("/api/meeting-series/:seriesId/summary", controllers.SummarizeMeetingSeries)
+
+		// Availability and scheduling link routes
+		protected.GET("/api/availability", controllers.GetAvailability)
+		protected.GET("/api/scheduling-links", controllers.ListSchedulingLinks)
+		protected.POST("/api/scheduling-links", controllers.CreateSchedulingLink)
+		protected.PUT("/api/scheduling-links/:linkId", controllers.UpdateSchedulingLink)
+		protected.DELETE("/api/scheduling-links/:linkId", controllers.DeleteSchedulingLink)
+		protected.GET("/api/booking-requests", controllers.ListBookingRequests)
+		protected.POST("/api/booking-requests/:requestId/confirm", controllers.ConfirmBookingRequest)
+		protected.POST("/api/booking-requests/:requestId/decline", controllers.DeclineBookingRequest)
+
+		// Google Drive import routes
+		protected.POST("/api/drive-auth", auth.BeginDriveOAuth)
+		protected.GET("/api/drive/connection", controllers.GetDriveConnection)
+		protected.DELETE("/api/drive/connection", controllers.DisconnectDrive)
+		protected.GET("/api/drive/files", controllers.ListDriveFiles)
+		protected.POST("/api/drive/import", controllers.ImportDriveFiles)
+		protected.GET("/api/drive/imports", controllers.ListDriveImports)
+		protected.POST("/api/drive/imports/refresh", controllers.RefreshDriveImports)
 	}
 
 	// Webhook routes (no authentication required for external services)
@@ -306,7 +804,7 @@ func main() {
 	{
 		webhook.POST("/recall", controllers.HandleRecallWebhook)
 		webhook.POST("/calendar/sync", controllers.HandleCalendarWebhook)
-		webhook.POST("/clerk", auth.UserCreatedWebhook) // Clerk user sync webhook
+		webhook.POST("/clerk", auth.ClerkWebhook) // Clerk membership and user events
 	}
 
 	// WhatsApp webhook routes
@@ -319,12 +817,51 @@ func main() {
 
 	// WhatsApp authentication routes (protected)
 	whatsappAuth := r.Group("/api/whatsapp")
-	whatsappAuth.Use(middleware.ClerkMiddleware())
-	whatsappAuth.Use(middleware.RequireAuth())
+	whatsappAuth.Use(authMiddleware...)
 	{
 		whatsappAuth.POST("/link", controllers.LinkWhatsAppAccount)
 	}
 
+	// Quick capture for shortcuts and launchers (API key or session authentication)
+	capture := r.Group("/api/capture")
+	capture.Use(middleware.AutomationKeyOrSession(services.NewAutomationService().Authenticate, authMiddleware...)...)
+	{
+		capture.POST("", controllers.Capture)
+	}
+
+	// Inbox triage of captured snippets (API key or session authentication)
+	inbox := r.Group("/api/inbox")
+	inbox.Use(middleware.AutomationKeyOrSession(services.NewAutomationService().Authenticate, authMiddleware...)...)
+	{
+		inbox.GET("", controllers.ListInbox)
+		inbox.GET("/:id/suggestion", controllers.GetInboxSuggestion)
+		inbox.POST("/:id/file", controllers.FileInboxItem)
+	}
+
+	// Automation API for Zapier, Make and similar platforms (API key authentication)
+	automation := r.Group("/api/automation")
+	automation.Use(middleware.RequireAutomationKey(services.NewAutomationService().Authenticate))
+	{
+		automation.GET("/me", controllers.GetAutomationMe)
+		automation.GET("/triggers/:trigger", controllers.PollAutomationTrigger)
+		automation.POST("/hooks", controllers.SubscribeAutomationHook)
+		automation.DELETE("/hooks/:hookId", controllers.UnsubscribeAutomationHook)
+		automation.GET("/chapters", controllers.ListAutomationChapters)
+		automation.GET("/boards", controllers.ListAutomationTaskBoards)
+		automation.POST("/actions/notes", controllers.AutomationCreateNote)
+		automation.POST("/actions/notes/:noteId/append", controllers.AutomationAppendToNote)
+		automation.POST("/actions/tasks", controllers.AutomationCreateTask)
+
+		// Used by notes-cli
+		automation.GET("/notes", controllers.SearchAutomationNotes)
+		automation.PUT("/notes/:noteId", controllers.AutomationUpdateNote)
+		automation.GET("/export", controllers.ExportAutomationNotes)
+		automation.GET("/changes", controllers.GetAutomationNoteChanges)
+		automation.GET("/conflicts", controllers.ListAutomationNoteConflicts)
+		automation.GET("/conflicts/:conflictId", controllers.GetAutomationNoteConflict)
+		automation.DELETE("/conflicts/:conflictId", controllers.DismissAutomationNoteConflict)
+	}
+
 	// Metrics endpoint (Prometheus)
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
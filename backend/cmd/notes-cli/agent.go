@@ -0,0 +1,134 @@
+package main
+
+import (
+	"backend/pkg/notesapi"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// fileStamp is what the agent compares to notice a file was saved
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// runAgent keeps a folder synced until it's stopped. Files saved in the folder are uploaded within the
+// watch interval and the server is asked for changes every interval, both with the same sync as the
+// sync command, so notes changed on both sides get conflict files.
+func runAgent(ctx context.Context, client *notesapi.Client, args []string) error {
+	flags := flag.NewFlagSet("agent", flag.ExitOnError)
+	interval := flags.Duration("interval", 30*time.Second, "how often to check the server for changes")
+	watchInterval := flags.Duration("watch", 2*time.Second, "how often to check the folder for changes")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("agent takes the folder to sync")
+	}
+	if *interval <= 0 || *watchInterval <= 0 {
+		return errors.New("intervals must be positive")
+	}
+	dir := flags.Arg(0)
+
+	state, err := openSyncState(dir, client)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start with a full sync to catch up on what changed while the agent wasn't running, and keep
+	// trying one until it works
+	full := true
+	sync := func() {
+		report, err := syncFolder(ctx, client, dir, state, full)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s Sync failed, retrying later: %v\n", time.Now().Format(time.TimeOnly), err)
+			return
+		}
+		full = false
+		if *report != (syncReport{}) {
+			fmt.Printf("%s Synced: %s\n", time.Now().Format(time.TimeOnly), report)
+		}
+	}
+
+	sync()
+	fmt.Printf("Syncing %s with %s, press Ctrl+C to stop\n", dir, client.BaseURL)
+
+	// Syncing writes files too, so the folder is scanned again after every sync
+	files, err := scanFolder(dir)
+	if err != nil {
+		return err
+	}
+	remoteTicker := time.NewTicker(*interval)
+	defer remoteTicker.Stop()
+	watchTicker := time.NewTicker(*watchInterval)
+	defer watchTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopped")
+			return nil
+		case <-remoteTicker.C:
+			sync()
+		case <-watchTicker.C:
+			current, err := scanFolder(dir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s %v\n", time.Now().Format(time.TimeOnly), err)
+				continue
+			}
+			if maps.Equal(current, files) {
+				continue
+			}
+			sync()
+		}
+
+		if current, err := scanFolder(dir); err == nil {
+			files = current
+		}
+	}
+}
+
+// scanFolder returns the Markdown files in a synced folder, conflict files included so deleting one
+// uploads its merged note
+func scanFolder(dir string) (map[string]fileStamp, error) {
+	files := map[string]fileStamp{}
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && filePath != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files[filePath] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return files, nil
+}
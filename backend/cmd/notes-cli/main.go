@@ -0,0 +1,218 @@
+// notes-cli adds, searches, exports and syncs notes from the command line, and can run as an agent
+// that keeps a folder synced. It authenticates with an automation API key created in the app's
+// settings, passed with --key or NOTES_API_KEY.
+package main
+
+import (
+	"backend/pkg/notesapi"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const usage = `Usage: notes-cli [--url URL] [--key KEY] <command> [arguments]
+
+Commands:
+  add --chapter CHAPTER [--name NAME] [FILE]   Create a note from a Markdown file, or stdin
+  search [--limit N] QUERY                     Find notes by name or content
+  export DIR                                   Write every note to DIR as Markdown
+  sync [--full] DIR                            Sync notes both ways with a Markdown folder
+  agent [--interval 30s] [--watch 2s] DIR      Keep a Markdown folder synced until stopped
+
+CHAPTER is a chapter ID or "notebook/chapter". The server URL and API key default to
+NOTES_API_URL (or http://localhost:8080) and NOTES_API_KEY.
+`
+
+func main() {
+	flags := flag.NewFlagSet("notes-cli", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	serverURL := flags.String("url", envOrDefault("NOTES_API_URL", "http://localhost:8080"), "server URL")
+	apiKey := flags.String("key", os.Getenv("NOTES_API_KEY"), "automation API key")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *apiKey == "" {
+		fail(errors.New("an API key is required, pass --key or set NOTES_API_KEY"))
+	}
+
+	client := notesapi.NewClient(*serverURL, *apiKey)
+	ctx := context.Background()
+	command, args := flags.Arg(0), flags.Args()[1:]
+
+	var err error
+	switch command {
+	case "add":
+		err = runAdd(ctx, client, args)
+	case "search":
+		err = runSearch(ctx, client, args)
+	case "export":
+		err = runExport(ctx, client, args)
+	case "sync":
+		err = runSync(ctx, client, args)
+	case "agent":
+		err = runAgent(ctx, client, args)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+// runAdd creates a note from a Markdown file or stdin
+func runAdd(ctx context.Context, client *notesapi.Client, args []string) error {
+	flags := flag.NewFlagSet("add", flag.ExitOnError)
+	chapter := flags.String("chapter", os.Getenv("NOTES_CHAPTER"), `chapter ID or "notebook/chapter"`)
+	name := flags.String("name", "", "note name, defaults to the file name or first line")
+	flags.Parse(args)
+
+	if *chapter == "" {
+		return errors.New("--chapter is required")
+	}
+
+	var content []byte
+	var err error
+	switch flags.NArg() {
+	case 0:
+		content, err = io.ReadAll(os.Stdin)
+	case 1:
+		content, err = os.ReadFile(flags.Arg(0))
+		if *name == "" {
+			*name = strings.TrimSuffix(filepath.Base(flags.Arg(0)), filepath.Ext(flags.Arg(0)))
+		}
+	default:
+		return errors.New("add takes at most one file")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read note: %w", err)
+	}
+
+	file := parseNoteFile(string(content))
+	if *name == "" {
+		*name = file.Title
+	}
+	if *name == "" {
+		*name = firstLine(file.Body)
+	}
+	if *name == "" {
+		return errors.New("the note needs a name, pass --name")
+	}
+
+	chapterID, err := resolveChapter(ctx, client, *chapter)
+	if err != nil {
+		return err
+	}
+	note, err := client.CreateNote(ctx, chapterID, *name, file.Body)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %q (%s) in %s / %s\n", note.Name, note.NoteID, note.NotebookName, note.ChapterName)
+	return nil
+}
+
+// runSearch prints the notes matching a query
+func runSearch(ctx context.Context, client *notesapi.Client, args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "maximum number of results")
+	flags.Parse(args)
+
+	query := strings.Join(flags.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return errors.New("search needs a query")
+	}
+
+	notes, err := client.SearchNotes(ctx, query, *limit)
+	if err != nil {
+		return err
+	}
+	if len(notes) == 0 {
+		fmt.Println("No notes found")
+		return nil
+	}
+	for _, note := range notes {
+		fmt.Printf("%s\t%s / %s / %s\n", note.NoteID, note.NotebookName, note.ChapterName, note.Name)
+	}
+	return nil
+}
+
+// runExport writes every note to a folder, laid out like sync does
+func runExport(ctx context.Context, client *notesapi.Client, args []string) error {
+	if len(args) != 1 {
+		return errors.New("export takes the folder to write to")
+	}
+	dir := args[0]
+
+	export, err := client.Export(ctx)
+	if err != nil {
+		return err
+	}
+	layout := newFolderLayout(export.Chapters)
+	for i := range export.Notes {
+		note := &export.Notes[i]
+		if _, err := writeNoteFile(dir, layout.notePath(note), note); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Exported %d notes to %s\n", len(export.Notes), dir)
+	return nil
+}
+
+// resolveChapter returns the ID of a chapter given by ID or as "notebook/chapter", matching names or slugs
+func resolveChapter(ctx context.Context, client *notesapi.Client, chapter string) (string, error) {
+	export, err := client.Export(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	notebookName, chapterName, byName := strings.Cut(chapter, "/")
+	var matches []notesapi.Chapter
+	for _, c := range export.Chapters {
+		if c.ID == chapter {
+			return c.ID, nil
+		}
+		if byName && nameMatches(notebookName, c.NotebookName, c.NotebookSlug) && nameMatches(chapterName, c.Name, c.Slug) {
+			matches = append(matches, c)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("chapter %q not found", chapter)
+	case 1:
+		return matches[0].ID, nil
+	default:
+		return "", fmt.Errorf("more than one chapter matches %q, use its ID", chapter)
+	}
+}
+
+func nameMatches(given, name, slug string) bool {
+	given = strings.TrimSpace(given)
+	return strings.EqualFold(given, name) || strings.EqualFold(given, slug)
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(strings.TrimLeft(line, "# "))
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(1)
+}
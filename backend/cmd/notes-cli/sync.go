@@ -0,0 +1,602 @@
+package main
+
+import (
+	"backend/pkg/notesapi"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stateFileName is the file in a synced folder that remembers each note's file and last synced version
+const stateFileName = ".notes-sync.json"
+
+// conflictSuffix marks the merge, or the server's version, of a note that changed on both sides
+const conflictSuffix = ".conflict.md"
+
+// baseDirName is the folder that keeps each note's content at the last sync, for the server to merge
+// notes changed on both sides with
+const baseDirName = ".notes-sync-base"
+
+// syncState is what the last sync saw, to tell local edits from server edits
+type syncState struct {
+	Server string                 `json:"server"`
+	Cursor time.Time              `json:"cursor"` // From the server, to ask for the notes changed since
+	Notes  map[string]*syncedNote `json:"notes"`  // By note ID
+}
+
+// syncedNote is a note's file and the versions of both sides at the last sync
+type syncedNote struct {
+	Path      string    `json:"path"` // Relative, with forward slashes
+	UpdatedAt time.Time `json:"updatedAt"`
+	Hash      string    `json:"hash"` // SHA-256 of the file
+}
+
+// syncReport counts what a sync did
+type syncReport struct {
+	downloaded, uploaded, created, merged, conflicts, skipped int
+}
+
+func (r *syncReport) String() string {
+	return fmt.Sprintf("%d downloaded, %d uploaded, %d created, %d merged, %d conflicts, %d skipped",
+		r.downloaded, r.uploaded, r.created, r.merged, r.conflicts, r.skipped)
+}
+
+// runSync syncs a folder of Markdown files with the server both ways. Files are laid out as
+// notebook/chapter/note.md. Edits on either side are copied to the other; when a note changed on both,
+// the server merges the two. A clean merge is synced both ways, otherwise the merge is saved next to the
+// file as .conflict.md and the file isn't uploaded until it's resolved and the conflict file deleted.
+// Deleting or moving files isn't synced.
+func runSync(ctx context.Context, client *notesapi.Client, args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	full := flags.Bool("full", false, "compare every note, not only those changed since the last sync")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("sync takes the folder to sync")
+	}
+	dir := flags.Arg(0)
+
+	state, err := openSyncState(dir, client)
+	if err != nil {
+		return err
+	}
+	report, err := syncFolder(ctx, client, dir, state, *full)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Synced %s: %s\n", dir, report)
+	return nil
+}
+
+// openSyncState loads the sync state of a folder, creating the folder for a first sync
+func openSyncState(dir string, client *notesapi.Client) (*syncState, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create folder: %w", err)
+	}
+	state, err := loadSyncState(dir)
+	if err != nil {
+		return nil, err
+	}
+	if state.Server != "" && state.Server != client.BaseURL {
+		return nil, fmt.Errorf("%s is synced with %s, not %s", dir, state.Server, client.BaseURL)
+	}
+	state.Server = client.BaseURL
+	return state, nil
+}
+
+// syncFolder syncs a folder once: the notes changed on the server since the last sync, or every note
+// when full, then the files edited or added since. What was synced is saved even if it fails part way,
+// so the next sync doesn't redo it.
+func syncFolder(ctx context.Context, client *notesapi.Client, dir string, state *syncState, full bool) (*syncReport, error) {
+	report := &syncReport{}
+	err := syncChanges(ctx, client, dir, state, full || state.Cursor.IsZero(), report)
+	if saveErr := saveSyncState(dir, state); saveErr != nil {
+		return report, errors.Join(err, saveErr)
+	}
+	return report, err
+}
+
+func syncChanges(ctx context.Context, client *notesapi.Client, dir string, state *syncState, full bool, report *syncReport) error {
+	since := state.Cursor
+	if full {
+		since = time.Time{}
+	}
+	changes, err := client.GetChanges(ctx, since)
+	if err != nil {
+		return err
+	}
+	layout := newFolderLayout(changes.Chapters)
+
+	changed := make(map[string]bool, len(changes.Notes))
+	for i := range changes.Notes {
+		note := &changes.Notes[i]
+		changed[note.NoteID] = true
+		if err := syncRemoteNote(ctx, client, dir, layout, state, note, report); err != nil {
+			return err
+		}
+	}
+
+	// Notes deleted on the server keep their files, they're only no longer synced
+	remote := make(map[string]bool, len(changes.NoteIDs))
+	for _, noteID := range changes.NoteIDs {
+		remote[noteID] = true
+	}
+	for noteID, synced := range state.Notes {
+		if !remote[noteID] {
+			fmt.Printf("No longer on the server, keeping %s\n", synced.Path)
+			delete(state.Notes, noteID)
+		}
+	}
+
+	// Files edited since the last sync whose notes didn't change on the server
+	noteIDs := make([]string, 0, len(state.Notes))
+	for noteID := range state.Notes {
+		if !changed[noteID] {
+			noteIDs = append(noteIDs, noteID)
+		}
+	}
+	sort.Strings(noteIDs)
+	for _, noteID := range noteIDs {
+		if err := syncLocalNote(ctx, client, dir, noteID, state.Notes[noteID], report); err != nil {
+			return err
+		}
+	}
+
+	if err := createLocalNotes(ctx, client, dir, layout, state, remote, report); err != nil {
+		return err
+	}
+	state.Cursor = changes.Cursor
+	return nil
+}
+
+// syncRemoteNote brings a note from the server and its file in line
+func syncRemoteNote(ctx context.Context, client *notesapi.Client, dir string, layout *folderLayout, state *syncState, note *notesapi.Note, report *syncReport) error {
+	synced := state.Notes[note.NoteID]
+	if synced == nil {
+		return syncNewRemoteNote(dir, layout, state, note, report)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(synced.Path)))
+	if errors.Is(err, fs.ErrNotExist) {
+		// Deleting a file doesn't delete the note, download it again
+		return downloadNote(dir, synced.Path, state, note, report)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", synced.Path, err)
+	}
+
+	localChanged := hashContent(content) != synced.Hash
+	remoteChanged := !note.UpdatedAt.Equal(synced.UpdatedAt)
+	switch {
+	case localChanged && remoteChanged:
+		return mergeNote(ctx, client, dir, synced, note, content, report)
+	case remoteChanged:
+		return downloadNote(dir, synced.Path, state, note, report)
+	case localChanged:
+		return uploadNote(ctx, client, dir, note.NoteID, synced, content, report)
+	}
+	return nil
+}
+
+// syncLocalNote uploads a note's file if it was edited since the last sync. A deleted file is left
+// alone, a full sync downloads it again.
+func syncLocalNote(ctx context.Context, client *notesapi.Client, dir, noteID string, synced *syncedNote, report *syncReport) error {
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(synced.Path)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", synced.Path, err)
+	}
+	if hashContent(content) == synced.Hash {
+		return nil
+	}
+	return uploadNote(ctx, client, dir, noteID, synced, content, report)
+}
+
+// syncNewRemoteNote tracks a note the folder hasn't seen. A file already at its path from an export
+// of the same note is kept and compared like a conflict; a different file there keeps its path.
+func syncNewRemoteNote(dir string, layout *folderLayout, state *syncState, note *notesapi.Note, report *syncReport) error {
+	notePath := layout.notePath(note)
+	for n := 2; ; n++ {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(notePath)))
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", notePath, err)
+		}
+
+		if parseNoteFile(string(content)).ID == note.NoteID {
+			if string(content) == formatNoteFile(note) {
+				state.Notes[note.NoteID] = &syncedNote{Path: notePath, UpdatedAt: note.UpdatedAt, Hash: hashContent(content)}
+				return saveBase(dir, note.NoteID, note.Content)
+			}
+			// No hash, so the next sync uploads the file like after any conflict
+			synced := &syncedNote{Path: notePath}
+			state.Notes[note.NoteID] = synced
+			return saveConflict(dir, synced, note, report)
+		}
+		notePath = strings.TrimSuffix(layout.notePath(note), ".md") + fmt.Sprintf("-%d.md", n)
+	}
+	return downloadNote(dir, notePath, state, note, report)
+}
+
+// downloadNote writes the server's version of a note to its file
+func downloadNote(dir, notePath string, state *syncState, note *notesapi.Note, report *syncReport) error {
+	hash, err := writeNoteFile(dir, notePath, note)
+	if err != nil {
+		return err
+	}
+	state.Notes[note.NoteID] = &syncedNote{Path: notePath, UpdatedAt: note.UpdatedAt, Hash: hash}
+	report.downloaded++
+	return saveBase(dir, note.NoteID, note.Content)
+}
+
+// uploadNote sends a file edited since the last sync to the server. The server refuses it if the note
+// changed in the meantime, and the next sync handles it as a conflict. Files with a conflict file next
+// to them wait until it's merged and deleted.
+func uploadNote(ctx context.Context, client *notesapi.Client, dir, noteID string, synced *syncedNote, content []byte, report *syncReport) error {
+	conflictPath := conflictFilePath(synced.Path)
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(conflictPath))); err == nil {
+		report.skipped++
+		fmt.Printf("Merge %s into %s and delete it to upload the file\n", conflictPath, synced.Path)
+		return nil
+	}
+
+	file := parseNoteFile(string(content))
+	update := notesapi.NoteUpdate{Content: &file.Body, UpdatedAt: &synced.UpdatedAt}
+	if file.Title != "" {
+		update.Name = &file.Title
+	}
+
+	updated, err := client.UpdateNote(ctx, noteID, update)
+	if errors.Is(err, notesapi.ErrConflict) {
+		report.skipped++
+		fmt.Printf("Changed on the server while syncing, skipping %s until the next sync\n", synced.Path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", synced.Path, err)
+	}
+
+	// The file is kept as written, the server's Markdown may differ in formatting only
+	synced.UpdatedAt = updated.UpdatedAt
+	synced.Hash = hashContent(content)
+	report.uploaded++
+	return saveBase(dir, noteID, file.Body)
+}
+
+// mergeNote uploads a file whose note changed on both sides with the content last synced, for the
+// server to merge with its version. A clean merge is uploaded and written to the file. Otherwise the
+// merge, quoting both versions of the blocks changed on both sides, is saved next to the file like the
+// server's version would be.
+func mergeNote(ctx context.Context, client *notesapi.Client, dir string, synced *syncedNote, note *notesapi.Note, content []byte, report *syncReport) error {
+	file := parseNoteFile(string(content))
+	update := notesapi.NoteUpdate{Content: &file.Body, UpdatedAt: &synced.UpdatedAt}
+	if base, err := os.ReadFile(basePath(dir, note.NoteID)); err == nil {
+		baseContent := string(base)
+		update.BaseContent = &baseContent
+	}
+
+	_, err := client.UpdateNote(ctx, note.NoteID, update)
+	var conflictErr *notesapi.ConflictError
+	if !errors.As(err, &conflictErr) {
+		if err != nil && !errors.Is(err, notesapi.ErrConflict) {
+			return fmt.Errorf("failed to upload %s: %w", synced.Path, err)
+		}
+		// A server that doesn't merge, or a note that changed back
+		return saveConflict(dir, synced, note, report)
+	}
+	conflict, err := client.GetConflict(ctx, conflictErr.ConflictID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the merge of %s: %w", synced.Path, err)
+	}
+
+	if conflict.ConflictCount == 0 {
+		merged, err := client.UpdateNote(ctx, note.NoteID, notesapi.NoteUpdate{Content: &conflict.Merged, UpdatedAt: &conflict.ServerUpdatedAt})
+		if errors.Is(err, notesapi.ErrConflict) {
+			report.skipped++
+			fmt.Printf("Changed on the server while merging, skipping %s until the next sync\n", synced.Path)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to upload the merge of %s: %w", synced.Path, err)
+		}
+		hash, err := writeNoteFile(dir, synced.Path, merged)
+		if err != nil {
+			return err
+		}
+		synced.UpdatedAt = merged.UpdatedAt
+		synced.Hash = hash
+		report.merged++
+		fmt.Printf("Changed on both sides, merged %s\n", synced.Path)
+		return saveBase(dir, note.NoteID, merged.Content)
+	}
+
+	conflictPath := conflictFilePath(synced.Path)
+	mergedNote := *note
+	mergedNote.Content = conflict.Merged
+	if _, err := writeNoteFile(dir, conflictPath, &mergedNote); err != nil {
+		return err
+	}
+	synced.UpdatedAt = conflict.ServerUpdatedAt
+	report.conflicts++
+	fmt.Printf("Changed on both sides, saved a merge with %d conflicts to %s. Resolve them, copy it over %s and delete it.\n",
+		conflict.ConflictCount, conflictPath, synced.Path)
+	return nil
+}
+
+// saveConflict writes the server's version of a note changed on both sides next to its file. The note
+// is marked as synced with the server so the merged file is uploaded once the conflict file is deleted.
+func saveConflict(dir string, synced *syncedNote, note *notesapi.Note, report *syncReport) error {
+	conflictPath := conflictFilePath(synced.Path)
+	if _, err := writeNoteFile(dir, conflictPath, note); err != nil {
+		return err
+	}
+	synced.UpdatedAt = note.UpdatedAt
+	report.conflicts++
+	fmt.Printf("Changed on both sides, saved the server's version to %s. Merge it into %s and delete it.\n", conflictPath, synced.Path)
+	return nil
+}
+
+func conflictFilePath(notePath string) string {
+	return strings.TrimSuffix(notePath, ".md") + conflictSuffix
+}
+
+// createLocalNotes creates notes for new files in chapter folders
+func createLocalNotes(ctx context.Context, client *notesapi.Client, dir string, layout *folderLayout, state *syncState, remote map[string]bool, report *syncReport) error {
+	tracked := make(map[string]bool, len(state.Notes))
+	for _, synced := range state.Notes {
+		tracked[synced.Path] = true
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && filePath != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") || strings.HasSuffix(entry.Name(), conflictSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); !tracked[rel] {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	sort.Strings(files)
+
+	for _, rel := range files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+		file := parseNoteFile(string(content))
+		if file.ID != "" {
+			if remote[file.ID] {
+				fmt.Printf("Moving notes isn't synced, skipping %s\n", rel)
+			} else {
+				fmt.Printf("Note %s isn't on the server, skipping %s\n", file.ID, rel)
+			}
+			report.skipped++
+			continue
+		}
+
+		chapterID, ok := layout.dirChapters[path.Dir(rel)]
+		if !ok {
+			fmt.Printf("Not in a chapter folder, skipping %s\n", rel)
+			report.skipped++
+			continue
+		}
+		name := file.Title
+		if name == "" {
+			name = strings.TrimSuffix(path.Base(rel), ".md")
+		}
+
+		note, err := client.CreateNote(ctx, chapterID, name, file.Body)
+		if err != nil {
+			return fmt.Errorf("failed to create a note from %s: %w", rel, err)
+		}
+		// Rewrite the file with the note's ID so the next sync knows it
+		hash, err := writeNoteFile(dir, rel, note)
+		if err != nil {
+			return err
+		}
+		state.Notes[note.NoteID] = &syncedNote{Path: rel, UpdatedAt: note.UpdatedAt, Hash: hash}
+		report.created++
+		if err := saveBase(dir, note.NoteID, note.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// folderLayout maps chapters to folders, notebook/chapter, by slug
+type folderLayout struct {
+	chapterDirs map[string]string // Chapter ID to folder
+	dirChapters map[string]string // Folder to chapter ID
+}
+
+func newFolderLayout(chapters []notesapi.Chapter) *folderLayout {
+	layout := &folderLayout{
+		chapterDirs: make(map[string]string, len(chapters)),
+		dirChapters: make(map[string]string, len(chapters)),
+	}
+
+	// Notebooks with the same name get numbered folders, in the order the server lists them
+	notebookDirs := map[string]string{}
+	taken := map[string]bool{}
+	for _, chapter := range chapters {
+		notebookDir, ok := notebookDirs[chapter.NotebookID]
+		if !ok {
+			base := slugOr(chapter.NotebookSlug, chapter.NotebookID)
+			notebookDir = base
+			for n := 2; taken[notebookDir]; n++ {
+				notebookDir = fmt.Sprintf("%s-%d", base, n)
+			}
+			notebookDirs[chapter.NotebookID] = notebookDir
+			taken[notebookDir] = true
+		}
+
+		chapterDir := notebookDir + "/" + slugOr(chapter.Slug, chapter.ID)
+		layout.chapterDirs[chapter.ID] = chapterDir
+		layout.dirChapters[chapterDir] = chapter.ID
+	}
+	return layout
+}
+
+// notePath returns a note's file, relative to the synced folder
+func (l *folderLayout) notePath(note *notesapi.Note) string {
+	chapterDir, ok := l.chapterDirs[note.ChapterID]
+	if !ok {
+		chapterDir = "unsorted"
+	}
+	return chapterDir + "/" + slugOr(note.Slug, note.NoteID) + ".md"
+}
+
+// slugOr returns the slug, or the ID for records slugged before slugs existed
+func slugOr(slug, id string) string {
+	if slug == "" {
+		return id
+	}
+	return slug
+}
+
+// noteFile is a note's Markdown file: front matter with its ID and title, then the content
+type noteFile struct {
+	ID    string
+	Title string
+	Body  string
+}
+
+// formatNoteFile renders a note as a Markdown file with front matter
+func formatNoteFile(note *notesapi.Note) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("id: " + note.NoteID + "\n")
+	b.WriteString("title: " + strings.ReplaceAll(note.Name, "\n", " ") + "\n")
+	b.WriteString("---\n\n")
+	b.WriteString(strings.TrimSpace(note.Content))
+	b.WriteString("\n")
+	return b.String()
+}
+
+// parseNoteFile reads a Markdown file with optional front matter
+func parseNoteFile(content string) noteFile {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	rest, ok := strings.CutPrefix(content, "---\n")
+	if !ok {
+		return noteFile{Body: strings.TrimSpace(content)}
+	}
+	frontMatter, body, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		return noteFile{Body: strings.TrimSpace(content)}
+	}
+
+	file := noteFile{Body: strings.TrimSpace(body)}
+	for _, line := range strings.Split(frontMatter, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(key) {
+		case "id":
+			file.ID = value
+		case "title":
+			file.Title = value
+		}
+	}
+	return file
+}
+
+// writeNoteFile writes a note to its file and returns the hash of what was written
+func writeNoteFile(dir, notePath string, note *notesapi.Note) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(notePath))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create folder for %s: %w", notePath, err)
+	}
+	content := []byte(formatNoteFile(note))
+	if err := os.WriteFile(target, content, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", notePath, err)
+	}
+	return hashContent(content), nil
+}
+
+// saveBase keeps a note's content as last synced
+func saveBase(dir, noteID, content string) error {
+	target := basePath(dir, noteID)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", baseDirName, err)
+	}
+	if err := os.WriteFile(target, []byte(strings.TrimSpace(content)), 0o644); err != nil {
+		return fmt.Errorf("failed to save the synced content of %s: %w", noteID, err)
+	}
+	return nil
+}
+
+func basePath(dir, noteID string) string {
+	return filepath.Join(dir, baseDirName, noteID+".md")
+}
+
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func loadSyncState(dir string) (*syncState, error) {
+	state := &syncState{Notes: map[string]*syncedNote{}}
+	content, err := os.ReadFile(filepath.Join(dir, stateFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", stateFileName, err)
+	}
+	if state.Notes == nil {
+		state.Notes = map[string]*syncedNote{}
+	}
+	return state, nil
+}
+
+func saveSyncState(dir string, state *syncState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sync state: %w", err)
+	}
+	tmp := filepath.Join(dir, stateFileName+".tmp")
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, stateFileName)); err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	return nil
+}
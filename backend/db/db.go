@@ -2,12 +2,11 @@ package db
 
 import (
 	"backend/internal/models"
+	"context"
 	"os"
-	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -20,13 +19,15 @@ func InitDB() {
 		log.Print("Failed to load .env file:", err)
 	}
 
-	dsn := os.Getenv("DB_URL")
+	// DB_URL is a PostgreSQL DSN, or the database file when DB_DRIVER is sqlite
+	driver = Driver()
+	dialector, err := openDialector(driver, os.Getenv("DB_URL"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure database")
+	}
 
-	// Open connection with local PostgreSQL optimized settings
-	DB, err = gorm.Open(postgres.New(postgres.Config{
-		DSN:                  dsn,
-		PreferSimpleProtocol: true, // Use simple protocol to avoid prepared statement conflicts with hot-reload
-	}), &gorm.Config{
+	// Open connection with hot-reload friendly settings
+	DB, err = gorm.Open(dialector, &gorm.Config{
 		PrepareStmt:            false,                                // Disable prepared statements for hot-reload compatibility
 		SkipDefaultTransaction: true,                                 // Improves performance
 		Logger:                 logger.Default.LogMode(logger.Error), // Only log errors, not slow queries during startup
@@ -35,45 +36,157 @@ func InitDB() {
 		log.Fatal().Err(err).Msg("Failed to connect to database")
 	}
 
-	// Configure connection pool for local PostgreSQL
+	// Time every query and stop those that would otherwise hang a request
+	if err := RegisterQueryCallbacks(DB, QueryTimeout()); err != nil {
+		log.Fatal().Err(err).Msg("Failed to register query callbacks")
+	}
+
+	// Configure the connection pool, sized for local PostgreSQL unless overridden
 	sqlDB, err := DB.DB()
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to get database instance")
 	}
-	sqlDB.SetMaxOpenConns(25)                  // Higher for local DB
-	sqlDB.SetMaxIdleConns(10)                  // More idle connections for local
-	sqlDB.SetConnMaxLifetime(30 * time.Minute) // Longer lifetime for local
-	sqlDB.SetConnMaxIdleTime(5 * time.Minute)  // Longer idle time for local
-
-	log.Info().Msg("Database connected successfully")
+	pool := LoadPoolConfig()
+	pool.apply(sqlDB)
+	registerPoolMetrics(sqlDB, "primary")
 
-	// Run migrations in background to not block server startup
-	go func() {
-		log.Info().Msg("Starting database schema migration in background...")
-		if err := DB.AutoMigrate(
-			&models.Notebook{},
-			&models.Chapter{},
-			&models.Notes{},
-			&models.NoteLink{},
-			&models.TaskBoard{},
-			&models.Task{},
-			&models.TaskAssignment{},
-			&models.AICredential{},
-			&models.OrganizationAPICredential{},
-			&models.MeetingRecording{},
-			&models.Calendar{},
-			&models.CalendarEvent{},
-			&models.CalendarOAuthState{},
-			&models.YjsDocument{},
-			&models.YjsUpdate{},
-			&models.WhatsAppUser{},
-			&models.WhatsAppConversationContext{},
-			&models.WhatsAppGroupLink{},
-			&models.WhatsAppMessage{},
-		); err != nil {
-			log.Error().Err(err).Msg("Failed to migrate schema")
-		} else {
-			log.Info().Msg("Database schema migrated successfully")
+	// Heavy reads go to the read replicas when there are any
+	if IsSQLite() {
+		if os.Getenv("DB_REPLICA_URLS") != "" {
+			log.Warn().Msg("DB_REPLICA_URLS is ignored with SQLite")
 		}
-	}()
+	} else if err := registerReplicas(DB, pool); err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure read replicas")
+	}
+
+	log.Info().Str("driver", driver).Msg("Database connected successfully")
+
+	// A SQLite file is small and likely new, so migrate it before serving. PostgreSQL migrates in the
+	// background to not block server startup.
+	if IsSQLite() {
+		migrate()
+		return
+	}
+	go migrate()
+}
+
+// migrated is closed once the schema migration has run
+var migrated = make(chan struct{})
+
+// Migrated returns a channel that's closed once the schema migration has run, for startup work that
+// needs the tables
+func Migrated() <-chan struct{} {
+	return migrated
+}
+
+// migrate brings the schema up to date with the models. AutoMigrate issues the DDL for the driver
+// in use, so the same models migrate PostgreSQL and SQLite.
+func migrate() {
+	defer close(migrated)
+	log.Info().Msg("Starting database schema migration...")
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+	if err := DB.WithContext(ctx).AutoMigrate(Models()...); err != nil {
+		log.Error().Err(err).Msg("Failed to migrate schema")
+	} else {
+		log.Info().Msg("Database schema migrated successfully")
+	}
+}
+
+// Models returns the models of every table the app owns, migrated on start and included in backups
+func Models() []interface{} {
+	return []interface{}{
+		&models.Notebook{},
+		&models.Chapter{},
+		&models.Notes{},
+		&models.NoteLink{},
+		&models.ExternalLink{},
+		&models.NoteTag{},
+		&models.TaskBoard{},
+		&models.Task{},
+		&models.TaskAssignment{},
+		&models.AICredential{},
+		&models.OrganizationAPICredential{},
+		&models.UserAISettings{},
+		&models.OrganizationAISettings{},
+		&models.MeetingRecording{},
+		&models.Calendar{},
+		&models.CalendarEvent{},
+		&models.CalendarOAuthState{},
+		&models.MeetingSeries{},
+		&models.SchedulingLink{},
+		&models.BookingRequest{},
+		&models.UserPreferences{},
+		&models.OrganizationRedactionPolicy{},
+		&models.TranscriptRedaction{},
+		&models.OrganizationTranscriptionSettings{},
+		&models.OrganizationBotSettings{},
+		&models.GoogleDriveConnection{},
+		&models.GoogleDocImport{},
+		&models.GitHubIntegration{},
+		&models.GitHubSyncedFile{},
+		&models.AutomationAPIKey{},
+		&models.AutomationWebhook{},
+		&models.NoteConflict{},
+		&models.TaskComment{},
+		&models.TaskSyncIntegration{},
+		&models.TaskExternalLink{},
+		&models.OrganizationEmbedSettings{},
+		&models.NoteAccessEvent{},
+		&models.KnowledgeReport{},
+		&models.NoteReview{},
+		&models.OrganizationPublishingSettings{},
+		&models.NotebookSnapshot{},
+		&models.NotebookQASettings{},
+		&models.NotebookQAUsage{},
+		&models.NoteFeedback{},
+		&models.NoteSuggestion{},
+		&models.NoteFavorite{},
+		&models.NoteResurfaceEvent{},
+		&models.WritingActivity{},
+		&models.WritingMilestone{},
+		&models.Goal{},
+		&models.OrganizationOnboardingSettings{},
+		&models.OrganizationProvisionedNotebook{},
+		&models.NoteEmbed{},
+		&models.NotebookPDFExport{},
+		&models.NoteEmail{},
+		&models.CaptureItem{},
+		&models.NoteRevision{},
+		&models.OrganizationRetentionPolicy{},
+		&models.NotebookLegalHold{},
+		&models.NotebookPrivacySettings{},
+		&models.NotebookLanguageVariant{},
+		&models.OrganizationStyleGuide{},
+		&models.GlossaryTerm{},
+		&models.GlossaryOccurrence{},
+		&models.LinkSuggestion{},
+		&models.ReadingListItem{},
+		&models.ReadingListHighlight{},
+		&models.NoteAnnotation{},
+		&models.Source{},
+		&models.DiagramRender{},
+		&models.TaskBoardShare{},
+		&models.PublicNoteRead{},
+		&models.FeedSubscription{},
+		&models.FeedEntry{},
+		&models.MeetingFilingSuggestion{},
+		&models.LinkCheck{},
+		&models.LinkCheckIssue{},
+		&models.NoteProperty{},
+		&models.NoteView{},
+		&models.TaskDependency{},
+		&models.Iteration{},
+		&models.OrganizationModerationPolicy{},
+		&models.ModerationFinding{},
+		&models.NotebookKeyEnvelope{},
+		&models.NoteLock{},
+		&models.YjsDocument{},
+		&models.YjsUpdate{},
+		&models.WhatsAppUser{},
+		&models.WhatsAppConversationContext{},
+		&models.WhatsAppGroupLink{},
+		&models.WhatsAppMessage{},
+		&models.SystemPrompt{},
+	}
 }
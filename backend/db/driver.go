@@ -0,0 +1,86 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Database drivers selectable with DB_DRIVER
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// defaultSQLitePath is the database file used when DB_DRIVER is sqlite and DB_URL is unset
+const defaultSQLitePath = "notes.db"
+
+// sqlitePragmas turn on foreign keys, let readers run alongside the writer and wait for a held write
+// lock instead of failing right away
+var sqlitePragmas = []string{"_foreign_keys=on", "_journal_mode=WAL", "_busy_timeout=5000"}
+
+// driver is the driver the database was opened with
+var driver = DriverPostgres
+
+// Driver returns the driver set by DB_DRIVER, postgres unless set to sqlite
+func Driver() string {
+	switch value := strings.ToLower(strings.TrimSpace(os.Getenv("DB_DRIVER"))); value {
+	case "", DriverPostgres, "postgresql":
+		return DriverPostgres
+	case DriverSQLite, "sqlite3":
+		return DriverSQLite
+	default:
+		return value
+	}
+}
+
+// ActiveDriver returns the driver the database was opened with, which DB_DRIVER only changes on restart
+func ActiveDriver() string {
+	return driver
+}
+
+// IsSQLite reports whether the database was opened with the SQLite driver
+func IsSQLite() bool {
+	return driver == DriverSQLite
+}
+
+// openDialector returns the dialector for a driver and DSN. For SQLite the DSN is a file path, with
+// the pragmas added unless it already sets them.
+func openDialector(name, dsn string) (gorm.Dialector, error) {
+	switch name {
+	case DriverPostgres:
+		return postgres.New(postgres.Config{
+			DSN:                  dsn,
+			PreferSimpleProtocol: true, // Use simple protocol to avoid prepared statement conflicts with hot-reload
+		}), nil
+	case DriverSQLite:
+		return sqlite.Open(sqliteDSN(dsn)), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q, use %q or %q", name, DriverPostgres, DriverSQLite)
+	}
+}
+
+func sqliteDSN(path string) string {
+	if path == "" {
+		path = defaultSQLitePath
+	}
+	var missing []string
+	for _, pragma := range sqlitePragmas {
+		key := pragma[:strings.Index(pragma, "=")+1]
+		if !strings.Contains(path, key) {
+			missing = append(missing, pragma)
+		}
+	}
+	if len(missing) == 0 {
+		return path
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + strings.Join(missing, "&")
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaResolver names the resolver that reads from the replicas. Reads only go to it through Replica,
+// everything else stays on the primary so requests read their own writes.
+const replicaResolver = "replica"
+
+// replicasEnabled is set once DB_REPLICA_URLS configured at least one replica
+var replicasEnabled bool
+
+// PoolConfig sizes a connection pool
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// LoadPoolConfig reads pool sizing from DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME and
+// DB_CONN_MAX_IDLE_TIME, keeping the defaults for unset or invalid values
+func LoadPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    envInt("DB_MAX_IDLE_CONNS", 10),
+		ConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: envDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+	}
+}
+
+// apply sizes a pool
+func (p PoolConfig) apply(sqlDB *sql.DB) {
+	sqlDB.SetMaxOpenConns(p.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(p.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(p.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+// Replica returns the query reading from a read replica when replicas are configured, and the query
+// unchanged otherwise. Use it for heavy reads that can lag behind writes a little, like search, the
+// graph, public pages and exports. Preloads still read from the primary.
+func Replica(tx *gorm.DB) *gorm.DB {
+	if !replicasEnabled {
+		return tx
+	}
+	return tx.Clauses(dbresolver.Use(replicaResolver))
+}
+
+// registerReplicas connects to the comma-separated replica DSNs in DB_REPLICA_URLS and registers them
+// with dbresolver. Each replica gets its own pool sized like the primary's.
+func registerReplicas(db *gorm.DB, pool PoolConfig) error {
+	var dialectors []gorm.Dialector
+	for i, dsn := range strings.Split(os.Getenv("DB_REPLICA_URLS"), ",") {
+		dsn = strings.TrimSpace(dsn)
+		if dsn == "" {
+			continue
+		}
+
+		replica, err := gorm.Open(postgres.New(postgres.Config{
+			DSN:                  dsn,
+			PreferSimpleProtocol: true,
+		}), &gorm.Config{Logger: db.Config.Logger})
+		if err != nil {
+			return fmt.Errorf("failed to connect to replica %d: %w", i+1, err)
+		}
+		sqlDB, err := replica.DB()
+		if err != nil {
+			return fmt.Errorf("failed to get replica %d instance: %w", i+1, err)
+		}
+		pool.apply(sqlDB)
+		registerPoolMetrics(sqlDB, fmt.Sprintf("replica_%d", i+1))
+
+		dialectors = append(dialectors, postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}))
+	}
+	if len(dialectors) == 0 {
+		return nil
+	}
+
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   dbresolver.RandomPolicy{},
+	}, replicaResolver)); err != nil {
+		return fmt.Errorf("failed to register replicas: %w", err)
+	}
+	replicasEnabled = true
+	log.Info().Int("replicas", len(dialectors)).Msg("Read replicas configured")
+	return nil
+}
+
+// registerPoolMetrics exposes a pool's open, idle and in-use connections and waits on /metrics
+func registerPoolMetrics(sqlDB *sql.DB, name string) {
+	if err := prometheus.Register(collectors.NewDBStatsCollector(sqlDB, name)); err != nil {
+		log.Warn().Err(err).Str("pool", name).Msg("Failed to register connection pool metrics")
+	}
+}
+
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Warn().Str("key", key).Str("value", value).Msg("Invalid integer setting, using the default")
+		return fallback
+	}
+	return n
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Warn().Str("key", key).Str("value", value).Msg("Invalid duration setting, using the default")
+		return fallback
+	}
+	return duration
+}
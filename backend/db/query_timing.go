@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// DefaultQueryTimeout bounds queries whose context has no deadline of its own
+const DefaultQueryTimeout = 30 * time.Second
+
+// migrationTimeout bounds each statement of the schema migration, which can rebuild indexes on large tables
+const migrationTimeout = 10 * time.Minute
+
+const (
+	queryStartKey    = "query_timing:start"
+	queryDeadlineKey = "query_timing:deadline"
+)
+
+// queryDeadline is the deadline a query was given, with the context to restore once it finishes so
+// a statement that runs several queries (like a count and then a find) doesn't reuse a cancelled context
+type queryDeadline struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+var queryDuration = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of database queries in seconds",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"operation", "table"},
+)
+
+// QueryStats adds up the queries made with a context, such as a request's
+type QueryStats struct {
+	count    atomic.Int64
+	duration atomic.Int64
+}
+
+// Count returns how many queries were made
+func (s *QueryStats) Count() int64 {
+	return s.count.Load()
+}
+
+// Duration returns the time spent in queries
+func (s *QueryStats) Duration() time.Duration {
+	return time.Duration(s.duration.Load())
+}
+
+func (s *QueryStats) add(elapsed time.Duration) {
+	s.count.Add(1)
+	s.duration.Add(int64(elapsed))
+}
+
+type queryStatsKey struct{}
+
+// WithQueryStats returns a context whose queries are added up in the returned stats
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	stats := &QueryStats{}
+	return context.WithValue(ctx, queryStatsKey{}, stats), stats
+}
+
+// QueryStatsFromContext returns the stats queries made with the context add up to, or nil
+func QueryStatsFromContext(ctx context.Context) *QueryStats {
+	if ctx == nil {
+		return nil
+	}
+	stats, _ := ctx.Value(queryStatsKey{}).(*QueryStats)
+	return stats
+}
+
+// QueryTimeout returns the query timeout set by DB_QUERY_TIMEOUT (like "15s"), or the default
+func QueryTimeout() time.Duration {
+	value := os.Getenv("DB_QUERY_TIMEOUT")
+	if value == "" {
+		return DefaultQueryTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		log.Warn().Str("value", value).Msg("Invalid DB_QUERY_TIMEOUT, using the default")
+		return DefaultQueryTimeout
+	}
+	return timeout
+}
+
+// RegisterQueryCallbacks times every query and cancels those that run past the timeout when their
+// context has no deadline. Row queries are only timed, their rows are read after the callbacks return.
+func RegisterQueryCallbacks(db *gorm.DB, timeout time.Duration) error {
+	callbacks := db.Callback()
+	register := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+		bound     bool
+	}{
+		{"create", callbacks.Create().Before("gorm:begin_transaction").Register, callbacks.Create().After("gorm:commit_or_rollback_transaction").Register, true},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:after_query").Register, true},
+		{"update", callbacks.Update().Before("gorm:begin_transaction").Register, callbacks.Update().After("gorm:commit_or_rollback_transaction").Register, true},
+		{"delete", callbacks.Delete().Before("gorm:begin_transaction").Register, callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register, true},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register, true},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register, false},
+	}
+
+	for _, r := range register {
+		if err := r.before("query_timing:before_"+r.operation, startQuery(timeout, r.bound)); err != nil {
+			return err
+		}
+		if err := r.after("query_timing:after_"+r.operation, finishQuery(r.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startQuery records when a query starts and gives it a deadline if it has none
+func startQuery(timeout time.Duration, bound bool) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		tx.InstanceSet(queryStartKey, time.Now())
+		if !bound || timeout <= 0 {
+			return
+		}
+
+		parent := tx.Statement.Context
+		ctx := parent
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if _, ok := ctx.Deadline(); ok {
+			return
+		}
+		bounded, cancel := context.WithTimeout(ctx, timeout)
+		tx.Statement.Context = bounded
+		tx.InstanceSet(queryDeadlineKey, &queryDeadline{parent: parent, cancel: cancel})
+	}
+}
+
+// finishQuery releases a query's deadline and records how long it took
+func finishQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		started, ok := tx.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(started.(time.Time))
+		queryDuration.WithLabelValues(operation, tx.Statement.Table).Observe(elapsed.Seconds())
+		if stats := QueryStatsFromContext(tx.Statement.Context); stats != nil {
+			stats.add(elapsed)
+		}
+
+		if value, ok := tx.InstanceGet(queryDeadlineKey); ok {
+			if deadline, ok := value.(*queryDeadline); ok && deadline != nil {
+				deadline.cancel()
+				tx.Statement.Context = deadline.parent
+				tx.InstanceSet(queryDeadlineKey, (*queryDeadline)(nil))
+			}
+		}
+		if tx.Error != nil && errors.Is(tx.Error, context.DeadlineExceeded) {
+			log.Error().Str("operation", operation).Str("table", tx.Statement.Table).Dur("elapsed", elapsed).Msg("Database query timed out")
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"backend/db"
 	"backend/internal/middleware"
 	"backend/internal/models"
+	"backend/internal/services"
 	"backend/pkg/utils"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -82,7 +84,7 @@ func GetCurrentUser(c *gin.Context) {
 
 	// Check if user has any AI credentials
 	var credentialCount int64
-	db.DB.Model(&models.AICredential{}).Where("clerk_user_id = ?", clerkUserID).Count(&credentialCount)
+	db.DB.WithContext(c.Request.Context()).Model(&models.AICredential{}).Where("clerk_user_id = ?", clerkUserID).Count(&credentialCount)
 	hasApiKey := credentialCount > 0
 
 	currentUser := CurrentUserDTO{
@@ -242,7 +244,7 @@ func SetAICredential(c *gin.Context) {
 	}
 
 	// Encrypt the API key
-	encryptedKey, err := utils.Encrypt(apiKey)
+	encryptedKey, err := services.NewSecretsService().Encrypt(apiKey)
 	if err != nil {
 		log.Error().Err(err).Msg("Error encrypting API key")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt API key"})
@@ -256,7 +258,7 @@ func SetAICredential(c *gin.Context) {
 		KeyCipher:   encryptedKey,
 	}
 
-	if err := db.DB.Where(models.AICredential{ClerkUserID: clerkUserID, Provider: requestBody.Provider}).
+	if err := db.DB.WithContext(c.Request.Context()).Where(models.AICredential{ClerkUserID: clerkUserID, Provider: requestBody.Provider}).
 		Assign(models.AICredential{KeyCipher: encryptedKey}).
 		FirstOrCreate(&credential).Error; err != nil {
 		log.Error().Err(err).Msg("Error saving AI credential")
@@ -293,7 +295,7 @@ func DeleteAICredential(c *gin.Context) {
 	}
 
 	// Delete the credential
-	if err := db.DB.Where("clerk_user_id = ? AND provider = ?", clerkUserID, requestBody.Provider).
+	if err := db.DB.WithContext(c.Request.Context()).Where("clerk_user_id = ? AND provider = ?", clerkUserID, requestBody.Provider).
 		Delete(&models.AICredential{}).Error; err != nil {
 		log.Error().Err(err).Msg("Error deleting AI credential")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete API key"})
@@ -315,7 +317,7 @@ func GetAICredentials(c *gin.Context) {
 
 	// Get all credentials for the user
 	var credentials []models.AICredential
-	if err := db.DB.Where("clerk_user_id = ?", clerkUserID).Find(&credentials).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("clerk_user_id = ?", clerkUserID).Find(&credentials).Error; err != nil {
 		log.Error().Err(err).Msg("Error fetching AI credentials")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
 		return
@@ -332,11 +334,113 @@ func GetAICredentials(c *gin.Context) {
 	})
 }
 
-// UserCreatedWebhook handles Clerk user.created webhook events
-// Note: With the users table removed, this webhook is no longer needed
-// but kept for future extensibility if we need to trigger actions on user creation
-func UserCreatedWebhook(c *gin.Context) {
-	// No database sync needed anymore since Clerk is the source of truth
-	// This can be used for other side effects like sending welcome emails, etc.
-	c.JSON(http.StatusOK, gin.H{"message": "User webhook received"})
+// GetAISettings returns the user's default AI providers and models for chat, generation and background jobs
+func GetAISettings(c *gin.Context) {
+	claims, ok := clerk.SessionClaimsFromContext(c.Request.Context())
+	if !ok || claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	settings, err := services.NewAIModelSettingsService().GetUserSettings(claims.Subject)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching AI settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch AI settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateAISettings stores the user's default AI providers and models
+// Empty values clear a setting so the organization or server default applies
+func UpdateAISettings(c *gin.Context) {
+	claims, ok := clerk.SessionClaimsFromContext(c.Request.Context())
+	if !ok || claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var requestBody models.AIModelDefaults
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settingsService := services.NewAIModelSettingsService()
+	if err := settingsService.SetUserSettings(claims.Subject, requestBody); err != nil {
+		if errors.Is(err, services.ErrInvalidAIModelSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("Error saving AI settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save AI settings"})
+		return
+	}
+
+	settings, err := settingsService.GetUserSettings(claims.Subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch AI settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "AI settings saved successfully",
+		"settings": settings,
+	})
+}
+
+// GetAIToolSettings returns which assistant tools the user has enabled
+func GetAIToolSettings(c *gin.Context) {
+	claims, ok := clerk.SessionClaimsFromContext(c.Request.Context())
+	if !ok || claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	settings, err := services.NewAIToolPermissionService().GetUserPermissions(claims.Subject)
+	if err != nil {
+		log.Error().Err(err).Msg("Error fetching AI tool settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch AI tool settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateAIToolSettings stores which assistant tools the user has enabled
+func UpdateAIToolSettings(c *gin.Context) {
+	claims, ok := clerk.SessionClaimsFromContext(c.Request.Context())
+	if !ok || claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var requestBody services.AIToolPermissionSettings
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	permissionService := services.NewAIToolPermissionService()
+	if err := permissionService.SetUserPermissions(claims.Subject, requestBody); err != nil {
+		if errors.Is(err, services.ErrInvalidAIToolPermissions) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("Error saving AI tool settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save AI tool settings"})
+		return
+	}
+
+	settings, err := permissionService.GetUserPermissions(claims.Subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch AI tool settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "AI tool settings saved successfully",
+		"settings": settings,
+	})
 }
@@ -3,8 +3,8 @@ package auth
 import (
 	"backend/db"
 	"backend/internal/models"
+	"backend/internal/services"
 	"backend/pkg/recallai"
-	"backend/pkg/utils"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
@@ -94,7 +94,7 @@ func BeginCalendarOAuth(c *gin.Context) {
 		ExpiresAt:   time.Now().Add(10 * time.Minute),
 	}
 
-	if err := db.DB.Create(&oauthState).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Create(&oauthState).Error; err != nil {
 		log.Error().Err(err).Msg("Error storing OAuth state")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store state"})
 		return
@@ -167,7 +167,7 @@ func GoogleCalendarCallback(c *gin.Context) {
 
 	// Validate state and get user
 	var oauthState models.CalendarOAuthState
-	result := db.DB.Where("state = ? AND platform = ? AND expires_at > ?", state, "google", time.Now()).First(&oauthState)
+	result := db.DB.WithContext(c.Request.Context()).Where("state = ? AND platform = ? AND expires_at > ?", state, "google", time.Now()).First(&oauthState)
 	if result.Error != nil {
 		log.Error().Err(result.Error).Msg("Invalid or expired OAuth state")
 		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?calendar_error=invalid_state")
@@ -175,7 +175,7 @@ func GoogleCalendarCallback(c *gin.Context) {
 	}
 
 	// Delete the used state
-	db.DB.Delete(&oauthState)
+	db.DB.WithContext(c.Request.Context()).Delete(&oauthState)
 
 	// Exchange code for tokens
 	tokenResp, err := exchangeGoogleCode(code)
@@ -197,14 +197,14 @@ func GoogleCalendarCallback(c *gin.Context) {
 	recallClient := recallai.NewClient()
 
 	// Encrypt OAuth credentials
-	encryptedClientSecret, err := utils.EncryptString(CalendarConfig.GoogleClientSecret)
+	encryptedClientSecret, err := services.NewSecretsService().EncryptString(CalendarConfig.GoogleClientSecret)
 	if err != nil {
 		log.Error().Err(err).Msg("Error encrypting client secret")
 		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?calendar_error=encryption_failed")
 		return
 	}
 
-	encryptedRefreshToken, err := utils.EncryptString(tokenResp.RefreshToken)
+	encryptedRefreshToken, err := services.NewSecretsService().EncryptString(tokenResp.RefreshToken)
 	if err != nil {
 		log.Error().Err(err).Msg("Error encrypting refresh token")
 		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?calendar_error=encryption_failed")
@@ -247,7 +247,7 @@ func GoogleCalendarCallback(c *gin.Context) {
 	for _, calendarResp := range allCalendars {
 		// Check if this calendar already exists for this user
 		var existingCalendar models.Calendar
-		result := db.DB.Where("clerk_user_id = ? AND recall_calendar_id = ?", oauthState.ClerkUserID, calendarResp.ID).First(&existingCalendar)
+		result := db.DB.WithContext(c.Request.Context()).Where("clerk_user_id = ? AND recall_calendar_id = ?", oauthState.ClerkUserID, calendarResp.ID).First(&existingCalendar)
 
 		if result.Error == nil {
 			// Calendar already exists, skip
@@ -268,7 +268,7 @@ func GoogleCalendarCallback(c *gin.Context) {
 			Status:            calendarResp.Status,
 		}
 
-		if err := db.DB.Create(&calendar).Error; err != nil {
+		if err := db.DB.WithContext(c.Request.Context()).Create(&calendar).Error; err != nil {
 			log.Error().
 				Err(err).
 				Str("clerk_user_id", oauthState.ClerkUserID).
@@ -304,7 +304,7 @@ func GoogleCalendarCallback(c *gin.Context) {
 	// Trigger initial sync in background for all calendars
 	for _, calendarResp := range allCalendars {
 		var calendar models.Calendar
-		if err := db.DB.Where("recall_calendar_id = ? AND clerk_user_id = ?", calendarResp.ID, oauthState.ClerkUserID).First(&calendar).Error; err == nil {
+		if err := db.DB.WithContext(c.Request.Context()).Where("recall_calendar_id = ? AND clerk_user_id = ?", calendarResp.ID, oauthState.ClerkUserID).First(&calendar).Error; err == nil {
 			go performInitialCalendarSync(calendar)
 		}
 	}
@@ -326,7 +326,7 @@ func MicrosoftCalendarCallback(c *gin.Context) {
 
 	// Validate state and get user
 	var oauthState models.CalendarOAuthState
-	result := db.DB.Where("state = ? AND platform = ? AND expires_at > ?", state, "microsoft", time.Now()).First(&oauthState)
+	result := db.DB.WithContext(c.Request.Context()).Where("state = ? AND platform = ? AND expires_at > ?", state, "microsoft", time.Now()).First(&oauthState)
 	if result.Error != nil {
 		log.Error().Err(result.Error).Msg("Invalid or expired OAuth state")
 		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?calendar_error=invalid_state")
@@ -334,7 +334,7 @@ func MicrosoftCalendarCallback(c *gin.Context) {
 	}
 
 	// Delete the used state
-	db.DB.Delete(&oauthState)
+	db.DB.WithContext(c.Request.Context()).Delete(&oauthState)
 
 	// Exchange code for tokens
 	tokenResp, err := exchangeMicrosoftCode(code)
@@ -356,14 +356,14 @@ func MicrosoftCalendarCallback(c *gin.Context) {
 	recallClient := recallai.NewClient()
 
 	// Encrypt OAuth credentials
-	encryptedClientSecret, err := utils.EncryptString(CalendarConfig.MicrosoftClientSecret)
+	encryptedClientSecret, err := services.NewSecretsService().EncryptString(CalendarConfig.MicrosoftClientSecret)
 	if err != nil {
 		log.Error().Err(err).Msg("Error encrypting client secret")
 		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?calendar_error=encryption_failed")
 		return
 	}
 
-	encryptedRefreshToken, err := utils.EncryptString(tokenResp.RefreshToken)
+	encryptedRefreshToken, err := services.NewSecretsService().EncryptString(tokenResp.RefreshToken)
 	if err != nil {
 		log.Error().Err(err).Msg("Error encrypting refresh token")
 		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?calendar_error=encryption_failed")
@@ -406,7 +406,7 @@ func MicrosoftCalendarCallback(c *gin.Context) {
 	for _, calendarResp := range allCalendars {
 		// Check if this calendar already exists for this user
 		var existingCalendar models.Calendar
-		result := db.DB.Where("clerk_user_id = ? AND recall_calendar_id = ?", oauthState.ClerkUserID, calendarResp.ID).First(&existingCalendar)
+		result := db.DB.WithContext(c.Request.Context()).Where("clerk_user_id = ? AND recall_calendar_id = ?", oauthState.ClerkUserID, calendarResp.ID).First(&existingCalendar)
 
 		if result.Error == nil {
 			// Calendar already exists, skip
@@ -427,7 +427,7 @@ func MicrosoftCalendarCallback(c *gin.Context) {
 			Status:            calendarResp.Status,
 		}
 
-		if err := db.DB.Create(&calendar).Error; err != nil {
+		if err := db.DB.WithContext(c.Request.Context()).Create(&calendar).Error; err != nil {
 			log.Error().
 				Err(err).
 				Str("clerk_user_id", oauthState.ClerkUserID).
@@ -463,7 +463,7 @@ func MicrosoftCalendarCallback(c *gin.Context) {
 	// Trigger initial sync in background for all calendars
 	for _, calendarResp := range allCalendars {
 		var calendar models.Calendar
-		if err := db.DB.Where("recall_calendar_id = ? AND clerk_user_id = ?", calendarResp.ID, oauthState.ClerkUserID).First(&calendar).Error; err == nil {
+		if err := db.DB.WithContext(c.Request.Context()).Where("recall_calendar_id = ? AND clerk_user_id = ?", calendarResp.ID, oauthState.ClerkUserID).First(&calendar).Error; err == nil {
 			go performInitialCalendarSync(calendar)
 		}
 	}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// clerkWebhookTolerance is how old a webhook delivery can be before it's rejected as a replay
+const clerkWebhookTolerance = 5 * time.Minute
+
+// clerkWebhookEvent is the envelope of a Clerk webhook. Data depends on the event type.
+type clerkWebhookEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type clerkMembershipEventData struct {
+	Organization struct {
+		ID string `json:"id"`
+	} `json:"organization"`
+	PublicUserData struct {
+		UserID     string `json:"user_id"`
+		FirstName  string `json:"first_name"`
+		LastName   string `json:"last_name"`
+		Identifier string `json:"identifier"`
+	} `json:"public_user_data"`
+}
+
+// MemberName returns the member's full name, or their email or username when they have none
+func (d clerkMembershipEventData) MemberName() string {
+	if name := strings.TrimSpace(d.PublicUserData.FirstName + " " + d.PublicUserData.LastName); name != "" {
+		return name
+	}
+	return d.PublicUserData.Identifier
+}
+
+type clerkObjectEventData struct {
+	ID string `json:"id"`
+}
+
+// clerkUserEventData is the part of a Clerk user we sync. The timezone is read from public metadata,
+// or from unsafe metadata the frontend can set itself.
+type clerkUserEventData struct {
+	ID             string `json:"id"`
+	PublicMetadata struct {
+		Timezone string `json:"timezone"`
+	} `json:"public_metadata"`
+	UnsafeMetadata struct {
+		Timezone string `json:"timezone"`
+	} `json:"unsafe_metadata"`
+}
+
+// Timezone returns the timezone in the user's metadata, empty if there's none
+func (d clerkUserEventData) Timezone() string {
+	if d.PublicMetadata.Timezone != "" {
+		return d.PublicMetadata.Timezone
+	}
+	return d.UnsafeMetadata.Timezone
+}
+
+// ClerkWebhook handles Clerk webhook events. Membership, organization and user changes invalidate the
+// cached membership and user data so access checks see them straight away, new members get the
+// organization's onboarding notebooks, and user changes sync the user's timezone.
+func ClerkWebhook(c *gin.Context) {
+	rawBody, err := c.GetRawData()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read Clerk webhook body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	if !verifyClerkWebhookSignature(c, rawBody) {
+		log.Error().Msg("Invalid Clerk webhook signature")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	var event clerkWebhookEvent
+	if err := json.Unmarshal(rawBody, &event); err != nil {
+		log.Error().Err(err).Msg("Invalid Clerk webhook payload")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+
+	switch event.Type {
+	case "organizationMembership.created", "organizationMembership.updated", "organizationMembership.deleted":
+		var data clerkMembershipEventData
+		if err := json.Unmarshal(event.Data, &data); err != nil || data.Organization.ID == "" || data.PublicUserData.UserID == "" {
+			log.Error().Err(err).Str("type", event.Type).Msg("Invalid Clerk membership webhook data")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+			return
+		}
+		middleware.GetOrgCache().Invalidate(data.Organization.ID, data.PublicUserData.UserID)
+		log.Info().Str("type", event.Type).Str("org_id", data.Organization.ID).Str("user_id", data.PublicUserData.UserID).Msg("Org membership cache invalidated")
+
+		// New members land in the organization's onboarding notebooks
+		if event.Type == "organizationMembership.created" {
+			if _, err := services.NewOrgOnboardingService().ProvisionMember(c.Request.Context(), data.Organization.ID, data.PublicUserData.UserID, data.MemberName()); err != nil {
+				// Provisioning skips what it already created, so Clerk's retry finishes the job
+				log.Error().Err(err).Str("org_id", data.Organization.ID).Str("user_id", data.PublicUserData.UserID).Msg("Failed to provision onboarding notebooks")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to provision onboarding notebooks"})
+				return
+			}
+		}
+
+	case "organization.deleted":
+		var data clerkObjectEventData
+		if err := json.Unmarshal(event.Data, &data); err == nil && data.ID != "" {
+			middleware.GetOrgCache().InvalidateOrg(data.ID)
+			log.Info().Str("org_id", data.ID).Msg("Org membership cache invalidated for deleted organization")
+		}
+
+	case "user.created", "user.updated", "user.deleted":
+		var data clerkUserEventData
+		if err := json.Unmarshal(event.Data, &data); err == nil && data.ID != "" {
+			middleware.GetUserCache().Invalidate(data.ID)
+			if event.Type == "user.deleted" {
+				middleware.GetOrgCache().InvalidateUser(data.ID)
+			} else if err := services.NewUserPreferencesService().SyncClerkTimezone(c.Request.Context(), data.ID, data.Timezone()); err != nil {
+				// Clerk retries failed deliveries, the caches are already invalidated so a retry is harmless
+				log.Error().Err(err).Str("user_id", data.ID).Msg("Failed to sync user timezone from Clerk")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync user"})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook received"})
+}
+
+// verifyClerkWebhookSignature verifies the Svix signature Clerk sends its webhooks with
+func verifyClerkWebhookSignature(c *gin.Context, payload []byte) bool {
+	secret := os.Getenv("CLERK_WEBHOOK_SIGNING_SECRET")
+	if secret == "" {
+		// If no secret is configured, skip verification (dev mode)
+		log.Warn().Msg("CLERK_WEBHOOK_SIGNING_SECRET not set, skipping signature verification")
+		return true
+	}
+
+	msgID := c.GetHeader("svix-id")
+	timestamp := c.GetHeader("svix-timestamp")
+	signatures := c.GetHeader("svix-signature")
+	if msgID == "" || timestamp == "" || signatures == "" {
+		log.Error().Msg("Missing Clerk webhook signature headers")
+		return false
+	}
+
+	return validClerkWebhookSignature(secret, msgID, timestamp, signatures, payload, time.Now())
+}
+
+// validClerkWebhookSignature checks one of the space separated "v1,<signature>" entries is the
+// HMAC-SHA256 of "<id>.<timestamp>.<payload>" under the base64 secret after its "whsec_" prefix
+func validClerkWebhookSignature(secret, msgID, timestamp, signatures string, payload []byte, now time.Time) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	sentAt := time.Unix(seconds, 0)
+	if now.Sub(sentAt) > clerkWebhookTolerance || sentAt.Sub(now) > clerkWebhookTolerance {
+		return false
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid CLERK_WEBHOOK_SIGNING_SECRET")
+		return false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msgID + "." + timestamp + "."))
+	mac.Write(payload)
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	for _, signature := range strings.Fields(signatures) {
+		version, value, ok := strings.Cut(signature, ",")
+		if ok && version == "v1" && hmac.Equal([]byte(value), []byte(expected)) {
+			return true
+		}
+	}
+	return false
+}
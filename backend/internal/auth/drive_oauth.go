@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/pkg/googledrive"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// driveOAuthPlatform identifies Drive authorizations in the shared OAuth state table
+const driveOAuthPlatform = "google_drive"
+
+// BeginDriveOAuth initiates the OAuth flow for Google Drive.
+// Drive is authorized separately from the calendar so each integration only asks for its own scope.
+func BeginDriveOAuth(c *gin.Context) {
+	claims, ok := clerk.SessionClaimsFromContext(c.Request.Context())
+	if !ok || claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	driveConfig := config.LoadGoogleDriveConfig()
+	if !driveConfig.IsConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Google Drive integration is not configured"})
+		return
+	}
+
+	state, err := generateSecureState()
+	if err != nil {
+		log.Error().Err(err).Msg("Error generating OAuth state")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate state"})
+		return
+	}
+
+	oauthState := models.CalendarOAuthState{
+		ClerkUserID: claims.Subject,
+		State:       state,
+		Platform:    driveOAuthPlatform,
+		ExpiresAt:   time.Now().Add(10 * time.Minute),
+	}
+	if err := db.DB.WithContext(c.Request.Context()).Create(&oauthState).Error; err != nil {
+		log.Error().Err(err).Msg("Error storing OAuth state")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store state"})
+		return
+	}
+
+	go cleanupExpiredStates()
+
+	params := url.Values{}
+	params.Add("client_id", driveConfig.ClientID)
+	params.Add("redirect_uri", driveConfig.RedirectURL)
+	params.Add("response_type", "code")
+	params.Add("scope", googledrive.Scope+" https://www.googleapis.com/auth/userinfo.email")
+	params.Add("access_type", "offline")
+	params.Add("prompt", "consent")
+	params.Add("state", state)
+
+	c.JSON(http.StatusOK, gin.H{"authUrl": "https://accounts.google.com/o/oauth2/v2/auth?" + params.Encode()})
+}
+
+// GoogleDriveCallback handles the OAuth callback from Google for Drive access
+func GoogleDriveCallback(c *gin.Context) {
+	code := c.Query("code")
+	state := c.Query("state")
+	frontendURL := getFrontendURL()
+
+	if code == "" || state == "" {
+		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?drive_error=missing_params")
+		return
+	}
+
+	var oauthState models.CalendarOAuthState
+	if err := db.DB.WithContext(c.Request.Context()).Where("state = ? AND platform = ? AND expires_at > ?", state, driveOAuthPlatform, time.Now()).First(&oauthState).Error; err != nil {
+		log.Error().Err(err).Msg("Invalid or expired Drive OAuth state")
+		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?drive_error=invalid_state")
+		return
+	}
+	db.DB.WithContext(c.Request.Context()).Delete(&oauthState)
+
+	tokenResp, err := exchangeGoogleDriveCode(code)
+	if err != nil {
+		log.Error().Err(err).Msg("Error exchanging Google Drive code")
+		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?drive_error=token_exchange_failed")
+		return
+	}
+
+	userEmail, err := getGoogleUserEmail(tokenResp.AccessToken)
+	if err != nil {
+		log.Error().Err(err).Msg("Error getting Google user email")
+		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?drive_error=email_fetch_failed")
+		return
+	}
+
+	driveService := services.NewGoogleDriveService()
+	if err := driveService.SaveConnection(oauthState.ClerkUserID, userEmail, tokenResp.RefreshToken); err != nil {
+		log.Error().Err(err).Str("user_id", oauthState.ClerkUserID).Msg("Error saving Google Drive connection")
+		c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?drive_error=save_failed")
+		return
+	}
+
+	log.Info().Str("user_id", oauthState.ClerkUserID).Str("email", userEmail).Msg("Google Drive connected")
+	c.Redirect(http.StatusTemporaryRedirect, frontendURL+"/profile?drive_success=google")
+}
+
+// exchangeGoogleDriveCode exchanges an authorization code using the Drive OAuth client
+func exchangeGoogleDriveCode(code string) (*TokenResponse, error) {
+	driveConfig := config.LoadGoogleDriveConfig()
+
+	data := url.Values{}
+	data.Set("code", code)
+	data.Set("client_id", driveConfig.ClientID)
+	data.Set("client_secret", driveConfig.ClientSecret)
+	data.Set("redirect_uri", driveConfig.RedirectURL)
+	data.Set("grant_type", "authorization_code")
+
+	resp, err := http.PostForm("https://oauth2.googleapis.com/token", data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token exchange failed with status: %d", resp.StatusCode)
+	}
+
+	var tokenResp TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, err
+	}
+	return &tokenResp, nil
+}
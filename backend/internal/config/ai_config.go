@@ -0,0 +1,92 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Supported AI providers
+const (
+	AIProviderOpenAI    = "openai"
+	AIProviderAnthropic = "anthropic"
+	AIProviderGoogle    = "google"
+)
+
+// AIConfig holds provider fallback, retry and circuit breaker settings for AI calls
+type AIConfig struct {
+	ProviderChain           []string          // Providers to try in order
+	Models                  map[string]string // Default model per provider
+	MaxRetries              int               // Retries per provider for transient errors
+	InitialBackoff          time.Duration
+	MaxBackoff              time.Duration
+	CircuitFailureThreshold int // Consecutive transient failures before a provider's circuit opens
+	CircuitCooldown         time.Duration
+}
+
+// LoadAIConfig loads AI provider configuration from environment variables
+func LoadAIConfig() *AIConfig {
+	config := &AIConfig{
+		ProviderChain: parseProviderChain(getEnvOrDefault("AI_PROVIDER_CHAIN", "openai,anthropic")),
+		Models: map[string]string{
+			AIProviderOpenAI:    getEnvOrDefault("AI_OPENAI_MODEL", "gpt-4o-mini"),
+			AIProviderAnthropic: getEnvOrDefault("AI_ANTHROPIC_MODEL", "claude-haiku-4-5"),
+			AIProviderGoogle:    getEnvOrDefault("AI_GOOGLE_MODEL", "gemini-2.5-flash"),
+		},
+		MaxRetries:              getEnvIntOrDefault("AI_MAX_RETRIES", 2),
+		InitialBackoff:          time.Duration(getEnvIntOrDefault("AI_RETRY_BACKOFF_MS", 500)) * time.Millisecond,
+		MaxBackoff:              time.Duration(getEnvIntOrDefault("AI_RETRY_MAX_BACKOFF_MS", 8000)) * time.Millisecond,
+		CircuitFailureThreshold: getEnvIntOrDefault("AI_CIRCUIT_FAILURE_THRESHOLD", 5),
+		CircuitCooldown:         time.Duration(getEnvIntOrDefault("AI_CIRCUIT_COOLDOWN_SECONDS", 60)) * time.Second,
+	}
+
+	if len(config.ProviderChain) == 0 {
+		log.Warn().Msg("AI_PROVIDER_CHAIN has no supported providers, defaulting to openai")
+		config.ProviderChain = []string{AIProviderOpenAI}
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.CircuitFailureThreshold < 1 {
+		config.CircuitFailureThreshold = 1
+	}
+
+	log.Info().
+		Strs("provider_chain", config.ProviderChain).
+		Int("max_retries", config.MaxRetries).
+		Dur("initial_backoff", config.InitialBackoff).
+		Int("circuit_failure_threshold", config.CircuitFailureThreshold).
+		Dur("circuit_cooldown", config.CircuitCooldown).
+		Msg("AI provider configuration loaded successfully")
+
+	return config
+}
+
+// IsSupportedAIProvider reports whether the provider name is one the app can call
+func IsSupportedAIProvider(provider string) bool {
+	switch provider {
+	case AIProviderOpenAI, AIProviderAnthropic, AIProviderGoogle:
+		return true
+	}
+	return false
+}
+
+// parseProviderChain parses a comma separated provider list, dropping unknown and duplicate entries
+func parseProviderChain(value string) []string {
+	chain := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, provider := range strings.Split(value, ",") {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if provider == "" || seen[provider] {
+			continue
+		}
+		if !IsSupportedAIProvider(provider) {
+			log.Warn().Str("provider", provider).Msg("Ignoring unsupported provider in AI_PROVIDER_CHAIN")
+			continue
+		}
+		seen[provider] = true
+		chain = append(chain, provider)
+	}
+	return chain
+}
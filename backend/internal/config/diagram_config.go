@@ -0,0 +1,27 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// DiagramConfig holds the Kroki server mermaid and PlantUML code blocks are rendered with, for published
+// pages, PDFs and emails where the browser can't render them
+type DiagramConfig struct {
+	RendererURL string // Base URL of a Kroki server, such as https://kroki.io
+	Timeout     time.Duration
+}
+
+// LoadDiagramConfig loads diagram rendering configuration from environment variables
+func LoadDiagramConfig() *DiagramConfig {
+	return &DiagramConfig{
+		RendererURL: strings.TrimRight(os.Getenv("DIAGRAM_RENDERER_URL"), "/"),
+		Timeout:     time.Duration(getEnvIntOrDefault("DIAGRAM_RENDERER_TIMEOUT_SECONDS", 10)) * time.Second,
+	}
+}
+
+// IsConfigured reports whether diagrams are rendered on the server
+func (c *DiagramConfig) IsConfigured() bool {
+	return c.RendererURL != ""
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// EmailConfig holds the SMTP server notes are emailed through
+type EmailConfig struct {
+	SMTPHost            string
+	SMTPPort            int
+	SMTPUsername        string
+	SMTPPassword        string
+	From                string // Address emails are sent from, replies go to the sender's address
+	FromName            string
+	HourlyLimit         int // Emails a user can send an hour
+	DailyRecipientLimit int // Recipients a user can email a day
+}
+
+// LoadEmailConfig loads email configuration from environment variables
+func LoadEmailConfig() *EmailConfig {
+	return &EmailConfig{
+		SMTPHost:            os.Getenv("SMTP_HOST"),
+		SMTPPort:            getEnvIntOrDefault("SMTP_PORT", 587),
+		SMTPUsername:        os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:        os.Getenv("SMTP_PASSWORD"),
+		From:                os.Getenv("EMAIL_FROM"),
+		FromName:            getEnvOrDefault("EMAIL_FROM_NAME", "Notes"),
+		HourlyLimit:         getEnvIntOrDefault("EMAIL_HOURLY_LIMIT", 20),
+		DailyRecipientLimit: getEnvIntOrDefault("EMAIL_DAILY_RECIPIENT_LIMIT", 100),
+	}
+}
+
+// IsConfigured reports whether emails can be sent
+func (c *EmailConfig) IsConfigured() bool {
+	return c.SMTPHost != "" && c.From != ""
+}
+
+// SMTPAddress returns the host:port of the SMTP server
+func (c *EmailConfig) SMTPAddress() string {
+	return c.SMTPHost + ":" + strconv.Itoa(c.SMTPPort)
+}
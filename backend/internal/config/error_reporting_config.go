@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Release is the version the server was built as, set at build time with
+// -ldflags "-X backend/internal/config.Release=v1.2.3"
+var Release string
+
+// ErrorReportingConfig holds the Sentry settings for reporting panics and server errors. GlitchTip and
+// other Sentry-compatible servers work too, with their DSN.
+type ErrorReportingConfig struct {
+	DSN              string // Reporting is off without it
+	Environment      string
+	Release          string
+	SampleRate       float64 // Share of errors sent
+	TracesSampleRate float64 // Share of requests traced, none by default
+}
+
+// LoadErrorReportingConfig loads error reporting configuration from environment variables
+func LoadErrorReportingConfig() *ErrorReportingConfig {
+	return &ErrorReportingConfig{
+		DSN:              os.Getenv("SENTRY_DSN"),
+		Environment:      getEnvOrDefault("SENTRY_ENVIRONMENT", "production"),
+		Release:          getEnvOrDefault("SENTRY_RELEASE", buildRelease()),
+		SampleRate:       getEnvRateOrDefault("SENTRY_SAMPLE_RATE", 1),
+		TracesSampleRate: getEnvRateOrDefault("SENTRY_TRACES_SAMPLE_RATE", 0),
+	}
+}
+
+// IsConfigured reports whether errors should be reported
+func (c *ErrorReportingConfig) IsConfigured() bool {
+	return c.DSN != ""
+}
+
+// buildRelease returns the release set at build time, or the commit the binary was built from
+func buildRelease() string {
+	if Release != "" {
+		return Release
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var revision string
+	modified := false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// getEnvRateOrDefault returns an environment variable as a rate between 0 and 1, or the default if
+// it's not set or invalid
+func getEnvRateOrDefault(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if rate, err := strconv.ParseFloat(value, 64); err == nil && rate >= 0 && rate <= 1 {
+			return rate
+		}
+		log.Warn().
+			Str("key", key).
+			Str("value", value).
+			Float64("default", defaultValue).
+			Msg("Invalid rate for environment variable, using default")
+	}
+	return defaultValue
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+)
+
+// GoogleDriveConfig holds OAuth configuration for the Google Drive integration
+type GoogleDriveConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// LoadGoogleDriveConfig loads Google Drive configuration from environment variables.
+// Drive uses its own OAuth consent and scope but may share the calendar's OAuth client.
+func LoadGoogleDriveConfig() *GoogleDriveConfig {
+	config := &GoogleDriveConfig{
+		ClientID:     os.Getenv("GOOGLE_DRIVE_CLIENT_ID"),
+		ClientSecret: os.Getenv("GOOGLE_DRIVE_CLIENT_SECRET"),
+		RedirectURL:  getEnvOrDefault("GOOGLE_DRIVE_REDIRECT_URL", "http://localhost:8080/api/drive/google/callback"),
+	}
+	if config.ClientID == "" {
+		config.ClientID = os.Getenv("GOOGLE_CALENDAR_CLIENT_ID")
+		config.ClientSecret = os.Getenv("GOOGLE_CALENDAR_CLIENT_SECRET")
+	}
+	return config
+}
+
+// IsConfigured reports whether an OAuth client is available
+func (c *GoogleDriveConfig) IsConfigured() bool {
+	return c.ClientID != "" && c.ClientSecret != ""
+}
@@ -0,0 +1,21 @@
+package config
+
+import "os"
+
+// MeetingVideoConfig holds where meeting recordings are archived. Recall.ai only keeps recordings for
+// its retention period, and its download URLs expire.
+type MeetingVideoConfig struct {
+	ArchiveDir string // Directory recordings are copied to when meetings end, empty to not archive
+}
+
+// LoadMeetingVideoConfig loads meeting video configuration from environment variables
+func LoadMeetingVideoConfig() *MeetingVideoConfig {
+	return &MeetingVideoConfig{
+		ArchiveDir: os.Getenv("MEETING_VIDEO_ARCHIVE_DIR"),
+	}
+}
+
+// IsArchiveEnabled reports whether meeting recordings are archived
+func (c *MeetingVideoConfig) IsArchiveEnabled() bool {
+	return c.ArchiveDir != ""
+}
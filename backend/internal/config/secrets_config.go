@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Secret key providers
+const (
+	SecretsProviderLocal  = "local"
+	SecretsProviderAWSKMS = "aws-kms"
+	SecretsProviderVault  = "vault"
+)
+
+// SecretsConfig holds the keys stored credentials, OAuth secrets and access tokens are encrypted with.
+// Keys are versioned so they can be rotated: new values are encrypted with the current version and
+// older versions are kept to decrypt what was stored before.
+type SecretsConfig struct {
+	Provider   string         // local, aws-kms or vault
+	KeyVersion int            // Version new values are encrypted with
+	Keys       map[int]string // Key material by version: raw keys for local, data keys wrapped by the KMS otherwise
+	LegacyKey  string         // Key values encrypted before keys were versioned were encrypted with
+
+	AWSRegion          string
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+	AWSKMSEndpoint     string // Overrides the regional endpoint, for VPC endpoints
+
+	VaultAddr         string
+	VaultToken        string
+	VaultNamespace    string
+	VaultTransitMount string
+	VaultTransitKey   string
+}
+
+// LoadSecretsConfig loads secrets configuration from environment variables. Key versions come from
+// SECRETS_KEY_V1, SECRETS_KEY_V2 and so on; with the local provider, version 1 defaults to
+// AI_CREDENTIALS_ENC_KEY.
+func LoadSecretsConfig() *SecretsConfig {
+	config := &SecretsConfig{
+		Provider:           strings.ToLower(getEnvOrDefault("SECRETS_KEY_PROVIDER", SecretsProviderLocal)),
+		KeyVersion:         getEnvIntOrDefault("SECRETS_KEY_VERSION", 1),
+		Keys:               map[int]string{},
+		LegacyKey:          os.Getenv("AI_CREDENTIALS_ENC_KEY"),
+		AWSRegion:          getEnvOrDefault("AWS_REGION", os.Getenv("AWS_DEFAULT_REGION")),
+		AWSAccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		AWSSecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		AWSSessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		AWSKMSEndpoint:     os.Getenv("AWS_KMS_ENDPOINT"),
+		VaultAddr:          strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+		VaultToken:         os.Getenv("VAULT_TOKEN"),
+		VaultNamespace:     os.Getenv("VAULT_NAMESPACE"),
+		VaultTransitMount:  getEnvOrDefault("VAULT_TRANSIT_MOUNT", "transit"),
+		VaultTransitKey:    os.Getenv("VAULT_TRANSIT_KEY"),
+	}
+
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		suffix, ok := strings.CutPrefix(name, "SECRETS_KEY_V")
+		if !ok || value == "" {
+			continue
+		}
+		if version, err := strconv.Atoi(suffix); err == nil && version > 0 {
+			config.Keys[version] = value
+		}
+	}
+	if _, ok := config.Keys[1]; !ok && config.Provider == SecretsProviderLocal {
+		config.Keys[1] = config.LegacyKey
+	}
+	return config
+}
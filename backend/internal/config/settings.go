@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/joho/godotenv"
+)
+
+// SetupCompletedKey is the setting first-run setup writes once it has run
+const SetupCompletedKey = "SETUP_COMPLETED"
+
+// SettingsFilePath returns the file runtime settings are persisted in, set by CONFIG_FILE. Point it at
+// a mounted volume in Docker so settings survive the container.
+func SettingsFilePath() string {
+	return getEnvOrDefault("CONFIG_FILE", "settings.env")
+}
+
+// LoadSettings loads the persisted settings into the environment. Variables already set in the
+// environment or .env win, so a deployment can always override what setup saved.
+func LoadSettings() error {
+	err := godotenv.Load(SettingsFilePath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load settings file: %w", err)
+	}
+	return nil
+}
+
+// ReadSettings returns the persisted settings, without the environment
+func ReadSettings() (map[string]string, error) {
+	settings, err := godotenv.Read(SettingsFilePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveSettings merges values into the persisted settings and sets them in the environment so they
+// apply without a restart wherever they are read per use. The file is only readable by its owner
+// since it holds secrets.
+func SaveSettings(values map[string]string) error {
+	settings, err := ReadSettings()
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		settings[key] = value
+	}
+
+	content, err := godotenv.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	// Write a temporary file and rename it over the old one so a crash never leaves half a file
+	path := SettingsFilePath()
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("failed to create settings directory: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to write settings file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace settings file: %w", err)
+	}
+
+	for key, value := range values {
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to apply setting %s: %w", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// TranscriptionConfig holds the self-hosted Whisper endpoint organizations can transcribe meetings with
+// instead of Recall.ai
+type TranscriptionConfig struct {
+	WhisperURL     string // OpenAI-compatible /v1/audio/transcriptions endpoint
+	WhisperAPIKey  string
+	WhisperModel   string
+	WhisperTimeout time.Duration
+}
+
+// LoadTranscriptionConfig loads transcription configuration from environment variables
+func LoadTranscriptionConfig() *TranscriptionConfig {
+	return &TranscriptionConfig{
+		WhisperURL:     os.Getenv("WHISPER_API_URL"),
+		WhisperAPIKey:  os.Getenv("WHISPER_API_KEY"),
+		WhisperModel:   getEnvOrDefault("WHISPER_MODEL", "whisper-1"),
+		WhisperTimeout: time.Duration(getEnvIntOrDefault("WHISPER_TIMEOUT_SECONDS", 600)) * time.Second,
+	}
+}
+
+// IsWhisperConfigured reports whether a Whisper endpoint is available
+func (c *TranscriptionConfig) IsWhisperConfigured() bool {
+	return c.WhisperURL != ""
+}
@@ -0,0 +1,454 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateAutomationKeyRequest represents the request body for creating an automation API key
+type CreateAutomationKeyRequest struct {
+	Name string `json:"name"`
+}
+
+// automationOwner resolves whose API keys the request manages.
+// Routes under /organizations/:orgId manage the organization's keys; the rest manage the user's own.
+func automationOwner(c *gin.Context) (services.AutomationScope, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return services.AutomationScope{}, false
+	}
+
+	scope := services.AutomationScope{ClerkUserID: clerkUserID}
+	if orgID := c.Param("orgId"); orgID != "" {
+		scope.OrganizationID = &orgID
+	}
+	return scope, true
+}
+
+// automationScope returns the workspace of the API key that authenticated the request.
+// Organization keys stop working once their creator leaves the organization.
+func automationScope(c *gin.Context) (services.AutomationScope, bool) {
+	key, exists := middleware.GetAutomationKey(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+		return services.AutomationScope{}, false
+	}
+
+	if key.OrganizationID != nil && *key.OrganizationID != "" {
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), *key.OrganizationID, key.ClerkUserID)
+		if err != nil || !isMember {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key is no longer valid for this organization"})
+			return services.AutomationScope{}, false
+		}
+	}
+	return services.ScopeForAutomationKey(key), true
+}
+
+// ListAutomationKeys lists automation API keys
+// GET /settings/automation-keys
+// GET /organizations/:orgId/automation-keys
+func ListAutomationKeys(c *gin.Context) {
+	scope, ok := automationOwner(c)
+	if !ok {
+		return
+	}
+
+	keys, err := services.NewAutomationService().ListAPIKeys(scope)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", scope.ClerkUserID).Msg("Failed to fetch automation API keys")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// CreateAutomationKey creates an automation API key. The key is only shown in this response.
+// POST /settings/automation-keys
+// POST /organizations/:orgId/automation-keys
+func CreateAutomationKey(c *gin.Context) {
+	scope, ok := automationOwner(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAutomationKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	key, err := services.NewAutomationService().CreateAPIKey(scope, req.Name)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to create API key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// RevokeAutomationKey deletes an automation API key and its webhook subscriptions
+// DELETE /settings/automation-keys/:keyId
+// DELETE /organizations/:orgId/automation-keys/:keyId
+func RevokeAutomationKey(c *gin.Context) {
+	scope, ok := automationOwner(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewAutomationService().RevokeAPIKey(scope, c.Param("keyId")); err != nil {
+		sendAutomationError(c, err, "Failed to revoke API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
+
+// GetAutomationMe identifies the API key's workspace, used by platforms to test the connection
+// GET /api/automation/me
+func GetAutomationMe(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+	key, _ := middleware.GetAutomationKey(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":             key.ID,
+		"name":           key.Name,
+		"userId":         scope.ClerkUserID,
+		"organizationId": scope.OrganizationID,
+	})
+}
+
+// PollAutomationTrigger returns recent events for a trigger, newest first
+// GET /api/automation/triggers/:trigger?tag=&limit=
+func PollAutomationTrigger(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	events, err := services.NewAutomationService().Poll(scope, c.Param("trigger"), services.AutomationPollOptions{
+		Tag:   c.Query("tag"),
+		Limit: limit,
+	})
+	if err != nil {
+		sendAutomationError(c, err, "Failed to fetch trigger events")
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// SubscribeAutomationHook subscribes a URL to a trigger (REST hooks)
+// POST /api/automation/hooks
+func SubscribeAutomationHook(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	var req services.AutomationSubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	webhook, err := services.NewAutomationService().Subscribe(scope, req)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to subscribe webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// UnsubscribeAutomationHook removes a REST hook subscription
+// DELETE /api/automation/hooks/:hookId
+func UnsubscribeAutomationHook(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewAutomationService().Unsubscribe(scope, c.Param("hookId")); err != nil {
+		sendAutomationError(c, err, "Failed to unsubscribe webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook unsubscribed successfully"})
+}
+
+// ListAutomationChapters lists the chapters notes can be created in
+// GET /api/automation/chapters
+func ListAutomationChapters(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	chapters, err := services.NewAutomationService().ListChapters(scope)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to fetch chapters")
+		return
+	}
+
+	c.JSON(http.StatusOK, chapters)
+}
+
+// ListAutomationTaskBoards lists the boards tasks can be created on
+// GET /api/automation/boards
+func ListAutomationTaskBoards(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	boards, err := services.NewAutomationService().ListTaskBoards(scope)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to fetch task boards")
+		return
+	}
+
+	c.JSON(http.StatusOK, boards)
+}
+
+// AutomationCreateNote creates a note from Markdown
+// POST /api/automation/actions/notes
+func AutomationCreateNote(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	var req services.AutomationCreateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	note, err := services.NewAutomationService().CreateNote(c.Request.Context(), scope, req)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to create note")
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// AutomationAppendToNote appends Markdown to the end of a note
+// POST /api/automation/actions/notes/:noteId/append
+func AutomationAppendToNote(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	var req services.AutomationAppendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	note, err := services.NewAutomationService().AppendToNote(c.Request.Context(), scope, c.Param("noteId"), req)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to append to note")
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+// AutomationUpdateNote renames a note or replaces its content with Markdown
+// PUT /api/automation/notes/:noteId
+func AutomationUpdateNote(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	var req services.AutomationUpdateNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	note, err := services.NewAutomationService().UpdateNote(c.Request.Context(), scope, c.Param("noteId"), req)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to update note")
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+// ListAutomationNoteConflicts lists the API key's refused updates waiting to be resolved
+// GET /api/automation/conflicts
+func ListAutomationNoteConflicts(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	conflicts, err := services.NewAutomationService().ListNoteConflicts(c.Request.Context(), scope)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to list note conflicts")
+		return
+	}
+
+	c.JSON(http.StatusOK, conflicts)
+}
+
+// GetAutomationNoteConflict returns both versions of a conflicting update and a merge of them. Upload
+// the resolution to the note with the conflict's serverUpdatedAt.
+// GET /api/automation/conflicts/:conflictId
+func GetAutomationNoteConflict(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	conflict, err := services.NewAutomationService().GetNoteConflict(c.Request.Context(), scope, c.Param("conflictId"))
+	if err != nil {
+		sendAutomationError(c, err, "Failed to get note conflict")
+		return
+	}
+
+	c.JSON(http.StatusOK, conflict)
+}
+
+// DismissAutomationNoteConflict drops a conflict, keeping the server's version of the note
+// DELETE /api/automation/conflicts/:conflictId
+func DismissAutomationNoteConflict(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewAutomationService().DismissNoteConflict(c.Request.Context(), scope, c.Param("conflictId")); err != nil {
+		sendAutomationError(c, err, "Failed to dismiss note conflict")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conflict dismissed"})
+}
+
+// SearchAutomationNotes finds notes whose name or content contains the query
+// GET /api/automation/notes?q=&limit=
+func SearchAutomationNotes(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	notes, err := services.NewAutomationService().SearchNotes(c.Request.Context(), scope, c.Query("q"), limit)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to search notes")
+		return
+	}
+
+	c.JSON(http.StatusOK, notes)
+}
+
+// ExportAutomationNotes returns every note of the workspace as Markdown, with its chapters
+// GET /api/automation/export
+func ExportAutomationNotes(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	export, err := services.NewAutomationService().ExportNotes(c.Request.Context(), scope)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to export notes")
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// GetAutomationNoteChanges returns the notes updated since a sync client's cursor, all notes without one
+// GET /api/automation/changes?since=
+func GetAutomationNoteChanges(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	if value := c.Query("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+
+	changes, err := services.NewAutomationService().NoteChanges(c.Request.Context(), scope, since)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to fetch changes")
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// AutomationCreateTask creates a task on a board
+// POST /api/automation/actions/tasks
+func AutomationCreateTask(c *gin.Context) {
+	scope, ok := automationScope(c)
+	if !ok {
+		return
+	}
+
+	var req services.AutomationCreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	task, err := services.NewAutomationService().CreateTask(c.Request.Context(), scope, req)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to create task")
+		return
+	}
+
+	c.JSON(http.StatusCreated, task)
+}
+
+// sendAutomationError maps automation service errors to responses
+func sendAutomationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAutomationRequest), errors.Is(err, services.ErrUnknownAutomationTrigger):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAutomationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, services.ErrAutomationConflict):
+		var conflictErr *services.AutomationConflictError
+		if errors.As(err, &conflictErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":         "Note changed since it was last read",
+				"conflictId":    conflictErr.Conflict.ID,
+				"conflictCount": conflictErr.Conflict.ConflictCount,
+			})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Note changed since it was last read"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
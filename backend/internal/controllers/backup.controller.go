@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateBackup downloads a backup of the whole workspace: every table and the archived meeting videos,
+// with a manifest of checksums. Encrypted credentials in it only decrypt on a server with the same
+// secrets key.
+// POST /admin/backup
+func CreateBackup(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Written to a temporary file first so a failed backup is an error response, not a truncated download
+	file, err := os.CreateTemp("", "notes-backup-*.zip")
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to create backup")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create backup"})
+		return
+	}
+	defer os.Remove(file.Name())
+
+	_, err = services.NewBackupService().CreateBackup(c.Request.Context(), file, clerkUserID)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		sendBackupError(c, err, "Failed to create backup")
+		return
+	}
+
+	c.Header("Content-Type", services.BackupContentType)
+	c.FileAttachment(file.Name(), fmt.Sprintf("notes-backup-%s.zip", time.Now().UTC().Format("20060102-150405")))
+}
+
+// RestoreBackup loads a backup uploaded as the backup form file. The database must be empty unless
+// replace is set, so it's meant for a new server before anyone uses it.
+// POST /admin/restore
+func RestoreBackup(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("backup")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A backup file is required"})
+		return
+	}
+	replace, _ := strconv.ParseBool(c.PostForm("replace"))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read backup file"})
+		return
+	}
+	defer file.Close()
+
+	report, err := services.NewBackupService().RestoreBackup(c.Request.Context(), file, fileHeader.Size, replace)
+	if err != nil {
+		sendBackupError(c, err, "Failed to restore backup")
+		return
+	}
+
+	log.Info().Str("user_id", clerkUserID).Int("rows", report.Rows).Msg("Backup restored")
+	c.JSON(http.StatusOK, report)
+}
+
+// sendBackupError maps backup service errors to responses
+func sendBackupError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidBackup), errors.Is(err, services.ErrUnsupportedBackupVersion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRestoreTargetNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
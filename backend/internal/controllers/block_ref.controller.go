@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// SetTaskBlockRefRequest represents the request body for pointing a task at a note block
+type SetTaskBlockRefRequest struct {
+	BlockRef string `json:"blockRef"` // "<noteId>#<blockId>", empty to clear
+}
+
+// ResolveBlockRef returns the note and current excerpt of a block reference
+// GET /blocks/resolve?ref=<noteId>#<blockId>
+func ResolveBlockRef(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	ref := c.Query("ref")
+	noteID, _, err := services.ParseBlockRef(ref)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil || !hasAccess {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+
+	block, err := services.NewBlockRefService().Resolve(ref)
+	if err != nil {
+		sendBlockRefError(c, err, "Failed to resolve block reference")
+		return
+	}
+
+	c.JSON(http.StatusOK, block)
+}
+
+// GetNoteBlockReferences lists the tasks and note links that point at blocks of a note
+// GET /note/:id/block-refs
+func GetNoteBlockReferences(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to access note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	references, err := services.NewBlockRefService().ListReferences(noteID)
+	if err != nil {
+		sendBlockRefError(c, err, "Failed to fetch block references")
+		return
+	}
+
+	c.JSON(http.StatusOK, references)
+}
+
+// SetTaskBlockRef points a task at a note block, or clears it
+// PUT /tasks/:taskId/block-ref
+func SetTaskBlockRef(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	taskID := c.Param("taskId")
+	hasAccess, err := CheckTaskAccess(c.Request.Context(), db.DB, taskID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("task_id", taskID).Str("user_id", clerkUserID).Msg("User not authorized to update task")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req SetTaskBlockRefRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	blockRef := strings.TrimSpace(req.BlockRef)
+	if blockRef != "" && !validateTaskBlockRef(c, blockRef, clerkUserID) {
+		return
+	}
+
+	if err := db.DB.WithContext(c.Request.Context()).Model(&models.Task{}).Where("id = ?", taskID).Update("block_ref", blockRef).Error; err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("Failed to update task block reference")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": taskID, "blockRef": blockRef})
+}
+
+// validateTaskBlockRef checks a task's block reference points at an existing block of a note the user can access
+func validateTaskBlockRef(c *gin.Context, blockRef, clerkUserID string) bool {
+	noteID, _, err := services.ParseBlockRef(blockRef)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil || !hasAccess {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Referenced note not found"})
+		return false
+	}
+	if _, err := services.NewBlockRefService().Resolve(blockRef); err != nil {
+		sendBlockRefError(c, err, "Failed to resolve block reference")
+		return false
+	}
+	return true
+}
+
+// sendBlockRefError maps block reference service errors to responses
+func sendBlockRefError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidBlockRef):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBlockNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrgBotSettings returns how the meeting bot presents itself in the organization members' meetings
+// GET /organizations/:orgId/bot-settings
+func GetOrgBotSettings(c *gin.Context) {
+	settings, err := services.NewBotSettingsService().GetSettings(c.Request.Context(), c.Param("orgId"))
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch bot settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bot settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateOrgBotSettings sets the meeting bot's name, the message it sends when it joins, and whether it
+// announces the recording and leaves when the host objects. It applies to bots created or scheduled from
+// then on.
+// PUT /organizations/:orgId/bot-settings
+func UpdateOrgBotSettings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req services.BotSettingsInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := services.NewBotSettingsService().SaveSettings(c.Request.Context(), c.Param("orgId"), req, clerkUserID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBotSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to save bot settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save bot settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// PreviewOrgBotSettings returns what the bot says and does when it joins a member's meeting, with the
+// bot_config sent to Recall.ai
+// GET /organizations/:orgId/bot-settings/preview
+func PreviewOrgBotSettings(c *gin.Context) {
+	preview, err := services.NewBotSettingsService().Preview(c.Request.Context(), c.Param("orgId"))
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to preview bot settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to preview bot settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
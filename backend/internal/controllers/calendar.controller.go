@@ -4,11 +4,13 @@ import (
 	"backend/db"
 	"backend/internal/middleware"
 	"backend/internal/models"
+	"backend/internal/services"
 	"backend/pkg/recallai"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -27,7 +29,7 @@ func GetUserCalendars(c *gin.Context) {
 	}
 
 	var calendars []models.Calendar
-	if err := db.DB.Where("clerk_user_id = ?", clerkUserID).Find(&calendars).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("clerk_user_id = ?", clerkUserID).Find(&calendars).Error; err != nil {
 		log.Error().Err(err).Msg("Error fetching user calendars")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendars"})
 		return
@@ -46,7 +48,7 @@ func SyncMissingCalendars(c *gin.Context) {
 
 	// Get user's existing calendars
 	var existingCalendars []models.Calendar
-	if err := db.DB.Where("clerk_user_id = ?", clerkUserID).Find(&existingCalendars).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("clerk_user_id = ?", clerkUserID).Find(&existingCalendars).Error; err != nil {
 		log.Error().Err(err).Msg("Error fetching existing calendars")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch existing calendars"})
 		return
@@ -89,7 +91,7 @@ func SyncMissingCalendars(c *gin.Context) {
 		for _, recallCal := range allCalendars {
 			// Check if calendar already exists
 			var existingCal models.Calendar
-			result := db.DB.Where("recall_calendar_id = ? AND clerk_user_id = ?", recallCal.ID, clerkUserID).First(&existingCal)
+			result := db.DB.WithContext(c.Request.Context()).Where("recall_calendar_id = ? AND clerk_user_id = ?", recallCal.ID, clerkUserID).First(&existingCal)
 
 			if result.Error == nil {
 				// Calendar already exists
@@ -109,7 +111,7 @@ func SyncMissingCalendars(c *gin.Context) {
 				Status:            recallCal.Status,
 			}
 
-			if err := db.DB.Create(&newCalendar).Error; err != nil {
+			if err := db.DB.WithContext(c.Request.Context()).Create(&newCalendar).Error; err != nil {
 				log.Error().
 					Err(err).
 					Str("recall_calendar_id", recallCal.ID).
@@ -152,7 +154,7 @@ func DisconnectCalendar(c *gin.Context) {
 
 	// Find calendar
 	var calendar models.Calendar
-	if err := db.DB.Where("id = ? AND clerk_user_id = ?", calendarID, clerkUserID).First(&calendar).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ? AND clerk_user_id = ?", calendarID, clerkUserID).First(&calendar).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
 		return
 	}
@@ -165,7 +167,7 @@ func DisconnectCalendar(c *gin.Context) {
 	}
 
 	// Delete from database (cascade will delete events)
-	if err := db.DB.Delete(&calendar).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Delete(&calendar).Error; err != nil {
 		log.Error().Err(err).Msg("Error deleting calendar from database")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar"})
 		return
@@ -179,7 +181,8 @@ func DisconnectCalendar(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Calendar disconnected successfully"})
 }
 
-// GetCalendarEvents returns all events for a specific calendar with pagination
+// GetCalendarEvents returns all events for a specific calendar with pagination. ?date=YYYY-MM-DD lists
+// the events starting that day in the user's timezone.
 func GetCalendarEvents(c *gin.Context) {
 	clerkUserID, exists := middleware.GetClerkUserID(c)
 	if !exists {
@@ -210,13 +213,13 @@ func GetCalendarEvents(c *gin.Context) {
 
 	// Verify calendar belongs to user
 	var calendar models.Calendar
-	if err := db.DB.Where("id = ? AND clerk_user_id = ?", calendarID, clerkUserID).First(&calendar).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ? AND clerk_user_id = ?", calendarID, clerkUserID).First(&calendar).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
 		return
 	}
 
 	// Build query for events
-	query := db.DB.Where("calendar_id = ? AND is_deleted = ?", calendarID, false)
+	query := db.DB.WithContext(c.Request.Context()).Where("calendar_id = ? AND is_deleted = ?", calendarID, false)
 
 	// Optional: filter upcoming events only
 	currentTime := time.Now()
@@ -232,6 +235,16 @@ func GetCalendarEvents(c *gin.Context) {
 			Msg("Filtering for upcoming and ongoing events")
 	}
 
+	location := services.UserLocation(c.Request.Context(), db.DB, clerkUserID)
+	if date := c.Query("date"); date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, location)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("start_time >= ? AND start_time < ?", day, day.AddDate(0, 0, 1))
+	}
+
 	// Get total count
 	var total int64
 	query.Model(&models.CalendarEvent{}).Count(&total)
@@ -239,7 +252,7 @@ func GetCalendarEvents(c *gin.Context) {
 	// Get events with pagination
 	var events []models.CalendarEvent
 	offset := (page - 1) * pageSize
-	if err := query.Order("start_time ASC").Limit(pageSize).Offset(offset).Find(&events).Error; err != nil {
+	if err := query.Preload("AgendaNote", services.SelectAgendaNote).Order("start_time ASC").Limit(pageSize).Offset(offset).Find(&events).Error; err != nil {
 		log.Error().Err(err).Msg("Error fetching calendar events")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
 		return
@@ -265,6 +278,7 @@ func GetCalendarEvents(c *gin.Context) {
 		"totalPages": totalPages,
 		"hasNext":    page < totalPages,
 		"hasPrev":    page > 1,
+		"timezone":   services.TimezoneName(location),
 	}
 
 	c.JSON(http.StatusOK, paginatedResponse)
@@ -282,7 +296,7 @@ func SyncCalendarEvents(c *gin.Context) {
 
 	// Verify calendar belongs to user
 	var calendar models.Calendar
-	if err := db.DB.Where("id = ? AND clerk_user_id = ?", calendarID, clerkUserID).First(&calendar).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ? AND clerk_user_id = ?", calendarID, clerkUserID).First(&calendar).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
 		return
 	}
@@ -321,7 +335,7 @@ func SyncCalendarEvents(c *gin.Context) {
 		}
 
 		// Upsert event
-		if err := db.DB.Where(models.CalendarEvent{RecallEventID: event.ID}).
+		if err := db.DB.WithContext(c.Request.Context()).Where(models.CalendarEvent{RecallEventID: event.ID}).
 			Assign(calendarEvent).
 			FirstOrCreate(&calendarEvent).Error; err != nil {
 			log.Error().Err(err).Str("event_id", event.ID).Msg("Error upserting calendar event")
@@ -332,7 +346,7 @@ func SyncCalendarEvents(c *gin.Context) {
 	}
 
 	// Update last synced timestamp
-	db.DB.Model(&calendar).Update("last_synced_at", time.Now())
+	db.DB.WithContext(c.Request.Context()).Model(&calendar).Update("last_synced_at", time.Now())
 
 	log.Info().
 		Str("clerk_user_id", clerkUserID).
@@ -357,68 +371,22 @@ func ScheduleBotForEvent(c *gin.Context) {
 
 	eventID := c.Param("eventId")
 
-	// Find event and verify ownership
-	var event models.CalendarEvent
-	if err := db.DB.Preload("Calendar").Where("id = ?", eventID).First(&event).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
-		return
-	}
-
-	if event.Calendar.ClerkUserID != clerkUserID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	if event.BotScheduled {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bot already scheduled for this event"})
-		return
-	}
-
-	// Schedule bot via Recall.ai
-	recallClient := recallai.NewClient()
-
-	// Use ICalUID as deduplication key (ensures one bot per recurring event series)
-	deduplicationKey := event.ICalUID
-	if deduplicationKey == "" {
-		deduplicationKey = event.RecallEventID
-	}
-
-	botConfig := map[string]interface{}{
-		"recording_config": map[string]interface{}{
-			"transcript": map[string]interface{}{
-				"provider": map[string]interface{}{
-					"recallai_streaming": map[string]interface{}{
-						"mode": "prioritize_accuracy",
-					},
-				},
-			},
-			"video_mixed_layout": "gallery_view_v2",
-			"video_separate_mp4": map[string]interface{}{},
-		},
-	}
-
-	updatedEvent, err := recallClient.ScheduleBotForEvent(event.RecallEventID, deduplicationKey, botConfig)
+	event, err := services.NewCalendarSchedulerService().ScheduleBotForEvent(clerkUserID, eventID)
 	if err != nil {
-		log.Error().Err(err).Str("event_id", eventID).Msg("Error scheduling bot for event")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule bot"})
+		switch {
+		case errors.Is(err, services.ErrCalendarEventNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		case errors.Is(err, services.ErrBotAlreadyScheduled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bot already scheduled for this event"})
+		case errors.Is(err, services.ErrWhisperNotConfigured):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Your organization transcribes meetings with Whisper, which isn't available on this server"})
+		default:
+			log.Error().Err(err).Str("event_id", eventID).Msg("Error scheduling bot for event")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule bot"})
+		}
 		return
 	}
 
-	// Update event in database
-	event.BotScheduled = true
-	if len(updatedEvent.Bots) > 0 {
-		event.BotID = &updatedEvent.Bots[0].BotID
-	}
-
-	if err := db.DB.Save(&event).Error; err != nil {
-		log.Error().Err(err).Msg("Error updating event with bot info")
-	}
-
-	log.Info().
-		Str("clerk_user_id", clerkUserID).
-		Str("event_id", eventID).
-		Msg("Bot scheduled for calendar event")
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Bot scheduled successfully",
 		"event":   event,
@@ -437,7 +405,7 @@ func CancelBotForEvent(c *gin.Context) {
 
 	// Find event and verify ownership
 	var event models.CalendarEvent
-	if err := db.DB.Preload("Calendar").Where("id = ?", eventID).First(&event).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Preload("Calendar").Where("id = ?", eventID).First(&event).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
@@ -469,7 +437,7 @@ func CancelBotForEvent(c *gin.Context) {
 	event.BotScheduled = false
 	event.BotID = nil
 
-	if err := db.DB.Save(&event).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Save(&event).Error; err != nil {
 		log.Error().Err(err).Msg("Error updating event")
 	}
 
@@ -483,6 +451,76 @@ func CancelBotForEvent(c *gin.Context) {
 	})
 }
 
+// LinkAgendaNoteRequest is the body for linking an agenda note to a calendar event
+type LinkAgendaNoteRequest struct {
+	NoteID string `json:"noteId" binding:"required"`
+}
+
+// LinkNoteToEvent attaches an agenda note to an upcoming calendar event. The meeting's notes are added to
+// it once the recording bot's note is generated.
+// POST /api/calendar-events/:eventId/link-note
+func LinkNoteToEvent(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req LinkAgendaNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "noteId is required"})
+		return
+	}
+
+	eventID := c.Param("eventId")
+	event, err := services.NewCalendarSchedulerService().LinkAgendaNote(c.Request.Context(), clerkUserID, eventID, req.NoteID)
+	if err != nil {
+		sendAgendaNoteError(c, err, "Failed to link note")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Note linked successfully",
+		"event":   event,
+	})
+}
+
+// UnlinkNoteFromEvent removes the agenda note from a calendar event
+// DELETE /api/calendar-events/:eventId/link-note
+func UnlinkNoteFromEvent(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	event, err := services.NewCalendarSchedulerService().UnlinkAgendaNote(c.Request.Context(), clerkUserID, c.Param("eventId"))
+	if err != nil {
+		sendAgendaNoteError(c, err, "Failed to unlink note")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Note unlinked successfully",
+		"event":   event,
+	})
+}
+
+// sendAgendaNoteError maps agenda note link errors to HTTP responses
+func sendAgendaNoteError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrCalendarEventNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+	case errors.Is(err, services.ErrCalendarEventEnded):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event has already ended"})
+	case errors.Is(err, services.ErrAgendaNoteNotEditable):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to edit this note"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
 // verifyWebhookSignature verifies the Recall.ai webhook signature
 func verifyWebhookSignature(c *gin.Context, payload []byte) bool {
 	webhookSecret := os.Getenv("RECALL_CALENDAR_WEBHOOK_SECRET")
@@ -545,7 +583,7 @@ func HandleCalendarWebhook(c *gin.Context) {
 
 	// Find calendar in database
 	var calendar models.Calendar
-	if err := db.DB.Where("recall_calendar_id = ?", webhook.Data.CalendarID).First(&calendar).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("recall_calendar_id = ?", webhook.Data.CalendarID).First(&calendar).Error; err != nil {
 		log.Error().Err(err).Str("recall_calendar_id", webhook.Data.CalendarID).Msg("Calendar not found for webhook")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
 		return
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCaptureBodySize leaves room for JSON encoding around the longest snippet
+const maxCaptureBodySize = 64 << 10
+
+// Capture appends a snippet with the time it was captured to a note, for iOS Shortcuts, Alfred, Raycast
+// and similar tools. Without noteId or chapterId it goes to the Inbox, which is created when needed.
+// Authenticates with an automation API key, or the session with ?organizationId= for an organization.
+// POST /api/capture
+// Body: {"text": "...", "noteId": "...", "chapterId": "..."}, or the text as text/plain with the target
+// in ?noteId= or ?chapterId=
+func Capture(c *gin.Context) {
+	scope, ok := captureScope(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCaptureBodySize)
+	var req services.CaptureRequest
+	if strings.HasPrefix(c.ContentType(), "text/") {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Snippet is too large"})
+			return
+		}
+		req = services.CaptureRequest{Text: string(body), NoteID: c.Query("noteId"), ChapterID: c.Query("chapterId")}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := services.NewAutomationService().Capture(c.Request.Context(), scope, req)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to capture snippet")
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// captureScope returns the API key's workspace, or for sessions the user's personal workspace or the
+// organization in ?organizationId when they're a member
+func captureScope(c *gin.Context) (services.AutomationScope, bool) {
+	if _, ok := middleware.GetAutomationKey(c); ok {
+		return automationScope(c)
+	}
+
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return services.AutomationScope{}, false
+	}
+
+	scope := services.AutomationScope{ClerkUserID: clerkUserID}
+	if orgID := c.Query("organizationId"); orgID != "" {
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
+		if err != nil || !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+			return services.AutomationScope{}, false
+		}
+		scope.OrganizationID = &orgID
+	}
+	return scope, true
+}
@@ -4,6 +4,7 @@ import (
 	"backend/db"
 	"backend/internal/middleware"
 	"backend/internal/models"
+	"backend/internal/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -14,7 +15,7 @@ import (
 func userCanAccessNotebook(c *gin.Context, notebook *models.Notebook, clerkUserID string) bool {
 	if notebook.OrganizationID != nil && *notebook.OrganizationID != "" {
 		// Organization notebook - verify membership
-		_, isMember, err := middleware.GetOrgMemberRole(c.Request.Context(), *notebook.OrganizationID, clerkUserID)
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), *notebook.OrganizationID, clerkUserID)
 		return err == nil && isMember
 	}
 	// Personal notebook - verify ownership
@@ -32,7 +33,7 @@ func CreateChapter(c *gin.Context) {
 	var chapter models.Chapter
 
 	if err := c.ShouldBindJSON(&chapter); err != nil {
-		log.Print("Missing data to create a chapter: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Missing data to create a chapter")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -40,7 +41,7 @@ func CreateChapter(c *gin.Context) {
 	// Check authorization efficiently
 	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, chapter.NotebookID, clerkUserID)
 	if err != nil {
-		log.Print("Notebook not found: ", chapter.NotebookID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", chapter.NotebookID).Msg("Notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
 		return
 	}
@@ -52,16 +53,18 @@ func CreateChapter(c *gin.Context) {
 
 	// Get organization_id from parent notebook
 	var notebook models.Notebook
-	if err := db.DB.Select("organization_id").Where("id = ?", chapter.NotebookID).First(&notebook).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Select("organization_id").Where("id = ?", chapter.NotebookID).First(&notebook).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chapter"})
 		return
 	}
 
 	// Inherit organization_id from parent notebook
 	chapter.OrganizationID = notebook.OrganizationID
+	// Note defaults are set through the settings endpoint, which validates them
+	chapter.NoteTemplate, chapter.AITone, chapter.AILength = "", "", ""
 
-	if err := db.DB.Create(&chapter).Error; err != nil {
-		log.Print("Error creating chapter in db: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Create(&chapter).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error creating chapter")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -82,7 +85,7 @@ func GetChaptersByNotebook(c *gin.Context) {
 	// Check authorization efficiently
 	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
 	if err != nil {
-		log.Print("Notebook not found: ", notebookID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", notebookID).Msg("Notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
 		return
 	}
@@ -94,8 +97,8 @@ func GetChaptersByNotebook(c *gin.Context) {
 
 	// Get chapters without preloading notes (optimized)
 	var chapters []models.Chapter
-	if err := db.DB.Where("notebook_id = ?", notebookID).Find(&chapters).Error; err != nil {
-		log.Print("Error fetching chapters for notebook: ", notebookID, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Where("notebook_id = ?", notebookID).Find(&chapters).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Str("notebook_id", notebookID).Msg("Error fetching chapters for notebook")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -115,8 +118,8 @@ func GetChapterById(c *gin.Context) {
 	var chapter models.Chapter
 
 	// Get chapter without preloading notebook
-	if err := db.DB.Where("id = ?", id).First(&chapter).Error; err != nil {
-		log.Print("Chapter not found with id: ", id, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", id).First(&chapter).Error; err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("chapter_id", id).Msg("Chapter not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
 		return
 	}
@@ -145,7 +148,7 @@ func DeleteChapter(c *gin.Context) {
 	// Check authorization efficiently
 	hasAccess, err := middleware.CheckChapterAccess(c.Request.Context(), db.DB, id, clerkUserID)
 	if err != nil {
-		log.Print("Chapter not found with id: ", id, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("chapter_id", id).Msg("Chapter not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
 		return
 	}
@@ -155,9 +158,14 @@ func DeleteChapter(c *gin.Context) {
 		return
 	}
 
+	if err := services.CheckChapterLegalHold(db.DB.WithContext(c.Request.Context()), id); err != nil {
+		sendRetentionError(c, err, "Failed to delete chapter")
+		return
+	}
+
 	// Delete the chapter
-	if err := db.DB.Delete(&models.Chapter{}, "id = ?", id).Error; err != nil {
-		log.Print("Error deleting chapter with id: ", id, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Delete(&models.Chapter{}, "id = ?", id).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Str("chapter_id", id).Msg("Error deleting chapter")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -178,7 +186,7 @@ func UpdateChapter(c *gin.Context) {
 	// Check authorization efficiently
 	hasAccess, err := middleware.CheckChapterAccess(c.Request.Context(), db.DB, id, clerkUserID)
 	if err != nil {
-		log.Print("Chapter not found with id: ", id, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("chapter_id", id).Msg("Chapter not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
 		return
 	}
@@ -191,14 +199,14 @@ func UpdateChapter(c *gin.Context) {
 	// Bind the update data from request body
 	var updateData models.Chapter
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		log.Print("Invalid update data for chapter: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid update data for chapter")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get current chapter to preserve protected fields
 	var chapter models.Chapter
-	if err := db.DB.Where("id = ?", id).First(&chapter).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", id).First(&chapter).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chapter"})
 		return
 	}
@@ -206,13 +214,23 @@ func UpdateChapter(c *gin.Context) {
 	// Prevent changing notebook_id and organization_id through update (security)
 	updateData.NotebookID = chapter.NotebookID
 	updateData.OrganizationID = chapter.OrganizationID
+	// Note defaults are changed through the settings endpoint, which validates them
+	updateData.NoteTemplate, updateData.AITone, updateData.AILength = "", "", ""
+	// Slugs follow the name
+	updateData.Slug = ""
 
 	// Update the chapter
-	if err := db.DB.Model(&chapter).Updates(updateData).Error; err != nil {
-		log.Print("Error updating chapter with id: ", id, " Error: ", err)
+	renamed := updateData.Name != "" && updateData.Name != chapter.Name
+	if err := db.DB.WithContext(c.Request.Context()).Model(&chapter).Updates(updateData).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Str("chapter_id", id).Msg("Error updating chapter")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if renamed {
+		if chapter.Slug, err = services.RefreshChapterSlug(db.DB, chapter.ID); err != nil {
+			log.Error().Err(err).Str("chapter_id", chapter.ID).Msg("Failed to refresh chapter slug")
+		}
+	}
 
 	c.JSON(http.StatusOK, chapter)
 }
@@ -230,7 +248,7 @@ func MoveChapter(c *gin.Context) {
 	// Check authorization for source chapter efficiently
 	hasAccess, err := middleware.CheckChapterAccess(c.Request.Context(), db.DB, id, clerkUserID)
 	if err != nil {
-		log.Print("Chapter not found with id: ", id, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("chapter_id", id).Msg("Chapter not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
 		return
 	}
@@ -242,24 +260,18 @@ func MoveChapter(c *gin.Context) {
 
 	// Bind the move data from request body
 	var moveData struct {
-		NotebookID     string  `json:"notebook_id" binding:"required"`
-		OrganizationID *string `json:"organization_id"`
+		NotebookID string `json:"notebook_id" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&moveData); err != nil {
-		log.Print("Invalid move data for chapter: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid move data for chapter")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Log the received organization ID
-	if moveData.OrganizationID != nil {
-		log.Printf("DEBUG: Move chapter request with organization ID: %s", *moveData.OrganizationID)
-	}
-
 	// Check authorization for target notebook efficiently
 	targetHasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, moveData.NotebookID, clerkUserID)
 	if err != nil {
-		log.Print("Target notebook not found: ", moveData.NotebookID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", moveData.NotebookID).Msg("Target notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Target notebook not found"})
 		return
 	}
@@ -269,63 +281,18 @@ func MoveChapter(c *gin.Context) {
 		return
 	}
 
-	// Get target notebook's organization_id
-	var targetNotebook models.Notebook
-	if err := db.DB.Select("organization_id").Where("id = ?", moveData.NotebookID).First(&targetNotebook).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move chapter"})
-		return
-	}
-
-	// Log before update
-	log.Printf("DEBUG: Before update - Chapter ID: %s, Target Notebook ID: %s", id, moveData.NotebookID)
-
-	// Determine which organization ID to use
-	var orgIDToUse *string
-	if moveData.OrganizationID != nil && *moveData.OrganizationID != "" {
-		// Use the provided organization ID from request
-		orgIDToUse = moveData.OrganizationID
-		log.Printf("DEBUG: Using organization ID from request: %s", *orgIDToUse)
-	} else {
-		// Fall back to target notebook's organization ID
-		orgIDToUse = targetNotebook.OrganizationID
-		if orgIDToUse != nil {
-			log.Printf("DEBUG: Using organization ID from target notebook: %s", *orgIDToUse)
-		}
-	}
-
-	// Update the chapter's notebook_id and organization_id using Updates with Select
-	updateData := map[string]interface{}{
-		"notebook_id":     moveData.NotebookID,
-		"organization_id": orgIDToUse,
-	}
-
-	log.Printf("DEBUG: About to update with data: %+v", updateData)
-
-	// Use Model().Select().Updates() for explicit column updates
-	result := db.DB.Model(&models.Chapter{}).Where("id = ?", id).Select("notebook_id", "organization_id").Updates(updateData)
-	if result.Error != nil {
-		log.Printf("ERROR: Failed to update chapter - Error: %v", result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	encryption := services.NewNotebookEncryptionService()
+	if rejectCrossEncryptionMove(c, encryption.ChapterEncrypted, id, encryption.NotebookEncrypted, moveData.NotebookID) {
 		return
 	}
 
-	log.Printf("DEBUG: GORM Update Result - Rows Affected: %d, Error: %v", result.RowsAffected, result.Error)
-
-	if result.RowsAffected == 0 {
-		log.Printf("WARNING: Update returned 0 rows affected for chapter ID: %s", id)
-	}
-
-	// Reload the chapter from scratch to verify the update
-	var updatedChapter models.Chapter
-	if err := db.DB.Preload("Notebook").Where("id = ?", id).First(&updatedChapter).Error; err != nil {
-		log.Printf("ERROR: Failed to reload chapter after move: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload chapter"})
+	// The chapter and its notes take the target notebook's organization
+	updatedChapter, err := services.NewOrgConsistencyService().MoveChapter(id, moveData.NotebookID)
+	if err != nil {
+		sendMoveError(c, err, "Failed to move chapter")
 		return
 	}
 
-	log.Printf("DEBUG: After reload - Chapter ID: %s, Notebook ID: %s (expected: %s)",
-		updatedChapter.ID, updatedChapter.NotebookID, moveData.NotebookID)
-	log.Printf("Chapter moved successfully. Chapter ID: %s, New Notebook ID: %s", updatedChapter.ID, moveData.NotebookID)
-
+	log.Info().Str("chapter_id", id).Str("notebook_id", moveData.NotebookID).Msg("Chapter moved successfully")
 	c.JSON(http.StatusOK, updatedChapter)
 }
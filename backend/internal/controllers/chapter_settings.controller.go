@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChapterSettings returns the defaults applied to notes created in a chapter
+// GET /chapter/:id/settings
+func GetChapterSettings(c *gin.Context) {
+	chapterID, ok := authorizeChapterSettings(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	settings, err := services.NewChapterSettingsService().GetSettings(chapterID)
+	if err != nil {
+		sendChapterSettingsError(c, err, "Failed to fetch chapter settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateChapterSettings replaces the defaults applied to notes created in a chapter
+// PUT /chapter/:id/settings
+func UpdateChapterSettings(c *gin.Context) {
+	chapterID, ok := authorizeChapterSettings(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var input services.ChapterSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := services.NewChapterSettingsService().UpdateSettings(chapterID, input)
+	if err != nil {
+		sendChapterSettingsError(c, err, "Failed to update chapter settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// authorizeChapterSettings checks the user has the required access to the chapter in the path
+func authorizeChapterSettings(c *gin.Context, required middleware.NoteAccess) (string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+
+	chapterID := c.Param("id")
+	if !authorizeChapter(c, chapterID, clerkUserID, required) {
+		return "", false
+	}
+	return chapterID, true
+}
+
+// sendChapterSettingsError maps chapter settings service errors to responses
+func sendChapterSettingsError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidChapterSettings):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChapterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -3,13 +3,17 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"backend/db"
+	"backend/internal/config"
 	"backend/internal/middleware"
 	"backend/internal/models"
 	"backend/internal/services"
@@ -18,21 +22,32 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/organizationmembership"
 	"github.com/coder/aisdk-go"
 	"github.com/gin-gonic/gin"
 	"github.com/openai/openai-go"
 	openaioption "github.com/openai/openai-go/option"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/genai"
+	"gorm.io/gorm"
 )
 
 // ChatRequest wraps the aisdk.Chat with optional extra fields
 type ChatRequest struct {
 	aisdk.Chat
-	Provider       string  `json:"provider"`
-	Model          string  `json:"model"`
-	Thinking       bool    `json:"thinking"`
-	OrganizationID *string `json:"organizationId,omitempty"` // Optional organization context
+	Provider       string            `json:"provider"` // Optional, defaults to the chat model settings
+	Model          string            `json:"model"`
+	Thinking       bool              `json:"thinking"`
+	OrganizationID *string           `json:"organizationId,omitempty"` // Optional organization context
+	Scope          *ChatScopeRequest `json:"scope,omitempty"`          // Optional notebook or chapter to focus on
+	NoteID         string            `json:"noteId,omitempty"`         // Optional note the conversation starts from
+}
+
+// ChatScopeRequest limits a chat to one notebook or one chapter
+type ChatScopeRequest struct {
+	NotebookID string `json:"notebookId,omitempty"`
+	ChapterID  string `json:"chapterId,omitempty"`
 }
 
 var (
@@ -63,20 +78,20 @@ func (w *autoFlushWriter) Write(p []byte) (n int, err error) {
 func userCanAccessNotebookChat(ctx context.Context, notebook *models.Notebook, clerkUserID string) bool {
 	if notebook.OrganizationID != nil && *notebook.OrganizationID != "" {
 		// Organization notebook - verify membership
-		_, isMember, err := middleware.GetOrgMemberRole(ctx, *notebook.OrganizationID, clerkUserID)
+		_, isMember, err := middleware.GetOrgMemberRoleCached(ctx, *notebook.OrganizationID, clerkUserID)
 		return err == nil && isMember
 	}
 	// Personal notebook - verify ownership
 	return notebook.ClerkUserID == clerkUserID
 }
 
-func getOpenAIClient(apiKey string) *openai.Client {
-	client := openai.NewClient(openaioption.WithAPIKey(apiKey))
+func getOpenAIClient(apiKey string, opts ...openaioption.RequestOption) *openai.Client {
+	client := openai.NewClient(append([]openaioption.RequestOption{openaioption.WithAPIKey(apiKey)}, opts...)...)
 	return &client
 }
 
-func getAnthropicClient(apiKey string) *anthropic.Client {
-	client := anthropic.NewClient(anthropicoption.WithAPIKey(apiKey))
+func getAnthropicClient(apiKey string, opts ...anthropicoption.RequestOption) *anthropic.Client {
+	client := anthropic.NewClient(append([]anthropicoption.RequestOption{anthropicoption.WithAPIKey(apiKey)}, opts...)...)
 	return &client
 }
 
@@ -87,11 +102,6 @@ func getGoogleClient(ctx context.Context, apiKey string) (*genai.Client, error)
 	})
 }
 
-// getUserAPIKey retrieves the user's API key for a specific provider using the new APIKeyResolver
-func getUserAPIKey(clerkUserID string, provider string) (string, error) {
-	return getUserAPIKeyWithOrg(clerkUserID, nil, provider)
-}
-
 // getUserAPIKeyWithOrg retrieves the user's API key for a specific provider with organization context
 func getUserAPIKeyWithOrg(clerkUserID string, organizationID *string, provider string) (string, error) {
 	resolver := services.NewAPIKeyResolver()
@@ -103,45 +113,147 @@ func getUserAPIKeyWithOrg(clerkUserID string, organizationID *string, provider s
 }
 
 // handleNotesToolCall handles tool calls for notes-related operations
-func handleNotesToolCall(toolCall aisdk.ToolCall, clerkUserID string, organizationID *string) any {
+// Tools outside the user's or organization's permission scope are rejected even if the model calls them,
+// and so are calls that reach outside the notebook or chapter a scoped chat is limited to, into an encrypted notebook
+// or to a locked note. Note content is paged to fit the token budget.
+// noteEditTools are the tools that change notes, with the arguments naming the notes they change and the
+// chapters they add notes to
+var noteEditTools = map[string]struct{ notes, chapters []string }{
+	"createNote":        {chapters: []string{"chapterId"}},
+	"moveNote":          {notes: []string{"noteId"}, chapters: []string{"targetChapterId"}},
+	"renameNote":        {notes: []string{"noteId"}},
+	"deleteNote":        {notes: []string{"noteId"}},
+	"updateNoteContent": {notes: []string{"noteId"}},
+	"generateNoteVideo": {notes: []string{"noteId"}},
+	"deleteNoteVideo":   {notes: []string{"noteId"}},
+}
+
+// checkNoteEditToolCall stops tools from changing notes the user can only view or comment on. Notes and
+// chapters the user can't access at all are left to the tools, which answer that they weren't found.
+func checkNoteEditToolCall(ctx context.Context, toolCall aisdk.ToolCall, clerkUserID string) error {
+	tool, ok := noteEditTools[toolCall.Name]
+	if !ok {
+		return nil
+	}
+	for _, arg := range tool.notes {
+		noteID, _ := toolCall.Args[arg].(string)
+		if noteID == "" {
+			continue
+		}
+		access, err := middleware.GetNoteAccess(ctx, db.DB, noteID, clerkUserID)
+		if err == nil && access != middleware.NoteAccessNone && access < middleware.NoteAccessCanEdit {
+			return fmt.Errorf("the user can %s note %s but not change it", access, noteID)
+		}
+	}
+	for _, arg := range tool.chapters {
+		chapterID, _ := toolCall.Args[arg].(string)
+		if chapterID == "" {
+			continue
+		}
+		access, err := middleware.GetChapterAccess(ctx, db.DB, chapterID, clerkUserID)
+		if err == nil && access != middleware.NoteAccessNone && access < middleware.NoteAccessCanEdit {
+			return fmt.Errorf("the user can %s the notes of chapter %s but not add notes to it", access, chapterID)
+		}
+	}
+	return nil
+}
+
+func handleNotesToolCall(ctx context.Context, toolCall aisdk.ToolCall, clerkUserID string, organizationID *string, scope services.AIToolScope, chatScope *services.AIChatScope, budget services.AITokenBudget) any {
+	if !scope.Allows(toolCall.Name) {
+		log.Warn().
+			Str("tool", toolCall.Name).
+			Str("clerk_user_id", clerkUserID).
+			Msg("Blocked AI tool call outside permission scope")
+		return map[string]string{"error": fmt.Sprintf("The %s tool is disabled in this workspace", toolCall.Name)}
+	}
+	if err := chatScope.CheckToolCall(toolCall.Name, toolCall.Args); err != nil {
+		log.Warn().
+			Str("tool", toolCall.Name).
+			Str("clerk_user_id", clerkUserID).
+			Msg("Blocked AI tool call outside chat scope")
+		return map[string]string{"error": err.Error()}
+	}
+	if err := services.NewNotebookEncryptionService().CheckToolCall(toolCall.Args); err != nil {
+		log.Warn().
+			Err(err).
+			Str("tool", toolCall.Name).
+			Str("clerk_user_id", clerkUserID).
+			Msg("Blocked AI tool call on encrypted notebook")
+		return map[string]string{"error": err.Error()}
+	}
+	if err := services.NewNoteLockService().CheckToolCall(toolCall.Args); err != nil {
+		log.Warn().
+			Err(err).
+			Str("tool", toolCall.Name).
+			Str("clerk_user_id", clerkUserID).
+			Msg("Blocked AI tool call on locked note")
+		return map[string]string{"error": err.Error()}
+	}
+	if err := checkNoteEditToolCall(ctx, toolCall, clerkUserID); err != nil {
+		log.Warn().
+			Str("tool", toolCall.Name).
+			Str("clerk_user_id", clerkUserID).
+			Msg("Blocked AI tool call changing a read-only note")
+		return map[string]string{"error": err.Error()}
+	}
+
 	switch toolCall.Name {
 	case "searchNotes":
 		query, ok := toolCall.Args["query"].(string)
 		if !ok {
 			return map[string]string{"error": "Invalid query parameter"}
 		}
-		return searchNotes(clerkUserID, organizationID, query)
+		var where []string
+		if values, ok := toolCall.Args["where"].([]interface{}); ok {
+			for _, value := range values {
+				if filter, ok := value.(string); ok {
+					where = append(where, filter)
+				}
+			}
+		}
+		return searchNotes(ctx, clerkUserID, organizationID, query, where, chatScope)
 
 	case "listNotebooks":
-		return listNotebooks(clerkUserID, organizationID)
+		return listNotebooks(ctx, clerkUserID, organizationID, chatScope)
 
 	case "listChapters":
 		notebookID, ok := toolCall.Args["notebookId"].(string)
 		if !ok {
 			return map[string]string{"error": "Invalid notebookId parameter"}
 		}
-		return listChapters(clerkUserID, notebookID)
+		return listChapters(ctx, clerkUserID, notebookID)
 
 	case "getNoteContent":
 		noteID, ok := toolCall.Args["noteId"].(string)
 		if !ok {
 			return map[string]string{"error": "Invalid noteId parameter"}
 		}
-		return getNoteContent(clerkUserID, noteID)
+		return getNoteContent(ctx, clerkUserID, noteID, 1, budget.NotePage)
+
+	case "getNoteContentPage":
+		noteID, ok := toolCall.Args["noteId"].(string)
+		if !ok {
+			return map[string]string{"error": "Invalid noteId parameter"}
+		}
+		page, ok := toolCall.Args["page"].(float64)
+		if !ok || page < 1 {
+			return map[string]string{"error": "Invalid page parameter"}
+		}
+		return getNoteContent(ctx, clerkUserID, noteID, int(page), budget.NotePage)
 
 	case "listNotesInChapter":
 		chapterID, ok := toolCall.Args["chapterId"].(string)
 		if !ok {
 			return map[string]string{"error": "Invalid chapterId parameter"}
 		}
-		return listNotesInChapter(clerkUserID, chapterID)
+		return listNotesInChapter(ctx, clerkUserID, chapterID)
 
 	case "createNotebook":
 		name, ok := toolCall.Args["name"].(string)
 		if !ok {
 			return map[string]string{"error": "Invalid name parameter"}
 		}
-		return createNotebook(clerkUserID, organizationID, name)
+		return createNotebook(ctx, clerkUserID, organizationID, name)
 
 	case "createChapter":
 		notebookID, ok := toolCall.Args["notebookId"].(string)
@@ -152,7 +264,7 @@ func handleNotesToolCall(toolCall aisdk.ToolCall, clerkUserID string, organizati
 		if !ok {
 			return map[string]string{"error": "Invalid name parameter"}
 		}
-		return createChapter(clerkUserID, notebookID, name)
+		return createChapter(ctx, clerkUserID, notebookID, name)
 
 	case "renameNotebook":
 		notebookID, ok := toolCall.Args["notebookId"].(string)
@@ -163,7 +275,7 @@ func handleNotesToolCall(toolCall aisdk.ToolCall, clerkUserID string, organizati
 		if !ok {
 			return map[string]string{"error": "Invalid newName parameter"}
 		}
-		return renameNotebook(clerkUserID, notebookID, newName)
+		return renameNotebook(ctx, clerkUserID, notebookID, newName)
 
 	case "renameChapter":
 		chapterID, ok := toolCall.Args["chapterId"].(string)
@@ -174,7 +286,7 @@ func handleNotesToolCall(toolCall aisdk.ToolCall, clerkUserID string, organizati
 		if !ok {
 			return map[string]string{"error": "Invalid newName parameter"}
 		}
-		return renameChapter(clerkUserID, chapterID, newName)
+		return renameChapter(ctx, clerkUserID, chapterID, newName)
 
 	case "createNote":
 		chapterID, ok := toolCall.Args["chapterId"].(string)
@@ -190,7 +302,7 @@ func handleNotesToolCall(toolCall aisdk.ToolCall, clerkUserID string, organizati
 		if contentArg, ok := toolCall.Args["content"].(string); ok {
 			content = contentArg
 		}
-		return createNote(clerkUserID, chapterID, title, content)
+		return createNote(ctx, clerkUserID, chapterID, title, content)
 
 	case "moveNote":
 		noteID, ok := toolCall.Args["noteId"].(string)
@@ -201,7 +313,7 @@ func handleNotesToolCall(toolCall aisdk.ToolCall, clerkUserID string, organizati
 		if !ok {
 			return map[string]string{"error": "Invalid targetChapterId parameter"}
 		}
-		return moveNote(clerkUserID, noteID, targetChapterID)
+		return moveNote(ctx, clerkUserID, noteID, targetChapterID)
 
 	case "moveChapter":
 		chapterID, ok := toolCall.Args["chapterId"].(string)
@@ -212,21 +324,21 @@ func handleNotesToolCall(toolCall aisdk.ToolCall, clerkUserID string, organizati
 		if !ok {
 			return map[string]string{"error": "Invalid targetNotebookId parameter"}
 		}
-		return moveChapter(clerkUserID, chapterID, targetNotebookID)
+		return moveChapter(ctx, clerkUserID, chapterID, targetNotebookID)
 
 	case "generateNoteVideo":
 		noteID, ok := toolCall.Args["noteId"].(string)
 		if !ok {
 			return map[string]string{"error": "Invalid noteId parameter"}
 		}
-		return generateNoteVideo(clerkUserID, noteID)
+		return generateNoteVideo(ctx, clerkUserID, noteID)
 
 	case "deleteNoteVideo":
 		noteID, ok := toolCall.Args["noteId"].(string)
 		if !ok {
 			return map[string]string{"error": "Invalid noteId parameter"}
 		}
-		return deleteNoteVideo(clerkUserID, noteID)
+		return deleteNoteVideo(ctx, clerkUserID, noteID)
 
 	case "renameNote":
 		noteID, ok := toolCall.Args["noteId"].(string)
@@ -237,14 +349,14 @@ func handleNotesToolCall(toolCall aisdk.ToolCall, clerkUserID string, organizati
 		if !ok {
 			return map[string]string{"error": "Invalid newName parameter"}
 		}
-		return renameNote(clerkUserID, noteID, newName)
+		return renameNote(ctx, clerkUserID, noteID, newName)
 
 	case "deleteNote":
 		noteID, ok := toolCall.Args["noteId"].(string)
 		if !ok {
 			return map[string]string{"error": "Invalid noteId parameter"}
 		}
-		return deleteNote(clerkUserID, noteID)
+		return deleteNote(ctx, clerkUserID, noteID)
 
 	case "updateNoteContent":
 		noteID, ok := toolCall.Args["noteId"].(string)
@@ -255,7 +367,81 @@ func handleNotesToolCall(toolCall aisdk.ToolCall, clerkUserID string, organizati
 		if !ok {
 			return map[string]string{"error": "Invalid content parameter"}
 		}
-		return updateNoteContent(clerkUserID, noteID, content)
+		return updateNoteContent(ctx, clerkUserID, noteID, content)
+
+	case "listBoards":
+		return listBoards(ctx, clerkUserID, organizationID, chatScope)
+
+	case "createTask":
+		boardID, ok := toolCall.Args["boardId"].(string)
+		if !ok {
+			return map[string]string{"error": "Invalid boardId parameter"}
+		}
+		title, ok := toolCall.Args["title"].(string)
+		if !ok || strings.TrimSpace(title) == "" {
+			return map[string]string{"error": "Invalid title parameter"}
+		}
+		description, _ := toolCall.Args["description"].(string)
+		status, _ := toolCall.Args["status"].(string)
+		priority, _ := toolCall.Args["priority"].(string)
+		return createBoardTask(ctx, clerkUserID, boardID, title, description, status, priority)
+
+	case "moveTaskToColumn":
+		taskID, ok := toolCall.Args["taskId"].(string)
+		if !ok {
+			return map[string]string{"error": "Invalid taskId parameter"}
+		}
+		column, ok := toolCall.Args["column"].(string)
+		if !ok {
+			return map[string]string{"error": "Invalid column parameter"}
+		}
+		return moveTaskToColumn(ctx, clerkUserID, taskID, column)
+
+	case "assignTask":
+		taskID, ok := toolCall.Args["taskId"].(string)
+		if !ok {
+			return map[string]string{"error": "Invalid taskId parameter"}
+		}
+		assigneeArgs, ok := toolCall.Args["assignees"].([]any)
+		if !ok {
+			return map[string]string{"error": "Invalid assignees parameter"}
+		}
+		assignees := make([]string, 0, len(assigneeArgs))
+		for _, arg := range assigneeArgs {
+			assignee, ok := arg.(string)
+			if !ok {
+				return map[string]string{"error": "Invalid assignees parameter"}
+			}
+			assignees = append(assignees, assignee)
+		}
+		return assignTask(ctx, clerkUserID, taskID, assignees)
+
+	case "completeTask":
+		taskID, ok := toolCall.Args["taskId"].(string)
+		if !ok {
+			return map[string]string{"error": "Invalid taskId parameter"}
+		}
+		return completeTask(ctx, clerkUserID, taskID)
+
+	case "listUpcomingMeetings":
+		days := 7
+		if daysArg, ok := toolCall.Args["days"].(float64); ok {
+			days = int(daysArg)
+		}
+		return listUpcomingMeetings(clerkUserID, days)
+
+	case "scheduleBotForMeeting":
+		eventID, _ := toolCall.Args["eventId"].(string)
+		meetingURL, _ := toolCall.Args["meetingUrl"].(string)
+		return scheduleBotForMeeting(clerkUserID, eventID, meetingURL)
+
+	case "getMeetingSummary":
+		query, _ := toolCall.Args["query"].(string)
+		date, _ := toolCall.Args["date"].(string)
+		if query == "" && date == "" {
+			return map[string]string{"error": "Provide a query, a date, or both"}
+		}
+		return getMeetingSummary(clerkUserID, query, date)
 
 	default:
 		return map[string]string{"error": "Unknown tool: " + toolCall.Name}
@@ -291,6 +477,14 @@ func getPreviewText(content string, maxLength int) string {
 	return content
 }
 
+// notePreview returns a note's AI summary, or the start of its text for notes without one
+func notePreview(note *models.Notes) string {
+	if note.Summary != "" {
+		return note.Summary
+	}
+	return getPreviewText(note.Content, 150)
+}
+
 // extractTextFromProseMirror recursively extracts plain text from ProseMirror JSON
 func extractTextFromProseMirror(node map[string]interface{}) string {
 	var textParts []string
@@ -315,19 +509,23 @@ func extractTextFromProseMirror(node map[string]interface{}) string {
 	return strings.Join(textParts, " ")
 }
 
-// searchNotes searches for notes by query in title and content
-func searchNotes(clerkUserID string, organizationID *string, query string) any {
+// searchNotes searches for notes by query in title and content, optionally filtered by properties and
+// limited to a chat's scope
+func searchNotes(ctx context.Context, clerkUserID string, organizationID *string, query string, where []string, chatScope *services.AIChatScope) any {
 	var allNotes []models.Notes
 
-	searchQuery := "%" + strings.ToLower(query) + "%"
+	filters, err := services.ParseNotePropertyFilters(where)
+	if err != nil {
+		return map[string]string{"error": err.Error()}
+	}
 
 	if organizationID != nil && *organizationID != "" {
 		// Search in organization notebooks
-		err := db.DB.Preload("Chapter.Notebook").
+		err := chatScope.FilterNotes(services.MatchNoteText(services.ApplyNotePropertyFilters(db.DB.WithContext(ctx).Preload("Chapter.Notebook").Preload("Properties"), filters), query)).
 			Joins("JOIN chapters ON notes.chapter_id = chapters.id").
 			Joins("JOIN notebooks ON chapters.notebook_id = notebooks.id").
-			Where("notebooks.organization_id = ? AND (LOWER(notes.name) LIKE ? OR LOWER(notes.content) LIKE ?)",
-				*organizationID, searchQuery, searchQuery).
+			Where("notebooks.organization_id = ? AND notebooks.encrypted = ? AND notes.locked = ?",
+				*organizationID, false, false).
 			Limit(10).
 			Find(&allNotes).Error
 
@@ -337,11 +535,11 @@ func searchNotes(clerkUserID string, organizationID *string, query string) any {
 		}
 	} else {
 		// Search in personal notebooks (organization_id IS NULL)
-		err := db.DB.Preload("Chapter.Notebook").
+		err := chatScope.FilterNotes(services.MatchNoteText(services.ApplyNotePropertyFilters(db.DB.WithContext(ctx).Preload("Chapter.Notebook").Preload("Properties"), filters), query)).
 			Joins("JOIN chapters ON notes.chapter_id = chapters.id").
 			Joins("JOIN notebooks ON chapters.notebook_id = notebooks.id").
-			Where("notebooks.clerk_user_id = ? AND notebooks.organization_id IS NULL AND (LOWER(notes.name) LIKE ? OR LOWER(notes.content) LIKE ?)",
-				clerkUserID, searchQuery, searchQuery).
+			Where("notebooks.clerk_user_id = ? AND notebooks.organization_id IS NULL AND notebooks.encrypted = ? AND notes.locked = ?",
+				clerkUserID, false, false).
 			Limit(10).
 			Find(&allNotes).Error
 
@@ -366,13 +564,20 @@ func searchNotes(clerkUserID string, organizationID *string, query string) any {
 			"id":           note.ID,
 			"name":         note.Name,
 			"content":      note.Content,
-			"preview":      getPreviewText(note.Content, 150),
+			"preview":      notePreview(&note),
 			"chapterId":    note.Chapter.ID,
 			"chapterName":  note.Chapter.Name,
 			"notebookId":   note.Chapter.Notebook.ID,
 			"notebookName": note.Chapter.Notebook.Name,
 			"updatedAt":    note.UpdatedAt.Format("2006-01-02 15:04:05"),
 		}
+		if len(note.Properties) > 0 {
+			properties := make(map[string]string, len(note.Properties))
+			for _, property := range note.Properties {
+				properties[property.Key] = property.Value
+			}
+			results[i]["properties"] = properties
+		}
 	}
 
 	return map[string]any{
@@ -382,13 +587,19 @@ func searchNotes(clerkUserID string, organizationID *string, query string) any {
 	}
 }
 
-// listNotebooks lists all notebooks for a user
-func listNotebooks(clerkUserID string, organizationID *string) any {
+// listNotebooks lists all notebooks for a user, or only the notebook a scoped chat is limited to
+func listNotebooks(ctx context.Context, clerkUserID string, organizationID *string, chatScope *services.AIChatScope) any {
 	var notebooks []models.Notebook
 
-	if organizationID != nil && *organizationID != "" {
+	if chatScope != nil {
+		err := db.DB.WithContext(ctx).Preload("Chapters").Where("id = ?", chatScope.NotebookID).Find(&notebooks).Error
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list scoped notebook")
+			return map[string]string{"error": "Failed to list notebooks"}
+		}
+	} else if organizationID != nil && *organizationID != "" {
 		// List organization notebooks
-		err := db.DB.Preload("Chapters").
+		err := db.DB.WithContext(ctx).Preload("Chapters").
 			Where("organization_id = ?", *organizationID).
 			Order("updated_at DESC").
 			Find(&notebooks).Error
@@ -407,7 +618,7 @@ func listNotebooks(clerkUserID string, organizationID *string) any {
 		}
 	} else {
 		// List personal notebooks (organization_id IS NULL)
-		err := db.DB.Preload("Chapters").
+		err := db.DB.WithContext(ctx).Preload("Chapters").
 			Where("clerk_user_id = ? AND organization_id IS NULL", clerkUserID).
 			Order("updated_at DESC").
 			Find(&notebooks).Error
@@ -445,21 +656,21 @@ func listNotebooks(clerkUserID string, organizationID *string) any {
 }
 
 // listChapters lists all chapters in a notebook
-func listChapters(clerkUserID string, notebookID string) any {
+func listChapters(ctx context.Context, clerkUserID string, notebookID string) any {
 	// Find notebook first
 	var notebook models.Notebook
-	err := db.DB.Where("id = ?", notebookID).First(&notebook).Error
+	err := db.DB.WithContext(ctx).Where("id = ?", notebookID).First(&notebook).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Notebook not found")
 		return map[string]string{"error": "Notebook not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &notebook, clerkUserID) {
 		return map[string]string{"error": "Notebook not found or access denied"}
 	}
 
 	var chapters []models.Chapter
-	err = db.DB.Preload("Files").
+	err = db.DB.WithContext(ctx).Preload("Files").
 		Where("notebook_id = ?", notebookID).
 		Order("created_at ASC").
 		Find(&chapters).Error
@@ -498,25 +709,34 @@ func listChapters(clerkUserID string, notebookID string) any {
 	}
 }
 
-// getNoteContent gets the full content of a note
-func getNoteContent(clerkUserID string, noteID string) any {
+// getNoteContent gets a page of a note's content as Markdown. Short notes fit on one page; long ones are
+// split into pages of about pageTokens, each telling the model which page to ask for next.
+func getNoteContent(ctx context.Context, clerkUserID string, noteID string, page int, pageTokens int) any {
 	var note models.Notes
 
 	// Get note with relationships
-	err := db.DB.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	err := db.DB.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Note not found")
 		return map[string]string{"error": "Note not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &note.Chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &note.Chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Note not found or access denied"}
 	}
 
-	return map[string]any{
+	// Markdown takes a fraction of the tokens of the stored TipTap JSON
+	content, err := internalutils.TipTapToMarkdown(note.Content)
+	if err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to convert note to markdown for chat")
+		content = note.Content
+	}
+	contentPage := services.PageText(content, page, pageTokens)
+
+	result := map[string]any{
 		"id":           note.ID,
 		"name":         note.Name,
-		"content":      note.Content,
+		"content":      contentPage.Content,
 		"chapterId":    note.Chapter.ID,
 		"chapterName":  note.Chapter.Name,
 		"notebookId":   note.Chapter.Notebook.ID,
@@ -524,24 +744,33 @@ func getNoteContent(clerkUserID string, noteID string) any {
 		"createdAt":    note.CreatedAt.Format("2006-01-02 15:04:05"),
 		"updatedAt":    note.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
+	if contentPage.Pages > 1 {
+		result["page"] = contentPage.Page
+		result["pages"] = contentPage.Pages
+		if contentPage.HasMore() {
+			result["nextPage"] = contentPage.Page + 1
+			result["continuation"] = fmt.Sprintf("This is page %d of %d of a long note. Call getNoteContentPage with page %d to read on, and read every page before rewriting the note with updateNoteContent.", contentPage.Page, contentPage.Pages, contentPage.Page+1)
+		}
+	}
+	return result
 }
 
 // listNotesInChapter lists all notes in a chapter
-func listNotesInChapter(clerkUserID string, chapterID string) any {
+func listNotesInChapter(ctx context.Context, clerkUserID string, chapterID string) any {
 	// Get chapter with notebook
 	var chapter models.Chapter
-	err := db.DB.Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error
+	err := db.DB.WithContext(ctx).Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Chapter not found")
 		return map[string]string{"error": "Chapter not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Chapter not found or access denied"}
 	}
 
 	var notes []models.Notes
-	err = db.DB.Where("chapter_id = ?", chapterID).
+	err = db.DB.WithContext(ctx).Where("chapter_id = ?", chapterID).
 		Order("updated_at DESC").
 		Find(&notes).Error
 
@@ -568,7 +797,7 @@ func listNotesInChapter(clerkUserID string, chapterID string) any {
 			"id":        note.ID,
 			"name":      note.Name,
 			"content":   note.Content,
-			"preview":   getPreviewText(note.Content, 150),
+			"preview":   notePreview(&note),
 			"updatedAt": note.UpdatedAt.Format("2006-01-02 15:04:05"),
 		}
 	}
@@ -583,16 +812,16 @@ func listNotesInChapter(clerkUserID string, chapterID string) any {
 }
 
 // createNote creates a new note in a chapter
-func createNote(clerkUserID string, chapterID string, title string, content string) any {
+func createNote(ctx context.Context, clerkUserID string, chapterID string, title string, content string) any {
 	// Get chapter with notebook
 	var chapter models.Chapter
-	err := db.DB.Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error
+	err := db.DB.WithContext(ctx).Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Chapter not found")
 		return map[string]string{"error": "Chapter not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Chapter not found or access denied"}
 	}
 
@@ -602,6 +831,7 @@ func createNote(clerkUserID string, chapterID string, title string, content stri
 		log.Error().Err(err).Str("chapterID", chapterID).Msg("Failed to convert markdown to TipTap JSON")
 		return map[string]string{"error": "Failed to convert content format"}
 	}
+	tiptapContent = services.ApplyChapterNoteDefaults(&chapter, tiptapContent)
 
 	// Create the note with inherited organization_id
 	note := models.Notes{
@@ -611,14 +841,14 @@ func createNote(clerkUserID string, chapterID string, title string, content stri
 		OrganizationID: chapter.OrganizationID,
 	}
 
-	err = db.DB.Create(&note).Error
+	err = db.DB.WithContext(ctx).Create(&note).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create note")
 		return map[string]string{"error": "Failed to create note"}
 	}
 
 	// Reload note with relationships
-	err = db.DB.Preload("Chapter.Notebook").First(&note, "id = ?", note.ID).Error
+	err = db.DB.WithContext(ctx).Preload("Chapter.Notebook").First(&note, "id = ?", note.ID).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to reload note")
 		// Still return success even if reload fails
@@ -639,28 +869,28 @@ func createNote(clerkUserID string, chapterID string, title string, content stri
 }
 
 // moveChapter moves a chapter to a different notebook
-func moveChapter(clerkUserID string, chapterID string, targetNotebookID string) any {
+func moveChapter(ctx context.Context, clerkUserID string, chapterID string, targetNotebookID string) any {
 	// Get chapter with notebook
 	var chapter models.Chapter
-	err := db.DB.Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error
+	err := db.DB.WithContext(ctx).Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Chapter not found")
 		return map[string]string{"error": "Chapter not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Chapter not found or access denied"}
 	}
 
 	// Get target notebook and check authorization
 	var targetNotebook models.Notebook
-	err = db.DB.Where("id = ?", targetNotebookID).First(&targetNotebook).Error
+	err = db.DB.WithContext(ctx).Where("id = ?", targetNotebookID).First(&targetNotebook).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Target notebook not found")
 		return map[string]string{"error": "Target notebook not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &targetNotebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &targetNotebook, clerkUserID) {
 		return map[string]string{"error": "Target notebook not found or access denied"}
 	}
 
@@ -668,17 +898,14 @@ func moveChapter(clerkUserID string, chapterID string, targetNotebookID string)
 
 	// Count notes in chapter
 	var noteCount int64
-	db.DB.Model(&models.Notes{}).Where("chapter_id = ?", chapterID).Count(&noteCount)
-
-	// Update chapter's notebook and inherit target notebook's organization_id using explicit Updates
-	updateData := map[string]interface{}{
-		"notebook_id":     targetNotebookID,
-		"organization_id": targetNotebook.OrganizationID,
-	}
+	db.DB.WithContext(ctx).Model(&models.Notes{}).Where("chapter_id = ?", chapterID).Count(&noteCount)
 
-	result := db.DB.Model(&models.Chapter{}).Where("id = ?", chapterID).Select("notebook_id", "organization_id").Updates(updateData)
-	if result.Error != nil {
-		log.Error().Err(result.Error).Msg("Failed to move chapter")
+	// Move the chapter and give it and its notes the target notebook's organization
+	if _, err := services.NewOrgConsistencyService().MoveChapter(chapterID, targetNotebookID); err != nil {
+		if errors.Is(err, services.ErrLegalHold) {
+			return map[string]string{"error": "The chapter's notebook is under legal hold and can't be moved out of"}
+		}
+		log.Error().Err(err).Msg("Failed to move chapter")
 		return map[string]string{"error": "Failed to move chapter"}
 	}
 
@@ -694,43 +921,40 @@ func moveChapter(clerkUserID string, chapterID string, targetNotebookID string)
 }
 
 // moveNote moves a note to a different chapter
-func moveNote(clerkUserID string, noteID string, targetChapterID string) any {
+func moveNote(ctx context.Context, clerkUserID string, noteID string, targetChapterID string) any {
 	// Get note with relationships
 	var note models.Notes
-	err := db.DB.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	err := db.DB.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Note not found")
 		return map[string]string{"error": "Note not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &note.Chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &note.Chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Note not found or access denied"}
 	}
 
 	// Get target chapter and check authorization
 	var targetChapter models.Chapter
-	err = db.DB.Preload("Notebook").Where("id = ?", targetChapterID).First(&targetChapter).Error
+	err = db.DB.WithContext(ctx).Preload("Notebook").Where("id = ?", targetChapterID).First(&targetChapter).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Target chapter not found")
 		return map[string]string{"error": "Target chapter not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &targetChapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &targetChapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Target chapter not found or access denied"}
 	}
 
 	oldChapterName := note.Chapter.Name
 	oldNotebookName := note.Chapter.Notebook.Name
 
-	// Update note's chapter and inherit target chapter's organization_id using explicit Updates
-	updateData := map[string]interface{}{
-		"chapter_id":      targetChapterID,
-		"organization_id": targetChapter.OrganizationID,
-	}
-
-	result := db.DB.Model(&models.Notes{}).Where("id = ?", noteID).Select("chapter_id", "organization_id").Updates(updateData)
-	if result.Error != nil {
-		log.Error().Err(result.Error).Msg("Failed to move note")
+	// Move the note and give it the target notebook's organization
+	if _, err := services.NewOrgConsistencyService().MoveNote(noteID, targetChapterID); err != nil {
+		if errors.Is(err, services.ErrLegalHold) {
+			return map[string]string{"error": "The note's notebook is under legal hold and can't be moved out of"}
+		}
+		log.Error().Err(err).Msg("Failed to move note")
 		return map[string]string{"error": "Failed to move note"}
 	}
 
@@ -749,26 +973,29 @@ func moveNote(clerkUserID string, noteID string, targetChapterID string) any {
 }
 
 // renameNote renames a note
-func renameNote(clerkUserID string, noteID string, newName string) any {
+func renameNote(ctx context.Context, clerkUserID string, noteID string, newName string) any {
 	// Get note with relationships
 	var note models.Notes
-	err := db.DB.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	err := db.DB.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Note not found")
 		return map[string]string{"error": "Note not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &note.Chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &note.Chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Note not found or access denied"}
 	}
 
 	oldName := note.Name
 
-	result := db.DB.Model(&note).Select("Name").Updates(models.Notes{Name: newName})
+	result := db.DB.WithContext(ctx).Model(&note).Select("Name").Updates(models.Notes{Name: newName})
 	if result.Error != nil {
 		log.Error().Err(result.Error).Msg("Failed to rename note")
 		return map[string]string{"error": "Failed to rename note"}
 	}
+	if _, err := services.RefreshNoteSlug(db.DB, note.ID); err != nil {
+		log.Error().Err(err).Str("note_id", note.ID).Msg("Failed to refresh note slug")
+	}
 
 	return map[string]any{
 		"success":      true,
@@ -784,16 +1011,16 @@ func renameNote(clerkUserID string, noteID string, newName string) any {
 }
 
 // deleteNote deletes a note
-func deleteNote(clerkUserID string, noteID string) any {
+func deleteNote(ctx context.Context, clerkUserID string, noteID string) any {
 	// Get note with relationships
 	var note models.Notes
-	err := db.DB.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	err := db.DB.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Note not found")
 		return map[string]string{"error": "Note not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &note.Chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &note.Chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Note not found or access denied"}
 	}
 
@@ -801,7 +1028,15 @@ func deleteNote(clerkUserID string, noteID string) any {
 	chapterName := note.Chapter.Name
 	notebookName := note.Chapter.Notebook.Name
 
-	err = db.DB.Delete(&note).Error
+	if err := services.CheckNoteLegalHold(db.DB.WithContext(ctx), note.ID); err != nil {
+		if errors.Is(err, services.ErrLegalHold) {
+			return map[string]string{"error": "The note's notebook is under legal hold and can't be deleted from"}
+		}
+		log.Error().Err(err).Msg("Failed to check legal hold")
+		return map[string]string{"error": "Failed to delete note"}
+	}
+
+	err = db.DB.WithContext(ctx).Delete(&note).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to delete note")
 		return map[string]string{"error": "Failed to delete note"}
@@ -817,15 +1052,15 @@ func deleteNote(clerkUserID string, noteID string) any {
 }
 
 // updateNoteContent updates the content of a note
-func updateNoteContent(clerkUserID string, noteID string, content string) any {
+func updateNoteContent(ctx context.Context, clerkUserID string, noteID string, content string) any {
 	var note models.Notes
-	err := db.DB.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	err := db.DB.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
 	if err != nil {
 		log.Error().Err(err).Str("noteID", noteID).Msg("Note not found")
 		return map[string]string{"error": "Note not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &note.Chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &note.Chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Note not found or access denied"}
 	}
 
@@ -835,7 +1070,7 @@ func updateNoteContent(clerkUserID string, noteID string, content string) any {
 		return map[string]string{"error": "Failed to convert content format"}
 	}
 
-	result := db.DB.Model(&note).Updates(models.Notes{Content: tiptapContent})
+	result := db.DB.WithContext(ctx).Model(&note).Updates(models.Notes{Content: tiptapContent})
 	if result.Error != nil {
 		log.Error().Err(result.Error).Str("noteID", noteID).Msg("Failed to update note")
 		return map[string]string{"error": "Failed to update note"}
@@ -844,7 +1079,7 @@ func updateNoteContent(clerkUserID string, noteID string, content string) any {
 	yjsService := services.NewYjsService(db.DB)
 	_ = yjsService.DeleteYjsDocument(noteID)
 
-	err = db.DB.Preload("Chapter.Notebook").First(&note, "id = ?", note.ID).Error
+	err = db.DB.WithContext(ctx).Preload("Chapter.Notebook").First(&note, "id = ?", note.ID).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to reload note after update")
 	}
@@ -864,20 +1099,20 @@ func updateNoteContent(clerkUserID string, noteID string, content string) any {
 }
 
 // generateNoteVideo creates video data for a note using AI
-func generateNoteVideo(clerkUserID string, noteID string) any {
+func generateNoteVideo(ctx context.Context, clerkUserID string, noteID string) any {
 	// Get note with relationships
 	var note models.Notes
-	err := db.DB.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	err := db.DB.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
 	if err != nil {
 		log.Error().Err(err).Str("noteID", noteID).Msg("Note not found")
 		return map[string]string{"error": "Note not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &note.Chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &note.Chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Note not found or access denied"}
 	}
 
-	videoData, err := GenerateVideoDataWithAI(clerkUserID, note.Name, note.Content)
+	videoData, err := GenerateVideoDataWithAI(clerkUserID, note.Chapter.Notebook.OrganizationID, note.Name, note.Content)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate AI video, using fallback")
 		// Fallback to simple generation
@@ -910,7 +1145,7 @@ func generateNoteVideo(clerkUserID string, noteID string) any {
 	}
 
 	// Update note with video data
-	result := db.DB.Model(&note).Select("VideoData", "HasVideo").Updates(models.Notes{
+	result := db.DB.WithContext(ctx).Model(&note).Select("VideoData", "HasVideo").Updates(models.Notes{
 		VideoData: string(videoDataJSON),
 		HasVideo:  true,
 	})
@@ -934,20 +1169,20 @@ func generateNoteVideo(clerkUserID string, noteID string) any {
 }
 
 // deleteNoteVideo removes video data from a note
-func deleteNoteVideo(clerkUserID string, noteID string) any {
+func deleteNoteVideo(ctx context.Context, clerkUserID string, noteID string) any {
 	// Get note with relationships
 	var note models.Notes
-	err := db.DB.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	err := db.DB.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
 	if err != nil {
 		log.Error().Err(err).Str("noteID", noteID).Msg("Note not found")
 		return map[string]string{"error": "Note not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &note.Chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &note.Chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Note not found or access denied"}
 	}
 
-	result := db.DB.Model(&note).Select("VideoData", "HasVideo").Updates(map[string]interface{}{
+	result := db.DB.WithContext(ctx).Model(&note).Select("VideoData", "HasVideo").Updates(map[string]interface{}{
 		"video_data": "",
 		"has_video":  false,
 	})
@@ -971,7 +1206,7 @@ func deleteNoteVideo(clerkUserID string, noteID string) any {
 }
 
 // createNotebook creates a new notebook
-func createNotebook(clerkUserID string, organizationID *string, name string) any {
+func createNotebook(ctx context.Context, clerkUserID string, organizationID *string, name string) any {
 	// Create the notebook with appropriate organization context
 	notebook := models.Notebook{
 		Name:           name,
@@ -979,7 +1214,7 @@ func createNotebook(clerkUserID string, organizationID *string, name string) any
 		OrganizationID: organizationID,
 	}
 
-	err := db.DB.Create(&notebook).Error
+	err := db.DB.WithContext(ctx).Create(&notebook).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create notebook")
 		return map[string]string{"error": "Failed to create notebook"}
@@ -995,16 +1230,16 @@ func createNotebook(clerkUserID string, organizationID *string, name string) any
 }
 
 // createChapter creates a new chapter in a notebook
-func createChapter(clerkUserID string, notebookID string, name string) any {
+func createChapter(ctx context.Context, clerkUserID string, notebookID string, name string) any {
 	// Get notebook
 	var notebook models.Notebook
-	err := db.DB.Where("id = ?", notebookID).First(&notebook).Error
+	err := db.DB.WithContext(ctx).Where("id = ?", notebookID).First(&notebook).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Notebook not found")
 		return map[string]string{"error": "Notebook not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &notebook, clerkUserID) {
 		return map[string]string{"error": "Notebook not found or access denied"}
 	}
 
@@ -1014,7 +1249,7 @@ func createChapter(clerkUserID string, notebookID string, name string) any {
 		OrganizationID: notebook.OrganizationID,
 	}
 
-	err = db.DB.Create(&chapter).Error
+	err = db.DB.WithContext(ctx).Create(&chapter).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create chapter")
 		return map[string]string{"error": "Failed to create chapter"}
@@ -1032,22 +1267,22 @@ func createChapter(clerkUserID string, notebookID string, name string) any {
 }
 
 // renameNotebook renames a notebook
-func renameNotebook(clerkUserID string, notebookID string, newName string) any {
+func renameNotebook(ctx context.Context, clerkUserID string, notebookID string, newName string) any {
 	// Get notebook
 	var notebook models.Notebook
-	err := db.DB.Where("id = ?", notebookID).First(&notebook).Error
+	err := db.DB.WithContext(ctx).Where("id = ?", notebookID).First(&notebook).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Notebook not found")
 		return map[string]string{"error": "Notebook not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &notebook, clerkUserID) {
 		return map[string]string{"error": "Notebook not found or access denied"}
 	}
 
 	oldName := notebook.Name
 
-	result := db.DB.Model(&notebook).Select("Name").Updates(models.Notebook{Name: newName})
+	result := db.DB.WithContext(ctx).Model(&notebook).Select("Name").Updates(models.Notebook{Name: newName})
 	if result.Error != nil {
 		log.Error().Err(result.Error).Msg("Failed to rename notebook")
 		return map[string]string{"error": "Failed to rename notebook"}
@@ -1063,26 +1298,29 @@ func renameNotebook(clerkUserID string, notebookID string, newName string) any {
 }
 
 // renameChapter renames a chapter
-func renameChapter(clerkUserID string, chapterID string, newName string) any {
+func renameChapter(ctx context.Context, clerkUserID string, chapterID string, newName string) any {
 	// Get chapter with notebook
 	var chapter models.Chapter
-	err := db.DB.Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error
+	err := db.DB.WithContext(ctx).Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error
 	if err != nil {
 		log.Error().Err(err).Msg("Chapter not found")
 		return map[string]string{"error": "Chapter not found"}
 	}
 
-	if !userCanAccessNotebookChat(context.Background(), &chapter.Notebook, clerkUserID) {
+	if !userCanAccessNotebookChat(ctx, &chapter.Notebook, clerkUserID) {
 		return map[string]string{"error": "Chapter not found or access denied"}
 	}
 
 	oldName := chapter.Name
 
-	result := db.DB.Model(&chapter).Select("Name").Updates(models.Chapter{Name: newName})
+	result := db.DB.WithContext(ctx).Model(&chapter).Select("Name").Updates(models.Chapter{Name: newName})
 	if result.Error != nil {
 		log.Error().Err(result.Error).Msg("Failed to rename chapter")
 		return map[string]string{"error": "Failed to rename chapter"}
 	}
+	if _, err := services.RefreshChapterSlug(db.DB, chapter.ID); err != nil {
+		log.Error().Err(err).Str("chapter_id", chapter.ID).Msg("Failed to refresh chapter slug")
+	}
 
 	return map[string]any{
 		"success":      true,
@@ -1094,196 +1332,679 @@ func renameChapter(clerkUserID string, chapterID string, newName string) any {
 	}
 }
 
-// GenerateRequest represents the request body for the generate endpoint
-type GenerateRequest struct {
-	Prompt  string `json:"prompt"`
-	Option  string `json:"option"`
-	Command string `json:"command"`
+// taskColumns are the Kanban columns a task can be in, keyed by status
+var taskColumns = map[string]string{
+	"backlog":     "Backlog",
+	"todo":        "To Do",
+	"in_progress": "In Progress",
+	"done":        "Done",
 }
 
-// GenerateHandler handles AI text generation requests (improve, fix, continue, etc.)
-func GenerateHandler(c *gin.Context) {
-	ctx := c.Request.Context()
+// listBoards lists the task boards in the current workspace with their tasks
+func listBoards(ctx context.Context, clerkUserID string, organizationID *string, chatScope *services.AIChatScope) any {
+	query := db.DB.WithContext(ctx).Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("status, position")
+	}).Preload("Tasks.Assignments")
 
-	var req GenerateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Error().Err(err).Msg("Failed to parse generate request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
+	var boards []models.TaskBoard
+	var err error
+	if organizationID != nil && *organizationID != "" {
+		err = query.Where("organization_id = ?", *organizationID).Order("updated_at DESC").Find(&boards).Error
+	} else {
+		err = query.Where("clerk_user_id = ? AND organization_id IS NULL", clerkUserID).Order("updated_at DESC").Find(&boards).Error
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list task boards")
+		return map[string]string{"error": "Failed to list task boards"}
 	}
 
-	// Debug logging
-	log.Info().
-		Str("prompt", req.Prompt).
-		Str("option", req.Option).
-		Str("command", req.Command).
-		Int("promptLen", len(req.Prompt)).
-		Msg("Generate request received")
+	// Note-associated boards follow the note's permissions, so check each one
+	results := make([]map[string]any, 0, len(boards))
+	for _, board := range boards {
+		hasAccess, err := CheckTaskBoardAccess(ctx, db.DB, board.ID, clerkUserID)
+		if err != nil || !hasAccess || !chatScope.AllowsBoard(board.ID) {
+			continue
+		}
 
-	// Validate prompt
-	if strings.TrimSpace(req.Prompt) == "" && req.Option != "continue" {
-		log.Warn().Str("option", req.Option).Msg("Empty prompt received")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No text selected. Please select some text to edit."})
-		return
+		tasks := make([]map[string]any, len(board.Tasks))
+		for i, task := range board.Tasks {
+			assignees := make([]string, len(task.Assignments))
+			for j, assignment := range task.Assignments {
+				assignees[j] = assignment.UserID
+			}
+			tasks[i] = map[string]any{
+				"id":        task.ID,
+				"title":     task.Title,
+				"status":    task.Status,
+				"priority":  task.Priority,
+				"assignees": assignees,
+			}
+		}
+
+		results = append(results, map[string]any{
+			"id":          board.ID,
+			"name":        board.Name,
+			"description": board.Description,
+			"noteId":      board.NoteID,
+			"taskCount":   len(board.Tasks),
+			"tasks":       tasks,
+		})
 	}
 
-	// Get user ID from session
-	clerkUserID, exists := middleware.GetClerkUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
+	if len(results) == 0 {
+		return map[string]any{
+			"message": "No task boards found in this workspace.",
+			"count":   0,
+			"boards":  []any{},
+		}
 	}
 
-	// Get user's OpenAI API key (defaulting to OpenAI for text generation)
-	apiKey, err := getUserAPIKey(clerkUserID, "openai")
-	if err != nil {
-		log.Error().Err(err).Str("clerk_user_id", clerkUserID).Msg("Failed to get user API key")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "OpenAI API key not configured. Please set up your API key in settings."})
-		return
+	return map[string]any{
+		"count":   len(results),
+		"boards":  results,
+		"columns": taskColumns,
 	}
+}
 
-	// Construct system and user messages based on option
-	var systemMessage, userMessage string
+// createBoardTask creates a task on a board
+func createBoardTask(ctx context.Context, clerkUserID string, boardID string, title string, description string, status string, priority string) any {
+	hasAccess, err := CheckTaskBoardAccess(ctx, db.DB, boardID, clerkUserID)
+	if err != nil || !hasAccess {
+		return map[string]string{"error": "Task board not found or access denied"}
+	}
 
-	switch req.Option {
-	case "continue":
-		systemMessage = "You are an AI writing assistant that continues existing text based on context from prior text. " +
-			"Give more weight/priority to the later characters than the beginning ones. " +
-			"Limit your response to no more than 200 characters, but make sure to construct complete sentences. " +
-			"Use Markdown formatting when appropriate. " +
-			"Return ONLY the continuation text without any preamble, explanation, or closing remarks."
-		userMessage = req.Prompt
+	if status == "" {
+		status = "todo"
+	}
+	if _, ok := taskColumns[status]; !ok {
+		return map[string]string{"error": "Invalid status. Use one of: backlog, todo, in_progress, done"}
+	}
+	if priority == "" {
+		priority = "medium"
+	}
+	if priority != "low" && priority != "medium" && priority != "high" {
+		return map[string]string{"error": "Invalid priority. Use one of: low, medium, high"}
+	}
 
-	case "improve":
-		systemMessage = "You are an AI writing assistant that improves existing text. " +
-			"Limit your response to no more than 200 characters, but make sure to construct complete sentences. " +
-			"Use Markdown formatting when appropriate. " +
-			"Return ONLY the improved text without any preamble like 'Here is...' or closing remarks. " +
-			"Do not add any conversational filler - just return the enhanced version of the text directly."
-		userMessage = req.Prompt
+	task := models.Task{
+		Title:       title,
+		Description: description,
+		Status:      status,
+		Priority:    priority,
+		Position:    nextTaskPosition(ctx, boardID, status),
+	}
+	if err := createTaskInBoard(ctx, &task, boardID); err != nil {
+		log.Error().Err(err).Str("board_id", boardID).Msg("Failed to create task")
+		return map[string]string{"error": "Failed to create task"}
+	}
 
-	case "shorter":
-		systemMessage = "You are an AI writing assistant that shortens existing text. " +
-			"Use Markdown formatting when appropriate. " +
-			"Return ONLY the shortened text without any preamble like 'Here is...' or closing remarks. " +
-			"Do not add any conversational filler - just return the condensed version directly."
-		userMessage = req.Prompt
+	return map[string]any{
+		"success":  true,
+		"message":  "Task created successfully!",
+		"taskId":   task.ID,
+		"title":    task.Title,
+		"status":   task.Status,
+		"priority": task.Priority,
+		"boardId":  boardID,
+	}
+}
 
-	case "longer":
-		systemMessage = "You are an AI writing assistant that lengthens existing text. " +
-			"Use Markdown formatting when appropriate. " +
-			"Return ONLY the expanded text without any preamble like 'Certainly!' or 'Here is...' or closing remarks like 'This elaboration...'. " +
-			"Do not add any conversational filler or meta-commentary - just return the lengthened version of the text directly."
-		userMessage = req.Prompt
+// moveTaskToColumn moves a task to the end of another Kanban column
+func moveTaskToColumn(ctx context.Context, clerkUserID string, taskID string, column string) any {
+	hasAccess, err := CheckTaskAccess(ctx, db.DB, taskID, clerkUserID)
+	if err != nil || !hasAccess {
+		return map[string]string{"error": "Task not found or access denied"}
+	}
 
-	case "fix":
-		systemMessage = "You are an AI writing assistant that fixes grammar and spelling errors in existing text. " +
-			"Limit your response to no more than 200 characters, but make sure to construct complete sentences. " +
-			"Use Markdown formatting when appropriate. " +
-			"Return ONLY the corrected text without any preamble like 'Here is...' or closing remarks. " +
-			"Do not add any conversational filler - just return the fixed version directly."
-		userMessage = req.Prompt
+	columnName, ok := taskColumns[column]
+	if !ok {
+		return map[string]string{"error": "Invalid column. Use one of: backlog, todo, in_progress, done"}
+	}
 
-	case "zap":
-		systemMessage = "You are an AI writing assistant that generates text based on a prompt. " +
-			"You take an input from the user and a command for manipulating the text. " +
-			"Use Markdown formatting when appropriate. " +
-			"Return ONLY the generated/edited text without any preamble or explanation. " +
-			"Do not add any conversational filler - just return the result directly."
-		userMessage = fmt.Sprintf("For this text: %s. You have to respect the command: %s", req.Prompt, req.Command)
+	var task models.Task
+	if err := db.DB.WithContext(ctx).Where("id = ?", taskID).First(&task).Error; err != nil {
+		return map[string]string{"error": "Task not found"}
+	}
 
-	default:
-		log.Error().Str("option", req.Option).Msg("Invalid option")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid option"})
-		return
+	oldStatus := task.Status
+	if oldStatus == column {
+		return map[string]any{
+			"success": true,
+			"message": fmt.Sprintf("Task is already in %s", columnName),
+			"taskId":  task.ID,
+			"status":  task.Status,
+		}
 	}
 
-	log.Info().
-		Str("systemMessage", systemMessage).
-		Str("userMessage", userMessage).
-		Msg("Messages prepared for OpenAI")
+	err = db.DB.WithContext(ctx).Model(&task).Updates(map[string]any{
+		"status":   column,
+		"position": nextTaskPosition(ctx, task.TaskBoardID, column),
+	}).Error
+	if err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("Failed to move task")
+		return map[string]string{"error": "Failed to move task"}
+	}
+	services.NewAutomationService().TaskStatusChanged(task.ID, oldStatus)
+	services.NewTaskSyncService().QueueSyncForBoard(task.TaskBoardID)
 
-	// IMPORTANT: Set anti-buffering and anti-compression headers FIRST
-	c.Header("X-Accel-Buffering", "no")                 // Disable nginx buffering
-	c.Header("Cache-Control", "no-cache, no-transform") // Prevent caching and transformation
-	c.Header("Connection", "keep-alive")                // Keep connection open
-	c.Header("Content-Encoding", "identity")            // Disable compression (critical for streaming!)
-	c.Header("X-Content-Type-Options", "nosniff")       // Prevent MIME sniffing
+	return map[string]any{
+		"success":   true,
+		"message":   fmt.Sprintf("Task moved to %s", columnName),
+		"taskId":    task.ID,
+		"title":     task.Title,
+		"oldStatus": oldStatus,
+		"newStatus": column,
+	}
+}
 
-	// Set data stream headers (Content-Type, etc.)
-	aisdk.WriteDataStreamHeaders(c.Writer)
+// completeTask moves a task to the Done column
+func completeTask(ctx context.Context, clerkUserID string, taskID string) any {
+	return moveTaskToColumn(ctx, clerkUserID, taskID, "done")
+}
 
-	// Flush headers immediately to start streaming
-	if flusher, ok := c.Writer.(http.Flusher); ok {
-		flusher.Flush()
+// assignTask sets a task's assignees. Assignees may be user IDs, emails, names or "me".
+func assignTask(ctx context.Context, clerkUserID string, taskID string, assignees []string) any {
+	hasAccess, err := CheckTaskAccess(ctx, db.DB, taskID, clerkUserID)
+	if err != nil || !hasAccess {
+		return map[string]string{"error": "Task not found or access denied"}
 	}
 
-	// Create OpenAI messages directly using the SDK
-	client := getOpenAIClient(apiKey)
+	var task models.Task
+	if err := db.DB.WithContext(ctx).Where("id = ?", taskID).First(&task).Error; err != nil {
+		return map[string]string{"error": "Task not found"}
+	}
 
-	// Build messages in OpenAI format directly
-	openaiMessages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(systemMessage),
-		openai.UserMessage(userMessage),
+	userIDs, err := resolveTaskAssignees(ctx, task, clerkUserID, assignees)
+	if err != nil {
+		return map[string]string{"error": err.Error()}
 	}
 
-	log.Info().
-		Int("messageCount", len(openaiMessages)).
-		Msg("OpenAI messages prepared")
-
-	stream := aisdk.OpenAIToDataStream(client.Chat.Completions.NewStreaming(ctx, openai.ChatCompletionNewParams{
-		Model:               openai.ChatModelGPT4oMini,
-		Messages:            openaiMessages,
-		MaxCompletionTokens: openai.Int(4096),
-		Temperature:         openai.Float(0.7),
-		TopP:                openai.Float(1),
-		FrequencyPenalty:    openai.Float(0),
-		PresencePenalty:     openai.Float(0),
-	}))
-
-	if stream == nil {
-		log.Error().Msg("Failed to create stream")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create stream"})
-		return
+	assignments, err := replaceTaskAssignments(ctx, taskID, userIDs)
+	if err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("Failed to assign task")
+		return map[string]string{"error": "Failed to assign task"}
 	}
 
-	// Wrap the writer to auto-flush for real-time streaming
-	flushWriter := &autoFlushWriter{Writer: c.Writer}
+	return map[string]any{
+		"success":   true,
+		"message":   "Task assigned successfully!",
+		"taskId":    task.ID,
+		"title":     task.Title,
+		"assignees": userIDs,
+		"count":     len(assignments),
+	}
+}
 
-	// Pipe the stream to the auto-flushing writer
-	err = stream.Pipe(flushWriter)
-	if err != nil {
-		log.Error().Err(err).Msg("Error piping AI response stream")
+// resolveTaskAssignees maps assignee references to Clerk user IDs.
+// Personal tasks can only be assigned to their owner, organization tasks to organization members.
+func resolveTaskAssignees(ctx context.Context, task models.Task, clerkUserID string, assignees []string) ([]string, error) {
+	if task.OrganizationID == nil || *task.OrganizationID == "" {
+		for _, assignee := range assignees {
+			if !strings.EqualFold(assignee, "me") && assignee != clerkUserID {
+				return nil, fmt.Errorf("personal tasks can only be assigned to yourself")
+			}
+		}
+		if len(assignees) == 0 {
+			return []string{}, nil
+		}
+		return []string{clerkUserID}, nil
+	}
 
-		// Try to extract error message
-		errorMsg := err.Error()
-		userFriendlyMsg := errorMsg
+	params := &organizationmembership.ListParams{}
+	params.Limit = clerk.Int64(100)
+	params.OrganizationID = *task.OrganizationID
+	memberships, err := organizationmembership.List(ctx, params)
+	if err != nil {
+		log.Error().Err(err).Str("org_id", *task.OrganizationID).Msg("Failed to fetch organization members")
+		return nil, fmt.Errorf("failed to look up organization members")
+	}
 
-		// Check if it's an API key error
-		if strings.Contains(errorMsg, "401") || strings.Contains(errorMsg, "Unauthorized") || strings.Contains(errorMsg, "invalid_api_key") || strings.Contains(errorMsg, "Incorrect API key") {
-			userFriendlyMsg = "Invalid API key. Please check your API key in Profile settings and ensure it's correct."
-		} else if strings.Contains(errorMsg, "insufficient_quota") {
-			userFriendlyMsg = "API quota exceeded. Please check your API usage limits."
-		} else if strings.Contains(errorMsg, "rate_limit") {
-			userFriendlyMsg = "Rate limit exceeded. Please try again in a moment."
-		} else {
-			userFriendlyMsg = "An error occurred while processing your request. Please try again."
+	seen := make(map[string]bool)
+	userIDs := make([]string, 0, len(assignees))
+	for _, assignee := range assignees {
+		ref := strings.TrimSpace(assignee)
+		if strings.EqualFold(ref, "me") {
+			ref = clerkUserID
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": userFriendlyMsg})
-		return
+		var matched string
+		for _, membership := range memberships.OrganizationMemberships {
+			member := membership.PublicUserData
+			if member == nil {
+				continue
+			}
+			if member.UserID == ref || strings.EqualFold(member.Identifier, ref) || strings.EqualFold(getDisplayName(member), ref) {
+				matched = member.UserID
+				break
+			}
+		}
+		if matched == "" {
+			return nil, fmt.Errorf("%q is not a member of this organization", assignee)
+		}
+		if !seen[matched] {
+			seen[matched] = true
+			userIDs = append(userIDs, matched)
+		}
 	}
 
-	log.Info().Str("option", req.Option).Msg("Generate request completed successfully")
+	return userIDs, nil
 }
 
-// DumpHandler dumps the last messages to a JSON file
-func DumpHandler(c *gin.Context) {
-	data, _ := json.MarshalIndent(lastMessages, "", "  ")
-	err := os.WriteFile("dump.json", data, 0644)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to write dump file")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dump messages"})
+// nextTaskPosition returns the position after the last task in a board column
+func nextTaskPosition(ctx context.Context, boardID string, status string) int {
+	var maxPosition *int
+	db.DB.WithContext(ctx).Model(&models.Task{}).
+		Where("task_board_id = ? AND status = ?", boardID, status).
+		Select("MAX(position)").
+		Scan(&maxPosition)
+	if maxPosition == nil {
+		return 0
+	}
+	return *maxPosition + 1
+}
+
+// listUpcomingMeetings lists meetings from the user's connected calendars in the next few days
+func listUpcomingMeetings(clerkUserID string, days int) any {
+	if days <= 0 {
+		days = 7
+	}
+	if days > 30 {
+		days = 30
+	}
+
+	events, err := services.NewCalendarSchedulerService().ListUpcomingEvents(clerkUserID, time.Now().AddDate(0, 0, days))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list upcoming meetings")
+		return map[string]string{"error": "Failed to list upcoming meetings"}
+	}
+
+	if len(events) == 0 {
+		return map[string]any{
+			"message":  fmt.Sprintf("No meetings in the next %d days. The user may need to connect a calendar.", days),
+			"count":    0,
+			"meetings": []any{},
+		}
+	}
+
+	// Times are in the user's timezone so "today" and "tomorrow" mean what they do to the user
+	location := services.UserLocation(context.Background(), db.DB, clerkUserID)
+	results := make([]map[string]any, len(events))
+	for i, event := range events {
+		results[i] = map[string]any{
+			"id":              event.ID,
+			"title":           event.Title,
+			"startTime":       event.StartTime.In(location).Format(time.RFC3339),
+			"endTime":         event.EndTime.In(location).Format(time.RFC3339),
+			"meetingPlatform": event.MeetingPlatform,
+			"hasMeetingLink":  event.MeetingURL != "",
+			"botScheduled":    event.BotScheduled,
+		}
+		addMeetingAgenda(clerkUserID, event.AgendaNote, results[i])
+	}
+
+	return map[string]any{
+		"count":    len(results),
+		"meetings": results,
+	}
+}
+
+// maxAgendaContentLength caps the agenda note content returned with each upcoming meeting
+const maxAgendaContentLength = 2000
+
+// addMeetingAgenda adds the agenda note linked to a meeting to its listing, for briefing the user
+// before it. The content is left out of notes the user can no longer read and of locked notes.
+func addMeetingAgenda(clerkUserID string, agenda *models.Notes, result map[string]any) {
+	if agenda == nil {
+		return
+	}
+	access, err := middleware.GetNoteAccess(context.Background(), db.DB, agenda.ID, clerkUserID)
+	if err != nil || access < middleware.NoteAccessCanView {
+		return
+	}
+
+	result["agendaNoteId"] = agenda.ID
+	result["agendaNoteName"] = agenda.Name
+	if agenda.Locked {
+		return
+	}
+	content := agenda.Content
+	if strings.HasPrefix(strings.TrimSpace(content), "{") {
+		if markdown, err := internalutils.TipTapToMarkdown(content); err == nil {
+			content = markdown
+		}
+	}
+	if len(content) > maxAgendaContentLength {
+		content = content[:maxAgendaContentLength] + "\n\n[Content truncated]"
+	}
+	result["agenda"] = content
+}
+
+// scheduleBotForMeeting sends a recording bot to a calendar event, or straight into a meeting by URL
+func scheduleBotForMeeting(clerkUserID string, eventID string, meetingURL string) any {
+	if meetingURL != "" {
+		if !isValidMeetingURL(meetingURL) {
+			return map[string]string{"error": "Invalid meeting URL"}
+		}
+
+		recording, err := services.NewMeetingService().StartMeetingRecording(context.Background(), clerkUserID, meetingURL)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to start meeting recording")
+			return map[string]string{"error": "Failed to send the recording bot to the meeting"}
+		}
+
+		return map[string]any{
+			"success":     true,
+			"message":     "Recording bot is joining the meeting now. A note will be created when the meeting ends.",
+			"recordingId": recording.ID,
+		}
+	}
+
+	if eventID == "" {
+		return map[string]string{"error": "Provide either an eventId or a meetingUrl"}
+	}
+
+	event, err := services.NewCalendarSchedulerService().ScheduleBotForEvent(clerkUserID, eventID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCalendarEventNotFound):
+			return map[string]string{"error": "Meeting not found"}
+		case errors.Is(err, services.ErrBotAlreadyScheduled):
+			return map[string]any{
+				"success": true,
+				"message": "A recording bot is already scheduled for this meeting",
+				"eventId": eventID,
+			}
+		}
+		log.Error().Err(err).Str("event_id", eventID).Msg("Failed to schedule bot for meeting")
+		return map[string]string{"error": "Failed to schedule the recording bot"}
+	}
+
+	return map[string]any{
+		"success":   true,
+		"message":   "Recording bot scheduled. It will join when the meeting starts and a note will be created afterwards.",
+		"eventId":   event.ID,
+		"title":     event.Title,
+		"startTime": event.StartTime.UTC().Format(time.RFC3339),
+	}
+}
+
+// currentTimePrompt tells the model the current time in the user's timezone, for reading dates the user
+// gives relative to today
+func currentTimePrompt(ctx context.Context, clerkUserID string) string {
+	location := services.UserLocation(ctx, db.DB, clerkUserID)
+	return fmt.Sprintf("\n\nThe current time is %s. The user's timezone is %s.", time.Now().In(location).Format(time.RFC1123), services.TimezoneName(location))
+}
+
+// maxMeetingContentLength caps the meeting note content returned to the model
+const maxMeetingContentLength = 6000
+
+// getMeetingSummary finds recorded meetings by title and/or date and returns their AI summaries
+func getMeetingSummary(clerkUserID string, query string, date string) any {
+	var from, to *time.Time
+	if date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, services.UserLocation(context.Background(), db.DB, clerkUserID))
+		if err != nil {
+			return map[string]string{"error": "Invalid date, use YYYY-MM-DD"}
+		}
+		end := day.AddDate(0, 0, 1)
+		from, to = &day, &end
+	}
+
+	meetings, err := services.NewMeetingService().FindMeetingNotes(clerkUserID, query, from, to, 3)
+	if err != nil {
+		return map[string]string{"error": "Failed to search meetings"}
+	}
+
+	if len(meetings) == 0 {
+		return map[string]any{
+			"message":  "No recorded meetings matched. Only meetings recorded by the Atlas bot have summaries.",
+			"count":    0,
+			"meetings": []any{},
+		}
+	}
+
+	results := make([]map[string]any, len(meetings))
+	for i, meeting := range meetings {
+		content := meeting.Content
+		if strings.HasPrefix(strings.TrimSpace(content), "{") {
+			if markdown, err := internalutils.TipTapToMarkdown(content); err == nil {
+				content = markdown
+			}
+		}
+		if len(content) > maxMeetingContentLength {
+			content = content[:maxMeetingContentLength] + "\n\n[Content truncated]"
+		}
+
+		results[i] = map[string]any{
+			"noteId":       meeting.NoteID,
+			"noteName":     meeting.NoteName,
+			"meetingTitle": meeting.MeetingTitle,
+			"recordedAt":   meeting.RecordedAt.UTC().Format(time.RFC3339),
+			"summary":      meeting.Summary,
+			"content":      content,
+		}
+	}
+
+	return map[string]any{
+		"count":    len(results),
+		"meetings": results,
+	}
+}
+
+// GenerateRequest represents the request body for the generate endpoint
+type GenerateRequest struct {
+	Prompt         string  `json:"prompt"`
+	Option         string  `json:"option"`
+	Command        string  `json:"command"`
+	Provider       string  `json:"provider,omitempty"`       // Optional, defaults to the user's settings
+	Model          string  `json:"model,omitempty"`          // Optional, defaults to the provider's default model
+	OrganizationID *string `json:"organizationId,omitempty"` // Optional organization context
+}
+
+// GenerateHandler handles AI text generation requests (improve, fix, continue, etc.)
+func GenerateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req GenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Error().Err(err).Msg("Failed to parse generate request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Debug logging
+	log.Info().
+		Str("prompt", req.Prompt).
+		Str("option", req.Option).
+		Str("command", req.Command).
+		Int("promptLen", len(req.Prompt)).
+		Msg("Generate request received")
+
+	// Validate prompt
+	if strings.TrimSpace(req.Prompt) == "" && req.Option != "continue" {
+		log.Warn().Str("option", req.Option).Msg("Empty prompt received")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No text selected. Please select some text to edit."})
+		return
+	}
+
+	// Get user ID from session
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Use the requested provider/model, falling back to the saved defaults
+	policy := services.GetAIProviderPolicy()
+	provider, model := resolveRequestModel(policy, clerkUserID, req.OrganizationID, models.AIModelPurposeGenerate, req.Provider, req.Model)
+	if !config.IsSupportedAIProvider(provider) {
+		log.Error().Str("provider", provider).Msg("Invalid provider")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider"})
+		return
+	}
+
+	// Get user's API key for the provider with organization context
+	apiKey, err := getUserAPIKeyWithOrg(clerkUserID, req.OrganizationID, provider)
+	if err != nil {
+		log.Error().Err(err).Str("provider", provider).Str("clerk_user_id", clerkUserID).Msg("Failed to get user API key")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "API key not configured for this provider. Please set up your API key in settings."})
+		return
+	}
+
+	// Construct system and user messages based on option
+	var systemMessage, userMessage string
+
+	switch req.Option {
+	case "continue":
+		systemMessage = "You are an AI writing assistant that continues existing text based on context from prior text. " +
+			"Give more weight/priority to the later characters than the beginning ones. " +
+			"Limit your response to no more than 200 characters, but make sure to construct complete sentences. " +
+			"Use Markdown formatting when appropriate. " +
+			"Return ONLY the continuation text without any preamble, explanation, or closing remarks."
+		userMessage = req.Prompt
+
+	case "improve":
+		systemMessage = "You are an AI writing assistant that improves existing text. " +
+			"Limit your response to no more than 200 characters, but make sure to construct complete sentences. " +
+			"Use Markdown formatting when appropriate. " +
+			"Return ONLY the improved text without any preamble like 'Here is...' or closing remarks. " +
+			"Do not add any conversational filler - just return the enhanced version of the text directly."
+		userMessage = req.Prompt
+
+	case "shorter":
+		systemMessage = "You are an AI writing assistant that shortens existing text. " +
+			"Use Markdown formatting when appropriate. " +
+			"Return ONLY the shortened text without any preamble like 'Here is...' or closing remarks. " +
+			"Do not add any conversational filler - just return the condensed version directly."
+		userMessage = req.Prompt
+
+	case "longer":
+		systemMessage = "You are an AI writing assistant that lengthens existing text. " +
+			"Use Markdown formatting when appropriate. " +
+			"Return ONLY the expanded text without any preamble like 'Certainly!' or 'Here is...' or closing remarks like 'This elaboration...'. " +
+			"Do not add any conversational filler or meta-commentary - just return the lengthened version of the text directly."
+		userMessage = req.Prompt
+
+	case "fix":
+		systemMessage = "You are an AI writing assistant that fixes grammar and spelling errors in existing text. " +
+			"Limit your response to no more than 200 characters, but make sure to construct complete sentences. " +
+			"Use Markdown formatting when appropriate. " +
+			"Return ONLY the corrected text without any preamble like 'Here is...' or closing remarks. " +
+			"Do not add any conversational filler - just return the fixed version directly."
+		userMessage = req.Prompt
+
+	case "zap":
+		systemMessage = "You are an AI writing assistant that generates text based on a prompt. " +
+			"You take an input from the user and a command for manipulating the text. " +
+			"Use Markdown formatting when appropriate. " +
+			"Return ONLY the generated/edited text without any preamble or explanation. " +
+			"Do not add any conversational filler - just return the result directly."
+		userMessage = fmt.Sprintf("For this text: %s. You have to respect the command: %s", req.Prompt, req.Command)
+
+	default:
+		log.Error().Str("option", req.Option).Msg("Invalid option")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid option"})
+		return
+	}
+	// Text written in an organization follows its style guide
+	systemMessage += services.NewNoteLintService().StyleGuidePrompt(ctx, req.OrganizationID)
+
+	log.Info().
+		Str("systemMessage", systemMessage).
+		Str("userMessage", userMessage).
+		Str("provider", provider).
+		Str("model", model).
+		Msg("Messages prepared for AI generation")
+
+	// IMPORTANT: Set anti-buffering and anti-compression headers FIRST
+	c.Header("X-Accel-Buffering", "no")                 // Disable nginx buffering
+	c.Header("Cache-Control", "no-cache, no-transform") // Prevent caching and transformation
+	c.Header("Connection", "keep-alive")                // Keep connection open
+	c.Header("Content-Encoding", "identity")            // Disable compression (critical for streaming!)
+	c.Header("X-Content-Type-Options", "nosniff")       // Prevent MIME sniffing
+
+	// Set data stream headers (Content-Type, etc.)
+	aisdk.WriteDataStreamHeaders(c.Writer)
+
+	// Flush headers immediately to start streaming
+	if flusher, ok := c.Writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	// Reuse the chat streaming path so every provider and the fallback chain are supported
+	chatReq := ChatRequest{
+		Chat: aisdk.Chat{
+			Messages: []aisdk.Message{
+				{Role: "system", Content: systemMessage},
+				{Role: "user", Content: userMessage, Parts: []aisdk.Part{{Type: aisdk.PartTypeText, Text: userMessage}}},
+			},
+		},
+		Provider:       provider,
+		Model:          model,
+		OrganizationID: req.OrganizationID,
+	}
+	candidates := buildChatProviderCandidates(policy, clerkUserID, chatReq, apiKey)
+
+	stream, err := openChatStream(ctx, policy, candidates, chatReq, nil, generateMaxTokens)
+	if err == nil {
+		// Wrap the writer to auto-flush for real-time streaming
+		flushWriter := &autoFlushWriter{Writer: c.Writer}
+
+		// Pipe the stream to the auto-flushing writer
+		err = stream.Pipe(flushWriter)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Error streaming AI generation response")
+
+		// Try to extract error message
+		errorMsg := err.Error()
+		userFriendlyMsg := errorMsg
+
+		// Check if it's an API key error
+		if strings.Contains(errorMsg, "401") || strings.Contains(errorMsg, "Unauthorized") || strings.Contains(errorMsg, "invalid_api_key") || strings.Contains(errorMsg, "Incorrect API key") {
+			userFriendlyMsg = "Invalid API key. Please check your API key in Profile settings and ensure it's correct."
+		} else if strings.Contains(errorMsg, "insufficient_quota") {
+			userFriendlyMsg = "API quota exceeded. Please check your API usage limits."
+		} else if strings.Contains(errorMsg, "rate_limit") {
+			userFriendlyMsg = "Rate limit exceeded. Please try again in a moment."
+		} else {
+			userFriendlyMsg = "An error occurred while processing your request. Please try again."
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": userFriendlyMsg})
+		return
+	}
+
+	log.Info().Str("option", req.Option).Msg("Generate request completed successfully")
+}
+
+// resolveRequestModel picks the provider and model for a request.
+// An explicit provider in the request wins, otherwise the organization's or user's saved default for the purpose applies.
+func resolveRequestModel(policy *services.AIProviderPolicy, clerkUserID string, organizationID *string, purpose, provider, model string) (string, string) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	model = strings.TrimSpace(model)
+
+	if provider == "" {
+		selection := services.NewAIModelSettingsService().ResolveModel(clerkUserID, organizationID, purpose)
+		provider = selection.Provider
+		if model == "" {
+			model = selection.Model
+		}
+	}
+	if model == "" {
+		model = policy.DefaultModel(provider)
+	}
+
+	return provider, model
+}
+
+// DumpHandler dumps the last messages to a JSON file
+func DumpHandler(c *gin.Context) {
+	data, _ := json.MarshalIndent(lastMessages, "", "  ")
+	err := os.WriteFile("dump.json", data, 0644)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to write dump file")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dump messages"})
 		return
 	}
 	log.Info().Msg("Dumped messages to dump.json")
@@ -1308,6 +2029,36 @@ func ChatHandler(c *gin.Context) {
 		return
 	}
 
+	// Focus the conversation on one notebook or chapter when the request asks for it
+	chatScope, ok := resolveChatScope(c, req, clerkUserID)
+	if !ok {
+		return
+	}
+
+	// Conversations started from a note get its content as context
+	chatNote, ok := resolveChatNote(c, req, clerkUserID, chatScope)
+	if !ok {
+		return
+	}
+
+	// Messages in organization workspaces are scanned for secrets and personal data before they reach the model
+	if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == "user" {
+		message := req.Messages[n-1].Content
+		moderation, ok := checkModeration(c, req.OrganizationID, message)
+		if !ok {
+			return
+		}
+		recordModeration(req.OrganizationID, services.ModerationTarget{Source: models.ModerationSourceChat, ClerkUserID: clerkUserID}, moderation, message)
+	}
+
+	// Use the requested provider/model, falling back to the saved defaults
+	req.Provider, req.Model = resolveRequestModel(services.GetAIProviderPolicy(), clerkUserID, req.OrganizationID, models.AIModelPurposeChat, req.Provider, req.Model)
+	if !config.IsSupportedAIProvider(req.Provider) {
+		log.Error().Str("provider", req.Provider).Msg("Invalid provider")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider"})
+		return
+	}
+
 	// Get user's API key for the requested provider with organization context
 	apiKey, err := getUserAPIKeyWithOrg(clerkUserID, req.OrganizationID, req.Provider)
 	if err != nil {
@@ -1335,6 +2086,10 @@ func ChatHandler(c *gin.Context) {
 		return
 	}
 
+	// Fall back to other configured providers the user has keys for if the requested one is unavailable
+	policy := services.GetAIProviderPolicy()
+	candidates := buildChatProviderCandidates(policy, clerkUserID, req, apiKey)
+
 	// Define tools for Atlas
 	tools := []aisdk.Tool{
 		{
@@ -1347,6 +2102,11 @@ func ChatHandler(c *gin.Context) {
 						"type":        "string",
 						"description": "The search query to find in notes",
 					},
+					"where": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "Optional note property filters, e.g. [\"status = reading\", \"pages >= 100\", \"due < 2025-01-01\"]",
+					},
 				},
 			},
 		},
@@ -1447,6 +2207,23 @@ func ChatHandler(c *gin.Context) {
 				},
 			},
 		},
+		{
+			Name:        "getNoteContentPage",
+			Description: "Get a further page of a long note's content. Use this when getNoteContent returns a nextPage, passing that page number.",
+			Schema: aisdk.Schema{
+				Required: []string{"noteId", "page"},
+				Properties: map[string]any{
+					"noteId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the note to read",
+					},
+					"page": map[string]any{
+						"type":        "integer",
+						"description": "The page to read, starting from 1",
+					},
+				},
+			},
+		},
 		{
 			Name:        "listNotesInChapter",
 			Description: "List all notes in a specific chapter. Returns note IDs, names, and preview of content.",
@@ -1470,129 +2247,292 @@ func ChatHandler(c *gin.Context) {
 						"type":        "string",
 						"description": "The ID of the chapter to create the note in",
 					},
-					"title": map[string]any{
+					"title": map[string]any{
+						"type":        "string",
+						"description": "The title of the note",
+					},
+					"content": map[string]any{
+						"type":        "string",
+						"description": "Optional: The initial markdown content of the note. Can be left empty and populated later with updateNoteContent.",
+					},
+				},
+			},
+		},
+		{
+			Name:        "moveNote",
+			Description: "Move a note to a different chapter. Use this when the user wants to reorganize their notes.",
+			Schema: aisdk.Schema{
+				Required: []string{"noteId", "targetChapterId"},
+				Properties: map[string]any{
+					"noteId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the note to move",
+					},
+					"targetChapterId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the chapter to move the note to",
+					},
+				},
+			},
+		},
+		{
+			Name:        "moveChapter",
+			Description: "Move an entire chapter (with all its notes) to a different notebook. Use this when the user wants to reorganize chapters between notebooks.",
+			Schema: aisdk.Schema{
+				Required: []string{"chapterId", "targetNotebookId"},
+				Properties: map[string]any{
+					"chapterId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the chapter to move",
+					},
+					"targetNotebookId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the notebook to move the chapter to",
+					},
+				},
+			},
+		},
+		{
+			Name:        "renameNote",
+			Description: "Rename a note. Use this when the user wants to change a note's title.",
+			Schema: aisdk.Schema{
+				Required: []string{"noteId", "newName"},
+				Properties: map[string]any{
+					"noteId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the note to rename",
+					},
+					"newName": map[string]any{
+						"type":        "string",
+						"description": "The new name for the note",
+					},
+				},
+			},
+		},
+		{
+			Name:        "deleteNote",
+			Description: "Delete a note permanently. Use this when the user explicitly wants to remove a note.",
+			Schema: aisdk.Schema{
+				Required: []string{"noteId"},
+				Properties: map[string]any{
+					"noteId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the note to delete",
+					},
+				},
+			},
+		},
+		{
+			Name:        "updateNoteContent",
+			Description: "Update and save the content of an existing note. REQUIRED: You must call this tool whenever the user asks to update, modify, edit, or change a note's content. First call getNoteContent to read the current content, then call this tool with the complete updated markdown content to save it permanently. This is the ONLY way to save changes to a note.",
+			Schema: aisdk.Schema{
+				Required: []string{"noteId", "content"},
+				Properties: map[string]any{
+					"noteId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the note to update",
+					},
+					"content": map[string]any{
+						"type":        "string",
+						"description": "The complete new markdown content for the note. This will replace the entire note content.",
+					},
+				},
+			},
+		},
+		{
+			Name:        "generateNoteVideo",
+			Description: "Generate a short explanatory video for an existing note based on its title and content. The video will be created automatically and can be viewed in the note editor. Use this when the user wants to create a video explanation for their note content.",
+			Schema: aisdk.Schema{
+				Required: []string{"noteId"},
+				Properties: map[string]any{
+					"noteId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the note to generate a video for",
+					},
+				},
+			},
+		},
+		{
+			Name:        "deleteNoteVideo",
+			Description: "Remove the video from a note. Use this when the user wants to delete an existing video from their note.",
+			Schema: aisdk.Schema{
+				Required: []string{"noteId"},
+				Properties: map[string]any{
+					"noteId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the note to remove the video from",
+					},
+				},
+			},
+		},
+		{
+			Name:        "listBoards",
+			Description: "List all task boards (Kanban boards) in the current workspace with their tasks, statuses, priorities and assignees. Use this to find board and task IDs before managing tasks.",
+			Schema: aisdk.Schema{
+				Required:   []string{},
+				Properties: map[string]any{},
+			},
+		},
+		{
+			Name:        "createTask",
+			Description: "Create a new task on a task board. If the user doesn't specify a board, list available boards first.",
+			Schema: aisdk.Schema{
+				Required: []string{"boardId", "title"},
+				Properties: map[string]any{
+					"boardId": map[string]any{
+						"type":        "string",
+						"description": "The ID of the task board to add the task to",
+					},
+					"title": map[string]any{
+						"type":        "string",
+						"description": "A short, actionable task title",
+					},
+					"description": map[string]any{
+						"type":        "string",
+						"description": "Optional: More detail about the task",
+					},
+					"status": map[string]any{
 						"type":        "string",
-						"description": "The title of the note",
+						"enum":        []string{"backlog", "todo", "in_progress", "done"},
+						"description": "Optional: The column to create the task in, defaults to todo",
 					},
-					"content": map[string]any{
+					"priority": map[string]any{
 						"type":        "string",
-						"description": "Optional: The initial markdown content of the note. Can be left empty and populated later with updateNoteContent.",
+						"enum":        []string{"low", "medium", "high"},
+						"description": "Optional: The task priority, defaults to medium",
 					},
 				},
 			},
 		},
 		{
-			Name:        "moveNote",
-			Description: "Move a note to a different chapter. Use this when the user wants to reorganize their notes.",
+			Name:        "moveTaskToColumn",
+			Description: "Move a task to a different column on its Kanban board, e.g. to start working on it or put it back in the backlog.",
 			Schema: aisdk.Schema{
-				Required: []string{"noteId", "targetChapterId"},
+				Required: []string{"taskId", "column"},
 				Properties: map[string]any{
-					"noteId": map[string]any{
+					"taskId": map[string]any{
 						"type":        "string",
-						"description": "The ID of the note to move",
+						"description": "The ID of the task to move",
 					},
-					"targetChapterId": map[string]any{
+					"column": map[string]any{
 						"type":        "string",
-						"description": "The ID of the chapter to move the note to",
+						"enum":        []string{"backlog", "todo", "in_progress", "done"},
+						"description": "The column to move the task to",
 					},
 				},
 			},
 		},
 		{
-			Name:        "moveChapter",
-			Description: "Move an entire chapter (with all its notes) to a different notebook. Use this when the user wants to reorganize chapters between notebooks.",
+			Name:        "assignTask",
+			Description: "Set who a task is assigned to, replacing any existing assignees. In an organization workspace tasks can be assigned to any member; personal tasks can only be assigned to the user. Pass an empty list to unassign everyone.",
 			Schema: aisdk.Schema{
-				Required: []string{"chapterId", "targetNotebookId"},
+				Required: []string{"taskId", "assignees"},
 				Properties: map[string]any{
-					"chapterId": map[string]any{
+					"taskId": map[string]any{
 						"type":        "string",
-						"description": "The ID of the chapter to move",
+						"description": "The ID of the task to assign",
 					},
-					"targetNotebookId": map[string]any{
-						"type":        "string",
-						"description": "The ID of the notebook to move the chapter to",
+					"assignees": map[string]any{
+						"type":        "array",
+						"items":       map[string]any{"type": "string"},
+						"description": "The people to assign, as user IDs, email addresses, full names, or \"me\" for the current user",
 					},
 				},
 			},
 		},
 		{
-			Name:        "renameNote",
-			Description: "Rename a note. Use this when the user wants to change a note's title.",
+			Name:        "completeTask",
+			Description: "Mark a task as done by moving it to the Done column.",
 			Schema: aisdk.Schema{
-				Required: []string{"noteId", "newName"},
+				Required: []string{"taskId"},
 				Properties: map[string]any{
-					"noteId": map[string]any{
-						"type":        "string",
-						"description": "The ID of the note to rename",
-					},
-					"newName": map[string]any{
+					"taskId": map[string]any{
 						"type":        "string",
-						"description": "The new name for the note",
+						"description": "The ID of the task to complete",
 					},
 				},
 			},
 		},
 		{
-			Name:        "deleteNote",
-			Description: "Delete a note permanently. Use this when the user explicitly wants to remove a note.",
+			Name:        "listUpcomingMeetings",
+			Description: "List upcoming meetings from the user's connected calendars, including whether a recording bot is scheduled and the agenda note linked to prepare for each. Times are in UTC. Use the agenda to brief the user before a meeting. Use this to find the event ID before scheduling a recording bot.",
 			Schema: aisdk.Schema{
-				Required: []string{"noteId"},
+				Required: []string{},
 				Properties: map[string]any{
-					"noteId": map[string]any{
-						"type":        "string",
-						"description": "The ID of the note to delete",
+					"days": map[string]any{
+						"type":        "number",
+						"description": "Optional: How many days ahead to look, defaults to 7 (max 30)",
 					},
 				},
 			},
 		},
 		{
-			Name:        "updateNoteContent",
-			Description: "Update and save the content of an existing note. REQUIRED: You must call this tool whenever the user asks to update, modify, edit, or change a note's content. First call getNoteContent to read the current content, then call this tool with the complete updated markdown content to save it permanently. This is the ONLY way to save changes to a note.",
+			Name:        "scheduleBotForMeeting",
+			Description: "Send the Atlas recording bot to a meeting so it is transcribed and turned into a note. Pass the eventId of a calendar meeting (from listUpcomingMeetings), or a meetingUrl to have the bot join a meeting right now.",
 			Schema: aisdk.Schema{
-				Required: []string{"noteId", "content"},
+				Required: []string{},
 				Properties: map[string]any{
-					"noteId": map[string]any{
+					"eventId": map[string]any{
 						"type":        "string",
-						"description": "The ID of the note to update",
+						"description": "The ID of the calendar meeting to record",
 					},
-					"content": map[string]any{
+					"meetingUrl": map[string]any{
 						"type":        "string",
-						"description": "The complete new markdown content for the note. This will replace the entire note content.",
+						"description": "A Zoom, Google Meet or Teams link to join immediately",
 					},
 				},
 			},
 		},
 		{
-			Name:        "generateNoteVideo",
-			Description: "Generate a short explanatory video for an existing note based on its title and content. The video will be created automatically and can be viewed in the note editor. Use this when the user wants to create a video explanation for their note content.",
+			Name:        "getMeetingSummary",
+			Description: "Look up recorded meetings by title and/or date and return their summaries, key points, decisions and action items. Use this for questions like \"what was decided in yesterday's design review\".",
 			Schema: aisdk.Schema{
-				Required: []string{"noteId"},
+				Required: []string{},
 				Properties: map[string]any{
-					"noteId": map[string]any{
+					"query": map[string]any{
 						"type":        "string",
-						"description": "The ID of the note to generate a video for",
+						"description": "Words from the meeting or note title, e.g. \"design review\"",
 					},
-				},
-			},
-		},
-		{
-			Name:        "deleteNoteVideo",
-			Description: "Remove the video from a note. Use this when the user wants to delete an existing video from their note.",
-			Schema: aisdk.Schema{
-				Required: []string{"noteId"},
-				Properties: map[string]any{
-					"noteId": map[string]any{
+					"date": map[string]any{
 						"type":        "string",
-						"description": "The ID of the note to remove the video from",
+						"description": "Optional: The day the meeting took place, as YYYY-MM-DD (UTC)",
 					},
 				},
 			},
 		},
 	}
 
+	// Only offer the model the tools the user and organization allow
+	toolScope := services.NewAIToolPermissionService().ResolveScope(clerkUserID, req.OrganizationID)
+	tools = filterToolsByScope(tools, toolScope)
+	if chatScope != nil {
+		scopedTools := make([]aisdk.Tool, 0, len(tools))
+		for _, tool := range tools {
+			if chatScope.OffersTool(tool.Name) {
+				scopedTools = append(scopedTools, tool)
+			}
+		}
+		tools = scopedTools
+	}
+
+	// Keep tool results and the conversation within the smallest context window of the providers that
+	// may serve the chat
+	budget := chatTokenBudget(candidates)
+
+	// Register the run so the user can follow and cancel its tool calls
+	runs := services.GetChatRunRegistry()
+	run := runs.Start(clerkUserID)
+	defer runs.Finish(run.ID)
+
 	// Tool handler
 	handleToolCall := func(toolCall aisdk.ToolCall) any {
-		return handleNotesToolCall(toolCall, clerkUserID, req.OrganizationID)
+		step, ok := run.BeginToolCall(toolCall.ID, toolCall.Name)
+		if !ok {
+			return map[string]string{"error": "The user cancelled this run, so the tool was not executed. Don't call any more tools; summarize what was done so far."}
+		}
+		result := handleNotesToolCall(ctx, toolCall, clerkUserID, req.OrganizationID, toolScope, chatScope, budget)
+		run.FinishToolCall(step, toolResultError(result))
+		return services.TruncateToolResult(result, budget.ToolResult)
 	}
 
 	// IMPORTANT: Set anti-buffering and anti-compression headers FIRST
@@ -1602,6 +2542,8 @@ func ChatHandler(c *gin.Context) {
 	c.Header("Content-Encoding", "identity")            // Disable compression (critical for streaming!)
 	c.Header("X-Content-Type-Options", "nosniff")       // Prevent MIME sniffing
 
+	c.Header("X-Chat-Run-ID", run.ID)
+
 	// Set data stream headers (Content-Type, etc.)
 	aisdk.WriteDataStreamHeaders(c.Writer)
 
@@ -1610,7 +2552,12 @@ func ChatHandler(c *gin.Context) {
 		flusher.Flush()
 	}
 
-	// Add system message if not present
+	// Wrap the writer to auto-flush for real-time streaming
+	flushWriter := &autoFlushWriter{Writer: c.Writer}
+	writeChatRunEvent(flushWriter, services.ChatRunEvent{Type: services.ChatRunEventStarted, RunID: run.ID})
+
+	// Add system message if not present. The prompt is managed in the system prompt store, so it can be
+	// customized per organization and changed without a deploy.
 	if len(req.Messages) == 0 || req.Messages[0].Role != "system" {
 		contextInfo := "personal workspace"
 		if req.OrganizationID != nil && *req.OrganizationID != "" {
@@ -1619,150 +2566,503 @@ func ChatHandler(c *gin.Context) {
 
 		req.Messages = append([]aisdk.Message{{
 			Role:    "system",
-			Content: fmt.Sprintf("You are a helpful AI assistant integrated into Atlas, a knowledge management application. You are currently operating in the user's %s. You have access to tools that can search, list, retrieve, create, update, move, rename, delete notes, chapters, and notebooks, and manage videos within this context.\n\nIMPORTANT: All operations will be scoped to the current workspace context (%s). You will only see and interact with notes, chapters, and notebooks that belong to this workspace.\n\nAvailable tools:\n- searchNotes: Search through all notes by content or title in the current workspace\n- listNotebooks: List all notebooks in the current workspace\n- listChapters: List chapters in a notebook\n- listNotesInChapter: List notes in a chapter\n- getNoteContent: Get the full content of a specific note\n- createNotebook: Create a new notebook in the current workspace\n- createChapter: Create a new chapter in a notebook\n- createNote: Create a new note with markdown content in a chapter\n- moveNote: Move a note to a different chapter\n- moveChapter: Move an entire chapter (with all its notes) to a different notebook\n- renameNotebook: Rename a notebook\n- renameChapter: Rename a chapter\n- renameNote: Rename a note\n- updateNoteContent: Update the content of an existing note\n- deleteNote: Delete a note permanently\n- generateNoteVideo: Generate a short explanatory video for a note based on its content\n- deleteNoteVideo: Remove a video from a note\n\nWhen managing notes and chapters:\n1. For create/move operations: If the user doesn't specify which chapter/notebook, list available options first\n2. For moving chapters: Use moveChapter to move entire chapters between notebooks in one operation\n3. For delete operations: Confirm the user really wants to delete before executing\n4. For rename operations: Keep the name concise and descriptive\n5. When creating/updating content: Generate high-quality markdown with proper formatting, then IMMEDIATELY call the appropriate tool (createNote or updateNoteContent) to save it\n6. IMPORTANT: If user asks to update/modify/edit note content, you MUST call getNoteContent first to read current content, then call updateNoteContent with the new content to save it. Never just describe what to write - always actually save it using the tool.\n7. For videos: Use generateNoteVideo when users want to create explanatory videos for their notes. Videos are generated automatically from note title and content.\n\nREORGANIZATION CAPABILITY:\nYou have the ability to intelligently reorganize the entire notes structure within the current workspace. When asked to reorganize:\n1. Use listNotebooks to see all notebooks in the current workspace\n2. For each notebook, use listChapters to see chapters\n3. For each chapter, use listNotesInChapter and getNoteContent to understand the content\n4. Analyze the content and determine better organizational structure\n5. Create new notebooks/chapters as needed using createNotebook and createChapter\n6. Move notes and chapters to their optimal locations using moveNote and moveChapter\n7. Rename notebooks, chapters, and notes for better clarity using renameNotebook, renameChapter, and renameNote\n8. Provide a summary of all changes made\n\nWhen reorganizing, think about:\n- Thematic grouping (similar topics together)\n- Logical hierarchy (general to specific)\n- Clear, descriptive names\n- Reducing clutter and improving discoverability\n\nAlways provide a clear, helpful text response after using tools. Be conversational and helpful.", contextInfo, contextInfo),
+			Content: services.NewSystemPromptService().Render(models.SystemPromptChat, req.OrganizationID, services.SystemPromptData{Workspace: contextInfo}) + currentTimePrompt(ctx, clerkUserID) + services.NewNoteLintService().StyleGuidePrompt(ctx, req.OrganizationID) + toolRestrictionPrompt(tools, toolScope) + chatScopePrompt(chatScope) + chatNotePrompt(chatNote, budget.NotePage),
 		}}, req.Messages...)
 	}
 
 	// Main streaming loop (handles tool calls)
 	for {
-		var stream aisdk.DataStream
+		trimChatToolResults(req.Messages, budget.Input)
+		stream, err := openChatStream(ctx, policy, candidates, req, tools, chatMaxTokens)
+		if err != nil {
+			log.Error().Err(err).Str("provider", req.Provider).Msg("Failed to open AI response stream")
+			writeChatStreamError(c, req, err)
+			return
+		}
 
-		switch req.Provider {
-		case "openai":
-			messages, err := aisdk.MessagesToOpenAI(req.Messages)
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to convert messages for OpenAI")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare messages"})
-				return
-			}
+		// Setup accumulator and tool calling
+		var acc aisdk.DataStreamAccumulator
+		stream = stream.WithToolCalling(handleToolCall)
+		stream = stream.WithAccumulator(&acc)
+		stream = withChatRunEvents(stream, run)
 
-			reasoningEffort := openai.ReasoningEffort("")
-			if req.Thinking {
-				reasoningEffort = openai.ReasoningEffortMedium
-			}
+		// Pipe the stream to the auto-flushing writer
+		if err := stream.Pipe(flushWriter); err != nil {
+			log.Error().Err(err).Msg("Error piping AI response stream")
+			writeChatStreamError(c, req, err)
+			return
+		}
 
-			client := getOpenAIClient(apiKey)
-			stream = aisdk.OpenAIToDataStream(client.Chat.Completions.NewStreaming(ctx, openai.ChatCompletionNewParams{
-				Model:               req.Model,
-				Messages:            messages,
-				ReasoningEffort:     reasoningEffort,
-				Tools:               aisdk.ToolsToOpenAI(tools),
-				MaxCompletionTokens: openai.Int(16384),
-			}))
-
-		case "anthropic":
-			messages, system, err := aisdk.MessagesToAnthropic(req.Messages)
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to convert messages for Anthropic")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare messages"})
-				return
-			}
+		req.Messages = append(req.Messages, acc.Messages()...)
+		lastMessages = req.Messages[:]
 
-			thinking := anthropic.ThinkingConfigParamUnion{}
-			if req.Thinking {
-				thinking = anthropic.ThinkingConfigParamOfEnabled(2048)
-			}
+		if run.Cancelled() {
+			log.Info().Str("run_id", run.ID).Str("user_id", clerkUserID).Msg("Chat run cancelled")
+			writeChatRunEvent(flushWriter, services.ChatRunEvent{Type: services.ChatRunEventCancelled, RunID: run.ID})
+			break
+		}
 
-			client := getAnthropicClient(apiKey)
-			stream = aisdk.AnthropicToDataStream(client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
-				Model:     anthropic.Model(req.Model),
-				Messages:  messages,
-				System:    system,
-				MaxTokens: 16384,
-				Thinking:  thinking,
-				Tools:     aisdk.ToolsToAnthropic(tools),
-			}))
-
-		case "google":
-			googleClient, err := getGoogleClient(ctx, apiKey)
-			if err != nil {
-				log.Error().Err(err).Msg("Google client not initialized")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Google client not configured"})
-				return
-			}
+		if acc.FinishReason() == aisdk.FinishReasonToolCalls {
+			continue
+		}
 
-			messages, err := aisdk.MessagesToGoogle(req.Messages)
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to convert messages for Google")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare messages"})
+		break
+	}
+}
+
+// withChatRunEvents adds a progress event after each tool call and result of the run
+func withChatRunEvents(stream aisdk.DataStream, run *services.ChatRun) aisdk.DataStream {
+	return func(yield func(aisdk.DataStreamPart, error) bool) {
+		for part, err := range stream {
+			if !yield(part, err) || err != nil {
 				return
 			}
 
-			var thinkingConfig *genai.ThinkingConfig
-			if req.Thinking {
-				thinkingConfig = &genai.ThinkingConfig{
-					IncludeThoughts: true,
+			var event *services.ChatRunEvent
+			switch p := part.(type) {
+			case aisdk.ToolCallStreamPart:
+				event = &services.ChatRunEvent{Type: services.ChatRunEventToolStarted, RunID: run.ID, ToolCallID: p.ToolCallID, Tool: p.ToolName}
+			case aisdk.ToolResultStreamPart:
+				call, ok := run.ToolCall(p.ToolCallID)
+				if !ok {
+					continue
+				}
+				event = &services.ChatRunEvent{Type: services.ChatRunEventToolFinished, RunID: run.ID, Step: call.Step, ToolCallID: call.ToolCallID, Tool: call.Tool, Error: call.Error}
+				if call.Status == services.ChatRunToolSkipped {
+					event.Type = services.ChatRunEventToolSkipped
 				}
 			}
-
-			googleTools, err := aisdk.ToolsToGoogle(tools)
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to convert tools for Google")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare tools"})
+			if event != nil && !yield(aisdk.DataStreamDataPart{Content: []any{event}}, nil) {
 				return
 			}
+		}
+	}
+}
+
+// writeChatRunEvent writes a run progress event outside of a model stream
+func writeChatRunEvent(w io.Writer, event services.ChatRunEvent) {
+	formatted, err := aisdk.DataStreamDataPart{Content: []any{event}}.Format()
+	if err != nil {
+		return
+	}
+	if _, err := io.WriteString(w, formatted); err != nil {
+		log.Warn().Err(err).Str("run_id", event.RunID).Msg("Failed to write chat run event")
+	}
+}
+
+// chatTokenBudget returns the token budget of the provider with the smallest context window among the
+// candidates, so a fallback provider can take the conversation as it is
+func chatTokenBudget(candidates []chatProviderCandidate) services.AITokenBudget {
+	var budget services.AITokenBudget
+	for i, candidate := range candidates {
+		candidateBudget := services.NewAITokenBudget(candidate.Provider, chatMaxTokens)
+		if i == 0 || candidateBudget.Input < budget.Input {
+			budget = candidateBudget
+		}
+	}
+	return budget
+}
+
+// trimChatToolResults drops the results of the oldest tool calls until the conversation fits the input
+// budget. The model is told a result was dropped, so it can call the tool again if it needs it. The
+// latest message is kept whole, its results are what the model is about to answer from.
+func trimChatToolResults(messages []aisdk.Message, inputTokens int) {
+	total := services.EstimateValueTokens(messages)
+	for i := 0; i < len(messages)-1 && total > inputTokens; i++ {
+		for j := range messages[i].Parts {
+			invocation := messages[i].Parts[j].ToolInvocation
+			if invocation == nil || invocation.Result == nil || total <= inputTokens {
+				continue
+			}
+			if _, dropped := invocation.Result.(droppedToolResult); dropped {
+				continue
+			}
+			total -= services.EstimateValueTokens(invocation.Result)
+			invocation.Result = droppedToolResult{Dropped: "This earlier result was removed to fit the context window. Call the tool again if you need it."}
+			total += services.EstimateValueTokens(invocation.Result)
+		}
+	}
+}
+
+// droppedToolResult replaces a tool result trimmed from the conversation
+type droppedToolResult struct {
+	Dropped string `json:"dropped"`
+}
 
-			stream = aisdk.GoogleToDataStream(googleClient.Models.GenerateContentStream(ctx, req.Model, messages, &genai.GenerateContentConfig{
-				Tools:          googleTools,
-				ThinkingConfig: thinkingConfig,
-			}))
+// toolResultError returns the error message of a failed tool call result
+func toolResultError(result any) string {
+	switch r := result.(type) {
+	case map[string]string:
+		return r["error"]
+	case map[string]any:
+		message, _ := r["error"].(string)
+		return message
+	}
+	return ""
+}
+
+// resolveChatScope resolves the notebook or chapter a chat is limited to, checking the user can access
+// it and that it belongs to the chat's workspace. It returns nil when the chat isn't scoped.
+func resolveChatScope(c *gin.Context, req ChatRequest, clerkUserID string) (*services.AIChatScope, bool) {
+	if req.Scope == nil || (req.Scope.NotebookID == "" && req.Scope.ChapterID == "") {
+		return nil, true
+	}
 
+	chatScope, err := services.NewAIChatScope(req.Scope.NotebookID, req.Scope.ChapterID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidChatScope):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrChatScopeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notebook or chapter not found"})
 		default:
-			log.Error().Str("provider", req.Provider).Msg("Invalid provider")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider"})
-			return
+			log.Error().Err(err).Msg("Failed to resolve chat scope")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve chat scope"})
 		}
+		return nil, false
+	}
 
-		if stream == nil {
-			log.Error().Msg("Failed to create stream")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create stream"})
-			return
+	var notebook models.Notebook
+	if err := db.DB.WithContext(c.Request.Context()).Select("id", "clerk_user_id", "organization_id").Where("id = ?", chatScope.NotebookID).First(&notebook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook or chapter not found"})
+		return nil, false
+	}
+	if !userCanAccessNotebookChat(c.Request.Context(), &notebook, clerkUserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+	inOrganization := notebook.OrganizationID != nil && *notebook.OrganizationID != ""
+	requestedOrganization := req.OrganizationID != nil && *req.OrganizationID != ""
+	if inOrganization != requestedOrganization || (inOrganization && *notebook.OrganizationID != *req.OrganizationID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The chat scope is outside the current workspace"})
+		return nil, false
+	}
+
+	return chatScope, true
+}
+
+// chatScopePrompt tells the model which notebook or chapter the conversation is about and lists its
+// outline, so it can answer without listing everything first
+func chatScopePrompt(chatScope *services.AIChatScope) string {
+	if chatScope == nil {
+		return ""
+	}
+
+	prompt := fmt.Sprintf("\n\nFOCUSED CONVERSATION:\nThis conversation is limited to %s. Every tool only sees and changes notes inside it, and calls that reach outside it will fail. Don't create notebooks or move content out of it.", chatScope.Label())
+	outline, err := chatScope.Outline()
+	if err != nil {
+		log.Warn().Err(err).Str("notebook_id", chatScope.NotebookID).Msg("Failed to build chat scope outline")
+		return prompt
+	}
+	return prompt + "\n\nOutline:\n" + outline
+}
+
+// resolveChatNote loads the note a conversation starts from, when the request names one. The note has to
+// be in the chat's workspace and scope, and readable by the assistant, so not locked or encrypted.
+func resolveChatNote(c *gin.Context, req ChatRequest, clerkUserID string, chatScope *services.AIChatScope) (*models.Notes, bool) {
+	if req.NoteID == "" {
+		return nil, true
+	}
+
+	var note models.Notes
+	if err := db.DB.WithContext(c.Request.Context()).Preload("Chapter.Notebook").Where("id = ?", req.NoteID).First(&note).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return nil, false
+	}
+	if !userCanAccessNotebookChat(c.Request.Context(), &note.Chapter.Notebook, clerkUserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+	inOrganization := note.Chapter.Notebook.OrganizationID != nil && *note.Chapter.Notebook.OrganizationID != ""
+	requestedOrganization := req.OrganizationID != nil && *req.OrganizationID != ""
+	if inOrganization != requestedOrganization || (inOrganization && *note.Chapter.Notebook.OrganizationID != *req.OrganizationID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The note is outside the current workspace"})
+		return nil, false
+	}
+
+	args := map[string]any{"noteId": note.ID}
+	if err := chatScope.CheckToolCall("getNoteContent", args); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	for _, check := range []func(map[string]any) error{
+		services.NewNotebookEncryptionService().CheckToolCall,
+		services.NewNoteLockService().CheckToolCall,
+	} {
+		if err := check(args); err != nil {
+			if errors.Is(err, services.ErrNotebookEncrypted) || errors.Is(err, services.ErrNoteLocked) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			} else {
+				log.Error().Err(err).Str("note_id", note.ID).Msg("Failed to check chat note")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load note"})
+			}
+			return nil, false
 		}
+	}
 
-		// Setup accumulator and tool calling
-		var acc aisdk.DataStreamAccumulator
-		stream = stream.WithToolCalling(handleToolCall)
-		stream = stream.WithAccumulator(&acc)
+	return &note, true
+}
 
-		// Wrap the writer to auto-flush for real-time streaming
-		flushWriter := &autoFlushWriter{Writer: c.Writer}
+// chatNotePrompt gives the model the note the conversation started from, as Markdown. Long notes are
+// paged like getNoteContent, so the model reads on with getNoteContentPage.
+func chatNotePrompt(note *models.Notes, pageTokens int) string {
+	if note == nil {
+		return ""
+	}
 
-		// Pipe the stream to the auto-flushing writer
-		err := stream.Pipe(flushWriter)
+	content, err := internalutils.TipTapToMarkdown(note.Content)
+	if err != nil {
+		log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to convert chat note to markdown")
+		content = note.Content
+	}
+	contentPage := services.PageText(content, 1, pageTokens)
+
+	prompt := fmt.Sprintf("\n\nSTARTING NOTE:\nThe user started this conversation from the note %q (noteId %s) in the chapter %q of the notebook %q. Questions that don't say otherwise are about this note.\n\nContent:\n%s",
+		note.Name, note.ID, note.Chapter.Name, note.Chapter.Notebook.Name, contentPage.Content)
+	if contentPage.HasMore() {
+		prompt += fmt.Sprintf("\n\nThis is page 1 of %d of a long note. Call getNoteContentPage with page 2 to read on.", contentPage.Pages)
+	}
+	return prompt
+}
+
+// filterToolsByScope returns the tools allowed by the permission scope
+func filterToolsByScope(tools []aisdk.Tool, scope services.AIToolScope) []aisdk.Tool {
+	allowed := make([]aisdk.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if scope.Allows(tool.Name) {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed
+}
+
+// toolRestrictionPrompt tells the model which tools it actually has when some are disabled,
+// so it doesn't promise changes it can't make
+func toolRestrictionPrompt(tools []aisdk.Tool, scope services.AIToolScope) string {
+	if !scope.ReadOnly && len(scope.Disabled) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+
+	prompt := "\n\nTOOL RESTRICTIONS:\nSome tools have been disabled in this workspace. You can only use: " + strings.Join(names, ", ") + "."
+	if scope.ReadOnly {
+		prompt += " The assistant is in read-only mode, so you cannot create, modify, move or delete anything."
+	}
+	return prompt + " If the user asks for something you have no tool for, explain that it has been disabled in their AI settings."
+}
+
+// Completion token limits for streamed responses
+const (
+	chatMaxTokens     = 16384
+	generateMaxTokens = 4096
+)
+
+// chatProviderCandidate is a provider the chat can be served by, with the model and key to use
+type chatProviderCandidate struct {
+	Provider string
+	Model    string
+	APIKey   string
+}
+
+// buildChatProviderCandidates returns the requested provider followed by every other configured
+// provider the user or organization has a key for
+func buildChatProviderCandidates(policy *services.AIProviderPolicy, clerkUserID string, req ChatRequest, apiKey string) []chatProviderCandidate {
+	candidates := []chatProviderCandidate{{Provider: req.Provider, Model: req.Model, APIKey: apiKey}}
+	for _, provider := range policy.ProviderChain(req.Provider)[1:] {
+		fallbackKey, err := getUserAPIKeyWithOrg(clerkUserID, req.OrganizationID, provider)
 		if err != nil {
-			log.Error().Err(err).Msg("Error piping AI response stream")
+			continue
+		}
+		candidates = append(candidates, chatProviderCandidate{
+			Provider: provider,
+			Model:    policy.DefaultModel(provider),
+			APIKey:   fallbackKey,
+		})
+	}
+	return candidates
+}
+
+// openChatStream opens a response stream on the first candidate provider that accepts the request.
+// Transient errors are retried, and a failing provider falls back to the next candidate.
+func openChatStream(ctx context.Context, policy *services.AIProviderPolicy, candidates []chatProviderCandidate, req ChatRequest, tools []aisdk.Tool, maxTokens int64) (aisdk.DataStream, error) {
+	providers := make([]string, 0, len(candidates))
+	byProvider := make(map[string]chatProviderCandidate, len(candidates))
+	for _, candidate := range candidates {
+		providers = append(providers, candidate.Provider)
+		byProvider[candidate.Provider] = candidate
+	}
+
+	var stream aisdk.DataStream
+	provider, err := policy.Run(ctx, providers, func(ctx context.Context, provider string) error {
+		var err error
+		stream, err = openProviderChatStream(ctx, byProvider[provider], req, tools, maxTokens)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if provider != req.Provider {
+		log.Warn().
+			Str("requested_provider", req.Provider).
+			Str("provider", provider).
+			Str("model", byProvider[provider].Model).
+			Msg("Chat served by fallback provider")
+	}
 
-			// Get appropriate API error based on the error message
-			apiErr := types.GetAPIKeyErrorFromMessage(err)
+	return stream, nil
+}
 
-			// Add context-specific suggestions
-			if apiErr.Code == types.ErrorCodeAPIKeyInvalid {
-				if req.OrganizationID != nil && *req.OrganizationID != "" {
-					apiErr = apiErr.WithSuggestion("Check your organization's API key settings or contact your admin")
-				} else {
-					apiErr = apiErr.WithSuggestion("Please check your API key in Profile settings and ensure it's correct")
-				}
+// openProviderChatStream starts a streaming completion with a single provider.
+// Provider errors are surfaced here, before anything has been written to the client, so the request can be retried.
+func openProviderChatStream(ctx context.Context, candidate chatProviderCandidate, req ChatRequest, tools []aisdk.Tool, maxTokens int64) (aisdk.DataStream, error) {
+	switch candidate.Provider {
+	case config.AIProviderOpenAI:
+		messages, err := aisdk.MessagesToOpenAI(req.Messages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare messages for OpenAI: %w", err)
+		}
+
+		reasoningEffort := openai.ReasoningEffort("")
+		if req.Thinking {
+			reasoningEffort = openai.ReasoningEffortMedium
+		}
+
+		// Retries are handled by the provider policy
+		client := getOpenAIClient(candidate.APIKey, openaioption.WithMaxRetries(0))
+		params := openai.ChatCompletionNewParams{
+			Model:               candidate.Model,
+			Messages:            messages,
+			ReasoningEffort:     reasoningEffort,
+			MaxCompletionTokens: openai.Int(maxTokens),
+		}
+		if len(tools) > 0 {
+			params.Tools = aisdk.ToolsToOpenAI(tools)
+		}
+
+		stream := client.Chat.Completions.NewStreaming(ctx, params)
+		if err := stream.Err(); err != nil {
+			return nil, err
+		}
+		return aisdk.OpenAIToDataStream(stream), nil
+
+	case config.AIProviderAnthropic:
+		messages, system, err := aisdk.MessagesToAnthropic(req.Messages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare messages for Anthropic: %w", err)
+		}
+
+		thinking := anthropic.ThinkingConfigParamUnion{}
+		if req.Thinking {
+			thinking = anthropic.ThinkingConfigParamOfEnabled(2048)
+		}
+
+		// Retries are handled by the provider policy
+		client := getAnthropicClient(candidate.APIKey, anthropicoption.WithMaxRetries(0))
+		params := anthropic.MessageNewParams{
+			Model:     anthropic.Model(candidate.Model),
+			Messages:  messages,
+			System:    system,
+			MaxTokens: maxTokens,
+			Thinking:  thinking,
+		}
+		if len(tools) > 0 {
+			params.Tools = aisdk.ToolsToAnthropic(tools)
+		}
+
+		stream := client.Messages.NewStreaming(ctx, params)
+		if err := stream.Err(); err != nil {
+			return nil, err
+		}
+		return aisdk.AnthropicToDataStream(stream), nil
+
+	case config.AIProviderGoogle:
+		googleClient, err := getGoogleClient(ctx, candidate.APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("google client not configured: %w", err)
+		}
+
+		messages, err := aisdk.MessagesToGoogle(req.Messages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare messages for Google: %w", err)
+		}
+
+		var thinkingConfig *genai.ThinkingConfig
+		if req.Thinking {
+			thinkingConfig = &genai.ThinkingConfig{
+				IncludeThoughts: true,
 			}
+		}
 
-			// Return structured JSON error response
-			c.JSON(apiErr.HTTPStatus, gin.H{
-				"error": gin.H{
-					"code":       apiErr.Code,
-					"message":    apiErr.Message,
-					"details":    apiErr.Details,
-					"suggestion": apiErr.Suggestion,
-				},
-			})
-			return
+		generateConfig := &genai.GenerateContentConfig{
+			ThinkingConfig:  thinkingConfig,
+			MaxOutputTokens: int32(maxTokens),
+		}
+		if len(tools) > 0 {
+			googleTools, err := aisdk.ToolsToGoogle(tools)
+			if err != nil {
+				return nil, fmt.Errorf("failed to prepare tools for Google: %w", err)
+			}
+			generateConfig.Tools = googleTools
 		}
 
-		req.Messages = append(req.Messages, acc.Messages()...)
-		lastMessages = req.Messages[:]
+		// The Gemini stream is lazy, so pull the first part to surface request errors
+		return primeDataStream(aisdk.GoogleToDataStream(googleClient.Models.GenerateContentStream(ctx, candidate.Model, messages, generateConfig)))
+	}
 
-		if acc.FinishReason() == aisdk.FinishReasonToolCalls {
-			continue
+	return nil, fmt.Errorf("unsupported provider: %s", candidate.Provider)
+}
+
+// primeDataStream reads the first part of a stream and returns an error if the stream failed immediately.
+// The returned stream replays the first part followed by the rest of the original stream.
+func primeDataStream(stream aisdk.DataStream) (aisdk.DataStream, error) {
+	next, stop := iter.Pull2(iter.Seq2[aisdk.DataStreamPart, error](stream))
+
+	first, err, ok := next()
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	return func(yield func(aisdk.DataStreamPart, error) bool) {
+		defer stop()
+		if !ok || !yield(first, nil) {
+			return
 		}
+		for {
+			part, err, ok := next()
+			if !ok || !yield(part, err) {
+				return
+			}
+		}
+	}, nil
+}
 
-		break
+// writeChatStreamError writes a structured error response for a failed chat stream
+func writeChatStreamError(c *gin.Context, req ChatRequest, err error) {
+	// Get appropriate API error based on the error message
+	apiErr := types.GetAPIKeyErrorFromMessage(err)
+
+	// Add context-specific suggestions
+	if apiErr.Code == types.ErrorCodeAPIKeyInvalid {
+		if req.OrganizationID != nil && *req.OrganizationID != "" {
+			apiErr = apiErr.WithSuggestion("Check your organization's API key settings or contact your admin")
+		} else {
+			apiErr = apiErr.WithSuggestion("Please check your API key in Profile settings and ensure it's correct")
+		}
 	}
+
+	// Return structured JSON error response
+	c.JSON(apiErr.HTTPStatus, gin.H{
+		"error": gin.H{
+			"code":       apiErr.Code,
+			"message":    apiErr.Message,
+			"details":    apiErr.Details,
+			"suggestion": apiErr.Suggestion,
+		},
+	})
 }
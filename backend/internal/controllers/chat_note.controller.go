@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/coder/aisdk-go"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// SaveChatAsNoteRequest is the conversation to save and where to save it. Conversations live in the
+// client, so the messages are sent as they are sent to the chat.
+type SaveChatAsNoteRequest struct {
+	ChapterID string          `json:"chapterId" binding:"required"`
+	Title     string          `json:"title"`
+	Messages  []aisdk.Message `json:"messages"`
+}
+
+// SaveChatAsNote saves an assistant conversation as a note in a chapter, with the tools it called
+// summarized under each answer. Conversations are started from a note by sending noteId to /api/chat.
+// The server doesn't store conversations, so the conversation ID in the path only labels the log entry.
+// POST /api/chat/:conversationId/save-as-note
+func SaveChatAsNote(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SaveChatAsNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if !authorizeChapter(c, req.ChapterID, clerkUserID, middleware.NoteAccessCanEdit) {
+		return
+	}
+
+	note, err := services.NewChatNoteService().SaveConversation(c.Request.Context(), req.ChapterID, req.Title, chatTranscript(req.Messages))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidChatTranscript):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrChapterNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
+		case errors.Is(err, services.ErrNotebookEncrypted):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			middleware.ReportError(c, err, "Failed to save chat as note")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save chat as note"})
+		}
+		return
+	}
+
+	log.Info().Str("conversation_id", c.Param("conversationId")).Str("note_id", note.ID).Str("user_id", clerkUserID).Msg("Chat saved as note")
+	c.JSON(http.StatusCreated, note)
+}
+
+// chatTranscript reads the text and tool calls of chat messages. Messages with parts are read from them,
+// since their content leaves out tool calls; older clients only send content.
+func chatTranscript(messages []aisdk.Message) []services.ChatTranscriptMessage {
+	transcript := make([]services.ChatTranscriptMessage, 0, len(messages))
+	for _, message := range messages {
+		entry := services.ChatTranscriptMessage{Role: message.Role, Content: message.Content}
+		if len(message.Parts) > 0 {
+			var texts []string
+			for _, part := range message.Parts {
+				switch {
+				case part.Type == aisdk.PartTypeText && strings.TrimSpace(part.Text) != "":
+					texts = append(texts, part.Text)
+				case part.Type == aisdk.PartTypeToolInvocation && part.ToolInvocation != nil:
+					args, _ := part.ToolInvocation.Args.(map[string]any)
+					entry.ToolCalls = append(entry.ToolCalls, services.ChatTranscriptToolCall{
+						Tool:  part.ToolInvocation.ToolName,
+						Args:  args,
+						Done:  part.ToolInvocation.State == aisdk.ToolInvocationStateResult,
+						Error: toolResultError(part.ToolInvocation.Result),
+					})
+				}
+			}
+			entry.Content = strings.Join(texts, "\n\n")
+		}
+		transcript = append(transcript, entry)
+	}
+	return transcript
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// GetChatRun returns the tool calls made so far by an in-flight chat run
+// GET /api/chat/runs/:id
+func GetChatRun(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	run, err := services.GetChatRunRegistry().Get(c.Param("id"), clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chat run not found or already finished"})
+		return
+	}
+
+	c.JSON(http.StatusOK, run.Status())
+}
+
+// CancelChatRun stops an in-flight chat run from executing further tools. The tool call in progress
+// finishes, and the response stream ends with a run_cancelled event.
+// POST /api/chat/runs/:id/cancel
+func CancelChatRun(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	run, err := services.GetChatRunRegistry().Cancel(c.Param("id"), clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chat run not found or already finished"})
+		return
+	}
+
+	log.Info().Str("run_id", run.ID).Str("user_id", clerkUserID).Msg("Chat run cancellation requested")
+	c.JSON(http.StatusOK, run.Status())
+}
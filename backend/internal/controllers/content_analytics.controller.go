@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// GetOrgContentAnalytics returns the organization's most viewed notes, stale notebooks,
+// top contributors and orphaned notes
+// GET /organizations/:orgId/content-analytics?days=30&staleDays=90&limit=10
+func GetOrgContentAnalytics(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	days, _ := strconv.Atoi(c.Query("days"))
+	staleDays, _ := strconv.Atoi(c.Query("staleDays"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	report, err := services.NewContentAnalyticsService().GetOrganizationReport(orgID, services.ContentAnalyticsOptions{
+		Days:      days,
+		StaleDays: staleDays,
+		Limit:     limit,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to build content analytics")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch content analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
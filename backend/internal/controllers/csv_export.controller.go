@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportTaskBoardCSV exports a board's tasks as a spreadsheet, with their assignees and dates
+// GET /kanban/:boardId/export.csv
+func ExportTaskBoardCSV(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	boardID := c.Param("boardId")
+	hasAccess, err := CheckTaskBoardAccess(c.Request.Context(), db.DB, boardID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("board_id", boardID).Str("user_id", clerkUserID).Msg("User not authorized to export task board")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	export, err := services.NewCSVExportService().ExportBoard(c.Request.Context(), boardID)
+	if err != nil {
+		sendCSVExportError(c, err, "Failed to export task board")
+		return
+	}
+
+	sendCSV(c, export, "board")
+}
+
+// ExportNotebookNotesCSV exports the list of a notebook's notes with their metadata as a spreadsheet
+// GET /notebook/:id/notes.csv
+func ExportNotebookNotesCSV(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notebookID := c.Param("id")
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User not authorized to export notebook")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	export, err := services.NewCSVExportService().ExportNotebookNotes(c.Request.Context(), notebookID)
+	if err != nil {
+		sendCSVExportError(c, err, "Failed to export notebook")
+		return
+	}
+
+	sendCSV(c, export, "notes")
+}
+
+// sendCSV responds with a spreadsheet to download, named after the board or notebook
+func sendCSV(c *gin.Context, export *services.CSVExport, fallbackName string) {
+	name := models.Slugify(export.Name)
+	if name == "" {
+		name = fallbackName
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+	c.Data(http.StatusOK, services.CSVContentType, export.Data)
+}
+
+// sendCSVExportError maps CSV export service errors to responses
+func sendCSVExportError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTaskBoardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
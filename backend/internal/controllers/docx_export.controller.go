@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportNoteDocx exports a note as a Word document, its headings, lists, tables and images mapped to
+// Word's built-in styles
+// GET /note/:id/export.docx
+func ExportNoteDocx(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to export note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	export, err := services.NewDocxExportService().ExportNote(c.Request.Context(), noteID)
+	if err != nil {
+		sendDocxExportError(c, err, "Failed to export note")
+		return
+	}
+
+	sendDocx(c, export, "note")
+}
+
+// ExportNotebookDocx exports a notebook as a Word document, each chapter starting on a new page
+// GET /notebook/:id/export.docx
+func ExportNotebookDocx(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notebookID := c.Param("id")
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User not authorized to export notebook")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	export, err := services.NewDocxExportService().ExportNotebook(c.Request.Context(), notebookID)
+	if err != nil {
+		sendDocxExportError(c, err, "Failed to export notebook")
+		return
+	}
+
+	sendDocx(c, export, "notebook")
+}
+
+// sendDocx responds with a Word document to download, named after the note or notebook
+func sendDocx(c *gin.Context, export *services.DocxExport, fallbackName string) {
+	name := models.Slugify(export.Name)
+	if name == "" {
+		name = fallbackName
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".docx"))
+	c.Data(http.StatusOK, services.DocxContentType, export.Data)
+}
+
+// sendDocxExportError maps Word export service errors to responses
+func sendDocxExportError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+	case errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDocxExportTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListFeedSubscriptions returns the user's feed subscriptions
+// GET /api/feeds
+func ListFeedSubscriptions(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	subscriptions, err := services.NewFeedSubscriptionService().List(c.Request.Context(), clerkUserID)
+	if err != nil {
+		sendFeedSubscriptionError(c, err, "Failed to fetch feed subscriptions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+}
+
+// SubscribeToFeed subscribes a chapter, or the reading list with target "reading_list", to an RSS or
+// Atom feed. New entries matching the keyword filters become notes or reading list items.
+// POST /api/feeds
+func SubscribeToFeed(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req services.FeedSubscriptionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if !checkFeedChapterAccess(c, req.ChapterID, clerkUserID) {
+		return
+	}
+
+	subscription, err := services.NewFeedSubscriptionService().Subscribe(c.Request.Context(), clerkUserID, req)
+	if err != nil {
+		sendFeedSubscriptionError(c, err, "Failed to subscribe to feed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// UpdateFeedSubscription changes where a feed's entries go, its keyword filters, poll interval or
+// whether it's enabled
+// PUT /api/feeds/:id
+func UpdateFeedSubscription(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req services.FeedSubscriptionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if !checkFeedChapterAccess(c, req.ChapterID, clerkUserID) {
+		return
+	}
+
+	subscription, err := services.NewFeedSubscriptionService().Update(c.Request.Context(), c.Param("id"), clerkUserID, req)
+	if err != nil {
+		sendFeedSubscriptionError(c, err, "Failed to update feed subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// UnsubscribeFromFeed deletes a feed subscription, keeping the notes it created
+// DELETE /api/feeds/:id
+func UnsubscribeFromFeed(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := services.NewFeedSubscriptionService().Unsubscribe(c.Request.Context(), c.Param("id"), clerkUserID); err != nil {
+		sendFeedSubscriptionError(c, err, "Failed to unsubscribe from feed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed from feed"})
+}
+
+// ListFeedEntries returns the latest entries seen in a subscription's feed and whether they were
+// imported, filtered out or skipped
+// GET /api/feeds/:id/entries
+func ListFeedEntries(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	entries, err := services.NewFeedSubscriptionService().ListEntries(c.Request.Context(), c.Param("id"), clerkUserID)
+	if err != nil {
+		sendFeedSubscriptionError(c, err, "Failed to fetch feed entries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// PollFeed polls a subscription's feed now rather than waiting for its interval
+// POST /api/feeds/:id/poll
+func PollFeed(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	result, err := services.NewFeedSubscriptionService().Poll(c.Request.Context(), c.Param("id"), clerkUserID)
+	if err != nil {
+		sendFeedSubscriptionError(c, err, "Failed to poll feed")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// checkFeedChapterAccess checks the user can add notes to the chapter a feed is subscribed to, and
+// responds when they can't
+func checkFeedChapterAccess(c *gin.Context, chapterID *string, clerkUserID string) bool {
+	if chapterID == nil || strings.TrimSpace(*chapterID) == "" {
+		return true
+	}
+	return authorizeChapter(c, strings.TrimSpace(*chapterID), clerkUserID, middleware.NoteAccessCanEdit)
+}
+
+// sendFeedSubscriptionError maps feed subscription service errors to responses
+func sendFeedSubscriptionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidFeedSubscription):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFeedSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChapterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
+	case errors.Is(err, services.ErrFeedSubscriptionExists), errors.Is(err, services.ErrFeedPollInProgress),
+		errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFeedPollFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
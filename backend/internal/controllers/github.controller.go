@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// githubOwner resolves whose integration the request manages.
+// Routes under /organizations/:orgId manage the organization's integration; the rest manage the user's own.
+func githubOwner(c *gin.Context) (services.GitHubOwner, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return services.GitHubOwner{}, false
+	}
+
+	owner := services.GitHubOwner{ClerkUserID: clerkUserID}
+	if orgID := c.Param("orgId"); orgID != "" {
+		owner.OrganizationID = &orgID
+	}
+	return owner, true
+}
+
+// GetGitHubIntegration returns the GitHub integration settings and last sync status
+// GET /settings/github
+// GET /organizations/:orgId/github
+func GetGitHubIntegration(c *gin.Context) {
+	owner, ok := githubOwner(c)
+	if !ok {
+		return
+	}
+
+	integration, err := services.NewGitHubSyncService().GetIntegration(owner)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", owner.ClerkUserID).Msg("Failed to fetch GitHub integration")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch GitHub integration"})
+		return
+	}
+	if integration == nil {
+		c.JSON(http.StatusOK, gin.H{"configured": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"configured":  true,
+		"integration": integration,
+	})
+}
+
+// SaveGitHubIntegration creates or updates the GitHub integration
+// PUT /settings/github
+// PUT /organizations/:orgId/github
+func SaveGitHubIntegration(c *gin.Context) {
+	owner, ok := githubOwner(c)
+	if !ok {
+		return
+	}
+
+	var req services.GitHubIntegrationSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	integration, err := services.NewGitHubSyncService().SaveIntegration(c.Request.Context(), owner, req, owner.ClerkUserID)
+	if err != nil {
+		sendGitHubError(c, err, "Failed to save GitHub integration")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"configured":  true,
+		"integration": integration,
+	})
+}
+
+// DeleteGitHubIntegration removes the GitHub integration. Files already pushed stay in the repository.
+// DELETE /settings/github
+// DELETE /organizations/:orgId/github
+func DeleteGitHubIntegration(c *gin.Context) {
+	owner, ok := githubOwner(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewGitHubSyncService().DeleteIntegration(owner); err != nil {
+		sendGitHubError(c, err, "Failed to delete GitHub integration")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "GitHub integration removed successfully"})
+}
+
+// SyncGitHubIntegration runs a sync right away and returns what changed
+// POST /settings/github/sync
+// POST /organizations/:orgId/github/sync
+func SyncGitHubIntegration(c *gin.Context) {
+	owner, ok := githubOwner(c)
+	if !ok {
+		return
+	}
+
+	syncService := services.NewGitHubSyncService()
+	integration, err := syncService.GetIntegration(owner)
+	if err != nil {
+		sendGitHubError(c, err, "Failed to sync with GitHub")
+		return
+	}
+	if integration == nil {
+		sendGitHubError(c, services.ErrGitHubIntegrationNotFound, "Failed to sync with GitHub")
+		return
+	}
+
+	result, err := syncService.Sync(c.Request.Context(), integration.ID, services.GitHubSyncTriggerManual, owner.ClerkUserID)
+	if err != nil {
+		sendGitHubError(c, err, "Failed to sync with GitHub")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// sendGitHubError maps GitHub sync service errors to responses
+func sendGitHubError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidGitHubIntegration):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrGitHubIntegrationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "GitHub integration is not configured"})
+	case errors.Is(err, services.ErrGitHubSyncInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": "A GitHub sync is already in progress"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListGlossary returns the organization's glossary with how much notes use each term
+// GET /organizations/:orgId/glossary
+func ListGlossary(c *gin.Context) {
+	terms, err := services.NewGlossaryService().ListTerms(c.Request.Context(), c.Param("orgId"))
+	if err != nil {
+		sendGlossaryError(c, err, "Failed to fetch glossary")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"terms": terms})
+}
+
+// CreateGlossaryTerm adds a term to the organization's glossary
+// POST /organizations/:orgId/glossary
+func CreateGlossaryTerm(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req services.GlossaryTermInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	term, err := services.NewGlossaryService().CreateTerm(c.Request.Context(), c.Param("orgId"), req, clerkUserID)
+	if err != nil {
+		sendGlossaryError(c, err, "Failed to create glossary term")
+		return
+	}
+
+	c.JSON(http.StatusCreated, term)
+}
+
+// UpdateGlossaryTerm changes a term of the organization's glossary
+// PUT /organizations/:orgId/glossary/:termId
+func UpdateGlossaryTerm(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req services.GlossaryTermInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	term, err := services.NewGlossaryService().UpdateTerm(c.Request.Context(), c.Param("orgId"), c.Param("termId"), req, clerkUserID)
+	if err != nil {
+		sendGlossaryError(c, err, "Failed to update glossary term")
+		return
+	}
+
+	c.JSON(http.StatusOK, term)
+}
+
+// DeleteGlossaryTerm removes a term from the organization's glossary
+// DELETE /organizations/:orgId/glossary/:termId
+func DeleteGlossaryTerm(c *gin.Context) {
+	if err := services.NewGlossaryService().DeleteTerm(c.Request.Context(), c.Param("orgId"), c.Param("termId")); err != nil {
+		sendGlossaryError(c, err, "Failed to delete glossary term")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Glossary term deleted"})
+}
+
+// GetGlossaryTermOccurrences returns the organization's notes using a glossary term
+// GET /organizations/:orgId/glossary/:termId/occurrences
+func GetGlossaryTermOccurrences(c *gin.Context) {
+	occurrences, err := services.NewGlossaryService().TermOccurrences(c.Request.Context(), c.Param("orgId"), c.Param("termId"))
+	if err != nil {
+		sendGlossaryError(c, err, "Failed to fetch glossary occurrences")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"occurrences": occurrences})
+}
+
+// GetNoteGlossary returns the glossary terms a note uses with their definitions and ProseMirror
+// positions, for editors to show definitions over them
+// GET /note/:id/glossary
+func GetNoteGlossary(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanView); !ok {
+		return
+	}
+
+	terms, err := services.NewGlossaryService().NoteGlossary(c.Request.Context(), noteID)
+	if err != nil {
+		sendGlossaryError(c, err, "Failed to fetch note glossary")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"terms": terms})
+}
+
+// sendGlossaryError maps glossary errors to responses
+func sendGlossaryError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidGlossaryTerm):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrGlossaryTermNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Glossary term not found"})
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	case errors.Is(err, services.ErrNoteLocked), errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
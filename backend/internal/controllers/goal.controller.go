@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// GoalProgressRequest represents the request body for updating a custom goal's progress
+type GoalProgressRequest struct {
+	Value int `json:"value"`
+}
+
+// ListGoals returns the user's personal goals, or an organization's with ?organizationId, with their progress
+// GET /goals
+func ListGoals(c *gin.Context) {
+	scope, ok := goalScope(c)
+	if !ok {
+		return
+	}
+
+	goals, err := services.NewGoalService().ListGoals(c.Request.Context(), scope)
+	if err != nil {
+		sendGoalError(c, err, "Failed to fetch goals")
+		return
+	}
+
+	c.JSON(http.StatusOK, goals)
+}
+
+// CreateGoal adds a personal goal, or an organization goal with ?organizationId
+// POST /goals
+func CreateGoal(c *gin.Context) {
+	scope, ok := goalScope(c)
+	if !ok {
+		return
+	}
+
+	var input services.GoalInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	goal, err := services.NewGoalService().CreateGoal(c.Request.Context(), scope, input)
+	if err != nil {
+		sendGoalError(c, err, "Failed to create goal")
+		return
+	}
+
+	c.JSON(http.StatusCreated, goal)
+}
+
+// GetGoalsWeeklySummary returns this week's progress on the goals compared with last week, as included in digests
+// GET /goals/weekly-summary
+func GetGoalsWeeklySummary(c *gin.Context) {
+	scope, ok := goalScope(c)
+	if !ok {
+		return
+	}
+
+	summary, err := services.NewGoalService().WeeklySummary(c.Request.Context(), scope)
+	if err != nil {
+		sendGoalError(c, err, "Failed to summarize goals")
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// UpdateGoal changes a goal
+// PUT /goals/:id
+func UpdateGoal(c *gin.Context) {
+	goal, clerkUserID, ok := authorizeGoal(c)
+	if !ok {
+		return
+	}
+
+	var input services.GoalInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	updated, err := services.NewGoalService().UpdateGoal(c.Request.Context(), goal.ID, clerkUserID, input)
+	if err != nil {
+		sendGoalError(c, err, "Failed to update goal")
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteGoal removes a goal
+// DELETE /goals/:id
+func DeleteGoal(c *gin.Context) {
+	goal, _, ok := authorizeGoal(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewGoalService().DeleteGoal(c.Request.Context(), goal.ID); err != nil {
+		sendGoalError(c, err, "Failed to delete goal")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Goal deleted successfully"})
+}
+
+// UpdateGoalProgress records the progress of a custom goal
+// PUT /goals/:id/progress
+func UpdateGoalProgress(c *gin.Context) {
+	goal, clerkUserID, ok := authorizeGoal(c)
+	if !ok {
+		return
+	}
+
+	var req GoalProgressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	updated, err := services.NewGoalService().SetCustomProgress(c.Request.Context(), goal.ID, clerkUserID, req.Value)
+	if err != nil {
+		sendGoalError(c, err, "Failed to update goal progress")
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// goalScope returns the user's personal scope, or the organization in ?organizationId when they're a member
+func goalScope(c *gin.Context) (services.GoalScope, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return services.GoalScope{}, false
+	}
+
+	scope := services.GoalScope{ClerkUserID: clerkUserID}
+	if orgID := c.Query("organizationId"); orgID != "" {
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
+		if err != nil || !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+			return services.GoalScope{}, false
+		}
+		scope.OrganizationID = &orgID
+	}
+	return scope, true
+}
+
+// authorizeGoal returns the goal in the path when it's the user's own, or their organization's
+func authorizeGoal(c *gin.Context) (*models.Goal, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, "", false
+	}
+
+	goal, err := services.NewGoalService().GetGoal(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendGoalError(c, err, "Failed to fetch goal")
+		return nil, "", false
+	}
+
+	hasAccess := goal.ClerkUserID == clerkUserID
+	if goal.OrganizationID != nil && *goal.OrganizationID != "" {
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), *goal.OrganizationID, clerkUserID)
+		hasAccess = err == nil && isMember
+	}
+	if !hasAccess {
+		log.Warn().Str("goal_id", goal.ID).Str("user_id", clerkUserID).Msg("User not authorized to access goal")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal not found"})
+		return nil, "", false
+	}
+	return goal, clerkUserID, true
+}
+
+// sendGoalError maps goal service errors to responses
+func sendGoalError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidGoal):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrGoalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
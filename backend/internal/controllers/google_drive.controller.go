@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"backend/pkg/googledrive"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ImportDriveFilesRequest represents the request body for importing Google Docs
+type ImportDriveFilesRequest struct {
+	ChapterID string   `json:"chapterId" binding:"required"`
+	FileIDs   []string `json:"fileIds" binding:"required,min=1"`
+}
+
+// RefreshDriveImportsRequest represents the request body for refreshing imported Google Docs
+type RefreshDriveImportsRequest struct {
+	ImportIDs []string `json:"importIds"`
+	Force     bool     `json:"force"`
+}
+
+// GetDriveConnection returns whether the user has connected Google Drive
+// GET /api/drive/connection
+func GetDriveConnection(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	connection, err := services.NewGoogleDriveService().GetConnection(clerkUserID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", clerkUserID).Msg("Failed to fetch Google Drive connection")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch Google Drive connection"})
+		return
+	}
+	if connection == nil {
+		c.JSON(http.StatusOK, gin.H{"connected": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"connected":   true,
+		"email":       connection.Email,
+		"connectedAt": connection.CreatedAt,
+	})
+}
+
+// DisconnectDrive removes the user's Google Drive authorization
+// DELETE /api/drive/connection
+func DisconnectDrive(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := services.NewGoogleDriveService().Disconnect(clerkUserID); err != nil {
+		log.Error().Err(err).Str("user_id", clerkUserID).Msg("Failed to disconnect Google Drive")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disconnect Google Drive"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Google Drive disconnected successfully"})
+}
+
+// ListDriveFiles lists the Google Docs and folders the user can import
+// GET /api/drive/files?folderId=&q=&pageToken=
+func ListDriveFiles(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	files, err := services.NewGoogleDriveService().ListFiles(c.Request.Context(), clerkUserID, googledrive.ListFilesOptions{
+		FolderID:  c.Query("folderId"),
+		Query:     c.Query("q"),
+		PageToken: c.Query("pageToken"),
+	})
+	if err != nil {
+		sendDriveError(c, err, "Failed to list Google Drive files")
+		return
+	}
+
+	c.JSON(http.StatusOK, files)
+}
+
+// ImportDriveFiles imports the selected Google Docs and folders into a chapter
+// POST /api/drive/import
+func ImportDriveFiles(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req ImportDriveFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if !authorizeChapter(c, req.ChapterID, clerkUserID, middleware.NoteAccessCanEdit) {
+		return
+	}
+
+	result, err := services.NewGoogleDriveService().ImportFiles(c.Request.Context(), clerkUserID, req.ChapterID, req.FileIDs)
+	if err != nil {
+		sendDriveError(c, err, "Failed to import Google Docs")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListDriveImports lists the Google Docs the user has imported
+// GET /api/drive/imports
+func ListDriveImports(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	imports, err := services.NewGoogleDriveService().ListImports(clerkUserID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", clerkUserID).Msg("Failed to fetch Google Drive imports")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch Google Drive imports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, imports)
+}
+
+// RefreshDriveImports re-imports Google Docs that changed since they were imported
+// POST /api/drive/imports/refresh
+func RefreshDriveImports(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req RefreshDriveImportsRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	result, err := services.NewGoogleDriveService().RefreshImports(c.Request.Context(), clerkUserID, req.ImportIDs, req.Force)
+	if err != nil {
+		sendDriveError(c, err, "Failed to refresh Google Docs")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// sendDriveError maps Google Drive service errors to responses
+func sendDriveError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrDriveNotConnected):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Google Drive is not connected"})
+	case errors.Is(err, services.ErrDriveNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Google Drive integration is not configured"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -2,34 +2,39 @@ package controllers
 
 import (
 	"backend/internal/middleware"
+	"backend/internal/models"
 	"backend/internal/services"
+	"backend/internal/utils"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
 
-// GetGraphData retrieves graph visualization data
+// The graph endpoints take the same filters: q to search notes, notebookId, chapterId, tags and
+// modifiedSince to show part of the workspace, and origins (manual, wiki, ai, glossary) to show some layers of
+// links. Large graphs are fetched as clusters first and expanded cluster by cluster, then kept up to date
+// with changes since the cursor of the last response.
+
+// GetGraphData retrieves graph visualization data, the most linked notes only with limit and the fields
+// asked for only with fields, e.g. "nodes.id,nodes.name,links.source,links.target"
+// GET /api/graph/data?q=&notebookId=&chapterId=&tags=&modifiedSince=&origins=&limit=&fields=
 func GetGraphData(c *gin.Context) {
-	clerkUserID, exists := middleware.GetClerkUserID(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	query, ok := graphQuery(c)
+	if !ok {
 		return
 	}
 
-	log.Info().Str("user_id", clerkUserID).Msg("Fetching graph data")
-
-	var organizationID *string
-	if orgID, exists := middleware.GetOrganizationID(c); exists && orgID != "" {
-		organizationID = &orgID
-	}
-
-	// Get optional search query
-	searchQuery := c.Query("q")
+	log.Info().Str("user_id", query.ClerkUserID).Msg("Fetching graph data")
 
 	// Initialize service (lazy initialization to ensure DB is ready)
 	graphService := services.NewNoteLinkService()
-	graphData, err := graphService.GetGraphData(organizationID, searchQuery)
+	graphData, err := graphService.GetGraphData(query)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get graph data")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -41,5 +46,157 @@ func GetGraphData(c *gin.Context) {
 		Int("link_count", len(graphData.Links)).
 		Msg("Graph data retrieved successfully")
 
+	utils.SendSelectedFields(c, http.StatusOK, graphData)
+}
+
+// GetGraphClusters returns the graph with closely linked notes grouped into clusters
+// GET /api/graph/clusters?q=&notebookId=&chapterId=
+func GetGraphClusters(c *gin.Context) {
+	query, ok := graphQuery(c)
+	if !ok {
+		return
+	}
+
+	graphData, err := services.NewNoteLinkService().GetClusteredGraph(query)
+	if err != nil {
+		sendGraphError(c, err, "Failed to get graph clusters")
+		return
+	}
+
 	c.JSON(http.StatusOK, graphData)
 }
+
+// GetGraphCluster expands a cluster into its notes and links
+// GET /api/graph/clusters/:clusterId?q=&notebookId=&chapterId=
+func GetGraphCluster(c *gin.Context) {
+	query, ok := graphQuery(c)
+	if !ok {
+		return
+	}
+
+	cluster, err := services.NewNoteLinkService().GetGraphCluster(query, c.Param("clusterId"))
+	if err != nil {
+		sendGraphError(c, err, "Failed to get graph cluster")
+		return
+	}
+
+	c.JSON(http.StatusOK, cluster)
+}
+
+// GetGraphNeighborhood returns the notes within depth links of a note and the links between them
+// GET /api/graph/neighborhood/:noteId?depth=2
+func GetGraphNeighborhood(c *gin.Context) {
+	query, ok := graphQuery(c)
+	if !ok {
+		return
+	}
+
+	var depth int
+	if value := c.Query("depth"); value != "" {
+		var err error
+		if depth, err = strconv.Atoi(value); err != nil || depth < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "depth must be a positive number"})
+			return
+		}
+	}
+
+	neighborhood, err := services.NewNoteLinkService().GetGraphNeighborhood(query, c.Param("noteId"), depth)
+	if err != nil {
+		sendGraphError(c, err, "Failed to get note neighborhood")
+		return
+	}
+
+	c.JSON(http.StatusOK, neighborhood)
+}
+
+// GetGraphChanges returns the notes and links of the graph that changed since a cursor
+// GET /api/graph/changes?since=&q=&notebookId=&chapterId=
+func GetGraphChanges(c *gin.Context) {
+	query, ok := graphQuery(c)
+	if !ok {
+		return
+	}
+
+	since, err := time.Parse(time.RFC3339Nano, c.Query("since"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+		return
+	}
+
+	changes, err := services.NewNoteLinkService().GetGraphChanges(query, since)
+	if err != nil {
+		sendGraphError(c, err, "Failed to get graph changes")
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// graphQuery reads the workspace and filters of a graph request, responding itself when they're invalid
+func graphQuery(c *gin.Context) (services.GraphQuery, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return services.GraphQuery{}, false
+	}
+
+	query := services.GraphQuery{
+		ClerkUserID: clerkUserID,
+		Search:      c.Query("q"),
+		NotebookID:  c.Query("notebookId"),
+		ChapterID:   c.Query("chapterId"),
+	}
+	if orgID, exists := middleware.GetOrganizationID(c); exists && orgID != "" {
+		query.OrganizationID = &orgID
+	}
+	for _, tags := range c.QueryArray("tags") {
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				query.Tags = append(query.Tags, tag)
+			}
+		}
+	}
+	for _, origins := range c.QueryArray("origins") {
+		for _, origin := range strings.Split(origins, ",") {
+			if origin = strings.TrimSpace(origin); origin == "" {
+				continue
+			}
+			if !slices.Contains(models.ValidLinkOrigins(), origin) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "origins must be manual, wiki, ai or glossary"})
+				return services.GraphQuery{}, false
+			}
+			query.Origins = append(query.Origins, origin)
+		}
+	}
+	if value := c.Query("modifiedSince"); value != "" {
+		modifiedSince, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "modifiedSince must be an RFC 3339 timestamp"})
+			return services.GraphQuery{}, false
+		}
+		query.ModifiedSince = modifiedSince
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return services.GraphQuery{}, false
+		}
+		query.Limit = limit
+	}
+	return query, true
+}
+
+// sendGraphError maps graph service errors to responses
+func sendGraphError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrGraphClusterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cluster not found, fetch the clusters again"})
+		return
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	middleware.ReportError(c, err, message)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ImportBookmarks imports a browser bookmarks export (Netscape HTML format) as notes.
+// Each bookmark becomes a note and each folder a chapter; link previews are fetched in the background.
+// With readingList set the bookmarks are added to the user's reading list instead.
+// POST /api/import/bookmarks
+func ImportBookmarks(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxBookmarkFileSize+(1<<20))
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A bookmarks file is required"})
+		return
+	}
+	if fileHeader.Size > services.MaxBookmarkFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Bookmarks file is too large"})
+		return
+	}
+
+	options := services.BookmarkImportOptions{
+		NotebookID:   c.PostForm("notebookId"),
+		NotebookName: c.PostForm("notebookName"),
+	}
+	options.Flatten, _ = strconv.ParseBool(c.PostForm("flatten"))
+	options.ReadingList, _ = strconv.ParseBool(c.PostForm("readingList"))
+
+	// Reading lists are personal, so only notebook imports need access to the notebook or organization
+	if options.ReadingList {
+		options.NotebookID = ""
+	} else if options.NotebookID != "" {
+		if !authorizeNotebook(c, options.NotebookID, clerkUserID, middleware.NoteAccessCanEdit) {
+			return
+		}
+	} else if orgID := c.PostForm("organizationId"); orgID != "" {
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
+		if err != nil || !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+			return
+		}
+		options.OrganizationID = &orgID
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read bookmarks file"})
+		return
+	}
+	defer file.Close()
+
+	bookmarkImportService := services.NewBookmarkImportService()
+	result, err := bookmarkImportService.ImportBookmarks(c.Request.Context(), clerkUserID, file, options)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBookmarkFile) || errors.Is(err, services.ErrTooManyBookmarks) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Str("user_id", clerkUserID).Msg("Failed to import bookmarks")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import bookmarks"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListInbox lists the snippets captured to the Inbox that haven't been filed yet, newest first.
+// Authenticates like POST /api/capture.
+// GET /api/inbox
+func ListInbox(c *gin.Context) {
+	scope, ok := captureScope(c)
+	if !ok {
+		return
+	}
+
+	items, err := services.NewAutomationService().ListInbox(c.Request.Context(), scope)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to fetch inbox")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// GetInboxSuggestion asks AI which chapter a captured snippet belongs in and what to name its note
+// GET /api/inbox/:id/suggestion
+func GetInboxSuggestion(c *gin.Context) {
+	scope, ok := captureScope(c)
+	if !ok {
+		return
+	}
+
+	suggestion, err := services.NewAutomationService().SuggestInboxDestination(c.Request.Context(), scope, c.Param("id"))
+	if err != nil {
+		sendAutomationError(c, err, "Failed to suggest where to file snippet")
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestion)
+}
+
+// FileInboxItem moves a captured snippet out of the Inbox into a new note of a chapter
+// POST /api/inbox/:id/file
+// Body: {"chapterId": "...", "title": "..."}, or {"suggest": true} to file it where AI suggests
+func FileInboxItem(c *gin.Context) {
+	scope, ok := captureScope(c)
+	if !ok {
+		return
+	}
+
+	var req services.InboxFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	note, err := services.NewAutomationService().FileCapture(c.Request.Context(), scope, c.Param("id"), req)
+	if err != nil {
+		sendAutomationError(c, err, "Failed to file snippet")
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// CloseIterationRequest represents the optional request body for closing an iteration
+type CloseIterationRequest struct {
+	RollToIterationID string `json:"rollToIterationId"` // Defaults to the board's next open iteration
+}
+
+// SetTaskIterationRequest represents the request body for moving a task to an iteration
+type SetTaskIterationRequest struct {
+	IterationID string `json:"iterationId"` // Empty moves the task back to the backlog
+}
+
+// CreateIteration adds an iteration to an organization board
+// POST /kanban/:boardId/iterations
+func CreateIteration(c *gin.Context) {
+	clerkUserID, boardID, ok := authorizeIterationBoard(c)
+	if !ok {
+		return
+	}
+
+	var input services.IterationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	iteration, err := services.NewIterationService().CreateIteration(boardID, clerkUserID, input)
+	if err != nil {
+		sendIterationError(c, err, "Failed to create iteration")
+		return
+	}
+
+	c.JSON(http.StatusCreated, iteration)
+}
+
+// GetIterations lists a board's iterations
+// GET /kanban/:boardId/iterations
+func GetIterations(c *gin.Context) {
+	_, boardID, ok := authorizeIterationBoard(c)
+	if !ok {
+		return
+	}
+
+	iterations, err := services.NewIterationService().ListIterations(boardID, time.Now())
+	if err != nil {
+		sendIterationError(c, err, "Failed to fetch iterations")
+		return
+	}
+
+	c.JSON(http.StatusOK, iterations)
+}
+
+// UpdateIteration renames or reschedules an open iteration
+// PUT /kanban/:boardId/iterations/:id
+func UpdateIteration(c *gin.Context) {
+	_, boardID, ok := authorizeIterationBoard(c)
+	if !ok {
+		return
+	}
+
+	var input services.IterationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	iteration, err := services.NewIterationService().UpdateIteration(boardID, c.Param("id"), input)
+	if err != nil {
+		sendIterationError(c, err, "Failed to update iteration")
+		return
+	}
+
+	c.JSON(http.StatusOK, iteration)
+}
+
+// DeleteIteration removes an iteration, moving its tasks back to the backlog
+// DELETE /kanban/:boardId/iterations/:id
+func DeleteIteration(c *gin.Context) {
+	_, boardID, ok := authorizeIterationBoard(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewIterationService().DeleteIteration(boardID, c.Param("id")); err != nil {
+		sendIterationError(c, err, "Failed to delete iteration")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Iteration deleted successfully"})
+}
+
+// CloseIteration closes an iteration and rolls its open tasks over
+// POST /kanban/:boardId/iterations/:id/close
+func CloseIteration(c *gin.Context) {
+	_, boardID, ok := authorizeIterationBoard(c)
+	if !ok {
+		return
+	}
+
+	var req CloseIterationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	result, err := services.NewIterationService().CloseIteration(boardID, c.Param("id"), strings.TrimSpace(req.RollToIterationID), time.Now())
+	if err != nil {
+		sendIterationError(c, err, "Failed to close iteration")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetIterationBurndown returns the burndown chart data of an iteration
+// GET /kanban/:boardId/iterations/:id/burndown
+func GetIterationBurndown(c *gin.Context) {
+	_, boardID, ok := authorizeIterationBoard(c)
+	if !ok {
+		return
+	}
+
+	burndown, err := services.NewIterationService().GetBurndown(boardID, c.Param("id"), time.Now())
+	if err != nil {
+		sendIterationError(c, err, "Failed to fetch burndown")
+		return
+	}
+
+	c.JSON(http.StatusOK, burndown)
+}
+
+// SetTaskIteration moves a task to an iteration of its board or back to the backlog
+// PUT /tasks/:taskId/iteration
+func SetTaskIteration(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	taskID := c.Param("taskId")
+	hasAccess, err := CheckTaskAccess(c.Request.Context(), db.DB, taskID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("task_id", taskID).Str("user_id", clerkUserID).Msg("User not authorized to update task")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req SetTaskIterationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	iterationID := strings.TrimSpace(req.IterationID)
+	if err := services.NewIterationService().SetTaskIteration(taskID, iterationID); err != nil {
+		sendIterationError(c, err, "Failed to update task iteration")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": taskID, "iterationId": iterationID})
+}
+
+// authorizeIterationBoard checks the user can access the board in the path
+func authorizeIterationBoard(c *gin.Context) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	boardID := c.Param("boardId")
+	hasAccess, err := CheckTaskBoardAccess(c.Request.Context(), db.DB, boardID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
+		return "", "", false
+	}
+	if !hasAccess {
+		log.Warn().Str("board_id", boardID).Str("user_id", clerkUserID).Msg("User not authorized to access task board")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return "", "", false
+	}
+	return clerkUserID, boardID, true
+}
+
+// sendIterationError maps iteration service errors to responses
+func sendIterationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidIteration), errors.Is(err, services.ErrIterationsRequireOrganization):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrIterationClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrIterationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
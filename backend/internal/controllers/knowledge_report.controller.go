@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestKnowledgeReport starts an AI analysis of the organization's notebooks and recent meeting notes
+// that finds undocumented topics and contradictory notes. The report is written to a note when done.
+// POST /organizations/:orgId/knowledge-report
+func RequestKnowledgeReport(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	orgID := c.Param("orgId")
+
+	var req struct {
+		Days int `json:"days"` // Meeting notes from this many days are analyzed, 30 by default
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	report, err := services.NewKnowledgeReportService().RequestReport(orgID, clerkUserID, req.Days)
+	if err != nil {
+		sendKnowledgeReportError(c, err, "Failed to start knowledge report")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"report": report})
+}
+
+// GetKnowledgeReport returns the status of a knowledge report and, once completed, its note
+// GET /organizations/:orgId/knowledge-report/:reportId
+func GetKnowledgeReport(c *gin.Context) {
+	report, err := services.NewKnowledgeReportService().GetReport(c.Param("orgId"), c.Param("reportId"))
+	if err != nil {
+		sendKnowledgeReportError(c, err, "Failed to fetch knowledge report")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// sendKnowledgeReportError maps knowledge report service errors to responses
+func sendKnowledgeReportError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrKnowledgeReportInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": "A knowledge report is already being generated"})
+	case errors.Is(err, services.ErrKnowledgeReportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge report not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotebookLinkReport returns the broken external links and stale note links found by the last check
+// of a notebook, with the fixes each can take
+// GET /notebook/:id/link-report
+func GetNotebookLinkReport(c *gin.Context) {
+	_, ok := checkLinkReportAccess(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	report, err := services.NewLinkCheckService().Report(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendLinkCheckError(c, err, "Failed to fetch link report")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// CheckNotebookLinks checks a notebook's links in the background rather than waiting for the weekly
+// check. The report shows the check is running until it's done.
+// POST /notebook/:id/link-report/check
+func CheckNotebookLinks(c *gin.Context) {
+	_, ok := checkLinkReportAccess(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	service := services.NewLinkCheckService()
+	if err := service.QueueCheck(c.Param("id")); err != nil {
+		sendLinkCheckError(c, err, "Failed to queue link check")
+		return
+	}
+	report, err := service.Report(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendLinkCheckError(c, err, "Failed to fetch link report")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, report)
+}
+
+// UpdateLinkIssueURL replaces a broken or moved URL in its note, with the URL it moved to when none
+// is given
+// POST /notebook/:id/link-report/issues/:issueId/update-url
+func UpdateLinkIssueURL(c *gin.Context) {
+	clerkUserID, ok := checkLinkReportAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		URL string `json:"url"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	note, err := services.NewLinkCheckService().UpdateURL(c.Request.Context(), c.Param("id"), c.Param("issueId"), req.URL, clerkUserID)
+	if err != nil {
+		sendLinkCheckError(c, err, "Failed to update link")
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+// RemoveLinkIssueLink takes a broken or stale link out of its note
+// POST /notebook/:id/link-report/issues/:issueId/remove-link
+func RemoveLinkIssueLink(c *gin.Context) {
+	clerkUserID, ok := checkLinkReportAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	note, err := services.NewLinkCheckService().RemoveLink(c.Request.Context(), c.Param("id"), c.Param("issueId"), clerkUserID)
+	if err != nil {
+		sendLinkCheckError(c, err, "Failed to remove link")
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+// RestoreLinkIssueTarget brings back the deleted note a link points at from the notebook's latest
+// snapshot holding it
+// POST /notebook/:id/link-report/issues/:issueId/restore-target
+func RestoreLinkIssueTarget(c *gin.Context) {
+	clerkUserID, ok := checkLinkReportAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	note, err := services.NewLinkCheckService().RestoreTarget(c.Request.Context(), c.Param("id"), c.Param("issueId"), clerkUserID)
+	if err != nil {
+		sendLinkCheckError(c, err, "Failed to restore linked note")
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// checkLinkReportAccess checks the user has the required access to the notebook, and responds when they
+// don't. Fixing a link changes its note, so fixes need edit access.
+func checkLinkReportAccess(c *gin.Context, required middleware.NoteAccess) (string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+
+	if !authorizeNotebook(c, c.Param("id"), clerkUserID, required) {
+		return "", false
+	}
+	return clerkUserID, true
+}
+
+// sendLinkCheckError maps link check errors to responses
+func sendLinkCheckError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidLinkURL), errors.Is(err, services.ErrLinkFixNotApplicable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLinkIssueNotFound), errors.Is(err, services.ErrNoteNotFound),
+		errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteLocked), errors.Is(err, services.ErrNotebookEncrypted),
+		errors.Is(err, services.ErrLinkCheckInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
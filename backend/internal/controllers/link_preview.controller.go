@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/models/dto"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// GetLinkPreview fetches OpenGraph/Twitter metadata for a URL so the editor can render a link card.
+// When a note ID is given the preview is saved on that note.
+// POST /api/link-preview
+func GetLinkPreview(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req dto.LinkPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.NoteID != nil && *req.NoteID == "" {
+		req.NoteID = nil
+	}
+	if req.NoteID != nil {
+		hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, *req.NoteID, clerkUserID)
+		if err != nil || !hasAccess {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+			return
+		}
+	}
+
+	linkPreviewService := services.NewLinkPreviewService()
+	link, err := linkPreviewService.PreviewLink(c.Request.Context(), req.URL, req.NoteID, clerkUserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidLinkURL):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLinkPreviewBlocked):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This link can't be previewed"})
+		case errors.Is(err, services.ErrLinkPreviewFetchFailed):
+			log.Warn().Err(err).Str("url", req.URL).Msg("Failed to fetch link preview")
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch link"})
+		default:
+			log.Error().Err(err).Str("url", req.URL).Msg("Failed to create link preview")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link preview"})
+		}
+		return
+	}
+
+	// Previews saved on a note follow the note's organization; others follow the active workspace
+	if req.NoteID == nil {
+		if orgID := middleware.GetActiveOrgID(c); orgID != "" {
+			linkPreviewService.ApplyEmbedPolicy(c.Request.Context(), link, &orgID)
+		}
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// UpdateEmbedSettingsRequest represents the request body for updating an organization's embed allowlist
+type UpdateEmbedSettingsRequest struct {
+	AllowedProviders []string `json:"allowedProviders"`
+}
+
+// GetOrgEmbedSettings returns which providers' links are embedded in the organization's notes
+// GET /organizations/:orgId/embed-settings
+func GetOrgEmbedSettings(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	allowed, err := services.NewLinkPreviewService().GetEmbedSettings(c.Request.Context(), orgID)
+	if err != nil {
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to fetch embed settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch embed settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"allowedProviders": allowed,
+		"providers":        models.EmbedProviders,
+	})
+}
+
+// UpdateOrgEmbedSettings replaces the organization's embed allowlist
+// PUT /organizations/:orgId/embed-settings
+func UpdateOrgEmbedSettings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	orgID := c.Param("orgId")
+
+	var req UpdateEmbedSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	allowed, err := services.NewLinkPreviewService().SaveEmbedSettings(c.Request.Context(), orgID, req.AllowedProviders, clerkUserID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidEmbedProvider) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to save embed settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save embed settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"allowedProviders": allowed,
+		"providers":        models.EmbedProviders,
+	})
+}
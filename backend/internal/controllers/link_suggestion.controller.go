@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPendingLinkSuggestions returns the links suggested between the user's notes waiting for review
+// GET /api/link-suggestions/pending
+func GetPendingLinkSuggestions(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	suggestions, err := services.NewLinkSuggestionService().ListPending(c.Request.Context(), clerkUserID)
+	if err != nil {
+		sendLinkSuggestionError(c, err, "Failed to fetch link suggestions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// AcceptLinkSuggestion links the notes of a suggestion
+// POST /api/link-suggestions/:id/accept
+func AcceptLinkSuggestion(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	link, err := services.NewLinkSuggestionService().Accept(c.Request.Context(), c.Param("id"), clerkUserID)
+	if err != nil {
+		sendLinkSuggestionError(c, err, "Failed to accept link suggestion")
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// DismissLinkSuggestion turns a suggestion down, so its notes aren't suggested again
+// POST /api/link-suggestions/:id/dismiss
+func DismissLinkSuggestion(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := services.NewLinkSuggestionService().Dismiss(c.Request.Context(), c.Param("id"), clerkUserID); err != nil {
+		sendLinkSuggestionError(c, err, "Failed to dismiss link suggestion")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Link suggestion dismissed"})
+}
+
+// sendLinkSuggestionError maps link suggestion errors to responses
+func sendLinkSuggestionError(c *gin.Context, err error, message string) {
+	if errors.Is(err, services.ErrLinkSuggestionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link suggestion not found"})
+		return
+	}
+	middleware.ReportError(c, err, message)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -8,6 +8,7 @@ import (
 	"backend/pkg/recallai"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 
@@ -69,7 +70,7 @@ func GetUserMeetings(ctx *gin.Context) {
 
 	var meetings []models.MeetingRecording
 	// Remove Preload to avoid loading full note with chapter and notebook
-	err := db.DB.Where("clerk_user_id = ?", clerkUserID).
+	err := db.DB.WithContext(ctx.Request.Context()).Where("clerk_user_id = ?", clerkUserID).
 		Order("created_at DESC").
 		Find(&meetings).Error
 
@@ -94,6 +95,7 @@ func GetUserMeetings(ctx *gin.Context) {
 			"recallRecordingId":     meeting.RecallRecordingID,
 			"transcriptDownloadUrl": meeting.TranscriptDownloadURL,
 			"videoDownloadUrl":      meeting.VideoDownloadURL,
+			"videoArchivedAt":       meeting.VideoArchivedAt,
 			"generatedNoteId":       meeting.GeneratedNoteID,
 			"createdAt":             meeting.CreatedAt,
 			"updatedAt":             meeting.UpdatedAt,
@@ -120,14 +122,14 @@ func StartMeetingRecording(ctx *gin.Context) {
 
 	var req StartRecordingRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		log.Print("Invalid request data for meeting recording: ", err)
+		middleware.Logger(ctx).Warn().Err(err).Msg("Invalid request data for meeting recording")
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
 		return
 	}
 
 	// Validate meeting URL format
 	if !isValidMeetingURL(req.MeetingURL) {
-		log.Print("Invalid meeting URL format: ", req.MeetingURL)
+		middleware.Logger(ctx).Warn().Str("meeting_url", req.MeetingURL).Msg("Invalid meeting URL format")
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meeting URL format"})
 		return
 	}
@@ -137,11 +139,31 @@ func StartMeetingRecording(ctx *gin.Context) {
 		Str("meeting_url", req.MeetingURL).
 		Msg("Starting meeting recording")
 
+	// Transcribe with Recall.ai unless the user's organizations opted out
+	provider, err := services.NewTranscriptionSettingsService().ProviderForUser(ctx.Request.Context(), clerkUserID)
+	if err != nil {
+		if errors.Is(err, services.ErrWhisperNotConfigured) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Your organization transcribes meetings with Whisper, which isn't available on this server"})
+			return
+		}
+		middleware.ReportError(ctx, err, "Failed to choose transcription provider")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to choose transcription provider"})
+		return
+	}
+
+	// Name the bot and announce it as the user's organizations configured
+	botOptions, err := services.NewBotSettingsService().OptionsForUser(ctx.Request.Context(), clerkUserID)
+	if err != nil {
+		middleware.ReportError(ctx, err, "Failed to load bot settings")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load bot settings"})
+		return
+	}
+
 	// Create Recall.ai client and bot
 	recallClient := recallai.NewClient()
 
 	// Create bot in Recall.ai
-	botResp, err := recallClient.CreateBot(req.MeetingURL)
+	botResp, err := recallClient.CreateBot(req.MeetingURL, provider == models.TranscriptionProviderRecall, botOptions)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -154,13 +176,14 @@ func StartMeetingRecording(ctx *gin.Context) {
 
 	// Save meeting recording to database
 	recording := &models.MeetingRecording{
-		ClerkUserID: clerkUserID,
-		BotID:       botResp.ID,
-		MeetingURL:  req.MeetingURL,
-		Status:      "pending",
+		ClerkUserID:           clerkUserID,
+		BotID:                 botResp.ID,
+		MeetingURL:            req.MeetingURL,
+		Status:                "pending",
+		TranscriptionProvider: provider,
 	}
 
-	if err := db.DB.Create(recording).Error; err != nil {
+	if err := db.DB.WithContext(ctx.Request.Context()).Create(recording).Error; err != nil {
 		log.Error().
 			Err(err).
 			Str("clerk_user_id", clerkUserID).
@@ -198,7 +221,7 @@ func GetMeetingTranscript(ctx *gin.Context) {
 
 	// Find the meeting recording
 	var recording models.MeetingRecording
-	if err := db.DB.Where("id = ? AND clerk_user_id = ?", meetingID, clerkUserID).First(&recording).Error; err != nil {
+	if err := db.DB.WithContext(ctx.Request.Context()).Where("id = ? AND clerk_user_id = ?", meetingID, clerkUserID).First(&recording).Error; err != nil {
 		log.Error().
 			Err(err).
 			Str("meeting_id", meetingID).
@@ -208,48 +231,68 @@ func GetMeetingTranscript(ctx *gin.Context) {
 		return
 	}
 
-	// Fetch fresh download URL from Recall.ai (pre-signed URLs expire)
-	recallClient := recallai.NewClient()
-	botDetails, err := recallClient.GetBot(recording.BotID)
-	if err != nil {
-		log.Error().
-			Err(err).
-			Str("meeting_id", meetingID).
-			Str("bot_id", recording.BotID).
-			Msg("Failed to fetch bot details from Recall.ai")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meeting details"})
-		return
-	}
+	var transcript []recallai.TranscriptEntry
+	if recording.TranscriptionProvider == models.TranscriptionProviderWhisper {
+		// Whisper transcripts aren't kept by Recall.ai, so the recorded audio is transcribed again
+		if recording.Status != "completed" {
+			ctx.JSON(http.StatusOK, gin.H{
+				"transcript": nil,
+				"status":     recording.Status,
+				"message":    "Transcript not yet available",
+			})
+			return
+		}
+		var err error
+		transcript, err = services.NewMeetingNoteService().TranscribeMeeting(ctx.Request.Context(), &recording)
+		if err != nil {
+			middleware.ReportError(ctx, err, "Failed to transcribe meeting")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transcribe meeting"})
+			return
+		}
+	} else {
+		// Fetch fresh download URL from Recall.ai (pre-signed URLs expire)
+		recallClient := recallai.NewClient()
+		botDetails, err := recallClient.GetBot(recording.BotID)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("meeting_id", meetingID).
+				Str("bot_id", recording.BotID).
+				Msg("Failed to fetch bot details from Recall.ai")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meeting details"})
+			return
+		}
 
-	// Check if recordings exist and get fresh transcript URL
-	if len(botDetails.Recordings) == 0 {
-		ctx.JSON(http.StatusOK, gin.H{
-			"transcript": nil,
-			"status":     recording.Status,
-			"message":    "Transcript not yet available",
-		})
-		return
-	}
+		// Check if recordings exist and get fresh transcript URL
+		if len(botDetails.Recordings) == 0 {
+			ctx.JSON(http.StatusOK, gin.H{
+				"transcript": nil,
+				"status":     recording.Status,
+				"message":    "Transcript not yet available",
+			})
+			return
+		}
 
-	transcriptURL := botDetails.Recordings[0].MediaShortcuts.Transcript.Data.DownloadURL
-	if transcriptURL == "" {
-		ctx.JSON(http.StatusOK, gin.H{
-			"transcript": nil,
-			"status":     recording.Status,
-			"message":    "Transcript not yet available",
-		})
-		return
-	}
+		transcriptURL := botDetails.Recordings[0].MediaShortcuts.Transcript.Data.DownloadURL
+		if transcriptURL == "" {
+			ctx.JSON(http.StatusOK, gin.H{
+				"transcript": nil,
+				"status":     recording.Status,
+				"message":    "Transcript not yet available",
+			})
+			return
+		}
 
-	// Download and parse transcript using fresh URL
-	transcript, err := recallClient.DownloadTranscript(transcriptURL)
-	if err != nil {
-		log.Error().
-			Err(err).
-			Str("meeting_id", meetingID).
-			Msg("Failed to download transcript")
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download transcript"})
-		return
+		// Download and parse transcript using fresh URL
+		transcript, err = recallClient.DownloadTranscript(transcriptURL)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("meeting_id", meetingID).
+				Msg("Failed to download transcript")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download transcript"})
+			return
+		}
 	}
 
 	// Format transcript for display
@@ -360,7 +403,7 @@ func HandleRecallWebhook(ctx *gin.Context) {
 
 		// Update the recording status
 		var recording models.MeetingRecording
-		if err := db.DB.Where("bot_id = ?", botID).First(&recording).Error; err != nil {
+		if err := db.DB.WithContext(ctx.Request.Context()).Where("bot_id = ?", botID).First(&recording).Error; err != nil {
 			log.Error().
 				Err(err).
 				Str("bot_id", botID).
@@ -444,7 +487,7 @@ func HandleRecallWebhook(ctx *gin.Context) {
 
 		// Update status to completed
 		recording.Status = "completed"
-		if err := db.DB.Save(&recording).Error; err != nil {
+		if err := db.DB.WithContext(ctx.Request.Context()).Save(&recording).Error; err != nil {
 			log.Error().
 				Err(err).
 				Str("recording_id", recording.ID).
@@ -458,26 +501,7 @@ func HandleRecallWebhook(ctx *gin.Context) {
 		}
 
 		// Trigger note generation asynchronously
-		go func() {
-			noteService := services.NewMeetingNoteService()
-			ctx := context.Background()
-
-			log.Info().
-				Str("meeting_id", recording.ID).
-				Msg("Starting automatic note generation from transcript")
-
-			if err := noteService.ProcessMeetingTranscript(ctx, &recording); err != nil {
-				log.Error().
-					Err(err).
-					Str("meeting_id", recording.ID).
-					Msg("Failed to generate note from transcript")
-			} else {
-				log.Info().
-					Str("meeting_id", recording.ID).
-					Str("note_id", *recording.GeneratedNoteID).
-					Msg("Successfully generated note from transcript")
-			}
-		}()
+		go generateMeetingNote(recording)
 
 		ctx.JSON(http.StatusOK, gin.H{"status": "received", "message": "Transcript processing completed"})
 		return
@@ -496,13 +520,21 @@ func HandleRecallWebhook(ctx *gin.Context) {
 
 		if botID != "" {
 			var recording models.MeetingRecording
-			if err := db.DB.Where("bot_id = ?", botID).First(&recording).Error; err == nil {
+			if err := db.DB.WithContext(ctx.Request.Context()).Where("bot_id = ?", botID).First(&recording).Error; err == nil {
 				recording.Status = "completed"
-				db.DB.Save(&recording)
+				db.DB.WithContext(ctx.Request.Context()).Save(&recording)
 				log.Info().
 					Str("recording_id", recording.ID).
 					Str("event_type", eventType).
 					Msg("Updated recording status to completed")
+
+				// Whisper recordings get no transcript.done, so their audio is transcribed once the bot is done
+				if eventType == "bot.done" && recording.TranscriptionProvider == models.TranscriptionProviderWhisper && recording.GeneratedNoteID == nil {
+					go generateMeetingNote(recording)
+				}
+				if eventType == "bot.done" {
+					go archiveMeetingVideo(recording.ID)
+				}
 			}
 		}
 	}
@@ -520,9 +552,9 @@ func HandleRecallWebhook(ctx *gin.Context) {
 
 		if botID != "" {
 			var recording models.MeetingRecording
-			if err := db.DB.Where("bot_id = ?", botID).First(&recording).Error; err == nil {
+			if err := db.DB.WithContext(ctx.Request.Context()).Where("bot_id = ?", botID).First(&recording).Error; err == nil {
 				recording.Status = "recording"
-				db.DB.Save(&recording)
+				db.DB.WithContext(ctx.Request.Context()).Save(&recording)
 				log.Info().
 					Str("recording_id", recording.ID).
 					Msg("Updated recording status to recording")
@@ -548,7 +580,7 @@ func HandleRecallWebhook(ctx *gin.Context) {
 
 			// Find calendar in database
 			var calendar models.Calendar
-			if err := db.DB.Where("recall_calendar_id = ?", calendarID).First(&calendar).Error; err != nil {
+			if err := db.DB.WithContext(ctx.Request.Context()).Where("recall_calendar_id = ?", calendarID).First(&calendar).Error; err != nil {
 				log.Error().
 					Err(err).
 					Str("recall_calendar_id", calendarID).
@@ -590,7 +622,7 @@ func BackfillVideoURLs(ctx *gin.Context) {
 
 	// Find all completed meetings for this user that have recall_recording_id but missing video_download_url
 	var meetings []models.MeetingRecording
-	err := db.DB.Where("clerk_user_id = ? AND status = ? AND recall_recording_id != ? AND (video_download_url IS NULL OR video_download_url = ?)",
+	err := db.DB.WithContext(ctx.Request.Context()).Where("clerk_user_id = ? AND status = ? AND recall_recording_id != ? AND (video_download_url IS NULL OR video_download_url = ?)",
 		clerkUserID, "completed", "", "").
 		Find(&meetings).Error
 
@@ -671,7 +703,7 @@ func BackfillVideoURLs(ctx *gin.Context) {
 
 		// Update the meeting record
 		meeting.VideoDownloadURL = videoURL
-		if err := db.DB.Save(&meeting).Error; err != nil {
+		if err := db.DB.WithContext(ctx.Request.Context()).Save(&meeting).Error; err != nil {
 			log.Error().
 				Err(err).
 				Str("meeting_id", meeting.ID).
@@ -708,3 +740,36 @@ func BackfillVideoURLs(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, response)
 }
+
+// generateMeetingNote transcribes a finished meeting and creates a note from it, in the background
+func generateMeetingNote(recording models.MeetingRecording) {
+	noteService := services.NewMeetingNoteService()
+	ctx := context.Background()
+
+	log.Info().
+		Str("meeting_id", recording.ID).
+		Msg("Starting automatic note generation from transcript")
+
+	if err := noteService.ProcessMeetingTranscript(ctx, &recording); err != nil {
+		log.Error().
+			Err(err).
+			Str("meeting_id", recording.ID).
+			Msg("Failed to generate note from transcript")
+	} else {
+		log.Info().
+			Str("meeting_id", recording.ID).
+			Str("note_id", *recording.GeneratedNoteID).
+			Msg("Successfully generated note from transcript")
+	}
+}
+
+// archiveMeetingVideo copies a finished meeting's video to the archive when archiving is enabled, in the
+// background
+func archiveMeetingVideo(recordingID string) {
+	if err := services.NewMeetingVideoService().ArchiveVideo(context.Background(), recordingID); err != nil {
+		log.Error().
+			Err(err).
+			Str("meeting_id", recordingID).
+			Msg("Failed to archive meeting video")
+	}
+}
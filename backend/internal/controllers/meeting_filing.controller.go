@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPendingMeetingFilings returns the meeting notes suggested to be filed in project chapters, waiting
+// for review
+// GET /api/meeting-filing/pending
+func GetPendingMeetingFilings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	filings, err := services.NewMeetingFilingService().ListPending(c.Request.Context(), clerkUserID)
+	if err != nil {
+		sendMeetingFilingError(c, err, "Failed to fetch meeting filings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filings": filings})
+}
+
+// AcceptMeetingFiling moves a meeting note to the chapter it was suggested to be filed in
+// POST /api/meeting-filing/:id/accept
+func AcceptMeetingFiling(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	note, err := services.NewMeetingFilingService().Accept(c.Request.Context(), c.Param("id"), clerkUserID)
+	if err != nil {
+		sendMeetingFilingError(c, err, "Failed to accept meeting filing")
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+// DismissMeetingFiling leaves a meeting note where it was generated
+// POST /api/meeting-filing/:id/dismiss
+func DismissMeetingFiling(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := services.NewMeetingFilingService().Dismiss(c.Request.Context(), c.Param("id"), clerkUserID); err != nil {
+		sendMeetingFilingError(c, err, "Failed to dismiss meeting filing")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Meeting filing dismissed"})
+}
+
+// sendMeetingFilingError maps meeting filing errors to responses
+func sendMeetingFilingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrMeetingFilingNotFound), errors.Is(err, services.ErrChapterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotebookEncrypted), errors.Is(err, services.ErrLegalHold):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListMeetingSeries returns the user's recurring meetings
+// GET /api/meeting-series
+func ListMeetingSeries(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	series, err := services.NewMeetingSeriesService().ListSeries(c.Request.Context(), clerkUserID)
+	if err != nil {
+		sendMeetingSeriesError(c, err, "Failed to fetch meeting series")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"series": series})
+}
+
+// GetMeetingSeriesTimeline returns a recurring meeting's occurrences with the notes of the recorded ones
+// GET /api/meeting-series/:seriesId
+func GetMeetingSeriesTimeline(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	timeline, err := services.NewMeetingSeriesService().GetTimeline(c.Request.Context(), clerkUserID, c.Param("seriesId"))
+	if err != nil {
+		sendMeetingSeriesError(c, err, "Failed to fetch meeting series")
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// SummarizeMeetingSeries asks the AI what changed in the latest recorded meeting of a series since the
+// ones before it
+// POST /api/meeting-series/:seriesId/summary
+func SummarizeMeetingSeries(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	changes, err := services.NewMeetingSeriesService().SummarizeChanges(c.Request.Context(), clerkUserID, c.Param("seriesId"))
+	if err != nil {
+		sendMeetingSeriesError(c, err, "Failed to summarize meeting series")
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// sendMeetingSeriesError maps meeting series service errors to HTTP responses
+func sendMeetingSeriesError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrMeetingSeriesNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meeting series not found"})
+	case errors.Is(err, services.ErrNotEnoughSeriesMeetings):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least two meetings of the series need to be recorded to compare them"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
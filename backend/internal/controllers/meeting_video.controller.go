@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Headers passed through when streaming a meeting video, so players can seek
+var proxiedVideoHeaders = []string{"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Last-Modified"}
+
+// GetMeetingVideo serves one of the user's meeting videos. Archived videos are served directly. Otherwise
+// the client is redirected to a freshly signed Recall.ai URL, or with ?stream=true the video is streamed
+// through the server so the URL isn't exposed.
+// GET /meeting/:id/video
+func GetMeetingVideo(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	video, err := services.NewMeetingVideoService().GetVideo(c.Request.Context(), clerkUserID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrMeetingNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
+		case errors.Is(err, services.ErrVideoNotAvailable):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not yet available"})
+		default:
+			middleware.ReportError(c, err, "Failed to fetch meeting video")
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch meeting video"})
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	switch {
+	case video.ArchivePath != "":
+		c.File(video.ArchivePath)
+	case c.Query("stream") == "true":
+		streamMeetingVideo(c, video.DownloadURL)
+	default:
+		c.Redirect(http.StatusFound, video.DownloadURL)
+	}
+}
+
+// streamMeetingVideo proxies a video download, forwarding range requests
+func streamMeetingVideo(c *gin.Context, downloadURL string) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, downloadURL, nil)
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to stream meeting video")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stream meeting video"})
+		return
+	}
+	for _, header := range []string{"Range", "If-Range"} {
+		if value := c.GetHeader(header); value != "" {
+			req.Header.Set(header, value)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if !errors.Is(c.Request.Context().Err(), context.Canceled) {
+			middleware.ReportError(c, err, "Failed to stream meeting video")
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to stream meeting video"})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		log.Error().
+			Int("status_code", resp.StatusCode).
+			Str("meeting_id", c.Param("id")).
+			Msg("Recall.ai refused meeting video download")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to stream meeting video"})
+		return
+	}
+
+	for _, header := range proxiedVideoHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			c.Header(header, value)
+		}
+	}
+	c.Status(resp.StatusCode)
+	// Copy errors are the client going away mid-stream
+	_, _ = io.Copy(c.Writer, resp.Body)
+}
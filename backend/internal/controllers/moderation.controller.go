@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ReviewModerationFindingRequest is the body of a moderation finding review
+type ReviewModerationFindingRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// GetOrgModerationPolicy returns how the organization's content is scanned for secrets and personal data
+// GET /organizations/:orgId/moderation-policy
+func GetOrgModerationPolicy(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	policy, err := services.NewModerationService().GetPolicy(orgID)
+	if err != nil {
+		sendModerationError(c, err, "Failed to fetch moderation policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateOrgModerationPolicy sets whether secrets and personal data are flagged or blocked
+// PUT /organizations/:orgId/moderation-policy
+func UpdateOrgModerationPolicy(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	orgID := c.Param("orgId")
+
+	var input services.ModerationPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	policy, err := services.NewModerationService().SavePolicy(orgID, input, clerkUserID)
+	if err != nil {
+		sendModerationError(c, err, "Failed to save moderation policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// ListModerationFindings returns the organization's review queue, optionally filtered by ?status=
+// GET /organizations/:orgId/moderation-findings
+func ListModerationFindings(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	findings, err := services.NewModerationService().ListFindings(orgID, c.Query("status"))
+	if err != nil {
+		sendModerationError(c, err, "Failed to fetch moderation findings")
+		return
+	}
+
+	c.JSON(http.StatusOK, findings)
+}
+
+// ReviewModerationFinding resolves or dismisses a finding, or reopens it
+// PUT /organizations/:orgId/moderation-findings/:findingId
+func ReviewModerationFinding(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	orgID := c.Param("orgId")
+
+	var req ReviewModerationFindingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	finding, err := services.NewModerationService().ReviewFinding(orgID, c.Param("findingId"), req.Status, clerkUserID)
+	if err != nil {
+		sendModerationError(c, err, "Failed to update moderation finding")
+		return
+	}
+
+	c.JSON(http.StatusOK, finding)
+}
+
+// checkModeration scans text written to an organization workspace. It writes a 422 response and returns
+// false when the organization's policy blocks what was found. Personal workspaces aren't scanned.
+func checkModeration(c *gin.Context, organizationID *string, text string) (*services.ModerationResult, bool) {
+	if organizationID == nil || *organizationID == "" {
+		return nil, true
+	}
+
+	result, err := services.NewModerationService().Check(*organizationID, text)
+	if err != nil {
+		// A moderation outage shouldn't stop people from writing
+		log.Warn().Err(err).Str("org_id", *organizationID).Msg("Failed to check content moderation")
+		return nil, true
+	}
+	if result.Blocked {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "This content contains secrets or personal data that your organization doesn't allow. Remove them and try again.",
+			"findings": result.Matches,
+		})
+		return result, false
+	}
+	return result, true
+}
+
+// recordModeration adds the findings of saved content to the review queue and runs the AI classifier
+// in the background. Failures are logged, the content is already saved.
+func recordModeration(organizationID *string, target services.ModerationTarget, result *services.ModerationResult, text string) {
+	if organizationID == nil || *organizationID == "" {
+		return
+	}
+	orgID := *organizationID
+
+	moderation := services.NewModerationService()
+	if err := moderation.Record(orgID, target, result); err != nil {
+		log.Warn().Err(err).Str("org_id", orgID).Msg("Failed to record moderation findings")
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := moderation.Classify(ctx, orgID, target, text); err != nil {
+			log.Warn().Err(err).Str("org_id", orgID).Msg("Failed to classify content for moderation")
+		}
+	}()
+}
+
+// sendModerationError maps moderation service errors to responses
+func sendModerationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidModerationPolicy),
+		errors.Is(err, services.ErrInvalidModerationStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrModerationFindingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Moderation finding not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNoteAnnotations returns a note's highlights and margin notes, anchored to its current content
+// GET /note/:id/annotations
+func GetNoteAnnotations(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanView); !ok {
+		return
+	}
+
+	annotations, err := services.NewNoteAnnotationService().List(c.Request.Context(), noteID)
+	if err != nil {
+		sendAnnotationError(c, err, "Failed to fetch annotations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"annotations": annotations})
+}
+
+// CreateNoteAnnotation highlights a passage of a clipped note, with an optional margin note
+// POST /note/:id/annotations
+func CreateNoteAnnotation(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	var req services.NoteAnnotationInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	annotation, err := services.NewNoteAnnotationService().Create(c.Request.Context(), noteID, req, clerkUserID)
+	if err != nil {
+		sendAnnotationError(c, err, "Failed to save annotation")
+		return
+	}
+
+	c.JSON(http.StatusCreated, annotation)
+}
+
+// UpdateNoteAnnotation changes an annotation's margin note and color
+// PUT /note/:id/annotations/:annotationId
+func UpdateNoteAnnotation(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	var req services.NoteAnnotationInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	annotation, err := services.NewNoteAnnotationService().Update(c.Request.Context(), noteID, c.Param("annotationId"), req)
+	if err != nil {
+		sendAnnotationError(c, err, "Failed to update annotation")
+		return
+	}
+
+	c.JSON(http.StatusOK, annotation)
+}
+
+// DeleteNoteAnnotation removes an annotation from a note
+// DELETE /note/:id/annotations/:annotationId
+func DeleteNoteAnnotation(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	if err := services.NewNoteAnnotationService().Delete(c.Request.Context(), noteID, c.Param("annotationId")); err != nil {
+		sendAnnotationError(c, err, "Failed to delete annotation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Annotation deleted"})
+}
+
+// CreateHighlightsDigest collects the highlights of a notebook's notes into a new note in its Highlights chapter
+// POST /notebook/:id/highlights/digest
+func CreateHighlightsDigest(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notebookID := c.Param("id")
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil || !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	note, err := services.NewNoteAnnotationService().NotebookDigest(c.Request.Context(), notebookID)
+	if err != nil {
+		sendAnnotationError(c, err, "Failed to create highlights digest")
+		return
+	}
+
+	services.NewAutomationService().NoteChanged(note.ID, true)
+	services.NewNoteSummaryService().NoteChanged(note.ID)
+	services.NewNoteLanguageService().NoteChanged(note.ID)
+	services.NewGlossaryService().NoteChanged(note.ID)
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// sendAnnotationError maps annotation errors to responses
+func sendAnnotationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAnnotation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAnnotationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Annotation not found"})
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+	case errors.Is(err, services.ErrNoteNotClipped), errors.Is(err, services.ErrNoteLocked), errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// EmailNote emails a note rendered as HTML to the recipients, optionally with the note attached as a
+// PDF, Word document or markdown file. Replies go to the sender's address.
+// POST /note/:id/email
+// Body: {"recipients": ["a@example.com"], "subject": "...", "message": "...", "includePdf": true,
+// "includeDocx": false, "includeMarkdown": false}
+func EmailNote(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to email note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req services.NoteEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	sender := services.NoteEmailSender{UserID: clerkUserID}
+	if user, err := middleware.GetUserCached(c.Request.Context(), clerkUserID); err == nil && user != nil {
+		var nameParts []string
+		if user.FirstName != nil && *user.FirstName != "" {
+			nameParts = append(nameParts, *user.FirstName)
+		}
+		if user.LastName != nil && *user.LastName != "" {
+			nameParts = append(nameParts, *user.LastName)
+		}
+		sender.Name = strings.Join(nameParts, " ")
+		for _, address := range user.EmailAddresses {
+			if user.PrimaryEmailAddressID != nil && address.ID == *user.PrimaryEmailAddressID {
+				sender.Email = address.EmailAddress
+			}
+		}
+	}
+
+	sent, err := services.NewNoteEmailService().Send(c.Request.Context(), noteID, sender, req)
+	if err != nil {
+		if errors.Is(err, services.ErrNoteEmailNotSent) {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "email": sent})
+			return
+		}
+		sendNoteEmailError(c, err, "Failed to email note")
+		return
+	}
+
+	c.JSON(http.StatusOK, sent)
+}
+
+// GetNoteEmails lists who a note was emailed to, newest first
+// GET /note/:id/emails
+func GetNoteEmails(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to view note emails")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	emails, err := services.NewNoteEmailService().ListEmails(c.Request.Context(), noteID)
+	if err != nil {
+		sendNoteEmailError(c, err, "Failed to fetch note emails")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"emails": emails})
+}
+
+// sendNoteEmailError maps note email service errors to responses
+func sendNoteEmailError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	case errors.Is(err, services.ErrInvalidNoteEmail):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotebookEncrypted), errors.Is(err, services.ErrNoteEmailLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteEmailRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEmailNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
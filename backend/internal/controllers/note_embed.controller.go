@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/internal/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// GetNoteEmbeddedIn lists the notes that embed a note, so authors can see where an edit will show up
+// GET /note/:id/embedded-in
+func GetNoteEmbeddedIn(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to access note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	usages, err := services.NewNoteEmbedService().ListEmbeddedIn(noteID)
+	if err != nil {
+		log.Error().Err(err).Str("note_id", noteID).Msg("Failed to fetch note embeds")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch note embeds"})
+		return
+	}
+
+	// Only show the embedding notes the user can open
+	visible := make([]services.NoteEmbedUsage, 0, len(usages))
+	for _, usage := range usages {
+		if ok, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, usage.NoteID, clerkUserID); err == nil && ok {
+			visible = append(visible, usage)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"embeddedIn": visible, "hiddenCount": len(usages) - len(visible)})
+}
+
+// GetRenderedNote returns a note with its embeds resolved to the current content of the embedded notes
+// and its formulas rendered to MathML
+// GET /note/:id/rendered
+func GetRenderedNote(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil || !hasAccess {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to access note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var note models.Notes
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", noteID).First(&note).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+
+	content, err := services.NewNoteEmbedService().RenderContent(note.ID, note.Content, func(embedded *models.Notes) bool {
+		ok, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, embedded.ID, clerkUserID)
+		return err == nil && ok
+	})
+	if err != nil {
+		log.Error().Err(err).Str("note_id", noteID).Msg("Failed to render note")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render note"})
+		return
+	}
+	content = services.NewDiagramService().RenderContent(c.Request.Context(), content)
+	note.Content = utils.RenderTipTapMath(content)
+
+	c.JSON(http.StatusOK, note)
+}
+
+// validateNoteEmbeds responds with a bad request when new content would embed the note in itself
+func validateNoteEmbeds(c *gin.Context, noteID, content string) bool {
+	if content == "" {
+		return true
+	}
+	if err := services.NewNoteEmbedService().ValidateEmbeds(noteID, content); err != nil {
+		if errors.Is(err, services.ErrEmbedCycle) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+		log.Error().Err(err).Str("note_id", noteID).Msg("Failed to validate note embeds")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate note embeds"})
+		return false
+	}
+	return true
+}
+
+// syncNoteEmbeds records the embeds of saved content. Failures are logged, the content is already saved.
+func syncNoteEmbeds(noteID, content, clerkUserID string) {
+	if err := services.NewNoteEmbedService().SyncEmbeds(noteID, content, clerkUserID); err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to sync note embeds")
+	}
+}
+
+// publicEmbedVisible reports whether an embedded note is published along with its chapter and notebook
+func publicEmbedVisible(note *models.Notes) bool {
+	return note.IsPublic && note.Chapter.IsPublic && note.Chapter.Notebook.IsPublic
+}
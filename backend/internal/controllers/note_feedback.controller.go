@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// SubmitPublicNoteFeedback records a reader's anonymous reaction and/or comment on a public note
+// POST /public/:id/feedback
+func SubmitPublicNoteFeedback(c *gin.Context) {
+	var input services.NoteFeedbackInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := services.NewNoteFeedbackService().SubmitFeedback(c.Request.Context(), c.Param("id"), c.ClientIP(), input); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidNoteFeedback):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPublicNoteNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Note not found or not public"})
+		case errors.Is(err, services.ErrNoteFeedbackLimitReached):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			middleware.ReportError(c, err, "Failed to save feedback")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save feedback"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Thanks for your feedback"})
+}
+
+// GetNotebookFeedback returns the reactions and comments readers left on a notebook's public notes, per note
+// GET /notebook/:id/feedback
+func GetNotebookFeedback(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notebookID := c.Param("id")
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User not authorized to access notebook feedback")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	feedback, err := services.NewNoteFeedbackService().NotebookFeedback(c.Request.Context(), notebookID)
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch feedback")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch feedback"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notes": feedback})
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// NoteStatusRequest represents the request body for changing a note's status
+type NoteStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// NoteReviewRequest represents the request body for requesting, approving or rejecting a review
+type NoteReviewRequest struct {
+	Comment string `json:"comment"`
+}
+
+// UpdatePublishingSettingsRequest represents the request body for an organization's publishing settings
+type UpdatePublishingSettingsRequest struct {
+	RequireApproval bool `json:"requireApproval"`
+}
+
+// noteLifecycleAccess checks the user has the required access to the note and returns its ID
+func noteLifecycleAccess(c *gin.Context, required middleware.NoteAccess) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	noteID := c.Param("id")
+	if !authorizeNote(c, noteID, clerkUserID, required) {
+		return "", "", false
+	}
+
+	return noteID, clerkUserID, true
+}
+
+// ChangeNoteStatus moves a note to draft, in review, approved or archived
+// PATCH /note/:id/status
+func ChangeNoteStatus(c *gin.Context) {
+	noteID, clerkUserID, ok := noteLifecycleAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var req NoteStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	note, err := services.NewNoteLifecycleService().ChangeStatus(noteID, clerkUserID, req.Status)
+	if err != nil {
+		sendNoteLifecycleError(c, err, "Failed to change note status")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": note.ID, "status": note.Status, "isPublic": note.IsPublic})
+}
+
+// RequestNoteReview sends an organization note to its admins for review
+// POST /note/:id/review
+func RequestNoteReview(c *gin.Context) {
+	noteID, clerkUserID, ok := noteLifecycleAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var req NoteReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	review, err := services.NewNoteLifecycleService().RequestReview(noteID, clerkUserID, req.Comment)
+	if err != nil {
+		sendNoteLifecycleError(c, err, "Failed to request review")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"review": review, "status": models.NoteStatusInReview})
+}
+
+// ApproveNoteReview approves a note in review
+// POST /note/:id/review/approve
+func ApproveNoteReview(c *gin.Context) {
+	reviewNote(c, true)
+}
+
+// RejectNoteReview sends a note in review back to draft with a comment
+// POST /note/:id/review/reject
+func RejectNoteReview(c *gin.Context) {
+	reviewNote(c, false)
+}
+
+// reviewNote records an organization admin's decision on a note in review
+func reviewNote(c *gin.Context, approve bool) {
+	noteID, clerkUserID, ok := noteLifecycleAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var note models.Notes
+	if err := db.DB.WithContext(c.Request.Context()).Select("organization_id").Where("id = ?", noteID).First(&note).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if note.OrganizationID == nil || *note.OrganizationID == "" {
+		sendNoteLifecycleError(c, services.ErrNoteNotInOrganization, "Failed to review note")
+		return
+	}
+	role, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), *note.OrganizationID, clerkUserID)
+	if err != nil {
+		log.Error().Err(err).Str("org_id", *note.OrganizationID).Msg("Failed to check org membership")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify organization membership"})
+		return
+	}
+	if !isMember || role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only organization admins can review notes"})
+		return
+	}
+
+	var req NoteReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	review, err := services.NewNoteLifecycleService().Review(noteID, clerkUserID, approve, req.Comment)
+	if err != nil {
+		sendNoteLifecycleError(c, err, "Failed to review note")
+		return
+	}
+
+	status := models.NoteStatusApproved
+	if !approve {
+		status = models.NoteStatusDraft
+	}
+	c.JSON(http.StatusOK, gin.H{"review": review, "status": status})
+}
+
+// GetNoteReviews returns the review history of a note
+// GET /note/:id/reviews
+func GetNoteReviews(c *gin.Context) {
+	noteID, _, ok := noteLifecycleAccess(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	reviews, err := services.NewNoteLifecycleService().ListReviews(noteID)
+	if err != nil {
+		sendNoteLifecycleError(c, err, "Failed to fetch note reviews")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+// GetOrgPublishingSettings returns whether the organization only publishes approved notes
+// GET /organizations/:orgId/publishing-settings
+func GetOrgPublishingSettings(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	settings, err := services.NewNoteLifecycleService().GetPublishingSettings(orgID)
+	if err != nil {
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to fetch publishing settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch publishing settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateOrgPublishingSettings sets whether the organization only publishes approved notes.
+// Turning it on unpublishes the published notes that aren't approved.
+// PUT /organizations/:orgId/publishing-settings
+func UpdateOrgPublishingSettings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	orgID := c.Param("orgId")
+
+	var req UpdatePublishingSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := services.NewNoteLifecycleService().SavePublishingSettings(orgID, req.RequireApproval, clerkUserID)
+	if err != nil {
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to save publishing settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save publishing settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// sendNoteLifecycleError maps note lifecycle service errors to responses
+func sendNoteLifecycleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidNoteStatus),
+		errors.Is(err, services.ErrNoteReviewCommentRequired),
+		errors.Is(err, services.ErrNoteNotInOrganization):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidNoteTransition),
+		errors.Is(err, services.ErrNoteReviewRequired),
+		errors.Is(err, services.ErrNoPendingNoteReview):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
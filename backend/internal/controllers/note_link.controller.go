@@ -1,10 +1,13 @@
 package controllers
 
 import (
+	"backend/db"
 	"backend/internal/middleware"
 	"backend/internal/models/dto"
 	"backend/internal/services"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
@@ -42,13 +45,14 @@ func CreateNoteLink(c *gin.Context) {
 		req.SourceNoteID,
 		req.TargetNoteID,
 		req.LinkType,
+		strings.TrimSpace(req.BlockRef),
+		req.Origin,
+		req.Annotation,
 		clerkUserID,
 		organizationID,
 	)
-
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to create note link")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		sendNoteLinkError(c, err, "Failed to create note link")
 		return
 	}
 
@@ -69,8 +73,7 @@ func DeleteNoteLink(c *gin.Context) {
 
 	err := noteLinkService.DeleteNoteLink(linkID, clerkUserID)
 	if err != nil {
-		log.Error().Err(err).Str("link_id", linkID).Msg("Failed to delete note link")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		sendNoteLinkError(c, err, "Failed to delete note link")
 		return
 	}
 
@@ -99,7 +102,7 @@ func GetNoteLinksByNoteID(c *gin.Context) {
 	c.JSON(http.StatusOK, links)
 }
 
-// UpdateNoteLink updates a note link's type
+// UpdateNoteLink updates a note link's type or annotation
 func UpdateNoteLink(c *gin.Context) {
 	// Initialize service (lazy initialization to ensure DB is ready)
 	noteLinkService := services.NewNoteLinkService()
@@ -118,10 +121,9 @@ func UpdateNoteLink(c *gin.Context) {
 		return
 	}
 
-	link, err := noteLinkService.UpdateNoteLink(linkID, req.LinkType, clerkUserID)
+	link, err := noteLinkService.UpdateNoteLink(linkID, req.LinkType, req.Annotation, clerkUserID)
 	if err != nil {
-		log.Error().Err(err).Str("link_id", linkID).Msg("Failed to update note link")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		sendNoteLinkError(c, err, "Failed to update note link")
 		return
 	}
 
@@ -152,3 +154,56 @@ func GetAllLinks(c *gin.Context) {
 
 	c.JSON(http.StatusOK, links)
 }
+
+// GetNoteBacklinks lists the links pointing at a note with their types and annotations
+// GET /api/notes/:id/backlinks
+func GetNoteBacklinks(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to access note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	backlinks, err := services.NewNoteLinkService().GetBacklinks(noteID)
+	if err != nil {
+		sendNoteLinkError(c, err, "Failed to get backlinks")
+		return
+	}
+
+	c.JSON(http.StatusOK, backlinks)
+}
+
+// sendNoteLinkError maps note link service errors to responses
+func sendNoteLinkError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrBlockNotFound),
+		errors.Is(err, services.ErrInvalidLinkOrigin),
+		errors.Is(err, services.ErrInvalidLinkType),
+		errors.Is(err, services.ErrLinkAnnotationTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note link not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// syncNoteWikiLinks keeps a note's wiki links in step with its saved content, logging rather than failing the save
+func syncNoteWikiLinks(noteID, clerkUserID string) {
+	if err := services.NewNoteLinkService().SyncWikiLinks(noteID, clerkUserID); err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to sync wiki links")
+	}
+}
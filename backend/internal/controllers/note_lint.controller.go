@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LintNote checks the style of a note: passive voice, long sentences, words the organization's style
+// guide rules out and AI suggestions. Issues have ProseMirror positions in the posted document, or the
+// saved one when none is posted, for editors to show as decorations.
+// POST /note/:id/lint
+func LintNote(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	var input services.NoteLintInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	report, err := services.NewNoteLintService().LintNote(c.Request.Context(), noteID, input, clerkUserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownLintRule):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNoteLocked), errors.Is(err, services.ErrNotebookEncrypted):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNoteNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		default:
+			middleware.ReportError(c, err, "Failed to check note style")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check note style"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetOrgStyleGuide returns the style checks run on the organization's notes
+// GET /organizations/:orgId/style-guide
+func GetOrgStyleGuide(c *gin.Context) {
+	guide, err := services.NewNoteLintService().GetStyleGuide(c.Request.Context(), c.Param("orgId"))
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch style guide")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch style guide"})
+		return
+	}
+
+	c.JSON(http.StatusOK, guide)
+}
+
+// UpdateOrgStyleGuide sets the style checks run on the organization's notes
+// PUT /organizations/:orgId/style-guide
+func UpdateOrgStyleGuide(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req services.StyleGuideSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	guide, err := services.NewNoteLintService().SaveStyleGuide(c.Request.Context(), c.Param("orgId"), req, clerkUserID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStyleGuide) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to save style guide")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save style guide"})
+		return
+	}
+
+	c.JSON(http.StatusOK, guide)
+}
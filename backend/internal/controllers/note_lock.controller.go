@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// NotePassphraseRequest carries the passphrase of a password-locked note
+type NotePassphraseRequest struct {
+	Passphrase string `json:"passphrase" binding:"required"`
+}
+
+// noteLockAccess checks the user has the required access to the note and returns its ID, the user's ID and their unlock session
+func noteLockAccess(c *gin.Context, required middleware.NoteAccess) (string, string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", "", false
+	}
+
+	noteID := c.Param("id")
+	if !authorizeNote(c, noteID, clerkUserID, required) {
+		return "", "", "", false
+	}
+
+	session, ok := noteUnlockSession(c, clerkUserID)
+	if !ok {
+		return "", "", "", false
+	}
+	return noteID, clerkUserID, session, true
+}
+
+// noteUnlockSession returns the session notes are unlocked in, which is the user's Clerk session
+func noteUnlockSession(c *gin.Context, clerkUserID string) (string, bool) {
+	sessionID, exists := middleware.GetClerkSessionID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Locked notes need a signed-in session"})
+		return "", false
+	}
+	return services.NoteUnlockSession(clerkUserID, sessionID), true
+}
+
+// LockNote encrypts the note's content with a passphrase. It has to be unlocked in each session to be read again.
+// POST /note/:id/lock
+func LockNote(c *gin.Context) {
+	noteID, clerkUserID, _, ok := noteLockAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var req NotePassphraseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := services.NewNoteLockService().Lock(noteID, clerkUserID, req.Passphrase); err != nil {
+		sendNoteLockError(c, err, "Failed to lock note")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"noteId": noteID, "locked": true})
+}
+
+// UnlockNote checks the passphrase, returns the note's content and keeps it readable for the rest of the session
+// POST /note/:id/unlock
+func UnlockNote(c *gin.Context) {
+	noteID, _, session, ok := noteLockAccess(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	var req NotePassphraseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	content, err := services.NewNoteLockService().Unlock(noteID, session, req.Passphrase)
+	if err != nil {
+		sendNoteLockError(c, err, "Failed to unlock note")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"noteId": noteID, "content": content})
+}
+
+// RelockNote locks the note again in the current session
+// POST /note/:id/relock
+func RelockNote(c *gin.Context) {
+	noteID, _, session, ok := noteLockAccess(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	services.NewNoteLockService().Relock(noteID, session)
+	c.JSON(http.StatusOK, gin.H{"noteId": noteID, "locked": true})
+}
+
+// RemoveNoteLock decrypts the note's content for good
+// DELETE /note/:id/lock
+func RemoveNoteLock(c *gin.Context) {
+	noteID, _, _, ok := noteLockAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var req NotePassphraseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := services.NewNoteLockService().RemoveLock(noteID, req.Passphrase); err != nil {
+		sendNoteLockError(c, err, "Failed to remove note lock")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"noteId": noteID, "locked": false})
+}
+
+// rejectLockedNotes responds with an error when some notes are password locked, for features that
+// need to read their content on the server
+func rejectLockedNotes(c *gin.Context, noteIDs []string) bool {
+	locked, err := services.NewNoteLockService().LockedNotes(noteIDs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check note locks")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check note locks"})
+		return true
+	}
+	if len(locked) > 0 {
+		c.JSON(http.StatusLocked, gin.H{
+			"error":   "Locked notes can't be used here, remove their lock first",
+			"noteIds": locked,
+		})
+		return true
+	}
+	return false
+}
+
+// sendNoteLockError maps note lock service errors to responses
+func sendNoteLockError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrWeakNotePassphrase):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWrongNotePassphrase):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wrong passphrase"})
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	case errors.Is(err, services.ErrNoteLocked):
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error(), "locked": true})
+	case errors.Is(err, services.ErrNoteAlreadyLocked),
+		errors.Is(err, services.ErrNoteNotLocked),
+		errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// maxNoteSearchResults caps the notes returned by a property search
+const maxNoteSearchResults = 200
+
+// SetNotePropertyRequest represents the request body for setting a note property
+type SetNotePropertyRequest struct {
+	Type  string `json:"type" binding:"required"`
+	Value string `json:"value"`
+}
+
+// notePropertyAccess checks the user has the required access to the note and returns its ID
+func notePropertyAccess(c *gin.Context, required middleware.NoteAccess) (string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", false
+	}
+
+	noteID := c.Param("id")
+	if !authorizeNote(c, noteID, clerkUserID, required) {
+		return "", false
+	}
+
+	return noteID, true
+}
+
+// GetNoteProperties returns a note's properties
+// GET /note/:id/properties
+func GetNoteProperties(c *gin.Context) {
+	noteID, ok := notePropertyAccess(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	properties, err := services.NewNotePropertyService().ListProperties(noteID)
+	if err != nil {
+		sendNotePropertyError(c, err, "Failed to fetch note properties")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"properties": properties})
+}
+
+// SetNoteProperty creates or replaces a note property
+// PUT /note/:id/properties/:key
+func SetNoteProperty(c *gin.Context) {
+	noteID, ok := notePropertyAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var req SetNotePropertyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	property, err := services.NewNotePropertyService().SetProperty(noteID, c.Param("key"), req.Type, req.Value)
+	if err != nil {
+		sendNotePropertyError(c, err, "Failed to save note property")
+		return
+	}
+
+	c.JSON(http.StatusOK, property)
+}
+
+// DeleteNoteProperty removes a note property
+// DELETE /note/:id/properties/:key
+func DeleteNoteProperty(c *gin.Context) {
+	noteID, ok := notePropertyAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	if err := services.NewNotePropertyService().DeleteProperty(noteID, c.Param("key")); err != nil {
+		sendNotePropertyError(c, err, "Failed to delete note property")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Property deleted successfully"})
+}
+
+// SearchNotes finds notes by text and property filters, for database-like views over notes.
+// Filters are passed as repeated where parameters, e.g. ?where=status = reading&where=pages >= 100.
+// With allWorkspaces=true it searches every workspace the user belongs to and labels each result.
+// GET /notes/search
+func SearchNotes(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	filters, err := services.ParseNotePropertyFilters(c.QueryArray("where"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Encrypted notebooks and locked notes are left out of search
+	query := db.Replica(db.DB).WithContext(c.Request.Context()).Model(&models.Notes{}).
+		Joins("JOIN chapters ON notes.chapter_id = chapters.id").
+		Joins("JOIN notebooks ON chapters.notebook_id = notebooks.id").
+		Where("notebooks.encrypted = ? AND notes.locked = ?", false, false)
+
+	// allWorkspaces searches the personal workspace and every organization at once, labeling each result
+	var workspaces []services.Workspace
+	orgID := c.Query("organizationId")
+	if c.Query("allWorkspaces") == "true" {
+		workspaces, err = services.NewWorkspaceService().ListWorkspaces(c.Request.Context(), clerkUserID)
+		if err != nil {
+			middleware.ReportError(c, err, "Failed to fetch workspaces")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search notes"})
+			return
+		}
+		query = services.ScopeAllWorkspaces(query, clerkUserID, workspaces)
+	} else if orgID != "" {
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
+		if err != nil || !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+			return
+		}
+		query = query.Where("notebooks.organization_id = ?", orgID)
+	} else {
+		query = query.Where("notebooks.clerk_user_id = ? AND notebooks.organization_id IS NULL", clerkUserID)
+	}
+
+	query = services.MatchNoteText(query, c.Query("q"))
+	query = services.ApplyNotePropertyFilters(query, filters)
+
+	var notes []models.Notes
+	if err := query.
+		Select("notes.id, notes.name, notes.slug, notes.chapter_id, notes.organization_id, notes.is_public, notes.status, notes.summary, notes.created_at, notes.updated_at").
+		Preload("Chapter.Notebook").
+		Preload("Properties", func(db *gorm.DB) *gorm.DB {
+			return db.Order("key ASC")
+		}).
+		Order("notes.updated_at DESC").
+		Limit(maxNoteSearchResults).
+		Find(&notes).Error; err != nil {
+		log.Error().Err(err).Str("user_id", clerkUserID).Msg("Failed to search notes")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search notes"})
+		return
+	}
+
+	if workspaces != nil {
+		c.JSON(http.StatusOK, gin.H{"notes": labelWorkspaceNotes(notes, workspaces), "count": len(notes)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notes": notes, "count": len(notes)})
+}
+
+// workspaceNote is a search result labeled with the workspace it's in
+type workspaceNote struct {
+	models.Notes
+	Workspace services.Workspace `json:"workspace"`
+}
+
+// labelWorkspaceNotes labels each note with the workspace its notebook is in
+func labelWorkspaceNotes(notes []models.Notes, workspaces []services.Workspace) []workspaceNote {
+	byOrganization := make(map[string]services.Workspace, len(workspaces))
+	personal := services.Workspace{Type: services.WorkspaceTypePersonal, Name: "Personal"}
+	for _, workspace := range workspaces {
+		if workspace.OrganizationID != nil {
+			byOrganization[*workspace.OrganizationID] = workspace
+		} else {
+			personal = workspace
+		}
+	}
+
+	labeled := make([]workspaceNote, len(notes))
+	for i, note := range notes {
+		labeled[i] = workspaceNote{Notes: note, Workspace: personal}
+		if orgID := note.Chapter.Notebook.OrganizationID; orgID != nil && *orgID != "" {
+			labeled[i].Workspace = byOrganization[*orgID]
+		}
+	}
+	return labeled
+}
+
+// sendNotePropertyError maps note property service errors to responses
+func sendNotePropertyError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPropertyKey),
+		errors.Is(err, services.ErrInvalidPropertyType),
+		errors.Is(err, services.ErrInvalidPropertyValue):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPropertyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Property not found"})
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// SetFavoriteRequest represents the request body for adding a note to or removing it from favorites
+type SetFavoriteRequest struct {
+	Favorite bool `json:"favorite"`
+}
+
+// GetNextReviewNote returns the next note to review, drawn from the notes the user hasn't seen in a while.
+// Optional query params: organizationId, and tag to review only notes with that #tag.
+// GET /api/review/next
+func GetNextReviewNote(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope := services.ResurfaceScope{ClerkUserID: clerkUserID, Tag: c.Query("tag")}
+	if orgID := c.Query("organizationId"); orgID != "" {
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
+		if err != nil || !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+			return
+		}
+		scope.OrganizationID = &orgID
+	}
+
+	note, err := services.NewNoteResurfaceService().Next(c.Request.Context(), scope)
+	if err != nil {
+		if errors.Is(err, services.ErrNothingToReview) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to pick a note to review")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pick a note to review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+// CompleteNoteReview records that the user reviewed a note, so it rests before coming up again
+// POST /api/review/:noteId/done
+func CompleteNoteReview(c *gin.Context) {
+	noteID, clerkUserID, ok := reviewableNote(c)
+	if !ok {
+		return
+	}
+
+	event, err := services.NewNoteResurfaceService().Done(c.Request.Context(), clerkUserID, noteID)
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to record review")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record review"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+// SetNoteFavorite adds a note to or removes it from the user's favorites, which come up more often in review
+// PUT /api/review/:noteId/favorite
+func SetNoteFavorite(c *gin.Context) {
+	noteID, clerkUserID, ok := reviewableNote(c)
+	if !ok {
+		return
+	}
+
+	var req SetFavoriteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := services.NewNoteResurfaceService().SetFavorite(c.Request.Context(), clerkUserID, noteID, req.Favorite); err != nil {
+		middleware.ReportError(c, err, "Failed to save favorite")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save favorite"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"noteId": noteID, "favorite": req.Favorite})
+}
+
+// reviewableNote checks the user can access the note in the path and returns its ID
+func reviewableNote(c *gin.Context) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	noteID := c.Param("noteId")
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return "", "", false
+	}
+	if !hasAccess {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to review note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return "", "", false
+	}
+
+	return noteID, clerkUserID, true
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// GetNoteRevisions returns the revision history of a note, and whether edits to it wait in a revision
+// GET /note/:id/revisions
+func GetNoteRevisions(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanView); !ok {
+		return
+	}
+
+	revisionService := services.NewNoteRevisionService()
+	requiresRevision, err := revisionService.RequiresRevision(noteID)
+	if err != nil {
+		sendNoteRevisionError(c, err, "Failed to fetch note revisions")
+		return
+	}
+	revisions, err := revisionService.ListRevisions(noteID)
+	if err != nil {
+		sendNoteRevisionError(c, err, "Failed to fetch note revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions, "requiresRevision": requiresRevision})
+}
+
+// PublishNoteRevision makes the pending revision of a note its live version
+// POST /note/:id/revision/publish
+func PublishNoteRevision(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	// The words the revision adds are counted against the live version it replaces
+	var live models.Notes
+	if err := db.DB.WithContext(c.Request.Context()).Select("content").Where("id = ?", noteID).First(&live).Error; err != nil {
+		sendNoteRevisionError(c, err, "Failed to publish note revision")
+		return
+	}
+
+	note, revision, err := services.NewNoteRevisionService().PublishRevision(noteID, clerkUserID)
+	if err != nil {
+		sendNoteRevisionError(c, err, "Failed to publish note revision")
+		return
+	}
+
+	// The revision's edits count for its author once, now that they're live
+	go services.NewContentAnalyticsService().RecordNoteAccess(note.ID, revision.CreatedBy, models.NoteAccessEdit)
+	if revision.Content != "" {
+		go services.NewWritingStatsService().RecordWriting(revision.CreatedBy, note.OrganizationID, live.Content, revision.Content, false)
+		syncNoteEmbeds(note.ID, note.Content, clerkUserID)
+		syncNoteWikiLinks(note.ID, clerkUserID)
+		services.NewAutomationService().NoteChanged(note.ID, false)
+		services.NewNoteSummaryService().NoteChanged(note.ID)
+		services.NewNoteLanguageService().NoteChanged(note.ID)
+		services.NewGlossaryService().NoteChanged(note.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"note": note, "revision": revision})
+}
+
+// DiscardNoteRevision drops the pending revision of a note, keeping its live version
+// DELETE /note/:id/revision
+func DiscardNoteRevision(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	revision, err := services.NewNoteRevisionService().DiscardRevision(noteID, clerkUserID)
+	if err != nil {
+		sendNoteRevisionError(c, err, "Failed to discard note revision")
+		return
+	}
+
+	// The collaborative document holds the discarded edits, editors reload the live version
+	if err := services.NewYjsService(db.DB).DeleteYjsDocument(noteID); err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to reset Yjs document after discarding revision")
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revision": revision})
+}
+
+// sendNoteRevisionError maps note revision service errors to responses
+func sendNoteRevisionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNoPendingNoteRevision):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteNotInOrganization):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
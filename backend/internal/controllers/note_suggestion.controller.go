@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ReviewSuggestionRequest represents the request body for accepting or rejecting a suggestion
+type ReviewSuggestionRequest struct {
+	Comment string `json:"comment"`
+}
+
+// SubmitNoteSuggestion lets a signed-in reader suggest an edit to a public note
+// POST /note/:id/suggestions
+func SubmitNoteSuggestion(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input services.NoteSuggestionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	suggestion, err := services.NewNoteSuggestionService().SubmitSuggestion(c.Request.Context(), c.Param("id"), clerkUserID, input)
+	if err != nil {
+		sendNoteSuggestionError(c, err, "Failed to save suggestion")
+		return
+	}
+
+	c.JSON(http.StatusCreated, suggestion)
+}
+
+// ListNotebookSuggestions returns the review queue of edits readers suggested to a notebook's public notes,
+// filtered by ?status=pending|accepted|rejected
+// GET /notebook/:id/suggestions
+func ListNotebookSuggestions(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notebookID := c.Param("id")
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User not authorized to access notebook suggestions")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.NoteSuggestionPending, models.NoteSuggestionAccepted, models.NoteSuggestionRejected:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, accepted or rejected"})
+		return
+	}
+
+	suggestions, err := services.NewNoteSuggestionService().ListSuggestions(c.Request.Context(), notebookID, status)
+	if err != nil {
+		sendNoteSuggestionError(c, err, "Failed to fetch suggestions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// GetNoteSuggestion returns a suggestion with the note as the reader saw it, their version and, while
+// pending, a preview of the note after accepting
+// GET /suggestions/:id
+func GetNoteSuggestion(c *gin.Context) {
+	review, _, ok := reviewableSuggestion(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// AcceptNoteSuggestion applies a suggestion to its note, merged with the author's changes made since
+// POST /suggestions/:id/accept
+func AcceptNoteSuggestion(c *gin.Context) {
+	review, clerkUserID, ok := reviewableSuggestion(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// The applied content goes through the same moderation as the author's own edits
+	moderationText := services.NoteModerationText(review.MergedContent)
+	moderation, ok := checkModeration(c, review.OrganizationID, moderationText)
+	if !ok {
+		return
+	}
+
+	note, err := services.NewNoteSuggestionService().AcceptSuggestion(c.Request.Context(), review.ID, clerkUserID, req.Comment)
+	if err != nil {
+		sendNoteSuggestionError(c, err, "Failed to accept suggestion")
+		return
+	}
+
+	recordModeration(note.OrganizationID, services.ModerationTarget{Source: models.ModerationSourceNote, NoteID: &note.ID, ClerkUserID: clerkUserID}, moderation, moderationText)
+	syncNoteEmbeds(note.ID, note.Content, clerkUserID)
+	syncNoteWikiLinks(note.ID, clerkUserID)
+	services.NewAutomationService().NoteChanged(note.ID, false)
+	services.NewNoteSummaryService().NoteChanged(note.ID)
+	services.NewNoteLanguageService().NoteChanged(note.ID)
+	services.NewGlossaryService().NoteChanged(note.ID)
+	go services.NewContentAnalyticsService().RecordNoteAccess(note.ID, clerkUserID, models.NoteAccessEdit)
+
+	c.JSON(http.StatusOK, note)
+}
+
+// RejectNoteSuggestion declines a suggestion
+// POST /suggestions/:id/reject
+func RejectNoteSuggestion(c *gin.Context) {
+	review, clerkUserID, ok := reviewableSuggestion(c)
+	if !ok {
+		return
+	}
+
+	var req ReviewSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	suggestion, err := services.NewNoteSuggestionService().RejectSuggestion(c.Request.Context(), review.ID, clerkUserID, req.Comment)
+	if err != nil {
+		sendNoteSuggestionError(c, err, "Failed to reject suggestion")
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestion)
+}
+
+// reviewableSuggestion returns the suggestion in the path when the user can edit its note
+func reviewableSuggestion(c *gin.Context) (*services.NoteSuggestionReview, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, "", false
+	}
+
+	review, err := services.NewNoteSuggestionService().GetSuggestion(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		sendNoteSuggestionError(c, err, "Failed to fetch suggestion")
+		return nil, "", false
+	}
+
+	access, err := middleware.GetNoteAccess(c.Request.Context(), db.DB, review.NoteID, clerkUserID)
+	if err != nil || access == middleware.NoteAccessNone {
+		log.Warn().Err(err).Str("suggestion_id", review.ID).Str("user_id", clerkUserID).Msg("User not authorized to review suggestion")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suggestion not found"})
+		return nil, "", false
+	}
+	if access < middleware.NoteAccessCanEdit {
+		sendNoteAccessDenied(c, access)
+		return nil, "", false
+	}
+	return review, clerkUserID, true
+}
+
+// sendNoteSuggestionError maps note suggestion service errors to responses
+func sendNoteSuggestionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidNoteSuggestion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEmbedCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteSuggestionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suggestion not found"})
+	case errors.Is(err, services.ErrPublicNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found or not public"})
+	case errors.Is(err, services.ErrNoteSuggestionStale), errors.Is(err, services.ErrNoteSuggestionReviewed),
+		errors.Is(err, services.ErrNoteSuggestionConflicts), errors.Is(err, services.ErrNoteLocked),
+		errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTooManyNoteSuggestions):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
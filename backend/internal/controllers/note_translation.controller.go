@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TranslateNote translates a note with AI into the language given by its ISO 639-1 code, as a note
+// in the same chapter linked to the original. Translating again updates the translation.
+// POST /note/:id/translate?lang=
+func TranslateNote(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	translation, err := services.NewNoteLanguageService().TranslateNote(c.Request.Context(), noteID, c.Query("lang"), clerkUserID)
+	if err != nil {
+		sendNoteTranslationError(c, err, "Failed to translate note")
+		return
+	}
+
+	note := translation.Note
+	syncNoteWikiLinks(note.ID, clerkUserID)
+	services.NewAutomationService().NoteChanged(note.ID, translation.Created)
+	services.NewNoteSummaryService().NoteChanged(note.ID)
+
+	status := http.StatusOK
+	if translation.Created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, translation)
+}
+
+// sendNoteTranslationError maps note language service errors to responses
+func sendNoteTranslationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUnsupportedLanguage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteAlreadyInLanguage), errors.Is(err, services.ErrNoteLocked),
+		errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateNoteView saves a table, board or calendar view over a notebook's notes
+// POST /notebook/:id/views
+func CreateNoteView(c *gin.Context) {
+	notebookID, clerkUserID, ok := noteViewNotebookAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var input services.NoteViewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	view, err := services.NewNoteViewService().CreateView(notebookID, clerkUserID, input)
+	if err != nil {
+		sendNoteViewError(c, err, "Failed to create view")
+		return
+	}
+
+	c.JSON(http.StatusCreated, view)
+}
+
+// ListNoteViews returns a notebook's views
+// GET /notebook/:id/views
+func ListNoteViews(c *gin.Context) {
+	notebookID, _, ok := noteViewNotebookAccess(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	views, err := services.NewNoteViewService().ListViews(notebookID)
+	if err != nil {
+		sendNoteViewError(c, err, "Failed to fetch views")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"views": views})
+}
+
+// GetNoteView returns a view's definition
+// GET /views/:viewId
+func GetNoteView(c *gin.Context) {
+	view, ok := noteViewAccess(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// UpdateNoteView replaces a view's definition
+// PUT /views/:viewId
+func UpdateNoteView(c *gin.Context) {
+	view, ok := noteViewAccess(c)
+	if !ok {
+		return
+	}
+
+	var input services.NoteViewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	updated, err := services.NewNoteViewService().UpdateView(view.ID, input)
+	if err != nil {
+		sendNoteViewError(c, err, "Failed to update view")
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteNoteView removes a view
+// DELETE /views/:viewId
+func DeleteNoteView(c *gin.Context) {
+	view, ok := noteViewAccess(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewNoteViewService().DeleteView(view.ID); err != nil {
+		sendNoteViewError(c, err, "Failed to delete view")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "View deleted successfully"})
+}
+
+// QueryNoteView returns a view's notes, filtered and sorted by the server. Extra filters and a sort can be
+// passed for the request (?where=status = reading&sort=pages&direction=desc), and calendar views take a
+// date range (?start=2025-01-01&end=2025-02-01).
+// GET /views/:viewId/notes
+func QueryNoteView(c *gin.Context) {
+	view, ok := noteViewAccess(c)
+	if !ok {
+		return
+	}
+
+	options := services.NoteViewQuery{
+		Where:         c.QueryArray("where"),
+		SortBy:        c.Query("sort"),
+		SortDirection: c.Query("direction"),
+	}
+	if options.Start, ok = noteViewDateParam(c, "start"); !ok {
+		return
+	}
+	if options.End, ok = noteViewDateParam(c, "end"); !ok {
+		return
+	}
+
+	result, err := services.NewNoteViewService().QueryView(view.ID, options)
+	if err != nil {
+		sendNoteViewError(c, err, "Failed to query view")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// noteViewAccess loads the view and checks the user can access its notebook
+func noteViewAccess(c *gin.Context) (*services.NoteViewResponse, bool) {
+	view, err := services.NewNoteViewService().GetView(c.Param("viewId"))
+	if err != nil {
+		sendNoteViewError(c, err, "Failed to fetch view")
+		return nil, false
+	}
+	if _, _, ok := noteViewNotebookAccess(c, view.NotebookID); !ok {
+		return nil, false
+	}
+	return view, true
+}
+
+// noteViewNotebookAccess checks the user can access the notebook
+func noteViewNotebookAccess(c *gin.Context, notebookID string) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return "", "", false
+	}
+	if !hasAccess {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User not authorized to access notebook views")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return "", "", false
+	}
+
+	return notebookID, clerkUserID, true
+}
+
+// noteViewDateParam parses an optional date or RFC 3339 timestamp query parameter
+func noteViewDateParam(c *gin.Context, param string) (*time.Time, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return &date, true
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " date, use YYYY-MM-DD or RFC 3339"})
+		return nil, false
+	}
+	return &date, true
+}
+
+// sendNoteViewError maps note view service errors to responses
+func sendNoteViewError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidNoteView),
+		errors.Is(err, services.ErrInvalidPropertyFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteViewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "View not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"backend/db"
 	"backend/internal/middleware"
 	"backend/internal/models"
+	"backend/internal/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -28,7 +29,7 @@ func GetUserNotebooks(c *gin.Context) {
 
 	if orgID != "" {
 		// Get organization notebooks - verify membership first
-		role, isMember, err := middleware.GetOrgMemberRole(c.Request.Context(), orgID, clerkUserID)
+		role, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
 		if err != nil || !isMember {
 			log.Warn().Str("org_id", orgID).Str("user_id", clerkUserID).Msg("User not authorized for org notebooks")
 			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
@@ -37,10 +38,17 @@ func GetUserNotebooks(c *gin.Context) {
 		log.Debug().Str("org_id", orgID).Str("user_id", clerkUserID).Str("role", role).Msg("Fetching org notebooks")
 
 		// For org notebooks, fetch ALL notebooks in the org (not just user's own)
-		query = db.DB.Where("organization_id = ?", orgID)
+		query = db.DB.WithContext(c.Request.Context()).Where("organization_id = ?", orgID)
 	} else {
 		// Get personal notebooks only (null organization_id, owned by this user)
-		query = db.DB.Where("clerk_user_id = ? AND organization_id IS NULL", clerkUserID)
+		query = db.DB.WithContext(c.Request.Context()).Where("clerk_user_id = ? AND organization_id IS NULL", clerkUserID)
+	}
+
+	// Optional note status filter, e.g. ?status=approved
+	statuses, err := services.ParseNoteStatusFilter(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Get notebooks with chapters and notes, but exclude large content fields from notes
@@ -50,11 +58,14 @@ func GetUserNotebooks(c *gin.Context) {
 		}).
 		Preload("Chapters.Files", func(db *gorm.DB) *gorm.DB {
 			// Only select metadata fields, exclude large content fields
-			return db.Select("id, name, chapter_id, organization_id, is_public, has_video, meeting_recording_id, created_at, updated_at").
-				Order("created_at DESC")
+			db = db.Select("id, name, chapter_id, organization_id, is_public, status, has_video, meeting_recording_id, summary, created_at, updated_at")
+			if len(statuses) > 0 {
+				db = db.Where("status IN ?", statuses)
+			}
+			return db.Order("created_at DESC")
 		}).
 		Find(&notebooks).Error; err != nil {
-		log.Print("Error fetching notebooks for user: ", clerkUserID, " Error: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error fetching notebooks")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -74,8 +85,8 @@ func GetNotebookById(c *gin.Context) {
 	id := c.Param("id")
 
 	// Find notebook without preloads
-	if err := db.DB.Where("id = ?", id).First(&notebook).Error; err != nil {
-		log.Print("Notebook not found with id: ", id, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", id).First(&notebook).Error; err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", id).Msg("Notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
 		return
 	}
@@ -90,7 +101,7 @@ func GetNotebookById(c *gin.Context) {
 
 	// Load chapters without notes
 	var chapters []models.Chapter
-	db.DB.Where("notebook_id = ?", id).Find(&chapters)
+	db.DB.WithContext(c.Request.Context()).Where("notebook_id = ?", id).Find(&chapters)
 	notebook.Chapters = chapters
 
 	c.JSON(http.StatusOK, notebook)
@@ -107,17 +118,19 @@ func CreateNotebook(c *gin.Context) {
 	var notebook models.Notebook
 
 	if err := c.ShouldBindJSON(&notebook); err != nil {
-		log.Print("Missing data to create a notebook", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Missing data to create a notebook")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Set the Clerk user ID from authenticated session (security)
 	notebook.ClerkUserID = clerkUserID
+	// Notebooks are encrypted through the encryption endpoints, which upload the key envelope
+	notebook.Encrypted = false
 
 	// If organizationId is provided, verify membership
 	if notebook.OrganizationID != nil && *notebook.OrganizationID != "" {
-		_, isMember, err := middleware.GetOrgMemberRole(c.Request.Context(), *notebook.OrganizationID, clerkUserID)
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), *notebook.OrganizationID, clerkUserID)
 		if err != nil || !isMember {
 			log.Warn().Str("org_id", *notebook.OrganizationID).Str("user_id", clerkUserID).Msg("User not authorized to create notebook in org")
 			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
@@ -126,8 +139,8 @@ func CreateNotebook(c *gin.Context) {
 		log.Info().Str("org_id", *notebook.OrganizationID).Str("user_id", clerkUserID).Msg("Creating org notebook")
 	}
 
-	if err := db.DB.Create(&notebook).Error; err != nil {
-		log.Print("Error creating Notebook in db: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Create(&notebook).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error creating notebook")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -148,7 +161,7 @@ func DeleteNotebook(c *gin.Context) {
 	// Check authorization efficiently
 	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, id, clerkUserID)
 	if err != nil {
-		log.Print("Notebook not found with id: ", id, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", id).Msg("Notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
 		return
 	}
@@ -158,9 +171,14 @@ func DeleteNotebook(c *gin.Context) {
 		return
 	}
 
+	if err := services.CheckNotebookLegalHold(db.DB.WithContext(c.Request.Context()), id); err != nil {
+		sendRetentionError(c, err, "Failed to delete notebook")
+		return
+	}
+
 	// Delete the notebook
-	if err := db.DB.Delete(&models.Notebook{}, "id = ?", id).Error; err != nil {
-		log.Print("Error deleting Notebook with id: ", id, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Delete(&models.Notebook{}, "id = ?", id).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Str("notebook_id", id).Msg("Error deleting notebook")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -181,7 +199,7 @@ func UpdateNotebook(c *gin.Context) {
 	// Check authorization efficiently
 	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, id, clerkUserID)
 	if err != nil {
-		log.Print("Notebook not found with id: ", id, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", id).Msg("Notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
 		return
 	}
@@ -194,14 +212,14 @@ func UpdateNotebook(c *gin.Context) {
 	// Bind the update data from request body
 	var updateData models.Notebook
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		log.Print("Invalid update data for notebook: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid update data for notebook")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get current notebook to preserve protected fields
 	var notebook models.Notebook
-	if err := db.DB.Where("id = ?", id).First(&notebook).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", id).First(&notebook).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notebook"})
 		return
 	}
@@ -209,10 +227,15 @@ func UpdateNotebook(c *gin.Context) {
 	// Prevent changing clerk_user_id and organization_id through update
 	updateData.ClerkUserID = notebook.ClerkUserID
 	updateData.OrganizationID = notebook.OrganizationID
+	// Encryption is turned on and off through the encryption endpoints, and encrypted notebooks can't be published
+	updateData.Encrypted = notebook.Encrypted
+	if notebook.Encrypted {
+		updateData.IsPublic = false
+	}
 
 	// Update the notebook
-	if err := db.DB.Model(&notebook).Updates(updateData).Error; err != nil {
-		log.Print("Error updating Notebook with id: ", id, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Model(&notebook).Updates(updateData).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Str("notebook_id", id).Msg("Error updating notebook")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// DisableNotebookEncryptionRequest carries every note of the notebook decrypted by the client
+type DisableNotebookEncryptionRequest struct {
+	Notes []services.NoteContentInput `json:"notes"`
+}
+
+// encryptionNotebook checks the user can access the notebook and returns its ID
+func encryptionNotebook(c *gin.Context) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	notebookID := c.Param("id")
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return "", "", false
+	}
+	if !hasAccess {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User not authorized to manage notebook encryption")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return "", "", false
+	}
+
+	return notebookID, clerkUserID, true
+}
+
+// EnableNotebookEncryption turns on end-to-end encryption. The client encrypts every note with a new
+// notebook key and uploads them with the key wrapped for the caller.
+// PUT /notebook/:id/encryption
+func EnableNotebookEncryption(c *gin.Context) {
+	notebookID, clerkUserID, ok := encryptionNotebook(c)
+	if !ok {
+		return
+	}
+	// Encrypting or decrypting replaces every note for everyone in the notebook
+	if !authorizeNotebookManager(c, notebookID, clerkUserID) {
+		return
+	}
+
+	var input services.EnableNotebookEncryptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	notebook, err := services.NewNotebookEncryptionService().Enable(notebookID, clerkUserID, input)
+	if err != nil {
+		sendNotebookEncryptionError(c, err, "Failed to encrypt notebook")
+		return
+	}
+
+	c.JSON(http.StatusOK, notebook)
+}
+
+// DisableNotebookEncryption turns off encryption with every note decrypted by the client
+// DELETE /notebook/:id/encryption
+func DisableNotebookEncryption(c *gin.Context) {
+	notebookID, clerkUserID, ok := encryptionNotebook(c)
+	if !ok {
+		return
+	}
+	if !authorizeNotebookManager(c, notebookID, clerkUserID) {
+		return
+	}
+
+	var req DisableNotebookEncryptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	notebook, err := services.NewNotebookEncryptionService().Disable(notebookID, req.Notes)
+	if err != nil {
+		sendNotebookEncryptionError(c, err, "Failed to decrypt notebook")
+		return
+	}
+
+	c.JSON(http.StatusOK, notebook)
+}
+
+// GetNotebookKeyEnvelope returns the notebook key wrapped for the caller
+// GET /notebook/:id/encryption/envelope
+func GetNotebookKeyEnvelope(c *gin.Context) {
+	notebookID, clerkUserID, ok := encryptionNotebook(c)
+	if !ok {
+		return
+	}
+
+	envelope, err := services.NewNotebookEncryptionService().GetEnvelope(notebookID, clerkUserID)
+	if err != nil {
+		sendNotebookEncryptionError(c, err, "Failed to fetch key envelope")
+		return
+	}
+
+	c.JSON(http.StatusOK, envelope)
+}
+
+// SaveNotebookKeyEnvelope stores the notebook key wrapped for a user, either the caller after a key
+// rotation or another member of the notebook's organization the caller shares it with. Only members who
+// hold the key can share it, and members who already have an envelope replace it themselves.
+// PUT /notebook/:id/encryption/envelopes/:userId
+func SaveNotebookKeyEnvelope(c *gin.Context) {
+	notebookID, clerkUserID, ok := encryptionNotebook(c)
+	if !ok {
+		return
+	}
+
+	targetUserID := c.Param("userId")
+	if targetUserID != clerkUserID {
+		hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, targetUserID)
+		if err != nil || !hasAccess {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Keys can only be shared with members of the notebook's organization"})
+			return
+		}
+	}
+
+	var input services.KeyEnvelopeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	envelope, err := services.NewNotebookEncryptionService().SaveEnvelope(notebookID, targetUserID, clerkUserID, input)
+	if err != nil {
+		sendNotebookEncryptionError(c, err, "Failed to save key envelope")
+		return
+	}
+
+	c.JSON(http.StatusOK, envelope)
+}
+
+// ExportEncryptedNotebook downloads the notebook's encrypted notes with the caller's key envelope
+// GET /notebook/:id/export/encrypted
+func ExportEncryptedNotebook(c *gin.Context) {
+	notebookID, clerkUserID, ok := encryptionNotebook(c)
+	if !ok {
+		return
+	}
+
+	export, err := services.NewNotebookEncryptionService().Export(notebookID, clerkUserID)
+	if err != nil {
+		sendNotebookEncryptionError(c, err, "Failed to export notebook")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "notebook-"+notebookID+".encrypted.json"))
+	c.JSON(http.StatusOK, export)
+}
+
+// rejectEncryptedNotebook responds with a conflict when the notebook is encrypted, for features that
+// need to read its notes on the server
+func rejectEncryptedNotebook(c *gin.Context, notebook *models.Notebook) bool {
+	if notebook == nil || !notebook.Encrypted {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": services.ErrNotebookEncrypted.Error()})
+	return true
+}
+
+// rejectEncryptedNote responds with a conflict when the note belongs to an encrypted notebook
+func rejectEncryptedNote(c *gin.Context, noteID string) bool {
+	encrypted, err := services.NewNotebookEncryptionService().NoteEncrypted(noteID)
+	if err != nil {
+		log.Error().Err(err).Str("note_id", noteID).Msg("Failed to check note encryption")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check note encryption"})
+		return true
+	}
+	if encrypted {
+		c.JSON(http.StatusConflict, gin.H{"error": services.ErrNotebookEncrypted.Error()})
+		return true
+	}
+	return false
+}
+
+// rejectCrossEncryptionMove responds with a conflict when a note or chapter would move between an
+// encrypted and a plain notebook. Its content can only be encrypted or decrypted by the client.
+func rejectCrossEncryptionMove(c *gin.Context, sourceEncrypted func(string) (bool, error), sourceID string, targetEncrypted func(string) (bool, error), targetID string) bool {
+	source, err := sourceEncrypted(sourceID)
+	if err != nil {
+		log.Error().Err(err).Str("source_id", sourceID).Msg("Failed to check move source encryption")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check notebook encryption"})
+		return true
+	}
+	target, err := targetEncrypted(targetID)
+	if err != nil {
+		log.Error().Err(err).Str("target_id", targetID).Msg("Failed to check move target encryption")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check notebook encryption"})
+		return true
+	}
+	if source != target {
+		c.JSON(http.StatusConflict, gin.H{"error": "Notes can't be moved between encrypted and unencrypted notebooks"})
+		return true
+	}
+	return false
+}
+
+// sendNotebookEncryptionError maps notebook encryption service errors to responses
+func sendNotebookEncryptionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidKeyEnvelope),
+		errors.Is(err, services.ErrEncryptedNotesMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+	case errors.Is(err, services.ErrKeyEnvelopeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Key envelope not found"})
+	case errors.Is(err, services.ErrKeyEnvelopeNotHeld):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotebookNotEncrypted),
+		errors.Is(err, services.ErrNotebookAlreadyEncrypted),
+		errors.Is(err, services.ErrNotebookHasLockedNotes),
+		errors.Is(err, services.ErrKeyEnvelopeExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
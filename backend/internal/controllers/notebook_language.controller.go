@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotebookLanguages returns the languages a published notebook is offered in
+// GET /notebook/:id/languages
+func GetNotebookLanguages(c *gin.Context) {
+	notebookID, _, ok := notebookSettingsAccess(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	languages, err := services.NewNotebookLanguageService().GetLanguages(c.Request.Context(), notebookID)
+	if err != nil {
+		sendNotebookLanguageError(c, err, "Failed to fetch notebook languages")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"languages": languages})
+}
+
+// UpdateNotebookLanguages sets the languages a published notebook is offered in, and what readers of
+// each see for notes that aren't translated to it
+// PUT /notebook/:id/languages
+func UpdateNotebookLanguages(c *gin.Context) {
+	notebookID, _, ok := notebookSettingsAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var input services.NotebookLanguagesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	languages, err := services.NewNotebookLanguageService().SaveLanguages(c.Request.Context(), notebookID, input)
+	if err != nil {
+		sendNotebookLanguageError(c, err, "Failed to save notebook languages")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"languages": languages})
+}
+
+// publicReaderLanguage picks the language a guest reads a published notebook in, from the lang query
+// parameter, the language of the translation they followed a link to, or their browser's languages.
+// It's nil for notebooks published in one language.
+func publicReaderLanguage(c *gin.Context, notebookID, linkedLanguage string) (*services.PublicLanguageSwitcher, bool) {
+	requested := c.Query("lang")
+	if requested == "" {
+		requested = linkedLanguage
+	}
+	switcher, err := services.NewNotebookLanguageService().ReaderLanguage(c.Request.Context(), notebookID, requested, c.GetHeader("Accept-Language"))
+	if err != nil {
+		sendNotebookLanguageError(c, err, "Failed to fetch notebook languages")
+		return nil, false
+	}
+	return switcher, true
+}
+
+// sendNotebookLanguageError maps notebook language service errors to responses
+func sendNotebookLanguageError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidNotebookLanguages):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
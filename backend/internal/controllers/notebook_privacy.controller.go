@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotebookPrivacySettings returns what a published notebook's public site records about its readers,
+// with its guest reads of the last 30 days
+// GET /notebook/:id/privacy
+func GetNotebookPrivacySettings(c *gin.Context) {
+	notebookID, _, ok := notebookSettingsAccess(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	settings, err := services.NewNotebookPrivacyService().GetSettings(c.Request.Context(), notebookID)
+	if err != nil {
+		sendNotebookPrivacyError(c, err, "Failed to fetch notebook privacy settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateNotebookPrivacySettings turns guest read analytics on or off for a notebook, and sets whether they
+// use a cookie and need the reader's consent
+// PUT /notebook/:id/privacy
+func UpdateNotebookPrivacySettings(c *gin.Context) {
+	notebookID, clerkUserID, ok := notebookSettingsAccess(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var input services.NotebookPrivacyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := services.NewNotebookPrivacyService().SaveSettings(c.Request.Context(), notebookID, input, clerkUserID)
+	if err != nil {
+		sendNotebookPrivacyError(c, err, "Failed to save notebook privacy settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetPublicNotebookPrivacy describes what a published notebook's public site records about its readers,
+// for consent banners and privacy pages
+// GET /public/:notebookId/privacy
+func GetPublicNotebookPrivacy(c *gin.Context) {
+	policy, err := services.NewNotebookPrivacyService().PublicPolicy(c.Request.Context(), c.Param("notebookId"))
+	if err != nil {
+		sendNotebookPrivacyError(c, err, "Failed to fetch notebook privacy policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// recordPublicRead counts a guest reading a published note as the notebook's privacy settings allow,
+// refreshing the reader's session cookie when it uses one
+func recordPublicRead(c *gin.Context, notebookID, noteID string) {
+	sessionID, _ := c.Cookie(services.PublicReaderCookie)
+	reader := services.PublicReader{
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		SessionID:  sessionID,
+		DoNotTrack: c.GetHeader("DNT") == "1" || c.GetHeader("Sec-GPC") == "1",
+		Consent:    strings.EqualFold(strings.TrimSpace(c.GetHeader(services.PublicConsentHeader)), "granted"),
+	}
+
+	session := services.NewNotebookPrivacyService().RecordPublicRead(c.Request.Context(), notebookID, noteID, reader)
+	if session == "" {
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(services.PublicReaderCookie, session, int(services.PublicReaderSessionTTL.Seconds()), "/public", "",
+		c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https", true)
+}
+
+// notebookSettingsAccess checks the user has the required access to the notebook whose publishing
+// settings are requested
+func notebookSettingsAccess(c *gin.Context, required middleware.NoteAccess) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	notebookID := c.Param("id")
+	if !authorizeNotebook(c, notebookID, clerkUserID, required) {
+		return "", "", false
+	}
+
+	return notebookID, clerkUserID, true
+}
+
+// sendNotebookPrivacyError maps notebook privacy service errors to responses
+func sendNotebookPrivacyError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found or not public"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
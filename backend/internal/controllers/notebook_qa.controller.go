@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AskNotebookRequest represents the request body for asking a published notebook a question
+type AskNotebookRequest struct {
+	Question string `json:"question" binding:"required"`
+}
+
+// WidgetChatRequest represents the request body for a chat widget conversation, oldest message first
+type WidgetChatRequest struct {
+	Messages []services.NotebookChatMessage `json:"messages" binding:"required"`
+}
+
+// GetNotebookQASettings returns whether readers can ask a published notebook questions, its monthly cap
+// and this month's usage
+// GET /notebook/:id/qa-settings
+func GetNotebookQASettings(c *gin.Context) {
+	notebookID, _, ok := qaSettingsNotebook(c)
+	if !ok {
+		return
+	}
+
+	settings, err := services.NewNotebookQAService().GetSettings(c.Request.Context(), notebookID)
+	if err != nil {
+		sendNotebookQAError(c, err, "Failed to fetch notebook question settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateNotebookQASettings turns reader questions on or off for a notebook and sets its monthly cap.
+// Answers are paid with the notebook author's AI keys.
+// PUT /notebook/:id/qa-settings
+func UpdateNotebookQASettings(c *gin.Context) {
+	notebookID, clerkUserID, ok := qaSettingsNotebook(c)
+	if !ok {
+		return
+	}
+	// Answers are paid with the owner's AI keys, so only the owner or an admin decides to offer them
+	if !authorizeNotebookManager(c, notebookID, clerkUserID) {
+		return
+	}
+
+	var input services.NotebookQASettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := services.NewNotebookQAService().SaveSettings(c.Request.Context(), notebookID, input, clerkUserID)
+	if err != nil {
+		sendNotebookQAError(c, err, "Failed to save notebook question settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// AskPublicNotebook answers a reader's question from a published notebook's public notes, citing them
+// POST /public/:id/ask
+func AskPublicNotebook(c *gin.Context) {
+	var req AskNotebookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	answer, err := services.NewNotebookQAService().Ask(c.Request.Context(), c.Param("id"), req.Question)
+	if err != nil {
+		sendNotebookQAError(c, err, "Failed to answer question")
+		return
+	}
+
+	c.JSON(http.StatusOK, answer)
+}
+
+// GetNotebookWidgetConfig returns what the embeddable chat widget script needs, for the websites the author
+// added to the notebook's widget settings
+// GET /public/:notebookId/widget-config
+func GetNotebookWidgetConfig(c *gin.Context) {
+	config, err := services.NewNotebookQAService().WidgetConfig(c.Request.Context(), c.Param("notebookId"), c.GetHeader("Origin"))
+	if err != nil {
+		sendNotebookQAError(c, err, "Failed to fetch widget configuration")
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// NotebookWidgetChat answers a chat widget conversation on the author's website from the notebook's public notes
+// POST /public/:id/widget-chat
+func NotebookWidgetChat(c *gin.Context) {
+	var req WidgetChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	answer, err := services.NewNotebookQAService().WidgetChat(c.Request.Context(), c.Param("id"), c.GetHeader("Origin"), req.Messages)
+	if err != nil {
+		sendNotebookQAError(c, err, "Failed to answer question")
+		return
+	}
+
+	c.JSON(http.StatusOK, answer)
+}
+
+// AllowNotebookWidgetOrigin lets the websites a notebook's chat widget is embedded on call the widget
+// endpoints across origins. Route params aren't set yet when CORS runs, so the notebook ID is read from the path.
+func AllowNotebookWidgetOrigin(c *gin.Context, origin string) bool {
+	parts := strings.Split(strings.Trim(c.Request.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "public" || (parts[2] != "widget-chat" && parts[2] != "widget-config") {
+		return false
+	}
+	return services.NewNotebookQAService().WidgetOriginAllowed(c.Request.Context(), parts[1], origin)
+}
+
+// qaSettingsNotebook checks the user can access the notebook and returns its ID
+func qaSettingsNotebook(c *gin.Context) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	notebookID := c.Param("id")
+	if !authorizeNotebook(c, notebookID, clerkUserID, middleware.NoteAccessCanView) {
+		return "", "", false
+	}
+
+	return notebookID, clerkUserID, true
+}
+
+// sendNotebookQAError maps notebook question service errors to responses
+func sendNotebookQAError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidNotebookQASettings), errors.Is(err, services.ErrInvalidNotebookQuestion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found or not public"})
+	case errors.Is(err, services.ErrNotebookQADisabled), errors.Is(err, services.ErrNotebookWidgetDisabled),
+		errors.Is(err, services.ErrNotebookWidgetOriginNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotebookQACapReached):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSnapshotRequest represents the request body for creating a notebook snapshot
+type CreateSnapshotRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// snapshotNotebook checks the user has the required access to the notebook and returns its ID
+func snapshotNotebook(c *gin.Context, required middleware.NoteAccess) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	notebookID := c.Param("id")
+	if !authorizeNotebook(c, notebookID, clerkUserID, required) {
+		return "", "", false
+	}
+
+	return notebookID, clerkUserID, true
+}
+
+// CreateNotebookSnapshot freezes the notebook's current chapters and notes under a version name
+// POST /notebook/:id/snapshots
+func CreateNotebookSnapshot(c *gin.Context) {
+	notebookID, clerkUserID, ok := snapshotNotebook(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	var req CreateSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Snapshots are published versions, which encrypted notebooks can't have
+	encrypted, err := services.NewNotebookEncryptionService().NotebookEncrypted(notebookID)
+	if err != nil {
+		sendNotebookEncryptionError(c, err, "Failed to create snapshot")
+		return
+	}
+	if encrypted {
+		c.JSON(http.StatusConflict, gin.H{"error": services.ErrNotebookEncrypted.Error()})
+		return
+	}
+
+	snapshot, err := services.NewNotebookSnapshotService().CreateSnapshot(notebookID, req.Name, req.Description, clerkUserID)
+	if err != nil {
+		sendSnapshotError(c, err, "Failed to create snapshot")
+		return
+	}
+
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+// ListNotebookSnapshots returns the notebook's snapshots without their content
+// GET /notebook/:id/snapshots
+func ListNotebookSnapshots(c *gin.Context) {
+	notebookID, _, ok := snapshotNotebook(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	snapshots, err := services.NewNotebookSnapshotService().ListSnapshots(notebookID, false)
+	if err != nil {
+		sendSnapshotError(c, err, "Failed to fetch snapshots")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"snapshots": snapshots})
+}
+
+// GetNotebookSnapshot returns a snapshot with its chapters and notes
+// GET /notebook/:id/snapshots/:name
+func GetNotebookSnapshot(c *gin.Context) {
+	notebookID, _, ok := snapshotNotebook(c, middleware.NoteAccessCanView)
+	if !ok {
+		return
+	}
+
+	snapshot, err := services.NewNotebookSnapshotService().GetSnapshot(notebookID, c.Param("name"), false)
+	if err != nil {
+		sendSnapshotError(c, err, "Failed to fetch snapshot")
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// DeleteNotebookSnapshot removes a snapshot
+// DELETE /notebook/:id/snapshots/:name
+func DeleteNotebookSnapshot(c *gin.Context) {
+	notebookID, _, ok := snapshotNotebook(c, middleware.NoteAccessCanEdit)
+	if !ok {
+		return
+	}
+
+	if err := services.NewNotebookSnapshotService().DeleteSnapshot(notebookID, c.Param("name")); err != nil {
+		sendSnapshotError(c, err, "Failed to delete snapshot")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Snapshot deleted successfully"})
+}
+
+// GetPublicNotebookSnapshots lists the published versions of a public notebook
+// GET /public/:notebookId/snapshots
+func GetPublicNotebookSnapshots(c *gin.Context) {
+	notebookID := c.Param("notebookId")
+	if !isPublicNotebook(c, notebookID) {
+		return
+	}
+
+	snapshots, err := services.NewNotebookSnapshotService().ListSnapshots(notebookID, true)
+	if err != nil {
+		sendSnapshotError(c, err, "Failed to fetch snapshots")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"snapshots": snapshots})
+}
+
+// GetPublicNotebookSnapshot returns the notes of a public notebook that were published in a version
+// GET /public/:notebookId/snapshots/:name
+func GetPublicNotebookSnapshot(c *gin.Context) {
+	notebookID := c.Param("notebookId")
+	if !isPublicNotebook(c, notebookID) {
+		return
+	}
+
+	snapshot, err := services.NewNotebookSnapshotService().GetSnapshot(notebookID, c.Param("name"), true)
+	if err != nil {
+		sendSnapshotError(c, err, "Failed to fetch snapshot")
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// isPublicNotebook responds with not found unless the notebook is published
+func isPublicNotebook(c *gin.Context, notebookID string) bool {
+	var count int64
+	if err := db.DB.WithContext(c.Request.Context()).Model(&models.Notebook{}).Where("id = ? AND is_public = ?", notebookID, true).Count(&count).Error; err != nil || count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found or not public"})
+		return false
+	}
+	return true
+}
+
+// sendSnapshotError maps notebook snapshot service errors to responses
+func sendSnapshotError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSnapshotName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSnapshotExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSnapshotNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
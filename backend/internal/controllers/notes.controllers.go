@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"backend/db"
+	"backend/internal/config"
 	"backend/internal/middleware"
 	"backend/internal/models"
 	"backend/internal/services"
@@ -18,6 +19,7 @@ import (
 	openaioption "github.com/openai/openai-go/option"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/genai"
+	"gorm.io/gorm"
 )
 
 func GetNoteById(c *gin.Context) {
@@ -32,8 +34,8 @@ func GetNoteById(c *gin.Context) {
 	id := c.Param("id")
 
 	// Get note without preloads
-	if err := db.DB.Where("id = ?", id).First(&note).Error; err != nil {
-		log.Print("Note not found with id: ", id, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", id).First(&note).Error; err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("note_id", id).Msg("Note not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
 		return
 	}
@@ -46,6 +48,25 @@ func GetNoteById(c *gin.Context) {
 		return
 	}
 
+	// Locked notes only return their content once unlocked in the session
+	if note.Locked {
+		session, ok := noteUnlockSession(c, clerkUserID)
+		if !ok {
+			return
+		}
+		content, err := services.NewNoteLockService().Content(id, session)
+		if err != nil {
+			sendNoteLockError(c, err, "Failed to read locked note")
+			return
+		}
+		note.Content = content
+	} else if note.PendingRevision, err = services.NewNoteRevisionService().PendingRevision(id); err != nil {
+		// The live version is still worth returning
+		log.Error().Err(err).Str("note_id", id).Msg("Failed to fetch pending note revision")
+	}
+
+	go services.NewContentAnalyticsService().RecordNoteAccess(note.ID, clerkUserID, models.NoteAccessView)
+
 	c.JSON(http.StatusOK, note)
 }
 
@@ -81,7 +102,7 @@ func GetNotesByChapter(c *gin.Context) {
 	// Check authorization efficiently
 	hasAccess, err := middleware.CheckChapterAccess(c.Request.Context(), db.DB, chapterID, clerkUserID)
 	if err != nil {
-		log.Print("Chapter not found with id: ", chapterID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("chapter_id", chapterID).Msg("Chapter not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
 		return
 	}
@@ -91,20 +112,30 @@ func GetNotesByChapter(c *gin.Context) {
 		return
 	}
 
+	// Optional status filter, e.g. ?status=draft,in_review
+	statuses, err := services.ParseNoteStatusFilter(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query := db.DB.WithContext(c.Request.Context()).Model(&models.Notes{}).Where("chapter_id = ?", chapterID)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+
 	// Get total count
 	var total int64
-	db.DB.Model(&models.Notes{}).Where("chapter_id = ?", chapterID).Count(&total)
+	query.Session(&gorm.Session{}).Count(&total)
 
 	// Get notes without large content fields, with pagination
 	var notes []models.Notes
 	offset := (page - 1) * pageSize
-	if err := db.DB.Select("id, name, chapter_id, organization_id, is_public, has_video, meeting_recording_id, created_at, updated_at").
-		Where("chapter_id = ?", chapterID).
+	if err := query.Session(&gorm.Session{}).Select("id, name, chapter_id, organization_id, is_public, status, has_video, meeting_recording_id, summary, created_at, updated_at").
 		Order("created_at DESC").
 		Limit(pageSize).
 		Offset(offset).
 		Find(&notes).Error; err != nil {
-		log.Print("Error fetching notes for chapter: ", chapterID, " Error: ", err)
+		middleware.Logger(c).Error().Err(err).Str("chapter_id", chapterID).Msg("Error fetching notes for chapter")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -118,8 +149,10 @@ func GetNotesByChapter(c *gin.Context) {
 			"chapterId":          note.ChapterID,
 			"organizationId":     note.OrganizationID,
 			"isPublic":           note.IsPublic,
+			"status":             note.Status,
 			"hasVideo":           note.HasVideo,
 			"meetingRecordingId": note.MeetingRecordingID,
+			"summary":            note.Summary,
 			"createdAt":          note.CreatedAt,
 			"updatedAt":          note.UpdatedAt,
 		}
@@ -155,40 +188,79 @@ func CreateNote(c *gin.Context) {
 	var note models.Notes
 
 	if err := c.ShouldBindJSON(&note); err != nil {
-		log.Print("Missing data to create a note", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Missing data to create a note")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check authorization efficiently
-	hasAccess, err := middleware.CheckChapterAccess(c.Request.Context(), db.DB, note.ChapterID, clerkUserID)
-	if err != nil {
-		log.Print("Chapter not found with id: ", note.ChapterID, " Error: ", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
-		return
-	}
-	if !hasAccess {
-		log.Warn().Str("chapter_id", note.ChapterID).Str("user_id", clerkUserID).Msg("User not authorized to create note in chapter")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+	// Viewers and commenters of organization notebooks can't add notes to them
+	if !authorizeChapter(c, note.ChapterID, clerkUserID, middleware.NoteAccessCanEdit) {
 		return
 	}
 
-	// Get organization_id from parent chapter
+	// Get organization_id and note defaults from parent chapter
 	var chapter models.Chapter
-	if err := db.DB.Select("organization_id").Where("id = ?", note.ChapterID).First(&chapter).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Select("organization_id", "note_template", "default_tags").Where("id = ?", note.ChapterID).First(&chapter).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
 		return
 	}
 
 	// Inherit organization_id from parent chapter
 	note.OrganizationID = chapter.OrganizationID
+	// New notes start as drafts, their status changes through the lifecycle endpoints
+	note.Status = models.NoteStatusDraft
+	// Properties are set through the property endpoints, which validate their types
+	note.Properties = nil
+	// Notes are locked through the lock endpoints
+	note.Locked = false
+	// Languages are detected from the content, and translations are made through the translate endpoint
+	note.Language = ""
+	note.TranslationOfID = nil
+	// Notes of encrypted notebooks arrive encrypted, so the server can't template, scan or index them
+	encrypted, err := services.NewNotebookEncryptionService().ChapterEncrypted(note.ChapterID)
+	if err != nil {
+		log.Error().Err(err).Str("chapter_id", note.ChapterID).Msg("Failed to check chapter encryption")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
+		return
+	}
 
-	if err := db.DB.Create(&note).Error; err != nil {
-		log.Print("Error creating note in db", err.Error())
+	var moderation *services.ModerationResult
+	var moderationText string
+	if !encrypted {
+		// Start from the chapter's template and default tags, then give blocks stable IDs so tasks and links can point at them
+		note.Content = services.ApplyChapterNoteDefaults(&chapter, note.Content)
+		note.Content = services.AssignTipTapBlockIDs("", note.Content)
+
+		// Organization notes are scanned for secrets and personal data before they are saved
+		var ok bool
+		moderationText = services.NoteModerationText(note.Content)
+		if moderation, ok = checkModeration(c, note.OrganizationID, moderationText); !ok {
+			return
+		}
+	}
+
+	if err := db.DB.WithContext(c.Request.Context()).Create(&note).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error creating note")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if !encrypted {
+		syncNoteEmbeds(note.ID, note.Content, clerkUserID)
+		syncNoteWikiLinks(note.ID, clerkUserID)
+		recordModeration(note.OrganizationID, services.ModerationTarget{Source: models.ModerationSourceNote, NoteID: &note.ID, ClerkUserID: clerkUserID}, moderation, moderationText)
+		services.NewAutomationService().NoteChanged(note.ID, true)
+		services.NewNoteSummaryService().NoteChanged(note.ID)
+		services.NewNoteLanguageService().NoteChanged(note.ID)
+		services.NewGlossaryService().NoteChanged(note.ID)
+	}
+	go services.NewContentAnalyticsService().RecordNoteAccess(note.ID, clerkUserID, models.NoteAccessEdit)
+	writtenContent := ""
+	if !encrypted {
+		writtenContent = note.Content
+	}
+	go services.NewWritingStatsService().RecordWriting(clerkUserID, note.OrganizationID, "", writtenContent, true)
+
 	c.JSON(http.StatusCreated, note)
 }
 
@@ -202,21 +274,18 @@ func DeleteNote(c *gin.Context) {
 
 	id := c.Param("id")
 
-	// Check authorization efficiently
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, id, clerkUserID)
-	if err != nil {
-		log.Print("Note not found with id: ", id, " Error: ", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	// Viewers and commenters of organization notes can't change them
+	if !authorizeNote(c, id, clerkUserID, middleware.NoteAccessCanEdit) {
 		return
 	}
-	if !hasAccess {
-		log.Warn().Str("note_id", id).Str("user_id", clerkUserID).Msg("User not authorized to delete note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+
+	if err := services.CheckNoteLegalHold(db.DB.WithContext(c.Request.Context()), id); err != nil {
+		sendRetentionError(c, err, "Failed to delete note")
 		return
 	}
 
-	if err := db.DB.Delete(&models.Notes{}, "id = ?", id).Error; err != nil {
-		log.Print("Error deleting Note with id: ", id, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Delete(&models.Notes{}, "id = ?", id).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Str("note_id", id).Msg("Error deleting note")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -234,45 +303,143 @@ func UpdateNote(c *gin.Context) {
 
 	id := c.Param("id")
 
-	// Check authorization efficiently
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, id, clerkUserID)
-	if err != nil {
-		log.Print("Note not found with id: ", id, " Error: ", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
-		return
-	}
-	if !hasAccess {
-		log.Warn().Str("note_id", id).Str("user_id", clerkUserID).Msg("User not authorized to update note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+	// Viewers and commenters of organization notes can't change them
+	if !authorizeNote(c, id, clerkUserID, middleware.NoteAccessCanEdit) {
 		return
 	}
 
 	// Bind the update data from request body
 	var updateData models.Notes
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		log.Print("Invalid update data for note: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid update data for note")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get current note to preserve protected fields
 	var note models.Notes
-	if err := db.DB.Where("id = ?", id).First(&note).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", id).First(&note).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
 		return
 	}
 
-	// Prevent changing chapter_id and organization_id through update (security)
+	// Prevent changing chapter_id, organization_id, status and properties through update (security)
 	updateData.ChapterID = note.ChapterID
 	updateData.OrganizationID = note.OrganizationID
+	updateData.Status = ""
+	updateData.Properties = nil
+	// Slugs follow the name
+	updateData.Slug = ""
+	// Notes are locked and unlocked through the lock endpoints
+	updateData.Locked = note.Locked
+	// Languages follow the content, translations are linked by the translate endpoint
+	updateData.Language = ""
+	updateData.TranslationOfID = nil
+
+	// Locked notes are only changed while unlocked in the session, and their content stays encrypted
+	lockService := services.NewNoteLockService()
+	var lockSession string
+	if note.Locked {
+		var ok bool
+		if lockSession, ok = noteUnlockSession(c, clerkUserID); !ok {
+			return
+		}
+		var err error
+		if note.Content, err = lockService.Content(id, lockSession); err != nil {
+			sendNoteLockError(c, err, "Failed to update note")
+			return
+		}
+	}
+
+	// Encrypted content is stored as is, the server can't read it
+	encrypted, err := services.NewNotebookEncryptionService().NoteEncrypted(id)
+	if err != nil {
+		log.Error().Err(err).Str("note_id", id).Msg("Failed to check note encryption")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
+		return
+	}
+	plainContent := updateData.Content != "" && !encrypted
+
+	if plainContent {
+		updateData.Content = services.AssignTipTapBlockIDs(note.Content, updateData.Content)
+		if !validateNoteEmbeds(c, id, updateData.Content) {
+			return
+		}
+	}
+	// Words written are counted from the readable content before and after the save
+	var previousContent, writtenContent string
+	if plainContent {
+		previousContent, writtenContent = note.Content, updateData.Content
+	}
+	var moderation *services.ModerationResult
+	var moderationText string
+	if plainContent {
+		var ok bool
+		moderationText = services.NoteModerationText(updateData.Content)
+		if moderation, ok = checkModeration(c, note.OrganizationID, moderationText); !ok {
+			return
+		}
+	}
+	// Published notes of organizations that require approval keep their live version, edits wait in a
+	// pending revision until an editor publishes it
+	var revision *models.NoteRevision
+	if !note.Locked && !encrypted && (updateData.Name != "" || updateData.Content != "") {
+		revisionService := services.NewNoteRevisionService()
+		requiresRevision, err := revisionService.RequiresRevision(id)
+		if err != nil {
+			log.Error().Err(err).Str("note_id", id).Msg("Failed to check note revision mode")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
+			return
+		}
+		if requiresRevision {
+			if revision, err = revisionService.SaveRevision(id, clerkUserID, updateData.Name, updateData.Content); err != nil {
+				sendNoteRevisionError(c, err, "Failed to update note")
+				return
+			}
+			updateData.Name, updateData.Content = "", ""
+		}
+	}
+	if note.Locked && updateData.Content != "" {
+		if err := lockService.UpdateContent(id, lockSession, updateData.Content); err != nil {
+			sendNoteLockError(c, err, "Failed to update note")
+			return
+		}
+		note.Content = updateData.Content
+		updateData.Content = ""
+	}
 
 	// Update the note
-	if err := db.DB.Model(&note).Updates(updateData).Error; err != nil {
-		log.Print("Error updating note with id: ", id, " Error: ", err)
+	renamed := updateData.Name != "" && updateData.Name != note.Name
+	if err := db.DB.WithContext(c.Request.Context()).Model(&note).Updates(updateData).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Str("note_id", id).Msg("Error updating note")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if renamed {
+		if note.Slug, err = services.RefreshNoteSlug(db.DB, note.ID); err != nil {
+			log.Error().Err(err).Str("note_id", note.ID).Msg("Failed to refresh note slug")
+		}
+	}
 
+	if plainContent {
+		recordModeration(note.OrganizationID, services.ModerationTarget{Source: models.ModerationSourceNote, NoteID: &note.ID, ClerkUserID: clerkUserID}, moderation, moderationText)
+		// Locked notes are left out of embeds and automations, and pending revisions until they're published
+		if !note.Locked && revision == nil {
+			syncNoteEmbeds(note.ID, updateData.Content, clerkUserID)
+			syncNoteWikiLinks(note.ID, clerkUserID)
+			services.NewAutomationService().NoteChanged(note.ID, false)
+			services.NewNoteSummaryService().NoteChanged(note.ID)
+			services.NewNoteLanguageService().NoteChanged(note.ID)
+			services.NewGlossaryService().NoteChanged(note.ID)
+		}
+	}
+	// Edits waiting in a pending revision are counted when it's published
+	if revision == nil {
+		go services.NewContentAnalyticsService().RecordNoteAccess(note.ID, clerkUserID, models.NoteAccessEdit)
+		go services.NewWritingStatsService().RecordWriting(clerkUserID, note.OrganizationID, previousContent, writtenContent, false)
+	}
+
+	note.PendingRevision = revision
 	c.JSON(http.StatusOK, note)
 }
 
@@ -286,106 +453,39 @@ func MoveNote(c *gin.Context) {
 
 	id := c.Param("id")
 
-	// Check authorization for source note efficiently
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, id, clerkUserID)
-	if err != nil {
-		log.Print("Note not found with id: ", id, " Error: ", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
-		return
-	}
-	if !hasAccess {
-		log.Warn().Str("note_id", id).Str("user_id", clerkUserID).Msg("User not authorized to move note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+	// Viewers and commenters of organization notes can't change them
+	if !authorizeNote(c, id, clerkUserID, middleware.NoteAccessCanEdit) {
 		return
 	}
 
 	// Bind the move data from request body
 	var moveData struct {
-		ChapterID      string  `json:"chapter_id" binding:"required"`
-		OrganizationID *string `json:"organization_id"`
+		ChapterID string `json:"chapter_id" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&moveData); err != nil {
-		log.Print("Invalid move data for note: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid move data for note")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Log the received organization ID
-	if moveData.OrganizationID != nil {
-		log.Printf("DEBUG: Move note request with organization ID: %s", *moveData.OrganizationID)
-	}
-
-	// Check authorization for target chapter efficiently
-	targetHasAccess, err := middleware.CheckChapterAccess(c.Request.Context(), db.DB, moveData.ChapterID, clerkUserID)
-	if err != nil {
-		log.Print("Target chapter not found with id: ", moveData.ChapterID, " Error: ", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Target chapter not found"})
-		return
-	}
-	if !targetHasAccess {
-		log.Warn().Str("chapter_id", moveData.ChapterID).Str("user_id", clerkUserID).Msg("User not authorized to move note to target chapter")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized: Target chapter does not belong to user"})
-		return
-	}
-
-	// Get target chapter's organization_id
-	var targetChapter models.Chapter
-	if err := db.DB.Select("organization_id").Where("id = ?", moveData.ChapterID).First(&targetChapter).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move note"})
+	// Check the user may add notes to the target chapter
+	if !authorizeChapter(c, moveData.ChapterID, clerkUserID, middleware.NoteAccessCanEdit) {
 		return
 	}
 
-	// Log before update
-	log.Printf("DEBUG: Before update - Note ID: %s, Target Chapter ID: %s", id, moveData.ChapterID)
-
-	// Determine which organization ID to use
-	var orgIDToUse *string
-	if moveData.OrganizationID != nil && *moveData.OrganizationID != "" {
-		// Use the provided organization ID from request
-		orgIDToUse = moveData.OrganizationID
-		log.Printf("DEBUG: Using organization ID from request: %s", *orgIDToUse)
-	} else {
-		// Fall back to target chapter's organization ID
-		orgIDToUse = targetChapter.OrganizationID
-		if orgIDToUse != nil {
-			log.Printf("DEBUG: Using organization ID from target chapter: %s", *orgIDToUse)
-		}
-	}
-
-	// Update the note's chapter_id and organization_id using Updates with Select
-	updateData := map[string]interface{}{
-		"chapter_id":      moveData.ChapterID,
-		"organization_id": orgIDToUse,
-	}
-
-	log.Printf("DEBUG: About to update with data: %+v", updateData)
-
-	// Use Model().Select().Updates() for explicit column updates
-	result := db.DB.Model(&models.Notes{}).Where("id = ?", id).Select("chapter_id", "organization_id").Updates(updateData)
-	if result.Error != nil {
-		log.Printf("ERROR: Failed to update note - Error: %v", result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	encryption := services.NewNotebookEncryptionService()
+	if rejectCrossEncryptionMove(c, encryption.NoteEncrypted, id, encryption.ChapterEncrypted, moveData.ChapterID) {
 		return
 	}
 
-	log.Printf("DEBUG: GORM Update Result - Rows Affected: %d, Error: %v", result.RowsAffected, result.Error)
-
-	if result.RowsAffected == 0 {
-		log.Printf("WARNING: Update returned 0 rows affected for note ID: %s", id)
-	}
-
-	// Reload the note without preloads
-	var updatedNote models.Notes
-	if err := db.DB.Where("id = ?", id).First(&updatedNote).Error; err != nil {
-		log.Printf("ERROR: Failed to reload note after move: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload note"})
+	// The note takes the organization of the target chapter's notebook
+	updatedNote, err := services.NewOrgConsistencyService().MoveNote(id, moveData.ChapterID)
+	if err != nil {
+		sendMoveError(c, err, "Failed to move note")
 		return
 	}
 
-	log.Printf("DEBUG: After reload - Note ID: %s, Chapter ID: %s (expected: %s)",
-		updatedNote.ID, updatedNote.ChapterID, moveData.ChapterID)
-	log.Printf("Note moved successfully. Note ID: %s, New Chapter ID: %s", updatedNote.ID, moveData.ChapterID)
-
+	log.Info().Str("note_id", id).Str("chapter_id", moveData.ChapterID).Msg("Note moved successfully")
 	c.JSON(http.StatusOK, updatedNote)
 }
 
@@ -399,29 +499,25 @@ func GenerateNoteVideo(c *gin.Context) {
 
 	id := c.Param("id")
 
-	// Check authorization efficiently
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, id, clerkUserID)
-	if err != nil {
-		log.Print("Note not found with id: ", id, " Error: ", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	// Viewers and commenters of organization notes can't change them
+	if !authorizeNote(c, id, clerkUserID, middleware.NoteAccessCanEdit) {
 		return
 	}
-	if !hasAccess {
-		log.Warn().Str("note_id", id).Str("user_id", clerkUserID).Msg("User not authorized to generate video for note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+
+	if rejectEncryptedNote(c, id) || rejectLockedNotes(c, []string{id}) {
 		return
 	}
 
 	// Get note for video generation
 	var note models.Notes
-	if err := db.DB.Where("id = ?", id).First(&note).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Preload("Chapter.Notebook").Where("id = ?", id).First(&note).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate video"})
 		return
 	}
 
 	// Generate video data with AI based on note content
 	log.Info().Str("note_id", id).Msg("Generating AI-powered video for note")
-	videoData, err := GenerateVideoDataWithAI(clerkUserID, note.Name, note.Content)
+	videoData, err := GenerateVideoDataWithAI(clerkUserID, note.Chapter.Notebook.OrganizationID, note.Name, note.Content)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate AI video, using fallback")
 		// Fallback to simple generation
@@ -432,7 +528,7 @@ func GenerateNoteVideo(c *gin.Context) {
 	// Convert video data to JSON string
 	videoDataJSON, err := json.Marshal(videoData)
 	if err != nil {
-		log.Print("Error marshaling video data: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error marshaling video data")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate video data"})
 		return
 	}
@@ -443,15 +539,16 @@ func GenerateNoteVideo(c *gin.Context) {
 		HasVideo:  true,
 	}
 
-	if err := db.DB.Model(&note).Updates(update).Error; err != nil {
-		log.Print("Error updating note with video data: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Model(&note).Updates(update).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error updating note with video data")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save video data"})
 		return
 	}
 
 	// Reload the note without preloads
-	if err := db.DB.Where("id = ?", id).First(&note).Error; err != nil {
-		log.Print("Error reloading note after video generation: ", err)
+	note = models.Notes{}
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", id).First(&note).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error reloading note after video generation")
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Video generated successfully", "note": note})
@@ -468,32 +565,24 @@ func DeleteNoteVideo(c *gin.Context) {
 	id := c.Param("id")
 	var note models.Notes
 
-	// Check authorization efficiently
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, id, clerkUserID)
-	if err != nil {
-		log.Print("Note not found with id: ", id, " Error: ", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
-		return
-	}
-	if !hasAccess {
-		log.Warn().Str("note_id", id).Str("user_id", clerkUserID).Msg("User not authorized to delete video for note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+	// Viewers and commenters of organization notes can't change them
+	if !authorizeNote(c, id, clerkUserID, middleware.NoteAccessCanEdit) {
 		return
 	}
 
 	// Remove video data from note - use Select to force update empty strings
-	if err := db.DB.Model(&models.Notes{}).Where("id = ?", id).Select("VideoData", "HasVideo").Updates(map[string]interface{}{
+	if err := db.DB.WithContext(c.Request.Context()).Model(&models.Notes{}).Where("id = ?", id).Select("VideoData", "HasVideo").Updates(map[string]interface{}{
 		"video_data": "",
 		"has_video":  false,
 	}).Error; err != nil {
-		log.Print("Error removing video data from note: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error removing video data from note")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove video data"})
 		return
 	}
 
 	// Reload the note without preloads
-	if err := db.DB.Where("id = ?", id).First(&note).Error; err != nil {
-		log.Print("Error reloading note after video deletion: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", id).First(&note).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error reloading note after video deletion")
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Video removed successfully", "note": note})
@@ -519,11 +608,10 @@ type VideoStructure struct {
 	TransitionStyle string       `json:"transitionStyle"` // "fade", "slide", "zoom"
 }
 
-// getUserAPIKeyForVideo retrieves the user's API key for video generation using the new APIKeyResolver
-func getUserAPIKeyForVideo(clerkUserID string, provider string) (string, error) {
-	// TODO: Add organization context support for video generation
+// getUserAPIKeyForVideo retrieves the API key for video generation, the organization's for its notes
+func getUserAPIKeyForVideo(clerkUserID string, organizationID *string, provider string) (string, error) {
 	resolver := services.NewAPIKeyResolver()
-	result, err := resolver.GetAPIKey(clerkUserID, nil, provider)
+	result, err := resolver.GetAPIKey(clerkUserID, organizationID, provider)
 	if err != nil {
 		return "", err
 	}
@@ -531,17 +619,24 @@ func getUserAPIKeyForVideo(clerkUserID string, provider string) (string, error)
 }
 
 // GenerateVideoDataWithAI uses AI to create a structured video from note content
-func GenerateVideoDataWithAI(clerkUserID string, title, content string) (map[string]interface{}, error) {
+func GenerateVideoDataWithAI(clerkUserID string, organizationID *string, title, content string) (map[string]interface{}, error) {
 	// Extract text from JSON content if needed
 	extractedContent := ExtractTextFromJSON(content)
 
-	// Try to get API keys in order of preference: OpenAI, Anthropic, Google
-	providers := []string{"openai", "anthropic", "google"}
+	// Try the background model from the organization's or user's settings first, then any other provider
+	// with a key
+	selection := services.NewAIModelSettingsService().ResolveModel(clerkUserID, organizationID, models.AIModelPurposeBackground)
+	providers := []string{selection.Provider}
+	for _, p := range []string{config.AIProviderOpenAI, config.AIProviderAnthropic, config.AIProviderGoogle} {
+		if p != selection.Provider {
+			providers = append(providers, p)
+		}
+	}
 	var apiKey string
 	var provider string
 
 	for _, p := range providers {
-		key, err := getUserAPIKeyForVideo(clerkUserID, p)
+		key, err := getUserAPIKeyForVideo(clerkUserID, organizationID, p)
 		if err == nil && key != "" {
 			apiKey = key
 			provider = p
@@ -555,7 +650,12 @@ func GenerateVideoDataWithAI(clerkUserID string, title, content string) (map[str
 		return generateVideoData(title, extractedContent), nil
 	}
 
-	log.Info().Str("provider", provider).Msg("Generating video with AI provider")
+	model := services.GetAIProviderPolicy().DefaultModel(provider)
+	if provider == selection.Provider {
+		model = selection.Model
+	}
+
+	log.Info().Str("provider", provider).Str("model", model).Msg("Generating video with AI provider")
 
 	// Create prompt for AI to generate video structure
 	prompt := fmt.Sprintf(`You are a video content creator. Analyze the following note content and create an engaging video structure.
@@ -607,11 +707,11 @@ Respond with a JSON object in this exact format (no markdown, just raw JSON):
 
 	switch provider {
 	case "openai":
-		responseText, err = generateWithOpenAI(apiKey, prompt)
+		responseText, err = generateWithOpenAI(apiKey, model, prompt)
 	case "anthropic":
-		responseText, err = generateWithAnthropic(apiKey, prompt)
+		responseText, err = generateWithAnthropic(apiKey, model, prompt)
 	case "google":
-		responseText, err = generateWithGoogle(apiKey, prompt)
+		responseText, err = generateWithGoogle(apiKey, model, prompt)
 	default:
 		return generateVideoData(title, extractedContent), nil
 	}
@@ -661,7 +761,7 @@ Respond with a JSON object in this exact format (no markdown, just raw JSON):
 	return resultMap, nil
 }
 
-func generateWithOpenAI(apiKey, prompt string) (string, error) {
+func generateWithOpenAI(apiKey, model, prompt string) (string, error) {
 	client := openai.NewClient(openaioption.WithAPIKey(apiKey))
 
 	ctx := context.Background()
@@ -669,7 +769,7 @@ func generateWithOpenAI(apiKey, prompt string) (string, error) {
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.UserMessage(prompt),
 		},
-		Model: openai.ChatModelGPT4oMini,
+		Model: model,
 	})
 
 	if err != nil {
@@ -683,12 +783,12 @@ func generateWithOpenAI(apiKey, prompt string) (string, error) {
 	return completion.Choices[0].Message.Content, nil
 }
 
-func generateWithAnthropic(apiKey, prompt string) (string, error) {
+func generateWithAnthropic(apiKey, model, prompt string) (string, error) {
 	client := anthropic.NewClient(anthropicoption.WithAPIKey(apiKey))
 
 	ctx := context.Background()
 	message, err := client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.Model("claude-3-5-sonnet-20241022"),
+		Model:     anthropic.Model(model),
 		MaxTokens: 2000,
 		Messages: []anthropic.MessageParam{
 			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
@@ -706,7 +806,7 @@ func generateWithAnthropic(apiKey, prompt string) (string, error) {
 	return message.Content[0].Text, nil
 }
 
-func generateWithGoogle(apiKey, prompt string) (string, error) {
+func generateWithGoogle(apiKey, model, prompt string) (string, error) {
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
 		APIKey:  apiKey,
@@ -717,7 +817,7 @@ func generateWithGoogle(apiKey, prompt string) (string, error) {
 	}
 
 	// Collect streamed response
-	stream := client.Models.GenerateContentStream(ctx, "gemini-1.5-flash",
+	stream := client.Models.GenerateContentStream(ctx, model,
 		[]*genai.Content{{
 			Role: "user",
 			Parts: []*genai.Part{{
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type noteAccessFixture struct {
+	router   *gin.Engine
+	notebook models.Notebook
+	chapter  models.Chapter
+	note     models.Notes
+}
+
+// setupTestNoteAccess serves the note handlers over an in-memory database with an organization notebook,
+// acting as the user in the X-Test-User header
+func setupTestNoteAccess(t *testing.T) noteAccessFixture {
+	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, testDB.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NotebookSnapshot{}), "Failed to migrate test database")
+
+	previous := db.DB
+	db.DB = testDB
+	t.Cleanup(func() { db.DB = previous })
+
+	orgID := "org_note_access"
+	f := noteAccessFixture{notebook: models.Notebook{Name: "Handbook", ClerkUserID: "user_admin", OrganizationID: &orgID}}
+	require.NoError(t, testDB.Create(&f.notebook).Error)
+	f.chapter = models.Chapter{Name: "Onboarding", NotebookID: f.notebook.ID, OrganizationID: &orgID}
+	require.NoError(t, testDB.Create(&f.chapter).Error)
+	f.note = models.Notes{Name: "First week", ChapterID: f.chapter.ID, OrganizationID: &orgID, Content: "Meet the team"}
+	require.NoError(t, testDB.Create(&f.note).Error)
+
+	for user, role := range map[string]string{
+		"user_viewer":    middleware.OrgRoleViewer,
+		"user_commenter": middleware.OrgRoleCommenter,
+		"user_member":    middleware.OrgRoleMember,
+	} {
+		middleware.GetOrgCache().Set(orgID, user, role, true)
+		t.Cleanup(func() { middleware.GetOrgCache().Invalidate(orgID, user) })
+	}
+
+	gin.SetMode(gin.TestMode)
+	f.router = gin.New()
+	f.router.Use(func(c *gin.Context) {
+		c.Set("clerk_user_id", c.GetHeader("X-Test-User"))
+	})
+	f.router.POST("/note", CreateNote)
+	f.router.POST("/api/chat/:conversationId/save-as-note", SaveChatAsNote)
+	f.router.PUT("/note/:id", UpdateNote)
+	f.router.DELETE("/note/:id", DeleteNote)
+	f.router.PUT("/note/:id/move", MoveNote)
+	f.router.DELETE("/note/:id/video", DeleteNoteVideo)
+	f.router.PUT("/chapter/:id/settings", UpdateChapterSettings)
+	f.router.GET("/notebook/:id/snapshots", ListNotebookSnapshots)
+	f.router.POST("/notebook/:id/snapshots", CreateNotebookSnapshot)
+	f.router.DELETE("/notebook/:id/snapshots/:name", DeleteNotebookSnapshot)
+	f.router.POST("/notebook/:id/link-report/issues/:issueId/remove-link", RemoveLinkIssueLink)
+	f.router.PUT("/notebook/:id/privacy", UpdateNotebookPrivacySettings)
+	f.router.PUT("/notebook/:id/encryption", EnableNotebookEncryption)
+	f.router.DELETE("/notebook/:id/encryption", DisableNotebookEncryption)
+	f.router.PUT("/notebook/:id/qa-settings", UpdateNotebookQASettings)
+	return f
+}
+
+func (f noteAccessFixture) request(t *testing.T, method, path, user, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", user)
+	resp := httptest.NewRecorder()
+	f.router.ServeHTTP(resp, req)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &decoded))
+	return resp.Code, decoded
+}
+
+func TestNoteMutationsNeedEditAccess(t *testing.T) {
+	f := setupTestNoteAccess(t)
+
+	status, body := f.request(t, http.MethodPut, "/note/"+f.note.ID, "user_viewer", `{"name":"Renamed","content":"Changed"}`)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "read_only", body["code"])
+
+	status, body = f.request(t, http.MethodPut, "/note/"+f.note.ID, "user_commenter", `{"content":"Changed"}`)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "comment_only", body["code"])
+
+	for _, call := range []struct{ method, path, body string }{
+		{http.MethodDelete, "/note/" + f.note.ID, ""},
+		{http.MethodPut, "/note/" + f.note.ID + "/move", `{"chapter_id":"` + f.chapter.ID + `"}`},
+		{http.MethodDelete, "/note/" + f.note.ID + "/video", ""},
+		{http.MethodPost, "/note", `{"name":"New","chapterId":"` + f.chapter.ID + `"}`},
+		{http.MethodPost, "/api/chat/conv_1/save-as-note", `{"chapterId":"` + f.chapter.ID + `","messages":[{"role":"user","content":"Hi"}]}`},
+	} {
+		status, _ = f.request(t, call.method, call.path, "user_viewer", call.body)
+		assert.Equal(t, http.StatusForbidden, status, call.method+" "+call.path)
+	}
+
+	var note models.Notes
+	require.NoError(t, db.DB.First(&note, "id = ?", f.note.ID).Error)
+	assert.Equal(t, "First week", note.Name)
+	assert.Equal(t, "Meet the team", note.Content)
+	var count int64
+	require.NoError(t, db.DB.Model(&models.Notes{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	status, _ = f.request(t, http.MethodPut, "/note/missing", "user_viewer", `{"content":"Changed"}`)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestNotebookMutationsNeedEditAccess(t *testing.T) {
+	f := setupTestNoteAccess(t)
+
+	status, body := f.request(t, http.MethodPost, "/notebook/"+f.notebook.ID+"/snapshots", "user_viewer", `{"name":"v1"}`)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "read_only", body["code"])
+
+	status, body = f.request(t, http.MethodPut, "/chapter/"+f.chapter.ID+"/settings", "user_commenter", `{}`)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "comment_only", body["code"])
+
+	status, _ = f.request(t, http.MethodDelete, "/notebook/"+f.notebook.ID+"/snapshots/v1", "user_commenter", "")
+	assert.Equal(t, http.StatusForbidden, status)
+
+	status, body = f.request(t, http.MethodPost, "/notebook/"+f.notebook.ID+"/link-report/issues/issue_1/remove-link", "user_viewer", "")
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "read_only", body["code"])
+
+	status, _ = f.request(t, http.MethodPut, "/notebook/"+f.notebook.ID+"/privacy", "user_viewer", `{"analyticsEnabled":true}`)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// Reading stays open to every member
+	status, _ = f.request(t, http.MethodGet, "/notebook/"+f.notebook.ID+"/snapshots", "user_viewer", "")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = f.request(t, http.MethodPost, "/notebook/missing/snapshots", "user_viewer", `{"name":"v1"}`)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestNotebookManagementNeedsOwnerOrAdmin(t *testing.T) {
+	f := setupTestNoteAccess(t)
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		status, _ := f.request(t, method, "/notebook/"+f.notebook.ID+"/encryption", "user_member", `{}`)
+		assert.Equal(t, http.StatusForbidden, status, method)
+	}
+
+	status, _ := f.request(t, http.MethodPut, "/notebook/"+f.notebook.ID+"/qa-settings", "user_member", `{"enabled":true}`)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	var notebook models.Notebook
+	require.NoError(t, db.DB.First(&notebook, "id = ?", f.notebook.ID).Error)
+	assert.False(t, notebook.Encrypted)
+}
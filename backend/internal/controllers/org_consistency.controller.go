@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RepairOrgConsistency gives the chapters, notes and attached records of the organization's notebooks the
+// notebook's organization, and moves records that still claim the organization after leaving it
+// POST /organizations/:orgId/consistency/repair
+func RepairOrgConsistency(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	report, err := services.NewOrgConsistencyService().RepairOrganization(orgID)
+	if err != nil {
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to repair organization consistency")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to repair organization consistency"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// sendMoveError maps errors from moving notes and chapters to responses
+func sendMoveError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	case errors.Is(err, services.ErrChapterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+	case errors.Is(err, services.ErrLegalHold):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
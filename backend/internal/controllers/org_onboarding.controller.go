@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrgOnboardingSettings returns the notebooks members land in when they join the organization
+// GET /organizations/:orgId/onboarding-settings
+func GetOrgOnboardingSettings(c *gin.Context) {
+	settings, err := services.NewOrgOnboardingService().GetSettings(c.Request.Context(), c.Param("orgId"))
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch onboarding settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch onboarding settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateOrgOnboardingSettings replaces the shared notebooks and member workspace created for members who
+// join the organization from then on
+// PUT /organizations/:orgId/onboarding-settings
+func UpdateOrgOnboardingSettings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req services.OrgOnboardingSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := services.NewOrgOnboardingService().SaveSettings(c.Request.Context(), c.Param("orgId"), req, clerkUserID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOnboardingSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to save onboarding settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save onboarding settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
@@ -2,10 +2,12 @@ package controllers
 
 import (
 	"backend/internal/middleware"
+	"backend/internal/models"
 	"backend/internal/services"
 	"backend/internal/types"
 	internalutils "backend/internal/utils"
 	"backend/pkg/utils"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -93,10 +95,7 @@ func ListUserOrganizations(c *gin.Context) {
 	orgs := make([]gin.H, 0)
 	for _, membership := range memberships.OrganizationMemberships {
 		// Map Clerk role to our simplified role format
-		role := "member"
-		if membership.Role == "org:admin" {
-			role = "admin"
-		}
+		role := middleware.OrgRoleFromClerk(membership.Role)
 
 		orgs = append(orgs, gin.H{
 			"id":           membership.Organization.ID,
@@ -196,6 +195,7 @@ func DeleteOrganization(c *gin.Context) {
 		return
 	}
 
+	middleware.GetOrgCache().InvalidateOrg(orgID)
 	log.Info().Str("org_id", orgID).Msg("Organization deleted")
 	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted successfully"})
 }
@@ -348,6 +348,126 @@ func DeleteOrgAPICredential(c *gin.Context) {
 	})
 }
 
+// GetOrgAISettings returns the organization's default AI providers and models
+func GetOrgAISettings(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	settings, err := services.NewAIModelSettingsService().GetOrgSettings(orgID)
+	if err != nil {
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to get organization AI settings")
+		apiErr := types.ErrDatabaseError.WithDetails("Failed to retrieve organization AI settings")
+		internalutils.SendErrorResponse(c, apiErr)
+		return
+	}
+
+	internalutils.SendSuccessResponse(c, gin.H{
+		"settings": settings,
+	})
+}
+
+// UpdateOrgAISettings sets the organization's default AI providers and models (admin only)
+// These apply to every member working in the organization, overriding personal settings
+func UpdateOrgAISettings(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	// Get authenticated user ID
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		internalutils.SendErrorResponse(c, types.ErrUnauthorized)
+		return
+	}
+
+	var req models.AIModelDefaults
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiErr := types.ErrMissingFields.WithDetails("Invalid AI settings payload")
+		internalutils.SendErrorResponse(c, apiErr)
+		return
+	}
+
+	settingsService := services.NewAIModelSettingsService()
+	if err := settingsService.SetOrgSettings(orgID, req, clerkUserID); err != nil {
+		if errors.Is(err, services.ErrInvalidAIModelSettings) {
+			internalutils.SendErrorResponse(c, types.ErrInvalidProvider.WithDetails(err.Error()))
+			return
+		}
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to save organization AI settings")
+		internalutils.SendErrorResponse(c, types.ErrDatabaseError.WithDetails("Failed to save organization AI settings"))
+		return
+	}
+
+	settings, err := settingsService.GetOrgSettings(orgID)
+	if err != nil {
+		internalutils.SendErrorResponse(c, types.ErrDatabaseError.WithDetails("Failed to retrieve organization AI settings"))
+		return
+	}
+
+	log.Info().Str("org_id", orgID).Str("updated_by", clerkUserID).Msg("Organization AI settings updated")
+
+	internalutils.SendSuccessMessageResponse(c, "Organization AI settings saved successfully", gin.H{
+		"settings": settings,
+	})
+}
+
+// GetOrgAIToolSettings returns which assistant tools are enabled in the organization
+func GetOrgAIToolSettings(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	settings, err := services.NewAIToolPermissionService().GetOrgPermissions(orgID)
+	if err != nil {
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to get organization AI tool settings")
+		apiErr := types.ErrDatabaseError.WithDetails("Failed to retrieve organization AI tool settings")
+		internalutils.SendErrorResponse(c, apiErr)
+		return
+	}
+
+	internalutils.SendSuccessResponse(c, gin.H{
+		"settings": settings,
+	})
+}
+
+// UpdateOrgAIToolSettings sets which assistant tools are enabled in the organization (admin only)
+// Members can restrict tools further in their own settings but can't re-enable them
+func UpdateOrgAIToolSettings(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	// Get authenticated user ID
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		internalutils.SendErrorResponse(c, types.ErrUnauthorized)
+		return
+	}
+
+	var req services.AIToolPermissionSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiErr := types.ErrMissingFields.WithDetails("Invalid AI tool settings payload")
+		internalutils.SendErrorResponse(c, apiErr)
+		return
+	}
+
+	permissionService := services.NewAIToolPermissionService()
+	if err := permissionService.SetOrgPermissions(orgID, req, clerkUserID); err != nil {
+		if errors.Is(err, services.ErrInvalidAIToolPermissions) {
+			internalutils.SendErrorResponse(c, types.NewAPIError(types.ErrorCodeValidationFailed, "Invalid AI tool settings", http.StatusBadRequest).WithDetails(err.Error()))
+			return
+		}
+		log.Error().Err(err).Str("org_id", orgID).Msg("Failed to save organization AI tool settings")
+		internalutils.SendErrorResponse(c, types.ErrDatabaseError.WithDetails("Failed to save organization AI tool settings"))
+		return
+	}
+
+	settings, err := permissionService.GetOrgPermissions(orgID)
+	if err != nil {
+		internalutils.SendErrorResponse(c, types.ErrDatabaseError.WithDetails("Failed to retrieve organization AI tool settings"))
+		return
+	}
+
+	log.Info().Str("org_id", orgID).Str("updated_by", clerkUserID).Msg("Organization AI tool settings updated")
+
+	internalutils.SendSuccessMessageResponse(c, "Organization AI tool settings saved successfully", gin.H{
+		"settings": settings,
+	})
+}
+
 // GetOrganizationMembers fetches all members of an organization for task assignment
 func GetOrganizationMembers(c *gin.Context) {
 	orgID := c.Param("orgId")
@@ -360,7 +480,7 @@ func GetOrganizationMembers(c *gin.Context) {
 	}
 
 	// Verify user is a member of the organization
-	_, isMember, err := middleware.GetOrgMemberRole(c.Request.Context(), orgID, clerkUserID)
+	_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
 	if err != nil || !isMember {
 		log.Warn().Str("org_id", orgID).Str("user_id", clerkUserID).Msg("User not authorized to view organization members")
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
@@ -383,10 +503,7 @@ func GetOrganizationMembers(c *gin.Context) {
 	members := make([]gin.H, 0)
 	for _, membership := range memberships.OrganizationMemberships {
 		// Map Clerk role to our simplified role format
-		role := "member"
-		if membership.Role == "org:admin" {
-			role = "admin"
-		}
+		role := middleware.OrgRoleFromClerk(membership.Role)
 
 		members = append(members, gin.H{
 			"id":       membership.PublicUserData.UserID,
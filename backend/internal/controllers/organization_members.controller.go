@@ -2,7 +2,10 @@ package controllers
 
 import (
 	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/clerk/clerk-sdk-go/v2/organization"
@@ -37,8 +40,8 @@ func InviteMember(c *gin.Context) {
 	}
 
 	// Validate role
-	if req.Role != "org:admin" && req.Role != "org:member" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be 'org:admin' or 'org:member'"})
+	if !middleware.ValidClerkOrgRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be 'org:admin', 'org:member', 'org:commenter' or 'org:viewer'"})
 		return
 	}
 
@@ -84,6 +87,56 @@ func InviteMember(c *gin.Context) {
 	})
 }
 
+// ImportMembers invites everyone a CSV of emails and roles lists, reporting what happened to each row
+// (admin only). With dryRun set the rows are only checked.
+// POST /organizations/:orgId/members/import
+func ImportMembers(c *gin.Context) {
+	orgID := c.Param("orgId")
+
+	inviterUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxMemberImportFileSize+(1<<20))
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A CSV file is required"})
+		return
+	}
+	if fileHeader.Size > services.MaxMemberImportFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "CSV file is too large"})
+		return
+	}
+
+	options := services.MemberImportOptions{
+		DefaultRole: c.PostForm("role"),
+		RedirectURL: c.PostForm("redirectUrl"),
+	}
+	options.DryRun, _ = strconv.ParseBool(c.PostForm("dryRun"))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read CSV file"})
+		return
+	}
+	defer file.Close()
+
+	result, err := services.NewOrgMemberImportService().Import(c.Request.Context(), orgID, inviterUserID, file, options)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMemberImport) || errors.Is(err, services.ErrMemberImportTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to import members")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // ListMembers gets all members of an organization
 func ListMembers(c *gin.Context) {
 	orgID := c.Param("orgId")
@@ -150,8 +203,8 @@ func UpdateMemberRole(c *gin.Context) {
 	}
 
 	// Validate role
-	if req.Role != "org:admin" && req.Role != "org:member" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be 'org:admin' or 'org:member'"})
+	if !middleware.ValidClerkOrgRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be 'org:admin', 'org:member', 'org:commenter' or 'org:viewer'"})
 		return
 	}
 
@@ -197,6 +250,7 @@ func UpdateMemberRole(c *gin.Context) {
 		return
 	}
 
+	middleware.GetOrgCache().Invalidate(orgID, userID)
 	log.Info().Str("org_id", orgID).Str("user_id", userID).Str("new_role", req.Role).Msg("Member role updated")
 	c.JSON(http.StatusOK, gin.H{
 		"id":             membership.ID,
@@ -251,6 +305,7 @@ func RemoveMember(c *gin.Context) {
 		return
 	}
 
+	middleware.GetOrgCache().Invalidate(orgID, userID)
 	log.Info().Str("org_id", orgID).Str("user_id", userID).Msg("Member removed from organization")
 	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
 }
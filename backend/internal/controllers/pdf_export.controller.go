@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportNotePDF renders a note as a PDF with a cover page and a table of contents of its headings.
+// The page size is a4, letter or legal, and cover=false or toc=false leave those pages out.
+// GET /note/:id/export.pdf?pageSize=a4&cover=true&toc=true
+func ExportNotePDF(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to export note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	options, ok := pdfExportOptions(c)
+	if !ok {
+		return
+	}
+	export, err := services.NewPDFExportService().ExportNote(c.Request.Context(), noteID, options)
+	if err != nil {
+		sendPDFExportError(c, err, "Failed to export note")
+		return
+	}
+
+	sendPDF(c, export, "note")
+}
+
+// ExportNotebookPDF renders a notebook as a PDF, each note starting on a new page after a cover page
+// and a table of contents. Notebooks too large to render during the request are exported in the
+// background: the response is 202 with the export, which is downloaded from downloadUrl once completed.
+// GET /notebook/:id/export.pdf?pageSize=a4&cover=true&toc=true
+// GET /notebook/:id/export.pdf?exportId=...
+func ExportNotebookPDF(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notebookID := c.Param("id")
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User not authorized to export notebook")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	service := services.NewPDFExportService()
+	var export *services.PDFExport
+	if exportID := c.Query("exportId"); exportID != "" {
+		export, err = service.GetNotebookExport(c.Request.Context(), notebookID, exportID)
+	} else {
+		options, ok := pdfExportOptions(c)
+		if !ok {
+			return
+		}
+		export, err = service.ExportNotebook(c.Request.Context(), notebookID, clerkUserID, options)
+	}
+	if err != nil {
+		sendPDFExportError(c, err, "Failed to export notebook")
+		return
+	}
+
+	if export.PDF == nil {
+		sendPendingPDFExport(c, notebookID, export.Export)
+		return
+	}
+	sendPDF(c, export, "notebook")
+}
+
+// pdfExportOptions reads the export options from the query, responding with an error when invalid
+func pdfExportOptions(c *gin.Context) (services.PDFExportOptions, bool) {
+	options, err := services.NewPDFExportOptions(c.Query("pageSize"), c.DefaultQuery("cover", "true") != "false",
+		c.DefaultQuery("toc", "true") != "false")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return options, false
+	}
+	return options, true
+}
+
+// sendPendingPDFExport responds with a background export that isn't ready, or its error when it failed
+func sendPendingPDFExport(c *gin.Context, notebookID string, export *models.NotebookPDFExport) {
+	if export.Status == models.PDFExportStatusFailed {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export notebook", "export": export})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"export":      export,
+		"downloadUrl": fmt.Sprintf("/notebook/%s/export.pdf?exportId=%s", notebookID, export.ID),
+	})
+}
+
+// sendPDF responds with a PDF to download, named after the note or notebook
+func sendPDF(c *gin.Context, export *services.PDFExport, fallbackName string) {
+	name := models.Slugify(export.Name)
+	if name == "" {
+		name = fallbackName
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".pdf"))
+	c.Data(http.StatusOK, "application/pdf", export.PDF)
+}
+
+// sendPDFExportError maps PDF export service errors to responses
+func sendPDFExportError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+	case errors.Is(err, services.ErrPDFExportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "PDF export not found, it may have expired"})
+	case errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPDFExportTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -2,11 +2,13 @@ package controllers
 
 import (
 	"backend/db"
+	"backend/internal/middleware"
 	"backend/internal/models"
+	"backend/internal/services"
+	"backend/internal/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/rs/zerolog/log"
 )
 
 // GetPublicNotebook returns a public notebook with only public chapters and notes
@@ -16,18 +18,18 @@ func GetPublicNotebook(c *gin.Context) {
 	var notebook models.Notebook
 
 	// Get notebook only if it's public
-	if err := db.DB.Where("id = ? AND is_public = ?", notebookID, true).First(&notebook).Error; err != nil {
-		log.Print("Public notebook not found with id: ", notebookID, " Error: ", err)
+	if err := db.Replica(db.DB).WithContext(c.Request.Context()).Where("id = ? AND is_public = ?", notebookID, true).First(&notebook).Error; err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", notebookID).Msg("Public notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found or not public"})
 		return
 	}
 
 	// Get only public chapters and their public notes
 	var chapters []models.Chapter
-	if err := db.DB.Where("notebook_id = ? AND is_public = ?", notebookID, true).
+	if err := db.Replica(db.DB).WithContext(c.Request.Context()).Where("notebook_id = ? AND is_public = ?", notebookID, true).
 		Preload("Files", "is_public = ?", true).
 		Find(&chapters).Error; err != nil {
-		log.Print("Error fetching public chapters for notebook: ", notebookID, " Error: ", err)
+		middleware.Logger(c).Error().Err(err).Str("notebook_id", notebookID).Msg("Error fetching public chapters for notebook")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch chapters"})
 		return
 	}
@@ -40,9 +42,40 @@ func GetPublicNotebook(c *gin.Context) {
 		}
 	}
 
+	switcher, ok := publicReaderLanguage(c, notebookID, "")
+	if !ok {
+		return
+	}
+	languageService := services.NewNotebookLanguageService()
+	for i := range filteredChapters {
+		filteredChapters[i].Files = languageService.LocalizeNotes(switcher, filteredChapters[i].Files)
+	}
+
 	notebook.Chapters = filteredChapters
 
-	c.JSON(http.StatusOK, notebook)
+	c.JSON(http.StatusOK, publicNotebook{Notebook: notebook, Languages: switcher})
+}
+
+// publicNotebook is a published notebook with the languages readers can switch it to
+type publicNotebook struct {
+	models.Notebook
+	Languages *services.PublicLanguageSwitcher `json:"languages,omitempty"`
+}
+
+// publicChapter is a published chapter with the languages readers can switch it to
+type publicChapter struct {
+	models.Chapter
+	Languages *services.PublicLanguageSwitcher `json:"languages,omitempty"`
+}
+
+// publicNote is a published note in the reader's language, with the note to show for each language
+// readers can switch to and the highlights made on it
+type publicNote struct {
+	models.Notes
+	Fallback    bool                              `json:"fallback,omitempty"` // The note isn't translated to the reader's language
+	Variants    []services.PublicNoteVariant      `json:"variants,omitempty"`
+	Languages   *services.PublicLanguageSwitcher  `json:"languages,omitempty"`
+	Annotations []services.AnchoredNoteAnnotation `json:"annotations,omitempty"`
 }
 
 // GetPublicChapter returns a public chapter with only public notes
@@ -53,10 +86,10 @@ func GetPublicChapter(c *gin.Context) {
 	var chapter models.Chapter
 
 	// Get chapter only if it's public and belongs to a public notebook
-	if err := db.DB.Where("id = ? AND notebook_id = ? AND is_public = ?", chapterID, notebookID, true).
+	if err := db.Replica(db.DB).WithContext(c.Request.Context()).Where("id = ? AND notebook_id = ? AND is_public = ?", chapterID, notebookID, true).
 		Preload("Notebook", "is_public = ?", true).
 		First(&chapter).Error; err != nil {
-		log.Print("Public chapter not found with id: ", chapterID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("chapter_id", chapterID).Msg("Public chapter not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found or not public"})
 		return
 	}
@@ -69,15 +102,19 @@ func GetPublicChapter(c *gin.Context) {
 
 	// Get only public notes for this chapter
 	var notes []models.Notes
-	if err := db.DB.Where("chapter_id = ? AND is_public = ?", chapterID, true).Find(&notes).Error; err != nil {
-		log.Print("Error fetching public notes for chapter: ", chapterID, " Error: ", err)
+	if err := db.Replica(db.DB).WithContext(c.Request.Context()).Where("chapter_id = ? AND is_public = ?", chapterID, true).Find(&notes).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Str("chapter_id", chapterID).Msg("Error fetching public notes for chapter")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notes"})
 		return
 	}
 
-	chapter.Files = notes
+	switcher, ok := publicReaderLanguage(c, notebookID, "")
+	if !ok {
+		return
+	}
+	chapter.Files = services.NewNotebookLanguageService().LocalizeNotes(switcher, notes)
 
-	c.JSON(http.StatusOK, chapter)
+	c.JSON(http.StatusOK, publicChapter{Chapter: chapter, Languages: switcher})
 }
 
 // GetPublicNote returns a single public note
@@ -89,11 +126,11 @@ func GetPublicNote(c *gin.Context) {
 	var note models.Notes
 
 	// Get note only if it's public and belongs to a public chapter and notebook
-	if err := db.DB.Where("id = ? AND chapter_id = ? AND is_public = ?", noteID, chapterID, true).
+	if err := db.Replica(db.DB).WithContext(c.Request.Context()).Where("id = ? AND chapter_id = ? AND is_public = ?", noteID, chapterID, true).
 		Preload("Chapter", "is_public = ?", true).
 		Preload("Chapter.Notebook", "is_public = ?", true).
 		First(&note).Error; err != nil {
-		log.Print("Public note not found with id: ", noteID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("note_id", noteID).Msg("Public note not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found or not public"})
 		return
 	}
@@ -104,7 +141,48 @@ func GetPublicNote(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, note)
+	// Notebooks published in several languages show the note in the reader's language
+	linkedLanguage := ""
+	if note.TranslationOfID != nil {
+		linkedLanguage = note.Language
+	}
+	switcher, ok := publicReaderLanguage(c, notebookID, linkedLanguage)
+	if !ok {
+		return
+	}
+	localized, err := services.NewNotebookLanguageService().LocalizeNote(c.Request.Context(), switcher, &note)
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch note translations")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch note"})
+		return
+	}
+	shown := localized.Note
+	shown.Chapter = note.Chapter
+
+	// Resolve embedded notes, only published ones are shown
+	content, err := services.NewNoteEmbedService().RenderContent(shown.ID, shown.Content, publicEmbedVisible)
+	if err != nil {
+		middleware.Logger(c).Error().Err(err).Str("note_id", shown.ID).Msg("Failed to render public note")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render note"})
+		return
+	}
+	shown.Content = content
+
+	// Highlights are made on the note itself, so translations show none
+	var annotations []services.AnchoredNoteAnnotation
+	if shown.ID == note.ID {
+		if annotations, err = services.NewNoteAnnotationService().PublicAnnotations(c.Request.Context(), shown.ID, shown.Content); err != nil {
+			middleware.Logger(c).Warn().Err(err).Str("note_id", shown.ID).Msg("Failed to fetch public note annotations")
+		}
+	}
+
+	// Formulas and diagrams are rendered here so published pages show them without KaTeX or mermaid
+	shown.Content = utils.RenderTipTapMath(shown.Content)
+	shown.Content = services.NewDiagramService().RenderContent(c.Request.Context(), shown.Content)
+
+	recordPublicRead(c, notebookID, shown.ID)
+
+	c.JSON(http.StatusOK, publicNote{Notes: *shown, Fallback: localized.Fallback, Variants: localized.Variants, Languages: switcher, Annotations: annotations})
 }
 
 // GetPublicUserProfile returns a user's public profile with their public notebooks
@@ -115,11 +193,11 @@ func GetPublicUserProfile(c *gin.Context) {
 
 	// Get user's public notebooks with public chapters and notes
 	var notebooks []models.Notebook
-	if err := db.DB.Where("clerk_user_id = ? AND is_public = ?", clerkUserID, true).
+	if err := db.Replica(db.DB).WithContext(c.Request.Context()).Where("clerk_user_id = ? AND is_public = ?", clerkUserID, true).
 		Preload("Chapters", "is_public = ?", true).
 		Preload("Chapters.Files", "is_public = ?", true).
 		Find(&notebooks).Error; err != nil {
-		log.Print("Error fetching public notebooks for user: ", clerkUserID, " Error: ", err)
+		middleware.Logger(c).Error().Err(err).Str("profile_user_id", clerkUserID).Msg("Error fetching public notebooks")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notebooks"})
 		return
 	}
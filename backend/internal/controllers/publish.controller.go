@@ -4,6 +4,7 @@ import (
 	"backend/db"
 	"backend/internal/middleware"
 	"backend/internal/models"
+	"backend/internal/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,7 +13,63 @@ import (
 
 // PublishRequest represents the request body for publishing a notebook
 type PublishRequest struct {
-	NoteIds []string `json:"noteIds" binding:"required"`
+	NoteIds  []string `json:"noteIds" binding:"required"`
+	Override bool     `json:"override"` // Publish even though the notes contain secrets or personal data
+}
+
+// rejectUnapprovedNotes responds with an error when some notes can't be published because their
+// organization only publishes approved notes
+func rejectUnapprovedNotes(c *gin.Context, noteIDs []string) bool {
+	blocked, err := services.NewNoteLifecycleService().UnpublishableNotes(noteIDs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check note statuses")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check note statuses"})
+		return true
+	}
+	if len(blocked) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Only approved notes can be published in this organization",
+			"noteIds": blocked,
+		})
+		return true
+	}
+	return false
+}
+
+// rejectSensitiveNotes responds with the findings when notes about to be published contain secrets or
+// personal data. With override they are published anyway, and organization notes go to the admins' review queue.
+func rejectSensitiveNotes(c *gin.Context, noteIDs []string, override bool, userID string) bool {
+	moderation := services.NewModerationService()
+	results, err := moderation.ScanForPublishing(noteIDs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to scan notes before publishing")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan notes before publishing"})
+		return true
+	}
+	if len(results) == 0 {
+		return false
+	}
+
+	if !override {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Some notes contain what looks like secrets or personal data. Remove them, or publish again with override to publish anyway.",
+			"notes": results,
+		})
+		return true
+	}
+
+	for _, result := range results {
+		log.Warn().Str("note_id", result.NoteID).Str("user_id", userID).Int("findings", len(result.Findings)).Msg("Note published despite sensitive content")
+		if result.OrganizationID == nil || *result.OrganizationID == "" {
+			continue
+		}
+		noteID := result.NoteID
+		target := services.ModerationTarget{Source: models.ModerationSourcePublish, NoteID: &noteID, ClerkUserID: userID}
+		if err := moderation.Record(*result.OrganizationID, target, &services.ModerationResult{Matches: result.Findings}); err != nil {
+			log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to record publish findings")
+		}
+	}
+	return false
 }
 
 // PublishNotebook publishes a notebook by marking specific notes as public
@@ -29,21 +86,25 @@ func PublishNotebook(c *gin.Context) {
 	var req PublishRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Print("Invalid publish request: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid publish request")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
 	// Verify notebook ownership
 	var notebook models.Notebook
-	if err := db.DB.Where("id = ? AND clerk_user_id = ?", notebookID, userID).First(&notebook).Error; err != nil {
-		log.Print("Notebook not found with id: ", notebookID, " for user: ", userID, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ? AND clerk_user_id = ?", notebookID, userID).First(&notebook).Error; err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", notebookID).Msg("Notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
 		return
 	}
 
+	if rejectEncryptedNotebook(c, &notebook) || rejectLockedNotes(c, req.NoteIds) || rejectUnapprovedNotes(c, req.NoteIds) || rejectSensitiveNotes(c, req.NoteIds, req.Override, userID) {
+		return
+	}
+
 	// Start transaction
-	tx := db.DB.Begin()
+	tx := db.DB.WithContext(c.Request.Context()).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -55,7 +116,7 @@ func PublishNotebook(c *gin.Context) {
 		Where("chapter_id IN (SELECT id FROM chapters WHERE notebook_id = ?)", notebookID).
 		Update("is_public", false).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error resetting notes to private: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error resetting notes to private")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notes"})
 		return
 	}
@@ -63,7 +124,7 @@ func PublishNotebook(c *gin.Context) {
 	// Reset all chapters in this notebook to private
 	if err := tx.Model(&models.Chapter{}).Where("notebook_id = ?", notebookID).Update("is_public", false).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error resetting chapters to private: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error resetting chapters to private")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chapters"})
 		return
 	}
@@ -71,7 +132,7 @@ func PublishNotebook(c *gin.Context) {
 	// Reset notebook to private
 	if err := tx.Model(&notebook).Update("is_public", false).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error resetting notebook to private: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error resetting notebook to private")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notebook"})
 		return
 	}
@@ -86,7 +147,7 @@ func PublishNotebook(c *gin.Context) {
 	// Mark selected notes as public
 	if err := tx.Model(&models.Notes{}).Where("id IN ? AND chapter_id IN (SELECT id FROM chapters WHERE notebook_id = ?)", req.NoteIds, notebookID).Update("is_public", true).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error marking notes as public: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error marking notes as public")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish notes"})
 		return
 	}
@@ -100,7 +161,7 @@ func PublishNotebook(c *gin.Context) {
 		)
 	`, notebookID).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error marking chapters as public: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error marking chapters as public")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish chapters"})
 		return
 	}
@@ -108,7 +169,7 @@ func PublishNotebook(c *gin.Context) {
 	// Mark notebook as public since it has published content
 	if err := tx.Model(&notebook).Update("is_public", true).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error marking notebook as public: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error marking notebook as public")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish notebook"})
 		return
 	}
@@ -116,6 +177,9 @@ func PublishNotebook(c *gin.Context) {
 	// Commit transaction
 	tx.Commit()
 
+	// Push the published notebook to GitHub for integrations that sync on publish
+	services.NewGitHubSyncService().QueueSyncForNotebook(notebookID, userID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Notebook published successfully"})
 }
 
@@ -132,21 +196,25 @@ func UpdatePublishedNotes(c *gin.Context) {
 	var req PublishRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Print("Invalid update published notes request: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid update published notes request")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
 	// Verify notebook ownership and that it's published
 	var notebook models.Notebook
-	if err := db.DB.Where("id = ? AND clerk_user_id = ? AND is_public = ?", notebookID, userID, true).First(&notebook).Error; err != nil {
-		log.Print("Published notebook not found with id: ", notebookID, " for user: ", userID, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ? AND clerk_user_id = ? AND is_public = ?", notebookID, userID, true).First(&notebook).Error; err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", notebookID).Msg("Published notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found or not published"})
 		return
 	}
 
+	if rejectEncryptedNotebook(c, &notebook) || rejectLockedNotes(c, req.NoteIds) || rejectUnapprovedNotes(c, req.NoteIds) || rejectSensitiveNotes(c, req.NoteIds, req.Override, userID) {
+		return
+	}
+
 	// Start transaction
-	tx := db.DB.Begin()
+	tx := db.DB.WithContext(c.Request.Context()).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -158,7 +226,7 @@ func UpdatePublishedNotes(c *gin.Context) {
 		Where("chapter_id IN (SELECT id FROM chapters WHERE notebook_id = ?)", notebookID).
 		Update("is_public", false).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error resetting notes to private: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error resetting notes to private")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notes"})
 		return
 	}
@@ -166,7 +234,7 @@ func UpdatePublishedNotes(c *gin.Context) {
 	// Reset all chapters in this notebook to private
 	if err := tx.Model(&models.Chapter{}).Where("notebook_id = ?", notebookID).Update("is_public", false).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error resetting chapters to private: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error resetting chapters to private")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chapters"})
 		return
 	}
@@ -175,7 +243,7 @@ func UpdatePublishedNotes(c *gin.Context) {
 	if len(req.NoteIds) == 0 {
 		if err := tx.Model(&notebook).Update("is_public", false).Error; err != nil {
 			tx.Rollback()
-			log.Print("Error unpublishing notebook: ", err)
+			middleware.Logger(c).Error().Err(err).Msg("Error unpublishing notebook")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpublish notebook"})
 			return
 		}
@@ -187,7 +255,7 @@ func UpdatePublishedNotes(c *gin.Context) {
 	// Mark selected notes as public
 	if err := tx.Model(&models.Notes{}).Where("id IN ? AND chapter_id IN (SELECT id FROM chapters WHERE notebook_id = ?)", req.NoteIds, notebookID).Update("is_public", true).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error marking notes as public: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error marking notes as public")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish notes"})
 		return
 	}
@@ -201,7 +269,7 @@ func UpdatePublishedNotes(c *gin.Context) {
 		)
 	`, notebookID).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error marking chapters as public: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error marking chapters as public")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish chapters"})
 		return
 	}
@@ -209,6 +277,9 @@ func UpdatePublishedNotes(c *gin.Context) {
 	// Commit transaction
 	tx.Commit()
 
+	// Push the published notebook to GitHub for integrations that sync on publish
+	services.NewGitHubSyncService().QueueSyncForNotebook(notebookID, userID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Published notes updated successfully"})
 }
 
@@ -225,14 +296,14 @@ func UnpublishNotebook(c *gin.Context) {
 
 	// Verify notebook ownership
 	var notebook models.Notebook
-	if err := db.DB.Where("id = ? AND clerk_user_id = ?", notebookID, userID).First(&notebook).Error; err != nil {
-		log.Print("Notebook not found with id: ", notebookID, " for user: ", userID, " Error: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ? AND clerk_user_id = ?", notebookID, userID).First(&notebook).Error; err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", notebookID).Msg("Notebook not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
 		return
 	}
 
 	// Start transaction
-	tx := db.DB.Begin()
+	tx := db.DB.WithContext(c.Request.Context()).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -242,7 +313,7 @@ func UnpublishNotebook(c *gin.Context) {
 	// Mark notebook as private
 	if err := tx.Model(&notebook).Update("is_public", false).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error unpublishing notebook: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error unpublishing notebook")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpublish notebook"})
 		return
 	}
@@ -250,7 +321,7 @@ func UnpublishNotebook(c *gin.Context) {
 	// Mark all chapters as private
 	if err := tx.Model(&models.Chapter{}).Where("notebook_id = ?", notebookID).Update("is_public", false).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error unpublishing chapters: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error unpublishing chapters")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpublish chapters"})
 		return
 	}
@@ -260,7 +331,7 @@ func UnpublishNotebook(c *gin.Context) {
 		Where("chapter_id IN (SELECT id FROM chapters WHERE notebook_id = ?)", notebookID).
 		Update("is_public", false).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error unpublishing notes: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error unpublishing notes")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpublish notes"})
 		return
 	}
@@ -271,7 +342,8 @@ func UnpublishNotebook(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Notebook unpublished successfully"})
 }
 
-// PublishNote toggles the publish status of an individual note
+// PublishNote toggles the publish status of an individual note. Publishing notes with secrets or
+// personal data needs ?override=true.
 func PublishNote(c *gin.Context) {
 	// Get authenticated user ID
 	userID, exists := middleware.GetUserID(c)
@@ -284,10 +356,10 @@ func PublishNote(c *gin.Context) {
 
 	// Get note and verify ownership through notebook
 	var note models.Notes
-	if err := db.DB.Where("id = ?", noteID).
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", noteID).
 		Preload("Chapter.Notebook", "clerk_user_id = ?", userID).
 		First(&note).Error; err != nil {
-		log.Print("Note not found with id: ", noteID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("note_id", noteID).Msg("Note not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
 		return
 	}
@@ -300,9 +372,12 @@ func PublishNote(c *gin.Context) {
 
 	// Toggle the publish status
 	newStatus := !note.IsPublic
+	if newStatus && (rejectEncryptedNotebook(c, &note.Chapter.Notebook) || rejectLockedNotes(c, []string{note.ID}) || rejectUnapprovedNotes(c, []string{note.ID}) || rejectSensitiveNotes(c, []string{note.ID}, c.Query("override") == "true", userID)) {
+		return
+	}
 
 	// Start transaction
-	tx := db.DB.Begin()
+	tx := db.DB.WithContext(c.Request.Context()).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -312,7 +387,7 @@ func PublishNote(c *gin.Context) {
 	// Update note status
 	if err := tx.Model(&note).Update("is_public", newStatus).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error updating note publish status: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error updating note publish status")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
 		return
 	}
@@ -324,7 +399,7 @@ func PublishNote(c *gin.Context) {
 	chapterStatus := publicNotesCount > 0
 	if err := tx.Model(&models.Chapter{}).Where("id = ?", note.ChapterID).Update("is_public", chapterStatus).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error updating chapter publish status: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error updating chapter publish status")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chapter"})
 		return
 	}
@@ -336,7 +411,7 @@ func PublishNote(c *gin.Context) {
 	notebookStatus := publicChaptersCount > 0
 	if err := tx.Model(&models.Notebook{}).Where("id = ?", note.Chapter.Notebook.ID).Update("is_public", notebookStatus).Error; err != nil {
 		tx.Rollback()
-		log.Print("Error updating notebook publish status: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error updating notebook publish status")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notebook"})
 		return
 	}
@@ -347,6 +422,7 @@ func PublishNote(c *gin.Context) {
 	status := "unpublished"
 	if newStatus {
 		status = "published"
+		services.NewGitHubSyncService().QueueSyncForNotebook(note.Chapter.Notebook.ID, userID)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Note " + status + " successfully", "isPublic": newStatus})
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetReadingListReadRequest marks a reading list item read or unread
+type SetReadingListReadRequest struct {
+	Read bool `json:"read"`
+}
+
+// ConvertReadingListItemRequest names the chapter a reading list item is converted into a note in
+type ConvertReadingListItemRequest struct {
+	ChapterID string `json:"chapterId" binding:"required"`
+}
+
+// ListReadingList returns the user's reading list, optionally only its read or unread items
+// GET /api/reading-list?status=unread
+func ListReadingList(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	items, err := services.NewReadingListService().List(c.Request.Context(), clerkUserID, c.Query("status"))
+	if err != nil {
+		sendReadingListError(c, err, "Failed to fetch reading list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// SaveToReadingList saves a web clip or link to the user's reading list
+// POST /api/reading-list
+func SaveToReadingList(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req services.ReadingListClip
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	item, err := services.NewReadingListService().Save(c.Request.Context(), clerkUserID, req, models.ReadingListSourceClip)
+	if err != nil {
+		sendReadingListError(c, err, "Failed to save to reading list")
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+// GetReadingListItem returns a reading list item with its text and highlights
+// GET /api/reading-list/:id
+func GetReadingListItem(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	item, err := services.NewReadingListService().Get(c.Request.Context(), c.Param("id"), clerkUserID)
+	if err != nil {
+		sendReadingListError(c, err, "Failed to fetch reading list item")
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// SetReadingListItemRead marks a reading list item read or unread
+// PUT /api/reading-list/:id/read
+func SetReadingListItemRead(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SetReadingListReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	item, err := services.NewReadingListService().SetRead(c.Request.Context(), c.Param("id"), clerkUserID, req.Read)
+	if err != nil {
+		sendReadingListError(c, err, "Failed to update reading list item")
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// DeleteReadingListItem removes an item from the user's reading list
+// DELETE /api/reading-list/:id
+func DeleteReadingListItem(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := services.NewReadingListService().Delete(c.Request.Context(), c.Param("id"), clerkUserID); err != nil {
+		sendReadingListError(c, err, "Failed to delete reading list item")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Removed from reading list"})
+}
+
+// AddReadingListHighlight highlights a passage of a reading list item
+// POST /api/reading-list/:id/highlights
+func AddReadingListHighlight(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req services.ReadingListHighlightInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	highlight, err := services.NewReadingListService().AddHighlight(c.Request.Context(), c.Param("id"), clerkUserID, req)
+	if err != nil {
+		sendReadingListError(c, err, "Failed to save highlight")
+		return
+	}
+
+	c.JSON(http.StatusCreated, highlight)
+}
+
+// DeleteReadingListHighlight removes a highlight from a reading list item
+// DELETE /api/reading-list/:id/highlights/:highlightId
+func DeleteReadingListHighlight(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := services.NewReadingListService().DeleteHighlight(c.Request.Context(), c.Param("id"), c.Param("highlightId"), clerkUserID); err != nil {
+		sendReadingListError(c, err, "Failed to delete highlight")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Highlight deleted"})
+}
+
+// ConvertReadingListItem turns a reading list item into a note with its highlights and text
+// POST /api/reading-list/:id/convert
+func ConvertReadingListItem(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req ConvertReadingListItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chapterId is required"})
+		return
+	}
+
+	hasAccess, err := middleware.CheckChapterAccess(c.Request.Context(), db.DB, req.ChapterID, clerkUserID)
+	if err != nil || !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	note, err := services.NewReadingListService().ConvertToNote(c.Request.Context(), c.Param("id"), clerkUserID, req.ChapterID)
+	if err != nil {
+		sendReadingListError(c, err, "Failed to convert reading list item")
+		return
+	}
+
+	syncNoteEmbeds(note.ID, note.Content, clerkUserID)
+	services.NewAutomationService().NoteChanged(note.ID, true)
+	services.NewNoteSummaryService().NoteChanged(note.ID)
+	services.NewNoteLanguageService().NoteChanged(note.ID)
+	services.NewGlossaryService().NoteChanged(note.ID)
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// sendReadingListError maps reading list errors to responses
+func sendReadingListError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidReadingListItem):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReadingListItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChapterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
+	case errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateRetentionPolicyRequest represents the request body for an organization's retention policy
+type UpdateRetentionPolicyRequest struct {
+	ArchiveAfterMonths int `json:"archiveAfterMonths"`
+}
+
+// PlaceLegalHoldRequest represents the request body for putting a notebook under legal hold
+type PlaceLegalHoldRequest struct {
+	Reason string `json:"reason"`
+}
+
+// GetOrgRetentionPolicy returns how long the organization's notes stay active before they're archived
+// GET /organizations/:orgId/retention-policy
+func GetOrgRetentionPolicy(c *gin.Context) {
+	policy, err := services.NewRetentionService().GetPolicy(c.Param("orgId"))
+	if err != nil {
+		sendRetentionError(c, err, "Failed to fetch retention policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateOrgRetentionPolicy sets after how many months untouched notes are archived, 0 to never archive
+// them. The retention job applies it.
+// PUT /organizations/:orgId/retention-policy
+func UpdateOrgRetentionPolicy(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req UpdateRetentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	policy, err := services.NewRetentionService().SavePolicy(c.Param("orgId"), req.ArchiveAfterMonths, clerkUserID)
+	if err != nil {
+		sendRetentionError(c, err, "Failed to save retention policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// ListOrgLegalHolds returns the organization's notebooks under legal hold
+// GET /organizations/:orgId/legal-holds
+func ListOrgLegalHolds(c *gin.Context) {
+	holds, err := services.NewRetentionService().ListLegalHolds(c.Param("orgId"))
+	if err != nil {
+		sendRetentionError(c, err, "Failed to fetch legal holds")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"holds": holds})
+}
+
+// PlaceLegalHold keeps a notebook and its chapters and notes from being deleted or moved out of it
+// PUT /organizations/:orgId/legal-holds/:notebookId
+func PlaceLegalHold(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req PlaceLegalHoldRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	hold, err := services.NewRetentionService().PlaceLegalHold(c.Param("orgId"), c.Param("notebookId"), req.Reason, clerkUserID)
+	if err != nil {
+		sendRetentionError(c, err, "Failed to place legal hold")
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+// ReleaseLegalHold ends a notebook's legal hold
+// DELETE /organizations/:orgId/legal-holds/:notebookId
+func ReleaseLegalHold(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	hold, err := services.NewRetentionService().ReleaseLegalHold(c.Param("orgId"), c.Param("notebookId"), clerkUserID)
+	if err != nil {
+		sendRetentionError(c, err, "Failed to release legal hold")
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+// sendRetentionError maps retention service errors to responses
+func sendRetentionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRetentionPolicy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+	case errors.Is(err, services.ErrNoLegalHold), errors.Is(err, services.ErrLegalHold):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
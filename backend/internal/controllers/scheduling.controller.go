@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAvailability returns the user's busy and free windows across their connected calendars
+// GET /api/availability?from=&to=
+func GetAvailability(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	from, err := parseAvailabilityTime(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 time or a YYYY-MM-DD date"})
+		return
+	}
+	to, err := parseAvailabilityTime(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 time or a YYYY-MM-DD date"})
+		return
+	}
+
+	availability, err := services.NewSchedulingService().GetAvailability(c.Request.Context(), clerkUserID, from, to)
+	if err != nil {
+		sendSchedulingError(c, err, "Failed to fetch availability")
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// ListSchedulingLinks returns the user's scheduling links
+// GET /api/scheduling-links
+func ListSchedulingLinks(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	links, err := services.NewSchedulingService().ListLinks(c.Request.Context(), clerkUserID)
+	if err != nil {
+		sendSchedulingError(c, err, "Failed to fetch scheduling links")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// CreateSchedulingLink creates a public scheduling link people can request meetings through
+// POST /api/scheduling-links
+func CreateSchedulingLink(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req services.SchedulingLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	link, err := services.NewSchedulingService().CreateLink(c.Request.Context(), clerkUserID, req)
+	if err != nil {
+		sendSchedulingError(c, err, "Failed to create scheduling link")
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// UpdateSchedulingLink changes a scheduling link's settings
+// PUT /api/scheduling-links/:linkId
+func UpdateSchedulingLink(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req services.SchedulingLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	link, err := services.NewSchedulingService().UpdateLink(c.Request.Context(), clerkUserID, c.Param("linkId"), req)
+	if err != nil {
+		sendSchedulingError(c, err, "Failed to update scheduling link")
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// DeleteSchedulingLink deletes a scheduling link and its booking requests
+// DELETE /api/scheduling-links/:linkId
+func DeleteSchedulingLink(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	if err := services.NewSchedulingService().DeleteLink(c.Request.Context(), clerkUserID, c.Param("linkId")); err != nil {
+		sendSchedulingError(c, err, "Failed to delete scheduling link")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduling link deleted successfully"})
+}
+
+// ListBookingRequests returns the meetings requested through the user's scheduling links
+// GET /api/booking-requests?status=
+func ListBookingRequests(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	requests, err := services.NewSchedulingService().ListBookingRequests(c.Request.Context(), clerkUserID, c.Query("status"))
+	if err != nil {
+		sendSchedulingError(c, err, "Failed to fetch booking requests")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"requests": requests})
+}
+
+// ConfirmBookingRequest confirms a pending booking request
+// POST /api/booking-requests/:requestId/confirm
+func ConfirmBookingRequest(c *gin.Context) {
+	respondToBookingRequest(c, true)
+}
+
+// DeclineBookingRequest declines a pending booking request, freeing its slot
+// POST /api/booking-requests/:requestId/decline
+func DeclineBookingRequest(c *gin.Context) {
+	respondToBookingRequest(c, false)
+}
+
+func respondToBookingRequest(c *gin.Context, confirm bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	request, err := services.NewSchedulingService().RespondToBookingRequest(c.Request.Context(), clerkUserID, c.Param("requestId"), confirm)
+	if err != nil {
+		sendSchedulingError(c, err, "Failed to answer booking request")
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// GetBookingPage returns a scheduling link's free slots for its public booking page
+// GET /book/:slug
+func GetBookingPage(c *gin.Context) {
+	link, err := services.NewSchedulingService().GetPublicLink(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		sendSchedulingError(c, err, "Failed to fetch scheduling link")
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// RequestBooking requests a meeting in one of a scheduling link's free slots, for the owner to confirm
+// POST /book/:slug
+func RequestBooking(c *gin.Context) {
+	var input services.BookingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	request, err := services.NewSchedulingService().RequestBooking(c.Request.Context(), c.Param("slug"), input)
+	if err != nil {
+		sendSchedulingError(c, err, "Failed to request booking")
+		return
+	}
+
+	// The requester only gets back what they sent, not the owner's details
+	c.JSON(http.StatusCreated, gin.H{
+		"id":        request.ID,
+		"status":    request.Status,
+		"startTime": request.StartTime,
+		"endTime":   request.EndTime,
+	})
+}
+
+// parseAvailabilityTime parses an RFC 3339 time or a date, leaving the time zero when the value is empty
+func parseAvailabilityTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// sendSchedulingError maps scheduling service errors to HTTP responses
+func sendSchedulingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrSchedulingLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scheduling link not found"})
+	case errors.Is(err, services.ErrBookingRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking request not found"})
+	case errors.Is(err, services.ErrInvalidAvailabilityRange),
+		errors.Is(err, services.ErrInvalidSchedulingLink),
+		errors.Is(err, services.ErrInvalidBookingRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSchedulingSlugTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "That link name is already taken"})
+	case errors.Is(err, services.ErrBookingSlotUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": "The requested time is no longer available"})
+	case errors.Is(err, services.ErrBookingRequestAnswered):
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking request was already answered"})
+	case errors.Is(err, services.ErrTooManyBookingRequests):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many pending booking requests, try again later"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
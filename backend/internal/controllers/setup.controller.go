@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"backend/internal/services"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// GetSetupStatus reports whether first-run setup is still needed and, until it's done, what is configured.
+// The route is public, so a server that's set up doesn't describe its configuration.
+// GET /setup/status
+func GetSetupStatus(c *gin.Context) {
+	tokenRequired := os.Getenv("SETUP_TOKEN") != ""
+	status := services.NewSetupService().Status()
+	if status.Completed {
+		c.JSON(http.StatusOK, gin.H{
+			"status":        gin.H{"completed": true},
+			"tokenRequired": tokenRequired,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":        status,
+		"tokenRequired": tokenRequired,
+	})
+}
+
+// RunSetup persists the first-run settings of a self-hosted server. It only works until authentication
+// is configured, and needs the X-Setup-Token header when SETUP_TOKEN is set.
+// POST /setup
+func RunSetup(c *gin.Context) {
+	if token := os.Getenv("SETUP_TOKEN"); token != "" {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Setup-Token")), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid setup token"})
+			return
+		}
+	}
+
+	var req services.SetupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := services.NewSetupService().Setup(req)
+	switch {
+	case errors.Is(err, services.ErrSetupCompleted):
+		c.JSON(http.StatusConflict, gin.H{"error": "Setup has already been completed"})
+		return
+	case errors.Is(err, services.ErrInvalidSetup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Error().Err(err).Msg("Failed to run setup")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSetupStatus_HidesConfigurationOnceCompleted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/setup/status", GetSetupStatus)
+	services.SetAccessLookups(services.AccessLookups{SingleUser: middleware.SingleUserEnabled})
+
+	status := func() map[string]interface{} {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/setup/status", nil))
+		require.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Status map[string]interface{} `json:"status"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		return body.Status
+	}
+
+	for _, key := range []string{config.SetupCompletedKey, "CLERK_SECRET_KEY", "SINGLE_USER"} {
+		t.Setenv(key, "")
+	}
+	pending := status()
+	assert.Equal(t, false, pending["completed"])
+	assert.Contains(t, pending, "apiKeys", "Setup shows what's configured while it's needed")
+
+	t.Setenv(config.SetupCompletedKey, "true")
+	assert.Equal(t, map[string]interface{}{"completed": true}, status())
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ResolveNoteLink finds the note a wiki link points at by its "note" or "chapter/note" slug
+// GET /notebook/:id/resolve?target=
+func ResolveNoteLink(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notebookID := c.Param("id")
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User not authorized to resolve notebook links")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	note, err := services.NewSlugService().ResolveNoteLink(notebookID, c.Query("target"))
+	switch {
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	case errors.Is(err, services.ErrAmbiguousNoteLink):
+		c.JSON(http.StatusConflict, gin.H{"error": "More than one note matches, link to it as chapter/note"})
+		return
+	case err != nil:
+		log.Error().Err(err).Str("notebook_id", notebookID).Msg("Failed to resolve note link")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve note link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":        note.ID,
+		"name":      note.Name,
+		"slug":      note.Slug,
+		"chapterId": note.ChapterID,
+	})
+}
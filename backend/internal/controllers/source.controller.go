@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNoteSources returns the sources a note cites
+// GET /note/:id/sources
+func GetNoteSources(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanView); !ok {
+		return
+	}
+
+	sources, err := services.NewSourceService().List(c.Request.Context(), noteID)
+	if err != nil {
+		sendSourceError(c, err, "Failed to fetch sources")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sources": sources})
+}
+
+// CreateNoteSource cites a work from a note. A source with only a DOI or ISBN is looked up.
+// POST /note/:id/sources
+func CreateNoteSource(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	var req services.SourceInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	source, err := services.NewSourceService().Create(c.Request.Context(), noteID, req, clerkUserID)
+	if err != nil {
+		sendSourceError(c, err, "Failed to save source")
+		return
+	}
+
+	c.JSON(http.StatusCreated, source)
+}
+
+// UpdateNoteSource replaces a source's details
+// PUT /note/:id/sources/:sourceId
+func UpdateNoteSource(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	var req services.SourceInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	source, err := services.NewSourceService().Update(c.Request.Context(), noteID, c.Param("sourceId"), req)
+	if err != nil {
+		sendSourceError(c, err, "Failed to update source")
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// DeleteNoteSource removes a source from a note
+// DELETE /note/:id/sources/:sourceId
+func DeleteNoteSource(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	noteID := c.Param("id")
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	if err := services.NewSourceService().Delete(c.Request.Context(), noteID, c.Param("sourceId")); err != nil {
+		sendSourceError(c, err, "Failed to delete source")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Source deleted"})
+}
+
+// LookupSource returns the details of the work registered under a DOI or an ISBN
+// GET /sources/lookup?doi=...|isbn=...
+func LookupSource(c *gin.Context) {
+	if _, exists := middleware.GetClerkUserID(c); !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	service := services.NewSourceService()
+	var source *services.SourceInput
+	var err error
+	switch {
+	case c.Query("doi") != "":
+		source, err = service.LookupDOI(c.Request.Context(), c.Query("doi"))
+	case c.Query("isbn") != "":
+		source, err = service.LookupISBN(c.Request.Context(), c.Query("isbn"))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A doi or isbn is required"})
+		return
+	}
+	if err != nil {
+		sendSourceError(c, err, "Failed to look up source")
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+// GetNotebookBibliography formats the works cited by a notebook's notes
+// GET /notebook/:id/bibliography?style=apa|mla
+func GetNotebookBibliography(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notebookID := c.Param("id")
+	hasAccess, err := middleware.CheckNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil || !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	bibliography, err := services.NewSourceService().Bibliography(c.Request.Context(), notebookID, c.Query("style"))
+	if err != nil {
+		sendSourceError(c, err, "Failed to create bibliography")
+		return
+	}
+
+	c.JSON(http.StatusOK, bibliography)
+}
+
+// sendSourceError maps source errors to responses
+func sendSourceError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSource):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSourceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+	case errors.Is(err, services.ErrSourceMetadataNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	case errors.Is(err, services.ErrNotebookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+	case errors.Is(err, services.ErrNoteLocked), errors.Is(err, services.ErrNotebookEncrypted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSourceLookupFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": "The DOI or ISBN registry couldn't be reached"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The same handlers serve the default prompts under /admin/system-prompts and an organization's under
+// /organizations/:orgId/system-prompts
+
+// GetSystemPrompt returns the version of a prompt in effect and its versions
+// GET /admin/system-prompts/:name
+// GET /organizations/:orgId/system-prompts/:name
+func GetSystemPrompt(c *gin.Context) {
+	details, err := services.NewSystemPromptService().Get(c.Param("name"), systemPromptOrgID(c))
+	if err != nil {
+		sendSystemPromptError(c, err, "Failed to fetch system prompt")
+		return
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// CreateSystemPromptVersion adds a version of a prompt, optionally putting it in use
+// POST /admin/system-prompts/:name/versions
+// POST /organizations/:orgId/system-prompts/:name/versions
+func CreateSystemPromptVersion(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input services.SystemPromptInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	prompt, err := services.NewSystemPromptService().CreateVersion(c.Param("name"), systemPromptOrgID(c), input, clerkUserID)
+	if err != nil {
+		sendSystemPromptError(c, err, "Failed to save system prompt")
+		return
+	}
+
+	c.JSON(http.StatusCreated, prompt)
+}
+
+// ActivateSystemPromptVersion puts a version of a prompt in use, to roll out or roll back
+// POST /admin/system-prompts/:name/versions/:version/activate
+// POST /organizations/:orgId/system-prompts/:name/versions/:version/activate
+func ActivateSystemPromptVersion(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	prompt, err := services.NewSystemPromptService().ActivateVersion(c.Param("name"), systemPromptOrgID(c), version)
+	if err != nil {
+		sendSystemPromptError(c, err, "Failed to activate system prompt")
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+// ResetOrgSystemPrompt returns the organization to the default prompt, keeping its versions
+// DELETE /organizations/:orgId/system-prompts/:name
+func ResetOrgSystemPrompt(c *gin.Context) {
+	if err := services.NewSystemPromptService().Reset(c.Param("name"), c.Param("orgId")); err != nil {
+		sendSystemPromptError(c, err, "Failed to reset system prompt")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "The organization now uses the default prompt"})
+}
+
+// PreviewSystemPrompt renders a draft, or the version in effect, as a chat would see it
+// POST /admin/system-prompts/:name/preview
+// POST /organizations/:orgId/system-prompts/:name/preview
+func PreviewSystemPrompt(c *gin.Context) {
+	var req services.SystemPromptPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	preview, err := services.NewSystemPromptService().Preview(c.Param("name"), systemPromptOrgID(c), req)
+	if err != nil {
+		sendSystemPromptError(c, err, "Failed to preview system prompt")
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// systemPromptOrgID returns the organization of an organization route, nil for the default prompts
+func systemPromptOrgID(c *gin.Context) *string {
+	if orgID := c.Param("orgId"); orgID != "" {
+		return &orgID
+	}
+	return nil
+}
+
+// sendSystemPromptError maps system prompt service errors to responses
+func sendSystemPromptError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidSystemPrompt):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSystemPromptNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "System prompt not found"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSystemStatus reports the health of the server's external dependencies and the AI providers the
+// user can use, in the active organization when they belong to it, for degraded-mode banners
+// GET /api/system/status
+func GetSystemStatus(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var organizationID *string
+	if orgID, exists := middleware.GetOrganizationID(c); exists && orgID != "" {
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
+		if err != nil || !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+			return
+		}
+		organizationID = &orgID
+	}
+
+	status, err := services.GetSystemStatusService().GetStatus(c.Request.Context(), clerkUserID, organizationID)
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to check system status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check system status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
@@ -5,9 +5,12 @@ import (
 	"backend/internal/middleware"
 	"backend/internal/models"
 	"backend/internal/services"
+	"backend/internal/utils"
 	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
@@ -24,7 +27,8 @@ func CheckTaskAccess(ctx context.Context, db *gorm.DB, taskID, clerkUserID strin
 	return middleware.CheckTaskAccess(ctx, db, taskID, clerkUserID)
 }
 
-// GetTaskBoard retrieves a task board with its tasks
+// GetTaskBoard retrieves a task board with its tasks. ?fields= limits the response to the fields asked
+// for, e.g. "id,name,tasks.title,tasks.status", and skips loading the relations left out.
 func GetTaskBoard(c *gin.Context) {
 	// Get authenticated user ID
 	clerkUserID, exists := middleware.GetClerkUserID(c)
@@ -38,7 +42,7 @@ func GetTaskBoard(c *gin.Context) {
 	// Check authorization for task board
 	hasAccess, err := CheckTaskBoardAccess(c.Request.Context(), db.DB, boardID, clerkUserID)
 	if err != nil {
-		log.Print("Task board not found with id: ", boardID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("board_id", boardID).Msg("Task board not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
 		return
 	}
@@ -48,21 +52,30 @@ func GetTaskBoard(c *gin.Context) {
 		return
 	}
 
-	// Get task board with tasks and note relations
+	// Get task board with tasks and note relations, leaving out the relations ?fields= doesn't ask for
+	fields := utils.ParseFieldSelection(c.Query("fields"))
+	query := db.DB.WithContext(c.Request.Context())
+	for _, relation := range []struct{ field, preload string }{
+		{"tasks", "Tasks"},
+		{"tasks.assignments", "Tasks.Assignments"},
+		{"tasks.externalLink", "Tasks.ExternalLink"},
+		{"note", "Note"},
+		{"note.chapter", "Note.Chapter"},
+		{"note.chapter.notebook", "Note.Chapter.Notebook"},
+	} {
+		if fields.Includes(relation.field) {
+			query = query.Preload(relation.preload)
+		}
+	}
+
 	var taskBoard models.TaskBoard
-	if err := db.DB.
-		Preload("Tasks.Assignments").
-		Preload("Note").
-		Preload("Note.Chapter").
-		Preload("Note.Chapter.Notebook").
-		Where("id = ?", boardID).
-		First(&taskBoard).Error; err != nil {
-		log.Print("Error fetching task board: ", boardID, " Error: ", err)
+	if err := query.Where("id = ?", boardID).First(&taskBoard).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Str("board_id", boardID).Msg("Error fetching task board")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task board"})
 		return
 	}
 
-	c.JSON(http.StatusOK, taskBoard)
+	utils.SendSelectedFields(c, http.StatusOK, taskBoard)
 }
 
 // CreateTaskBoard creates a new task board
@@ -76,7 +89,7 @@ func CreateTaskBoard(c *gin.Context) {
 
 	var taskBoard models.TaskBoard
 	if err := c.ShouldBindJSON(&taskBoard); err != nil {
-		log.Print("Invalid task board data: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid task board data")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -86,21 +99,13 @@ func CreateTaskBoard(c *gin.Context) {
 
 	// If this is a note-associated board, verify note access
 	if taskBoard.NoteID != nil && *taskBoard.NoteID != "" {
-		hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, *taskBoard.NoteID, clerkUserID)
-		if err != nil {
-			log.Print("Note not found with id: ", *taskBoard.NoteID, " Error: ", err)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
-			return
-		}
-		if !hasAccess {
-			log.Warn().Str("note_id", *taskBoard.NoteID).Str("user_id", clerkUserID).Msg("User not authorized to create task board for note")
-			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		if !authorizeNote(c, *taskBoard.NoteID, clerkUserID, middleware.NoteAccessCanEdit) {
 			return
 		}
 
 		// Get organization ID from note
 		var note models.Notes
-		if err := db.DB.Select("organization_id").Where("id = ?", *taskBoard.NoteID).First(&note).Error; err != nil {
+		if err := db.DB.WithContext(c.Request.Context()).Select("organization_id").Where("id = ?", *taskBoard.NoteID).First(&note).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task board"})
 			return
 		}
@@ -115,8 +120,8 @@ func CreateTaskBoard(c *gin.Context) {
 	}
 
 	// Create the task board
-	if err := db.DB.Create(&taskBoard).Error; err != nil {
-		log.Print("Error creating task board: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Create(&taskBoard).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error creating task board")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task board"})
 		return
 	}
@@ -138,7 +143,7 @@ func UpdateTaskBoard(c *gin.Context) {
 	// Check authorization for task board
 	hasAccess, err := CheckTaskBoardAccess(c.Request.Context(), db.DB, boardID, clerkUserID)
 	if err != nil {
-		log.Print("Task board not found with id: ", boardID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("board_id", boardID).Msg("Task board not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
 		return
 	}
@@ -151,14 +156,14 @@ func UpdateTaskBoard(c *gin.Context) {
 	// Bind update data
 	var updateData models.TaskBoard
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		log.Print("Invalid update data for task board: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid update data for task board")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get current task board to preserve protected fields
 	var taskBoard models.TaskBoard
-	if err := db.DB.Where("id = ?", boardID).First(&taskBoard).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", boardID).First(&taskBoard).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task board"})
 		return
 	}
@@ -170,8 +175,8 @@ func UpdateTaskBoard(c *gin.Context) {
 	updateData.IsStandalone = taskBoard.IsStandalone
 
 	// Update the task board
-	if err := db.DB.Model(&taskBoard).Updates(updateData).Error; err != nil {
-		log.Print("Error updating task board: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Model(&taskBoard).Updates(updateData).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error updating task board")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task board"})
 		return
 	}
@@ -193,7 +198,7 @@ func DeleteTaskBoard(c *gin.Context) {
 	// Check authorization for task board
 	hasAccess, err := CheckTaskBoardAccess(c.Request.Context(), db.DB, boardID, clerkUserID)
 	if err != nil {
-		log.Print("Task board not found with id: ", boardID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("board_id", boardID).Msg("Task board not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
 		return
 	}
@@ -204,11 +209,14 @@ func DeleteTaskBoard(c *gin.Context) {
 	}
 
 	// Delete the task board (tasks will be deleted by cascade)
-	if err := db.DB.Delete(&models.TaskBoard{}, "id = ?", boardID).Error; err != nil {
-		log.Print("Error deleting task board: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Delete(&models.TaskBoard{}, "id = ?", boardID).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error deleting task board")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task board"})
 		return
 	}
+	if err := db.DB.WithContext(c.Request.Context()).Delete(&models.TaskBoardShare{}, "task_board_id = ?", boardID).Error; err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("board_id", boardID).Msg("Failed to delete board link")
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task board deleted successfully"})
 }
@@ -245,17 +253,17 @@ func GetUserTaskBoards(c *gin.Context) {
 	// Filter by organization context
 	if orgID != "" {
 		// Verify organization membership before returning org task boards
-		_, isMember, err := middleware.GetOrgMemberRole(c.Request.Context(), orgID, clerkUserID)
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
 		if err != nil || !isMember {
 			log.Warn().Str("org_id", orgID).Str("user_id", clerkUserID).Msg("User not authorized for org task boards")
 			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
 			return
 		}
 		// In organization context, return ALL task boards for the organization
-		query = db.DB.Model(&models.TaskBoard{}).Where("task_boards.organization_id = ?", orgID)
+		query = db.DB.WithContext(c.Request.Context()).Model(&models.TaskBoard{}).Where("task_boards.organization_id = ?", orgID)
 	} else {
 		// In personal context, return only user's personal task boards
-		query = db.DB.Model(&models.TaskBoard{}).Where("clerk_user_id = ? AND task_boards.organization_id IS NULL", clerkUserID)
+		query = db.DB.WithContext(c.Request.Context()).Model(&models.TaskBoard{}).Where("clerk_user_id = ? AND task_boards.organization_id IS NULL", clerkUserID)
 	}
 
 	// Get total count
@@ -277,7 +285,7 @@ func GetUserTaskBoards(c *gin.Context) {
 		Limit(pageSize).
 		Offset(offset).
 		Find(&taskBoards).Error; err != nil {
-		log.Print("Error fetching user task boards: ", err)
+		middleware.Logger(c).Error().Err(err).Msg("Error fetching user task boards")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task boards"})
 		return
 	}
@@ -315,7 +323,7 @@ func CreateTask(c *gin.Context) {
 	// Check authorization for task board
 	hasAccess, err := CheckTaskBoardAccess(c.Request.Context(), db.DB, boardID, clerkUserID)
 	if err != nil {
-		log.Print("Task board not found with id: ", boardID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("board_id", boardID).Msg("Task board not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
 		return
 	}
@@ -327,32 +335,50 @@ func CreateTask(c *gin.Context) {
 
 	var task models.Task
 	if err := c.ShouldBindJSON(&task); err != nil {
-		log.Print("Invalid task data: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid task data")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Set the task board ID
-	task.TaskBoardID = boardID
-
-	// Get organization ID from task board
-	var taskBoard models.TaskBoard
-	if err := db.DB.Select("organization_id").Where("id = ?", boardID).First(&taskBoard).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+	if err := services.ValidateTaskDuration(task.DurationDays); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	task.OrganizationID = taskBoard.OrganizationID
+	task.BlockRef = strings.TrimSpace(task.BlockRef)
+	if task.BlockRef != "" && !validateTaskBlockRef(c, task.BlockRef, clerkUserID) {
+		return
+	}
+	// Tasks are moved into iterations through their endpoint, which checks the iteration is open
+	task.IterationID = nil
 
 	// Create the task
-	if err := db.DB.Create(&task).Error; err != nil {
-		log.Print("Error creating task: ", err)
+	if err := createTaskInBoard(c.Request.Context(), &task, boardID); err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error creating task")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 		return
 	}
 
+	services.NewTaskSyncService().QueueSyncForBoard(boardID)
+
 	c.JSON(http.StatusCreated, task)
 }
 
+// createTaskInBoard creates a task in a board, inheriting the board's organization
+func createTaskInBoard(ctx context.Context, task *models.Task, boardID string) error {
+	// Set the task board ID
+	task.TaskBoardID = boardID
+	// Links to issue trackers are only created by task sync
+	task.ExternalLink = nil
+
+	// Get organization ID from task board
+	var taskBoard models.TaskBoard
+	if err := db.DB.WithContext(ctx).Select("organization_id").Where("id = ?", boardID).First(&taskBoard).Error; err != nil {
+		return err
+	}
+	task.OrganizationID = taskBoard.OrganizationID
+
+	return db.DB.WithContext(ctx).Create(task).Error
+}
+
 // UpdateTask updates an existing task
 func UpdateTask(c *gin.Context) {
 	// Get authenticated user ID
@@ -367,7 +393,7 @@ func UpdateTask(c *gin.Context) {
 	// Check authorization for task
 	hasAccess, err := CheckTaskAccess(c.Request.Context(), db.DB, taskID, clerkUserID)
 	if err != nil {
-		log.Print("Task not found with id: ", taskID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("task_id", taskID).Msg("Task not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
@@ -380,14 +406,18 @@ func UpdateTask(c *gin.Context) {
 	// Bind update data
 	var updateData models.Task
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		log.Print("Invalid update data for task: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid update data for task")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := services.ValidateTaskDuration(updateData.DurationDays); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get current task to preserve protected fields
 	var task models.Task
-	if err := db.DB.Where("id = ?", taskID).First(&task).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", taskID).First(&task).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
 		return
 	}
@@ -395,14 +425,28 @@ func UpdateTask(c *gin.Context) {
 	// Prevent changing protected fields
 	updateData.TaskBoardID = task.TaskBoardID
 	updateData.OrganizationID = task.OrganizationID
+	updateData.CompletedAt = nil
+	updateData.ExternalLink = nil
+	// Block references are changed through their endpoint, which checks the block exists
+	updateData.BlockRef = ""
+	updateData.IterationID = nil
+	oldStatus := task.Status
+	oldTitle := task.Title
 
 	// Update the task
-	if err := db.DB.Model(&task).Updates(updateData).Error; err != nil {
-		log.Print("Error updating task: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Model(&task).Updates(updateData).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error updating task")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
 		return
 	}
 
+	if updateData.Status != "" && updateData.Status != oldStatus {
+		services.NewAutomationService().TaskStatusChanged(task.ID, oldStatus)
+	}
+	if (updateData.Status != "" && updateData.Status != oldStatus) || (updateData.Title != "" && updateData.Title != oldTitle) {
+		services.NewTaskSyncService().QueueSyncForBoard(task.TaskBoardID)
+	}
+
 	c.JSON(http.StatusOK, task)
 }
 
@@ -420,7 +464,7 @@ func DeleteTask(c *gin.Context) {
 	// Check authorization for task
 	hasAccess, err := CheckTaskAccess(c.Request.Context(), db.DB, taskID, clerkUserID)
 	if err != nil {
-		log.Print("Task not found with id: ", taskID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("task_id", taskID).Msg("Task not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
@@ -431,8 +475,8 @@ func DeleteTask(c *gin.Context) {
 	}
 
 	// Delete the task
-	if err := db.DB.Delete(&models.Task{}, "id = ?", taskID).Error; err != nil {
-		log.Print("Error deleting task: ", err)
+	if err := db.DB.WithContext(c.Request.Context()).Delete(&models.Task{}, "id = ?", taskID).Error; err != nil {
+		middleware.Logger(c).Error().Err(err).Msg("Error deleting task")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
 		return
 	}
@@ -454,7 +498,7 @@ func GetTasksForNote(c *gin.Context) {
 	// Check authorization for note
 	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
 	if err != nil {
-		log.Print("Note not found with id: ", noteID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("note_id", noteID).Msg("Note not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
 		return
 	}
@@ -466,7 +510,7 @@ func GetTasksForNote(c *gin.Context) {
 
 	// Get task board for note
 	var taskBoard models.TaskBoard
-	if err := db.DB.Preload("Tasks.Assignments").Where("note_id = ?", noteID).First(&taskBoard).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Preload("Tasks.Assignments").Where("note_id = ?", noteID).First(&taskBoard).Error; err != nil {
 		// No task board exists for this note yet
 		c.JSON(http.StatusOK, gin.H{"taskBoard": nil, "tasks": []models.Task{}})
 		return
@@ -486,29 +530,25 @@ func GenerateTasksFromNote(c *gin.Context) {
 
 	noteID := c.Param("noteId")
 
-	// Check authorization for note
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
-	if err != nil {
-		log.Print("Note not found with id: ", noteID, " Error: ", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+	// Check the user may change the note
+	if !authorizeNote(c, noteID, clerkUserID, middleware.NoteAccessCanEdit) {
 		return
 	}
-	if !hasAccess {
-		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to generate tasks for note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+
+	if rejectEncryptedNote(c, noteID) || rejectLockedNotes(c, []string{noteID}) {
 		return
 	}
 
 	// Check if task board already exists for this note
 	var existingBoard models.TaskBoard
-	if err := db.DB.Where("note_id = ?", noteID).First(&existingBoard).Error; err == nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("note_id = ?", noteID).First(&existingBoard).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Task board already exists for this note", "taskBoardId": existingBoard.ID})
 		return
 	}
 
 	// Get the note content
 	var note models.Notes
-	if err := db.DB.Where("id = ?", noteID).First(&note).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", noteID).First(&note).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch note"})
 		return
 	}
@@ -543,7 +583,7 @@ func GenerateTasksFromNote(c *gin.Context) {
 	}
 
 	// Start database transaction
-	tx := db.DB.Begin()
+	tx := db.DB.WithContext(c.Request.Context()).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -616,7 +656,7 @@ func AssignTaskToUsers(c *gin.Context) {
 	// Check authorization for task
 	hasAccess, err := CheckTaskAccess(c.Request.Context(), db.DB, taskID, clerkUserID)
 	if err != nil {
-		log.Print("Task not found with id: ", taskID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("task_id", taskID).Msg("Task not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
@@ -631,14 +671,14 @@ func AssignTaskToUsers(c *gin.Context) {
 		UserIDs []string `json:"userIds" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&assignmentRequest); err != nil {
-		log.Print("Invalid assignment data: ", err)
+		middleware.Logger(c).Warn().Err(err).Msg("Invalid assignment data")
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get task to verify organization context
 	var task models.Task
-	if err := db.DB.Where("id = ?", taskID).First(&task).Error; err != nil {
+	if err := db.DB.WithContext(c.Request.Context()).Where("id = ?", taskID).First(&task).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign task"})
 		return
 	}
@@ -646,7 +686,7 @@ func AssignTaskToUsers(c *gin.Context) {
 	// Verify all users belong to the same organization (if task has organization)
 	if task.OrganizationID != nil {
 		for _, userID := range assignmentRequest.UserIDs {
-			_, isMember, err := middleware.GetOrgMemberRole(c.Request.Context(), *task.OrganizationID, userID)
+			_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), *task.OrganizationID, userID)
 			if err != nil || !isMember {
 				log.Warn().Str("user_id", userID).Str("org_id", *task.OrganizationID).Msg("User not member of task organization")
 				c.JSON(http.StatusBadRequest, gin.H{"error": "One or more users are not members of the task's organization"})
@@ -655,41 +695,9 @@ func AssignTaskToUsers(c *gin.Context) {
 		}
 	}
 
-	// Start transaction
-	tx := db.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Remove existing assignments for this task
-	if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskAssignment{}).Error; err != nil {
-		tx.Rollback()
-		log.Error().Err(err).Str("task_id", taskID).Msg("Error removing existing task assignments")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task assignments"})
-		return
-	}
-
-	// Create new assignments
-	var assignments []models.TaskAssignment
-	for _, userID := range assignmentRequest.UserIDs {
-		assignment := models.TaskAssignment{
-			TaskID: taskID,
-			UserID: userID,
-		}
-		if err := tx.Create(&assignment).Error; err != nil {
-			tx.Rollback()
-			log.Error().Err(err).Str("task_id", taskID).Str("user_id", userID).Msg("Error creating task assignment")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign task"})
-			return
-		}
-		assignments = append(assignments, assignment)
-	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		log.Error().Err(err).Str("task_id", taskID).Msg("Error committing task assignment transaction")
+	assignments, err := replaceTaskAssignments(c.Request.Context(), taskID, assignmentRequest.UserIDs)
+	if err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("Error updating task assignments")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign task"})
 		return
 	}
@@ -705,6 +713,34 @@ func AssignTaskToUsers(c *gin.Context) {
 	})
 }
 
+// replaceTaskAssignments replaces a task's assignees in a single transaction
+func replaceTaskAssignments(ctx context.Context, taskID string, userIDs []string) ([]models.TaskAssignment, error) {
+	var assignments []models.TaskAssignment
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Remove existing assignments for this task
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskAssignment{}).Error; err != nil {
+			return fmt.Errorf("failed to remove existing assignments: %w", err)
+		}
+
+		// Create new assignments
+		for _, userID := range userIDs {
+			assignment := models.TaskAssignment{
+				TaskID: taskID,
+				UserID: userID,
+			}
+			if err := tx.Create(&assignment).Error; err != nil {
+				return fmt.Errorf("failed to assign user %s: %w", userID, err)
+			}
+			assignments = append(assignments, assignment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
 // UnassignUserFromTask removes a user assignment from a task
 func UnassignUserFromTask(c *gin.Context) {
 	// Get authenticated user ID
@@ -720,7 +756,7 @@ func UnassignUserFromTask(c *gin.Context) {
 	// Check authorization for task
 	hasAccess, err := CheckTaskAccess(c.Request.Context(), db.DB, taskID, clerkUserID)
 	if err != nil {
-		log.Print("Task not found with id: ", taskID, " Error: ", err)
+		middleware.Logger(c).Warn().Err(err).Str("task_id", taskID).Msg("Task not found")
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
@@ -731,7 +767,7 @@ func UnassignUserFromTask(c *gin.Context) {
 	}
 
 	// Remove the assignment
-	result := db.DB.Where("task_id = ? AND user_id = ?", taskID, userIDToUnassign).Delete(&models.TaskAssignment{})
+	result := db.DB.WithContext(c.Request.Context()).Where("task_id = ? AND user_id = ?", taskID, userIDToUnassign).Delete(&models.TaskAssignment{})
 	if result.Error != nil {
 		log.Error().Err(result.Error).Str("task_id", taskID).Str("user_id", userIDToUnassign).Msg("Error removing task assignment")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unassign task"})
@@ -750,3 +786,111 @@ func UnassignUserFromTask(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "User unassigned from task successfully"})
 }
+
+// CreateTaskCommentRequest represents the request body for commenting on a task
+type CreateTaskCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// GetTaskComments lists a task's comments, oldest first
+func GetTaskComments(c *gin.Context) {
+	// Get authenticated user ID
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	taskID := c.Param("taskId")
+
+	// Check authorization for task
+	hasAccess, err := CheckTaskAccess(c.Request.Context(), db.DB, taskID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("task_id", taskID).Str("user_id", clerkUserID).Msg("User not authorized to view task comments")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var comments []models.TaskComment
+	if err := db.DB.WithContext(c.Request.Context()).Where("task_id = ?", taskID).Order("created_at").Find(&comments).Error; err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("Error fetching task comments")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// CreateTaskComment adds a comment to a task. Comments on synced boards are mirrored to the linked issue.
+func CreateTaskComment(c *gin.Context) {
+	// Get authenticated user ID
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	taskID := c.Param("taskId")
+
+	// Check authorization for task
+	hasAccess, err := CheckTaskAccess(c.Request.Context(), db.DB, taskID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("task_id", taskID).Str("user_id", clerkUserID).Msg("User not authorized to comment on task")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CreateTaskCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment body is required"})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.WithContext(c.Request.Context()).Select("id", "task_board_id").Where("id = ?", taskID).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	comment := models.TaskComment{
+		TaskID:      taskID,
+		ClerkUserID: clerkUserID,
+		AuthorName:  taskCommentAuthorName(c.Request.Context(), clerkUserID),
+		Body:        strings.TrimSpace(req.Body),
+		Source:      models.TaskCommentSourceLocal,
+	}
+	if err := db.DB.WithContext(c.Request.Context()).Create(&comment).Error; err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("Error creating task comment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+
+	services.NewTaskSyncService().QueueSyncForBoard(task.TaskBoardID)
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// taskCommentAuthorName returns the user's full name, or an empty string if it can't be looked up
+func taskCommentAuthorName(ctx context.Context, clerkUserID string) string {
+	user, err := middleware.GetUserCached(ctx, clerkUserID)
+	if err != nil || user == nil {
+		return ""
+	}
+
+	var nameParts []string
+	if user.FirstName != nil && *user.FirstName != "" {
+		nameParts = append(nameParts, *user.FirstName)
+	}
+	if user.LastName != nil && *user.LastName != "" {
+		nameParts = append(nameParts, *user.LastName)
+	}
+	return strings.Join(nameParts, " ")
+}
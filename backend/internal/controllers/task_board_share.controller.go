@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// UnlockPublicTaskBoardRequest represents the request body for opening a password protected board
+type UnlockPublicTaskBoardRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// PublishTaskBoard creates a read-only public link to a board, replacing the link it had
+// POST /kanban/:boardId/publish
+func PublishTaskBoard(c *gin.Context) {
+	clerkUserID, boardID, ok := authorizeTaskBoardShare(c)
+	if !ok {
+		return
+	}
+
+	var input services.BoardShareInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	share, err := services.NewTaskBoardShareService().Publish(c.Request.Context(), boardID, clerkUserID, input)
+	if err != nil {
+		sendTaskBoardShareError(c, err, "Failed to publish task board")
+		return
+	}
+
+	c.JSON(http.StatusCreated, share)
+}
+
+// GetTaskBoardShare returns a board's public link
+// GET /kanban/:boardId/publish
+func GetTaskBoardShare(c *gin.Context) {
+	_, boardID, ok := authorizeTaskBoardShare(c)
+	if !ok {
+		return
+	}
+
+	share, err := services.NewTaskBoardShareService().GetShare(c.Request.Context(), boardID)
+	if err != nil {
+		sendTaskBoardShareError(c, err, "Failed to fetch board link")
+		return
+	}
+
+	c.JSON(http.StatusOK, share)
+}
+
+// RevokeTaskBoardShare deletes a board's public link
+// DELETE /kanban/:boardId/publish
+func RevokeTaskBoardShare(c *gin.Context) {
+	_, boardID, ok := authorizeTaskBoardShare(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewTaskBoardShareService().Revoke(c.Request.Context(), boardID); err != nil {
+		sendTaskBoardShareError(c, err, "Failed to revoke board link")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Board link revoked"})
+}
+
+// GetPublicTaskBoard returns the board a public link shares
+// GET /public/boards/:token
+func GetPublicTaskBoard(c *gin.Context) {
+	board, err := services.NewTaskBoardShareService().GetPublicBoard(c.Request.Context(), c.Param("token"), "")
+	if err != nil {
+		sendTaskBoardShareError(c, err, "Failed to fetch shared board")
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// UnlockPublicTaskBoard returns the board a password protected public link shares
+// POST /public/boards/:token
+func UnlockPublicTaskBoard(c *gin.Context) {
+	var req UnlockPublicTaskBoardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password is required"})
+		return
+	}
+
+	board, err := services.NewTaskBoardShareService().GetPublicBoard(c.Request.Context(), c.Param("token"), req.Password)
+	if err != nil {
+		sendTaskBoardShareError(c, err, "Failed to fetch shared board")
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// authorizeTaskBoardShare checks that the user can access the board whose link is managed, writing the
+// error response when they can't
+func authorizeTaskBoardShare(c *gin.Context) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	boardID := c.Param("boardId")
+	hasAccess, err := CheckTaskBoardAccess(c.Request.Context(), db.DB, boardID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
+		return "", "", false
+	}
+	if !hasAccess {
+		log.Warn().Str("board_id", boardID).Str("user_id", clerkUserID).Msg("User not authorized to share task board")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return "", "", false
+	}
+	return clerkUserID, boardID, true
+}
+
+func sendTaskBoardShareError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTaskBoardNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
+	case errors.Is(err, services.ErrBoardShareNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shared board not found"})
+	case errors.Is(err, services.ErrBoardShareExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBoardSharePasswordRequired), errors.Is(err, services.ErrBoardSharePasswordIncorrect):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "passwordRequired": true})
+	case errors.Is(err, services.ErrInvalidBoardShare):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// taskSyncBoard checks the user can access the board and returns its ID.
+// When manage is set, boards of an organization can only be configured by its admins.
+func taskSyncBoard(c *gin.Context, manage bool) (string, string, bool) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return "", "", false
+	}
+
+	boardID := c.Param("boardId")
+	hasAccess, err := CheckTaskBoardAccess(c.Request.Context(), db.DB, boardID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
+		return "", "", false
+	}
+	if !hasAccess {
+		log.Warn().Str("board_id", boardID).Str("user_id", clerkUserID).Msg("User not authorized to access task board sync")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return "", "", false
+	}
+
+	if manage {
+		var board models.TaskBoard
+		if err := db.DB.WithContext(c.Request.Context()).Select("organization_id").Where("id = ?", boardID).First(&board).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
+			return "", "", false
+		}
+		if board.OrganizationID != nil && *board.OrganizationID != "" {
+			role, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), *board.OrganizationID, clerkUserID)
+			if err != nil {
+				log.Error().Err(err).Str("org_id", *board.OrganizationID).Msg("Failed to check org membership")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify organization membership"})
+				return "", "", false
+			}
+			if !isMember || role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Only organization admins can configure task sync"})
+				return "", "", false
+			}
+		}
+	}
+
+	return boardID, clerkUserID, true
+}
+
+// GetTaskSyncIntegration returns the board's Jira or Linear sync settings
+// GET /kanban/:boardId/sync
+func GetTaskSyncIntegration(c *gin.Context) {
+	boardID, _, ok := taskSyncBoard(c, false)
+	if !ok {
+		return
+	}
+
+	integration, err := services.NewTaskSyncService().GetIntegration(boardID)
+	if err != nil {
+		sendTaskSyncError(c, err, "Failed to fetch task sync settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"integration": integration})
+}
+
+// SaveTaskSyncIntegration creates or updates the board's sync settings
+// PUT /kanban/:boardId/sync
+func SaveTaskSyncIntegration(c *gin.Context) {
+	boardID, clerkUserID, ok := taskSyncBoard(c, true)
+	if !ok {
+		return
+	}
+
+	var settings services.TaskSyncSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	syncService := services.NewTaskSyncService()
+	integration, err := syncService.SaveIntegration(c.Request.Context(), boardID, settings, clerkUserID)
+	if err != nil {
+		sendTaskSyncError(c, err, "Failed to save task sync settings")
+		return
+	}
+
+	if integration.Enabled {
+		if err := syncService.QueueSync(integration.ID); err != nil {
+			log.Warn().Err(err).Str("integration_id", integration.ID).Msg("Failed to queue task sync")
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"integration": integration})
+}
+
+// DeleteTaskSyncIntegration stops syncing the board
+// DELETE /kanban/:boardId/sync
+func DeleteTaskSyncIntegration(c *gin.Context) {
+	boardID, _, ok := taskSyncBoard(c, true)
+	if !ok {
+		return
+	}
+
+	if err := services.NewTaskSyncService().DeleteIntegration(boardID); err != nil {
+		sendTaskSyncError(c, err, "Failed to delete task sync settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task sync removed successfully"})
+}
+
+// GetTaskSyncStatus returns when the board last synced, whether it's syncing and how many conflicts there were
+// GET /kanban/:boardId/sync/status
+func GetTaskSyncStatus(c *gin.Context) {
+	boardID, _, ok := taskSyncBoard(c, false)
+	if !ok {
+		return
+	}
+
+	status, err := services.NewTaskSyncService().GetStatus(boardID)
+	if err != nil {
+		sendTaskSyncError(c, err, "Failed to fetch task sync status")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// RunTaskSync syncs the board right away and returns what changed
+// POST /kanban/:boardId/sync/run
+func RunTaskSync(c *gin.Context) {
+	boardID, _, ok := taskSyncBoard(c, false)
+	if !ok {
+		return
+	}
+
+	syncService := services.NewTaskSyncService()
+	integration, err := syncService.GetIntegration(boardID)
+	if err != nil {
+		sendTaskSyncError(c, err, "Failed to sync tasks")
+		return
+	}
+	if integration == nil {
+		sendTaskSyncError(c, services.ErrTaskSyncIntegrationNotFound, "Failed to sync tasks")
+		return
+	}
+
+	result, err := syncService.Sync(c.Request.Context(), integration.ID)
+	if err != nil {
+		sendTaskSyncError(c, err, "Failed to sync tasks")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// sendTaskSyncError maps task sync service errors to responses
+func sendTaskSyncError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTaskSyncIntegration):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTaskSyncIntegrationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task sync is not configured for this board"})
+	case errors.Is(err, services.ErrTaskSyncInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": "A task sync is already in progress"})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
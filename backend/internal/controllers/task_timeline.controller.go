@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"backend/db"
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// AddTaskDependencyRequest represents the request body for making a task wait on another task
+type AddTaskDependencyRequest struct {
+	DependsOnID string `json:"dependsOnId" binding:"required"`
+}
+
+// GetBoardTimeline returns dependency-aware scheduling data for a board's timeline view
+// GET /kanban/:boardId/timeline
+func GetBoardTimeline(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	boardID := c.Param("boardId")
+	hasAccess, err := CheckTaskBoardAccess(c.Request.Context(), db.DB, boardID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task board not found"})
+		return
+	}
+	if !hasAccess {
+		log.Warn().Str("board_id", boardID).Str("user_id", clerkUserID).Msg("User not authorized to access task board")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	timeline, err := services.NewTaskTimelineService().GetTimeline(boardID, time.Now())
+	if err != nil {
+		sendTaskTimelineError(c, err, "Failed to build timeline")
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// AddTaskDependency makes a task wait on another task of its board
+// POST /tasks/:taskId/dependencies
+func AddTaskDependency(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	taskID := c.Param("taskId")
+	if !authorizeTaskDependency(c, taskID, clerkUserID) {
+		return
+	}
+
+	var req AddTaskDependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dependsOnId is required"})
+		return
+	}
+
+	dependency, err := services.NewTaskTimelineService().AddDependency(taskID, req.DependsOnID)
+	if err != nil {
+		sendTaskTimelineError(c, err, "Failed to add task dependency")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dependency)
+}
+
+// RemoveTaskDependency removes a task's dependency on another task
+// DELETE /tasks/:taskId/dependencies/:dependsOnId
+func RemoveTaskDependency(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	taskID := c.Param("taskId")
+	if !authorizeTaskDependency(c, taskID, clerkUserID) {
+		return
+	}
+
+	if err := services.NewTaskTimelineService().RemoveDependency(taskID, c.Param("dependsOnId")); err != nil {
+		sendTaskTimelineError(c, err, "Failed to remove task dependency")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task dependency removed successfully"})
+}
+
+// authorizeTaskDependency checks the user can change the task's dependencies. The other task must be
+// on the same board, which the service checks.
+func authorizeTaskDependency(c *gin.Context, taskID, clerkUserID string) bool {
+	hasAccess, err := CheckTaskAccess(c.Request.Context(), db.DB, taskID, clerkUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return false
+	}
+	if !hasAccess {
+		log.Warn().Str("task_id", taskID).Str("user_id", clerkUserID).Msg("User not authorized to update task dependencies")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return false
+	}
+	return true
+}
+
+// sendTaskTimelineError maps task timeline service errors to responses
+func sendTaskTimelineError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTaskDependency), errors.Is(err, services.ErrTaskDependencyCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTaskDependencyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTaskDependencyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrgRedactionPolicy returns what is redacted from the organization members' meeting transcripts
+// before AI processing
+// GET /organizations/:orgId/redaction-policy
+func GetOrgRedactionPolicy(c *gin.Context) {
+	policy, err := services.NewTranscriptRedactionService().GetPolicy(c.Request.Context(), c.Param("orgId"))
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch redaction policy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch redaction policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateOrgRedactionPolicy sets what is redacted from the organization members' meeting transcripts.
+// It applies to transcripts processed from then on.
+// PUT /organizations/:orgId/redaction-policy
+func UpdateOrgRedactionPolicy(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req services.RedactionPolicySettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	policy, err := services.NewTranscriptRedactionService().SavePolicy(c.Request.Context(), c.Param("orgId"), req, clerkUserID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRedactionPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to save redaction policy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save redaction policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// GetMeetingRedaction returns what was redacted from a meeting's transcript before AI processing
+// GET /meeting/:id/redaction
+func GetMeetingRedaction(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	redaction, err := services.NewTranscriptRedactionService().GetMeetingRedaction(c.Request.Context(), clerkUserID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrMeetingNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to fetch redaction report")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch redaction report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"redacted": redaction != nil, "redaction": redaction})
+}
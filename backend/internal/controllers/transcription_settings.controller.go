@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateTranscriptionSettingsRequest is the request body for choosing an organization's transcription provider
+type UpdateTranscriptionSettingsRequest struct {
+	Provider string `json:"provider" binding:"required"`
+}
+
+// GetOrgTranscriptionSettings returns how the organization members' meetings are transcribed
+// GET /organizations/:orgId/transcription-settings
+func GetOrgTranscriptionSettings(c *gin.Context) {
+	settings, err := services.NewTranscriptionSettingsService().GetSettings(c.Request.Context(), c.Param("orgId"))
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch transcription settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transcription settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateOrgTranscriptionSettings chooses between Recall.ai and the self-hosted Whisper server for the
+// organization members' meetings. It applies to meetings recorded from then on.
+// PUT /organizations/:orgId/transcription-settings
+func UpdateOrgTranscriptionSettings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req UpdateTranscriptionSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := services.NewTranscriptionSettingsService().SaveSettings(c.Request.Context(), c.Param("orgId"), req.Provider, clerkUserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTranscriptionProvider), errors.Is(err, services.ErrWhisperNotConfigured):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			middleware.ReportError(c, err, "Failed to save transcription settings")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save transcription settings"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTimezoneSettings returns the timezone the user's calendar, meeting and schedule dates are shown in
+// GET /settings/timezone
+func GetTimezoneSettings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	settings, err := services.NewUserPreferencesService().GetTimezone(c.Request.Context(), clerkUserID)
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch timezone")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timezone"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateTimezoneSettings sets the user's timezone, an empty one goes back to the server's
+// PUT /settings/timezone
+func UpdateTimezoneSettings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	settings, err := services.NewUserPreferencesService().SetTimezone(c.Request.Context(), clerkUserID, req.Timezone)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTimezone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Timezone must be an IANA name like Europe/Berlin"})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to save timezone")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save timezone"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetMeetingFilingSettings returns whether the user's generated meeting notes are filed into project
+// chapters automatically, suggested for review or left where they're generated
+// GET /settings/meeting-filing
+func GetMeetingFilingSettings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	mode, err := services.NewUserPreferencesService().GetMeetingFiling(c.Request.Context(), clerkUserID)
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch meeting filing setting")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meeting filing setting"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mode": mode})
+}
+
+// UpdateMeetingFilingSettings sets how the user's generated meeting notes are filed: "suggest", "auto"
+// or "off"
+// PUT /settings/meeting-filing
+func UpdateMeetingFilingSettings(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req struct {
+		Mode string `json:"mode"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	mode, err := services.NewUserPreferencesService().SetMeetingFiling(c.Request.Context(), clerkUserID, req.Mode)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMeetingFiling) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mode must be suggest, auto or off"})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to save meeting filing setting")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save meeting filing setting"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mode": mode})
+}
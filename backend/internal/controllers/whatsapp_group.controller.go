@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListWhatsAppGroups returns the WhatsApp groups linked to the organization with their message volume.
+// ?days= sets how far back recent messages are counted, 30 by default.
+// GET /organizations/:orgId/whatsapp-groups
+func ListWhatsAppGroups(c *gin.Context) {
+	days := services.DefaultWhatsAppGroupVolumeDays
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a number"})
+			return
+		}
+		days = parsed
+	}
+
+	groups, err := services.NewWhatsAppGroupService().ListGroups(c.Request.Context(), c.Param("orgId"), days)
+	if err != nil {
+		sendWhatsAppGroupError(c, err, "Failed to fetch WhatsApp groups")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"groups": groups, "days": days})
+}
+
+// LinkWhatsAppGroup links a WhatsApp group to the organization, optionally with the notebook notes
+// captured in it are saved to
+// POST /organizations/:orgId/whatsapp-groups
+func LinkWhatsAppGroup(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	var req services.WhatsAppGroupInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	group, err := services.NewWhatsAppGroupService().LinkGroup(c.Request.Context(), c.Param("orgId"), req, clerkUserID)
+	if err != nil {
+		sendWhatsAppGroupError(c, err, "Failed to link WhatsApp group")
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// UpdateWhatsAppGroup sets the notebook notes captured in a linked group are saved to
+// PUT /organizations/:orgId/whatsapp-groups/:groupId
+func UpdateWhatsAppGroup(c *gin.Context) {
+	var req services.WhatsAppGroupInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	group, err := services.NewWhatsAppGroupService().UpdateGroup(c.Request.Context(), c.Param("orgId"), c.Param("groupId"), req)
+	if err != nil {
+		sendWhatsAppGroupError(c, err, "Failed to update WhatsApp group")
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// UnlinkWhatsAppGroup unlinks a WhatsApp group from the organization
+// DELETE /organizations/:orgId/whatsapp-groups/:groupId
+func UnlinkWhatsAppGroup(c *gin.Context) {
+	if err := services.NewWhatsAppGroupService().UnlinkGroup(c.Request.Context(), c.Param("orgId"), c.Param("groupId")); err != nil {
+		sendWhatsAppGroupError(c, err, "Failed to unlink WhatsApp group")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "WhatsApp group unlinked"})
+}
+
+// sendWhatsAppGroupError maps WhatsApp group service errors to responses
+func sendWhatsAppGroupError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWhatsAppGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWhatsAppGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "WhatsApp group not found"})
+	case errors.Is(err, services.ErrWhatsAppGroupLinked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		middleware.ReportError(c, err, message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWorkspacesOverview returns the user's personal workspace and every organization they belong to,
+// each with its counts and most recently updated notes
+// GET /api/workspaces/overview
+func GetWorkspacesOverview(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	workspaces, err := services.NewWorkspaceService().Overview(c.Request.Context(), clerkUserID)
+	if err != nil {
+		middleware.ReportError(c, err, "Failed to fetch workspaces overview")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workspaces overview"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspaces": workspaces})
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"backend/internal/middleware"
+	"backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserStats returns the user's daily words written, notes created and edits over ?range=7d|30d|90d|365d,
+// with their writing streaks and milestones. Pass organizationId for their writing in an organization.
+// GET /user/stats
+func GetUserStats(c *gin.Context) {
+	clerkUserID, exists := middleware.GetClerkUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	scope := services.WritingStatsScope{ClerkUserID: clerkUserID}
+	if orgID := c.Query("organizationId"); orgID != "" {
+		_, isMember, err := middleware.GetOrgMemberRoleCached(c.Request.Context(), orgID, clerkUserID)
+		if err != nil || !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+			return
+		}
+		scope.OrganizationID = &orgID
+	}
+
+	stats, err := services.NewWritingStatsService().GetStats(c.Request.Context(), scope, c.Query("range"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWritingStatsRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		middleware.ReportError(c, err, "Failed to fetch writing stats")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch writing stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"backend/db"
 	"backend/internal/middleware"
+	"backend/internal/models"
 	"backend/internal/services"
 	"io"
 	"net/http"
@@ -24,16 +25,8 @@ func GetYjsState(c *gin.Context) {
 	noteID := c.Param("id")
 	log.Debug().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("GetYjsState: Checking access")
 
-	// Check authorization
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
-	if err != nil {
-		log.Error().Err(err).Str("note_id", noteID).Str("user_id", clerkUserID).Msg("GetYjsState: Error checking note access")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
-		return
-	}
-	if !hasAccess {
-		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("GetYjsState: User not authorized to access note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+	// Check authorization, anyone who can read the note can fetch its state
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanView); !ok {
 		return
 	}
 
@@ -75,10 +68,11 @@ func InitializeYjsDocument(c *gin.Context) {
 	noteID := c.Param("id")
 
 	// Check authorization
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
-	if err != nil || !hasAccess {
-		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to access note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
+		return
+	}
+
+	if rejectEncryptedNote(c, noteID) || rejectLockedNotes(c, []string{noteID}) {
 		return
 	}
 
@@ -125,21 +119,18 @@ func ApplyYjsUpdate(c *gin.Context) {
 	noteID := c.Param("id")
 	log.Debug().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("ApplyYjsUpdate: Checking access")
 
-	// Check authorization
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
-	if err != nil {
-		log.Error().Err(err).Str("note_id", noteID).Str("user_id", clerkUserID).Msg("ApplyYjsUpdate: Error checking note access")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
-		return
-	}
-	if !hasAccess {
-		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("ApplyYjsUpdate: User not authorized to access note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+	// Check authorization. Updates carry the whole document, so commenters save their comments through
+	// yjs-sync where they can be told apart from edits.
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanEdit); !ok {
 		return
 	}
 
 	log.Debug().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("ApplyYjsUpdate: Access granted")
 
+	if rejectEncryptedNote(c, noteID) || rejectLockedNotes(c, []string{noteID}) {
+		return
+	}
+
 	// Expect two fields: update (binary) and state (binary)
 	// We'll use multipart form or expect JSON with base64
 	// For simplicity, let's use query params to differentiate
@@ -184,11 +175,13 @@ func SyncYjsToNote(c *gin.Context) {
 
 	noteID := c.Param("id")
 
-	// Check authorization
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
-	if err != nil || !hasAccess {
-		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to access note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+	// Check authorization, commenters may sync content that only adds or removes comments
+	access, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanComment)
+	if !ok {
+		return
+	}
+
+	if rejectEncryptedNote(c, noteID) || rejectLockedNotes(c, []string{noteID}) {
 		return
 	}
 
@@ -202,6 +195,46 @@ func SyncYjsToNote(c *gin.Context) {
 		return
 	}
 
+	// Notes in review mode sync to their pending revision, which the Yjs document holds when there is one
+	revisionService := services.NewNoteRevisionService()
+	requiresRevision, err := revisionService.RequiresRevision(noteID)
+	if err != nil {
+		log.Error().Err(err).Str("note_id", noteID).Msg("Failed to check note revision mode")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync content"})
+		return
+	}
+
+	// Keep block IDs stable, the Yjs document doesn't carry them
+	var current models.Notes
+	if err := db.DB.WithContext(c.Request.Context()).Select("content", "organization_id").Where("id = ?", noteID).First(&current).Error; err == nil {
+		if requiresRevision {
+			if pending, err := revisionService.PendingRevision(noteID); err == nil && pending != nil && pending.Content != "" {
+				current.Content = pending.Content
+			}
+		}
+		requestData.Content = services.AssignTipTapBlockIDs(current.Content, requestData.Content)
+	}
+	if access < middleware.NoteAccessCanEdit && !services.OnlyCommentsChanged(current.Content, requestData.Content) {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("SyncYjsToNote: Commenter tried to edit note")
+		sendNoteAccessDenied(c, access)
+		return
+	}
+
+	if !validateNoteEmbeds(c, noteID, requestData.Content) {
+		return
+	}
+
+	if requiresRevision {
+		if _, err := revisionService.SaveRevision(noteID, clerkUserID, "", requestData.Content); err != nil {
+			sendNoteRevisionError(c, err, "Failed to sync content")
+			return
+		}
+		go services.NewContentAnalyticsService().RecordNoteAccess(noteID, clerkUserID, models.NoteAccessEdit)
+		go services.NewWritingStatsService().RecordWriting(clerkUserID, current.OrganizationID, current.Content, requestData.Content, false)
+		c.JSON(http.StatusOK, gin.H{"message": "Content synced to pending revision", "pendingRevision": true})
+		return
+	}
+
 	// Sync to note
 	yjsService := services.NewYjsService(db.DB)
 	err = yjsService.SyncYjsToNoteContent(noteID, requestData.Content)
@@ -211,6 +244,15 @@ func SyncYjsToNote(c *gin.Context) {
 		return
 	}
 
+	syncNoteEmbeds(noteID, requestData.Content, clerkUserID)
+	syncNoteWikiLinks(noteID, clerkUserID)
+	services.NewAutomationService().NoteChanged(noteID, false)
+	services.NewNoteSummaryService().NoteChanged(noteID)
+	services.NewNoteLanguageService().NoteChanged(noteID)
+	services.NewGlossaryService().NoteChanged(noteID)
+	go services.NewContentAnalyticsService().RecordNoteAccess(noteID, clerkUserID, models.NoteAccessEdit)
+	go services.NewWritingStatsService().RecordWriting(clerkUserID, current.OrganizationID, current.Content, requestData.Content, false)
+
 	log.Debug().Str("note_id", noteID).Msg("Content synced to note")
 	c.JSON(http.StatusOK, gin.H{"message": "Content synced"})
 }
@@ -228,10 +270,7 @@ func GetDocumentVersion(c *gin.Context) {
 	noteID := c.Param("id")
 
 	// Check authorization
-	hasAccess, err := middleware.CheckNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
-	if err != nil || !hasAccess {
-		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to access note")
-		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+	if _, ok := requireNoteAccess(c, noteID, clerkUserID, middleware.NoteAccessCanView); !ok {
 		return
 	}
 
@@ -246,3 +285,137 @@ func GetDocumentVersion(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"version": version})
 }
+
+// requireNoteAccess checks that the user may do at least what's required with a note, responding with
+// why not otherwise. It returns what the user may do.
+func requireNoteAccess(c *gin.Context, noteID, clerkUserID string, required middleware.NoteAccess) (middleware.NoteAccess, bool) {
+	access, err := middleware.GetNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil || access == middleware.NoteAccessNone {
+		log.Warn().Err(err).Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to access note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return access, false
+	}
+	if access < required {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Str("access", access.String()).Msg("User may not change note")
+		sendNoteAccessDenied(c, access)
+		return access, false
+	}
+	return access, true
+}
+
+// sendNoteAccessDenied tells a member who can read a note what they may do with it instead of editing
+func sendNoteAccessDenied(c *gin.Context, access middleware.NoteAccess) {
+	if access == middleware.NoteAccessCanComment {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  "You can comment on this note but not edit it",
+			"code":   "comment_only",
+			"access": access.String(),
+		})
+		return
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":  "You can view this note but not edit it",
+		"code":   "read_only",
+		"access": access.String(),
+	})
+}
+
+// authorizeNote checks the user may do what's required with a note, answering 404 for notes that don't
+// exist
+func authorizeNote(c *gin.Context, noteID, clerkUserID string, required middleware.NoteAccess) bool {
+	access, err := middleware.GetNoteAccess(c.Request.Context(), db.DB, noteID, clerkUserID)
+	if err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("note_id", noteID).Msg("Note not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return false
+	}
+	if access == middleware.NoteAccessNone {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("User not authorized to access note")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return false
+	}
+	if access < required {
+		log.Warn().Str("note_id", noteID).Str("user_id", clerkUserID).Str("access", access.String()).Msg("User may not change note")
+		sendNoteAccessDenied(c, access)
+		return false
+	}
+	return true
+}
+
+// authorizeChapter checks the user may do what's required with the notes of a chapter, like adding notes
+// to it, answering 404 for chapters that don't exist
+func authorizeChapter(c *gin.Context, chapterID, clerkUserID string, required middleware.NoteAccess) bool {
+	access, err := middleware.GetChapterAccess(c.Request.Context(), db.DB, chapterID, clerkUserID)
+	if err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("chapter_id", chapterID).Msg("Chapter not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chapter not found"})
+		return false
+	}
+	if access == middleware.NoteAccessNone {
+		log.Warn().Str("chapter_id", chapterID).Str("user_id", clerkUserID).Msg("User not authorized to access chapter")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return false
+	}
+	if access < required {
+		log.Warn().Str("chapter_id", chapterID).Str("user_id", clerkUserID).Str("access", access.String()).Msg("User may not change chapter")
+		sendContentAccessDenied(c, access, "chapter")
+		return false
+	}
+	return true
+}
+
+// authorizeNotebook checks the user may do what's required with the chapters and notes of a notebook,
+// answering 404 for notebooks that don't exist
+func authorizeNotebook(c *gin.Context, notebookID, clerkUserID string, required middleware.NoteAccess) bool {
+	access, err := middleware.GetNotebookAccess(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", notebookID).Msg("Notebook not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return false
+	}
+	if access == middleware.NoteAccessNone {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User not authorized to access notebook")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return false
+	}
+	if access < required {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Str("access", access.String()).Msg("User may not change notebook")
+		sendContentAccessDenied(c, access, "notebook")
+		return false
+	}
+	return true
+}
+
+// authorizeNotebookManager checks the user may make decisions for everyone in a notebook, like encrypting
+// it: its owner or an admin of its organization
+func authorizeNotebookManager(c *gin.Context, notebookID, clerkUserID string) bool {
+	canManage, err := middleware.CanManageNotebook(c.Request.Context(), db.DB, notebookID, clerkUserID)
+	if err != nil {
+		middleware.Logger(c).Warn().Err(err).Str("notebook_id", notebookID).Msg("Notebook not found")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return false
+	}
+	if !canManage {
+		log.Warn().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("User may not manage notebook")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the notebook's owner or an organization admin can do this"})
+		return false
+	}
+	return true
+}
+
+// sendContentAccessDenied tells a member who can read a notebook or chapter that they may not change it
+func sendContentAccessDenied(c *gin.Context, access middleware.NoteAccess, kind string) {
+	if access == middleware.NoteAccessCanComment {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  "You can comment on the notes of this " + kind + " but not change it",
+			"code":   "comment_only",
+			"access": access.String(),
+		})
+		return
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":  "You can view this " + kind + " but not change it",
+		"code":   "read_only",
+		"access": access.String(),
+	})
+}
@@ -0,0 +1,125 @@
+// Package errorreport sends panics and unexpected errors to Sentry, or a Sentry-compatible server like
+// GlitchTip, when one is configured. Everything here is a no-op otherwise.
+package errorreport
+
+import (
+	"backend/internal/config"
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+var enabled atomic.Bool
+
+// sensitiveHeaderWords mark request headers that carry credentials, beyond the ones Sentry drops itself
+var sensitiveHeaderWords = []string{"key", "token", "secret", "signature", "auth", "cookie"}
+
+// Init starts reporting to the configured server. Reporting stays off without a DSN.
+func Init(cfg *config.ErrorReportingConfig) error {
+	if !cfg.IsConfigured() {
+		return nil
+	}
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              cfg.DSN,
+		Environment:      cfg.Environment,
+		Release:          cfg.Release,
+		SampleRate:       cfg.SampleRate,
+		EnableTracing:    cfg.TracesSampleRate > 0,
+		TracesSampleRate: cfg.TracesSampleRate,
+		AttachStacktrace: true,
+		BeforeSend:       scrubEvent,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize Sentry: %w", err)
+	}
+	enabled.Store(true)
+	return nil
+}
+
+// Enabled reports whether errors are being reported
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Flush waits for reported events to be sent, up to the timeout
+func Flush(timeout time.Duration) {
+	if Enabled() {
+		sentry.Flush(timeout)
+	}
+}
+
+// NewContext gives a context a hub of its own, so events reported with the context carry what's set on
+// its scope, like the request it belongs to
+func NewContext(ctx context.Context) (context.Context, *sentry.Hub) {
+	hub := sentry.CurrentHub().Clone()
+	return sentry.SetHubOnContext(ctx, hub), hub
+}
+
+// Capture reports an error, with the scope of the request or job the context belongs to
+func Capture(ctx context.Context, err error, tags map[string]string) {
+	if !Enabled() || err == nil {
+		return
+	}
+	hub := hubFromContext(ctx)
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(tags)
+		hub.CaptureException(err)
+	})
+}
+
+// CaptureMessage reports something that went wrong without an error to go with it
+func CaptureMessage(ctx context.Context, message string, tags map[string]string) {
+	if !Enabled() {
+		return
+	}
+	hub := hubFromContext(ctx)
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(tags)
+		hub.CaptureMessage(message)
+	})
+}
+
+// CapturePanic reports a value recovered from a panic, with the stack it was recovered on
+func CapturePanic(ctx context.Context, recovered interface{}, tags map[string]string) {
+	if !Enabled() || recovered == nil {
+		return
+	}
+	hub := hubFromContext(ctx)
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetTags(tags)
+		scope.SetLevel(sentry.LevelFatal)
+		hub.RecoverWithContext(ctx, recovered)
+	})
+}
+
+func hubFromContext(ctx context.Context) *sentry.Hub {
+	if ctx != nil {
+		if hub := sentry.GetHubFromContext(ctx); hub != nil {
+			return hub
+		}
+	}
+	return sentry.CurrentHub()
+}
+
+// scrubEvent drops request headers and cookies that carry credentials, like API keys and webhook
+// signatures
+func scrubEvent(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+	if event.Request == nil {
+		return event
+	}
+	event.Request.Cookies = ""
+	for name := range event.Request.Headers {
+		lower := strings.ToLower(name)
+		for _, word := range sensitiveHeaderWords {
+			if strings.Contains(lower, word) {
+				delete(event.Request.Headers, name)
+				break
+			}
+		}
+	}
+	return event
+}
@@ -43,6 +43,7 @@ func RequireAuth() gin.HandlerFunc {
 
 		// Store Clerk user ID in context (no database user lookup needed)
 		c.Set("clerk_user_id", clerkUserID)
+		c.Set("clerk_session_id", claims.SessionID)
 
 		c.Next()
 	}
@@ -117,6 +118,17 @@ func GetClerkUserID(c *gin.Context) (string, bool) {
 	return userIDStr, ok
 }
 
+// GetClerkSessionID retrieves the Clerk session ID from the context
+func GetClerkSessionID(c *gin.Context) (string, bool) {
+	sessionID, exists := c.Get("clerk_session_id")
+	if !exists {
+		return "", false
+	}
+
+	sessionIDStr, ok := sessionID.(string)
+	return sessionIDStr, ok && sessionIDStr != ""
+}
+
 // GetUserID is deprecated - use GetClerkUserID instead
 // Kept for backwards compatibility but will be removed
 func GetUserID(c *gin.Context) (string, bool) {
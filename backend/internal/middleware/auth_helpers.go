@@ -9,14 +9,21 @@ import (
 	"gorm.io/gorm"
 )
 
-// CheckNotebookAccess verifies user has access to notebook without preloading full relationships
+// CheckNotebookAccess verifies user has access to notebook without preloading full relationships. The result is
+// cached for the rest of the request.
 func CheckNotebookAccess(ctx context.Context, db *gorm.DB, notebookID, clerkUserID string) (bool, error) {
+	return cachedAccess(ctx, "notebook:"+notebookID, clerkUserID, func() (bool, error) {
+		return checkNotebookAccess(ctx, db, notebookID, clerkUserID)
+	})
+}
+
+func checkNotebookAccess(ctx context.Context, db *gorm.DB, notebookID, clerkUserID string) (bool, error) {
 	var result struct {
 		ClerkUserID    string
 		OrganizationID *string
 	}
 
-	err := db.Model(&models.Notebook{}).
+	err := db.WithContext(ctx).Model(&models.Notebook{}).
 		Select("clerk_user_id, organization_id").
 		Where("id = ?", notebookID).
 		First(&result).Error
@@ -79,7 +86,7 @@ func CheckNotebookAccessWithGin(c *gin.Context, db *gorm.DB, notebookID, clerkUs
 		OrganizationID *string
 	}
 
-	err := db.Model(&models.Notebook{}).
+	err := db.WithContext(c.Request.Context()).Model(&models.Notebook{}).
 		Select("clerk_user_id, organization_id").
 		Where("id = ?", notebookID).
 		First(&result).Error
@@ -98,14 +105,21 @@ func CheckNotebookAccessWithGin(c *gin.Context, db *gorm.DB, notebookID, clerkUs
 	return isMember, err
 }
 
-// CheckChapterAccess verifies user has access to chapter without preloading full relationships
+// CheckChapterAccess verifies user has access to chapter without preloading full relationships. The result is
+// cached for the rest of the request.
 func CheckChapterAccess(ctx context.Context, db *gorm.DB, chapterID, clerkUserID string) (bool, error) {
+	return cachedAccess(ctx, "chapter:"+chapterID, clerkUserID, func() (bool, error) {
+		return checkChapterAccess(ctx, db, chapterID, clerkUserID)
+	})
+}
+
+func checkChapterAccess(ctx context.Context, db *gorm.DB, chapterID, clerkUserID string) (bool, error) {
 	var result struct {
 		NotebookID     string
 		OrganizationID *string
 	}
 
-	err := db.Model(&models.Chapter{}).
+	err := db.WithContext(ctx).Model(&models.Chapter{}).
 		Select("chapters.notebook_id, chapters.organization_id").
 		Where("chapters.id = ?", chapterID).
 		First(&result).Error
@@ -143,7 +157,7 @@ func CheckChapterAccessWithGin(c *gin.Context, db *gorm.DB, chapterID, clerkUser
 		NotebookID string
 	}
 
-	err := db.Model(&models.Chapter{}).
+	err := db.WithContext(c.Request.Context()).Model(&models.Chapter{}).
 		Select("notebook_id").
 		Where("id = ?", chapterID).
 		First(&result).Error
@@ -155,14 +169,21 @@ func CheckChapterAccessWithGin(c *gin.Context, db *gorm.DB, chapterID, clerkUser
 	return CheckNotebookAccessWithGin(c, db, result.NotebookID, clerkUserID)
 }
 
-// CheckNoteAccess verifies user has access to note without preloading full relationships
+// CheckNoteAccess verifies user has access to note without preloading full relationships. The result is
+// cached for the rest of the request.
 func CheckNoteAccess(ctx context.Context, db *gorm.DB, noteID, clerkUserID string) (bool, error) {
+	return cachedAccess(ctx, "note:"+noteID, clerkUserID, func() (bool, error) {
+		return checkNoteAccess(ctx, db, noteID, clerkUserID)
+	})
+}
+
+func checkNoteAccess(ctx context.Context, db *gorm.DB, noteID, clerkUserID string) (bool, error) {
 	var result struct {
 		ChapterID      string
 		OrganizationID *string
 	}
 
-	err := db.Model(&models.Notes{}).
+	err := db.WithContext(ctx).Model(&models.Notes{}).
 		Select("notes.chapter_id, notes.organization_id").
 		Where("notes.id = ?", noteID).
 		First(&result).Error
@@ -202,7 +223,7 @@ func CheckNoteAccessWithGin(c *gin.Context, db *gorm.DB, noteID, clerkUserID str
 		ChapterID string
 	}
 
-	err := db.Model(&models.Notes{}).
+	err := db.WithContext(c.Request.Context()).Model(&models.Notes{}).
 		Select("chapter_id").
 		Where("id = ?", noteID).
 		First(&result).Error
@@ -223,7 +244,7 @@ func CheckTaskBoardAccess(ctx context.Context, db *gorm.DB, taskBoardID, clerkUs
 		IsStandalone   bool
 	}
 
-	err := db.Model(&models.TaskBoard{}).
+	err := db.WithContext(ctx).Model(&models.TaskBoard{}).
 		Select("clerk_user_id, organization_id, note_id, is_standalone").
 		Where("id = ?", taskBoardID).
 		First(&result).Error
@@ -292,7 +313,7 @@ func CheckTaskAccess(ctx context.Context, db *gorm.DB, taskID, clerkUserID strin
 		TaskBoardID string
 	}
 
-	err := db.Model(&models.Task{}).
+	err := db.WithContext(ctx).Model(&models.Task{}).
 		Select("task_board_id").
 		Where("id = ?", taskID).
 		First(&result).Error
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
@@ -86,8 +87,22 @@ func (c *OrgMembershipCache) InvalidateOrg(orgID string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	prefix := orgID + ":"
 	for key := range c.cache {
-		if len(key) > len(orgID) && key[:len(orgID)] == orgID {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.cache, key)
+		}
+	}
+}
+
+// InvalidateUser removes all cache entries for a user
+func (c *OrgMembershipCache) InvalidateUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	suffix := ":" + userID
+	for key := range c.cache {
+		if strings.HasSuffix(key, suffix) {
 			delete(c.cache, key)
 		}
 	}
@@ -110,24 +125,39 @@ func (c *OrgMembershipCache) cleanup() {
 	}
 }
 
-// GetOrgMemberRoleCached is a cached version of GetOrgMemberRole
+// GetOrgMemberRoleCached is a cached version of GetOrgMemberRole. It checks the request's cache, then
+// the memory cache shared across requests, then the Clerk API. Clerk membership webhooks invalidate the
+// memory cache so role changes and removals apply before the TTL runs out.
 func GetOrgMemberRoleCached(ctx context.Context, orgID, userID string) (string, bool, error) {
+	key := orgID + ":" + userID
+	reqCache := requestCacheFromContext(ctx)
+	if reqCache != nil {
+		if data, exists := reqCache.getMembership(key); exists {
+			return data.role, data.isMember, nil
+		}
+	}
+
 	cache := GetOrgCache()
 
 	// Try cache first
-	if role, isMember, found := cache.Get(orgID, userID); found {
+	role, isMember, found := cache.Get(orgID, userID)
+	if found {
 		log.Debug().Str("org_id", orgID).Str("user_id", userID).Msg("Org membership cache hit")
-		return role, isMember, nil
-	}
+	} else {
+		// Cache miss - fetch from Clerk
+		log.Debug().Str("org_id", orgID).Str("user_id", userID).Msg("Org membership cache miss")
+		var err error
+		role, isMember, err = GetOrgMemberRole(ctx, orgID, userID)
+		if err != nil {
+			return "", false, err
+		}
 
-	// Cache miss - fetch from Clerk
-	log.Debug().Str("org_id", orgID).Str("user_id", userID).Msg("Org membership cache miss")
-	role, isMember, err := GetOrgMemberRole(ctx, orgID, userID)
-	if err != nil {
-		return "", false, err
+		// Store in cache
+		cache.Set(orgID, userID, role, isMember)
 	}
 
-	// Store in cache
-	cache.Set(orgID, userID, role, isMember)
+	if reqCache != nil {
+		reqCache.setMembership(key, orgMembershipData{role: role, isMember: isMember})
+	}
 	return role, isMember, nil
 }
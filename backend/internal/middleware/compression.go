@@ -0,0 +1,258 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// minCompressSize is the response size below which compressing costs more than it saves
+	minCompressSize = 1024
+	// noCompressionKey marks requests whose responses are sent as they are
+	noCompressionKey = "noCompression"
+)
+
+// compressibleTypes are the content types worth compressing, by prefix. Images, PDFs, archives and
+// Office documents are compressed already.
+var compressibleTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/ld+json",
+	"image/svg+xml",
+	"text/",
+}
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		writer, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return writer
+	},
+}
+
+// Compression gzips responses for clients that accept it. Responses are only compressed when they're
+// large enough and of a compressible type, and never when the handler set its own Content-Encoding, as
+// streaming endpoints do, or streams events. Brotli isn't offered, clients asking for "br" and gzip get
+// gzip. Routes can opt out with NoCompression.
+func Compression() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &compressWriter{ResponseWriter: original, ctx: c}
+		c.Writer = writer
+		finished := false
+		defer func() {
+			// Outer middleware writes to the client directly, like the 500 ErrorReporting sends when a
+			// handler panics
+			c.Writer = original
+			if finished {
+				writer.close()
+			} else {
+				writer.abandon()
+			}
+		}()
+		c.Next()
+		finished = true
+	}
+}
+
+// NoCompression sends a route's responses as they are, for endpoints that stream or serve files that
+// are compressed already
+func NoCompression() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(noCompressionKey, true)
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header accepts gzip, honoring q=0
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressWriter holds back the start of a response until it knows whether to compress it
+type compressWriter struct {
+	gin.ResponseWriter
+	ctx     *gin.Context
+	buffer  []byte
+	status  int
+	decided bool
+	gzip    *gzip.Writer
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	w.decide(true)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if !w.compressible() {
+			w.decide(false)
+		} else if len(w.buffer)+len(data) < minCompressSize {
+			w.buffer = append(w.buffer, data...)
+			return len(data), nil
+		} else {
+			w.decide(true)
+		}
+	}
+	if w.gzip != nil {
+		return w.gzip.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Status returns the status the handler set, before it's written
+func (w *compressWriter) Status() int {
+	if !w.decided && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+// Written reports whether the handler responded, so later handlers don't respond again
+func (w *compressWriter) Written() bool {
+	return w.ResponseWriter.Written() || len(w.buffer) > 0
+}
+
+// Size returns the bytes written to the client
+func (w *compressWriter) Size() int {
+	return w.ResponseWriter.Size()
+}
+
+func (w *compressWriter) Flush() {
+	// Streaming handlers flush as they go, what they wrote before isn't held back
+	w.decide(false)
+	if w.gzip != nil {
+		_ = w.gzip.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// compressible reports whether the response can be compressed, from its headers so far
+func (w *compressWriter) compressible() bool {
+	if w.ctx.GetBool(noCompressionKey) {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	if w.status == http.StatusNoContent || w.status == http.StatusNotModified || (w.status >= 100 && w.status < 200) {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	if contentType == "" || strings.HasPrefix(contentType, "text/event-stream") {
+		return false
+	}
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// decide writes the held back headers and body, compressed when compress is set and the response can be
+func (w *compressWriter) decide(compress bool) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	header := w.Header()
+	header.Add("Vary", "Accept-Encoding")
+	if compress && w.compressible() {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gzip = gzipWriters.Get().(*gzip.Writer)
+		w.gzip.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buffer) > 0 {
+		if w.gzip != nil {
+			_, _ = w.gzip.Write(w.buffer)
+		} else {
+			_, _ = w.ResponseWriter.Write(w.buffer)
+		}
+		w.buffer = nil
+	}
+}
+
+// close sends what's held back and ends the compressed stream
+func (w *compressWriter) close() {
+	if !w.decided {
+		if len(w.buffer) == 0 && w.status == 0 {
+			return
+		}
+		w.decide(false)
+	}
+	w.release()
+}
+
+// abandon drops what a handler that didn't finish held back, so the response can be sent in its place, and
+// ends the compressed stream if it already started
+func (w *compressWriter) abandon() {
+	if !w.decided {
+		w.decided = true
+		w.buffer = nil
+		w.status = 0
+		return
+	}
+	w.release()
+}
+
+// release ends the compressed stream and returns its writer to the pool
+func (w *compressWriter) release() {
+	if w.gzip != nil {
+		_ = w.gzip.Close()
+		w.gzip.Reset(nil)
+		gzipWriters.Put(w.gzip)
+		w.gzip = nil
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"backend/internal/errorreport"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+)
+
+const errorReportedKey = "error_reported"
+
+// ErrorReporting recovers from panics in handlers, answering with a 500 that carries the request ID,
+// and reports them along with errors from ReportError and other 500 responses to Sentry. Reports carry
+// the request, its ID and, once authentication has run, the user and organization. Register it after
+// RequestLogger.
+func ErrorReporting() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, hub := errorreport.NewContext(c.Request.Context())
+		// Services may report with the request's context after it's done, the gin context is reused by then
+		var finished atomic.Bool
+		defer finished.Store(true)
+
+		scope := hub.Scope()
+		scope.SetRequest(c.Request)
+		scope.SetTag("request_id", GetRequestID(c))
+		scope.AddEventProcessor(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+			if finished.Load() {
+				return event
+			}
+			if userID, exists := GetClerkUserID(c); exists {
+				event.User.ID = userID
+			}
+			if orgID, exists := GetOrganizationID(c); exists {
+				if event.Tags == nil {
+					event.Tags = map[string]string{}
+				}
+				event.Tags["org_id"] = orgID
+			}
+			return event
+		})
+
+		if errorreport.Enabled() {
+			transaction := sentry.StartTransaction(ctx, c.Request.Method+" "+c.Request.URL.Path,
+				sentry.WithOpName("http.server"),
+				sentry.ContinueFromRequest(c.Request),
+				sentry.WithTransactionSource(sentry.SourceURL))
+			defer func() {
+				if route := c.FullPath(); route != "" {
+					transaction.Name = c.Request.Method + " " + route
+					transaction.Source = sentry.SourceRoute
+				}
+				transaction.Status = sentry.HTTPtoSpanStatus(c.Writer.Status())
+				transaction.Finish()
+			}()
+			ctx = transaction.Context()
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if isBrokenConnection(recovered) {
+				// The client went away, there's no one to answer
+				Logger(c).Warn().Interface("panic", recovered).Msg("Connection closed while writing response")
+				c.Abort()
+				return
+			}
+			c.Set(errorReportedKey, true)
+			errorreport.CapturePanic(ctx, recovered, nil)
+			Logger(c).Error().Interface("panic", recovered).Msg("Request panicked")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}()
+
+		c.Next()
+
+		// Other 5xx, like 503 for an integration that isn't configured, aren't the server's fault
+		if c.Writer.Status() == http.StatusInternalServerError && !c.GetBool(errorReportedKey) {
+			if err := c.Errors.Last(); err != nil {
+				errorreport.Capture(ctx, err.Err, nil)
+			} else {
+				errorreport.CaptureMessage(ctx, fmt.Sprintf("%s %s responded 500", c.Request.Method, routeOrPath(c)), nil)
+			}
+		}
+	}
+}
+
+// ReportError logs an unexpected error with the request's logger and reports it with the request
+func ReportError(c *gin.Context, err error, message string) {
+	Logger(c).Error().Err(err).Msg(message)
+	c.Set(errorReportedKey, true)
+	errorreport.Capture(c.Request.Context(), err, nil)
+}
+
+func routeOrPath(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return c.Request.URL.Path
+}
+
+// isBrokenConnection reports whether a panic came from writing to a client that disconnected
+func isBrokenConnection(recovered interface{}) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	message := strings.ToLower(syscallErr.Error())
+	return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+}
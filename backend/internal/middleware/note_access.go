@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"backend/internal/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// NoteAccess is what a user may do with a note, each level allowing what the ones below it do
+type NoteAccess int
+
+const (
+	NoteAccessNone       NoteAccess = iota
+	NoteAccessCanView               // Read the note
+	NoteAccessCanComment            // Read the note and comment on it
+	NoteAccessCanEdit               // Change the note
+)
+
+func (a NoteAccess) String() string {
+	switch a {
+	case NoteAccessCanView:
+		return "view"
+	case NoteAccessCanComment:
+		return "comment"
+	case NoteAccessCanEdit:
+		return "edit"
+	}
+	return "none"
+}
+
+// GetNoteAccess returns what a user may do with a note. Personal notes are their owner's to edit, in an
+// organization it depends on the member's role.
+func GetNoteAccess(ctx context.Context, db *gorm.DB, noteID, clerkUserID string) (NoteAccess, error) {
+	hasAccess, err := CheckNoteAccess(ctx, db, noteID, clerkUserID)
+	if err != nil || !hasAccess {
+		return NoteAccessNone, err
+	}
+
+	var result struct {
+		OrganizationID *string
+	}
+	err = db.WithContext(ctx).Model(&models.Notes{}).
+		Select("notebooks.organization_id").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notes.id = ?", noteID).
+		First(&result).Error
+	if err != nil {
+		return NoteAccessNone, err
+	}
+	return orgNoteAccess(ctx, result.OrganizationID, clerkUserID)
+}
+
+// CanEditNote reports whether a user may change a note
+func CanEditNote(ctx context.Context, db *gorm.DB, noteID, clerkUserID string) (bool, error) {
+	access, err := GetNoteAccess(ctx, db, noteID, clerkUserID)
+	return access >= NoteAccessCanEdit, err
+}
+
+// GetChapterAccess returns what a user may do with the notes of a chapter, like creating them or moving
+// notes into it
+func GetChapterAccess(ctx context.Context, db *gorm.DB, chapterID, clerkUserID string) (NoteAccess, error) {
+	hasAccess, err := CheckChapterAccess(ctx, db, chapterID, clerkUserID)
+	if err != nil || !hasAccess {
+		return NoteAccessNone, err
+	}
+
+	var result struct {
+		OrganizationID *string
+	}
+	err = db.WithContext(ctx).Model(&models.Chapter{}).
+		Select("notebooks.organization_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("chapters.id = ?", chapterID).
+		First(&result).Error
+	if err != nil {
+		return NoteAccessNone, err
+	}
+	return orgNoteAccess(ctx, result.OrganizationID, clerkUserID)
+}
+
+// CanEditChapter reports whether a user may add notes to a chapter or change its notes
+func CanEditChapter(ctx context.Context, db *gorm.DB, chapterID, clerkUserID string) (bool, error) {
+	access, err := GetChapterAccess(ctx, db, chapterID, clerkUserID)
+	return access >= NoteAccessCanEdit, err
+}
+
+// GetNotebookAccess returns what a user may do with the chapters and notes of a notebook, like importing
+// into it or publishing it
+func GetNotebookAccess(ctx context.Context, db *gorm.DB, notebookID, clerkUserID string) (NoteAccess, error) {
+	hasAccess, err := CheckNotebookAccess(ctx, db, notebookID, clerkUserID)
+	if err != nil || !hasAccess {
+		return NoteAccessNone, err
+	}
+
+	var result struct {
+		OrganizationID *string
+	}
+	err = db.WithContext(ctx).Model(&models.Notebook{}).
+		Select("organization_id").
+		Where("id = ?", notebookID).
+		First(&result).Error
+	if err != nil {
+		return NoteAccessNone, err
+	}
+	return orgNoteAccess(ctx, result.OrganizationID, clerkUserID)
+}
+
+// CanManageNotebook reports whether a user may make decisions that hold for everyone in the notebook, like
+// encrypting it or what it spends on AI: its owner, or an admin of its organization
+func CanManageNotebook(ctx context.Context, db *gorm.DB, notebookID, clerkUserID string) (bool, error) {
+	var result struct {
+		ClerkUserID    string
+		OrganizationID *string
+	}
+	err := db.WithContext(ctx).Model(&models.Notebook{}).
+		Select("clerk_user_id", "organization_id").
+		Where("id = ?", notebookID).
+		First(&result).Error
+	if err != nil {
+		return false, err
+	}
+	if result.OrganizationID == nil || *result.OrganizationID == "" {
+		return result.ClerkUserID == clerkUserID, nil
+	}
+
+	role, isMember, err := GetOrgMemberRoleCached(ctx, *result.OrganizationID, clerkUserID)
+	if err != nil || !isMember {
+		return false, err
+	}
+	return result.ClerkUserID == clerkUserID || role == OrgRoleAdmin, nil
+}
+
+// orgNoteAccess returns what a user may do with the notes of a notebook in the organization, all of it
+// for personal notebooks
+func orgNoteAccess(ctx context.Context, organizationID *string, clerkUserID string) (NoteAccess, error) {
+	if organizationID == nil || *organizationID == "" {
+		return NoteAccessCanEdit, nil
+	}
+
+	role, isMember, err := GetOrgMemberRoleCached(ctx, *organizationID, clerkUserID)
+	if err != nil || !isMember {
+		return NoteAccessNone, err
+	}
+	switch role {
+	case OrgRoleViewer:
+		return NoteAccessCanView, nil
+	case OrgRoleCommenter:
+		return NoteAccessCanComment, nil
+	}
+	return NoteAccessCanEdit, nil
+}
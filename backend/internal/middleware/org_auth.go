@@ -10,6 +10,37 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// Organization roles, simplified from Clerk's. Commenters and viewers are custom Clerk roles
+// (org:commenter, org:viewer) that have to be created in the Clerk dashboard before members can get them.
+const (
+	OrgRoleAdmin     = "admin"
+	OrgRoleMember    = "member"
+	OrgRoleCommenter = "commenter" // Reads notes and comments on them
+	OrgRoleViewer    = "viewer"    // Reads notes
+)
+
+// clerkOrgRoles maps the Clerk roles members can be given to our simplified roles
+var clerkOrgRoles = map[string]string{
+	"org:admin":     OrgRoleAdmin,
+	"org:member":    OrgRoleMember,
+	"org:commenter": OrgRoleCommenter,
+	"org:viewer":    OrgRoleViewer,
+}
+
+// OrgRoleFromClerk simplifies a Clerk organization role. Other custom roles count as members.
+func OrgRoleFromClerk(clerkRole string) string {
+	if role, ok := clerkOrgRoles[clerkRole]; ok {
+		return role
+	}
+	return OrgRoleMember
+}
+
+// ValidClerkOrgRole reports whether members can be given a Clerk organization role
+func ValidClerkOrgRole(clerkRole string) bool {
+	_, ok := clerkOrgRoles[clerkRole]
+	return ok
+}
+
 // GetActiveOrgID extracts organization ID from query parameter or header
 func GetActiveOrgID(c *gin.Context) string {
 	// Try query parameter first
@@ -24,8 +55,13 @@ func GetActiveOrgID(c *gin.Context) string {
 }
 
 // GetOrgMemberRole fetches the user's role in an organization from Clerk
-// Returns role ("admin" or "member") and whether the user is a member
+// Returns role (see the OrgRole constants) and whether the user is a member
 func GetOrgMemberRole(ctx context.Context, orgID, userID string) (string, bool, error) {
+	// Organizations live in Clerk, so the local user of single-user mode belongs to none
+	if SingleUserEnabled() {
+		return "", false, nil
+	}
+
 	// Check if user is a member by fetching memberships for this specific organization
 	params := &organizationmembership.ListParams{}
 	params.Limit = clerk.Int64(100)
@@ -52,15 +88,8 @@ func GetOrgMemberRole(ctx context.Context, orgID, userID string) (string, bool,
 		return "", false, nil
 	}
 
-	// Extract role (admin or member)
-	// In Clerk, the role is stored as "org:admin" or "org:member"
-	role := membership.Role
-	isAdmin := role == "org:admin"
-
-	if isAdmin {
-		return "admin", true, nil
-	}
-	return "member", true, nil
+	// In Clerk, the role is stored as "org:admin", "org:member" or a custom "org:" role
+	return OrgRoleFromClerk(membership.Role), true, nil
 }
 
 // RequireOrgMembership middleware verifies that the user is a member of the organization
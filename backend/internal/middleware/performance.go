@@ -1,41 +1,47 @@
 package middleware
 
 import (
+	"backend/db"
 	"fmt"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/rs/zerolog/log"
 )
 
-// ResponseTimeMiddleware tracks and logs API request performance
+// ResponseTimeMiddleware tracks and logs API request performance, including the time spent in queries
+// made with the request's context
 func ResponseTimeMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
+		ctx, queries := db.WithQueryStats(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
 
 		duration := time.Since(start)
 		durationMs := duration.Milliseconds()
+		dbMs := queries.Duration().Milliseconds()
 
-		// Add response time header
+		// Add response time headers
 		c.Header("X-Response-Time", fmt.Sprintf("%dms", durationMs))
+		c.Header("X-DB-Time", fmt.Sprintf("%dms", dbMs))
+		c.Header("X-DB-Queries", fmt.Sprintf("%d", queries.Count()))
 
 		// Log slow queries
 		if durationMs > 200 {
-			log.Warn().
-				Str("method", c.Request.Method).
-				Str("path", c.Request.URL.Path).
+			Logger(c).Warn().
 				Int64("duration_ms", durationMs).
+				Int64("db_ms", dbMs).
+				Int64("db_queries", queries.Count()).
 				Int("status", c.Writer.Status()).
 				Msg("Slow API request detected")
 		}
 
 		// Log all requests in debug mode
-		log.Debug().
-			Str("method", c.Request.Method).
-			Str("path", c.Request.URL.Path).
+		Logger(c).Debug().
 			Int64("duration_ms", durationMs).
+			Int64("db_ms", dbMs).
+			Int64("db_queries", queries.Count()).
 			Int("status", c.Writer.Status()).
 			Msg("API request completed")
 	}
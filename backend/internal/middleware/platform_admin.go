@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// IsPlatformAdmin reports whether a user administers the whole server, set by the comma-separated Clerk
+// user IDs in ADMIN_USER_IDS. In single-user mode the local user is the admin.
+func IsPlatformAdmin(clerkUserID string) bool {
+	if SingleUserEnabled() {
+		return clerkUserID == LocalUserID
+	}
+	for _, id := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if strings.TrimSpace(id) == clerkUserID && clerkUserID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePlatformAdmin middleware limits a route to the server's admins, for settings that apply to
+// every workspace
+func RequirePlatformAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clerkUserID, exists := GetClerkUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+			c.Abort()
+			return
+		}
+		if !IsPlatformAdmin(clerkUserID) {
+			log.Warn().Str("user_id", clerkUserID).Str("path", c.FullPath()).Msg("User is not a platform admin")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only server admins can do this"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// PublicRateLimiter limits unauthenticated requests per client IP, for public endpoints that cost the
+// content's author something such as AI calls
+type PublicRateLimiter struct {
+	limiters        map[string]*rateLimiterEntry
+	mu              sync.Mutex
+	rate            rate.Limit
+	burst           int
+	cleanupInterval time.Duration
+}
+
+// NewPublicRateLimiter creates a new rate limiter
+// requestsPerMinute: number of requests allowed per minute per client IP
+func NewPublicRateLimiter(requestsPerMinute int) *PublicRateLimiter {
+	rl := &PublicRateLimiter{
+		limiters:        make(map[string]*rateLimiterEntry),
+		rate:            rate.Limit(float64(requestsPerMinute) / 60.0), // Convert to per-second rate
+		burst:           requestsPerMinute,
+		cleanupInterval: 5 * time.Minute,
+	}
+
+	// Start cleanup goroutine
+	go rl.cleanupRoutine()
+
+	return rl
+}
+
+// Allow checks if a request from the given client IP is allowed
+func (rl *PublicRateLimiter) Allow(clientIP string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	entry, exists := rl.limiters[clientIP]
+	if !exists {
+		entry = &rateLimiterEntry{limiter: rate.NewLimiter(rl.rate, rl.burst)}
+		rl.limiters[clientIP] = entry
+	}
+	entry.lastSeen = time.Now()
+
+	return entry.limiter.Allow()
+}
+
+// cleanupRoutine periodically removes inactive limiters
+func (rl *PublicRateLimiter) cleanupRoutine() {
+	ticker := time.NewTicker(rl.cleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rl.mu.Lock()
+		now := time.Now()
+		for clientIP, entry := range rl.limiters {
+			if now.Sub(entry.lastSeen) > rl.cleanupInterval {
+				delete(rl.limiters, clientIP)
+			}
+		}
+		rl.mu.Unlock()
+	}
+}
+
+// Middleware returns a Gin middleware function for rate limiting
+func (rl *PublicRateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rl.Allow(c.ClientIP()) {
+			c.JSON(429, gin.H{
+				"error":   "rate_limit_exceeded",
+				"message": "Too many requests. Please wait a moment before trying again.",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,8 +12,14 @@ const (
 	requestCacheKey = "request_cache"
 )
 
+// requestCacheContextKey stores the request cache on the request's context, so checks that only get a
+// context.Context (like chat tool calls) share it with the handler
+type requestCacheContextKey struct{}
+
 type requestCache struct {
+	mu             sync.Mutex
 	orgMemberships map[string]orgMembershipData
+	access         map[string]bool
 }
 
 type orgMembershipData struct {
@@ -20,39 +27,89 @@ type orgMembershipData struct {
 	isMember bool
 }
 
+func newRequestCache() *requestCache {
+	return &requestCache{
+		orgMemberships: make(map[string]orgMembershipData),
+		access:         make(map[string]bool),
+	}
+}
+
+// RequestCache attaches a cache to each request that lives until the response is sent
+func RequestCache() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		GetRequestCache(c)
+		c.Next()
+	}
+}
+
 // GetRequestCache retrieves or creates a request-level cache
 func GetRequestCache(c *gin.Context) *requestCache {
 	if cache, exists := c.Get(requestCacheKey); exists {
 		return cache.(*requestCache)
 	}
 
-	cache := &requestCache{
-		orgMemberships: make(map[string]orgMembershipData),
+	cache := requestCacheFromContext(c.Request.Context())
+	if cache == nil {
+		cache = newRequestCache()
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestCacheContextKey{}, cache))
 	}
 	c.Set(requestCacheKey, cache)
 	return cache
 }
 
-// GetOrgMemberRoleWithRequestCache checks request cache first, then memory cache, then Clerk API
-func GetOrgMemberRoleWithRequestCache(ctx context.Context, c *gin.Context, orgID, userID string) (string, bool, error) {
-	// Check request-level cache first (fastest)
-	reqCache := GetRequestCache(c)
-	key := orgID + ":" + userID
-	if data, exists := reqCache.orgMemberships[key]; exists {
-		return data.role, data.isMember, nil
+// requestCacheFromContext returns the request's cache, or nil outside a request
+func requestCacheFromContext(ctx context.Context) *requestCache {
+	if ctx == nil {
+		return nil
+	}
+	cache, _ := ctx.Value(requestCacheContextKey{}).(*requestCache)
+	return cache
+}
+
+func (rc *requestCache) getMembership(key string) (orgMembershipData, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	data, exists := rc.orgMemberships[key]
+	return data, exists
+}
+
+func (rc *requestCache) setMembership(key string, data orgMembershipData) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.orgMemberships[key] = data
+}
+
+// cachedAccess runs an access check once per request for each resource and user. Errors aren't cached.
+func cachedAccess(ctx context.Context, resource, userID string, check func() (bool, error)) (bool, error) {
+	cache := requestCacheFromContext(ctx)
+	if cache == nil {
+		return check()
 	}
 
-	// Fall back to memory cache and Clerk API
-	role, isMember, err := GetOrgMemberRoleCached(ctx, orgID, userID)
-	if err != nil {
-		return "", false, err
+	key := resource + ":" + userID
+	cache.mu.Lock()
+	hasAccess, exists := cache.access[key]
+	cache.mu.Unlock()
+	if exists {
+		return hasAccess, nil
 	}
 
-	// Store in request cache for this request
-	reqCache.orgMemberships[key] = orgMembershipData{
-		role:     role,
-		isMember: isMember,
+	hasAccess, err := check()
+	if err != nil {
+		return false, err
 	}
 
-	return role, isMember, nil
+	cache.mu.Lock()
+	cache.access[key] = hasAccess
+	cache.mu.Unlock()
+	return hasAccess, nil
+}
+
+// GetOrgMemberRoleWithRequestCache checks request cache first, then memory cache, then Clerk API
+func GetOrgMemberRoleWithRequestCache(ctx context.Context, c *gin.Context, orgID, userID string) (string, bool, error) {
+	cache := GetRequestCache(c)
+	if requestCacheFromContext(ctx) == nil {
+		ctx = context.WithValue(ctx, requestCacheContextKey{}, cache)
+	}
+	return GetOrgMemberRoleCached(ctx, orgID, userID)
 }
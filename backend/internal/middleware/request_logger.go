@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucsky/cuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestIDHeader carries a request's ID, from a proxy that assigned one and back to the client
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	requestIDKey     = "request_id"
+	requestLoggerKey = "request_logger"
+)
+
+// requestIDPattern is what IDs from proxies have to look like to be kept, so they're safe to log
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestLogger gives each request an ID and a logger carrying it, in the gin context and in the
+// request's context for services. The ID is sent back in the X-Request-ID header and as requestId in
+// JSON error responses, so a user reporting an error can point support at its logs.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = cuid.New()
+		}
+
+		logger := log.With().
+			Str("request_id", requestID).
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Logger()
+		c.Set(requestIDKey, requestID)
+		c.Set(requestLoggerKey, &logger)
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context()))
+
+		c.Header(RequestIDHeader, requestID)
+		c.Writer = &requestIDWriter{ResponseWriter: c.Writer, requestID: requestID}
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID RequestLogger gave the request
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Logger returns the request's logger with the user and organization, once authentication has run
+func Logger(c *gin.Context) *zerolog.Logger {
+	logger := &log.Logger
+	if value, exists := c.Get(requestLoggerKey); exists {
+		logger = value.(*zerolog.Logger)
+	}
+
+	fields := logger.With()
+	if userID, exists := GetClerkUserID(c); exists {
+		fields = fields.Str("user_id", userID)
+	}
+	if orgID, exists := GetOrganizationID(c); exists {
+		fields = fields.Str("org_id", orgID)
+	}
+	contextLogger := fields.Logger()
+	return &contextLogger
+}
+
+// requestIDWriter adds the request ID to JSON error responses
+type requestIDWriter struct {
+	gin.ResponseWriter
+	requestID string
+	written   bool
+}
+
+func (w *requestIDWriter) Write(data []byte) (int, error) {
+	if w.written || w.Status() < 400 || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		w.written = true
+		return w.ResponseWriter.Write(data)
+	}
+	w.written = true
+
+	body, ok := withRequestID(data, w.requestID)
+	if !ok {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, err := w.ResponseWriter.Write(body); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *requestIDWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// withRequestID adds requestId to a JSON object, leaving other JSON alone
+func withRequestID(data []byte, requestID string) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) < 2 || trimmed[0] != '{' || bytes.Contains(trimmed, []byte(`"requestId"`)) {
+		return nil, false
+	}
+	id, err := json.Marshal(requestID)
+	if err != nil {
+		return nil, false
+	}
+
+	rest := bytes.TrimSpace(trimmed[1:])
+	body := append([]byte(`{"requestId":`), id...)
+	if rest[0] != '}' {
+		body = append(body, ',')
+	}
+	return append(body, rest...), true
+}
@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"backend/internal/models"
+	"backend/internal/models/dto"
+	"backend/internal/utils"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// payloadGraph builds the graph of a workspace with the given number of notes, each linked to the next three
+func payloadGraph(notes int) dto.GraphData {
+	day := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	graph := dto.GraphData{TotalNodes: notes, Cursor: day}
+	for i := 0; i < notes; i++ {
+		graph.Nodes = append(graph.Nodes, dto.GraphNode{
+			ID:           fmt.Sprintf("note_%04d", i),
+			Name:         fmt.Sprintf("Design review %d", i),
+			ChapterName:  fmt.Sprintf("Sprint %d", i%12),
+			NotebookName: "Engineering",
+			CreatedAt:    day.Add(time.Duration(i) * time.Hour),
+			UpdatedAt:    day.Add(time.Duration(i) * time.Hour),
+			LinkCount:    3,
+			Metadata:     map[string]string{"status": "draft", "language": "en"},
+		})
+		for j := 1; j <= 3; j++ {
+			graph.Links = append(graph.Links, dto.GraphLink{
+				ID:       fmt.Sprintf("link_%04d_%d", i, j),
+				Source:   fmt.Sprintf("note_%04d", i),
+				Target:   fmt.Sprintf("note_%04d", (i+j)%notes),
+				LinkType: models.LinkTypeReferences,
+				Origin:   models.LinkOriginWiki,
+			})
+		}
+	}
+	return graph
+}
+
+// payloadTaskBoard builds a note's task board, preloaded the way GetTaskBoard loads it
+func payloadTaskBoard(tasks int) models.TaskBoard {
+	noteID := "note_1"
+	content := strings.Repeat(`{"type":"paragraph","content":[{"type":"text","text":"Plan the launch and track the work."}]},`, 200)
+	board := models.TaskBoard{
+		ID:     "board_1",
+		Name:   "Launch",
+		NoteID: &noteID,
+		Note: &models.Notes{
+			ID:      noteID,
+			Name:    "Launch plan",
+			Content: `{"type":"doc","content":[` + strings.TrimSuffix(content, ",") + `]}`,
+			Chapter: models.Chapter{ID: "chapter_1", Name: "Q3", Notebook: models.Notebook{ID: "notebook_1", Name: "Roadmap"}},
+		},
+	}
+	for i := 0; i < tasks; i++ {
+		board.Tasks = append(board.Tasks, models.Task{
+			ID:          fmt.Sprintf("task_%03d", i),
+			Title:       fmt.Sprintf("Task %d", i),
+			Description: "Write it up, get it reviewed and ship it.",
+			Status:      "todo",
+			Priority:    "medium",
+			TaskBoardID: board.ID,
+		})
+	}
+	return board
+}
+
+// payloadRouter serves a value with field selection behind the compression middleware
+func payloadRouter(value interface{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compression())
+	router.GET("/payload", func(c *gin.Context) {
+		utils.SendSelectedFields(c, http.StatusOK, value)
+	})
+	return router
+}
+
+// fetchPayload requests the payload and returns the response
+func fetchPayload(router *gin.Engine, fields string, acceptGzip bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/payload?fields="+fields, nil)
+	if acceptGzip {
+		req.Header.Set("Accept-Encoding", "br, gzip")
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestFieldSelection(t *testing.T) {
+	fields := utils.ParseFieldSelection(" id, tasks.title ,note.chapter.notebook.name,,id")
+	assert.Equal(t, utils.FieldSelection{"id", "tasks.title", "note.chapter.notebook.name"}, fields)
+	assert.True(t, fields.Includes("tasks"))
+	assert.True(t, fields.Includes("note.chapter.notebook"))
+	assert.False(t, fields.Includes("tasks.assignments"))
+	assert.True(t, utils.ParseFieldSelection("").Includes("anything"))
+
+	encoded, err := fields.Apply(payloadTaskBoard(2))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"board_1","tasks":[{"title":"Task 0"},{"title":"Task 1"}],
+		"note":{"chapter":{"notebook":{"name":"Roadmap"}}}}`, string(encoded))
+
+	// Selecting a field keeps everything under it, whichever way round the paths come
+	encoded, err = utils.ParseFieldSelection("note.chapter.name,note,missing").Apply(payloadTaskBoard(0))
+	require.NoError(t, err)
+	var decoded map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Len(t, decoded, 1)
+	assert.Equal(t, "Launch plan", decoded["note"]["name"])
+}
+
+func TestCompression(t *testing.T) {
+	router := payloadRouter(payloadGraph(50))
+
+	t.Run("large JSON is gzipped", func(t *testing.T) {
+		resp := fetchPayload(router, "", true)
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+		assert.Contains(t, resp.Header().Values("Vary"), "Accept-Encoding")
+
+		reader, err := gzip.NewReader(resp.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		var graph dto.GraphData
+		require.NoError(t, json.Unmarshal(body, &graph))
+		assert.Len(t, graph.Nodes, 50)
+	})
+
+	t.Run("clients that don't accept gzip get plain JSON", func(t *testing.T) {
+		resp := fetchPayload(router, "", false)
+		assert.Empty(t, resp.Header().Get("Content-Encoding"))
+		assert.True(t, json.Valid(resp.Body.Bytes()))
+	})
+
+	t.Run("small responses are sent as they are", func(t *testing.T) {
+		resp := fetchPayload(router, "totalNodes", true)
+		assert.Empty(t, resp.Header().Get("Content-Encoding"))
+		assert.JSONEq(t, `{"totalNodes":50}`, resp.Body.String())
+	})
+
+	t.Run("a panicking handler still gets its 500", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.Use(RequestLogger(), ErrorReporting(), Compression())
+		router.GET("/panic", func(c *gin.Context) {
+			c.Header("Content-Type", "application/json")
+			_, _ = c.Writer.WriteString(`{"partial":`)
+			panic("handler failed")
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+		assert.Empty(t, resp.Header().Get("Content-Encoding"))
+		var body map[string]string
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, "Internal server error", body["error"])
+	})
+
+	t.Run("streams and opted out routes aren't compressed", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.Use(Compression())
+		large := strings.Repeat("data: chunk\n\n", 200)
+		router.GET("/stream", func(c *gin.Context) {
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("Content-Encoding", "identity")
+			c.String(http.StatusOK, large)
+		})
+		router.GET("/opted-out", NoCompression(), func(c *gin.Context) {
+			c.String(http.StatusOK, large)
+		})
+
+		for _, path := range []string{"/stream", "/opted-out"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			assert.NotEqual(t, "gzip", resp.Header().Get("Content-Encoding"), path)
+			assert.Equal(t, large, resp.Body.String(), path)
+		}
+	})
+}
+
+// benchmarkPayload serves a value for each request and reports the bytes sent
+func benchmarkPayload(b *testing.B, value interface{}, fields string, acceptGzip bool) {
+	router := payloadRouter(value)
+	var size int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		size = fetchPayload(router, fields, acceptGzip).Body.Len()
+	}
+	b.ReportMetric(float64(size), "response-bytes")
+}
+
+// BenchmarkGraphDataPayload compares what GetGraphData sends for a 500 note graph in full, with the
+// fields a graph view draws, and gzipped
+func BenchmarkGraphDataPayload(b *testing.B) {
+	graph := payloadGraph(500)
+	fields := "nodes.id,nodes.name,nodes.linkCount,links.source,links.target"
+	b.Run("full", func(b *testing.B) { benchmarkPayload(b, graph, "", false) })
+	b.Run("fields", func(b *testing.B) { benchmarkPayload(b, graph, fields, false) })
+	b.Run("full-gzip", func(b *testing.B) { benchmarkPayload(b, graph, "", true) })
+	b.Run("fields-gzip", func(b *testing.B) { benchmarkPayload(b, graph, fields, true) })
+}
+
+// BenchmarkTaskBoardPayload compares what GetTaskBoard sends for a 200 task board of a long note in
+// full, with the fields a kanban view shows, and gzipped
+func BenchmarkTaskBoardPayload(b *testing.B) {
+	board := payloadTaskBoard(200)
+	fields := "id,name,tasks.id,tasks.title,tasks.status,tasks.position"
+	b.Run("full", func(b *testing.B) { benchmarkPayload(b, board, "", false) })
+	b.Run("fields", func(b *testing.B) { benchmarkPayload(b, board, fields, false) })
+	b.Run("full-gzip", func(b *testing.B) { benchmarkPayload(b, board, "", true) })
+	b.Run("fields-gzip", func(b *testing.B) { benchmarkPayload(b, board, fields, true) })
+}
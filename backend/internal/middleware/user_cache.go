@@ -96,6 +96,10 @@ func (c *UserCache) cleanup() {
 
 // GetUserCached is a cached version of user.Get
 func GetUserCached(ctx context.Context, userID string) (*clerk.User, error) {
+	if localUser != nil && userID == localUser.ID {
+		return localUser, nil
+	}
+
 	cache := GetUserCache()
 
 	// Try cache first
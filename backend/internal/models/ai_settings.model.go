@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+)
+
+// AI model purposes, each with its own default provider and model
+const (
+	AIModelPurposeChat       = "chat"       // Assistant chat
+	AIModelPurposeGenerate   = "generate"   // Editor text generation
+	AIModelPurposeBackground = "background" // Cheap model for background jobs (tasks, summaries, videos)
+)
+
+// AIModelDefaults holds the default provider and model for each kind of AI work
+type AIModelDefaults struct {
+	ChatProvider       string `json:"chatProvider" gorm:"type:varchar(50)"`
+	ChatModel          string `json:"chatModel" gorm:"type:varchar(255)"`
+	GenerateProvider   string `json:"generateProvider" gorm:"type:varchar(50)"`
+	GenerateModel      string `json:"generateModel" gorm:"type:varchar(255)"`
+	BackgroundProvider string `json:"backgroundProvider" gorm:"type:varchar(50)"`
+	BackgroundModel    string `json:"backgroundModel" gorm:"type:varchar(255)"`
+}
+
+// ForPurpose returns the configured provider and model for a purpose, empty if unset
+func (d AIModelDefaults) ForPurpose(purpose string) (string, string) {
+	switch purpose {
+	case AIModelPurposeChat:
+		return d.ChatProvider, d.ChatModel
+	case AIModelPurposeGenerate:
+		return d.GenerateProvider, d.GenerateModel
+	case AIModelPurposeBackground:
+		return d.BackgroundProvider, d.BackgroundModel
+	}
+	return "", ""
+}
+
+// AIToolPermissions restricts which assistant tools may run
+type AIToolPermissions struct {
+	ToolsReadOnly bool   `json:"toolsReadOnly" gorm:"default:false"` // Only tools that don't modify data are allowed
+	DisabledTools string `json:"-" gorm:"type:text"`                 // Comma-separated tool names
+}
+
+// UserAISettings stores a user's preferred AI providers, models and assistant tool permissions
+type UserAISettings struct {
+	ID                uint   `json:"id" gorm:"primaryKey"`
+	ClerkUserID       string `json:"clerkUserId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	AIModelDefaults   `gorm:"embedded"`
+	AIToolPermissions `gorm:"embedded"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// TableName specifies the table name for GORM
+func (UserAISettings) TableName() string {
+	return "user_ai_settings"
+}
+
+// OrganizationAISettings stores an organization's default AI providers, models and assistant tool permissions
+// Model defaults take precedence over members' personal settings in the organization workspace,
+// tool permissions are combined with them so the stricter one applies
+type OrganizationAISettings struct {
+	ID                uint   `json:"id" gorm:"primaryKey"`
+	OrganizationID    string `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	AIModelDefaults   `gorm:"embedded"`
+	AIToolPermissions `gorm:"embedded"`
+	UpdatedBy         string    `json:"updatedBy" gorm:"type:varchar(255)"` // Clerk user ID of the admin who last changed the settings
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// TableName specifies the table name for GORM
+func (OrganizationAISettings) TableName() string {
+	return "organization_ai_settings"
+}
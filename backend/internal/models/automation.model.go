@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Automation triggers exposed to Zapier, Make and similar platforms
+const (
+	AutomationTriggerNewNote       = "new_note"
+	AutomationTriggerNoteTagged    = "note_tagged"
+	AutomationTriggerTaskCompleted = "task_completed"
+)
+
+// AutomationAPIKey authenticates automation platforms on behalf of a user.
+// Organization keys have OrganizationID set and only reach that organization's content.
+type AutomationAPIKey struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID    string     `json:"clerkUserId" gorm:"type:varchar(255);not null;index"`
+	OrganizationID *string    `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	Name           string     `json:"name" gorm:"not null"`
+	KeyPrefix      string     `json:"keyPrefix" gorm:"type:varchar(32);not null"`
+	KeyHash        string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"` // SHA-256 of the key
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+func (k *AutomationAPIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == "" {
+		k.ID = cuid.New()
+	}
+	return nil
+}
+
+// AutomationWebhook is a REST hook subscription that receives trigger events as they happen
+type AutomationWebhook struct {
+	ID              string            `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	APIKeyID        string            `json:"apiKeyId" gorm:"type:varchar(255);not null;index"`
+	ClerkUserID     string            `json:"-" gorm:"type:varchar(255);not null;index"`
+	OrganizationID  *string           `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	Event           string            `json:"event" gorm:"type:varchar(50);not null;index"`
+	TargetURL       string            `json:"targetUrl" gorm:"type:text;not null"`
+	Tag             string            `json:"tag,omitempty" gorm:"type:varchar(100)"` // Only for note_tagged
+	FailureCount    int               `json:"failureCount" gorm:"default:0"`
+	LastDeliveredAt *time.Time        `json:"lastDeliveredAt,omitempty"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	APIKey          *AutomationAPIKey `json:"-" gorm:"foreignKey:APIKeyID;constraint:OnDelete:CASCADE"`
+}
+
+func (w *AutomationWebhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == "" {
+		w.ID = cuid.New()
+	}
+	return nil
+}
+
+// NoteConflict is an update from a sync client refused because the note changed on the server since the
+// client read it. It keeps both versions and a merge of them until the client uploads its resolution.
+type NoteConflict struct {
+	ID              string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID          string    `json:"noteId" gorm:"type:varchar(255);not null;index"`
+	APIKeyID        string    `json:"apiKeyId" gorm:"type:varchar(255);not null;index"`
+	BaseContent     string    `json:"-" gorm:"type:text"` // TipTap JSON the client last synced, empty when it didn't send it
+	LocalContent    string    `json:"-" gorm:"type:text"`
+	ServerContent   string    `json:"-" gorm:"type:text"`
+	MergedContent   string    `json:"-" gorm:"type:text"`
+	ConflictCount   int       `json:"conflictCount" gorm:"not null;default:0"` // Blocks changed on both sides
+	ServerUpdatedAt time.Time `json:"serverUpdatedAt"`                         // The note's version the merge is based on
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	Note            *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+}
+
+func (c *NoteConflict) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = cuid.New()
+	}
+	return nil
+}
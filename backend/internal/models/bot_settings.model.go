@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// How the meeting bot asks for consent to record
+const (
+	BotConsentNone             = "none"               // No notice beyond the join message
+	BotConsentNotify           = "notify"             // The bot tells the meeting it's recording when it joins
+	BotConsentLeaveOnObjection = "leave_on_objection" // It also leaves when the host denies recording permission
+)
+
+// OrganizationBotSettings is how the meeting bot presents itself in its members' meetings. Organizations
+// without settings use Recall.ai's bot name and send no message.
+type OrganizationBotSettings struct {
+	ID             uint      `json:"-" gorm:"primaryKey"`
+	OrganizationID string    `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	BotName        string    `json:"botName" gorm:"type:varchar(100)"`
+	JoinMessage    string    `json:"joinMessage" gorm:"type:text"`
+	ConsentMode    string    `json:"consentMode" gorm:"type:varchar(30);not null;default:none"`
+	UpdatedBy      string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
@@ -49,6 +49,8 @@ type CalendarEvent struct {
 	BotID              *string           `json:"botId,omitempty"`
 	MeetingRecordingID *string           `json:"meetingRecordingId,omitempty"`
 	MeetingRecording   *MeetingRecording `json:"meetingRecording,omitempty" gorm:"foreignKey:MeetingRecordingID"`
+	AgendaNoteID       *string           `json:"agendaNoteId,omitempty" gorm:"type:varchar(255);index"` // Prep note, the meeting's notes are added to it afterwards
+	AgendaNote         *Notes            `json:"agendaNote,omitempty" gorm:"foreignKey:AgendaNoteID;constraint:OnDelete:SET NULL"`
 	CreatedAt          time.Time         `json:"createdAt"`
 	UpdatedAt          time.Time         `json:"updatedAt"`
 }
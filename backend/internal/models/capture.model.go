@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Capture item statuses
+const (
+	CaptureStatusUnfiled = "unfiled"
+	CaptureStatusFiled   = "filed"
+	CaptureStatusRemoved = "removed" // Deleted from the Inbox note by hand
+)
+
+// CaptureItem is a snippet captured to an Inbox note, kept until it's filed into a note of its own.
+// The snippet is the block with BlockID and the top level blocks after it, BlockCount in all.
+type CaptureItem struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID    string     `json:"clerkUserId" gorm:"type:varchar(255);not null;index"`
+	OrganizationID *string    `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	NoteID         string     `json:"noteId" gorm:"type:varchar(255);not null;index"` // The Inbox note
+	BlockID        string     `json:"blockId" gorm:"type:varchar(255);not null"`
+	BlockCount     int        `json:"blockCount" gorm:"not null"`
+	Text           string     `json:"text" gorm:"type:text"` // The snippet as captured
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;default:'unfiled';index"`
+	FiledNoteID    *string    `json:"filedNoteId,omitempty" gorm:"type:varchar(255)"`
+	FiledAt        *time.Time `json:"filedAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+func (i *CaptureItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = cuid.New()
+	}
+	return nil
+}
@@ -10,19 +10,54 @@ import (
 type Chapter struct {
 	ID             string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
 	Name           string    `json:"name"`
+	Slug           string    `json:"slug" gorm:"type:varchar(255);index"` // Unique within the notebook, follows the name
 	NotebookID     string    `json:"notebookId" gorm:"type:varchar(255);index"`
 	OrganizationID *string   `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
 	Notebook       Notebook  `json:"notebook" gorm:"foreignKey:NotebookID"`
 	Files          []Notes   `json:"notes" gorm:"foreignKey:ChapterID"`
 	IsPublic       bool      `json:"isPublic" gorm:"default:false"`
+	NoteTemplate   string    `json:"noteTemplate,omitempty" gorm:"type:text"`    // Markdown that new notes in the chapter start from
+	DefaultTags    string    `json:"-" gorm:"type:text"`                         // Comma-separated tags added to new notes
+	AITone         string    `json:"aiTone,omitempty" gorm:"type:varchar(20)"`   // Tone of AI-generated notes, empty for the default
+	AILength       string    `json:"aiLength,omitempty" gorm:"type:varchar(20)"` // Length of AI-generated notes, empty for the default
 	CreatedAt      time.Time `json:"createdAt"`
 	UpdatedAt      time.Time `json:"updatedAt"`
 }
 
-// BeforeCreate hook to generate CUID before creating a chapter
+// AI generation tones a chapter can ask for
+const (
+	ChapterAIToneNeutral  = "neutral"
+	ChapterAIToneFormal   = "formal"
+	ChapterAIToneCasual   = "casual"
+	ChapterAIToneAcademic = "academic"
+)
+
+// AI generation lengths a chapter can ask for
+const (
+	ChapterAILengthShort  = "short"
+	ChapterAILengthMedium = "medium"
+	ChapterAILengthLong   = "long"
+)
+
+// ChapterAITones lists the valid AI generation tones
+var ChapterAITones = []string{ChapterAIToneNeutral, ChapterAIToneFormal, ChapterAIToneCasual, ChapterAIToneAcademic}
+
+// ChapterAILengths lists the valid AI generation lengths
+var ChapterAILengths = []string{ChapterAILengthShort, ChapterAILengthMedium, ChapterAILengthLong}
+
+// BeforeCreate hook to generate CUID and a slug unique in the notebook before creating a chapter
 func (c *Chapter) BeforeCreate(tx *gorm.DB) error {
 	if c.ID == "" {
 		c.ID = cuid.New()
 	}
+	source := c.Slug
+	if source == "" {
+		source = c.Name
+	}
+	slug, err := UniqueSlug(tx, "chapters", "notebook_id", c.NotebookID, source, c.ID)
+	if err != nil {
+		return err
+	}
+	c.Slug = slug
 	return nil
 }
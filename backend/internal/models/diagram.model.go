@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Diagram formats rendered on the server
+const (
+	DiagramFormatSVG = "svg" // For published pages and emails
+	DiagramFormatPNG = "png" // For PDFs
+)
+
+// DiagramRender is a cached rendering of a mermaid or PlantUML diagram, keyed by a hash of its language and
+// source so a diagram is only rendered again once it changes. Sources the renderer rejects are cached
+// with their error.
+type DiagramRender struct {
+	Hash      string    `json:"hash" gorm:"primaryKey;type:varchar(64)"`
+	Format    string    `json:"format" gorm:"primaryKey;type:varchar(10)"`
+	Language  string    `json:"language" gorm:"type:varchar(20);not null"`
+	Data      []byte    `json:"-"`
+	Error     string    `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -11,21 +11,69 @@ type GraphNode struct {
 	CreatedAt    time.Time         `json:"createdAt"`
 	UpdatedAt    time.Time         `json:"updatedAt"`
 	LinkCount    int               `json:"linkCount"`
+	ClusterID    string            `json:"clusterId,omitempty"` // Community of closely linked notes the note is in
 	Metadata     map[string]string `json:"metadata,omitempty"`
 }
 
 // GraphLink represents a link between two nodes in the graph
 type GraphLink struct {
-	ID       string `json:"id"`
-	Source   string `json:"source"`
-	Target   string `json:"target"`
-	LinkType string `json:"linkType"`
+	ID         string `json:"id"`
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+	LinkType   string `json:"linkType"`
+	Origin     string `json:"origin"` // How the link was made: manual, wiki or ai
+	Annotation string `json:"annotation,omitempty"`
 }
 
 // GraphData represents the complete graph structure
 type GraphData struct {
-	Nodes []GraphNode `json:"nodes"`
-	Links []GraphLink `json:"links"`
+	Nodes      []GraphNode `json:"nodes"`
+	Links      []GraphLink `json:"links"`
+	TotalNodes int         `json:"totalNodes"` // Notes in the graph before the limit
+	Cursor     time.Time   `json:"cursor"`     // Pass as since to /api/graph/changes for updates
+}
+
+// GraphCluster is a community of closely linked notes, shown as one node until it's expanded
+type GraphCluster struct {
+	ID           string      `json:"id"`
+	Label        string      `json:"label"` // Name of the cluster's most linked note
+	NotebookName string      `json:"notebookName,omitempty"`
+	Size         int         `json:"size"`      // Notes in the cluster
+	LinkCount    int         `json:"linkCount"` // Links between notes of the cluster
+	TopNotes     []GraphNode `json:"topNotes"`  // Most linked notes of the cluster
+}
+
+// GraphClusterLink stands for the links between a cluster, or a note, and another cluster
+type GraphClusterLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"` // Links it stands for
+}
+
+// ClusteredGraphData is the graph with closely linked notes grouped into clusters
+type ClusteredGraphData struct {
+	Clusters   []GraphCluster     `json:"clusters"`
+	Links      []GraphClusterLink `json:"links"`
+	TotalNodes int                `json:"totalNodes"`
+	TotalLinks int                `json:"totalLinks"`
+	Cursor     time.Time          `json:"cursor"`
+}
+
+// GraphClusterData is an expanded cluster: its notes, the links between them and its links out
+type GraphClusterData struct {
+	Cluster       GraphCluster       `json:"cluster"`
+	Nodes         []GraphNode        `json:"nodes"`
+	Links         []GraphLink        `json:"links"`
+	ExternalLinks []GraphClusterLink `json:"externalLinks"` // From a note of the cluster to another cluster
+}
+
+// GraphChanges is what changed in the graph since a cursor
+type GraphChanges struct {
+	Cursor  time.Time   `json:"cursor"`  // Pass back as since for the next changes
+	Nodes   []GraphNode `json:"nodes"`   // Notes updated, or whose links changed, since the cursor
+	Links   []GraphLink `json:"links"`   // Links created or updated since the cursor
+	NodeIDs []string    `json:"nodeIds"` // Every note in the graph, to spot removed ones
+	LinkIDs []string    `json:"linkIds"` // Every link in the graph, to spot removed ones
 }
 
 // CreateNoteLinkRequest represents the request body for creating a note link
@@ -33,10 +81,22 @@ type CreateNoteLinkRequest struct {
 	SourceNoteID string `json:"sourceNoteId" binding:"required"`
 	TargetNoteID string `json:"targetNoteId" binding:"required"`
 	LinkType     string `json:"linkType"`
+	BlockRef     string `json:"blockRef"`   // Optional block ID in the target note
+	Origin       string `json:"origin"`     // "manual" (default) or "ai" for an accepted AI suggestion
+	Annotation   string `json:"annotation"` // Optional note on why the notes are linked
 }
 
-// UpdateNoteLinkRequest represents the request body for updating a note link
+// UpdateNoteLinkRequest represents the request body for updating a note link. Fields left out are kept.
 type UpdateNoteLinkRequest struct {
-	LinkType string `json:"linkType" binding:"required"`
+	LinkType   string  `json:"linkType"`
+	Annotation *string `json:"annotation"` // Empty to clear it
 }
 
+// GraphNeighborhood is the part of the graph within a few links of a note
+type GraphNeighborhood struct {
+	NoteID    string         `json:"noteId"`
+	Depth     int            `json:"depth"`
+	Nodes     []GraphNode    `json:"nodes"`
+	Links     []GraphLink    `json:"links"`
+	Distances map[string]int `json:"distances"` // Links from the note to each node
+}
@@ -0,0 +1,7 @@
+package dto
+
+// LinkPreviewRequest represents the request body for fetching a link preview
+type LinkPreviewRequest struct {
+	URL    string  `json:"url" binding:"required"`
+	NoteID *string `json:"noteId"`
+}
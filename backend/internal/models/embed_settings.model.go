@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Link embed providers the editor can render live
+const (
+	EmbedProviderFigma   = "figma"
+	EmbedProviderMiro    = "miro"
+	EmbedProviderLoom    = "loom"
+	EmbedProviderYouTube = "youtube"
+)
+
+// EmbedProviders lists every supported embed provider
+var EmbedProviders = []string{EmbedProviderFigma, EmbedProviderMiro, EmbedProviderLoom, EmbedProviderYouTube}
+
+// OrganizationEmbedSettings restricts which providers' links are embedded in an organization's notes.
+// Organizations without settings allow every provider; links from other providers show as plain link cards.
+type OrganizationEmbedSettings struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID   string    `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	AllowedProviders string    `json:"-" gorm:"type:text"` // Comma-separated
+	UpdatedBy        string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// ExternalLink stores the preview metadata fetched for a URL pasted into a note
+type ExternalLink struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID      *string   `json:"noteId,omitempty" gorm:"type:varchar(255);uniqueIndex:idx_external_links_note_url"`
+	URL         string    `json:"url" gorm:"type:text;not null;uniqueIndex:idx_external_links_note_url;index"`
+	Title       string    `json:"title" gorm:"type:text"`
+	Description string    `json:"description" gorm:"type:text"`
+	ImageURL    string    `json:"imageUrl" gorm:"type:text"`
+	SiteName    string    `json:"siteName" gorm:"type:varchar(255)"`
+	ContentType string    `json:"contentType" gorm:"type:varchar(255)"`
+	Provider    string    `json:"provider,omitempty" gorm:"type:varchar(50)"` // Embed provider, empty for plain links
+	EmbedURL    string    `json:"embedUrl,omitempty" gorm:"type:text"`
+	CreatedBy   string    `json:"createdBy" gorm:"type:varchar(255);not null;index"`
+	FetchedAt   time.Time `json:"fetchedAt"`
+	Note        *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the table name for ExternalLink
+func (ExternalLink) TableName() string {
+	return "external_links"
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// What new entries of a feed become
+const (
+	FeedTargetNote        = "note"
+	FeedTargetReadingList = "reading_list"
+)
+
+// What happened to an entry of a feed
+const (
+	FeedEntryStatusImported = "imported"
+	FeedEntryStatusFiltered = "filtered" // Left out by the subscription's keywords
+	FeedEntryStatusSkipped  = "skipped"  // Already in the feed when subscribing, or over a poll's limit
+)
+
+// FeedSubscription polls an RSS or Atom feed for a user and turns new entries into notes in a chapter,
+// or into reading list items
+type FeedSubscription struct {
+	ID                  string      `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID         string      `json:"clerkUserId" gorm:"type:varchar(255);not null;index"`
+	ChapterID           *string     `json:"chapterId,omitempty" gorm:"type:varchar(255);index"` // Where notes are created, unset for reading list subscriptions
+	Chapter             *Chapter    `json:"-" gorm:"foreignKey:ChapterID;constraint:OnDelete:CASCADE"`
+	FeedURL             string      `json:"feedUrl" gorm:"type:varchar(2048);not null"`
+	Title               string      `json:"title" gorm:"type:varchar(300)"`
+	SiteURL             string      `json:"siteUrl" gorm:"type:text"`
+	Target              string      `json:"target" gorm:"type:varchar(20);not null"`
+	IncludeKeywords     string      `json:"-" gorm:"type:text"` // Newline-separated, entries must mention one of them
+	ExcludeKeywords     string      `json:"-" gorm:"type:text"` // Newline-separated, entries mentioning any of them are left out
+	PollIntervalMinutes int         `json:"pollIntervalMinutes" gorm:"default:60"`
+	Enabled             bool        `json:"enabled" gorm:"default:true"`
+	ETag                string      `json:"-" gorm:"type:varchar(255)"`
+	LastModified        string      `json:"-" gorm:"type:varchar(255)"`
+	LastPolledAt        *time.Time  `json:"lastPolledAt,omitempty"`
+	LastError           string      `json:"lastError,omitempty" gorm:"type:text"`
+	Entries             []FeedEntry `json:"-" gorm:"foreignKey:SubscriptionID;constraint:OnDelete:CASCADE"`
+	CreatedAt           time.Time   `json:"createdAt"`
+	UpdatedAt           time.Time   `json:"updatedAt"`
+}
+
+// FeedEntry records an entry of a subscribed feed, so each entry is only imported once
+type FeedEntry struct {
+	ID                string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	SubscriptionID    string     `json:"subscriptionId" gorm:"type:varchar(255);not null;uniqueIndex:idx_feed_entries_subscription_key"`
+	EntryKey          string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex:idx_feed_entries_subscription_key"` // Hash of the entry's ID, link or title
+	Title             string     `json:"title" gorm:"type:varchar(300)"`
+	URL               string     `json:"url" gorm:"type:text"`
+	PublishedAt       *time.Time `json:"publishedAt,omitempty"`
+	Status            string     `json:"status" gorm:"type:varchar(20);not null"`
+	NoteID            *string    `json:"noteId,omitempty" gorm:"type:varchar(255)"`
+	Note              *Notes     `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:SET NULL"`
+	ReadingListItemID *string    `json:"readingListItemId,omitempty" gorm:"type:varchar(255)"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a feed subscription
+func (f *FeedSubscription) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == "" {
+		f.ID = cuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to generate CUID before creating a feed entry
+func (f *FeedEntry) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == "" {
+		f.ID = cuid.New()
+	}
+	return nil
+}
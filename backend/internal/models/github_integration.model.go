@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// GitHub sync modes
+const (
+	GitHubSyncModeManual   = "manual"
+	GitHubSyncModeSchedule = "schedule"
+	GitHubSyncModePublish  = "publish"
+)
+
+// GitHub sync statuses
+const (
+	GitHubSyncStatusSuccess = "success"
+	GitHubSyncStatusFailed  = "failed"
+)
+
+// GitHubIntegration pushes a user's or organization's notebooks to a GitHub repository as Markdown.
+// Organization integrations have OrganizationID set; personal ones are owned by ClerkUserID.
+type GitHubIntegration struct {
+	ID                  string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID         string     `json:"clerkUserId" gorm:"type:varchar(255);not null;index"`
+	OrganizationID      *string    `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	Repository          string     `json:"repository" gorm:"not null"` // owner/name
+	Branch              string     `json:"branch" gorm:"not null;default:'main'"`
+	BasePath            string     `json:"basePath"`
+	AccessToken         string     `json:"-" gorm:"type:text;not null"` // Encrypted
+	NotebookIDs         string     `json:"-" gorm:"type:text"`          // Comma-separated
+	SyncMode            string     `json:"syncMode" gorm:"not null;default:'manual'"`
+	SyncIntervalMinutes int        `json:"syncIntervalMinutes" gorm:"default:60"`
+	PullEnabled         bool       `json:"pullEnabled" gorm:"default:false"`
+	Enabled             bool       `json:"enabled" gorm:"default:true"`
+	UpdatedBy           string     `json:"updatedBy" gorm:"type:varchar(255)"`
+	LastSyncedAt        *time.Time `json:"lastSyncedAt,omitempty"`
+	LastSyncStatus      string     `json:"lastSyncStatus,omitempty"`
+	LastSyncError       string     `json:"lastSyncError,omitempty" gorm:"type:text"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+func (gi *GitHubIntegration) BeforeCreate(tx *gorm.DB) error {
+	if gi.ID == "" {
+		gi.ID = cuid.New()
+	}
+	return nil
+}
+
+// GitHubSyncedFile tracks the repository file a note was last synced to
+type GitHubSyncedFile struct {
+	ID            string             `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	IntegrationID string             `json:"integrationId" gorm:"type:varchar(255);not null;uniqueIndex:idx_github_synced_files_integration_note"`
+	NoteID        string             `json:"noteId" gorm:"type:varchar(255);not null;uniqueIndex:idx_github_synced_files_integration_note"`
+	Path          string             `json:"path" gorm:"type:text;not null"`
+	BlobSHA       string             `json:"blobSha" gorm:"type:varchar(64)"`
+	ContentHash   string             `json:"contentHash" gorm:"type:varchar(64)"`
+	SyncedAt      time.Time          `json:"syncedAt"`
+	Integration   *GitHubIntegration `json:"-" gorm:"foreignKey:IntegrationID;constraint:OnDelete:CASCADE"`
+}
+
+func (gsf *GitHubSyncedFile) BeforeCreate(tx *gorm.DB) error {
+	if gsf.ID == "" {
+		gsf.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// GlossaryTerm is a term of an organization's glossary. Notes using the term show its definition and
+// are linked to its canonical note, the note explaining it.
+type GlossaryTerm struct {
+	ID              string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	OrganizationID  string    `json:"organizationId" gorm:"type:varchar(255);not null;uniqueIndex:idx_glossary_terms_org_term"`
+	Term            string    `json:"term" gorm:"type:varchar(100);not null"`
+	NormalizedTerm  string    `json:"-" gorm:"type:varchar(100);not null;uniqueIndex:idx_glossary_terms_org_term"` // Lowercased, so terms are unique whatever their case
+	Aliases         string    `json:"-" gorm:"type:text"`                                                          // Newline-separated other ways of writing the term
+	Definition      string    `json:"definition" gorm:"type:text;not null"`
+	CanonicalNoteID *string   `json:"canonicalNoteId,omitempty" gorm:"type:varchar(255);index"`
+	CaseSensitive   bool      `json:"caseSensitive"` // Only match the term as written, for acronyms like "ACE"
+	CreatedBy       string    `json:"createdBy" gorm:"type:varchar(255);not null"`
+	UpdatedBy       string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a glossary term
+func (g *GlossaryTerm) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == "" {
+		g.ID = cuid.New()
+	}
+	return nil
+}
+
+// GlossaryOccurrence is how many times a glossary term is used in a note, kept up to date as notes and
+// the glossary change
+type GlossaryOccurrence struct {
+	ID             string        `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	TermID         string        `json:"termId" gorm:"type:varchar(255);not null;uniqueIndex:idx_glossary_occurrences_term_note"`
+	NoteID         string        `json:"noteId" gorm:"type:varchar(255);not null;uniqueIndex:idx_glossary_occurrences_term_note;index"`
+	OrganizationID string        `json:"organizationId" gorm:"type:varchar(255);not null;index"`
+	Count          int           `json:"count" gorm:"not null"`
+	Term           *GlossaryTerm `json:"-" gorm:"foreignKey:TermID;constraint:OnDelete:CASCADE"`
+	Note           *Notes        `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	UpdatedAt      time.Time     `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a glossary occurrence
+func (g *GlossaryOccurrence) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == "" {
+		g.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Goal metrics
+const (
+	GoalMetricNotesWritten   = "notes_written"   // Notes created, optionally in a notebook
+	GoalMetricTasksCompleted = "tasks_completed" // Tasks completed, optionally on a board
+	GoalMetricWords          = "words"           // Words written, from writing activity
+	GoalMetricCustom         = "custom"          // Progress is updated by hand
+)
+
+// GoalMetrics lists every goal metric
+var GoalMetrics = []string{GoalMetricNotesWritten, GoalMetricTasksCompleted, GoalMetricWords, GoalMetricCustom}
+
+// Goal periods
+const (
+	GoalPeriodWeekly  = "weekly"  // Progress restarts every Monday
+	GoalPeriodMonthly = "monthly" // Progress restarts on the first of the month
+	GoalPeriodOnce    = "once"    // Progress counts from the goal's creation until its due date
+)
+
+// GoalPeriods lists every goal period
+var GoalPeriods = []string{GoalPeriodWeekly, GoalPeriodMonthly, GoalPeriodOnce}
+
+// Goal is a target a user sets for themselves, or an organization for its members, with progress
+// computed from their notes, tasks and writing activity
+type Goal struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	Title          string     `json:"title" gorm:"type:varchar(200);not null"`
+	Metric         string     `json:"metric" gorm:"type:varchar(20);not null"`
+	Target         int        `json:"target" gorm:"not null"`
+	Period         string     `json:"period" gorm:"type:varchar(10);not null;default:'weekly'"`
+	NotebookID     *string    `json:"notebookId,omitempty" gorm:"type:varchar(255);index"`  // Notes written goals can count a single notebook
+	TaskBoardID    *string    `json:"taskBoardId,omitempty" gorm:"type:varchar(255);index"` // Tasks completed goals can count a single board
+	CustomProgress int        `json:"customProgress"`                                       // Progress of custom goals
+	DueDate        *time.Time `json:"dueDate,omitempty"`
+	ClerkUserID    string     `json:"clerkUserId" gorm:"type:varchar(255);not null;index"` // Owner of personal goals, creator of organization goals
+	OrganizationID *string    `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	Notebook       *Notebook  `json:"-" gorm:"foreignKey:NotebookID;constraint:OnDelete:CASCADE"`
+	TaskBoard      *TaskBoard `json:"-" gorm:"foreignKey:TaskBoardID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a goal
+func (g *Goal) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == "" {
+		g.ID = cuid.New()
+	}
+	return nil
+}
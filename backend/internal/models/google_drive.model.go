@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// GoogleDriveConnection stores a user's Google Drive authorization
+type GoogleDriveConnection struct {
+	ID                string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID       string    `json:"clerkUserId" gorm:"uniqueIndex;not null"`
+	Email             string    `json:"email"`
+	OAuthRefreshToken string    `json:"-" gorm:"column:oauth_refresh_token;type:text"` // Encrypted
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+func (gdc *GoogleDriveConnection) BeforeCreate(tx *gorm.DB) error {
+	if gdc.ID == "" {
+		gdc.ID = cuid.New()
+	}
+	return nil
+}
+
+// Google Doc import statuses
+const (
+	GoogleDocImportStatusSynced  = "synced"
+	GoogleDocImportStatusMissing = "missing"
+)
+
+// GoogleDocImport maps an imported Google Doc to the note it was imported into
+type GoogleDocImport struct {
+	ID                string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID       string    `json:"clerkUserId" gorm:"not null;uniqueIndex:idx_google_doc_imports_user_file"`
+	DriveFileID       string    `json:"driveFileId" gorm:"not null;uniqueIndex:idx_google_doc_imports_user_file"`
+	NoteID            string    `json:"noteId" gorm:"type:varchar(255);not null;index"`
+	Name              string    `json:"name"`
+	WebViewLink       string    `json:"webViewLink"`
+	Status            string    `json:"status" gorm:"default:'synced'"`
+	DriveModifiedTime time.Time `json:"driveModifiedTime"`
+	LastSyncedAt      time.Time `json:"lastSyncedAt"`
+	Note              *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+func (gdi *GoogleDocImport) BeforeCreate(tx *gorm.DB) error {
+	if gdi.ID == "" {
+		gdi.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Iteration is a sprint on an organization's task board. Tasks still open when it closes roll over to
+// the next iteration.
+type Iteration struct {
+	ID                string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	TaskBoardID       string     `json:"taskBoardId" gorm:"type:varchar(255);not null;index"`
+	OrganizationID    *string    `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	Name              string     `json:"name" gorm:"type:varchar(100);not null"`
+	StartDate         time.Time  `json:"startDate" gorm:"not null"`
+	EndDate           time.Time  `json:"endDate" gorm:"not null;index"` // Last day of the iteration
+	CreatedBy         string     `json:"createdBy" gorm:"type:varchar(255)"`
+	ClosedAt          *time.Time `json:"closedAt,omitempty" gorm:"index"`
+	RolledOverTo      *string    `json:"rolledOverTo,omitempty" gorm:"type:varchar(255)"` // Iteration the open tasks moved to, nil for the backlog
+	RolledOverTaskIDs string     `json:"-" gorm:"type:text"`                              // Comma-separated tasks that were open when it closed
+	TaskBoard         *TaskBoard `json:"-" gorm:"foreignKey:TaskBoardID;constraint:OnDelete:CASCADE"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating an iteration
+func (i *Iteration) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = cuid.New()
+	}
+	return nil
+}
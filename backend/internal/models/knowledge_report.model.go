@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Knowledge report statuses
+const (
+	KnowledgeReportStatusPending   = "pending"
+	KnowledgeReportStatusRunning   = "running"
+	KnowledgeReportStatusCompleted = "completed"
+	KnowledgeReportStatusFailed    = "failed"
+)
+
+// KnowledgeReport is an AI analysis of an organization's notebooks and recent meetings that
+// finds undocumented topics and contradictory notes. The findings are written to NoteID.
+type KnowledgeReport struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	OrganizationID string     `json:"organizationId" gorm:"type:varchar(255);not null;index"`
+	RequestedBy    string     `json:"requestedBy" gorm:"type:varchar(255);not null"`
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	Days           int        `json:"days" gorm:"not null;default:30"` // Meeting notes from this many days are analyzed
+	NoteID         *string    `json:"noteId,omitempty" gorm:"type:varchar(255)"`
+	ChapterID      *string    `json:"chapterId,omitempty" gorm:"type:varchar(255)"`
+	NotebookID     *string    `json:"notebookId,omitempty" gorm:"type:varchar(255)"`
+	TopicsFound    int        `json:"topicsFound"`
+	Contradictions int        `json:"contradictions"`
+	Error          string     `json:"error,omitempty" gorm:"type:text"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+func (r *KnowledgeReport) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = cuid.New()
+	}
+	return nil
+}
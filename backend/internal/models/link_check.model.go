@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Kinds of problems the link checker finds in a notebook's notes
+const (
+	LinkIssueBrokenURL          = "broken_url"           // An external URL answers with an error or can't be reached
+	LinkIssueRedirectedURL      = "redirected_url"       // An external URL moved permanently
+	LinkIssueMissingNote        = "missing_note"         // A note link points at a deleted note
+	LinkIssueUnresolvedWikiLink = "unresolved_wiki_link" // A [[wiki link]] matches no note of the notebook
+)
+
+// LinkCheck records when a notebook's links were last checked
+type LinkCheck struct {
+	NotebookID   string     `json:"notebookId" gorm:"primaryKey;type:varchar(255)"`
+	Notebook     *Notebook  `json:"-" gorm:"foreignKey:NotebookID;constraint:OnDelete:CASCADE"`
+	CheckedAt    *time.Time `json:"checkedAt,omitempty"`
+	NotesChecked int        `json:"notesChecked"`
+	URLsChecked  int        `json:"urlsChecked"`
+	LastError    string     `json:"lastError,omitempty" gorm:"type:text"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// LinkCheckIssue is a broken, moved or stale link found in a note by the last check of its notebook
+type LinkCheckIssue struct {
+	ID              string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NotebookID      string    `json:"notebookId" gorm:"type:varchar(255);not null;index"`
+	NoteID          string    `json:"noteId" gorm:"type:varchar(255);not null;index"` // The note the link is in
+	Kind            string    `json:"kind" gorm:"type:varchar(30);not null"`
+	URL             string    `json:"url,omitempty" gorm:"type:text"`
+	StatusCode      int       `json:"statusCode,omitempty"`                      // 0 when the page couldn't be reached
+	RedirectURL     string    `json:"redirectUrl,omitempty" gorm:"type:text"`    // Where a moved URL points now
+	LinkID          string    `json:"linkId,omitempty" gorm:"type:varchar(255)"` // The note link pointing at a deleted note
+	TargetNoteID    string    `json:"targetNoteId,omitempty" gorm:"type:varchar(255)"`
+	WikiTarget      string    `json:"wikiTarget,omitempty" gorm:"type:varchar(500)"`
+	RestoreSnapshot string    `json:"restoreSnapshot,omitempty" gorm:"type:varchar(100)"` // Snapshot of the notebook the missing note can be restored from
+	Message         string    `json:"message" gorm:"type:text"`
+	Notebook        *Notebook `json:"-" gorm:"foreignKey:NotebookID;constraint:OnDelete:CASCADE"`
+	Note            *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a link check issue
+func (i *LinkCheckIssue) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Link suggestion statuses
+const (
+	LinkSuggestionPending   = "pending"
+	LinkSuggestionAccepted  = "accepted"
+	LinkSuggestionDismissed = "dismissed"
+)
+
+// LinkSuggestion is a link between two notes suggested to the owner of the source note for review.
+// Suggestions stay after they're reviewed, so a dismissed pair of notes isn't suggested again.
+type LinkSuggestion struct {
+	ID              string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID     string     `json:"clerkUserId" gorm:"type:varchar(255);not null;index"` // Who reviews the suggestion
+	SourceNoteID    string     `json:"sourceNoteId" gorm:"type:varchar(255);not null;uniqueIndex:idx_link_suggestions_pair"`
+	TargetNoteID    string     `json:"targetNoteId" gorm:"type:varchar(255);not null;uniqueIndex:idx_link_suggestions_pair;index"`
+	OrganizationID  *string    `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	SharedAttendees string     `json:"-" gorm:"type:text"` // Newline-separated
+	SharedTopics    string     `json:"-" gorm:"type:text"` // Newline-separated
+	Score           float64    `json:"score"`              // 0 to 1, how much the meetings overlap
+	Status          string     `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	LinkID          *string    `json:"linkId,omitempty" gorm:"type:varchar(255)"` // The link made when the suggestion was accepted
+	ReviewedAt      *time.Time `json:"reviewedAt,omitempty"`
+	SourceNote      *Notes     `json:"-" gorm:"foreignKey:SourceNoteID;constraint:OnDelete:CASCADE"`
+	TargetNote      *Notes     `json:"-" gorm:"foreignKey:TargetNoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a link suggestion
+func (l *LinkSuggestion) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = cuid.New()
+	}
+	return nil
+}
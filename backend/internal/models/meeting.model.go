@@ -8,19 +8,23 @@ import (
 )
 
 type MeetingRecording struct {
-	ID                    string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
-	ClerkUserID           string     `json:"clerkUserId" gorm:"not null;index"`
-	BotID                 string     `json:"botId" gorm:"uniqueIndex;not null"`
-	MeetingURL            string     `json:"meetingUrl" gorm:"not null"`
-	Status                string     `json:"status" gorm:"default:'pending'"` // pending, recording, processing, completed, failed
-	RecallRecordingID     string     `json:"recallRecordingId,omitempty"`
-	TranscriptDownloadURL string     `json:"transcriptDownloadUrl,omitempty"`
-	VideoDownloadURL      string     `json:"videoDownloadUrl,omitempty"`
-	GeneratedNoteID       *string    `json:"generatedNoteId,omitempty" gorm:"type:varchar(255)"`
-	GeneratedNote         *Notes     `json:"generatedNote,omitempty" gorm:"foreignKey:GeneratedNoteID;references:ID"`
-	CreatedAt             time.Time  `json:"createdAt"`
-	UpdatedAt             time.Time  `json:"updatedAt"`
-	CompletedAt           *time.Time `json:"completedAt,omitempty"`
+	ID                    string               `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID           string               `json:"clerkUserId" gorm:"not null;index"`
+	BotID                 string               `json:"botId" gorm:"uniqueIndex;not null"`
+	MeetingURL            string               `json:"meetingUrl" gorm:"not null"`
+	Status                string               `json:"status" gorm:"default:'pending'"` // pending, recording, processing, completed, failed
+	RecallRecordingID     string               `json:"recallRecordingId,omitempty"`
+	TranscriptDownloadURL string               `json:"transcriptDownloadUrl,omitempty"`
+	VideoDownloadURL      string               `json:"videoDownloadUrl,omitempty"`
+	VideoArchivePath      string               `json:"-"` // Archived copy of the video, relative to the archive directory
+	VideoArchivedAt       *time.Time           `json:"videoArchivedAt,omitempty"`
+	TranscriptionProvider string               `json:"transcriptionProvider,omitempty" gorm:"type:varchar(20)"` // Empty for Recall.ai
+	GeneratedNoteID       *string              `json:"generatedNoteId,omitempty" gorm:"type:varchar(255)"`
+	GeneratedNote         *Notes               `json:"generatedNote,omitempty" gorm:"foreignKey:GeneratedNoteID;references:ID"`
+	Redaction             *TranscriptRedaction `json:"redaction,omitempty" gorm:"foreignKey:MeetingRecordingID;constraint:OnDelete:CASCADE"`
+	CreatedAt             time.Time            `json:"createdAt"`
+	UpdatedAt             time.Time            `json:"updatedAt"`
+	CompletedAt           *time.Time           `json:"completedAt,omitempty"`
 }
 
 // BeforeCreate hook to generate CUID before creating a meeting recording
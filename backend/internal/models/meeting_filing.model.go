@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Meeting filing suggestion statuses
+const (
+	MeetingFilingPending   = "pending"
+	MeetingFilingAccepted  = "accepted"
+	MeetingFilingDismissed = "dismissed"
+	MeetingFilingApplied   = "applied" // Filed without review, the owner files meeting notes automatically
+)
+
+// MeetingFilingSuggestion suggests moving a generated meeting note into the project chapter its topics
+// match. Suggestions stay after they're reviewed, so a note isn't suggested again.
+type MeetingFilingSuggestion struct {
+	ID            string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID   string     `json:"clerkUserId" gorm:"type:varchar(255);not null;index"` // Who reviews the suggestion
+	NoteID        string     `json:"noteId" gorm:"type:varchar(255);not null;uniqueIndex"`
+	FromChapterID string     `json:"fromChapterId" gorm:"type:varchar(255);not null"` // Where the note was generated
+	ChapterID     string     `json:"chapterId" gorm:"type:varchar(255);not null;index"`
+	MatchedTerms  string     `json:"-" gorm:"type:text"` // Newline-separated
+	Score         float64    `json:"score"`              // 0 to 1, how well the chapter matches the meeting
+	Status        string     `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	ReviewedAt    *time.Time `json:"reviewedAt,omitempty"`
+	Note          *Notes     `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	Chapter       *Chapter   `json:"-" gorm:"foreignKey:ChapterID;constraint:OnDelete:CASCADE"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a meeting filing suggestion
+func (m *MeetingFilingSuggestion) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// MeetingSeries is a user's recurring calendar meeting. Its occurrences are the calendar events that
+// share its iCalendar UID, and each recorded one adds a dated section to the rolling note.
+type MeetingSeries struct {
+	ID            string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID   string    `json:"clerkUserId" gorm:"type:varchar(255);not null;uniqueIndex:idx_meeting_series_uid"`
+	ICalUID       string    `json:"iCalUid" gorm:"type:varchar(255);not null;uniqueIndex:idx_meeting_series_uid"`
+	Title         string    `json:"title"`
+	RollingNoteID *string   `json:"rollingNoteId,omitempty" gorm:"type:varchar(255)"`
+	RollingNote   *Notes    `json:"-" gorm:"foreignKey:RollingNoteID;constraint:OnDelete:SET NULL"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// TableName keeps the table name from being pluralized again
+func (MeetingSeries) TableName() string {
+	return "meeting_series"
+}
+
+// BeforeCreate hook to generate CUID before creating a meeting series
+func (s *MeetingSeries) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// What a moderation policy does with content matching a category
+const (
+	ModerationActionOff   = "off"   // Don't scan for the category
+	ModerationActionFlag  = "flag"  // Save the content and add a finding to the review queue
+	ModerationActionBlock = "block" // Reject the content
+)
+
+// ModerationActions lists every moderation policy action
+var ModerationActions = []string{ModerationActionOff, ModerationActionFlag, ModerationActionBlock}
+
+// Categories of sensitive content
+const (
+	ModerationCategorySecret = "secret" // API keys, tokens, private keys and passwords
+	ModerationCategoryPII    = "pii"    // Personal data such as emails, phone numbers and card numbers
+)
+
+// Where moderated content was written
+const (
+	ModerationSourceNote    = "note"
+	ModerationSourceChat    = "chat"
+	ModerationSourcePublish = "publish" // Published despite the pre-publish scan
+)
+
+// Who detected a finding
+const (
+	ModerationDetectorPattern    = "pattern"
+	ModerationDetectorClassifier = "classifier"
+)
+
+// Moderation finding statuses
+const (
+	ModerationFindingOpen      = "open"
+	ModerationFindingResolved  = "resolved"  // The content was cleaned up
+	ModerationFindingDismissed = "dismissed" // Not sensitive, or acceptable where it is
+)
+
+// ModerationFindingStatuses lists every moderation finding status
+var ModerationFindingStatuses = []string{ModerationFindingOpen, ModerationFindingResolved, ModerationFindingDismissed}
+
+// OrganizationModerationPolicy controls how an organization's notes and assistant chats are scanned for
+// secrets and personal data. Organizations without a policy aren't scanned.
+type OrganizationModerationPolicy struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID string    `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	SecretsAction  string    `json:"secretsAction" gorm:"type:varchar(20);not null;default:'off'"`
+	PIIAction      string    `json:"piiAction" gorm:"type:varchar(20);not null;default:'off'"`
+	AIClassifier   bool      `json:"aiClassifier" gorm:"default:false"` // Also ask the background AI model, its findings are only flagged
+	UpdatedBy      string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// ModerationFinding is sensitive content found in an organization's notes or chats, waiting for an admin's review.
+// The matched value itself is never stored, only a masked excerpt and a fingerprint to avoid duplicates.
+type ModerationFinding struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	OrganizationID string     `json:"organizationId" gorm:"type:varchar(255);not null;index"`
+	Source         string     `json:"source" gorm:"type:varchar(20);not null"`
+	NoteID         *string    `json:"noteId,omitempty" gorm:"type:varchar(255);index"`
+	ClerkUserID    string     `json:"clerkUserId" gorm:"type:varchar(255);not null"` // Who wrote the content
+	Category       string     `json:"category" gorm:"type:varchar(20);not null"`
+	Kind           string     `json:"kind" gorm:"type:varchar(50);not null"` // e.g. aws_access_key, email
+	Detector       string     `json:"detector" gorm:"type:varchar(20);not null"`
+	Excerpt        string     `json:"excerpt" gorm:"type:text"`
+	Fingerprint    string     `json:"-" gorm:"type:varchar(64);index"`
+	Blocked        bool       `json:"blocked" gorm:"default:false"` // The content was rejected rather than saved
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;default:'open';index"`
+	ReviewedBy     string     `json:"reviewedBy,omitempty" gorm:"type:varchar(255)"`
+	ReviewedAt     *time.Time `json:"reviewedAt,omitempty"`
+	Note           *Notes     `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+func (f *ModerationFinding) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == "" {
+		f.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Note access actions
+const (
+	NoteAccessView = "view"
+	NoteAccessEdit = "edit"
+)
+
+// NoteAccessEvent records a user viewing or editing a note, used for content analytics.
+// Repeated access by the same user within a short window is recorded once.
+type NoteAccessEvent struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID         string    `json:"noteId" gorm:"type:varchar(255);not null;index:idx_note_access_events_note_action"`
+	ClerkUserID    string    `json:"clerkUserId" gorm:"type:varchar(255);not null;index"`
+	OrganizationID *string   `json:"organizationId,omitempty" gorm:"type:varchar(255);index:idx_note_access_events_org_created"`
+	Action         string    `json:"action" gorm:"type:varchar(20);not null;index:idx_note_access_events_note_action"`
+	Note           *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"index:idx_note_access_events_org_created"`
+}
+
+func (e *NoteAccessEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// NoteAnnotation is a highlighted passage of a note with an optional margin note. Annotations are kept
+// apart from the note's content; the quoted text finds the passage again after the note is edited.
+type NoteAnnotation struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID      string    `json:"noteId" gorm:"type:varchar(255);not null;index"`
+	ClerkUserID string    `json:"clerkUserId,omitempty" gorm:"type:varchar(255);not null"` // Who annotated the passage
+	From        int       `json:"from"`                                                    // ProseMirror position where the passage starts
+	To          int       `json:"to"`                                                      // ProseMirror position where the passage ends
+	Quote       string    `json:"quote" gorm:"type:text;not null"`                         // The highlighted text
+	Comment     string    `json:"comment" gorm:"type:text"`                                // The margin note
+	Color       string    `json:"color" gorm:"type:varchar(20);not null;default:'yellow'"`
+	Note        *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ValidAnnotationColors are the highlight colors annotations can have
+var ValidAnnotationColors = map[string]bool{
+	"yellow": true,
+	"green":  true,
+	"blue":   true,
+	"pink":   true,
+	"purple": true,
+}
+
+// BeforeCreate hook to generate CUID before creating a note annotation
+func (a *NoteAnnotation) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = cuid.New()
+	}
+	return nil
+}
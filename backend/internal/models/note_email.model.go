@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Note email statuses
+const (
+	NoteEmailStatusSent   = "sent"
+	NoteEmailStatusFailed = "failed"
+)
+
+// NoteEmail is the audit record of a note emailed to its recipients, kept whether or not the SMTP
+// server accepted it. It also counts towards the sender's email rate limits.
+type NoteEmail struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID         string    `json:"noteId" gorm:"type:varchar(255);not null;index"`
+	SentBy         string    `json:"sentBy" gorm:"type:varchar(255);not null;index:idx_note_email_sender"`
+	Recipients     string    `json:"recipients" gorm:"type:text;not null"` // Comma separated addresses
+	RecipientCount int       `json:"recipientCount"`
+	Subject        string    `json:"subject" gorm:"type:varchar(255)"`
+	Message        string    `json:"message" gorm:"type:text"`
+	Attachments    string    `json:"attachments" gorm:"type:varchar(100)"` // Comma separated: pdf, docx, markdown
+	NoteUpdatedAt  time.Time `json:"noteUpdatedAt"`                        // Version of the note that was sent
+	Size           int       `json:"size"`                                 // Bytes
+	Status         string    `json:"status" gorm:"type:varchar(20);not null"`
+	Error          string    `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"index:idx_note_email_sender"`
+}
+
+func (e *NoteEmail) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = cuid.New()
+	}
+	return nil
+}
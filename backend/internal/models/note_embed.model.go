@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// NoteEmbed records that a note (the source) transcludes a live block of another note (the target).
+// Rows are derived from the noteEmbed nodes in the source note's content.
+type NoteEmbed struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	SourceNoteID   string    `json:"sourceNoteId" gorm:"type:varchar(255);not null;uniqueIndex:idx_note_embeds_source_target_block"`
+	TargetNoteID   string    `json:"targetNoteId" gorm:"type:varchar(255);not null;index;uniqueIndex:idx_note_embeds_source_target_block"`
+	BlockID        string    `json:"blockId" gorm:"type:varchar(255);not null;default:'';uniqueIndex:idx_note_embeds_source_target_block"` // Empty embeds the whole note
+	OrganizationID *string   `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	CreatedBy      string    `json:"createdBy" gorm:"type:varchar(255);not null"`
+	SourceNote     *Notes    `json:"sourceNote,omitempty" gorm:"foreignKey:SourceNoteID;constraint:OnDelete:CASCADE"`
+	TargetNote     *Notes    `json:"targetNote,omitempty" gorm:"foreignKey:TargetNoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the table name for NoteEmbed
+func (NoteEmbed) TableName() string {
+	return "note_embeds"
+}
+
+// NoteEmbedNodeType is the TipTap node type of a transcluded block
+const NoteEmbedNodeType = "noteEmbed"
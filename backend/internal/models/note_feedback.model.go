@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Reactions readers can leave on public notes
+const (
+	NoteReactionThumbsUp   = "👍"
+	NoteReactionThumbsDown = "👎"
+	NoteReactionHeart      = "❤️"
+	NoteReactionParty      = "🎉"
+	NoteReactionThinking   = "🤔"
+)
+
+// NoteReactions lists every reaction readers can leave
+var NoteReactions = []string{NoteReactionThumbsUp, NoteReactionThumbsDown, NoteReactionHeart, NoteReactionParty, NoteReactionThinking}
+
+// NoteFeedback is an anonymous reaction and/or short comment a reader left on a public note
+type NoteFeedback struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	NoteID     string    `json:"noteId" gorm:"not null;index;type:varchar(255)"`
+	Reaction   string    `json:"reaction,omitempty" gorm:"type:varchar(16)"`
+	Comment    string    `json:"comment,omitempty" gorm:"type:text"`
+	ReaderHash string    `json:"-" gorm:"type:varchar(64);index"` // Hash of the reader's IP and the note, to limit feedback per reader
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -10,6 +12,9 @@ type NoteLink struct {
 	SourceNoteID   string    `json:"sourceNoteId" gorm:"type:varchar(255);not null;index"`
 	TargetNoteID   string    `json:"targetNoteId" gorm:"type:varchar(255);not null;index"`
 	LinkType       string    `json:"linkType" gorm:"type:varchar(50);default:'references'"`
+	Annotation     string    `json:"annotation,omitempty" gorm:"type:text"`                           // Why the notes are linked, shown on the link
+	BlockRef       string    `json:"blockRef,omitempty" gorm:"type:varchar(255);not null;default:''"` // Block of the target note the link points at, empty for the whole note
+	Origin         string    `json:"origin" gorm:"type:varchar(20);not null;default:'manual';index"`  // How the link was made, see LinkOrigin constants
 	OrganizationID *string   `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
 	CreatedBy      string    `json:"createdBy" gorm:"type:varchar(255);not null;index"`
 	SourceNote     *Notes    `json:"sourceNote,omitempty" gorm:"foreignKey:SourceNoteID"`
@@ -30,8 +35,15 @@ const (
 	LinkTypeContradicts  = "contradicts"
 	LinkTypeRelated      = "related"
 	LinkTypePrerequisite = "prerequisite"
+	LinkTypeSource       = "source" // The target note is where the source note's content comes from
 )
 
+// linkTypeAliases are other names clients may use for link types
+var linkTypeAliases = map[string]string{
+	"relates-to": LinkTypeRelated,
+	"depends-on": LinkTypePrerequisite,
+}
+
 // ValidLinkTypes returns a list of valid link types
 func ValidLinkTypes() []string {
 	return []string{
@@ -40,5 +52,34 @@ func ValidLinkTypes() []string {
 		LinkTypeContradicts,
 		LinkTypeRelated,
 		LinkTypePrerequisite,
+		LinkTypeSource,
+	}
+}
+
+// NormalizeLinkType returns the link type a name stands for, resolving aliases, and whether it's valid
+func NormalizeLinkType(linkType string) (string, bool) {
+	linkType = strings.ToLower(strings.TrimSpace(linkType))
+	if alias, ok := linkTypeAliases[linkType]; ok {
+		linkType = alias
+	}
+	return linkType, slices.Contains(ValidLinkTypes(), linkType)
+}
+
+// LinkOrigin constants tell links made by hand from those kept in step with [[wiki links]] in a note's
+// content, those suggested by AI features and those kept in step with the glossary terms a note uses
+const (
+	LinkOriginManual   = "manual"
+	LinkOriginWiki     = "wiki"
+	LinkOriginAI       = "ai"
+	LinkOriginGlossary = "glossary"
+)
+
+// ValidLinkOrigins returns a list of valid link origins
+func ValidLinkOrigins() []string {
+	return []string{
+		LinkOriginManual,
+		LinkOriginWiki,
+		LinkOriginAI,
+		LinkOriginGlossary,
 	}
 }
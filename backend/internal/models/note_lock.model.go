@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// NoteLock holds the content of a password-locked note, encrypted with AES-GCM under a key derived from the
+// owner's passphrase. The note's own content is empty while it is locked.
+type NoteLock struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	NoteID     string    `json:"noteId" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Salt       string    `json:"-" gorm:"type:varchar(64);not null"` // Base64 PBKDF2 salt
+	Iterations int       `json:"-" gorm:"not null"`                  // PBKDF2 iterations the key was derived with
+	Nonce      string    `json:"-" gorm:"type:varchar(64);not null"` // Base64 AES-GCM nonce
+	Ciphertext string    `json:"-" gorm:"type:text;not null"`        // Base64 encrypted content
+	LockedBy   string    `json:"lockedBy" gorm:"type:varchar(255)"`
+	Note       *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// NoteProperty is a typed key-value field on a note, like frontmatter metadata
+type NoteProperty struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID         string     `json:"noteId" gorm:"type:varchar(255);not null;uniqueIndex:idx_note_properties_note_key"`
+	Key            string     `json:"key" gorm:"type:varchar(100);not null;uniqueIndex:idx_note_properties_note_key;index"`
+	Type           string     `json:"type" gorm:"type:varchar(20);not null"` // "text", "date", "number", "select", "person"
+	Value          string     `json:"value" gorm:"type:text"`
+	NumberValue    *float64   `json:"-"` // Set for number properties, for range filters
+	DateValue      *time.Time `json:"-"` // Set for date properties, for range filters
+	OrganizationID *string    `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	Note           *Notes     `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a note property
+func (np *NoteProperty) BeforeCreate(tx *gorm.DB) error {
+	if np.ID == "" {
+		np.ID = cuid.New()
+	}
+	return nil
+}
+
+// Note property types
+const (
+	NotePropertyText   = "text"
+	NotePropertyDate   = "date"
+	NotePropertyNumber = "number"
+	NotePropertySelect = "select"
+	NotePropertyPerson = "person"
+)
+
+// NotePropertyTypes lists the valid note property types
+var NotePropertyTypes = []string{
+	NotePropertyText,
+	NotePropertyDate,
+	NotePropertyNumber,
+	NotePropertySelect,
+	NotePropertyPerson,
+}
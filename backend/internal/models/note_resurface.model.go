@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// NoteFavorite marks a note a user wants to see more often in review mode
+type NoteFavorite struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID      string    `json:"noteId" gorm:"type:varchar(255);not null;uniqueIndex:idx_note_favorites_note_user"`
+	ClerkUserID string    `json:"clerkUserId" gorm:"type:varchar(255);not null;uniqueIndex:idx_note_favorites_note_user;index"`
+	Note        *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (f *NoteFavorite) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == "" {
+		f.ID = cuid.New()
+	}
+	return nil
+}
+
+// NoteResurfaceEvent records a user finishing the review of a note resurfaced by review mode
+type NoteResurfaceEvent struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID      string    `json:"noteId" gorm:"type:varchar(255);not null;index:idx_note_resurface_events_user_note"`
+	ClerkUserID string    `json:"clerkUserId" gorm:"type:varchar(255);not null;index:idx_note_resurface_events_user_note"`
+	Note        *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (e *NoteResurfaceEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Note lifecycle statuses
+const (
+	NoteStatusDraft    = "draft"
+	NoteStatusInReview = "in_review"
+	NoteStatusApproved = "approved"
+	NoteStatusArchived = "archived"
+)
+
+// NoteStatuses lists every note lifecycle status
+var NoteStatuses = []string{NoteStatusDraft, NoteStatusInReview, NoteStatusApproved, NoteStatusArchived}
+
+// Note review decisions
+const (
+	NoteReviewPending   = "pending"
+	NoteReviewApproved  = "approved"
+	NoteReviewRejected  = "rejected"
+	NoteReviewWithdrawn = "withdrawn" // The note left review without a decision
+)
+
+// NoteReview is a request to review an organization note and the reviewer's decision
+type NoteReview struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID         string     `json:"noteId" gorm:"type:varchar(255);not null;index"`
+	OrganizationID string     `json:"organizationId" gorm:"type:varchar(255);not null;index"`
+	RequestedBy    string     `json:"requestedBy" gorm:"type:varchar(255);not null"`
+	RequestComment string     `json:"requestComment,omitempty" gorm:"type:text"`
+	Decision       string     `json:"decision" gorm:"type:varchar(20);not null;default:'pending'"`
+	ReviewedBy     string     `json:"reviewedBy,omitempty" gorm:"type:varchar(255)"`
+	Comment        string     `json:"comment,omitempty" gorm:"type:text"`
+	ReviewedAt     *time.Time `json:"reviewedAt,omitempty"`
+	Note           *Notes     `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+func (r *NoteReview) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = cuid.New()
+	}
+	return nil
+}
+
+// OrganizationPublishingSettings controls what an organization's members can publish.
+// Organizations without settings can publish notes in any status.
+type OrganizationPublishingSettings struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID  string    `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	RequireApproval bool      `json:"requireApproval" gorm:"default:false"` // Only approved notes can be published
+	UpdatedBy       string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Note revision statuses
+const (
+	NoteRevisionPending   = "pending"
+	NoteRevisionPublished = "published"
+	NoteRevisionDiscarded = "discarded"
+)
+
+// NoteRevision holds edits to a published note of an organization that requires approval, kept out of
+// the live version readers see until an editor publishes them. A note has at most one pending
+// revision, which later edits update.
+type NoteRevision struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID         string     `json:"noteId" gorm:"type:varchar(255);not null;index"`
+	OrganizationID string     `json:"organizationId" gorm:"type:varchar(255);not null;index"`
+	Name           string     `json:"name,omitempty"`                     // Empty when the name wasn't changed
+	Content        string     `json:"content,omitempty" gorm:"type:text"` // Empty when the content wasn't changed
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	CreatedBy      string     `json:"createdBy" gorm:"type:varchar(255);not null"`
+	UpdatedBy      string     `json:"updatedBy" gorm:"type:varchar(255);not null"`
+	ClosedBy       string     `json:"closedBy,omitempty" gorm:"type:varchar(255)"` // Who published or discarded it
+	ClosedAt       *time.Time `json:"closedAt,omitempty"`
+	Note           *Notes     `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+func (r *NoteRevision) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = cuid.New()
+	}
+	return nil
+}
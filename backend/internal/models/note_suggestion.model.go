@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Note suggestion statuses
+const (
+	NoteSuggestionPending  = "pending"
+	NoteSuggestionAccepted = "accepted"
+	NoteSuggestionRejected = "rejected"
+)
+
+// NoteSuggestion is an edit a signed-in reader proposed to a public note, waiting in the author's review
+// queue. Accepted suggestions keep the note's content from before they were applied, as its revision.
+type NoteSuggestion struct {
+	ID               string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID           string     `json:"noteId" gorm:"type:varchar(255);not null;index"`
+	NotebookID       string     `json:"notebookId" gorm:"type:varchar(255);not null;index"`
+	SuggestedBy      string     `json:"suggestedBy" gorm:"type:varchar(255);not null;index"`
+	Comment          string     `json:"comment,omitempty" gorm:"type:text"` // The reader's explanation of the change
+	BaseContent      string     `json:"-" gorm:"type:text"`                 // TipTap JSON of the public note the reader edited
+	SuggestedContent string     `json:"-" gorm:"type:text"`                 // TipTap JSON of the reader's version
+	PreviousContent  string     `json:"-" gorm:"type:text"`                 // TipTap JSON of the note before the suggestion was accepted
+	Status           string     `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	ReviewedBy       string     `json:"reviewedBy,omitempty" gorm:"type:varchar(255)"`
+	ReviewComment    string     `json:"reviewComment,omitempty" gorm:"type:text"`
+	ReviewedAt       *time.Time `json:"reviewedAt,omitempty"`
+	Note             *Notes     `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+}
+
+func (s *NoteSuggestion) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = cuid.New()
+	}
+	return nil
+}
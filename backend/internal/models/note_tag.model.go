@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// NoteTag is a #hashtag found in a note's content
+type NoteTag struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID         string    `json:"noteId" gorm:"type:varchar(255);not null;uniqueIndex:idx_note_tags_note_tag"`
+	Tag            string    `json:"tag" gorm:"type:varchar(100);not null;uniqueIndex:idx_note_tags_note_tag;index"`
+	OrganizationID *string   `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	Note           *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a note tag
+func (nt *NoteTag) BeforeCreate(tx *gorm.DB) error {
+	if nt.ID == "" {
+		nt.ID = cuid.New()
+	}
+	return nil
+}
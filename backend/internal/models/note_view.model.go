@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Note view layouts
+const (
+	NoteViewTable    = "table"
+	NoteViewBoard    = "board"    // Notes grouped by a property, like a kanban board
+	NoteViewCalendar = "calendar" // Notes placed by a date property
+)
+
+// NoteViewLayouts lists every note view layout
+var NoteViewLayouts = []string{NoteViewTable, NoteViewBoard, NoteViewCalendar}
+
+// NoteView is a saved, database-like view over the notes of a notebook or one of its chapters
+type NoteView struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	Name           string    `json:"name" gorm:"type:varchar(255);not null"`
+	Layout         string    `json:"layout" gorm:"type:varchar(20);not null"`
+	NotebookID     string    `json:"notebookId" gorm:"type:varchar(255);not null;index"`
+	ChapterID      *string   `json:"chapterId,omitempty" gorm:"type:varchar(255);index"` // Nil for notebook-wide views
+	OrganizationID *string   `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	CreatedBy      string    `json:"createdBy" gorm:"type:varchar(255);not null"`
+	Filters        string    `json:"-" gorm:"type:text"`                              // Newline-separated property filters, e.g. "status = reading"
+	Columns        string    `json:"-" gorm:"type:text"`                              // Comma-separated property keys shown by table views
+	SortBy         string    `json:"sortBy" gorm:"type:varchar(100)"`                 // "name", "created_at", "updated_at" or a property key
+	SortDirection  string    `json:"sortDirection" gorm:"type:varchar(4)"`            // "asc" or "desc"
+	GroupBy        string    `json:"groupBy,omitempty" gorm:"type:varchar(100)"`      // Property key of board columns
+	DateProperty   string    `json:"dateProperty,omitempty" gorm:"type:varchar(100)"` // Date property key of calendar entries
+	Notebook       *Notebook `json:"-" gorm:"foreignKey:NotebookID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a note view
+func (v *NoteView) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == "" {
+		v.ID = cuid.New()
+	}
+	return nil
+}
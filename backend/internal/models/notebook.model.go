@@ -14,8 +14,25 @@ type Notebook struct {
 	OrganizationID *string   `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
 	Chapters       []Chapter `json:"chapters" gorm:"foreignKey:NotebookID"`
 	IsPublic       bool      `json:"isPublic" gorm:"default:false"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
+	// Encrypted notebooks hold client-side encrypted note content the server can't read.
+	// It is turned on and off through the encryption endpoints, which re-upload every note.
+	Encrypted    bool                 `json:"encrypted" gorm:"default:false"`
+	Capabilities NotebookCapabilities `json:"capabilities" gorm:"-"`
+	CreatedAt    time.Time            `json:"createdAt"`
+	UpdatedAt    time.Time            `json:"updatedAt"`
+}
+
+// NotebookCapabilities tells clients which server-side features work on a notebook's notes
+type NotebookCapabilities struct {
+	AI            bool `json:"ai"`            // Assistant tools, generation, task extraction and videos
+	Search        bool `json:"search"`        // Full-text and property search
+	Publish       bool `json:"publish"`       // Public pages
+	Collaboration bool `json:"collaboration"` // Real-time Yjs editing
+}
+
+// CapabilitiesFor returns the features available to a notebook, all of which need readable content
+func CapabilitiesFor(encrypted bool) NotebookCapabilities {
+	return NotebookCapabilities{AI: !encrypted, Search: !encrypted, Publish: !encrypted, Collaboration: !encrypted}
 }
 
 // BeforeCreate hook to generate CUID before creating a notebook
@@ -25,3 +42,15 @@ func (n *Notebook) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// AfterFind sets the capabilities of a loaded notebook
+func (n *Notebook) AfterFind(tx *gorm.DB) error {
+	n.Capabilities = CapabilitiesFor(n.Encrypted)
+	return nil
+}
+
+// AfterSave sets the capabilities of a created or updated notebook
+func (n *Notebook) AfterSave(tx *gorm.DB) error {
+	n.Capabilities = CapabilitiesFor(n.Encrypted)
+	return nil
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// NotebookKeyEnvelope is a notebook's content key wrapped for one user. The client wraps and unwraps it,
+// the server only stores the opaque envelope. Organization notebooks have one envelope per member with access.
+type NotebookKeyEnvelope struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	NotebookID  string    `json:"notebookId" gorm:"type:varchar(255);not null;uniqueIndex:idx_notebook_key_envelopes_notebook_user"`
+	ClerkUserID string    `json:"clerkUserId" gorm:"type:varchar(255);not null;uniqueIndex:idx_notebook_key_envelopes_notebook_user"`
+	Algorithm   string    `json:"algorithm" gorm:"type:varchar(50);not null"` // e.g. AES-GCM-256, as named by the client
+	KeyVersion  int       `json:"keyVersion" gorm:"not null;default:1"`
+	Envelope    string    `json:"envelope" gorm:"type:text;not null"` // Base64 wrapped key
+	CreatedBy   string    `json:"createdBy" gorm:"type:varchar(255)"`
+	Notebook    *Notebook `json:"-" gorm:"foreignKey:NotebookID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// NotebookLanguageVariant is a language a published notebook is offered in. Readers pick one of its
+// languages and see each note in its translation to that language, falling back to another language
+// when there's none.
+type NotebookLanguageVariant struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	NotebookID       string    `json:"notebookId" gorm:"not null;uniqueIndex:idx_notebook_language_variants_language;type:varchar(255)"`
+	Language         string    `json:"language" gorm:"not null;uniqueIndex:idx_notebook_language_variants_language;type:varchar(16)"`
+	IsDefault        bool      `json:"isDefault"`                                // The language the notebook's original notes are written in
+	FallbackLanguage string    `json:"fallbackLanguage" gorm:"type:varchar(16)"` // Tried before the default language for notes without a translation
+	HideUntranslated bool      `json:"hideUntranslated"`                         // Notes without a translation are left out of listings instead of falling back
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// NotebookPrivacySettings controls what a published notebook's public site records about its readers.
+// Notebooks without settings count reads without cookies and without asking for consent.
+type NotebookPrivacySettings struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	NotebookID       string    `json:"notebookId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	AnalyticsEnabled bool      `json:"analyticsEnabled"`
+	Cookieless       bool      `json:"cookieless"`     // Readers are told apart by a hash that changes daily instead of a session cookie
+	RequireConsent   bool      `json:"requireConsent"` // Reads are only counted when the site passes on the reader's consent
+	UpdatedBy        string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// PublicNoteRead records a guest reading a published note. Readers are only known by a session hash, never
+// by their IP or cookie.
+type PublicNoteRead struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	NoteID      string    `json:"noteId" gorm:"not null;index;type:varchar(255)"`
+	NotebookID  string    `json:"notebookId" gorm:"not null;index:idx_public_note_reads_notebook_created;type:varchar(255)"`
+	SessionHash string    `json:"-" gorm:"type:varchar(64)"`
+	Note        *Notes    `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"index:idx_public_note_reads_notebook_created"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// NotebookQASettings lets readers of a published notebook ask questions answered from its public notes,
+// on the public site and through a chat widget on the author's own websites. Notebooks without settings
+// don't answer questions.
+type NotebookQASettings struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	NotebookID    string    `json:"notebookId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	Enabled       bool      `json:"enabled"`
+	WidgetEnabled bool      `json:"widgetEnabled"`
+	WidgetOrigins string    `json:"-" gorm:"type:text"` // Comma-separated origins of the websites the widget is embedded on
+	MonthlyCap    int       `json:"monthlyCap"`         // Questions answered per calendar month (UTC), paid with the author's AI keys
+	UpdatedBy     string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// NotebookQAUsage counts the questions a published notebook answered in a month
+type NotebookQAUsage struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	NotebookID string    `json:"notebookId" gorm:"not null;uniqueIndex:idx_notebook_qa_usage_month;type:varchar(255)"`
+	Month      string    `json:"month" gorm:"not null;uniqueIndex:idx_notebook_qa_usage_month;type:varchar(7)"` // YYYY-MM
+	Questions  int       `json:"questions"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// NotebookSnapshot is a named, read-only copy of a notebook's chapters and notes, like a docs release.
+// The frozen content is stored as JSON in Content.
+type NotebookSnapshot struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NotebookID     string    `json:"notebookId" gorm:"type:varchar(255);not null;uniqueIndex:idx_notebook_snapshots_notebook_name"`
+	Name           string    `json:"name" gorm:"type:varchar(100);not null;uniqueIndex:idx_notebook_snapshots_notebook_name"`
+	Description    string    `json:"description,omitempty" gorm:"type:text"`
+	NotebookName   string    `json:"notebookName"`
+	OrganizationID *string   `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	CreatedBy      string    `json:"createdBy" gorm:"type:varchar(255);not null"`
+	ChapterCount   int       `json:"chapterCount"`
+	NoteCount      int       `json:"noteCount"`
+	PublicNotes    int       `json:"publicNotes"` // Notes that were published when the snapshot was taken
+	Content        string    `json:"-" gorm:"type:text;not null"`
+	Notebook       *Notebook `json:"-" gorm:"foreignKey:NotebookID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func (s *NotebookSnapshot) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = cuid.New()
+	}
+	return nil
+}
@@ -8,27 +8,46 @@ import (
 )
 
 type Notes struct {
-	ID                 string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
-	Name               string     `json:"name"`
-	Content            string     `json:"content" gorm:"type:text"`
-	ChapterID          string     `json:"chapterId" gorm:"type:varchar(255);index"`
-	OrganizationID     *string    `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
-	Chapter            Chapter    `json:"chapter" gorm:"foreignKey:ChapterID"`
-	IsPublic           bool       `json:"isPublic" gorm:"default:false"`
-	VideoData          string     `json:"videoData" gorm:"type:text"`
-	HasVideo           bool       `json:"hasVideo" gorm:"default:false"`
-	MeetingRecordingID *string    `json:"meetingRecordingId,omitempty" gorm:"type:varchar(255)"`
-	AISummary          string     `json:"aiSummary,omitempty" gorm:"type:text"`
-	TranscriptRaw      string     `json:"transcriptRaw,omitempty" gorm:"type:text"`
-	TaskBoard          *TaskBoard `json:"taskBoard,omitempty" gorm:"foreignKey:NoteID"`
-	CreatedAt          time.Time  `json:"createdAt"`
-	UpdatedAt          time.Time  `json:"updatedAt"`
+	ID                 string         `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	Name               string         `json:"name"`
+	Slug               string         `json:"slug" gorm:"type:varchar(255);index"` // Unique within the chapter, follows the name
+	Content            string         `json:"content" gorm:"type:text"`
+	ChapterID          string         `json:"chapterId" gorm:"type:varchar(255);index"`
+	OrganizationID     *string        `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	Chapter            Chapter        `json:"chapter" gorm:"foreignKey:ChapterID"`
+	IsPublic           bool           `json:"isPublic" gorm:"default:false"`
+	Locked             bool           `json:"locked" gorm:"default:false"`                                   // Password locked, the content is in the note's NoteLock
+	Status             string         `json:"status" gorm:"type:varchar(20);not null;default:'draft';index"` // "draft", "in_review", "approved", "archived"
+	VideoData          string         `json:"videoData" gorm:"type:text"`
+	HasVideo           bool           `json:"hasVideo" gorm:"default:false"`
+	MeetingRecordingID *string        `json:"meetingRecordingId,omitempty" gorm:"type:varchar(255)"`
+	AISummary          string         `json:"aiSummary,omitempty" gorm:"type:text"`
+	Summary            string         `json:"summary,omitempty" gorm:"type:text"` // Short AI summary kept up to date with the content, used as the preview
+	SummaryFingerprint string         `json:"-" gorm:"type:varchar(16)"`          // Simhash of the content the summary was written from
+	SummaryUpdatedAt   *time.Time     `json:"summaryUpdatedAt,omitempty"`
+	TranscriptRaw      string         `json:"transcriptRaw,omitempty" gorm:"type:text"`
+	Language           string         `json:"language,omitempty" gorm:"type:varchar(16);not null;default:'';index"` // Detected from the content, ISO 639-1
+	TranslationOfID    *string        `json:"translationOfId,omitempty" gorm:"type:varchar(255);index"`             // The note this note is a translation of
+	TaskBoard          *TaskBoard     `json:"taskBoard,omitempty" gorm:"foreignKey:NoteID"`
+	Properties         []NoteProperty `json:"properties,omitempty" gorm:"foreignKey:NoteID"`
+	PendingRevision    *NoteRevision  `json:"pendingRevision,omitempty" gorm:"-"` // Edits waiting to be published, see NoteRevision
+	CreatedAt          time.Time      `json:"createdAt"`
+	UpdatedAt          time.Time      `json:"updatedAt"`
 }
 
-// BeforeCreate hook to generate CUID before creating a note
+// BeforeCreate hook to generate CUID and a slug unique in the chapter before creating a note
 func (n *Notes) BeforeCreate(tx *gorm.DB) error {
 	if n.ID == "" {
 		n.ID = cuid.New()
 	}
+	source := n.Slug
+	if source == "" {
+		source = n.Name
+	}
+	slug, err := UniqueSlug(tx, "notes", "chapter_id", n.ChapterID, source, n.ID)
+	if err != nil {
+		return err
+	}
+	n.Slug = slug
 	return nil
 }
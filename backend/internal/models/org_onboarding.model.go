@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// OrganizationOnboardingSettings sets up the notebooks members land in when they join an organization:
+// shared notebooks created for the whole organization, and optionally a workspace notebook of their own
+type OrganizationOnboardingSettings struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID  string    `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	SharedNotebooks string    `json:"-" gorm:"type:text"` // JSON notebook templates
+	MemberWorkspace string    `json:"-" gorm:"type:text"` // JSON notebook template, empty for no member workspaces
+	UpdatedBy       string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// OrganizationProvisionedNotebook records a notebook created from an organization's onboarding settings,
+// so joining members don't create it again. It goes away with the notebook.
+type OrganizationProvisionedNotebook struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID string    `json:"organizationId" gorm:"not null;type:varchar(255);uniqueIndex:idx_org_provisioned_notebooks_key"`
+	ProvisionKey   string    `json:"provisionKey" gorm:"not null;type:varchar(255);uniqueIndex:idx_org_provisioned_notebooks_key"` // "shared:<name>" or "member:<clerk user id>"
+	NotebookID     string    `json:"notebookId" gorm:"not null;type:varchar(255);index"`
+	Notebook       Notebook  `json:"-" gorm:"foreignKey:NotebookID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// PDF export statuses
+const (
+	PDFExportStatusPending   = "pending"
+	PDFExportStatusRunning   = "running"
+	PDFExportStatusCompleted = "completed"
+	PDFExportStatusFailed    = "failed"
+)
+
+// NotebookPDFExport is a PDF of a notebook too large to render during the request, generated in the
+// background and kept for a day to be downloaded
+type NotebookPDFExport struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NotebookID  string     `json:"notebookId" gorm:"type:varchar(255);not null;index"`
+	RequestedBy string     `json:"requestedBy" gorm:"type:varchar(255);not null"`
+	Status      string     `json:"status" gorm:"type:varchar(20);not null;default:'pending'"`
+	PageSize    string     `json:"pageSize" gorm:"type:varchar(10);not null"`
+	Cover       bool       `json:"cover"`
+	TOC         bool       `json:"toc"`
+	Pages       int        `json:"pages"`
+	Size        int        `json:"size"` // Bytes
+	PDF         []byte     `json:"-"`
+	Error       string     `json:"error,omitempty" gorm:"type:text"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+func (e *NotebookPDFExport) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Where reading list items come from
+const (
+	ReadingListSourceClip     = "clip"
+	ReadingListSourceBookmark = "bookmark"
+	ReadingListSourceWhatsApp = "whatsapp"
+	ReadingListSourceFeed     = "feed"
+)
+
+// ReadingListItem is a link saved to read later. Clipped items carry the page's text, other items
+// get their title, excerpt and reading time when the page is fetched in the background.
+type ReadingListItem struct {
+	ID             string                 `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID    string                 `json:"clerkUserId" gorm:"type:varchar(255);not null;uniqueIndex:idx_reading_list_user_url"`
+	URL            string                 `json:"url" gorm:"type:varchar(2048);not null;uniqueIndex:idx_reading_list_user_url"`
+	Title          string                 `json:"title" gorm:"type:varchar(300)"`
+	Excerpt        string                 `json:"excerpt" gorm:"type:text"`
+	SiteName       string                 `json:"siteName" gorm:"type:varchar(255)"`
+	ImageURL       string                 `json:"imageUrl" gorm:"type:text"`
+	Content        string                 `json:"content,omitempty" gorm:"type:text"` // The page's text, paragraphs separated by blank lines
+	WordCount      int                    `json:"wordCount"`
+	ReadingMinutes int                    `json:"readingMinutes"` // Estimated, 0 until the page's text is known
+	Source         string                 `json:"source" gorm:"type:varchar(20);not null"`
+	IsRead         bool                   `json:"isRead" gorm:"default:false;index"`
+	ReadAt         *time.Time             `json:"readAt,omitempty"`
+	FetchedAt      *time.Time             `json:"fetchedAt,omitempty"`
+	NoteID         *string                `json:"noteId,omitempty" gorm:"type:varchar(255)"` // The note the item was converted into
+	Note           *Notes                 `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:SET NULL"`
+	Highlights     []ReadingListHighlight `json:"highlights,omitempty" gorm:"foreignKey:ItemID;constraint:OnDelete:CASCADE"`
+	CreatedAt      time.Time              `json:"createdAt"`
+	UpdatedAt      time.Time              `json:"updatedAt"`
+}
+
+// ReadingListHighlight is a passage highlighted in a reading list item, with an optional comment
+type ReadingListHighlight struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ItemID    string    `json:"itemId" gorm:"type:varchar(255);not null;index"`
+	Text      string    `json:"text" gorm:"type:text;not null"`
+	Comment   string    `json:"comment" gorm:"type:text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a reading list item
+func (r *ReadingListItem) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = cuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to generate CUID before creating a reading list highlight
+func (r *ReadingListHighlight) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// OrganizationRedactionPolicy controls what is redacted from its members' meeting transcripts before
+// they are sent to AI providers. Organizations without a policy don't redact.
+type OrganizationRedactionPolicy struct {
+	ID             uint      `json:"-" gorm:"primaryKey"`
+	OrganizationID string    `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	Enabled        bool      `json:"enabled" gorm:"default:false"`
+	RedactNames    bool      `json:"redactNames" gorm:"default:false"`   // Participant names, replaced with speaker labels
+	RedactEmails   bool      `json:"redactEmails" gorm:"default:false"`  // Email addresses
+	RedactNumbers  bool      `json:"redactNumbers" gorm:"default:false"` // Phone, card and account numbers
+	CustomPatterns string    `json:"-" gorm:"type:text"`                 // Newline-separated regular expressions
+	UpdatedBy      string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// TranscriptRedaction reports what was redacted from a meeting's transcript before AI processing. Only
+// counts are kept, never the redacted text.
+type TranscriptRedaction struct {
+	ID                 string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	MeetingRecordingID string    `json:"meetingRecordingId" gorm:"type:varchar(255);not null;uniqueIndex"`
+	OrganizationIDs    string    `json:"organizationIds" gorm:"type:text"` // Comma-separated organizations whose policies applied
+	Names              int       `json:"names"`
+	Emails             int       `json:"emails"`
+	Numbers            int       `json:"numbers"`
+	CustomMatches      int       `json:"customMatches"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a transcript redaction
+func (r *TranscriptRedaction) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = cuid.New()
+	}
+	return nil
+}
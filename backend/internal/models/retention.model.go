@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// OrganizationRetentionPolicy controls how long an organization's content stays active. Organizations
+// without a policy keep everything as it is.
+type OrganizationRetentionPolicy struct {
+	ID                 uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID     string     `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	ArchiveAfterMonths int        `json:"archiveAfterMonths" gorm:"default:0"` // Notes untouched this long are archived, 0 never
+	LastAppliedAt      *time.Time `json:"lastAppliedAt,omitempty"`
+	UpdatedBy          string     `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+}
+
+// NotebookLegalHold keeps a notebook and everything in it from being deleted or moved out of it while
+// the hold is active. Released holds are kept as a record.
+type NotebookLegalHold struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NotebookID     string     `json:"notebookId" gorm:"type:varchar(255);not null;index"`
+	OrganizationID string     `json:"organizationId" gorm:"type:varchar(255);not null;index"`
+	Reason         string     `json:"reason,omitempty" gorm:"type:text"`
+	PlacedBy       string     `json:"placedBy" gorm:"type:varchar(255);not null"`
+	ReleasedBy     string     `json:"releasedBy,omitempty" gorm:"type:varchar(255)"`
+	ReleasedAt     *time.Time `json:"releasedAt,omitempty" gorm:"index"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+func (h *NotebookLegalHold) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == "" {
+		h.ID = cuid.New()
+	}
+	return nil
+}
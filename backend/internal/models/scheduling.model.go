@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Booking request statuses
+const (
+	BookingRequestStatusPending   = "pending"
+	BookingRequestStatusConfirmed = "confirmed"
+	BookingRequestStatusDeclined  = "declined"
+)
+
+// SchedulingLink is a public booking page at /book/:slug where anyone can request a meeting with the
+// user in a free slot of their connected calendars
+type SchedulingLink struct {
+	ID               string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	ClerkUserID      string    `json:"clerkUserId" gorm:"type:varchar(255);not null;index"`
+	Slug             string    `json:"slug" gorm:"type:varchar(100);not null;uniqueIndex"`
+	Title            string    `json:"title" gorm:"not null"`
+	Description      string    `json:"description" gorm:"type:text"`
+	DurationMinutes  int       `json:"durationMinutes" gorm:"not null;default:30"`
+	Timezone         string    `json:"timezone" gorm:"type:varchar(64);not null;default:'UTC'"` // IANA name the day hours are in
+	DayStart         string    `json:"dayStart" gorm:"type:varchar(5);not null;default:'09:00'"`
+	DayEnd           string    `json:"dayEnd" gorm:"type:varchar(5);not null;default:'17:00'"`
+	Weekdays         string    `json:"weekdays" gorm:"type:varchar(20);not null;default:'1,2,3,4,5'"` // Bookable days, 0 is Sunday
+	MinNoticeMinutes int       `json:"minNoticeMinutes" gorm:"not null"`
+	DaysAhead        int       `json:"daysAhead" gorm:"not null;default:14"`
+	Active           bool      `json:"active" gorm:"not null"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a scheduling link
+func (l *SchedulingLink) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = cuid.New()
+	}
+	return nil
+}
+
+// BookingRequest is a meeting requested through a scheduling link, waiting for its owner to confirm it.
+// Pending and confirmed requests hold their slot.
+type BookingRequest struct {
+	ID               string          `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	SchedulingLinkID string          `json:"schedulingLinkId" gorm:"type:varchar(255);not null;index"`
+	SchedulingLink   *SchedulingLink `json:"schedulingLink,omitempty" gorm:"foreignKey:SchedulingLinkID;constraint:OnDelete:CASCADE"`
+	ClerkUserID      string          `json:"clerkUserId" gorm:"type:varchar(255);not null;index"` // Owner of the link
+	Name             string          `json:"name" gorm:"not null"`
+	Email            string          `json:"email" gorm:"not null"`
+	Message          string          `json:"message,omitempty" gorm:"type:text"`
+	StartTime        time.Time       `json:"startTime" gorm:"not null;index"`
+	EndTime          time.Time       `json:"endTime" gorm:"not null"`
+	Status           string          `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	RespondedAt      *time.Time      `json:"respondedAt,omitempty"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	UpdatedAt        time.Time       `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a booking request
+func (r *BookingRequest) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = cuid.New()
+	}
+	return nil
+}
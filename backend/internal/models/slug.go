@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// maxSlugLength is the longest slug generated from a name, in bytes
+const maxSlugLength = 80
+
+// Slugify turns a name into a readable, URL-safe slug. Names without letters or digits become "untitled".
+func Slugify(name string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+			dash = false
+		} else if !dash && builder.Len() > 0 {
+			builder.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := builder.String()
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		for !utf8.ValidString(slug) {
+			slug = slug[:len(slug)-1]
+		}
+	}
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		return "untitled"
+	}
+	return slug
+}
+
+// UniqueSlug returns the slug of name that no other row of the table with the same scope column value uses.
+// Taken slugs get a numeric suffix: kickoff, kickoff-2, kickoff-3.
+func UniqueSlug(tx *gorm.DB, table, scopeColumn, scopeID, name, excludeID string) (string, error) {
+	base := Slugify(name)
+
+	var taken []string
+	if err := tx.Session(&gorm.Session{NewDB: true}).Table(table).
+		Where(scopeColumn+" = ? AND id <> ?", scopeID, excludeID).
+		Where("slug = ? OR slug LIKE ?", base, base+"-%").
+		Pluck("slug", &taken).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch slugs: %w", err)
+	}
+
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
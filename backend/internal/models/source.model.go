@@ -0,0 +1,78 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Source types, which decide how a source is formatted in a bibliography
+const (
+	SourceTypeArticle = "article" // Journal and conference articles
+	SourceTypeBook    = "book"
+	SourceTypeWebpage = "webpage"
+)
+
+// Source is a work cited by a note: a web page, an article found by its DOI or a book found by its ISBN
+type Source struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	NoteID     string     `json:"noteId" gorm:"type:varchar(255);not null;index"`
+	Type       string     `json:"type" gorm:"type:varchar(20);not null"`
+	Title      string     `json:"title" gorm:"type:text;not null"`
+	Authors    string     `json:"-" gorm:"type:text"` // Newline-separated, each "Family, Given" or an organization's name
+	AuthorList []string   `json:"authors" gorm:"-"`   // Authors, in citation order
+	Year       int        `json:"year,omitempty"`     // Zero when the work isn't dated
+	Publisher  string     `json:"publisher,omitempty" gorm:"type:varchar(255)"`
+	Container  string     `json:"container,omitempty" gorm:"type:varchar(500)"` // The journal or website the work appeared in
+	Volume     string     `json:"volume,omitempty" gorm:"type:varchar(50)"`
+	Issue      string     `json:"issue,omitempty" gorm:"type:varchar(50)"`
+	Pages      string     `json:"pages,omitempty" gorm:"type:varchar(50)"`
+	DOI        string     `json:"doi,omitempty" gorm:"column:doi;type:varchar(255);index"`
+	ISBN       string     `json:"isbn,omitempty" gorm:"column:isbn;type:varchar(20);index"`
+	URL        string     `json:"url,omitempty" gorm:"type:text"`
+	AccessedAt *time.Time `json:"accessedAt,omitempty"` // When a web page was read, cited by MLA
+	CreatedBy  string     `json:"createdBy" gorm:"type:varchar(255);not null"`
+	Note       *Notes     `json:"-" gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// ValidSourceTypes are the types a source can have
+var ValidSourceTypes = map[string]bool{
+	SourceTypeArticle: true,
+	SourceTypeBook:    true,
+	SourceTypeWebpage: true,
+}
+
+// BeforeCreate hook to generate CUID before creating a source
+func (s *Source) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = cuid.New()
+	}
+	return nil
+}
+
+// AfterFind sets the author list of a loaded source
+func (s *Source) AfterFind(tx *gorm.DB) error {
+	s.AuthorList = splitSourceAuthors(s.Authors)
+	return nil
+}
+
+// AfterSave sets the author list of a created or updated source
+func (s *Source) AfterSave(tx *gorm.DB) error {
+	s.AuthorList = splitSourceAuthors(s.Authors)
+	return nil
+}
+
+// splitSourceAuthors splits the stored authors of a source
+func splitSourceAuthors(authors string) []string {
+	list := []string{}
+	for _, author := range strings.Split(authors, "\n") {
+		if author = strings.TrimSpace(author); author != "" {
+			list = append(list, author)
+		}
+	}
+	return list
+}
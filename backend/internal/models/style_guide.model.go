@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// OrganizationStyleGuide configures the style checks run on its members' notes. Organizations without a
+// style guide, and personal notes, get the default checks. With EnforceInAI the guide is also given to
+// the assistant, so text it writes for the organization follows it.
+type OrganizationStyleGuide struct {
+	ID               uint      `json:"-" gorm:"primaryKey"`
+	OrganizationID   string    `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	PassiveVoice     bool      `json:"passiveVoice"`
+	MaxSentenceWords int       `json:"maxSentenceWords"`   // Longer sentences are flagged, 0 doesn't check
+	BannedWords      string    `json:"-" gorm:"type:text"` // Newline-separated, "word" or "word => replacement"
+	AISuggestions    bool      `json:"aiSuggestions"`
+	Guidelines       string    `json:"guidelines" gorm:"type:text"`                       // The style guide in the organization's words, followed by AI suggestions
+	DocumentNoteID   *string   `json:"documentNoteId,omitempty" gorm:"type:varchar(255)"` // A note of the organization holding the full style guide
+	EnforceInAI      bool      `json:"enforceInAi"`
+	UpdatedBy        string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
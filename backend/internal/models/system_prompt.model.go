@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// System prompts the app manages
+const (
+	SystemPromptChat = "chat" // Assistant chat
+)
+
+// SystemPromptSeeder is who created the versions seeded from the built-in prompts
+const SystemPromptSeeder = "system"
+
+// SystemPrompt is a version of a system prompt. Versions without an organization belong to the default
+// prompt, seeded from the built-in one; an organization's versions override the default for its
+// workspace. At most one version of each is active. Versions are never edited, only added.
+type SystemPrompt struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Name           string    `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_system_prompts_version"`
+	OrganizationID *string   `json:"organizationId,omitempty" gorm:"type:varchar(255);uniqueIndex:idx_system_prompts_version"`
+	Version        int       `json:"version" gorm:"not null;uniqueIndex:idx_system_prompts_version"`
+	Content        string    `json:"content" gorm:"type:text;not null"` // A text/template
+	Note           string    `json:"note" gorm:"type:varchar(500)"`     // What changed in this version
+	Active         bool      `json:"active" gorm:"default:false"`
+	CreatedBy      string    `json:"createdBy" gorm:"type:varchar(255)"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
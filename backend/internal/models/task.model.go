@@ -8,18 +8,24 @@ import (
 )
 
 type Task struct {
-	ID             string           `json:"id" gorm:"primaryKey;type:varchar(255)"`
-	Title          string           `json:"title"`
-	Description    string           `json:"description" gorm:"type:text"`
-	Status         string           `json:"status" gorm:"default:'backlog'"`  // "backlog", "todo", "in_progress", "done"
-	Priority       string           `json:"priority" gorm:"default:'medium'"` // "low", "medium", "high"
-	TaskBoardID    string           `json:"taskBoardId" gorm:"type:varchar(255);index"`
-	Position       int              `json:"position" gorm:"default:0"`
-	OrganizationID *string          `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
-	TaskBoard      TaskBoard        `json:"taskBoard" gorm:"foreignKey:TaskBoardID"`
-	Assignments    []TaskAssignment `json:"assignments" gorm:"foreignKey:TaskID"`
-	CreatedAt      time.Time        `json:"createdAt"`
-	UpdatedAt      time.Time        `json:"updatedAt"`
+	ID             string            `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	Title          string            `json:"title"`
+	Description    string            `json:"description" gorm:"type:text"`
+	Status         string            `json:"status" gorm:"default:'backlog'"`  // "backlog", "todo", "in_progress", "done"
+	Priority       string            `json:"priority" gorm:"default:'medium'"` // "low", "medium", "high"
+	TaskBoardID    string            `json:"taskBoardId" gorm:"type:varchar(255);index"`
+	Position       int               `json:"position" gorm:"default:0"`
+	OrganizationID *string           `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	BlockRef       string            `json:"blockRef,omitempty" gorm:"type:varchar(255);index"` // Note block the task points at, "<noteId>#<blockId>"
+	StartDate      *time.Time        `json:"startDate,omitempty"`                               // Earliest day work can start, for the timeline
+	DurationDays   int               `json:"durationDays" gorm:"default:1"`                     // Estimated days of work, for the timeline
+	IterationID    *string           `json:"iterationId,omitempty" gorm:"type:varchar(255);index"`
+	TaskBoard      TaskBoard         `json:"taskBoard" gorm:"foreignKey:TaskBoardID"`
+	Assignments    []TaskAssignment  `json:"assignments" gorm:"foreignKey:TaskID"`
+	ExternalLink   *TaskExternalLink `json:"externalLink,omitempty" gorm:"foreignKey:TaskID"`
+	CompletedAt    *time.Time        `json:"completedAt,omitempty" gorm:"index"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
 }
 
 // TaskAssignment represents the many-to-many relationship between tasks and users
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// TaskBoardShare publishes a read-only view of a task board at a secret link, until it expires or is
+// revoked. A board has at most one link; publishing it again replaces the link.
+type TaskBoardShare struct {
+	ID                 string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	TaskBoardID        string     `json:"taskBoardId" gorm:"type:varchar(255);uniqueIndex"`
+	Token              string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	PasswordHash       string     `json:"-" gorm:"type:varchar(255)"` // Base64 PBKDF2-SHA256 of the password, empty without one
+	PasswordSalt       string     `json:"-" gorm:"type:varchar(255)"` // Base64
+	PasswordIterations int        `json:"-"`
+	ExpiresAt          *time.Time `json:"expiresAt,omitempty" gorm:"index"`
+	CreatedBy          string     `json:"createdBy" gorm:"type:varchar(255)"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a task board share
+func (s *TaskBoardShare) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = cuid.New()
+	}
+	return nil
+}
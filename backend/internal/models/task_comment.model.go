@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Task comment sources
+const (
+	TaskCommentSourceLocal = "local"
+)
+
+// TaskComment is a comment on a task. Comments mirrored from an issue tracker have Source set to
+// the provider and ExternalID to the tracker's comment ID; local comments get an ExternalID once pushed.
+type TaskComment struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	TaskID      string    `json:"taskId" gorm:"type:varchar(255);not null;index"`
+	ClerkUserID string    `json:"clerkUserId,omitempty" gorm:"type:varchar(255)"`
+	AuthorName  string    `json:"authorName"`
+	Body        string    `json:"body" gorm:"type:text;not null"`
+	Source      string    `json:"source" gorm:"type:varchar(20);not null;default:'local'"`
+	ExternalID  string    `json:"externalId,omitempty" gorm:"type:varchar(255);index"`
+	Task        *Task     `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (tc *TaskComment) BeforeCreate(tx *gorm.DB) error {
+	if tc.ID == "" {
+		tc.ID = cuid.New()
+	}
+	if tc.Source == "" {
+		tc.Source = TaskCommentSourceLocal
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// TaskDependency records that a task can't start before another task of the same board finishes
+type TaskDependency struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	TaskID      string    `json:"taskId" gorm:"type:varchar(255);not null;uniqueIndex:idx_task_dependencies_task_depends_on"`
+	DependsOnID string    `json:"dependsOnId" gorm:"type:varchar(255);not null;uniqueIndex:idx_task_dependencies_task_depends_on;index"`
+	TaskBoardID string    `json:"taskBoardId" gorm:"type:varchar(255);not null;index"`
+	Task        *Task     `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+	DependsOn   *Task     `json:"-" gorm:"foreignKey:DependsOnID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// BeforeCreate hook to generate CUID before creating a task dependency
+func (td *TaskDependency) BeforeCreate(tx *gorm.DB) error {
+	if td.ID == "" {
+		td.ID = cuid.New()
+	}
+	return nil
+}
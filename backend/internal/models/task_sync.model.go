@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// Task sync providers
+const (
+	TaskSyncProviderJira   = "jira"
+	TaskSyncProviderLinear = "linear"
+)
+
+// Task sync conflict policies, applied when a task changed both here and in the tracker since the last sync
+const (
+	TaskSyncConflictRemoteWins = "remote_wins"
+	TaskSyncConflictLocalWins  = "local_wins"
+	TaskSyncConflictNewestWins = "newest_wins"
+)
+
+// Task sync statuses
+const (
+	TaskSyncStatusSuccess = "success"
+	TaskSyncStatusFailed  = "failed"
+)
+
+// TaskSyncIntegration mirrors a task board with a Jira project or a Linear team
+type TaskSyncIntegration struct {
+	ID                  string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	TaskBoardID         string     `json:"taskBoardId" gorm:"type:varchar(255);not null;uniqueIndex"`
+	OrganizationID      *string    `json:"organizationId,omitempty" gorm:"type:varchar(255);index"`
+	Provider            string     `json:"provider" gorm:"type:varchar(20);not null"`
+	BaseURL             string     `json:"baseUrl"`                     // Jira site URL
+	Email               string     `json:"email"`                       // Jira account email
+	AccessToken         string     `json:"-" gorm:"type:text;not null"` // Encrypted Jira API token or Linear API key
+	ProjectKey          string     `json:"projectKey" gorm:"not null"`  // Jira project key or Linear team ID
+	IssueType           string     `json:"issueType" gorm:"default:'Task'"`
+	ConflictPolicy      string     `json:"conflictPolicy" gorm:"not null;default:'newest_wins'"`
+	SyncIntervalMinutes int        `json:"syncIntervalMinutes" gorm:"default:15"`
+	Enabled             bool       `json:"enabled" gorm:"default:true"`
+	UpdatedBy           string     `json:"updatedBy" gorm:"type:varchar(255)"`
+	LastSyncedAt        *time.Time `json:"lastSyncedAt,omitempty"`
+	LastSyncStatus      string     `json:"lastSyncStatus,omitempty"`
+	LastSyncError       string     `json:"lastSyncError,omitempty" gorm:"type:text"`
+	LastConflicts       int        `json:"lastConflicts"`
+	TaskBoard           *TaskBoard `json:"-" gorm:"foreignKey:TaskBoardID;constraint:OnDelete:CASCADE"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+func (tsi *TaskSyncIntegration) BeforeCreate(tx *gorm.DB) error {
+	if tsi.ID == "" {
+		tsi.ID = cuid.New()
+	}
+	return nil
+}
+
+// TaskExternalLink links a task to the issue it is mirrored with, and records the state both sides
+// agreed on at the last sync so changes can be detected on either side
+type TaskExternalLink struct {
+	ID              string               `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	IntegrationID   string               `json:"integrationId" gorm:"type:varchar(255);not null;uniqueIndex:idx_task_external_links_integration_external"`
+	TaskID          string               `json:"taskId" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Provider        string               `json:"provider" gorm:"type:varchar(20);not null"`
+	ExternalID      string               `json:"externalId" gorm:"type:varchar(255);not null;uniqueIndex:idx_task_external_links_integration_external"`
+	ExternalKey     string               `json:"externalKey"` // e.g. PROJ-12 or ENG-34
+	ExternalURL     string               `json:"externalUrl"`
+	SyncedTitle     string               `json:"-"`
+	SyncedStatus    string               `json:"-" gorm:"type:varchar(20)"`
+	RemoteUpdatedAt time.Time            `json:"remoteUpdatedAt"`
+	LastSyncedAt    time.Time            `json:"lastSyncedAt"`
+	Integration     *TaskSyncIntegration `json:"-" gorm:"foreignKey:IntegrationID;constraint:OnDelete:CASCADE"`
+	Task            *Task                `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+}
+
+func (tel *TaskExternalLink) BeforeCreate(tx *gorm.DB) error {
+	if tel.ID == "" {
+		tel.ID = cuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Meeting transcription providers
+const (
+	TranscriptionProviderRecall  = "recall"  // Recall.ai transcribes during the meeting
+	TranscriptionProviderWhisper = "whisper" // The recorded audio is transcribed by the self-hosted Whisper server
+)
+
+// OrganizationTranscriptionSettings chooses how its members' meetings are transcribed. Organizations
+// without settings use Recall.ai.
+type OrganizationTranscriptionSettings struct {
+	ID             uint      `json:"-" gorm:"primaryKey"`
+	OrganizationID string    `json:"organizationId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	Provider       string    `json:"provider" gorm:"type:varchar(20);not null"`
+	UpdatedBy      string    `json:"updatedBy" gorm:"type:varchar(255)"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
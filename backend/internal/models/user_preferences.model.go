@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Where a user's timezone came from
+const (
+	TimezoneSourceSettings = "settings" // Chosen by the user, Clerk sync doesn't overwrite it
+	TimezoneSourceClerk    = "clerk"    // Synced from the timezone in the user's Clerk metadata
+)
+
+// How generated meeting notes are filed into the project chapters their topics match
+const (
+	MeetingFilingSuggest = "suggest" // Suggested for review, the default
+	MeetingFilingAuto    = "auto"    // Moved and linked without review
+	MeetingFilingOff     = "off"
+)
+
+// UserPreferences stores per-user preferences that aren't kept in Clerk
+type UserPreferences struct {
+	ID             uint      `json:"-" gorm:"primaryKey"`
+	ClerkUserID    string    `json:"clerkUserId" gorm:"not null;uniqueIndex;type:varchar(255)"`
+	Timezone       string    `json:"timezone" gorm:"type:varchar(64)"` // IANA name, empty for the server's timezone
+	TimezoneSource string    `json:"timezoneSource" gorm:"type:varchar(20)"`
+	MeetingFiling  string    `json:"meetingFiling" gorm:"type:varchar(20)"` // Empty suggests
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
@@ -50,13 +50,14 @@ func (w *WhatsAppConversationContext) BeforeCreate(tx *gorm.DB) error {
 
 // WhatsAppGroupLink links WhatsApp groups to organizations for group mode functionality
 type WhatsAppGroupLink struct {
-	ID             string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
-	GroupID        string    `json:"groupId" gorm:"uniqueIndex;not null;type:varchar(255)"`
-	OrganizationID string    `json:"organizationId" gorm:"index;not null;type:varchar(255)"`
-	LinkedBy       string    `json:"linkedBy" gorm:"not null;type:varchar(255)"` // ClerkUserID of admin
-	IsActive       bool      `json:"isActive" gorm:"default:true"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
+	ID                string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	GroupID           string    `json:"groupId" gorm:"uniqueIndex;not null;type:varchar(255)"`
+	OrganizationID    string    `json:"organizationId" gorm:"index;not null;type:varchar(255)"`
+	LinkedBy          string    `json:"linkedBy" gorm:"not null;type:varchar(255)"` // ClerkUserID of admin
+	IsActive          bool      `json:"isActive" gorm:"default:true"`
+	DefaultNotebookID *string   `json:"defaultNotebookId,omitempty" gorm:"type:varchar(255)"` // Where notes captured in the group go
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
 }
 
 // BeforeCreate hook to generate CUID before creating a group link
@@ -72,6 +73,7 @@ type WhatsAppMessage struct {
 	ID           string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
 	MessageID    string    `json:"messageId" gorm:"uniqueIndex;type:varchar(255)"` // WhatsApp message ID
 	PhoneNumber  string    `json:"phoneNumber" gorm:"index;type:varchar(20)"`
+	GroupID      *string   `json:"groupId,omitempty" gorm:"index;type:varchar(255)"`
 	Direction    string    `json:"direction" gorm:"type:varchar(10)"`   // "inbound" or "outbound"
 	MessageType  string    `json:"messageType" gorm:"type:varchar(20)"` // "text", "image", etc.
 	Content      string    `json:"content" gorm:"type:text"`
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// WritingActivity aggregates a user's writing on one day, in their timezone. It's updated as notes are
+// saved. Personal writing has an empty OrganizationID, so the unique index covers it.
+type WritingActivity struct {
+	ID             uint      `json:"-" gorm:"primaryKey"`
+	ClerkUserID    string    `json:"clerkUserId" gorm:"not null;uniqueIndex:idx_writing_activity_user_day;type:varchar(255)"`
+	OrganizationID string    `json:"organizationId,omitempty" gorm:"not null;default:'';uniqueIndex:idx_writing_activity_user_day;type:varchar(255)"`
+	Day            string    `json:"day" gorm:"not null;uniqueIndex:idx_writing_activity_user_day;type:varchar(10)"` // YYYY-MM-DD
+	WordsWritten   int       `json:"wordsWritten"`                                                                   // Words added to notes, net of words removed by the same save
+	NotesCreated   int       `json:"notesCreated"`
+	Edits          int       `json:"edits"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Writing milestone kinds
+const (
+	WritingMilestoneStreak = "streak" // Value is the streak length in days
+)
+
+// WritingMilestone records a user reaching a writing milestone, such as a 7 day streak
+type WritingMilestone struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	ClerkUserID    string    `json:"clerkUserId" gorm:"not null;uniqueIndex:idx_writing_milestone;type:varchar(255)"`
+	OrganizationID string    `json:"organizationId,omitempty" gorm:"not null;default:'';uniqueIndex:idx_writing_milestone;type:varchar(255)"`
+	Kind           string    `json:"kind" gorm:"not null;uniqueIndex:idx_writing_milestone;type:varchar(20)"`
+	Value          int       `json:"value" gorm:"uniqueIndex:idx_writing_milestone"`
+	Day            string    `json:"day" gorm:"type:varchar(10)"` // Reached on, YYYY-MM-DD
+	CreatedAt      time.Time `json:"createdAt"`
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"gorm.io/gorm"
+)
+
+// AccessLookups are the questions about users that middleware answers, like what they may change.
+// Services sit below middleware and don't import it, so main passes middleware's answers in with
+// SetAccessLookups before serving.
+type AccessLookups struct {
+	// CanEditNote reports whether a user may change a note
+	CanEditNote func(ctx context.Context, db *gorm.DB, noteID, clerkUserID string) (bool, error)
+	// CanEditChapter reports whether a user may add notes to a chapter
+	CanEditChapter func(ctx context.Context, db *gorm.DB, chapterID, clerkUserID string) (bool, error)
+	// User returns a user's Clerk profile
+	User func(ctx context.Context, clerkUserID string) (*clerk.User, error)
+	// SingleUser reports whether the server serves one local user without Clerk
+	SingleUser func() bool
+	// OrgRoleFromClerk simplifies a Clerk organization role to one of the roles access is checked by
+	OrgRoleFromClerk func(clerkRole string) string
+	// ValidClerkOrgRole reports whether members can be given a Clerk organization role
+	ValidClerkOrgRole func(clerkRole string) bool
+}
+
+// accessLookups are the lookups main set
+var accessLookups AccessLookups
+
+// SetAccessLookups sets the lookups services use from then on
+func SetAccessLookups(lookups AccessLookups) {
+	accessLookups = lookups
+}
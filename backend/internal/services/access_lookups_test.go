@@ -0,0 +1,28 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// useTestAccessLookups sets the lookups services use for the rest of the test. Users can edit the notes
+// of their personal notebooks and nothing else.
+func useTestAccessLookups(t *testing.T) {
+	previous := accessLookups
+	SetAccessLookups(AccessLookups{
+		CanEditNote: func(ctx context.Context, db *gorm.DB, noteID, clerkUserID string) (bool, error) {
+			var count int64
+			err := db.WithContext(ctx).Model(&models.Notes{}).
+				Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+				Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+				Where("notes.id = ? AND notebooks.clerk_user_id = ? AND notebooks.organization_id IS NULL", noteID, clerkUserID).
+				Count(&count).Error
+			return count > 0, err
+		},
+		SingleUser: func() bool { return false },
+	})
+	t.Cleanup(func() { SetAccessLookups(previous) })
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lucsky/cuid"
+)
+
+// ErrChatRunNotFound is returned when there is no in-flight chat run with the ID for the user
+var ErrChatRunNotFound = errors.New("chat run not found")
+
+// Chat run event types, streamed to the client as data parts
+const (
+	ChatRunEventStarted      = "run_started"
+	ChatRunEventToolStarted  = "tool_started"
+	ChatRunEventToolFinished = "tool_finished"
+	ChatRunEventToolSkipped  = "tool_skipped"
+	ChatRunEventCancelled    = "run_cancelled"
+)
+
+// Chat run tool call statuses
+const (
+	ChatRunToolRunning = "running"
+	ChatRunToolDone    = "done"
+	ChatRunToolFailed  = "failed"
+	ChatRunToolSkipped = "skipped"
+)
+
+// ChatRunEvent is a progress event for a chat run
+type ChatRunEvent struct {
+	Type       string `json:"type"`
+	RunID      string `json:"runId"`
+	Step       int    `json:"step,omitempty"`
+	ToolCallID string `json:"toolCallId,omitempty"`
+	Tool       string `json:"tool,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ChatRunToolCall is a tool call made during a chat run
+type ChatRunToolCall struct {
+	Step       int       `json:"step"`
+	ToolCallID string    `json:"toolCallId"`
+	Tool       string    `json:"tool"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+}
+
+// ChatRunStatus is a snapshot of a chat run
+type ChatRunStatus struct {
+	ID        string            `json:"id"`
+	StartedAt time.Time         `json:"startedAt"`
+	Cancelled bool              `json:"cancelled"`
+	ToolCalls []ChatRunToolCall `json:"toolCalls"`
+}
+
+// ChatRun is the tool-calling loop of one chat request. Cancelling it stops further tool calls; the
+// tool call in progress, if any, is allowed to finish so no mutation is left half done.
+type ChatRun struct {
+	ID          string
+	ClerkUserID string
+	StartedAt   time.Time
+
+	mu        sync.Mutex
+	cancelled bool
+	toolCalls []ChatRunToolCall
+}
+
+// Cancelled reports whether the run has been cancelled
+func (r *ChatRun) Cancelled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cancelled
+}
+
+// BeginToolCall records a tool call and returns its step number. It returns false, recording the call as
+// skipped, when the run has been cancelled and the tool must not be executed.
+func (r *ChatRun) BeginToolCall(toolCallID, tool string) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	call := ChatRunToolCall{
+		Step:       len(r.toolCalls) + 1,
+		ToolCallID: toolCallID,
+		Tool:       tool,
+		Status:     ChatRunToolRunning,
+		StartedAt:  time.Now(),
+	}
+	if r.cancelled {
+		call.Status = ChatRunToolSkipped
+	}
+	r.toolCalls = append(r.toolCalls, call)
+	return call.Step, !r.cancelled
+}
+
+// FinishToolCall records the outcome of a tool call
+func (r *ChatRun) FinishToolCall(step int, errMessage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if step < 1 || step > len(r.toolCalls) {
+		return
+	}
+	call := &r.toolCalls[step-1]
+	call.Status = ChatRunToolDone
+	if errMessage != "" {
+		call.Status = ChatRunToolFailed
+		call.Error = errMessage
+	}
+}
+
+// ToolCall returns the recorded tool call with the ID
+func (r *ChatRun) ToolCall(toolCallID string) (ChatRunToolCall, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, call := range r.toolCalls {
+		if call.ToolCallID == toolCallID {
+			return call, true
+		}
+	}
+	return ChatRunToolCall{}, false
+}
+
+// Status returns a snapshot of the run
+func (r *ChatRun) Status() ChatRunStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	toolCalls := make([]ChatRunToolCall, len(r.toolCalls))
+	copy(toolCalls, r.toolCalls)
+	return ChatRunStatus{ID: r.ID, StartedAt: r.StartedAt, Cancelled: r.cancelled, ToolCalls: toolCalls}
+}
+
+// cancel marks the run as cancelled
+func (r *ChatRun) cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancelled = true
+}
+
+// ChatRunRegistry tracks the in-flight chat runs of this server
+type ChatRunRegistry struct {
+	mu   sync.Mutex
+	runs map[string]*ChatRun
+}
+
+var (
+	chatRunRegistry     *ChatRunRegistry
+	chatRunRegistryOnce sync.Once
+)
+
+// GetChatRunRegistry returns the shared chat run registry
+func GetChatRunRegistry() *ChatRunRegistry {
+	chatRunRegistryOnce.Do(func() {
+		chatRunRegistry = NewChatRunRegistry()
+	})
+	return chatRunRegistry
+}
+
+// NewChatRunRegistry creates an empty chat run registry
+func NewChatRunRegistry() *ChatRunRegistry {
+	return &ChatRunRegistry{runs: make(map[string]*ChatRun)}
+}
+
+// Start registers a new run for the user
+func (r *ChatRunRegistry) Start(clerkUserID string) *ChatRun {
+	run := &ChatRun{ID: cuid.New(), ClerkUserID: clerkUserID, StartedAt: time.Now()}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[run.ID] = run
+	return run
+}
+
+// Get returns the user's in-flight run with the ID
+func (r *ChatRunRegistry) Get(runID, clerkUserID string) (*ChatRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, ok := r.runs[runID]
+	if !ok || run.ClerkUserID != clerkUserID {
+		return nil, ErrChatRunNotFound
+	}
+	return run, nil
+}
+
+// Cancel stops further tool execution in the user's run
+func (r *ChatRunRegistry) Cancel(runID, clerkUserID string) (*ChatRun, error) {
+	run, err := r.Get(runID, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	run.cancel()
+	return run, nil
+}
+
+// Finish removes a run once its request has completed
+func (r *ChatRunRegistry) Finish(runID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, runID)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatRunRegistry_Cancel(t *testing.T) {
+	registry := NewChatRunRegistry()
+	run := registry.Start("user_1")
+
+	step, ok := run.BeginToolCall("call_1", "moveNote")
+	require.True(t, ok)
+	assert.Equal(t, 1, step)
+	run.FinishToolCall(step, "")
+
+	// Only the owner can see or cancel the run
+	_, err := registry.Cancel(run.ID, "user_2")
+	assert.ErrorIs(t, err, ErrChatRunNotFound)
+	_, err = registry.Cancel(run.ID, "user_1")
+	require.NoError(t, err)
+	assert.True(t, run.Cancelled())
+
+	step, ok = run.BeginToolCall("call_2", "renameNote")
+	assert.False(t, ok)
+	assert.Equal(t, 2, step)
+
+	status := run.Status()
+	assert.True(t, status.Cancelled)
+	require.Len(t, status.ToolCalls, 2)
+	assert.Equal(t, ChatRunToolDone, status.ToolCalls[0].Status)
+	assert.Equal(t, ChatRunToolSkipped, status.ToolCalls[1].Status)
+
+	registry.Finish(run.ID)
+	_, err = registry.Get(run.ID, "user_1")
+	assert.ErrorIs(t, err, ErrChatRunNotFound)
+}
+
+func TestChatRun_FinishToolCallWithError(t *testing.T) {
+	run := NewChatRunRegistry().Start("user_1")
+
+	step, _ := run.BeginToolCall("call_1", "deleteNote")
+	run.FinishToolCall(step, "Note not found")
+
+	call, ok := run.ToolCall("call_1")
+	require.True(t, ok)
+	assert.Equal(t, ChatRunToolFailed, call.Status)
+	assert.Equal(t, "Note not found", call.Error)
+
+	_, ok = run.ToolCall("missing")
+	assert.False(t, ok)
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// maxChatOutlineNotes caps the notes listed in a scoped chat's outline
+const maxChatOutlineNotes = 300
+
+var (
+	// ErrInvalidChatScope is returned for a scope naming both or neither of a notebook and a chapter
+	ErrInvalidChatScope = errors.New("chat scope must name a notebookId or a chapterId")
+	// ErrChatScopeNotFound is returned when the scope's notebook or chapter doesn't exist
+	ErrChatScopeNotFound = errors.New("chat scope not found")
+)
+
+// aiChatScopeUnscopedTools are the tools that work outside notebooks, so a scoped chat doesn't offer them
+var aiChatScopeUnscopedTools = map[string]bool{
+	"createNotebook":        true,
+	"listUpcomingMeetings":  true,
+	"scheduleBotForMeeting": true,
+	"getMeetingSummary":     true,
+}
+
+// aiChatScopeArgs are the tool arguments that name something the scope has to contain
+var aiChatScopeArgs = []string{"notebookId", "targetNotebookId", "chapterId", "targetChapterId", "noteId", "boardId", "taskId"}
+
+// AIChatScope restricts an assistant conversation to one notebook, or to one chapter of it. A nil
+// scope allows everything in the workspace.
+type AIChatScope struct {
+	NotebookID   string
+	NotebookName string
+	ChapterID    string // Empty when the whole notebook is in scope
+	ChapterName  string
+	db           *gorm.DB
+}
+
+// NewAIChatScope resolves a chat scope from a notebook or a chapter ID
+func NewAIChatScope(notebookID, chapterID string) (*AIChatScope, error) {
+	return newAIChatScope(db.DB, notebookID, chapterID)
+}
+
+// newAIChatScope resolves a chat scope on a database
+func newAIChatScope(database *gorm.DB, notebookID, chapterID string) (*AIChatScope, error) {
+	if (notebookID == "") == (chapterID == "") {
+		return nil, ErrInvalidChatScope
+	}
+
+	scope := &AIChatScope{db: database}
+	if chapterID != "" {
+		var chapter models.Chapter
+		if err := database.Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrChatScopeNotFound
+			}
+			return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+		}
+		scope.ChapterID = chapter.ID
+		scope.ChapterName = chapter.Name
+		scope.NotebookID = chapter.NotebookID
+		scope.NotebookName = chapter.Notebook.Name
+		return scope, nil
+	}
+
+	var notebook models.Notebook
+	if err := database.Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatScopeNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	scope.NotebookID = notebook.ID
+	scope.NotebookName = notebook.Name
+	return scope, nil
+}
+
+// OffersTool reports whether a scoped chat offers a tool at all
+func (s *AIChatScope) OffersTool(toolName string) bool {
+	return s == nil || !aiChatScopeUnscopedTools[toolName]
+}
+
+// CheckToolCall reports whether a tool call stays inside the scope, returning a message for the model
+// when it doesn't
+func (s *AIChatScope) CheckToolCall(toolName string, args map[string]any) error {
+	if s == nil {
+		return nil
+	}
+	if !s.OffersTool(toolName) {
+		return fmt.Errorf("the %s tool isn't available in this conversation, which is limited to %s", toolName, s.Label())
+	}
+
+	for _, name := range aiChatScopeArgs {
+		id, ok := args[name].(string)
+		if !ok || id == "" {
+			continue
+		}
+		var allowed bool
+		switch name {
+		case "notebookId", "targetNotebookId":
+			allowed = s.AllowsNotebook(id)
+		case "chapterId", "targetChapterId":
+			allowed = s.AllowsChapter(id)
+		case "noteId":
+			allowed = s.AllowsNote(id)
+		case "boardId":
+			allowed = s.AllowsBoard(id)
+		case "taskId":
+			allowed = s.AllowsTask(id)
+		}
+		if !allowed {
+			return fmt.Errorf("%s %s is outside this conversation, which is limited to %s", name, id, s.Label())
+		}
+	}
+	return nil
+}
+
+// Label describes the scope for the model, e.g. chapter "Week 1" of notebook "Biology"
+func (s *AIChatScope) Label() string {
+	if s.ChapterID != "" {
+		return fmt.Sprintf("chapter %q of notebook %q", s.ChapterName, s.NotebookName)
+	}
+	return fmt.Sprintf("notebook %q", s.NotebookName)
+}
+
+// AllowsNotebook reports whether notebook-level operations on a notebook stay inside the scope, which
+// is only the case for a notebook scope
+func (s *AIChatScope) AllowsNotebook(notebookID string) bool {
+	return s == nil || (s.ChapterID == "" && notebookID == s.NotebookID)
+}
+
+// AllowsChapter reports whether a chapter is inside the scope
+func (s *AIChatScope) AllowsChapter(chapterID string) bool {
+	if s == nil {
+		return true
+	}
+	if s.ChapterID != "" {
+		return chapterID == s.ChapterID
+	}
+	var count int64
+	s.db.Model(&models.Chapter{}).Where("id = ? AND notebook_id = ?", chapterID, s.NotebookID).Count(&count)
+	return count > 0
+}
+
+// AllowsNote reports whether a note is inside the scope
+func (s *AIChatScope) AllowsNote(noteID string) bool {
+	if s == nil {
+		return true
+	}
+	var count int64
+	s.FilterNotes(s.db.Model(&models.Notes{}).Joins("JOIN chapters ON notes.chapter_id = chapters.id")).
+		Where("notes.id = ?", noteID).
+		Count(&count)
+	return count > 0
+}
+
+// AllowsBoard reports whether a task board belongs to a note inside the scope
+func (s *AIChatScope) AllowsBoard(boardID string) bool {
+	if s == nil {
+		return true
+	}
+	var board models.TaskBoard
+	if err := s.db.Select("note_id").Where("id = ?", boardID).First(&board).Error; err != nil || board.NoteID == nil {
+		return false
+	}
+	return s.AllowsNote(*board.NoteID)
+}
+
+// AllowsTask reports whether a task is on a board inside the scope
+func (s *AIChatScope) AllowsTask(taskID string) bool {
+	if s == nil {
+		return true
+	}
+	var task models.Task
+	if err := s.db.Select("task_board_id").Where("id = ?", taskID).First(&task).Error; err != nil {
+		return false
+	}
+	return s.AllowsBoard(task.TaskBoardID)
+}
+
+// FilterNotes restricts a notes query that joins chapters to the notes inside the scope
+func (s *AIChatScope) FilterNotes(query *gorm.DB) *gorm.DB {
+	if s == nil {
+		return query
+	}
+	if s.ChapterID != "" {
+		return query.Where("notes.chapter_id = ?", s.ChapterID)
+	}
+	return query.Where("chapters.notebook_id = ?", s.NotebookID)
+}
+
+// Outline lists the scope's chapters and note titles with their IDs, for the system prompt
+func (s *AIChatScope) Outline() (string, error) {
+	var chapters []models.Chapter
+	query := s.db.Where("notebook_id = ?", s.NotebookID)
+	if s.ChapterID != "" {
+		query = s.db.Where("id = ?", s.ChapterID)
+	}
+	if err := query.Order("created_at ASC").Find(&chapters).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch chapters: %w", err)
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Notebook %q (id: %s)\n", s.NotebookName, s.NotebookID)
+	listed := 0
+	for _, chapter := range chapters {
+		fmt.Fprintf(&builder, "- Chapter %q (id: %s)\n", chapter.Name, chapter.ID)
+
+		var notes []models.Notes
+		if err := s.db.Select("id", "name").Where("chapter_id = ?", chapter.ID).Order("created_at ASC").Find(&notes).Error; err != nil {
+			return "", fmt.Errorf("failed to fetch notes: %w", err)
+		}
+		for _, note := range notes {
+			if listed == maxChatOutlineNotes {
+				builder.WriteString("  - (more notes not listed, use listNotesInChapter)\n")
+				break
+			}
+			fmt.Fprintf(&builder, "  - Note %q (id: %s)\n", note.Name, note.ID)
+			listed++
+		}
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// chatScopeFixture is a notebook with two chapters and a second notebook
+type chatScopeFixture struct {
+	db            *gorm.DB
+	notebook      models.Notebook
+	week1, week2  models.Chapter
+	note1, note2  models.Notes
+	otherNote     models.Notes
+	board, others models.TaskBoard
+	task          models.Task
+}
+
+// setupTestAIChatScope creates the chat scope fixture on an in-memory database
+func setupTestAIChatScope(t *testing.T) chatScopeFixture {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.TaskBoard{}, &models.Task{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	f := chatScopeFixture{db: db}
+	f.notebook = models.Notebook{Name: "Biology", ClerkUserID: "user_owner"}
+	require.NoError(t, db.Create(&f.notebook).Error)
+	f.week1 = models.Chapter{Name: "Week 1", NotebookID: f.notebook.ID}
+	require.NoError(t, db.Create(&f.week1).Error)
+	f.week2 = models.Chapter{Name: "Week 2", NotebookID: f.notebook.ID}
+	require.NoError(t, db.Create(&f.week2).Error)
+	f.note1 = models.Notes{Name: "Cells", ChapterID: f.week1.ID}
+	require.NoError(t, db.Create(&f.note1).Error)
+	f.note2 = models.Notes{Name: "Genetics", ChapterID: f.week2.ID}
+	require.NoError(t, db.Create(&f.note2).Error)
+
+	other := models.Notebook{Name: "Chemistry", ClerkUserID: "user_owner"}
+	require.NoError(t, db.Create(&other).Error)
+	otherChapter := models.Chapter{Name: "Atoms", NotebookID: other.ID}
+	require.NoError(t, db.Create(&otherChapter).Error)
+	f.otherNote = models.Notes{Name: "Bonds", ChapterID: otherChapter.ID}
+	require.NoError(t, db.Create(&f.otherNote).Error)
+
+	f.board = models.TaskBoard{Name: "Cells tasks", ClerkUserID: "user_owner", NoteID: &f.note1.ID}
+	require.NoError(t, db.Create(&f.board).Error)
+	f.others = models.TaskBoard{Name: "Standalone", ClerkUserID: "user_owner", IsStandalone: true}
+	require.NoError(t, db.Create(&f.others).Error)
+	f.task = models.Task{Title: "Draw a cell", TaskBoardID: f.board.ID}
+	require.NoError(t, db.Create(&f.task).Error)
+	return f
+}
+
+func TestNewAIChatScope(t *testing.T) {
+	f := setupTestAIChatScope(t)
+
+	scope, err := newAIChatScope(f.db, "", f.week1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.notebook.ID, scope.NotebookID)
+	assert.Equal(t, `chapter "Week 1" of notebook "Biology"`, scope.Label())
+
+	_, err = newAIChatScope(f.db, f.notebook.ID, f.week1.ID)
+	assert.ErrorIs(t, err, ErrInvalidChatScope)
+	_, err = newAIChatScope(f.db, "", "")
+	assert.ErrorIs(t, err, ErrInvalidChatScope)
+	_, err = newAIChatScope(f.db, "missing", "")
+	assert.ErrorIs(t, err, ErrChatScopeNotFound)
+}
+
+func TestAIChatScope_CheckToolCall_Notebook(t *testing.T) {
+	f := setupTestAIChatScope(t)
+	scope, err := newAIChatScope(f.db, f.notebook.ID, "")
+	require.NoError(t, err)
+
+	assert.NoError(t, scope.CheckToolCall("getNoteContent", map[string]any{"noteId": f.note2.ID}))
+	assert.NoError(t, scope.CheckToolCall("moveNote", map[string]any{"noteId": f.note1.ID, "targetChapterId": f.week2.ID}))
+	assert.NoError(t, scope.CheckToolCall("createChapter", map[string]any{"notebookId": f.notebook.ID, "name": "Week 3"}))
+	assert.NoError(t, scope.CheckToolCall("completeTask", map[string]any{"taskId": f.task.ID}))
+
+	assert.Error(t, scope.CheckToolCall("getNoteContent", map[string]any{"noteId": f.otherNote.ID}))
+	assert.Error(t, scope.CheckToolCall("moveNote", map[string]any{"noteId": f.note1.ID, "targetChapterId": f.otherNote.ChapterID}))
+	assert.Error(t, scope.CheckToolCall("createTask", map[string]any{"boardId": f.others.ID}))
+	assert.Error(t, scope.CheckToolCall("createNotebook", map[string]any{"name": "New"}))
+	assert.False(t, scope.OffersTool("listUpcomingMeetings"))
+
+	// Unscoped chats allow everything
+	var unscoped *AIChatScope
+	assert.NoError(t, unscoped.CheckToolCall("getNoteContent", map[string]any{"noteId": f.otherNote.ID}))
+	assert.True(t, unscoped.OffersTool("createNotebook"))
+}
+
+func TestAIChatScope_CheckToolCall_Chapter(t *testing.T) {
+	f := setupTestAIChatScope(t)
+	scope, err := newAIChatScope(f.db, "", f.week1.ID)
+	require.NoError(t, err)
+
+	assert.NoError(t, scope.CheckToolCall("createNote", map[string]any{"chapterId": f.week1.ID, "title": "Mitosis"}))
+	assert.Error(t, scope.CheckToolCall("createNote", map[string]any{"chapterId": f.week2.ID, "title": "Mitosis"}))
+	assert.Error(t, scope.CheckToolCall("getNoteContent", map[string]any{"noteId": f.note2.ID}))
+	assert.Error(t, scope.CheckToolCall("renameNotebook", map[string]any{"notebookId": f.notebook.ID, "newName": "Bio"}))
+}
+
+func TestAIChatScope_FilterNotesAndOutline(t *testing.T) {
+	f := setupTestAIChatScope(t)
+	scope, err := newAIChatScope(f.db, f.notebook.ID, "")
+	require.NoError(t, err)
+
+	var names []string
+	require.NoError(t, scope.FilterNotes(f.db.Model(&models.Notes{}).Joins("JOIN chapters ON notes.chapter_id = chapters.id")).
+		Order("notes.name ASC").
+		Pluck("notes.name", &names).Error)
+	assert.Equal(t, []string{"Cells", "Genetics"}, names)
+
+	outline, err := scope.Outline()
+	require.NoError(t, err)
+	assert.Contains(t, outline, `Notebook "Biology"`)
+	assert.Contains(t, outline, `- Chapter "Week 2" (id: `+f.week2.ID+`)`)
+	assert.Contains(t, outline, `  - Note "Cells" (id: `+f.note1.ID+`)`)
+	assert.NotContains(t, outline, "Bonds")
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"backend/internal/config"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// aiProviderContextTokens is the context window of each provider's chat models, in tokens
+var aiProviderContextTokens = map[string]int{
+	config.AIProviderOpenAI:    128000,
+	config.AIProviderAnthropic: 200000,
+	config.AIProviderGoogle:    1000000,
+}
+
+const (
+	// defaultAIContextTokens is the context window assumed for providers without a known one
+	defaultAIContextTokens = 128000
+	// maxAIToolResultTokens caps a single tool result, however large the context window
+	maxAIToolResultTokens = 24000
+	// maxAINotePageTokens caps a page of note content, so a long note takes a few calls to read
+	maxAINotePageTokens = 12000
+	// minAITruncatedStringChars is the shortest a string in a tool result is cut to
+	minAITruncatedStringChars = 200
+	// aiCharsPerToken estimates tokens from text length, close enough for English and Markdown
+	aiCharsPerToken = 4
+)
+
+// AITokenBudget is how much of a provider's context window a chat may spend on input
+type AITokenBudget struct {
+	Input      int // Messages, tools and results, leaving room for the response
+	ToolResult int // One tool result
+	NotePage   int // One page of note content
+}
+
+// NewAITokenBudget returns the token budget for chatting with a provider that may respond with up to
+// maxOutputTokens
+func NewAITokenBudget(provider string, maxOutputTokens int) AITokenBudget {
+	contextTokens, ok := aiProviderContextTokens[provider]
+	if !ok {
+		contextTokens = defaultAIContextTokens
+	}
+	input := contextTokens - maxOutputTokens
+	return AITokenBudget{
+		Input:      input,
+		ToolResult: min(input/8, maxAIToolResultTokens),
+		NotePage:   min(input/16, maxAINotePageTokens),
+	}
+}
+
+// EstimateTokens estimates how many tokens a text takes
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + aiCharsPerToken - 1) / aiCharsPerToken
+}
+
+// EstimateValueTokens estimates how many tokens a value takes once encoded as JSON for the model
+func EstimateValueTokens(value any) int {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return EstimateTokens(string(encoded))
+}
+
+// TextPage is one page of a text split to fit a token budget
+type TextPage struct {
+	Content string
+	Page    int // From 1
+	Pages   int
+}
+
+// HasMore reports whether pages follow this one
+func (p TextPage) HasMore() bool {
+	return p.Page < p.Pages
+}
+
+// PageText splits a text into pages of about pageTokens each and returns one of them. Pages break
+// between paragraphs, or lines, where they can. A page past the end returns the last page.
+func PageText(text string, page, pageTokens int) TextPage {
+	pages := splitTextPages(text, max(pageTokens, 1)*aiCharsPerToken)
+	page = max(min(page, len(pages)), 1)
+	return TextPage{Content: pages[page-1], Page: page, Pages: len(pages)}
+}
+
+// splitTextPages splits a text into pages of at most pageChars runes
+func splitTextPages(text string, pageChars int) []string {
+	var pages []string
+	for utf8.RuneCountInString(text) > pageChars {
+		cut := runeOffset(text, pageChars)
+		// Prefer a paragraph break, then a line break, in the second half of the page
+		if i := strings.LastIndex(text[:cut], "\n\n"); i > cut/2 {
+			cut = i + 2
+		} else if i := strings.LastIndex(text[:cut], "\n"); i > cut/2 {
+			cut = i + 1
+		}
+		pages = append(pages, text[:cut])
+		text = text[cut:]
+	}
+	return append(pages, text)
+}
+
+// runeOffset returns the byte offset of the nth rune of a text
+func runeOffset(text string, n int) int {
+	for offset := range text {
+		if n == 0 {
+			return offset
+		}
+		n--
+	}
+	return len(text)
+}
+
+// TruncateToolResult shrinks a tool result that would take more than maxTokens. The longest strings are
+// cut first, to the same length, so IDs and names survive while note contents shrink; if that isn't
+// enough the longest lists are shortened. Results within budget are returned unchanged.
+func TruncateToolResult(result any, maxTokens int) any {
+	if EstimateValueTokens(result) <= maxTokens {
+		return result
+	}
+
+	// Work on a generic copy, tool results are built from typed maps and slices
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return result
+	}
+	var value any
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		return result
+	}
+
+	if object, ok := value.(map[string]any); ok {
+		object["truncated"] = true
+		object["truncationNote"] = "This result was shortened to fit the context window. Fetch single notes with getNoteContent for their full content."
+	}
+	fits := func(v any) bool { return EstimateValueTokens(v) <= maxTokens }
+
+	// Find the longest string length that fits, between the shortest allowed and the longest string
+	low, high := minAITruncatedStringChars, longestString(value)
+	if fits(truncateStrings(value, low)) {
+		for low < high {
+			mid := (low + high + 1) / 2
+			if fits(truncateStrings(value, mid)) {
+				low = mid
+			} else {
+				high = mid - 1
+			}
+		}
+		value = truncateStrings(value, low)
+	} else {
+		value = truncateStrings(value, low)
+		for !fits(value) && halveLongestList(value) {
+		}
+	}
+
+	return value
+}
+
+// truncateStrings returns a copy of a JSON value with every string cut to at most maxChars runes
+func truncateStrings(value any, maxChars int) any {
+	switch v := value.(type) {
+	case string:
+		if utf8.RuneCountInString(v) <= maxChars {
+			return v
+		}
+		return v[:runeOffset(v, maxChars)] + "…[truncated]"
+	case map[string]any:
+		truncated := make(map[string]any, len(v))
+		for key, item := range v {
+			truncated[key] = truncateStrings(item, maxChars)
+		}
+		return truncated
+	case []any:
+		truncated := make([]any, len(v))
+		for i, item := range v {
+			truncated[i] = truncateStrings(item, maxChars)
+		}
+		return truncated
+	}
+	return value
+}
+
+// longestString returns the length in runes of the longest string in a JSON value
+func longestString(value any) int {
+	longest := 0
+	switch v := value.(type) {
+	case string:
+		longest = utf8.RuneCountInString(v)
+	case map[string]any:
+		for _, item := range v {
+			longest = max(longest, longestString(item))
+		}
+	case []any:
+		for _, item := range v {
+			longest = max(longest, longestString(item))
+		}
+	}
+	return longest
+}
+
+// halveLongestList drops the second half of the longest list held by an object in a JSON value, noting
+// how many items were left out. It returns false when there's no list left to shorten.
+func halveLongestList(value any) bool {
+	var parent map[string]any
+	var key string
+	longest := 1
+	var find func(any)
+	find = func(value any) {
+		switch v := value.(type) {
+		case map[string]any:
+			for k, item := range v {
+				if list, ok := item.([]any); ok && len(list) > longest {
+					parent, key, longest = v, k, len(list)
+				}
+				find(item)
+			}
+		case []any:
+			for _, item := range v {
+				find(item)
+			}
+		}
+	}
+	find(value)
+	if parent == nil {
+		return false
+	}
+
+	list := parent[key].([]any)
+	kept := len(list) / 2
+	parent[key] = list[:kept]
+	omitted, _ := parent[key+"Omitted"].(int)
+	parent[key+"Omitted"] = omitted + len(list) - kept
+	return true
+}
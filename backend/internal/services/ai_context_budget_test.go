@@ -0,0 +1,79 @@
+package services
+
+import (
+	"backend/internal/config"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAITokenBudget_ScalesWithTheProvider(t *testing.T) {
+	openAI := NewAITokenBudget(config.AIProviderOpenAI, 16384)
+	anthropic := NewAITokenBudget(config.AIProviderAnthropic, 16384)
+	assert.Equal(t, 128000-16384, openAI.Input)
+	assert.Less(t, openAI.NotePage, anthropic.NotePage, "Larger context windows read longer pages")
+	assert.LessOrEqual(t, NewAITokenBudget(config.AIProviderGoogle, 16384).ToolResult, maxAIToolResultTokens)
+	assert.Equal(t, openAI, NewAITokenBudget("unknown", 16384))
+}
+
+func TestPageText(t *testing.T) {
+	var paragraphs []string
+	for i := 1; i <= 40; i++ {
+		paragraphs = append(paragraphs, fmt.Sprintf("Paragraph %d %s", i, strings.Repeat("word ", 20)))
+	}
+	text := strings.Join(paragraphs, "\n\n")
+
+	first := PageText(text, 1, 200)
+	require.Greater(t, first.Pages, 1)
+	assert.True(t, first.HasMore())
+	assert.True(t, strings.HasSuffix(first.Content, "\n\n"), "Pages should break between paragraphs")
+	assert.LessOrEqual(t, EstimateTokens(first.Content), 200)
+
+	var joined strings.Builder
+	for page := 1; page <= first.Pages; page++ {
+		joined.WriteString(PageText(text, page, 200).Content)
+	}
+	assert.Equal(t, text, joined.String(), "Pages should add up to the whole text")
+
+	last := PageText(text, first.Pages+5, 200)
+	assert.Equal(t, first.Pages, last.Page, "Pages past the end return the last page")
+	assert.False(t, last.HasMore())
+
+	short := PageText("Short note", 1, 200)
+	assert.Equal(t, TextPage{Content: "Short note", Page: 1, Pages: 1}, short)
+}
+
+func TestTruncateToolResult(t *testing.T) {
+	small := map[string]any{"id": "note_1", "content": "Hello"}
+	assert.Equal(t, small, TruncateToolResult(small, 100), "Results within budget are unchanged")
+
+	notes := make([]map[string]any, 5)
+	for i := range notes {
+		notes[i] = map[string]any{"id": fmt.Sprintf("note_%d", i), "content": strings.Repeat("x", 10000)}
+	}
+	result := TruncateToolResult(map[string]any{"count": 5, "notes": notes}, 2000)
+	assert.LessOrEqual(t, EstimateValueTokens(result), 2000)
+
+	truncated := result.(map[string]any)
+	assert.Equal(t, true, truncated["truncated"])
+	kept := truncated["notes"].([]any)
+	require.Len(t, kept, 5, "Cutting contents should be enough")
+	for i, item := range kept {
+		note := item.(map[string]any)
+		assert.Equal(t, fmt.Sprintf("note_%d", i), note["id"], "Short fields should survive")
+		assert.Contains(t, note["content"], "[truncated]")
+	}
+
+	many := make([]map[string]any, 500)
+	for i := range many {
+		many[i] = map[string]any{"id": fmt.Sprintf("note_%d", i), "content": strings.Repeat("y", 1000)}
+	}
+	result = TruncateToolResult(map[string]any{"notes": many}, 2000)
+	assert.LessOrEqual(t, EstimateValueTokens(result), 2000)
+	truncated = result.(map[string]any)
+	assert.Less(t, len(truncated["notes"].([]any)), 500, "Long lists should be shortened once strings can't shrink further")
+	assert.Positive(t, truncated["notesOmitted"])
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAIModelSettings is returned when saved model settings name an unknown provider or lack one
+var ErrInvalidAIModelSettings = errors.New("invalid AI model settings")
+
+// AIModelSelection is the provider and model chosen for an AI call
+type AIModelSelection struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+// AIModelSettingsService interface defines methods for managing default AI models
+type AIModelSettingsService interface {
+	ResolveModel(clerkUserID string, organizationID *string, purpose string) AIModelSelection
+	GetUserSettings(clerkUserID string) (*models.AIModelDefaults, error)
+	SetUserSettings(clerkUserID string, defaults models.AIModelDefaults) error
+	GetOrgSettings(organizationID string) (*models.AIModelDefaults, error)
+	SetOrgSettings(organizationID string, defaults models.AIModelDefaults, updatedBy string) error
+}
+
+// aiModelSettingsServiceImpl implements the AIModelSettingsService interface
+type aiModelSettingsServiceImpl struct {
+	db     *gorm.DB
+	policy *AIProviderPolicy
+}
+
+// NewAIModelSettingsService creates a new AIModelSettingsService instance
+func NewAIModelSettingsService() AIModelSettingsService {
+	return &aiModelSettingsServiceImpl{
+		db:     db.DB,
+		policy: GetAIProviderPolicy(),
+	}
+}
+
+// ResolveModel picks the provider and model for a purpose.
+// Organization settings win in an organization workspace, then the user's settings, then the server defaults.
+func (s *aiModelSettingsServiceImpl) ResolveModel(clerkUserID string, organizationID *string, purpose string) AIModelSelection {
+	var layers []models.AIModelDefaults
+
+	if organizationID != nil && *organizationID != "" {
+		if orgDefaults, err := s.GetOrgSettings(*organizationID); err == nil {
+			layers = append(layers, *orgDefaults)
+		}
+	}
+	if clerkUserID != "" {
+		if userDefaults, err := s.GetUserSettings(clerkUserID); err == nil {
+			layers = append(layers, *userDefaults)
+		}
+	}
+
+	for _, defaults := range layers {
+		provider, model := defaults.ForPurpose(purpose)
+		if provider == "" {
+			continue
+		}
+		if model == "" {
+			model = s.policy.DefaultModel(provider)
+		}
+		return AIModelSelection{Provider: provider, Model: model}
+	}
+
+	provider := s.policy.ProviderChain("")[0]
+	return AIModelSelection{Provider: provider, Model: s.policy.DefaultModel(provider)}
+}
+
+// GetUserSettings returns a user's default models, empty if none are saved
+func (s *aiModelSettingsServiceImpl) GetUserSettings(clerkUserID string) (*models.AIModelDefaults, error) {
+	var settings models.UserAISettings
+	if err := s.db.Where("clerk_user_id = ?", clerkUserID).Limit(1).Find(&settings).Error; err != nil {
+		log.Error().
+			Err(err).
+			Str("clerkUserId", clerkUserID).
+			Msg("Failed to fetch user AI settings")
+		return nil, fmt.Errorf("failed to fetch user AI settings: %w", err)
+	}
+	return &settings.AIModelDefaults, nil
+}
+
+// SetUserSettings saves a user's default models
+func (s *aiModelSettingsServiceImpl) SetUserSettings(clerkUserID string, defaults models.AIModelDefaults) error {
+	defaults, err := normalizeAIModelDefaults(defaults)
+	if err != nil {
+		return err
+	}
+
+	settings := models.UserAISettings{ClerkUserID: clerkUserID}
+	if err := s.db.Where(models.UserAISettings{ClerkUserID: clerkUserID}).
+		Assign(aiModelDefaultsColumns(defaults)).
+		FirstOrCreate(&settings).Error; err != nil {
+		return fmt.Errorf("failed to save user AI settings: %w", err)
+	}
+	return nil
+}
+
+// GetOrgSettings returns an organization's default models, empty if none are saved
+func (s *aiModelSettingsServiceImpl) GetOrgSettings(organizationID string) (*models.AIModelDefaults, error) {
+	var settings models.OrganizationAISettings
+	if err := s.db.Where("organization_id = ?", organizationID).Limit(1).Find(&settings).Error; err != nil {
+		log.Error().
+			Err(err).
+			Str("organizationId", organizationID).
+			Msg("Failed to fetch organization AI settings")
+		return nil, fmt.Errorf("failed to fetch organization AI settings: %w", err)
+	}
+	return &settings.AIModelDefaults, nil
+}
+
+// SetOrgSettings saves an organization's default models
+func (s *aiModelSettingsServiceImpl) SetOrgSettings(organizationID string, defaults models.AIModelDefaults, updatedBy string) error {
+	defaults, err := normalizeAIModelDefaults(defaults)
+	if err != nil {
+		return err
+	}
+
+	columns := aiModelDefaultsColumns(defaults)
+	columns["updated_by"] = updatedBy
+
+	settings := models.OrganizationAISettings{OrganizationID: organizationID}
+	if err := s.db.Where(models.OrganizationAISettings{OrganizationID: organizationID}).
+		Assign(columns).
+		FirstOrCreate(&settings).Error; err != nil {
+		return fmt.Errorf("failed to save organization AI settings: %w", err)
+	}
+	return nil
+}
+
+// normalizeAIModelDefaults trims the settings and checks every provider is supported
+func normalizeAIModelDefaults(defaults models.AIModelDefaults) (models.AIModelDefaults, error) {
+	pairs := []struct {
+		provider *string
+		model    *string
+	}{
+		{&defaults.ChatProvider, &defaults.ChatModel},
+		{&defaults.GenerateProvider, &defaults.GenerateModel},
+		{&defaults.BackgroundProvider, &defaults.BackgroundModel},
+	}
+
+	for _, pair := range pairs {
+		*pair.provider = strings.ToLower(strings.TrimSpace(*pair.provider))
+		*pair.model = strings.TrimSpace(*pair.model)
+
+		if *pair.provider != "" && !config.IsSupportedAIProvider(*pair.provider) {
+			return defaults, fmt.Errorf("%w: unsupported provider %s", ErrInvalidAIModelSettings, *pair.provider)
+		}
+		if *pair.model != "" && *pair.provider == "" {
+			return defaults, fmt.Errorf("%w: a provider is required when setting a model", ErrInvalidAIModelSettings)
+		}
+	}
+
+	return defaults, nil
+}
+
+// aiModelDefaultsColumns maps the settings to columns so empty values clear previous settings
+func aiModelDefaultsColumns(defaults models.AIModelDefaults) map[string]interface{} {
+	return map[string]interface{}{
+		"chat_provider":       defaults.ChatProvider,
+		"chat_model":          defaults.ChatModel,
+		"generate_provider":   defaults.GenerateProvider,
+		"generate_model":      defaults.GenerateModel,
+		"background_provider": defaults.BackgroundProvider,
+		"background_model":    defaults.BackgroundModel,
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestModelSettingsService creates a model settings service backed by an in-memory database
+func setupTestModelSettingsService(t *testing.T) *aiModelSettingsServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.UserAISettings{}, &models.OrganizationAISettings{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &aiModelSettingsServiceImpl{
+		db:     db,
+		policy: setupTestPolicy(t),
+	}
+}
+
+func TestResolveModel_UsesServerDefaults(t *testing.T) {
+	service := setupTestModelSettingsService(t)
+
+	selection := service.ResolveModel("user_1", nil, models.AIModelPurposeChat)
+	assert.Equal(t, AIModelSelection{Provider: "openai", Model: "gpt-4o-mini"}, selection)
+
+	// System calls without a user also get the server defaults
+	selection = service.ResolveModel("", nil, models.AIModelPurposeBackground)
+	assert.Equal(t, AIModelSelection{Provider: "openai", Model: "gpt-4o-mini"}, selection)
+}
+
+func TestResolveModel_PrefersOrgOverUser(t *testing.T) {
+	service := setupTestModelSettingsService(t)
+	orgID := "org_1"
+
+	require.NoError(t, service.SetUserSettings("user_1", models.AIModelDefaults{
+		ChatProvider:     "anthropic",
+		ChatModel:        "claude-sonnet-4-5",
+		GenerateProvider: "anthropic",
+	}))
+	require.NoError(t, service.SetOrgSettings(orgID, models.AIModelDefaults{
+		ChatProvider: "openai",
+		ChatModel:    "gpt-4o",
+	}, "admin_1"))
+
+	// Personal workspace uses the user's settings
+	selection := service.ResolveModel("user_1", nil, models.AIModelPurposeChat)
+	assert.Equal(t, AIModelSelection{Provider: "anthropic", Model: "claude-sonnet-4-5"}, selection)
+
+	// Organization settings win in the organization workspace
+	selection = service.ResolveModel("user_1", &orgID, models.AIModelPurposeChat)
+	assert.Equal(t, AIModelSelection{Provider: "openai", Model: "gpt-4o"}, selection)
+
+	// Purposes the organization leaves unset fall through to the user, with the provider's default model
+	selection = service.ResolveModel("user_1", &orgID, models.AIModelPurposeGenerate)
+	assert.Equal(t, AIModelSelection{Provider: "anthropic", Model: "claude-haiku-4-5"}, selection)
+}
+
+func TestSetUserSettings_OverwritesAndClears(t *testing.T) {
+	service := setupTestModelSettingsService(t)
+
+	require.NoError(t, service.SetUserSettings("user_1", models.AIModelDefaults{
+		ChatProvider:       " Anthropic ",
+		BackgroundProvider: "google",
+		BackgroundModel:    "gemini-2.5-flash-lite",
+	}))
+	require.NoError(t, service.SetUserSettings("user_1", models.AIModelDefaults{
+		ChatProvider: "anthropic",
+	}))
+
+	settings, err := service.GetUserSettings("user_1")
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", settings.ChatProvider)
+	assert.Empty(t, settings.BackgroundProvider, "Empty values should clear previous settings")
+	assert.Empty(t, settings.BackgroundModel)
+}
+
+func TestSetUserSettings_RejectsInvalidSettings(t *testing.T) {
+	service := setupTestModelSettingsService(t)
+
+	err := service.SetUserSettings("user_1", models.AIModelDefaults{ChatProvider: "mistral"})
+	assert.ErrorIs(t, err, ErrInvalidAIModelSettings)
+
+	err = service.SetUserSettings("user_1", models.AIModelDefaults{GenerateModel: "gpt-4o"})
+	assert.ErrorIs(t, err, ErrInvalidAIModelSettings)
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"backend/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/genai"
+)
+
+// ErrAICircuitOpen is returned when a provider is skipped because its circuit breaker is open
+var ErrAICircuitOpen = errors.New("AI provider circuit is open")
+
+// AIProviderPolicy controls provider fallback order, retries and circuit breaking for AI calls
+type AIProviderPolicy struct {
+	config   *config.AIConfig
+	circuits map[string]*providerCircuit
+	mu       sync.Mutex
+}
+
+// providerCircuit tracks consecutive transient failures for a single provider
+type providerCircuit struct {
+	failures  int
+	openUntil time.Time
+}
+
+var (
+	aiProviderPolicy     *AIProviderPolicy
+	aiProviderPolicyOnce sync.Once
+)
+
+// GetAIProviderPolicy returns the shared provider policy so circuit state is common to all AI callers
+func GetAIProviderPolicy() *AIProviderPolicy {
+	aiProviderPolicyOnce.Do(func() {
+		aiProviderPolicy = NewAIProviderPolicy(config.LoadAIConfig())
+	})
+	return aiProviderPolicy
+}
+
+// NewAIProviderPolicy creates a provider policy from the given configuration
+func NewAIProviderPolicy(cfg *config.AIConfig) *AIProviderPolicy {
+	return &AIProviderPolicy{
+		config:   cfg,
+		circuits: make(map[string]*providerCircuit),
+	}
+}
+
+// ProviderChain returns the providers to try in order, starting with the preferred provider if given
+func (p *AIProviderPolicy) ProviderChain(preferred string) []string {
+	chain := make([]string, 0, len(p.config.ProviderChain)+1)
+	if preferred != "" {
+		chain = append(chain, preferred)
+	}
+	for _, provider := range p.config.ProviderChain {
+		if provider != preferred {
+			chain = append(chain, provider)
+		}
+	}
+	return chain
+}
+
+// DefaultModel returns the model used when falling back to a provider
+func (p *AIProviderPolicy) DefaultModel(provider string) string {
+	return p.config.Models[provider]
+}
+
+// Allow reports whether the provider's circuit currently accepts requests.
+// Once the cooldown has passed a trial request is let through; another failure reopens the circuit.
+func (p *AIProviderPolicy) Allow(provider string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	circuit, exists := p.circuits[provider]
+	if !exists {
+		return true
+	}
+	return !time.Now().Before(circuit.openUntil)
+}
+
+// RecordSuccess closes the provider's circuit
+func (p *AIProviderPolicy) RecordSuccess(provider string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.circuits, provider)
+}
+
+// RecordFailure counts a transient failure and opens the circuit once the threshold is reached
+func (p *AIProviderPolicy) RecordFailure(provider string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	circuit, exists := p.circuits[provider]
+	if !exists {
+		circuit = &providerCircuit{}
+		p.circuits[provider] = circuit
+	}
+
+	circuit.failures++
+	if circuit.failures >= p.config.CircuitFailureThreshold {
+		circuit.openUntil = time.Now().Add(p.config.CircuitCooldown)
+		log.Warn().
+			Str("provider", provider).
+			Int("failures", circuit.failures).
+			Dur("cooldown", p.config.CircuitCooldown).
+			Msg("AI provider circuit opened")
+	}
+}
+
+// Do calls fn for a single provider, retrying transient errors with exponential backoff
+func (p *AIProviderPolicy) Do(ctx context.Context, provider string, fn func(ctx context.Context) error) error {
+	if !p.Allow(provider) {
+		return fmt.Errorf("%w: %s", ErrAICircuitOpen, provider)
+	}
+
+	var err error
+	for attempt := 0; attempt <= p.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := p.backoff(attempt)
+			log.Debug().
+				Str("provider", provider).
+				Int("attempt", attempt).
+				Dur("delay", delay).
+				Msg("Retrying AI provider after transient error")
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		err = fn(ctx)
+		if err == nil {
+			p.RecordSuccess(provider)
+			return nil
+		}
+
+		// Permanent errors (bad request, invalid key) say nothing about provider health
+		if !IsTransientAIError(err) {
+			return err
+		}
+
+		p.RecordFailure(provider)
+		if !p.Allow(provider) {
+			break
+		}
+	}
+
+	return err
+}
+
+// Run tries each provider in order until fn succeeds and returns the provider that handled the call
+func (p *AIProviderPolicy) Run(ctx context.Context, providers []string, fn func(ctx context.Context, provider string) error) (string, error) {
+	var lastErr error
+	for _, provider := range providers {
+		err := p.Do(ctx, provider, func(ctx context.Context) error {
+			return fn(ctx, provider)
+		})
+		if err == nil {
+			return provider, nil
+		}
+
+		// Stop early if the caller went away, there is nobody to fall back for
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		log.Warn().
+			Err(err).
+			Str("provider", provider).
+			Msg("AI provider failed, trying next provider")
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return "", fmt.Errorf("no AI providers configured")
+	}
+	return "", fmt.Errorf("all AI providers failed: %w", lastErr)
+}
+
+// backoff returns the delay before a retry, doubling each attempt with jitter
+func (p *AIProviderPolicy) backoff(attempt int) time.Duration {
+	delay := p.config.InitialBackoff << (attempt - 1)
+	if delay > p.config.MaxBackoff || delay <= 0 {
+		delay = p.config.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// Up to 50% jitter so concurrent callers don't retry in lockstep
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// IsTransientAIError reports whether an AI provider error is worth retrying
+// (rate limiting, overload, server errors and network timeouts)
+func IsTransientAIError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return isTransientStatusCode(openaiErr.StatusCode)
+	}
+
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return isTransientStatusCode(anthropicErr.StatusCode)
+	}
+
+	var googleErr genai.APIError
+	if errors.As(err, &googleErr) {
+		return isTransientStatusCode(googleErr.Code)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isTransientStatusCode reports whether an HTTP status from a provider is temporary
+func isTransientStatusCode(statusCode int) bool {
+	return statusCode == 408 || statusCode == 409 || statusCode == 429 || statusCode >= 500
+}
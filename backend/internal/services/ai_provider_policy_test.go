@@ -0,0 +1,139 @@
+package services
+
+import (
+	"backend/internal/config"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// setupTestPolicy creates a provider policy with fast retries for testing
+func setupTestPolicy(t *testing.T) *AIProviderPolicy {
+	t.Helper()
+
+	return NewAIProviderPolicy(&config.AIConfig{
+		ProviderChain: []string{"openai", "anthropic"},
+		Models: map[string]string{
+			"openai":    "gpt-4o-mini",
+			"anthropic": "claude-haiku-4-5",
+		},
+		MaxRetries:              2,
+		InitialBackoff:          time.Millisecond,
+		MaxBackoff:              2 * time.Millisecond,
+		CircuitFailureThreshold: 3,
+		CircuitCooldown:         time.Minute,
+	})
+}
+
+// transientTestError simulates a provider rate limit
+var transientTestError = genai.APIError{Code: 429, Message: "rate limited"}
+
+func TestProviderChain(t *testing.T) {
+	policy := setupTestPolicy(t)
+
+	assert.Equal(t, []string{"openai", "anthropic"}, policy.ProviderChain(""))
+	assert.Equal(t, []string{"anthropic", "openai"}, policy.ProviderChain("anthropic"))
+	assert.Equal(t, []string{"google", "openai", "anthropic"}, policy.ProviderChain("google"))
+}
+
+func TestDo_RetriesTransientErrors(t *testing.T) {
+	policy := setupTestPolicy(t)
+
+	calls := 0
+	err := policy.Do(context.Background(), "openai", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return transientTestError
+		}
+		return nil
+	})
+
+	require.NoError(t, err, "Do should succeed once the transient errors stop")
+	assert.Equal(t, 3, calls, "Do should retry up to MaxRetries times")
+	assert.True(t, policy.Allow("openai"), "A success should close the circuit")
+}
+
+func TestDo_DoesNotRetryPermanentErrors(t *testing.T) {
+	policy := setupTestPolicy(t)
+
+	calls := 0
+	permanentErr := errors.New("invalid request")
+	err := policy.Do(context.Background(), "openai", func(ctx context.Context) error {
+		calls++
+		return permanentErr
+	})
+
+	assert.ErrorIs(t, err, permanentErr)
+	assert.Equal(t, 1, calls, "Permanent errors should not be retried")
+}
+
+func TestDo_OpensCircuitAfterThreshold(t *testing.T) {
+	policy := setupTestPolicy(t)
+
+	calls := 0
+	err := policy.Do(context.Background(), "openai", func(ctx context.Context) error {
+		calls++
+		return transientTestError
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+	assert.False(t, policy.Allow("openai"), "Circuit should be open after reaching the failure threshold")
+
+	// Further calls are rejected without reaching the provider
+	err = policy.Do(context.Background(), "openai", func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrAICircuitOpen)
+	assert.Equal(t, 3, calls)
+
+	// Other providers are unaffected
+	assert.True(t, policy.Allow("anthropic"))
+}
+
+func TestRun_FallsBackToNextProvider(t *testing.T) {
+	policy := setupTestPolicy(t)
+
+	var attempted []string
+	provider, err := policy.Run(context.Background(), policy.ProviderChain(""), func(ctx context.Context, provider string) error {
+		attempted = append(attempted, provider)
+		if provider == "openai" {
+			return transientTestError
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic", provider)
+	assert.Equal(t, []string{"openai", "openai", "openai", "anthropic"}, attempted)
+}
+
+func TestRun_AllProvidersFail(t *testing.T) {
+	policy := setupTestPolicy(t)
+
+	permanentErr := errors.New("no API key")
+	_, err := policy.Run(context.Background(), policy.ProviderChain(""), func(ctx context.Context, provider string) error {
+		return permanentErr
+	})
+
+	assert.ErrorIs(t, err, permanentErr)
+	assert.Contains(t, err.Error(), "all AI providers failed")
+}
+
+func TestIsTransientAIError(t *testing.T) {
+	assert.False(t, IsTransientAIError(nil))
+	assert.False(t, IsTransientAIError(context.Canceled))
+	assert.False(t, IsTransientAIError(errors.New("invalid request")))
+	assert.False(t, IsTransientAIError(genai.APIError{Code: 401}))
+
+	assert.True(t, IsTransientAIError(genai.APIError{Code: 429}))
+	assert.True(t, IsTransientAIError(genai.APIError{Code: 503}))
+	assert.True(t, IsTransientAIError(&net.DNSError{IsTimeout: true}))
+}
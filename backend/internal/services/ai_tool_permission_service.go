@@ -0,0 +1,236 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAIToolPermissions is returned when tool permissions name a tool the assistant doesn't have
+var ErrInvalidAIToolPermissions = errors.New("invalid AI tool permissions")
+
+// aiAssistantTools lists every assistant tool and whether it only reads data
+var aiAssistantTools = map[string]bool{
+	"searchNotes":           true,
+	"listNotebooks":         true,
+	"listChapters":          true,
+	"getNoteContent":        true,
+	"getNoteContentPage":    true,
+	"listNotesInChapter":    true,
+	"createNotebook":        false,
+	"createChapter":         false,
+	"renameNotebook":        false,
+	"renameChapter":         false,
+	"createNote":            false,
+	"moveNote":              false,
+	"moveChapter":           false,
+	"renameNote":            false,
+	"deleteNote":            false,
+	"updateNoteContent":     false,
+	"generateNoteVideo":     false,
+	"deleteNoteVideo":       false,
+	"listBoards":            true,
+	"createTask":            false,
+	"moveTaskToColumn":      false,
+	"assignTask":            false,
+	"completeTask":          false,
+	"listUpcomingMeetings":  true,
+	"scheduleBotForMeeting": false,
+	"getMeetingSummary":     true,
+}
+
+// aiContinuationTools maps tools that read further pages of another tool's result to that tool, so
+// disabling the tool disables both
+var aiContinuationTools = map[string]string{
+	"getNoteContentPage": "getNoteContent",
+}
+
+// AIToolPermissionSettings is the API representation of a set of tool permissions
+type AIToolPermissionSettings struct {
+	ReadOnly      bool     `json:"readOnly"`
+	DisabledTools []string `json:"disabledTools"`
+}
+
+// AIToolScope is the effective set of tools the assistant may use for a request
+type AIToolScope struct {
+	ReadOnly bool
+	Disabled map[string]bool
+}
+
+// Allows reports whether the named tool may run in this scope
+func (s AIToolScope) Allows(toolName string) bool {
+	readOnly, known := aiAssistantTools[toolName]
+	if !known {
+		return false
+	}
+	if s.ReadOnly && !readOnly {
+		return false
+	}
+	if parent, ok := aiContinuationTools[toolName]; ok && s.Disabled[parent] {
+		return false
+	}
+	return !s.Disabled[toolName]
+}
+
+// AIToolPermissionService interface defines methods for managing assistant tool permissions
+type AIToolPermissionService interface {
+	ResolveScope(clerkUserID string, organizationID *string) AIToolScope
+	GetUserPermissions(clerkUserID string) (*AIToolPermissionSettings, error)
+	SetUserPermissions(clerkUserID string, settings AIToolPermissionSettings) error
+	GetOrgPermissions(organizationID string) (*AIToolPermissionSettings, error)
+	SetOrgPermissions(organizationID string, settings AIToolPermissionSettings, updatedBy string) error
+}
+
+// aiToolPermissionServiceImpl implements the AIToolPermissionService interface
+type aiToolPermissionServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewAIToolPermissionService creates a new AIToolPermissionService instance
+func NewAIToolPermissionService() AIToolPermissionService {
+	return &aiToolPermissionServiceImpl{
+		db: db.DB,
+	}
+}
+
+// ResolveScope combines the user's and, in an organization workspace, the organization's permissions.
+// A tool is only allowed if neither disables it. If the settings can't be loaded the assistant is read-only.
+func (s *aiToolPermissionServiceImpl) ResolveScope(clerkUserID string, organizationID *string) AIToolScope {
+	scope := AIToolScope{Disabled: make(map[string]bool)}
+
+	layers := make([]*AIToolPermissionSettings, 0, 2)
+	userPermissions, err := s.GetUserPermissions(clerkUserID)
+	if err != nil {
+		return AIToolScope{ReadOnly: true, Disabled: scope.Disabled}
+	}
+	layers = append(layers, userPermissions)
+
+	if organizationID != nil && *organizationID != "" {
+		orgPermissions, err := s.GetOrgPermissions(*organizationID)
+		if err != nil {
+			return AIToolScope{ReadOnly: true, Disabled: scope.Disabled}
+		}
+		layers = append(layers, orgPermissions)
+	}
+
+	for _, layer := range layers {
+		scope.ReadOnly = scope.ReadOnly || layer.ReadOnly
+		for _, tool := range layer.DisabledTools {
+			scope.Disabled[tool] = true
+		}
+	}
+
+	return scope
+}
+
+// GetUserPermissions returns a user's tool permissions, allowing everything if none are saved
+func (s *aiToolPermissionServiceImpl) GetUserPermissions(clerkUserID string) (*AIToolPermissionSettings, error) {
+	var settings models.UserAISettings
+	if err := s.db.Where("clerk_user_id = ?", clerkUserID).Limit(1).Find(&settings).Error; err != nil {
+		log.Error().
+			Err(err).
+			Str("clerkUserId", clerkUserID).
+			Msg("Failed to fetch user AI tool permissions")
+		return nil, fmt.Errorf("failed to fetch user AI tool permissions: %w", err)
+	}
+	return toAIToolPermissionSettings(settings.AIToolPermissions), nil
+}
+
+// SetUserPermissions saves a user's tool permissions
+func (s *aiToolPermissionServiceImpl) SetUserPermissions(clerkUserID string, settings AIToolPermissionSettings) error {
+	permissions, err := fromAIToolPermissionSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	record := models.UserAISettings{ClerkUserID: clerkUserID}
+	if err := s.db.Where(models.UserAISettings{ClerkUserID: clerkUserID}).
+		Assign(aiToolPermissionsColumns(permissions)).
+		FirstOrCreate(&record).Error; err != nil {
+		return fmt.Errorf("failed to save user AI tool permissions: %w", err)
+	}
+	return nil
+}
+
+// GetOrgPermissions returns an organization's tool permissions, allowing everything if none are saved
+func (s *aiToolPermissionServiceImpl) GetOrgPermissions(organizationID string) (*AIToolPermissionSettings, error) {
+	var settings models.OrganizationAISettings
+	if err := s.db.Where("organization_id = ?", organizationID).Limit(1).Find(&settings).Error; err != nil {
+		log.Error().
+			Err(err).
+			Str("organizationId", organizationID).
+			Msg("Failed to fetch organization AI tool permissions")
+		return nil, fmt.Errorf("failed to fetch organization AI tool permissions: %w", err)
+	}
+	return toAIToolPermissionSettings(settings.AIToolPermissions), nil
+}
+
+// SetOrgPermissions saves an organization's tool permissions
+func (s *aiToolPermissionServiceImpl) SetOrgPermissions(organizationID string, settings AIToolPermissionSettings, updatedBy string) error {
+	permissions, err := fromAIToolPermissionSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	columns := aiToolPermissionsColumns(permissions)
+	columns["updated_by"] = updatedBy
+
+	record := models.OrganizationAISettings{OrganizationID: organizationID}
+	if err := s.db.Where(models.OrganizationAISettings{OrganizationID: organizationID}).
+		Assign(columns).
+		FirstOrCreate(&record).Error; err != nil {
+		return fmt.Errorf("failed to save organization AI tool permissions: %w", err)
+	}
+	return nil
+}
+
+// toAIToolPermissionSettings converts stored permissions to their API representation
+func toAIToolPermissionSettings(permissions models.AIToolPermissions) *AIToolPermissionSettings {
+	disabled := []string{}
+	for _, tool := range strings.Split(permissions.DisabledTools, ",") {
+		if tool = strings.TrimSpace(tool); tool != "" {
+			disabled = append(disabled, tool)
+		}
+	}
+	return &AIToolPermissionSettings{
+		ReadOnly:      permissions.ToolsReadOnly,
+		DisabledTools: disabled,
+	}
+}
+
+// fromAIToolPermissionSettings validates tool names and converts the settings for storage
+func fromAIToolPermissionSettings(settings AIToolPermissionSettings) (models.AIToolPermissions, error) {
+	seen := make(map[string]bool)
+	disabled := make([]string, 0, len(settings.DisabledTools))
+	for _, tool := range settings.DisabledTools {
+		tool = strings.TrimSpace(tool)
+		if tool == "" || seen[tool] {
+			continue
+		}
+		if _, known := aiAssistantTools[tool]; !known {
+			return models.AIToolPermissions{}, fmt.Errorf("%w: unknown tool %s", ErrInvalidAIToolPermissions, tool)
+		}
+		seen[tool] = true
+		disabled = append(disabled, tool)
+	}
+	sort.Strings(disabled)
+
+	return models.AIToolPermissions{
+		ToolsReadOnly: settings.ReadOnly,
+		DisabledTools: strings.Join(disabled, ","),
+	}, nil
+}
+
+// aiToolPermissionsColumns maps the permissions to columns for upserts
+func aiToolPermissionsColumns(permissions models.AIToolPermissions) map[string]interface{} {
+	return map[string]interface{}{
+		"tools_read_only": permissions.ToolsReadOnly,
+		"disabled_tools":  permissions.DisabledTools,
+	}
+}
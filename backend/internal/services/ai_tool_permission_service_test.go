@@ -0,0 +1,90 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestToolPermissionService creates a tool permission service backed by an in-memory database
+func setupTestToolPermissionService(t *testing.T) *aiToolPermissionServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.UserAISettings{}, &models.OrganizationAISettings{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &aiToolPermissionServiceImpl{db: db}
+}
+
+func TestResolveScope_AllowsEverythingByDefault(t *testing.T) {
+	service := setupTestToolPermissionService(t)
+
+	scope := service.ResolveScope("user_1", nil)
+	assert.True(t, scope.Allows("deleteNote"))
+	assert.True(t, scope.Allows("searchNotes"))
+	assert.False(t, scope.Allows("dropDatabase"), "Unknown tools are never allowed")
+}
+
+func TestResolveScope_CombinesUserAndOrgRestrictions(t *testing.T) {
+	service := setupTestToolPermissionService(t)
+	orgID := "org_1"
+
+	require.NoError(t, service.SetUserPermissions("user_1", AIToolPermissionSettings{
+		DisabledTools: []string{"generateNoteVideo"},
+	}))
+	require.NoError(t, service.SetOrgPermissions(orgID, AIToolPermissionSettings{
+		DisabledTools: []string{"deleteNote", "updateNoteContent"},
+	}, "admin_1"))
+
+	// Organization restrictions only apply in the organization workspace
+	personal := service.ResolveScope("user_1", nil)
+	assert.True(t, personal.Allows("deleteNote"))
+	assert.False(t, personal.Allows("generateNoteVideo"))
+
+	org := service.ResolveScope("user_1", &orgID)
+	assert.False(t, org.Allows("deleteNote"))
+	assert.False(t, org.Allows("updateNoteContent"))
+	assert.False(t, org.Allows("generateNoteVideo"), "Members' own restrictions still apply")
+	assert.True(t, org.Allows("createNote"))
+}
+
+func TestAIToolScope_ContinuationToolsFollowTheirTool(t *testing.T) {
+	scope := AIToolScope{ReadOnly: true, Disabled: map[string]bool{}}
+	assert.True(t, scope.Allows("getNoteContentPage"), "Reading further pages is read-only")
+
+	scope.Disabled["getNoteContent"] = true
+	assert.False(t, scope.Allows("getNoteContentPage"), "Disabling a tool disables reading its further pages")
+}
+
+func TestResolveScope_ReadOnly(t *testing.T) {
+	service := setupTestToolPermissionService(t)
+	orgID := "org_1"
+
+	require.NoError(t, service.SetOrgPermissions(orgID, AIToolPermissionSettings{ReadOnly: true}, "admin_1"))
+
+	scope := service.ResolveScope("user_1", &orgID)
+	assert.True(t, scope.Allows("searchNotes"))
+	assert.True(t, scope.Allows("getNoteContent"))
+	assert.False(t, scope.Allows("createNote"))
+	assert.False(t, scope.Allows("moveChapter"))
+}
+
+func TestSetUserPermissions_ValidatesTools(t *testing.T) {
+	service := setupTestToolPermissionService(t)
+
+	err := service.SetUserPermissions("user_1", AIToolPermissionSettings{DisabledTools: []string{"dropDatabase"}})
+	assert.ErrorIs(t, err, ErrInvalidAIToolPermissions)
+
+	require.NoError(t, service.SetUserPermissions("user_1", AIToolPermissionSettings{
+		DisabledTools: []string{" deleteNote ", "createNote", "deleteNote"},
+	}))
+
+	settings, err := service.GetUserPermissions("user_1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"createNote", "deleteNote"}, settings.DisabledTools)
+}
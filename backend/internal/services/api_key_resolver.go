@@ -3,7 +3,6 @@ package services
 import (
 	"backend/db"
 	"backend/internal/models"
-	"backend/pkg/utils"
 	"fmt"
 	"strings"
 
@@ -17,6 +16,7 @@ type APIKeySource string
 const (
 	APIKeySourceOrganization APIKeySource = "organization"
 	APIKeySourceIndividual   APIKeySource = "individual"
+	APIKeySourceEnvironment  APIKeySource = "environment" // The server's own key, used when neither is set
 )
 
 // APIKeyResult contains the resolved API key and its source
@@ -134,7 +134,7 @@ func (r *apiKeyResolverImpl) getOrganizationAPIKey(organizationID string, provid
 	}
 
 	// Decrypt the API key
-	apiKey, err := utils.Decrypt(credential.KeyCipher)
+	apiKey, err := NewSecretsService().Decrypt(credential.KeyCipher)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -163,7 +163,7 @@ func (r *apiKeyResolverImpl) getIndividualAPIKey(clerkUserID string, provider st
 	}
 
 	// Decrypt the API key
-	apiKey, err := utils.Decrypt(credential.KeyCipher)
+	apiKey, err := NewSecretsService().Decrypt(credential.KeyCipher)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lucsky/cuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// captureInboxName names the notebook, chapter and note snippets are captured to by default
+	captureInboxName       = "Inbox"
+	maxCaptureLength       = 10000
+	captureTimestampFormat = "2006-01-02 15:04"
+	// captureAttempts is how many times an append is retried when the note changed while appending
+	captureAttempts = 3
+)
+
+// CaptureRequest is a snippet captured from a shortcut or launcher such as iOS Shortcuts, Alfred or
+// Raycast. It is appended to NoteID, to the Inbox note of ChapterID, or to the workspace's Inbox.
+type CaptureRequest struct {
+	Text      string `json:"text"`
+	NoteID    string `json:"noteId"`
+	ChapterID string `json:"chapterId"`
+}
+
+// CaptureResult tells where a snippet was captured to
+type CaptureResult struct {
+	NoteID      string    `json:"noteId"`
+	NoteName    string    `json:"noteName"`
+	ChapterID   string    `json:"chapterId"`
+	NotebookID  string    `json:"notebookId"`
+	NoteCreated bool      `json:"noteCreated"` // The Inbox note didn't exist yet
+	CapturedAt  time.Time `json:"capturedAt"`
+}
+
+// Capture appends a snippet to a note as a paragraph starting with the time it was captured, in the
+// user's timezone. Without a target the snippet goes to the Inbox note of the workspace's first Inbox
+// chapter, which is created with an Inbox notebook when there is none. Snippets captured to an Inbox
+// note are listed by ListInbox until they're filed.
+func (s *automationServiceImpl) Capture(ctx context.Context, scope AutomationScope, req CaptureRequest) (*CaptureResult, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: text is required", ErrInvalidAutomationRequest)
+	}
+	if len(text) > maxCaptureLength {
+		return nil, fmt.Errorf("%w: text can be at most %d characters", ErrInvalidAutomationRequest, maxCaptureLength)
+	}
+	nodes, err := markdownNodes(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var note *models.Notes
+	created := false
+	if req.NoteID != "" {
+		note = &models.Notes{}
+		if err := s.scopedNotes(scope).Where("notes.id = ?", req.NoteID).First(note).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrAutomationNotFound
+			}
+			return nil, fmt.Errorf("failed to fetch note: %w", err)
+		}
+	} else {
+		chapter, err := s.captureChapter(ctx, scope, req.ChapterID)
+		if err != nil {
+			return nil, err
+		}
+		note, created, err = s.captureNote(ctx, chapter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	capturedAt := time.Now()
+	timestamp := capturedAt.In(UserLocation(ctx, s.db, scope.ClerkUserID)).Format(captureTimestampFormat)
+	nodes = timestampCapture(nodes, timestamp)
+	// Snippets captured to an Inbox note wait there to be filed, found by the ID of their first block
+	inbox := req.NoteID == ""
+	if inbox {
+		setBlockNodeID(&nodes[0], cuid.New())
+	}
+	if err := s.appendCapture(ctx, note, nodes); err != nil {
+		return nil, err
+	}
+	if inbox {
+		item := models.CaptureItem{
+			ClerkUserID:    scope.ClerkUserID,
+			OrganizationID: scope.OrganizationID,
+			NoteID:         note.ID,
+			BlockID:        blockNodeID(nodes[0]),
+			BlockCount:     len(nodes),
+			Text:           text,
+			Status:         models.CaptureStatusUnfiled,
+		}
+		if err := s.db.WithContext(ctx).Create(&item).Error; err != nil {
+			log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to add captured snippet to the inbox")
+		}
+	}
+
+	// Drop the collaborative document so editors load the captured snippet
+	if err := NewYjsService(s.db).DeleteYjsDocument(note.ID); err != nil {
+		log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to reset Yjs document after capture")
+	}
+	if created {
+		s.NoteCreated(note.ID)
+	}
+	if err := s.SyncNoteTags(note.ID); err != nil {
+		log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to sync note tags")
+	}
+
+	var chapter models.Chapter
+	if err := s.db.WithContext(ctx).Select("id", "notebook_id").Where("id = ?", note.ChapterID).First(&chapter).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+	}
+	return &CaptureResult{
+		NoteID:      note.ID,
+		NoteName:    note.Name,
+		ChapterID:   chapter.ID,
+		NotebookID:  chapter.NotebookID,
+		NoteCreated: created,
+		CapturedAt:  capturedAt,
+	}, nil
+}
+
+// captureChapter returns the chapter snippets go to: the one asked for, or the workspace's first Inbox
+// chapter, created in a new Inbox notebook when there is none
+func (s *automationServiceImpl) captureChapter(ctx context.Context, scope AutomationScope, chapterID string) (*models.Chapter, error) {
+	var chapter models.Chapter
+	if chapterID != "" {
+		if err := s.scopedChapters(scope).Where("chapters.id = ?", chapterID).First(&chapter).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrAutomationNotFound
+			}
+			return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+		}
+		return &chapter, nil
+	}
+
+	err := s.scopedChapters(scope).Where("chapters.name = ?", captureInboxName).
+		Order("chapters.created_at ASC").First(&chapter).Error
+	if err == nil {
+		return &chapter, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch inbox chapter: %w", err)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		notebook := models.Notebook{Name: captureInboxName, ClerkUserID: scope.ClerkUserID, OrganizationID: scope.OrganizationID}
+		if err := tx.Create(&notebook).Error; err != nil {
+			return err
+		}
+		chapter = models.Chapter{Name: captureInboxName, NotebookID: notebook.ID, OrganizationID: scope.OrganizationID}
+		return tx.Create(&chapter).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inbox: %w", err)
+	}
+	log.Info().Str("user_id", scope.ClerkUserID).Str("chapter_id", chapter.ID).Msg("Created inbox for captured snippets")
+	return &chapter, nil
+}
+
+// captureNote returns the chapter's first Inbox note, creating it when there is none
+func (s *automationServiceImpl) captureNote(ctx context.Context, chapter *models.Chapter) (*models.Notes, bool, error) {
+	var note models.Notes
+	err := s.db.WithContext(ctx).Where("chapter_id = ? AND name = ? AND locked = ?", chapter.ID, captureInboxName, false).
+		Order("created_at ASC").First(&note).Error
+	if err == nil {
+		return &note, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to fetch inbox note: %w", err)
+	}
+
+	note = models.Notes{
+		Name:           captureInboxName,
+		Content:        ApplyChapterNoteDefaults(chapter, ""),
+		ChapterID:      chapter.ID,
+		OrganizationID: chapter.OrganizationID,
+	}
+	if err := s.db.WithContext(ctx).Create(&note).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to create inbox note: %w", err)
+	}
+	return &note, true, nil
+}
+
+// appendCapture appends blocks to a note. The update only applies to the version of the note it was
+// made from, so snippets captured at the same time don't overwrite each other.
+func (s *automationServiceImpl) appendCapture(ctx context.Context, note *models.Notes, nodes []utils.TipTapNode) error {
+	for attempt := 0; attempt < captureAttempts; attempt++ {
+		content, err := appendNodesToTipTap(note.Content, nodes)
+		if err != nil {
+			return err
+		}
+		result := s.db.WithContext(ctx).Model(&models.Notes{}).
+			Where("id = ? AND updated_at = ?", note.ID, note.UpdatedAt).Update("content", content)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update note: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+		if err := s.db.WithContext(ctx).Where("id = ?", note.ID).First(note).Error; err != nil {
+			return fmt.Errorf("failed to fetch note: %w", err)
+		}
+	}
+	return fmt.Errorf("failed to update note: it kept changing while capturing")
+}
+
+// timestampCapture starts a captured snippet with the time in bold, in its first paragraph when it
+// starts with one
+func timestampCapture(nodes []utils.TipTapNode, timestamp string) []utils.TipTapNode {
+	stamp := utils.TipTapNode{Type: "text", Text: timestamp, Marks: []utils.TipTapMark{{Type: "bold"}}}
+	if len(nodes) > 0 && nodes[0].Type == "paragraph" {
+		nodes[0].Content = append([]utils.TipTapNode{stamp, {Type: "text", Text: " "}}, nodes[0].Content...)
+		return nodes
+	}
+	return append([]utils.TipTapNode{{Type: "paragraph", Content: []utils.TipTapNode{stamp}}}, nodes...)
+}
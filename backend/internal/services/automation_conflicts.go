@@ -0,0 +1,151 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AutomationNoteConflict is a sync client's update refused because the note changed on the server, with
+// both versions and a merge of them as Markdown. The client resolves it by uploading its resolution
+// with ServerUpdatedAt, or dismisses it to keep the server's version.
+type AutomationNoteConflict struct {
+	ID              string    `json:"id"`
+	NoteID          string    `json:"noteId"`
+	Name            string    `json:"name"`
+	Base            string    `json:"base"`          // The version the client last synced, empty when unknown
+	Local           string    `json:"local"`         // The client's version
+	Server          string    `json:"server"`        // The server's version
+	Merged          string    `json:"merged"`        // Blocks changed on both sides are kept from both, in callouts
+	MergedContent   string    `json:"mergedContent"` // The merge as TipTap JSON
+	ConflictCount   int       `json:"conflictCount"` // Blocks changed on both sides, none when the merge is clean
+	ServerUpdatedAt time.Time `json:"serverUpdatedAt"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// AutomationConflictError is ErrAutomationConflict with the conflict kept for the client to resolve
+type AutomationConflictError struct {
+	Conflict *AutomationNoteConflict
+}
+
+func (e *AutomationConflictError) Error() string {
+	return ErrAutomationConflict.Error()
+}
+
+func (e *AutomationConflictError) Unwrap() error {
+	return ErrAutomationConflict
+}
+
+// recordNoteConflict keeps a refused update with a merge of it and the note, replacing the client's
+// earlier conflict on the note
+func (s *automationServiceImpl) recordNoteConflict(ctx context.Context, scope AutomationScope, note *models.Notes, content string, baseContent *string) (*AutomationNoteConflict, error) {
+	local, err := utils.MarkdownToTipTap(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: content could not be converted: %v", ErrInvalidAutomationRequest, err)
+	}
+	var base string
+	if baseContent != nil {
+		if base, err = utils.MarkdownToTipTap(*baseContent); err != nil {
+			return nil, fmt.Errorf("%w: base content could not be converted: %v", ErrInvalidAutomationRequest, err)
+		}
+	}
+	merged, conflicts, err := MergeTipTapContent(base, local, note.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge note: %w", err)
+	}
+
+	conflict := models.NoteConflict{NoteID: note.ID, APIKeyID: scope.APIKeyID}
+	err = s.db.WithContext(ctx).
+		Where("note_id = ? AND api_key_id = ?", note.ID, scope.APIKeyID).
+		FirstOrInit(&conflict).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch note conflict: %w", err)
+	}
+	conflict.BaseContent = base
+	conflict.LocalContent = local
+	conflict.ServerContent = note.Content
+	conflict.MergedContent = merged
+	conflict.ConflictCount = conflicts
+	conflict.ServerUpdatedAt = note.UpdatedAt
+	if err := s.db.WithContext(ctx).Save(&conflict).Error; err != nil {
+		return nil, fmt.Errorf("failed to save note conflict: %w", err)
+	}
+	conflict.Note = note
+	return toAutomationNoteConflict(&conflict), nil
+}
+
+// ListNoteConflicts returns the API key's conflicts waiting to be resolved, oldest first
+func (s *automationServiceImpl) ListNoteConflicts(ctx context.Context, scope AutomationScope) ([]AutomationNoteConflict, error) {
+	var conflicts []models.NoteConflict
+	if err := s.scopedNoteConflicts(ctx, scope).Preload("Note").Order("note_conflicts.created_at ASC").Find(&conflicts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note conflicts: %w", err)
+	}
+	results := make([]AutomationNoteConflict, len(conflicts))
+	for i := range conflicts {
+		results[i] = *toAutomationNoteConflict(&conflicts[i])
+	}
+	return results, nil
+}
+
+// GetNoteConflict returns one of the API key's conflicts
+func (s *automationServiceImpl) GetNoteConflict(ctx context.Context, scope AutomationScope, conflictID string) (*AutomationNoteConflict, error) {
+	var conflict models.NoteConflict
+	if err := s.scopedNoteConflicts(ctx, scope).Preload("Note").Where("note_conflicts.id = ?", conflictID).First(&conflict).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutomationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note conflict: %w", err)
+	}
+	return toAutomationNoteConflict(&conflict), nil
+}
+
+// DismissNoteConflict drops one of the API key's conflicts, keeping the server's version
+func (s *automationServiceImpl) DismissNoteConflict(ctx context.Context, scope AutomationScope, conflictID string) error {
+	if _, err := s.GetNoteConflict(ctx, scope, conflictID); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Where("id = ?", conflictID).Delete(&models.NoteConflict{}).Error; err != nil {
+		return fmt.Errorf("failed to delete note conflict: %w", err)
+	}
+	return nil
+}
+
+// scopedNoteConflicts selects the API key's conflicts on notes it can still reach
+func (s *automationServiceImpl) scopedNoteConflicts(ctx context.Context, scope AutomationScope) *gorm.DB {
+	return s.db.WithContext(ctx).Model(&models.NoteConflict{}).
+		Where("note_conflicts.api_key_id = ?", scope.APIKeyID).
+		Where("note_conflicts.note_id IN (?)", s.scopedNotes(scope).Select("notes.id"))
+}
+
+// toAutomationNoteConflict converts a conflict loaded with its note
+func toAutomationNoteConflict(conflict *models.NoteConflict) *AutomationNoteConflict {
+	var name string
+	if conflict.Note != nil {
+		name = conflict.Note.Name
+	}
+	markdown := func(content string) string {
+		converted, err := utils.TipTapToMarkdown(content)
+		if err != nil {
+			return ""
+		}
+		return converted
+	}
+	return &AutomationNoteConflict{
+		ID:              conflict.ID,
+		NoteID:          conflict.NoteID,
+		Name:            name,
+		Base:            markdown(conflict.BaseContent),
+		Local:           markdown(conflict.LocalContent),
+		Server:          markdown(conflict.ServerContent),
+		Merged:          markdown(conflict.MergedContent),
+		MergedContent:   conflict.MergedContent,
+		ConflictCount:   conflict.ConflictCount,
+		ServerUpdatedAt: conflict.ServerUpdatedAt,
+		CreatedAt:       conflict.CreatedAt,
+	}
+}
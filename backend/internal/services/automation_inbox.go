@@ -0,0 +1,397 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// maxFiledCaptureNameLength caps the name of a note filed from a snippet's first line
+const maxFiledCaptureNameLength = 80
+
+// errInboxNoteChanged is returned inside a filing transaction when the Inbox note changed while filing
+var errInboxNoteChanged = errors.New("inbox note changed")
+
+// InboxItem is a captured snippet waiting in an Inbox note to be filed
+type InboxItem struct {
+	ID         string    `json:"id"`
+	Text       string    `json:"text"`   // The snippet as it reads in the Inbox note now
+	NoteID     string    `json:"noteId"` // The Inbox note
+	CapturedAt time.Time `json:"capturedAt"`
+}
+
+// InboxFileRequest files a snippet as a note of a chapter. With Suggest and no ChapterID, the chapter
+// and, without a Title, the note's name are picked by AI.
+type InboxFileRequest struct {
+	ChapterID string `json:"chapterId"`
+	Title     string `json:"title"`
+	Suggest   bool   `json:"suggest"`
+}
+
+// InboxSuggestion is where AI suggests filing a snippet
+type InboxSuggestion struct {
+	ChapterID    string `json:"chapterId"`
+	ChapterName  string `json:"chapterName"`
+	NotebookID   string `json:"notebookId"`
+	NotebookName string `json:"notebookName"`
+	Title        string `json:"title"`
+}
+
+// inboxCapture is an unfiled snippet with its Inbox note and its top level blocks there, start to end
+type inboxCapture struct {
+	item       models.CaptureItem
+	note       models.Notes
+	doc        utils.TipTapDoc
+	start, end int
+}
+
+// inboxChapter is a chapter snippets can be filed to
+type inboxChapter struct {
+	ID           string
+	Name         string
+	NotebookID   string
+	NotebookName string
+}
+
+// ListInbox lists the snippets captured to the workspace's Inbox notes that haven't been filed, newest
+// first. Snippets deleted from their Inbox note are dropped from the inbox.
+func (s *automationServiceImpl) ListInbox(ctx context.Context, scope AutomationScope) ([]InboxItem, error) {
+	var items []models.CaptureItem
+	if err := s.scopedCaptures(scope).WithContext(ctx).Where("status = ?", models.CaptureStatusUnfiled).
+		Order("created_at DESC").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch inbox: %w", err)
+	}
+
+	noteIDs := make([]string, 0, len(items))
+	starts := make(map[string]map[string]bool)
+	for _, item := range items {
+		if starts[item.NoteID] == nil {
+			starts[item.NoteID] = make(map[string]bool)
+			noteIDs = append(noteIDs, item.NoteID)
+		}
+		starts[item.NoteID][item.BlockID] = true
+	}
+	var notes []models.Notes
+	if len(noteIDs) > 0 {
+		if err := s.db.WithContext(ctx).Select("id", "content", "locked").Where("id IN ?", noteIDs).Find(&notes).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch inbox notes: %w", err)
+		}
+	}
+	docs := make(map[string]*utils.TipTapDoc, len(notes))
+	for _, note := range notes {
+		var doc utils.TipTapDoc
+		if note.Locked {
+			docs[note.ID] = nil
+			continue
+		}
+		_ = json.Unmarshal([]byte(note.Content), &doc)
+		docs[note.ID] = &doc
+	}
+
+	result := make([]InboxItem, 0, len(items))
+	var removed []string
+	for _, item := range items {
+		doc, ok := docs[item.NoteID]
+		if ok && doc == nil {
+			continue // The Inbox note was locked, its snippets can't be read until it's unlocked
+		}
+		if !ok {
+			removed = append(removed, item.ID)
+			continue
+		}
+		start, end, found := captureSpan(doc.Content, item, starts[item.NoteID])
+		if !found {
+			removed = append(removed, item.ID)
+			continue
+		}
+		result = append(result, InboxItem{
+			ID:         item.ID,
+			Text:       capturedText(doc.Content[start:end]),
+			NoteID:     item.NoteID,
+			CapturedAt: item.CreatedAt,
+		})
+	}
+
+	if len(removed) > 0 {
+		if err := s.db.WithContext(ctx).Model(&models.CaptureItem{}).Where("id IN ?", removed).
+			Update("status", models.CaptureStatusRemoved).Error; err != nil {
+			log.Warn().Err(err).Int("count", len(removed)).Msg("Failed to drop removed snippets from the inbox")
+		}
+	}
+	return result, nil
+}
+
+// SuggestInboxDestination asks AI which chapter of the workspace a snippet belongs in, leaving out
+// Inbox chapters, and for a name for the note it will be filed as
+func (s *automationServiceImpl) SuggestInboxDestination(ctx context.Context, scope AutomationScope, itemID string) (*InboxSuggestion, error) {
+	capture, err := s.inboxCapture(ctx, scope, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	var chapters []inboxChapter
+	if err := s.scopedChapters(scope).WithContext(ctx).
+		Select("chapters.id, chapters.name, notebooks.id AS notebook_id, notebooks.name AS notebook_name").
+		Where("chapters.name <> ? AND chapters.id <> ?", captureInboxName, capture.note.ChapterID).
+		Order("notebooks.name ASC, chapters.name ASC").Limit(maxCaptureDestinationChapters).
+		Scan(&chapters).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch chapters: %w", err)
+	}
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("%w: there are no chapters to file the snippet to", ErrInvalidAutomationRequest)
+	}
+
+	choices := make([]CaptureDestinationChoice, len(chapters))
+	for i, chapter := range chapters {
+		choices[i] = CaptureDestinationChoice{ChapterID: chapter.ID, NotebookName: chapter.NotebookName, ChapterName: chapter.Name}
+	}
+	destination, err := s.suggestDestination(ctx, CaptureDestinationRequest{
+		Text:     capturedText(capture.doc.Content[capture.start:capture.end]),
+		Chapters: choices,
+		UserID:   scope.ClerkUserID,
+		OrgID:    scope.OrganizationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chapter := range chapters {
+		if chapter.ID == destination.ChapterID {
+			return &InboxSuggestion{
+				ChapterID:    chapter.ID,
+				ChapterName:  chapter.Name,
+				NotebookID:   chapter.NotebookID,
+				NotebookName: chapter.NotebookName,
+				Title:        truncateLinkText(destination.Title, maxFiledCaptureNameLength),
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("suggested chapter %s isn't in the workspace", destination.ChapterID)
+}
+
+// FileCapture moves a snippet out of its Inbox note into a new note of a chapter, without the time it
+// was captured. The note is named after the request's title or the snippet's first line.
+func (s *automationServiceImpl) FileCapture(ctx context.Context, scope AutomationScope, itemID string, req InboxFileRequest) (*AutomationNote, error) {
+	title := strings.TrimSpace(req.Title)
+	if req.ChapterID == "" {
+		if !req.Suggest {
+			return nil, fmt.Errorf("%w: chapterId is required unless suggest is set", ErrInvalidAutomationRequest)
+		}
+		suggestion, err := s.SuggestInboxDestination(ctx, scope, itemID)
+		if err != nil {
+			return nil, err
+		}
+		req.ChapterID = suggestion.ChapterID
+		if title == "" {
+			title = suggestion.Title
+		}
+	}
+
+	var chapter models.Chapter
+	if err := s.scopedChapters(scope).Where("chapters.id = ?", req.ChapterID).First(&chapter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutomationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+	}
+
+	for attempt := 0; attempt < captureAttempts; attempt++ {
+		capture, err := s.inboxCapture(ctx, scope, itemID)
+		if err != nil {
+			return nil, err
+		}
+		note, err := s.fileCapture(ctx, capture, &chapter, title)
+		if errors.Is(err, errInboxNoteChanged) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Drop the collaborative document so editors load the Inbox without the snippet
+		if err := NewYjsService(s.db).DeleteYjsDocument(capture.note.ID); err != nil {
+			log.Warn().Err(err).Str("note_id", capture.note.ID).Msg("Failed to reset Yjs document after filing")
+		}
+		s.NoteCreated(note.ID)
+		for _, noteID := range []string{note.ID, capture.note.ID} {
+			if err := s.SyncNoteTags(noteID); err != nil {
+				log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to sync note tags")
+			}
+		}
+		return s.loadAutomationNote(note.ID)
+	}
+	return nil, fmt.Errorf("failed to file snippet: the Inbox note kept changing")
+}
+
+// fileCapture creates the snippet's note and removes it from the Inbox in one transaction, which fails
+// with errInboxNoteChanged when the Inbox note isn't the version the snippet was read from
+func (s *automationServiceImpl) fileCapture(ctx context.Context, capture *inboxCapture, chapter *models.Chapter, title string) (*models.Notes, error) {
+	nodes := untimestampCapture(capture.doc.Content[capture.start:capture.end])
+	if title == "" {
+		title = strings.TrimSpace(strings.SplitN(capturedText(nodes), "\n", 2)[0])
+	}
+	if title == "" {
+		title = "Captured snippet"
+	}
+
+	content, err := json.Marshal(utils.TipTapDoc{Type: "doc", Content: nodes})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode note content: %w", err)
+	}
+	remaining := append(append([]utils.TipTapNode{}, capture.doc.Content[:capture.start]...), capture.doc.Content[capture.end:]...)
+	inbox := capture.doc
+	inbox.Content = remaining
+	inboxContent, err := json.Marshal(inbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode note content: %w", err)
+	}
+
+	note := models.Notes{
+		Name:           truncateLinkText(title, maxFiledCaptureNameLength),
+		Content:        ApplyChapterNoteDefaults(chapter, string(content)),
+		ChapterID:      chapter.ID,
+		OrganizationID: chapter.OrganizationID,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Notes{}).Where("id = ? AND updated_at = ?", capture.note.ID, capture.note.UpdatedAt).
+			Update("content", string(inboxContent))
+		if result.Error != nil {
+			return fmt.Errorf("failed to update inbox note: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errInboxNoteChanged
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			return fmt.Errorf("failed to create note: %w", err)
+		}
+		filedAt := time.Now()
+		result = tx.Model(&models.CaptureItem{}).Where("id = ? AND status = ?", capture.item.ID, models.CaptureStatusUnfiled).
+			Updates(map[string]interface{}{"status": models.CaptureStatusFiled, "filed_note_id": note.ID, "filed_at": filedAt})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update inbox: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrAutomationNotFound // Filed by another request meanwhile
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// inboxCapture loads an unfiled snippet of the scope and finds its blocks in its Inbox note. Snippets
+// deleted from the note are dropped from the inbox and aren't found.
+func (s *automationServiceImpl) inboxCapture(ctx context.Context, scope AutomationScope, itemID string) (*inboxCapture, error) {
+	var capture inboxCapture
+	if err := s.scopedCaptures(scope).WithContext(ctx).Where("id = ? AND status = ?", itemID, models.CaptureStatusUnfiled).
+		First(&capture.item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutomationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch snippet: %w", err)
+	}
+
+	err := s.db.WithContext(ctx).Where("id = ?", capture.item.NoteID).First(&capture.note).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch inbox note: %w", err)
+	}
+	found := err == nil
+	if found {
+		if capture.note.Locked {
+			return nil, fmt.Errorf("%w: the Inbox note is locked", ErrInvalidAutomationRequest)
+		}
+		var blockIDs []string
+		if err := s.db.WithContext(ctx).Model(&models.CaptureItem{}).
+			Where("note_id = ? AND status = ?", capture.note.ID, models.CaptureStatusUnfiled).
+			Pluck("block_id", &blockIDs).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch inbox: %w", err)
+		}
+		starts := make(map[string]bool, len(blockIDs))
+		for _, id := range blockIDs {
+			starts[id] = true
+		}
+		_ = json.Unmarshal([]byte(capture.note.Content), &capture.doc)
+		capture.start, capture.end, found = captureSpan(capture.doc.Content, capture.item, starts)
+	}
+	if !found {
+		if err := s.db.WithContext(ctx).Model(&capture.item).Update("status", models.CaptureStatusRemoved).Error; err != nil {
+			log.Warn().Err(err).Str("capture_id", capture.item.ID).Msg("Failed to drop removed snippet from the inbox")
+		}
+		return nil, ErrAutomationNotFound
+	}
+	return &capture, nil
+}
+
+// scopedCaptures selects the snippets captured in the scope's workspace
+func (s *automationServiceImpl) scopedCaptures(scope AutomationScope) *gorm.DB {
+	query := s.db.Model(&models.CaptureItem{})
+	if scope.isOrganization() {
+		return query.Where("organization_id = ?", *scope.OrganizationID)
+	}
+	return query.Where("clerk_user_id = ? AND organization_id IS NULL", scope.ClerkUserID)
+}
+
+// captureSpan finds a snippet's top level blocks: its first block and up to BlockCount blocks from
+// there, stopping early at the first block of another snippet
+func captureSpan(nodes []utils.TipTapNode, item models.CaptureItem, starts map[string]bool) (int, int, bool) {
+	for i := range nodes {
+		if blockNodeID(nodes[i]) != item.BlockID {
+			continue
+		}
+		end := i + 1
+		for end < len(nodes) && end-i < item.BlockCount && !starts[blockNodeID(nodes[end])] {
+			end++
+		}
+		return i, end, true
+	}
+	return 0, 0, false
+}
+
+// capturedText returns the text of a snippet's blocks, one per line, without the time it was captured
+func capturedText(nodes []utils.TipTapNode) string {
+	lines := make([]string, 0, len(nodes))
+	for _, node := range untimestampCapture(nodes) {
+		if text := strings.TrimSpace(blockText(node)); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// untimestampCapture returns a copy of a snippet's blocks without the bold time timestampCapture
+// started it with, dropping its first paragraph when that's all it held
+func untimestampCapture(nodes []utils.TipTapNode) []utils.TipTapNode {
+	nodes = append([]utils.TipTapNode{}, nodes...)
+	if len(nodes) == 0 || nodes[0].Type != "paragraph" || len(nodes[0].Content) == 0 {
+		return nodes
+	}
+	stamp := nodes[0].Content[0]
+	if stamp.Type != "text" || len(stamp.Marks) != 1 || stamp.Marks[0].Type != "bold" {
+		return nodes
+	}
+	if _, err := time.Parse(captureTimestampFormat, stamp.Text); err != nil {
+		return nodes
+	}
+
+	rest := nodes[0].Content[1:]
+	if len(rest) > 0 && rest[0].Type == "text" && rest[0].Text == " " && len(rest[0].Marks) == 0 {
+		rest = rest[1:]
+	}
+	if len(rest) == 0 {
+		return nodes[1:]
+	}
+	first := nodes[0]
+	first.Content = rest
+	nodes[0] = first
+	return nodes
+}
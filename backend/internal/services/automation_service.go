@@ -0,0 +1,1225 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidAutomationKey is returned when an API key is unknown or revoked
+	ErrInvalidAutomationKey = errors.New("invalid automation API key")
+	// ErrUnknownAutomationTrigger is returned for triggers that don't exist
+	ErrUnknownAutomationTrigger = errors.New("unknown automation trigger")
+	// ErrInvalidAutomationRequest is returned when an action or subscription is missing required input
+	ErrInvalidAutomationRequest = errors.New("invalid automation request")
+	// ErrAutomationNotFound is returned when a resource doesn't exist or is outside the key's workspace
+	ErrAutomationNotFound = errors.New("automation resource not found")
+	// ErrAutomationConflict is returned when a note changed since the client last read it
+	ErrAutomationConflict = errors.New("note changed since it was last read")
+)
+
+const (
+	automationKeyPrefix          = "nak_"
+	maxAutomationKeysPerOwner    = 20
+	maxAutomationHooksPerKey     = 50
+	defaultAutomationPollLimit   = 50
+	maxAutomationPollLimit       = 100
+	maxAutomationHookFailures    = 20
+	automationKeyTouchInterval   = time.Minute
+	automationHookMaxAttempts    = 3
+	maxAutomationAppendLength    = 100000
+	defaultAutomationSearchLimit = 20
+	maxAutomationSearchLimit     = 100
+	automationWebhookUserAgent   = "NotesAppAutomation/1.0"
+	automationWebhookEventHeader = "X-Notes-Event"
+)
+
+// noteHashtagPattern matches #tags that aren't part of a word, URL fragment or Markdown heading
+var noteHashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/#])#(\p{L}[\p{L}\p{N}_-]{0,62})`)
+
+var automationTaskStatuses = map[string]bool{
+	"backlog":     true,
+	"todo":        true,
+	"in_progress": true,
+	"done":        true,
+}
+
+var automationTriggers = map[string]bool{
+	models.AutomationTriggerNewNote:       true,
+	models.AutomationTriggerNoteTagged:    true,
+	models.AutomationTriggerTaskCompleted: true,
+}
+
+// AutomationScope is the workspace an automation API key acts in: a user's personal workspace or one organization
+type AutomationScope struct {
+	APIKeyID       string
+	ClerkUserID    string
+	OrganizationID *string
+}
+
+// isOrganization reports whether the scope is an organization
+func (s AutomationScope) isOrganization() bool {
+	return s.OrganizationID != nil && *s.OrganizationID != ""
+}
+
+// ScopeForAutomationKey returns the scope an API key acts in
+func ScopeForAutomationKey(key *models.AutomationAPIKey) AutomationScope {
+	return AutomationScope{
+		APIKeyID:       key.ID,
+		ClerkUserID:    key.ClerkUserID,
+		OrganizationID: key.OrganizationID,
+	}
+}
+
+// CreatedAutomationAPIKey is a newly created key. The plaintext key is only ever returned here.
+type CreatedAutomationAPIKey struct {
+	*models.AutomationAPIKey
+	Key string `json:"key"`
+}
+
+// AutomationNote is a note as sent to automation platforms.
+// ID is unique per event so platforms can deduplicate polled results.
+type AutomationNote struct {
+	ID             string    `json:"id"`
+	NoteID         string    `json:"noteId"`
+	Name           string    `json:"name"`
+	Slug           string    `json:"slug"`
+	Content        string    `json:"content"`
+	Tag            string    `json:"tag,omitempty"`
+	ChapterID      string    `json:"chapterId"`
+	ChapterName    string    `json:"chapterName"`
+	NotebookID     string    `json:"notebookId"`
+	NotebookName   string    `json:"notebookName"`
+	OrganizationID *string   `json:"organizationId,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// AutomationChapter is a chapter with the slugs clients lay out folders by
+type AutomationChapter struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	NotebookID   string `json:"notebookId"`
+	NotebookName string `json:"notebookName"`
+	NotebookSlug string `json:"notebookSlug"`
+}
+
+// AutomationExport is every note of a workspace with the chapters notes can be created in
+type AutomationExport struct {
+	Chapters []AutomationChapter `json:"chapters"`
+	Notes    []AutomationNote    `json:"notes"`
+}
+
+// AutomationChanges is what changed in a workspace since a sync client's cursor
+type AutomationChanges struct {
+	Cursor   time.Time           `json:"cursor"` // Pass back as since for the next changes
+	Chapters []AutomationChapter `json:"chapters"`
+	Notes    []AutomationNote    `json:"notes"`   // Updated since the cursor, or all without one
+	NoteIDs  []string            `json:"noteIds"` // Every note, to spot deleted ones
+}
+
+// AutomationTask is a task as sent to automation platforms
+type AutomationTask struct {
+	ID             string     `json:"id"`
+	TaskID         string     `json:"taskId"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	Status         string     `json:"status"`
+	Priority       string     `json:"priority"`
+	BoardID        string     `json:"boardId"`
+	BoardName      string     `json:"boardName"`
+	OrganizationID *string    `json:"organizationId,omitempty"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// AutomationChoice is an option for a dynamic dropdown, such as the chapter to create notes in
+type AutomationChoice struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// AutomationPollOptions filters polled trigger results
+type AutomationPollOptions struct {
+	Tag   string
+	Limit int
+}
+
+// AutomationSubscribeRequest subscribes a URL to a trigger
+type AutomationSubscribeRequest struct {
+	Event     string `json:"event"`
+	TargetURL string `json:"targetUrl"`
+	Tag       string `json:"tag"`
+}
+
+// AutomationCreateNoteRequest creates a note from Markdown
+type AutomationCreateNoteRequest struct {
+	ChapterID string `json:"chapterId"`
+	Name      string `json:"name"`
+	Content   string `json:"content"`
+}
+
+// AutomationAppendRequest appends Markdown to the end of a note
+type AutomationAppendRequest struct {
+	Content string `json:"content"`
+}
+
+// AutomationUpdateNoteRequest renames a note or replaces its content with Markdown. When UpdatedAt
+// is set, the update is refused if the note changed since, so sync clients don't overwrite unseen edits.
+// A refused content update is kept as a conflict, merged with the server's version from BaseContent,
+// the Markdown the client last synced.
+type AutomationUpdateNoteRequest struct {
+	Name        *string    `json:"name"`
+	Content     *string    `json:"content"`
+	BaseContent *string    `json:"baseContent"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+}
+
+// AutomationCreateTaskRequest creates a task on a board
+type AutomationCreateTaskRequest struct {
+	BoardID     string `json:"boardId"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Priority    string `json:"priority"`
+}
+
+// AutomationService interface defines the trigger and action API used by Zapier, Make and similar platforms
+type AutomationService interface {
+	CreateAPIKey(scope AutomationScope, name string) (*CreatedAutomationAPIKey, error)
+	ListAPIKeys(scope AutomationScope) ([]models.AutomationAPIKey, error)
+	RevokeAPIKey(scope AutomationScope, keyID string) error
+	Authenticate(rawKey string) (*models.AutomationAPIKey, error)
+
+	Poll(scope AutomationScope, trigger string, options AutomationPollOptions) ([]interface{}, error)
+	Subscribe(scope AutomationScope, req AutomationSubscribeRequest) (*models.AutomationWebhook, error)
+	Unsubscribe(scope AutomationScope, webhookID string) error
+
+	CreateNote(ctx context.Context, scope AutomationScope, req AutomationCreateNoteRequest) (*AutomationNote, error)
+	AppendToNote(ctx context.Context, scope AutomationScope, noteID string, req AutomationAppendRequest) (*AutomationNote, error)
+	UpdateNote(ctx context.Context, scope AutomationScope, noteID string, req AutomationUpdateNoteRequest) (*AutomationNote, error)
+	SearchNotes(ctx context.Context, scope AutomationScope, query string, limit int) ([]AutomationNote, error)
+	ExportNotes(ctx context.Context, scope AutomationScope) (*AutomationExport, error)
+	NoteChanges(ctx context.Context, scope AutomationScope, since time.Time) (*AutomationChanges, error)
+	ListNoteConflicts(ctx context.Context, scope AutomationScope) ([]AutomationNoteConflict, error)
+	GetNoteConflict(ctx context.Context, scope AutomationScope, conflictID string) (*AutomationNoteConflict, error)
+	DismissNoteConflict(ctx context.Context, scope AutomationScope, conflictID string) error
+	CreateTask(ctx context.Context, scope AutomationScope, req AutomationCreateTaskRequest) (*AutomationTask, error)
+	ListChapters(scope AutomationScope) ([]AutomationChoice, error)
+	ListTaskBoards(scope AutomationScope) ([]AutomationChoice, error)
+	Capture(ctx context.Context, scope AutomationScope, req CaptureRequest) (*CaptureResult, error)
+	ListInbox(ctx context.Context, scope AutomationScope) ([]InboxItem, error)
+	SuggestInboxDestination(ctx context.Context, scope AutomationScope, itemID string) (*InboxSuggestion, error)
+	FileCapture(ctx context.Context, scope AutomationScope, itemID string, req InboxFileRequest) (*AutomationNote, error)
+
+	NoteChanged(noteID string, created bool)
+	NoteCreated(noteID string)
+	SyncNoteTags(noteID string) error
+	TaskStatusChanged(taskID, oldStatus string)
+}
+
+// automationServiceImpl implements the AutomationService interface
+type automationServiceImpl struct {
+	db                 *gorm.DB
+	queue              *JobQueue
+	client             *http.Client
+	suggestDestination func(ctx context.Context, request CaptureDestinationRequest) (*CaptureDestination, error)
+}
+
+// NewAutomationService creates a new AutomationService instance
+func NewAutomationService() AutomationService {
+	return &automationServiceImpl{
+		db:     db.DB,
+		queue:  GetJobQueue(),
+		client: newLinkPreviewClient(),
+		suggestDestination: func(ctx context.Context, request CaptureDestinationRequest) (*CaptureDestination, error) {
+			return NewAIService().SuggestCaptureDestination(ctx, request)
+		},
+	}
+}
+
+// CreateAPIKey creates a key for the scope. Only a hash of the key is stored.
+func (s *automationServiceImpl) CreateAPIKey(scope AutomationScope, name string) (*CreatedAutomationAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Automation key"
+	}
+	name = truncateLinkText(name, 100)
+
+	var count int64
+	if err := s.scopedKeys(scope).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if count >= maxAutomationKeysPerOwner {
+		return nil, fmt.Errorf("%w: at most %d API keys can be created", ErrInvalidAutomationRequest, maxAutomationKeysPerOwner)
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	rawKey := automationKeyPrefix + hex.EncodeToString(secret)
+
+	key := models.AutomationAPIKey{
+		ClerkUserID: scope.ClerkUserID,
+		Name:        name,
+		KeyPrefix:   rawKey[:len(automationKeyPrefix)+8],
+		KeyHash:     hashAutomationKey(rawKey),
+	}
+	if scope.isOrganization() {
+		key.OrganizationID = scope.OrganizationID
+	}
+	if err := s.db.Create(&key).Error; err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &CreatedAutomationAPIKey{AutomationAPIKey: &key, Key: rawKey}, nil
+}
+
+// ListAPIKeys lists the scope's keys, newest first
+func (s *automationServiceImpl) ListAPIKeys(scope AutomationScope) ([]models.AutomationAPIKey, error) {
+	var keys []models.AutomationAPIKey
+	if err := s.scopedKeys(scope).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey deletes a key along with its webhook subscriptions
+func (s *automationServiceImpl) RevokeAPIKey(scope AutomationScope, keyID string) error {
+	var key models.AutomationAPIKey
+	if err := s.scopedKeys(scope).Where("id = ?", keyID).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAutomationNotFound
+		}
+		return fmt.Errorf("failed to fetch API key: %w", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("api_key_id = ?", key.ID).Delete(&models.AutomationWebhook{}).Error; err != nil {
+			return fmt.Errorf("failed to delete webhooks: %w", err)
+		}
+		if err := tx.Delete(&key).Error; err != nil {
+			return fmt.Errorf("failed to delete API key: %w", err)
+		}
+		return nil
+	})
+}
+
+// Authenticate resolves a plaintext key to its record and records when it was last used
+func (s *automationServiceImpl) Authenticate(rawKey string) (*models.AutomationAPIKey, error) {
+	rawKey = strings.TrimSpace(rawKey)
+	if !strings.HasPrefix(rawKey, automationKeyPrefix) {
+		return nil, ErrInvalidAutomationKey
+	}
+
+	var key models.AutomationAPIKey
+	if err := s.db.Where("key_hash = ?", hashAutomationKey(rawKey)).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAutomationKey
+		}
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > automationKeyTouchInterval {
+		if err := s.db.Model(&key).Update("last_used_at", now).Error; err != nil {
+			log.Warn().Err(err).Str("key_id", key.ID).Msg("Failed to record automation key use")
+		}
+		key.LastUsedAt = &now
+	}
+	return &key, nil
+}
+
+// Poll returns the most recent events for a trigger, newest first, as polling triggers expect
+func (s *automationServiceImpl) Poll(scope AutomationScope, trigger string, options AutomationPollOptions) ([]interface{}, error) {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = defaultAutomationPollLimit
+	}
+	if limit > maxAutomationPollLimit {
+		limit = maxAutomationPollLimit
+	}
+
+	events := []interface{}{}
+	switch trigger {
+	case models.AutomationTriggerNewNote:
+		var notes []models.Notes
+		if err := s.scopedNotes(scope).Preload("Chapter.Notebook").
+			Order("notes.created_at DESC").Limit(limit).Find(&notes).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch notes: %w", err)
+		}
+		for i := range notes {
+			events = append(events, toAutomationNote(&notes[i], notes[i].ID, ""))
+		}
+
+	case models.AutomationTriggerNoteTagged:
+		query := s.db.Model(&models.NoteTag{}).
+			Joins("JOIN notes ON notes.id = note_tags.note_id").
+			Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+			Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id")
+		query = scopeAutomationNotebooks(query, scope)
+		if tag := normalizeNoteTag(options.Tag); tag != "" {
+			query = query.Where("note_tags.tag = ?", tag)
+		}
+		var tags []models.NoteTag
+		if err := query.Preload("Note.Chapter.Notebook").
+			Order("note_tags.created_at DESC").Limit(limit).Find(&tags).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch tagged notes: %w", err)
+		}
+		for i := range tags {
+			if tags[i].Note != nil {
+				events = append(events, toAutomationNote(tags[i].Note, tags[i].ID, tags[i].Tag))
+			}
+		}
+
+	case models.AutomationTriggerTaskCompleted:
+		var tasks []models.Task
+		if err := s.scopedTasks(scope).Where("tasks.status = ? AND tasks.completed_at IS NOT NULL", "done").
+			Preload("TaskBoard").Order("tasks.completed_at DESC").Limit(limit).Find(&tasks).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch tasks: %w", err)
+		}
+		for i := range tasks {
+			events = append(events, toAutomationTask(&tasks[i]))
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAutomationTrigger, trigger)
+	}
+
+	return events, nil
+}
+
+// Subscribe registers a REST hook that receives the trigger's events as they happen
+func (s *automationServiceImpl) Subscribe(scope AutomationScope, req AutomationSubscribeRequest) (*models.AutomationWebhook, error) {
+	if !automationTriggers[req.Event] {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAutomationTrigger, req.Event)
+	}
+	targetURL, err := normalizeLinkURL(req.TargetURL)
+	if err != nil || !strings.HasPrefix(targetURL, "https://") {
+		return nil, fmt.Errorf("%w: targetUrl must be an https URL", ErrInvalidAutomationRequest)
+	}
+
+	var count int64
+	if err := s.db.Model(&models.AutomationWebhook{}).Where("api_key_id = ?", scope.APIKeyID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	if count >= maxAutomationHooksPerKey {
+		return nil, fmt.Errorf("%w: at most %d webhooks can be subscribed per API key", ErrInvalidAutomationRequest, maxAutomationHooksPerKey)
+	}
+
+	webhook := models.AutomationWebhook{
+		APIKeyID:    scope.APIKeyID,
+		ClerkUserID: scope.ClerkUserID,
+		Event:       req.Event,
+		TargetURL:   targetURL,
+	}
+	if scope.isOrganization() {
+		webhook.OrganizationID = scope.OrganizationID
+	}
+	if req.Event == models.AutomationTriggerNoteTagged {
+		webhook.Tag = normalizeNoteTag(req.Tag)
+	}
+	if err := s.db.Create(&webhook).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+// Unsubscribe removes a REST hook created with the same API key
+func (s *automationServiceImpl) Unsubscribe(scope AutomationScope, webhookID string) error {
+	result := s.db.Where("id = ? AND api_key_id = ?", webhookID, scope.APIKeyID).Delete(&models.AutomationWebhook{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete webhook: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAutomationNotFound
+	}
+	return nil
+}
+
+// CreateNote creates a note from Markdown in a chapter of the scope's workspace
+func (s *automationServiceImpl) CreateNote(ctx context.Context, scope AutomationScope, req AutomationCreateNoteRequest) (*AutomationNote, error) {
+	name := strings.TrimSpace(req.Name)
+	if req.ChapterID == "" || name == "" {
+		return nil, fmt.Errorf("%w: chapterId and name are required", ErrInvalidAutomationRequest)
+	}
+
+	var chapter models.Chapter
+	if err := s.scopedChapters(scope).Where("chapters.id = ?", req.ChapterID).First(&chapter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutomationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+	}
+
+	content, err := utils.MarkdownToTipTap(req.Content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: content could not be converted: %v", ErrInvalidAutomationRequest, err)
+	}
+	content = ApplyChapterNoteDefaults(&chapter, content)
+
+	note := models.Notes{
+		Name:           truncateLinkText(name, 255),
+		Content:        content,
+		ChapterID:      chapter.ID,
+		OrganizationID: chapter.OrganizationID,
+	}
+	if err := s.db.WithContext(ctx).Create(&note).Error; err != nil {
+		return nil, fmt.Errorf("failed to create note: %w", err)
+	}
+
+	s.NoteCreated(note.ID)
+	if err := s.SyncNoteTags(note.ID); err != nil {
+		log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to sync note tags")
+	}
+	return s.loadAutomationNote(note.ID)
+}
+
+// AppendToNote appends Markdown as new paragraphs at the end of a note
+func (s *automationServiceImpl) AppendToNote(ctx context.Context, scope AutomationScope, noteID string, req AutomationAppendRequest) (*AutomationNote, error) {
+	text := strings.TrimSpace(req.Content)
+	if text == "" {
+		return nil, fmt.Errorf("%w: content is required", ErrInvalidAutomationRequest)
+	}
+	if len(text) > maxAutomationAppendLength {
+		return nil, fmt.Errorf("%w: content can be at most %d characters", ErrInvalidAutomationRequest, maxAutomationAppendLength)
+	}
+
+	var note models.Notes
+	if err := s.scopedNotes(scope).Where("notes.id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutomationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	content, err := appendMarkdownToTipTap(note.Content, text)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Notes{}).Where("id = ?", note.ID).Update("content", content).Error; err != nil {
+		return nil, fmt.Errorf("failed to update note: %w", err)
+	}
+	// Drop the collaborative document so editors load the appended content
+	if err := NewYjsService(s.db).DeleteYjsDocument(note.ID); err != nil {
+		log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to reset Yjs document after append")
+	}
+
+	if err := s.SyncNoteTags(note.ID); err != nil {
+		log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to sync note tags")
+	}
+	return s.loadAutomationNote(note.ID)
+}
+
+// UpdateNote renames a note or replaces its content with Markdown
+func (s *automationServiceImpl) UpdateNote(ctx context.Context, scope AutomationScope, noteID string, req AutomationUpdateNoteRequest) (*AutomationNote, error) {
+	if req.Name == nil && req.Content == nil {
+		return nil, fmt.Errorf("%w: name or content is required", ErrInvalidAutomationRequest)
+	}
+
+	var note models.Notes
+	if err := s.scopedNotes(scope).Select("notes.*").Where("notes.id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutomationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	// Compare at the database's microsecond precision
+	if req.UpdatedAt != nil && !note.UpdatedAt.Truncate(time.Microsecond).Equal(req.UpdatedAt.Truncate(time.Microsecond)) {
+		if req.Content == nil {
+			return nil, ErrAutomationConflict
+		}
+		conflict, err := s.recordNoteConflict(ctx, scope, &note, *req.Content, req.BaseContent)
+		if err != nil {
+			log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to record note conflict")
+			return nil, ErrAutomationConflict
+		}
+		return nil, &AutomationConflictError{Conflict: conflict}
+	}
+
+	updates := map[string]interface{}{}
+	renamed := false
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name can't be empty", ErrInvalidAutomationRequest)
+		}
+		name = truncateLinkText(name, 255)
+		renamed = name != note.Name
+		updates["name"] = name
+	}
+	if req.Content != nil {
+		content, err := utils.MarkdownToTipTap(*req.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: content could not be converted: %v", ErrInvalidAutomationRequest, err)
+		}
+		updates["content"] = AssignTipTapBlockIDs(note.Content, content)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&note).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update note: %w", err)
+		}
+		if renamed {
+			if _, err := RefreshNoteSlug(tx, note.ID); err != nil {
+				return err
+			}
+		}
+		// Uploading over the latest version resolves the client's conflicts on the note
+		if req.Content != nil && scope.APIKeyID != "" {
+			if err := tx.Where("note_id = ? AND api_key_id = ?", note.ID, scope.APIKeyID).Delete(&models.NoteConflict{}).Error; err != nil {
+				return fmt.Errorf("failed to resolve note conflicts: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Content != nil {
+		// Drop the collaborative document so editors load the new content
+		if err := NewYjsService(s.db).DeleteYjsDocument(note.ID); err != nil {
+			log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to reset Yjs document after update")
+		}
+		if err := s.SyncNoteTags(note.ID); err != nil {
+			log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to sync note tags")
+		}
+	}
+	return s.loadAutomationNote(note.ID)
+}
+
+// SearchNotes finds the notes of the scope's workspace whose name or content contains the query,
+// most recently updated first
+func (s *automationServiceImpl) SearchNotes(ctx context.Context, scope AutomationScope, query string, limit int) ([]AutomationNote, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: a search query is required", ErrInvalidAutomationRequest)
+	}
+	if limit <= 0 {
+		limit = defaultAutomationSearchLimit
+	}
+	if limit > maxAutomationSearchLimit {
+		limit = maxAutomationSearchLimit
+	}
+
+	var notes []models.Notes
+	if err := MatchNoteText(db.Replica(s.scopedNotes(scope).WithContext(ctx)), query).
+		Select("notes.*").
+		Preload("Chapter.Notebook").
+		Order("notes.updated_at DESC").
+		Limit(limit).
+		Find(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to search notes: %w", err)
+	}
+
+	return toAutomationNotes(notes), nil
+}
+
+// ExportNotes returns every note of the scope's workspace as Markdown, with its chapters
+func (s *automationServiceImpl) ExportNotes(ctx context.Context, scope AutomationScope) (*AutomationExport, error) {
+	var chapters []models.Chapter
+	if err := db.Replica(s.scopedChapters(scope).WithContext(ctx)).
+		Select("chapters.*").
+		Preload("Notebook").
+		Order("chapters.created_at").
+		Find(&chapters).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch chapters: %w", err)
+	}
+
+	var notes []models.Notes
+	if err := db.Replica(s.scopedNotes(scope).WithContext(ctx)).
+		Select("notes.*").
+		Preload("Chapter.Notebook").
+		Order("notes.created_at").
+		Find(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notes: %w", err)
+	}
+
+	return &AutomationExport{
+		Chapters: toAutomationChapters(chapters),
+		Notes:    toAutomationNotes(notes),
+	}, nil
+}
+
+// NoteChanges returns the notes of the scope's workspace updated since a cursor, for sync clients.
+// It reads from the primary, a lagging replica could hide changes made just before the new cursor.
+func (s *automationServiceImpl) NoteChanges(ctx context.Context, scope AutomationScope, since time.Time) (*AutomationChanges, error) {
+	// Taken before reading so a note updated during the reads is sent again next time, not missed
+	cursor := time.Now().UTC()
+
+	var chapters []models.Chapter
+	if err := s.scopedChapters(scope).WithContext(ctx).
+		Select("chapters.*").
+		Preload("Notebook").
+		Order("chapters.created_at").
+		Find(&chapters).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch chapters: %w", err)
+	}
+
+	query := s.scopedNotes(scope).WithContext(ctx).Select("notes.*").Preload("Chapter.Notebook")
+	if !since.IsZero() {
+		query = query.Where("notes.updated_at >= ?", since)
+	}
+	var notes []models.Notes
+	if err := query.Order("notes.updated_at").Find(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch changed notes: %w", err)
+	}
+
+	var noteIDs []string
+	if err := s.scopedNotes(scope).WithContext(ctx).Pluck("notes.id", &noteIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note IDs: %w", err)
+	}
+
+	return &AutomationChanges{
+		Cursor:   cursor,
+		Chapters: toAutomationChapters(chapters),
+		Notes:    toAutomationNotes(notes),
+		NoteIDs:  noteIDs,
+	}, nil
+}
+
+// CreateTask creates a task on a board of the scope's workspace
+func (s *automationServiceImpl) CreateTask(ctx context.Context, scope AutomationScope, req AutomationCreateTaskRequest) (*AutomationTask, error) {
+	title := strings.TrimSpace(req.Title)
+	if req.BoardID == "" || title == "" {
+		return nil, fmt.Errorf("%w: boardId and title are required", ErrInvalidAutomationRequest)
+	}
+
+	status := req.Status
+	if status == "" {
+		status = "todo"
+	}
+	if !automationTaskStatuses[status] {
+		return nil, fmt.Errorf("%w: status must be one of backlog, todo, in_progress, done", ErrInvalidAutomationRequest)
+	}
+	priority := req.Priority
+	if priority == "" {
+		priority = "medium"
+	}
+	if priority != "low" && priority != "medium" && priority != "high" {
+		return nil, fmt.Errorf("%w: priority must be one of low, medium, high", ErrInvalidAutomationRequest)
+	}
+
+	var board models.TaskBoard
+	if err := s.scopedBoards(scope).Where("task_boards.id = ?", req.BoardID).First(&board).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutomationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch task board: %w", err)
+	}
+
+	var position int64
+	s.db.Model(&models.Task{}).Where("task_board_id = ? AND status = ?", board.ID, status).Count(&position)
+
+	task := models.Task{
+		Title:          truncateLinkText(title, 255),
+		Description:    req.Description,
+		Status:         status,
+		Priority:       priority,
+		TaskBoardID:    board.ID,
+		Position:       int(position),
+		OrganizationID: board.OrganizationID,
+	}
+	if status == "done" {
+		now := time.Now()
+		task.CompletedAt = &now
+	}
+	if err := s.db.WithContext(ctx).Create(&task).Error; err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+
+	task.TaskBoard = board
+	if status == "done" {
+		s.emit(scopeForBoard(&board), models.AutomationTriggerTaskCompleted, "", toAutomationTask(&task))
+	}
+	return toAutomationTask(&task), nil
+}
+
+// ListChapters lists the chapters notes can be created in, for dynamic dropdowns
+func (s *automationServiceImpl) ListChapters(scope AutomationScope) ([]AutomationChoice, error) {
+	var rows []struct {
+		ID           string
+		Name         string
+		NotebookName string
+	}
+	if err := s.scopedChapters(scope).
+		Select("chapters.id, chapters.name, notebooks.name AS notebook_name").
+		Order("notebooks.name, chapters.name").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch chapters: %w", err)
+	}
+
+	choices := make([]AutomationChoice, len(rows))
+	for i, row := range rows {
+		choices[i] = AutomationChoice{ID: row.ID, Name: row.NotebookName + " / " + row.Name}
+	}
+	return choices, nil
+}
+
+// ListTaskBoards lists the boards tasks can be created on, for dynamic dropdowns
+func (s *automationServiceImpl) ListTaskBoards(scope AutomationScope) ([]AutomationChoice, error) {
+	var boards []models.TaskBoard
+	if err := s.scopedBoards(scope).Select("task_boards.id, task_boards.name").Order("task_boards.name").Find(&boards).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch task boards: %w", err)
+	}
+
+	choices := make([]AutomationChoice, len(boards))
+	for i, board := range boards {
+		choices[i] = AutomationChoice{ID: board.ID, Name: board.Name}
+	}
+	return choices, nil
+}
+
+// NoteChanged syncs a note's tags in the background, delivering the new_note event first when it was just created
+func (s *automationServiceImpl) NoteChanged(noteID string, created bool) {
+	err := s.queue.Enqueue(Job{
+		Name:        "automation-note-change",
+		MaxAttempts: 1,
+		Run: func(ctx context.Context) error {
+			if created {
+				s.NoteCreated(noteID)
+			}
+			return s.SyncNoteTags(noteID)
+		},
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to queue note automation events")
+	}
+}
+
+// NoteCreated delivers the new_note event for a note to subscribed webhooks
+func (s *automationServiceImpl) NoteCreated(noteID string) {
+	var note models.Notes
+	if err := s.db.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to load note for automation event")
+		return
+	}
+	s.emit(scopeForNotebook(&note.Chapter.Notebook), models.AutomationTriggerNewNote, "", toAutomationNote(&note, note.ID, ""))
+}
+
+// SyncNoteTags stores the #tags in a note's content and delivers note_tagged events for new ones
+func (s *automationServiceImpl) SyncNoteTags(noteID string) error {
+	var note models.Notes
+	if err := s.db.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		return fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	markdown, err := utils.TipTapToMarkdown(note.Content)
+	if err != nil {
+		return fmt.Errorf("failed to convert note to markdown: %w", err)
+	}
+	tags := extractNoteTags(markdown)
+
+	var existing []models.NoteTag
+	if err := s.db.Where("note_id = ?", note.ID).Find(&existing).Error; err != nil {
+		return fmt.Errorf("failed to fetch note tags: %w", err)
+	}
+	current := make(map[string]bool, len(existing))
+	var removed []string
+	for _, tag := range existing {
+		current[tag.Tag] = true
+		if !containsString(tags, tag.Tag) {
+			removed = append(removed, tag.ID)
+		}
+	}
+
+	if len(removed) > 0 {
+		if err := s.db.Where("id IN ?", removed).Delete(&models.NoteTag{}).Error; err != nil {
+			return fmt.Errorf("failed to delete note tags: %w", err)
+		}
+	}
+
+	scope := scopeForNotebook(&note.Chapter.Notebook)
+	for _, tag := range tags {
+		if current[tag] {
+			continue
+		}
+		noteTag := models.NoteTag{NoteID: note.ID, Tag: tag, OrganizationID: note.OrganizationID}
+		if err := s.db.Create(&noteTag).Error; err != nil {
+			return fmt.Errorf("failed to create note tag: %w", err)
+		}
+		s.emit(scope, models.AutomationTriggerNoteTagged, tag, toAutomationNote(&note, noteTag.ID, tag))
+	}
+	return nil
+}
+
+// TaskStatusChanged keeps a task's completion time in step with its status and delivers task_completed events
+func (s *automationServiceImpl) TaskStatusChanged(taskID, oldStatus string) {
+	var task models.Task
+	if err := s.db.Preload("TaskBoard").Where("id = ?", taskID).First(&task).Error; err != nil {
+		log.Warn().Err(err).Str("task_id", taskID).Msg("Failed to load task for automation event")
+		return
+	}
+	if task.Status == oldStatus {
+		return
+	}
+
+	if task.Status != "done" {
+		if task.CompletedAt != nil {
+			s.db.Model(&task).Update("completed_at", nil)
+		}
+		return
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&task).Update("completed_at", now).Error; err != nil {
+		log.Warn().Err(err).Str("task_id", taskID).Msg("Failed to record task completion")
+		return
+	}
+	task.CompletedAt = &now
+	s.emit(scopeForBoard(&task.TaskBoard), models.AutomationTriggerTaskCompleted, "", toAutomationTask(&task))
+}
+
+// emit queues delivery of an event to every webhook subscribed to it in the scope's workspace
+func (s *automationServiceImpl) emit(scope AutomationScope, trigger, tag string, payload interface{}) {
+	query := s.db.Model(&models.AutomationWebhook{}).Where("event = ?", trigger)
+	if scope.isOrganization() {
+		query = query.Where("organization_id = ?", *scope.OrganizationID)
+	} else {
+		query = query.Where("clerk_user_id = ? AND organization_id IS NULL", scope.ClerkUserID)
+	}
+	if trigger == models.AutomationTriggerNoteTagged {
+		query = query.Where("tag = '' OR tag = ?", tag)
+	}
+
+	var webhooks []models.AutomationWebhook
+	if err := query.Find(&webhooks).Error; err != nil {
+		log.Error().Err(err).Str("trigger", trigger).Msg("Failed to find automation webhooks")
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Error().Err(err).Str("trigger", trigger).Msg("Failed to encode automation event")
+		return
+	}
+
+	for _, webhook := range webhooks {
+		webhook := webhook
+		err := s.queue.Enqueue(Job{
+			Name:        "automation-webhook",
+			MaxAttempts: automationHookMaxAttempts,
+			Run: func(ctx context.Context) error {
+				return s.deliver(ctx, &webhook, body)
+			},
+		})
+		if err != nil {
+			log.Warn().Err(err).Str("webhook_id", webhook.ID).Msg("Failed to queue automation webhook delivery")
+		}
+	}
+}
+
+// deliver posts an event to a webhook. Subscriptions the receiver has removed (410 Gone) or that keep failing are deleted.
+func (s *automationServiceImpl) deliver(ctx context.Context, webhook *models.AutomationWebhook, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.TargetURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", automationWebhookUserAgent)
+	req.Header.Set(automationWebhookEventHeader, webhook.Event)
+
+	resp, err := s.client.Do(req)
+	if err == nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusGone {
+			log.Info().Str("webhook_id", webhook.ID).Msg("Automation webhook unsubscribed by receiver")
+			return s.db.Delete(webhook).Error
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return s.db.Model(webhook).Updates(map[string]interface{}{
+				"failure_count":     0,
+				"last_delivered_at": time.Now(),
+			}).Error
+		}
+		err = fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	if updateErr := s.db.Model(webhook).Update("failure_count", gorm.Expr("failure_count + 1")).Error; updateErr != nil {
+		log.Warn().Err(updateErr).Str("webhook_id", webhook.ID).Msg("Failed to record automation webhook failure")
+	}
+	var current models.AutomationWebhook
+	if s.db.Select("failure_count").Where("id = ?", webhook.ID).First(&current).Error == nil && current.FailureCount >= maxAutomationHookFailures {
+		log.Warn().Str("webhook_id", webhook.ID).Msg("Removing automation webhook after repeated failures")
+		s.db.Delete(webhook)
+		return nil
+	}
+	return err
+}
+
+// loadAutomationNote loads a note with its chapter and notebook for a response
+func (s *automationServiceImpl) loadAutomationNote(noteID string) (*AutomationNote, error) {
+	var note models.Notes
+	if err := s.db.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	return toAutomationNote(&note, note.ID, ""), nil
+}
+
+// scopedKeys selects the API keys belonging to the scope
+func (s *automationServiceImpl) scopedKeys(scope AutomationScope) *gorm.DB {
+	query := s.db.Model(&models.AutomationAPIKey{})
+	if scope.isOrganization() {
+		return query.Where("organization_id = ?", *scope.OrganizationID)
+	}
+	return query.Where("clerk_user_id = ? AND organization_id IS NULL", scope.ClerkUserID)
+}
+
+// scopedChapters selects the chapters in the scope's workspace
+func (s *automationServiceImpl) scopedChapters(scope AutomationScope) *gorm.DB {
+	query := s.db.Model(&models.Chapter{}).Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id")
+	return scopeAutomationNotebooks(query, scope)
+}
+
+// scopedNotes selects the notes in the scope's workspace, leaving out locked notes
+func (s *automationServiceImpl) scopedNotes(scope AutomationScope) *gorm.DB {
+	query := s.db.Model(&models.Notes{}).
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notes.locked = ?", false)
+	return scopeAutomationNotebooks(query, scope)
+}
+
+// scopedBoards selects the task boards in the scope's workspace
+func (s *automationServiceImpl) scopedBoards(scope AutomationScope) *gorm.DB {
+	query := s.db.Model(&models.TaskBoard{})
+	if scope.isOrganization() {
+		return query.Where("task_boards.organization_id = ?", *scope.OrganizationID)
+	}
+	return query.Where("task_boards.clerk_user_id = ? AND (task_boards.organization_id IS NULL OR task_boards.organization_id = '')", scope.ClerkUserID)
+}
+
+// scopedTasks selects the tasks in the scope's workspace
+func (s *automationServiceImpl) scopedTasks(scope AutomationScope) *gorm.DB {
+	query := s.db.Model(&models.Task{}).Joins("JOIN task_boards ON task_boards.id = tasks.task_board_id")
+	if scope.isOrganization() {
+		return query.Where("task_boards.organization_id = ?", *scope.OrganizationID)
+	}
+	return query.Where("task_boards.clerk_user_id = ? AND (task_boards.organization_id IS NULL OR task_boards.organization_id = '')", scope.ClerkUserID)
+}
+
+// scopeAutomationNotebooks restricts a query joined with notebooks to the scope's workspace. Encrypted
+// notebooks are left out, automations can't read or write their notes.
+func scopeAutomationNotebooks(query *gorm.DB, scope AutomationScope) *gorm.DB {
+	query = query.Where("notebooks.encrypted = ?", false)
+	if scope.isOrganization() {
+		return query.Where("notebooks.organization_id = ?", *scope.OrganizationID)
+	}
+	return query.Where("notebooks.clerk_user_id = ? AND (notebooks.organization_id IS NULL OR notebooks.organization_id = '')", scope.ClerkUserID)
+}
+
+// scopeForNotebook returns the workspace a notebook belongs to
+func scopeForNotebook(notebook *models.Notebook) AutomationScope {
+	scope := AutomationScope{ClerkUserID: notebook.ClerkUserID}
+	if notebook.OrganizationID != nil && *notebook.OrganizationID != "" {
+		scope.OrganizationID = notebook.OrganizationID
+	}
+	return scope
+}
+
+// scopeForBoard returns the workspace a task board belongs to
+func scopeForBoard(board *models.TaskBoard) AutomationScope {
+	scope := AutomationScope{ClerkUserID: board.ClerkUserID}
+	if board.OrganizationID != nil && *board.OrganizationID != "" {
+		scope.OrganizationID = board.OrganizationID
+	}
+	return scope
+}
+
+// toAutomationNote converts a note loaded with its chapter and notebook
+func toAutomationNote(note *models.Notes, eventID, tag string) *AutomationNote {
+	content, err := utils.TipTapToMarkdown(note.Content)
+	if err != nil {
+		content = ""
+	}
+	return &AutomationNote{
+		ID:             eventID,
+		NoteID:         note.ID,
+		Name:           note.Name,
+		Slug:           note.Slug,
+		Content:        content,
+		Tag:            tag,
+		ChapterID:      note.ChapterID,
+		ChapterName:    note.Chapter.Name,
+		NotebookID:     note.Chapter.NotebookID,
+		NotebookName:   note.Chapter.Notebook.Name,
+		OrganizationID: note.OrganizationID,
+		CreatedAt:      note.CreatedAt,
+		UpdatedAt:      note.UpdatedAt,
+	}
+}
+
+// toAutomationNotes converts notes loaded with their chapter and notebook
+func toAutomationNotes(notes []models.Notes) []AutomationNote {
+	converted := make([]AutomationNote, len(notes))
+	for i := range notes {
+		converted[i] = *toAutomationNote(&notes[i], notes[i].ID, "")
+	}
+	return converted
+}
+
+// toAutomationChapters converts chapters loaded with their notebook
+func toAutomationChapters(chapters []models.Chapter) []AutomationChapter {
+	converted := make([]AutomationChapter, len(chapters))
+	for i, chapter := range chapters {
+		converted[i] = AutomationChapter{
+			ID:           chapter.ID,
+			Name:         chapter.Name,
+			Slug:         chapter.Slug,
+			NotebookID:   chapter.NotebookID,
+			NotebookName: chapter.Notebook.Name,
+			NotebookSlug: models.Slugify(chapter.Notebook.Name),
+		}
+	}
+	return converted
+}
+
+// toAutomationTask converts a task loaded with its board.
+// Completed tasks get an event ID per completion, so a task completed again triggers again.
+func toAutomationTask(task *models.Task) *AutomationTask {
+	id := task.ID
+	if task.CompletedAt != nil {
+		id = fmt.Sprintf("%s-%d", task.ID, task.CompletedAt.Unix())
+	}
+	return &AutomationTask{
+		ID:             id,
+		TaskID:         task.ID,
+		Title:          task.Title,
+		Description:    task.Description,
+		Status:         task.Status,
+		Priority:       task.Priority,
+		BoardID:        task.TaskBoardID,
+		BoardName:      task.TaskBoard.Name,
+		OrganizationID: task.OrganizationID,
+		CompletedAt:    task.CompletedAt,
+		CreatedAt:      task.CreatedAt,
+	}
+}
+
+// appendMarkdownToTipTap appends Markdown blocks to the end of a TipTap document
+func appendMarkdownToTipTap(content, markdown string) (string, error) {
+	appended, err := markdownNodes(markdown)
+	if err != nil {
+		return "", err
+	}
+	return appendNodesToTipTap(content, appended)
+}
+
+// markdownNodes converts Markdown to TipTap blocks
+func markdownNodes(markdown string) ([]utils.TipTapNode, error) {
+	converted, err := utils.MarkdownToTipTap(markdown)
+	if err != nil {
+		return nil, fmt.Errorf("%w: content could not be converted: %v", ErrInvalidAutomationRequest, err)
+	}
+
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(converted), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse converted content: %w", err)
+	}
+	return doc.Content, nil
+}
+
+// appendNodesToTipTap appends blocks to the end of a TipTap document
+func appendNodesToTipTap(content string, nodes []utils.TipTapNode) (string, error) {
+	doc := utils.TipTapDoc{Type: "doc"}
+	if strings.TrimSpace(content) != "" {
+		if err := json.Unmarshal([]byte(content), &doc); err != nil {
+			// Content that isn't a TipTap document is kept as Markdown
+			existing, convertErr := utils.MarkdownToTipTap(content)
+			if convertErr != nil {
+				return "", fmt.Errorf("failed to convert existing content: %w", convertErr)
+			}
+			if err := json.Unmarshal([]byte(existing), &doc); err != nil {
+				return "", fmt.Errorf("failed to parse existing content: %w", err)
+			}
+		}
+	}
+	doc.Content = append(doc.Content, nodes...)
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode note content: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// extractNoteTags returns the distinct, normalized #tags in Markdown text, ignoring code
+func extractNoteTags(markdown string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	inFence := false
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, match := range noteHashtagPattern.FindAllStringSubmatch(stripInlineCode(line), -1) {
+			tag := normalizeNoteTag(match[1])
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// stripInlineCode removes `code` spans from a line
+func stripInlineCode(line string) string {
+	parts := strings.Split(line, "`")
+	var builder strings.Builder
+	for i := 0; i < len(parts); i += 2 {
+		builder.WriteString(parts[i])
+		builder.WriteByte(' ')
+	}
+	return builder.String()
+}
+
+// normalizeNoteTag lowercases a tag and strips a leading #
+func normalizeNoteTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// hashAutomationKey fingerprints an API key for storage and lookup
+func hashAutomationKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// containsString reports whether a slice contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
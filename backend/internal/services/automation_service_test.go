@@ -0,0 +1,460 @@
+package services
+
+import (
+	"backend/internal/models"
+	internalutils "backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestAutomationService creates an automation service with a personal notebook and task board for user_1
+func setupTestAutomationService(t *testing.T) (*automationServiceImpl, *models.Chapter, *models.TaskBoard) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(
+		&models.Notebook{},
+		&models.Chapter{},
+		&models.Notes{},
+		&models.NoteTag{},
+		&models.TaskBoard{},
+		&models.Task{},
+		&models.AutomationAPIKey{},
+		&models.AutomationWebhook{},
+		&models.NoteConflict{},
+		&models.YjsDocument{},
+		&models.YjsUpdate{},
+		&models.UserPreferences{},
+		&models.CaptureItem{},
+	)
+	require.NoError(t, err, "Failed to migrate test database")
+
+	service := &automationServiceImpl{
+		db:    db,
+		queue: NewJobQueue(1, 10),
+	}
+
+	notebook := models.Notebook{Name: "Work", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Inbox", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+	board := models.TaskBoard{Name: "Launch", ClerkUserID: "user_1", IsStandalone: true}
+	require.NoError(t, db.Create(&board).Error)
+
+	return service, &chapter, &board
+}
+
+func TestAutomationAPIKeys_AuthenticateAndRevoke(t *testing.T) {
+	service, _, _ := setupTestAutomationService(t)
+	owner := AutomationScope{ClerkUserID: "user_1"}
+
+	created, err := service.CreateAPIKey(owner, " Zapier ")
+	require.NoError(t, err)
+	assert.Equal(t, "Zapier", created.Name)
+	assert.True(t, len(created.Key) > len(created.KeyPrefix))
+	assert.Contains(t, created.Key, created.KeyPrefix)
+	assert.NotEqual(t, created.Key, created.KeyHash, "Only a hash of the key should be stored")
+
+	key, err := service.Authenticate(created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+	assert.NotNil(t, key.LastUsedAt)
+
+	_, err = service.Authenticate(created.Key + "x")
+	assert.ErrorIs(t, err, ErrInvalidAutomationKey)
+
+	// Keys can only be revoked by their owner
+	assert.ErrorIs(t, service.RevokeAPIKey(AutomationScope{ClerkUserID: "user_2"}, created.ID), ErrAutomationNotFound)
+	require.NoError(t, service.RevokeAPIKey(owner, created.ID))
+
+	_, err = service.Authenticate(created.Key)
+	assert.ErrorIs(t, err, ErrInvalidAutomationKey)
+}
+
+func TestAutomationActions_CreateAndAppendNotes(t *testing.T) {
+	service, chapter, _ := setupTestAutomationService(t)
+	scope := AutomationScope{APIKeyID: "key_1", ClerkUserID: "user_1"}
+
+	note, err := service.CreateNote(context.Background(), scope, AutomationCreateNoteRequest{
+		ChapterID: chapter.ID,
+		Name:      "Lead from form",
+		Content:   "New signup #sales",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, note.NoteID, note.ID)
+	assert.Equal(t, "Inbox", note.ChapterName)
+	assert.Equal(t, "Work", note.NotebookName)
+	assert.Contains(t, note.Content, "New signup")
+
+	appended, err := service.AppendToNote(context.Background(), scope, note.NoteID, AutomationAppendRequest{Content: "Follow up on Monday"})
+	require.NoError(t, err)
+	assert.Contains(t, appended.Content, "New signup")
+	assert.Contains(t, appended.Content, "Follow up on Monday")
+
+	// Other users' chapters and notes are out of reach
+	other := AutomationScope{APIKeyID: "key_2", ClerkUserID: "user_2"}
+	_, err = service.CreateNote(context.Background(), other, AutomationCreateNoteRequest{ChapterID: chapter.ID, Name: "Sneaky"})
+	assert.ErrorIs(t, err, ErrAutomationNotFound)
+	_, err = service.AppendToNote(context.Background(), other, note.NoteID, AutomationAppendRequest{Content: "Sneaky"})
+	assert.ErrorIs(t, err, ErrAutomationNotFound)
+
+	_, err = service.CreateNote(context.Background(), scope, AutomationCreateNoteRequest{ChapterID: chapter.ID})
+	assert.ErrorIs(t, err, ErrInvalidAutomationRequest)
+
+	events, err := service.Poll(scope, models.AutomationTriggerNewNote, AutomationPollOptions{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	events, err = service.Poll(other, models.AutomationTriggerNewNote, AutomationPollOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestCapture_AppendsToInbox(t *testing.T) {
+	service, chapter, _ := setupTestAutomationService(t)
+	ctx := context.Background()
+	scope := AutomationScope{ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&models.UserPreferences{ClerkUserID: "user_1", Timezone: "Asia/Tokyo"}).Error)
+
+	first, err := service.Capture(ctx, scope, CaptureRequest{Text: "Call the dentist #errands"})
+	require.NoError(t, err)
+	assert.Equal(t, chapter.ID, first.ChapterID, "Snippets go to the existing Inbox chapter")
+	assert.Equal(t, "Inbox", first.NoteName)
+	assert.True(t, first.NoteCreated)
+
+	second, err := service.Capture(ctx, scope, CaptureRequest{Text: "- milk\n- eggs"})
+	require.NoError(t, err)
+	assert.Equal(t, first.NoteID, second.NoteID)
+	assert.False(t, second.NoteCreated)
+
+	var note models.Notes
+	require.NoError(t, service.db.Where("id = ?", first.NoteID).First(&note).Error)
+	var doc internalutils.TipTapDoc
+	require.NoError(t, json.Unmarshal([]byte(note.Content), &doc))
+	require.Len(t, doc.Content, 3, "The list gets a paragraph for its timestamp")
+	stamp := first.CapturedAt.In(time.FixedZone("JST", 9*3600)).Format(captureTimestampFormat)
+	assert.Equal(t, stamp, doc.Content[0].Content[0].Text, "Timestamps are in the user's timezone")
+	assert.Equal(t, []internalutils.TipTapMark{{Type: "bold"}}, doc.Content[0].Content[0].Marks)
+	assert.Equal(t, "Call the dentist #errands", doc.Content[0].Content[2].Text)
+	assert.Equal(t, "bulletList", doc.Content[2].Type)
+
+	var tags int64
+	require.NoError(t, service.db.Model(&models.NoteTag{}).Where("note_id = ? AND tag = ?", note.ID, "errands").Count(&tags).Error)
+	assert.Equal(t, int64(1), tags)
+
+	// Without an Inbox chapter, one is created in an Inbox notebook
+	other, err := service.Capture(ctx, AutomationScope{ClerkUserID: "user_2"}, CaptureRequest{Text: "Idea"})
+	require.NoError(t, err)
+	var inbox models.Notebook
+	require.NoError(t, service.db.Where("id = ?", other.NotebookID).First(&inbox).Error)
+	assert.Equal(t, "Inbox", inbox.Name)
+	assert.Equal(t, "user_2", inbox.ClerkUserID)
+
+	// Snippets can target a note or a chapter of the workspace
+	targeted, err := service.Capture(ctx, AutomationScope{ClerkUserID: "user_2"}, CaptureRequest{Text: "More", NoteID: other.NoteID})
+	require.NoError(t, err)
+	assert.Equal(t, other.NoteID, targeted.NoteID)
+	_, err = service.Capture(ctx, scope, CaptureRequest{Text: "Sneaky", NoteID: other.NoteID})
+	assert.ErrorIs(t, err, ErrAutomationNotFound)
+	_, err = service.Capture(ctx, scope, CaptureRequest{Text: "Sneaky", ChapterID: other.ChapterID})
+	assert.ErrorIs(t, err, ErrAutomationNotFound)
+
+	_, err = service.Capture(ctx, scope, CaptureRequest{Text: "  "})
+	assert.ErrorIs(t, err, ErrInvalidAutomationRequest)
+}
+
+func TestInbox_ListAndFileCaptures(t *testing.T) {
+	service, inboxChapter, _ := setupTestAutomationService(t)
+	ctx := context.Background()
+	scope := AutomationScope{ClerkUserID: "user_1"}
+	projects := models.Chapter{Name: "Projects", NotebookID: inboxChapter.NotebookID}
+	require.NoError(t, service.db.Create(&projects).Error)
+	var suggested CaptureDestinationRequest
+	service.suggestDestination = func(ctx context.Context, request CaptureDestinationRequest) (*CaptureDestination, error) {
+		suggested = request
+		return &CaptureDestination{ChapterID: projects.ID, Title: "Garden plan"}, nil
+	}
+
+	first, err := service.Capture(ctx, scope, CaptureRequest{Text: "Plant tomatoes #garden\n\n- basil\n- mint"})
+	require.NoError(t, err)
+	_, err = service.Capture(ctx, scope, CaptureRequest{Text: "Book flights"})
+	require.NoError(t, err)
+	_, err = service.Capture(ctx, scope, CaptureRequest{Text: "Already filed", NoteID: first.NoteID})
+	require.NoError(t, err)
+
+	items, err := service.ListInbox(ctx, scope)
+	require.NoError(t, err)
+	require.Len(t, items, 2, "Snippets captured to a note of choice aren't in the inbox")
+	assert.Equal(t, "Book flights", items[0].Text)
+	assert.Equal(t, "Plant tomatoes #garden\nbasil mint", items[1].Text)
+
+	suggestion, err := service.SuggestInboxDestination(ctx, scope, items[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Projects", suggestion.ChapterName)
+	assert.Equal(t, "Work", suggestion.NotebookName)
+	require.Len(t, suggested.Chapters, 1, "Inbox chapters aren't suggested")
+
+	note, err := service.FileCapture(ctx, scope, items[1].ID, InboxFileRequest{Suggest: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Garden plan", note.Name)
+	assert.Equal(t, projects.ID, note.ChapterID)
+	assert.True(t, strings.HasPrefix(note.Content, "Plant tomatoes #garden"), "The time it was captured is left out: %s", note.Content)
+	assert.NotContains(t, note.Content, "Already filed")
+	var tags int64
+	require.NoError(t, service.db.Model(&models.NoteTag{}).Where("note_id = ? AND tag = ?", note.ID, "garden").Count(&tags).Error)
+	assert.Equal(t, int64(1), tags)
+
+	var inbox models.Notes
+	require.NoError(t, service.db.Where("id = ?", first.NoteID).First(&inbox).Error)
+	var doc internalutils.TipTapDoc
+	require.NoError(t, json.Unmarshal([]byte(inbox.Content), &doc))
+	require.Len(t, doc.Content, 2, "The filed snippet leaves the Inbox note")
+	assert.Equal(t, "Book flights", doc.Content[0].Content[2].Text)
+
+	// Snippets deleted from the Inbox note leave the inbox
+	doc.Content = doc.Content[1:]
+	encoded, err := json.Marshal(doc)
+	require.NoError(t, err)
+	require.NoError(t, service.db.Model(&inbox).Update("content", string(encoded)).Error)
+	items, err = service.ListInbox(ctx, scope)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	other, err := service.Capture(ctx, AutomationScope{ClerkUserID: "user_2"}, CaptureRequest{Text: "Private"})
+	require.NoError(t, err)
+	var item models.CaptureItem
+	require.NoError(t, service.db.Where("note_id = ?", other.NoteID).First(&item).Error)
+	_, err = service.FileCapture(ctx, scope, item.ID, InboxFileRequest{ChapterID: projects.ID})
+	assert.ErrorIs(t, err, ErrAutomationNotFound)
+	_, err = service.FileCapture(ctx, AutomationScope{ClerkUserID: "user_2"}, item.ID, InboxFileRequest{ChapterID: projects.ID})
+	assert.ErrorIs(t, err, ErrAutomationNotFound, "Snippets are filed within their workspace")
+	_, err = service.FileCapture(ctx, AutomationScope{ClerkUserID: "user_2"}, item.ID, InboxFileRequest{Suggest: true})
+	assert.ErrorIs(t, err, ErrInvalidAutomationRequest, "There are no chapters but the Inbox to file to")
+}
+
+func TestAutomationActions_UpdateSearchAndExportNotes(t *testing.T) {
+	service, chapter, _ := setupTestAutomationService(t)
+	scope := AutomationScope{APIKeyID: "key_1", ClerkUserID: "user_1"}
+	ctx := context.Background()
+
+	note, err := service.CreateNote(ctx, scope, AutomationCreateNoteRequest{ChapterID: chapter.ID, Name: "Garden", Content: "Plant tomatoes"})
+	require.NoError(t, err)
+	assert.Equal(t, "garden", note.Slug)
+
+	name, content := "Garden plan", "Plant basil"
+	updated, err := service.UpdateNote(ctx, scope, note.NoteID, AutomationUpdateNoteRequest{Name: &name, Content: &content, UpdatedAt: &note.UpdatedAt})
+	require.NoError(t, err)
+	assert.Equal(t, "Garden plan", updated.Name)
+	assert.Equal(t, "garden-plan", updated.Slug, "Renaming should refresh the slug")
+	assert.Contains(t, updated.Content, "Plant basil")
+	assert.NotContains(t, updated.Content, "tomatoes")
+
+	// Updates based on an older version are refused
+	_, err = service.UpdateNote(ctx, scope, note.NoteID, AutomationUpdateNoteRequest{Content: &content, UpdatedAt: &note.UpdatedAt})
+	assert.ErrorIs(t, err, ErrAutomationConflict)
+	_, err = service.UpdateNote(ctx, scope, note.NoteID, AutomationUpdateNoteRequest{})
+	assert.ErrorIs(t, err, ErrInvalidAutomationRequest)
+
+	results, err := service.SearchNotes(ctx, scope, "BASIL", 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, note.NoteID, results[0].NoteID)
+	results, err = service.SearchNotes(ctx, scope, "tomatoes", 0)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	export, err := service.ExportNotes(ctx, scope)
+	require.NoError(t, err)
+	require.Len(t, export.Chapters, 1)
+	assert.Equal(t, "inbox", export.Chapters[0].Slug)
+	assert.Equal(t, "work", export.Chapters[0].NotebookSlug)
+	require.Len(t, export.Notes, 1)
+	assert.Contains(t, export.Notes[0].Content, "Plant basil")
+
+	// Other users' notes are out of reach
+	other := AutomationScope{APIKeyID: "key_2", ClerkUserID: "user_2"}
+	_, err = service.UpdateNote(ctx, other, note.NoteID, AutomationUpdateNoteRequest{Content: &content})
+	assert.ErrorIs(t, err, ErrAutomationNotFound)
+	results, err = service.SearchNotes(ctx, other, "basil", 0)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	export, err = service.ExportNotes(ctx, other)
+	require.NoError(t, err)
+	assert.Empty(t, export.Chapters)
+	assert.Empty(t, export.Notes)
+}
+
+func TestAutomationActions_UpdateNoteConflict(t *testing.T) {
+	service, chapter, _ := setupTestAutomationService(t)
+	scope := AutomationScope{APIKeyID: "key_1", ClerkUserID: "user_1"}
+	ctx := context.Background()
+
+	base := "Buy seeds\n\nDig beds"
+	note, err := service.CreateNote(ctx, scope, AutomationCreateNoteRequest{ChapterID: chapter.ID, Name: "Garden", Content: base})
+	require.NoError(t, err)
+	serverContent := "Buy seeds\n\nDig raised beds"
+	server, err := service.UpdateNote(ctx, scope, note.NoteID, AutomationUpdateNoteRequest{Content: &serverContent})
+	require.NoError(t, err)
+
+	// An update from the old version is refused and merged with the server's
+	local := "Buy tomato seeds\n\nDig beds"
+	_, err = service.UpdateNote(ctx, scope, note.NoteID, AutomationUpdateNoteRequest{Content: &local, BaseContent: &base, UpdatedAt: &note.UpdatedAt})
+	require.ErrorIs(t, err, ErrAutomationConflict)
+	var conflictErr *AutomationConflictError
+	require.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, 0, conflictErr.Conflict.ConflictCount)
+
+	conflict, err := service.GetNoteConflict(ctx, scope, conflictErr.Conflict.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Garden", conflict.Name)
+	assert.Contains(t, conflict.Merged, "Buy tomato seeds")
+	assert.Contains(t, conflict.Merged, "Dig raised beds")
+	assert.True(t, conflict.ServerUpdatedAt.Equal(server.UpdatedAt))
+
+	// Pushing again replaces the conflict
+	_, err = service.UpdateNote(ctx, scope, note.NoteID, AutomationUpdateNoteRequest{Content: &local, UpdatedAt: &note.UpdatedAt})
+	require.ErrorIs(t, err, ErrAutomationConflict)
+	conflicts, err := service.ListNoteConflicts(ctx, scope)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, 1, conflicts[0].ConflictCount, "without a base the changed blocks conflict")
+
+	_, err = service.GetNoteConflict(ctx, AutomationScope{APIKeyID: "key_2", ClerkUserID: "user_1"}, conflict.ID)
+	assert.ErrorIs(t, err, ErrAutomationNotFound, "conflicts belong to the key that pushed")
+
+	// Uploading the resolution over the server's version resolves it
+	_, err = service.UpdateNote(ctx, scope, note.NoteID, AutomationUpdateNoteRequest{Content: &conflict.Merged, UpdatedAt: &conflict.ServerUpdatedAt})
+	require.NoError(t, err)
+	conflicts, err = service.ListNoteConflicts(ctx, scope)
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+	assert.ErrorIs(t, service.DismissNoteConflict(ctx, scope, conflict.ID), ErrAutomationNotFound)
+}
+
+func TestAutomationActions_NoteChanges(t *testing.T) {
+	service, chapter, _ := setupTestAutomationService(t)
+	scope := AutomationScope{APIKeyID: "key_1", ClerkUserID: "user_1"}
+	ctx := context.Background()
+
+	first, err := service.CreateNote(ctx, scope, AutomationCreateNoteRequest{ChapterID: chapter.ID, Name: "First", Content: "One"})
+	require.NoError(t, err)
+
+	changes, err := service.NoteChanges(ctx, scope, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, changes.Notes, 1, "Without a cursor every note is a change")
+	assert.Len(t, changes.Chapters, 1)
+	assert.False(t, changes.Cursor.IsZero())
+
+	second, err := service.CreateNote(ctx, scope, AutomationCreateNoteRequest{ChapterID: chapter.ID, Name: "Second", Content: "Two"})
+	require.NoError(t, err)
+
+	changes, err = service.NoteChanges(ctx, scope, changes.Cursor)
+	require.NoError(t, err)
+	require.Len(t, changes.Notes, 1)
+	assert.Equal(t, second.NoteID, changes.Notes[0].NoteID)
+	assert.ElementsMatch(t, []string{first.NoteID, second.NoteID}, changes.NoteIDs, "Every note is listed to spot deletions")
+
+	changes, err = service.NoteChanges(ctx, AutomationScope{APIKeyID: "key_2", ClerkUserID: "user_2"}, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, changes.Notes)
+	assert.Empty(t, changes.NoteIDs)
+}
+
+func TestSyncNoteTags_PollsNoteTaggedEvents(t *testing.T) {
+	service, chapter, _ := setupTestAutomationService(t)
+	scope := AutomationScope{APIKeyID: "key_1", ClerkUserID: "user_1"}
+
+	content, err := internalutils.MarkdownToTipTap("# Heading\n\nPlanning for #Launch and #ideas, see example.com/#anchor")
+	require.NoError(t, err)
+	note := models.Notes{Name: "Plan", Content: content, ChapterID: chapter.ID}
+	require.NoError(t, service.db.Create(&note).Error)
+
+	require.NoError(t, service.SyncNoteTags(note.ID))
+
+	events, err := service.Poll(scope, models.AutomationTriggerNoteTagged, AutomationPollOptions{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	events, err = service.Poll(scope, models.AutomationTriggerNoteTagged, AutomationPollOptions{Tag: "#launch"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	tagged := events[0].(*AutomationNote)
+	assert.Equal(t, "launch", tagged.Tag)
+	assert.Equal(t, note.ID, tagged.NoteID)
+	assert.NotEqual(t, note.ID, tagged.ID, "Tag events need their own IDs for deduplication")
+
+	// Removing a tag from the content removes it from the note
+	content, err = internalutils.MarkdownToTipTap("Planning for #launch")
+	require.NoError(t, err)
+	require.NoError(t, service.db.Model(&note).Update("content", content).Error)
+	require.NoError(t, service.SyncNoteTags(note.ID))
+
+	var tags []models.NoteTag
+	require.NoError(t, service.db.Where("note_id = ?", note.ID).Find(&tags).Error)
+	require.Len(t, tags, 1)
+	assert.Equal(t, tagged.ID, tags[0].ID, "Existing tags should be kept")
+}
+
+func TestTaskStatusChanged_PollsCompletedTasks(t *testing.T) {
+	service, _, board := setupTestAutomationService(t)
+	scope := AutomationScope{APIKeyID: "key_1", ClerkUserID: "user_1"}
+
+	task, err := service.CreateTask(context.Background(), scope, AutomationCreateTaskRequest{BoardID: board.ID, Title: "Write launch post"})
+	require.NoError(t, err)
+	assert.Equal(t, "todo", task.Status)
+	assert.Equal(t, "Launch", task.BoardName)
+
+	_, err = service.CreateTask(context.Background(), scope, AutomationCreateTaskRequest{BoardID: board.ID, Title: "Bad", Priority: "urgent"})
+	assert.ErrorIs(t, err, ErrInvalidAutomationRequest)
+
+	events, err := service.Poll(scope, models.AutomationTriggerTaskCompleted, AutomationPollOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	require.NoError(t, service.db.Model(&models.Task{}).Where("id = ?", task.TaskID).Update("status", "done").Error)
+	service.TaskStatusChanged(task.TaskID, "todo")
+
+	events, err = service.Poll(scope, models.AutomationTriggerTaskCompleted, AutomationPollOptions{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	completed := events[0].(*AutomationTask)
+	assert.Equal(t, task.TaskID, completed.TaskID)
+	assert.NotNil(t, completed.CompletedAt)
+
+	// Reopening clears the completion
+	require.NoError(t, service.db.Model(&models.Task{}).Where("id = ?", task.TaskID).Update("status", "in_progress").Error)
+	service.TaskStatusChanged(task.TaskID, "done")
+
+	events, err = service.Poll(scope, models.AutomationTriggerTaskCompleted, AutomationPollOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestSubscribe_ValidatesHooks(t *testing.T) {
+	service, _, _ := setupTestAutomationService(t)
+	created, err := service.CreateAPIKey(AutomationScope{ClerkUserID: "user_1"}, "Make")
+	require.NoError(t, err)
+	scope := ScopeForAutomationKey(created.AutomationAPIKey)
+
+	_, err = service.Subscribe(scope, AutomationSubscribeRequest{Event: "note_deleted", TargetURL: "https://hooks.example.com/1"})
+	assert.ErrorIs(t, err, ErrUnknownAutomationTrigger)
+
+	_, err = service.Subscribe(scope, AutomationSubscribeRequest{Event: models.AutomationTriggerNewNote, TargetURL: "http://hooks.example.com/1"})
+	assert.ErrorIs(t, err, ErrInvalidAutomationRequest, "Webhooks must use https")
+
+	webhook, err := service.Subscribe(scope, AutomationSubscribeRequest{Event: models.AutomationTriggerNoteTagged, TargetURL: "https://hooks.example.com/1", Tag: "#Sales"})
+	require.NoError(t, err)
+	assert.Equal(t, "sales", webhook.Tag)
+
+	assert.ErrorIs(t, service.Unsubscribe(AutomationScope{APIKeyID: "other"}, webhook.ID), ErrAutomationNotFound)
+	require.NoError(t, service.Unsubscribe(scope, webhook.ID))
+}
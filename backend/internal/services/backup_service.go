@@ -0,0 +1,576 @@
+package services
+
+import (
+	"archive/zip"
+	"backend/db"
+	"backend/internal/config"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// BackupFormat identifies the app's backup archives
+	BackupFormat = "notes-app-backup"
+	// BackupFormatVersion is the version of the backup format written, and the newest one restored
+	BackupFormatVersion = 1
+	// BackupContentType is the media type of backup archives
+	BackupContentType = "application/zip"
+
+	// backupManifestName is the archive entry describing the backup
+	backupManifestName = "manifest.json"
+	// backupTablesDir holds a JSON Lines file per table
+	backupTablesDir = "tables/"
+	// backupVideosDir holds the archived meeting videos
+	backupVideosDir = "objects/meeting-videos/"
+	// backupBatchSize is how many rows are read or written per query
+	backupBatchSize = 500
+)
+
+var (
+	// ErrInvalidBackup is returned for an archive that isn't a backup or doesn't match its manifest
+	ErrInvalidBackup = errors.New("invalid backup")
+	// ErrUnsupportedBackupVersion is returned for a backup written by a newer format version
+	ErrUnsupportedBackupVersion = errors.New("unsupported backup format version")
+	// ErrRestoreTargetNotEmpty is returned when restoring over existing content without replacing it
+	ErrRestoreTargetNotEmpty = errors.New("the database already has content, restore with replace to overwrite it")
+)
+
+// BackupTable is a table dump in a backup
+type BackupTable struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Rows   int    `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupObject is a stored file in a backup
+type BackupObject struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupManifest describes a backup: the format it's written in, where it came from and a checksum of
+// every table dump and stored file in it
+type BackupManifest struct {
+	Format    string         `json:"format"`
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"createdAt"`
+	CreatedBy string         `json:"createdBy"`
+	Driver    string         `json:"driver"`
+	Tables    []BackupTable  `json:"tables"`
+	Objects   []BackupObject `json:"objects"`
+}
+
+// RestoreReport counts what restoring a backup wrote
+type RestoreReport struct {
+	Tables         int      `json:"tables"`
+	Rows           int      `json:"rows"`
+	Objects        int      `json:"objects"`
+	SkippedTables  []string `json:"skippedTables"`
+	SkippedObjects int      `json:"skippedObjects"`
+}
+
+// BackupService interface defines methods for backing up and restoring the whole workspace, for moving
+// a self-hosted server
+type BackupService interface {
+	CreateBackup(ctx context.Context, w io.Writer, createdBy string) (*BackupManifest, error)
+	RestoreBackup(ctx context.Context, r io.ReaderAt, size int64, replace bool) (*RestoreReport, error)
+}
+
+// backupServiceImpl implements the BackupService interface
+type backupServiceImpl struct {
+	db       *gorm.DB
+	models   []interface{}
+	videoDir string
+	now      func() time.Time
+}
+
+// NewBackupService creates a new BackupService instance
+func NewBackupService() BackupService {
+	return &backupServiceImpl{
+		db:       db.DB,
+		models:   db.Models(),
+		videoDir: config.LoadMeetingVideoConfig().ArchiveDir,
+		now:      time.Now,
+	}
+}
+
+// CreateBackup writes a zip archive of every table and archived meeting video. The tables are read in
+// one transaction so they're a consistent snapshot. Rows are stored with their Go types' JSON, so a
+// backup restores on either database driver.
+func (s *backupServiceImpl) CreateBackup(ctx context.Context, w io.Writer, createdBy string) (*BackupManifest, error) {
+	manifest := &BackupManifest{
+		Format:    BackupFormat,
+		Version:   BackupFormatVersion,
+		CreatedAt: s.now().UTC(),
+		CreatedBy: createdBy,
+		Driver:    s.db.Dialector.Name(),
+		Tables:    []BackupTable{},
+		Objects:   []BackupObject{},
+	}
+	archive := zip.NewWriter(w)
+
+	dump := func(tx *gorm.DB) error {
+		for _, model := range s.models {
+			table, err := s.dumpTable(tx, archive, model)
+			if err != nil {
+				return err
+			}
+			manifest.Tables = append(manifest.Tables, *table)
+		}
+		return nil
+	}
+	tx := s.db.WithContext(ctx)
+	var err error
+	if s.db.Dialector.Name() == db.DriverPostgres {
+		err = tx.Transaction(dump, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	} else {
+		// SQLite transactions already read a snapshot
+		err = tx.Transaction(dump)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := s.archiveVideos(archive)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Objects = objects
+
+	entry, err := archive.Create(backupManifestName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", createdBy).
+		Int("tables", len(manifest.Tables)).
+		Int("objects", len(manifest.Objects)).
+		Msg("Workspace backup created")
+	return manifest, nil
+}
+
+// dumpTable writes a table as one JSON object per row, keyed by column
+func (s *backupServiceImpl) dumpTable(tx *gorm.DB, archive *zip.Writer, model interface{}) (*BackupTable, error) {
+	tableSchema, err := parseBackupModel(tx, model)
+	if err != nil {
+		return nil, err
+	}
+	table := &BackupTable{Name: tableSchema.Table, File: backupTablesDir + tableSchema.Table + ".jsonl"}
+
+	entry, err := archive.Create(table.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", table.Name, err)
+	}
+	checksum := sha256.New()
+	encoder := json.NewEncoder(io.MultiWriter(entry, checksum))
+
+	rows := reflect.New(reflect.SliceOf(tableSchema.ModelType))
+	// Hooks are skipped so rows are written exactly as they are stored
+	result := tx.Session(&gorm.Session{SkipHooks: true}).Model(model).FindInBatches(rows.Interface(), backupBatchSize, func(batch *gorm.DB, _ int) error {
+		slice := rows.Elem()
+		for i := 0; i < slice.Len(); i++ {
+			row := make(map[string]interface{}, len(tableSchema.Fields))
+			for _, field := range tableSchema.Fields {
+				if field.DBName == "" {
+					continue
+				}
+				row[field.DBName], _ = field.ValueOf(tx.Statement.Context, slice.Index(i))
+			}
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+			table.Rows++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to back up %s: %w", table.Name, result.Error)
+	}
+
+	table.SHA256 = hex.EncodeToString(checksum.Sum(nil))
+	return table, nil
+}
+
+// archiveVideos adds the archived meeting videos to the backup. They're already compressed, so
+// they're stored as is.
+func (s *backupServiceImpl) archiveVideos(archive *zip.Writer) ([]BackupObject, error) {
+	objects := []BackupObject{}
+	if s.videoDir == "" {
+		return objects, nil
+	}
+	entries, err := os.ReadDir(s.videoDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return objects, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read video archive: %w", err)
+	}
+
+	for _, dirEntry := range entries {
+		// Partial downloads are skipped, the video isn't archived until they're renamed
+		if !dirEntry.Type().IsRegular() || strings.HasSuffix(dirEntry.Name(), ".part") {
+			continue
+		}
+		object, err := addBackupObject(archive, filepath.Join(s.videoDir, dirEntry.Name()), backupVideosDir+dirEntry.Name())
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, *object)
+	}
+	return objects, nil
+}
+
+// addBackupObject copies a file into the archive
+func addBackupObject(archive *zip.Writer, path, name string) (*BackupObject, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer file.Close()
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	checksum := sha256.New()
+	size, err := io.Copy(io.MultiWriter(entry, checksum), file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return &BackupObject{Path: name, Size: size, SHA256: hex.EncodeToString(checksum.Sum(nil))}, nil
+}
+
+// RestoreBackup loads a backup into the database and video archive. Every table dump and stored file is
+// checked against the manifest before anything is written, and the tables are written in one
+// transaction. Unless replace is set, the database must not have content yet.
+func (s *backupServiceImpl) RestoreBackup(ctx context.Context, r io.ReaderAt, size int64, replace bool) (*RestoreReport, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a zip archive", ErrInvalidBackup)
+	}
+	manifest, files, err := verifyBackup(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RestoreReport{SkippedTables: []string{}}
+	schemas := make(map[string]*schema.Schema, len(s.models))
+	for _, model := range s.models {
+		tableSchema, err := parseBackupModel(s.db, model)
+		if err != nil {
+			return nil, err
+		}
+		schemas[tableSchema.Table] = tableSchema
+	}
+	tables := make(map[string]BackupTable, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		if schemas[table.Name] == nil {
+			// A table this version doesn't have any more
+			report.SkippedTables = append(report.SkippedTables, table.Name)
+			continue
+		}
+		tables[table.Name] = table
+	}
+
+	// Parents are written before the tables referencing them, and emptied after them
+	ordered := s.models
+	if reorderer, ok := s.db.Migrator().(interface {
+		ReorderModels([]interface{}, bool) []interface{}
+	}); ok {
+		ordered = reorderer.ReorderModels(s.models, false)
+	}
+
+	err = s.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		if err := s.clearTables(tx, ordered, replace); err != nil {
+			return err
+		}
+		for _, model := range ordered {
+			tableSchema, err := parseBackupModel(tx, model)
+			if err != nil {
+				return err
+			}
+			table, ok := tables[tableSchema.Table]
+			if !ok {
+				continue
+			}
+			rows, err := s.restoreTable(tx, model, tableSchema, files[table.File])
+			if err != nil {
+				return err
+			}
+			report.Tables++
+			report.Rows += rows
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, object := range manifest.Objects {
+		if s.videoDir == "" {
+			report.SkippedObjects++
+			continue
+		}
+		if err := restoreBackupObject(files[object.Path], s.videoDir, strings.TrimPrefix(object.Path, backupVideosDir)); err != nil {
+			return report, err
+		}
+		report.Objects++
+	}
+
+	log.Info().
+		Time("backup_created_at", manifest.CreatedAt).
+		Int("tables", report.Tables).
+		Int("rows", report.Rows).
+		Int("objects", report.Objects).
+		Bool("replace", replace).
+		Msg("Workspace backup restored")
+	return report, nil
+}
+
+// clearTables empties the tables before a restore when replacing, or checks they're empty
+func (s *backupServiceImpl) clearTables(tx *gorm.DB, ordered []interface{}, replace bool) error {
+	for i := len(ordered) - 1; i >= 0; i-- {
+		model := ordered[i]
+		if replace {
+			if err := tx.Where("1 = 1").Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to clear tables: %w", err)
+			}
+			continue
+		}
+		var count int64
+		if err := tx.Model(model).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check tables: %w", err)
+		}
+		if count > 0 {
+			return ErrRestoreTargetNotEmpty
+		}
+	}
+	return nil
+}
+
+// restoreTable inserts a table dump. Columns the model no longer has are left out and columns added
+// since the backup take their defaults.
+func (s *backupServiceImpl) restoreTable(tx *gorm.DB, model interface{}, tableSchema *schema.Schema, file *zip.File) (int, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", tableSchema.Table, err)
+	}
+	defer reader.Close()
+
+	restored := 0
+	batch := make([]map[string]interface{}, 0, backupBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		// Counted first, creating maps can append the generated keys to the slice
+		restored += len(batch)
+		if err := tx.Model(model).Create(&batch).Error; err != nil {
+			return fmt.Errorf("failed to restore %s: %w", tableSchema.Table, err)
+		}
+		batch = make([]map[string]interface{}, 0, backupBatchSize)
+		return nil
+	}
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var raw map[string]json.RawMessage
+		if err := decoder.Decode(&raw); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return restored, fmt.Errorf("%w: %s has a malformed row", ErrInvalidBackup, tableSchema.Table)
+		}
+
+		row := make(map[string]interface{}, len(raw))
+		for column, value := range raw {
+			field := tableSchema.LookUpField(column)
+			if field == nil || field.DBName == "" {
+				continue
+			}
+			typed := reflect.New(field.FieldType)
+			if err := json.Unmarshal(value, typed.Interface()); err != nil {
+				return restored, fmt.Errorf("%w: %s.%s has a malformed value", ErrInvalidBackup, tableSchema.Table, column)
+			}
+			row[field.DBName] = typed.Elem().Interface()
+		}
+		batch = append(batch, row)
+		if len(batch) == backupBatchSize {
+			if err := flush(); err != nil {
+				return restored, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return restored, err
+	}
+
+	// PostgreSQL sequences don't see rows inserted with their IDs
+	if tx.Dialector.Name() == db.DriverPostgres && tableSchema.PrioritizedPrimaryField != nil &&
+		tableSchema.PrioritizedPrimaryField.AutoIncrement {
+		column := tableSchema.PrioritizedPrimaryField.DBName
+		if err := tx.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			tx.Statement.Quote(column), tx.Statement.Quote(tableSchema.Table)), tableSchema.Table, column).Error; err != nil {
+			return restored, fmt.Errorf("failed to reset %s sequence: %w", tableSchema.Table, err)
+		}
+	}
+	return restored, nil
+}
+
+// restoreBackupObject writes a stored file into a directory, through a temporary file so a failed
+// restore never leaves a partial copy
+func restoreBackupObject(file *zip.File, dir, name string) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	defer reader.Close()
+
+	target, err := os.CreateTemp(dir, name+"-*.part")
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", file.Name, err)
+	}
+	defer os.Remove(target.Name())
+
+	_, err = io.Copy(target, reader)
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", file.Name, err)
+	}
+	if err := os.Rename(target.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// verifyBackup reads the manifest of a backup and checks every entry it lists against its checksum,
+// returning the archive's entries by name
+func verifyBackup(archive *zip.Reader) (*BackupManifest, map[string]*zip.File, error) {
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	manifestFile, ok := files[backupManifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s is missing", ErrInvalidBackup, backupManifestName)
+	}
+	reader, err := manifestFile.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s can't be read", ErrInvalidBackup, backupManifestName)
+	}
+	var manifest BackupManifest
+	err = json.NewDecoder(reader).Decode(&manifest)
+	reader.Close()
+	if err != nil || manifest.Format != BackupFormat {
+		return nil, nil, fmt.Errorf("%w: not a %s archive", ErrInvalidBackup, BackupFormat)
+	}
+	if manifest.Version < 1 || manifest.Version > BackupFormatVersion {
+		return nil, nil, fmt.Errorf("%w: version %d, this server reads up to %d", ErrUnsupportedBackupVersion, manifest.Version, BackupFormatVersion)
+	}
+
+	seen := map[string]bool{}
+	for _, table := range manifest.Tables {
+		if seen[table.Name] || table.File != backupTablesDir+table.Name+".jsonl" {
+			return nil, nil, fmt.Errorf("%w: unexpected table %q", ErrInvalidBackup, table.Name)
+		}
+		seen[table.Name] = true
+
+		lines, err := checksumBackupEntry(files[table.File], func(checksum hash.Hash, reader io.Reader) (int64, error) {
+			scanner := bufio.NewScanner(io.TeeReader(reader, checksum))
+			scanner.Buffer(make([]byte, 64*1024), 1<<30)
+			var count int64
+			for scanner.Scan() {
+				count++
+			}
+			return count, scanner.Err()
+		}, table.SHA256)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s %v", ErrInvalidBackup, table.Name, err)
+		}
+		if lines != int64(table.Rows) {
+			return nil, nil, fmt.Errorf("%w: %s has %d rows, the manifest lists %d", ErrInvalidBackup, table.Name, lines, table.Rows)
+		}
+	}
+
+	for _, object := range manifest.Objects {
+		name := strings.TrimPrefix(object.Path, backupVideosDir)
+		if name == object.Path || name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+			return nil, nil, fmt.Errorf("%w: unexpected object %q", ErrInvalidBackup, object.Path)
+		}
+		size, err := checksumBackupEntry(files[object.Path], func(checksum hash.Hash, reader io.Reader) (int64, error) {
+			return io.Copy(checksum, reader)
+		}, object.SHA256)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s %v", ErrInvalidBackup, object.Path, err)
+		}
+		if size != object.Size {
+			return nil, nil, fmt.Errorf("%w: %s is %d bytes, the manifest lists %d", ErrInvalidBackup, object.Path, size, object.Size)
+		}
+	}
+
+	return &manifest, files, nil
+}
+
+// checksumBackupEntry reads an archive entry through read and checks its SHA-256 checksum
+func checksumBackupEntry(file *zip.File, read func(hash.Hash, io.Reader) (int64, error), expected string) (int64, error) {
+	if file == nil {
+		return 0, errors.New("is missing")
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return 0, errors.New("can't be read")
+	}
+	defer reader.Close()
+
+	checksum := sha256.New()
+	count, err := read(checksum, reader)
+	if err != nil {
+		return 0, errors.New("can't be read")
+	}
+	if !strings.EqualFold(hex.EncodeToString(checksum.Sum(nil)), expected) {
+		return 0, errors.New("doesn't match its checksum")
+	}
+	return count, nil
+}
+
+// parseBackupModel resolves the schema of a model
+func parseBackupModel(tx *gorm.DB, model interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return nil, fmt.Errorf("failed to parse model: %w", err)
+	}
+	return stmt.Schema, nil
+}
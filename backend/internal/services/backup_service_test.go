@@ -0,0 +1,137 @@
+package services
+
+import (
+	"archive/zip"
+	"backend/internal/models"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestBackupService creates a backup service on an in-memory database with its own video archive
+func setupTestBackupService(t *testing.T) *backupServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	backupModels := []interface{}{&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.AICredential{}}
+	require.NoError(t, db.AutoMigrate(backupModels...), "Failed to migrate test database")
+
+	return &backupServiceImpl{
+		db:       db,
+		models:   backupModels,
+		videoDir: t.TempDir(),
+		now:      func() time.Time { return time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC) },
+	}
+}
+
+func TestBackup_RestoresOnAnotherServer(t *testing.T) {
+	source := setupTestBackupService(t)
+	ctx := context.Background()
+
+	orgID := "org_1"
+	note := createLifecycleNote(t, source.db, &orgID)
+	require.NoError(t, source.db.Model(&models.Notes{}).Where("id = ?", note.ID).Update("is_public", false).Error)
+	require.NoError(t, source.db.Create(&models.AICredential{ClerkUserID: "user_owner", Provider: "openai", KeyCipher: []byte{0, 1, 2, 255}}).Error)
+	require.NoError(t, os.WriteFile(filepath.Join(source.videoDir, "meeting_1.mp4"), []byte("video"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(source.videoDir, "meeting_2-123.part"), []byte("partial"), 0o600))
+
+	var archive bytes.Buffer
+	manifest, err := source.CreateBackup(ctx, &archive, "user_admin")
+	require.NoError(t, err)
+	assert.Equal(t, BackupFormatVersion, manifest.Version)
+	require.Len(t, manifest.Tables, 4)
+	assert.Equal(t, 1, manifest.Tables[2].Rows)
+	require.Len(t, manifest.Objects, 1, "Partial downloads aren't backed up")
+
+	target := setupTestBackupService(t)
+	report, err := target.RestoreBackup(ctx, bytes.NewReader(archive.Bytes()), int64(archive.Len()), false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Tables)
+	assert.Equal(t, 4, report.Rows)
+	assert.Equal(t, 1, report.Objects)
+
+	var restored models.Notes
+	require.NoError(t, target.db.First(&restored, "id = ?", note.ID).Error)
+	assert.Equal(t, note.Name, restored.Name)
+	assert.Equal(t, &orgID, restored.OrganizationID)
+	assert.False(t, restored.IsPublic, "Zero values are restored as they were")
+	var credentials []models.AICredential
+	require.NoError(t, target.db.Find(&credentials).Error)
+	require.Len(t, credentials, 1)
+	assert.Equal(t, []byte{0, 1, 2, 255}, credentials[0].KeyCipher)
+	video, err := os.ReadFile(filepath.Join(target.videoDir, "meeting_1.mp4"))
+	require.NoError(t, err)
+	assert.Equal(t, "video", string(video))
+
+	_, err = target.RestoreBackup(ctx, bytes.NewReader(archive.Bytes()), int64(archive.Len()), false)
+	assert.ErrorIs(t, err, ErrRestoreTargetNotEmpty)
+	report, err = target.RestoreBackup(ctx, bytes.NewReader(archive.Bytes()), int64(archive.Len()), true)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Rows, "Replacing clears the existing content first")
+}
+
+func TestBackup_RejectsTamperedArchives(t *testing.T) {
+	source := setupTestBackupService(t)
+	ctx := context.Background()
+	createLifecycleNote(t, source.db, nil)
+
+	var archive bytes.Buffer
+	_, err := source.CreateBackup(ctx, &archive, "user_admin")
+	require.NoError(t, err)
+
+	// rewrite copies the backup, changing the entries edit returns new content for
+	rewrite := func(edit func(name string, content []byte) []byte) []byte {
+		reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+		require.NoError(t, err)
+		var out bytes.Buffer
+		writer := zip.NewWriter(&out)
+		for _, file := range reader.File {
+			entry, err := file.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(entry)
+			require.NoError(t, err)
+			entry.Close()
+			target, err := writer.Create(file.Name)
+			require.NoError(t, err)
+			_, err = target.Write(edit(file.Name, content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, writer.Close())
+		return out.Bytes()
+	}
+
+	target := setupTestBackupService(t)
+	tampered := rewrite(func(name string, content []byte) []byte {
+		if name == "tables/notes.jsonl" {
+			return bytes.Replace(content, []byte("Expenses"), []byte("Expensed"), 1)
+		}
+		return content
+	})
+	_, err = target.RestoreBackup(ctx, bytes.NewReader(tampered), int64(len(tampered)), false)
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+
+	newer := rewrite(func(name string, content []byte) []byte {
+		if name == backupManifestName {
+			return bytes.Replace(content, []byte(`"version": 1`), []byte(`"version": 2`), 1)
+		}
+		return content
+	})
+	_, err = target.RestoreBackup(ctx, bytes.NewReader(newer), int64(len(newer)), false)
+	assert.ErrorIs(t, err, ErrUnsupportedBackupVersion)
+
+	_, err = target.RestoreBackup(ctx, bytes.NewReader([]byte("not a zip")), 9, false)
+	assert.ErrorIs(t, err, ErrInvalidBackup)
+
+	var count int64
+	require.NoError(t, target.db.Model(&models.Notes{}).Count(&count).Error)
+	assert.Zero(t, count, "Nothing is restored from a rejected backup")
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lucsky/cuid"
+	"gorm.io/gorm"
+)
+
+// maxBlockExcerptLength caps the text returned for a resolved block
+const maxBlockExcerptLength = 280
+
+var (
+	// ErrInvalidBlockRef is returned for a block reference that isn't of the form noteId#blockId
+	ErrInvalidBlockRef = errors.New("block references look like <noteId>#<blockId>")
+	// ErrBlockNotFound is returned when the note has no block with the ID
+	ErrBlockNotFound = errors.New("block not found in note")
+)
+
+// blockIDNodeTypes are the TipTap nodes that get a stable ID so tasks and links can point at them
+var blockIDNodeTypes = map[string]bool{
+	"paragraph":  true,
+	"heading":    true,
+	"codeBlock":  true,
+	"blockquote": true,
+	"listItem":   true,
+	"taskItem":   true,
+	"image":      true,
+}
+
+// ResolvedBlock is the note and current text of a block reference
+type ResolvedBlock struct {
+	Ref        string `json:"ref"`
+	NoteID     string `json:"noteId"`
+	NoteName   string `json:"noteName"`
+	ChapterID  string `json:"chapterId"`
+	NotebookID string `json:"notebookId"`
+	BlockID    string `json:"blockId"`
+	BlockType  string `json:"blockType"`
+	Excerpt    string `json:"excerpt"`
+}
+
+// BlockTaskRef is a task that points at a block of a note
+type BlockTaskRef struct {
+	TaskID      string `json:"taskId"`
+	Title       string `json:"title"`
+	Status      string `json:"status"`
+	TaskBoardID string `json:"taskBoardId"`
+	BlockID     string `json:"blockId"`
+}
+
+// BlockLinkRef is a note link that points at a block of a note
+type BlockLinkRef struct {
+	LinkID       string `json:"linkId"`
+	SourceNoteID string `json:"sourceNoteId"`
+	SourceName   string `json:"sourceName"`
+	LinkType     string `json:"linkType"`
+	Annotation   string `json:"annotation,omitempty"`
+	BlockID      string `json:"blockId"`
+}
+
+// NoteBlockReferences lists what points at the blocks of a note
+type NoteBlockReferences struct {
+	Tasks []BlockTaskRef `json:"tasks"`
+	Links []BlockLinkRef `json:"links"`
+}
+
+// BlockRefService interface defines methods for resolving references to note blocks
+type BlockRefService interface {
+	Resolve(ref string) (*ResolvedBlock, error)
+	ListReferences(noteID string) (*NoteBlockReferences, error)
+}
+
+// blockRefServiceImpl implements the BlockRefService interface
+type blockRefServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewBlockRefService creates a new BlockRefService instance
+func NewBlockRefService() BlockRefService {
+	return &blockRefServiceImpl{
+		db: db.DB,
+	}
+}
+
+// Resolve returns the note and current excerpt of a block reference
+func (s *blockRefServiceImpl) Resolve(ref string) (*ResolvedBlock, error) {
+	noteID, blockID, err := ParseBlockRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var note models.Notes
+	if err := s.db.Preload("Chapter").Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(note.Content), &doc); err != nil {
+		return nil, ErrBlockNotFound
+	}
+	node, ok := findNodeByID(doc.Content, blockID)
+	if !ok {
+		return nil, ErrBlockNotFound
+	}
+
+	return &ResolvedBlock{
+		Ref:        FormatBlockRef(noteID, blockID),
+		NoteID:     note.ID,
+		NoteName:   note.Name,
+		ChapterID:  note.ChapterID,
+		NotebookID: note.Chapter.NotebookID,
+		BlockID:    blockID,
+		BlockType:  node.Type,
+		Excerpt:    truncateLinkText(strings.TrimSpace(blockText(node)), maxBlockExcerptLength),
+	}, nil
+}
+
+// ListReferences returns the tasks and note links that point at blocks of a note
+func (s *blockRefServiceImpl) ListReferences(noteID string) (*NoteBlockReferences, error) {
+	references := &NoteBlockReferences{Tasks: []BlockTaskRef{}, Links: []BlockLinkRef{}}
+
+	var tasks []models.Task
+	if err := s.db.Where("block_ref LIKE ?", noteID+"#%").Order("created_at ASC").Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch block tasks: %w", err)
+	}
+	for _, task := range tasks {
+		_, blockID, _ := ParseBlockRef(task.BlockRef)
+		references.Tasks = append(references.Tasks, BlockTaskRef{
+			TaskID:      task.ID,
+			Title:       task.Title,
+			Status:      task.Status,
+			TaskBoardID: task.TaskBoardID,
+			BlockID:     blockID,
+		})
+	}
+
+	var links []models.NoteLink
+	if err := s.db.Preload("SourceNote").
+		Where("target_note_id = ? AND block_ref <> ''", noteID).
+		Order("created_at ASC").
+		Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch block links: %w", err)
+	}
+	for _, link := range links {
+		entry := BlockLinkRef{LinkID: link.ID, SourceNoteID: link.SourceNoteID, LinkType: link.LinkType, Annotation: link.Annotation, BlockID: link.BlockRef}
+		if link.SourceNote != nil {
+			entry.SourceName = link.SourceNote.Name
+		}
+		references.Links = append(references.Links, entry)
+	}
+
+	return references, nil
+}
+
+// ParseBlockRef splits a block reference into its note and block IDs
+func ParseBlockRef(ref string) (string, string, error) {
+	noteID, blockID, ok := strings.Cut(strings.TrimSpace(ref), "#")
+	if !ok || noteID == "" || blockID == "" || strings.ContainsAny(blockID, "# ") {
+		return "", "", ErrInvalidBlockRef
+	}
+	return noteID, blockID, nil
+}
+
+// FormatBlockRef joins a note and block ID into a block reference
+func FormatBlockRef(noteID, blockID string) string {
+	return noteID + "#" + blockID
+}
+
+// HasBlock reports whether TipTap content has a block with the ID
+func HasBlock(content, blockID string) bool {
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return false
+	}
+	_, ok := findNodeByID(doc.Content, blockID)
+	return ok
+}
+
+// AssignTipTapBlockIDs gives every block of TipTap content a stable id attribute. Blocks that lost their
+// ID in an edit take it back from the previous version, matched by text and otherwise by position among
+// blocks of the same type, so references to them keep working. Content that isn't a TipTap document is
+// returned unchanged.
+func AssignTipTapBlockIDs(previous, content string) string {
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" {
+		return content
+	}
+
+	var previousBlocks []*utils.TipTapNode
+	var previousDoc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(previous), &previousDoc); err == nil {
+		previousBlocks = collectTipTapBlocks(previousDoc.Content)
+	}
+	blocks := collectTipTapBlocks(doc.Content)
+
+	used := make(map[string]bool)
+	var missing []int
+	for i, block := range blocks {
+		id := blockNodeID(*block)
+		if id != "" && !used[id] {
+			used[id] = true
+			continue
+		}
+		// Pasted blocks carry a duplicate ID, they get their own
+		if id != "" {
+			delete(block.Attrs, "id")
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return content
+	}
+
+	// Unused previous IDs, by the block's text and by type and position
+	byText := make(map[string][]string)
+	byPosition := make(map[string]string)
+	typeCounts := make(map[string]int)
+	for _, block := range previousBlocks {
+		key := fmt.Sprintf("%s:%d", block.Type, typeCounts[block.Type])
+		typeCounts[block.Type]++
+		id := blockNodeID(*block)
+		if id == "" || used[id] {
+			continue
+		}
+		textKey := block.Type + ":" + blockText(*block)
+		byText[textKey] = append(byText[textKey], id)
+		byPosition[key] = id
+	}
+	positions := make(map[*utils.TipTapNode]string)
+	typeCounts = make(map[string]int)
+	for _, block := range blocks {
+		positions[block] = fmt.Sprintf("%s:%d", block.Type, typeCounts[block.Type])
+		typeCounts[block.Type]++
+	}
+
+	take := func(id string) bool {
+		if id == "" || used[id] {
+			return false
+		}
+		used[id] = true
+		return true
+	}
+	var unmatched []*utils.TipTapNode
+	for _, i := range missing {
+		block := blocks[i]
+		textKey := block.Type + ":" + blockText(*block)
+		matched := false
+		for len(byText[textKey]) > 0 && !matched {
+			id := byText[textKey][0]
+			byText[textKey] = byText[textKey][1:]
+			if take(id) {
+				setBlockNodeID(block, id)
+				matched = true
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, block)
+		}
+	}
+	for _, block := range unmatched {
+		id := byPosition[positions[block]]
+		if !take(id) {
+			id = cuid.New()
+			used[id] = true
+		}
+		setBlockNodeID(block, id)
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return content
+	}
+	return string(encoded)
+}
+
+// collectTipTapBlocks returns pointers to the blocks that carry IDs, in document order. Paragraphs of
+// list items share their item's ID, and embedded copies of other notes are skipped.
+func collectTipTapBlocks(nodes []utils.TipTapNode) []*utils.TipTapNode {
+	var blocks []*utils.TipTapNode
+	var walk func(nodes []utils.TipTapNode, parentType string)
+	walk = func(nodes []utils.TipTapNode, parentType string) {
+		for i := range nodes {
+			node := &nodes[i]
+			if node.Type == models.NoteEmbedNodeType {
+				continue
+			}
+			itemParagraph := node.Type == "paragraph" && (parentType == "listItem" || parentType == "taskItem")
+			if blockIDNodeTypes[node.Type] && !itemParagraph {
+				blocks = append(blocks, node)
+			}
+			walk(node.Content, node.Type)
+		}
+	}
+	walk(nodes, "doc")
+	return blocks
+}
+
+// blockNodeID returns a node's id attribute
+func blockNodeID(node utils.TipTapNode) string {
+	id, _ := node.Attrs["id"].(string)
+	return id
+}
+
+// setBlockNodeID sets a node's id attribute
+func setBlockNodeID(node *utils.TipTapNode, id string) {
+	if node.Attrs == nil {
+		node.Attrs = make(map[string]interface{})
+	}
+	node.Attrs["id"] = id
+}
+
+// blockText returns the text of a block with its child blocks separated by spaces
+func blockText(node utils.TipTapNode) string {
+	if node.Type == "text" {
+		return node.Text
+	}
+	parts := make([]string, 0, len(node.Content))
+	inline := true
+	for _, child := range node.Content {
+		if child.Type != "text" && child.Type != "hardBreak" {
+			inline = false
+		}
+		parts = append(parts, blockText(child))
+	}
+	if inline {
+		return strings.Join(parts, "")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestBlockRefService creates a block ref service on an in-memory database
+func setupTestBlockRefService(t *testing.T) *blockRefServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.TaskBoard{}, &models.Task{}, &models.NoteLink{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &blockRefServiceImpl{db: db}
+}
+
+// blockIDs returns the ids of the top-level blocks of TipTap content
+func blockIDs(t *testing.T, content string) []string {
+	var doc utils.TipTapDoc
+	require.NoError(t, json.Unmarshal([]byte(content), &doc))
+	ids := make([]string, len(doc.Content))
+	for i, node := range doc.Content {
+		ids[i] = blockNodeID(node)
+	}
+	return ids
+}
+
+func TestParseBlockRef(t *testing.T) {
+	noteID, blockID, err := ParseBlockRef(" note1#block1 ")
+	require.NoError(t, err)
+	assert.Equal(t, "note1", noteID)
+	assert.Equal(t, "block1", blockID)
+
+	for _, ref := range []string{"", "note1", "note1#", "#block1", "note1#a#b"} {
+		_, _, err := ParseBlockRef(ref)
+		assert.ErrorIs(t, err, ErrInvalidBlockRef, ref)
+	}
+}
+
+func TestAssignTipTapBlockIDs_KeepsIDsAcrossEdits(t *testing.T) {
+	original := AssignTipTapBlockIDs("", `{"type":"doc","content":[`+
+		`{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"Plan"}]},`+
+		`{"type":"paragraph","content":[{"type":"text","text":"First"}]},`+
+		`{"type":"paragraph","content":[{"type":"text","text":"Second"}]}]}`)
+	ids := blockIDs(t, original)
+	for _, id := range ids {
+		assert.NotEmpty(t, id)
+	}
+
+	// The editor dropped the ids, reordered the paragraphs and edited the heading
+	edited := AssignTipTapBlockIDs(original, `{"type":"doc","content":[`+
+		`{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"New plan"}]},`+
+		`{"type":"paragraph","content":[{"type":"text","text":"Second"}]},`+
+		`{"type":"paragraph","content":[{"type":"text","text":"First"}]}]}`)
+	assert.Equal(t, []string{ids[0], ids[2], ids[1]}, blockIDs(t, edited))
+
+	// Content that already has its ids is left alone
+	assert.Equal(t, edited, AssignTipTapBlockIDs(original, edited))
+}
+
+func TestAssignTipTapBlockIDs_DuplicateIDs(t *testing.T) {
+	content := AssignTipTapBlockIDs("", `{"type":"doc","content":[`+
+		`{"type":"paragraph","attrs":{"id":"same"},"content":[{"type":"text","text":"One"}]},`+
+		`{"type":"paragraph","attrs":{"id":"same"},"content":[{"type":"text","text":"Pasted"}]}]}`)
+	ids := blockIDs(t, content)
+	assert.Equal(t, "same", ids[0])
+	assert.NotEmpty(t, ids[1])
+	assert.NotEqual(t, "same", ids[1])
+}
+
+func TestAssignTipTapBlockIDs_ListItems(t *testing.T) {
+	content := AssignTipTapBlockIDs("", `{"type":"doc","content":[{"type":"bulletList","content":[`+
+		`{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"Item"}]}]}]}]}`)
+	var doc utils.TipTapDoc
+	require.NoError(t, json.Unmarshal([]byte(content), &doc))
+	item := doc.Content[0].Content[0]
+	assert.NotEmpty(t, blockNodeID(item))
+	assert.Empty(t, blockNodeID(item.Content[0]), "paragraphs of list items share the item's id")
+
+	assert.Equal(t, "not json", AssignTipTapBlockIDs("", "not json"))
+}
+
+func TestBlockRefService_Resolve(t *testing.T) {
+	service := setupTestBlockRefService(t)
+	note := createEmbedNote(t, service.db, "Spec", `{"type":"doc","content":[`+
+		`{"type":"paragraph","attrs":{"id":"b1"},"content":[{"type":"text","text":"Ship the "},{"type":"text","text":"importer"}]}]}`)
+
+	block, err := service.Resolve(FormatBlockRef(note.ID, "b1"))
+	require.NoError(t, err)
+	assert.Equal(t, note.ID, block.NoteID)
+	assert.Equal(t, "Spec", block.NoteName)
+	assert.Equal(t, "paragraph", block.BlockType)
+	assert.Equal(t, "Ship the importer", block.Excerpt)
+	assert.NotEmpty(t, block.NotebookID)
+
+	_, err = service.Resolve(FormatBlockRef(note.ID, "missing"))
+	assert.ErrorIs(t, err, ErrBlockNotFound)
+	_, err = service.Resolve("unknown#b1")
+	assert.ErrorIs(t, err, ErrNoteNotFound)
+}
+
+func TestBlockRefService_ListReferences(t *testing.T) {
+	service := setupTestBlockRefService(t)
+	target := createEmbedNote(t, service.db, "Target", `{"type":"doc","content":[{"type":"paragraph","attrs":{"id":"b1"}}]}`)
+	source := createEmbedNote(t, service.db, "Source", `{"type":"doc","content":[]}`)
+
+	board := models.TaskBoard{Name: "Board", ClerkUserID: "user_owner"}
+	require.NoError(t, service.db.Create(&board).Error)
+	require.NoError(t, service.db.Create(&models.Task{Title: "Linked", TaskBoardID: board.ID, BlockRef: FormatBlockRef(target.ID, "b1")}).Error)
+	require.NoError(t, service.db.Create(&models.Task{Title: "Unlinked", TaskBoardID: board.ID}).Error)
+	require.NoError(t, service.db.Create(&models.NoteLink{ID: "link1", SourceNoteID: source.ID, TargetNoteID: target.ID, BlockRef: "b1", CreatedBy: "user_owner"}).Error)
+	require.NoError(t, service.db.Create(&models.NoteLink{ID: "link2", SourceNoteID: source.ID, TargetNoteID: target.ID, CreatedBy: "user_owner"}).Error)
+
+	references, err := service.ListReferences(target.ID)
+	require.NoError(t, err)
+	require.Len(t, references.Tasks, 1)
+	assert.Equal(t, "Linked", references.Tasks[0].Title)
+	assert.Equal(t, "b1", references.Tasks[0].BlockID)
+	require.Len(t, references.Links, 1)
+	assert.Equal(t, "link1", references.Links[0].LinkID)
+	assert.Equal(t, "Source", references.Links[0].SourceName)
+}
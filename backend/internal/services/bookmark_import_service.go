@@ -0,0 +1,393 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidBookmarkFile is returned when an upload isn't a bookmarks export or has no bookmarks
+	ErrInvalidBookmarkFile = errors.New("invalid bookmarks file")
+	// ErrTooManyBookmarks is returned when an export has more bookmarks than a single import allows
+	ErrTooManyBookmarks = errors.New("too many bookmarks")
+)
+
+const (
+	// MaxBookmarkFileSize is the largest bookmarks export accepted for import
+	MaxBookmarkFileSize   = 10 << 20
+	maxBookmarksPerImport = 5000
+	defaultBookmarkFolder = "Bookmarks"
+)
+
+// ParsedBookmark is a single link from a bookmarks export
+type ParsedBookmark struct {
+	Title       string
+	URL         string
+	Description string
+	Folders     []string
+	AddedAt     *time.Time
+}
+
+// BookmarkImportOptions controls where imported bookmarks are placed
+type BookmarkImportOptions struct {
+	NotebookID     string
+	NotebookName   string
+	OrganizationID *string
+	Flatten        bool
+	ReadingList    bool // Add the bookmarks to the reading list instead of creating notes
+}
+
+// BookmarkImportResult summarizes an import
+type BookmarkImportResult struct {
+	NotebookID      string `json:"notebookId"`
+	ChaptersCreated int    `json:"chaptersCreated"`
+	NotesCreated    int    `json:"notesCreated"`
+	Skipped         int    `json:"skipped"`
+	PreviewsQueued  int    `json:"previewsQueued"`
+	ReadingList     int    `json:"readingList,omitempty"`
+}
+
+// BookmarkImportService interface defines methods for importing browser bookmarks
+type BookmarkImportService interface {
+	ImportBookmarks(ctx context.Context, clerkUserID string, file io.Reader, options BookmarkImportOptions) (*BookmarkImportResult, error)
+}
+
+// bookmarkImportServiceImpl implements the BookmarkImportService interface
+type bookmarkImportServiceImpl struct {
+	db          *gorm.DB
+	previews    LinkPreviewService
+	readingList ReadingListService
+	queue       *JobQueue
+}
+
+// NewBookmarkImportService creates a new BookmarkImportService instance
+func NewBookmarkImportService() BookmarkImportService {
+	return &bookmarkImportServiceImpl{
+		db:          db.DB,
+		previews:    NewLinkPreviewService(),
+		readingList: NewReadingListService(),
+		queue:       GetJobQueue(),
+	}
+}
+
+// ImportBookmarks creates a note per bookmark, with a chapter per folder unless flattened.
+// Link previews are fetched in the background once the notes exist. Bookmarks imported to the
+// reading list become reading list items instead.
+func (s *bookmarkImportServiceImpl) ImportBookmarks(ctx context.Context, clerkUserID string, file io.Reader, options BookmarkImportOptions) (*BookmarkImportResult, error) {
+	bookmarks, err := ParseNetscapeBookmarks(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(bookmarks) > maxBookmarksPerImport {
+		return nil, fmt.Errorf("%w: an import can contain at most %d bookmarks", ErrTooManyBookmarks, maxBookmarksPerImport)
+	}
+	if options.ReadingList {
+		added, err := s.readingList.SaveBookmarks(ctx, clerkUserID, bookmarks)
+		if err != nil {
+			log.Error().Err(err).Str("clerk_user_id", clerkUserID).Msg("Failed to import bookmarks to reading list")
+			return nil, err
+		}
+		return &BookmarkImportResult{ReadingList: added, Skipped: len(bookmarks) - added}, nil
+	}
+
+	result := &BookmarkImportResult{}
+	type createdNote struct {
+		id  string
+		url string
+	}
+	var created []createdNote
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var notebook models.Notebook
+		if options.NotebookID != "" {
+			if err := tx.Where("id = ?", options.NotebookID).First(&notebook).Error; err != nil {
+				return fmt.Errorf("failed to find notebook: %w", err)
+			}
+		} else {
+			notebook = models.Notebook{
+				Name:           options.NotebookName,
+				ClerkUserID:    clerkUserID,
+				OrganizationID: options.OrganizationID,
+			}
+			if notebook.Name == "" {
+				notebook.Name = "Imported Bookmarks"
+			}
+			if err := tx.Create(&notebook).Error; err != nil {
+				return fmt.Errorf("failed to create notebook: %w", err)
+			}
+		}
+		result.NotebookID = notebook.ID
+
+		chapters := make(map[string]*models.Chapter)
+		seen := make(map[string]bool)
+
+		for _, bookmark := range bookmarks {
+			if !isImportableBookmark(bookmark.URL) {
+				result.Skipped++
+				continue
+			}
+
+			chapterName := defaultBookmarkFolder
+			if !options.Flatten && len(bookmark.Folders) > 0 {
+				chapterName = truncateLinkText(strings.Join(bookmark.Folders, " / "), 255)
+			}
+			if seen[chapterName+"\x00"+bookmark.URL] {
+				result.Skipped++
+				continue
+			}
+			seen[chapterName+"\x00"+bookmark.URL] = true
+
+			chapter, exists := chapters[chapterName]
+			if !exists {
+				chapter = &models.Chapter{
+					Name:           chapterName,
+					NotebookID:     notebook.ID,
+					OrganizationID: notebook.OrganizationID,
+				}
+				if err := tx.Create(chapter).Error; err != nil {
+					return fmt.Errorf("failed to create chapter: %w", err)
+				}
+				chapters[chapterName] = chapter
+				result.ChaptersCreated++
+			}
+
+			content, err := bookmarkNoteContent(bookmark)
+			if err != nil {
+				return err
+			}
+			note := models.Notes{
+				Name:           bookmarkNoteName(bookmark),
+				Content:        content,
+				ChapterID:      chapter.ID,
+				OrganizationID: notebook.OrganizationID,
+			}
+			if err := tx.Create(&note).Error; err != nil {
+				return fmt.Errorf("failed to create note: %w", err)
+			}
+			created = append(created, createdNote{id: note.ID, url: bookmark.URL})
+			result.NotesCreated++
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Str("clerk_user_id", clerkUserID).Msg("Failed to import bookmarks")
+		return nil, err
+	}
+
+	for _, note := range created {
+		noteID, link := note.id, note.url
+		err := s.queue.Enqueue(Job{
+			Name:        "link-preview",
+			MaxAttempts: 2,
+			Run: func(ctx context.Context) error {
+				_, err := s.previews.PreviewLink(ctx, link, &noteID, clerkUserID)
+				if errors.Is(err, ErrInvalidLinkURL) || errors.Is(err, ErrLinkPreviewBlocked) {
+					return nil
+				}
+				return err
+			},
+		})
+		if err != nil {
+			break
+		}
+		result.PreviewsQueued++
+	}
+
+	log.Info().
+		Str("clerk_user_id", clerkUserID).
+		Str("notebook_id", result.NotebookID).
+		Int("notes", result.NotesCreated).
+		Int("chapters", result.ChaptersCreated).
+		Int("skipped", result.Skipped).
+		Msg("Imported bookmarks")
+
+	return result, nil
+}
+
+// ParseNetscapeBookmarks reads a Netscape bookmarks export as written by Chrome, Firefox, Safari and Edge
+func ParseNetscapeBookmarks(r io.Reader) ([]ParsedBookmark, error) {
+	var (
+		bookmarks []ParsedBookmark
+		folders   []string
+		levels    []bool
+		pending   string
+		text      strings.Builder
+		current   *ParsedBookmark
+		inFolder  bool
+		inDesc    bool
+		afterLink bool
+		sawHeader bool
+	)
+
+	tokenizer := html.NewTokenizer(r)
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidBookmarkFile, err)
+			}
+			break
+		}
+
+		switch tokenType {
+		case html.DoctypeToken:
+			sawHeader = sawHeader || strings.Contains(strings.ToUpper(string(tokenizer.Text())), "NETSCAPE-BOOKMARK-FILE")
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "p" {
+				inDesc = false
+			}
+			switch token.Data {
+			case "h3":
+				inFolder = true
+				afterLink = false
+				text.Reset()
+			case "dl":
+				// A list opened right after a folder heading holds that folder's contents
+				levels = append(levels, pending != "")
+				if pending != "" {
+					folders = append(folders, pending)
+				}
+				pending = ""
+				afterLink = false
+			case "a":
+				current = &ParsedBookmark{Folders: append([]string(nil), folders...)}
+				for _, attr := range token.Attr {
+					switch attr.Key {
+					case "href":
+						current.URL = strings.TrimSpace(attr.Val)
+					case "add_date":
+						if seconds, err := strconv.ParseInt(attr.Val, 10, 64); err == nil && seconds > 0 {
+							addedAt := time.Unix(seconds, 0).UTC()
+							current.AddedAt = &addedAt
+						}
+					}
+				}
+				text.Reset()
+			case "dd":
+				// Descriptions follow the bookmark they belong to; folder descriptions are ignored
+				inDesc = afterLink
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "h3":
+				if inFolder {
+					pending = strings.Join(strings.Fields(text.String()), " ")
+					if pending == "" {
+						pending = "Untitled folder"
+					}
+					inFolder = false
+				}
+			case "dl":
+				if n := len(levels); n > 0 {
+					if levels[n-1] && len(folders) > 0 {
+						folders = folders[:len(folders)-1]
+					}
+					levels = levels[:n-1]
+				}
+				inDesc = false
+			case "a":
+				if current != nil {
+					current.Title = strings.Join(strings.Fields(text.String()), " ")
+					if current.URL != "" {
+						bookmarks = append(bookmarks, *current)
+					}
+					afterLink = current.URL != ""
+					current = nil
+				}
+			}
+		case html.TextToken:
+			switch {
+			case inFolder || current != nil:
+				text.Write(tokenizer.Text())
+			case inDesc:
+				last := &bookmarks[len(bookmarks)-1]
+				last.Description = strings.TrimSpace(last.Description + " " + strings.Join(strings.Fields(string(tokenizer.Text())), " "))
+			}
+		}
+	}
+
+	if len(bookmarks) == 0 {
+		if !sawHeader {
+			return nil, fmt.Errorf("%w: not a bookmarks export", ErrInvalidBookmarkFile)
+		}
+		return nil, fmt.Errorf("%w: no bookmarks found", ErrInvalidBookmarkFile)
+	}
+	return bookmarks, nil
+}
+
+// isImportableBookmark skips browser-internal links such as javascript: bookmarklets and place: queries
+func isImportableBookmark(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	return parsed.Scheme == "http" || parsed.Scheme == "https"
+}
+
+// bookmarkNoteName uses the bookmark title, falling back to the link's host
+func bookmarkNoteName(bookmark ParsedBookmark) string {
+	if bookmark.Title != "" {
+		return truncateLinkText(bookmark.Title, 255)
+	}
+	if parsed, err := url.Parse(bookmark.URL); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return truncateLinkText(bookmark.URL, 255)
+}
+
+// bookmarkNoteContent builds the TipTap document for a bookmark note
+func bookmarkNoteContent(bookmark ParsedBookmark) (string, error) {
+	nodes := []utils.TipTapNode{
+		{
+			Type: "paragraph",
+			Content: []utils.TipTapNode{
+				{
+					Type: "text",
+					Text: bookmark.URL,
+					Marks: []utils.TipTapMark{
+						{Type: "link", Attrs: map[string]interface{}{"href": bookmark.URL}},
+					},
+				},
+			},
+		},
+	}
+	if bookmark.Description != "" {
+		nodes = append(nodes, utils.TipTapNode{
+			Type:    "paragraph",
+			Content: []utils.TipTapNode{{Type: "text", Text: bookmark.Description}},
+		})
+	}
+	if bookmark.AddedAt != nil {
+		nodes = append(nodes, utils.TipTapNode{
+			Type: "paragraph",
+			Content: []utils.TipTapNode{{
+				Type:  "text",
+				Text:  "Bookmarked on " + bookmark.AddedAt.Format("January 2, 2006"),
+				Marks: []utils.TipTapMark{{Type: "italic"}},
+			}},
+		})
+	}
+
+	content, err := json.Marshal(utils.TipTapDoc{Type: "doc", Content: nodes})
+	if err != nil {
+		return "", fmt.Errorf("failed to build note content: %w", err)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testBookmarksExport = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1600000000" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DD>Folder description
+    <DL><p>
+        <DT><A HREF="https://go.dev/" ADD_DATE="1700000000">The Go Programming Language</A>
+        <DD>Docs &amp; downloads
+        <DT><H3>Reading</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/article">An article</A>
+            <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+        </DL><p>
+        <DT><A HREF="https://example.com/untitled"></A>
+    </DL><p>
+    <DT><A HREF="https://example.com/article">An article</A>
+</DL><p>`
+
+// setupTestBookmarkImportService creates a bookmark import service backed by an in-memory database
+func setupTestBookmarkImportService(t *testing.T) *bookmarkImportServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.ExternalLink{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &bookmarkImportServiceImpl{
+		db:       db,
+		previews: setupTestLinkPreviewService(t),
+		queue:    NewJobQueue(1, 100),
+	}
+}
+
+func TestParseNetscapeBookmarks(t *testing.T) {
+	bookmarks, err := ParseNetscapeBookmarks(strings.NewReader(testBookmarksExport))
+	require.NoError(t, err)
+	require.Len(t, bookmarks, 5)
+
+	assert.Equal(t, "The Go Programming Language", bookmarks[0].Title)
+	assert.Equal(t, "https://go.dev/", bookmarks[0].URL)
+	assert.Equal(t, "Docs & downloads", bookmarks[0].Description)
+	assert.Equal(t, []string{"Bookmarks bar"}, bookmarks[0].Folders)
+	require.NotNil(t, bookmarks[0].AddedAt)
+	assert.Equal(t, int64(1700000000), bookmarks[0].AddedAt.Unix())
+
+	assert.Equal(t, []string{"Bookmarks bar", "Reading"}, bookmarks[1].Folders)
+	assert.Empty(t, bookmarks[1].Description)
+	assert.Equal(t, []string{"Bookmarks bar"}, bookmarks[3].Folders, "Closing a folder should return to its parent")
+	assert.Empty(t, bookmarks[4].Folders)
+}
+
+func TestParseNetscapeBookmarks_RejectsOtherFiles(t *testing.T) {
+	_, err := ParseNetscapeBookmarks(strings.NewReader("<html><body>Hello</body></html>"))
+	assert.ErrorIs(t, err, ErrInvalidBookmarkFile)
+
+	_, err = ParseNetscapeBookmarks(strings.NewReader("<!DOCTYPE NETSCAPE-Bookmark-file-1><DL><p></DL>"))
+	assert.ErrorIs(t, err, ErrInvalidBookmarkFile)
+}
+
+func TestImportBookmarks_CreatesChaptersPerFolder(t *testing.T) {
+	service := setupTestBookmarkImportService(t)
+
+	result, err := service.ImportBookmarks(context.Background(), "user_1", strings.NewReader(testBookmarksExport), BookmarkImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.ChaptersCreated)
+	assert.Equal(t, 4, result.NotesCreated)
+	assert.Equal(t, 1, result.Skipped, "Bookmarklets should be skipped")
+	assert.Equal(t, 4, result.PreviewsQueued)
+	assert.Equal(t, 4, service.queue.Len())
+
+	var notebook models.Notebook
+	require.NoError(t, service.db.Preload("Chapters.Files").First(&notebook, "id = ?", result.NotebookID).Error)
+	assert.Equal(t, "Imported Bookmarks", notebook.Name)
+	assert.Equal(t, "user_1", notebook.ClerkUserID)
+
+	chapters := make(map[string][]models.Notes)
+	for _, chapter := range notebook.Chapters {
+		chapters[chapter.Name] = chapter.Files
+	}
+	require.Len(t, chapters["Bookmarks bar"], 2)
+	require.Len(t, chapters["Bookmarks bar / Reading"], 1)
+	require.Len(t, chapters["Bookmarks"], 1)
+	assert.Contains(t, chapters["Bookmarks bar / Reading"][0].Content, `"href":"https://example.com/article"`)
+
+	names := []string{chapters["Bookmarks bar"][0].Name, chapters["Bookmarks bar"][1].Name}
+	assert.ElementsMatch(t, []string{"The Go Programming Language", "example.com"}, names, "Untitled bookmarks should be named after their host")
+}
+
+func TestImportBookmarks_FlattenDeduplicates(t *testing.T) {
+	service := setupTestBookmarkImportService(t)
+	notebook := models.Notebook{Name: "Links", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+
+	result, err := service.ImportBookmarks(context.Background(), "user_1", strings.NewReader(testBookmarksExport), BookmarkImportOptions{
+		NotebookID: notebook.ID,
+		Flatten:    true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, notebook.ID, result.NotebookID)
+	assert.Equal(t, 1, result.ChaptersCreated)
+	assert.Equal(t, 3, result.NotesCreated)
+	assert.Equal(t, 2, result.Skipped, "The repeated article and the bookmarklet should be skipped")
+}
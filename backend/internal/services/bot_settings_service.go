@@ -0,0 +1,245 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/pkg/recallai"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultBotName is the name Recall.ai gives bots that aren't named
+	defaultBotName = "Meeting Notetaker"
+	// maxBotNameLength caps a bot's name, in characters
+	maxBotNameLength = 100
+	// maxJoinMessageLength caps a join message, in characters, leaving room for the consent notice in a
+	// chat message
+	maxJoinMessageLength = 1000
+)
+
+// ErrInvalidBotSettings is returned when saving a bot name, join message or consent mode that can't be used
+var ErrInvalidBotSettings = errors.New("invalid bot settings")
+
+// BotSettingsInput is what an organization sets for its meeting bot
+type BotSettingsInput struct {
+	BotName     string `json:"botName"`
+	JoinMessage string `json:"joinMessage"`
+	ConsentMode string `json:"consentMode"`
+}
+
+// BotSettings is the API representation of an organization's meeting bot settings
+type BotSettings struct {
+	BotName     string `json:"botName"`
+	JoinMessage string `json:"joinMessage"`
+	ConsentMode string `json:"consentMode"`
+}
+
+// BotConfigPreview is what the bot does when it joins an organization member's meeting, and the bot_config
+// sent to Recall.ai for it
+type BotConfigPreview struct {
+	BotName                string                 `json:"botName"`
+	ChatMessage            string                 `json:"chatMessage"`
+	LeaveIfRecordingDenied bool                   `json:"leaveIfRecordingDenied"`
+	Transcribe             bool                   `json:"transcribe"`
+	BotConfig              map[string]interface{} `json:"botConfig"`
+}
+
+// BotSettingsService interface defines methods for configuring how the meeting bot presents itself in
+// organization members' meetings
+type BotSettingsService interface {
+	GetSettings(ctx context.Context, organizationID string) (*BotSettings, error)
+	SaveSettings(ctx context.Context, organizationID string, input BotSettingsInput, updatedBy string) (*BotSettings, error)
+	Preview(ctx context.Context, organizationID string) (*BotConfigPreview, error)
+	OptionsForUser(ctx context.Context, clerkUserID string) (recallai.BotOptions, error)
+}
+
+// botSettingsServiceImpl implements the BotSettingsService interface
+type botSettingsServiceImpl struct {
+	db              *gorm.DB
+	organizationIDs func(ctx context.Context, clerkUserID string) ([]string, error)
+}
+
+// NewBotSettingsService creates a new BotSettingsService instance
+func NewBotSettingsService() BotSettingsService {
+	return newBotSettingsService(db.DB)
+}
+
+func newBotSettingsService(tx *gorm.DB) *botSettingsServiceImpl {
+	return &botSettingsServiceImpl{db: tx, organizationIDs: clerkUserOrganizationIDs}
+}
+
+// GetSettings returns an organization's bot settings, Recall.ai's defaults if none are saved
+func (s *botSettingsServiceImpl) GetSettings(ctx context.Context, organizationID string) (*BotSettings, error) {
+	settings, err := s.findSettings(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	return toBotSettings(settings), nil
+}
+
+// SaveSettings sets how the bot presents itself in an organization's meetings. It applies to bots created
+// or scheduled from then on.
+func (s *botSettingsServiceImpl) SaveSettings(ctx context.Context, organizationID string, input BotSettingsInput, updatedBy string) (*BotSettings, error) {
+	botName := strings.TrimSpace(input.BotName)
+	joinMessage := strings.TrimSpace(input.JoinMessage)
+	consentMode := input.ConsentMode
+	if consentMode == "" {
+		consentMode = models.BotConsentNone
+	}
+
+	switch {
+	case strings.ContainsAny(botName, "\r\n"):
+		return nil, fmt.Errorf("%w: the bot name must be one line", ErrInvalidBotSettings)
+	case utf8.RuneCountInString(botName) > maxBotNameLength:
+		return nil, fmt.Errorf("%w: the bot name can be at most %d characters", ErrInvalidBotSettings, maxBotNameLength)
+	case utf8.RuneCountInString(joinMessage) > maxJoinMessageLength:
+		return nil, fmt.Errorf("%w: the join message can be at most %d characters", ErrInvalidBotSettings, maxJoinMessageLength)
+	}
+	switch consentMode {
+	case models.BotConsentNone, models.BotConsentNotify, models.BotConsentLeaveOnObjection:
+	default:
+		return nil, fmt.Errorf("%w: unknown consent mode %q", ErrInvalidBotSettings, consentMode)
+	}
+
+	settings := models.OrganizationBotSettings{OrganizationID: organizationID}
+	if err := s.db.WithContext(ctx).Where(models.OrganizationBotSettings{OrganizationID: organizationID}).
+		Assign(map[string]interface{}{
+			"bot_name":     botName,
+			"join_message": joinMessage,
+			"consent_mode": consentMode,
+			"updated_by":   updatedBy,
+		}).
+		FirstOrCreate(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to save bot settings: %w", err)
+	}
+	return toBotSettings(&settings), nil
+}
+
+// Preview returns what the bot does when it joins a meeting with an organization's saved settings, and the
+// bot_config sent to Recall.ai for it
+func (s *botSettingsServiceImpl) Preview(ctx context.Context, organizationID string) (*BotConfigPreview, error) {
+	settings, err := s.findSettings(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var transcription models.OrganizationTranscriptionSettings
+	if err := s.db.WithContext(ctx).Where("organization_id = ?", organizationID).Limit(1).Find(&transcription).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch transcription settings: %w", err)
+	}
+	transcribe := transcription.Provider != models.TranscriptionProviderWhisper
+
+	options := botOptions(settings.BotName, settings.JoinMessage, settings.ConsentMode)
+	botName := options.BotName
+	if botName == "" {
+		botName = defaultBotName
+	}
+	return &BotConfigPreview{
+		BotName:                botName,
+		ChatMessage:            options.JoinMessage,
+		LeaveIfRecordingDenied: options.LeaveIfRecordingDenied,
+		Transcribe:             transcribe,
+		BotConfig:              MeetingBotConfig(transcribe, options),
+	}, nil
+}
+
+// OptionsForUser returns how a new bot of the user's presents itself. The bot name and join message come
+// from the first of their organizations to set them, and the strictest consent mode among their
+// organizations applies.
+func (s *botSettingsServiceImpl) OptionsForUser(ctx context.Context, clerkUserID string) (recallai.BotOptions, error) {
+	organizationIDs, err := s.organizationIDs(ctx, clerkUserID)
+	if err != nil {
+		return recallai.BotOptions{}, fmt.Errorf("failed to fetch organizations: %w", err)
+	}
+	if len(organizationIDs) == 0 {
+		return recallai.BotOptions{}, nil
+	}
+
+	var settings []models.OrganizationBotSettings
+	if err := s.db.WithContext(ctx).Where("organization_id IN ?", organizationIDs).
+		Order("created_at ASC, id ASC").
+		Find(&settings).Error; err != nil {
+		return recallai.BotOptions{}, fmt.Errorf("failed to fetch bot settings: %w", err)
+	}
+
+	var botName, joinMessage string
+	consentMode := models.BotConsentNone
+	for _, setting := range settings {
+		if botName == "" {
+			botName = setting.BotName
+		}
+		if joinMessage == "" {
+			joinMessage = setting.JoinMessage
+		}
+		if consentStrictness(setting.ConsentMode) > consentStrictness(consentMode) {
+			consentMode = setting.ConsentMode
+		}
+	}
+	return botOptions(botName, joinMessage, consentMode), nil
+}
+
+func (s *botSettingsServiceImpl) findSettings(ctx context.Context, organizationID string) (*models.OrganizationBotSettings, error) {
+	var settings models.OrganizationBotSettings
+	if err := s.db.WithContext(ctx).Where("organization_id = ?", organizationID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch bot settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// botOptions returns the Recall.ai bot options for settings, with the consent notice after the join message
+func botOptions(botName, joinMessage, consentMode string) recallai.BotOptions {
+	name := botName
+	if name == "" {
+		name = defaultBotName
+	}
+
+	message := joinMessage
+	switch consentMode {
+	case models.BotConsentNotify:
+		message = joinBotMessage(message, fmt.Sprintf("%s is recording and transcribing this meeting.", name))
+	case models.BotConsentLeaveOnObjection:
+		message = joinBotMessage(message, fmt.Sprintf("%s is recording and transcribing this meeting. If the host denies it permission to record, it will leave.", name))
+	}
+
+	return recallai.BotOptions{
+		BotName:                botName,
+		JoinMessage:            message,
+		LeaveIfRecordingDenied: consentMode == models.BotConsentLeaveOnObjection,
+	}
+}
+
+func joinBotMessage(message, notice string) string {
+	if message == "" {
+		return notice
+	}
+	return message + "\n\n" + notice
+}
+
+// consentStrictness orders consent modes from the least to the most strict
+func consentStrictness(mode string) int {
+	switch mode {
+	case models.BotConsentNotify:
+		return 1
+	case models.BotConsentLeaveOnObjection:
+		return 2
+	}
+	return 0
+}
+
+func toBotSettings(settings *models.OrganizationBotSettings) *BotSettings {
+	consentMode := settings.ConsentMode
+	if consentMode == "" {
+		consentMode = models.BotConsentNone
+	}
+	return &BotSettings{
+		BotName:     settings.BotName,
+		JoinMessage: settings.JoinMessage,
+		ConsentMode: consentMode,
+	}
+}
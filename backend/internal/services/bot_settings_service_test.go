@@ -0,0 +1,124 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/pkg/recallai"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestBotSettingsService(t *testing.T) *botSettingsServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.OrganizationBotSettings{}, &models.OrganizationTranscriptionSettings{}), "Failed to migrate test database")
+
+	return &botSettingsServiceImpl{
+		db: db,
+		organizationIDs: func(ctx context.Context, clerkUserID string) ([]string, error) {
+			switch clerkUserID {
+			case "user_1":
+				return []string{"org_1", "org_2"}, nil
+			case "user_broken":
+				return nil, errors.New("clerk unavailable")
+			}
+			return nil, nil
+		},
+	}
+}
+
+func TestSaveBotSettings(t *testing.T) {
+	service := setupTestBotSettingsService(t)
+	ctx := context.Background()
+
+	settings, err := service.GetSettings(ctx, "org_1")
+	require.NoError(t, err)
+	assert.Equal(t, BotSettings{ConsentMode: models.BotConsentNone}, *settings, "Organizations use Recall.ai's defaults")
+
+	settings, err = service.SaveSettings(ctx, "org_1", BotSettingsInput{
+		BotName:     "  Acme Notes  ",
+		JoinMessage: "Hi, I'm taking notes for Acme.",
+		ConsentMode: models.BotConsentNotify,
+	}, "admin_1")
+	require.NoError(t, err)
+	assert.Equal(t, BotSettings{BotName: "Acme Notes", JoinMessage: "Hi, I'm taking notes for Acme.", ConsentMode: models.BotConsentNotify}, *settings)
+
+	settings, err = service.SaveSettings(ctx, "org_1", BotSettingsInput{BotName: "Acme Notes"}, "admin_1")
+	require.NoError(t, err)
+	assert.Equal(t, models.BotConsentNone, settings.ConsentMode, "Saving replaces the settings")
+
+	for _, input := range []BotSettingsInput{
+		{ConsentMode: "ask_everyone"},
+		{BotName: "Acme\nNotes"},
+		{BotName: strings.Repeat("a", maxBotNameLength+1)},
+		{JoinMessage: strings.Repeat("a", maxJoinMessageLength+1)},
+	} {
+		_, err = service.SaveSettings(ctx, "org_2", input, "admin_2")
+		assert.ErrorIs(t, err, ErrInvalidBotSettings)
+	}
+}
+
+func TestBotOptionsForUser(t *testing.T) {
+	service := setupTestBotSettingsService(t)
+	ctx := context.Background()
+
+	options, err := service.OptionsForUser(ctx, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, recallai.BotOptions{}, options, "Bots are left as they are without settings")
+
+	_, err = service.SaveSettings(ctx, "org_1", BotSettingsInput{BotName: "Acme Notes", JoinMessage: "Hi from Acme."}, "admin_1")
+	require.NoError(t, err)
+	_, err = service.SaveSettings(ctx, "org_2", BotSettingsInput{BotName: "Other Notes", ConsentMode: models.BotConsentLeaveOnObjection}, "admin_2")
+	require.NoError(t, err)
+
+	options, err = service.OptionsForUser(ctx, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Notes", options.BotName, "The first organization to name the bot wins")
+	assert.True(t, options.LeaveIfRecordingDenied, "The strictest consent mode applies")
+	assert.Equal(t, "Hi from Acme.\n\nAcme Notes is recording and transcribing this meeting. If the host denies it permission to record, it will leave.", options.JoinMessage)
+
+	options, err = service.OptionsForUser(ctx, "user_2")
+	require.NoError(t, err)
+	assert.Equal(t, recallai.BotOptions{}, options)
+
+	_, err = service.OptionsForUser(ctx, "user_broken")
+	assert.Error(t, err, "Bots aren't sent without the consent notice when the organizations can't be checked")
+}
+
+func TestPreviewBotSettings(t *testing.T) {
+	service := setupTestBotSettingsService(t)
+	ctx := context.Background()
+
+	preview, err := service.Preview(ctx, "org_1")
+	require.NoError(t, err)
+	assert.Equal(t, defaultBotName, preview.BotName)
+	assert.Empty(t, preview.ChatMessage)
+	assert.True(t, preview.Transcribe)
+	assert.NotContains(t, preview.BotConfig, "chat")
+
+	_, err = service.SaveSettings(ctx, "org_1", BotSettingsInput{ConsentMode: models.BotConsentLeaveOnObjection}, "admin_1")
+	require.NoError(t, err)
+	require.NoError(t, service.db.Create(&models.OrganizationTranscriptionSettings{OrganizationID: "org_1", Provider: models.TranscriptionProviderWhisper}).Error)
+
+	preview, err = service.Preview(ctx, "org_1")
+	require.NoError(t, err)
+	assert.Equal(t, "Meeting Notetaker is recording and transcribing this meeting. If the host denies it permission to record, it will leave.", preview.ChatMessage)
+	assert.True(t, preview.LeaveIfRecordingDenied)
+	assert.False(t, preview.Transcribe)
+
+	// The bot_config is what's scheduled for calendar events
+	encoded, err := json.Marshal(preview.BotConfig)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"recording_config": {"video_mixed_layout": "gallery_view_v2", "video_separate_mp4": {}, "audio_mixed_mp3": {}},
+		"chat": {"on_bot_join": {"send_to": "everyone", "message": "Meeting Notetaker is recording and transcribing this meeting. If the host denies it permission to record, it will leave."}},
+		"automatic_leave": {"recording_permission_denied_timeout": 0}
+	}`, string(encoded))
+}
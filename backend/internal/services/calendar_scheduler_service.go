@@ -4,20 +4,39 @@ import (
 	"backend/db"
 	"backend/internal/models"
 	"backend/pkg/recallai"
+	"context"
+	"errors"
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Errors returned when scheduling a bot for a calendar event on a user's behalf
+var (
+	ErrCalendarEventNotFound = errors.New("calendar event not found")
+	ErrBotAlreadyScheduled   = errors.New("bot already scheduled for this event")
+)
+
+// Errors returned when linking an agenda note to a calendar event
+var (
+	ErrCalendarEventEnded    = errors.New("calendar event has already ended")
+	ErrAgendaNoteNotEditable = errors.New("agenda note not found or not editable")
 )
 
 // CalendarSchedulerService handles automatic bot scheduling for calendar events
 type CalendarSchedulerService struct {
-	recallClient *recallai.Client
+	recallClient  *recallai.Client
+	transcription *transcriptionSettingsServiceImpl
+	botSettings   *botSettingsServiceImpl
 }
 
 // NewCalendarSchedulerService creates a new calendar scheduler service
 func NewCalendarSchedulerService() *CalendarSchedulerService {
 	return &CalendarSchedulerService{
-		recallClient: recallai.NewClient(),
+		recallClient:  recallai.NewClient(),
+		transcription: newTranscriptionSettingsService(db.DB),
+		botSettings:   newBotSettingsService(db.DB),
 	}
 }
 
@@ -149,23 +168,17 @@ func (s *CalendarSchedulerService) scheduleBot(calendar models.Calendar, event r
 		deduplicationKey = event.ID
 	}
 
-	// Bot configuration with transcription and recording
-	botConfig := map[string]interface{}{
-		"recording_config": map[string]interface{}{
-			"transcript": map[string]interface{}{
-				"provider": map[string]interface{}{
-					"recallai_streaming": map[string]interface{}{
-						"mode": "prioritize_accuracy",
-					},
-				},
-			},
-			"video_mixed_layout": "gallery_view_v2",
-			"video_separate_mp4": map[string]interface{}{},
-		},
+	provider, err := s.transcription.ProviderForUser(context.Background(), calendar.ClerkUserID)
+	if err != nil {
+		return err
+	}
+	options, err := s.botSettings.OptionsForUser(context.Background(), calendar.ClerkUserID)
+	if err != nil {
+		return err
 	}
 
 	// Schedule bot via Recall API
-	_, err := s.recallClient.ScheduleBotForEvent(event.ID, deduplicationKey, botConfig)
+	_, err = s.recallClient.ScheduleBotForEvent(event.ID, deduplicationKey, MeetingBotConfig(provider == models.TranscriptionProviderRecall, options))
 	if err != nil {
 		return err
 	}
@@ -220,3 +233,176 @@ func (s *CalendarSchedulerService) SyncCalendar(calendarID string) error {
 	s.syncAndScheduleCalendar(calendar)
 	return nil
 }
+
+// MeetingBotConfig returns the Recall.ai bot configuration with recording, and transcription unless the
+// meeting is transcribed elsewhere from its recorded audio, and how the bot presents itself
+func MeetingBotConfig(transcribe bool, options recallai.BotOptions) map[string]interface{} {
+	recordingConfig := map[string]interface{}{
+		"video_mixed_layout": "gallery_view_v2",
+		"video_separate_mp4": map[string]interface{}{},
+	}
+	if transcribe {
+		recordingConfig["transcript"] = map[string]interface{}{
+			"provider": map[string]interface{}{
+				"recallai_streaming": map[string]interface{}{
+					"mode": "prioritize_accuracy",
+				},
+			},
+		}
+	} else {
+		recordingConfig["audio_mixed_mp3"] = map[string]interface{}{}
+	}
+	config := map[string]interface{}{"recording_config": recordingConfig}
+
+	var presentation recallai.CreateBotRequest
+	options.Apply(&presentation)
+	if presentation.BotName != "" {
+		config["bot_name"] = presentation.BotName
+	}
+	if presentation.Chat != nil {
+		config["chat"] = presentation.Chat
+	}
+	if presentation.AutomaticLeave != nil {
+		config["automatic_leave"] = presentation.AutomaticLeave
+	}
+	return config
+}
+
+// ListUpcomingEvents returns the user's calendar events that haven't ended and start before the given time
+func (s *CalendarSchedulerService) ListUpcomingEvents(clerkUserID string, until time.Time) ([]models.CalendarEvent, error) {
+	var events []models.CalendarEvent
+	err := db.DB.Joins("JOIN calendars ON calendars.id = calendar_events.calendar_id").
+		Where("calendars.clerk_user_id = ? AND calendar_events.is_deleted = ?", clerkUserID, false).
+		Where("calendar_events.end_time >= ? AND calendar_events.start_time <= ?", time.Now(), until).
+		Preload("AgendaNote").
+		Order("calendar_events.start_time ASC").
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ScheduleBotForEvent schedules a recording bot for one of the user's calendar events
+func (s *CalendarSchedulerService) ScheduleBotForEvent(clerkUserID string, eventID string) (*models.CalendarEvent, error) {
+	// Find event and verify ownership
+	var event models.CalendarEvent
+	if err := db.DB.Preload("Calendar").Where("id = ?", eventID).First(&event).Error; err != nil {
+		return nil, ErrCalendarEventNotFound
+	}
+	if event.Calendar.ClerkUserID != clerkUserID {
+		return nil, ErrCalendarEventNotFound
+	}
+	if event.BotScheduled {
+		return nil, ErrBotAlreadyScheduled
+	}
+
+	// Use ICalUID as deduplication key (ensures one bot per recurring event series)
+	deduplicationKey := event.ICalUID
+	if deduplicationKey == "" {
+		deduplicationKey = event.RecallEventID
+	}
+
+	provider, err := s.transcription.ProviderForUser(context.Background(), clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	options, err := s.botSettings.OptionsForUser(context.Background(), clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedEvent, err := s.recallClient.ScheduleBotForEvent(event.RecallEventID, deduplicationKey, MeetingBotConfig(provider == models.TranscriptionProviderRecall, options))
+	if err != nil {
+		return nil, err
+	}
+
+	// Update event in database
+	event.BotScheduled = true
+	if len(updatedEvent.Bots) > 0 {
+		event.BotID = &updatedEvent.Bots[0].BotID
+	}
+
+	if err := db.DB.Save(&event).Error; err != nil {
+		log.Error().Err(err).Msg("Error updating event with bot info")
+	}
+
+	log.Info().
+		Str("clerk_user_id", clerkUserID).
+		Str("event_id", eventID).
+		Msg("Bot scheduled for calendar event")
+
+	return &event, nil
+}
+
+// LinkAgendaNote attaches a prep note to one of the user's upcoming calendar events, replacing the one
+// linked before. The user must be able to edit the note, the meeting's notes are added to it afterwards.
+func (s *CalendarSchedulerService) LinkAgendaNote(ctx context.Context, clerkUserID, eventID, noteID string) (*models.CalendarEvent, error) {
+	event, err := findUserCalendarEvent(ctx, clerkUserID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.EndTime.Before(time.Now()) {
+		return nil, ErrCalendarEventEnded
+	}
+
+	canEdit, err := accessLookups.CanEditNote(ctx, db.DB, noteID, clerkUserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAgendaNoteNotEditable
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit {
+		return nil, ErrAgendaNoteNotEditable
+	}
+
+	if err := db.DB.WithContext(ctx).Model(&models.CalendarEvent{}).Where("id = ?", event.ID).Update("agenda_note_id", noteID).Error; err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("clerk_user_id", clerkUserID).
+		Str("event_id", eventID).
+		Str("note_id", noteID).
+		Msg("Agenda note linked to calendar event")
+
+	return findUserCalendarEvent(ctx, clerkUserID, eventID)
+}
+
+// UnlinkAgendaNote removes the prep note from one of the user's calendar events
+func (s *CalendarSchedulerService) UnlinkAgendaNote(ctx context.Context, clerkUserID, eventID string) (*models.CalendarEvent, error) {
+	event, err := findUserCalendarEvent(ctx, clerkUserID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.DB.WithContext(ctx).Model(&models.CalendarEvent{}).Where("id = ?", event.ID).Update("agenda_note_id", nil).Error; err != nil {
+		return nil, err
+	}
+	event.AgendaNoteID = nil
+	event.AgendaNote = nil
+	return event, nil
+}
+
+// findUserCalendarEvent loads one of the user's calendar events with the name of its agenda note
+func findUserCalendarEvent(ctx context.Context, clerkUserID, eventID string) (*models.CalendarEvent, error) {
+	var event models.CalendarEvent
+	err := db.DB.WithContext(ctx).
+		Joins("JOIN calendars ON calendars.id = calendar_events.calendar_id").
+		Where("calendar_events.id = ? AND calendars.clerk_user_id = ?", eventID, clerkUserID).
+		Preload("AgendaNote", SelectAgendaNote).
+		First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCalendarEventNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// SelectAgendaNote loads only what event listings show of an agenda note, for
+// Preload("AgendaNote", services.SelectAgendaNote)
+func SelectAgendaNote(tx *gorm.DB) *gorm.DB {
+	return tx.Select("id", "name", "chapter_id", "organization_id")
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxChapterTemplateLength caps a chapter's note template
+	maxChapterTemplateLength = 20000
+	// maxChapterDefaultTags caps the number of default tags of a chapter
+	maxChapterDefaultTags = 20
+)
+
+var (
+	// ErrInvalidChapterSettings is returned for settings that can't be stored
+	ErrInvalidChapterSettings = errors.New("invalid chapter settings")
+	// ErrChapterNotFound is returned when the chapter doesn't exist
+	ErrChapterNotFound = errors.New("chapter not found")
+)
+
+// ChapterSettings are the defaults applied to notes created in a chapter
+type ChapterSettings struct {
+	ChapterID    string   `json:"chapterId"`
+	NoteTemplate string   `json:"noteTemplate"`
+	DefaultTags  []string `json:"defaultTags"`
+	AITone       string   `json:"aiTone"`
+	AILength     string   `json:"aiLength"`
+}
+
+// ChapterSettingsInput is the request body for updating a chapter's settings
+type ChapterSettingsInput struct {
+	NoteTemplate string   `json:"noteTemplate"`
+	DefaultTags  []string `json:"defaultTags"`
+	AITone       string   `json:"aiTone"`
+	AILength     string   `json:"aiLength"`
+}
+
+// ChapterSettingsService interface defines methods for managing chapter note defaults
+type ChapterSettingsService interface {
+	GetSettings(chapterID string) (*ChapterSettings, error)
+	UpdateSettings(chapterID string, input ChapterSettingsInput) (*ChapterSettings, error)
+}
+
+// chapterSettingsServiceImpl implements the ChapterSettingsService interface
+type chapterSettingsServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewChapterSettingsService creates a new ChapterSettingsService instance
+func NewChapterSettingsService() ChapterSettingsService {
+	return &chapterSettingsServiceImpl{
+		db: db.DB,
+	}
+}
+
+// GetSettings returns a chapter's settings
+func (s *chapterSettingsServiceImpl) GetSettings(chapterID string) (*ChapterSettings, error) {
+	var chapter models.Chapter
+	if err := s.db.Where("id = ?", chapterID).First(&chapter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChapterNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+	}
+	return toChapterSettings(&chapter), nil
+}
+
+// UpdateSettings validates and replaces a chapter's settings
+func (s *chapterSettingsServiceImpl) UpdateSettings(chapterID string, input ChapterSettingsInput) (*ChapterSettings, error) {
+	template := strings.TrimSpace(input.NoteTemplate)
+	if len(template) > maxChapterTemplateLength {
+		return nil, fmt.Errorf("%w: the note template can be at most %d characters", ErrInvalidChapterSettings, maxChapterTemplateLength)
+	}
+	tone := strings.ToLower(strings.TrimSpace(input.AITone))
+	if tone != "" && !containsString(models.ChapterAITones, tone) {
+		return nil, fmt.Errorf("%w: aiTone must be one of %s", ErrInvalidChapterSettings, strings.Join(models.ChapterAITones, ", "))
+	}
+	length := strings.ToLower(strings.TrimSpace(input.AILength))
+	if length != "" && !containsString(models.ChapterAILengths, length) {
+		return nil, fmt.Errorf("%w: aiLength must be one of %s", ErrInvalidChapterSettings, strings.Join(models.ChapterAILengths, ", "))
+	}
+	tags, err := normalizeChapterTags(input.DefaultTags)
+	if err != nil {
+		return nil, err
+	}
+
+	var chapter models.Chapter
+	if err := s.db.Where("id = ?", chapterID).First(&chapter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChapterNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+	}
+
+	updates := map[string]interface{}{
+		"note_template": template,
+		"default_tags":  strings.Join(tags, ","),
+		"ai_tone":       tone,
+		"ai_length":     length,
+	}
+	if err := s.db.Model(&chapter).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update chapter settings: %w", err)
+	}
+	chapter.NoteTemplate = template
+	chapter.DefaultTags = strings.Join(tags, ",")
+	chapter.AITone = tone
+	chapter.AILength = length
+	return toChapterSettings(&chapter), nil
+}
+
+// ApplyChapterNoteDefaults returns the TipTap content of a new note in the chapter: empty content starts
+// from the chapter's template, and the chapter's default tags the content doesn't have yet are added as
+// a closing paragraph. Content that isn't a TipTap document is returned unchanged.
+func ApplyChapterNoteDefaults(chapter *models.Chapter, content string) string {
+	if chapter == nil {
+		return content
+	}
+
+	doc := utils.TipTapDoc{Type: "doc"}
+	if strings.TrimSpace(content) != "" {
+		if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" {
+			return content
+		}
+	}
+	if len(doc.Content) == 0 && chapter.NoteTemplate != "" {
+		if template, err := utils.MarkdownToTipTap(chapter.NoteTemplate); err == nil {
+			_ = json.Unmarshal([]byte(template), &doc)
+		}
+	}
+
+	tags := chapterDefaultTags(chapter)
+	if len(tags) > 0 {
+		markdown, _ := utils.TipTapToMarkdown(encodeTipTapDoc(doc))
+		present := make(map[string]bool)
+		for _, tag := range extractNoteTags(markdown) {
+			present[tag] = true
+		}
+		var nodes []utils.TipTapNode
+		for _, tag := range tags {
+			if present[tag] {
+				continue
+			}
+			if len(nodes) > 0 {
+				nodes = append(nodes, utils.TipTapNode{Type: "text", Text: " "})
+			}
+			nodes = append(nodes, utils.TipTapNode{Type: "text", Text: "#" + tag})
+		}
+		if len(nodes) > 0 {
+			doc.Content = append(doc.Content, utils.TipTapNode{Type: "paragraph", Content: nodes})
+		}
+	}
+
+	if len(doc.Content) == 0 {
+		return content
+	}
+	return encodeTipTapDoc(doc)
+}
+
+// ApplyChapterGenerationSettings fills in the chapter's tone, length and template on an AI note
+// generation request
+func ApplyChapterGenerationSettings(chapter *models.Chapter, request *NoteContentGenerationRequest) {
+	if chapter == nil {
+		return
+	}
+	if request.Tone == "" {
+		request.Tone = chapter.AITone
+	}
+	if request.Length == "" {
+		request.Length = chapter.AILength
+	}
+	if request.Template == "" {
+		request.Template = chapter.NoteTemplate
+	}
+}
+
+// toChapterSettings returns the settings stored on a chapter
+func toChapterSettings(chapter *models.Chapter) *ChapterSettings {
+	return &ChapterSettings{
+		ChapterID:    chapter.ID,
+		NoteTemplate: chapter.NoteTemplate,
+		DefaultTags:  chapterDefaultTags(chapter),
+		AITone:       chapter.AITone,
+		AILength:     chapter.AILength,
+	}
+}
+
+// chapterDefaultTags splits a chapter's stored default tags
+func chapterDefaultTags(chapter *models.Chapter) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(chapter.DefaultTags, ",") {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// normalizeChapterTags normalizes default tags and checks they can be written as #tags
+func normalizeChapterTags(values []string) ([]string, error) {
+	tags := []string{}
+	for _, value := range values {
+		tag := normalizeNoteTag(value)
+		if tag == "" || containsString(tags, tag) {
+			continue
+		}
+		if found := extractNoteTags("#" + tag); len(found) != 1 || found[0] != tag {
+			return nil, fmt.Errorf("%w: %q can't be used as a tag", ErrInvalidChapterSettings, value)
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxChapterDefaultTags {
+		return nil, fmt.Errorf("%w: a chapter can have at most %d default tags", ErrInvalidChapterSettings, maxChapterDefaultTags)
+	}
+	return tags, nil
+}
+
+// encodeTipTapDoc encodes a TipTap document that was decoded from JSON, so it can't fail
+func encodeTipTapDoc(doc utils.TipTapDoc) string {
+	encoded, _ := json.Marshal(doc)
+	return string(encoded)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestChapterSettingsService creates a chapter settings service with a chapter on an in-memory database
+func setupTestChapterSettingsService(t *testing.T) (*chapterSettingsServiceImpl, models.Chapter) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	notebook := models.Notebook{Name: "Reading", ClerkUserID: "user_owner"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Books", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+
+	return &chapterSettingsServiceImpl{db: db}, chapter
+}
+
+// docTexts returns the text of each top-level block of TipTap content
+func docTexts(t *testing.T, content string) []string {
+	var doc utils.TipTapDoc
+	require.NoError(t, json.Unmarshal([]byte(content), &doc))
+	texts := make([]string, len(doc.Content))
+	for i, node := range doc.Content {
+		texts[i] = blockText(node)
+	}
+	return texts
+}
+
+func TestChapterSettingsService_UpdateSettings(t *testing.T) {
+	service, chapter := setupTestChapterSettingsService(t)
+
+	settings, err := service.UpdateSettings(chapter.ID, ChapterSettingsInput{
+		NoteTemplate: "## Summary\n\n## Quotes",
+		DefaultTags:  []string{"#Reading", "books", "reading", " "},
+		AITone:       "Casual",
+		AILength:     "short",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reading", "books"}, settings.DefaultTags)
+	assert.Equal(t, "casual", settings.AITone)
+
+	stored, err := service.GetSettings(chapter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, settings, stored)
+
+	// Clearing the settings
+	settings, err = service.UpdateSettings(chapter.ID, ChapterSettingsInput{})
+	require.NoError(t, err)
+	assert.Empty(t, settings.NoteTemplate)
+	assert.Empty(t, settings.DefaultTags)
+}
+
+func TestChapterSettingsService_UpdateSettings_Invalid(t *testing.T) {
+	service, chapter := setupTestChapterSettingsService(t)
+
+	_, err := service.UpdateSettings(chapter.ID, ChapterSettingsInput{AITone: "sarcastic"})
+	assert.ErrorIs(t, err, ErrInvalidChapterSettings)
+	_, err = service.UpdateSettings(chapter.ID, ChapterSettingsInput{AILength: "epic"})
+	assert.ErrorIs(t, err, ErrInvalidChapterSettings)
+	_, err = service.UpdateSettings(chapter.ID, ChapterSettingsInput{DefaultTags: []string{"two words"}})
+	assert.ErrorIs(t, err, ErrInvalidChapterSettings)
+	_, err = service.UpdateSettings("missing", ChapterSettingsInput{})
+	assert.ErrorIs(t, err, ErrChapterNotFound)
+}
+
+func TestApplyChapterNoteDefaults(t *testing.T) {
+	chapter := &models.Chapter{NoteTemplate: "## Summary\n\nWhat it's about", DefaultTags: "reading,books"}
+
+	// Empty notes start from the template and get the tags
+	content := ApplyChapterNoteDefaults(chapter, "")
+	assert.Equal(t, []string{"Summary", "What it's about", "#reading #books"}, docTexts(t, content))
+
+	// Notes with content keep it, and tags they already have aren't repeated
+	content = ApplyChapterNoteDefaults(chapter, `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Loved it #books"}]}]}`)
+	assert.Equal(t, []string{"Loved it #books", "#reading"}, docTexts(t, content))
+
+	// Chapters without settings and non-TipTap content are left alone
+	assert.Equal(t, "", ApplyChapterNoteDefaults(&models.Chapter{}, ""))
+	assert.Equal(t, "plain text", ApplyChapterNoteDefaults(chapter, "plain text"))
+}
+
+func TestApplyChapterGenerationSettings(t *testing.T) {
+	chapter := &models.Chapter{AITone: models.ChapterAIToneFormal, AILength: models.ChapterAILengthLong, NoteTemplate: "## Summary"}
+
+	request := NoteContentGenerationRequest{NoteTitle: "Dune", Length: models.ChapterAILengthShort}
+	ApplyChapterGenerationSettings(chapter, &request)
+	assert.Equal(t, models.ChapterAIToneFormal, request.Tone)
+	assert.Equal(t, models.ChapterAILengthShort, request.Length, "explicit settings win over the chapter's")
+	assert.Equal(t, "## Summary", request.Template)
+
+	prompt := formatGenerationStylePrompt(request)
+	assert.Contains(t, prompt, "formal")
+	assert.Contains(t, prompt, "## Summary")
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidChatTranscript is returned, wrapped with the reason, for a conversation that can't be saved
+var ErrInvalidChatTranscript = errors.New("invalid chat transcript")
+
+const (
+	maxChatTranscriptMessages = 500
+	maxChatNoteTitleLength    = 255
+	// chatNoteTitleRunes is how much of the first question untitled conversations are named after
+	chatNoteTitleRunes = 60
+	// chatToolArgRunes caps each argument shown in a tool call summary
+	chatToolArgRunes = 80
+)
+
+// ChatTranscriptMessage is a message of an assistant conversation, with the tools the assistant called
+// while writing it
+type ChatTranscriptMessage struct {
+	Role      string
+	Content   string
+	ToolCalls []ChatTranscriptToolCall
+}
+
+// ChatTranscriptToolCall is a tool call made by the assistant. Error is set when the call failed, and
+// Done once it returned.
+type ChatTranscriptToolCall struct {
+	Tool  string
+	Args  map[string]any
+	Done  bool
+	Error string
+}
+
+// ChatNoteService interface defines methods for saving assistant conversations as notes
+type ChatNoteService interface {
+	SaveConversation(ctx context.Context, chapterID, title string, messages []ChatTranscriptMessage) (*models.Notes, error)
+}
+
+// chatNoteServiceImpl implements the ChatNoteService interface
+type chatNoteServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewChatNoteService creates a new ChatNoteService instance
+func NewChatNoteService() ChatNoteService {
+	return &chatNoteServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// SaveConversation creates a note in a chapter from a conversation, with a heading per message and a
+// summary of the tools the assistant used. Untitled conversations are named after their first question.
+func (s *chatNoteServiceImpl) SaveConversation(ctx context.Context, chapterID, title string, messages []ChatTranscriptMessage) (*models.Notes, error) {
+	title = strings.TrimSpace(title)
+	if len(title) > maxChatNoteTitleLength {
+		return nil, fmt.Errorf("%w: title must be at most %d characters", ErrInvalidChatTranscript, maxChatNoteTitleLength)
+	}
+	if len(messages) > maxChatTranscriptMessages {
+		return nil, fmt.Errorf("%w: at most %d messages can be saved", ErrInvalidChatTranscript, maxChatTranscriptMessages)
+	}
+	markdown := chatTranscriptMarkdown(messages)
+	if markdown == "" {
+		return nil, fmt.Errorf("%w: the conversation has no messages to save", ErrInvalidChatTranscript)
+	}
+	if title == "" {
+		title = s.chatNoteTitle(messages)
+	}
+
+	var chapter models.Chapter
+	if err := s.db.WithContext(ctx).Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChapterNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+	}
+	if chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	content, err := utils.MarkdownToTipTap(markdown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert conversation: %w", err)
+	}
+	note := models.Notes{
+		Name:           title,
+		Content:        ApplyChapterNoteDefaults(&chapter, content),
+		ChapterID:      chapter.ID,
+		OrganizationID: chapter.OrganizationID,
+	}
+	if err := s.db.WithContext(ctx).Create(&note).Error; err != nil {
+		return nil, fmt.Errorf("failed to create note: %w", err)
+	}
+
+	log.Info().Str("note_id", note.ID).Str("chapter_id", chapter.ID).Int("messages", len(messages)).Msg("Chat conversation saved as note")
+	return &note, nil
+}
+
+// chatNoteTitle names a conversation after its first question, or its date when it has none
+func (s *chatNoteServiceImpl) chatNoteTitle(messages []ChatTranscriptMessage) string {
+	for _, message := range messages {
+		if message.Role != "user" {
+			continue
+		}
+		if line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(message.Content), "\n", 2)[0]); line != "" {
+			return truncateLinkText(line, chatNoteTitleRunes)
+		}
+	}
+	return "Chat on " + s.now().Format("January 2, 2006")
+}
+
+// chatTranscriptMarkdown writes the user's and assistant's messages as Markdown. System messages and
+// tool results aren't included; tool calls are listed by name and arguments.
+func chatTranscriptMarkdown(messages []ChatTranscriptMessage) string {
+	var sections []string
+	for _, message := range messages {
+		var speaker string
+		switch message.Role {
+		case "user":
+			speaker = "You"
+		case "assistant":
+			speaker = "Assistant"
+		default:
+			continue
+		}
+		content := strings.TrimSpace(message.Content)
+		if content == "" && len(message.ToolCalls) == 0 {
+			continue
+		}
+
+		section := "### " + speaker + "\n\n"
+		if len(message.ToolCalls) > 0 {
+			section += "Tools used:\n\n"
+			for _, call := range message.ToolCalls {
+				section += "- " + chatToolCallSummary(call) + "\n"
+			}
+			section += "\n"
+		}
+		sections = append(sections, section+content)
+	}
+	return strings.TrimSpace(strings.Join(sections, "\n\n"))
+}
+
+// chatToolCallSummary describes a tool call on one line, such as `searchNotes: query "roadmap"`, with how
+// it ended when it didn't succeed
+func chatToolCallSummary(call ChatTranscriptToolCall) string {
+	keys := make([]string, 0, len(call.Args))
+	for key := range call.Args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, key := range keys {
+		var value string
+		switch v := call.Args[key].(type) {
+		case string:
+			value = fmt.Sprintf("%q", truncateLinkText(strings.Join(strings.Fields(v), " "), chatToolArgRunes))
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			value = truncateLinkText(string(encoded), chatToolArgRunes)
+		}
+		args = append(args, key+" "+value)
+	}
+
+	summary := call.Tool
+	if len(args) > 0 {
+		summary += ": " + strings.Join(args, ", ")
+	}
+	switch {
+	case call.Error != "":
+		summary += " (failed: " + truncateLinkText(call.Error, chatToolArgRunes) + ")"
+	case !call.Done:
+		summary += " (didn't finish)"
+	}
+	return summary
+}
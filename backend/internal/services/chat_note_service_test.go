@@ -0,0 +1,91 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestChatNoteService(t *testing.T) (*chatNoteServiceImpl, models.Chapter) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}), "Failed to migrate test database")
+
+	orgID := "org_1"
+	notebook := models.Notebook{Name: "Research", ClerkUserID: "user_1", OrganizationID: &orgID}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Chats", NotebookID: notebook.ID, OrganizationID: &orgID}
+	require.NoError(t, db.Create(&chapter).Error)
+
+	return &chatNoteServiceImpl{
+		db:  db,
+		now: func() time.Time { return time.Date(2026, 3, 9, 10, 0, 0, 0, time.UTC) },
+	}, chapter
+}
+
+func TestChatNoteService_SaveConversation(t *testing.T) {
+	service, chapter := setupTestChatNoteService(t)
+	ctx := context.Background()
+
+	messages := []ChatTranscriptMessage{
+		{Role: "system", Content: "You are Atlas"},
+		{Role: "user", Content: "What did we decide about the roadmap?\nKeep it short."},
+		{Role: "assistant", Content: "We agreed to ship **search** first.", ToolCalls: []ChatTranscriptToolCall{
+			{Tool: "searchNotes", Args: map[string]any{"query": "roadmap", "where": []any{"status = done"}}, Done: true},
+			{Tool: "getNoteContent", Args: map[string]any{"noteId": "note_1"}, Done: true, Error: "Note not found"},
+			{Tool: "listNotebooks", Args: map[string]any{}},
+		}},
+		{Role: "user", Content: "   "},
+	}
+	note, err := service.SaveConversation(ctx, chapter.ID, "", messages)
+	require.NoError(t, err)
+	assert.Equal(t, "What did we decide about the roadmap?", note.Name, "Untitled conversations are named after the first question")
+	assert.Equal(t, chapter.ID, note.ChapterID)
+	require.NotNil(t, note.OrganizationID)
+	assert.Equal(t, "org_1", *note.OrganizationID)
+
+	markdown, err := utils.TipTapToMarkdown(note.Content)
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "### You")
+	assert.Contains(t, markdown, "### Assistant")
+	assert.Contains(t, markdown, `- searchNotes: query "roadmap", where ["status = done"]`)
+	assert.Contains(t, markdown, `- getNoteContent: noteId "note_1" (failed: Note not found)`)
+	assert.Contains(t, markdown, "- listNotebooks (didn't finish)")
+	assert.Contains(t, markdown, "We agreed to ship search first.")
+	assert.NotContains(t, markdown, "You are Atlas", "System prompts aren't saved")
+
+	note, err = service.SaveConversation(ctx, chapter.ID, "", []ChatTranscriptMessage{{Role: "assistant", Content: "Hello! How can I help?"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Chat on March 9, 2026", note.Name)
+
+	note, err = service.SaveConversation(ctx, chapter.ID, " Roadmap chat ", messages)
+	require.NoError(t, err)
+	assert.Equal(t, "Roadmap chat", note.Name)
+}
+
+func TestChatNoteService_SaveConversation_Errors(t *testing.T) {
+	service, chapter := setupTestChatNoteService(t)
+	ctx := context.Background()
+	messages := []ChatTranscriptMessage{{Role: "user", Content: "Hi"}}
+
+	_, err := service.SaveConversation(ctx, chapter.ID, "", []ChatTranscriptMessage{{Role: "system", Content: "prompt"}})
+	assert.ErrorIs(t, err, ErrInvalidChatTranscript)
+	_, err = service.SaveConversation(ctx, chapter.ID, "", make([]ChatTranscriptMessage, maxChatTranscriptMessages+1))
+	assert.ErrorIs(t, err, ErrInvalidChatTranscript)
+	_, err = service.SaveConversation(ctx, "missing", "", messages)
+	assert.ErrorIs(t, err, ErrChapterNotFound)
+
+	encrypted := models.Notebook{Name: "Private", ClerkUserID: "user_1", Encrypted: true}
+	require.NoError(t, service.db.Create(&encrypted).Error)
+	private := models.Chapter{Name: "Private", NotebookID: encrypted.ID}
+	require.NoError(t, service.db.Create(&private).Error)
+	_, err = service.SaveConversation(ctx, private.ID, "", messages)
+	assert.ErrorIs(t, err, ErrNotebookEncrypted, "The server can't write plain notes into encrypted notebooks")
+}
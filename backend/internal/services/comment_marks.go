@@ -0,0 +1,62 @@
+package services
+
+import (
+	"backend/internal/utils"
+	"encoding/json"
+	"reflect"
+)
+
+// CommentMarkType is the TipTap mark that anchors a comment to the text it's about
+const CommentMarkType = "comment"
+
+// OnlyCommentsChanged reports whether TipTap content differs from the previous version only by the
+// comment marks added or removed, which is all that members who may only comment can change. Content
+// that isn't a TipTap document counts as changed.
+func OnlyCommentsChanged(previous, content string) bool {
+	var previousDoc, doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(previous), &previousDoc); err != nil || previousDoc.Type != "doc" {
+		return false
+	}
+	if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" {
+		return false
+	}
+	return reflect.DeepEqual(withoutCommentMarks(previousDoc.Content), withoutCommentMarks(doc.Content))
+}
+
+// withoutCommentMarks copies TipTap nodes without their comment marks or block IDs, joining the text
+// that a comment split up
+func withoutCommentMarks(nodes []utils.TipTapNode) []utils.TipTapNode {
+	stripped := make([]utils.TipTapNode, 0, len(nodes))
+	for _, node := range nodes {
+		var marks []utils.TipTapMark
+		for _, mark := range node.Marks {
+			if mark.Type != CommentMarkType {
+				marks = append(marks, mark)
+			}
+		}
+		node.Marks = marks
+
+		// A block's ID doesn't change its content, and older notes get theirs when saved
+		if _, ok := node.Attrs["id"]; ok && blockIDNodeTypes[node.Type] {
+			attrs := make(map[string]interface{}, len(node.Attrs))
+			for key, value := range node.Attrs {
+				if key != "id" {
+					attrs[key] = value
+				}
+			}
+			node.Attrs = attrs
+		}
+		if len(node.Attrs) == 0 {
+			node.Attrs = nil
+		}
+		node.Content = withoutCommentMarks(node.Content)
+
+		if last := len(stripped) - 1; last >= 0 && node.Type == "text" && stripped[last].Type == "text" &&
+			reflect.DeepEqual(stripped[last].Marks, node.Marks) && stripped[last].Attrs == nil && node.Attrs == nil {
+			stripped[last].Text += node.Text
+			continue
+		}
+		stripped = append(stripped, node)
+	}
+	return stripped
+}
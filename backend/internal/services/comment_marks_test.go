@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnlyCommentsChanged(t *testing.T) {
+	previous := `{"type":"doc","content":[{"type":"paragraph","attrs":{"id":"p1"},"content":[{"type":"text","text":"The results look good."}]}]}`
+
+	commented := `{"type":"doc","content":[{"type":"paragraph","attrs":{"id":"p1"},"content":[` +
+		`{"type":"text","text":"The "},` +
+		`{"type":"text","marks":[{"type":"comment","attrs":{"commentId":"c1"}}],"text":"results"},` +
+		`{"type":"text","text":" look good."}]}]}`
+	assert.True(t, OnlyCommentsChanged(previous, commented))
+	assert.True(t, OnlyCommentsChanged(commented, previous), "resolving a comment removes its mark")
+
+	edited := `{"type":"doc","content":[{"type":"paragraph","attrs":{"id":"p1"},"content":[` +
+		`{"type":"text","marks":[{"type":"comment","attrs":{"commentId":"c1"}}],"text":"results"},` +
+		`{"type":"text","text":" look good."}]}]}`
+	assert.False(t, OnlyCommentsChanged(previous, edited), "text was removed")
+
+	bold := `{"type":"doc","content":[{"type":"paragraph","attrs":{"id":"p1"},"content":[` +
+		`{"type":"text","text":"The "},` +
+		`{"type":"text","marks":[{"type":"bold"}],"text":"results"},` +
+		`{"type":"text","text":" look good."}]}]}`
+	assert.False(t, OnlyCommentsChanged(previous, bold), "other marks are edits")
+
+	// Block IDs given on save aren't edits
+	withoutIDs := `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"The results look good."}]}]}`
+	assert.True(t, OnlyCommentsChanged(withoutIDs, previous))
+
+	assert.False(t, OnlyCommentsChanged(previous, "not json"))
+}
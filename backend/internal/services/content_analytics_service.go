@@ -0,0 +1,276 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// noteAccessDedupWindow is how long repeated access by the same user to the same note counts once
+	noteAccessDedupWindow = 15 * time.Minute
+
+	defaultContentAnalyticsDays      = 30
+	defaultContentAnalyticsStaleDays = 90
+	defaultContentAnalyticsLimit     = 10
+	maxContentAnalyticsDays          = 365
+	maxContentAnalyticsLimit         = 100
+)
+
+var (
+	// recentNoteAccess remembers when each user last had each note access recorded
+	recentNoteAccess   = make(map[string]time.Time)
+	recentNoteAccessMu sync.Mutex
+)
+
+// ContentAnalyticsOptions controls the windows and sizes of a content analytics report
+type ContentAnalyticsOptions struct {
+	Days      int // Window for views and contributions
+	StaleDays int // Notebooks without edits for this long are stale
+	Limit     int // Entries per section
+}
+
+// ViewedNote is a note with its view counts in the report window
+type ViewedNote struct {
+	NoteID        string `json:"noteId"`
+	Name          string `json:"name"`
+	NotebookID    string `json:"notebookId"`
+	NotebookName  string `json:"notebookName"`
+	Views         int64  `json:"views"`
+	UniqueViewers int64  `json:"uniqueViewers"`
+}
+
+// StaleNotebook is a notebook without recent edits
+type StaleNotebook struct {
+	NotebookID   string    `json:"notebookId"`
+	Name         string    `json:"name"`
+	NoteCount    int64     `json:"noteCount"`
+	LastEditedAt time.Time `json:"lastEditedAt"`
+}
+
+// ContentContributor is a user with their edits in the report window
+type ContentContributor struct {
+	UserID      string `json:"userId"`
+	Edits       int64  `json:"edits"`
+	NotesEdited int64  `json:"notesEdited"`
+}
+
+// OrphanedNote is a note that no other note links to or from and that nobody has viewed
+type OrphanedNote struct {
+	NoteID       string    `json:"noteId"`
+	Name         string    `json:"name"`
+	NotebookID   string    `json:"notebookId"`
+	NotebookName string    `json:"notebookName"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// ContentAnalyticsReport helps knowledge managers find what's used, what's neglected and who maintains it
+type ContentAnalyticsReport struct {
+	Days            int                  `json:"days"`
+	StaleDays       int                  `json:"staleDays"`
+	GeneratedAt     time.Time            `json:"generatedAt"`
+	MostViewed      []ViewedNote         `json:"mostViewed"`
+	StaleNotebooks  []StaleNotebook      `json:"staleNotebooks"`
+	TopContributors []ContentContributor `json:"topContributors"`
+	OrphanedNotes   []OrphanedNote       `json:"orphanedNotes"`
+	OrphanedCount   int64                `json:"orphanedCount"`
+}
+
+// ContentAnalyticsService interface defines methods for tracking note access and reporting on content use
+type ContentAnalyticsService interface {
+	RecordNoteAccess(noteID, clerkUserID, action string)
+	GetOrganizationReport(organizationID string, options ContentAnalyticsOptions) (*ContentAnalyticsReport, error)
+}
+
+// contentAnalyticsServiceImpl implements the ContentAnalyticsService interface
+type contentAnalyticsServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewContentAnalyticsService creates a new ContentAnalyticsService instance
+func NewContentAnalyticsService() ContentAnalyticsService {
+	return &contentAnalyticsServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// RecordNoteAccess records that a user viewed or edited a note. Failures are logged, never returned,
+// so tracking can't break the request it's attached to.
+func (s *contentAnalyticsServiceImpl) RecordNoteAccess(noteID, clerkUserID, action string) {
+	if noteID == "" || clerkUserID == "" {
+		return
+	}
+
+	now := s.now()
+	key := action + ":" + noteID + ":" + clerkUserID
+	recentNoteAccessMu.Lock()
+	if last, exists := recentNoteAccess[key]; exists && now.Sub(last) < noteAccessDedupWindow {
+		recentNoteAccessMu.Unlock()
+		return
+	}
+	recentNoteAccess[key] = now
+	// Forget old entries once in a while so the map doesn't grow forever
+	if len(recentNoteAccess) > 10000 {
+		for k, last := range recentNoteAccess {
+			if now.Sub(last) >= noteAccessDedupWindow {
+				delete(recentNoteAccess, k)
+			}
+		}
+	}
+	recentNoteAccessMu.Unlock()
+
+	var note models.Notes
+	if err := s.db.Select("id", "organization_id").Where("id = ?", noteID).Limit(1).Find(&note).Error; err != nil || note.ID == "" {
+		return
+	}
+
+	event := models.NoteAccessEvent{
+		NoteID:         noteID,
+		ClerkUserID:    clerkUserID,
+		OrganizationID: note.OrganizationID,
+		Action:         action,
+		CreatedAt:      now,
+	}
+	if err := s.db.Create(&event).Error; err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Str("action", action).Msg("Failed to record note access")
+	}
+}
+
+// GetOrganizationReport builds the content analytics report of an organization
+func (s *contentAnalyticsServiceImpl) GetOrganizationReport(organizationID string, options ContentAnalyticsOptions) (*ContentAnalyticsReport, error) {
+	options = normalizeContentAnalyticsOptions(options)
+	now := s.now()
+	since := now.AddDate(0, 0, -options.Days)
+	staleBefore := now.AddDate(0, 0, -options.StaleDays)
+
+	report := &ContentAnalyticsReport{
+		Days:            options.Days,
+		StaleDays:       options.StaleDays,
+		GeneratedAt:     now,
+		MostViewed:      []ViewedNote{},
+		StaleNotebooks:  []StaleNotebook{},
+		TopContributors: []ContentContributor{},
+		OrphanedNotes:   []OrphanedNote{},
+	}
+
+	if err := s.db.Table("note_access_events").
+		Select("note_access_events.note_id, notes.name, chapters.notebook_id, notebooks.name AS notebook_name, "+
+			"COUNT(*) AS views, COUNT(DISTINCT note_access_events.clerk_user_id) AS unique_viewers").
+		Joins("JOIN notes ON notes.id = note_access_events.note_id").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notes.organization_id = ? AND note_access_events.action = ? AND note_access_events.created_at >= ?", organizationID, models.NoteAccessView, since).
+		Group("note_access_events.note_id, notes.name, chapters.notebook_id, notebooks.name").
+		Order("views DESC, unique_viewers DESC").
+		Limit(options.Limit).
+		Scan(&report.MostViewed).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch most viewed notes: %w", err)
+	}
+
+	if err := s.db.Table("note_access_events").
+		Select("clerk_user_id AS user_id, COUNT(*) AS edits, COUNT(DISTINCT note_id) AS notes_edited").
+		Where("organization_id = ? AND action = ? AND created_at >= ?", organizationID, models.NoteAccessEdit, since).
+		Group("clerk_user_id").
+		Order("edits DESC, notes_edited DESC").
+		Limit(options.Limit).
+		Scan(&report.TopContributors).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch top contributors: %w", err)
+	}
+
+	staleNotebooks, err := s.staleNotebooks(organizationID, staleBefore, options.Limit)
+	if err != nil {
+		return nil, err
+	}
+	report.StaleNotebooks = staleNotebooks
+
+	orphaned := s.db.Table("notes").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notes.organization_id = ?", organizationID).
+		Where("NOT EXISTS (SELECT 1 FROM note_links WHERE note_links.source_note_id = notes.id OR note_links.target_note_id = notes.id)").
+		Where("NOT EXISTS (SELECT 1 FROM note_access_events WHERE note_access_events.note_id = notes.id AND note_access_events.action = ?)", models.NoteAccessView)
+	if err := orphaned.Session(&gorm.Session{}).Count(&report.OrphanedCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count orphaned notes: %w", err)
+	}
+	if err := orphaned.Session(&gorm.Session{}).
+		Select("notes.id AS note_id, notes.name, chapters.notebook_id, notebooks.name AS notebook_name, notes.updated_at").
+		Order("notes.updated_at ASC").
+		Limit(options.Limit).
+		Scan(&report.OrphanedNotes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch orphaned notes: %w", err)
+	}
+
+	return report, nil
+}
+
+// staleNotebooks returns the organization's notebooks with no note edited since the cutoff, least recently edited first
+func (s *contentAnalyticsServiceImpl) staleNotebooks(organizationID string, staleBefore time.Time, limit int) ([]StaleNotebook, error) {
+	var notebooks []models.Notebook
+	if err := s.db.Where("organization_id = ? AND updated_at < ?", organizationID, staleBefore).
+		Where("NOT EXISTS (SELECT 1 FROM chapters JOIN notes ON notes.chapter_id = chapters.id "+
+			"WHERE chapters.notebook_id = notebooks.id AND notes.updated_at >= ?)", staleBefore).
+		Find(&notebooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch stale notebooks: %w", err)
+	}
+
+	stale := make([]StaleNotebook, 0, len(notebooks))
+	for _, notebook := range notebooks {
+		entry := StaleNotebook{NotebookID: notebook.ID, Name: notebook.Name, LastEditedAt: notebook.UpdatedAt}
+
+		var lastEdited models.Notes
+		query := s.db.Model(&models.Notes{}).
+			Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+			Where("chapters.notebook_id = ?", notebook.ID)
+		if err := query.Session(&gorm.Session{}).Count(&entry.NoteCount).Error; err != nil {
+			return nil, fmt.Errorf("failed to count notebook notes: %w", err)
+		}
+		if err := query.Session(&gorm.Session{}).Select("notes.updated_at").Order("notes.updated_at DESC").Limit(1).Find(&lastEdited).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch notebook notes: %w", err)
+		}
+		if lastEdited.UpdatedAt.After(entry.LastEditedAt) {
+			entry.LastEditedAt = lastEdited.UpdatedAt
+		}
+		stale = append(stale, entry)
+	}
+
+	// Least recently edited first
+	for i := 1; i < len(stale); i++ {
+		for j := i; j > 0 && stale[j].LastEditedAt.Before(stale[j-1].LastEditedAt); j-- {
+			stale[j], stale[j-1] = stale[j-1], stale[j]
+		}
+	}
+	if len(stale) > limit {
+		stale = stale[:limit]
+	}
+	return stale, nil
+}
+
+// normalizeContentAnalyticsOptions applies defaults and bounds
+func normalizeContentAnalyticsOptions(options ContentAnalyticsOptions) ContentAnalyticsOptions {
+	if options.Days <= 0 {
+		options.Days = defaultContentAnalyticsDays
+	}
+	if options.Days > maxContentAnalyticsDays {
+		options.Days = maxContentAnalyticsDays
+	}
+	if options.StaleDays <= 0 {
+		options.StaleDays = defaultContentAnalyticsStaleDays
+	}
+	if options.StaleDays > maxContentAnalyticsDays {
+		options.StaleDays = maxContentAnalyticsDays
+	}
+	if options.Limit <= 0 {
+		options.Limit = defaultContentAnalyticsLimit
+	}
+	if options.Limit > maxContentAnalyticsLimit {
+		options.Limit = maxContentAnalyticsLimit
+	}
+	return options
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestContentAnalyticsService creates a content analytics service with a fixed clock
+func setupTestContentAnalyticsService(t *testing.T, now time.Time) *contentAnalyticsServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteLink{}, &models.NoteAccessEvent{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &contentAnalyticsServiceImpl{
+		db:  db,
+		now: func() time.Time { return now },
+	}
+}
+
+// createAnalyticsNotebook creates an organization notebook with one chapter and notes last edited at the given times
+func createAnalyticsNotebook(t *testing.T, db *gorm.DB, orgID, name string, editedAt ...time.Time) []models.Notes {
+	notebook := models.Notebook{Name: name, ClerkUserID: "user_owner", OrganizationID: &orgID, CreatedAt: editedAt[0], UpdatedAt: editedAt[0]}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Chapter", NotebookID: notebook.ID, OrganizationID: &orgID, CreatedAt: editedAt[0], UpdatedAt: editedAt[0]}
+	require.NoError(t, db.Create(&chapter).Error)
+
+	notes := make([]models.Notes, len(editedAt))
+	for i, at := range editedAt {
+		notes[i] = models.Notes{Name: name + " note", ChapterID: chapter.ID, OrganizationID: &orgID, CreatedAt: at, UpdatedAt: at}
+		require.NoError(t, db.Create(&notes[i]).Error)
+	}
+	return notes
+}
+
+func TestRecordNoteAccess_DeduplicatesWithinWindow(t *testing.T) {
+	now := time.Now()
+	service := setupTestContentAnalyticsService(t, now)
+	notes := createAnalyticsNotebook(t, service.db, "org_dedup", "Handbook", now)
+
+	service.RecordNoteAccess(notes[0].ID, "user_a", models.NoteAccessView)
+	service.RecordNoteAccess(notes[0].ID, "user_a", models.NoteAccessView)
+	service.RecordNoteAccess(notes[0].ID, "user_a", models.NoteAccessEdit)
+	service.RecordNoteAccess(notes[0].ID, "user_b", models.NoteAccessView)
+
+	service.now = func() time.Time { return now.Add(noteAccessDedupWindow) }
+	service.RecordNoteAccess(notes[0].ID, "user_a", models.NoteAccessView)
+
+	var events []models.NoteAccessEvent
+	require.NoError(t, service.db.Order("created_at").Find(&events).Error)
+	require.Len(t, events, 4)
+	for _, event := range events {
+		require.NotNil(t, event.OrganizationID)
+		assert.Equal(t, "org_dedup", *event.OrganizationID, "Events should carry the note's organization")
+	}
+}
+
+func TestGetOrganizationReport(t *testing.T) {
+	now := time.Now()
+	service := setupTestContentAnalyticsService(t, now)
+
+	active := createAnalyticsNotebook(t, service.db, "org_report", "Active", now.AddDate(0, 0, -1), now.AddDate(0, 0, -200), now.AddDate(0, 0, -5))
+	stale := createAnalyticsNotebook(t, service.db, "org_report", "Archive", now.AddDate(0, 0, -120), now.AddDate(0, 0, -100))
+	createAnalyticsNotebook(t, service.db, "org_other", "Other", now.AddDate(0, 0, -300))
+
+	// active[0] is viewed most, active[1] is linked, stale notes are never viewed nor linked
+	service.RecordNoteAccess(active[0].ID, "user_a", models.NoteAccessView)
+	service.RecordNoteAccess(active[0].ID, "user_b", models.NoteAccessView)
+	service.RecordNoteAccess(active[2].ID, "user_a", models.NoteAccessView)
+	service.RecordNoteAccess(active[0].ID, "user_a", models.NoteAccessEdit)
+	service.RecordNoteAccess(active[2].ID, "user_a", models.NoteAccessEdit)
+	service.RecordNoteAccess(active[2].ID, "user_b", models.NoteAccessEdit)
+	require.NoError(t, service.db.Create(&models.NoteLink{SourceNoteID: active[1].ID, TargetNoteID: active[0].ID}).Error)
+
+	// Views before the window don't count
+	require.NoError(t, service.db.Create(&models.NoteAccessEvent{
+		NoteID: active[2].ID, ClerkUserID: "user_c", OrganizationID: active[2].OrganizationID,
+		Action: models.NoteAccessView, CreatedAt: now.AddDate(0, 0, -60),
+	}).Error)
+
+	report, err := service.GetOrganizationReport("org_report", ContentAnalyticsOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 30, report.Days)
+	assert.Equal(t, 90, report.StaleDays)
+
+	require.Len(t, report.MostViewed, 2)
+	assert.Equal(t, active[0].ID, report.MostViewed[0].NoteID)
+	assert.Equal(t, int64(2), report.MostViewed[0].Views)
+	assert.Equal(t, int64(2), report.MostViewed[0].UniqueViewers)
+	assert.Equal(t, "Active", report.MostViewed[0].NotebookName)
+	assert.Equal(t, int64(1), report.MostViewed[1].Views)
+
+	require.Len(t, report.StaleNotebooks, 1)
+	assert.Equal(t, "Archive", report.StaleNotebooks[0].Name)
+	assert.Equal(t, int64(2), report.StaleNotebooks[0].NoteCount)
+	assert.WithinDuration(t, now.AddDate(0, 0, -100), report.StaleNotebooks[0].LastEditedAt, time.Second)
+
+	require.Len(t, report.TopContributors, 2)
+	assert.Equal(t, "user_a", report.TopContributors[0].UserID)
+	assert.Equal(t, int64(2), report.TopContributors[0].Edits)
+	assert.Equal(t, int64(2), report.TopContributors[0].NotesEdited)
+
+	assert.Equal(t, int64(2), report.OrphanedCount)
+	require.Len(t, report.OrphanedNotes, 2)
+	assert.Equal(t, stale[0].ID, report.OrphanedNotes[0].NoteID, "Orphaned notes should list the oldest first")
+	assert.Equal(t, stale[1].ID, report.OrphanedNotes[1].NoteID)
+}
+
+func TestNormalizeContentAnalyticsOptions(t *testing.T) {
+	options := normalizeContentAnalyticsOptions(ContentAnalyticsOptions{Days: 1000, StaleDays: -1, Limit: 500})
+	assert.Equal(t, maxContentAnalyticsDays, options.Days)
+	assert.Equal(t, defaultContentAnalyticsStaleDays, options.StaleDays)
+	assert.Equal(t, maxContentAnalyticsLimit, options.Limit)
+}
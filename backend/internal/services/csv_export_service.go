@@ -0,0 +1,244 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CSVContentType is the media type of CSV exports
+const CSVContentType = "text/csv; charset=utf-8"
+
+// csvDateFormat is how times are written in CSV exports, which spreadsheets parse as dates
+const csvDateFormat = "2006-01-02 15:04:05"
+
+// CSVExport is an exported spreadsheet and the name of the board or notebook it lists
+type CSVExport struct {
+	Name string
+	Data []byte
+}
+
+// CSVExportService interface defines methods for exporting boards and notebooks as spreadsheets
+type CSVExportService interface {
+	ExportBoard(ctx context.Context, boardID string) (*CSVExport, error)
+	ExportNotebookNotes(ctx context.Context, notebookID string) (*CSVExport, error)
+}
+
+// csvExportServiceImpl implements the CSVExportService interface
+type csvExportServiceImpl struct {
+	db *gorm.DB
+	// userName returns a user's name or email, empty when they can't be looked up
+	userName func(ctx context.Context, clerkUserID string) string
+}
+
+// NewCSVExportService creates a new CSVExportService instance
+func NewCSVExportService() CSVExportService {
+	return &csvExportServiceImpl{
+		db:       db.DB,
+		userName: csvUserName,
+	}
+}
+
+// ExportBoard lists a board's tasks, one per row in board order, with their assignees and dates
+func (s *csvExportServiceImpl) ExportBoard(ctx context.Context, boardID string) (*CSVExport, error) {
+	var board models.TaskBoard
+	if err := s.db.WithContext(ctx).
+		Preload("Tasks", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, created_at ASC") }).
+		Preload("Tasks.Assignments", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Tasks.ExternalLink").
+		Where("id = ?", boardID).First(&board).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskBoardNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch task board: %w", err)
+	}
+
+	var iterations []models.Iteration
+	if err := s.db.WithContext(ctx).Where("task_board_id = ?", boardID).Find(&iterations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch iterations: %w", err)
+	}
+	iterationNames := make(map[string]string, len(iterations))
+	for _, iteration := range iterations {
+		iterationNames[iteration.ID] = iteration.Name
+	}
+
+	rows := [][]string{{
+		"ID", "Title", "Description", "Status", "Priority", "Assignees", "Assignee IDs", "Iteration",
+		"Start Date", "Duration (days)", "Completed At", "Created At", "Updated At", "External Key", "External URL",
+	}}
+	names := make(map[string]string)
+	for _, task := range board.Tasks {
+		var assignees, assigneeIDs []string
+		for _, assignment := range task.Assignments {
+			name, ok := names[assignment.UserID]
+			if !ok {
+				name = s.userName(ctx, assignment.UserID)
+				if name == "" {
+					name = assignment.UserID
+				}
+				names[assignment.UserID] = name
+			}
+			assignees = append(assignees, name)
+			assigneeIDs = append(assigneeIDs, assignment.UserID)
+		}
+		var iteration, externalKey, externalURL string
+		if task.IterationID != nil {
+			iteration = iterationNames[*task.IterationID]
+		}
+		if task.ExternalLink != nil {
+			externalKey, externalURL = task.ExternalLink.ExternalKey, task.ExternalLink.ExternalURL
+		}
+		startDate := ""
+		if task.StartDate != nil {
+			startDate = task.StartDate.Format("2006-01-02")
+		}
+		rows = append(rows, []string{
+			task.ID, task.Title, task.Description, task.Status, task.Priority,
+			strings.Join(assignees, "; "), strings.Join(assigneeIDs, "; "), iteration,
+			startDate, strconv.Itoa(task.DurationDays), csvTime(task.CompletedAt),
+			csvTime(&task.CreatedAt), csvTime(&task.UpdatedAt), externalKey, externalURL,
+		})
+	}
+
+	data, err := writeCSV(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVExport{Name: board.Name, Data: data}, nil
+}
+
+// ExportNotebookNotes lists a notebook's notes with their metadata, one per row grouped by chapter, and
+// a column for each note property used in the notebook. Notes' content isn't included.
+func (s *csvExportServiceImpl) ExportNotebookNotes(ctx context.Context, notebookID string) (*CSVExport, error) {
+	var notebook models.Notebook
+	if err := s.db.WithContext(ctx).Select("id, name, encrypted").Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotebookNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+
+	var notes []models.Notes
+	if err := s.db.WithContext(ctx).
+		Preload("Chapter").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ?", notebookID).
+		Order("chapters.created_at ASC, notes.created_at ASC").
+		Find(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notes: %w", err)
+	}
+
+	var properties []models.NoteProperty
+	if err := s.db.WithContext(ctx).
+		Joins("JOIN notes ON notes.id = note_properties.note_id").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ?", notebookID).
+		Find(&properties).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note properties: %w", err)
+	}
+	values := make(map[string]map[string]string)
+	var keys []string
+	seen := make(map[string]bool)
+	for _, property := range properties {
+		if values[property.NoteID] == nil {
+			values[property.NoteID] = make(map[string]string)
+		}
+		values[property.NoteID][property.Key] = property.Value
+		if !seen[property.Key] {
+			seen[property.Key] = true
+			keys = append(keys, property.Key)
+		}
+	}
+	sort.Strings(keys)
+
+	header := []string{
+		"ID", "Name", "Chapter", "Status", "Language", "Public", "Locked", "Has Video", "Words", "Created At", "Updated At",
+	}
+	rows := [][]string{append(header, keys...)}
+	for _, note := range notes {
+		words := ""
+		if !note.Locked && !notebook.Encrypted {
+			words = strconv.Itoa(noteWordCount(note.Content))
+		}
+		row := []string{
+			note.ID, note.Name, note.Chapter.Name, note.Status, note.Language,
+			strconv.FormatBool(note.IsPublic), strconv.FormatBool(note.Locked), strconv.FormatBool(note.HasVideo),
+			words, csvTime(&note.CreatedAt), csvTime(&note.UpdatedAt),
+		}
+		for _, key := range keys {
+			row = append(row, values[note.ID][key])
+		}
+		rows = append(rows, row)
+	}
+
+	data, err := writeCSV(rows)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVExport{Name: notebook.Name, Data: data}, nil
+}
+
+// writeCSV writes rows as CSV. Cells that spreadsheets would run as formulas are prefixed with a quote
+// so they're shown as text.
+func writeCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for _, row := range rows {
+		for i, cell := range row {
+			if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+				row[i] = "'" + cell
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// csvTime formats a time in UTC, leaving it empty when unset
+func csvTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(csvDateFormat)
+}
+
+// csvUserName returns a user's full name, or their primary email when they have no name
+func csvUserName(ctx context.Context, clerkUserID string) string {
+	user, err := accessLookups.User(ctx, clerkUserID)
+	if err != nil || user == nil {
+		return ""
+	}
+
+	var nameParts []string
+	if user.FirstName != nil && *user.FirstName != "" {
+		nameParts = append(nameParts, *user.FirstName)
+	}
+	if user.LastName != nil && *user.LastName != "" {
+		nameParts = append(nameParts, *user.LastName)
+	}
+	if len(nameParts) > 0 {
+		return strings.Join(nameParts, " ")
+	}
+	for _, address := range user.EmailAddresses {
+		if user.PrimaryEmailAddressID != nil && address.ID == *user.PrimaryEmailAddressID {
+			return address.EmailAddress
+		}
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestCSVExportService(t *testing.T) *csvExportServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteProperty{},
+		&models.TaskBoard{}, &models.Task{}, &models.TaskAssignment{}, &models.TaskExternalLink{}, &models.Iteration{}),
+		"Failed to migrate test database")
+
+	return &csvExportServiceImpl{
+		db: db,
+		userName: func(ctx context.Context, clerkUserID string) string {
+			if clerkUserID == "user_alice" {
+				return "Alice Smith"
+			}
+			return ""
+		},
+	}
+}
+
+// readCSV parses an export into its rows
+func readCSV(t *testing.T, data []byte) [][]string {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	return rows
+}
+
+func TestCSVExportService_ExportBoard(t *testing.T) {
+	service := setupTestCSVExportService(t)
+	ctx := context.Background()
+
+	board := models.TaskBoard{Name: "Q3 Launch", ClerkUserID: "user_owner"}
+	require.NoError(t, service.db.Create(&board).Error)
+	iteration := models.Iteration{TaskBoardID: board.ID, Name: "Sprint 4", StartDate: time.Now(), EndDate: time.Now()}
+	require.NoError(t, service.db.Create(&iteration).Error)
+	start := time.Date(2026, 5, 4, 0, 0, 0, 0, time.UTC)
+	completed := time.Date(2026, 5, 6, 15, 30, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{Title: "Ship it", Description: "Line one, \"quoted\"\nline two", Status: "done", Priority: "high", TaskBoardID: board.ID,
+			Position: 1, StartDate: &start, DurationDays: 2, CompletedAt: &completed, IterationID: &iteration.ID},
+		{Title: "=HYPERLINK(\"https://evil.example\")", Status: "todo", TaskBoardID: board.ID, Position: 0},
+	}
+	require.NoError(t, service.db.Create(&tasks).Error)
+	require.NoError(t, service.db.Create(&[]models.TaskAssignment{
+		{TaskID: tasks[0].ID, UserID: "user_alice"},
+		{TaskID: tasks[0].ID, UserID: "user_bob"},
+	}).Error)
+
+	export, err := service.ExportBoard(ctx, board.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Q3 Launch", export.Name)
+
+	rows := readCSV(t, export.Data)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{"ID", "Title", "Description", "Status", "Priority", "Assignees", "Assignee IDs", "Iteration",
+		"Start Date", "Duration (days)", "Completed At", "Created At", "Updated At", "External Key", "External URL"}, rows[0])
+	assert.Equal(t, `'=HYPERLINK("https://evil.example")`, rows[1][1], "Formulas are exported as text")
+	ship := rows[2]
+	assert.Equal(t, "Ship it", ship[1])
+	assert.Equal(t, "Line one, \"quoted\"\nline two", ship[2])
+	assert.Equal(t, "Alice Smith; user_bob", ship[5], "Users without a name are listed by ID")
+	assert.Equal(t, "user_alice; user_bob", ship[6])
+	assert.Equal(t, "Sprint 4", ship[7])
+	assert.Equal(t, "2026-05-04", ship[8])
+	assert.Equal(t, "2", ship[9])
+	assert.Equal(t, "2026-05-06 15:30:00", ship[10])
+
+	_, err = service.ExportBoard(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTaskBoardNotFound)
+}
+
+func TestCSVExportService_ExportNotebookNotes(t *testing.T) {
+	service := setupTestCSVExportService(t)
+	ctx := context.Background()
+
+	notebook := models.Notebook{Name: "Handbook", ClerkUserID: "user_owner"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	policies := models.Chapter{Name: "Policies", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&policies).Error)
+	expenses := models.Notes{Name: "Expenses", ChapterID: policies.ID, Content: "Submit receipts within 30 days", IsPublic: true}
+	require.NoError(t, service.db.Create(&expenses).Error)
+	travel := models.Notes{Name: "Travel", ChapterID: policies.ID, Locked: true, Status: "approved"}
+	require.NoError(t, service.db.Create(&travel).Error)
+	require.NoError(t, service.db.Create(&[]models.NoteProperty{
+		{NoteID: expenses.ID, Key: "owner", Type: models.NotePropertyPerson, Value: "Finance"},
+		{NoteID: travel.ID, Key: "due", Type: models.NotePropertyDate, Value: "2026-06-01"},
+	}).Error)
+
+	other := models.Notebook{Name: "Other", ClerkUserID: "user_owner"}
+	require.NoError(t, service.db.Create(&other).Error)
+	otherChapter := models.Chapter{Name: "Elsewhere", NotebookID: other.ID}
+	require.NoError(t, service.db.Create(&otherChapter).Error)
+	require.NoError(t, service.db.Create(&models.Notes{Name: "Not listed", ChapterID: otherChapter.ID}).Error)
+
+	export, err := service.ExportNotebookNotes(ctx, notebook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Handbook", export.Name)
+
+	rows := readCSV(t, export.Data)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{"ID", "Name", "Chapter", "Status", "Language", "Public", "Locked", "Has Video", "Words",
+		"Created At", "Updated At", "due", "owner"}, rows[0])
+	assert.Equal(t, []string{expenses.ID, "Expenses", "Policies", "draft", "", "true", "false", "false", "5"}, rows[1][:9])
+	assert.Equal(t, []string{"", "Finance"}, rows[1][11:])
+	assert.Equal(t, "", rows[2][8], "Words aren't counted for locked notes")
+	assert.Equal(t, []string{"2026-06-01", ""}, rows[2][11:])
+
+	_, err = service.ExportNotebookNotes(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotebookNotFound)
+}
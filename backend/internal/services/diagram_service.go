@@ -0,0 +1,421 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDiagramsNotConfigured is returned when the server has no diagram renderer
+	ErrDiagramsNotConfigured = errors.New("diagram rendering is not configured on this server")
+	// ErrUnsupportedDiagram is returned for code blocks in languages that aren't rendered as diagrams
+	ErrUnsupportedDiagram = errors.New("unsupported diagram language")
+	// ErrInvalidDiagram is returned for diagrams that are empty, too large, or that the renderer rejects
+	ErrInvalidDiagram = errors.New("invalid diagram")
+	// ErrDiagramRenderFailed is returned when the renderer can't be reached or returns an unusable image
+	ErrDiagramRenderFailed = errors.New("failed to render diagram")
+)
+
+const (
+	maxDiagramSourceBytes = 50 << 10
+	maxDiagramImageBytes  = 5 << 20
+	maxDiagramPixels      = 20_000_000 // PNGs are decoded for PDFs, larger ones are left as code
+	// maxDiagramsPerDocument bounds the renders a page view or export can start
+	maxDiagramsPerDocument = 30
+)
+
+// diagramLanguages maps the languages of code blocks rendered as diagrams to their Kroki diagram type
+var diagramLanguages = map[string]string{
+	"mermaid":  "mermaid",
+	"plantuml": "plantuml",
+	"puml":     "plantuml",
+}
+
+// svgBlockedElements are removed from rendered diagrams with everything in them, since they run scripts
+// or load other documents
+var svgBlockedElements = map[string]bool{
+	"script": true, "iframe": true, "object": true, "embed": true, "audio": true, "video": true, "frame": true,
+}
+
+// DiagramService interface defines methods for rendering mermaid and PlantUML code blocks where the
+// browser can't: published pages, emails and PDFs
+type DiagramService interface {
+	Render(ctx context.Context, language, source, format string) ([]byte, error)
+	RenderContent(ctx context.Context, content string) string
+	RenderDocument(ctx context.Context, doc *utils.TipTapDoc)
+	Images(ctx context.Context, doc utils.TipTapDoc) map[string]image.Image
+}
+
+// diagramServiceImpl implements the DiagramService interface
+type diagramServiceImpl struct {
+	db     *gorm.DB
+	config *config.DiagramConfig
+	// render asks the renderer for a diagram of a Kroki type in a format
+	render func(ctx context.Context, diagramType, format, source string) ([]byte, error)
+}
+
+// NewDiagramService creates a new DiagramService instance
+func NewDiagramService() DiagramService {
+	cfg := config.LoadDiagramConfig()
+	client := &http.Client{Timeout: cfg.Timeout}
+	return &diagramServiceImpl{
+		db:     db.DB,
+		config: cfg,
+		render: func(ctx context.Context, diagramType, format, source string) ([]byte, error) {
+			return renderKrokiDiagram(ctx, client, cfg.RendererURL, diagramType, format, source)
+		},
+	}
+}
+
+// Render returns a diagram rendered as an SVG or a PNG, rendering it only when its source changed.
+// SVGs are stripped of scripts and event handlers so pages can show them inline.
+func (s *diagramServiceImpl) Render(ctx context.Context, language, source, format string) ([]byte, error) {
+	hash, diagramType, ok := diagramHash(language, source)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDiagram, language)
+	}
+	if format != models.DiagramFormatSVG && format != models.DiagramFormatPNG {
+		return nil, fmt.Errorf("%w: unknown format %s", ErrInvalidDiagram, format)
+	}
+	renders, err := s.cached(ctx, []string{hash}, format)
+	if err != nil {
+		return nil, err
+	}
+	if render, ok := renders[hash]; ok {
+		return renderResult(render)
+	}
+	render, err := s.renderNew(ctx, hash, diagramType, format, source)
+	if err != nil {
+		return nil, err
+	}
+	return renderResult(render)
+}
+
+// RenderContent sets the svg attribute of the diagram code blocks of TipTap content to their rendering.
+// Content that isn't a TipTap document or has no diagrams is returned unchanged.
+func (s *diagramServiceImpl) RenderContent(ctx context.Context, content string) string {
+	if !strings.Contains(content, `"codeBlock"`) {
+		return content
+	}
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" {
+		return content
+	}
+	s.RenderDocument(ctx, &doc)
+	rendered, err := json.Marshal(doc)
+	if err != nil {
+		return content
+	}
+	return string(rendered)
+}
+
+// RenderDocument sets the svg attribute of a document's diagram code blocks to their rendering. Diagrams
+// that can't be rendered stay code blocks, and svg attributes stored in the content are dropped.
+func (s *diagramServiceImpl) RenderDocument(ctx context.Context, doc *utils.TipTapDoc) {
+	blocks := diagramBlocks(doc.Content)
+	if len(blocks) == 0 {
+		return
+	}
+	renders := s.renderBlocks(ctx, blocks, models.DiagramFormatSVG)
+	for _, block := range blocks {
+		if render, ok := renders[block.hash]; ok && render.Error == "" {
+			block.node.Attrs["svg"] = string(render.Data)
+		}
+	}
+}
+
+// Images returns the diagrams of a document rendered as images for PDFs, keyed by diagramHash
+func (s *diagramServiceImpl) Images(ctx context.Context, doc utils.TipTapDoc) map[string]image.Image {
+	images := make(map[string]image.Image)
+	blocks := diagramBlocks(doc.Content)
+	if len(blocks) == 0 {
+		return images
+	}
+	for hash, render := range s.renderBlocks(ctx, blocks, models.DiagramFormatPNG) {
+		if render.Error != "" {
+			continue
+		}
+		cfg, err := png.DecodeConfig(bytes.NewReader(render.Data))
+		if err != nil || cfg.Width*cfg.Height > maxDiagramPixels {
+			log.Warn().Err(err).Str("hash", hash).Msg("Skipping diagram image")
+			continue
+		}
+		img, err := png.Decode(bytes.NewReader(render.Data))
+		if err != nil {
+			log.Warn().Err(err).Str("hash", hash).Msg("Failed to decode diagram image")
+			continue
+		}
+		images[hash] = img
+	}
+	return images
+}
+
+// diagramBlock is a diagram code block of a document
+type diagramBlock struct {
+	node              *utils.TipTapNode
+	hash, diagramType string
+	source            string
+}
+
+// diagramBlocks returns the diagram code blocks among nodes. Every code block gets its own attributes
+// without an svg, so renderings are only ever set by this service and don't change the nodes they were
+// copied from.
+func diagramBlocks(nodes []utils.TipTapNode) []diagramBlock {
+	var blocks []diagramBlock
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Type != "codeBlock" {
+			blocks = append(blocks, diagramBlocks(node.Content)...)
+			continue
+		}
+		attrs := make(map[string]interface{}, len(node.Attrs)+1)
+		for key, value := range node.Attrs {
+			if key != "svg" {
+				attrs[key] = value
+			}
+		}
+		node.Attrs = attrs
+		source := blockText(*node)
+		hash, diagramType, ok := diagramHash(attrString(node.Attrs, "language"), source)
+		if !ok {
+			continue
+		}
+		blocks = append(blocks, diagramBlock{node: node, hash: hash, diagramType: diagramType, source: source})
+	}
+	return blocks
+}
+
+// renderBlocks returns the renders of diagram blocks in a format, keyed by hash. Diagrams that fail to
+// render are left out.
+func (s *diagramServiceImpl) renderBlocks(ctx context.Context, blocks []diagramBlock, format string) map[string]*models.DiagramRender {
+	if !s.config.IsConfigured() {
+		return nil
+	}
+	hashes := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		hashes = append(hashes, block.hash)
+	}
+	renders, err := s.cached(ctx, hashes, format)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch cached diagrams")
+		return nil
+	}
+
+	started := 0
+	for _, block := range blocks {
+		if _, ok := renders[block.hash]; ok {
+			continue
+		}
+		if started++; started > maxDiagramsPerDocument {
+			break
+		}
+		render, err := s.renderNew(ctx, block.hash, block.diagramType, format, block.source)
+		if err != nil {
+			log.Warn().Err(err).Str("hash", block.hash).Str("language", block.diagramType).Msg("Failed to render diagram")
+			continue
+		}
+		renders[block.hash] = render
+	}
+	return renders
+}
+
+// cached returns the cached renders of diagrams in a format, keyed by hash
+func (s *diagramServiceImpl) cached(ctx context.Context, hashes []string, format string) (map[string]*models.DiagramRender, error) {
+	var found []models.DiagramRender
+	if err := db.Replica(s.db).WithContext(ctx).Where("hash IN ? AND format = ?", hashes, format).Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch cached diagrams: %w", err)
+	}
+	renders := make(map[string]*models.DiagramRender, len(found))
+	for i := range found {
+		renders[found[i].Hash] = &found[i]
+	}
+	return renders, nil
+}
+
+// renderNew renders a diagram that isn't cached and caches it. Sources the renderer rejects are cached
+// too, so they aren't sent again until they change.
+func (s *diagramServiceImpl) renderNew(ctx context.Context, hash, diagramType, format, source string) (*models.DiagramRender, error) {
+	if !s.config.IsConfigured() {
+		return nil, ErrDiagramsNotConfigured
+	}
+	if strings.TrimSpace(source) == "" || len(source) > maxDiagramSourceBytes {
+		return nil, fmt.Errorf("%w: diagrams must be at most %d KB", ErrInvalidDiagram, maxDiagramSourceBytes>>10)
+	}
+
+	render := &models.DiagramRender{Hash: hash, Format: format, Language: diagramType}
+	data, err := s.render(ctx, diagramType, format, source)
+	switch {
+	case errors.Is(err, ErrInvalidDiagram):
+		render.Error = strings.TrimSpace(strings.TrimPrefix(err.Error(), ErrInvalidDiagram.Error()+":"))
+	case err != nil:
+		return nil, err
+	case format == models.DiagramFormatSVG:
+		svg, err := sanitizeSVG(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDiagramRenderFailed, err)
+		}
+		render.Data = []byte(svg)
+	default:
+		render.Data = data
+	}
+
+	if err := s.db.WithContext(ctx).Where("hash = ? AND format = ?", hash, format).FirstOrCreate(render).Error; err != nil {
+		return nil, fmt.Errorf("failed to cache diagram: %w", err)
+	}
+	return render, nil
+}
+
+// renderResult returns a cached render's image, or why it couldn't be rendered
+func renderResult(render *models.DiagramRender) ([]byte, error) {
+	if render.Error != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDiagram, render.Error)
+	}
+	return render.Data, nil
+}
+
+// diagramHash returns the cache key of a diagram, the hash of its Kroki type and source, and the type.
+// ok is false for languages that aren't diagrams.
+func diagramHash(language, source string) (hash, diagramType string, ok bool) {
+	diagramType, ok = diagramLanguages[strings.ToLower(strings.TrimSpace(language))]
+	if !ok {
+		return "", "", false
+	}
+	sum := sha256.Sum256([]byte(diagramType + "\n" + source))
+	return hex.EncodeToString(sum[:]), diagramType, true
+}
+
+// renderKrokiDiagram renders a diagram with a Kroki server. Sources it can't parse are invalid diagrams.
+func renderKrokiDiagram(ctx context.Context, client *http.Client, baseURL, diagramType, format, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/"+diagramType+"/"+format, strings.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiagramRenderFailed, err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiagramRenderFailed, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDiagramImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiagramRenderFailed, err)
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		message, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDiagram, truncateLinkText(message, 500))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrDiagramRenderFailed, resp.StatusCode)
+	}
+	if len(data) > maxDiagramImageBytes {
+		return nil, fmt.Errorf("%w: the image is larger than %d MB", ErrDiagramRenderFailed, maxDiagramImageBytes>>20)
+	}
+	return data, nil
+}
+
+// sanitizeSVG rewrites a rendered SVG without scripts, event handlers, links to scripts, stylesheet
+// imports, comments or a doctype, so it can be shown inline in pages and emails
+func sanitizeSVG(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// Labels of mermaid diagrams are HTML, which may use its named entities
+	decoder.Entity = xml.HTMLEntity
+
+	var out strings.Builder
+	var open []string
+	skipped := 0
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipped > 0 || svgBlockedElements[strings.ToLower(t.Name.Local)] {
+				skipped++
+				continue
+			}
+			if len(open) == 0 && t.Name.Local != "svg" {
+				return "", fmt.Errorf("not an SVG image: %s", t.Name.Local)
+			}
+			open = append(open, t.Name.Local)
+			out.WriteString("<" + xmlName(t.Name))
+			for _, attr := range t.Attr {
+				if !svgAttrAllowed(attr) {
+					continue
+				}
+				out.WriteString(" " + xmlName(attr.Name) + `="`)
+				xml.EscapeText(&out, []byte(attr.Value))
+				out.WriteString(`"`)
+			}
+			out.WriteString(">")
+		case xml.EndElement:
+			if skipped > 0 {
+				skipped--
+				continue
+			}
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			out.WriteString("</" + xmlName(t.Name) + ">")
+		case xml.CharData:
+			if skipped > 0 || len(open) == 0 {
+				continue
+			}
+			text := []byte(t)
+			if open[len(open)-1] == "style" {
+				text = bytes.ReplaceAll(text, []byte("@import"), nil)
+			}
+			xml.EscapeText(&out, text)
+		}
+	}
+	if out.Len() == 0 {
+		return "", errors.New("empty SVG image")
+	}
+	return out.String(), nil
+}
+
+// svgAttrAllowed reports whether an attribute is kept in a sanitized SVG: event handlers are dropped,
+// and links may only point within the image or to web pages
+func svgAttrAllowed(attr xml.Attr) bool {
+	name := strings.ToLower(attr.Name.Local)
+	if strings.HasPrefix(name, "on") {
+		return false
+	}
+	if name == "href" || name == "src" || name == "action" || name == "formaction" {
+		value := strings.ToLower(strings.TrimSpace(attr.Value))
+		return strings.HasPrefix(value, "#") || strings.HasPrefix(value, "https://") ||
+			strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "mailto:")
+	}
+	return true
+}
+
+// xmlName returns an element or attribute name with its namespace prefix
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
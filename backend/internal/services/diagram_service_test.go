@@ -0,0 +1,169 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const renderedDiagram = `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" onload="alert(1)" viewBox="0 0 10 10">` +
+	`<!-- generated --><style>@import url(https://evil.example/x.css); .node { fill: #fff; }</style>` +
+	`<script>alert(2)</script><a xlink:href="javascript:alert(3)"><text x="1" y="2">A &amp; B&nbsp;</text></a>` +
+	`<a href="https://example.com"><rect width="5" height="5" onclick="alert(4)"/></a></svg>`
+
+// diagramContent is a note with a mermaid diagram, a PlantUML diagram and a code block that claims to
+// have been rendered already
+const diagramContent = `{"type":"doc","content":[
+	{"type":"codeBlock","attrs":{"language":"mermaid"},"content":[{"type":"text","text":"graph TD\n  A --> B"}]},
+	{"type":"codeBlock","attrs":{"language":"javascript","svg":"<svg onload=\"alert(1)\"></svg>"},"content":[{"type":"text","text":"alert(1)"}]},
+	{"type":"blockquote","content":[{"type":"codeBlock","attrs":{"language":"puml"},"content":[{"type":"text","text":"Alice -> Bob"}]}]}
+]}`
+
+func setupTestDiagramService(t *testing.T, render func(ctx context.Context, diagramType, format, source string) ([]byte, error)) *diagramServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.DiagramRender{}), "Failed to migrate test database")
+
+	return &diagramServiceImpl{
+		db:     db,
+		config: &config.DiagramConfig{RendererURL: "https://kroki.example.com", Timeout: time.Second},
+		render: render,
+	}
+}
+
+// diagramPNG returns a small PNG like the renderer would
+func diagramPNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	img.Set(1, 1, color.RGBA{R: 200, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestRenderDiagram(t *testing.T) {
+	var calls []string
+	service := setupTestDiagramService(t, func(ctx context.Context, diagramType, format, source string) ([]byte, error) {
+		calls = append(calls, diagramType+"/"+format)
+		return []byte(renderedDiagram), nil
+	})
+	ctx := context.Background()
+
+	svg, err := service.Render(ctx, "Mermaid", "graph TD\n  A --> B", models.DiagramFormatSVG)
+	require.NoError(t, err)
+	assert.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 10 10">`+
+		`<style> url(https://evil.example/x.css); .node { fill: #fff; }</style><a><text x="1" y="2">A &amp; B`+" "+`</text></a>`+
+		`<a href="https://example.com"><rect width="5" height="5"></rect></a></svg>`, string(svg))
+
+	again, err := service.Render(ctx, "mermaid", "graph TD\n  A --> B", models.DiagramFormatSVG)
+	require.NoError(t, err)
+	assert.Equal(t, svg, again)
+	assert.Equal(t, []string{"mermaid/svg"}, calls, "Diagrams are rendered once per source")
+
+	_, err = service.Render(ctx, "plantuml", "graph TD\n  A --> B", models.DiagramFormatSVG)
+	require.NoError(t, err)
+	assert.Len(t, calls, 2, "The same source in another language is another diagram")
+
+	_, err = service.Render(ctx, "go", "package main", models.DiagramFormatSVG)
+	assert.ErrorIs(t, err, ErrUnsupportedDiagram)
+	_, err = service.Render(ctx, "mermaid", "   ", models.DiagramFormatSVG)
+	assert.ErrorIs(t, err, ErrInvalidDiagram)
+	assert.Len(t, calls, 2)
+}
+
+func TestRenderDiagram_Errors(t *testing.T) {
+	calls := 0
+	service := setupTestDiagramService(t, func(ctx context.Context, diagramType, format, source string) ([]byte, error) {
+		calls++
+		switch source {
+		case "graph ???":
+			return nil, fmt.Errorf("%w: Parse error on line 1", ErrInvalidDiagram)
+		case "offline":
+			return nil, fmt.Errorf("%w: status 503", ErrDiagramRenderFailed)
+		}
+		return []byte("<html><script>alert(1)</script></html>"), nil
+	})
+	ctx := context.Background()
+
+	for range 2 {
+		_, err := service.Render(ctx, "mermaid", "graph ???", models.DiagramFormatSVG)
+		assert.ErrorIs(t, err, ErrInvalidDiagram)
+		assert.EqualError(t, err, "invalid diagram: Parse error on line 1")
+	}
+	assert.Equal(t, 1, calls, "Diagrams the renderer rejects aren't sent again")
+
+	for range 2 {
+		_, err := service.Render(ctx, "mermaid", "offline", models.DiagramFormatSVG)
+		assert.ErrorIs(t, err, ErrDiagramRenderFailed)
+	}
+	assert.Equal(t, 3, calls, "Diagrams are rendered again after the renderer fails")
+
+	_, err := service.Render(ctx, "mermaid", "html", models.DiagramFormatSVG)
+	assert.ErrorIs(t, err, ErrDiagramRenderFailed, "Renderings that aren't SVGs are rejected")
+
+	service.config = &config.DiagramConfig{}
+	_, err = service.Render(ctx, "mermaid", "graph LR", models.DiagramFormatSVG)
+	assert.ErrorIs(t, err, ErrDiagramsNotConfigured)
+}
+
+func TestRenderDiagramContent(t *testing.T) {
+	service := setupTestDiagramService(t, func(ctx context.Context, diagramType, format, source string) ([]byte, error) {
+		return []byte(`<svg xmlns="http://www.w3.org/2000/svg"><text>` + diagramType + `</text></svg>`), nil
+	})
+	ctx := context.Background()
+
+	var doc utils.TipTapDoc
+	require.NoError(t, json.Unmarshal([]byte(service.RenderContent(ctx, diagramContent)), &doc))
+	assert.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg"><text>mermaid</text></svg>`, doc.Content[0].Attrs["svg"])
+	assert.Equal(t, "graph TD\n  A --> B", doc.Content[0].Content[0].Text, "The source is kept")
+	assert.NotContains(t, doc.Content[1].Attrs, "svg", "Only diagrams the server rendered are shown")
+	assert.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg"><text>plantuml</text></svg>`, doc.Content[2].Content[0].Attrs["svg"])
+
+	html := utils.TipTapToHTML(doc)
+	assert.Contains(t, html, `<div style="margin:0 0 12px;overflow-x:auto"><svg xmlns="http://www.w3.org/2000/svg"><text>mermaid</text></svg></div>`)
+	assert.Contains(t, html, "<code>alert(1)</code>")
+	assert.NotContains(t, html, "onload")
+
+	service.config = &config.DiagramConfig{}
+	var unrendered utils.TipTapDoc
+	require.NoError(t, json.Unmarshal([]byte(service.RenderContent(ctx, diagramContent)), &unrendered))
+	assert.NotContains(t, unrendered.Content[0].Attrs, "svg", "Diagrams stay code blocks without a renderer")
+	assert.NotContains(t, unrendered.Content[1].Attrs, "svg")
+	assert.Equal(t, "plain text", service.RenderContent(ctx, "plain text"))
+}
+
+func TestExportNotePDF_Diagrams(t *testing.T) {
+	diagrams := setupTestDiagramService(t, func(ctx context.Context, diagramType, format, source string) ([]byte, error) {
+		if format != models.DiagramFormatPNG {
+			return nil, fmt.Errorf("%w: unexpected format %s", ErrDiagramRenderFailed, format)
+		}
+		return diagramPNG(t), nil
+	})
+	service := setupTestPDFExportService(t)
+	service.diagrams = diagrams
+	note := createLifecycleNote(t, service.db, nil)
+	require.NoError(t, service.db.Model(&note).Update("content", diagramContent).Error)
+
+	export, err := service.ExportNote(context.Background(), note.ID, PDFExportOptions{PageSize: "a4"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, bytes.Count(export.PDF, []byte("/Subtype /Image /Width 40 /Height 20")), "Both diagrams are drawn as images")
+
+	text := pdfContent(t, export.PDF)
+	assert.Contains(t, text, "30 0 0 15 56 ", "Images are drawn at their size on screen")
+	assert.Contains(t, text, "/Im1 Do")
+	assert.NotContains(t, text, "(graph TD) Tj", "Rendered diagrams aren't printed as code")
+	assert.Contains(t, text, "(alert\\(1\\)) Tj")
+}
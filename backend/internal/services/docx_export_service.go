@@ -0,0 +1,387 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"backend/pkg/docx"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // Registers GIF for image.DecodeConfig
+	_ "image/jpeg" // Registers JPEG for image.DecodeConfig
+	_ "image/png"  // Registers PNG for image.DecodeConfig
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrDocxExportTooLarge is returned for notebooks with more notes than a Word document is exported with
+var ErrDocxExportTooLarge = errors.New("the notebook has too many notes to export as a Word document")
+
+const (
+	maxDocxExportNotes = 500
+	// maxDocxImages is the most images embedded in a document, later ones are linked instead
+	maxDocxImages     = 50
+	maxDocxImageBytes = 5 << 20
+	docxImageTimeout  = 10 * time.Second
+)
+
+// DocxContentType is the media type of Word documents
+const DocxContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// DocxExport is an exported Word document and the name of the note or notebook it holds
+type DocxExport struct {
+	Name string
+	Data []byte
+}
+
+// DocxExportService interface defines methods for exporting notes and notebooks as Word documents
+type DocxExportService interface {
+	ExportNote(ctx context.Context, noteID string) (*DocxExport, error)
+	ExportNotebook(ctx context.Context, notebookID string) (*DocxExport, error)
+}
+
+// docxExportServiceImpl implements the DocxExportService interface
+type docxExportServiceImpl struct {
+	db         *gorm.DB
+	fetchImage func(ctx context.Context, src string) ([]byte, error)
+	now        func() time.Time
+}
+
+// NewDocxExportService creates a new DocxExportService instance
+func NewDocxExportService() DocxExportService {
+	client := newLinkPreviewClient()
+	return &docxExportServiceImpl{
+		db: db.DB,
+		fetchImage: func(ctx context.Context, src string) ([]byte, error) {
+			return fetchDocxImage(ctx, client, src)
+		},
+		now: time.Now,
+	}
+}
+
+// ExportNote exports a note as a Word document titled with the note's name
+func (s *docxExportServiceImpl) ExportNote(ctx context.Context, noteID string) (*DocxExport, error) {
+	var note models.Notes
+	if err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.Chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	w := s.newWriter(ctx, note.Name)
+	w.doc.Paragraph(docx.StyleTitle, []docx.Run{{Text: note.Name}})
+	w.doc.Paragraph(docx.StyleSubtitle, []docx.Run{{Text: note.Chapter.Notebook.Name + " / " + note.Chapter.Name}})
+	w.note(note, 0)
+
+	data, err := w.doc.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write Word document: %w", err)
+	}
+	return &DocxExport{Name: note.Name, Data: data}, nil
+}
+
+// ExportNotebook exports a notebook as a Word document, each chapter starting on a new page with its
+// notes as sections under it
+func (s *docxExportServiceImpl) ExportNotebook(ctx context.Context, notebookID string) (*DocxExport, error) {
+	var notebook models.Notebook
+	if err := s.db.WithContext(ctx).Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotebookNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	chapters, notes, err := exportChapters(s.db.WithContext(ctx), notebook.ID)
+	if err != nil {
+		return nil, err
+	}
+	if notes > maxDocxExportNotes {
+		return nil, ErrDocxExportTooLarge
+	}
+
+	w := s.newWriter(ctx, notebook.Name)
+	w.doc.Paragraph(docx.StyleTitle, []docx.Run{{Text: notebook.Name}})
+	w.doc.Paragraph(docx.StyleSubtitle, []docx.Run{{Text: fmt.Sprintf("%s · %s · Exported %s",
+		pluralize(len(chapters), "chapter"), pluralize(notes, "note"), s.now().Format(pdfExportDateFormat))}})
+
+	for _, chapter := range chapters {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		w.doc.PageBreak()
+		w.doc.Paragraph(docx.Heading(1), []docx.Run{{Text: chapter.Name}})
+		for _, note := range chapter.Files {
+			w.doc.Paragraph(docx.Heading(2), []docx.Run{{Text: note.Name}})
+			// The note's own headings nest under the chapter and note headings
+			w.note(note, 2)
+		}
+	}
+
+	data, err := w.doc.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write Word document: %w", err)
+	}
+	return &DocxExport{Name: notebook.Name, Data: data}, nil
+}
+
+func (s *docxExportServiceImpl) newWriter(ctx context.Context, title string) *docxWriter {
+	doc := docx.New(docx.A4)
+	doc.SetInfo(title, s.now())
+	return &docxWriter{ctx: ctx, doc: doc, fetchImage: s.fetchImage}
+}
+
+// docxWriter maps the nodes of TipTap documents to Word paragraphs, lists, tables and images
+type docxWriter struct {
+	ctx           context.Context
+	doc           *docx.Document
+	fetchImage    func(ctx context.Context, src string) ([]byte, error)
+	images        int
+	headingOffset int
+}
+
+// note writes the content of a note, its headings moved down by headingOffset levels
+func (w *docxWriter) note(note models.Notes, headingOffset int) {
+	if note.Locked {
+		w.doc.Paragraph(docx.StyleCaption, []docx.Run{{Text: lockedNoteExportText}})
+		return
+	}
+	w.headingOffset = headingOffset
+	w.blocks(exportNoteDocument(note.Content).Content, docx.StyleNormal)
+}
+
+// blocks writes block nodes, with paragraphs in a style so quotes keep theirs
+func (w *docxWriter) blocks(nodes []utils.TipTapNode, paragraphStyle string) {
+	for _, node := range nodes {
+		switch node.Type {
+		case "paragraph":
+			w.doc.Paragraph(paragraphStyle, docxRuns(node.Content, false))
+		case "heading":
+			w.doc.Paragraph(docx.Heading(headingLevel(node)+w.headingOffset), docxRuns(node.Content, false))
+		case "bulletList", "orderedList", "taskList":
+			w.list(node, 0)
+		case "codeBlock":
+			w.doc.Paragraph(docx.StyleCode, []docx.Run{{Text: strings.TrimRight(blockText(node), "\n")}})
+		case "blockquote":
+			w.blocks(node.Content, docx.StyleQuote)
+		case "callout":
+			if title := attrString(node.Attrs, "title"); title != "" {
+				w.doc.Paragraph(docx.StyleQuote, []docx.Run{{Text: title, Bold: true}})
+			}
+			w.blocks(node.Content, docx.StyleQuote)
+		case "horizontalRule":
+			w.doc.Rule()
+		case "table":
+			w.table(node)
+		case "image":
+			w.image(node)
+		default:
+			if len(node.Content) > 0 {
+				w.blocks(node.Content, paragraphStyle)
+			} else if text := strings.TrimSpace(node.Text); text != "" {
+				w.doc.Paragraph(paragraphStyle, []docx.Run{{Text: text}})
+			}
+		}
+	}
+}
+
+// list writes a list as Word numbering, nested lists one level deeper. Task items start with a checkbox.
+func (w *docxWriter) list(node utils.TipTapNode, level int) {
+	start := 1
+	if value, ok := node.Attrs["start"].(float64); ok {
+		start = int(value)
+	}
+	list := w.doc.NewList(node.Type == "orderedList", start)
+
+	for _, item := range node.Content {
+		var runs []docx.Run
+		if item.Type == "taskItem" {
+			box := "☐ "
+			if checked, _ := item.Attrs["checked"].(bool); checked {
+				box = "☒ "
+			}
+			runs = append(runs, docx.Run{Text: box})
+		}
+
+		var rest []utils.TipTapNode
+		for i, child := range item.Content {
+			if child.Type != "paragraph" {
+				rest = append(rest, child)
+				continue
+			}
+			// Paragraphs after the first continue the item on a new line
+			if i > 0 {
+				runs = append(runs, docx.Run{Text: "\n"})
+			}
+			runs = append(runs, docxRuns(child.Content, false)...)
+		}
+		w.doc.ListItem(list, level, runs)
+
+		for _, child := range rest {
+			switch child.Type {
+			case "bulletList", "orderedList", "taskList":
+				w.list(child, level+1)
+			default:
+				w.blocks([]utils.TipTapNode{child}, docx.StyleNormal)
+			}
+		}
+	}
+}
+
+// table writes a table, with its first row as a header when it's made of header cells
+func (w *docxWriter) table(node utils.TipTapNode) {
+	rows := make([][]docx.Cell, 0, len(node.Content))
+	header := false
+	for i, row := range node.Content {
+		cells := make([]docx.Cell, 0, len(row.Content))
+		for _, cell := range row.Content {
+			if i == 0 && cell.Type == "tableHeader" {
+				header = true
+			}
+			var paragraphs docx.Cell
+			for _, child := range cell.Content {
+				if child.Type == "paragraph" {
+					paragraphs = append(paragraphs, docxRuns(child.Content, false))
+				} else if text := strings.TrimSpace(blockText(child)); text != "" {
+					paragraphs = append(paragraphs, []docx.Run{{Text: text}})
+				}
+			}
+			cells = append(cells, paragraphs)
+		}
+		rows = append(rows, cells)
+	}
+	w.doc.Table(rows, header)
+}
+
+// image embeds an image, or links to it when it can't be fetched or there are too many
+func (w *docxWriter) image(node utils.TipTapNode) {
+	src := attrString(node.Attrs, "src")
+	alt := attrString(node.Attrs, "alt")
+	if src == "" {
+		return
+	}
+
+	if w.images < maxDocxImages {
+		data, err := w.fetchImage(w.ctx, src)
+		if err == nil {
+			config, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err == nil {
+				w.images++
+				w.doc.Image(data, format, config.Width, config.Height, alt)
+				if title := attrString(node.Attrs, "title"); title != "" {
+					w.doc.Paragraph(docx.StyleCaption, []docx.Run{{Text: title}})
+				}
+				return
+			}
+		}
+		log.Debug().Err(err).Msg("Linking image instead of embedding it in Word document")
+	}
+
+	label := alt
+	if label == "" {
+		label = "Image"
+	}
+	run := docx.Run{Text: "[" + label + "]"}
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		run.Link = src
+	}
+	w.doc.Paragraph(docx.StyleCaption, []docx.Run{run})
+}
+
+// docxRuns turns the inline nodes of a block into styled runs
+func docxRuns(nodes []utils.TipTapNode, bold bool) []docx.Run {
+	var runs []docx.Run
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			run := docx.Run{Text: node.Text, Bold: bold}
+			for _, mark := range node.Marks {
+				switch mark.Type {
+				case "bold":
+					run.Bold = true
+				case "italic":
+					run.Italic = true
+				case "strike":
+					run.Strike = true
+				case "code":
+					run.Code = true
+				case "link":
+					if href := attrString(mark.Attrs, "href"); strings.HasPrefix(href, "http://") ||
+						strings.HasPrefix(href, "https://") || strings.HasPrefix(href, "mailto:") {
+						run.Link = href
+					}
+				}
+			}
+			runs = append(runs, run)
+		case "hardBreak":
+			runs = append(runs, docx.Run{Text: "\n"})
+		case "mention":
+			if label := attrString(node.Attrs, "label"); label != "" {
+				runs = append(runs, docx.Run{Text: "@" + label, Bold: bold})
+			}
+		case utils.MathNodeType:
+			if latex := utils.MathLaTeX(node); latex != "" {
+				runs = append(runs, docx.Run{Math: utils.LaTeXToOMML(latex)})
+			}
+		default:
+			runs = append(runs, docxRuns(node.Content, bold)...)
+		}
+	}
+	return runs
+}
+
+// fetchDocxImage reads an image from a data URL or downloads it from a public address
+func fetchDocxImage(ctx context.Context, client *http.Client, src string) ([]byte, error) {
+	if strings.HasPrefix(src, "data:") {
+		header, payload, ok := strings.Cut(strings.TrimPrefix(src, "data:"), ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, errors.New("unsupported data URL")
+		}
+		if base64.StdEncoding.DecodedLen(len(payload)) > maxDocxImageBytes {
+			return nil, errors.New("image is too large")
+		}
+		return base64.StdEncoding.DecodeString(payload)
+	}
+
+	normalized, err := normalizeLinkURL(src)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, docxImageTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, normalized, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image request failed with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDocxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDocxImageBytes {
+		return nil, errors.New("image is too large")
+	}
+	return data, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"archive/zip"
+	"backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDocxExportService creates a Word export service that reads images from data URLs only
+func setupTestDocxExportService(t *testing.T) *docxExportServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}), "Failed to migrate test database")
+
+	return &docxExportServiceImpl{
+		db: db,
+		fetchImage: func(ctx context.Context, src string) ([]byte, error) {
+			if !strings.HasPrefix(src, "data:") {
+				return nil, errors.New("offline")
+			}
+			return fetchDocxImage(ctx, nil, src)
+		},
+		now: func() time.Time { return time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+// docxPart returns a part of a Word document
+func docxPart(t *testing.T, data []byte, name string) string {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	file, err := archive.Open(name)
+	require.NoError(t, err, "Document has no %s", name)
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestExportNoteDocx(t *testing.T) {
+	service := setupTestDocxExportService(t)
+	ctx := context.Background()
+
+	var pixel bytes.Buffer
+	require.NoError(t, png.Encode(&pixel, image.NewRGBA(image.Rect(0, 0, 40, 20))))
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pixel.Bytes())
+
+	notebook := models.Notebook{Name: "Handbook", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Engineering", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&chapter).Error)
+	content := `{"type":"doc","content":[
+		{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Setup"}]},
+		{"type":"paragraph","content":[{"type":"text","text":"Read "},{"type":"text","marks":[{"type":"link","attrs":{"href":"https://go.dev"}}],"text":"the docs"},{"type":"text","marks":[{"type":"bold"}],"text":" & install"}]},
+		{"type":"orderedList","attrs":{"start":3},"content":[{"type":"listItem","content":[
+			{"type":"paragraph","content":[{"type":"text","text":"Go"}]},
+			{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"1.25"}]}]}]}
+		]}]},
+		{"type":"taskList","content":[{"type":"taskItem","attrs":{"checked":true},"content":[{"type":"paragraph","content":[{"type":"text","text":"Clone"}]}]}]},
+		{"type":"table","content":[
+			{"type":"tableRow","content":[{"type":"tableHeader","content":[{"type":"paragraph","content":[{"type":"text","text":"Tool"}]}]}]},
+			{"type":"tableRow","content":[{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"text","text":"make"}]}]}]}
+		]},
+		{"type":"image","attrs":{"src":"` + dataURL + `","alt":"Diagram"}},
+		{"type":"image","attrs":{"src":"https://example.com/missing.png","alt":"Remote"}},
+		{"type":"codeBlock","content":[{"type":"text","text":"make build\nmake test"}]}
+	]}`
+	note := models.Notes{Name: "Onboarding", Content: content, ChapterID: chapter.ID}
+	require.NoError(t, service.db.Create(&note).Error)
+
+	export, err := service.ExportNote(ctx, note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Onboarding", export.Name)
+
+	document := docxPart(t, export.Data, "word/document.xml")
+	assert.Contains(t, document, `<w:pStyle w:val="Title"/>`)
+	assert.Contains(t, document, "Handbook / Engineering")
+	assert.Contains(t, document, `<w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t xml:space="preserve">Setup</w:t>`)
+	assert.Contains(t, document, "&amp; install", "Text is escaped")
+	assert.Contains(t, document, `<w:hyperlink r:id=`)
+	assert.Contains(t, document, `<w:ilvl w:val="1"/>`, "Nested lists are a level deeper")
+	assert.Contains(t, document, "☒ ")
+	assert.Contains(t, document, "<w:tblHeader/>")
+	assert.Equal(t, 1, strings.Count(document, "<w:drawing>"), "Images that can't be fetched are linked instead")
+	assert.Contains(t, document, "[Remote]")
+	assert.Contains(t, document, `<w:pStyle w:val="Code"/>`)
+	assert.Contains(t, document, "make build</w:t><w:br/>")
+
+	assert.Contains(t, docxPart(t, export.Data, "word/numbering.xml"), `<w:startOverride w:val="3"/>`)
+	assert.Contains(t, docxPart(t, export.Data, "word/_rels/document.xml.rels"), `Target="https://go.dev" TargetMode="External"`)
+	docxPart(t, export.Data, "word/media/image1.png")
+
+	// Locked notes keep their content out of the document
+	require.NoError(t, service.db.Model(&note).Update("locked", true).Error)
+	export, err = service.ExportNote(ctx, note.ID)
+	require.NoError(t, err)
+	document = docxPart(t, export.Data, "word/document.xml")
+	assert.NotContains(t, document, "Setup")
+	assert.Contains(t, document, "This note is locked")
+
+	require.NoError(t, service.db.Model(&notebook).Update("encrypted", true).Error)
+	_, err = service.ExportNote(ctx, note.ID)
+	assert.ErrorIs(t, err, ErrNotebookEncrypted)
+}
+
+func TestExportNotebookDocx(t *testing.T) {
+	service := setupTestDocxExportService(t)
+	ctx := context.Background()
+
+	notebook := models.Notebook{Name: "Meetings", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	var chapter models.Chapter
+	for _, name := range []string{"Q1", "Q2"} {
+		chapter = models.Chapter{Name: name, NotebookID: notebook.ID}
+		require.NoError(t, service.db.Create(&chapter).Error)
+		content := `{"type":"doc","content":[{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"Decisions"}]}]}`
+		require.NoError(t, service.db.Create(&models.Notes{Name: name + " planning", Content: content, ChapterID: chapter.ID}).Error)
+	}
+
+	export, err := service.ExportNotebook(ctx, notebook.ID)
+	require.NoError(t, err)
+	document := docxPart(t, export.Data, "word/document.xml")
+	assert.Contains(t, document, "2 chapters · 2 notes · Exported May 4, 2026")
+	assert.Equal(t, 2, strings.Count(document, `<w:br w:type="page"/>`), "Each chapter starts on a new page")
+	assert.Contains(t, document, `<w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t xml:space="preserve">Q2</w:t>`)
+	assert.Contains(t, document, `<w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t xml:space="preserve">Q1 planning</w:t>`)
+	assert.Contains(t, document, `<w:pStyle w:val="Heading3"/></w:pPr><w:r><w:t xml:space="preserve">Decisions</w:t>`,
+		"Note headings nest under the note's heading")
+	assert.Contains(t, docxPart(t, export.Data, "docProps/core.xml"), "<dc:title>Meetings</dc:title>")
+
+	for i := 0; i < maxDocxExportNotes-1; i++ {
+		require.NoError(t, service.db.Create(&models.Notes{Name: fmt.Sprintf("Note %d", i), ChapterID: chapter.ID}).Error)
+	}
+	_, err = service.ExportNotebook(ctx, notebook.ID)
+	assert.ErrorIs(t, err, ErrDocxExportTooLarge)
+
+	_, err = service.ExportNotebook(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotebookNotFound)
+}
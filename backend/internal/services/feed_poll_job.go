@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// FeedPollJob periodically queues polls of feed subscriptions whose interval has elapsed
+type FeedPollJob struct {
+	service  FeedSubscriptionService
+	interval time.Duration
+	stopChan chan struct{}
+}
+
+// NewFeedPollJob creates a new feed poll scheduler
+func NewFeedPollJob(service FeedSubscriptionService, interval time.Duration) *FeedPollJob {
+	return &FeedPollJob{
+		service:  service,
+		interval: interval,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins checking for due polls
+func (j *FeedPollJob) Start(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Msg("Starting feed poll scheduler")
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if queued := j.service.QueueDuePolls(time.Now()); queued > 0 {
+				log.Info().Int("queued", queued).Msg("Queued feed polls")
+			}
+		case <-ctx.Done():
+			log.Info().Msg("Stopping feed poll scheduler (context cancelled)")
+			return
+		case <-j.stopChan:
+			log.Info().Msg("Stopping feed poll scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (j *FeedPollJob) Stop() {
+	close(j.stopChan)
+}
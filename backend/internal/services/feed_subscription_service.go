@@ -0,0 +1,1050 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html/charset"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrFeedSubscriptionNotFound is returned when a feed subscription doesn't exist or belongs to another user
+	ErrFeedSubscriptionNotFound = errors.New("feed subscription not found")
+	// ErrInvalidFeedSubscription is returned, wrapped with the reason, when a subscription can't be saved
+	ErrInvalidFeedSubscription = errors.New("invalid feed subscription")
+	// ErrFeedSubscriptionExists is returned when the user already gets the feed in the same place
+	ErrFeedSubscriptionExists = errors.New("already subscribed to this feed")
+	// ErrFeedPollInProgress is returned when a subscription's feed is already being polled
+	ErrFeedPollInProgress = errors.New("feed is already being polled")
+	// ErrFeedPollFailed is returned, wrapped with the reason, when a feed can't be fetched or read
+	ErrFeedPollFailed = errors.New("failed to poll feed")
+)
+
+const (
+	defaultFeedPollInterval     = 60
+	minFeedPollInterval         = 15
+	maxFeedPollInterval         = 7 * 24 * 60
+	maxFeedSubscriptionsPerUser = 100
+	maxFeedKeywords             = 20
+	maxFeedKeywordLength        = 100
+	// feedFirstPollEntries is how many of the entries already in a feed are imported when subscribing
+	feedFirstPollEntries = 5
+	// maxFeedEntriesPerPoll caps the entries imported by a poll, so a feed that floods doesn't flood the chapter
+	maxFeedEntriesPerPoll = 20
+	maxFeedEntries        = 100
+	feedMaxBytes          = 5 << 20
+	feedPollJobTimeout    = 2 * time.Minute
+)
+
+// feedDateLayouts are the date formats feeds are seen using, RSS's RFC 822 variants and Atom's RFC 3339
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+var (
+	// feedPollLocks prevents two polls of the same subscription from importing an entry twice
+	feedPollLocks sync.Map
+	// feedPollsQueued tracks subscriptions with a poll waiting in the job queue
+	feedPollsQueued sync.Map
+)
+
+var (
+	feedClient     *http.Client
+	feedClientOnce sync.Once
+)
+
+// FeedSubscriptionInput is the request body for subscribing to a feed or updating a subscription.
+// The feed URL can't be changed once subscribed, and new subscriptions start enabled.
+type FeedSubscriptionInput struct {
+	FeedURL             string   `json:"feedUrl"`
+	ChapterID           *string  `json:"chapterId"`
+	Target              string   `json:"target"`
+	IncludeKeywords     []string `json:"includeKeywords"`
+	ExcludeKeywords     []string `json:"excludeKeywords"`
+	PollIntervalMinutes int      `json:"pollIntervalMinutes"`
+	Enabled             *bool    `json:"enabled"`
+}
+
+// FeedSubscriptionDetails is a feed subscription with its keywords, chapter and how many entries it imported
+type FeedSubscriptionDetails struct {
+	models.FeedSubscription
+	IncludeKeywords []string `json:"includeKeywords"`
+	ExcludeKeywords []string `json:"excludeKeywords"`
+	ChapterName     string   `json:"chapterName,omitempty"`
+	ImportedEntries int64    `json:"importedEntries"`
+}
+
+// FeedPollResult is what a poll of a feed did with its entries
+type FeedPollResult struct {
+	Imported    int  `json:"imported"`
+	Filtered    int  `json:"filtered"`
+	Skipped     int  `json:"skipped"`
+	NotModified bool `json:"notModified"` // The feed hasn't changed since the last poll
+}
+
+// feedFetch is a downloaded feed, or the server saying it hasn't changed
+type feedFetch struct {
+	body         []byte
+	url          *url.URL
+	contentType  string
+	etag         string
+	lastModified string
+	notModified  bool
+}
+
+// parsedFeed is the parts of an RSS or Atom feed that are imported
+type parsedFeed struct {
+	title   string
+	siteURL string
+	entries []parsedFeedEntry
+}
+
+// parsedFeedEntry is an entry of a feed. Key identifies it across polls.
+type parsedFeedEntry struct {
+	key       string
+	title     string
+	url       string
+	summary   string // Paragraphs separated by blank lines
+	published *time.Time
+}
+
+// feedXMLDocument reads RSS 2.0, RSS 1.0 (RDF) and Atom documents. RSS keeps its items in the channel,
+// RSS 1.0 beside it and Atom has entries instead.
+type feedXMLDocument struct {
+	XMLName xml.Name
+	Channel struct {
+		Title feedXMLText    `xml:"title"`
+		Links []feedXMLLink  `xml:"link"`
+		Items []feedXMLEntry `xml:"item"`
+	} `xml:"channel"`
+	Items   []feedXMLEntry `xml:"item"`
+	Title   feedXMLText    `xml:"title"`
+	Links   []feedXMLLink  `xml:"link"`
+	Entries []feedXMLEntry `xml:"entry"`
+}
+
+// feedXMLEntry is an RSS item or Atom entry
+type feedXMLEntry struct {
+	Title       feedXMLText   `xml:"title"`
+	Links       []feedXMLLink `xml:"link"`
+	GUID        string        `xml:"guid"`
+	ID          string        `xml:"id"`
+	Description feedXMLText   `xml:"description"`
+	Summary     feedXMLText   `xml:"summary"`
+	Encoded     feedXMLText   `xml:"encoded"`
+	Content     feedXMLText   `xml:"content"`
+	PubDate     string        `xml:"pubDate"`
+	Published   string        `xml:"published"`
+	Updated     string        `xml:"updated"`
+	Date        string        `xml:"date"`
+}
+
+// feedXMLLink is an RSS link, which holds the URL as text, or an Atom link, which holds it in href
+type feedXMLLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+// feedXMLText is text that may be HTML, escaped or, for Atom's xhtml type, as markup
+type feedXMLText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// FeedSubscriptionService interface defines methods for subscribing chapters and the reading list to feeds
+type FeedSubscriptionService interface {
+	List(ctx context.Context, clerkUserID string) ([]FeedSubscriptionDetails, error)
+	Subscribe(ctx context.Context, clerkUserID string, input FeedSubscriptionInput) (*FeedSubscriptionDetails, error)
+	Update(ctx context.Context, id, clerkUserID string, input FeedSubscriptionInput) (*FeedSubscriptionDetails, error)
+	Unsubscribe(ctx context.Context, id, clerkUserID string) error
+	ListEntries(ctx context.Context, id, clerkUserID string) ([]models.FeedEntry, error)
+	Poll(ctx context.Context, id, clerkUserID string) (*FeedPollResult, error)
+	PollSubscription(ctx context.Context, id string) error
+	QueueDuePolls(now time.Time) int
+}
+
+// feedSubscriptionServiceImpl implements the FeedSubscriptionService interface
+type feedSubscriptionServiceImpl struct {
+	db             *gorm.DB
+	queue          *JobQueue
+	readingList    ReadingListService
+	fetchFeed      func(ctx context.Context, rawURL, etag, lastModified string) (*feedFetch, error)
+	canEditChapter func(ctx context.Context, db *gorm.DB, chapterID, clerkUserID string) (bool, error)
+	now            func() time.Time
+}
+
+// NewFeedSubscriptionService creates a new FeedSubscriptionService instance
+func NewFeedSubscriptionService() FeedSubscriptionService {
+	feedClientOnce.Do(func() {
+		feedClient = newLinkPreviewClient()
+	})
+	return &feedSubscriptionServiceImpl{
+		db:          db.DB,
+		queue:       GetJobQueue(),
+		readingList: NewReadingListService(),
+		fetchFeed: func(ctx context.Context, rawURL, etag, lastModified string) (*feedFetch, error) {
+			return fetchFeedURL(ctx, feedClient, rawURL, etag, lastModified)
+		},
+		canEditChapter: accessLookups.CanEditChapter,
+		now:            time.Now,
+	}
+}
+
+// List returns the user's feed subscriptions, most recent first
+func (s *feedSubscriptionServiceImpl) List(ctx context.Context, clerkUserID string) ([]FeedSubscriptionDetails, error) {
+	var subscriptions []models.FeedSubscription
+	if err := s.db.WithContext(ctx).Where("clerk_user_id = ?", clerkUserID).Order("created_at DESC").Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch feed subscriptions: %w", err)
+	}
+	return s.details(ctx, subscriptions)
+}
+
+// Subscribe subscribes a chapter or the user's reading list to a feed. The feed is read right away, so
+// URLs that aren't feeds are rejected, and its latest few entries are imported.
+func (s *feedSubscriptionServiceImpl) Subscribe(ctx context.Context, clerkUserID string, input FeedSubscriptionInput) (*FeedSubscriptionDetails, error) {
+	feedURL, err := normalizeLinkURL(input.FeedURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeedSubscription, err)
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.FeedSubscription{}).Where("clerk_user_id = ?", clerkUserID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count feed subscriptions: %w", err)
+	}
+	if count >= maxFeedSubscriptionsPerUser {
+		return nil, fmt.Errorf("%w: you can subscribe to at most %d feeds", ErrInvalidFeedSubscription, maxFeedSubscriptionsPerUser)
+	}
+
+	subscription := models.FeedSubscription{ClerkUserID: clerkUserID, FeedURL: feedURL, Enabled: true}
+	if err := s.applyInput(ctx, &subscription, input); err != nil {
+		return nil, err
+	}
+
+	fetched, err := s.fetchFeed(ctx, feedURL, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: the feed couldn't be fetched (%v)", ErrInvalidFeedSubscription, err)
+	}
+	feed, err := parseFeed(fetched.body, fetched.contentType, fetched.url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeedSubscription, err)
+	}
+
+	if err := s.db.WithContext(ctx).Create(&subscription).Error; err != nil {
+		return nil, fmt.Errorf("failed to save feed subscription: %w", err)
+	}
+	log.Info().Str("clerk_user_id", clerkUserID).Str("subscription_id", subscription.ID).Str("feed_url", feedURL).Msg("Subscribed to feed")
+
+	if _, err := s.importFeed(ctx, &subscription, fetched, feed); err != nil {
+		// The subscription is kept and retried on schedule; the error is shown on it
+		log.Warn().Err(err).Str("subscription_id", subscription.ID).Msg("Failed to import feed entries")
+	}
+	return s.detail(ctx, &subscription)
+}
+
+// Update changes where a subscription's entries go, its keywords, how often it's polled and whether it's enabled
+func (s *feedSubscriptionServiceImpl) Update(ctx context.Context, id, clerkUserID string, input FeedSubscriptionInput) (*FeedSubscriptionDetails, error) {
+	subscription, err := s.get(ctx, id, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInput(ctx, subscription, input); err != nil {
+		return nil, err
+	}
+	if input.Enabled != nil {
+		if *input.Enabled && !subscription.Enabled {
+			subscription.LastError = ""
+		}
+		subscription.Enabled = *input.Enabled
+	}
+	if err := s.db.WithContext(ctx).Save(subscription).Error; err != nil {
+		return nil, fmt.Errorf("failed to update feed subscription: %w", err)
+	}
+	return s.detail(ctx, subscription)
+}
+
+// Unsubscribe deletes a subscription and its record of entries. Notes and reading list items created
+// from the feed are kept.
+func (s *feedSubscriptionServiceImpl) Unsubscribe(ctx context.Context, id, clerkUserID string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND clerk_user_id = ?", id, clerkUserID).Delete(&models.FeedSubscription{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete feed subscription: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrFeedSubscriptionNotFound
+		}
+		if err := tx.Where("subscription_id = ?", id).Delete(&models.FeedEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to delete feed entries: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListEntries returns the latest entries seen in a subscription's feed and what was done with them
+func (s *feedSubscriptionServiceImpl) ListEntries(ctx context.Context, id, clerkUserID string) ([]models.FeedEntry, error) {
+	if _, err := s.get(ctx, id, clerkUserID); err != nil {
+		return nil, err
+	}
+	var entries []models.FeedEntry
+	if err := s.db.WithContext(ctx).Where("subscription_id = ?", id).Order("created_at DESC").Limit(maxFeedEntries).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch feed entries: %w", err)
+	}
+	return entries, nil
+}
+
+// Poll polls a subscription's feed now
+func (s *feedSubscriptionServiceImpl) Poll(ctx context.Context, id, clerkUserID string) (*FeedPollResult, error) {
+	subscription, err := s.get(ctx, id, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.poll(ctx, subscription)
+}
+
+// PollSubscription polls a subscription's feed from the scheduler. Subscriptions whose chapter the user
+// can no longer reach are disabled.
+func (s *feedSubscriptionServiceImpl) PollSubscription(ctx context.Context, id string) error {
+	var subscription models.FeedSubscription
+	err := s.db.WithContext(ctx).Where("id = ?", id).First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Unsubscribed since the poll was queued
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch feed subscription: %w", err)
+	}
+	if !subscription.Enabled {
+		return nil
+	}
+
+	if subscription.ChapterID != nil {
+		canEdit, err := s.canEditChapter(ctx, s.db, *subscription.ChapterID, subscription.ClerkUserID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check chapter access: %w", err)
+		}
+		if !canEdit {
+			log.Warn().Str("subscription_id", subscription.ID).Str("clerk_user_id", subscription.ClerkUserID).Msg("Disabling feed subscription to a chapter the user can't add notes to")
+			return s.db.WithContext(ctx).Model(&subscription).Updates(map[string]interface{}{
+				"enabled":    false,
+				"last_error": "You can no longer add notes to the chapter",
+			}).Error
+		}
+	}
+
+	_, err = s.poll(ctx, &subscription)
+	if errors.Is(err, ErrFeedPollInProgress) || errors.Is(err, ErrFeedPollFailed) {
+		// Recorded on the subscription and tried again on its next poll
+		return nil
+	}
+	return err
+}
+
+// QueueDuePolls queues polls of enabled subscriptions whose interval has elapsed and returns how many were queued
+func (s *feedSubscriptionServiceImpl) QueueDuePolls(now time.Time) int {
+	var subscriptions []models.FeedSubscription
+	if err := s.db.Where("enabled = ?", true).Find(&subscriptions).Error; err != nil {
+		log.Error().Err(err).Msg("Failed to find feed subscriptions")
+		return 0
+	}
+
+	queued := 0
+	for _, subscription := range subscriptions {
+		interval := time.Duration(subscription.PollIntervalMinutes) * time.Minute
+		if subscription.LastPolledAt != nil && now.Before(subscription.LastPolledAt.Add(interval)) {
+			continue
+		}
+		if err := s.queuePoll(subscription.ID); err != nil {
+			log.Warn().Err(err).Str("subscription_id", subscription.ID).Msg("Failed to queue feed poll")
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+// queuePoll polls a subscription's feed in the background
+func (s *feedSubscriptionServiceImpl) queuePoll(id string) error {
+	if _, queued := feedPollsQueued.LoadOrStore(id, true); queued {
+		return nil
+	}
+
+	err := s.queue.Enqueue(Job{
+		Name:        "feed-poll",
+		MaxAttempts: 2,
+		Timeout:     feedPollJobTimeout,
+		Run: func(ctx context.Context) error {
+			feedPollsQueued.Delete(id)
+			return s.PollSubscription(ctx, id)
+		},
+	})
+	if err != nil {
+		feedPollsQueued.Delete(id)
+	}
+	return err
+}
+
+// poll fetches a subscription's feed and imports its new entries. Feeds that can't be fetched or read
+// are recorded on the subscription.
+func (s *feedSubscriptionServiceImpl) poll(ctx context.Context, subscription *models.FeedSubscription) (*FeedPollResult, error) {
+	if _, locked := feedPollLocks.LoadOrStore(subscription.ID, true); locked {
+		return nil, ErrFeedPollInProgress
+	}
+	defer feedPollLocks.Delete(subscription.ID)
+
+	fetched, err := s.fetchFeed(ctx, subscription.FeedURL, subscription.ETag, subscription.LastModified)
+	if err != nil {
+		return nil, s.recordPollError(ctx, subscription, fmt.Errorf("%w: %v", ErrFeedPollFailed, err))
+	}
+	if fetched.notModified {
+		now := s.now()
+		if err := s.db.WithContext(ctx).Model(subscription).Updates(map[string]interface{}{"last_polled_at": now, "last_error": ""}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update feed subscription: %w", err)
+		}
+		return &FeedPollResult{NotModified: true}, nil
+	}
+
+	feed, err := parseFeed(fetched.body, fetched.contentType, fetched.url)
+	if err != nil {
+		return nil, s.recordPollError(ctx, subscription, fmt.Errorf("%w: %v", ErrFeedPollFailed, err))
+	}
+	return s.importFeed(ctx, subscription, fetched, feed)
+}
+
+// recordPollError shows why the last poll failed on the subscription and returns the error
+func (s *feedSubscriptionServiceImpl) recordPollError(ctx context.Context, subscription *models.FeedSubscription, pollErr error) error {
+	now := s.now()
+	subscription.LastPolledAt = &now
+	subscription.LastError = truncateLinkText(pollErr.Error(), 1000)
+	if err := s.db.WithContext(ctx).Model(subscription).Updates(map[string]interface{}{
+		"last_polled_at": subscription.LastPolledAt,
+		"last_error":     subscription.LastError,
+	}).Error; err != nil {
+		log.Error().Err(err).Str("subscription_id", subscription.ID).Msg("Failed to record feed poll error")
+	}
+	log.Warn().Err(pollErr).Str("subscription_id", subscription.ID).Msg("Feed poll failed")
+	return pollErr
+}
+
+// importFeed imports the entries of a feed the subscription hasn't seen. Entries the keywords leave
+// out are recorded as filtered; on the first poll only the latest few are imported and the rest skipped.
+func (s *feedSubscriptionServiceImpl) importFeed(ctx context.Context, subscription *models.FeedSubscription, fetched *feedFetch, feed *parsedFeed) (*FeedPollResult, error) {
+	var chapter *models.Chapter
+	if subscription.Target == models.FeedTargetNote && subscription.ChapterID != nil {
+		chapter = &models.Chapter{}
+		err := s.db.WithContext(ctx).Preload("Notebook").Where("id = ?", *subscription.ChapterID).First(chapter).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, s.recordPollError(ctx, subscription, ErrChapterNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+		}
+		if chapter.Notebook.Encrypted {
+			return nil, s.recordPollError(ctx, subscription, ErrNotebookEncrypted)
+		}
+	}
+
+	keys := make([]string, 0, len(feed.entries))
+	for _, entry := range feed.entries {
+		keys = append(keys, entry.key)
+	}
+	var seenKeys []string
+	if len(keys) > 0 {
+		if err := s.db.WithContext(ctx).Model(&models.FeedEntry{}).Where("subscription_id = ? AND entry_key IN ?", subscription.ID, keys).
+			Pluck("entry_key", &seenKeys).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch feed entries: %w", err)
+		}
+	}
+	seen := make(map[string]bool, len(seenKeys))
+	for _, key := range seenKeys {
+		seen[key] = true
+	}
+
+	limit := maxFeedEntriesPerPoll
+	if subscription.LastPolledAt == nil {
+		limit = feedFirstPollEntries
+	}
+	include := feedKeywords(subscription.IncludeKeywords)
+	exclude := feedKeywords(subscription.ExcludeKeywords)
+
+	result := &FeedPollResult{}
+	var toImport []parsedFeedEntry
+	var passed []models.FeedEntry
+	for _, entry := range feed.entries {
+		if seen[entry.key] {
+			continue
+		}
+		seen[entry.key] = true
+
+		status := models.FeedEntryStatusSkipped
+		switch {
+		case !feedEntryMatches(entry, include, exclude):
+			status = models.FeedEntryStatusFiltered
+			result.Filtered++
+		case len(toImport) < limit:
+			toImport = append(toImport, entry)
+			continue
+		default:
+			result.Skipped++
+		}
+		passed = append(passed, feedEntryRecord(subscription.ID, entry, status))
+	}
+
+	if len(passed) > 0 {
+		if err := s.db.WithContext(ctx).Create(&passed).Error; err != nil {
+			return nil, fmt.Errorf("failed to record feed entries: %w", err)
+		}
+	}
+
+	// Feeds list their latest entries first, import the oldest first so notes are created in order
+	for i := len(toImport) - 1; i >= 0; i-- {
+		if err := s.importEntry(ctx, subscription, chapter, feed, toImport[i]); err != nil {
+			return result, s.recordPollError(ctx, subscription, err)
+		}
+		result.Imported++
+	}
+
+	now := s.now()
+	subscription.LastPolledAt = &now
+	subscription.LastError = ""
+	subscription.ETag = fetched.etag
+	subscription.LastModified = fetched.lastModified
+	if feed.title != "" {
+		subscription.Title = feed.title
+	}
+	if feed.siteURL != "" {
+		subscription.SiteURL = feed.siteURL
+	}
+	if err := s.db.WithContext(ctx).Model(subscription).Updates(map[string]interface{}{
+		"last_polled_at": subscription.LastPolledAt,
+		"last_error":     "",
+		"e_tag":          subscription.ETag,
+		"last_modified":  subscription.LastModified,
+		"title":          subscription.Title,
+		"site_url":       subscription.SiteURL,
+	}).Error; err != nil {
+		return result, fmt.Errorf("failed to update feed subscription: %w", err)
+	}
+
+	if result.Imported > 0 {
+		log.Info().Str("subscription_id", subscription.ID).Int("imported", result.Imported).Int("filtered", result.Filtered).Msg("Imported feed entries")
+	}
+	return result, nil
+}
+
+// importEntry turns a feed entry into a note in the subscription's chapter, or a reading list item
+func (s *feedSubscriptionServiceImpl) importEntry(ctx context.Context, subscription *models.FeedSubscription, chapter *models.Chapter, feed *parsedFeed, entry parsedFeedEntry) error {
+	record := feedEntryRecord(subscription.ID, entry, models.FeedEntryStatusImported)
+
+	if subscription.Target == models.FeedTargetReadingList {
+		if entry.url == "" {
+			record.Status = models.FeedEntryStatusSkipped
+		} else {
+			item, err := s.readingList.Save(ctx, subscription.ClerkUserID, ReadingListClip{
+				URL:     entry.url,
+				Title:   entry.title,
+				Excerpt: strings.ReplaceAll(entry.summary, "\n\n", " "),
+			}, models.ReadingListSourceFeed)
+			if err != nil && !errors.Is(err, ErrInvalidReadingListItem) {
+				return err
+			}
+			if item != nil {
+				record.ReadingListItemID = &item.ID
+			} else {
+				record.Status = models.FeedEntryStatusSkipped
+			}
+		}
+		if err := s.db.WithContext(ctx).Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to record feed entry: %w", err)
+		}
+		return nil
+	}
+
+	if chapter == nil {
+		return ErrChapterNotFound
+	}
+	content, err := feedEntryNoteContent(feed, entry)
+	if err != nil {
+		return err
+	}
+	note := models.Notes{
+		Name:           truncateLinkText(entry.title, 255),
+		Content:        AssignTipTapBlockIDs("", ApplyChapterNoteDefaults(chapter, content)),
+		ChapterID:      chapter.ID,
+		OrganizationID: chapter.OrganizationID,
+		Status:         models.NoteStatusDraft,
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return fmt.Errorf("failed to create note: %w", err)
+		}
+		record.NoteID = &note.ID
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to record feed entry: %w", err)
+		}
+		return nil
+	})
+}
+
+// applyInput validates a subscription's target, chapter, keywords and poll interval and sets them on the model
+func (s *feedSubscriptionServiceImpl) applyInput(ctx context.Context, subscription *models.FeedSubscription, input FeedSubscriptionInput) error {
+	target := strings.TrimSpace(input.Target)
+	if target == "" {
+		target = models.FeedTargetNote
+		if subscription.Target != "" {
+			target = subscription.Target
+		}
+	}
+
+	var chapterID *string
+	switch target {
+	case models.FeedTargetNote:
+		if input.ChapterID != nil && strings.TrimSpace(*input.ChapterID) != "" {
+			id := strings.TrimSpace(*input.ChapterID)
+			chapterID = &id
+		} else {
+			chapterID = subscription.ChapterID
+		}
+		if chapterID == nil {
+			return fmt.Errorf("%w: a chapter is required for feeds that create notes", ErrInvalidFeedSubscription)
+		}
+
+		var chapter models.Chapter
+		err := s.db.WithContext(ctx).Preload("Notebook").Where("id = ?", *chapterID).First(&chapter).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrChapterNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch chapter: %w", err)
+		}
+		if chapter.Notebook.Encrypted {
+			return ErrNotebookEncrypted
+		}
+	case models.FeedTargetReadingList:
+	default:
+		return fmt.Errorf("%w: target must be %q or %q", ErrInvalidFeedSubscription, models.FeedTargetNote, models.FeedTargetReadingList)
+	}
+
+	include, err := normalizeFeedKeywords(input.IncludeKeywords)
+	if err != nil {
+		return err
+	}
+	exclude, err := normalizeFeedKeywords(input.ExcludeKeywords)
+	if err != nil {
+		return err
+	}
+
+	interval := input.PollIntervalMinutes
+	if interval == 0 {
+		interval = defaultFeedPollInterval
+		if subscription.PollIntervalMinutes != 0 {
+			interval = subscription.PollIntervalMinutes
+		}
+	}
+	if interval < minFeedPollInterval || interval > maxFeedPollInterval {
+		return fmt.Errorf("%w: poll interval must be between %d and %d minutes", ErrInvalidFeedSubscription, minFeedPollInterval, maxFeedPollInterval)
+	}
+
+	var duplicates int64
+	query := s.db.WithContext(ctx).Model(&models.FeedSubscription{}).
+		Where("clerk_user_id = ? AND feed_url = ? AND target = ? AND id <> ?", subscription.ClerkUserID, subscription.FeedURL, target, subscription.ID)
+	if chapterID != nil {
+		query = query.Where("chapter_id = ?", *chapterID)
+	}
+	if err := query.Count(&duplicates).Error; err != nil {
+		return fmt.Errorf("failed to check feed subscriptions: %w", err)
+	}
+	if duplicates > 0 {
+		return ErrFeedSubscriptionExists
+	}
+
+	subscription.Target = target
+	subscription.ChapterID = chapterID
+	subscription.IncludeKeywords = strings.Join(include, "\n")
+	subscription.ExcludeKeywords = strings.Join(exclude, "\n")
+	subscription.PollIntervalMinutes = interval
+	return nil
+}
+
+// get returns one of the user's subscriptions
+func (s *feedSubscriptionServiceImpl) get(ctx context.Context, id, clerkUserID string) (*models.FeedSubscription, error) {
+	var subscription models.FeedSubscription
+	err := s.db.WithContext(ctx).Where("id = ? AND clerk_user_id = ?", id, clerkUserID).First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrFeedSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+// detail returns a single subscription with its details
+func (s *feedSubscriptionServiceImpl) detail(ctx context.Context, subscription *models.FeedSubscription) (*FeedSubscriptionDetails, error) {
+	details, err := s.details(ctx, []models.FeedSubscription{*subscription})
+	if err != nil {
+		return nil, err
+	}
+	return &details[0], nil
+}
+
+// details adds to subscriptions their keywords, the names of their chapters and how many entries they imported
+func (s *feedSubscriptionServiceImpl) details(ctx context.Context, subscriptions []models.FeedSubscription) ([]FeedSubscriptionDetails, error) {
+	details := make([]FeedSubscriptionDetails, len(subscriptions))
+	if len(subscriptions) == 0 {
+		return details, nil
+	}
+
+	ids := make([]string, 0, len(subscriptions))
+	var chapterIDs []string
+	for _, subscription := range subscriptions {
+		ids = append(ids, subscription.ID)
+		if subscription.ChapterID != nil {
+			chapterIDs = append(chapterIDs, *subscription.ChapterID)
+		}
+	}
+
+	chapterNames := make(map[string]string)
+	if len(chapterIDs) > 0 {
+		var chapters []models.Chapter
+		if err := s.db.WithContext(ctx).Select("id", "name").Where("id IN ?", chapterIDs).Find(&chapters).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch chapters: %w", err)
+		}
+		for _, chapter := range chapters {
+			chapterNames[chapter.ID] = chapter.Name
+		}
+	}
+
+	var counts []struct {
+		SubscriptionID string
+		Count          int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.FeedEntry{}).Select("subscription_id, COUNT(*) AS count").
+		Where("subscription_id IN ? AND status = ?", ids, models.FeedEntryStatusImported).Group("subscription_id").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count feed entries: %w", err)
+	}
+	imported := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		imported[count.SubscriptionID] = count.Count
+	}
+
+	for i, subscription := range subscriptions {
+		details[i] = FeedSubscriptionDetails{
+			FeedSubscription: subscription,
+			IncludeKeywords:  feedKeywords(subscription.IncludeKeywords),
+			ExcludeKeywords:  feedKeywords(subscription.ExcludeKeywords),
+			ImportedEntries:  imported[subscription.ID],
+		}
+		if subscription.ChapterID != nil {
+			details[i].ChapterName = chapterNames[*subscription.ChapterID]
+		}
+	}
+	return details, nil
+}
+
+// normalizeFeedKeywords collapses the whitespace of keywords and drops empty and repeated ones
+func normalizeFeedKeywords(input []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var keywords []string
+	for _, keyword := range input {
+		keyword = strings.Join(strings.Fields(keyword), " ")
+		if keyword == "" || seen[strings.ToLower(keyword)] {
+			continue
+		}
+		if utf8.RuneCountInString(keyword) > maxFeedKeywordLength {
+			return nil, fmt.Errorf("%w: keywords can be at most %d characters", ErrInvalidFeedSubscription, maxFeedKeywordLength)
+		}
+		seen[strings.ToLower(keyword)] = true
+		keywords = append(keywords, keyword)
+	}
+	if len(keywords) > maxFeedKeywords {
+		return nil, fmt.Errorf("%w: a feed can have at most %d keywords of each kind", ErrInvalidFeedSubscription, maxFeedKeywords)
+	}
+	return keywords, nil
+}
+
+// feedKeywords splits stored keywords
+func feedKeywords(stored string) []string {
+	keywords := []string{}
+	for _, keyword := range strings.Split(stored, "\n") {
+		if keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// feedEntryMatches reports whether an entry's title or summary mentions one of the include keywords,
+// when there are any, and none of the exclude keywords. Keywords match regardless of case.
+func feedEntryMatches(entry parsedFeedEntry, include, exclude []string) bool {
+	text := strings.ToLower(entry.title + "\n" + entry.summary)
+	for _, keyword := range exclude {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, keyword := range include {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// feedEntryRecord is the record of an entry the subscription has seen
+func feedEntryRecord(subscriptionID string, entry parsedFeedEntry, status string) models.FeedEntry {
+	return models.FeedEntry{
+		SubscriptionID: subscriptionID,
+		EntryKey:       entry.key,
+		Title:          entry.title,
+		URL:            entry.url,
+		PublishedAt:    entry.published,
+		Status:         status,
+	}
+}
+
+// feedEntryNoteContent builds the TipTap document of a note created from a feed entry
+func feedEntryNoteContent(feed *parsedFeed, entry parsedFeedEntry) (string, error) {
+	paragraph := func(text string, marks ...utils.TipTapMark) utils.TipTapNode {
+		return utils.TipTapNode{Type: "paragraph", Content: []utils.TipTapNode{{Type: "text", Text: text, Marks: marks}}}
+	}
+
+	var nodes []utils.TipTapNode
+	if entry.url != "" {
+		nodes = append(nodes, paragraph(entry.url, utils.TipTapMark{Type: "link", Attrs: map[string]interface{}{"href": entry.url}}))
+	}
+	var details []string
+	if feed.title != "" {
+		details = append(details, feed.title)
+	}
+	if entry.published != nil {
+		details = append(details, "Published on "+entry.published.Format("January 2, 2006"))
+	}
+	if len(details) > 0 {
+		nodes = append(nodes, paragraph(strings.Join(details, " · "), utils.TipTapMark{Type: "italic"}))
+	}
+	if entry.summary != "" {
+		for _, text := range strings.Split(entry.summary, "\n\n") {
+			nodes = append(nodes, paragraph(text))
+		}
+	}
+
+	content, err := json.Marshal(utils.TipTapDoc{Type: "doc", Content: nodes})
+	if err != nil {
+		return "", fmt.Errorf("failed to build note content: %w", err)
+	}
+	return string(content), nil
+}
+
+// fetchFeedURL downloads a feed, asking the server to skip it when it hasn't changed since the last poll
+func fetchFeedURL(ctx context.Context, client *http.Client, rawURL, etag, lastModified string) (*feedFetch, error) {
+	normalized, err := normalizeLinkURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, normalized, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLinkURL, err)
+	}
+	req.Header.Set("User-Agent", linkPreviewUserAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/rdf+xml;q=0.9, application/xml;q=0.8, text/xml;q=0.8, */*;q=0.5")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrLinkPreviewBlocked) {
+			return nil, ErrLinkPreviewBlocked
+		}
+		return nil, fmt.Errorf("%w: %v", ErrLinkPreviewFetchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return &feedFetch{url: resp.Request.URL, etag: etag, lastModified: lastModified, notModified: true}, nil
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: status %d", ErrLinkPreviewFetchFailed, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, feedMaxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLinkPreviewFetchFailed, err)
+	}
+	return &feedFetch{
+		body:         body,
+		url:          resp.Request.URL,
+		contentType:  resp.Header.Get("Content-Type"),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// parseFeed reads an RSS or Atom feed. Relative links are resolved against the feed's URL.
+func parseFeed(body []byte, contentType string, base *url.URL) (*parsedFeed, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	var doc feedXMLDocument
+	if err := decoder.Decode(&doc); err != nil {
+		if strings.Contains(contentType, "html") {
+			return nil, errors.New("the URL is a web page, not an RSS or Atom feed")
+		}
+		return nil, errors.New("the feed isn't valid XML")
+	}
+
+	feed := &parsedFeed{}
+	var items []feedXMLEntry
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss":
+		feed.title = doc.Channel.Title.plain()
+		feed.siteURL = feedLink(doc.Channel.Links, base)
+		items = doc.Channel.Items
+	case "rdf":
+		feed.title = doc.Channel.Title.plain()
+		feed.siteURL = feedLink(doc.Channel.Links, base)
+		items = doc.Items
+	case "feed":
+		feed.title = doc.Title.plain()
+		feed.siteURL = feedLink(doc.Links, base)
+		items = doc.Entries
+	default:
+		return nil, errors.New("the URL isn't an RSS or Atom feed")
+	}
+	feed.title = truncateLinkText(feed.title, 300)
+
+	for _, item := range items {
+		entry := parsedFeedEntry{
+			title: truncateLinkText(item.Title.plain(), 300),
+			url:   feedLink(item.Links, base),
+		}
+		for _, text := range []feedXMLText{item.Summary, item.Description, item.Content, item.Encoded} {
+			if entry.summary = text.paragraphs(); entry.summary != "" {
+				break
+			}
+		}
+		for _, value := range []string{item.Published, item.PubDate, item.Date, item.Updated} {
+			if published, ok := parseFeedDate(value); ok {
+				entry.published = &published
+				break
+			}
+		}
+		if entry.title == "" {
+			entry.title = truncateLinkText(strings.SplitN(entry.summary, "\n\n", 2)[0], 100)
+		}
+		if entry.title == "" {
+			entry.title = readingListHost(entry.url)
+		}
+
+		id := strings.TrimSpace(item.GUID)
+		if id == "" {
+			id = strings.TrimSpace(item.ID)
+		}
+		if id == "" {
+			id = entry.url
+		}
+		if id == "" {
+			id = entry.title + "\n" + strings.TrimSpace(item.PubDate+item.Published+item.Date+item.Updated)
+		}
+		sum := sha256.Sum256([]byte(id))
+		entry.key = hex.EncodeToString(sum[:])
+		feed.entries = append(feed.entries, entry)
+	}
+	return feed, nil
+}
+
+// feedLink picks a feed's or entry's page: an RSS link's text or an Atom link to the alternate version
+func feedLink(links []feedXMLLink, base *url.URL) string {
+	for _, link := range links {
+		href := strings.TrimSpace(link.Text)
+		if link.Href != "" {
+			if link.Rel != "" && link.Rel != "alternate" {
+				continue
+			}
+			href = strings.TrimSpace(link.Href)
+		}
+		if href == "" {
+			continue
+		}
+		parsed, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		if base != nil {
+			parsed = base.ResolveReference(parsed)
+		}
+		if parsed.Scheme == "http" || parsed.Scheme == "https" {
+			return parsed.String()
+		}
+	}
+	return ""
+}
+
+// parseFeedDate reads a date in any of the formats feeds use
+func parseFeedDate(value string) (time.Time, bool) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range feedDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// plain returns the text on a single line, without markup
+func (t feedXMLText) plain() string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(t.paragraphs(), "\n\n", " ")), " ")
+}
+
+// paragraphs returns the text without markup, paragraphs separated by blank lines
+func (t feedXMLText) paragraphs() string {
+	text := t.Text
+	if strings.EqualFold(t.Type, "xhtml") {
+		text = t.Inner
+	}
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return normalizeReadingText(extractReadingText(strings.NewReader(text)))
+}
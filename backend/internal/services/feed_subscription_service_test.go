@@ -0,0 +1,305 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testFeed serves a feed to the service, counting fetches and answering conditional requests
+type testFeed struct {
+	body        string
+	contentType string
+	etag        string
+	fetches     int
+}
+
+func setupTestFeedSubscriptionService(t *testing.T, feed *testFeed) (*feedSubscriptionServiceImpl, models.Chapter) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.FeedSubscription{}, &models.FeedEntry{},
+		&models.ReadingListItem{}, &models.ReadingListHighlight{}), "Failed to migrate test database")
+
+	notebook := models.Notebook{Name: "Research", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Industry news", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+
+	now := func() time.Time { return time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC) }
+	return &feedSubscriptionServiceImpl{
+		db:    db,
+		queue: NewJobQueue(1, 10),
+		readingList: &readingListServiceImpl{
+			db:    db,
+			queue: NewJobQueue(1, 10),
+			fetchPage: func(ctx context.Context, rawURL string) (*readingPage, error) {
+				return nil, ErrLinkPreviewFetchFailed
+			},
+			now: now,
+		},
+		fetchFeed: func(ctx context.Context, rawURL, etag, lastModified string) (*feedFetch, error) {
+			feed.fetches++
+			base, _ := url.Parse(rawURL)
+			if feed.etag != "" && etag == feed.etag {
+				return &feedFetch{url: base, etag: etag, notModified: true}, nil
+			}
+			return &feedFetch{body: []byte(feed.body), url: base, contentType: feed.contentType, etag: feed.etag}, nil
+		},
+		canEditChapter: func(ctx context.Context, db *gorm.DB, chapterID, clerkUserID string) (bool, error) {
+			return clerkUserID == "user_1", nil
+		},
+		now: now,
+	}, chapter
+}
+
+// testRSSFeed builds an RSS feed with items numbered from first to last, the latest first
+func testRSSFeed(first, last int) string {
+	var items strings.Builder
+	for i := last; i >= first; i-- {
+		fmt.Fprintf(&items, `<item><title>Release %d</title><link>https://example.com/posts/%d</link><guid>post-%d</guid>
+<description>&lt;p&gt;Notes on release %d of the database.&lt;/p&gt;</description><pubDate>Mon, 0%d May 2026 08:00:00 GMT</pubDate></item>`,
+			i, i, i, i, i%10)
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Example Blog</title><link>https://example.com/</link>` +
+		items.String() + `</channel></rss>`
+}
+
+func TestFeedSubscriptionService_Subscribe(t *testing.T) {
+	feed := &testFeed{body: testRSSFeed(1, 7), contentType: "application/rss+xml"}
+	service, chapter := setupTestFeedSubscriptionService(t, feed)
+	ctx := context.Background()
+
+	subscription, err := service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "example.com/feed.xml", ChapterID: &chapter.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/feed.xml", subscription.FeedURL)
+	assert.Equal(t, models.FeedTargetNote, subscription.Target)
+	assert.Equal(t, "Example Blog", subscription.Title)
+	assert.Equal(t, "https://example.com/", subscription.SiteURL)
+	assert.Equal(t, "Industry news", subscription.ChapterName)
+	assert.Equal(t, defaultFeedPollInterval, subscription.PollIntervalMinutes)
+	assert.True(t, subscription.Enabled)
+	require.NotNil(t, subscription.LastPolledAt)
+	assert.Equal(t, int64(feedFirstPollEntries), subscription.ImportedEntries, "Only the latest entries are imported when subscribing")
+
+	var notes []models.Notes
+	require.NoError(t, service.db.Where("chapter_id = ?", chapter.ID).Order("created_at, id").Find(&notes).Error)
+	require.Len(t, notes, feedFirstPollEntries)
+	names := make([]string, len(notes))
+	for i, note := range notes {
+		names[i] = note.Name
+	}
+	assert.ElementsMatch(t, []string{"Release 3", "Release 4", "Release 5", "Release 6", "Release 7"}, names)
+
+	markdown, err := utils.TipTapToMarkdown(notes[0].Content)
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "https://example.com/posts/")
+	assert.Contains(t, markdown, "Example Blog · Published on May")
+	assert.Contains(t, markdown, "of the database.")
+
+	var skipped int64
+	require.NoError(t, service.db.Model(&models.FeedEntry{}).Where("status = ?", models.FeedEntryStatusSkipped).Count(&skipped).Error)
+	assert.Equal(t, int64(2), skipped)
+
+	_, err = service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/feed.xml", ChapterID: &chapter.ID})
+	assert.ErrorIs(t, err, ErrFeedSubscriptionExists)
+	reading, err := service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/feed.xml", Target: models.FeedTargetReadingList})
+	require.NoError(t, err, "The same feed can go to the reading list too")
+	assert.Nil(t, reading.ChapterID)
+}
+
+func TestFeedSubscriptionService_Subscribe_Errors(t *testing.T) {
+	feed := &testFeed{body: testRSSFeed(1, 1)}
+	service, chapter := setupTestFeedSubscriptionService(t, feed)
+	ctx := context.Background()
+
+	_, err := service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/feed.xml"})
+	assert.ErrorIs(t, err, ErrInvalidFeedSubscription, "Note feeds need a chapter")
+	_, err = service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/feed.xml", ChapterID: &chapter.ID, Target: "email"})
+	assert.ErrorIs(t, err, ErrInvalidFeedSubscription)
+	_, err = service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/feed.xml", ChapterID: &chapter.ID, PollIntervalMinutes: 5})
+	assert.ErrorIs(t, err, ErrInvalidFeedSubscription)
+	_, err = service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "ftp://example.com/feed.xml", ChapterID: &chapter.ID})
+	assert.ErrorIs(t, err, ErrInvalidFeedSubscription)
+	missing := "missing"
+	_, err = service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/feed.xml", ChapterID: &missing})
+	assert.ErrorIs(t, err, ErrChapterNotFound)
+
+	feed.body, feed.contentType = "<html><body><p>Welcome</p></body></html>", "text/html"
+	_, err = service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/", ChapterID: &chapter.ID})
+	assert.ErrorIs(t, err, ErrInvalidFeedSubscription, "Web pages aren't feeds")
+
+	encrypted := models.Notebook{Name: "Private", ClerkUserID: "user_1", Encrypted: true}
+	require.NoError(t, service.db.Create(&encrypted).Error)
+	private := models.Chapter{Name: "Private", NotebookID: encrypted.ID}
+	require.NoError(t, service.db.Create(&private).Error)
+	_, err = service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/feed.xml", ChapterID: &private.ID})
+	assert.ErrorIs(t, err, ErrNotebookEncrypted)
+
+	var count int64
+	require.NoError(t, service.db.Model(&models.FeedSubscription{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestFeedSubscriptionService_Poll(t *testing.T) {
+	feed := &testFeed{body: testRSSFeed(1, 2), etag: `"v1"`}
+	service, chapter := setupTestFeedSubscriptionService(t, feed)
+	ctx := context.Background()
+
+	subscription, err := service.Subscribe(ctx, "user_1", FeedSubscriptionInput{
+		FeedURL:         "https://example.com/feed.xml",
+		ChapterID:       &chapter.ID,
+		IncludeKeywords: []string{" release  5 ", "Release 6", "release 5", "release 8"},
+		ExcludeKeywords: []string{"RELEASE 8"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"release 5", "Release 6", "release 8"}, subscription.IncludeKeywords)
+	assert.Equal(t, []string{"RELEASE 8"}, subscription.ExcludeKeywords)
+	assert.Zero(t, subscription.ImportedEntries, "Neither entry mentions a keyword")
+
+	result, err := service.Poll(ctx, subscription.ID, "user_1")
+	require.NoError(t, err)
+	assert.True(t, result.NotModified, "The feed's ETag is sent back")
+
+	feed.body, feed.etag = testRSSFeed(1, 8), `"v2"`
+	result, err = service.Poll(ctx, subscription.ID, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, FeedPollResult{Imported: 2, Filtered: 4}, *result, "Release 8 is excluded and entries already seen aren't counted")
+
+	var names []string
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("chapter_id = ?", chapter.ID).Order("name").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"Release 5", "Release 6"}, names)
+
+	feed.etag = `"v3"`
+	result, err = service.Poll(ctx, subscription.ID, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, FeedPollResult{}, *result, "Entries are only imported once")
+
+	var stored models.FeedSubscription
+	require.NoError(t, service.db.First(&stored, "id = ?", subscription.ID).Error)
+	assert.Equal(t, `"v3"`, stored.ETag)
+
+	feed.body, feed.etag = "not a feed", ""
+	_, err = service.Poll(ctx, subscription.ID, "user_1")
+	assert.ErrorIs(t, err, ErrFeedPollFailed)
+	require.NoError(t, service.db.First(&stored, "id = ?", subscription.ID).Error)
+	assert.NotEmpty(t, stored.LastError)
+
+	_, err = service.Poll(ctx, subscription.ID, "user_2")
+	assert.ErrorIs(t, err, ErrFeedSubscriptionNotFound)
+}
+
+func TestFeedSubscriptionService_ReadingList(t *testing.T) {
+	feed := &testFeed{body: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title type="html">Team &lt;b&gt;Updates&lt;/b&gt;</title>
+  <link rel="self" href="/feed.atom"/>
+  <link href="/"/>
+  <entry>
+    <title>Shipping search</title>
+    <link rel="alternate" href="/posts/search"/>
+    <id>tag:example.com,2026:search</id>
+    <updated>2026-05-01T10:00:00Z</updated>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Search is live.</p><p>Try it out.</p></div></content>
+  </entry>
+</feed>`}
+	service, _ := setupTestFeedSubscriptionService(t, feed)
+	ctx := context.Background()
+
+	subscription, err := service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/feed.atom", Target: models.FeedTargetReadingList})
+	require.NoError(t, err)
+	assert.Equal(t, "Team Updates", subscription.Title)
+	assert.Equal(t, "https://example.com/", subscription.SiteURL)
+	assert.Equal(t, int64(1), subscription.ImportedEntries)
+
+	var item models.ReadingListItem
+	require.NoError(t, service.db.First(&item).Error)
+	assert.Equal(t, "https://example.com/posts/search", item.URL)
+	assert.Equal(t, "Shipping search", item.Title)
+	assert.Equal(t, "Search is live. Try it out.", item.Excerpt)
+	assert.Equal(t, models.ReadingListSourceFeed, item.Source)
+
+	entries, err := service.ListEntries(ctx, subscription.ID, "user_1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NotNil(t, entries[0].ReadingListItemID)
+	assert.Equal(t, item.ID, *entries[0].ReadingListItemID)
+	require.NotNil(t, entries[0].PublishedAt)
+	assert.True(t, time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC).Equal(*entries[0].PublishedAt))
+
+	require.NoError(t, service.Unsubscribe(ctx, subscription.ID, "user_1"))
+	assert.ErrorIs(t, service.Unsubscribe(ctx, subscription.ID, "user_1"), ErrFeedSubscriptionNotFound)
+	var count int64
+	require.NoError(t, service.db.Model(&models.FeedEntry{}).Count(&count).Error)
+	assert.Zero(t, count)
+	require.NoError(t, service.db.Model(&models.ReadingListItem{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count, "Items created from the feed are kept")
+}
+
+func TestFeedSubscriptionService_Schedule(t *testing.T) {
+	feed := &testFeed{body: testRSSFeed(1, 1)}
+	service, chapter := setupTestFeedSubscriptionService(t, feed)
+	ctx := context.Background()
+
+	subscription, err := service.Subscribe(ctx, "user_1", FeedSubscriptionInput{FeedURL: "https://example.com/feed.xml", ChapterID: &chapter.ID})
+	require.NoError(t, err)
+
+	polled := service.now()
+	assert.Zero(t, service.QueueDuePolls(polled.Add(30*time.Minute)))
+	assert.Equal(t, 1, service.QueueDuePolls(polled.Add(time.Hour)))
+	feedPollsQueued.Delete(subscription.ID)
+
+	disabled := false
+	updated, err := service.Update(ctx, subscription.ID, "user_1", FeedSubscriptionInput{Enabled: &disabled, PollIntervalMinutes: 30})
+	require.NoError(t, err)
+	assert.False(t, updated.Enabled)
+	assert.Equal(t, 30, updated.PollIntervalMinutes)
+	assert.Equal(t, chapter.ID, *updated.ChapterID, "Updates keep the chapter unless another is given")
+	assert.Zero(t, service.QueueDuePolls(polled.Add(time.Hour)))
+
+	// Subscriptions to chapters the user lost access to are disabled rather than polled
+	enabled := true
+	_, err = service.Update(ctx, subscription.ID, "user_1", FeedSubscriptionInput{Enabled: &enabled})
+	require.NoError(t, err)
+	require.NoError(t, service.db.Model(&models.FeedSubscription{}).Where("id = ?", subscription.ID).Update("clerk_user_id", "user_2").Error)
+	fetches := feed.fetches
+	require.NoError(t, service.PollSubscription(ctx, subscription.ID))
+	assert.Equal(t, fetches, feed.fetches)
+	var stored models.FeedSubscription
+	require.NoError(t, service.db.First(&stored, "id = ?", subscription.ID).Error)
+	assert.False(t, stored.Enabled)
+	assert.NotEmpty(t, stored.LastError)
+
+	require.NoError(t, service.PollSubscription(ctx, "missing"), "Unsubscribed feeds are ignored")
+}
+
+func TestParseFeed_RDF(t *testing.T) {
+	base, _ := url.Parse("https://example.org/index.rdf")
+	feed, err := parseFeed([]byte(`<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel><title>Old School</title><link>https://example.org/</link></channel>
+  <item><title>First</title><link>https://example.org/first</link><description>Hello &amp; welcome</description><dc:date>2026-04-02T08:30:00+02:00</dc:date></item>
+  <item><description>An untitled entry without a link</description></item>
+</rdf:RDF>`), "application/rdf+xml", base)
+	require.NoError(t, err)
+	assert.Equal(t, "Old School", feed.title)
+	require.Len(t, feed.entries, 2)
+	assert.Equal(t, "https://example.org/first", feed.entries[0].url)
+	assert.Equal(t, "Hello & welcome", feed.entries[0].summary)
+	require.NotNil(t, feed.entries[0].published)
+	assert.True(t, time.Date(2026, 4, 2, 6, 30, 0, 0, time.UTC).Equal(*feed.entries[0].published))
+	assert.Equal(t, "An untitled entry without a link", feed.entries[1].title)
+	assert.NotEqual(t, feed.entries[0].key, feed.entries[1].key)
+
+	_, err = parseFeed([]byte(`<html><body>Not a feed</body></html>`), "text/html", base)
+	assert.Error(t, err)
+}
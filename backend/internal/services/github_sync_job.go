@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// GitHubSyncJob periodically queues syncs for integrations on a schedule
+type GitHubSyncJob struct {
+	syncService GitHubSyncService
+	interval    time.Duration
+	stopChan    chan struct{}
+}
+
+// NewGitHubSyncJob creates a new scheduled sync job
+func NewGitHubSyncJob(syncService GitHubSyncService, interval time.Duration) *GitHubSyncJob {
+	return &GitHubSyncJob{
+		syncService: syncService,
+		interval:    interval,
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start begins checking for due syncs
+func (j *GitHubSyncJob) Start(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Msg("Starting GitHub sync scheduler")
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if queued := j.syncService.QueueDueSyncs(time.Now()); queued > 0 {
+				log.Info().Int("queued", queued).Msg("Queued scheduled GitHub syncs")
+			}
+		case <-ctx.Done():
+			log.Info().Msg("Stopping GitHub sync scheduler (context cancelled)")
+			return
+		case <-j.stopChan:
+			log.Info().Msg("Stopping GitHub sync scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (j *GitHubSyncJob) Stop() {
+	close(j.stopChan)
+}
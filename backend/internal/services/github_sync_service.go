@@ -0,0 +1,826 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	internalutils "backend/internal/utils"
+	"backend/pkg/github"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidGitHubIntegration is returned when integration settings are incomplete or can't be used
+	ErrInvalidGitHubIntegration = errors.New("invalid GitHub integration")
+	// ErrGitHubIntegrationNotFound is returned when no integration is configured
+	ErrGitHubIntegrationNotFound = errors.New("GitHub integration not found")
+	// ErrGitHubSyncInProgress is returned when the integration is already syncing
+	ErrGitHubSyncInProgress = errors.New("GitHub sync already in progress")
+)
+
+// GitHub sync triggers
+const (
+	GitHubSyncTriggerManual   = "manual"
+	GitHubSyncTriggerSchedule = "schedule"
+	GitHubSyncTriggerPublish  = "publish"
+)
+
+const (
+	defaultGitHubSyncInterval = 60
+	minGitHubSyncInterval     = 15
+	maxGitHubSyncInterval     = 7 * 24 * 60
+	githubSyncJobTimeout      = 10 * time.Minute
+)
+
+var githubRepositoryPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+var (
+	// githubSyncLocks prevents two syncs of the same integration from running at once
+	githubSyncLocks sync.Map
+	// githubSyncQueued tracks integrations with a sync waiting in the job queue
+	githubSyncQueued sync.Map
+)
+
+// githubAPI is the part of the GitHub API used for syncing
+type githubAPI interface {
+	GetRepository(ctx context.Context, repo string) (*github.Repository, error)
+	GetFile(ctx context.Context, repo, path, branch string) (*github.File, error)
+	PutFile(ctx context.Context, repo, path string, req github.PutFileRequest) (string, error)
+	DeleteFile(ctx context.Context, repo, path string, req github.DeleteFileRequest) error
+}
+
+// GitHubOwner identifies whose integration is being managed: an organization's, or a user's personal one
+type GitHubOwner struct {
+	ClerkUserID    string
+	OrganizationID *string
+}
+
+// isOrganization reports whether the owner is an organization
+func (o GitHubOwner) isOrganization() bool {
+	return o.OrganizationID != nil && *o.OrganizationID != ""
+}
+
+// GitHubIntegrationSettings is the API representation of integration settings.
+// The access token is write-only and kept when omitted.
+type GitHubIntegrationSettings struct {
+	Repository          string   `json:"repository"`
+	Branch              string   `json:"branch"`
+	BasePath            string   `json:"basePath"`
+	AccessToken         string   `json:"accessToken,omitempty"`
+	NotebookIDs         []string `json:"notebookIds"`
+	SyncMode            string   `json:"syncMode"`
+	SyncIntervalMinutes int      `json:"syncIntervalMinutes"`
+	PullEnabled         bool     `json:"pullEnabled"`
+	Enabled             *bool    `json:"enabled"`
+}
+
+// GitHubIntegrationResponse is an integration as returned by the API
+type GitHubIntegrationResponse struct {
+	*models.GitHubIntegration
+	NotebookIDs    []string `json:"notebookIds"`
+	HasAccessToken bool     `json:"hasAccessToken"`
+}
+
+// GitHubSyncResult summarizes a sync
+type GitHubSyncResult struct {
+	Pushed            int      `json:"pushed"`
+	Pulled            int      `json:"pulled"`
+	Deleted           int      `json:"deleted"`
+	Unchanged         int      `json:"unchanged"`
+	Conflicts         int      `json:"conflicts"`
+	ConflictedNoteIDs []string `json:"conflictedNoteIds,omitempty"`
+}
+
+// GitHubSyncService interface defines methods for syncing notebooks to GitHub
+type GitHubSyncService interface {
+	GetIntegration(owner GitHubOwner) (*GitHubIntegrationResponse, error)
+	SaveIntegration(ctx context.Context, owner GitHubOwner, settings GitHubIntegrationSettings, updatedBy string) (*GitHubIntegrationResponse, error)
+	DeleteIntegration(owner GitHubOwner) error
+	Sync(ctx context.Context, integrationID, trigger, actorID string) (*GitHubSyncResult, error)
+	QueueSync(integrationID, trigger, actorID string) error
+	QueueSyncForNotebook(notebookID, actorID string)
+	QueueDueSyncs(now time.Time) int
+}
+
+// githubSyncServiceImpl implements the GitHubSyncService interface
+type githubSyncServiceImpl struct {
+	db        *gorm.DB
+	queue     *JobQueue
+	newClient func(token string) githubAPI
+	authorFor func(ctx context.Context, clerkUserID string) *github.CommitAuthor
+}
+
+// NewGitHubSyncService creates a new GitHubSyncService instance
+func NewGitHubSyncService() GitHubSyncService {
+	return &githubSyncServiceImpl{
+		db:    db.DB,
+		queue: GetJobQueue(),
+		newClient: func(token string) githubAPI {
+			return github.NewClient(token)
+		},
+		authorFor: githubCommitAuthor,
+	}
+}
+
+// GetIntegration returns the owner's integration, or nil if none is configured
+func (s *githubSyncServiceImpl) GetIntegration(owner GitHubOwner) (*GitHubIntegrationResponse, error) {
+	integration, err := s.findIntegration(owner)
+	if err != nil || integration == nil {
+		return nil, err
+	}
+	return toGitHubIntegrationResponse(integration), nil
+}
+
+// SaveIntegration validates and saves the owner's integration.
+// The token is checked against the repository whenever it or the repository changes.
+func (s *githubSyncServiceImpl) SaveIntegration(ctx context.Context, owner GitHubOwner, settings GitHubIntegrationSettings, updatedBy string) (*GitHubIntegrationResponse, error) {
+	existing, err := s.findIntegration(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	repository := normalizeGitHubRepository(settings.Repository)
+	if !githubRepositoryPattern.MatchString(repository) {
+		return nil, fmt.Errorf("%w: repository must be in owner/name form", ErrInvalidGitHubIntegration)
+	}
+
+	basePath := strings.Trim(strings.TrimSpace(settings.BasePath), "/")
+	for _, segment := range strings.Split(basePath, "/") {
+		if segment == ".." || segment == "." {
+			return nil, fmt.Errorf("%w: base path can't contain relative segments", ErrInvalidGitHubIntegration)
+		}
+	}
+
+	syncMode := strings.TrimSpace(settings.SyncMode)
+	switch syncMode {
+	case "":
+		syncMode = models.GitHubSyncModeManual
+	case models.GitHubSyncModeManual, models.GitHubSyncModeSchedule, models.GitHubSyncModePublish:
+	default:
+		return nil, fmt.Errorf("%w: unknown sync mode %s", ErrInvalidGitHubIntegration, syncMode)
+	}
+
+	interval := settings.SyncIntervalMinutes
+	if interval == 0 {
+		interval = defaultGitHubSyncInterval
+	}
+	if interval < minGitHubSyncInterval || interval > maxGitHubSyncInterval {
+		return nil, fmt.Errorf("%w: sync interval must be between %d and %d minutes", ErrInvalidGitHubIntegration, minGitHubSyncInterval, maxGitHubSyncInterval)
+	}
+
+	notebookIDs, err := s.validateGitHubNotebooks(owner, settings.NotebookIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	token := strings.TrimSpace(settings.AccessToken)
+	encryptedToken := ""
+	if token != "" {
+		if encryptedToken, err = NewSecretsService().EncryptString(token); err != nil {
+			return nil, fmt.Errorf("failed to encrypt access token: %w", err)
+		}
+	} else if existing != nil {
+		encryptedToken = existing.AccessToken
+		if token, err = NewSecretsService().DecryptString(existing.AccessToken); err != nil {
+			return nil, fmt.Errorf("failed to decrypt access token: %w", err)
+		}
+	} else {
+		return nil, fmt.Errorf("%w: an access token is required", ErrInvalidGitHubIntegration)
+	}
+
+	branch := strings.TrimSpace(settings.Branch)
+	if existing == nil || settings.AccessToken != "" || existing.Repository != repository || branch == "" {
+		repo, err := s.newClient(token).GetRepository(ctx, repository)
+		if errors.Is(err, github.ErrNotFound) {
+			return nil, fmt.Errorf("%w: repository not found or not accessible with this token", ErrInvalidGitHubIntegration)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check repository: %w", err)
+		}
+		if !repo.Permissions.Push {
+			return nil, fmt.Errorf("%w: the token can't push to %s", ErrInvalidGitHubIntegration, repository)
+		}
+		if branch == "" {
+			branch = repo.DefaultBranch
+		}
+	}
+
+	enabled := true
+	if settings.Enabled != nil {
+		enabled = *settings.Enabled
+	} else if existing != nil {
+		enabled = existing.Enabled
+	}
+
+	columns := map[string]interface{}{
+		"repository":            repository,
+		"branch":                branch,
+		"base_path":             basePath,
+		"access_token":          encryptedToken,
+		"notebook_ids":          strings.Join(notebookIDs, ","),
+		"sync_mode":             syncMode,
+		"sync_interval_minutes": interval,
+		"pull_enabled":          settings.PullEnabled,
+		"enabled":               enabled,
+		"updated_by":            updatedBy,
+	}
+
+	if existing != nil {
+		if err := s.db.Model(existing).Updates(columns).Error; err != nil {
+			return nil, fmt.Errorf("failed to save GitHub integration: %w", err)
+		}
+		// The repository layout changed, so files have to be pushed again from scratch
+		if existing.Repository != repository || existing.Branch != branch || existing.BasePath != basePath {
+			s.db.Where("integration_id = ?", existing.ID).Delete(&models.GitHubSyncedFile{})
+		}
+	} else {
+		integration := models.GitHubIntegration{
+			ClerkUserID:    owner.ClerkUserID,
+			OrganizationID: owner.OrganizationID,
+		}
+		if !owner.isOrganization() {
+			integration.OrganizationID = nil
+		}
+		if err := s.db.Create(&integration).Error; err != nil {
+			return nil, fmt.Errorf("failed to save GitHub integration: %w", err)
+		}
+		if err := s.db.Model(&integration).Updates(columns).Error; err != nil {
+			return nil, fmt.Errorf("failed to save GitHub integration: %w", err)
+		}
+	}
+
+	return s.GetIntegration(owner)
+}
+
+// DeleteIntegration removes the owner's integration. Files already in the repository are left in place.
+func (s *githubSyncServiceImpl) DeleteIntegration(owner GitHubOwner) error {
+	integration, err := s.findIntegration(owner)
+	if err != nil {
+		return err
+	}
+	if integration == nil {
+		return ErrGitHubIntegrationNotFound
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("integration_id = ?", integration.ID).Delete(&models.GitHubSyncedFile{}).Error; err != nil {
+			return fmt.Errorf("failed to delete synced files: %w", err)
+		}
+		if err := tx.Delete(integration).Error; err != nil {
+			return fmt.Errorf("failed to delete GitHub integration: %w", err)
+		}
+		return nil
+	})
+}
+
+// Sync pulls edits made in the repository (when enabled) and pushes changed notes, one commit per file.
+// Commits are attributed to the user who triggered the sync, or to whoever last configured the integration.
+func (s *githubSyncServiceImpl) Sync(ctx context.Context, integrationID, trigger, actorID string) (*GitHubSyncResult, error) {
+	lock, _ := githubSyncLocks.LoadOrStore(integrationID, &sync.Mutex{})
+	mutex := lock.(*sync.Mutex)
+	if !mutex.TryLock() {
+		return nil, ErrGitHubSyncInProgress
+	}
+	defer mutex.Unlock()
+
+	var integration models.GitHubIntegration
+	if err := s.db.Where("id = ?", integrationID).First(&integration).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGitHubIntegrationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch GitHub integration: %w", err)
+	}
+	if !integration.Enabled {
+		return nil, fmt.Errorf("%w: integration is disabled", ErrInvalidGitHubIntegration)
+	}
+
+	token, err := NewSecretsService().DecryptString(integration.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt access token: %w", err)
+	}
+
+	if actorID == "" {
+		actorID = integration.UpdatedBy
+	}
+	author := s.authorFor(ctx, actorID)
+
+	result, syncErr := s.syncFiles(ctx, s.newClient(token), &integration, trigger, author)
+
+	now := time.Now()
+	status := models.GitHubSyncStatusSuccess
+	errorMessage := ""
+	if syncErr != nil {
+		status = models.GitHubSyncStatusFailed
+		errorMessage = syncErr.Error()
+	}
+	if err := s.db.Model(&integration).Updates(map[string]interface{}{
+		"last_synced_at":   now,
+		"last_sync_status": status,
+		"last_sync_error":  errorMessage,
+	}).Error; err != nil {
+		log.Error().Err(err).Str("integration_id", integration.ID).Msg("Failed to record GitHub sync status")
+	}
+
+	logEvent := log.Info()
+	if syncErr != nil {
+		logEvent = log.Error().Err(syncErr)
+	}
+	logEvent.
+		Str("integration_id", integration.ID).
+		Str("repository", integration.Repository).
+		Str("trigger", trigger).
+		Int("pushed", result.Pushed).
+		Int("pulled", result.Pulled).
+		Int("deleted", result.Deleted).
+		Int("conflicts", result.Conflicts).
+		Msg("GitHub sync finished")
+
+	if syncErr != nil {
+		return result, syncErr
+	}
+	return result, nil
+}
+
+// QueueSync runs a sync in the background. Syncs already waiting in the queue aren't queued twice.
+func (s *githubSyncServiceImpl) QueueSync(integrationID, trigger, actorID string) error {
+	if _, queued := githubSyncQueued.LoadOrStore(integrationID, true); queued {
+		return nil
+	}
+
+	err := s.queue.Enqueue(Job{
+		Name:        "github-sync",
+		MaxAttempts: 2,
+		Timeout:     githubSyncJobTimeout,
+		Run: func(ctx context.Context) error {
+			githubSyncQueued.Delete(integrationID)
+			_, err := s.Sync(ctx, integrationID, trigger, actorID)
+			if errors.Is(err, ErrGitHubSyncInProgress) || errors.Is(err, ErrInvalidGitHubIntegration) || errors.Is(err, ErrGitHubIntegrationNotFound) {
+				return nil
+			}
+			return err
+		},
+	})
+	if err != nil {
+		githubSyncQueued.Delete(integrationID)
+	}
+	return err
+}
+
+// QueueSyncForNotebook queues syncs for integrations that push the notebook on publish
+func (s *githubSyncServiceImpl) QueueSyncForNotebook(notebookID, actorID string) {
+	var integrations []models.GitHubIntegration
+	if err := s.db.Where("enabled = ? AND sync_mode = ? AND notebook_ids LIKE ?", true, models.GitHubSyncModePublish, "%"+notebookID+"%").
+		Find(&integrations).Error; err != nil {
+		log.Error().Err(err).Str("notebook_id", notebookID).Msg("Failed to find GitHub integrations for notebook")
+		return
+	}
+
+	for _, integration := range integrations {
+		for _, id := range splitGitHubNotebookIDs(integration.NotebookIDs) {
+			if id == notebookID {
+				if err := s.QueueSync(integration.ID, GitHubSyncTriggerPublish, actorID); err != nil {
+					log.Warn().Err(err).Str("integration_id", integration.ID).Msg("Failed to queue GitHub sync")
+				}
+				break
+			}
+		}
+	}
+}
+
+// QueueDueSyncs queues scheduled integrations whose interval has elapsed and returns how many were queued
+func (s *githubSyncServiceImpl) QueueDueSyncs(now time.Time) int {
+	var integrations []models.GitHubIntegration
+	if err := s.db.Where("enabled = ? AND sync_mode = ?", true, models.GitHubSyncModeSchedule).Find(&integrations).Error; err != nil {
+		log.Error().Err(err).Msg("Failed to find scheduled GitHub integrations")
+		return 0
+	}
+
+	queued := 0
+	for _, integration := range integrations {
+		interval := time.Duration(integration.SyncIntervalMinutes) * time.Minute
+		if integration.LastSyncedAt != nil && now.Before(integration.LastSyncedAt.Add(interval)) {
+			continue
+		}
+		if err := s.QueueSync(integration.ID, GitHubSyncTriggerSchedule, ""); err != nil {
+			log.Warn().Err(err).Str("integration_id", integration.ID).Msg("Failed to queue scheduled GitHub sync")
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+// syncFiles runs the pull, push and delete phases of a sync
+func (s *githubSyncServiceImpl) syncFiles(ctx context.Context, api githubAPI, integration *models.GitHubIntegration, trigger string, author *github.CommitAuthor) (*GitHubSyncResult, error) {
+	result := &GitHubSyncResult{}
+
+	notes, err := s.loadGitHubNotes(integration)
+	if err != nil {
+		return result, err
+	}
+	paths := githubNotePaths(integration.BasePath, notes)
+
+	var syncedFiles []models.GitHubSyncedFile
+	if err := s.db.Where("integration_id = ?", integration.ID).Find(&syncedFiles).Error; err != nil {
+		return result, fmt.Errorf("failed to fetch synced files: %w", err)
+	}
+	filesByNote := make(map[string]*models.GitHubSyncedFile, len(syncedFiles))
+	for i := range syncedFiles {
+		filesByNote[syncedFiles[i].NoteID] = &syncedFiles[i]
+	}
+
+	conflicted := make(map[string]bool)
+	commitSuffix := fmt.Sprintf("\n\nSynced from Notes (%s)", trigger)
+
+	// Pull: apply files edited in the repository, unless the note also changed here
+	if integration.PullEnabled {
+		for i := range notes {
+			note := &notes[i]
+			file := filesByNote[note.ID]
+			if file == nil || file.BlobSHA == "" {
+				continue
+			}
+
+			remote, err := api.GetFile(ctx, integration.Repository, file.Path, integration.Branch)
+			if errors.Is(err, github.ErrNotFound) {
+				file.BlobSHA = ""
+				continue
+			}
+			if err != nil {
+				return result, fmt.Errorf("failed to fetch %s: %w", file.Path, err)
+			}
+			if remote.SHA == file.BlobSHA {
+				continue
+			}
+
+			local, err := renderGitHubMarkdown(note)
+			if err != nil {
+				return result, err
+			}
+			if hashGitHubContent(local) != file.ContentHash {
+				conflicted[note.ID] = true
+				result.Conflicts++
+				result.ConflictedNoteIDs = append(result.ConflictedNoteIDs, note.ID)
+				continue
+			}
+
+			content, err := internalutils.MarkdownToTipTap(stripGitHubFrontMatter(remote.Content))
+			if err != nil {
+				return result, fmt.Errorf("failed to convert %s: %w", file.Path, err)
+			}
+			if err := s.db.Model(&models.Notes{}).Where("id = ?", note.ID).Update("content", content).Error; err != nil {
+				return result, fmt.Errorf("failed to update note: %w", err)
+			}
+			if err := NewYjsService(s.db).DeleteYjsDocument(note.ID); err != nil {
+				log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to reset Yjs document after GitHub pull")
+			}
+			note.Content = content
+
+			rendered, err := renderGitHubMarkdown(note)
+			if err != nil {
+				return result, err
+			}
+			file.BlobSHA = remote.SHA
+			file.ContentHash = hashGitHubContent(rendered)
+			file.SyncedAt = time.Now()
+			if err := s.db.Save(file).Error; err != nil {
+				return result, fmt.Errorf("failed to save synced file: %w", err)
+			}
+			result.Pulled++
+		}
+	}
+
+	// Push: commit every note whose content or location changed
+	current := make(map[string]bool, len(notes))
+	for i := range notes {
+		note := &notes[i]
+		current[note.ID] = true
+		if conflicted[note.ID] {
+			continue
+		}
+
+		content, err := renderGitHubMarkdown(note)
+		if err != nil {
+			return result, err
+		}
+		hash := hashGitHubContent(content)
+		path := paths[note.ID]
+		file := filesByNote[note.ID]
+
+		if file != nil && file.Path == path && file.ContentHash == hash && file.BlobSHA != "" {
+			result.Unchanged++
+			continue
+		}
+
+		sha := ""
+		if file != nil && file.Path == path {
+			sha = file.BlobSHA
+		}
+		if sha == "" {
+			existing, err := api.GetFile(ctx, integration.Repository, path, integration.Branch)
+			if err == nil {
+				sha = existing.SHA
+			} else if !errors.Is(err, github.ErrNotFound) {
+				return result, fmt.Errorf("failed to fetch %s: %w", path, err)
+			}
+		}
+
+		verb := "Update"
+		if sha == "" {
+			verb = "Add"
+		}
+		newSHA, err := api.PutFile(ctx, integration.Repository, path, github.PutFileRequest{
+			Message: fmt.Sprintf("%s %s", verb, note.Name) + commitSuffix,
+			Content: content,
+			Branch:  integration.Branch,
+			SHA:     sha,
+			Author:  author,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to push %s: %w", path, err)
+		}
+
+		if file != nil && file.Path != path {
+			if err := s.deleteGitHubFile(ctx, api, integration, file.Path, "Move "+note.Name+commitSuffix, author); err != nil {
+				return result, err
+			}
+		}
+
+		if file == nil {
+			file = &models.GitHubSyncedFile{IntegrationID: integration.ID, NoteID: note.ID}
+		}
+		file.Path = path
+		file.BlobSHA = newSHA
+		file.ContentHash = hash
+		file.SyncedAt = time.Now()
+		if err := s.db.Save(file).Error; err != nil {
+			return result, fmt.Errorf("failed to save synced file: %w", err)
+		}
+		result.Pushed++
+	}
+
+	// Delete: remove files for notes that were deleted or are no longer synced
+	for i := range syncedFiles {
+		file := &syncedFiles[i]
+		if current[file.NoteID] {
+			continue
+		}
+		if file.BlobSHA != "" {
+			if err := s.deleteGitHubFile(ctx, api, integration, file.Path, "Delete "+file.Path+commitSuffix, author); err != nil {
+				return result, err
+			}
+		}
+		if err := s.db.Delete(file).Error; err != nil {
+			return result, fmt.Errorf("failed to delete synced file: %w", err)
+		}
+		result.Deleted++
+	}
+
+	return result, nil
+}
+
+// deleteGitHubFile deletes a file at its current revision, ignoring files that are already gone
+func (s *githubSyncServiceImpl) deleteGitHubFile(ctx context.Context, api githubAPI, integration *models.GitHubIntegration, path, message string, author *github.CommitAuthor) error {
+	existing, err := api.GetFile(ctx, integration.Repository, path, integration.Branch)
+	if errors.Is(err, github.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", path, err)
+	}
+
+	err = api.DeleteFile(ctx, integration.Repository, path, github.DeleteFileRequest{
+		Message: message,
+		Branch:  integration.Branch,
+		SHA:     existing.SHA,
+		Author:  author,
+	})
+	if err != nil && !errors.Is(err, github.ErrNotFound) {
+		return fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadGitHubNotes loads the notes of the integration's notebooks that still belong to its owner
+func (s *githubSyncServiceImpl) loadGitHubNotes(integration *models.GitHubIntegration) ([]models.Notes, error) {
+	notebookIDs := splitGitHubNotebookIDs(integration.NotebookIDs)
+	if len(notebookIDs) == 0 {
+		return nil, nil
+	}
+
+	// Encrypted notebooks and locked notes aren't synced, the server only has their encrypted content
+	query := s.db.Model(&models.Notebook{}).Where("id IN ? AND encrypted = ?", notebookIDs, false)
+	if integration.OrganizationID != nil && *integration.OrganizationID != "" {
+		query = query.Where("organization_id = ?", *integration.OrganizationID)
+	} else {
+		query = query.Where("clerk_user_id = ? AND (organization_id IS NULL OR organization_id = '')", integration.ClerkUserID)
+	}
+	var ownedNotebookIDs []string
+	if err := query.Pluck("id", &ownedNotebookIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notebooks: %w", err)
+	}
+	if len(ownedNotebookIDs) == 0 {
+		return nil, nil
+	}
+
+	var notes []models.Notes
+	if err := s.db.Preload("Chapter.Notebook").
+		Preload("Properties", func(db *gorm.DB) *gorm.DB {
+			return db.Order("key ASC")
+		}).
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id IN ? AND notes.locked = ?", ownedNotebookIDs, false).
+		Order("notes.created_at, notes.id").
+		Find(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notes: %w", err)
+	}
+	return notes, nil
+}
+
+// findIntegration returns the owner's integration, or nil if none is configured
+func (s *githubSyncServiceImpl) findIntegration(owner GitHubOwner) (*models.GitHubIntegration, error) {
+	query := s.db.Model(&models.GitHubIntegration{})
+	if owner.isOrganization() {
+		query = query.Where("organization_id = ?", *owner.OrganizationID)
+	} else {
+		query = query.Where("clerk_user_id = ? AND organization_id IS NULL", owner.ClerkUserID)
+	}
+
+	var integration models.GitHubIntegration
+	if err := query.Limit(1).Find(&integration).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch GitHub integration: %w", err)
+	}
+	if integration.ID == "" {
+		return nil, nil
+	}
+	return &integration, nil
+}
+
+// validateGitHubNotebooks deduplicates the notebook IDs and checks they all belong to the owner
+func (s *githubSyncServiceImpl) validateGitHubNotebooks(owner GitHubOwner, notebookIDs []string) ([]string, error) {
+	seen := make(map[string]bool)
+	ids := make([]string, 0, len(notebookIDs))
+	for _, id := range notebookIDs {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	query := s.db.Model(&models.Notebook{}).Where("id IN ?", ids)
+	if owner.isOrganization() {
+		query = query.Where("organization_id = ?", *owner.OrganizationID)
+	} else {
+		query = query.Where("clerk_user_id = ? AND (organization_id IS NULL OR organization_id = '')", owner.ClerkUserID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check notebooks: %w", err)
+	}
+	if int(count) != len(ids) {
+		return nil, fmt.Errorf("%w: notebooks must belong to the integration's workspace", ErrInvalidGitHubIntegration)
+	}
+	return ids, nil
+}
+
+// toGitHubIntegrationResponse converts an integration to its API representation
+func toGitHubIntegrationResponse(integration *models.GitHubIntegration) *GitHubIntegrationResponse {
+	return &GitHubIntegrationResponse{
+		GitHubIntegration: integration,
+		NotebookIDs:       splitGitHubNotebookIDs(integration.NotebookIDs),
+		HasAccessToken:    integration.AccessToken != "",
+	}
+}
+
+// splitGitHubNotebookIDs parses the stored comma-separated notebook IDs
+func splitGitHubNotebookIDs(value string) []string {
+	ids := []string{}
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// normalizeGitHubRepository accepts owner/name as well as repository URLs
+func normalizeGitHubRepository(repository string) string {
+	repository = strings.TrimSpace(repository)
+	for _, prefix := range []string{"https://github.com/", "http://github.com/", "git@github.com:", "github.com/"} {
+		repository = strings.TrimPrefix(repository, prefix)
+	}
+	return strings.TrimSuffix(strings.Trim(repository, "/"), ".git")
+}
+
+// githubNotePaths assigns each note a file path of the form base/notebook/chapter/note.md from the
+// chapter and note slugs. Notes whose paths still collide get their ID appended so every path stays stable and unique.
+func githubNotePaths(basePath string, notes []models.Notes) map[string]string {
+	paths := make(map[string]string, len(notes))
+	used := make(map[string]bool, len(notes))
+
+	for _, note := range notes {
+		segments := []string{
+			models.Slugify(note.Chapter.Notebook.Name),
+			slugOrName(note.Chapter.Slug, note.Chapter.Name),
+		}
+		if basePath != "" {
+			segments = append([]string{basePath}, segments...)
+		}
+		directory := strings.Join(segments, "/")
+
+		slug := slugOrName(note.Slug, note.Name)
+		path := directory + "/" + slug + ".md"
+		if used[strings.ToLower(path)] {
+			path = directory + "/" + slug + "-" + note.ID + ".md"
+		}
+		used[strings.ToLower(path)] = true
+		paths[note.ID] = path
+	}
+	return paths
+}
+
+// renderGitHubMarkdown renders a note as Markdown with front matter identifying the note and listing its properties
+func renderGitHubMarkdown(note *models.Notes) (string, error) {
+	body, err := internalutils.TipTapToMarkdown(note.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert note %s to markdown: %w", note.ID, err)
+	}
+
+	properties := make([]models.NoteProperty, 0, len(note.Properties))
+	for _, property := range note.Properties {
+		// id, title and slug identify the note, properties can't override them
+		if property.Key != "id" && property.Key != "title" && property.Key != "slug" {
+			properties = append(properties, property)
+		}
+	}
+	return fmt.Sprintf("---\nid: %s\ntitle: %s\nslug: %s\n%s---\n\n%s\n", note.ID, strconv.Quote(note.Name), slugOrName(note.Slug, note.Name), NotePropertiesFrontMatter(properties), strings.TrimSpace(body)), nil
+}
+
+// stripGitHubFrontMatter returns the Markdown body of a synced file
+func stripGitHubFrontMatter(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if strings.HasPrefix(content, "---\n") {
+		if end := strings.Index(content[4:], "\n---\n"); end >= 0 {
+			content = content[4+end+5:]
+		}
+	}
+	return strings.TrimSpace(content)
+}
+
+// hashGitHubContent fingerprints rendered content to detect changes
+func hashGitHubContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// githubCommitAuthor attributes commits to a user's name and primary email
+func githubCommitAuthor(ctx context.Context, clerkUserID string) *github.CommitAuthor {
+	if clerkUserID == "" {
+		return nil
+	}
+	user, err := accessLookups.User(ctx, clerkUserID)
+	if err != nil || user == nil {
+		return nil
+	}
+
+	var email string
+	for _, address := range user.EmailAddresses {
+		if user.PrimaryEmailAddressID != nil && address.ID == *user.PrimaryEmailAddressID {
+			email = address.EmailAddress
+			break
+		}
+	}
+	if email == "" {
+		return nil
+	}
+
+	var nameParts []string
+	if user.FirstName != nil && *user.FirstName != "" {
+		nameParts = append(nameParts, *user.FirstName)
+	}
+	if user.LastName != nil && *user.LastName != "" {
+		nameParts = append(nameParts, *user.LastName)
+	}
+	name := strings.Join(nameParts, " ")
+	if name == "" {
+		name = email
+	}
+
+	return &github.CommitAuthor{Name: name, Email: email}
+}
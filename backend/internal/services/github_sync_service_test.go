@@ -0,0 +1,247 @@
+package services
+
+import (
+	"backend/internal/models"
+	internalutils "backend/internal/utils"
+	"backend/pkg/github"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeGitHubAPI keeps repository files in memory and records commits
+type fakeGitHubAPI struct {
+	files   map[string]github.File
+	commits []string
+	authors []*github.CommitAuthor
+	nextSHA int
+}
+
+func (f *fakeGitHubAPI) GetRepository(ctx context.Context, repo string) (*github.Repository, error) {
+	if repo != "acme/notes" {
+		return nil, github.ErrNotFound
+	}
+	repository := &github.Repository{FullName: repo, DefaultBranch: "main"}
+	repository.Permissions.Push = true
+	return repository, nil
+}
+
+func (f *fakeGitHubAPI) GetFile(ctx context.Context, repo, path, branch string) (*github.File, error) {
+	file, exists := f.files[path]
+	if !exists {
+		return nil, github.ErrNotFound
+	}
+	return &file, nil
+}
+
+func (f *fakeGitHubAPI) PutFile(ctx context.Context, repo, path string, req github.PutFileRequest) (string, error) {
+	if existing, exists := f.files[path]; exists && existing.SHA != req.SHA {
+		return "", fmt.Errorf("sha mismatch for %s", path)
+	}
+	sha := f.write(path, req.Content)
+	f.commits = append(f.commits, req.Message)
+	f.authors = append(f.authors, req.Author)
+	return sha, nil
+}
+
+func (f *fakeGitHubAPI) DeleteFile(ctx context.Context, repo, path string, req github.DeleteFileRequest) error {
+	delete(f.files, path)
+	f.commits = append(f.commits, req.Message)
+	return nil
+}
+
+// write stores a file under a fresh blob SHA, as a commit made directly on GitHub would
+func (f *fakeGitHubAPI) write(path, content string) string {
+	f.nextSHA++
+	sha := fmt.Sprintf("sha_%d", f.nextSHA)
+	f.files[path] = github.File{Path: path, SHA: sha, Content: content}
+	return sha
+}
+
+// setupTestGitHubSyncService creates a sync service and a personal notebook with one note
+func setupTestGitHubSyncService(t *testing.T) (*githubSyncServiceImpl, *fakeGitHubAPI, *models.Notebook, *models.Notes) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(
+		&models.Notebook{},
+		&models.Chapter{},
+		&models.Notes{},
+		&models.NoteProperty{},
+		&models.GitHubIntegration{},
+		&models.GitHubSyncedFile{},
+		&models.YjsDocument{},
+		&models.YjsUpdate{},
+	)
+	require.NoError(t, err, "Failed to migrate test database")
+
+	api := &fakeGitHubAPI{files: make(map[string]github.File)}
+	service := &githubSyncServiceImpl{
+		db: db,
+		newClient: func(token string) githubAPI {
+			return api
+		},
+		authorFor: func(ctx context.Context, clerkUserID string) *github.CommitAuthor {
+			return &github.CommitAuthor{Name: clerkUserID, Email: clerkUserID + "@example.com"}
+		},
+	}
+
+	notebook := models.Notebook{Name: "Work", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Meeting Notes", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+	content, err := internalutils.MarkdownToTipTap("Kickoff agenda")
+	require.NoError(t, err)
+	note := models.Notes{Name: "Kickoff: Q3", Content: content, ChapterID: chapter.ID}
+	require.NoError(t, db.Create(&note).Error)
+
+	return service, api, &notebook, &note
+}
+
+// saveTestGitHubIntegration configures a personal integration for the notebook
+func saveTestGitHubIntegration(t *testing.T, service *githubSyncServiceImpl, notebookID string, pullEnabled bool) *GitHubIntegrationResponse {
+	integration, err := service.SaveIntegration(context.Background(), GitHubOwner{ClerkUserID: "user_1"}, GitHubIntegrationSettings{
+		Repository:  "https://github.com/acme/notes.git",
+		BasePath:    "/kb/",
+		AccessToken: "ghp_token",
+		NotebookIDs: []string{notebookID, notebookID},
+		PullEnabled: pullEnabled,
+	}, "user_1")
+	require.NoError(t, err)
+	return integration
+}
+
+func TestSaveGitHubIntegration_ValidatesSettings(t *testing.T) {
+	service, _, notebook, _ := setupTestGitHubSyncService(t)
+	owner := GitHubOwner{ClerkUserID: "user_1"}
+
+	integration := saveTestGitHubIntegration(t, service, notebook.ID, false)
+	assert.Equal(t, "acme/notes", integration.Repository)
+	assert.Equal(t, "main", integration.Branch, "The default branch should be used when none is given")
+	assert.Equal(t, "kb", integration.BasePath)
+	assert.Equal(t, []string{notebook.ID}, integration.NotebookIDs)
+	assert.True(t, integration.HasAccessToken)
+	assert.NotEqual(t, "ghp_token", integration.AccessToken)
+
+	_, err := service.SaveIntegration(context.Background(), owner, GitHubIntegrationSettings{Repository: "acme/other", AccessToken: "ghp_token"}, "user_1")
+	assert.ErrorIs(t, err, ErrInvalidGitHubIntegration, "Inaccessible repositories should be rejected")
+
+	_, err = service.SaveIntegration(context.Background(), owner, GitHubIntegrationSettings{Repository: "acme/notes", BasePath: "../etc"}, "user_1")
+	assert.ErrorIs(t, err, ErrInvalidGitHubIntegration)
+
+	_, err = service.SaveIntegration(context.Background(), GitHubOwner{ClerkUserID: "user_2"}, GitHubIntegrationSettings{
+		Repository:  "acme/notes",
+		AccessToken: "ghp_token",
+		NotebookIDs: []string{notebook.ID},
+	}, "user_2")
+	assert.ErrorIs(t, err, ErrInvalidGitHubIntegration, "Notebooks of other users can't be synced")
+
+	// The stored token is kept when the settings are saved without one
+	updated, err := service.SaveIntegration(context.Background(), owner, GitHubIntegrationSettings{
+		Repository:          "acme/notes",
+		Branch:              "main",
+		SyncMode:            models.GitHubSyncModeSchedule,
+		SyncIntervalMinutes: 30,
+	}, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, integration.ID, updated.ID)
+	assert.Equal(t, integration.AccessToken, updated.AccessToken)
+	assert.Equal(t, 30, updated.SyncIntervalMinutes)
+}
+
+func TestGitHubSync_PushesChangesAsCommits(t *testing.T) {
+	service, api, notebook, note := setupTestGitHubSyncService(t)
+	integration := saveTestGitHubIntegration(t, service, notebook.ID, false)
+
+	result, err := service.Sync(context.Background(), integration.ID, GitHubSyncTriggerManual, "user_2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Pushed)
+
+	path := "kb/work/meeting-notes/kickoff-q3.md"
+	require.Contains(t, api.files, path)
+	assert.Contains(t, api.files[path].Content, "id: "+note.ID)
+	assert.Contains(t, api.files[path].Content, "Kickoff agenda")
+	require.Len(t, api.authors, 1)
+	assert.Equal(t, "user_2@example.com", api.authors[0].Email, "Commits should be attributed to the user who synced")
+
+	// Nothing is committed when nothing changed
+	result, err = service.Sync(context.Background(), integration.ID, GitHubSyncTriggerSchedule, "")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Pushed)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Len(t, api.commits, 1)
+
+	// Renaming a note moves its file
+	require.NoError(t, service.db.Model(note).Update("name", "Kickoff").Error)
+	_, err = RefreshNoteSlug(service.db, note.ID)
+	require.NoError(t, err)
+	result, err = service.Sync(context.Background(), integration.ID, GitHubSyncTriggerSchedule, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Pushed)
+	assert.NotContains(t, api.files, path)
+	assert.Contains(t, api.files, "kb/work/meeting-notes/kickoff.md")
+	assert.Equal(t, "user_1@example.com", api.authors[len(api.authors)-1].Email, "Scheduled syncs are attributed to whoever configured the integration")
+
+	// Deleting a note deletes its file
+	require.NoError(t, service.db.Delete(note).Error)
+	result, err = service.Sync(context.Background(), integration.ID, GitHubSyncTriggerSchedule, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Deleted)
+	assert.Empty(t, api.files)
+
+	var stored models.GitHubIntegration
+	require.NoError(t, service.db.First(&stored, "id = ?", integration.ID).Error)
+	assert.Equal(t, models.GitHubSyncStatusSuccess, stored.LastSyncStatus)
+	assert.NotNil(t, stored.LastSyncedAt)
+}
+
+func TestGitHubSync_PullsRemoteEdits(t *testing.T) {
+	service, api, notebook, note := setupTestGitHubSyncService(t)
+	integration := saveTestGitHubIntegration(t, service, notebook.ID, true)
+
+	_, err := service.Sync(context.Background(), integration.ID, GitHubSyncTriggerManual, "")
+	require.NoError(t, err)
+
+	path := "kb/work/meeting-notes/kickoff-q3.md"
+	api.write(path, "---\nid: "+note.ID+"\n---\n\nEdited on GitHub")
+
+	result, err := service.Sync(context.Background(), integration.ID, GitHubSyncTriggerManual, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Pulled)
+	assert.Equal(t, 0, result.Pushed, "Pulled edits shouldn't be pushed back")
+
+	var updated models.Notes
+	require.NoError(t, service.db.First(&updated, "id = ?", note.ID).Error)
+	assert.Contains(t, updated.Content, "Edited on GitHub")
+
+	// Edits on both sides are reported as a conflict and left alone
+	api.write(path, "Edited on GitHub again")
+	content, err := internalutils.MarkdownToTipTap("Edited in the app")
+	require.NoError(t, err)
+	require.NoError(t, service.db.Model(&updated).Update("content", content).Error)
+
+	result, err = service.Sync(context.Background(), integration.ID, GitHubSyncTriggerManual, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Conflicts)
+	assert.Equal(t, []string{note.ID}, result.ConflictedNoteIDs)
+	assert.Equal(t, "Edited on GitHub again", api.files[path].Content)
+}
+
+func TestGitHubNotePaths_DisambiguatesCollisions(t *testing.T) {
+	chapter := models.Chapter{Name: "Ideas", Notebook: models.Notebook{Name: "Personal"}}
+	notes := []models.Notes{
+		{ID: "note_1", Name: "Todo", Chapter: chapter},
+		{ID: "note_2", Name: "TODO!", Chapter: chapter},
+		{ID: "note_3", Name: "", Chapter: chapter},
+	}
+
+	paths := githubNotePaths("", notes)
+	assert.Equal(t, "personal/ideas/todo.md", paths["note_1"])
+	assert.Equal(t, "personal/ideas/todo-note_2.md", paths["note_2"])
+	assert.Equal(t, "personal/ideas/untitled.md", paths["note_3"])
+}
@@ -0,0 +1,666 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidGlossaryTerm is returned, wrapped with the reason, for a glossary term that can't be saved
+	ErrInvalidGlossaryTerm = errors.New("invalid glossary term")
+	// ErrGlossaryTermNotFound is returned when a term isn't in the organization's glossary
+	ErrGlossaryTermNotFound = errors.New("glossary term not found")
+)
+
+const (
+	maxGlossaryTerms            = 1000
+	maxGlossaryTermLength       = 100
+	maxGlossaryAliases          = 20
+	maxGlossaryDefinitionLength = 2000
+	glossaryReindexBatchSize    = 100
+)
+
+// Notes and organizations with a glossary pass waiting in the job queue, so bursts of edits queue one pass
+var (
+	glossaryNotesQueued         sync.Map
+	glossaryOrganizationsQueued sync.Map
+)
+
+// GlossaryTermInput is the request body for creating or updating a glossary term
+type GlossaryTermInput struct {
+	Term            string   `json:"term"`
+	Aliases         []string `json:"aliases"`
+	Definition      string   `json:"definition"`
+	CanonicalNoteID *string  `json:"canonicalNoteId"`
+	CaseSensitive   bool     `json:"caseSensitive"`
+}
+
+// GlossaryEntry is a glossary term with how much the organization's notes use it
+type GlossaryEntry struct {
+	models.GlossaryTerm
+	Aliases           []string `json:"aliases"`
+	CanonicalNoteName string   `json:"canonicalNoteName,omitempty"`
+	NoteCount         int      `json:"noteCount"` // Notes using the term
+	Uses              int      `json:"uses"`      // Times the term is used across those notes
+}
+
+// GlossaryNoteOccurrence is a note using a glossary term
+type GlossaryNoteOccurrence struct {
+	NoteID     string    `json:"noteId"`
+	NoteName   string    `json:"noteName"`
+	ChapterID  string    `json:"chapterId"`
+	NotebookID string    `json:"notebookId"`
+	Count      int       `json:"count"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// NoteGlossaryTerm is a glossary term used in a note, with where, for editors to show its definition
+type NoteGlossaryTerm struct {
+	TermID          string          `json:"termId"`
+	Term            string          `json:"term"`
+	Definition      string          `json:"definition"`
+	CanonicalNoteID *string         `json:"canonicalNoteId,omitempty"`
+	Matches         []GlossaryMatch `json:"matches"`
+}
+
+// GlossaryMatch is a use of a glossary term. From and To are ProseMirror positions in the document.
+type GlossaryMatch struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Text string `json:"text"`
+}
+
+// GlossaryService interface defines methods for organization glossaries and the terms notes use
+type GlossaryService interface {
+	ListTerms(ctx context.Context, organizationID string) ([]GlossaryEntry, error)
+	CreateTerm(ctx context.Context, organizationID string, input GlossaryTermInput, clerkUserID string) (*GlossaryEntry, error)
+	UpdateTerm(ctx context.Context, organizationID, termID string, input GlossaryTermInput, clerkUserID string) (*GlossaryEntry, error)
+	DeleteTerm(ctx context.Context, organizationID, termID string) error
+	TermOccurrences(ctx context.Context, organizationID, termID string) ([]GlossaryNoteOccurrence, error)
+	NoteGlossary(ctx context.Context, noteID string) ([]NoteGlossaryTerm, error)
+	NoteChanged(noteID string)
+	SyncNote(ctx context.Context, noteID string) error
+}
+
+// glossaryServiceImpl implements the GlossaryService interface
+type glossaryServiceImpl struct {
+	db    *gorm.DB
+	queue *JobQueue
+}
+
+// NewGlossaryService creates a new GlossaryService instance
+func NewGlossaryService() GlossaryService {
+	return &glossaryServiceImpl{
+		db:    db.DB,
+		queue: GetJobQueue(),
+	}
+}
+
+// ListTerms returns an organization's glossary in alphabetical order
+func (s *glossaryServiceImpl) ListTerms(ctx context.Context, organizationID string) ([]GlossaryEntry, error) {
+	var terms []models.GlossaryTerm
+	if err := db.Replica(s.db).WithContext(ctx).Where("organization_id = ?", organizationID).
+		Order("normalized_term ASC").Find(&terms).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch glossary: %w", err)
+	}
+	return s.entries(ctx, organizationID, terms)
+}
+
+// CreateTerm adds a term to an organization's glossary and finds it in the organization's notes in the
+// background
+func (s *glossaryServiceImpl) CreateTerm(ctx context.Context, organizationID string, input GlossaryTermInput, clerkUserID string) (*GlossaryEntry, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.GlossaryTerm{}).Where("organization_id = ?", organizationID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count glossary terms: %w", err)
+	}
+	if count >= maxGlossaryTerms {
+		return nil, fmt.Errorf("%w: a glossary can have at most %d terms", ErrInvalidGlossaryTerm, maxGlossaryTerms)
+	}
+
+	term := models.GlossaryTerm{OrganizationID: organizationID, CreatedBy: clerkUserID, UpdatedBy: clerkUserID}
+	if err := s.applyTermInput(ctx, &term, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(&term).Error; err != nil {
+		return nil, fmt.Errorf("failed to create glossary term: %w", err)
+	}
+
+	s.reindexOrganization(organizationID)
+	return s.entry(ctx, organizationID, &term)
+}
+
+// UpdateTerm changes a glossary term and finds it again in the organization's notes in the background
+func (s *glossaryServiceImpl) UpdateTerm(ctx context.Context, organizationID, termID string, input GlossaryTermInput, clerkUserID string) (*GlossaryEntry, error) {
+	term, err := s.term(ctx, organizationID, termID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyTermInput(ctx, term, input); err != nil {
+		return nil, err
+	}
+	term.UpdatedBy = clerkUserID
+	if err := s.db.WithContext(ctx).Save(term).Error; err != nil {
+		return nil, fmt.Errorf("failed to update glossary term: %w", err)
+	}
+
+	s.reindexOrganization(organizationID)
+	return s.entry(ctx, organizationID, term)
+}
+
+// DeleteTerm removes a term from an organization's glossary with its occurrences. The links it made are
+// removed in the background.
+func (s *glossaryServiceImpl) DeleteTerm(ctx context.Context, organizationID, termID string) error {
+	term, err := s.term(ctx, organizationID, termID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("term_id = ?", term.ID).Delete(&models.GlossaryOccurrence{}).Error; err != nil {
+			return fmt.Errorf("failed to delete glossary occurrences: %w", err)
+		}
+		if err := tx.Delete(term).Error; err != nil {
+			return fmt.Errorf("failed to delete glossary term: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.reindexOrganization(organizationID)
+	return nil
+}
+
+// TermOccurrences returns the notes using a glossary term, the most uses first
+func (s *glossaryServiceImpl) TermOccurrences(ctx context.Context, organizationID, termID string) ([]GlossaryNoteOccurrence, error) {
+	if _, err := s.term(ctx, organizationID, termID); err != nil {
+		return nil, err
+	}
+
+	occurrences := []GlossaryNoteOccurrence{}
+	if err := db.Replica(s.db).WithContext(ctx).Model(&models.GlossaryOccurrence{}).
+		Select("glossary_occurrences.note_id, notes.name AS note_name, notes.chapter_id, chapters.notebook_id, "+
+			"glossary_occurrences.count, glossary_occurrences.updated_at").
+		Joins("JOIN notes ON notes.id = glossary_occurrences.note_id").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("glossary_occurrences.term_id = ? AND glossary_occurrences.organization_id = ?", termID, organizationID).
+		Order("glossary_occurrences.count DESC, notes.name ASC").
+		Scan(&occurrences).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch glossary occurrences: %w", err)
+	}
+	return occurrences, nil
+}
+
+// NoteGlossary returns the glossary terms a note uses, in the order they first appear, with where they
+// appear. Notes outside organizations have no glossary.
+func (s *glossaryServiceImpl) NoteGlossary(ctx context.Context, noteID string) ([]NoteGlossaryTerm, error) {
+	var note models.Notes
+	err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.Locked {
+		return nil, ErrNoteLocked
+	}
+	if note.Chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	result := []NoteGlossaryTerm{}
+	if note.OrganizationID == nil || *note.OrganizationID == "" {
+		return result, nil
+	}
+	matcher, err := s.matcher(ctx, *note.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := matcher.find(note.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	for _, hit := range hits {
+		i, ok := index[hit.term.ID]
+		if !ok {
+			i = len(result)
+			index[hit.term.ID] = i
+			result = append(result, NoteGlossaryTerm{
+				TermID:          hit.term.ID,
+				Term:            hit.term.Term,
+				Definition:      hit.term.Definition,
+				CanonicalNoteID: hit.term.CanonicalNoteID,
+			})
+		}
+		result[i].Matches = append(result[i].Matches, hit.match)
+	}
+	return result, nil
+}
+
+// NoteChanged finds the glossary terms a note uses in the background
+func (s *glossaryServiceImpl) NoteChanged(noteID string) {
+	if _, queued := glossaryNotesQueued.LoadOrStore(noteID, true); queued {
+		return
+	}
+
+	err := s.queue.Enqueue(Job{
+		Name:        "glossary-note",
+		MaxAttempts: 2,
+		Run: func(ctx context.Context) error {
+			// Edits made from here on queue another pass
+			glossaryNotesQueued.Delete(noteID)
+			return s.SyncNote(ctx, noteID)
+		},
+	})
+	if err != nil {
+		glossaryNotesQueued.Delete(noteID)
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to queue glossary pass")
+	}
+}
+
+// SyncNote stores how often a note uses each glossary term of its organization and keeps its glossary
+// links, to the canonical notes of those terms, in step. Locked notes, notes in encrypted notebooks and
+// notes outside organizations use no terms.
+func (s *glossaryServiceImpl) SyncNote(ctx context.Context, noteID string) error {
+	var note models.Notes
+	err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Deleted since the pass was queued
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	matcher := &glossaryMatcher{}
+	if note.OrganizationID != nil && *note.OrganizationID != "" {
+		if matcher, err = s.matcher(ctx, *note.OrganizationID); err != nil {
+			return err
+		}
+	}
+	return s.syncNote(ctx, &note, matcher)
+}
+
+// syncNote stores a note's glossary occurrences and links with the organization's terms
+func (s *glossaryServiceImpl) syncNote(ctx context.Context, note *models.Notes, matcher *glossaryMatcher) error {
+	counts := make(map[string]int)
+	targets := make(map[string]bool)
+	if note.OrganizationID != nil && *note.OrganizationID != "" && !note.Locked && !note.Chapter.Notebook.Encrypted {
+		hits, err := matcher.find(note.Content)
+		if err != nil {
+			return err
+		}
+		for _, hit := range hits {
+			counts[hit.term.ID]++
+			if hit.term.CanonicalNoteID != nil && *hit.term.CanonicalNoteID != note.ID {
+				targets[*hit.term.CanonicalNoteID] = true
+			}
+		}
+	}
+
+	// Canonical notes may have been deleted since the term was saved
+	var existingTargets []string
+	if len(targets) > 0 {
+		if err := s.db.WithContext(ctx).Model(&models.Notes{}).Where("id IN ?", slices.Sorted(maps.Keys(targets))).
+			Pluck("id", &existingTargets).Error; err != nil {
+			return fmt.Errorf("failed to fetch canonical notes: %w", err)
+		}
+	}
+	targets = make(map[string]bool, len(existingTargets))
+	for _, id := range existingTargets {
+		targets[id] = true
+	}
+
+	var links []models.NoteLink
+	if err := s.db.WithContext(ctx).Where("source_note_id = ? AND origin = ?", note.ID, models.LinkOriginGlossary).Find(&links).Error; err != nil {
+		return fmt.Errorf("failed to fetch glossary links: %w", err)
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("note_id = ?", note.ID).Delete(&models.GlossaryOccurrence{}).Error; err != nil {
+			return fmt.Errorf("failed to clear glossary occurrences: %w", err)
+		}
+		for _, termID := range slices.Sorted(maps.Keys(counts)) {
+			occurrence := models.GlossaryOccurrence{
+				TermID:         termID,
+				NoteID:         note.ID,
+				OrganizationID: *note.OrganizationID,
+				Count:          counts[termID],
+			}
+			if err := tx.Create(&occurrence).Error; err != nil {
+				return fmt.Errorf("failed to save glossary occurrence: %w", err)
+			}
+		}
+
+		for _, link := range links {
+			if targets[link.TargetNoteID] {
+				delete(targets, link.TargetNoteID)
+				continue
+			}
+			if err := tx.Delete(&link).Error; err != nil {
+				return fmt.Errorf("failed to delete glossary link: %w", err)
+			}
+		}
+		for _, targetID := range slices.Sorted(maps.Keys(targets)) {
+			link := models.NoteLink{
+				ID:             uuid.New().String(),
+				SourceNoteID:   note.ID,
+				TargetNoteID:   targetID,
+				LinkType:       models.LinkTypeReferences,
+				Origin:         models.LinkOriginGlossary,
+				OrganizationID: note.OrganizationID,
+				CreatedBy:      note.Chapter.Notebook.ClerkUserID,
+			}
+			if err := tx.Create(&link).Error; err != nil {
+				return fmt.Errorf("failed to create glossary link: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// reindexOrganization runs the glossary pass over an organization's notes in the background, after its
+// glossary changed
+func (s *glossaryServiceImpl) reindexOrganization(organizationID string) {
+	if _, queued := glossaryOrganizationsQueued.LoadOrStore(organizationID, true); queued {
+		return
+	}
+
+	err := s.queue.Enqueue(Job{
+		Name:        "glossary-reindex",
+		MaxAttempts: 2,
+		Run: func(ctx context.Context) error {
+			// Glossary changes made from here on queue another pass
+			glossaryOrganizationsQueued.Delete(organizationID)
+			return s.reindex(ctx, organizationID)
+		},
+	})
+	if err != nil {
+		glossaryOrganizationsQueued.Delete(organizationID)
+		log.Warn().Err(err).Str("organization_id", organizationID).Msg("Failed to queue glossary reindex")
+	}
+}
+
+// reindex runs the glossary pass over every note of an organization. A note that fails doesn't stop the
+// others; the last failure is returned so the job is retried.
+func (s *glossaryServiceImpl) reindex(ctx context.Context, organizationID string) error {
+	matcher, err := s.matcher(ctx, organizationID)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	var notes []models.Notes
+	result := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("organization_id = ?", organizationID).
+		FindInBatches(&notes, glossaryReindexBatchSize, func(tx *gorm.DB, batch int) error {
+			for i := range notes {
+				if err := s.syncNote(ctx, &notes[i], matcher); err != nil {
+					log.Warn().Err(err).Str("note_id", notes[i].ID).Msg("Failed to run glossary pass")
+					lastErr = err
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to fetch organization notes: %w", result.Error)
+	}
+	return lastErr
+}
+
+// term returns a term of an organization's glossary
+func (s *glossaryServiceImpl) term(ctx context.Context, organizationID, termID string) (*models.GlossaryTerm, error) {
+	var term models.GlossaryTerm
+	err := s.db.WithContext(ctx).Where("id = ? AND organization_id = ?", termID, organizationID).First(&term).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGlossaryTermNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch glossary term: %w", err)
+	}
+	return &term, nil
+}
+
+// applyTermInput validates a glossary term and sets it on the model
+func (s *glossaryServiceImpl) applyTermInput(ctx context.Context, term *models.GlossaryTerm, input GlossaryTermInput) error {
+	name := strings.Join(strings.Fields(input.Term), " ")
+	if !strings.ContainsFunc(name, isWordRune) {
+		return fmt.Errorf("%w: a term is required", ErrInvalidGlossaryTerm)
+	}
+	if utf8.RuneCountInString(name) > maxGlossaryTermLength {
+		return fmt.Errorf("%w: terms can be at most %d characters", ErrInvalidGlossaryTerm, maxGlossaryTermLength)
+	}
+	definition := strings.TrimSpace(input.Definition)
+	if definition == "" {
+		return fmt.Errorf("%w: a definition is required", ErrInvalidGlossaryTerm)
+	}
+	if utf8.RuneCountInString(definition) > maxGlossaryDefinitionLength {
+		return fmt.Errorf("%w: definitions can be at most %d characters", ErrInvalidGlossaryTerm, maxGlossaryDefinitionLength)
+	}
+
+	seen := map[string]bool{strings.ToLower(name): true}
+	var aliases []string
+	for _, alias := range input.Aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		if !strings.ContainsFunc(alias, isWordRune) || seen[strings.ToLower(alias)] {
+			continue
+		}
+		if utf8.RuneCountInString(alias) > maxGlossaryTermLength {
+			return fmt.Errorf("%w: aliases can be at most %d characters", ErrInvalidGlossaryTerm, maxGlossaryTermLength)
+		}
+		seen[strings.ToLower(alias)] = true
+		aliases = append(aliases, alias)
+	}
+	if len(aliases) > maxGlossaryAliases {
+		return fmt.Errorf("%w: a term can have at most %d aliases", ErrInvalidGlossaryTerm, maxGlossaryAliases)
+	}
+
+	var duplicates int64
+	if err := s.db.WithContext(ctx).Model(&models.GlossaryTerm{}).
+		Where("organization_id = ? AND normalized_term = ? AND id <> ?", term.OrganizationID, strings.ToLower(name), term.ID).
+		Count(&duplicates).Error; err != nil {
+		return fmt.Errorf("failed to check glossary terms: %w", err)
+	}
+	if duplicates > 0 {
+		return fmt.Errorf("%w: %q is already in the glossary", ErrInvalidGlossaryTerm, name)
+	}
+
+	var canonicalNoteID *string
+	if input.CanonicalNoteID != nil && strings.TrimSpace(*input.CanonicalNoteID) != "" {
+		noteID := strings.TrimSpace(*input.CanonicalNoteID)
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.Notes{}).Where("id = ? AND organization_id = ?", noteID, term.OrganizationID).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to fetch canonical note: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: the canonical note must be a note of the organization", ErrInvalidGlossaryTerm)
+		}
+		canonicalNoteID = &noteID
+	}
+
+	term.Term = name
+	term.NormalizedTerm = strings.ToLower(name)
+	term.Aliases = strings.Join(aliases, "\n")
+	term.Definition = definition
+	term.CanonicalNoteID = canonicalNoteID
+	term.CaseSensitive = input.CaseSensitive
+	return nil
+}
+
+// entry returns a single glossary term with its use
+func (s *glossaryServiceImpl) entry(ctx context.Context, organizationID string, term *models.GlossaryTerm) (*GlossaryEntry, error) {
+	entries, err := s.entries(ctx, organizationID, []models.GlossaryTerm{*term})
+	if err != nil {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// entries adds to glossary terms the names of their canonical notes and how much notes use them
+func (s *glossaryServiceImpl) entries(ctx context.Context, organizationID string, terms []models.GlossaryTerm) ([]GlossaryEntry, error) {
+	entries := make([]GlossaryEntry, len(terms))
+	if len(terms) == 0 {
+		return entries, nil
+	}
+
+	termIDs := make([]string, len(terms))
+	var noteIDs []string
+	for i, term := range terms {
+		termIDs[i] = term.ID
+		if term.CanonicalNoteID != nil {
+			noteIDs = append(noteIDs, *term.CanonicalNoteID)
+		}
+	}
+
+	var usage []struct {
+		TermID    string
+		NoteCount int
+		Uses      int
+	}
+	if err := db.Replica(s.db).WithContext(ctx).Model(&models.GlossaryOccurrence{}).
+		Select("term_id, COUNT(*) AS note_count, SUM(count) AS uses").
+		Where("organization_id = ? AND term_id IN ?", organizationID, termIDs).
+		Group("term_id").Scan(&usage).Error; err != nil {
+		return nil, fmt.Errorf("failed to count glossary occurrences: %w", err)
+	}
+	noteCounts := make(map[string]int, len(usage))
+	uses := make(map[string]int, len(usage))
+	for _, row := range usage {
+		noteCounts[row.TermID] = row.NoteCount
+		uses[row.TermID] = row.Uses
+	}
+
+	names := make(map[string]string)
+	if len(noteIDs) > 0 {
+		var notes []models.Notes
+		if err := db.Replica(s.db).WithContext(ctx).Select("id, name").Where("id IN ?", noteIDs).Find(&notes).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch canonical notes: %w", err)
+		}
+		for _, note := range notes {
+			names[note.ID] = note.Name
+		}
+	}
+
+	for i, term := range terms {
+		entries[i] = GlossaryEntry{
+			GlossaryTerm: term,
+			Aliases:      glossaryAliases(&term),
+			NoteCount:    noteCounts[term.ID],
+			Uses:         uses[term.ID],
+		}
+		if term.CanonicalNoteID != nil {
+			entries[i].CanonicalNoteName = names[*term.CanonicalNoteID]
+		}
+	}
+	return entries, nil
+}
+
+// matcher returns a matcher for an organization's glossary
+func (s *glossaryServiceImpl) matcher(ctx context.Context, organizationID string) (*glossaryMatcher, error) {
+	var terms []models.GlossaryTerm
+	if err := s.db.WithContext(ctx).Where("organization_id = ?", organizationID).Find(&terms).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch glossary: %w", err)
+	}
+	return newGlossaryMatcher(terms), nil
+}
+
+// glossaryAliases returns the aliases of a glossary term
+func glossaryAliases(term *models.GlossaryTerm) []string {
+	aliases := []string{}
+	for _, alias := range strings.Split(term.Aliases, "\n") {
+		if alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// glossaryPattern is a way of writing a glossary term
+type glossaryPattern struct {
+	term *models.GlossaryTerm
+	text []rune
+}
+
+// glossaryHit is a use of a glossary term in a note
+type glossaryHit struct {
+	term  *models.GlossaryTerm
+	match GlossaryMatch
+}
+
+// glossaryMatcher finds the terms of a glossary, and their aliases, in notes
+type glossaryMatcher struct {
+	patterns []glossaryPattern
+}
+
+// newGlossaryMatcher builds a matcher trying longer patterns first, so "API key" wins over "API"
+func newGlossaryMatcher(terms []models.GlossaryTerm) *glossaryMatcher {
+	matcher := &glossaryMatcher{}
+	for i := range terms {
+		term := &terms[i]
+		for _, text := range append([]string{term.Term}, glossaryAliases(term)...) {
+			matcher.patterns = append(matcher.patterns, glossaryPattern{term: term, text: []rune(text)})
+		}
+	}
+	sort.SliceStable(matcher.patterns, func(i, j int) bool {
+		return len(matcher.patterns[i].text) > len(matcher.patterns[j].text)
+	})
+	return matcher
+}
+
+// find returns the uses of glossary terms in note content, in document order. Terms only match whole
+// words, and code isn't searched.
+func (m *glossaryMatcher) find(content string) ([]glossaryHit, error) {
+	if len(m.patterns) == 0 {
+		return nil, nil
+	}
+	doc, err := translatableTipTapDoc(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []glossaryHit
+	for _, block := range collectLintBlocks(doc.Content, 0, nil) {
+		taken := make([]bool, len(block.text))
+		var blockHits []glossaryHit
+		for _, pattern := range m.patterns {
+			for start := 0; start+len(pattern.text) <= len(block.text); start++ {
+				end := start + len(pattern.text)
+				if slices.Contains(taken[start:end], true) ||
+					(start > 0 && isWordRune(block.text[start-1])) || (end < len(block.text) && isWordRune(block.text[end])) {
+					continue
+				}
+				candidate := string(block.text[start:end])
+				if candidate != string(pattern.text) && (pattern.term.CaseSensitive || !strings.EqualFold(candidate, string(pattern.text))) {
+					continue
+				}
+				for i := start; i < end; i++ {
+					taken[i] = true
+				}
+				from, to := block.span(start, end)
+				blockHits = append(blockHits, glossaryHit{term: pattern.term, match: GlossaryMatch{From: from, To: to, Text: candidate}})
+				start = end - 1
+			}
+		}
+		sort.Slice(blockHits, func(i, j int) bool { return blockHits[i].match.From < blockHits[j].match.From })
+		hits = append(hits, blockHits...)
+	}
+	return hits, nil
+}
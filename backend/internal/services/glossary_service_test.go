@@ -0,0 +1,127 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestGlossaryService creates a glossary service on an in-memory database. Queued jobs don't run,
+// tests run the passes themselves.
+func setupTestGlossaryService(t *testing.T) *glossaryServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteLink{},
+		&models.GlossaryTerm{}, &models.GlossaryOccurrence{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &glossaryServiceImpl{db: db, queue: NewJobQueue(1, 10)}
+}
+
+// glossaryLinkTargets returns the notes a note is linked to by the glossary
+func glossaryLinkTargets(t *testing.T, db *gorm.DB, noteID string) []string {
+	var targets []string
+	require.NoError(t, db.Model(&models.NoteLink{}).Where("source_note_id = ? AND origin = ?", noteID, models.LinkOriginGlossary).
+		Order("target_note_id").Pluck("target_note_id", &targets).Error)
+	return targets
+}
+
+func TestGlossary_SyncNoteFindsTermsAndLinks(t *testing.T) {
+	service := setupTestGlossaryService(t)
+	ctx := context.Background()
+	orgID := "org_1"
+	note := createLifecycleNote(t, service.db, &orgID)
+	// Positions: the first paragraph's text starts at 1, the second's at 51
+	require.NoError(t, service.db.Model(&note).Update("content", tipTapParagraphs(t,
+		"Submit the Expense Report before the ACE review.",
+		"Expense reports use ace codes and an expense report API key.",
+	)).Error)
+	canonical := models.Notes{Name: "Expense reports", ChapterID: note.ChapterID, OrganizationID: &orgID}
+	require.NoError(t, service.db.Create(&canonical).Error)
+
+	report, err := service.CreateTerm(ctx, orgID, GlossaryTermInput{
+		Term:            " expense  report ",
+		Aliases:         []string{"expense reports", "Expense Report", ""},
+		Definition:      "The form for claiming back costs.",
+		CanonicalNoteID: &canonical.ID,
+	}, "user_admin")
+	require.NoError(t, err)
+	assert.Equal(t, "expense report", report.Term)
+	assert.Equal(t, []string{"expense reports"}, report.Aliases, "Aliases repeating the term are dropped")
+	assert.Equal(t, "Expense reports", report.CanonicalNoteName)
+	ace, err := service.CreateTerm(ctx, orgID, GlossaryTermInput{Term: "ACE", Definition: "Annual cost estimate.", CaseSensitive: true}, "user_admin")
+	require.NoError(t, err)
+	apiKey, err := service.CreateTerm(ctx, orgID, GlossaryTermInput{Term: "expense report API key", Definition: "Key of the reporting API."}, "user_admin")
+	require.NoError(t, err)
+
+	require.NoError(t, service.reindex(ctx, orgID))
+
+	terms, err := service.NoteGlossary(ctx, note.ID)
+	require.NoError(t, err)
+	require.Len(t, terms, 3)
+	assert.Equal(t, report.ID, terms[0].TermID)
+	assert.Equal(t, []GlossaryMatch{
+		{From: 12, To: 26, Text: "Expense Report"},
+		{From: 51, To: 66, Text: "Expense reports"},
+	}, terms[0].Matches, "Longer terms win over the terms they contain")
+	assert.Equal(t, ace.ID, terms[1].TermID)
+	assert.Len(t, terms[1].Matches, 1, "Case sensitive terms only match as written")
+	assert.Equal(t, apiKey.ID, terms[2].TermID)
+	assert.Equal(t, "expense report API key", terms[2].Matches[0].Text)
+
+	assert.Equal(t, []string{canonical.ID}, glossaryLinkTargets(t, service.db, note.ID))
+	occurrences, err := service.TermOccurrences(ctx, orgID, report.ID)
+	require.NoError(t, err)
+	require.Len(t, occurrences, 1)
+	assert.Equal(t, GlossaryNoteOccurrence{NoteID: note.ID, NoteName: "Expenses", ChapterID: note.ChapterID,
+		NotebookID: occurrences[0].NotebookID, Count: 2, UpdatedAt: occurrences[0].UpdatedAt}, occurrences[0])
+
+	entries, err := service.ListTerms(ctx, orgID)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "ACE", entries[0].Term)
+	assert.Equal(t, 1, entries[1].NoteCount)
+	assert.Equal(t, 2, entries[1].Uses)
+
+	require.NoError(t, service.DeleteTerm(ctx, orgID, report.ID))
+	require.NoError(t, service.SyncNote(ctx, note.ID))
+	assert.Empty(t, glossaryLinkTargets(t, service.db, note.ID), "Links of deleted terms are removed")
+	_, err = service.TermOccurrences(ctx, orgID, report.ID)
+	assert.ErrorIs(t, err, ErrGlossaryTermNotFound)
+	_, err = service.TermOccurrences(ctx, "org_2", ace.ID)
+	assert.ErrorIs(t, err, ErrGlossaryTermNotFound, "Terms of other organizations aren't found")
+}
+
+func TestGlossary_TermValidation(t *testing.T) {
+	service := setupTestGlossaryService(t)
+	ctx := context.Background()
+	orgID := "org_1"
+	personal := createLifecycleNote(t, service.db, nil)
+
+	_, err := service.CreateTerm(ctx, orgID, GlossaryTermInput{Term: "SLA", Definition: "Service level agreement."}, "user_admin")
+	require.NoError(t, err)
+
+	for name, input := range map[string]GlossaryTermInput{
+		"duplicate":          {Term: "sla", Definition: "Again."},
+		"no definition":      {Term: "OKR"},
+		"no term":            {Term: " - ", Definition: "Nothing."},
+		"personal canonical": {Term: "OKR", Definition: "Objectives.", CanonicalNoteID: &personal.ID},
+	} {
+		_, err := service.CreateTerm(ctx, orgID, input, "user_admin")
+		assert.ErrorIs(t, err, ErrInvalidGlossaryTerm, name)
+	}
+
+	_, err = service.CreateTerm(ctx, "org_2", GlossaryTermInput{Term: "SLA", Definition: "Another organization's."}, "user_admin")
+	assert.NoError(t, err, "Terms are unique per organization")
+
+	require.NoError(t, service.SyncNote(ctx, personal.ID))
+	terms, err := service.NoteGlossary(ctx, personal.ID)
+	require.NoError(t, err)
+	assert.Empty(t, terms, "Personal notes have no glossary")
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxGoalTitleLength = 200
+	maxGoalTarget      = 10000000
+)
+
+var (
+	// ErrInvalidGoal is returned for goals without a title, with an unknown metric or period, or with a
+	// notebook or board their metric can't count
+	ErrInvalidGoal = errors.New("invalid goal")
+	// ErrGoalNotFound is returned when a goal doesn't exist
+	ErrGoalNotFound = errors.New("goal not found")
+)
+
+// GoalScope selects a user's personal goals, or an organization's
+type GoalScope struct {
+	ClerkUserID    string
+	OrganizationID *string
+}
+
+// GoalInput is the request body for creating or updating a goal
+type GoalInput struct {
+	Title       string  `json:"title"`
+	Metric      string  `json:"metric"` // "notes_written", "tasks_completed", "words" or "custom"
+	Target      int     `json:"target"`
+	Period      string  `json:"period"` // "weekly" (default), "monthly" or "once"
+	NotebookID  *string `json:"notebookId"`
+	TaskBoardID *string `json:"taskBoardId"`
+	DueDate     string  `json:"dueDate"` // 2006-01-02, optional
+}
+
+// GoalProgress is a goal with its progress in the current period
+type GoalProgress struct {
+	models.Goal
+	Current     int       `json:"current"`
+	Percent     int       `json:"percent"` // Capped at 100
+	Completed   bool      `json:"completed"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+}
+
+// GoalWeekProgress is a goal's progress this week, compared with last week
+type GoalWeekProgress struct {
+	GoalID    string `json:"goalId"`
+	Title     string `json:"title"`
+	Metric    string `json:"metric"`
+	Period    string `json:"period"`
+	Target    int    `json:"target"`
+	ThisWeek  int    `json:"thisWeek"`
+	LastWeek  int    `json:"lastWeek"`
+	Current   int    `json:"current"` // Progress in the goal's own period
+	Completed bool   `json:"completed"`
+}
+
+// GoalWeeklySummary summarizes the week's progress on a user's or organization's goals, for digests
+type GoalWeeklySummary struct {
+	WeekStart time.Time          `json:"weekStart"`
+	Goals     []GoalWeekProgress `json:"goals"`
+	Text      string             `json:"text"` // Plain text rendering, one line per goal
+}
+
+// GoalService interface defines methods for goals and their progress
+type GoalService interface {
+	CreateGoal(ctx context.Context, scope GoalScope, input GoalInput) (*GoalProgress, error)
+	GetGoal(ctx context.Context, goalID string) (*models.Goal, error)
+	ListGoals(ctx context.Context, scope GoalScope) ([]GoalProgress, error)
+	UpdateGoal(ctx context.Context, goalID, viewerID string, input GoalInput) (*GoalProgress, error)
+	DeleteGoal(ctx context.Context, goalID string) error
+	SetCustomProgress(ctx context.Context, goalID, viewerID string, value int) (*GoalProgress, error)
+	WeeklySummary(ctx context.Context, scope GoalScope) (*GoalWeeklySummary, error)
+}
+
+// goalServiceImpl implements the GoalService interface
+type goalServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewGoalService creates a new GoalService instance
+func NewGoalService() GoalService {
+	return &goalServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// CreateGoal adds a goal to the user's personal goals, or the organization's
+func (s *goalServiceImpl) CreateGoal(ctx context.Context, scope GoalScope, input GoalInput) (*GoalProgress, error) {
+	goal := models.Goal{ClerkUserID: scope.ClerkUserID, OrganizationID: scope.OrganizationID}
+	if err := s.applyGoalInput(ctx, &goal, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(&goal).Error; err != nil {
+		return nil, fmt.Errorf("failed to create goal: %w", err)
+	}
+	return s.progress(ctx, &goal, scope.ClerkUserID)
+}
+
+// GetGoal returns a goal without its progress
+func (s *goalServiceImpl) GetGoal(ctx context.Context, goalID string) (*models.Goal, error) {
+	var goal models.Goal
+	if err := s.db.WithContext(ctx).Where("id = ?", goalID).First(&goal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGoalNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch goal: %w", err)
+	}
+	return &goal, nil
+}
+
+// ListGoals returns the scope's goals with their progress, oldest first
+func (s *goalServiceImpl) ListGoals(ctx context.Context, scope GoalScope) ([]GoalProgress, error) {
+	goals, err := s.scopeGoals(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]GoalProgress, 0, len(goals))
+	for i := range goals {
+		progress, err := s.progress(ctx, &goals[i], scope.ClerkUserID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *progress)
+	}
+	return result, nil
+}
+
+// UpdateGoal changes a goal's title, metric, target, period, links or due date
+func (s *goalServiceImpl) UpdateGoal(ctx context.Context, goalID, viewerID string, input GoalInput) (*GoalProgress, error) {
+	goal, err := s.GetGoal(ctx, goalID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyGoalInput(ctx, goal, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Select("title", "metric", "target", "period", "notebook_id", "task_board_id", "due_date").
+		Save(goal).Error; err != nil {
+		return nil, fmt.Errorf("failed to update goal: %w", err)
+	}
+	return s.progress(ctx, goal, viewerID)
+}
+
+// DeleteGoal removes a goal
+func (s *goalServiceImpl) DeleteGoal(ctx context.Context, goalID string) error {
+	result := s.db.WithContext(ctx).Where("id = ?", goalID).Delete(&models.Goal{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete goal: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrGoalNotFound
+	}
+	return nil
+}
+
+// SetCustomProgress records the progress of a custom goal
+func (s *goalServiceImpl) SetCustomProgress(ctx context.Context, goalID, viewerID string, value int) (*GoalProgress, error) {
+	goal, err := s.GetGoal(ctx, goalID)
+	if err != nil {
+		return nil, err
+	}
+	if goal.Metric != models.GoalMetricCustom {
+		return nil, fmt.Errorf("%w: only custom goals are updated by hand", ErrInvalidGoal)
+	}
+	if value < 0 || value > maxGoalTarget {
+		return nil, fmt.Errorf("%w: progress must be between 0 and %d", ErrInvalidGoal, maxGoalTarget)
+	}
+
+	goal.CustomProgress = value
+	if err := s.db.WithContext(ctx).Model(goal).UpdateColumn("custom_progress", value).Error; err != nil {
+		return nil, fmt.Errorf("failed to update goal progress: %w", err)
+	}
+	return s.progress(ctx, goal, viewerID)
+}
+
+// WeeklySummary returns this week's and last week's progress on the scope's goals. Weeks start on Monday in
+// the user's timezone.
+func (s *goalServiceImpl) WeeklySummary(ctx context.Context, scope GoalScope) (*GoalWeeklySummary, error) {
+	goals, err := s.scopeGoals(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().In(UserLocation(ctx, s.db, scope.ClerkUserID))
+	weekStart, _ := goalPeriodBounds(models.GoalPeriodWeekly, now, time.Time{})
+	summary := &GoalWeeklySummary{WeekStart: weekStart, Goals: make([]GoalWeekProgress, 0, len(goals))}
+
+	var text strings.Builder
+	for i := range goals {
+		goal := &goals[i]
+		thisWeek, err := s.measure(ctx, goal, weekStart, now)
+		if err != nil {
+			return nil, err
+		}
+		lastWeek, err := s.measure(ctx, goal, weekStart.AddDate(0, 0, -7), weekStart)
+		if err != nil {
+			return nil, err
+		}
+		progress, err := s.progress(ctx, goal, scope.ClerkUserID)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := GoalWeekProgress{
+			GoalID:    goal.ID,
+			Title:     goal.Title,
+			Metric:    goal.Metric,
+			Period:    goal.Period,
+			Target:    goal.Target,
+			ThisWeek:  thisWeek,
+			LastWeek:  lastWeek,
+			Current:   progress.Current,
+			Completed: progress.Completed,
+		}
+		summary.Goals = append(summary.Goals, entry)
+
+		status := fmt.Sprintf("%d/%d %s", progress.Current, goal.Target, goalPeriodLabel(goal.Period))
+		if progress.Completed {
+			status += ", reached"
+		}
+		if goal.Metric == models.GoalMetricCustom {
+			fmt.Fprintf(&text, "- %s: %s\n", goal.Title, status)
+		} else {
+			fmt.Fprintf(&text, "- %s: %d this week (%d last week), %s\n", goal.Title, thisWeek, lastWeek, status)
+		}
+	}
+	summary.Text = text.String()
+	return summary, nil
+}
+
+// scopeGoals returns the goals of a user's personal scope or an organization, oldest first
+func (s *goalServiceImpl) scopeGoals(ctx context.Context, scope GoalScope) ([]models.Goal, error) {
+	query := s.db.WithContext(ctx).Order("created_at ASC")
+	if scope.OrganizationID != nil && *scope.OrganizationID != "" {
+		query = query.Where("organization_id = ?", *scope.OrganizationID)
+	} else {
+		query = query.Where("clerk_user_id = ? AND organization_id IS NULL", scope.ClerkUserID)
+	}
+
+	var goals []models.Goal
+	if err := query.Find(&goals).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch goals: %w", err)
+	}
+	return goals, nil
+}
+
+// progress computes a goal's progress in its current period, in the viewer's timezone
+func (s *goalServiceImpl) progress(ctx context.Context, goal *models.Goal, viewerID string) (*GoalProgress, error) {
+	now := s.now().In(UserLocation(ctx, s.db, viewerID))
+	start, end := goalPeriodBounds(goal.Period, now, goal.CreatedAt)
+	if goal.Period == models.GoalPeriodOnce && goal.DueDate != nil {
+		// Due dates count whole
+		end = goal.DueDate.AddDate(0, 0, 1)
+	}
+	to := now
+	if end.Before(to) {
+		to = end
+	}
+
+	current, err := s.measure(ctx, goal, start, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GoalProgress{Goal: *goal, Current: current, PeriodStart: start, PeriodEnd: end, Completed: current >= goal.Target}
+	if goal.Target > 0 {
+		result.Percent = min(current*100/goal.Target, 100)
+	}
+	return result, nil
+}
+
+// measure counts a goal's metric between two times
+func (s *goalServiceImpl) measure(ctx context.Context, goal *models.Goal, from, to time.Time) (int, error) {
+	orgScoped := goal.OrganizationID != nil && *goal.OrganizationID != ""
+	var count int64
+
+	switch goal.Metric {
+	case models.GoalMetricNotesWritten:
+		query := s.db.WithContext(ctx).Table("notes").
+			Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+			Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+			Where("notes.created_at >= ? AND notes.created_at < ?", from, to)
+		switch {
+		case goal.NotebookID != nil:
+			query = query.Where("notebooks.id = ?", *goal.NotebookID)
+		case orgScoped:
+			query = query.Where("notebooks.organization_id = ?", *goal.OrganizationID)
+		default:
+			query = query.Where("notebooks.clerk_user_id = ? AND notebooks.organization_id IS NULL", goal.ClerkUserID)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("failed to count notes written: %w", err)
+		}
+
+	case models.GoalMetricTasksCompleted:
+		query := s.db.WithContext(ctx).Table("tasks").
+			Joins("JOIN task_boards ON task_boards.id = tasks.task_board_id").
+			Where("tasks.completed_at >= ? AND tasks.completed_at < ?", from, to)
+		switch {
+		case goal.TaskBoardID != nil:
+			query = query.Where("task_boards.id = ?", *goal.TaskBoardID)
+		case orgScoped:
+			query = query.Where("task_boards.organization_id = ?", *goal.OrganizationID)
+		default:
+			query = query.Where("task_boards.clerk_user_id = ? AND task_boards.organization_id IS NULL", goal.ClerkUserID)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("failed to count completed tasks: %w", err)
+		}
+
+	case models.GoalMetricWords:
+		// Writing activity is kept by day, so partial days count whole
+		query := s.db.WithContext(ctx).Model(&models.WritingActivity{}).
+			Where("day >= ? AND day <= ?", from.Format(writingDayLayout), to.Add(-time.Nanosecond).Format(writingDayLayout))
+		if orgScoped {
+			query = query.Where("organization_id = ?", *goal.OrganizationID)
+		} else {
+			query = query.Where("clerk_user_id = ? AND organization_id = ?", goal.ClerkUserID, "")
+		}
+		if err := query.Select("COALESCE(SUM(words_written), 0)").Scan(&count).Error; err != nil {
+			return 0, fmt.Errorf("failed to count words written: %w", err)
+		}
+
+	case models.GoalMetricCustom:
+		return goal.CustomProgress, nil
+	}
+	return int(count), nil
+}
+
+// applyGoalInput validates a goal's input and sets it on the goal. Linked notebooks and boards must be
+// in the goal's scope.
+func (s *goalServiceImpl) applyGoalInput(ctx context.Context, goal *models.Goal, input GoalInput) error {
+	title := strings.TrimSpace(input.Title)
+	if title == "" || len([]rune(title)) > maxGoalTitleLength {
+		return fmt.Errorf("%w: title must be between 1 and %d characters", ErrInvalidGoal, maxGoalTitleLength)
+	}
+	if !slices.Contains(models.GoalMetrics, input.Metric) {
+		return fmt.Errorf("%w: metric must be one of %s", ErrInvalidGoal, strings.Join(models.GoalMetrics, ", "))
+	}
+	if input.Target < 1 || input.Target > maxGoalTarget {
+		return fmt.Errorf("%w: target must be between 1 and %d", ErrInvalidGoal, maxGoalTarget)
+	}
+	period := input.Period
+	if period == "" {
+		period = models.GoalPeriodWeekly
+	}
+	if !slices.Contains(models.GoalPeriods, period) {
+		return fmt.Errorf("%w: period must be one of %s", ErrInvalidGoal, strings.Join(models.GoalPeriods, ", "))
+	}
+
+	var dueDate *time.Time
+	if input.DueDate != "" {
+		parsed, err := time.Parse("2006-01-02", input.DueDate)
+		if err != nil {
+			return fmt.Errorf("%w: dueDate must be a date like 2006-01-02", ErrInvalidGoal)
+		}
+		dueDate = &parsed
+	}
+
+	notebookID := emptyToNil(input.NotebookID)
+	taskBoardID := emptyToNil(input.TaskBoardID)
+	if notebookID != nil && input.Metric != models.GoalMetricNotesWritten {
+		return fmt.Errorf("%w: only notes written goals can be linked to a notebook", ErrInvalidGoal)
+	}
+	if taskBoardID != nil && input.Metric != models.GoalMetricTasksCompleted {
+		return fmt.Errorf("%w: only tasks completed goals can be linked to a board", ErrInvalidGoal)
+	}
+	if notebookID != nil {
+		if err := s.checkGoalLink(ctx, goal, "notebooks", *notebookID); err != nil {
+			return err
+		}
+	}
+	if taskBoardID != nil {
+		if err := s.checkGoalLink(ctx, goal, "task_boards", *taskBoardID); err != nil {
+			return err
+		}
+	}
+
+	goal.Title = title
+	goal.Metric = input.Metric
+	goal.Target = input.Target
+	goal.Period = period
+	goal.NotebookID = notebookID
+	goal.TaskBoardID = taskBoardID
+	goal.DueDate = dueDate
+	return nil
+}
+
+// checkGoalLink checks a linked notebook or board belongs to the goal's organization, or to its owner
+// for personal goals
+func (s *goalServiceImpl) checkGoalLink(ctx context.Context, goal *models.Goal, table, id string) error {
+	query := s.db.WithContext(ctx).Table(table).Where("id = ?", id)
+	if goal.OrganizationID != nil && *goal.OrganizationID != "" {
+		query = query.Where("organization_id = ?", *goal.OrganizationID)
+	} else {
+		query = query.Where("clerk_user_id = ? AND organization_id IS NULL", goal.ClerkUserID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check goal link: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: the linked notebook or board isn't in the goal's workspace", ErrInvalidGoal)
+	}
+	return nil
+}
+
+// goalPeriodBounds returns the start and end of the period containing now. Goals without a recurring
+// period run from their creation until now, or their due date.
+func goalPeriodBounds(period string, now, createdAt time.Time) (time.Time, time.Time) {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case models.GoalPeriodWeekly:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case models.GoalPeriodMonthly:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return createdAt, now
+	}
+}
+
+// goalPeriodLabel describes a goal's period in summaries
+func goalPeriodLabel(period string) string {
+	switch period {
+	case models.GoalPeriodWeekly:
+		return "this week"
+	case models.GoalPeriodMonthly:
+		return "this month"
+	default:
+		return "overall"
+	}
+}
+
+// emptyToNil returns nil for nil or blank IDs
+func emptyToNil(value *string) *string {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	return &trimmed
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestGoalService creates a goal service on an in-memory database, for a user in UTC on a Wednesday
+func setupTestGoalService(t *testing.T) *goalServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.UserPreferences{}, &models.Notebook{}, &models.Chapter{}, &models.Notes{},
+		&models.TaskBoard{}, &models.Task{}, &models.WritingActivity{}, &models.Goal{}), "Failed to migrate test database")
+	require.NoError(t, db.Create(&models.UserPreferences{ClerkUserID: "user_1", Timezone: "UTC"}).Error)
+
+	return &goalServiceImpl{
+		db:  db,
+		now: func() time.Time { return time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+func TestGoalProgress(t *testing.T) {
+	service := setupTestGoalService(t)
+	ctx := context.Background()
+	scope := GoalScope{ClerkUserID: "user_1"}
+	now := service.now()
+
+	notebook := models.Notebook{Name: "Journal", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "2026", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&chapter).Error)
+	for _, created := range []time.Time{now.AddDate(0, 0, -1), now.AddDate(0, 0, -2), now.AddDate(0, 0, -5)} {
+		require.NoError(t, service.db.Create(&models.Notes{Name: "Entry", ChapterID: chapter.ID, CreatedAt: created}).Error)
+	}
+
+	board := models.TaskBoard{Name: "Chores", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&board).Error)
+	completed := now.Add(-time.Hour)
+	require.NoError(t, service.db.Create(&models.Task{Title: "Laundry", TaskBoardID: board.ID, Status: "done", CompletedAt: &completed}).Error)
+	require.NoError(t, service.db.Create(&models.Task{Title: "Dishes", TaskBoardID: board.ID}).Error)
+
+	require.NoError(t, service.db.Create(&models.WritingActivity{ClerkUserID: "user_1", Day: "2026-03-10", WordsWritten: 300}).Error)
+	require.NoError(t, service.db.Create(&models.WritingActivity{ClerkUserID: "user_1", Day: "2026-03-02", WordsWritten: 900}).Error)
+
+	notes, err := service.CreateGoal(ctx, scope, GoalInput{Title: "Journal daily", Metric: models.GoalMetricNotesWritten, Target: 5, NotebookID: &notebook.ID})
+	require.NoError(t, err)
+	assert.Equal(t, models.GoalPeriodWeekly, notes.Period)
+	assert.Equal(t, 2, notes.Current, "Weeks start on Monday")
+	assert.Equal(t, 40, notes.Percent)
+	assert.Equal(t, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), notes.PeriodStart)
+
+	tasks, err := service.CreateGoal(ctx, scope, GoalInput{Title: "Chores", Metric: models.GoalMetricTasksCompleted, Target: 1, Period: models.GoalPeriodMonthly, TaskBoardID: &board.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, tasks.Current)
+	assert.True(t, tasks.Completed)
+
+	words, err := service.CreateGoal(ctx, scope, GoalInput{Title: "Write", Metric: models.GoalMetricWords, Target: 1000, Period: models.GoalPeriodMonthly})
+	require.NoError(t, err)
+	assert.Equal(t, 1200, words.Current)
+
+	custom, err := service.CreateGoal(ctx, scope, GoalInput{Title: "Read books", Metric: models.GoalMetricCustom, Target: 12, Period: models.GoalPeriodOnce, DueDate: "2026-12-31"})
+	require.NoError(t, err)
+	custom, err = service.SetCustomProgress(ctx, custom.ID, "user_1", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, custom.Current)
+	_, err = service.SetCustomProgress(ctx, notes.ID, "user_1", 3)
+	assert.ErrorIs(t, err, ErrInvalidGoal, "Computed goals aren't updated by hand")
+
+	goals, err := service.ListGoals(ctx, scope)
+	require.NoError(t, err)
+	assert.Len(t, goals, 4)
+	goals, err = service.ListGoals(ctx, GoalScope{ClerkUserID: "user_2"})
+	require.NoError(t, err)
+	assert.Empty(t, goals)
+
+	summary, err := service.WeeklySummary(ctx, scope)
+	require.NoError(t, err)
+	require.Len(t, summary.Goals, 4)
+	assert.Equal(t, 2, summary.Goals[0].ThisWeek)
+	assert.Equal(t, 1, summary.Goals[0].LastWeek)
+	assert.Equal(t, 900, summary.Goals[2].LastWeek)
+	assert.True(t, strings.HasPrefix(summary.Text, "- Journal daily: 2 this week (1 last week), 2/5 this week\n"), summary.Text)
+	assert.Contains(t, summary.Text, "- Read books: 3/12 overall\n")
+}
+
+func TestGoalValidation(t *testing.T) {
+	service := setupTestGoalService(t)
+	ctx := context.Background()
+	scope := GoalScope{ClerkUserID: "user_1"}
+
+	othersNotebook := models.Notebook{Name: "Private", ClerkUserID: "user_2"}
+	require.NoError(t, service.db.Create(&othersNotebook).Error)
+
+	for name, input := range map[string]GoalInput{
+		"missing title":       {Metric: models.GoalMetricWords, Target: 10},
+		"unknown metric":      {Title: "Goal", Metric: "pages", Target: 10},
+		"no target":           {Title: "Goal", Metric: models.GoalMetricWords},
+		"unknown period":      {Title: "Goal", Metric: models.GoalMetricWords, Target: 10, Period: "daily"},
+		"bad due date":        {Title: "Goal", Metric: models.GoalMetricWords, Target: 10, DueDate: "soon"},
+		"notebook for words":  {Title: "Goal", Metric: models.GoalMetricWords, Target: 10, NotebookID: &othersNotebook.ID},
+		"someone's notebook":  {Title: "Goal", Metric: models.GoalMetricNotesWritten, Target: 10, NotebookID: &othersNotebook.ID},
+		"board for notes":     {Title: "Goal", Metric: models.GoalMetricNotesWritten, Target: 10, TaskBoardID: &othersNotebook.ID},
+		"target out of range": {Title: "Goal", Metric: models.GoalMetricCustom, Target: maxGoalTarget + 1},
+	} {
+		_, err := service.CreateGoal(ctx, scope, input)
+		assert.ErrorIs(t, err, ErrInvalidGoal, name)
+	}
+
+	goal, err := service.CreateGoal(ctx, scope, GoalInput{Title: "Goal", Metric: models.GoalMetricWords, Target: 10})
+	require.NoError(t, err)
+	updated, err := service.UpdateGoal(ctx, goal.ID, "user_1", GoalInput{Title: " Renamed ", Metric: models.GoalMetricWords, Target: 20})
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.Title)
+	assert.Equal(t, 20, updated.Target)
+
+	require.NoError(t, service.DeleteGoal(ctx, goal.ID))
+	assert.ErrorIs(t, service.DeleteGoal(ctx, goal.ID), ErrGoalNotFound)
+}
@@ -0,0 +1,438 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"backend/internal/models"
+	internalutils "backend/internal/utils"
+	"backend/pkg/googledrive"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDriveNotConfigured is returned when no Google OAuth client is configured for Drive
+	ErrDriveNotConfigured = errors.New("google drive integration is not configured")
+	// ErrDriveNotConnected is returned when the user hasn't connected Google Drive
+	ErrDriveNotConnected = errors.New("google drive is not connected")
+)
+
+const (
+	maxDriveImportFiles = 100
+	maxDriveFolderDepth = 3
+)
+
+// driveImageDataPattern matches images Drive inlines as data URIs in Markdown exports
+var driveImageDataPattern = regexp.MustCompile(`!\[[^\]]*\]\(data:[^)]*\)`)
+
+// driveAPI is the part of the Drive API used for imports
+type driveAPI interface {
+	ListFiles(ctx context.Context, options googledrive.ListFilesOptions) (*googledrive.FileList, error)
+	GetFile(ctx context.Context, fileID string) (*googledrive.File, error)
+	ExportFile(ctx context.Context, fileID, mimeType string) (string, error)
+}
+
+// DriveImportResult summarizes an import from Google Drive
+type DriveImportResult struct {
+	Imported int                      `json:"imported"`
+	Updated  int                      `json:"updated"`
+	Skipped  int                      `json:"skipped"`
+	Imports  []models.GoogleDocImport `json:"imports"`
+}
+
+// DriveRefreshResult summarizes a refresh of imported Docs
+type DriveRefreshResult struct {
+	Checked int `json:"checked"`
+	Updated int `json:"updated"`
+	Missing int `json:"missing"`
+	Skipped int `json:"skipped"`
+}
+
+// GoogleDriveService interface defines methods for importing Google Docs
+type GoogleDriveService interface {
+	GetConnection(clerkUserID string) (*models.GoogleDriveConnection, error)
+	SaveConnection(clerkUserID, email, refreshToken string) error
+	Disconnect(clerkUserID string) error
+	ListFiles(ctx context.Context, clerkUserID string, options googledrive.ListFilesOptions) (*googledrive.FileList, error)
+	ImportFiles(ctx context.Context, clerkUserID, chapterID string, fileIDs []string) (*DriveImportResult, error)
+	ListImports(clerkUserID string) ([]models.GoogleDocImport, error)
+	RefreshImports(ctx context.Context, clerkUserID string, importIDs []string, force bool) (*DriveRefreshResult, error)
+}
+
+// googleDriveServiceImpl implements the GoogleDriveService interface
+type googleDriveServiceImpl struct {
+	db          *gorm.DB
+	newClient   func(ctx context.Context, refreshToken string) (driveAPI, error)
+	canEditNote func(ctx context.Context, db *gorm.DB, noteID, clerkUserID string) (bool, error)
+}
+
+// NewGoogleDriveService creates a new GoogleDriveService instance
+func NewGoogleDriveService() GoogleDriveService {
+	driveConfig := config.LoadGoogleDriveConfig()
+	return &googleDriveServiceImpl{
+		db: db.DB,
+		newClient: func(ctx context.Context, refreshToken string) (driveAPI, error) {
+			if !driveConfig.IsConfigured() {
+				return nil, ErrDriveNotConfigured
+			}
+			token, err := googledrive.RefreshAccessToken(ctx, driveConfig.ClientID, driveConfig.ClientSecret, refreshToken)
+			if err != nil {
+				return nil, err
+			}
+			return googledrive.NewClient(token.AccessToken), nil
+		},
+		canEditNote: accessLookups.CanEditNote,
+	}
+}
+
+// GetConnection returns the user's Drive connection, or nil if Drive isn't connected
+func (s *googleDriveServiceImpl) GetConnection(clerkUserID string) (*models.GoogleDriveConnection, error) {
+	var connection models.GoogleDriveConnection
+	if err := s.db.Where("clerk_user_id = ?", clerkUserID).Limit(1).Find(&connection).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch drive connection: %w", err)
+	}
+	if connection.ID == "" {
+		return nil, nil
+	}
+	return &connection, nil
+}
+
+// SaveConnection stores the user's Drive authorization, encrypting the refresh token
+func (s *googleDriveServiceImpl) SaveConnection(clerkUserID, email, refreshToken string) error {
+	columns := map[string]interface{}{"email": email}
+	if refreshToken != "" {
+		encrypted, err := NewSecretsService().EncryptString(refreshToken)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt refresh token: %w", err)
+		}
+		columns["oauth_refresh_token"] = encrypted
+	}
+
+	connection := models.GoogleDriveConnection{ClerkUserID: clerkUserID}
+	if err := s.db.Where(models.GoogleDriveConnection{ClerkUserID: clerkUserID}).
+		Assign(columns).
+		FirstOrCreate(&connection).Error; err != nil {
+		return fmt.Errorf("failed to save drive connection: %w", err)
+	}
+	return nil
+}
+
+// Disconnect removes the user's Drive authorization. Imported notes and their mappings are kept.
+func (s *googleDriveServiceImpl) Disconnect(clerkUserID string) error {
+	if err := s.db.Where("clerk_user_id = ?", clerkUserID).Delete(&models.GoogleDriveConnection{}).Error; err != nil {
+		return fmt.Errorf("failed to delete drive connection: %w", err)
+	}
+	return nil
+}
+
+// ListFiles lists the Docs and folders the user can pick from
+func (s *googleDriveServiceImpl) ListFiles(ctx context.Context, clerkUserID string, options googledrive.ListFilesOptions) (*googledrive.FileList, error) {
+	api, err := s.client(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	return api.ListFiles(ctx, options)
+}
+
+// ImportFiles imports Docs, and the Docs inside folders, into a chapter.
+// Docs that were imported before are refreshed in place instead of being duplicated.
+func (s *googleDriveServiceImpl) ImportFiles(ctx context.Context, clerkUserID, chapterID string, fileIDs []string) (*DriveImportResult, error) {
+	var chapter models.Chapter
+	if err := s.db.Where("id = ?", chapterID).First(&chapter).Error; err != nil {
+		return nil, fmt.Errorf("failed to find chapter: %w", err)
+	}
+
+	api, err := s.client(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DriveImportResult{Imports: []models.GoogleDocImport{}}
+	seen := make(map[string]bool)
+	var documents []googledrive.File
+
+	for _, fileID := range fileIDs {
+		file, err := api.GetFile(ctx, fileID)
+		if err != nil {
+			log.Warn().Err(err).Str("file_id", fileID).Msg("Failed to fetch drive file")
+			result.Skipped++
+			continue
+		}
+
+		switch {
+		case file.IsDocument():
+			documents = appendDriveDocument(documents, seen, *file)
+		case file.IsFolder():
+			folderDocuments, err := s.collectFolderDocuments(ctx, api, file.ID, 1)
+			if err != nil {
+				return nil, err
+			}
+			for _, document := range folderDocuments {
+				documents = appendDriveDocument(documents, seen, document)
+			}
+		default:
+			result.Skipped++
+		}
+	}
+
+	if len(documents) > maxDriveImportFiles {
+		result.Skipped += len(documents) - maxDriveImportFiles
+		documents = documents[:maxDriveImportFiles]
+	}
+
+	for i := range documents {
+		file := &documents[i]
+
+		var mapping models.GoogleDocImport
+		if err := s.db.Where("clerk_user_id = ? AND drive_file_id = ?", clerkUserID, file.ID).Limit(1).Find(&mapping).Error; err != nil {
+			return nil, fmt.Errorf("failed to look up drive import: %w", err)
+		}
+
+		if mapping.ID != "" {
+			if err := s.syncDocument(ctx, api, &mapping, file); err != nil {
+				log.Warn().Err(err).Str("file_id", file.ID).Msg("Failed to re-import Google Doc")
+				result.Skipped++
+				continue
+			}
+			result.Updated++
+		} else {
+			imported, err := s.importDocument(ctx, api, clerkUserID, &chapter, file)
+			if err != nil {
+				log.Warn().Err(err).Str("file_id", file.ID).Msg("Failed to import Google Doc")
+				result.Skipped++
+				continue
+			}
+			mapping = *imported
+			result.Imported++
+		}
+		result.Imports = append(result.Imports, mapping)
+	}
+
+	return result, nil
+}
+
+// ListImports returns the user's imported Docs, most recently synced first
+func (s *googleDriveServiceImpl) ListImports(clerkUserID string) ([]models.GoogleDocImport, error) {
+	var imports []models.GoogleDocImport
+	if err := s.db.Where("clerk_user_id = ?", clerkUserID).Order("last_synced_at DESC").Find(&imports).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch drive imports: %w", err)
+	}
+	return imports, nil
+}
+
+// RefreshImports re-imports Docs that changed in Drive since they were last synced.
+// With force every Doc is re-imported; with import IDs only those are checked. Docs whose notes the user
+// can no longer edit are skipped.
+func (s *googleDriveServiceImpl) RefreshImports(ctx context.Context, clerkUserID string, importIDs []string, force bool) (*DriveRefreshResult, error) {
+	query := s.db.Where("clerk_user_id = ?", clerkUserID)
+	if len(importIDs) > 0 {
+		query = query.Where("id IN ?", importIDs)
+	}
+
+	var mappings []models.GoogleDocImport
+	if err := query.Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch drive imports: %w", err)
+	}
+
+	result := &DriveRefreshResult{}
+	if len(mappings) == 0 {
+		return result, nil
+	}
+
+	api, err := s.client(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range mappings {
+		mapping := &mappings[i]
+		result.Checked++
+
+		if canEdit, err := s.canEditNote(ctx, s.db, mapping.NoteID, clerkUserID); err != nil || !canEdit {
+			result.Skipped++
+			continue
+		}
+
+		file, err := api.GetFile(ctx, mapping.DriveFileID)
+		if errors.Is(err, googledrive.ErrNotFound) {
+			s.db.Model(mapping).Update("status", models.GoogleDocImportStatusMissing)
+			result.Missing++
+			continue
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("file_id", mapping.DriveFileID).Msg("Failed to fetch drive file")
+			result.Skipped++
+			continue
+		}
+
+		if !force && !file.ModifiedTime.After(mapping.DriveModifiedTime) && mapping.Status == models.GoogleDocImportStatusSynced {
+			continue
+		}
+
+		if err := s.syncDocument(ctx, api, mapping, file); err != nil {
+			log.Warn().Err(err).Str("file_id", mapping.DriveFileID).Msg("Failed to refresh Google Doc")
+			result.Skipped++
+			continue
+		}
+		result.Updated++
+	}
+
+	return result, nil
+}
+
+// client creates a Drive client for the user's stored authorization
+func (s *googleDriveServiceImpl) client(ctx context.Context, clerkUserID string) (driveAPI, error) {
+	connection, err := s.GetConnection(clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	if connection == nil || connection.OAuthRefreshToken == "" {
+		return nil, ErrDriveNotConnected
+	}
+
+	refreshToken, err := NewSecretsService().DecryptString(connection.OAuthRefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt refresh token: %w", err)
+	}
+	return s.newClient(ctx, refreshToken)
+}
+
+// collectFolderDocuments lists the Docs in a folder and its subfolders
+func (s *googleDriveServiceImpl) collectFolderDocuments(ctx context.Context, api driveAPI, folderID string, depth int) ([]googledrive.File, error) {
+	var documents []googledrive.File
+	pageToken := ""
+
+	for {
+		list, err := api.ListFiles(ctx, googledrive.ListFilesOptions{FolderID: folderID, PageToken: pageToken, PageSize: 100})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list drive folder: %w", err)
+		}
+
+		for _, file := range list.Files {
+			switch {
+			case file.IsDocument():
+				documents = append(documents, file)
+			case file.IsFolder() && depth < maxDriveFolderDepth:
+				nested, err := s.collectFolderDocuments(ctx, api, file.ID, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				documents = append(documents, nested...)
+			}
+			if len(documents) > maxDriveImportFiles {
+				return documents, nil
+			}
+		}
+
+		if list.NextPageToken == "" {
+			return documents, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
+
+// importDocument creates a note for a Doc and records the mapping
+func (s *googleDriveServiceImpl) importDocument(ctx context.Context, api driveAPI, clerkUserID string, chapter *models.Chapter, file *googledrive.File) (*models.GoogleDocImport, error) {
+	content, err := exportDriveDocument(ctx, api, file.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := &models.GoogleDocImport{
+		ClerkUserID:       clerkUserID,
+		DriveFileID:       file.ID,
+		Name:              file.Name,
+		WebViewLink:       file.WebViewLink,
+		Status:            models.GoogleDocImportStatusSynced,
+		DriveModifiedTime: file.ModifiedTime,
+		LastSyncedAt:      time.Now(),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		note := models.Notes{
+			Name:           file.Name,
+			Content:        content,
+			ChapterID:      chapter.ID,
+			OrganizationID: chapter.OrganizationID,
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			return fmt.Errorf("failed to create note: %w", err)
+		}
+
+		mapping.NoteID = note.ID
+		if err := tx.Create(mapping).Error; err != nil {
+			return fmt.Errorf("failed to save drive import: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// syncDocument replaces an imported note's content with the Doc's current content
+func (s *googleDriveServiceImpl) syncDocument(ctx context.Context, api driveAPI, mapping *models.GoogleDocImport, file *googledrive.File) error {
+	content, err := exportDriveDocument(ctx, api, file.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Notes{}).Where("id = ?", mapping.NoteID).Updates(map[string]interface{}{
+			"name":    file.Name,
+			"content": content,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update note: %w", err)
+		}
+
+		mapping.Name = file.Name
+		mapping.WebViewLink = file.WebViewLink
+		mapping.Status = models.GoogleDocImportStatusSynced
+		mapping.DriveModifiedTime = file.ModifiedTime
+		mapping.LastSyncedAt = time.Now()
+		if err := tx.Save(mapping).Error; err != nil {
+			return fmt.Errorf("failed to save drive import: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Drop the collaborative state so editors load the refreshed content
+	if err := NewYjsService(s.db).DeleteYjsDocument(mapping.NoteID); err != nil {
+		log.Warn().Err(err).Str("note_id", mapping.NoteID).Msg("Failed to reset Yjs document after Drive refresh")
+	}
+	return nil
+}
+
+// exportDriveDocument exports a Doc as Markdown and converts it to TipTap JSON
+func exportDriveDocument(ctx context.Context, api driveAPI, fileID string) (string, error) {
+	markdown, err := api.ExportFile(ctx, fileID, googledrive.ExportMimeTypeMarkdown)
+	if err != nil {
+		return "", fmt.Errorf("failed to export document: %w", err)
+	}
+
+	markdown = driveImageDataPattern.ReplaceAllString(markdown, "")
+	content, err := internalutils.MarkdownToTipTap(markdown)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert document: %w", err)
+	}
+	return content, nil
+}
+
+// appendDriveDocument adds a Doc unless it was already collected
+func appendDriveDocument(documents []googledrive.File, seen map[string]bool, file googledrive.File) []googledrive.File {
+	if seen[file.ID] {
+		return documents
+	}
+	seen[file.ID] = true
+	return append(documents, file)
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/pkg/googledrive"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeDriveAPI serves files and exports from memory
+type fakeDriveAPI struct {
+	files   map[string]googledrive.File
+	exports map[string]string
+}
+
+func (f *fakeDriveAPI) ListFiles(ctx context.Context, options googledrive.ListFilesOptions) (*googledrive.FileList, error) {
+	list := &googledrive.FileList{}
+	for _, file := range f.files {
+		for _, parent := range file.Parents {
+			if parent == options.FolderID {
+				list.Files = append(list.Files, file)
+			}
+		}
+	}
+	return list, nil
+}
+
+func (f *fakeDriveAPI) GetFile(ctx context.Context, fileID string) (*googledrive.File, error) {
+	file, exists := f.files[fileID]
+	if !exists {
+		return nil, googledrive.ErrNotFound
+	}
+	return &file, nil
+}
+
+func (f *fakeDriveAPI) ExportFile(ctx context.Context, fileID, mimeType string) (string, error) {
+	return f.exports[fileID], nil
+}
+
+// setupTestGoogleDriveService creates a Drive service with a connected user and an empty chapter
+func setupTestGoogleDriveService(t *testing.T) (*googleDriveServiceImpl, *fakeDriveAPI, *models.Chapter) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(
+		&models.Notebook{},
+		&models.Chapter{},
+		&models.Notes{},
+		&models.GoogleDriveConnection{},
+		&models.GoogleDocImport{},
+		&models.YjsDocument{},
+		&models.YjsUpdate{},
+	)
+	require.NoError(t, err, "Failed to migrate test database")
+
+	modified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	api := &fakeDriveAPI{
+		files: map[string]googledrive.File{
+			"doc_1":    {ID: "doc_1", Name: "Roadmap", MimeType: googledrive.MimeTypeDocument, ModifiedTime: modified},
+			"folder_1": {ID: "folder_1", Name: "Specs", MimeType: googledrive.MimeTypeFolder},
+			"doc_2":    {ID: "doc_2", Name: "API spec", MimeType: googledrive.MimeTypeDocument, ModifiedTime: modified, Parents: []string{"folder_1"}},
+			"sheet_1":  {ID: "sheet_1", Name: "Budget", MimeType: "application/vnd.google-apps.spreadsheet"},
+		},
+		exports: map[string]string{
+			"doc_1": "# Roadmap\n\nShip it ![chart](data:image/png;base64,AAAA)",
+			"doc_2": "# API spec",
+		},
+	}
+
+	var requestedToken string
+	service := &googleDriveServiceImpl{
+		db: db,
+		newClient: func(ctx context.Context, refreshToken string) (driveAPI, error) {
+			requestedToken = refreshToken
+			return api, nil
+		},
+		canEditNote: func(ctx context.Context, db *gorm.DB, noteID, clerkUserID string) (bool, error) {
+			return true, nil
+		},
+	}
+	require.NoError(t, service.SaveConnection("user_1", "user@example.com", "refresh-token"))
+	t.Cleanup(func() {
+		assert.Equal(t, "refresh-token", requestedToken, "The stored refresh token should be decrypted for API calls")
+	})
+
+	notebook := models.Notebook{Name: "Work", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Docs", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+
+	return service, api, &chapter
+}
+
+func TestSaveConnection_EncryptsRefreshToken(t *testing.T) {
+	service, _, _ := setupTestGoogleDriveService(t)
+
+	connection, err := service.GetConnection("user_1")
+	require.NoError(t, err)
+	require.NotNil(t, connection)
+	assert.Equal(t, "user@example.com", connection.Email)
+	assert.NotEqual(t, "refresh-token", connection.OAuthRefreshToken)
+
+	_, err = service.ListFiles(context.Background(), "user_1", googledrive.ListFilesOptions{})
+	require.NoError(t, err)
+
+	_, err = service.ListFiles(context.Background(), "user_2", googledrive.ListFilesOptions{})
+	assert.ErrorIs(t, err, ErrDriveNotConnected)
+}
+
+func TestImportFiles_ImportsDocsAndFolders(t *testing.T) {
+	service, _, chapter := setupTestGoogleDriveService(t)
+
+	result, err := service.ImportFiles(context.Background(), "user_1", chapter.ID, []string{"doc_1", "folder_1", "sheet_1", "doc_1"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 1, result.Skipped, "Only Docs can be imported")
+
+	var notes []models.Notes
+	require.NoError(t, service.db.Where("chapter_id = ?", chapter.ID).Order("name").Find(&notes).Error)
+	require.Len(t, notes, 2)
+	assert.Equal(t, "API spec", notes[0].Name)
+	assert.Equal(t, "Roadmap", notes[1].Name)
+	assert.Contains(t, notes[1].Content, "Ship it")
+	assert.NotContains(t, notes[1].Content, "base64", "Inline images should be dropped")
+
+	// Importing again refreshes the existing notes instead of duplicating them
+	result, err = service.ImportFiles(context.Background(), "user_1", chapter.ID, []string{"doc_1"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 1, result.Updated)
+
+	var count int64
+	service.db.Model(&models.Notes{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestRefreshImports_UpdatesChangedDocs(t *testing.T) {
+	service, api, chapter := setupTestGoogleDriveService(t)
+
+	_, err := service.ImportFiles(context.Background(), "user_1", chapter.ID, []string{"doc_1", "doc_2"})
+	require.NoError(t, err)
+
+	// Unchanged Docs are left alone
+	result, err := service.RefreshImports(context.Background(), "user_1", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Checked)
+	assert.Equal(t, 0, result.Updated)
+
+	doc := api.files["doc_1"]
+	doc.Name = "Roadmap 2025"
+	doc.ModifiedTime = doc.ModifiedTime.Add(time.Hour)
+	api.files["doc_1"] = doc
+	api.exports["doc_1"] = "Updated plan"
+	delete(api.files, "doc_2")
+
+	result, err = service.RefreshImports(context.Background(), "user_1", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Missing)
+
+	imports, err := service.ListImports("user_1")
+	require.NoError(t, err)
+	statuses := make(map[string]string)
+	for _, imported := range imports {
+		statuses[imported.DriveFileID] = imported.Status
+		if imported.DriveFileID == "doc_1" {
+			var note models.Notes
+			require.NoError(t, service.db.First(&note, "id = ?", imported.NoteID).Error)
+			assert.Equal(t, "Roadmap 2025", note.Name)
+			assert.Contains(t, note.Content, "Updated plan")
+		}
+	}
+	assert.Equal(t, models.GoogleDocImportStatusSynced, statuses["doc_1"])
+	assert.Equal(t, models.GoogleDocImportStatusMissing, statuses["doc_2"])
+}
+
+func TestRefreshImports_SkipsNotesTheUserCantEdit(t *testing.T) {
+	service, api, chapter := setupTestGoogleDriveService(t)
+
+	result, err := service.ImportFiles(context.Background(), "user_1", chapter.ID, []string{"doc_1"})
+	require.NoError(t, err)
+	require.Len(t, result.Imports, 1)
+	service.canEditNote = func(ctx context.Context, db *gorm.DB, noteID, clerkUserID string) (bool, error) {
+		return false, nil
+	}
+
+	api.exports["doc_1"] = "Updated plan"
+	refresh, err := service.RefreshImports(context.Background(), "user_1", nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, refresh.Skipped)
+	assert.Equal(t, 0, refresh.Updated)
+
+	var note models.Notes
+	require.NoError(t, service.db.First(&note, "id = ?", result.Imports[0].NoteID).Error)
+	assert.NotContains(t, note.Content, "Updated plan")
+}
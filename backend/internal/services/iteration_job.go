@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// IterationJob periodically closes iterations whose last day has ended, rolling their open tasks over
+type IterationJob struct {
+	iterationService IterationService
+	interval         time.Duration
+	stopChan         chan struct{}
+}
+
+// NewIterationJob creates a new scheduled iteration job
+func NewIterationJob(iterationService IterationService, interval time.Duration) *IterationJob {
+	return &IterationJob{
+		iterationService: iterationService,
+		interval:         interval,
+		stopChan:         make(chan struct{}),
+	}
+}
+
+// Start begins checking for iterations to close
+func (j *IterationJob) Start(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Msg("Starting iteration scheduler")
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if closed := j.iterationService.CloseDueIterations(time.Now()); closed > 0 {
+				log.Info().Int("closed", closed).Msg("Closed ended iterations")
+			}
+		case <-ctx.Done():
+			log.Info().Msg("Stopping iteration scheduler (context cancelled)")
+			return
+		case <-j.stopChan:
+			log.Info().Msg("Stopping iteration scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (j *IterationJob) Stop() {
+	close(j.stopChan)
+}
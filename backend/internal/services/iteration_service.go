@@ -0,0 +1,418 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// maxIterationDays caps the length of an iteration
+const maxIterationDays = 366
+
+var (
+	// ErrInvalidIteration is returned for an iteration without a name or with dates out of order
+	ErrInvalidIteration = errors.New("invalid iteration")
+	// ErrIterationNotFound is returned when the board has no iteration with the ID
+	ErrIterationNotFound = errors.New("iteration not found")
+	// ErrIterationsRequireOrganization is returned for boards outside an organization
+	ErrIterationsRequireOrganization = errors.New("iterations are only available on organization boards")
+	// ErrIterationClosed is returned when changing or assigning tasks to a closed iteration
+	ErrIterationClosed = errors.New("iteration is closed")
+)
+
+// IterationInput is the request body for creating or updating an iteration
+type IterationInput struct {
+	Name      string `json:"name"`
+	StartDate string `json:"startDate"` // 2006-01-02
+	EndDate   string `json:"endDate"`   // 2006-01-02, the last day of the iteration
+}
+
+// IterationResponse is an iteration with its status and task counts
+type IterationResponse struct {
+	models.Iteration
+	Status         string `json:"status"` // "planned", "active" or "closed"
+	TaskCount      int64  `json:"taskCount"`
+	CompletedCount int64  `json:"completedCount"`
+}
+
+// IterationCloseResult reports where a closed iteration's open tasks went
+type IterationCloseResult struct {
+	Iteration       models.Iteration  `json:"iteration"`
+	RolledOverTo    *models.Iteration `json:"rolledOverTo,omitempty"`
+	RolledOverTasks int               `json:"rolledOverTasks"`
+}
+
+// BurndownPoint is the work left at the end of a day of an iteration. Remaining values are nil for
+// days that haven't ended yet.
+type BurndownPoint struct {
+	Date          time.Time `json:"date"`
+	Remaining     *int      `json:"remaining"`
+	RemainingDays *int      `json:"remainingDays"`
+	Ideal         float64   `json:"ideal"`
+}
+
+// IterationBurndown is the burndown chart data of an iteration, counted in tasks and in estimated days
+type IterationBurndown struct {
+	Iteration  models.Iteration `json:"iteration"`
+	TotalTasks int              `json:"totalTasks"`
+	TotalDays  int              `json:"totalDays"`
+	Completed  int              `json:"completed"`
+	Points     []BurndownPoint  `json:"points"`
+}
+
+// IterationService interface defines methods for board iterations
+type IterationService interface {
+	CreateIteration(boardID, createdBy string, input IterationInput) (*models.Iteration, error)
+	ListIterations(boardID string, now time.Time) ([]IterationResponse, error)
+	UpdateIteration(boardID, iterationID string, input IterationInput) (*models.Iteration, error)
+	DeleteIteration(boardID, iterationID string) error
+	SetTaskIteration(taskID, iterationID string) error
+	CloseIteration(boardID, iterationID, rollToID string, now time.Time) (*IterationCloseResult, error)
+	CloseDueIterations(now time.Time) int
+	GetBurndown(boardID, iterationID string, now time.Time) (*IterationBurndown, error)
+}
+
+// iterationServiceImpl implements the IterationService interface
+type iterationServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewIterationService creates a new IterationService instance
+func NewIterationService() IterationService {
+	return &iterationServiceImpl{
+		db: db.DB,
+	}
+}
+
+// CreateIteration adds an iteration to an organization board
+func (s *iterationServiceImpl) CreateIteration(boardID, createdBy string, input IterationInput) (*models.Iteration, error) {
+	var board models.TaskBoard
+	if err := s.db.Select("id", "organization_id").Where("id = ?", boardID).First(&board).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch task board: %w", err)
+	}
+	if board.OrganizationID == nil || *board.OrganizationID == "" {
+		return nil, ErrIterationsRequireOrganization
+	}
+
+	iteration := models.Iteration{TaskBoardID: boardID, OrganizationID: board.OrganizationID, CreatedBy: createdBy}
+	if err := applyIterationInput(&iteration, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&iteration).Error; err != nil {
+		return nil, fmt.Errorf("failed to create iteration: %w", err)
+	}
+	return &iteration, nil
+}
+
+// ListIterations returns a board's iterations by start date with their task counts
+func (s *iterationServiceImpl) ListIterations(boardID string, now time.Time) ([]IterationResponse, error) {
+	var iterations []models.Iteration
+	if err := s.db.Where("task_board_id = ?", boardID).Order("start_date ASC, created_at ASC").Find(&iterations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch iterations: %w", err)
+	}
+
+	var counts []struct {
+		IterationID string
+		Total       int64
+		Completed   int64
+	}
+	if err := s.db.Model(&models.Task{}).
+		Select("iteration_id, COUNT(*) AS total, SUM(CASE WHEN status = 'done' THEN 1 ELSE 0 END) AS completed").
+		Where("task_board_id = ? AND iteration_id IS NOT NULL", boardID).
+		Group("iteration_id").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count iteration tasks: %w", err)
+	}
+	totals := make(map[string][2]int64, len(counts))
+	for _, count := range counts {
+		totals[count.IterationID] = [2]int64{count.Total, count.Completed}
+	}
+
+	responses := make([]IterationResponse, len(iterations))
+	for i, iteration := range iterations {
+		responses[i] = IterationResponse{
+			Iteration:      iteration,
+			Status:         iterationStatus(&iteration, now),
+			TaskCount:      totals[iteration.ID][0],
+			CompletedCount: totals[iteration.ID][1],
+		}
+	}
+	return responses, nil
+}
+
+// UpdateIteration renames or reschedules an open iteration
+func (s *iterationServiceImpl) UpdateIteration(boardID, iterationID string, input IterationInput) (*models.Iteration, error) {
+	iteration, err := s.findIteration(s.db, boardID, iterationID)
+	if err != nil {
+		return nil, err
+	}
+	if iteration.ClosedAt != nil {
+		return nil, ErrIterationClosed
+	}
+	if err := applyIterationInput(iteration, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(iteration).Select("name", "start_date", "end_date").Updates(iteration).Error; err != nil {
+		return nil, fmt.Errorf("failed to update iteration: %w", err)
+	}
+	return iteration, nil
+}
+
+// DeleteIteration removes an iteration, moving its tasks back to the board's backlog
+func (s *iterationServiceImpl) DeleteIteration(boardID, iterationID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.findIteration(tx, boardID, iterationID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Task{}).Where("iteration_id = ?", iterationID).Update("iteration_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to unassign iteration tasks: %w", err)
+		}
+		if err := tx.Model(&models.Iteration{}).Where("rolled_over_to = ?", iterationID).Update("rolled_over_to", nil).Error; err != nil {
+			return fmt.Errorf("failed to update rolled over iterations: %w", err)
+		}
+		if err := tx.Delete(&models.Iteration{}, "id = ?", iterationID).Error; err != nil {
+			return fmt.Errorf("failed to delete iteration: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetTaskIteration assigns a task to an open iteration of its board, or back to the backlog when the
+// iteration ID is empty
+func (s *iterationServiceImpl) SetTaskIteration(taskID, iterationID string) error {
+	var task models.Task
+	if err := s.db.Select("id", "task_board_id").Where("id = ?", taskID).First(&task).Error; err != nil {
+		return fmt.Errorf("failed to fetch task: %w", err)
+	}
+
+	var value interface{}
+	if iterationID != "" {
+		iteration, err := s.findIteration(s.db, task.TaskBoardID, iterationID)
+		if err != nil {
+			return err
+		}
+		if iteration.ClosedAt != nil {
+			return ErrIterationClosed
+		}
+		value = iterationID
+	}
+
+	if err := s.db.Model(&models.Task{}).Where("id = ?", taskID).Update("iteration_id", value).Error; err != nil {
+		return fmt.Errorf("failed to update task iteration: %w", err)
+	}
+	return nil
+}
+
+// CloseIteration closes an iteration and rolls its open tasks over to rollToID, or when that's empty
+// to the board's next open iteration, or to the backlog when there is none
+func (s *iterationServiceImpl) CloseIteration(boardID, iterationID, rollToID string, now time.Time) (*IterationCloseResult, error) {
+	var result *IterationCloseResult
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		iteration, err := s.findIteration(tx, boardID, iterationID)
+		if err != nil {
+			return err
+		}
+		if iteration.ClosedAt != nil {
+			return ErrIterationClosed
+		}
+
+		var target *models.Iteration
+		if rollToID != "" {
+			if rollToID == iterationID {
+				return fmt.Errorf("%w: open tasks can't roll over to the iteration being closed", ErrInvalidIteration)
+			}
+			if target, err = s.findIteration(tx, boardID, rollToID); err != nil {
+				return err
+			}
+			if target.ClosedAt != nil {
+				return ErrIterationClosed
+			}
+		} else {
+			var next models.Iteration
+			err := tx.Where("task_board_id = ? AND id <> ? AND closed_at IS NULL AND start_date >= ?", boardID, iterationID, iteration.StartDate).
+				Order("start_date ASC, created_at ASC").
+				First(&next).Error
+			if err == nil {
+				target = &next
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("failed to find next iteration: %w", err)
+			}
+		}
+
+		var openTaskIDs []string
+		if err := tx.Model(&models.Task{}).Where("iteration_id = ? AND status <> ?", iterationID, "done").Pluck("id", &openTaskIDs).Error; err != nil {
+			return fmt.Errorf("failed to fetch open tasks: %w", err)
+		}
+		if len(openTaskIDs) > 0 {
+			var value interface{}
+			if target != nil {
+				value = target.ID
+			}
+			if err := tx.Model(&models.Task{}).Where("id IN ?", openTaskIDs).Update("iteration_id", value).Error; err != nil {
+				return fmt.Errorf("failed to roll over open tasks: %w", err)
+			}
+		}
+
+		closedAt := now
+		iteration.ClosedAt = &closedAt
+		iteration.RolledOverTaskIDs = strings.Join(openTaskIDs, ",")
+		iteration.RolledOverTo = nil
+		if target != nil {
+			iteration.RolledOverTo = &target.ID
+		}
+		if err := tx.Model(iteration).Select("closed_at", "rolled_over_task_ids", "rolled_over_to").Updates(iteration).Error; err != nil {
+			return fmt.Errorf("failed to close iteration: %w", err)
+		}
+
+		result = &IterationCloseResult{Iteration: *iteration, RolledOverTo: target, RolledOverTasks: len(openTaskIDs)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("iteration_id", iterationID).
+		Int("rolled_over", result.RolledOverTasks).
+		Msg("Iteration closed")
+	return result, nil
+}
+
+// CloseDueIterations closes the iterations whose last day has ended and returns how many were closed
+func (s *iterationServiceImpl) CloseDueIterations(now time.Time) int {
+	var due []models.Iteration
+	if err := s.db.Select("id", "task_board_id").
+		Where("closed_at IS NULL AND end_date < ?", truncateToDay(now)).
+		Order("end_date ASC").
+		Find(&due).Error; err != nil {
+		log.Error().Err(err).Msg("Failed to fetch due iterations")
+		return 0
+	}
+
+	closed := 0
+	for _, iteration := range due {
+		if _, err := s.CloseIteration(iteration.TaskBoardID, iteration.ID, "", now); err != nil {
+			log.Warn().Err(err).Str("iteration_id", iteration.ID).Msg("Failed to close iteration")
+			continue
+		}
+		closed++
+	}
+	return closed
+}
+
+// GetBurndown returns the tasks and estimated days left at the end of each day of an iteration. Tasks
+// that rolled over when it closed count as never completed.
+func (s *iterationServiceImpl) GetBurndown(boardID, iterationID string, now time.Time) (*IterationBurndown, error) {
+	iteration, err := s.findIteration(s.db, boardID, iterationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []models.Task
+	if err := s.db.Select("id", "duration_days", "completed_at").Where("iteration_id = ?", iterationID).Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch iteration tasks: %w", err)
+	}
+	if iteration.RolledOverTaskIDs != "" {
+		var rolledOver []models.Task
+		if err := s.db.Select("id", "duration_days").Where("id IN ?", strings.Split(iteration.RolledOverTaskIDs, ",")).Find(&rolledOver).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch rolled over tasks: %w", err)
+		}
+		tasks = append(tasks, rolledOver...)
+	}
+
+	burndown := &IterationBurndown{Iteration: *iteration, TotalTasks: len(tasks)}
+	for _, task := range tasks {
+		burndown.TotalDays += taskEstimateDays(task)
+		if task.CompletedAt != nil {
+			burndown.Completed++
+		}
+	}
+
+	start := truncateToDay(iteration.StartDate)
+	days := daysBetween(start, iteration.EndDate) + 1
+	today := truncateToDay(now)
+	for d := 0; d < days; d++ {
+		date := start.AddDate(0, 0, d)
+		point := BurndownPoint{Date: date}
+		if days > 1 {
+			point.Ideal = float64(burndown.TotalTasks) * float64(days-1-d) / float64(days-1)
+		}
+		if !date.After(today) {
+			endOfDay := date.AddDate(0, 0, 1)
+			remaining, remainingDays := 0, 0
+			for _, task := range tasks {
+				if task.CompletedAt == nil || !task.CompletedAt.Before(endOfDay) {
+					remaining++
+					remainingDays += taskEstimateDays(task)
+				}
+			}
+			point.Remaining = &remaining
+			point.RemainingDays = &remainingDays
+		}
+		burndown.Points = append(burndown.Points, point)
+	}
+	return burndown, nil
+}
+
+// findIteration fetches an iteration of a board
+func (s *iterationServiceImpl) findIteration(tx *gorm.DB, boardID, iterationID string) (*models.Iteration, error) {
+	var iteration models.Iteration
+	if err := tx.Where("id = ? AND task_board_id = ?", iterationID, boardID).First(&iteration).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIterationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch iteration: %w", err)
+	}
+	return &iteration, nil
+}
+
+// applyIterationInput validates an iteration's name and dates and sets them
+func applyIterationInput(iteration *models.Iteration, input IterationInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidIteration)
+	}
+	start, okStart := parsePropertyDate(strings.TrimSpace(input.StartDate))
+	end, okEnd := parsePropertyDate(strings.TrimSpace(input.EndDate))
+	if !okStart || !okEnd {
+		return fmt.Errorf("%w: startDate and endDate must be dates like 2025-01-31", ErrInvalidIteration)
+	}
+	start, end = truncateToDay(start), truncateToDay(end)
+	if end.Before(start) {
+		return fmt.Errorf("%w: endDate can't be before startDate", ErrInvalidIteration)
+	}
+	if daysBetween(start, end) >= maxIterationDays {
+		return fmt.Errorf("%w: iterations can be at most %d days long", ErrInvalidIteration, maxIterationDays)
+	}
+
+	iteration.Name = name
+	iteration.StartDate = start
+	iteration.EndDate = end
+	return nil
+}
+
+// iterationStatus returns whether an iteration is planned, active or closed
+func iterationStatus(iteration *models.Iteration, now time.Time) string {
+	switch {
+	case iteration.ClosedAt != nil:
+		return "closed"
+	case truncateToDay(now).Before(truncateToDay(iteration.StartDate)):
+		return "planned"
+	default:
+		return "active"
+	}
+}
+
+// taskEstimateDays returns a task's estimated days, at least one
+func taskEstimateDays(task models.Task) int {
+	if task.DurationDays < 1 {
+		return 1
+	}
+	return task.DurationDays
+}
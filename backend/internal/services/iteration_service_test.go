@@ -0,0 +1,136 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestIterationService creates an iteration service with an organization board on an in-memory database
+func setupTestIterationService(t *testing.T) (*iterationServiceImpl, models.TaskBoard) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.TaskBoard{}, &models.Task{}, &models.Iteration{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	orgID := "org_1"
+	board := models.TaskBoard{Name: "Sprint board", ClerkUserID: "user_owner", OrganizationID: &orgID, IsStandalone: true}
+	require.NoError(t, db.Create(&board).Error)
+
+	return &iterationServiceImpl{db: db}, board
+}
+
+// createIterationTask creates a task in an iteration
+func createIterationTask(t *testing.T, db *gorm.DB, boardID string, iterationID *string, title, status string, completedAt *time.Time) models.Task {
+	task := models.Task{Title: title, TaskBoardID: boardID, IterationID: iterationID, Status: status, CompletedAt: completedAt, DurationDays: 2}
+	require.NoError(t, db.Create(&task).Error)
+	return task
+}
+
+func TestIterationService_CreateIteration(t *testing.T) {
+	service, board := setupTestIterationService(t)
+
+	iteration, err := service.CreateIteration(board.ID, "user_owner", IterationInput{Name: " Sprint 1 ", StartDate: "2025-03-03", EndDate: "2025-03-14"})
+	require.NoError(t, err)
+	assert.Equal(t, "Sprint 1", iteration.Name)
+	assert.Equal(t, board.OrganizationID, iteration.OrganizationID)
+
+	_, err = service.CreateIteration(board.ID, "user_owner", IterationInput{Name: "Backwards", StartDate: "2025-03-14", EndDate: "2025-03-03"})
+	assert.ErrorIs(t, err, ErrInvalidIteration)
+	_, err = service.CreateIteration(board.ID, "user_owner", IterationInput{Name: "No dates"})
+	assert.ErrorIs(t, err, ErrInvalidIteration)
+
+	personal := models.TaskBoard{Name: "Personal", ClerkUserID: "user_owner", IsStandalone: true}
+	require.NoError(t, service.db.Create(&personal).Error)
+	_, err = service.CreateIteration(personal.ID, "user_owner", IterationInput{Name: "Sprint", StartDate: "2025-03-03", EndDate: "2025-03-14"})
+	assert.ErrorIs(t, err, ErrIterationsRequireOrganization)
+}
+
+func TestIterationService_CloseIteration_RollsOver(t *testing.T) {
+	service, board := setupTestIterationService(t)
+	now := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+
+	first, err := service.CreateIteration(board.ID, "user_owner", IterationInput{Name: "Sprint 1", StartDate: "2025-03-03", EndDate: "2025-03-14"})
+	require.NoError(t, err)
+	second, err := service.CreateIteration(board.ID, "user_owner", IterationInput{Name: "Sprint 2", StartDate: "2025-03-17", EndDate: "2025-03-28"})
+	require.NoError(t, err)
+
+	done := time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC)
+	createIterationTask(t, service.db, board.ID, &first.ID, "Finished", "done", &done)
+	open := createIterationTask(t, service.db, board.ID, &first.ID, "Unfinished", "in_progress", nil)
+
+	// Iterations close automatically once their last day has ended
+	assert.Equal(t, 1, service.CloseDueIterations(now))
+
+	var moved models.Task
+	require.NoError(t, service.db.First(&moved, "id = ?", open.ID).Error)
+	require.NotNil(t, moved.IterationID)
+	assert.Equal(t, second.ID, *moved.IterationID)
+
+	iterations, err := service.ListIterations(board.ID, now)
+	require.NoError(t, err)
+	require.Len(t, iterations, 2)
+	assert.Equal(t, "closed", iterations[0].Status)
+	assert.Equal(t, int64(1), iterations[0].TaskCount)
+	assert.Equal(t, int64(1), iterations[0].CompletedCount)
+	assert.Equal(t, "planned", iterations[1].Status)
+	assert.Equal(t, int64(1), iterations[1].TaskCount)
+
+	// Closed iterations can't take tasks or be closed again
+	assert.ErrorIs(t, service.SetTaskIteration(open.ID, first.ID), ErrIterationClosed)
+	_, err = service.CloseIteration(board.ID, first.ID, "", now)
+	assert.ErrorIs(t, err, ErrIterationClosed)
+
+	// Without a next iteration, open tasks go back to the backlog
+	result, err := service.CloseIteration(board.ID, second.ID, "", now)
+	require.NoError(t, err)
+	assert.Nil(t, result.RolledOverTo)
+	assert.Equal(t, 1, result.RolledOverTasks)
+	require.NoError(t, service.db.First(&moved, "id = ?", open.ID).Error)
+	assert.Nil(t, moved.IterationID)
+}
+
+func TestIterationService_GetBurndown(t *testing.T) {
+	service, board := setupTestIterationService(t)
+
+	iteration, err := service.CreateIteration(board.ID, "user_owner", IterationInput{Name: "Sprint", StartDate: "2025-03-03", EndDate: "2025-03-07"})
+	require.NoError(t, err)
+	day2 := time.Date(2025, 3, 4, 16, 0, 0, 0, time.UTC)
+	day3 := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+	createIterationTask(t, service.db, board.ID, &iteration.ID, "A", "done", &day2)
+	createIterationTask(t, service.db, board.ID, &iteration.ID, "B", "done", &day3)
+	createIterationTask(t, service.db, board.ID, &iteration.ID, "C", "todo", nil)
+	createIterationTask(t, service.db, board.ID, &iteration.ID, "D", "todo", nil)
+
+	now := time.Date(2025, 3, 5, 18, 0, 0, 0, time.UTC)
+	burndown, err := service.GetBurndown(board.ID, iteration.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, 4, burndown.TotalTasks)
+	assert.Equal(t, 8, burndown.TotalDays)
+	assert.Equal(t, 2, burndown.Completed)
+	require.Len(t, burndown.Points, 5)
+
+	expected := []int{4, 3, 2}
+	for i, remaining := range expected {
+		require.NotNil(t, burndown.Points[i].Remaining)
+		assert.Equal(t, remaining, *burndown.Points[i].Remaining)
+		assert.Equal(t, remaining*2, *burndown.Points[i].RemainingDays)
+	}
+	assert.Nil(t, burndown.Points[3].Remaining, "days that haven't ended have no actuals")
+	assert.Equal(t, 4.0, burndown.Points[0].Ideal)
+	assert.Equal(t, 0.0, burndown.Points[4].Ideal)
+
+	// Tasks that rolled over when the iteration closed still count against it
+	_, err = service.CloseIteration(board.ID, iteration.ID, "", time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	burndown, err = service.GetBurndown(board.ID, iteration.ID, time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 4, burndown.TotalTasks)
+	assert.Equal(t, 2, *burndown.Points[4].Remaining)
+}
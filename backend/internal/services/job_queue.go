@@ -0,0 +1,146 @@
+package services
+
+import (
+	"backend/internal/errorreport"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrJobQueueFull is returned when a job can't be queued because the queue is at capacity
+var ErrJobQueueFull = errors.New("job queue is full")
+
+const (
+	defaultJobQueueWorkers = 4
+	defaultJobQueueSize    = 5000
+	defaultJobTimeout      = time.Minute
+	defaultJobRetryDelay   = 5 * time.Second
+)
+
+// Job is a unit of background work
+type Job struct {
+	Name        string
+	Run         func(ctx context.Context) error
+	MaxAttempts int
+	// Timeout limits each attempt; the queue's default applies when zero
+	Timeout time.Duration
+}
+
+// JobQueue runs jobs in the background on a fixed pool of workers
+type JobQueue struct {
+	jobs       chan Job
+	workers    int
+	timeout    time.Duration
+	retryDelay time.Duration
+	startOnce  sync.Once
+}
+
+var (
+	jobQueue     *JobQueue
+	jobQueueOnce sync.Once
+)
+
+// GetJobQueue returns the singleton job queue
+func GetJobQueue() *JobQueue {
+	jobQueueOnce.Do(func() {
+		jobQueue = NewJobQueue(defaultJobQueueWorkers, defaultJobQueueSize)
+	})
+	return jobQueue
+}
+
+// NewJobQueue creates a job queue with the given number of workers and capacity
+func NewJobQueue(workers, size int) *JobQueue {
+	return &JobQueue{
+		jobs:       make(chan Job, size),
+		workers:    workers,
+		timeout:    defaultJobTimeout,
+		retryDelay: defaultJobRetryDelay,
+	}
+}
+
+// Start launches the workers. Jobs queued before Start run once it is called.
+func (q *JobQueue) Start(ctx context.Context) {
+	q.startOnce.Do(func() {
+		log.Info().Int("workers", q.workers).Msg("Starting background job queue")
+		for i := 0; i < q.workers; i++ {
+			go q.work(ctx)
+		}
+	})
+}
+
+// Enqueue adds a job without blocking
+func (q *JobQueue) Enqueue(job Job) error {
+	if job.MaxAttempts < 1 {
+		job.MaxAttempts = 1
+	}
+
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		log.Warn().Str("job", job.Name).Msg("Job queue is full, dropping job")
+		return ErrJobQueueFull
+	}
+}
+
+// Len returns the number of jobs waiting to run
+func (q *JobQueue) Len() int {
+	return len(q.jobs)
+}
+
+// work runs queued jobs until the context is cancelled
+func (q *JobQueue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-q.jobs:
+			q.run(ctx, job)
+		}
+	}
+}
+
+// run executes a job, retrying with a growing delay until it succeeds or runs out of attempts
+func (q *JobQueue) run(ctx context.Context, job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Interface("panic", r).Str("job", job.Name).Msg("Background job panicked")
+			errorreport.CapturePanic(ctx, r, map[string]string{"job": job.Name})
+		}
+	}()
+
+	timeout := q.timeout
+	if job.Timeout > 0 {
+		timeout = job.Timeout
+	}
+
+	for attempt := 1; attempt <= job.MaxAttempts; attempt++ {
+		jobCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := job.Run(jobCtx)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		log.Warn().
+			Err(err).
+			Str("job", job.Name).
+			Int("attempt", attempt).
+			Int("max_attempts", job.MaxAttempts).
+			Msg("Background job failed")
+
+		if attempt == job.MaxAttempts {
+			errorreport.Capture(ctx, fmt.Errorf("background job %s failed: %w", job.Name, err), map[string]string{"job": job.Name})
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(attempt) * q.retryDelay):
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobQueue_RetriesFailedJobs(t *testing.T) {
+	queue := NewJobQueue(2, 10)
+	queue.retryDelay = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts atomic.Int32
+	done := make(chan struct{})
+	require.NoError(t, queue.Enqueue(Job{
+		Name:        "flaky",
+		MaxAttempts: 3,
+		Run: func(ctx context.Context) error {
+			if attempts.Add(1) < 3 {
+				return errors.New("temporary failure")
+			}
+			close(done)
+			return nil
+		},
+	}))
+
+	// Jobs wait in the queue until the workers start
+	assert.Equal(t, 1, queue.Len())
+	queue.Start(ctx)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job did not succeed")
+	}
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestJobQueue_RejectsJobsWhenFull(t *testing.T) {
+	queue := NewJobQueue(1, 1)
+	noop := func(ctx context.Context) error { return nil }
+
+	require.NoError(t, queue.Enqueue(Job{Name: "first", Run: noop}))
+	assert.ErrorIs(t, queue.Enqueue(Job{Name: "second", Run: noop}), ErrJobQueueFull)
+}
@@ -0,0 +1,501 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrKnowledgeReportInProgress is returned when the organization already has a report being generated
+	ErrKnowledgeReportInProgress = errors.New("a knowledge report is already being generated")
+	// ErrKnowledgeReportNotFound is returned when a report doesn't exist in the organization
+	ErrKnowledgeReportNotFound = errors.New("knowledge report not found")
+	// ErrNoKnowledgeToAnalyze is returned when the organization has no notes to analyze
+	ErrNoKnowledgeToAnalyze = errors.New("the organization has no notes to analyze")
+)
+
+const (
+	knowledgeReportNotebookName = "Knowledge Reports"
+	knowledgeReportChapterName  = "Reports"
+	knowledgeReportJobTimeout   = 5 * time.Minute
+	// knowledgeReportStaleAfter is when a pending or running report is assumed lost and a new one may start
+	knowledgeReportStaleAfter = 30 * time.Minute
+
+	defaultKnowledgeReportDays  = 30
+	maxKnowledgeReportDays      = 180
+	maxKnowledgeMeetingNotes    = 20
+	maxKnowledgeRecentNotes     = 60
+	maxKnowledgeOutlineNotes    = 2000
+	maxKnowledgeMeetingExcerpt  = 3000
+	maxKnowledgeNoteExcerpt     = 1200
+	knowledgeReportNoteNameDate = "January 2, 2006"
+)
+
+// knowledgeOutlineRow is a notebook, chapter and note name of the outline
+type knowledgeOutlineRow struct {
+	NotebookName string
+	ChapterName  string
+	NoteName     string
+}
+
+// knowledgeSourceNote is a note sent to the AI, with what's needed to link to it
+type knowledgeSourceNote struct {
+	ID           string
+	Name         string
+	Content      string
+	ChapterID    string
+	ChapterName  string
+	NotebookID   string
+	NotebookName string
+}
+
+// KnowledgeReportService interface defines methods for generating AI knowledge gap reports
+type KnowledgeReportService interface {
+	RequestReport(organizationID, requestedBy string, days int) (*models.KnowledgeReport, error)
+	GetReport(organizationID, reportID string) (*models.KnowledgeReport, error)
+	Generate(ctx context.Context, reportID string) error
+}
+
+// knowledgeReportServiceImpl implements the KnowledgeReportService interface
+type knowledgeReportServiceImpl struct {
+	db              *gorm.DB
+	queue           *JobQueue
+	analyze         func(ctx context.Context, request KnowledgeGapRequest) (*KnowledgeGapAnalysis, error)
+	now             func() time.Time
+	frontendBaseURL string
+}
+
+// NewKnowledgeReportService creates a new KnowledgeReportService instance
+func NewKnowledgeReportService() KnowledgeReportService {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173"
+	}
+
+	return &knowledgeReportServiceImpl{
+		db:    db.DB,
+		queue: GetJobQueue(),
+		analyze: func(ctx context.Context, request KnowledgeGapRequest) (*KnowledgeGapAnalysis, error) {
+			return NewAIService().AnalyzeKnowledgeGaps(ctx, request)
+		},
+		now:             time.Now,
+		frontendBaseURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// RequestReport records a new report and generates it in the background
+func (s *knowledgeReportServiceImpl) RequestReport(organizationID, requestedBy string, days int) (*models.KnowledgeReport, error) {
+	if days <= 0 {
+		days = defaultKnowledgeReportDays
+	}
+	if days > maxKnowledgeReportDays {
+		days = maxKnowledgeReportDays
+	}
+
+	var active int64
+	if err := s.db.Model(&models.KnowledgeReport{}).
+		Where("organization_id = ? AND status IN ? AND updated_at > ?", organizationID,
+			[]string{models.KnowledgeReportStatusPending, models.KnowledgeReportStatusRunning}, s.now().Add(-knowledgeReportStaleAfter)).
+		Count(&active).Error; err != nil {
+		return nil, fmt.Errorf("failed to check knowledge reports: %w", err)
+	}
+	if active > 0 {
+		return nil, ErrKnowledgeReportInProgress
+	}
+
+	report := models.KnowledgeReport{
+		OrganizationID: organizationID,
+		RequestedBy:    requestedBy,
+		Status:         models.KnowledgeReportStatusPending,
+		Days:           days,
+	}
+	if err := s.db.Create(&report).Error; err != nil {
+		return nil, fmt.Errorf("failed to create knowledge report: %w", err)
+	}
+
+	reportID := report.ID
+	err := s.queue.Enqueue(Job{
+		Name:        "knowledge-report",
+		MaxAttempts: 2,
+		Timeout:     knowledgeReportJobTimeout,
+		Run: func(ctx context.Context) error {
+			err := s.Generate(ctx, reportID)
+			if errors.Is(err, ErrKnowledgeReportNotFound) || errors.Is(err, ErrNoKnowledgeToAnalyze) {
+				return nil
+			}
+			return err
+		},
+	})
+	if err != nil {
+		s.fail(reportID, err)
+		return nil, fmt.Errorf("failed to queue knowledge report: %w", err)
+	}
+
+	log.Info().Str("org_id", organizationID).Str("report_id", reportID).Int("days", days).Msg("Knowledge report queued")
+	return &report, nil
+}
+
+// GetReport returns a report of the organization
+func (s *knowledgeReportServiceImpl) GetReport(organizationID, reportID string) (*models.KnowledgeReport, error) {
+	var report models.KnowledgeReport
+	if err := s.db.Where("id = ? AND organization_id = ?", reportID, organizationID).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKnowledgeReportNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch knowledge report: %w", err)
+	}
+	return &report, nil
+}
+
+// Generate runs the analysis of a report and writes its findings to a note
+func (s *knowledgeReportServiceImpl) Generate(ctx context.Context, reportID string) error {
+	var report models.KnowledgeReport
+	if err := s.db.Where("id = ?", reportID).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrKnowledgeReportNotFound
+		}
+		return fmt.Errorf("failed to fetch knowledge report: %w", err)
+	}
+	if report.Status == models.KnowledgeReportStatusCompleted {
+		return nil
+	}
+	if err := s.db.Model(&report).Updates(map[string]interface{}{"status": models.KnowledgeReportStatusRunning, "error": ""}).Error; err != nil {
+		return fmt.Errorf("failed to update knowledge report: %w", err)
+	}
+
+	if err := s.generate(ctx, &report); err != nil {
+		s.fail(report.ID, err)
+		return err
+	}
+	return nil
+}
+
+func (s *knowledgeReportServiceImpl) generate(ctx context.Context, report *models.KnowledgeReport) error {
+	orgID := report.OrganizationID
+
+	// Earlier reports aren't analyzed
+	var reportNotebook models.Notebook
+	if err := s.db.Where("organization_id = ? AND name = ?", orgID, knowledgeReportNotebookName).Limit(1).Find(&reportNotebook).Error; err != nil {
+		return fmt.Errorf("failed to fetch knowledge report notebook: %w", err)
+	}
+
+	outline, err := s.outline(orgID, reportNotebook.ID)
+	if err != nil {
+		return err
+	}
+
+	since := s.now().AddDate(0, 0, -report.Days)
+	meetings, err := s.sourceNotes(orgID, reportNotebook.ID, maxKnowledgeMeetingNotes,
+		"notes.meeting_recording_id IS NOT NULL AND notes.created_at >= ?", since)
+	if err != nil {
+		return err
+	}
+	recent, err := s.sourceNotes(orgID, reportNotebook.ID, maxKnowledgeRecentNotes, "notes.meeting_recording_id IS NULL")
+	if err != nil {
+		return err
+	}
+	if len(meetings) == 0 && len(recent) == 0 {
+		return ErrNoKnowledgeToAnalyze
+	}
+
+	// Sources are cited by reference so the findings can link back to the notes
+	refs := make(map[string]knowledgeSourceNote)
+	sources := make([]KnowledgeSource, 0, len(meetings)+len(recent))
+	addSources := func(notes []knowledgeSourceNote, kind, prefix string, maxExcerpt int) {
+		for i, note := range notes {
+			ref := fmt.Sprintf("%s%d", prefix, i+1)
+			refs[ref] = note
+			content, err := utils.TipTapToMarkdown(note.Content)
+			if err != nil {
+				content = note.Content
+			}
+			sources = append(sources, KnowledgeSource{
+				Ref:      ref,
+				Kind:     kind,
+				Title:    note.Name,
+				Location: note.NotebookName + " / " + note.ChapterName,
+				Content:  truncateLinkText(strings.TrimSpace(content), maxExcerpt),
+			})
+		}
+	}
+	addSources(meetings, "meeting", "M", maxKnowledgeMeetingExcerpt)
+	addSources(recent, "note", "N", maxKnowledgeNoteExcerpt)
+
+	analysis, err := s.analyze(ctx, KnowledgeGapRequest{
+		Outline: outline,
+		Sources: sources,
+		UserID:  report.RequestedBy,
+		OrgID:   &orgID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.writeReport(report, analysis, refs, len(meetings))
+}
+
+// outline lists the organization's notebooks, chapters and note names
+func (s *knowledgeReportServiceImpl) outline(organizationID, excludeNotebookID string) (string, error) {
+	var rows []knowledgeOutlineRow
+	if err := s.db.Table("notebooks").
+		Select("notebooks.name AS notebook_name, chapters.name AS chapter_name, notes.name AS note_name").
+		Joins("LEFT JOIN chapters ON chapters.notebook_id = notebooks.id").
+		Joins("LEFT JOIN notes ON notes.chapter_id = chapters.id").
+		Where("notebooks.organization_id = ? AND notebooks.id <> ?", organizationID, excludeNotebookID).
+		Order("notebooks.name, chapters.name, notes.name").
+		Limit(maxKnowledgeOutlineNotes).
+		Scan(&rows).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch notebook outline: %w", err)
+	}
+
+	var outline strings.Builder
+	lastNotebook, lastChapter := "", ""
+	for i, row := range rows {
+		if i == 0 || row.NotebookName != lastNotebook {
+			outline.WriteString("- " + row.NotebookName + "\n")
+			lastNotebook, lastChapter = row.NotebookName, ""
+		}
+		if row.ChapterName != "" && row.ChapterName != lastChapter {
+			outline.WriteString("  - " + row.ChapterName + "\n")
+			lastChapter = row.ChapterName
+		}
+		if row.NoteName != "" {
+			outline.WriteString("    - " + row.NoteName + "\n")
+		}
+	}
+	return outline.String(), nil
+}
+
+// sourceNotes returns the organization's most recently updated notes matching the condition, leaving out
+// encrypted notebooks and locked notes
+func (s *knowledgeReportServiceImpl) sourceNotes(organizationID, excludeNotebookID string, limit int, condition string, args ...interface{}) ([]knowledgeSourceNote, error) {
+	var notes []knowledgeSourceNote
+	if err := s.db.Table("notes").
+		Select("notes.id, notes.name, notes.content, notes.chapter_id, chapters.name AS chapter_name, "+
+			"chapters.notebook_id, notebooks.name AS notebook_name").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notes.organization_id = ? AND notebooks.id <> ? AND notebooks.encrypted = ? AND notes.locked = ?", organizationID, excludeNotebookID, false, false).
+		Where(condition, args...).
+		Order("notes.updated_at DESC").
+		Limit(limit).
+		Scan(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notes: %w", err)
+	}
+	return notes, nil
+}
+
+// writeReport creates the report note in the organization's Knowledge Reports notebook and links it to its evidence
+func (s *knowledgeReportServiceImpl) writeReport(report *models.KnowledgeReport, analysis *KnowledgeGapAnalysis, refs map[string]knowledgeSourceNote, meetingCount int) error {
+	orgID := report.OrganizationID
+	now := s.now()
+
+	// Drop findings without evidence the AI was given, they can't be checked
+	topics := make([]KnowledgeGapTopic, 0, len(analysis.UndocumentedTopics))
+	for _, topic := range analysis.UndocumentedTopics {
+		topic.Evidence = knownKnowledgeRefs(topic.Evidence, refs)
+		if strings.TrimSpace(topic.Topic) != "" && len(topic.Evidence) > 0 {
+			topics = append(topics, topic)
+		}
+	}
+	contradictions := make([]KnowledgeContradiction, 0, len(analysis.Contradictions))
+	for _, contradiction := range analysis.Contradictions {
+		contradiction.Notes = knownKnowledgeRefs(contradiction.Notes, refs)
+		if strings.TrimSpace(contradiction.Summary) != "" && len(contradiction.Notes) >= 2 {
+			contradictions = append(contradictions, contradiction)
+		}
+	}
+
+	nodes := []utils.TipTapNode{
+		{
+			Type: "paragraph",
+			Content: []utils.TipTapNode{{
+				Type: "text",
+				Text: fmt.Sprintf("Generated on %s from %d notes, including %d meeting notes from the last %d days.",
+					now.Format(knowledgeReportNoteNameDate), len(refs), meetingCount, report.Days),
+				Marks: []utils.TipTapMark{{Type: "italic"}},
+			}},
+		},
+		knowledgeHeading(2, "Undocumented topics"),
+	}
+	if len(topics) == 0 {
+		nodes = append(nodes, knowledgeParagraph(utils.TipTapNode{Type: "text", Text: "No undocumented topics were found."}))
+	}
+	for _, topic := range topics {
+		nodes = append(nodes, knowledgeHeading(3, topic.Topic))
+		if reason := strings.TrimSpace(topic.Reason); reason != "" {
+			nodes = append(nodes, knowledgeParagraph(utils.TipTapNode{Type: "text", Text: reason}))
+		}
+		nodes = append(nodes, s.knowledgeEvidence("Evidence: ", topic.Evidence, refs))
+	}
+
+	nodes = append(nodes, knowledgeHeading(2, "Contradictory notes"))
+	if len(contradictions) == 0 {
+		nodes = append(nodes, knowledgeParagraph(utils.TipTapNode{Type: "text", Text: "No contradictory notes were found."}))
+	}
+	for _, contradiction := range contradictions {
+		nodes = append(nodes, knowledgeHeading(3, contradiction.Summary))
+		if details := strings.TrimSpace(contradiction.Details); details != "" {
+			nodes = append(nodes, knowledgeParagraph(utils.TipTapNode{Type: "text", Text: details}))
+		}
+		nodes = append(nodes, s.knowledgeEvidence("Notes: ", contradiction.Notes, refs))
+	}
+
+	content, err := json.Marshal(utils.TipTapDoc{Type: "doc", Content: nodes})
+	if err != nil {
+		return fmt.Errorf("failed to build report content: %w", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		notebook := models.Notebook{Name: knowledgeReportNotebookName, ClerkUserID: report.RequestedBy, OrganizationID: &orgID}
+		if err := tx.Where("organization_id = ? AND name = ?", orgID, knowledgeReportNotebookName).FirstOrCreate(&notebook).Error; err != nil {
+			return fmt.Errorf("failed to create knowledge report notebook: %w", err)
+		}
+		chapter := models.Chapter{Name: knowledgeReportChapterName, NotebookID: notebook.ID, OrganizationID: &orgID}
+		if err := tx.Where("notebook_id = ? AND name = ?", notebook.ID, knowledgeReportChapterName).FirstOrCreate(&chapter).Error; err != nil {
+			return fmt.Errorf("failed to create knowledge report chapter: %w", err)
+		}
+
+		note := models.Notes{
+			Name:           "Knowledge gaps - " + now.Format(knowledgeReportNoteNameDate),
+			Content:        string(content),
+			ChapterID:      chapter.ID,
+			OrganizationID: &orgID,
+		}
+		if err := tx.Create(&note).Error; err != nil {
+			return fmt.Errorf("failed to create knowledge report note: %w", err)
+		}
+
+		// The report references its evidence, and contradicting notes are linked to each other
+		linked := make(map[string]bool)
+		link := func(sourceID, targetID, linkType string) error {
+			key := sourceID + ":" + targetID + ":" + linkType
+			if linked[key] || sourceID == targetID {
+				return nil
+			}
+			linked[key] = true
+			return tx.Create(&models.NoteLink{
+				ID:             uuid.New().String(),
+				SourceNoteID:   sourceID,
+				TargetNoteID:   targetID,
+				LinkType:       linkType,
+				Origin:         models.LinkOriginAI,
+				OrganizationID: &orgID,
+				CreatedBy:      report.RequestedBy,
+			}).Error
+		}
+		for _, topic := range topics {
+			for _, ref := range topic.Evidence {
+				if err := link(note.ID, refs[ref].ID, models.LinkTypeReferences); err != nil {
+					return fmt.Errorf("failed to link report evidence: %w", err)
+				}
+			}
+		}
+		for _, contradiction := range contradictions {
+			first := refs[contradiction.Notes[0]].ID
+			for _, ref := range contradiction.Notes {
+				if err := link(note.ID, refs[ref].ID, models.LinkTypeReferences); err != nil {
+					return fmt.Errorf("failed to link report evidence: %w", err)
+				}
+				var existing int64
+				if err := tx.Model(&models.NoteLink{}).
+					Where("source_note_id = ? AND target_note_id = ? AND link_type = ?", first, refs[ref].ID, models.LinkTypeContradicts).
+					Count(&existing).Error; err != nil {
+					return fmt.Errorf("failed to check note links: %w", err)
+				}
+				if existing == 0 {
+					if err := link(first, refs[ref].ID, models.LinkTypeContradicts); err != nil {
+						return fmt.Errorf("failed to link contradicting notes: %w", err)
+					}
+				}
+			}
+		}
+
+		completedAt := now
+		report.Status = models.KnowledgeReportStatusCompleted
+		report.NoteID = &note.ID
+		report.ChapterID = &chapter.ID
+		report.NotebookID = &notebook.ID
+		report.TopicsFound = len(topics)
+		report.Contradictions = len(contradictions)
+		report.Error = ""
+		report.CompletedAt = &completedAt
+		if err := tx.Save(report).Error; err != nil {
+			return fmt.Errorf("failed to update knowledge report: %w", err)
+		}
+
+		log.Info().
+			Str("org_id", orgID).
+			Str("report_id", report.ID).
+			Str("note_id", note.ID).
+			Int("topics", len(topics)).
+			Int("contradictions", len(contradictions)).
+			Msg("Knowledge report generated")
+		return nil
+	})
+}
+
+// knowledgeEvidence builds a paragraph of links to the cited notes
+func (s *knowledgeReportServiceImpl) knowledgeEvidence(label string, cited []string, refs map[string]knowledgeSourceNote) utils.TipTapNode {
+	content := []utils.TipTapNode{{Type: "text", Text: label, Marks: []utils.TipTapMark{{Type: "bold"}}}}
+	for i, ref := range cited {
+		note := refs[ref]
+		if i > 0 {
+			content = append(content, utils.TipTapNode{Type: "text", Text: ", "})
+		}
+		href := fmt.Sprintf("%s/%s/%s/%s", s.frontendBaseURL, note.NotebookID, note.ChapterID, note.ID)
+		content = append(content, utils.TipTapNode{
+			Type:  "text",
+			Text:  note.Name,
+			Marks: []utils.TipTapMark{{Type: "link", Attrs: map[string]interface{}{"href": href}}},
+		})
+	}
+	return knowledgeParagraph(content...)
+}
+
+// fail marks a report as failed
+func (s *knowledgeReportServiceImpl) fail(reportID string, cause error) {
+	if err := s.db.Model(&models.KnowledgeReport{}).Where("id = ?", reportID).Updates(map[string]interface{}{
+		"status": models.KnowledgeReportStatusFailed,
+		"error":  truncateLinkText(cause.Error(), 1000),
+	}).Error; err != nil {
+		log.Error().Err(err).Str("report_id", reportID).Msg("Failed to mark knowledge report as failed")
+	}
+	log.Error().Err(cause).Str("report_id", reportID).Msg("Knowledge report failed")
+}
+
+// knownKnowledgeRefs keeps the references of notes that were sent to the AI, once each
+func knownKnowledgeRefs(cited []string, refs map[string]knowledgeSourceNote) []string {
+	known := make([]string, 0, len(cited))
+	for _, ref := range cited {
+		ref = strings.Trim(strings.TrimSpace(ref), "[]")
+		if _, exists := refs[ref]; exists && !containsString(known, ref) {
+			known = append(known, ref)
+		}
+	}
+	return known
+}
+
+func knowledgeHeading(level int, text string) utils.TipTapNode {
+	return utils.TipTapNode{
+		Type:    "heading",
+		Attrs:   map[string]interface{}{"level": level},
+		Content: []utils.TipTapNode{{Type: "text", Text: text}},
+	}
+}
+
+func knowledgeParagraph(content ...utils.TipTapNode) utils.TipTapNode {
+	return utils.TipTapNode{Type: "paragraph", Content: content}
+}
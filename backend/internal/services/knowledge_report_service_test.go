@@ -0,0 +1,138 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestKnowledgeReportService creates a knowledge report service with a fake AI analysis
+func setupTestKnowledgeReportService(t *testing.T, analyze func(ctx context.Context, request KnowledgeGapRequest) (*KnowledgeGapAnalysis, error)) *knowledgeReportServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteLink{}, &models.KnowledgeReport{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &knowledgeReportServiceImpl{
+		db:              db,
+		queue:           NewJobQueue(1, 10),
+		analyze:         analyze,
+		now:             time.Now,
+		frontendBaseURL: "https://notes.example.com",
+	}
+}
+
+// createKnowledgeNote creates an organization note, as a meeting note when meetingID is set
+func createKnowledgeNote(t *testing.T, db *gorm.DB, orgID, notebookName, name, content string, meetingID *string) models.Notes {
+	notebook := models.Notebook{Name: notebookName, ClerkUserID: "user_admin", OrganizationID: &orgID}
+	require.NoError(t, db.Where("organization_id = ? AND name = ?", orgID, notebookName).FirstOrCreate(&notebook).Error)
+	chapter := models.Chapter{Name: "General", NotebookID: notebook.ID, OrganizationID: &orgID}
+	require.NoError(t, db.Where("notebook_id = ?", notebook.ID).FirstOrCreate(&chapter).Error)
+
+	note := models.Notes{Name: name, Content: content, ChapterID: chapter.ID, OrganizationID: &orgID, MeetingRecordingID: meetingID}
+	require.NoError(t, db.Create(&note).Error)
+	return note
+}
+
+func TestKnowledgeReportGenerate_WritesReportWithEvidence(t *testing.T) {
+	var request KnowledgeGapRequest
+	service := setupTestKnowledgeReportService(t, func(ctx context.Context, r KnowledgeGapRequest) (*KnowledgeGapAnalysis, error) {
+		request = r
+		return &KnowledgeGapAnalysis{
+			UndocumentedTopics: []KnowledgeGapTopic{
+				{Topic: "On-call rotation", Reason: "Discussed in standup but never written down.", Evidence: []string{"M1", "X9"}},
+				{Topic: "Hallucinated", Reason: "No real evidence.", Evidence: []string{"X1"}},
+			},
+			Contradictions: []KnowledgeContradiction{
+				{Summary: "Release day", Details: "One note says Monday, another Friday.", Notes: []string{"[N1]", "N2"}},
+			},
+		}, nil
+	})
+
+	meetingID := "meeting_1"
+	meeting := createKnowledgeNote(t, service.db, "org_kr", "Meetings", "Standup", "We talked about the on-call rotation.", &meetingID)
+	releaseA := createKnowledgeNote(t, service.db, "org_kr", "Engineering", "Release process", "We release on Monday.", nil)
+	releaseB := createKnowledgeNote(t, service.db, "org_kr", "Engineering", "Release checklist", "We release on Friday.", nil)
+	createKnowledgeNote(t, service.db, "org_other", "Secrets", "Other org", "Not for this report.", nil)
+
+	report := models.KnowledgeReport{OrganizationID: "org_kr", RequestedBy: "user_admin", Status: models.KnowledgeReportStatusPending, Days: 30}
+	require.NoError(t, service.db.Create(&report).Error)
+
+	require.NoError(t, service.Generate(context.Background(), report.ID))
+
+	// Only the organization's notes are sent, meeting notes first
+	require.Len(t, request.Sources, 3)
+	assert.Equal(t, "M1", request.Sources[0].Ref)
+	assert.Equal(t, "meeting", request.Sources[0].Kind)
+	assert.Contains(t, request.Outline, "Engineering")
+	assert.NotContains(t, request.Outline, "Secrets")
+	assert.Equal(t, "user_admin", request.UserID)
+
+	var saved models.KnowledgeReport
+	require.NoError(t, service.db.First(&saved, "id = ?", report.ID).Error)
+	assert.Equal(t, models.KnowledgeReportStatusCompleted, saved.Status)
+	assert.Equal(t, 1, saved.TopicsFound, "Findings without known evidence should be dropped")
+	assert.Equal(t, 1, saved.Contradictions)
+	require.NotNil(t, saved.NoteID)
+	require.NotNil(t, saved.CompletedAt)
+
+	var note models.Notes
+	require.NoError(t, service.db.First(&note, "id = ?", *saved.NoteID).Error)
+	assert.True(t, strings.HasPrefix(note.Name, "Knowledge gaps - "))
+	assert.Contains(t, note.Content, "On-call rotation")
+	assert.NotContains(t, note.Content, "Hallucinated")
+	assert.Contains(t, note.Content, "https://notes.example.com/")
+	assert.Contains(t, note.Content, meeting.ID)
+
+	var references, contradicts int64
+	service.db.Model(&models.NoteLink{}).Where("source_note_id = ? AND link_type = ?", note.ID, models.LinkTypeReferences).Count(&references)
+	service.db.Model(&models.NoteLink{}).Where("source_note_id IN ? AND target_note_id IN ? AND link_type = ?",
+		[]string{releaseA.ID, releaseB.ID}, []string{releaseA.ID, releaseB.ID}, models.LinkTypeContradicts).Count(&contradicts)
+	assert.Equal(t, int64(3), references)
+	assert.Equal(t, int64(1), contradicts)
+
+	// A second report doesn't analyze the first one
+	second := models.KnowledgeReport{OrganizationID: "org_kr", RequestedBy: "user_admin", Status: models.KnowledgeReportStatusPending, Days: 30}
+	require.NoError(t, service.db.Create(&second).Error)
+	require.NoError(t, service.Generate(context.Background(), second.ID))
+	assert.Len(t, request.Sources, 3)
+	assert.NotContains(t, request.Outline, knowledgeReportNotebookName)
+}
+
+func TestKnowledgeReportRequest_RejectsConcurrentReports(t *testing.T) {
+	service := setupTestKnowledgeReportService(t, nil)
+
+	report, err := service.RequestReport("org_kr", "user_admin", 0)
+	require.NoError(t, err)
+	assert.Equal(t, defaultKnowledgeReportDays, report.Days)
+	assert.Equal(t, models.KnowledgeReportStatusPending, report.Status)
+	assert.Equal(t, 1, service.queue.Len())
+
+	_, err = service.RequestReport("org_kr", "user_admin", 30)
+	assert.ErrorIs(t, err, ErrKnowledgeReportInProgress)
+
+	_, err = service.GetReport("org_other", report.ID)
+	assert.ErrorIs(t, err, ErrKnowledgeReportNotFound, "Reports of other organizations should not be visible")
+}
+
+func TestKnowledgeReportGenerate_FailsWithoutNotes(t *testing.T) {
+	service := setupTestKnowledgeReportService(t, nil)
+
+	report := models.KnowledgeReport{OrganizationID: "org_empty", RequestedBy: "user_admin", Status: models.KnowledgeReportStatusPending, Days: 30}
+	require.NoError(t, service.db.Create(&report).Error)
+
+	assert.ErrorIs(t, service.Generate(context.Background(), report.ID), ErrNoKnowledgeToAnalyze)
+
+	var saved models.KnowledgeReport
+	require.NoError(t, service.db.First(&saved, "id = ?", report.ID).Error)
+	assert.Equal(t, models.KnowledgeReportStatusFailed, saved.Status)
+	assert.NotEmpty(t, saved.Error)
+}
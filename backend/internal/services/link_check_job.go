@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LinkCheckJob periodically queues link checks of notebooks that weren't checked in the last week
+type LinkCheckJob struct {
+	service  LinkCheckService
+	interval time.Duration
+	stopChan chan struct{}
+}
+
+// NewLinkCheckJob creates a new link check scheduler
+func NewLinkCheckJob(service LinkCheckService, interval time.Duration) *LinkCheckJob {
+	return &LinkCheckJob{
+		service:  service,
+		interval: interval,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins queueing due link checks
+func (j *LinkCheckJob) Start(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Msg("Starting link check scheduler")
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if queued := j.service.QueueDueChecks(time.Now()); queued > 0 {
+				log.Info().Int("queued", queued).Msg("Queued notebook link checks")
+			}
+		case <-ctx.Done():
+			log.Info().Msg("Stopping link check scheduler (context cancelled)")
+			return
+		case <-j.stopChan:
+			log.Info().Msg("Stopping link check scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (j *LinkCheckJob) Stop() {
+	close(j.stopChan)
+}
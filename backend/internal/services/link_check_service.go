@@ -0,0 +1,968 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrLinkIssueNotFound is returned when a link issue doesn't exist in the notebook, or was fixed
+	ErrLinkIssueNotFound = errors.New("link issue not found")
+	// ErrLinkFixNotApplicable is returned, wrapped with the reason, for a fix that doesn't apply to an issue
+	ErrLinkFixNotApplicable = errors.New("fix doesn't apply to this link issue")
+	// ErrLinkCheckInProgress is returned when the notebook's links are already being checked
+	ErrLinkCheckInProgress = errors.New("the notebook's links are already being checked")
+)
+
+// Fixes offered for link issues
+const (
+	LinkFixUpdateURL     = "update_url"
+	LinkFixRemoveLink    = "remove_link"
+	LinkFixRestoreTarget = "restore_target"
+)
+
+const (
+	// linkCheckInterval is how often notebooks are checked in the background
+	linkCheckInterval = 7 * 24 * time.Hour
+	// maxLinkChecksQueued caps how many notebooks a run of the background job queues
+	maxLinkChecksQueued = 50
+	// maxLinkCheckURLs caps the external URLs checked in a notebook
+	maxLinkCheckURLs = 300
+	// linkCheckConcurrency is how many URLs of a notebook are checked at once
+	linkCheckConcurrency = 4
+	linkCheckJobTimeout  = 10 * time.Minute
+	linkCheckUserAgent   = "NotesAppLinkChecker/1.0"
+)
+
+var (
+	// linkChecksRunning holds the notebooks whose links are being checked
+	linkChecksRunning sync.Map
+	// linkChecksQueued holds the notebooks waiting in the job queue for a check
+	linkChecksQueued sync.Map
+
+	linkCheckClient     *http.Client
+	linkCheckClientOnce sync.Once
+)
+
+// bareURLPattern finds http and https URLs in text
+var bareURLPattern = regexp.MustCompile(`https?://[^\s<>"'\x60\[\]]+`)
+
+// markdownLinkPattern finds the targets of [text](url) links in Markdown, allowing one level of
+// parentheses in the URL
+var markdownLinkPattern = regexp.MustCompile(`\]\((https?://[^\s()]*(?:\([^\s()]*\)[^\s()]*)*)\)`)
+
+// LinkReportIssue is a link issue with the note it's in and the fixes it can take
+type LinkReportIssue struct {
+	models.LinkCheckIssue
+	NoteName string   `json:"noteName"`
+	Fixes    []string `json:"fixes"`
+}
+
+// LinkReport is the result of the last check of a notebook's links
+type LinkReport struct {
+	NotebookID   string            `json:"notebookId"`
+	CheckedAt    *time.Time        `json:"checkedAt,omitempty"` // Unset until the notebook is checked
+	NotesChecked int               `json:"notesChecked"`
+	URLsChecked  int               `json:"urlsChecked"`
+	Checking     bool              `json:"checking"`
+	LastError    string            `json:"lastError,omitempty"`
+	Issues       []LinkReportIssue `json:"issues"`
+}
+
+// LinkCheckService interface defines methods for finding broken external links and stale note links in
+// notebooks, and fixing them
+type LinkCheckService interface {
+	Report(ctx context.Context, notebookID string) (*LinkReport, error)
+	Check(ctx context.Context, notebookID string) (*LinkReport, error)
+	QueueCheck(notebookID string) error
+	QueueDueChecks(now time.Time) int
+	UpdateURL(ctx context.Context, notebookID, issueID, newURL, clerkUserID string) (*models.Notes, error)
+	RemoveLink(ctx context.Context, notebookID, issueID, clerkUserID string) (*models.Notes, error)
+	RestoreTarget(ctx context.Context, notebookID, issueID, clerkUserID string) (*models.Notes, error)
+}
+
+// linkCheckServiceImpl implements the LinkCheckService interface
+type linkCheckServiceImpl struct {
+	db       *gorm.DB
+	queue    *JobQueue
+	checkURL func(ctx context.Context, rawURL string) linkURLCheck
+	now      func() time.Time
+}
+
+// NewLinkCheckService creates a new LinkCheckService instance
+func NewLinkCheckService() LinkCheckService {
+	linkCheckClientOnce.Do(func() {
+		linkCheckClient = newLinkPreviewClient()
+		// Redirects are followed by hand, to tell permanent moves from temporary ones
+		linkCheckClient.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	})
+	return &linkCheckServiceImpl{
+		db:    db.DB,
+		queue: GetJobQueue(),
+		checkURL: func(ctx context.Context, rawURL string) linkURLCheck {
+			return checkLinkURL(ctx, linkCheckClient, rawURL)
+		},
+		now: time.Now,
+	}
+}
+
+// linkURLCheck is what checking an external URL found
+type linkURLCheck struct {
+	status  int
+	movedTo string // Where the URL permanently redirects to, empty when it doesn't
+	err     error
+}
+
+// checkedNote is a note of a notebook being checked, with the links found in it
+type checkedNote struct {
+	id, name    string
+	urls        []string
+	wikiTargets []string
+}
+
+// Report returns the issues found by the last check of a notebook's links
+func (s *linkCheckServiceImpl) Report(ctx context.Context, notebookID string) (*LinkReport, error) {
+	report := &LinkReport{NotebookID: notebookID, Issues: []LinkReportIssue{}}
+	var check models.LinkCheck
+	err := s.db.WithContext(ctx).Where("notebook_id = ?", notebookID).First(&check).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch link check: %w", err)
+	}
+	if err == nil {
+		report.CheckedAt = check.CheckedAt
+		report.NotesChecked = check.NotesChecked
+		report.URLsChecked = check.URLsChecked
+		report.LastError = check.LastError
+	}
+	_, running := linkChecksRunning.Load(notebookID)
+	_, queued := linkChecksQueued.Load(notebookID)
+	report.Checking = running || queued
+
+	var rows []struct {
+		models.LinkCheckIssue
+		NoteName string
+	}
+	if err := s.db.WithContext(ctx).Model(&models.LinkCheckIssue{}).
+		Select("link_check_issues.*, notes.name AS note_name").
+		Joins("JOIN notes ON notes.id = link_check_issues.note_id").
+		Where("link_check_issues.notebook_id = ?", notebookID).
+		Order("notes.name, link_check_issues.kind, link_check_issues.created_at").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch link issues: %w", err)
+	}
+	for _, row := range rows {
+		report.Issues = append(report.Issues, LinkReportIssue{
+			LinkCheckIssue: row.LinkCheckIssue,
+			NoteName:       row.NoteName,
+			Fixes:          linkIssueFixes(&row.LinkCheckIssue),
+		})
+	}
+	return report, nil
+}
+
+// Check checks the links of a notebook's notes now: external URLs that answer with an error or moved
+// permanently, [[wiki links]] that match no note and note links to deleted notes. The issues replace
+// those of the notebook's last check. Locked notes are skipped, and encrypted notebooks can't be checked.
+func (s *linkCheckServiceImpl) Check(ctx context.Context, notebookID string) (*LinkReport, error) {
+	if _, running := linkChecksRunning.LoadOrStore(notebookID, true); running {
+		return nil, ErrLinkCheckInProgress
+	}
+	err := s.check(ctx, notebookID)
+	linkChecksRunning.Delete(notebookID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Report(ctx, notebookID)
+}
+
+// check finds the issues of a notebook's links and saves them
+func (s *linkCheckServiceImpl) check(ctx context.Context, notebookID string) error {
+	var notebook models.Notebook
+	if err := s.db.WithContext(ctx).Select("id", "encrypted").Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotebookNotFound
+		}
+		return fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if notebook.Encrypted {
+		return ErrNotebookEncrypted
+	}
+
+	var stored []models.Notes
+	if err := s.db.WithContext(ctx).Model(&models.Notes{}).Select("notes.id, notes.name, notes.content").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ? AND notes.locked = ?", notebookID, false).
+		Order("notes.created_at").Find(&stored).Error; err != nil {
+		return fmt.Errorf("failed to fetch notes: %w", err)
+	}
+
+	notes := make([]checkedNote, 0, len(stored))
+	var urls []string
+	for _, note := range stored {
+		checked := checkedNote{id: note.ID, name: note.Name, urls: extractContentURLs(note.Content)}
+		if markdown, err := utils.TipTapToMarkdown(note.Content); err == nil {
+			checked.wikiTargets = extractWikiLinks(markdown)
+		} else {
+			checked.wikiTargets = extractWikiLinks(note.Content)
+		}
+		for _, u := range checked.urls {
+			if len(urls) < maxLinkCheckURLs && !slices.Contains(urls, u) {
+				urls = append(urls, u)
+			}
+		}
+		notes = append(notes, checked)
+	}
+	results := s.checkURLs(ctx, urls)
+
+	snapshots, err := s.snapshotNotes(ctx, notebookID)
+	if err != nil {
+		return err
+	}
+
+	var issues []models.LinkCheckIssue
+	slugs := &slugServiceImpl{db: s.db.WithContext(ctx)}
+	for _, note := range notes {
+		for _, u := range note.urls {
+			result, ok := results[u]
+			if !ok {
+				continue
+			}
+			if issue, found := urlCheckIssue(u, result); found {
+				issue.NotebookID, issue.NoteID = notebookID, note.id
+				issues = append(issues, issue)
+			}
+		}
+		for _, target := range note.wikiTargets {
+			_, err := slugs.ResolveNoteLink(notebookID, target)
+			if errors.Is(err, ErrAmbiguousNoteLink) || err == nil {
+				continue
+			}
+			if !errors.Is(err, ErrNoteNotFound) {
+				return err
+			}
+			issue := models.LinkCheckIssue{
+				NotebookID: notebookID,
+				NoteID:     note.id,
+				Kind:       models.LinkIssueUnresolvedWikiLink,
+				WikiTarget: target,
+				Message:    fmt.Sprintf("[[%s]] doesn't match any note of the notebook", target),
+			}
+			if restorable := findSnapshotNote(snapshots, "", target); restorable != nil {
+				issue.RestoreSnapshot = restorable.snapshot
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	// Note links pointing at deleted notes
+	var dangling []models.NoteLink
+	if len(notes) > 0 {
+		ids := make([]string, len(notes))
+		for i, note := range notes {
+			ids[i] = note.id
+		}
+		if err := s.db.WithContext(ctx).Model(&models.NoteLink{}).Select("note_links.*").
+			Joins("LEFT JOIN notes ON notes.id = note_links.target_note_id").
+			Where("note_links.source_note_id IN ? AND notes.id IS NULL", ids).
+			Order("note_links.created_at").Find(&dangling).Error; err != nil {
+			return fmt.Errorf("failed to fetch note links: %w", err)
+		}
+	}
+	for _, link := range dangling {
+		issue := models.LinkCheckIssue{
+			NotebookID:   notebookID,
+			NoteID:       link.SourceNoteID,
+			Kind:         models.LinkIssueMissingNote,
+			LinkID:       link.ID,
+			TargetNoteID: link.TargetNoteID,
+			Message:      "Links to a note that was deleted",
+		}
+		if restorable := findSnapshotNote(snapshots, link.TargetNoteID, ""); restorable != nil {
+			issue.RestoreSnapshot = restorable.snapshot
+		}
+		issues = append(issues, issue)
+	}
+
+	now := s.now()
+	check := models.LinkCheck{NotebookID: notebookID, CheckedAt: &now, NotesChecked: len(notes), URLsChecked: len(results)}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("notebook_id = ?", notebookID).Delete(&models.LinkCheckIssue{}).Error; err != nil {
+			return fmt.Errorf("failed to clear link issues: %w", err)
+		}
+		if len(issues) > 0 {
+			if err := tx.Create(&issues).Error; err != nil {
+				return fmt.Errorf("failed to save link issues: %w", err)
+			}
+		}
+		if err := tx.Where(models.LinkCheck{NotebookID: notebookID}).
+			Assign(map[string]interface{}{"checked_at": now, "notes_checked": check.NotesChecked, "urls_checked": check.URLsChecked, "last_error": ""}).
+			FirstOrCreate(&check).Error; err != nil {
+			return fmt.Errorf("failed to save link check: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("notebook_id", notebookID).Int("notes", len(notes)).Int("urls", len(results)).
+		Int("issues", len(issues)).Msg("Checked notebook links")
+	return nil
+}
+
+// QueueCheck checks a notebook's links in the background
+func (s *linkCheckServiceImpl) QueueCheck(notebookID string) error {
+	if _, running := linkChecksRunning.Load(notebookID); running {
+		return nil
+	}
+	if _, queued := linkChecksQueued.LoadOrStore(notebookID, true); queued {
+		return nil
+	}
+
+	err := s.queue.Enqueue(Job{
+		Name:        "link-check",
+		MaxAttempts: 1,
+		Timeout:     linkCheckJobTimeout,
+		Run: func(ctx context.Context) error {
+			linkChecksQueued.Delete(notebookID)
+			_, err := s.Check(ctx, notebookID)
+			if err != nil && !errors.Is(err, ErrLinkCheckInProgress) && !errors.Is(err, ErrNotebookNotFound) {
+				s.recordCheckError(notebookID, err)
+				return err
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		linkChecksQueued.Delete(notebookID)
+	}
+	return err
+}
+
+// QueueDueChecks queues checks of plain notebooks that weren't checked in the last week, the longest
+// unchecked first, and returns how many were queued
+func (s *linkCheckServiceImpl) QueueDueChecks(now time.Time) int {
+	var notebookIDs []string
+	if err := s.db.Model(&models.Notebook{}).
+		Joins("LEFT JOIN link_checks ON link_checks.notebook_id = notebooks.id").
+		Where("notebooks.encrypted = ?", false).
+		Where("link_checks.checked_at IS NULL OR link_checks.checked_at < ?", now.Add(-linkCheckInterval)).
+		Order("link_checks.checked_at").Limit(maxLinkChecksQueued).
+		Pluck("notebooks.id", &notebookIDs).Error; err != nil {
+		log.Error().Err(err).Msg("Failed to find notebooks due for a link check")
+		return 0
+	}
+
+	queued := 0
+	for _, notebookID := range notebookIDs {
+		if err := s.QueueCheck(notebookID); err != nil {
+			log.Warn().Err(err).Str("notebook_id", notebookID).Msg("Failed to queue link check")
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+// UpdateURL replaces a broken or moved URL in its note, with the URL it moved to when newURL is empty
+func (s *linkCheckServiceImpl) UpdateURL(ctx context.Context, notebookID, issueID, newURL, clerkUserID string) (*models.Notes, error) {
+	issue, err := s.issue(ctx, notebookID, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if issue.Kind != models.LinkIssueBrokenURL && issue.Kind != models.LinkIssueRedirectedURL {
+		return nil, fmt.Errorf("%w: only external URLs can be updated", ErrLinkFixNotApplicable)
+	}
+	newURL = strings.TrimSpace(newURL)
+	if newURL == "" {
+		newURL = issue.RedirectURL
+	}
+	if newURL == "" {
+		return nil, fmt.Errorf("%w: URL is required", ErrInvalidLinkURL)
+	}
+	if _, err := normalizeLinkURL(newURL); err != nil {
+		return nil, err
+	}
+	if !strings.Contains(newURL, "://") {
+		newURL = "https://" + newURL
+	}
+
+	note, err := s.rewriteNote(ctx, issue.NoteID, func(content string) (string, bool) {
+		return replaceContentURL(content, issue.URL, newURL)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("note_id", note.ID).Str("user_id", clerkUserID).Str("url", issue.URL).Str("new_url", newURL).Msg("Updated link URL")
+	return note, s.resolveIssues(ctx, issue, "kind IN ? AND url = ?", []string{models.LinkIssueBrokenURL, models.LinkIssueRedirectedURL}, issue.URL)
+}
+
+// RemoveLink takes a link out of its note. Links to a broken URL become plain text and the URL itself
+// is dropped, unresolved wiki links become their text and note links to deleted notes are deleted.
+func (s *linkCheckServiceImpl) RemoveLink(ctx context.Context, notebookID, issueID, clerkUserID string) (*models.Notes, error) {
+	issue, err := s.issue(ctx, notebookID, issueID)
+	if err != nil {
+		return nil, err
+	}
+
+	var note *models.Notes
+	switch issue.Kind {
+	case models.LinkIssueBrokenURL, models.LinkIssueRedirectedURL:
+		note, err = s.rewriteNote(ctx, issue.NoteID, func(content string) (string, bool) {
+			return replaceContentURL(content, issue.URL, "")
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = s.resolveIssues(ctx, issue, "kind IN ? AND url = ?", []string{models.LinkIssueBrokenURL, models.LinkIssueRedirectedURL}, issue.URL)
+	case models.LinkIssueUnresolvedWikiLink:
+		note, err = s.rewriteNote(ctx, issue.NoteID, func(content string) (string, bool) {
+			return unlinkContentWikiLink(content, issue.WikiTarget)
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = s.resolveIssues(ctx, issue, "kind = ? AND wiki_target = ?", models.LinkIssueUnresolvedWikiLink, issue.WikiTarget)
+	case models.LinkIssueMissingNote:
+		if err := s.db.WithContext(ctx).Where("id = ?", issue.LinkID).Delete(&models.NoteLink{}).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete note link: %w", err)
+		}
+		if note, err = s.note(ctx, issue.NoteID); err != nil {
+			return nil, err
+		}
+		err = s.resolveIssues(ctx, issue, "kind = ? AND link_id = ?", models.LinkIssueMissingNote, issue.LinkID)
+	default:
+		return nil, fmt.Errorf("%w: unknown issue", ErrLinkFixNotApplicable)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("note_id", note.ID).Str("user_id", clerkUserID).Str("kind", issue.Kind).Msg("Removed link")
+	return note, nil
+}
+
+// RestoreTarget brings back the deleted note a note link or wiki link points at, from the latest
+// snapshot of the notebook holding it. The note goes back in its chapter, which is recreated when it
+// was deleted too. It returns the restored note.
+func (s *linkCheckServiceImpl) RestoreTarget(ctx context.Context, notebookID, issueID, clerkUserID string) (*models.Notes, error) {
+	issue, err := s.issue(ctx, notebookID, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if issue.Kind != models.LinkIssueMissingNote && issue.Kind != models.LinkIssueUnresolvedWikiLink {
+		return nil, fmt.Errorf("%w: only links to notes can be restored", ErrLinkFixNotApplicable)
+	}
+
+	snapshots, err := s.snapshotNotes(ctx, notebookID)
+	if err != nil {
+		return nil, err
+	}
+	restorable := findSnapshotNote(snapshots, issue.TargetNoteID, issue.WikiTarget)
+	if restorable == nil {
+		return nil, fmt.Errorf("%w: no snapshot of the notebook holds the note", ErrLinkFixNotApplicable)
+	}
+
+	note := models.Notes{Name: restorable.note.Name, Content: restorable.note.Content, Status: restorable.note.Status}
+	if note.Status == "" {
+		note.Status = models.NoteStatusDraft
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Notes deleted since the snapshot come back as they were, so links to them work again
+		var taken int64
+		if err := tx.Model(&models.Notes{}).Where("id = ?", restorable.note.ID).Count(&taken).Error; err != nil {
+			return fmt.Errorf("failed to check note: %w", err)
+		}
+		if taken == 0 {
+			note.ID = restorable.note.ID
+		}
+
+		var chapter models.Chapter
+		err := tx.Where("id = ? AND notebook_id = ?", restorable.chapter.ID, notebookID).First(&chapter).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			chapter = models.Chapter{Name: restorable.chapter.Name, NotebookID: notebookID}
+			var chapterTaken int64
+			if err := tx.Model(&models.Chapter{}).Where("id = ?", restorable.chapter.ID).Count(&chapterTaken).Error; err != nil {
+				return fmt.Errorf("failed to check chapter: %w", err)
+			}
+			if chapterTaken == 0 {
+				chapter.ID = restorable.chapter.ID
+			}
+			orgID, err := notebookOrganization(tx, notebookID)
+			if err != nil {
+				return err
+			}
+			chapter.OrganizationID = orgID
+			if err := tx.Create(&chapter).Error; err != nil {
+				return fmt.Errorf("failed to restore chapter: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to fetch chapter: %w", err)
+		}
+
+		note.ChapterID = chapter.ID
+		note.OrganizationID = chapter.OrganizationID
+		if err := tx.Create(&note).Error; err != nil {
+			return fmt.Errorf("failed to restore note: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if issue.Kind == models.LinkIssueUnresolvedWikiLink {
+		if err := (&NoteLinkService{db: s.db.WithContext(ctx)}).SyncWikiLinks(issue.NoteID, clerkUserID); err != nil {
+			log.Warn().Err(err).Str("note_id", issue.NoteID).Msg("Failed to sync wiki links after restoring a note")
+		}
+	}
+	// Other links to the note are fixed by restoring it too
+	if err := s.db.WithContext(ctx).Where("notebook_id = ? AND ((kind = ? AND target_note_id = ?) OR (kind = ? AND wiki_target = ?))",
+		notebookID, models.LinkIssueMissingNote, restorable.note.ID, models.LinkIssueUnresolvedWikiLink, issue.WikiTarget).
+		Delete(&models.LinkCheckIssue{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to clear link issues: %w", err)
+	}
+
+	log.Info().Str("note_id", note.ID).Str("user_id", clerkUserID).Str("snapshot", restorable.snapshot).Msg("Restored linked note from snapshot")
+	return &note, nil
+}
+
+// issue returns an issue of a notebook
+func (s *linkCheckServiceImpl) issue(ctx context.Context, notebookID, issueID string) (*models.LinkCheckIssue, error) {
+	var issue models.LinkCheckIssue
+	err := s.db.WithContext(ctx).Where("id = ? AND notebook_id = ?", issueID, notebookID).First(&issue).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrLinkIssueNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch link issue: %w", err)
+	}
+	return &issue, nil
+}
+
+// note returns a note by ID
+func (s *linkCheckServiceImpl) note(ctx context.Context, noteID string) (*models.Notes, error) {
+	var note models.Notes
+	if err := s.db.WithContext(ctx).Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	return &note, nil
+}
+
+// rewriteNote changes a note's content and resets its collaborative document, so editors load the change
+func (s *linkCheckServiceImpl) rewriteNote(ctx context.Context, noteID string, rewrite func(content string) (string, bool)) (*models.Notes, error) {
+	note, err := s.note(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note.Locked {
+		return nil, ErrNoteLocked
+	}
+	content, changed := rewrite(note.Content)
+	if !changed {
+		return note, nil
+	}
+	if err := s.db.WithContext(ctx).Model(note).Update("content", content).Error; err != nil {
+		return nil, fmt.Errorf("failed to update note: %w", err)
+	}
+	note.Content = content
+	if err := NewYjsService(s.db).DeleteYjsDocument(note.ID); err != nil {
+		log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to reset Yjs document after fixing a link")
+	}
+	return note, nil
+}
+
+// resolveIssues deletes the fixed issue and the issues of its note matching a condition, which the
+// same fix resolved
+func (s *linkCheckServiceImpl) resolveIssues(ctx context.Context, issue *models.LinkCheckIssue, condition string, args ...interface{}) error {
+	if err := s.db.WithContext(ctx).Where("id = ? OR (note_id = ? AND "+condition+")", append([]interface{}{issue.ID, issue.NoteID}, args...)...).
+		Delete(&models.LinkCheckIssue{}).Error; err != nil {
+		return fmt.Errorf("failed to clear link issues: %w", err)
+	}
+	return nil
+}
+
+// recordCheckError keeps why a background check of a notebook failed, for its report
+func (s *linkCheckServiceImpl) recordCheckError(notebookID string, checkErr error) {
+	check := models.LinkCheck{NotebookID: notebookID}
+	if err := s.db.Where(models.LinkCheck{NotebookID: notebookID}).
+		Assign(map[string]interface{}{"last_error": checkErr.Error()}).FirstOrCreate(&check).Error; err != nil {
+		log.Warn().Err(err).Str("notebook_id", notebookID).Msg("Failed to record link check error")
+	}
+}
+
+// checkURLs checks external URLs a few at a time
+func (s *linkCheckServiceImpl) checkURLs(ctx context.Context, urls []string) map[string]linkURLCheck {
+	results := make(map[string]linkURLCheck, len(urls))
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, linkCheckConcurrency)
+	)
+	for _, u := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(u string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result := s.checkURL(ctx, u)
+			mu.Lock()
+			results[u] = result
+			mu.Unlock()
+		}(u)
+	}
+	wg.Wait()
+	return results
+}
+
+// snapshotNote is a note as a snapshot of its notebook holds it
+type snapshotNote struct {
+	snapshot string
+	chapter  SnapshotChapter
+	note     SnapshotNote
+}
+
+// snapshotNotes returns the notes of a notebook's snapshots, the latest snapshot first
+func (s *linkCheckServiceImpl) snapshotNotes(ctx context.Context, notebookID string) ([]snapshotNote, error) {
+	var snapshots []models.NotebookSnapshot
+	if err := s.db.WithContext(ctx).Where("notebook_id = ?", notebookID).Order("created_at DESC").Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch snapshots: %w", err)
+	}
+
+	var notes []snapshotNote
+	for _, snapshot := range snapshots {
+		var chapters []SnapshotChapter
+		if err := json.Unmarshal([]byte(snapshot.Content), &chapters); err != nil {
+			log.Warn().Err(err).Str("snapshot_id", snapshot.ID).Msg("Failed to read snapshot")
+			continue
+		}
+		for _, chapter := range chapters {
+			for _, note := range chapter.Notes {
+				notes = append(notes, snapshotNote{snapshot: snapshot.Name, chapter: chapter, note: note})
+			}
+		}
+	}
+	return notes, nil
+}
+
+// findSnapshotNote returns the latest snapshotted version of a note, by ID or by the target of a wiki link
+func findSnapshotNote(notes []snapshotNote, noteID, wikiTarget string) *snapshotNote {
+	wikiTarget = strings.Trim(strings.TrimSpace(wikiTarget), "/")
+	chapterName, noteName, inChapter := strings.Cut(wikiTarget, "/")
+	for i, candidate := range notes {
+		switch {
+		case noteID != "":
+			if candidate.note.ID == noteID {
+				return &notes[i]
+			}
+		case inChapter:
+			if models.Slugify(candidate.chapter.Name) == models.Slugify(chapterName) && models.Slugify(candidate.note.Name) == models.Slugify(noteName) {
+				return &notes[i]
+			}
+		case wikiTarget != "":
+			if models.Slugify(candidate.note.Name) == models.Slugify(wikiTarget) {
+				return &notes[i]
+			}
+		}
+	}
+	return nil
+}
+
+// linkIssueFixes returns the fixes an issue can take
+func linkIssueFixes(issue *models.LinkCheckIssue) []string {
+	switch issue.Kind {
+	case models.LinkIssueBrokenURL, models.LinkIssueRedirectedURL:
+		return []string{LinkFixUpdateURL, LinkFixRemoveLink}
+	case models.LinkIssueMissingNote, models.LinkIssueUnresolvedWikiLink:
+		if issue.RestoreSnapshot != "" {
+			return []string{LinkFixRemoveLink, LinkFixRestoreTarget}
+		}
+		return []string{LinkFixRemoveLink}
+	}
+	return []string{}
+}
+
+// urlCheckIssue turns the check of an external URL into an issue, when it found one. URLs of private
+// addresses or that can't be checked aren't reported.
+func urlCheckIssue(rawURL string, result linkURLCheck) (models.LinkCheckIssue, bool) {
+	issue := models.LinkCheckIssue{URL: rawURL, StatusCode: result.status}
+	switch {
+	case errors.Is(result.err, ErrLinkPreviewBlocked), errors.Is(result.err, ErrInvalidLinkURL):
+		return issue, false
+	case result.err != nil:
+		issue.Kind = models.LinkIssueBrokenURL
+		issue.Message = "The page couldn't be reached"
+	case result.status >= http.StatusBadRequest:
+		issue.Kind = models.LinkIssueBrokenURL
+		issue.Message = fmt.Sprintf("The page answers %d %s", result.status, http.StatusText(result.status))
+	case result.movedTo != "":
+		issue.Kind = models.LinkIssueRedirectedURL
+		issue.RedirectURL = result.movedTo
+		issue.Message = "The page moved permanently to " + result.movedTo
+	default:
+		return issue, false
+	}
+	return issue, true
+}
+
+// checkLinkURL requests an external URL, following permanent redirects to where the page lives now
+func checkLinkURL(ctx context.Context, client *http.Client, rawURL string) linkURLCheck {
+	current, err := normalizeLinkURL(rawURL)
+	if err != nil {
+		return linkURLCheck{err: err}
+	}
+
+	var result linkURLCheck
+	for range linkPreviewMaxRedirects {
+		status, location, err := requestLinkURL(ctx, client, current)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		result.status = status
+		if (status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect) || location == "" {
+			return result
+		}
+
+		base, err := url.Parse(current)
+		if err != nil {
+			return result
+		}
+		next, err := base.Parse(location)
+		if err != nil {
+			return result
+		}
+		normalized, err := normalizeLinkURL(next.String())
+		if err != nil {
+			return result
+		}
+		result.movedTo, current = normalized, normalized
+	}
+	return result
+}
+
+// requestLinkURL returns the status and redirect target of a URL. HEAD is tried first, and GET when
+// the server doesn't answer HEAD requests.
+func requestLinkURL(ctx context.Context, client *http.Client, rawURL string) (int, string, error) {
+	var status int
+	var location string
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return 0, "", fmt.Errorf("%w: %v", ErrInvalidLinkURL, err)
+		}
+		req.Header.Set("User-Agent", linkCheckUserAgent)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if errors.Is(err, ErrLinkPreviewBlocked) {
+				return 0, "", ErrLinkPreviewBlocked
+			}
+			return 0, "", fmt.Errorf("%w: %v", ErrLinkPreviewFetchFailed, err)
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		status, location = resp.StatusCode, resp.Header.Get("Location")
+		if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented && status != http.StatusForbidden {
+			break
+		}
+	}
+	return status, location, nil
+}
+
+// extractContentURLs returns the external URLs of a note's content, links and URLs written out, outside code
+func extractContentURLs(content string) []string {
+	var urls []string
+	add := func(u string) {
+		if u != "" && !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" {
+		// Markdown content
+		for _, match := range markdownLinkPattern.FindAllStringSubmatch(content, -1) {
+			add(match[1])
+		}
+		for _, match := range bareURLPattern.FindAllString(markdownLinkPattern.ReplaceAllString(content, "]"), -1) {
+			add(trimURLPunctuation(match))
+		}
+		return urls
+	}
+
+	var walk func(nodes []utils.TipTapNode)
+	walk = func(nodes []utils.TipTapNode) {
+		for _, node := range nodes {
+			if node.Type == "codeBlock" {
+				continue
+			}
+			code := false
+			for _, mark := range node.Marks {
+				switch mark.Type {
+				case "link":
+					if href, ok := mark.Attrs["href"].(string); ok && bareURLPattern.MatchString(href) {
+						add(href)
+					}
+				case "code":
+					code = true
+				}
+			}
+			if !code {
+				for _, match := range bareURLPattern.FindAllString(node.Text, -1) {
+					add(trimURLPunctuation(match))
+				}
+			}
+			walk(node.Content)
+		}
+	}
+	walk(doc.Content)
+	return urls
+}
+
+// trimURLPunctuation drops the punctuation ending the sentence a URL was written in
+func trimURLPunctuation(u string) string {
+	u = strings.TrimRight(u, ".,;:!?")
+	if strings.HasSuffix(u, ")") && strings.Count(u, "(") < strings.Count(u, ")") {
+		u = strings.TrimSuffix(u, ")")
+	}
+	return u
+}
+
+// urlOccurrencePattern matches a URL written out in text, not as the start of a longer URL
+func urlOccurrencePattern(u string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(u) + `([.,;:!?]*(?:[\s)\]>"']|$))`)
+}
+
+// replaceContentURL replaces a URL in a note's content, in links and in text. An empty replacement
+// removes the URL: links to it become plain text and the URL written out is dropped. It reports
+// whether the content changed.
+func replaceContentURL(content, oldURL, newURL string) (string, bool) {
+	occurrence := urlOccurrencePattern(oldURL)
+	replaceText := func(text string) string {
+		return occurrence.ReplaceAllStringFunc(text, func(match string) string {
+			return newURL + strings.TrimPrefix(match, oldURL)
+		})
+	}
+
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" {
+		// Markdown content
+		link := regexp.MustCompile(`\[([^\]]*)\]\(` + regexp.QuoteMeta(oldURL) + `\)`)
+		updated := link.ReplaceAllStringFunc(content, func(match string) string {
+			text := link.FindStringSubmatch(match)[1]
+			if newURL == "" {
+				return text
+			}
+			return "[" + text + "](" + newURL + ")"
+		})
+		updated = replaceText(updated)
+		return updated, updated != content
+	}
+
+	changed := false
+	var rewrite func(nodes []utils.TipTapNode) []utils.TipTapNode
+	rewrite = func(nodes []utils.TipTapNode) []utils.TipTapNode {
+		kept := nodes[:0]
+		for _, node := range nodes {
+			marks := node.Marks[:0]
+			for _, mark := range node.Marks {
+				if href, ok := mark.Attrs["href"].(string); ok && mark.Type == "link" && href == oldURL {
+					changed = true
+					if newURL == "" {
+						continue
+					}
+					mark.Attrs["href"] = newURL
+				}
+				marks = append(marks, mark)
+			}
+			node.Marks = marks
+			if node.Type == "text" {
+				if text := replaceText(node.Text); text != node.Text {
+					changed = true
+					node.Text = text
+				}
+				if node.Text == "" {
+					continue
+				}
+			}
+			node.Content = rewrite(node.Content)
+			kept = append(kept, node)
+		}
+		return kept
+	}
+	doc.Content = rewrite(doc.Content)
+	if !changed {
+		return content, false
+	}
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return content, false
+	}
+	return string(encoded), true
+}
+
+// unlinkContentWikiLink turns the [[wiki links]] to a target in a note's content into their text, and
+// reports whether the content changed
+func unlinkContentWikiLink(content, target string) (string, bool) {
+	unlink := func(text string) string {
+		return wikiLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+			inner := strings.TrimSuffix(strings.TrimPrefix(match, "[["), "]]")
+			name, label, labeled := strings.Cut(inner, "|")
+			linked, _, _ := strings.Cut(name, "#")
+			if strings.TrimSpace(linked) != target {
+				return match
+			}
+			if labeled && strings.TrimSpace(label) != "" {
+				return strings.TrimSpace(label)
+			}
+			return strings.TrimSpace(name)
+		})
+	}
+
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" {
+		updated := unlink(content)
+		return updated, updated != content
+	}
+
+	changed := false
+	var rewrite func(nodes []utils.TipTapNode)
+	rewrite = func(nodes []utils.TipTapNode) {
+		for i := range nodes {
+			if text := unlink(nodes[i].Text); text != nodes[i].Text {
+				nodes[i].Text = text
+				changed = true
+			}
+			rewrite(nodes[i].Content)
+		}
+	}
+	rewrite(doc.Content)
+	if !changed {
+		return content, false
+	}
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return content, false
+	}
+	return string(encoded), true
+}
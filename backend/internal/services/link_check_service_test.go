@@ -0,0 +1,175 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestLinkCheckService creates a link check service on an in-memory database, with external URLs
+// answering as the statuses say and every other URL answering 200
+func setupTestLinkCheckService(t *testing.T, statuses map[string]linkURLCheck) (*linkCheckServiceImpl, *gorm.DB, *[]string) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteLink{},
+		&models.NotebookSnapshot{}, &models.LinkCheck{}, &models.LinkCheckIssue{}, &models.YjsDocument{}, &models.YjsUpdate{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	var (
+		mu      sync.Mutex
+		checked []string
+	)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	service := &linkCheckServiceImpl{
+		db:    db,
+		queue: NewJobQueue(1, 10),
+		checkURL: func(_ context.Context, rawURL string) linkURLCheck {
+			mu.Lock()
+			checked = append(checked, rawURL)
+			mu.Unlock()
+			if result, ok := statuses[rawURL]; ok {
+				return result
+			}
+			return linkURLCheck{status: 200}
+		},
+		now: func() time.Time { return now },
+	}
+	return service, db, &checked
+}
+
+// linkCheckDoc builds a TipTap document of one paragraph
+func linkCheckDoc(t *testing.T, nodes ...utils.TipTapNode) string {
+	encoded, err := json.Marshal(utils.TipTapDoc{Type: "doc", Content: []utils.TipTapNode{{Type: "paragraph", Content: nodes}}})
+	require.NoError(t, err)
+	return string(encoded)
+}
+
+func TestLinkCheck_ReportAndFixes(t *testing.T) {
+	service, db, checked := setupTestLinkCheckService(t, map[string]linkURLCheck{
+		"https://old.example.com/docs":  {status: 301, movedTo: "https://new.example.com/docs"},
+		"https://gone.example.com/page": {status: 404},
+		"http://10.0.0.1/wiki":          {err: ErrLinkPreviewBlocked},
+	})
+	ctx := context.Background()
+
+	notebook := models.Notebook{Name: "Docs", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Guides", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+	setup := models.Notes{Name: "Setup", Content: linkCheckDoc(t, utils.TipTapNode{Type: "text", Text: "Install it."}), ChapterID: chapter.ID}
+	require.NoError(t, db.Create(&setup).Error)
+	archive := models.Notes{Name: "Archive", Content: linkCheckDoc(t, utils.TipTapNode{Type: "text", Text: "Old releases."}), ChapterID: chapter.ID}
+	require.NoError(t, db.Create(&archive).Error)
+
+	guide := models.Notes{Name: "Guide", ChapterID: chapter.ID, Content: linkCheckDoc(t,
+		utils.TipTapNode{Type: "text", Text: "Old docs", Marks: []utils.TipTapMark{{Type: "link", Attrs: map[string]interface{}{"href": "https://old.example.com/docs"}}}},
+		utils.TipTapNode{Type: "text", Text: " and https://gone.example.com/page. See [[Setup]], [[Missing|the missing page]] and http://10.0.0.1/wiki "},
+		utils.TipTapNode{Type: "text", Text: "https://code.example.com", Marks: []utils.TipTapMark{{Type: "code"}}},
+	)}
+	require.NoError(t, db.Create(&guide).Error)
+
+	// The archive note is deleted after a snapshot, leaving a link to it
+	_, err := (&notebookSnapshotServiceImpl{db: db}).CreateSnapshot(notebook.ID, "v1", "", "user_1")
+	require.NoError(t, err)
+	link := models.NoteLink{ID: "link_1", SourceNoteID: guide.ID, TargetNoteID: archive.ID, CreatedBy: "user_1"}
+	require.NoError(t, db.Create(&link).Error)
+	require.NoError(t, db.Delete(&archive).Error)
+
+	report, err := service.Check(ctx, notebook.ID)
+	require.NoError(t, err)
+	require.NotNil(t, report.CheckedAt)
+	assert.False(t, report.Checking)
+	assert.Equal(t, 2, report.NotesChecked)
+	assert.NotContains(t, *checked, "https://code.example.com", "URLs in code aren't checked")
+
+	issues := map[string]LinkReportIssue{}
+	for _, issue := range report.Issues {
+		issues[issue.Kind] = issue
+	}
+	require.Len(t, report.Issues, 4, "the private URL isn't reported")
+	assert.Equal(t, "https://new.example.com/docs", issues[models.LinkIssueRedirectedURL].RedirectURL)
+	assert.Equal(t, 404, issues[models.LinkIssueBrokenURL].StatusCode)
+	assert.Equal(t, []string{LinkFixUpdateURL, LinkFixRemoveLink}, issues[models.LinkIssueBrokenURL].Fixes)
+	assert.Equal(t, "Missing", issues[models.LinkIssueUnresolvedWikiLink].WikiTarget)
+	assert.Equal(t, []string{LinkFixRemoveLink}, issues[models.LinkIssueUnresolvedWikiLink].Fixes)
+	assert.Equal(t, "v1", issues[models.LinkIssueMissingNote].RestoreSnapshot)
+	assert.Equal(t, []string{LinkFixRemoveLink, LinkFixRestoreTarget}, issues[models.LinkIssueMissingNote].Fixes)
+
+	t.Run("update a moved URL", func(t *testing.T) {
+		note, err := service.UpdateURL(ctx, notebook.ID, issues[models.LinkIssueRedirectedURL].ID, "", "user_1")
+		require.NoError(t, err)
+		assert.Contains(t, note.Content, "https://new.example.com/docs")
+		assert.NotContains(t, note.Content, "old.example.com")
+
+		_, err = service.UpdateURL(ctx, notebook.ID, issues[models.LinkIssueMissingNote].ID, "https://example.com", "user_1")
+		assert.ErrorIs(t, err, ErrLinkFixNotApplicable)
+	})
+
+	t.Run("remove a broken URL and an unresolved wiki link", func(t *testing.T) {
+		_, err := service.RemoveLink(ctx, notebook.ID, issues[models.LinkIssueBrokenURL].ID, "user_1")
+		require.NoError(t, err)
+		note, err := service.RemoveLink(ctx, notebook.ID, issues[models.LinkIssueUnresolvedWikiLink].ID, "user_1")
+		require.NoError(t, err)
+
+		markdown, err := utils.TipTapToMarkdown(note.Content)
+		require.NoError(t, err)
+		assert.NotContains(t, markdown, "gone.example.com")
+		assert.Contains(t, markdown, "and . See [[Setup]], the missing page and")
+	})
+
+	t.Run("restore a deleted note", func(t *testing.T) {
+		restored, err := service.RestoreTarget(ctx, notebook.ID, issues[models.LinkIssueMissingNote].ID, "user_1")
+		require.NoError(t, err)
+		assert.Equal(t, archive.ID, restored.ID)
+		assert.Equal(t, chapter.ID, restored.ChapterID)
+
+		_, err = service.RestoreTarget(ctx, notebook.ID, issues[models.LinkIssueMissingNote].ID, "user_1")
+		assert.ErrorIs(t, err, ErrLinkIssueNotFound)
+	})
+
+	report, err = service.Report(ctx, notebook.ID)
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues)
+
+	report, err = service.Check(ctx, notebook.ID)
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues, "fixed links aren't reported again")
+}
+
+func TestLinkCheck_EncryptedNotebook(t *testing.T) {
+	service, db, _ := setupTestLinkCheckService(t, nil)
+	notebook := models.Notebook{Name: "Secrets", ClerkUserID: "user_1", Encrypted: true}
+	require.NoError(t, db.Create(&notebook).Error)
+
+	_, err := service.Check(context.Background(), notebook.ID)
+	assert.ErrorIs(t, err, ErrNotebookEncrypted)
+	assert.Zero(t, service.QueueDueChecks(service.now()), "encrypted notebooks aren't checked in the background")
+}
+
+func TestReplaceContentURL_Markdown(t *testing.T) {
+	content := "Read [the guide](https://example.com/guide) or https://example.com/guide.\n" +
+		"Also https://example.com/guide-v2 and [wiki](https://en.wikipedia.org/wiki/Go_(language))."
+
+	assert.Equal(t, []string{"https://example.com/guide", "https://en.wikipedia.org/wiki/Go_(language)", "https://example.com/guide-v2"},
+		extractContentURLs(content))
+
+	updated, changed := replaceContentURL(content, "https://example.com/guide", "https://example.com/docs")
+	assert.True(t, changed)
+	assert.Equal(t, "Read [the guide](https://example.com/docs) or https://example.com/docs.\n"+
+		"Also https://example.com/guide-v2 and [wiki](https://en.wikipedia.org/wiki/Go_(language)).", updated)
+
+	removed, changed := replaceContentURL(content, "https://example.com/guide", "")
+	assert.True(t, changed)
+	assert.True(t, strings.HasPrefix(removed, "Read the guide or .\n"))
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrInvalidEmbedProvider is returned when an allowlist names an unsupported provider
+var ErrInvalidEmbedProvider = errors.New("unknown embed provider")
+
+var (
+	youtubeVideoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	loomVideoIDPattern    = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+	miroBoardIDPattern    = regexp.MustCompile(`^[A-Za-z0-9_=-]+$`)
+	youtubeStartPattern   = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s?)?$`)
+)
+
+// figmaEmbedPaths are the Figma URL kinds that can be embedded
+var figmaEmbedPaths = map[string]bool{
+	"file":   true,
+	"design": true,
+	"proto":  true,
+	"board":  true,
+	"slides": true,
+	"deck":   true,
+}
+
+// linkEmbed describes how a provider's link is embedded
+type linkEmbed struct {
+	Provider     string
+	SiteName     string
+	EmbedURL     string
+	ThumbnailURL string
+	OEmbedURL    string // Endpoint returning the title and thumbnail, when the provider has one
+}
+
+// oEmbedResponse is the part of an oEmbed response used for previews
+type oEmbedResponse struct {
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// detectLinkEmbed returns the embed for a Figma, Miro, Loom or YouTube link, or nil for other links
+func detectLinkEmbed(link *url.URL) *linkEmbed {
+	host := strings.TrimPrefix(strings.ToLower(link.Hostname()), "www.")
+	segments := strings.Split(strings.Trim(link.EscapedPath(), "/"), "/")
+	escaped := url.QueryEscape(link.String())
+
+	switch host {
+	case "figma.com":
+		if len(segments) >= 2 && figmaEmbedPaths[segments[0]] {
+			return &linkEmbed{
+				Provider:  models.EmbedProviderFigma,
+				SiteName:  "Figma",
+				EmbedURL:  "https://www.figma.com/embed?embed_host=notes&url=" + escaped,
+				OEmbedURL: "https://www.figma.com/api/oembed?url=" + escaped,
+			}
+		}
+
+	case "miro.com":
+		if len(segments) >= 3 && segments[0] == "app" && segments[1] == "board" && miroBoardIDPattern.MatchString(segments[2]) {
+			return &linkEmbed{
+				Provider:  models.EmbedProviderMiro,
+				SiteName:  "Miro",
+				EmbedURL:  "https://miro.com/app/live-embed/" + segments[2] + "/",
+				OEmbedURL: "https://miro.com/api/v1/oembed?url=" + escaped,
+			}
+		}
+
+	case "loom.com":
+		if len(segments) >= 2 && (segments[0] == "share" || segments[0] == "embed") && loomVideoIDPattern.MatchString(segments[1]) {
+			return &linkEmbed{
+				Provider:  models.EmbedProviderLoom,
+				SiteName:  "Loom",
+				EmbedURL:  "https://www.loom.com/embed/" + segments[1],
+				OEmbedURL: "https://www.loom.com/v1/oembed?url=" + url.QueryEscape("https://www.loom.com/share/"+segments[1]),
+			}
+		}
+
+	case "youtube.com", "m.youtube.com", "music.youtube.com", "youtube-nocookie.com", "youtu.be":
+		videoID := ""
+		switch {
+		case host == "youtu.be":
+			videoID = segments[0]
+		case segments[0] == "watch":
+			videoID = link.Query().Get("v")
+		case len(segments) >= 2 && (segments[0] == "embed" || segments[0] == "shorts" || segments[0] == "live"):
+			videoID = segments[1]
+		}
+		if !youtubeVideoIDPattern.MatchString(videoID) {
+			return nil
+		}
+
+		embedURL := "https://www.youtube.com/embed/" + videoID
+		if start := youtubeStartSeconds(link.Query().Get("t")); start > 0 {
+			embedURL += "?start=" + strconv.Itoa(start)
+		}
+		watchURL := "https://www.youtube.com/watch?v=" + videoID
+		return &linkEmbed{
+			Provider:     models.EmbedProviderYouTube,
+			SiteName:     "YouTube",
+			EmbedURL:     embedURL,
+			ThumbnailURL: "https://i.ytimg.com/vi/" + videoID + "/hqdefault.jpg",
+			OEmbedURL:    "https://www.youtube.com/oembed?format=json&url=" + url.QueryEscape(watchURL),
+		}
+	}
+
+	return nil
+}
+
+// youtubeStartSeconds parses a YouTube start time such as 90, 90s or 1m30s
+func youtubeStartSeconds(value string) int {
+	match := youtubeStartPattern.FindStringSubmatch(value)
+	if value == "" || match == nil {
+		return 0
+	}
+	hours, _ := strconv.Atoi(match[1])
+	minutes, _ := strconv.Atoi(match[2])
+	seconds, _ := strconv.Atoi(match[3])
+	return hours*3600 + minutes*60 + seconds
+}
+
+// fetchEmbedMetadata builds the preview of an embeddable link from the provider's oEmbed endpoint
+func (s *linkPreviewServiceImpl) fetchEmbedMetadata(ctx context.Context, normalized string, embed *linkEmbed) (*LinkMetadata, error) {
+	metadata := &LinkMetadata{
+		URL:         normalized,
+		SiteName:    embed.SiteName,
+		ContentType: "text/html",
+		ImageURL:    embed.ThumbnailURL,
+		Provider:    embed.Provider,
+		EmbedURL:    embed.EmbedURL,
+	}
+	if embed.OEmbedURL == "" {
+		return metadata, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, embed.OEmbedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLinkPreviewFetchFailed, err)
+	}
+	req.Header.Set("User-Agent", linkPreviewUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLinkPreviewFetchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: oEmbed status %d", ErrLinkPreviewFetchFailed, resp.StatusCode)
+	}
+
+	var oembed oEmbedResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, linkPreviewMaxBodyBytes)).Decode(&oembed); err != nil {
+		return nil, fmt.Errorf("%w: invalid oEmbed response: %v", ErrLinkPreviewFetchFailed, err)
+	}
+
+	metadata.Title = truncateLinkText(strings.TrimSpace(oembed.Title), 300)
+	if oembed.AuthorName != "" {
+		metadata.Description = truncateLinkText("By "+strings.TrimSpace(oembed.AuthorName), 1000)
+	}
+	if thumbnail, err := url.Parse(oembed.ThumbnailURL); err == nil && thumbnail.Scheme == "https" {
+		metadata.ImageURL = thumbnail.String()
+	}
+	return metadata, nil
+}
+
+// ApplyEmbedPolicy drops the embed from a preview when the organization doesn't allow its provider,
+// so the editor falls back to a plain link card
+func (s *linkPreviewServiceImpl) ApplyEmbedPolicy(ctx context.Context, link *models.ExternalLink, organizationID *string) {
+	if link.Provider == "" || organizationID == nil || *organizationID == "" {
+		return
+	}
+
+	allowed, err := s.GetEmbedSettings(ctx, *organizationID)
+	if err != nil {
+		log.Warn().Err(err).Str("org_id", *organizationID).Msg("Failed to load embed settings")
+		return
+	}
+	if !containsString(allowed, link.Provider) {
+		link.EmbedURL = ""
+	}
+}
+
+// GetEmbedSettings returns the providers an organization allows to be embedded
+func (s *linkPreviewServiceImpl) GetEmbedSettings(ctx context.Context, organizationID string) ([]string, error) {
+	var settings models.OrganizationEmbedSettings
+	if err := s.db.WithContext(ctx).Where("organization_id = ?", organizationID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch embed settings: %w", err)
+	}
+	if settings.ID == 0 {
+		return append([]string(nil), models.EmbedProviders...), nil
+	}
+	if settings.AllowedProviders == "" {
+		return []string{}, nil
+	}
+	return strings.Split(settings.AllowedProviders, ","), nil
+}
+
+// SaveEmbedSettings replaces the providers an organization allows to be embedded
+func (s *linkPreviewServiceImpl) SaveEmbedSettings(ctx context.Context, organizationID string, providers []string, updatedBy string) ([]string, error) {
+	allowed := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if !containsString(models.EmbedProviders, provider) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidEmbedProvider, provider)
+		}
+		if !containsString(allowed, provider) {
+			allowed = append(allowed, provider)
+		}
+	}
+
+	settings := models.OrganizationEmbedSettings{OrganizationID: organizationID}
+	if err := s.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Assign(map[string]interface{}{
+			"allowed_providers": strings.Join(allowed, ","),
+			"updated_by":        updatedBy,
+		}).
+		FirstOrCreate(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to save embed settings: %w", err)
+	}
+	return allowed, nil
+}
@@ -0,0 +1,541 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidLinkURL is returned when a URL can't be previewed
+	ErrInvalidLinkURL = errors.New("invalid link URL")
+	// ErrLinkPreviewBlocked is returned when a URL points at a private or reserved address
+	ErrLinkPreviewBlocked = errors.New("link target is not allowed")
+	// ErrLinkPreviewFetchFailed is returned when the linked page can't be fetched
+	ErrLinkPreviewFetchFailed = errors.New("failed to fetch link")
+)
+
+const (
+	linkPreviewTimeout      = 10 * time.Second
+	linkPreviewMaxRedirects = 5
+	linkPreviewMaxBodyBytes = 1 << 20
+	linkPreviewCacheTTL     = 24 * time.Hour
+	linkPreviewCacheSize    = 1000
+	linkPreviewUserAgent    = "NotesAppLinkPreview/1.0"
+)
+
+// blockedLinkPrefixes are reserved ranges not covered by the net.IP helpers
+var blockedLinkPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// LinkMetadata is the OpenGraph/Twitter card metadata of a page
+type LinkMetadata struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"imageUrl"`
+	SiteName    string `json:"siteName"`
+	ContentType string `json:"contentType"`
+	Provider    string `json:"provider,omitempty"`
+	EmbedURL    string `json:"embedUrl,omitempty"`
+}
+
+// LinkPreviewService interface defines methods for fetching link previews
+type LinkPreviewService interface {
+	FetchMetadata(ctx context.Context, rawURL string) (*LinkMetadata, error)
+	PreviewLink(ctx context.Context, rawURL string, noteID *string, clerkUserID string) (*models.ExternalLink, error)
+	ApplyEmbedPolicy(ctx context.Context, link *models.ExternalLink, organizationID *string)
+	GetEmbedSettings(ctx context.Context, organizationID string) ([]string, error)
+	SaveEmbedSettings(ctx context.Context, organizationID string, providers []string, updatedBy string) ([]string, error)
+}
+
+// linkPreviewServiceImpl implements the LinkPreviewService interface
+type linkPreviewServiceImpl struct {
+	db     *gorm.DB
+	client *http.Client
+	cache  *linkPreviewCache
+}
+
+// NewLinkPreviewService creates a new LinkPreviewService instance
+func NewLinkPreviewService() LinkPreviewService {
+	return &linkPreviewServiceImpl{
+		db:     db.DB,
+		client: newLinkPreviewClient(),
+		cache:  getLinkPreviewCache(),
+	}
+}
+
+// PreviewLink returns the preview for a URL, reusing recent results.
+// When a note is given the preview is stored as an ExternalLink on that note, and embeds follow the note's organization allowlist.
+func (s *linkPreviewServiceImpl) PreviewLink(ctx context.Context, rawURL string, noteID *string, clerkUserID string) (*models.ExternalLink, error) {
+	normalized, err := normalizeLinkURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, fetchedAt, err := s.cachedMetadata(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	link := &models.ExternalLink{
+		NoteID:      noteID,
+		URL:         normalized,
+		Title:       metadata.Title,
+		Description: metadata.Description,
+		ImageURL:    metadata.ImageURL,
+		SiteName:    metadata.SiteName,
+		ContentType: metadata.ContentType,
+		Provider:    metadata.Provider,
+		EmbedURL:    metadata.EmbedURL,
+		CreatedBy:   clerkUserID,
+		FetchedAt:   fetchedAt,
+	}
+	if noteID == nil {
+		return link, nil
+	}
+
+	if err := s.db.WithContext(ctx).
+		Where("note_id = ? AND url = ?", *noteID, normalized).
+		Attrs(models.ExternalLink{ID: uuid.New().String(), CreatedBy: clerkUserID}).
+		Assign(map[string]interface{}{
+			"title":        link.Title,
+			"description":  link.Description,
+			"image_url":    link.ImageURL,
+			"site_name":    link.SiteName,
+			"content_type": link.ContentType,
+			"provider":     link.Provider,
+			"embed_url":    link.EmbedURL,
+			"fetched_at":   link.FetchedAt,
+		}).
+		FirstOrCreate(link).Error; err != nil {
+		log.Error().Err(err).Str("note_id", *noteID).Str("url", normalized).Msg("Failed to save external link")
+		return nil, fmt.Errorf("failed to save external link: %w", err)
+	}
+
+	// The stored link keeps the embed so allowlist changes apply to previews made later
+	if link.Provider != "" {
+		var note models.Notes
+		if err := s.db.WithContext(ctx).Select("organization_id").Where("id = ?", *noteID).Limit(1).Find(&note).Error; err == nil {
+			s.ApplyEmbedPolicy(ctx, link, note.OrganizationID)
+		}
+	}
+
+	return link, nil
+}
+
+// cachedMetadata looks for a fresh preview in memory, then in stored links, before fetching the page
+func (s *linkPreviewServiceImpl) cachedMetadata(ctx context.Context, normalized string) (*LinkMetadata, time.Time, error) {
+	if entry, found := s.cache.Get(normalized); found {
+		return entry.metadata, entry.fetchedAt, nil
+	}
+
+	var stored models.ExternalLink
+	err := s.db.WithContext(ctx).
+		Where("url = ? AND fetched_at > ?", normalized, time.Now().Add(-linkPreviewCacheTTL)).
+		Order("fetched_at DESC").
+		Limit(1).
+		Find(&stored).Error
+	if err != nil {
+		log.Warn().Err(err).Str("url", normalized).Msg("Failed to look up stored link preview")
+	} else if stored.ID != "" {
+		metadata := &LinkMetadata{
+			URL:         stored.URL,
+			Title:       stored.Title,
+			Description: stored.Description,
+			ImageURL:    stored.ImageURL,
+			SiteName:    stored.SiteName,
+			ContentType: stored.ContentType,
+			Provider:    stored.Provider,
+			EmbedURL:    stored.EmbedURL,
+		}
+		s.cache.Set(normalized, metadata, stored.FetchedAt)
+		return metadata, stored.FetchedAt, nil
+	}
+
+	metadata, err := s.FetchMetadata(ctx, normalized)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	fetchedAt := time.Now()
+	s.cache.Set(normalized, metadata, fetchedAt)
+	return metadata, fetchedAt, nil
+}
+
+// FetchMetadata downloads a page and extracts its preview metadata.
+// Figma, Miro, Loom and YouTube links also get an embed URL, with the title and thumbnail from oEmbed when available.
+func (s *linkPreviewServiceImpl) FetchMetadata(ctx context.Context, rawURL string) (*LinkMetadata, error) {
+	normalized, err := normalizeLinkURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, _ := url.Parse(normalized)
+	embed := detectLinkEmbed(parsed)
+	if embed == nil {
+		return s.fetchPageMetadata(ctx, normalized)
+	}
+
+	metadata, err := s.fetchEmbedMetadata(ctx, normalized, embed)
+	if err == nil && metadata.Title != "" {
+		return metadata, nil
+	}
+	if err != nil {
+		log.Debug().Err(err).Str("url", normalized).Msg("oEmbed lookup failed, reading the page instead")
+	}
+
+	// Private files and boards have no oEmbed data, but their pages still have a title
+	page, pageErr := s.fetchPageMetadata(ctx, normalized)
+	if pageErr != nil {
+		return &LinkMetadata{
+			URL:         normalized,
+			SiteName:    embed.SiteName,
+			ContentType: "text/html",
+			ImageURL:    embed.ThumbnailURL,
+			Provider:    embed.Provider,
+			EmbedURL:    embed.EmbedURL,
+		}, nil
+	}
+	page.Provider = embed.Provider
+	page.EmbedURL = embed.EmbedURL
+	if page.ImageURL == "" {
+		page.ImageURL = embed.ThumbnailURL
+	}
+	return page, nil
+}
+
+// fetchPageMetadata downloads a page and extracts its OpenGraph metadata
+func (s *linkPreviewServiceImpl) fetchPageMetadata(ctx context.Context, normalized string) (*LinkMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, normalized, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLinkURL, err)
+	}
+	req.Header.Set("User-Agent", linkPreviewUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrLinkPreviewBlocked) {
+			return nil, ErrLinkPreviewBlocked
+		}
+		return nil, fmt.Errorf("%w: %v", ErrLinkPreviewFetchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: status %d", ErrLinkPreviewFetchFailed, resp.StatusCode)
+	}
+
+	finalURL := resp.Request.URL
+	contentType := resp.Header.Get("Content-Type")
+	metadata := &LinkMetadata{
+		URL:         finalURL.String(),
+		SiteName:    finalURL.Hostname(),
+		ContentType: strings.TrimSpace(strings.Split(contentType, ";")[0]),
+	}
+
+	switch {
+	case strings.HasPrefix(metadata.ContentType, "image/"):
+		metadata.ImageURL = metadata.URL
+		return metadata, nil
+	case metadata.ContentType != "" && metadata.ContentType != "text/html" && metadata.ContentType != "application/xhtml+xml":
+		return metadata, nil
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, linkPreviewMaxBodyBytes), contentType)
+	if err != nil {
+		body = io.LimitReader(resp.Body, linkPreviewMaxBodyBytes)
+	}
+	parseLinkMetadata(body, finalURL, metadata)
+
+	return metadata, nil
+}
+
+// parseLinkMetadata reads the document head, preferring OpenGraph tags, then Twitter cards, then plain HTML
+func parseLinkMetadata(body io.Reader, pageURL *url.URL, metadata *LinkMetadata) {
+	meta := make(map[string]string)
+	var title string
+	inTitle := false
+
+	tokenizer := html.NewTokenizer(body)
+parse:
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			break parse
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "body":
+				break parse
+			case "title":
+				inTitle = tokenType == html.StartTagToken
+			case "meta":
+				var key, content string
+				for _, attr := range token.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "property", "name":
+						if key == "" {
+							key = strings.ToLower(strings.TrimSpace(attr.Val))
+						}
+					case "content":
+						content = strings.TrimSpace(attr.Val)
+					}
+				}
+				if key != "" && content != "" {
+					if _, exists := meta[key]; !exists {
+						meta[key] = content
+					}
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break parse
+			}
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := meta[key]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	if value := first("og:title", "twitter:title"); value != "" {
+		title = value
+	}
+	metadata.Title = truncateLinkText(strings.Join(strings.Fields(title), " "), 300)
+	metadata.Description = truncateLinkText(first("og:description", "twitter:description", "description"), 1000)
+	if siteName := first("og:site_name"); siteName != "" {
+		metadata.SiteName = truncateLinkText(siteName, 255)
+	}
+	if image := first("og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		if imageURL, err := pageURL.Parse(image); err == nil && (imageURL.Scheme == "http" || imageURL.Scheme == "https") {
+			metadata.ImageURL = imageURL.String()
+		}
+	}
+}
+
+// truncateLinkText shortens text to at most max bytes without splitting a character
+func truncateLinkText(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	text = text[:max]
+	for !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+// normalizeLinkURL validates a URL for previewing and strips parts that don't affect the page.
+// Whether the host is reachable is checked when connecting, see newLinkPreviewClient.
+func normalizeLinkURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("%w: URL is required", ErrInvalidLinkURL)
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidLinkURL, err)
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("%w: only http and https links are supported", ErrInvalidLinkURL)
+	}
+	if parsed.User != nil {
+		return "", fmt.Errorf("%w: links with credentials are not supported", ErrInvalidLinkURL)
+	}
+	if parsed.Hostname() == "" {
+		return "", fmt.Errorf("%w: host is required", ErrInvalidLinkURL)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if port := parsed.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	parsed.Host = host
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+
+	return parsed.String(), nil
+}
+
+// isPublicLinkAddr reports whether an address is safe for the server to connect to
+func isPublicLinkAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedLinkPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// newLinkPreviewClient creates an HTTP client that only connects to public addresses.
+// The check runs on the resolved address of every connection, so redirects and DNS rebinding can't reach internal hosts.
+func newLinkPreviewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return ErrLinkPreviewBlocked
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !isPublicLinkAddr(addr) {
+				return ErrLinkPreviewBlocked
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: linkPreviewTimeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: 5 * time.Second,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: checkLinkPreviewRedirect,
+	}
+}
+
+// checkLinkPreviewRedirect limits redirects and re-validates each target
+func checkLinkPreviewRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= linkPreviewMaxRedirects {
+		return fmt.Errorf("%w: too many redirects", ErrLinkPreviewFetchFailed)
+	}
+	if _, err := normalizeLinkURL(req.URL.String()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// linkPreviewCache keeps recently fetched previews in memory
+type linkPreviewCache struct {
+	cache map[string]*linkPreviewCacheEntry
+	mu    sync.RWMutex
+	ttl   time.Duration
+	size  int
+}
+
+type linkPreviewCacheEntry struct {
+	metadata  *LinkMetadata
+	fetchedAt time.Time
+}
+
+var (
+	previewCache     *linkPreviewCache
+	previewCacheOnce sync.Once
+)
+
+// getLinkPreviewCache returns the singleton link preview cache
+func getLinkPreviewCache() *linkPreviewCache {
+	previewCacheOnce.Do(func() {
+		previewCache = newLinkPreviewCache(linkPreviewCacheTTL, linkPreviewCacheSize)
+	})
+	return previewCache
+}
+
+// newLinkPreviewCache creates an empty link preview cache
+func newLinkPreviewCache(ttl time.Duration, size int) *linkPreviewCache {
+	return &linkPreviewCache{
+		cache: make(map[string]*linkPreviewCacheEntry),
+		ttl:   ttl,
+		size:  size,
+	}
+}
+
+// Get retrieves a cached preview if it is still fresh
+func (c *linkPreviewCache) Get(url string) (*linkPreviewCacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, exists := c.cache[url]
+	if !exists || time.Since(entry.fetchedAt) > c.ttl {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Set stores a preview, evicting expired entries when the cache is full
+func (c *linkPreviewCache) Set(url string, metadata *LinkMetadata, fetchedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.cache) >= c.size {
+		oldestKey := ""
+		var oldest time.Time
+		for key, entry := range c.cache {
+			if time.Since(entry.fetchedAt) > c.ttl {
+				delete(c.cache, key)
+				continue
+			}
+			if oldestKey == "" || entry.fetchedAt.Before(oldest) {
+				oldestKey, oldest = key, entry.fetchedAt
+			}
+		}
+		if len(c.cache) >= c.size {
+			delete(c.cache, oldestKey)
+		}
+	}
+
+	c.cache[url] = &linkPreviewCacheEntry{
+		metadata:  metadata,
+		fetchedAt: fetchedAt,
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LinkSuggestionJob periodically suggests links between the notes of related meetings
+type LinkSuggestionJob struct {
+	linkSuggestionService LinkSuggestionService
+	interval              time.Duration
+	stopChan              chan struct{}
+}
+
+// NewLinkSuggestionJob creates a new scheduled link suggestion job
+func NewLinkSuggestionJob(linkSuggestionService LinkSuggestionService, interval time.Duration) *LinkSuggestionJob {
+	return &LinkSuggestionJob{
+		linkSuggestionService: linkSuggestionService,
+		interval:              interval,
+		stopChan:              make(chan struct{}),
+	}
+}
+
+// Start begins analyzing meetings
+func (j *LinkSuggestionJob) Start(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Msg("Starting meeting link suggestion scheduler")
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			suggested, err := j.linkSuggestionService.AnalyzeAll(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Suggesting meeting links failed")
+			} else if suggested > 0 {
+				log.Info().Int("suggested", suggested).Msg("Suggested links between meeting notes")
+			}
+		case <-ctx.Done():
+			log.Info().Msg("Stopping meeting link suggestion scheduler (context cancelled)")
+			return
+		case <-j.stopChan:
+			log.Info().Msg("Stopping meeting link suggestion scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (j *LinkSuggestionJob) Stop() {
+	close(j.stopChan)
+}
@@ -0,0 +1,495 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"backend/pkg/recallai"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrLinkSuggestionNotFound is returned when a suggestion doesn't exist, isn't the user's or was already
+// reviewed
+var ErrLinkSuggestionNotFound = errors.New("link suggestion not found")
+
+const (
+	// meetingLinkWindow is how far back meetings are compared with each other
+	meetingLinkWindow = 90 * 24 * time.Hour
+	// minMeetingLinkScore is the overlap two meetings need before a link between their notes is suggested
+	minMeetingLinkScore = 0.3
+	// Meetings sharing fewer attendees and topics than this aren't linked, however small they are
+	minSharedMeetingAttendees = 2
+	minSharedMeetingTopics    = 3
+	// maxLinkSuggestionsPerNote caps the suggestions made for a note in one analysis
+	maxLinkSuggestionsPerNote = 5
+	minMeetingTopicLength     = 4
+	maxMeetingTopicText       = 4000
+)
+
+// meetingTopicStopwords are words every meeting note uses, which say nothing about its topic
+var meetingTopicStopwords = wordSet("meeting meetings call discussed discussion team agenda notes note summary update updates " +
+	"sync next steps action items item point points review also will would should could need needs about their there " +
+	"with from into this that these those they them have been were what when where which while")
+
+// speakerLabelPattern matches the labels redacted and Whisper transcripts use instead of names
+var speakerLabelPattern = regexp.MustCompile(`(?i)^speaker(?: \d+)?$`)
+
+// PendingLinkSuggestion is a suggested link waiting for review, with why it was suggested
+type PendingLinkSuggestion struct {
+	ID              string    `json:"id"`
+	SourceNoteID    string    `json:"sourceNoteId"`
+	SourceName      string    `json:"sourceName"`
+	TargetNoteID    string    `json:"targetNoteId"`
+	TargetName      string    `json:"targetName"`
+	SharedAttendees []string  `json:"sharedAttendees"`
+	SharedTopics    []string  `json:"sharedTopics"`
+	Score           float64   `json:"score"`
+	Reason          string    `json:"reason"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// LinkSuggestionService interface defines methods for links suggested between the notes of related
+// meetings and their review
+type LinkSuggestionService interface {
+	MeetingNoteCreated(noteID string)
+	AnalyzeMeetingNote(ctx context.Context, noteID string) (int, error)
+	AnalyzeAll(ctx context.Context) (int, error)
+	ListPending(ctx context.Context, clerkUserID string) ([]PendingLinkSuggestion, error)
+	Accept(ctx context.Context, suggestionID, clerkUserID string) (*models.NoteLink, error)
+	Dismiss(ctx context.Context, suggestionID, clerkUserID string) error
+}
+
+// linkSuggestionServiceImpl implements the LinkSuggestionService interface
+type linkSuggestionServiceImpl struct {
+	db    *gorm.DB
+	queue *JobQueue
+	now   func() time.Time
+}
+
+// NewLinkSuggestionService creates a new LinkSuggestionService instance
+func NewLinkSuggestionService() LinkSuggestionService {
+	return &linkSuggestionServiceImpl{
+		db:    db.DB,
+		queue: GetJobQueue(),
+		now:   time.Now,
+	}
+}
+
+// meetingProfile is what a meeting note is compared with other meetings on
+type meetingProfile struct {
+	note      models.Notes
+	owner     string
+	attendees map[string]string // Lowercased name to name
+	topics    map[string]string // Stem to the word it was first seen as
+}
+
+// meetingLinkCandidate is a link suggested between the notes of two meetings
+type meetingLinkCandidate struct {
+	source, target  *meetingProfile
+	sharedAttendees []string
+	sharedTopics    []string
+	score           float64
+}
+
+// MeetingNoteCreated compares a meeting's generated note with the owner's other meetings in the
+// background
+func (s *linkSuggestionServiceImpl) MeetingNoteCreated(noteID string) {
+	err := s.queue.Enqueue(Job{
+		Name:        "meeting-link-suggestions",
+		MaxAttempts: 2,
+		Run: func(ctx context.Context) error {
+			_, err := s.AnalyzeMeetingNote(ctx, noteID)
+			return err
+		},
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to queue meeting link suggestions")
+	}
+}
+
+// AnalyzeMeetingNote suggests links between a meeting note and the notes of the owner's meetings from
+// the last 90 days with overlapping attendees and topics. It returns how many suggestions it made.
+func (s *linkSuggestionServiceImpl) AnalyzeMeetingNote(ctx context.Context, noteID string) (int, error) {
+	profiles, err := s.meetingProfiles(ctx, "notes.id = ?", noteID)
+	if err != nil || len(profiles) == 0 {
+		return 0, err
+	}
+	note := profiles[0]
+	others, err := s.meetingProfiles(ctx, "notebooks.clerk_user_id = ? AND notes.created_at >= ? AND notes.id <> ?",
+		note.owner, s.now().Add(-meetingLinkWindow), note.note.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	var candidates []meetingLinkCandidate
+	for _, other := range others {
+		if candidate, ok := compareMeetings(note, other); ok {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return s.saveCandidates(ctx, candidates)
+}
+
+// AnalyzeAll compares every user's meetings from the last 90 days with each other, for meetings whose
+// notes were edited since they were recorded or that were missed when recorded. Pairs of notes already
+// linked or suggested are skipped, so it can run any number of times.
+func (s *linkSuggestionServiceImpl) AnalyzeAll(ctx context.Context) (int, error) {
+	profiles, err := s.meetingProfiles(ctx, "notes.created_at >= ?", s.now().Add(-meetingLinkWindow))
+	if err != nil {
+		return 0, err
+	}
+
+	byOwner := make(map[string][]*meetingProfile)
+	for _, profile := range profiles {
+		byOwner[profile.owner] = append(byOwner[profile.owner], profile)
+	}
+	var candidates []meetingLinkCandidate
+	for _, meetings := range byOwner {
+		for i := range meetings {
+			for j := i + 1; j < len(meetings); j++ {
+				if candidate, ok := compareMeetings(meetings[i], meetings[j]); ok {
+					candidates = append(candidates, candidate)
+				}
+			}
+		}
+	}
+	return s.saveCandidates(ctx, candidates)
+}
+
+// ListPending returns the suggestions waiting for the user's review, the strongest first
+func (s *linkSuggestionServiceImpl) ListPending(ctx context.Context, clerkUserID string) ([]PendingLinkSuggestion, error) {
+	var rows []struct {
+		models.LinkSuggestion
+		SourceName string
+		TargetName string
+	}
+	if err := db.Replica(s.db).WithContext(ctx).Model(&models.LinkSuggestion{}).
+		Select("link_suggestions.*, sources.name AS source_name, targets.name AS target_name").
+		Joins("JOIN notes AS sources ON sources.id = link_suggestions.source_note_id").
+		Joins("JOIN notes AS targets ON targets.id = link_suggestions.target_note_id").
+		Where("link_suggestions.clerk_user_id = ? AND link_suggestions.status = ?", clerkUserID, models.LinkSuggestionPending).
+		Order("link_suggestions.score DESC, link_suggestions.created_at DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch link suggestions: %w", err)
+	}
+
+	pending := make([]PendingLinkSuggestion, len(rows))
+	for i, row := range rows {
+		pending[i] = PendingLinkSuggestion{
+			ID:              row.ID,
+			SourceNoteID:    row.SourceNoteID,
+			SourceName:      row.SourceName,
+			TargetNoteID:    row.TargetNoteID,
+			TargetName:      row.TargetName,
+			SharedAttendees: splitLines(row.SharedAttendees),
+			SharedTopics:    splitLines(row.SharedTopics),
+			Score:           row.Score,
+			Reason:          linkSuggestionReason(splitLines(row.SharedAttendees), splitLines(row.SharedTopics)),
+			CreatedAt:       row.CreatedAt,
+		}
+	}
+	return pending, nil
+}
+
+// Accept links the notes of a pending suggestion. The link is annotated with why it was suggested.
+func (s *linkSuggestionServiceImpl) Accept(ctx context.Context, suggestionID, clerkUserID string) (*models.NoteLink, error) {
+	suggestion, err := s.pending(ctx, suggestionID, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var source models.Notes
+	if err := s.db.WithContext(ctx).Select("id", "organization_id").Where("id = ?", suggestion.SourceNoteID).First(&source).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLinkSuggestionNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	reason := linkSuggestionReason(splitLines(suggestion.SharedAttendees), splitLines(suggestion.SharedTopics))
+	if utf8.RuneCountInString(reason) > maxLinkAnnotationLength {
+		reason = truncateLinkText(reason, maxLinkAnnotationLength-1) + "…"
+	}
+	links := &NoteLinkService{db: s.db.WithContext(ctx)}
+	link, err := links.CreateNoteLink(suggestion.SourceNoteID, suggestion.TargetNoteID, models.LinkTypeRelated, "",
+		models.LinkOriginAI, reason, clerkUserID, source.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if err := s.db.WithContext(ctx).Model(suggestion).Updates(map[string]interface{}{
+		"status":      models.LinkSuggestionAccepted,
+		"link_id":     link.ID,
+		"reviewed_at": now,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to save link suggestion: %w", err)
+	}
+	return link, nil
+}
+
+// Dismiss turns a pending suggestion down, so its notes aren't suggested again
+func (s *linkSuggestionServiceImpl) Dismiss(ctx context.Context, suggestionID, clerkUserID string) error {
+	suggestion, err := s.pending(ctx, suggestionID, clerkUserID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Model(suggestion).Updates(map[string]interface{}{
+		"status":      models.LinkSuggestionDismissed,
+		"reviewed_at": s.now(),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to save link suggestion: %w", err)
+	}
+	return nil
+}
+
+// pending returns a suggestion waiting for the user's review
+func (s *linkSuggestionServiceImpl) pending(ctx context.Context, suggestionID, clerkUserID string) (*models.LinkSuggestion, error) {
+	var suggestion models.LinkSuggestion
+	err := s.db.WithContext(ctx).Where("id = ? AND clerk_user_id = ? AND status = ?", suggestionID, clerkUserID, models.LinkSuggestionPending).
+		First(&suggestion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrLinkSuggestionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch link suggestion: %w", err)
+	}
+	return &suggestion, nil
+}
+
+// meetingProfiles returns the attendees and topics of the meeting notes matching a condition. Meeting
+// notes are notes generated from a recording. Locked notes and notes in encrypted notebooks are left out.
+func (s *linkSuggestionServiceImpl) meetingProfiles(ctx context.Context, condition string, args ...interface{}) ([]*meetingProfile, error) {
+	var rows []struct {
+		models.Notes
+		Owner string
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Notes{}).
+		Select("notes.*, notebooks.clerk_user_id AS owner").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notes.meeting_recording_id IS NOT NULL OR notes.id IN (?)",
+			s.db.Model(&models.MeetingRecording{}).Select("generated_note_id").Where("generated_note_id IS NOT NULL")).
+		Where("notes.locked = ? AND notebooks.encrypted = ?", false, false).
+		Where(condition, args...).
+		Order("notes.created_at ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch meeting notes: %w", err)
+	}
+
+	profiles := make([]*meetingProfile, 0, len(rows))
+	for _, row := range rows {
+		profiles = append(profiles, newMeetingProfile(row.Notes, row.Owner))
+	}
+	return profiles, nil
+}
+
+// saveCandidates stores the strongest suggestions for each source note, skipping pairs of notes that are
+// already linked or were suggested before. It returns how many it stored.
+func (s *linkSuggestionServiceImpl) saveCandidates(ctx context.Context, candidates []meetingLinkCandidate) (int, error) {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	perNote := make(map[string]int)
+	saved := 0
+	for _, candidate := range candidates {
+		if perNote[candidate.source.note.ID] >= maxLinkSuggestionsPerNote {
+			continue
+		}
+		pair := []interface{}{candidate.source.note.ID, candidate.target.note.ID, candidate.target.note.ID, candidate.source.note.ID}
+		var known int64
+		if err := s.db.WithContext(ctx).Model(&models.LinkSuggestion{}).
+			Where("(source_note_id = ? AND target_note_id = ?) OR (source_note_id = ? AND target_note_id = ?)", pair...).
+			Count(&known).Error; err != nil {
+			return saved, fmt.Errorf("failed to check link suggestions: %w", err)
+		}
+		if known == 0 {
+			if err := s.db.WithContext(ctx).Model(&models.NoteLink{}).
+				Where("(source_note_id = ? AND target_note_id = ?) OR (source_note_id = ? AND target_note_id = ?)", pair...).
+				Count(&known).Error; err != nil {
+				return saved, fmt.Errorf("failed to check note links: %w", err)
+			}
+		}
+		if known > 0 {
+			continue
+		}
+
+		suggestion := models.LinkSuggestion{
+			ClerkUserID:     candidate.source.owner,
+			SourceNoteID:    candidate.source.note.ID,
+			TargetNoteID:    candidate.target.note.ID,
+			OrganizationID:  candidate.source.note.OrganizationID,
+			SharedAttendees: strings.Join(candidate.sharedAttendees, "\n"),
+			SharedTopics:    strings.Join(candidate.sharedTopics, "\n"),
+			Score:           candidate.score,
+			Status:          models.LinkSuggestionPending,
+		}
+		if err := s.db.WithContext(ctx).Create(&suggestion).Error; err != nil {
+			return saved, fmt.Errorf("failed to save link suggestion: %w", err)
+		}
+		perNote[candidate.source.note.ID]++
+		saved++
+	}
+	return saved, nil
+}
+
+// newMeetingProfile reads the attendees and topics of a meeting note. Attendees come from the stored
+// transcript, or the note's Participants section when it has none. Topics come from its name, summary
+// and key points.
+func newMeetingProfile(note models.Notes, owner string) *meetingProfile {
+	profile := &meetingProfile{note: note, owner: owner, attendees: map[string]string{}, topics: map[string]string{}}
+
+	markdown, err := utils.TipTapToMarkdown(note.Content)
+	if err != nil {
+		// Generated meeting notes are Markdown until they're edited
+		markdown = note.Content
+	}
+	sections := markdownSections(markdown)
+
+	var names []string
+	var transcript []recallai.TranscriptEntry
+	if note.TranscriptRaw != "" && json.Unmarshal([]byte(note.TranscriptRaw), &transcript) == nil {
+		for _, entry := range transcript {
+			names = append(names, entry.Participant.Name)
+		}
+	} else {
+		for _, line := range strings.Split(sections["participants"], "\n") {
+			if name, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok {
+				names = append(names, strings.TrimSuffix(name, " (Host)"))
+			}
+		}
+	}
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" || speakerLabelPattern.MatchString(name) {
+			continue
+		}
+		if _, ok := profile.attendees[strings.ToLower(name)]; !ok {
+			profile.attendees[strings.ToLower(name)] = name
+		}
+	}
+
+	text := note.Name + "\n" + sections["summary"] + "\n" + sections["key points"]
+	if strings.TrimSpace(sections["summary"]+sections["key points"]) == "" {
+		text += "\n" + note.AISummary + "\n" + note.Summary
+	}
+	addMeetingTopics(profile.topics, text, note.Language)
+	return profile
+}
+
+// addMeetingTopics adds the words of text that can say what a meeting is about to topics, by stem
+func addMeetingTopics(topics map[string]string, text, language string) {
+	if language == "" {
+		language = "en"
+	}
+	for _, word := range languageWords(truncateLinkText(text, maxMeetingTopicText)) {
+		if utf8.RuneCountInString(word) < minMeetingTopicLength || meetingTopicStopwords[word] || isStopword(word) {
+			continue
+		}
+		stem := StemWord(language, word)
+		if _, ok := topics[stem]; !ok {
+			topics[stem] = word
+		}
+	}
+}
+
+// compareMeetings scores how much two meetings overlap, and suggests linking the later meeting's note
+// to the earlier one's when they overlap enough
+func compareMeetings(a, b *meetingProfile) (meetingLinkCandidate, bool) {
+	if b.note.CreatedAt.After(a.note.CreatedAt) {
+		a, b = b, a
+	}
+	candidate := meetingLinkCandidate{source: a, target: b}
+	for key, name := range a.attendees {
+		if _, ok := b.attendees[key]; ok {
+			candidate.sharedAttendees = append(candidate.sharedAttendees, name)
+		}
+	}
+	for stem, word := range a.topics {
+		if _, ok := b.topics[stem]; ok {
+			candidate.sharedTopics = append(candidate.sharedTopics, word)
+		}
+	}
+	sort.Strings(candidate.sharedAttendees)
+	sort.Strings(candidate.sharedTopics)
+
+	if len(candidate.sharedAttendees) < minSharedMeetingAttendees && len(candidate.sharedTopics) < minSharedMeetingTopics {
+		return candidate, false
+	}
+	score := (jaccard(len(candidate.sharedAttendees), len(a.attendees), len(b.attendees)) +
+		jaccard(len(candidate.sharedTopics), len(a.topics), len(b.topics))) / 2
+	candidate.score = math.Round(score*100) / 100
+	return candidate, candidate.score >= minMeetingLinkScore
+}
+
+// jaccard returns the Jaccard index of two sets from their sizes and the size of their intersection
+func jaccard(shared, a, b int) float64 {
+	if a+b-shared == 0 {
+		return 0
+	}
+	return float64(shared) / float64(a+b-shared)
+}
+
+// isStopword reports whether a word is among the most common words of a language notes are written in
+func isStopword(word string) bool {
+	for _, stopwords := range languageStopwords {
+		if stopwords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// markdownSections returns the text under each level 2 heading of Markdown, by lowercased heading
+func markdownSections(markdown string) map[string]string {
+	sections := make(map[string]string)
+	heading := ""
+	for _, line := range strings.Split(markdown, "\n") {
+		if title, ok := strings.CutPrefix(line, "## "); ok {
+			heading = strings.ToLower(strings.TrimSpace(title))
+			continue
+		}
+		if heading != "" {
+			sections[heading] += line + "\n"
+		}
+	}
+	return sections
+}
+
+// linkSuggestionReason says why two notes were suggested to be linked. Notes sharing attendees are
+// related meetings; meeting notes are also linked to the notes of the project they're filed in.
+func linkSuggestionReason(attendees, topics []string) string {
+	reason := "Related notes."
+	if len(attendees) > 0 {
+		reason = "Related meetings."
+	}
+	if len(attendees) > 0 {
+		reason += " Shared attendees: " + strings.Join(attendees, ", ") + "."
+	}
+	if len(topics) > 0 {
+		reason += " Shared topics: " + strings.Join(topics, ", ") + "."
+	}
+	return reason
+}
+
+// splitLines returns the non-empty lines of newline-separated text
+func splitLines(text string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
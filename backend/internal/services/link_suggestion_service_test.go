@@ -0,0 +1,137 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestLinkSuggestionService creates a link suggestion service on an in-memory database
+func setupTestLinkSuggestionService(t *testing.T) (*linkSuggestionServiceImpl, models.Chapter) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.MeetingRecording{},
+		&models.NoteLink{}, &models.LinkSuggestion{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	notebook := models.Notebook{Name: "Meetings", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "March", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	return &linkSuggestionServiceImpl{db: db, queue: NewJobQueue(1, 10), now: func() time.Time { return now }}, chapter
+}
+
+// createMeetingNote creates a note generated from a recording, in the Markdown meeting notes start as
+func createMeetingNote(t *testing.T, db *gorm.DB, chapterID, name, summary string, createdAt time.Time, participants ...string) models.Notes {
+	recording := models.MeetingRecording{ClerkUserID: "user_1", BotID: "bot_" + name, MeetingURL: "https://meet.example.com/" + name}
+	require.NoError(t, db.Create(&recording).Error)
+
+	content := fmt.Sprintf("# %s\n\n## Summary\n\n%s\n\n## Participants\n\n", name, summary)
+	for i, participant := range participants {
+		if i == 0 {
+			participant += " (Host)"
+		}
+		content += "- " + participant + "\n"
+	}
+	content += "\n## Full Transcript\n\n---\n\nEveryone: thanks for joining the meeting.\n"
+
+	note := models.Notes{Name: name, Content: content, ChapterID: chapterID, CreatedAt: createdAt}
+	require.NoError(t, db.Create(&note).Error)
+	require.NoError(t, db.Model(&recording).Update("generated_note_id", note.ID).Error)
+	return note
+}
+
+func TestLinkSuggestions_AnalyzeMeetingNote(t *testing.T) {
+	service, chapter := setupTestLinkSuggestionService(t)
+	ctx := context.Background()
+	day := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	planning := createMeetingNote(t, service.db, chapter.ID, "Pricing planning",
+		"We compared pricing tiers for the enterprise launch and the discount policy.", day, "Ana", "Ben", "Speaker 1")
+	createMeetingNote(t, service.db, chapter.ID, "Hiring sync",
+		"Interview loops for the designer role.", day.AddDate(0, 0, 1), "Ana", "Cleo")
+	createMeetingNote(t, service.db, chapter.ID, "Old pricing review",
+		"Pricing tiers for the enterprise launch.", day.AddDate(0, -6, 0), "Ana", "Ben")
+	followUp := createMeetingNote(t, service.db, chapter.ID, "Pricing follow-up",
+		"Final pricing tiers and discount policy for the enterprise launch.", day.AddDate(0, 0, 7), "Ana", "Ben", "Dee", "Speaker 1")
+
+	suggested, err := service.AnalyzeMeetingNote(ctx, followUp.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, suggested, "Meetings sharing one attendee and no topics, or older than the window, aren't suggested")
+
+	pending, err := service.ListPending(ctx, "user_1")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	suggestion := pending[0]
+	assert.Equal(t, followUp.ID, suggestion.SourceNoteID, "The later meeting links to the earlier one")
+	assert.Equal(t, planning.ID, suggestion.TargetNoteID)
+	assert.Equal(t, "Pricing planning", suggestion.TargetName)
+	assert.Equal(t, []string{"Ana", "Ben"}, suggestion.SharedAttendees, "Speaker labels aren't attendees")
+	assert.Subset(t, suggestion.SharedTopics, []string{"pricing", "tiers", "enterprise", "launch", "discount", "policy"})
+	assert.GreaterOrEqual(t, suggestion.Score, minMeetingLinkScore)
+	assert.Contains(t, suggestion.Reason, "Shared attendees: Ana, Ben.")
+
+	empty, err := service.ListPending(ctx, "user_2")
+	require.NoError(t, err)
+	assert.Empty(t, empty, "Suggestions are only listed for the owner of the notes")
+
+	suggested, err = service.AnalyzeAll(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, suggested, "Pairs already suggested aren't suggested again")
+}
+
+func TestLinkSuggestions_AcceptAndDismiss(t *testing.T) {
+	service, chapter := setupTestLinkSuggestionService(t)
+	ctx := context.Background()
+	day := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	summary := "Roadmap priorities for the mobile release and onboarding experiments."
+	first := createMeetingNote(t, service.db, chapter.ID, "Roadmap", summary, day, "Ana", "Ben")
+	second := createMeetingNote(t, service.db, chapter.ID, "Roadmap again", summary, day.AddDate(0, 0, 7), "Ana", "Ben")
+	third := createMeetingNote(t, service.db, chapter.ID, "Roadmap final", summary, day.AddDate(0, 0, 14), "Ana", "Ben")
+
+	suggested, err := service.AnalyzeAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, suggested)
+	pending, err := service.ListPending(ctx, "user_1")
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+
+	link, err := service.Accept(ctx, pending[0].ID, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, pending[0].SourceNoteID, link.SourceNoteID)
+	assert.Equal(t, models.LinkOriginAI, link.Origin)
+	assert.Equal(t, models.LinkTypeRelated, link.LinkType)
+	assert.Equal(t, pending[0].Reason, link.Annotation)
+	_, err = service.Accept(ctx, pending[0].ID, "user_1")
+	assert.ErrorIs(t, err, ErrLinkSuggestionNotFound, "Suggestions are only reviewed once")
+
+	assert.ErrorIs(t, service.Dismiss(ctx, pending[1].ID, "user_2"), ErrLinkSuggestionNotFound)
+	require.NoError(t, service.Dismiss(ctx, pending[1].ID, "user_1"))
+
+	pending, err = service.ListPending(ctx, "user_1")
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	// Dismissed and accepted pairs stay out of later analyses, in either direction
+	require.NoError(t, service.db.Where("status = ?", models.LinkSuggestionPending).Delete(&models.LinkSuggestion{}).Error)
+	suggested, err = service.AnalyzeAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, suggested)
+	for _, note := range []models.Notes{first, second, third} {
+		_, err := service.AnalyzeMeetingNote(ctx, note.ID)
+		require.NoError(t, err)
+	}
+	var count int64
+	require.NoError(t, service.db.Model(&models.LinkSuggestion{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+}
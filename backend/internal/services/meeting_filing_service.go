@@ -0,0 +1,441 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrMeetingFilingNotFound is returned when a filing suggestion doesn't exist, isn't the user's or was
+// already reviewed
+var ErrMeetingFilingNotFound = errors.New("meeting filing suggestion not found")
+
+const (
+	// minMeetingFilingScore is how well a chapter needs to match a meeting before the meeting's note is filed in it
+	minMeetingFilingScore = 0.25
+	// maxMeetingFilingNotes caps the recent notes chapters are matched on
+	maxMeetingFilingNotes = 500
+	// meetingFilingNotesPerChapter is how many of its latest notes a chapter is matched on
+	meetingFilingNotesPerChapter = 20
+)
+
+// PendingMeetingFiling is a meeting note suggested to be filed in a project chapter, waiting for review
+type PendingMeetingFiling struct {
+	ID           string    `json:"id"`
+	NoteID       string    `json:"noteId"`
+	NoteName     string    `json:"noteName"`
+	ChapterID    string    `json:"chapterId"`
+	ChapterName  string    `json:"chapterName"`
+	NotebookID   string    `json:"notebookId"`
+	NotebookName string    `json:"notebookName"`
+	MatchedTerms []string  `json:"matchedTerms"`
+	Score        float64   `json:"score"`
+	Reason       string    `json:"reason"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// MeetingFilingService interface defines methods for filing generated meeting notes into the project
+// chapters they're about, and the review of suggested filings
+type MeetingFilingService interface {
+	MeetingNoteCreated(noteID string)
+	FileMeetingNote(ctx context.Context, noteID string) (*models.MeetingFilingSuggestion, error)
+	ListPending(ctx context.Context, clerkUserID string) ([]PendingMeetingFiling, error)
+	Accept(ctx context.Context, suggestionID, clerkUserID string) (*models.Notes, error)
+	Dismiss(ctx context.Context, suggestionID, clerkUserID string) error
+}
+
+// meetingFilingServiceImpl implements the MeetingFilingService interface
+type meetingFilingServiceImpl struct {
+	db    *gorm.DB
+	queue *JobQueue
+	now   func() time.Time
+}
+
+// NewMeetingFilingService creates a new MeetingFilingService instance
+func NewMeetingFilingService() MeetingFilingService {
+	return &meetingFilingServiceImpl{
+		db:    db.DB,
+		queue: GetJobQueue(),
+		now:   time.Now,
+	}
+}
+
+// filingChapter is a chapter a meeting note can be filed in, with the topics of its name and recent notes
+type filingChapter struct {
+	id, name, notebookID, notebookName string
+	nameTopics                         map[string]string
+	noteTopics                         map[string]string
+	notes                              int
+}
+
+// MeetingNoteCreated files a meeting's generated note in the background
+func (s *meetingFilingServiceImpl) MeetingNoteCreated(noteID string) {
+	err := s.queue.Enqueue(Job{
+		Name:        "meeting-filing",
+		MaxAttempts: 2,
+		Run: func(ctx context.Context) error {
+			_, err := s.FileMeetingNote(ctx, noteID)
+			return err
+		},
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to queue meeting filing")
+	}
+}
+
+// FileMeetingNote matches a meeting note's topics against the names and recent notes of the owner's other
+// chapters. The best matching chapter is suggested, along with links to the related notes of its notebook,
+// or the note is moved and linked right away when the owner files meeting notes automatically. Chapters
+// of other organizations than the note's aren't considered. It returns nil when nothing matches.
+func (s *meetingFilingServiceImpl) FileMeetingNote(ctx context.Context, noteID string) (*models.MeetingFilingSuggestion, error) {
+	links := &linkSuggestionServiceImpl{db: s.db, queue: s.queue, now: s.now}
+	profiles, err := links.meetingProfiles(ctx, "notes.id = ?", noteID)
+	if err != nil || len(profiles) == 0 {
+		return nil, err
+	}
+	meeting := profiles[0]
+	if len(meeting.topics) == 0 {
+		return nil, nil
+	}
+
+	preferences, err := findUserPreferences(ctx, s.db, meeting.owner)
+	if err != nil {
+		return nil, err
+	}
+	mode := meetingFilingMode(preferences)
+	if mode == models.MeetingFilingOff {
+		return nil, nil
+	}
+	var filed int64
+	if err := s.db.WithContext(ctx).Model(&models.MeetingFilingSuggestion{}).Where("note_id = ?", noteID).Count(&filed).Error; err != nil {
+		return nil, fmt.Errorf("failed to check meeting filing: %w", err)
+	}
+	if filed > 0 {
+		return nil, nil
+	}
+
+	chapters, notes, err := s.filingChapters(ctx, meeting)
+	if err != nil {
+		return nil, err
+	}
+	best, matched, score := bestFilingChapter(meeting, chapters)
+	if best == nil {
+		return nil, nil
+	}
+
+	suggestion := models.MeetingFilingSuggestion{
+		ClerkUserID:   meeting.owner,
+		NoteID:        noteID,
+		FromChapterID: meeting.note.ChapterID,
+		ChapterID:     best.id,
+		MatchedTerms:  strings.Join(matched, "\n"),
+		Score:         score,
+		Status:        models.MeetingFilingPending,
+	}
+	if mode == models.MeetingFilingAuto {
+		if _, err := (&orgConsistencyServiceImpl{db: s.db.WithContext(ctx)}).MoveNote(noteID, best.id); err != nil {
+			return nil, err
+		}
+		now := s.now()
+		suggestion.Status = models.MeetingFilingApplied
+		suggestion.ReviewedAt = &now
+	}
+	if err := s.db.WithContext(ctx).Create(&suggestion).Error; err != nil {
+		return nil, fmt.Errorf("failed to save meeting filing: %w", err)
+	}
+
+	// Suggest links to the notes of the project the meeting is about
+	var candidates []meetingLinkCandidate
+	var related []string
+	for _, note := range notes {
+		if best.notebookID != note.notebookID {
+			continue
+		}
+		var shared []string
+		for stem, word := range meeting.topics {
+			if _, ok := note.topics[stem]; ok {
+				shared = append(shared, word)
+			}
+		}
+		if len(shared) < minSharedMeetingTopics {
+			continue
+		}
+		sort.Strings(shared)
+		candidates = append(candidates, meetingLinkCandidate{
+			source:       meeting,
+			target:       &meetingProfile{note: note.note, owner: meeting.owner},
+			sharedTopics: shared,
+			score:        math.Round(jaccard(len(shared), len(meeting.topics), len(note.topics))*100) / 100,
+		})
+		related = append(related, note.note.ID)
+	}
+	suggested, err := links.saveCandidates(ctx, candidates)
+	if err != nil {
+		return &suggestion, err
+	}
+	if mode == models.MeetingFilingAuto && suggested > 0 {
+		var pending []models.LinkSuggestion
+		if err := s.db.WithContext(ctx).Where("source_note_id = ? AND target_note_id IN ? AND status = ?", noteID, related, models.LinkSuggestionPending).
+			Find(&pending).Error; err != nil {
+			return &suggestion, fmt.Errorf("failed to fetch link suggestions: %w", err)
+		}
+		for _, link := range pending {
+			if _, err := links.Accept(ctx, link.ID, meeting.owner); err != nil {
+				return &suggestion, err
+			}
+		}
+	}
+
+	log.Info().Str("note_id", noteID).Str("chapter_id", best.id).Str("status", suggestion.Status).Float64("score", score).
+		Int("links", suggested).Msg("Filed meeting note")
+	return &suggestion, nil
+}
+
+// ListPending returns the filings waiting for the user's review, the best matches first
+func (s *meetingFilingServiceImpl) ListPending(ctx context.Context, clerkUserID string) ([]PendingMeetingFiling, error) {
+	var rows []struct {
+		models.MeetingFilingSuggestion
+		NoteName     string
+		ChapterName  string
+		NotebookID   string
+		NotebookName string
+	}
+	if err := db.Replica(s.db).WithContext(ctx).Model(&models.MeetingFilingSuggestion{}).
+		Select("meeting_filing_suggestions.*, notes.name AS note_name, chapters.name AS chapter_name, "+
+			"chapters.notebook_id AS notebook_id, notebooks.name AS notebook_name").
+		Joins("JOIN notes ON notes.id = meeting_filing_suggestions.note_id").
+		Joins("JOIN chapters ON chapters.id = meeting_filing_suggestions.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("meeting_filing_suggestions.clerk_user_id = ? AND meeting_filing_suggestions.status = ?", clerkUserID, models.MeetingFilingPending).
+		Order("meeting_filing_suggestions.score DESC, meeting_filing_suggestions.created_at DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch meeting filings: %w", err)
+	}
+
+	pending := make([]PendingMeetingFiling, len(rows))
+	for i, row := range rows {
+		terms := splitLines(row.MatchedTerms)
+		pending[i] = PendingMeetingFiling{
+			ID:           row.ID,
+			NoteID:       row.NoteID,
+			NoteName:     row.NoteName,
+			ChapterID:    row.ChapterID,
+			ChapterName:  row.ChapterName,
+			NotebookID:   row.NotebookID,
+			NotebookName: row.NotebookName,
+			MatchedTerms: terms,
+			Score:        row.Score,
+			Reason:       meetingFilingReason(row.NotebookName, row.ChapterName, terms),
+			CreatedAt:    row.CreatedAt,
+		}
+	}
+	return pending, nil
+}
+
+// Accept moves the note of a pending filing to the suggested chapter
+func (s *meetingFilingServiceImpl) Accept(ctx context.Context, suggestionID, clerkUserID string) (*models.Notes, error) {
+	suggestion, err := s.pending(ctx, suggestionID, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filing moves plain notes, it doesn't move notes into or out of encrypted notebooks
+	var encrypted int64
+	if err := s.db.WithContext(ctx).Model(&models.Chapter{}).
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notebooks.encrypted = ? AND (chapters.id = ? OR chapters.id IN (?))", true, suggestion.ChapterID,
+			s.db.Model(&models.Notes{}).Select("chapter_id").Where("id = ?", suggestion.NoteID)).
+		Count(&encrypted).Error; err != nil {
+		return nil, fmt.Errorf("failed to check notebook encryption: %w", err)
+	}
+	if encrypted > 0 {
+		return nil, ErrNotebookEncrypted
+	}
+
+	note, err := (&orgConsistencyServiceImpl{db: s.db.WithContext(ctx)}).MoveNote(suggestion.NoteID, suggestion.ChapterID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(suggestion).Updates(map[string]interface{}{
+		"status":      models.MeetingFilingAccepted,
+		"reviewed_at": s.now(),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to save meeting filing: %w", err)
+	}
+	return note, nil
+}
+
+// Dismiss turns a pending filing down, leaving the note where it was generated
+func (s *meetingFilingServiceImpl) Dismiss(ctx context.Context, suggestionID, clerkUserID string) error {
+	suggestion, err := s.pending(ctx, suggestionID, clerkUserID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Model(suggestion).Updates(map[string]interface{}{
+		"status":      models.MeetingFilingDismissed,
+		"reviewed_at": s.now(),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to save meeting filing: %w", err)
+	}
+	return nil
+}
+
+// pending returns a filing waiting for the user's review
+func (s *meetingFilingServiceImpl) pending(ctx context.Context, suggestionID, clerkUserID string) (*models.MeetingFilingSuggestion, error) {
+	var suggestion models.MeetingFilingSuggestion
+	err := s.db.WithContext(ctx).Where("id = ? AND clerk_user_id = ? AND status = ?", suggestionID, clerkUserID, models.MeetingFilingPending).
+		First(&suggestion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMeetingFilingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch meeting filing: %w", err)
+	}
+	return &suggestion, nil
+}
+
+// filingNote is a recent note of a chapter a meeting can be filed in, with its topics
+type filingNote struct {
+	note       models.Notes
+	notebookID string
+	topics     map[string]string
+}
+
+// filingChapters returns the chapters of the meeting owner's plain notebooks in the note's organization,
+// other than the note's own, with the topics of their names and latest notes. The notes are returned too.
+func (s *meetingFilingServiceImpl) filingChapters(ctx context.Context, meeting *meetingProfile) ([]*filingChapter, []filingNote, error) {
+	var rows []struct {
+		ID           string
+		Name         string
+		NotebookID   string
+		NotebookName string
+	}
+	query := s.db.WithContext(ctx).Model(&models.Chapter{}).
+		Select("chapters.id, chapters.name, chapters.notebook_id, notebooks.name AS notebook_name").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notebooks.clerk_user_id = ? AND notebooks.encrypted = ? AND chapters.id <> ?", meeting.owner, false, meeting.note.ChapterID)
+	if meeting.note.OrganizationID != nil && *meeting.note.OrganizationID != "" {
+		query = query.Where("notebooks.organization_id = ?", *meeting.note.OrganizationID)
+	} else {
+		query = query.Where("notebooks.organization_id IS NULL OR notebooks.organization_id = ''")
+	}
+	if err := query.Order("chapters.created_at").Scan(&rows).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch chapters: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil, nil
+	}
+
+	chapters := make([]*filingChapter, 0, len(rows))
+	byID := make(map[string]*filingChapter, len(rows))
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		chapter := &filingChapter{
+			id:           row.ID,
+			name:         row.Name,
+			notebookID:   row.NotebookID,
+			notebookName: row.NotebookName,
+			nameTopics:   map[string]string{},
+			noteTopics:   map[string]string{},
+		}
+		addMeetingTopics(chapter.nameTopics, row.NotebookName+"\n"+row.Name, "")
+		chapters = append(chapters, chapter)
+		byID[row.ID] = chapter
+		ids = append(ids, row.ID)
+	}
+
+	var recent []models.Notes
+	if err := s.db.WithContext(ctx).Select("id", "name", "chapter_id", "organization_id", "content", "summary", "ai_summary", "language", "created_at", "updated_at").
+		Where("chapter_id IN ? AND id <> ? AND locked = ? AND updated_at >= ?", ids, meeting.note.ID, false, s.now().Add(-meetingLinkWindow)).
+		Order("updated_at DESC").Limit(maxMeetingFilingNotes).Find(&recent).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch notes: %w", err)
+	}
+
+	notes := make([]filingNote, 0, len(recent))
+	for _, note := range recent {
+		chapter := byID[note.ChapterID]
+		if chapter.notes >= meetingFilingNotesPerChapter {
+			continue
+		}
+		chapter.notes++
+
+		markdown, err := utils.TipTapToMarkdown(note.Content)
+		if err != nil {
+			markdown = note.Content
+		}
+		topics := map[string]string{}
+		addMeetingTopics(topics, note.Name+"\n"+note.Summary+"\n"+note.AISummary+"\n"+markdown, note.Language)
+		for stem, word := range topics {
+			if _, ok := chapter.noteTopics[stem]; !ok {
+				chapter.noteTopics[stem] = word
+			}
+		}
+		notes = append(notes, filingNote{note: note, notebookID: chapter.notebookID, topics: topics})
+	}
+	return chapters, notes, nil
+}
+
+// bestFilingChapter scores how well each chapter matches a meeting, half on how much of its and its
+// notebook's name the meeting mentions and half on how many of the meeting's topics its notes share.
+// Chapters need a name in common or a few shared topics. It returns the best chapter with the meeting's
+// words it matched on, or nil when none matches well enough.
+func bestFilingChapter(meeting *meetingProfile, chapters []*filingChapter) (*filingChapter, []string, float64) {
+	var (
+		best      *filingChapter
+		bestTerms []string
+		bestScore float64
+	)
+	for _, chapter := range chapters {
+		var nameShared, noteShared int
+		terms := map[string]bool{}
+		for stem, word := range meeting.topics {
+			if _, ok := chapter.nameTopics[stem]; ok {
+				nameShared++
+				terms[word] = true
+			}
+			if _, ok := chapter.noteTopics[stem]; ok {
+				noteShared++
+				terms[word] = true
+			}
+		}
+		if nameShared == 0 && noteShared < minSharedMeetingTopics {
+			continue
+		}
+
+		score := float64(noteShared) / float64(len(meeting.topics)) / 2
+		if len(chapter.nameTopics) > 0 {
+			score += float64(nameShared) / float64(len(chapter.nameTopics)) / 2
+		}
+		score = math.Round(score*100) / 100
+		if score < minMeetingFilingScore || score <= bestScore {
+			continue
+		}
+
+		best, bestScore = chapter, score
+		bestTerms = make([]string, 0, len(terms))
+		for term := range terms {
+			bestTerms = append(bestTerms, term)
+		}
+		sort.Strings(bestTerms)
+	}
+	return best, bestTerms, bestScore
+}
+
+// meetingFilingReason says why a meeting note was suggested to be filed in a chapter
+func meetingFilingReason(notebookName, chapterName string, terms []string) string {
+	reason := fmt.Sprintf("Looks like part of %s › %s.", notebookName, chapterName)
+	if len(terms) > 0 {
+		reason += " Matched on: " + strings.Join(terms, ", ") + "."
+	}
+	return reason
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestMeetingFilingService creates a meeting filing service on an in-memory database, with the
+// chapter meeting notes are generated in
+func setupTestMeetingFilingService(t *testing.T) (*meetingFilingServiceImpl, *gorm.DB, models.Chapter) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.MeetingRecording{},
+		&models.NoteLink{}, &models.LinkSuggestion{}, &models.MeetingFilingSuggestion{}, &models.UserPreferences{},
+		&models.NoteTag{}, &models.NoteProperty{}, &models.NoteEmbed{}, &models.TaskBoard{}, &models.Task{},
+		&models.NotebookLegalHold{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	notebook := models.Notebook{Name: "Meetings", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Recorded Meetings", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	return &meetingFilingServiceImpl{db: db, queue: NewJobQueue(1, 10), now: func() time.Time { return now }}, db, chapter
+}
+
+// createFilingChapter creates a notebook with a chapter, and notes in it
+func createFilingChapter(t *testing.T, db *gorm.DB, notebookName, chapterName string, encrypted bool, notes ...string) models.Chapter {
+	notebook := models.Notebook{Name: notebookName, ClerkUserID: "user_1", Encrypted: encrypted}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: chapterName, NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+
+	updated := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	for i := 0; i+1 < len(notes); i += 2 {
+		note := models.Notes{Name: notes[i], Content: notes[i+1], ChapterID: chapter.ID}
+		require.NoError(t, db.Create(&note).Error)
+		require.NoError(t, db.Model(&note).UpdateColumn("updated_at", updated).Error)
+	}
+	return chapter
+}
+
+func TestMeetingFiling_SuggestsMatchingChapter(t *testing.T) {
+	service, db, meetings := setupTestMeetingFilingService(t)
+	ctx := context.Background()
+
+	project := createFilingChapter(t, db, "Apollo Launch", "Planning", false,
+		"Launch checklist", "Rocket telemetry dashboard and launch checklist for the apollo release.",
+		"Vendor contracts", "Telemetry vendor contracts are due before the launch window.")
+	createFilingChapter(t, db, "Recipes", "Desserts", false,
+		"Chocolate cake", "Flour, sugar, cocoa and eggs.")
+	createFilingChapter(t, db, "Apollo Secrets", "Launch", true)
+
+	note := createMeetingNote(t, db, meetings.ID, "Apollo launch sync",
+		"Reviewed the telemetry dashboard, the launch checklist and vendor contracts.", service.now().Add(-time.Hour))
+
+	suggestion, err := service.FileMeetingNote(ctx, note.ID)
+	require.NoError(t, err)
+	require.NotNil(t, suggestion)
+	assert.Equal(t, project.ID, suggestion.ChapterID)
+	assert.Equal(t, meetings.ID, suggestion.FromChapterID)
+	assert.Equal(t, models.MeetingFilingPending, suggestion.Status)
+	assert.Contains(t, suggestion.MatchedTerms, "apollo")
+	assert.GreaterOrEqual(t, suggestion.Score, minMeetingFilingScore)
+
+	// The note stays where it was generated until the suggestion is accepted
+	var stored models.Notes
+	require.NoError(t, db.First(&stored, "id = ?", note.ID).Error)
+	assert.Equal(t, meetings.ID, stored.ChapterID)
+
+	// Related notes of the project are suggested as links
+	var links []models.LinkSuggestion
+	require.NoError(t, db.Where("source_note_id = ?", note.ID).Find(&links).Error)
+	assert.NotEmpty(t, links)
+
+	pending, err := service.ListPending(ctx, "user_1")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "Apollo Launch", pending[0].NotebookName)
+	assert.Equal(t, "Planning", pending[0].ChapterName)
+	assert.Contains(t, pending[0].Reason, "Apollo Launch › Planning")
+
+	// A note is only filed once
+	again, err := service.FileMeetingNote(ctx, note.ID)
+	require.NoError(t, err)
+	assert.Nil(t, again)
+
+	moved, err := service.Accept(ctx, suggestion.ID, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, project.ID, moved.ChapterID)
+
+	_, err = service.Accept(ctx, suggestion.ID, "user_1")
+	assert.ErrorIs(t, err, ErrMeetingFilingNotFound)
+	pending, err = service.ListPending(ctx, "user_1")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestMeetingFiling_Modes(t *testing.T) {
+	service, db, meetings := setupTestMeetingFilingService(t)
+	ctx := context.Background()
+	project := createFilingChapter(t, db, "Apollo Launch", "Planning", false,
+		"Launch checklist", "Rocket telemetry dashboard and launch checklist for the apollo release.")
+	preferences := &userPreferencesServiceImpl{db: db}
+
+	t.Run("auto files the note and links related notes", func(t *testing.T) {
+		_, err := preferences.SetMeetingFiling(ctx, "user_1", "auto")
+		require.NoError(t, err)
+		note := createMeetingNote(t, db, meetings.ID, "Apollo launch review",
+			"Telemetry dashboard and launch checklist are ready.", service.now())
+
+		suggestion, err := service.FileMeetingNote(ctx, note.ID)
+		require.NoError(t, err)
+		require.NotNil(t, suggestion)
+		assert.Equal(t, models.MeetingFilingApplied, suggestion.Status)
+
+		var stored models.Notes
+		require.NoError(t, db.First(&stored, "id = ?", note.ID).Error)
+		assert.Equal(t, project.ID, stored.ChapterID)
+		var links int64
+		require.NoError(t, db.Model(&models.NoteLink{}).Where("source_note_id = ?", note.ID).Count(&links).Error)
+		assert.Equal(t, int64(1), links)
+	})
+
+	t.Run("off leaves the note", func(t *testing.T) {
+		_, err := preferences.SetMeetingFiling(ctx, "user_1", "off")
+		require.NoError(t, err)
+		note := createMeetingNote(t, db, meetings.ID, "Apollo launch retro",
+			"Telemetry dashboard and launch checklist went well.", service.now())
+
+		suggestion, err := service.FileMeetingNote(ctx, note.ID)
+		require.NoError(t, err)
+		assert.Nil(t, suggestion)
+	})
+
+	t.Run("unrelated meetings aren't filed", func(t *testing.T) {
+		_, err := preferences.SetMeetingFiling(ctx, "user_1", "suggest")
+		require.NoError(t, err)
+		note := createMeetingNote(t, db, meetings.ID, "Hiring interview",
+			"Discussed the candidate's background and salary expectations.", service.now())
+
+		suggestion, err := service.FileMeetingNote(ctx, note.ID)
+		require.NoError(t, err)
+		assert.Nil(t, suggestion)
+	})
+
+	_, err := preferences.SetMeetingFiling(ctx, "user_1", "sometimes")
+	assert.ErrorIs(t, err, ErrInvalidMeetingFiling)
+}
+
+func TestMeetingFiling_Dismiss(t *testing.T) {
+	service, db, meetings := setupTestMeetingFilingService(t)
+	ctx := context.Background()
+	createFilingChapter(t, db, "Apollo Launch", "Planning", false)
+	note := createMeetingNote(t, db, meetings.ID, "Apollo launch kickoff",
+		"Kicked off the launch planning.", service.now())
+
+	suggestion, err := service.FileMeetingNote(ctx, note.ID)
+	require.NoError(t, err)
+	require.NotNil(t, suggestion)
+
+	assert.ErrorIs(t, service.Dismiss(ctx, suggestion.ID, "user_2"), ErrMeetingFilingNotFound)
+	require.NoError(t, service.Dismiss(ctx, suggestion.ID, "user_1"))
+
+	var stored models.MeetingFilingSuggestion
+	require.NoError(t, db.First(&stored, "id = ?", suggestion.ID).Error)
+	assert.Equal(t, models.MeetingFilingDismissed, stored.Status)
+	assert.NotNil(t, stored.ReviewedAt)
+}
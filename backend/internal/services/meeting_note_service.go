@@ -12,35 +12,38 @@ import (
 )
 
 type MeetingNoteService struct {
-	aiService *AIService
+	aiService      *AIService
+	redactor       *transcriptRedactor
+	transcriptions map[string]TranscriptionProvider
 }
 
 func NewMeetingNoteService() *MeetingNoteService {
 	return &MeetingNoteService{
-		aiService: NewAIService(),
+		aiService:      NewAIService(),
+		redactor:       newTranscriptRedactor(db.DB),
+		transcriptions: newTranscriptionProviders(recallai.NewClient()),
 	}
 }
 
-// ProcessMeetingTranscript downloads transcript, analyzes it with AI, and creates a note
-func (s *MeetingNoteService) ProcessMeetingTranscript(ctx context.Context, recording *models.MeetingRecording) error {
-	if recording.TranscriptDownloadURL == "" {
-		return fmt.Errorf("transcript download URL not available")
-	}
+// TranscribeMeeting returns a recording's transcript from the provider it was recorded for
+func (s *MeetingNoteService) TranscribeMeeting(ctx context.Context, recording *models.MeetingRecording) ([]recallai.TranscriptEntry, error) {
+	return transcribeRecording(ctx, s.transcriptions, recording)
+}
 
+// ProcessMeetingTranscript transcribes the meeting, analyzes it with AI, and creates a note
+func (s *MeetingNoteService) ProcessMeetingTranscript(ctx context.Context, recording *models.MeetingRecording) error {
 	log.Info().
 		Str("meeting_id", recording.ID).
-		Str("transcript_url", recording.TranscriptDownloadURL).
+		Str("transcription_provider", recording.TranscriptionProvider).
 		Msg("Processing meeting transcript")
 
-	// Download transcript from Recall.ai
-	recallClient := recallai.NewClient()
-	transcript, err := recallClient.DownloadTranscript(recording.TranscriptDownloadURL)
+	transcript, err := s.TranscribeMeeting(ctx, recording)
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("meeting_id", recording.ID).
-			Msg("Failed to download transcript")
-		return fmt.Errorf("failed to download transcript: %w", err)
+			Msg("Failed to transcribe meeting")
+		return fmt.Errorf("failed to transcribe meeting: %w", err)
 	}
 
 	if len(transcript) == 0 {
@@ -50,8 +53,8 @@ func (s *MeetingNoteService) ProcessMeetingTranscript(ctx context.Context, recor
 		return fmt.Errorf("transcript is empty")
 	}
 
-	// Convert transcript to plain text for AI analysis
-	plainTextTranscript := s.aiService.TranscriptToPlainText(transcript)
+	// Convert transcript to plain text for AI analysis, redacted by the owner's organization policies
+	plainTextTranscript, redactErr := s.redactor.TranscriptForAI(ctx, recording, transcript)
 
 	// Get user's existing notebooks for context
 	var notebooks []models.Notebook
@@ -69,9 +72,15 @@ func (s *MeetingNoteService) ProcessMeetingTranscript(ctx context.Context, recor
 		existingNotebookNames[i] = nb.Name
 	}
 
-	// Analyze transcript with AI
-	analysis, err := s.aiService.AnalyzeTranscript(ctx, plainTextTranscript, existingNotebookNames)
-	if err != nil {
+	// Analyze transcript with AI, unless it couldn't be redacted
+	var analysis *TranscriptAnalysis
+	if redactErr != nil {
+		log.Error().
+			Err(redactErr).
+			Str("meeting_id", recording.ID).
+			Msg("Failed to redact transcript, using fallback instead of sending it to AI")
+		analysis = s.aiService.CreateFallbackAnalysis(recording.MeetingURL)
+	} else if analysis, err = s.aiService.AnalyzeTranscript(ctx, plainTextTranscript, existingNotebookNames); err != nil {
 		log.Warn().
 			Err(err).
 			Str("meeting_id", recording.ID).
@@ -113,6 +122,10 @@ func (s *MeetingNoteService) ProcessMeetingTranscript(ctx context.Context, recor
 		Str("note_id", noteID).
 		Msg("Successfully created note from meeting transcript")
 
+	addMeetingNoteToNotes(ctx, db.DB, recording, noteID)
+	NewLinkSuggestionService().MeetingNoteCreated(noteID)
+	NewMeetingFilingService().MeetingNoteCreated(noteID)
+
 	return nil
 }
 
@@ -0,0 +1,452 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMeetingSeriesNotFound is returned when a series doesn't exist or isn't the user's
+	ErrMeetingSeriesNotFound = errors.New("meeting series not found")
+	// ErrNotEnoughSeriesMeetings is returned when asking what changed in a series with fewer than two
+	// recorded meetings
+	ErrNotEnoughSeriesMeetings = errors.New("the series needs at least two recorded meetings to compare")
+)
+
+const (
+	// maxSeriesChangeMeetings is how many of the latest recorded meetings are compared
+	maxSeriesChangeMeetings = 6
+	maxSeriesMeetingExcerpt = 4000
+	meetingSeriesDateFormat = "January 2, 2006"
+)
+
+// MeetingSeriesSummary is a recurring meeting with counts of its occurrences
+type MeetingSeriesSummary struct {
+	ID               string     `json:"id"`
+	Title            string     `json:"title"`
+	Occurrences      int        `json:"occurrences"`
+	RecordedMeetings int        `json:"recordedMeetings"` // Occurrences with a generated note
+	LastStartTime    *time.Time `json:"lastStartTime,omitempty"`
+	NextStartTime    *time.Time `json:"nextStartTime,omitempty"`
+	RollingNoteID    *string    `json:"rollingNoteId,omitempty"`
+}
+
+// MeetingSeriesOccurrence is one calendar event of a series, with the note generated from its recording
+type MeetingSeriesOccurrence struct {
+	EventID      string    `json:"eventId"`
+	Title        string    `json:"title"`
+	StartTime    time.Time `json:"startTime"`
+	EndTime      time.Time `json:"endTime"`
+	BotScheduled bool      `json:"botScheduled"`
+	NoteID       *string   `json:"noteId,omitempty"`
+	NoteName     string    `json:"noteName,omitempty"`
+	Summary      string    `json:"summary,omitempty"`
+}
+
+// MeetingSeriesTimeline is a series with its occurrences, oldest first
+type MeetingSeriesTimeline struct {
+	Series      MeetingSeriesSummary      `json:"series"`
+	Occurrences []MeetingSeriesOccurrence `json:"occurrences"`
+}
+
+// MeetingSeriesChanges is an AI summary of what changed in the latest meeting of a series since the
+// previous ones
+type MeetingSeriesChanges struct {
+	SeriesID         string    `json:"seriesId"`
+	Summary          string    `json:"summary"`
+	Since            time.Time `json:"since"`  // Start of the previous recorded meeting
+	Latest           time.Time `json:"latest"` // Start of the latest recorded meeting
+	MeetingsCompared int       `json:"meetingsCompared"`
+}
+
+// MeetingSeriesService interface defines methods for recurring meetings grouped by their calendar UID
+type MeetingSeriesService interface {
+	ListSeries(ctx context.Context, clerkUserID string) ([]MeetingSeriesSummary, error)
+	GetTimeline(ctx context.Context, clerkUserID, seriesID string) (*MeetingSeriesTimeline, error)
+	SummarizeChanges(ctx context.Context, clerkUserID, seriesID string) (*MeetingSeriesChanges, error)
+}
+
+// meetingSeriesServiceImpl implements the MeetingSeriesService interface
+type meetingSeriesServiceImpl struct {
+	db        *gorm.DB
+	summarize func(ctx context.Context, request MeetingSeriesChangesRequest) (string, error)
+	now       func() time.Time
+}
+
+// NewMeetingSeriesService creates a new MeetingSeriesService instance
+func NewMeetingSeriesService() MeetingSeriesService {
+	return &meetingSeriesServiceImpl{
+		db: db.DB,
+		summarize: func(ctx context.Context, request MeetingSeriesChangesRequest) (string, error) {
+			return NewAIService().SummarizeMeetingSeriesChanges(ctx, request)
+		},
+		now: time.Now,
+	}
+}
+
+// seriesEvent is a calendar event of a series
+type seriesEvent struct {
+	ID           string
+	ICalUID      string
+	Title        string
+	StartTime    time.Time
+	EndTime      time.Time
+	BotID        *string
+	BotScheduled bool
+}
+
+// seriesMeetingNote is the note generated from a recorded occurrence
+type seriesMeetingNote struct {
+	BotID     string
+	NoteID    string
+	NoteName  string
+	AISummary string
+}
+
+// ListSeries returns the user's recurring meetings, the ones with an upcoming occurrence first. Every
+// calendar UID shared by more than one event is a series.
+func (s *meetingSeriesServiceImpl) ListSeries(ctx context.Context, clerkUserID string) ([]MeetingSeriesSummary, error) {
+	events, err := s.seriesEvents(ctx, clerkUserID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := map[string][]seriesEvent{}
+	for _, event := range events {
+		grouped[event.ICalUID] = append(grouped[event.ICalUID], event)
+	}
+	var allEvents []seriesEvent
+	for uid, occurrences := range grouped {
+		if len(occurrences) < 2 {
+			delete(grouped, uid)
+			continue
+		}
+		allEvents = append(allEvents, occurrences...)
+	}
+
+	notes, err := s.meetingNotesByBot(ctx, clerkUserID, allEvents)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]MeetingSeriesSummary, 0, len(grouped))
+	for uid, occurrences := range grouped {
+		series, err := findOrCreateMeetingSeries(ctx, s.db, clerkUserID, uid, occurrences[len(occurrences)-1].Title)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s.summarizeSeries(series, occurrences, notes))
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if (a.NextStartTime != nil) != (b.NextStartTime != nil) {
+			return a.NextStartTime != nil
+		}
+		if a.NextStartTime != nil && !a.NextStartTime.Equal(*b.NextStartTime) {
+			return a.NextStartTime.Before(*b.NextStartTime)
+		}
+		if a.LastStartTime != nil && b.LastStartTime != nil && !a.LastStartTime.Equal(*b.LastStartTime) {
+			return a.LastStartTime.After(*b.LastStartTime)
+		}
+		return a.Title < b.Title
+	})
+	return summaries, nil
+}
+
+// GetTimeline returns a series with all its occurrences and the notes of the recorded ones
+func (s *meetingSeriesServiceImpl) GetTimeline(ctx context.Context, clerkUserID, seriesID string) (*MeetingSeriesTimeline, error) {
+	var series models.MeetingSeries
+	if err := s.db.WithContext(ctx).Where("id = ? AND clerk_user_id = ?", seriesID, clerkUserID).First(&series).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMeetingSeriesNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch meeting series: %w", err)
+	}
+
+	events, err := s.seriesEvents(ctx, clerkUserID, series.ICalUID)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.meetingNotesByBot(ctx, clerkUserID, events)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := &MeetingSeriesTimeline{
+		Series:      s.summarizeSeries(&series, events, notes),
+		Occurrences: make([]MeetingSeriesOccurrence, len(events)),
+	}
+	for i, event := range events {
+		occurrence := MeetingSeriesOccurrence{
+			EventID:      event.ID,
+			Title:        event.Title,
+			StartTime:    event.StartTime,
+			EndTime:      event.EndTime,
+			BotScheduled: event.BotScheduled,
+		}
+		if event.BotID != nil {
+			if note, ok := notes[*event.BotID]; ok {
+				occurrence.NoteID = &note.NoteID
+				occurrence.NoteName = note.NoteName
+				occurrence.Summary = note.AISummary
+			}
+		}
+		timeline.Occurrences[i] = occurrence
+	}
+	return timeline, nil
+}
+
+// SummarizeChanges asks the AI what changed in the latest recorded meeting of a series since the ones
+// before it
+func (s *meetingSeriesServiceImpl) SummarizeChanges(ctx context.Context, clerkUserID, seriesID string) (*MeetingSeriesChanges, error) {
+	timeline, err := s.GetTimeline(ctx, clerkUserID, seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	var recorded []MeetingSeriesOccurrence
+	for _, occurrence := range timeline.Occurrences {
+		if occurrence.NoteID != nil {
+			recorded = append(recorded, occurrence)
+		}
+	}
+	if len(recorded) < 2 {
+		return nil, ErrNotEnoughSeriesMeetings
+	}
+	if len(recorded) > maxSeriesChangeMeetings {
+		recorded = recorded[len(recorded)-maxSeriesChangeMeetings:]
+	}
+
+	noteIDs := make([]string, len(recorded))
+	for i, occurrence := range recorded {
+		noteIDs[i] = *occurrence.NoteID
+	}
+	var notes []models.Notes
+	if err := s.db.WithContext(ctx).Select("id", "content", "locked").Where("id IN ?", noteIDs).Find(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch meeting notes: %w", err)
+	}
+	contents := make(map[string]string, len(notes))
+	for _, note := range notes {
+		if !note.Locked {
+			contents[note.ID] = note.Content
+		}
+	}
+
+	location := UserLocation(ctx, s.db, clerkUserID)
+	meetings := make([]SeriesMeeting, len(recorded))
+	for i, occurrence := range recorded {
+		content := contents[*occurrence.NoteID]
+		if strings.HasPrefix(strings.TrimSpace(content), "{") {
+			if markdown, err := utils.TipTapToMarkdown(content); err == nil {
+				content = markdown
+			}
+		}
+		if occurrence.Summary != "" {
+			content = "Summary: " + occurrence.Summary + "\n\n" + content
+		}
+		if len(content) > maxSeriesMeetingExcerpt {
+			content = content[:maxSeriesMeetingExcerpt] + "\n\n[Content truncated]"
+		}
+		meetings[i] = SeriesMeeting{
+			Date:    occurrence.StartTime.In(location).Format(meetingSeriesDateFormat),
+			Name:    occurrence.NoteName,
+			Content: content,
+		}
+	}
+
+	summary, err := s.summarize(ctx, MeetingSeriesChangesRequest{
+		Title:    timeline.Series.Title,
+		Meetings: meetings,
+		UserID:   clerkUserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MeetingSeriesChanges{
+		SeriesID:         seriesID,
+		Summary:          summary,
+		Since:            recorded[len(recorded)-2].StartTime,
+		Latest:           recorded[len(recorded)-1].StartTime,
+		MeetingsCompared: len(recorded),
+	}, nil
+}
+
+// summarizeSeries counts a series' occurrences, which are oldest first
+func (s *meetingSeriesServiceImpl) summarizeSeries(series *models.MeetingSeries, events []seriesEvent, notes map[string]seriesMeetingNote) MeetingSeriesSummary {
+	summary := MeetingSeriesSummary{
+		ID:            series.ID,
+		Title:         series.Title,
+		Occurrences:   len(events),
+		RollingNoteID: series.RollingNoteID,
+	}
+	now := s.now()
+	for _, event := range events {
+		if event.BotID != nil {
+			if _, ok := notes[*event.BotID]; ok {
+				summary.RecordedMeetings++
+			}
+		}
+		startTime := event.StartTime
+		if startTime.After(now) {
+			if summary.NextStartTime == nil {
+				summary.NextStartTime = &startTime
+			}
+		} else {
+			summary.LastStartTime = &startTime
+		}
+	}
+	return summary
+}
+
+// seriesEvents returns the user's calendar events that have a calendar UID, only those of one UID when
+// given, oldest first
+func (s *meetingSeriesServiceImpl) seriesEvents(ctx context.Context, clerkUserID, icalUID string) ([]seriesEvent, error) {
+	query := s.db.WithContext(ctx).Model(&models.CalendarEvent{}).
+		Select("calendar_events.id, calendar_events.i_cal_uid, calendar_events.title, calendar_events.start_time, "+
+			"calendar_events.end_time, calendar_events.bot_id, calendar_events.bot_scheduled").
+		Joins("JOIN calendars ON calendars.id = calendar_events.calendar_id").
+		Where("calendars.clerk_user_id = ? AND calendar_events.is_deleted = ? AND calendar_events.i_cal_uid <> ''", clerkUserID, false)
+	if icalUID != "" {
+		query = query.Where("calendar_events.i_cal_uid = ?", icalUID)
+	}
+
+	var events []seriesEvent
+	if err := query.Order("calendar_events.start_time ASC").Scan(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar events: %w", err)
+	}
+	return events, nil
+}
+
+// meetingNotesByBot returns the notes generated from the user's recordings of the events, by bot ID
+func (s *meetingSeriesServiceImpl) meetingNotesByBot(ctx context.Context, clerkUserID string, events []seriesEvent) (map[string]seriesMeetingNote, error) {
+	var botIDs []string
+	for _, event := range events {
+		if event.BotID != nil && *event.BotID != "" {
+			botIDs = append(botIDs, *event.BotID)
+		}
+	}
+	notes := map[string]seriesMeetingNote{}
+	if len(botIDs) == 0 {
+		return notes, nil
+	}
+
+	var rows []seriesMeetingNote
+	if err := s.db.WithContext(ctx).Model(&models.Notes{}).
+		Select("meeting_recordings.bot_id, notes.id AS note_id, notes.name AS note_name, notes.ai_summary").
+		Joins("JOIN meeting_recordings ON meeting_recordings.id = notes.meeting_recording_id").
+		Where("meeting_recordings.clerk_user_id = ? AND meeting_recordings.bot_id IN ?", clerkUserID, botIDs).
+		Order("notes.created_at ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch meeting notes: %w", err)
+	}
+	for _, row := range rows {
+		if _, exists := notes[row.BotID]; !exists {
+			notes[row.BotID] = row
+		}
+	}
+	return notes, nil
+}
+
+// findOrCreateMeetingSeries returns the user's series for a calendar UID, creating it with the title
+func findOrCreateMeetingSeries(ctx context.Context, tx *gorm.DB, clerkUserID, icalUID, title string) (*models.MeetingSeries, error) {
+	series := models.MeetingSeries{ClerkUserID: clerkUserID, ICalUID: icalUID}
+	if err := tx.WithContext(ctx).
+		Where(models.MeetingSeries{ClerkUserID: clerkUserID, ICalUID: icalUID}).
+		Attrs(models.MeetingSeries{Title: title}).
+		FirstOrCreate(&series).Error; err != nil {
+		return nil, fmt.Errorf("failed to find or create meeting series: %w", err)
+	}
+	return &series, nil
+}
+
+// addMeetingNoteToSeries appends a dated section about a recurring meeting's generated note to the
+// series' rolling note, creating the rolling note next to the generated one on the first recording.
+// Meetings that don't recur are skipped.
+func addMeetingNoteToSeries(ctx context.Context, tx *gorm.DB, recording *models.MeetingRecording, noteID string) error {
+	if recording.BotID == "" {
+		return nil
+	}
+
+	var event models.CalendarEvent
+	err := tx.WithContext(ctx).Where("bot_id = ? AND i_cal_uid <> ''", recording.BotID).First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch calendar event: %w", err)
+	}
+
+	var occurrences int64
+	if err := tx.WithContext(ctx).Model(&models.CalendarEvent{}).
+		Joins("JOIN calendars ON calendars.id = calendar_events.calendar_id").
+		Where("calendars.clerk_user_id = ? AND calendar_events.i_cal_uid = ?", recording.ClerkUserID, event.ICalUID).
+		Count(&occurrences).Error; err != nil {
+		return fmt.Errorf("failed to count series occurrences: %w", err)
+	}
+	if occurrences < 2 {
+		return nil
+	}
+
+	series, err := findOrCreateMeetingSeries(ctx, tx, recording.ClerkUserID, event.ICalUID, event.Title)
+	if err != nil {
+		return err
+	}
+
+	var note models.Notes
+	if err := tx.WithContext(ctx).Select("id", "name", "ai_summary", "chapter_id").Where("id = ?", noteID).First(&note).Error; err != nil {
+		return fmt.Errorf("failed to fetch meeting note: %w", err)
+	}
+
+	rollingNoteExists := false
+	if series.RollingNoteID != nil {
+		var count int64
+		if err := tx.WithContext(ctx).Model(&models.Notes{}).Where("id = ?", *series.RollingNoteID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to fetch rolling note: %w", err)
+		}
+		rollingNoteExists = count > 0
+	}
+	if !rollingNoteExists {
+		title := series.Title
+		if title == "" {
+			title = "Recurring meeting"
+		}
+		rollingNote := models.Notes{Name: title + ": rolling notes", ChapterID: note.ChapterID}
+		if err := tx.WithContext(ctx).Create(&rollingNote).Error; err != nil {
+			return fmt.Errorf("failed to create rolling note: %w", err)
+		}
+		if err := tx.WithContext(ctx).Model(series).Update("rolling_note_id", rollingNote.ID).Error; err != nil {
+			return fmt.Errorf("failed to save rolling note: %w", err)
+		}
+		series.RollingNoteID = &rollingNote.ID
+	}
+
+	date := event.StartTime.In(UserLocation(ctx, tx, recording.ClerkUserID)).Format(meetingSeriesDateFormat)
+	section := fmt.Sprintf("## %s\n\nNotes from the meeting: **%s**", date, note.Name)
+	if summary := strings.TrimSpace(note.AISummary); summary != "" {
+		section += "\n\n" + summary
+	}
+	added, err := appendMeetingSection(ctx, tx, *series.RollingNoteID, &note, recording.ClerkUserID, section, "Meeting in the series")
+	if err != nil || !added {
+		return err
+	}
+
+	log.Info().
+		Str("series_id", series.ID).
+		Str("rolling_note_id", *series.RollingNoteID).
+		Str("note_id", note.ID).
+		Msg("Added meeting note to series rolling note")
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestMeetingSeriesService creates a meeting series service with a weekly sync of three
+// occurrences, the first two recorded, and a one-off meeting
+func setupTestMeetingSeriesService(t *testing.T) *meetingSeriesServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(
+		&models.Notebook{},
+		&models.Chapter{},
+		&models.Notes{},
+		&models.NoteLink{},
+		&models.YjsDocument{},
+		&models.MeetingRecording{},
+		&models.Calendar{},
+		&models.CalendarEvent{},
+		&models.MeetingSeries{},
+		&models.UserPreferences{},
+	)
+	require.NoError(t, err, "Failed to migrate test database")
+
+	now := time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)
+	weekAgo, twoWeeksAgo, nextWeek := now.AddDate(0, 0, -7), now.AddDate(0, 0, -14), now.AddDate(0, 0, 7)
+
+	notebook := models.Notebook{Name: "Meetings", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Syncs", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+
+	calendars := []models.Calendar{
+		{ID: "cal_1", ClerkUserID: "user_1", RecallCalendarID: "recall_cal_1", Platform: "google_calendar"},
+		{ID: "cal_2", ClerkUserID: "user_2", RecallCalendarID: "recall_cal_2", Platform: "google_calendar"},
+	}
+	require.NoError(t, db.Create(&calendars).Error)
+
+	events := []models.CalendarEvent{
+		{ID: "sync_1", CalendarID: "cal_1", RecallEventID: "re_1", ICalUID: "weekly-sync", Title: "Weekly sync", StartTime: twoWeeksAgo, EndTime: twoWeeksAgo.Add(time.Hour), BotID: strPtr("bot_1")},
+		{ID: "sync_2", CalendarID: "cal_1", RecallEventID: "re_2", ICalUID: "weekly-sync", Title: "Weekly sync", StartTime: weekAgo, EndTime: weekAgo.Add(time.Hour), BotID: strPtr("bot_2")},
+		{ID: "sync_3", CalendarID: "cal_1", RecallEventID: "re_3", ICalUID: "weekly-sync", Title: "Weekly sync", StartTime: nextWeek, EndTime: nextWeek.Add(time.Hour)},
+		{ID: "one_off", CalendarID: "cal_1", RecallEventID: "re_4", ICalUID: "one-off", Title: "Interview", StartTime: nextWeek, EndTime: nextWeek.Add(time.Hour)},
+		{ID: "other_sync", CalendarID: "cal_2", RecallEventID: "re_5", ICalUID: "weekly-sync", Title: "Weekly sync", StartTime: weekAgo, EndTime: weekAgo.Add(time.Hour)},
+	}
+	require.NoError(t, db.Create(&events).Error)
+
+	recordings := []models.MeetingRecording{
+		{ID: "rec_1", ClerkUserID: "user_1", BotID: "bot_1"},
+		{ID: "rec_2", ClerkUserID: "user_1", BotID: "bot_2"},
+	}
+	require.NoError(t, db.Create(&recordings).Error)
+	notes := []models.Notes{
+		{ID: "note_1", Name: "Sync: launch plan", ChapterID: chapter.ID, MeetingRecordingID: strPtr("rec_1"), AISummary: "Launch slipped a week", Content: "Launch plan discussed"},
+		{ID: "note_2", Name: "Sync: launch done", ChapterID: chapter.ID, MeetingRecordingID: strPtr("rec_2"), AISummary: "Launch shipped", Content: "Launch went out"},
+	}
+	require.NoError(t, db.Create(&notes).Error)
+
+	useTestAccessLookups(t)
+	return &meetingSeriesServiceImpl{
+		db:  db,
+		now: func() time.Time { return now },
+		summarize: func(ctx context.Context, request MeetingSeriesChangesRequest) (string, error) {
+			return "summary", nil
+		},
+	}
+}
+
+func TestMeetingSeries_ListAndTimeline(t *testing.T) {
+	service := setupTestMeetingSeriesService(t)
+
+	series, err := service.ListSeries(context.Background(), "user_1")
+	require.NoError(t, err)
+	require.Len(t, series, 1, "A meeting that doesn't recur isn't a series")
+	assert.Equal(t, "Weekly sync", series[0].Title)
+	assert.Equal(t, 3, series[0].Occurrences, "Other users' events aren't part of the user's series")
+	assert.Equal(t, 2, series[0].RecordedMeetings)
+	require.NotNil(t, series[0].NextStartTime)
+	assert.Equal(t, service.now().AddDate(0, 0, 7), series[0].NextStartTime.UTC())
+
+	again, err := service.ListSeries(context.Background(), "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, series[0].ID, again[0].ID, "Listing again should keep the same series")
+
+	timeline, err := service.GetTimeline(context.Background(), "user_1", series[0].ID)
+	require.NoError(t, err)
+	require.Len(t, timeline.Occurrences, 3)
+	require.NotNil(t, timeline.Occurrences[0].NoteID)
+	assert.Equal(t, "note_1", *timeline.Occurrences[0].NoteID)
+	assert.Equal(t, "Launch shipped", timeline.Occurrences[1].Summary)
+	assert.Nil(t, timeline.Occurrences[2].NoteID)
+
+	_, err = service.GetTimeline(context.Background(), "user_2", series[0].ID)
+	assert.ErrorIs(t, err, ErrMeetingSeriesNotFound)
+}
+
+func TestMeetingSeries_SummarizeChanges(t *testing.T) {
+	service := setupTestMeetingSeriesService(t)
+	var request MeetingSeriesChangesRequest
+	service.summarize = func(ctx context.Context, r MeetingSeriesChangesRequest) (string, error) {
+		request = r
+		return "Launch shipped since last week", nil
+	}
+
+	series, err := service.ListSeries(context.Background(), "user_1")
+	require.NoError(t, err)
+
+	changes, err := service.SummarizeChanges(context.Background(), "user_1", series[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Launch shipped since last week", changes.Summary)
+	assert.Equal(t, 2, changes.MeetingsCompared)
+	assert.True(t, changes.Since.Before(changes.Latest))
+
+	require.Len(t, request.Meetings, 2)
+	assert.Equal(t, "Sync: launch plan", request.Meetings[0].Name, "Meetings are sent oldest first")
+	assert.Contains(t, request.Meetings[1].Content, "Launch shipped")
+	assert.Contains(t, request.Meetings[1].Content, "Launch went out")
+	assert.Equal(t, "user_1", request.UserID)
+
+	require.NoError(t, service.db.Where("id = ?", "note_1").Delete(&models.Notes{}).Error)
+	_, err = service.SummarizeChanges(context.Background(), "user_1", series[0].ID)
+	assert.ErrorIs(t, err, ErrNotEnoughSeriesMeetings)
+}
+
+func TestAddMeetingNoteToSeries(t *testing.T) {
+	service := setupTestMeetingSeriesService(t)
+	ctx := context.Background()
+
+	first := &models.MeetingRecording{ID: "rec_1", ClerkUserID: "user_1", BotID: "bot_1"}
+	second := &models.MeetingRecording{ID: "rec_2", ClerkUserID: "user_1", BotID: "bot_2"}
+	require.NoError(t, addMeetingNoteToSeries(ctx, service.db, first, "note_1"))
+	require.NoError(t, addMeetingNoteToSeries(ctx, service.db, second, "note_2"))
+	require.NoError(t, addMeetingNoteToSeries(ctx, service.db, second, "note_2"))
+
+	var series models.MeetingSeries
+	require.NoError(t, service.db.Where("clerk_user_id = ? AND i_cal_uid = ?", "user_1", "weekly-sync").First(&series).Error)
+	require.NotNil(t, series.RollingNoteID)
+
+	var rolling models.Notes
+	require.NoError(t, service.db.First(&rolling, "id = ?", *series.RollingNoteID).Error)
+	assert.Equal(t, "Weekly sync: rolling notes", rolling.Name)
+	assert.Equal(t, 1, strings.Count(rolling.Content, "Launch slipped a week"))
+	assert.Equal(t, 1, strings.Count(rolling.Content, "Launch shipped"), "Each meeting should only be added once")
+	assert.Less(t, strings.Index(rolling.Content, "Launch slipped"), strings.Index(rolling.Content, "Launch shipped"))
+
+	var links int64
+	service.db.Model(&models.NoteLink{}).Where("source_note_id = ?", rolling.ID).Count(&links)
+	assert.Equal(t, int64(2), links)
+}
+
+func TestAddMeetingNoteToSeries_SkipsOneOffMeetings(t *testing.T) {
+	service := setupTestMeetingSeriesService(t)
+	require.NoError(t, service.db.Model(&models.CalendarEvent{}).Where("id = ?", "one_off").Update("bot_id", "bot_3").Error)
+
+	recording := &models.MeetingRecording{ID: "rec_3", ClerkUserID: "user_1", BotID: "bot_3"}
+	require.NoError(t, addMeetingNoteToSeries(context.Background(), service.db, recording, "note_1"))
+
+	var count int64
+	service.db.Model(&models.MeetingSeries{}).Count(&count)
+	assert.Zero(t, count)
+}
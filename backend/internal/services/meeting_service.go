@@ -3,7 +3,9 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"backend/db"
@@ -15,17 +17,26 @@ import (
 )
 
 type MeetingService struct {
-	db           *gorm.DB
-	recallClient *recallai.Client
-	aiService    *AIService
+	db             *gorm.DB
+	recallClient   *recallai.Client
+	aiService      *AIService
+	redactor       *transcriptRedactor
+	transcription  *transcriptionSettingsServiceImpl
+	botSettings    *botSettingsServiceImpl
+	transcriptions map[string]TranscriptionProvider
 }
 
 // NewMeetingService creates a new meeting service instance
 func NewMeetingService() *MeetingService {
+	recallClient := recallai.NewClient()
 	return &MeetingService{
-		db:           db.DB,
-		recallClient: recallai.NewClient(),
-		aiService:    NewAIService(),
+		db:             db.DB,
+		recallClient:   recallClient,
+		aiService:      NewAIService(),
+		redactor:       newTranscriptRedactor(db.DB),
+		transcription:  newTranscriptionSettingsService(db.DB),
+		botSettings:    newBotSettingsService(db.DB),
+		transcriptions: newTranscriptionProviders(recallClient),
 	}
 }
 
@@ -40,8 +51,17 @@ func (s *MeetingService) StartMeetingRecording(ctx context.Context, clerkUserID
 		Str("meeting_url", meetingURL).
 		Msg("Starting meeting recording")
 
-	// Create bot in Recall.ai
-	botResp, err := s.recallClient.CreateBot(meetingURL)
+	provider, err := s.transcription.ProviderForUser(ctx, clerkUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to choose transcription provider: %w", err)
+	}
+	options, err := s.botSettings.OptionsForUser(ctx, clerkUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bot settings: %w", err)
+	}
+
+	// Create bot in Recall.ai, transcribing unless the meeting is transcribed elsewhere
+	botResp, err := s.recallClient.CreateBot(meetingURL, provider == models.TranscriptionProviderRecall, options)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -53,10 +73,11 @@ func (s *MeetingService) StartMeetingRecording(ctx context.Context, clerkUserID
 
 	// Save meeting recording to database
 	recording := &models.MeetingRecording{
-		ClerkUserID: clerkUserID,
-		BotID:       botResp.ID,
-		MeetingURL:  meetingURL,
-		Status:      "pending",
+		ClerkUserID:           clerkUserID,
+		BotID:                 botResp.ID,
+		MeetingURL:            meetingURL,
+		Status:                "pending",
+		TranscriptionProvider: provider,
 	}
 
 	if err := s.db.Create(recording).Error; err != nil {
@@ -83,6 +104,7 @@ func (s *MeetingService) GetUserMeetings(ctx context.Context, clerkUserID string
 	err := s.db.Where("clerk_user_id = ?", clerkUserID).
 		Preload("Participants").
 		Preload("GeneratedNote").
+		Preload("Redaction").
 		Order("created_at DESC").
 		Find(&meetings).Error
 
@@ -169,15 +191,15 @@ func (s *MeetingService) ProcessCompletedMeeting(ctx context.Context, botID stri
 		Bool("has_video", videoURL != "").
 		Msg("Retrieved recording URLs from Recall.ai")
 
-	// Download transcript
-	transcript, err := s.recallClient.DownloadTranscript(transcriptURL)
+	// Transcribe with the provider the meeting was recorded for
+	transcript, err := transcribeRecording(ctx, s.transcriptions, &recording)
 	if err != nil {
 		s.updateRecordingStatus(&recording, "failed")
 		log.Error().
 			Err(err).
-			Str("transcript_url", transcriptURL).
-			Msg("Failed to download transcript")
-		return fmt.Errorf("failed to download transcript: %w", err)
+			Str("recording_id", recording.ID).
+			Msg("Failed to transcribe meeting")
+		return fmt.Errorf("failed to transcribe meeting: %w", err)
 	}
 
 	// Process transcript and create note
@@ -218,8 +240,8 @@ func (s *MeetingService) updateRecordingStatus(recording *models.MeetingRecordin
 
 // processTranscriptAndCreateNote processes the transcript with AI and creates a note
 func (s *MeetingService) processTranscriptAndCreateNote(ctx context.Context, recording *models.MeetingRecording, transcript []recallai.TranscriptEntry) error {
-	// Convert transcript to plain text for AI analysis
-	transcriptText := s.aiService.TranscriptToPlainText(transcript)
+	// Convert transcript to plain text for AI analysis, redacted by the owner's organization policies
+	transcriptText, redactErr := s.redactor.TranscriptForAI(ctx, recording, transcript)
 
 	// Get user's existing notebooks
 	var notebooks []models.Notebook
@@ -236,9 +258,16 @@ func (s *MeetingService) processTranscriptAndCreateNote(ctx context.Context, rec
 		existingNotebooks[i] = nb.Name
 	}
 
-	// Analyze with AI
-	analysis, err := s.aiService.AnalyzeTranscript(ctx, transcriptText, existingNotebooks)
-	if err != nil {
+	// Analyze with AI, unless the transcript couldn't be redacted
+	var analysis *TranscriptAnalysis
+	var err error
+	if redactErr != nil {
+		log.Error().
+			Err(redactErr).
+			Str("recording_id", recording.ID).
+			Msg("Failed to redact transcript, using fallback instead of sending it to AI")
+		analysis = s.aiService.CreateFallbackAnalysis(recording.MeetingURL)
+	} else if analysis, err = s.aiService.AnalyzeTranscript(ctx, transcriptText, existingNotebooks); err != nil {
 		log.Warn().
 			Err(err).
 			Str("recording_id", recording.ID).
@@ -297,9 +326,126 @@ func (s *MeetingService) processTranscriptAndCreateNote(ctx context.Context, rec
 		Str("chapter_name", analysis.ChapterName).
 		Msg("Successfully created note from meeting transcript")
 
+	addMeetingNoteToNotes(ctx, s.db, recording, note.ID)
+	NewLinkSuggestionService().MeetingNoteCreated(note.ID)
+
 	return nil
 }
 
+// addMeetingNoteToNotes adds a meeting's generated note to the notes that follow the meeting: the agenda
+// note of its calendar event and the rolling note of its series. Failures are only logged, the
+// generated note stands on its own.
+func addMeetingNoteToNotes(ctx context.Context, tx *gorm.DB, recording *models.MeetingRecording, noteID string) {
+	if err := addMeetingNoteToAgenda(ctx, tx, recording, noteID); err != nil {
+		log.Warn().
+			Err(err).
+			Str("recording_id", recording.ID).
+			Str("note_id", noteID).
+			Msg("Failed to add meeting note to agenda note")
+	}
+	if err := addMeetingNoteToSeries(ctx, tx, recording, noteID); err != nil {
+		log.Warn().
+			Err(err).
+			Str("recording_id", recording.ID).
+			Str("note_id", noteID).
+			Msg("Failed to add meeting note to series rolling note")
+	}
+}
+
+// addMeetingNoteToAgenda appends the summary of a meeting's generated note to the agenda note linked to
+// its calendar event, and links the two notes. It's skipped when the event has no agenda note, the
+// user can no longer edit it or the meeting was already added.
+func addMeetingNoteToAgenda(ctx context.Context, tx *gorm.DB, recording *models.MeetingRecording, noteID string) error {
+	if recording.BotID == "" {
+		return nil
+	}
+
+	var event models.CalendarEvent
+	err := tx.WithContext(ctx).Where("bot_id = ? AND agenda_note_id IS NOT NULL", recording.BotID).First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch calendar event: %w", err)
+	}
+
+	var note models.Notes
+	if err := tx.WithContext(ctx).Select("id", "name", "ai_summary").Where("id = ?", noteID).First(&note).Error; err != nil {
+		return fmt.Errorf("failed to fetch meeting note: %w", err)
+	}
+	section := fmt.Sprintf("## Meeting notes\n\nNotes from the meeting: **%s**", note.Name)
+	if summary := strings.TrimSpace(note.AISummary); summary != "" {
+		section += "\n\n" + summary
+	}
+
+	added, err := appendMeetingSection(ctx, tx, *event.AgendaNoteID, &note, recording.ClerkUserID, section, "Notes from the meeting")
+	if err != nil || !added {
+		return err
+	}
+
+	log.Info().
+		Str("event_id", event.ID).
+		Str("agenda_note_id", *event.AgendaNoteID).
+		Str("note_id", note.ID).
+		Msg("Added meeting note to agenda note")
+
+	return nil
+}
+
+// appendMeetingSection appends a Markdown section about a meeting's note to another note and links the
+// two, annotating the link. It reports false without changing anything when the user can't edit the
+// note or the meeting note is already linked from it.
+func appendMeetingSection(ctx context.Context, tx *gorm.DB, targetNoteID string, meetingNote *models.Notes, clerkUserID, section, annotation string) (bool, error) {
+	canEdit, err := accessLookups.CanEditNote(ctx, tx, targetNoteID, clerkUserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, fmt.Errorf("failed to check note access: %w", err)
+	}
+	if !canEdit {
+		log.Info().
+			Str("note_id", targetNoteID).
+			Str("clerk_user_id", clerkUserID).
+			Msg("Note isn't editable by the meeting's owner, not adding the meeting to it")
+		return false, nil
+	}
+
+	var linked int64
+	if err := tx.WithContext(ctx).Model(&models.NoteLink{}).
+		Where("source_note_id = ? AND target_note_id = ?", targetNoteID, meetingNote.ID).
+		Count(&linked).Error; err != nil {
+		return false, fmt.Errorf("failed to check note links: %w", err)
+	}
+	if linked > 0 {
+		return false, nil
+	}
+
+	var target models.Notes
+	if err := tx.WithContext(ctx).Where("id = ?", targetNoteID).First(&target).Error; err != nil {
+		return false, fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	// A locked note's content is encrypted, it only gets the link
+	if !target.Locked {
+		content, err := appendMarkdownToTipTap(target.Content, section)
+		if err != nil {
+			return false, err
+		}
+		if err := tx.WithContext(ctx).Model(&models.Notes{}).Where("id = ?", target.ID).Update("content", content).Error; err != nil {
+			return false, fmt.Errorf("failed to update note: %w", err)
+		}
+		// Drop the collaborative document so editors load the appended content
+		if err := NewYjsService(tx).DeleteYjsDocument(target.ID); err != nil {
+			log.Warn().Err(err).Str("note_id", target.ID).Msg("Failed to reset Yjs document after adding meeting notes")
+		}
+	}
+
+	links := &NoteLinkService{db: tx}
+	if _, err := links.CreateNoteLink(target.ID, meetingNote.ID, models.LinkTypeReferences, "", models.LinkOriginManual,
+		annotation, clerkUserID, target.OrganizationID); err != nil {
+		return false, fmt.Errorf("failed to link meeting note: %w", err)
+	}
+	return true, nil
+}
+
 // findOrCreateNotebook finds an existing notebook or creates a new one
 func (s *MeetingService) findOrCreateNotebook(clerkUserID string, notebookName string) (*models.Notebook, error) {
 	var notebook models.Notebook
@@ -375,3 +521,49 @@ func (s *MeetingService) findOrCreateChapter(notebookID, chapterName string) (*m
 
 	return &chapter, nil
 }
+
+// MeetingNoteSummary is a recorded meeting together with the note generated from its transcript
+type MeetingNoteSummary struct {
+	RecordingID  string    `json:"recordingId"`
+	NoteID       string    `json:"noteId"`
+	NoteName     string    `json:"noteName"`
+	MeetingTitle string    `json:"meetingTitle,omitempty"`
+	MeetingURL   string    `json:"meetingUrl"`
+	RecordedAt   time.Time `json:"recordedAt"`
+	Summary      string    `json:"summary"`
+	Content      string    `json:"-"`
+}
+
+// FindMeetingNotes searches the user's recorded meetings by note name or calendar event title,
+// optionally limited to meetings recorded in the given time range. The most recent meetings come first.
+func (s *MeetingService) FindMeetingNotes(clerkUserID string, query string, from, to *time.Time, limit int) ([]MeetingNoteSummary, error) {
+	dbQuery := s.db.Table("notes").
+		Select("meeting_recordings.id AS recording_id, notes.id AS note_id, notes.name AS note_name, "+
+			"COALESCE(calendar_events.title, '') AS meeting_title, meeting_recordings.meeting_url, "+
+			"meeting_recordings.created_at AS recorded_at, COALESCE(notes.ai_summary, '') AS summary, notes.content").
+		Joins("JOIN meeting_recordings ON meeting_recordings.id = notes.meeting_recording_id").
+		Joins("LEFT JOIN calendar_events ON calendar_events.bot_id = meeting_recordings.bot_id").
+		Where("meeting_recordings.clerk_user_id = ?", clerkUserID)
+
+	if query = strings.ToLower(strings.TrimSpace(query)); query != "" {
+		pattern := "%" + query + "%"
+		dbQuery = dbQuery.Where("(LOWER(notes.name) LIKE ? OR LOWER(calendar_events.title) LIKE ?)", pattern, pattern)
+	}
+	if from != nil {
+		dbQuery = dbQuery.Where("meeting_recordings.created_at >= ?", *from)
+	}
+	if to != nil {
+		dbQuery = dbQuery.Where("meeting_recordings.created_at < ?", *to)
+	}
+
+	var results []MeetingNoteSummary
+	if err := dbQuery.Order("meeting_recordings.created_at DESC").Limit(limit).Scan(&results).Error; err != nil {
+		log.Error().
+			Err(err).
+			Str("clerk_user_id", clerkUserID).
+			Msg("Failed to search meeting notes")
+		return nil, fmt.Errorf("failed to search meeting notes: %w", err)
+	}
+
+	return results, nil
+}
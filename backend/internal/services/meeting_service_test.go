@@ -0,0 +1,129 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestMeetingService creates a meeting service with recorded meetings for testing
+func setupTestMeetingService(t *testing.T) *MeetingService {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.MeetingRecording{}, &models.Notes{}, &models.Calendar{}, &models.CalendarEvent{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	yesterday := time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC)
+	lastWeek := yesterday.AddDate(0, 0, -7)
+
+	recordings := []models.MeetingRecording{
+		{ID: "rec_design", ClerkUserID: "user_1", BotID: "bot_design", MeetingURL: "https://meet.google.com/abc", CreatedAt: yesterday},
+		{ID: "rec_standup", ClerkUserID: "user_1", BotID: "bot_standup", MeetingURL: "https://zoom.us/j/1", CreatedAt: lastWeek},
+		{ID: "rec_other", ClerkUserID: "user_2", BotID: "bot_other", MeetingURL: "https://zoom.us/j/2", CreatedAt: yesterday},
+	}
+	require.NoError(t, db.Create(&recordings).Error)
+
+	notes := []models.Notes{
+		{Name: "Q2 planning", ChapterID: "chapter_1", MeetingRecordingID: strPtr("rec_design"), AISummary: "Agreed on the new onboarding flow"},
+		{Name: "Daily standup", ChapterID: "chapter_1", MeetingRecordingID: strPtr("rec_standup"), AISummary: "Blocked on API review"},
+		{Name: "Design review", ChapterID: "chapter_2", MeetingRecordingID: strPtr("rec_other"), AISummary: "Someone else's meeting"},
+	}
+	require.NoError(t, db.Create(&notes).Error)
+
+	// The calendar event title differs from the generated note name
+	calendar := models.Calendar{ID: "cal_1", ClerkUserID: "user_1", RecallCalendarID: "recall_cal_1", Platform: "google_calendar"}
+	require.NoError(t, db.Create(&calendar).Error)
+	event := models.CalendarEvent{CalendarID: "cal_1", RecallEventID: "recall_event_1", Title: "Design Review", BotID: strPtr("bot_design")}
+	require.NoError(t, db.Create(&event).Error)
+
+	useTestAccessLookups(t)
+	return &MeetingService{db: db}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestFindMeetingNotes_MatchesCalendarTitle(t *testing.T) {
+	service := setupTestMeetingService(t)
+
+	results, err := service.FindMeetingNotes("user_1", "design review", nil, nil, 3)
+	require.NoError(t, err)
+	require.Len(t, results, 1, "Only the user's own meetings should match")
+	assert.Equal(t, "Q2 planning", results[0].NoteName)
+	assert.Equal(t, "Design Review", results[0].MeetingTitle)
+	assert.Equal(t, "Agreed on the new onboarding flow", results[0].Summary)
+}
+
+func TestFindMeetingNotes_FiltersByDate(t *testing.T) {
+	service := setupTestMeetingService(t)
+
+	from := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	results, err := service.FindMeetingNotes("user_1", "", &from, &to, 3)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "rec_design", results[0].RecordingID)
+
+	results, err = service.FindMeetingNotes("user_1", "", nil, nil, 3)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "rec_design", results[0].RecordingID, "Most recent meetings come first")
+}
+
+func TestAddMeetingNoteToAgenda(t *testing.T) {
+	service := setupTestMeetingService(t)
+	require.NoError(t, service.db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.NoteLink{}, &models.YjsDocument{}))
+
+	notebook := models.Notebook{Name: "Work", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Prep", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&chapter).Error)
+	agenda := models.Notes{Name: "Design review prep", ChapterID: chapter.ID}
+	require.NoError(t, service.db.Create(&agenda).Error)
+	require.NoError(t, service.db.Model(&models.CalendarEvent{}).Where("bot_id = ?", "bot_design").
+		Update("agenda_note_id", agenda.ID).Error)
+
+	var meetingNote models.Notes
+	require.NoError(t, service.db.Where("meeting_recording_id = ?", "rec_design").First(&meetingNote).Error)
+	recording := &models.MeetingRecording{ID: "rec_design", ClerkUserID: "user_1", BotID: "bot_design"}
+
+	require.NoError(t, addMeetingNoteToAgenda(context.Background(), service.db, recording, meetingNote.ID))
+	require.NoError(t, addMeetingNoteToAgenda(context.Background(), service.db, recording, meetingNote.ID))
+
+	require.NoError(t, service.db.First(&agenda, "id = ?", agenda.ID).Error)
+	assert.Equal(t, 1, strings.Count(agenda.Content, "Meeting notes"), "The meeting should only be added once")
+	assert.Contains(t, agenda.Content, "Q2 planning")
+	assert.Contains(t, agenda.Content, "Agreed on the new onboarding flow")
+
+	var links []models.NoteLink
+	require.NoError(t, service.db.Find(&links).Error)
+	require.Len(t, links, 1)
+	assert.Equal(t, agenda.ID, links[0].SourceNoteID)
+	assert.Equal(t, meetingNote.ID, links[0].TargetNoteID)
+
+	// Someone else's meeting isn't added to the user's agenda note
+	other := &models.MeetingRecording{ID: "rec_other", ClerkUserID: "user_2", BotID: "bot_design"}
+	var otherNote models.Notes
+	require.NoError(t, service.db.Where("meeting_recording_id = ?", "rec_other").First(&otherNote).Error)
+	require.NoError(t, addMeetingNoteToAgenda(context.Background(), service.db, other, otherNote.ID))
+	var count int64
+	service.db.Model(&models.NoteLink{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestAddMeetingNoteToAgenda_NoAgenda(t *testing.T) {
+	service := setupTestMeetingService(t)
+
+	recording := &models.MeetingRecording{ID: "rec_standup", ClerkUserID: "user_1", BotID: "bot_standup"}
+	assert.NoError(t, addMeetingNoteToAgenda(context.Background(), service.db, recording, "note_1"))
+}
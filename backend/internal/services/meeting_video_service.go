@@ -0,0 +1,181 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/pkg/recallai"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrVideoNotAvailable is returned when a meeting has no recorded video, or it isn't ready yet
+var ErrVideoNotAvailable = errors.New("meeting video not available")
+
+// MeetingVideo is where a meeting's video can be read from: the archived copy when there is one,
+// otherwise a freshly signed Recall.ai download URL
+type MeetingVideo struct {
+	ArchivePath string
+	DownloadURL string
+}
+
+// MeetingVideoService interface defines methods for accessing and archiving meeting videos
+type MeetingVideoService interface {
+	GetVideo(ctx context.Context, clerkUserID, recordingID string) (*MeetingVideo, error)
+	ArchiveVideo(ctx context.Context, recordingID string) error
+}
+
+// meetingVideoServiceImpl implements the MeetingVideoService interface
+type meetingVideoServiceImpl struct {
+	db           *gorm.DB
+	recallClient *recallai.Client
+	archiveDir   string
+	now          func() time.Time
+}
+
+// NewMeetingVideoService creates a new MeetingVideoService instance
+func NewMeetingVideoService() MeetingVideoService {
+	return &meetingVideoServiceImpl{
+		db:           db.DB,
+		recallClient: recallai.NewClient(),
+		archiveDir:   config.LoadMeetingVideoConfig().ArchiveDir,
+		now:          time.Now,
+	}
+}
+
+// GetVideo returns where one of the user's meeting videos can be read from. Recall.ai URLs expire, so a
+// fresh one is fetched for every request that isn't served from the archive.
+func (s *meetingVideoServiceImpl) GetVideo(ctx context.Context, clerkUserID, recordingID string) (*MeetingVideo, error) {
+	var recordings []models.MeetingRecording
+	if err := s.db.WithContext(ctx).Where("id = ? AND clerk_user_id = ?", recordingID, clerkUserID).Limit(1).Find(&recordings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch meeting: %w", err)
+	}
+	if len(recordings) == 0 {
+		return nil, ErrMeetingNotFound
+	}
+	recording := &recordings[0]
+
+	if path := s.archivedVideoPath(recording); path != "" {
+		return &MeetingVideo{ArchivePath: path}, nil
+	}
+
+	downloadURL, err := s.freshVideoURL(ctx, recording)
+	if err != nil {
+		return nil, err
+	}
+	return &MeetingVideo{DownloadURL: downloadURL}, nil
+}
+
+// ArchiveVideo copies a meeting's video to the archive directory. It does nothing when archiving is
+// disabled or the video is already archived.
+func (s *meetingVideoServiceImpl) ArchiveVideo(ctx context.Context, recordingID string) error {
+	if s.archiveDir == "" {
+		return nil
+	}
+
+	var recording models.MeetingRecording
+	if err := s.db.WithContext(ctx).Where("id = ?", recordingID).First(&recording).Error; err != nil {
+		return fmt.Errorf("failed to fetch meeting: %w", err)
+	}
+	if s.archivedVideoPath(&recording) != "" {
+		return nil
+	}
+
+	downloadURL, err := s.freshVideoURL(ctx, &recording)
+	if err != nil {
+		return err
+	}
+	video, err := s.recallClient.DownloadMedia(ctx, downloadURL)
+	if err != nil {
+		return err
+	}
+	defer video.Close()
+
+	if err := os.MkdirAll(s.archiveDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create video archive: %w", err)
+	}
+	// Written to a temporary file first so a failed download never looks archived
+	file, err := os.CreateTemp(s.archiveDir, recording.ID+"-*.part")
+	if err != nil {
+		return fmt.Errorf("failed to create archived video: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	size, err := io.Copy(file, video)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to archive video: %w", err)
+	}
+
+	name := recording.ID + ".mp4"
+	if err := os.Rename(file.Name(), filepath.Join(s.archiveDir, name)); err != nil {
+		return fmt.Errorf("failed to archive video: %w", err)
+	}
+
+	archivedAt := s.now()
+	if err := s.db.WithContext(ctx).Model(&recording).Updates(map[string]interface{}{
+		"video_archive_path": name,
+		"video_archived_at":  archivedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to save archived video: %w", err)
+	}
+
+	log.Info().
+		Str("meeting_id", recording.ID).
+		Int64("size", size).
+		Msg("Archived meeting video")
+	return nil
+}
+
+// archivedVideoPath returns the archived copy of a recording's video, empty when there is none
+func (s *meetingVideoServiceImpl) archivedVideoPath(recording *models.MeetingRecording) string {
+	if s.archiveDir == "" || recording.VideoArchivePath == "" {
+		return ""
+	}
+	path := filepath.Join(s.archiveDir, filepath.Base(recording.VideoArchivePath))
+	if _, err := os.Stat(path); err != nil {
+		log.Warn().
+			Err(err).
+			Str("meeting_id", recording.ID).
+			Msg("Archived meeting video is missing, falling back to Recall.ai")
+		return ""
+	}
+	return path
+}
+
+// freshVideoURL fetches a newly signed download URL for a recording's video from Recall.ai and keeps it
+// on the recording
+func (s *meetingVideoServiceImpl) freshVideoURL(ctx context.Context, recording *models.MeetingRecording) (string, error) {
+	botDetails, err := s.recallClient.GetBot(recording.BotID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get bot details: %w", err)
+	}
+	if len(botDetails.Recordings) == 0 {
+		return "", ErrVideoNotAvailable
+	}
+	downloadURL := botDetails.Recordings[0].MediaShortcuts.VideoMixed.Data.DownloadURL
+	if downloadURL == "" {
+		return "", ErrVideoNotAvailable
+	}
+
+	if downloadURL != recording.VideoDownloadURL {
+		recording.VideoDownloadURL = downloadURL
+		if err := s.db.WithContext(ctx).Model(recording).Update("video_download_url", downloadURL).Error; err != nil {
+			log.Warn().
+				Err(err).
+				Str("meeting_id", recording.ID).
+				Msg("Failed to save fresh video URL")
+		}
+	}
+	return downloadURL, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/pkg/recallai"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestMeetingVideoService(t *testing.T) *meetingVideoServiceImpl {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/bot/bot_1/":
+			bot := recallai.BotDetails{ID: "bot_1", Recordings: []recallai.Recording{{ID: "rec"}}}
+			bot.Recordings[0].MediaShortcuts.VideoMixed.Data.DownloadURL = server.URL + "/media/video.mp4?signature=fresh"
+			json.NewEncoder(w).Encode(bot)
+		case "/api/v1/bot/bot_2/":
+			json.NewEncoder(w).Encode(recallai.BotDetails{ID: "bot_2"})
+		case "/media/video.mp4":
+			w.Write([]byte("mp4 video"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.MeetingRecording{}), "Failed to migrate test database")
+	require.NoError(t, db.Create(&models.MeetingRecording{ID: "rec_1", ClerkUserID: "user_1", BotID: "bot_1", MeetingURL: "https://zoom.us/j/1", VideoDownloadURL: "https://expired"}).Error)
+	require.NoError(t, db.Create(&models.MeetingRecording{ID: "rec_2", ClerkUserID: "user_1", BotID: "bot_2", MeetingURL: "https://zoom.us/j/2"}).Error)
+
+	target, _ := url.Parse(server.URL)
+	return &meetingVideoServiceImpl{
+		db:           db,
+		recallClient: &recallai.Client{APIKey: "key", Region: "us-east-1", HTTPClient: &http.Client{Transport: rewriteTransport{target: target}}},
+		now:          func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC) },
+	}
+}
+
+func TestGetMeetingVideo(t *testing.T) {
+	service := setupTestMeetingVideoService(t)
+	ctx := context.Background()
+
+	video, err := service.GetVideo(ctx, "user_1", "rec_1")
+	require.NoError(t, err)
+	assert.Empty(t, video.ArchivePath)
+	assert.Contains(t, video.DownloadURL, "signature=fresh")
+
+	var recording models.MeetingRecording
+	require.NoError(t, service.db.First(&recording, "id = ?", "rec_1").Error)
+	assert.Equal(t, video.DownloadURL, recording.VideoDownloadURL, "The expired URL is replaced")
+
+	_, err = service.GetVideo(ctx, "user_2", "rec_1")
+	assert.ErrorIs(t, err, ErrMeetingNotFound)
+	_, err = service.GetVideo(ctx, "user_1", "rec_2")
+	assert.ErrorIs(t, err, ErrVideoNotAvailable)
+}
+
+func TestArchiveMeetingVideo(t *testing.T) {
+	service := setupTestMeetingVideoService(t)
+	ctx := context.Background()
+
+	require.NoError(t, service.ArchiveVideo(ctx, "rec_1"), "Archiving is a no-op when disabled")
+	service.archiveDir = filepath.Join(t.TempDir(), "videos")
+
+	require.NoError(t, service.ArchiveVideo(ctx, "rec_1"))
+	content, err := os.ReadFile(filepath.Join(service.archiveDir, "rec_1.mp4"))
+	require.NoError(t, err)
+	assert.Equal(t, "mp4 video", string(content))
+	entries, _ := os.ReadDir(service.archiveDir)
+	assert.Len(t, entries, 1, "No partial downloads are left behind")
+
+	video, err := service.GetVideo(ctx, "user_1", "rec_1")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(service.archiveDir, "rec_1.mp4"), video.ArchivePath)
+
+	var recording models.MeetingRecording
+	require.NoError(t, service.db.First(&recording, "id = ?", "rec_1").Error)
+	require.NotNil(t, recording.VideoArchivedAt)
+
+	require.NoError(t, os.Remove(video.ArchivePath))
+	video, err = service.GetVideo(ctx, "user_1", "rec_1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, video.DownloadURL, "Missing archives fall back to Recall.ai")
+
+	assert.ErrorIs(t, service.ArchiveVideo(ctx, "rec_2"), ErrVideoNotAvailable)
+}
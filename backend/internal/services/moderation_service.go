@@ -0,0 +1,465 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidModerationPolicy is returned for a policy action that isn't off, flag or block
+	ErrInvalidModerationPolicy = errors.New("moderation actions must be one of off, flag or block")
+	// ErrInvalidModerationStatus is returned for an unknown finding status
+	ErrInvalidModerationStatus = errors.New("finding status must be one of open, resolved or dismissed")
+	// ErrModerationFindingNotFound is returned when the organization has no finding with the ID
+	ErrModerationFindingNotFound = errors.New("moderation finding not found")
+)
+
+// moderationPattern detects one kind of sensitive content
+type moderationPattern struct {
+	category string
+	kind     string
+	pattern  *regexp.Regexp
+	group    int               // Submatch holding the sensitive value, 0 for the whole match
+	valid    func(string) bool // Optional check that rules out look-alikes
+}
+
+// moderationPatterns are checked in order, so specific patterns come before the generic ones they overlap with
+var moderationPatterns = []moderationPattern{
+	{category: models.ModerationCategorySecret, kind: "private_key", pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{category: models.ModerationCategorySecret, kind: "aws_access_key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{category: models.ModerationCategorySecret, kind: "github_token", pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{category: models.ModerationCategorySecret, kind: "anthropic_api_key", pattern: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{category: models.ModerationCategorySecret, kind: "openai_api_key", pattern: regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}`)},
+	{category: models.ModerationCategorySecret, kind: "stripe_secret_key", pattern: regexp.MustCompile(`\b(?:sk|rk)_live_[A-Za-z0-9]{16,}`)},
+	{category: models.ModerationCategorySecret, kind: "google_api_key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{category: models.ModerationCategorySecret, kind: "slack_token", pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{category: models.ModerationCategorySecret, kind: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{category: models.ModerationCategorySecret, kind: "connection_string", pattern: regexp.MustCompile(`\b[a-z][a-z0-9+.-]*://[^\s:/@]+:([^\s@/]+)@`), group: 1},
+	{category: models.ModerationCategorySecret, kind: "password", pattern: regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token)\s*[:=]\s*["']?([^\s"',;]{6,})`), group: 1},
+	{category: models.ModerationCategoryPII, kind: "credit_card", pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: passesLuhn},
+	{category: models.ModerationCategoryPII, kind: "us_ssn", pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{category: models.ModerationCategoryPII, kind: "email", pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{category: models.ModerationCategoryPII, kind: "phone_number", pattern: regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b`)},
+}
+
+// ModerationMatch is sensitive content found in a piece of text. Only a masked excerpt of the value is kept.
+type ModerationMatch struct {
+	Category    string `json:"category"`
+	Kind        string `json:"kind"`
+	Detector    string `json:"detector"`
+	Excerpt     string `json:"excerpt"`
+	Blocked     bool   `json:"blocked"`
+	fingerprint string
+}
+
+// ModerationResult is the outcome of checking content against an organization's moderation policy
+type ModerationResult struct {
+	Matches []ModerationMatch `json:"findings"`
+	Blocked bool              `json:"blocked"`
+}
+
+// PublishScanResult is what the pre-publish scan found in a note
+type PublishScanResult struct {
+	NoteID         string            `json:"noteId"`
+	NoteName       string            `json:"noteName"`
+	OrganizationID *string           `json:"-"`
+	Findings       []ModerationMatch `json:"findings"`
+}
+
+// ModerationTarget is where checked content was written and by whom
+type ModerationTarget struct {
+	Source      string
+	NoteID      *string
+	ClerkUserID string
+}
+
+// ModerationPolicyInput is the editable part of an organization's moderation policy
+type ModerationPolicyInput struct {
+	SecretsAction string `json:"secretsAction"`
+	PIIAction     string `json:"piiAction"`
+	AIClassifier  bool   `json:"aiClassifier"`
+}
+
+// ModerationService interface defines methods for scanning organization content for secrets and personal data
+type ModerationService interface {
+	GetPolicy(organizationID string) (*models.OrganizationModerationPolicy, error)
+	SavePolicy(organizationID string, input ModerationPolicyInput, updatedBy string) (*models.OrganizationModerationPolicy, error)
+	Check(organizationID, text string) (*ModerationResult, error)
+	Record(organizationID string, target ModerationTarget, result *ModerationResult) error
+	Classify(ctx context.Context, organizationID string, target ModerationTarget, text string) error
+	ListFindings(organizationID, status string) ([]models.ModerationFinding, error)
+	ReviewFinding(organizationID, findingID, status, reviewerID string) (*models.ModerationFinding, error)
+	ScanForPublishing(noteIDs []string) ([]PublishScanResult, error)
+}
+
+// moderationServiceImpl implements the ModerationService interface
+type moderationServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewModerationService creates a new ModerationService instance
+func NewModerationService() ModerationService {
+	return &moderationServiceImpl{
+		db: db.DB,
+	}
+}
+
+// GetPolicy returns an organization's moderation policy, with every category off when it has none
+func (s *moderationServiceImpl) GetPolicy(organizationID string) (*models.OrganizationModerationPolicy, error) {
+	var policy models.OrganizationModerationPolicy
+	if err := s.db.Where("organization_id = ?", organizationID).Limit(1).Find(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch moderation policy: %w", err)
+	}
+	policy.OrganizationID = organizationID
+	if policy.SecretsAction == "" {
+		policy.SecretsAction = models.ModerationActionOff
+	}
+	if policy.PIIAction == "" {
+		policy.PIIAction = models.ModerationActionOff
+	}
+	return &policy, nil
+}
+
+// SavePolicy creates or replaces an organization's moderation policy
+func (s *moderationServiceImpl) SavePolicy(organizationID string, input ModerationPolicyInput, updatedBy string) (*models.OrganizationModerationPolicy, error) {
+	secretsAction := strings.ToLower(strings.TrimSpace(input.SecretsAction))
+	piiAction := strings.ToLower(strings.TrimSpace(input.PIIAction))
+	if !containsString(models.ModerationActions, secretsAction) || !containsString(models.ModerationActions, piiAction) {
+		return nil, ErrInvalidModerationPolicy
+	}
+
+	policy, err := s.GetPolicy(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	policy.SecretsAction = secretsAction
+	policy.PIIAction = piiAction
+	policy.AIClassifier = input.AIClassifier
+	policy.UpdatedBy = updatedBy
+	if err := s.db.Save(policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save moderation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// Check scans text written to an organization workspace against its policy. Nothing is recorded, so
+// content can be checked before it is saved and recorded once it has an ID.
+func (s *moderationServiceImpl) Check(organizationID, text string) (*ModerationResult, error) {
+	result := &ModerationResult{Matches: []ModerationMatch{}}
+	if organizationID == "" {
+		return result, nil
+	}
+	policy, err := s.GetPolicy(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if policy.SecretsAction == models.ModerationActionOff && policy.PIIAction == models.ModerationActionOff {
+		return result, nil
+	}
+
+	for _, match := range ScanSensitiveContent(text) {
+		action := moderationAction(policy, match.Category)
+		if action == models.ModerationActionOff {
+			continue
+		}
+		match.Blocked = action == models.ModerationActionBlock
+		result.Blocked = result.Blocked || match.Blocked
+		result.Matches = append(result.Matches, match)
+	}
+	return result, nil
+}
+
+// Record adds a finding to the review queue for every match that isn't already open for the same note,
+// or the same user's chats
+func (s *moderationServiceImpl) Record(organizationID string, target ModerationTarget, result *ModerationResult) error {
+	if result == nil || len(result.Matches) == 0 {
+		return nil
+	}
+
+	for _, match := range result.Matches {
+		query := s.db.Model(&models.ModerationFinding{}).
+			Where("organization_id = ? AND fingerprint = ? AND status = ?", organizationID, match.fingerprint, models.ModerationFindingOpen)
+		if target.NoteID != nil {
+			query = query.Where("note_id = ?", *target.NoteID)
+		} else {
+			query = query.Where("source = ? AND clerk_user_id = ?", target.Source, target.ClerkUserID)
+		}
+		var existing int64
+		if err := query.Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to check moderation findings: %w", err)
+		}
+		if existing > 0 {
+			continue
+		}
+
+		finding := models.ModerationFinding{
+			OrganizationID: organizationID,
+			Source:         target.Source,
+			NoteID:         target.NoteID,
+			ClerkUserID:    target.ClerkUserID,
+			Category:       match.Category,
+			Kind:           match.Kind,
+			Detector:       match.Detector,
+			Excerpt:        match.Excerpt,
+			Fingerprint:    match.fingerprint,
+			Blocked:        match.Blocked,
+			Status:         models.ModerationFindingOpen,
+		}
+		if err := s.db.Create(&finding).Error; err != nil {
+			return fmt.Errorf("failed to record moderation finding: %w", err)
+		}
+	}
+
+	log.Info().
+		Str("org_id", organizationID).
+		Str("source", target.Source).
+		Int("matches", len(result.Matches)).
+		Bool("blocked", result.Blocked).
+		Msg("Moderation findings recorded")
+	return nil
+}
+
+// Classify asks the background AI model for sensitive content the patterns miss, when the organization's
+// policy enables the classifier. Its findings are flagged for review, never blocked, since the content
+// has already been saved by the time the classifier answers.
+func (s *moderationServiceImpl) Classify(ctx context.Context, organizationID string, target ModerationTarget, text string) error {
+	if organizationID == "" || strings.TrimSpace(text) == "" {
+		return nil
+	}
+	policy, err := s.GetPolicy(organizationID)
+	if err != nil {
+		return err
+	}
+	if !policy.AIClassifier || (policy.SecretsAction == models.ModerationActionOff && policy.PIIAction == models.ModerationActionOff) {
+		return nil
+	}
+
+	findings, err := NewAIService().ClassifySensitiveContent(ctx, SensitiveContentRequest{
+		Text:   text,
+		UserID: target.ClerkUserID,
+		OrgID:  &organizationID,
+	})
+	if err != nil {
+		return err
+	}
+
+	result := &ModerationResult{}
+	for _, finding := range findings {
+		category := strings.ToLower(strings.TrimSpace(finding.Category))
+		value := strings.TrimSpace(finding.Value)
+		// Only report text that is really in the content, in a category the policy scans
+		if value == "" || !strings.Contains(text, value) || moderationAction(policy, category) == models.ModerationActionOff {
+			continue
+		}
+		kind := strings.ToLower(strings.TrimSpace(finding.Kind))
+		if kind == "" {
+			kind = category
+		}
+		result.Matches = append(result.Matches, newModerationMatch(category, truncateLinkText(kind, 50), models.ModerationDetectorClassifier, value))
+	}
+	return s.Record(organizationID, target, result)
+}
+
+// ListFindings returns an organization's findings, newest first, optionally only those with a status
+func (s *moderationServiceImpl) ListFindings(organizationID, status string) ([]models.ModerationFinding, error) {
+	findings := []models.ModerationFinding{}
+	query := s.db.Where("organization_id = ?", organizationID)
+	if status != "" {
+		if !containsString(models.ModerationFindingStatuses, status) {
+			return nil, ErrInvalidModerationStatus
+		}
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("created_at DESC").Limit(500).Find(&findings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch moderation findings: %w", err)
+	}
+	return findings, nil
+}
+
+// ReviewFinding sets the status of a finding, recording who reviewed it
+func (s *moderationServiceImpl) ReviewFinding(organizationID, findingID, status, reviewerID string) (*models.ModerationFinding, error) {
+	if !containsString(models.ModerationFindingStatuses, status) {
+		return nil, ErrInvalidModerationStatus
+	}
+
+	var finding models.ModerationFinding
+	if err := s.db.Where("id = ? AND organization_id = ?", findingID, organizationID).First(&finding).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrModerationFindingNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch moderation finding: %w", err)
+	}
+
+	finding.Status = status
+	if status == models.ModerationFindingOpen {
+		finding.ReviewedBy = ""
+		finding.ReviewedAt = nil
+	} else {
+		now := time.Now()
+		finding.ReviewedBy = reviewerID
+		finding.ReviewedAt = &now
+	}
+	if err := s.db.Save(&finding).Error; err != nil {
+		return nil, fmt.Errorf("failed to update moderation finding: %w", err)
+	}
+	return &finding, nil
+}
+
+// ScanForPublishing scans notes about to be made public for secrets and personal data, whatever their
+// organization's policy, and returns the notes with findings
+func (s *moderationServiceImpl) ScanForPublishing(noteIDs []string) ([]PublishScanResult, error) {
+	results := []PublishScanResult{}
+	if len(noteIDs) == 0 {
+		return results, nil
+	}
+
+	var notes []models.Notes
+	if err := s.db.Select("id", "name", "content", "organization_id").Where("id IN ?", noteIDs).Order("name ASC").Find(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notes: %w", err)
+	}
+	for _, note := range notes {
+		matches := ScanSensitiveContent(note.Name + "\n" + NoteModerationText(note.Content))
+		if len(matches) == 0 {
+			continue
+		}
+		for i := range matches {
+			matches[i].Blocked = true
+		}
+		results = append(results, PublishScanResult{NoteID: note.ID, NoteName: note.Name, OrganizationID: note.OrganizationID, Findings: matches})
+	}
+	return results, nil
+}
+
+// ScanSensitiveContent returns the secrets and personal data the patterns find in text, in order of appearance.
+// Where patterns overlap, the most specific one wins.
+func ScanSensitiveContent(text string) []ModerationMatch {
+	type span struct {
+		start, end, pattern int
+		value               string
+	}
+	var spans []span
+	for i, p := range moderationPatterns {
+		for _, indexes := range p.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := indexes[2*p.group], indexes[2*p.group+1]
+			if start < 0 {
+				continue
+			}
+			value := text[start:end]
+			if p.valid != nil && !p.valid(value) {
+				continue
+			}
+			spans = append(spans, span{start: indexes[0], end: indexes[1], pattern: i, value: value})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].pattern != spans[j].pattern {
+			return spans[i].pattern < spans[j].pattern
+		}
+		return spans[i].start < spans[j].start
+	})
+
+	// Keep the spans of earlier patterns, dropping anything they overlap
+	var kept []span
+	for _, candidate := range spans {
+		overlaps := false
+		for _, existing := range kept {
+			if candidate.start < existing.end && existing.start < candidate.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, candidate)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].start < kept[j].start })
+
+	matches := make([]ModerationMatch, 0, len(kept))
+	for _, s := range kept {
+		p := moderationPatterns[s.pattern]
+		matches = append(matches, newModerationMatch(p.category, p.kind, models.ModerationDetectorPattern, s.value))
+	}
+	return matches
+}
+
+// NoteModerationText returns the text of note content for scanning, as markdown for TipTap documents
+func NoteModerationText(content string) string {
+	if markdown, err := utils.TipTapToMarkdown(content); err == nil && markdown != "" {
+		return markdown
+	}
+	return content
+}
+
+// newModerationMatch builds a match from a sensitive value, masking it and keeping its fingerprint
+func newModerationMatch(category, kind, detector, value string) ModerationMatch {
+	sum := sha256.Sum256([]byte(category + ":" + value))
+	return ModerationMatch{
+		Category:    category,
+		Kind:        kind,
+		Detector:    detector,
+		Excerpt:     maskSensitiveValue(value),
+		fingerprint: hex.EncodeToString(sum[:]),
+	}
+}
+
+// maskSensitiveValue keeps enough of a value to recognize it, e.g. AKIA********
+func maskSensitiveValue(value string) string {
+	runes := []rune(value)
+	keep := 1
+	if len(runes) >= 16 {
+		keep = 4
+	}
+	if len(runes) <= keep {
+		return strings.Repeat("*", len(runes))
+	}
+	masked := len(runes) - keep
+	if masked > 8 {
+		masked = 8
+	}
+	return string(runes[:keep]) + strings.Repeat("*", masked)
+}
+
+// moderationAction returns what the policy does with a category
+func moderationAction(policy *models.OrganizationModerationPolicy, category string) string {
+	switch category {
+	case models.ModerationCategorySecret:
+		return policy.SecretsAction
+	case models.ModerationCategoryPII:
+		return policy.PIIAction
+	}
+	return models.ModerationActionOff
+}
+
+// passesLuhn reports whether a card-like number has a valid Luhn checksum
+func passesLuhn(value string) bool {
+	sum, digits := 0, 0
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAnnotationNotFound is returned when an annotation doesn't exist on the note
+	ErrAnnotationNotFound = errors.New("annotation not found")
+	// ErrInvalidAnnotation is returned when an annotation's range or fields are invalid
+	ErrInvalidAnnotation = errors.New("invalid annotation")
+	// ErrNoteNotClipped is returned when annotating a note that wasn't clipped from the web
+	ErrNoteNotClipped = errors.New("only notes clipped from the web can be annotated")
+)
+
+const (
+	maxAnnotationComment   = 5000
+	maxAnnotationsPerNote  = 500
+	defaultAnnotationColor = "yellow"
+	// highlightsChapterName is the chapter highlight digests are saved into
+	highlightsChapterName = "Highlights"
+)
+
+// NoteAnnotationInput is a new annotation, or the changes to one. The range is only read when creating,
+// the quoted text is taken from the note.
+type NoteAnnotationInput struct {
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+	Comment string `json:"comment"`
+	Color   string `json:"color"`
+}
+
+// AnchoredNoteAnnotation is an annotation with its range in the note's current content. Annotations whose
+// passage was edited away aren't anchored.
+type AnchoredNoteAnnotation struct {
+	models.NoteAnnotation
+	Anchored bool `json:"anchored"`
+}
+
+// NoteAnnotationService interface defines methods for highlights and margin notes on clipped notes
+type NoteAnnotationService interface {
+	List(ctx context.Context, noteID string) ([]AnchoredNoteAnnotation, error)
+	Create(ctx context.Context, noteID string, input NoteAnnotationInput, clerkUserID string) (*AnchoredNoteAnnotation, error)
+	Update(ctx context.Context, noteID, annotationID string, input NoteAnnotationInput) (*models.NoteAnnotation, error)
+	Delete(ctx context.Context, noteID, annotationID string) error
+	PublicAnnotations(ctx context.Context, noteID, content string) ([]AnchoredNoteAnnotation, error)
+	NotebookDigest(ctx context.Context, notebookID string) (*models.Notes, error)
+}
+
+// noteAnnotationServiceImpl implements the NoteAnnotationService interface
+type noteAnnotationServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewNoteAnnotationService creates a new NoteAnnotationService instance
+func NewNoteAnnotationService() NoteAnnotationService {
+	return &noteAnnotationServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// List returns a note's annotations in document order, anchored to its current content
+func (s *noteAnnotationServiceImpl) List(ctx context.Context, noteID string) ([]AnchoredNoteAnnotation, error) {
+	note, err := s.readableNote(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+
+	var annotations []models.NoteAnnotation
+	if err := db.Replica(s.db).WithContext(ctx).Where("note_id = ?", noteID).Order("created_at ASC").Find(&annotations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch annotations: %w", err)
+	}
+	return anchorAnnotations(note.Content, annotations)
+}
+
+// Create highlights a range of a clipped note. The range must cover text within one paragraph or heading.
+func (s *noteAnnotationServiceImpl) Create(ctx context.Context, noteID string, input NoteAnnotationInput, clerkUserID string) (*AnchoredNoteAnnotation, error) {
+	note, err := s.readableNote(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	var clipped int64
+	if err := s.db.WithContext(ctx).Model(&models.ReadingListItem{}).Where("note_id = ?", noteID).Count(&clipped).Error; err != nil {
+		return nil, fmt.Errorf("failed to check note source: %w", err)
+	}
+	if clipped == 0 {
+		return nil, ErrNoteNotClipped
+	}
+
+	annotation := models.NoteAnnotation{NoteID: noteID, ClerkUserID: clerkUserID, From: input.From, To: input.To}
+	if err := applyAnnotationInput(&annotation, input); err != nil {
+		return nil, err
+	}
+	doc, err := translatableTipTapDoc(note.Content)
+	if err != nil {
+		return nil, err
+	}
+	if annotation.Quote = annotationQuote(collectLintBlocks(doc.Content, 0, nil), input.From, input.To); annotation.Quote == "" {
+		return nil, fmt.Errorf("%w: the range must cover text within one paragraph or heading", ErrInvalidAnnotation)
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.NoteAnnotation{}).Where("note_id = ?", noteID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count annotations: %w", err)
+	}
+	if count >= maxAnnotationsPerNote {
+		return nil, fmt.Errorf("%w: a note can have at most %d annotations", ErrInvalidAnnotation, maxAnnotationsPerNote)
+	}
+	if err := s.db.WithContext(ctx).Create(&annotation).Error; err != nil {
+		return nil, fmt.Errorf("failed to save annotation: %w", err)
+	}
+	return &AnchoredNoteAnnotation{NoteAnnotation: annotation, Anchored: true}, nil
+}
+
+// Update changes an annotation's margin note and color
+func (s *noteAnnotationServiceImpl) Update(ctx context.Context, noteID, annotationID string, input NoteAnnotationInput) (*models.NoteAnnotation, error) {
+	var annotation models.NoteAnnotation
+	err := s.db.WithContext(ctx).Where("id = ? AND note_id = ?", annotationID, noteID).First(&annotation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAnnotationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch annotation: %w", err)
+	}
+
+	if err := applyAnnotationInput(&annotation, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(&annotation).Updates(map[string]interface{}{
+		"comment": annotation.Comment,
+		"color":   annotation.Color,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+	return &annotation, nil
+}
+
+// Delete removes an annotation from a note
+func (s *noteAnnotationServiceImpl) Delete(ctx context.Context, noteID, annotationID string) error {
+	result := s.db.WithContext(ctx).Where("id = ? AND note_id = ?", annotationID, noteID).Delete(&models.NoteAnnotation{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete annotation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAnnotationNotFound
+	}
+	return nil
+}
+
+// PublicAnnotations returns the annotations to show readers of a published note, anchored to the content
+// as it's shown. Annotations that no longer anchor are left out, and so is who made them.
+func (s *noteAnnotationServiceImpl) PublicAnnotations(ctx context.Context, noteID, content string) ([]AnchoredNoteAnnotation, error) {
+	var annotations []models.NoteAnnotation
+	if err := db.Replica(s.db).WithContext(ctx).Where("note_id = ?", noteID).Order("created_at ASC").Find(&annotations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch annotations: %w", err)
+	}
+	if len(annotations) == 0 {
+		return nil, nil
+	}
+
+	anchored, err := anchorAnnotations(content, annotations)
+	if err != nil {
+		return nil, err
+	}
+	shown := make([]AnchoredNoteAnnotation, 0, len(anchored))
+	for _, annotation := range anchored {
+		if annotation.Anchored {
+			annotation.ClerkUserID = ""
+			shown = append(shown, annotation)
+		}
+	}
+	return shown, nil
+}
+
+// NotebookDigest collects the highlights and margin notes of every note in a notebook into a new note in
+// the notebook's Highlights chapter. Locked notes are left out.
+func (s *noteAnnotationServiceImpl) NotebookDigest(ctx context.Context, notebookID string) (*models.Notes, error) {
+	var notebook models.Notebook
+	err := s.db.WithContext(ctx).Where("id = ?", notebookID).First(&notebook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotebookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	var annotations []models.NoteAnnotation
+	if err := s.db.WithContext(ctx).Select("note_annotations.*").Preload("Note").
+		Joins("JOIN notes ON notes.id = note_annotations.note_id").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ? AND notes.locked = ?", notebookID, false).
+		Order("chapters.created_at ASC, notes.created_at ASC, note_annotations.created_at ASC").
+		Find(&annotations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch annotations: %w", err)
+	}
+	if len(annotations) == 0 {
+		return nil, fmt.Errorf("%w: the notebook has no highlights", ErrInvalidAnnotation)
+	}
+
+	// Each note's highlights follow in the order they appear in the note
+	var groups [][]AnchoredNoteAnnotation
+	names := make(map[string]string)
+	for start := 0; start < len(annotations); {
+		end := start
+		for end < len(annotations) && annotations[end].NoteID == annotations[start].NoteID {
+			end++
+		}
+		anchored, err := anchorAnnotations(annotations[start].Note.Content, annotations[start:end])
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, anchored)
+		names[annotations[start].NoteID] = annotations[start].Note.Name
+		start = end
+	}
+
+	var sources []models.ReadingListItem
+	noteIDs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		noteIDs = append(noteIDs, group[0].NoteID)
+	}
+	if err := s.db.WithContext(ctx).Select("note_id", "url").Where("note_id IN ?", noteIDs).Find(&sources).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note sources: %w", err)
+	}
+	sourceURLs := make(map[string]string, len(sources))
+	for _, source := range sources {
+		sourceURLs[*source.NoteID] = source.URL
+	}
+
+	content, err := highlightDigestContent(groups, names, sourceURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	note := models.Notes{
+		Name:           "Highlights - " + s.now().Format("Jan 2, 2006"),
+		Content:        AssignTipTapBlockIDs("", content),
+		OrganizationID: notebook.OrganizationID,
+		Status:         models.NoteStatusDraft,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		chapter := models.Chapter{Name: highlightsChapterName, NotebookID: notebook.ID, OrganizationID: notebook.OrganizationID}
+		if err := tx.Where("notebook_id = ? AND name = ?", notebook.ID, highlightsChapterName).FirstOrCreate(&chapter).Error; err != nil {
+			return fmt.Errorf("failed to create highlights chapter: %w", err)
+		}
+		note.ChapterID = chapter.ID
+		if err := tx.Create(&note).Error; err != nil {
+			return fmt.Errorf("failed to create highlights note: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// readableNote fetches a note the server can read the content of
+func (s *noteAnnotationServiceImpl) readableNote(ctx context.Context, noteID string) (*models.Notes, error) {
+	var note models.Notes
+	err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.Locked {
+		return nil, ErrNoteLocked
+	}
+	if note.Chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+	return &note, nil
+}
+
+// applyAnnotationInput validates and sets an annotation's margin note and color
+func applyAnnotationInput(annotation *models.NoteAnnotation, input NoteAnnotationInput) error {
+	comment := strings.TrimSpace(input.Comment)
+	if len(comment) > maxAnnotationComment {
+		return fmt.Errorf("%w: margin notes can be at most %d characters", ErrInvalidAnnotation, maxAnnotationComment)
+	}
+	color := strings.ToLower(strings.TrimSpace(input.Color))
+	if color == "" {
+		color = defaultAnnotationColor
+	}
+	if !models.ValidAnnotationColors[color] {
+		return fmt.Errorf("%w: unknown color %q", ErrInvalidAnnotation, input.Color)
+	}
+	annotation.Comment = comment
+	annotation.Color = color
+	return nil
+}
+
+// annotationQuote returns the text between two ProseMirror positions, empty unless both fall in the text
+// of the same block
+func annotationQuote(blocks []lintBlock, from, to int) string {
+	if from >= to {
+		return ""
+	}
+	for _, block := range blocks {
+		start := -1
+		for i, pos := range block.positions {
+			if pos == from {
+				start = i
+			}
+			if start >= 0 {
+				if _, end := block.span(start, i+1); end == to {
+					if quote := string(block.text[start : i+1]); strings.TrimSpace(quote) != "" {
+						return quote
+					}
+					return ""
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// anchorAnnotations finds each annotation's quote in content, taking the occurrence nearest to where the
+// annotation was made. The results are in document order, with unanchored annotations last.
+func anchorAnnotations(content string, annotations []models.NoteAnnotation) ([]AnchoredNoteAnnotation, error) {
+	anchored := make([]AnchoredNoteAnnotation, 0, len(annotations))
+	if len(annotations) == 0 {
+		return anchored, nil
+	}
+	doc, err := translatableTipTapDoc(content)
+	if err != nil {
+		return nil, err
+	}
+	blocks := collectLintBlocks(doc.Content, 0, nil)
+
+	var orphaned []AnchoredNoteAnnotation
+	for _, annotation := range annotations {
+		quote := []rune(annotation.Quote)
+		best, bestDistance := AnchoredNoteAnnotation{NoteAnnotation: annotation}, -1
+		for _, block := range blocks {
+			for start := 0; start+len(quote) <= len(block.text); start++ {
+				if string(block.text[start:start+len(quote)]) != annotation.Quote {
+					continue
+				}
+				from, to := block.span(start, start+len(quote))
+				distance := from - annotation.From
+				if distance < 0 {
+					distance = -distance
+				}
+				if bestDistance < 0 || distance < bestDistance {
+					best.From, best.To, best.Anchored, bestDistance = from, to, true, distance
+				}
+			}
+		}
+		if best.Anchored {
+			anchored = append(anchored, best)
+		} else {
+			orphaned = append(orphaned, best)
+		}
+	}
+
+	// Insertion sort keeps annotations on the same passage in the order they were made
+	for i := 1; i < len(anchored); i++ {
+		for j := i; j > 0 && anchored[j].From < anchored[j-1].From; j-- {
+			anchored[j], anchored[j-1] = anchored[j-1], anchored[j]
+		}
+	}
+	return append(anchored, orphaned...), nil
+}
+
+// highlightDigestContent builds the TipTap document of a highlights digest, a section per note
+func highlightDigestContent(groups [][]AnchoredNoteAnnotation, names, sourceURLs map[string]string) (string, error) {
+	text := func(value string, marks ...utils.TipTapMark) utils.TipTapNode {
+		return utils.TipTapNode{Type: "text", Text: value, Marks: marks}
+	}
+
+	var nodes []utils.TipTapNode
+	for _, group := range groups {
+		noteID := group[0].NoteID
+		name := names[noteID]
+		if name == "" {
+			name = "Untitled"
+		}
+		nodes = append(nodes, utils.TipTapNode{Type: "heading", Attrs: map[string]interface{}{"level": 2}, Content: []utils.TipTapNode{text(name)}})
+		if source := sourceURLs[noteID]; source != "" {
+			nodes = append(nodes, utils.TipTapNode{Type: "paragraph", Content: []utils.TipTapNode{
+				text(source, utils.TipTapMark{Type: "link", Attrs: map[string]interface{}{"href": source}}),
+			}})
+		}
+		for _, annotation := range group {
+			nodes = append(nodes, utils.TipTapNode{Type: "blockquote", Content: []utils.TipTapNode{
+				{Type: "paragraph", Content: []utils.TipTapNode{text(annotation.Quote)}},
+			}})
+			if annotation.Comment != "" {
+				nodes = append(nodes, utils.TipTapNode{Type: "paragraph", Content: []utils.TipTapNode{
+					text(annotation.Comment, utils.TipTapMark{Type: "italic"}),
+				}})
+			}
+		}
+	}
+
+	content, err := json.Marshal(utils.TipTapDoc{Type: "doc", Content: nodes})
+	if err != nil {
+		return "", fmt.Errorf("failed to build digest content: %w", err)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteAnnotationService creates a note annotation service on an in-memory database
+func setupTestNoteAnnotationService(t *testing.T) *noteAnnotationServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{},
+		&models.ReadingListItem{}, &models.NoteAnnotation{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	now := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	return &noteAnnotationServiceImpl{db: db, now: func() time.Time { return now }}
+}
+
+func TestNoteAnnotations_AnchorAfterEdits(t *testing.T) {
+	service := setupTestNoteAnnotationService(t)
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	// Positions: the first paragraph's text starts at 1, the second's at 35
+	require.NoError(t, service.db.Model(&note).Update("content", tipTapParagraphs(t,
+		"Submit the expense report today.",
+		"Second paragraph here.",
+	)).Error)
+
+	_, err := service.Create(ctx, note.ID, NoteAnnotationInput{From: 12, To: 26}, "user_owner")
+	assert.ErrorIs(t, err, ErrNoteNotClipped)
+	require.NoError(t, service.db.Create(&models.ReadingListItem{
+		ClerkUserID: "user_owner", URL: "https://example.com/expenses", Source: models.ReadingListSourceClip, NoteID: &note.ID,
+	}).Error)
+
+	report, err := service.Create(ctx, note.ID, NoteAnnotationInput{From: 12, To: 26, Comment: " Due Fridays "}, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, "expense report", report.Quote)
+	assert.Equal(t, "Due Fridays", report.Comment)
+	assert.Equal(t, "yellow", report.Color)
+	second, err := service.Create(ctx, note.ID, NoteAnnotationInput{From: 35, To: 41, Color: "Green"}, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, "Second", second.Quote)
+
+	for name, input := range map[string]NoteAnnotationInput{
+		"across paragraphs": {From: 12, To: 41},
+		"empty":             {From: 12, To: 12},
+		"unknown color":     {From: 12, To: 26, Color: "orange"},
+	} {
+		_, err := service.Create(ctx, note.ID, input, "user_owner")
+		assert.ErrorIs(t, err, ErrInvalidAnnotation, name)
+	}
+
+	// Text added before the highlight moves it, and rewording a passage orphans its highlight
+	require.NoError(t, service.db.Model(&note).Update("content", tipTapParagraphs(t,
+		"Now: Submit the expense report today.",
+		"Another paragraph here.",
+	)).Error)
+	annotations, err := service.List(ctx, note.ID)
+	require.NoError(t, err)
+	require.Len(t, annotations, 2)
+	assert.Equal(t, report.ID, annotations[0].ID)
+	assert.True(t, annotations[0].Anchored)
+	assert.Equal(t, 17, annotations[0].From)
+	assert.Equal(t, 31, annotations[0].To)
+	assert.False(t, annotations[1].Anchored)
+
+	var stored models.Notes
+	require.NoError(t, service.db.First(&stored, "id = ?", note.ID).Error)
+	public, err := service.PublicAnnotations(ctx, note.ID, stored.Content)
+	require.NoError(t, err)
+	require.Len(t, public, 1, "Readers only see highlights that still anchor")
+	assert.Empty(t, public[0].ClerkUserID)
+
+	updated, err := service.Update(ctx, note.ID, second.ID, NoteAnnotationInput{Comment: "Reworded", Color: "blue"})
+	require.NoError(t, err)
+	assert.Equal(t, "blue", updated.Color)
+	require.NoError(t, service.Delete(ctx, note.ID, second.ID))
+	assert.ErrorIs(t, service.Delete(ctx, note.ID, second.ID), ErrAnnotationNotFound)
+}
+
+func TestNoteAnnotations_NotebookDigest(t *testing.T) {
+	service := setupTestNoteAnnotationService(t)
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	require.NoError(t, service.db.Model(&note).Update("content", tipTapParagraphs(t, "Submit the expense report today.")).Error)
+	require.NoError(t, service.db.Create(&models.ReadingListItem{
+		ClerkUserID: "user_owner", URL: "https://example.com/expenses", Source: models.ReadingListSourceClip, NoteID: &note.ID,
+	}).Error)
+
+	var chapter models.Chapter
+	require.NoError(t, service.db.First(&chapter, "id = ?", note.ChapterID).Error)
+	_, err := service.NotebookDigest(ctx, chapter.NotebookID)
+	assert.ErrorIs(t, err, ErrInvalidAnnotation, "Notebooks without highlights have no digest")
+
+	_, err = service.Create(ctx, note.ID, NoteAnnotationInput{From: 12, To: 26, Comment: "Due Fridays"}, "user_owner")
+	require.NoError(t, err)
+	_, err = service.Create(ctx, note.ID, NoteAnnotationInput{From: 1, To: 7}, "user_owner")
+	require.NoError(t, err)
+
+	digest, err := service.NotebookDigest(ctx, chapter.NotebookID)
+	require.NoError(t, err)
+	assert.Equal(t, "Highlights - Jun 2, 2025", digest.Name)
+	assert.Contains(t, digest.Content, `"text":"Expenses"`)
+	assert.Contains(t, digest.Content, `"href":"https://example.com/expenses"`)
+	assert.Contains(t, digest.Content, "Due Fridays")
+	assert.Less(t, strings.Index(digest.Content, `"text":"Submit"`), strings.Index(digest.Content, `"text":"expense report"`),
+		"Highlights follow the order of the note")
+
+	var highlights models.Chapter
+	require.NoError(t, service.db.First(&highlights, "id = ?", digest.ChapterID).Error)
+	assert.Equal(t, "Highlights", highlights.Name)
+	assert.Equal(t, chapter.NotebookID, highlights.NotebookID)
+
+	again, err := service.NotebookDigest(ctx, chapter.NotebookID)
+	require.NoError(t, err)
+	assert.Equal(t, digest.ChapterID, again.ChapterID, "Digests share the Highlights chapter")
+}
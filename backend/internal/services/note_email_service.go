@@ -0,0 +1,342 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"backend/pkg/email"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEmailNotConfigured is returned when the server has no SMTP server to send emails through
+	ErrEmailNotConfigured = errors.New("email is not configured on this server")
+	// ErrInvalidNoteEmail is returned for emails without valid recipients, or with a subject, message or
+	// attachments too large
+	ErrInvalidNoteEmail = errors.New("invalid email")
+	// ErrNoteEmailLocked is returned for locked notes, whose content the server can't read
+	ErrNoteEmailLocked = errors.New("locked notes can't be emailed")
+	// ErrNoteEmailRateLimited is returned when the sender reached their hourly email or daily recipient limit
+	ErrNoteEmailRateLimited = errors.New("too many emails sent, try again later")
+	// ErrNoteEmailNotSent is returned when the SMTP server didn't accept the email
+	ErrNoteEmailNotSent = errors.New("the email could not be sent")
+)
+
+const (
+	maxNoteEmailRecipients      = 20
+	maxNoteEmailSubjectLength   = 200
+	maxNoteEmailMessageLength   = 2000
+	maxNoteEmailAttachmentBytes = 20 << 20
+	noteEmailTimeout            = 30 * time.Second
+	maxNoteEmailHistory         = 100
+)
+
+// Note email attachments
+const (
+	NoteEmailAttachmentPDF      = "pdf"
+	NoteEmailAttachmentDocx     = "docx"
+	NoteEmailAttachmentMarkdown = "markdown"
+)
+
+// NoteEmailRequest is the request body for emailing a note. The note is the body of the email, and
+// can also be attached as a PDF, Word document or markdown file.
+type NoteEmailRequest struct {
+	Recipients      []string `json:"recipients"`
+	Subject         string   `json:"subject"` // Defaults to the note's name
+	Message         string   `json:"message"` // Shown above the note
+	IncludePDF      bool     `json:"includePdf"`
+	IncludeDocx     bool     `json:"includeDocx"`
+	IncludeMarkdown bool     `json:"includeMarkdown"`
+}
+
+// NoteEmailSender is the user emailing a note. Replies go to their address.
+type NoteEmailSender struct {
+	UserID string
+	Name   string
+	Email  string
+}
+
+// NoteEmailService interface defines methods for emailing notes
+type NoteEmailService interface {
+	Send(ctx context.Context, noteID string, sender NoteEmailSender, req NoteEmailRequest) (*models.NoteEmail, error)
+	ListEmails(ctx context.Context, noteID string) ([]models.NoteEmail, error)
+}
+
+// noteEmailServiceImpl implements the NoteEmailService interface
+type noteEmailServiceImpl struct {
+	db         *gorm.DB
+	config     *config.EmailConfig
+	send       func(ctx context.Context, msg *email.Message) error
+	exportPDF  func(ctx context.Context, noteID string) ([]byte, error)
+	exportDocx func(ctx context.Context, noteID string) ([]byte, error)
+	diagrams   DiagramService // Renders diagram code blocks as SVGs, skipped when nil
+	now        func() time.Time
+}
+
+// NewNoteEmailService creates a new NoteEmailService instance
+func NewNoteEmailService() NoteEmailService {
+	cfg := config.LoadEmailConfig()
+	client := email.NewClient(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, noteEmailTimeout)
+	return &noteEmailServiceImpl{
+		db:     db.DB,
+		config: cfg,
+		send:   client.Send,
+		exportPDF: func(ctx context.Context, noteID string) ([]byte, error) {
+			export, err := NewPDFExportService().ExportNote(ctx, noteID, PDFExportOptions{PageSize: defaultPDFPageSize})
+			if err != nil {
+				return nil, err
+			}
+			return export.PDF, nil
+		},
+		exportDocx: func(ctx context.Context, noteID string) ([]byte, error) {
+			export, err := NewDocxExportService().ExportNote(ctx, noteID)
+			if err != nil {
+				return nil, err
+			}
+			return export.Data, nil
+		},
+		diagrams: NewDiagramService(),
+		now:      time.Now,
+	}
+}
+
+// Send emails a note rendered as HTML to its recipients, with the attachments asked for, and records
+// what was sent to whom
+func (s *noteEmailServiceImpl) Send(ctx context.Context, noteID string, sender NoteEmailSender, req NoteEmailRequest) (*models.NoteEmail, error) {
+	if !s.config.IsConfigured() {
+		return nil, ErrEmailNotConfigured
+	}
+	recipients, err := parseNoteEmailRecipients(req.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	req.Subject = strings.Join(strings.Fields(req.Subject), " ")
+	req.Message = strings.TrimSpace(req.Message)
+	if len(req.Subject) > maxNoteEmailSubjectLength {
+		return nil, fmt.Errorf("%w: subject must be at most %d characters", ErrInvalidNoteEmail, maxNoteEmailSubjectLength)
+	}
+	if len(req.Message) > maxNoteEmailMessageLength {
+		return nil, fmt.Errorf("%w: message must be at most %d characters", ErrInvalidNoteEmail, maxNoteEmailMessageLength)
+	}
+
+	var note models.Notes
+	if err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.Chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+	if note.Locked {
+		return nil, ErrNoteEmailLocked
+	}
+	if err := s.checkRateLimits(ctx, sender.UserID, len(recipients)); err != nil {
+		return nil, err
+	}
+
+	if req.Subject == "" {
+		req.Subject = note.Name
+	}
+	msg, attachments, err := s.message(ctx, note, sender, recipients, req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNoteEmail, err)
+	}
+
+	record := &models.NoteEmail{
+		NoteID:         note.ID,
+		SentBy:         sender.UserID,
+		Recipients:     strings.Join(recipients, ", "),
+		RecipientCount: len(recipients),
+		Subject:        req.Subject,
+		Message:        req.Message,
+		Attachments:    strings.Join(attachments, ","),
+		NoteUpdatedAt:  note.UpdatedAt,
+		Size:           len(data),
+		Status:         models.NoteEmailStatusSent,
+		CreatedAt:      s.now(),
+	}
+	sendErr := s.send(ctx, msg)
+	if sendErr != nil {
+		record.Status = models.NoteEmailStatusFailed
+		record.Error = sendErr.Error()
+	}
+	// The record is kept even if the request was canceled while sending
+	if err := s.db.WithContext(context.WithoutCancel(ctx)).Create(record).Error; err != nil {
+		log.Error().Err(err).Str("note_id", note.ID).Str("user_id", sender.UserID).Msg("Failed to record note email")
+	}
+	if sendErr != nil {
+		log.Warn().Err(sendErr).Str("note_id", note.ID).Str("user_id", sender.UserID).Msg("Failed to email note")
+		return record, fmt.Errorf("%w: %v", ErrNoteEmailNotSent, sendErr)
+	}
+
+	log.Info().Str("note_id", note.ID).Str("user_id", sender.UserID).Int("recipients", len(recipients)).
+		Strs("attachments", attachments).Msg("Note emailed")
+	return record, nil
+}
+
+// ListEmails returns the latest emails of a note, newest first
+func (s *noteEmailServiceImpl) ListEmails(ctx context.Context, noteID string) ([]models.NoteEmail, error) {
+	var emails []models.NoteEmail
+	if err := s.db.WithContext(ctx).Where("note_id = ?", noteID).Order("created_at DESC").
+		Limit(maxNoteEmailHistory).Find(&emails).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note emails: %w", err)
+	}
+	return emails, nil
+}
+
+// checkRateLimits refuses emails beyond the sender's hourly email limit, or that would reach more
+// recipients in a day than their daily limit. Failed emails count too, they reached the SMTP server.
+func (s *noteEmailServiceImpl) checkRateLimits(ctx context.Context, userID string, recipients int) error {
+	now := s.now()
+	var lastHour int64
+	if err := s.db.WithContext(ctx).Model(&models.NoteEmail{}).
+		Where("sent_by = ? AND created_at > ?", userID, now.Add(-time.Hour)).Count(&lastHour).Error; err != nil {
+		return fmt.Errorf("failed to count sent emails: %w", err)
+	}
+	if int(lastHour) >= s.config.HourlyLimit {
+		return fmt.Errorf("%w: at most %d emails can be sent an hour", ErrNoteEmailRateLimited, s.config.HourlyLimit)
+	}
+
+	var lastDay int
+	if err := s.db.WithContext(ctx).Model(&models.NoteEmail{}).Select("COALESCE(SUM(recipient_count), 0)").
+		Where("sent_by = ? AND created_at > ?", userID, now.Add(-24*time.Hour)).Scan(&lastDay).Error; err != nil {
+		return fmt.Errorf("failed to count emailed recipients: %w", err)
+	}
+	if lastDay+recipients > s.config.DailyRecipientLimit {
+		return fmt.Errorf("%w: at most %d recipients can be emailed a day", ErrNoteEmailRateLimited, s.config.DailyRecipientLimit)
+	}
+	return nil
+}
+
+// message builds the email of a note and returns it with the kinds of attachments it holds
+func (s *noteEmailServiceImpl) message(ctx context.Context, note models.Notes, sender NoteEmailSender, recipients []string, req NoteEmailRequest) (*email.Message, []string, error) {
+	senderName := sender.Name
+	if senderName == "" {
+		senderName = sender.Email
+	}
+	msg := &email.Message{
+		From:    mail.Address{Name: s.config.FromName, Address: s.config.From},
+		To:      recipients,
+		Subject: req.Subject,
+	}
+	if senderName != "" {
+		msg.From.Name = senderName + " via " + s.config.FromName
+	}
+	if _, err := mail.ParseAddress(sender.Email); err == nil {
+		msg.ReplyTo = &mail.Address{Name: sender.Name, Address: sender.Email}
+	}
+
+	doc := exportNoteDocument(note.Content)
+	if s.diagrams != nil {
+		s.diagrams.RenderDocument(ctx, &doc)
+	}
+	var body, text strings.Builder
+	body.WriteString(`<div style="font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;font-size:15px;line-height:1.5;color:#18181b;max-width:680px">`)
+	if req.Message != "" {
+		fmt.Fprintf(&body, `<p style="margin:0 0 16px;padding:12px;background:#f4f4f5;border-radius:4px">%s</p>`,
+			strings.ReplaceAll(html.EscapeString(req.Message), "\n", "<br>"))
+		text.WriteString(req.Message + "\n\n---\n\n")
+	}
+	fmt.Fprintf(&body, `<h1 style="margin:0 0 16px">%s</h1>`, html.EscapeString(note.Name))
+	body.WriteString(utils.TipTapToHTML(doc))
+	fmt.Fprintf(&body, `<p style="margin:24px 0 0;font-size:12px;color:#71717a">%s</p></div>`,
+		html.EscapeString(noteEmailFooter(note, senderName)))
+	msg.HTML = body.String()
+
+	markdown, err := utils.TipTapToMarkdown(note.Content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert note to markdown: %w", err)
+	}
+	text.WriteString("# " + note.Name + "\n\n" + strings.TrimSpace(markdown) + "\n\n" + noteEmailFooter(note, senderName) + "\n")
+	msg.Text = text.String()
+
+	filename := models.Slugify(note.Name)
+	if filename == "" {
+		filename = "note"
+	}
+	var attachments []string
+	size := 0
+	attach := func(kind, extension, contentType string, data []byte) error {
+		size += len(data)
+		if size > maxNoteEmailAttachmentBytes {
+			return fmt.Errorf("%w: attachments must be at most %d MB", ErrInvalidNoteEmail, maxNoteEmailAttachmentBytes>>20)
+		}
+		msg.Attachments = append(msg.Attachments, email.Attachment{Name: filename + extension, ContentType: contentType, Data: data})
+		attachments = append(attachments, kind)
+		return nil
+	}
+	if req.IncludePDF {
+		data, err := s.exportPDF(ctx, note.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to export note as PDF: %w", err)
+		}
+		if err := attach(NoteEmailAttachmentPDF, ".pdf", "application/pdf", data); err != nil {
+			return nil, nil, err
+		}
+	}
+	if req.IncludeDocx {
+		data, err := s.exportDocx(ctx, note.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to export note as Word document: %w", err)
+		}
+		if err := attach(NoteEmailAttachmentDocx, ".docx", DocxContentType, data); err != nil {
+			return nil, nil, err
+		}
+	}
+	if req.IncludeMarkdown {
+		if err := attach(NoteEmailAttachmentMarkdown, ".md", "text/markdown; charset=utf-8", []byte(markdown)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return msg, attachments, nil
+}
+
+// noteEmailFooter says who sent the note and where it's from
+func noteEmailFooter(note models.Notes, senderName string) string {
+	footer := "From " + note.Chapter.Notebook.Name + " / " + note.Chapter.Name
+	if senderName != "" {
+		footer = "Sent by " + senderName + " · " + footer
+	}
+	return footer
+}
+
+// parseNoteEmailRecipients validates recipient addresses and returns them without duplicates
+func parseNoteEmailRecipients(values []string) ([]string, error) {
+	seen := make(map[string]bool, len(values))
+	recipients := make([]string, 0, len(values))
+	for _, value := range values {
+		address, err := mail.ParseAddress(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not an email address", ErrInvalidNoteEmail, value)
+		}
+		key := strings.ToLower(address.Address)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		recipients = append(recipients, address.Address)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%w: at least one recipient is required", ErrInvalidNoteEmail)
+	}
+	if len(recipients) > maxNoteEmailRecipients {
+		return nil, fmt.Errorf("%w: at most %d recipients can be emailed at once", ErrInvalidNoteEmail, maxNoteEmailRecipients)
+	}
+	return recipients, nil
+}
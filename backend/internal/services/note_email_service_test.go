@@ -0,0 +1,148 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/pkg/email"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteEmailService creates a note email service that keeps the emails it sends in sent
+func setupTestNoteEmailService(t *testing.T) (*noteEmailServiceImpl, *[]*email.Message) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteEmail{}),
+		"Failed to migrate test database")
+
+	var sent []*email.Message
+	return &noteEmailServiceImpl{
+		db: db,
+		config: &config.EmailConfig{
+			SMTPHost: "smtp.example.com", SMTPPort: 587, From: "notes@example.com", FromName: "Notes",
+			HourlyLimit: 3, DailyRecipientLimit: 5,
+		},
+		send: func(ctx context.Context, msg *email.Message) error {
+			sent = append(sent, msg)
+			return nil
+		},
+		exportPDF:  func(ctx context.Context, noteID string) ([]byte, error) { return []byte("%PDF-1.4"), nil },
+		exportDocx: func(ctx context.Context, noteID string) ([]byte, error) { return []byte("PK"), nil },
+		now:        func() time.Time { return time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC) },
+	}, &sent
+}
+
+func createTestEmailNote(t *testing.T, db *gorm.DB) models.Notes {
+	notebook := models.Notebook{Name: "Handbook", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Engineering", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+	content := `{"type":"doc","content":[
+		{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Setup"}]},
+		{"type":"paragraph","content":[{"type":"text","text":"Run "},{"type":"text","marks":[{"type":"code"}],"text":"make <all>"},
+			{"type":"text","marks":[{"type":"link","attrs":{"href":"javascript:alert(1)"}}],"text":" now"}]},
+		{"type":"taskList","content":[{"type":"taskItem","attrs":{"checked":false},"content":[{"type":"paragraph","content":[{"type":"text","text":"Clone"}]}]}]}
+	]}`
+	note := models.Notes{Name: "Onboarding", Content: content, ChapterID: chapter.ID}
+	require.NoError(t, db.Create(&note).Error)
+	return note
+}
+
+func TestSendNoteEmail(t *testing.T) {
+	service, sent := setupTestNoteEmailService(t)
+	ctx := context.Background()
+	note := createTestEmailNote(t, service.db)
+	sender := NoteEmailSender{UserID: "user_1", Name: "Ada Lovelace", Email: "ada@example.com"}
+
+	record, err := service.Send(ctx, note.ID, sender, NoteEmailRequest{
+		Recipients:      []string{"Bob <bob@example.com>", "BOB@example.com", "carol@example.com"},
+		Message:         "Have a look\nbefore Monday",
+		IncludePDF:      true,
+		IncludeMarkdown: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, *sent, 1)
+	msg := (*sent)[0]
+
+	assert.Equal(t, []string{"bob@example.com", "carol@example.com"}, msg.To, "Recipients are deduplicated")
+	assert.Equal(t, "Onboarding", msg.Subject, "The subject defaults to the note's name")
+	assert.Equal(t, "Ada Lovelace via Notes", msg.From.Name)
+	assert.Equal(t, "notes@example.com", msg.From.Address)
+	require.NotNil(t, msg.ReplyTo)
+	assert.Equal(t, "ada@example.com", msg.ReplyTo.Address)
+	assert.Contains(t, msg.HTML, "Have a look<br>before Monday")
+	assert.Contains(t, msg.HTML, `<h2 style="margin:20px 0 8px">Setup</h2>`)
+	assert.Contains(t, msg.HTML, "make &lt;all&gt;", "Text is escaped")
+	assert.NotContains(t, msg.HTML, "javascript:", "Only http(s) and mailto links are kept")
+	assert.Contains(t, msg.HTML, "&#9744; Clone")
+	assert.Contains(t, msg.HTML, "Sent by Ada Lovelace · From Handbook / Engineering")
+	assert.Contains(t, msg.Text, "## Setup")
+	require.Len(t, msg.Attachments, 2)
+	assert.Equal(t, "onboarding.pdf", msg.Attachments[0].Name)
+	assert.Equal(t, "onboarding.md", msg.Attachments[1].Name)
+
+	assert.Equal(t, models.NoteEmailStatusSent, record.Status)
+	assert.Equal(t, "bob@example.com, carol@example.com", record.Recipients)
+	assert.Equal(t, "pdf,markdown", record.Attachments)
+	assert.Positive(t, record.Size)
+
+	emails, err := service.ListEmails(ctx, note.ID)
+	require.NoError(t, err)
+	require.Len(t, emails, 1)
+	assert.Equal(t, record.ID, emails[0].ID)
+
+	// Failed emails are recorded too
+	service.send = func(ctx context.Context, msg *email.Message) error { return errors.New("550 mailbox unavailable") }
+	record, err = service.Send(ctx, note.ID, sender, NoteEmailRequest{Recipients: []string{"dave@example.com"}, Subject: "Docs"})
+	assert.ErrorIs(t, err, ErrNoteEmailNotSent)
+	require.NotNil(t, record)
+	assert.Equal(t, models.NoteEmailStatusFailed, record.Status)
+	assert.Equal(t, "550 mailbox unavailable", record.Error)
+
+	_, err = service.Send(ctx, note.ID, sender, NoteEmailRequest{Recipients: []string{"not an address"}})
+	assert.ErrorIs(t, err, ErrInvalidNoteEmail)
+	_, err = service.Send(ctx, note.ID, sender, NoteEmailRequest{})
+	assert.ErrorIs(t, err, ErrInvalidNoteEmail)
+
+	require.NoError(t, service.db.Model(&note).Update("locked", true).Error)
+	_, err = service.Send(ctx, note.ID, sender, NoteEmailRequest{Recipients: []string{"bob@example.com"}})
+	assert.ErrorIs(t, err, ErrNoteEmailLocked)
+
+	service.config.SMTPHost = ""
+	_, err = service.Send(ctx, note.ID, sender, NoteEmailRequest{Recipients: []string{"bob@example.com"}})
+	assert.ErrorIs(t, err, ErrEmailNotConfigured)
+}
+
+func TestSendNoteEmail_RateLimits(t *testing.T) {
+	service, sent := setupTestNoteEmailService(t)
+	ctx := context.Background()
+	note := createTestEmailNote(t, service.db)
+	sender := NoteEmailSender{UserID: "user_1"}
+
+	_, err := service.Send(ctx, note.ID, sender, NoteEmailRequest{Recipients: []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"}})
+	require.NoError(t, err)
+	_, err = service.Send(ctx, note.ID, sender, NoteEmailRequest{Recipients: []string{"e@example.com", "f@example.com"}})
+	assert.ErrorIs(t, err, ErrNoteEmailRateLimited, "The daily recipient limit is 5")
+
+	_, err = service.Send(ctx, note.ID, sender, NoteEmailRequest{Recipients: []string{"e@example.com"}})
+	require.NoError(t, err)
+	_, err = service.Send(ctx, note.ID, NoteEmailSender{UserID: "user_2"}, NoteEmailRequest{Recipients: []string{"a@example.com"}})
+	require.NoError(t, err, "Limits are per sender")
+
+	// The next day only the hourly limit of 3 emails applies
+	service.now = func() time.Time { return time.Date(2026, 5, 5, 12, 30, 0, 0, time.UTC) }
+	for i := 0; i < 3; i++ {
+		_, err = service.Send(ctx, note.ID, sender, NoteEmailRequest{Recipients: []string{"a@example.com"}})
+		require.NoError(t, err)
+	}
+	_, err = service.Send(ctx, note.ID, sender, NoteEmailRequest{Recipients: []string{"a@example.com"}})
+	assert.ErrorIs(t, err, ErrNoteEmailRateLimited)
+	assert.Len(t, *sent, 6)
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// maxEmbedDepth limits how many levels of embeds inside embeds are resolved when rendering
+const maxEmbedDepth = 5
+
+// ErrEmbedCycle is returned when a note would end up embedding itself, directly or through other notes
+var ErrEmbedCycle = errors.New("embedding this note would create a cycle")
+
+// noteEmbedRef is a noteEmbed node found in a note's content
+type noteEmbedRef struct {
+	NoteID  string
+	BlockID string
+}
+
+// NoteEmbedUsage is a note that embeds another note
+type NoteEmbedUsage struct {
+	NoteID       string    `json:"noteId"`
+	Name         string    `json:"name"`
+	ChapterID    string    `json:"chapterId"`
+	NotebookID   string    `json:"notebookId"`
+	NotebookName string    `json:"notebookName"`
+	BlockID      string    `json:"blockId"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// NoteEmbedService interface defines methods for tracking transcluded notes and rendering them live
+type NoteEmbedService interface {
+	ValidateEmbeds(noteID, content string) error
+	SyncEmbeds(noteID, content, createdBy string) error
+	RenderContent(noteID, content string, canView func(note *models.Notes) bool) (string, error)
+	ListEmbeddedIn(noteID string) ([]NoteEmbedUsage, error)
+}
+
+// noteEmbedServiceImpl implements the NoteEmbedService interface
+type noteEmbedServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewNoteEmbedService creates a new NoteEmbedService instance
+func NewNoteEmbedService() NoteEmbedService {
+	return &noteEmbedServiceImpl{
+		db: db.DB,
+	}
+}
+
+// ValidateEmbeds checks that the embeds in a note's new content don't create a cycle
+func (s *noteEmbedServiceImpl) ValidateEmbeds(noteID, content string) error {
+	refs := extractNoteEmbeds(content)
+	if len(refs) == 0 {
+		return nil
+	}
+
+	// Walk what the embedded notes embed. The note's own current embeds are being replaced, so they're skipped.
+	visited := make(map[string]bool)
+	queue := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if ref.NoteID == noteID {
+			return ErrEmbedCycle
+		}
+		if !visited[ref.NoteID] {
+			visited[ref.NoteID] = true
+			queue = append(queue, ref.NoteID)
+		}
+	}
+
+	for len(queue) > 0 {
+		var targets []string
+		if err := s.db.Model(&models.NoteEmbed{}).
+			Where("source_note_id IN ? AND source_note_id <> ?", queue, noteID).
+			Distinct().
+			Pluck("target_note_id", &targets).Error; err != nil {
+			return fmt.Errorf("failed to fetch note embeds: %w", err)
+		}
+
+		queue = queue[:0]
+		for _, target := range targets {
+			if target == noteID {
+				return ErrEmbedCycle
+			}
+			if !visited[target] {
+				visited[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+	return nil
+}
+
+// SyncEmbeds replaces the recorded embeds of a note with those in its content
+func (s *noteEmbedServiceImpl) SyncEmbeds(noteID, content, createdBy string) error {
+	if err := s.ValidateEmbeds(noteID, content); err != nil {
+		return err
+	}
+
+	var note models.Notes
+	if err := s.db.Select("id", "organization_id").Where("id = ?", noteID).First(&note).Error; err != nil {
+		return fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	refs := extractNoteEmbeds(content)
+	targetIDs := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		targetIDs = append(targetIDs, ref.NoteID)
+	}
+	existing := make(map[string]bool)
+	if len(targetIDs) > 0 {
+		var ids []string
+		if err := s.db.Model(&models.Notes{}).Where("id IN ?", targetIDs).Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("failed to fetch embedded notes: %w", err)
+		}
+		for _, id := range ids {
+			existing[id] = true
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_note_id = ?", noteID).Delete(&models.NoteEmbed{}).Error; err != nil {
+			return fmt.Errorf("failed to clear note embeds: %w", err)
+		}
+		for _, ref := range refs {
+			// Embeds of deleted notes stay in the content and render as unavailable
+			if !existing[ref.NoteID] {
+				continue
+			}
+			embed := models.NoteEmbed{
+				ID:             uuid.New().String(),
+				SourceNoteID:   noteID,
+				TargetNoteID:   ref.NoteID,
+				BlockID:        ref.BlockID,
+				OrganizationID: note.OrganizationID,
+				CreatedBy:      createdBy,
+			}
+			if err := tx.Create(&embed).Error; err != nil {
+				return fmt.Errorf("failed to save note embed: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// RenderContent resolves the noteEmbed nodes of a note's content into the current content of the embedded
+// blocks. Embeds the viewer can't see, of missing notes or blocks, and cycles are left empty and marked
+// unavailable. Content that isn't a TipTap document is returned unchanged.
+func (s *noteEmbedServiceImpl) RenderContent(noteID, content string, canView func(note *models.Notes) bool) (string, error) {
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" || !strings.Contains(content, models.NoteEmbedNodeType) {
+		return content, nil
+	}
+
+	renderer := &embedRenderer{db: s.db, canView: canView, notes: make(map[string]*models.Notes)}
+	doc.Content = renderer.resolve(doc.Content, []string{noteID})
+
+	rendered, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode rendered content: %w", err)
+	}
+	return string(rendered), nil
+}
+
+// ListEmbeddedIn returns the notes that embed a note, most recently updated first
+func (s *noteEmbedServiceImpl) ListEmbeddedIn(noteID string) ([]NoteEmbedUsage, error) {
+	usages := []NoteEmbedUsage{}
+	if err := s.db.Table("note_embeds").
+		Select("notes.id AS note_id, notes.name, notes.chapter_id, chapters.notebook_id, notebooks.name AS notebook_name, "+
+			"note_embeds.block_id, notes.updated_at").
+		Joins("JOIN notes ON notes.id = note_embeds.source_note_id").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("note_embeds.target_note_id = ?", noteID).
+		Order("notes.updated_at DESC").
+		Scan(&usages).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note embeds: %w", err)
+	}
+	return usages, nil
+}
+
+// embedRenderer resolves embeds for one render, caching the notes it loads
+type embedRenderer struct {
+	db      *gorm.DB
+	canView func(note *models.Notes) bool
+	notes   map[string]*models.Notes
+}
+
+// resolve fills in the noteEmbed nodes among nodes. path holds the notes being rendered, outermost first.
+func (r *embedRenderer) resolve(nodes []utils.TipTapNode, path []string) []utils.TipTapNode {
+	for i, node := range nodes {
+		if node.Type != models.NoteEmbedNodeType {
+			if len(node.Content) > 0 {
+				nodes[i].Content = r.resolve(node.Content, path)
+			}
+			continue
+		}
+		nodes[i] = r.resolveEmbed(node, path)
+	}
+	return nodes
+}
+
+// resolveEmbed replaces an embed node's content with the embedded block
+func (r *embedRenderer) resolveEmbed(node utils.TipTapNode, path []string) utils.TipTapNode {
+	ref, ok := noteEmbedRefFromNode(node)
+	attrs := make(map[string]interface{}, len(node.Attrs)+2)
+	for key, value := range node.Attrs {
+		attrs[key] = value
+	}
+	node.Attrs = attrs
+	node.Content = nil
+
+	unavailable := func(reason string) utils.TipTapNode {
+		node.Attrs["unavailable"] = reason
+		return node
+	}
+	if !ok {
+		return unavailable("invalid")
+	}
+	if containsString(path, ref.NoteID) {
+		return unavailable("cycle")
+	}
+	if len(path) > maxEmbedDepth {
+		return unavailable("depth")
+	}
+
+	note := r.note(ref.NoteID)
+	if note == nil || (r.canView != nil && !r.canView(note)) {
+		return unavailable("not_found")
+	}
+
+	var embedded utils.TipTapDoc
+	if err := json.Unmarshal([]byte(note.Content), &embedded); err != nil || embedded.Type != "doc" {
+		return unavailable("not_found")
+	}
+	block := selectEmbedBlock(embedded.Content, ref.BlockID)
+	if len(block) == 0 {
+		return unavailable("block_not_found")
+	}
+
+	node.Attrs["title"] = note.Name
+	node.Attrs["chapterId"] = note.ChapterID
+	node.Attrs["updatedAt"] = note.UpdatedAt
+	node.Content = r.resolve(block, append(append([]string{}, path...), ref.NoteID))
+	return node
+}
+
+// note loads a note once per render
+func (r *embedRenderer) note(noteID string) *models.Notes {
+	if note, ok := r.notes[noteID]; ok {
+		return note
+	}
+	var note models.Notes
+	if err := r.db.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to load embedded note")
+		}
+		r.notes[noteID] = nil
+		return nil
+	}
+	r.notes[noteID] = &note
+	return &note
+}
+
+// extractNoteEmbeds returns the distinct embeds in TipTap content
+func extractNoteEmbeds(content string) []noteEmbedRef {
+	if !strings.Contains(content, models.NoteEmbedNodeType) {
+		return nil
+	}
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return nil
+	}
+
+	var refs []noteEmbedRef
+	seen := make(map[noteEmbedRef]bool)
+	var walk func(nodes []utils.TipTapNode)
+	walk = func(nodes []utils.TipTapNode) {
+		for _, node := range nodes {
+			if node.Type == models.NoteEmbedNodeType {
+				// The content of an embed node is a rendered copy, embeds inside it belong to the embedded note
+				if ref, ok := noteEmbedRefFromNode(node); ok && !seen[ref] {
+					seen[ref] = true
+					refs = append(refs, ref)
+				}
+				continue
+			}
+			walk(node.Content)
+		}
+	}
+	walk(doc.Content)
+	return refs
+}
+
+// noteEmbedRefFromNode reads the embedded note and block from an embed node's attributes
+func noteEmbedRefFromNode(node utils.TipTapNode) (noteEmbedRef, bool) {
+	noteID, _ := node.Attrs["noteId"].(string)
+	blockID, _ := node.Attrs["blockId"].(string)
+	noteID = strings.TrimSpace(noteID)
+	return noteEmbedRef{NoteID: noteID, BlockID: strings.TrimSpace(blockID)}, noteID != ""
+}
+
+// selectEmbedBlock returns the nodes an embed shows: the whole note without a block ID, otherwise the
+// node with that id attribute or the section under the heading with that slug
+func selectEmbedBlock(nodes []utils.TipTapNode, blockID string) []utils.TipTapNode {
+	if blockID == "" {
+		return nodes
+	}
+
+	if node, ok := findNodeByID(nodes, blockID); ok {
+		return []utils.TipTapNode{node}
+	}
+
+	for i, node := range nodes {
+		if node.Type != "heading" || models.Slugify(tiptapNodeText(node)) != blockID {
+			continue
+		}
+		level := headingLevel(node)
+		end := i + 1
+		for end < len(nodes) && !(nodes[end].Type == "heading" && headingLevel(nodes[end]) <= level) {
+			end++
+		}
+		return nodes[i:end]
+	}
+	return nil
+}
+
+// findNodeByID finds a node by its id attribute
+func findNodeByID(nodes []utils.TipTapNode, id string) (utils.TipTapNode, bool) {
+	for _, node := range nodes {
+		if nodeID, _ := node.Attrs["id"].(string); nodeID == id {
+			return node, true
+		}
+		if found, ok := findNodeByID(node.Content, id); ok {
+			return found, true
+		}
+	}
+	return utils.TipTapNode{}, false
+}
+
+// headingLevel returns a heading node's level
+func headingLevel(node utils.TipTapNode) int {
+	if level, ok := node.Attrs["level"].(float64); ok {
+		return int(level)
+	}
+	return 1
+}
+
+// tiptapNodeText returns the plain text of a node
+func tiptapNodeText(node utils.TipTapNode) string {
+	if node.Type == "text" {
+		return node.Text
+	}
+	var builder strings.Builder
+	for _, child := range node.Content {
+		builder.WriteString(tiptapNodeText(child))
+	}
+	return builder.String()
+}
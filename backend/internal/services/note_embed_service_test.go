@@ -0,0 +1,133 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteEmbedService creates a note embed service on an in-memory database
+func setupTestNoteEmbedService(t *testing.T) *noteEmbedServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteEmbed{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &noteEmbedServiceImpl{db: db}
+}
+
+// createEmbedNote creates a note with the given content in its own notebook
+func createEmbedNote(t *testing.T, db *gorm.DB, name, content string) models.Notes {
+	notebook := models.Notebook{Name: name + " notebook", ClerkUserID: "user_owner"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Chapter", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+	note := models.Notes{Name: name, Content: content, ChapterID: chapter.ID}
+	require.NoError(t, db.Create(&note).Error)
+	return note
+}
+
+// embedContent returns TipTap content with a paragraph and an embed of a note
+func embedContent(noteID, blockID string) string {
+	return fmt.Sprintf(`{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"See below"}]},`+
+		`{"type":"noteEmbed","attrs":{"noteId":%q,"blockId":%q}}]}`, noteID, blockID)
+}
+
+// renderedEmbed returns the embed node of rendered embedContent
+func renderedEmbed(t *testing.T, content string) utils.TipTapNode {
+	var doc utils.TipTapDoc
+	require.NoError(t, json.Unmarshal([]byte(content), &doc))
+	require.Len(t, doc.Content, 2)
+	return doc.Content[1]
+}
+
+func TestNoteEmbed_SyncAndListEmbeddedIn(t *testing.T) {
+	service := setupTestNoteEmbedService(t)
+	target := createEmbedNote(t, service.db, "Glossary", `{"type":"doc","content":[]}`)
+	host := createEmbedNote(t, service.db, "Onboarding", "")
+
+	require.NoError(t, service.SyncEmbeds(host.ID, embedContent(target.ID, "terms"), "user_owner"))
+	require.NoError(t, service.SyncEmbeds(host.ID, embedContent(target.ID, "terms"), "user_owner"), "Syncing again should replace the embeds")
+
+	usages, err := service.ListEmbeddedIn(target.ID)
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, host.ID, usages[0].NoteID)
+	assert.Equal(t, "Onboarding notebook", usages[0].NotebookName)
+	assert.Equal(t, "terms", usages[0].BlockID)
+
+	require.NoError(t, service.SyncEmbeds(host.ID, `{"type":"doc","content":[]}`, "user_owner"))
+	usages, err = service.ListEmbeddedIn(target.ID)
+	require.NoError(t, err)
+	assert.Empty(t, usages, "Removing the embed from the content should remove it")
+}
+
+func TestNoteEmbed_DetectsCycles(t *testing.T) {
+	service := setupTestNoteEmbedService(t)
+	a := createEmbedNote(t, service.db, "A", "")
+	b := createEmbedNote(t, service.db, "B", "")
+	c := createEmbedNote(t, service.db, "C", "")
+
+	assert.ErrorIs(t, service.ValidateEmbeds(a.ID, embedContent(a.ID, "")), ErrEmbedCycle, "A note can't embed itself")
+
+	require.NoError(t, service.SyncEmbeds(a.ID, embedContent(b.ID, ""), "user_owner"))
+	require.NoError(t, service.SyncEmbeds(b.ID, embedContent(c.ID, ""), "user_owner"))
+	assert.ErrorIs(t, service.SyncEmbeds(c.ID, embedContent(a.ID, ""), "user_owner"), ErrEmbedCycle)
+
+	// The embeds a note is replacing are not part of the check
+	assert.NoError(t, service.ValidateEmbeds(a.ID, embedContent(c.ID, "")))
+	assert.ErrorIs(t, service.ValidateEmbeds(c.ID, embedContent(a.ID, "")), ErrEmbedCycle)
+}
+
+func TestNoteEmbed_RenderResolvesBlocks(t *testing.T) {
+	service := setupTestNoteEmbedService(t)
+	target := createEmbedNote(t, service.db, "Runbook", `{"type":"doc","content":[`+
+		`{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Deploy Steps"}]},`+
+		`{"type":"paragraph","content":[{"type":"text","text":"Run make deploy"}]},`+
+		`{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Rollback"}]},`+
+		`{"type":"paragraph","attrs":{"id":"rollback-cmd"},"content":[{"type":"text","text":"Run make rollback"}]}]}`)
+	host := createEmbedNote(t, service.db, "Release", "")
+
+	rendered, err := service.RenderContent(host.ID, embedContent(target.ID, "deploy-steps"), nil)
+	require.NoError(t, err)
+	embed := renderedEmbed(t, rendered)
+	assert.Equal(t, "Runbook", embed.Attrs["title"])
+	require.Len(t, embed.Content, 2, "A heading embeds its section")
+	assert.Equal(t, "Run make deploy", tiptapNodeText(embed.Content[1]))
+
+	rendered, err = service.RenderContent(host.ID, embedContent(target.ID, "rollback-cmd"), nil)
+	require.NoError(t, err)
+	embed = renderedEmbed(t, rendered)
+	require.Len(t, embed.Content, 1)
+	assert.Equal(t, "Run make rollback", tiptapNodeText(embed.Content[0]))
+
+	rendered, err = service.RenderContent(host.ID, embedContent(target.ID, "missing"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "block_not_found", renderedEmbed(t, rendered).Attrs["unavailable"])
+
+	rendered, err = service.RenderContent(host.ID, embedContent(target.ID, ""), func(note *models.Notes) bool { return false })
+	require.NoError(t, err)
+	embed = renderedEmbed(t, rendered)
+	assert.Equal(t, "not_found", embed.Attrs["unavailable"])
+	assert.Empty(t, embed.Content, "Embeds the viewer can't see should not leak content")
+}
+
+func TestNoteEmbed_RenderStopsAtCycles(t *testing.T) {
+	service := setupTestNoteEmbedService(t)
+	a := createEmbedNote(t, service.db, "A", "")
+	b := createEmbedNote(t, service.db, "B", embedContent(a.ID, ""))
+
+	rendered, err := service.RenderContent(a.ID, embedContent(b.ID, ""), nil)
+	require.NoError(t, err)
+	outer := renderedEmbed(t, rendered)
+	require.Len(t, outer.Content, 2)
+	assert.Equal(t, "cycle", outer.Content[1].Attrs["unavailable"])
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	maxNoteFeedbackCommentLength = 500
+	// maxNoteFeedbackPerReader caps the feedback one reader leaves on a note per noteFeedbackWindow
+	maxNoteFeedbackPerReader = 5
+	noteFeedbackWindow       = 24 * time.Hour
+	// maxRecentNoteFeedbackComments is the number of latest comments returned per note
+	maxRecentNoteFeedbackComments = 20
+)
+
+var (
+	// ErrInvalidNoteFeedback is returned for feedback without a reaction or comment, or with an unknown reaction
+	ErrInvalidNoteFeedback = errors.New("invalid feedback")
+	// ErrNoteFeedbackLimitReached is returned when a reader left too much feedback on a note recently
+	ErrNoteFeedbackLimitReached = errors.New("you've left enough feedback on this note for today")
+	// ErrPublicNoteNotFound is returned when a note doesn't exist or isn't published
+	ErrPublicNoteNotFound = errors.New("note not found or not public")
+)
+
+// NoteFeedbackInput is the request body for leaving feedback on a public note
+type NoteFeedbackInput struct {
+	Reaction string `json:"reaction"`
+	Comment  string `json:"comment"`
+	// Website is a honeypot field hidden from readers. Bots filling it in are told their feedback was
+	// received, but it isn't stored.
+	Website string `json:"website"`
+}
+
+// NoteFeedbackComment is a reader's comment on a note
+type NoteFeedbackComment struct {
+	Comment   string    `json:"comment"`
+	Reaction  string    `json:"reaction,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NoteFeedbackSummary aggregates the feedback readers left on a note
+type NoteFeedbackSummary struct {
+	NoteID         string                `json:"noteId"`
+	NoteName       string                `json:"noteName"`
+	ChapterID      string                `json:"chapterId"`
+	Reactions      map[string]int        `json:"reactions"`
+	CommentCount   int                   `json:"commentCount"`
+	RecentComments []NoteFeedbackComment `json:"recentComments"`
+	LastFeedbackAt time.Time             `json:"lastFeedbackAt"`
+}
+
+// noteFeedbackRow is a feedback entry with the note it was left on
+type noteFeedbackRow struct {
+	NoteID    string
+	NoteName  string
+	ChapterID string
+	Reaction  string
+	Comment   string
+	CreatedAt time.Time
+}
+
+// NoteFeedbackService interface defines methods for reader feedback on public notes
+type NoteFeedbackService interface {
+	SubmitFeedback(ctx context.Context, noteID, clientIP string, input NoteFeedbackInput) error
+	NotebookFeedback(ctx context.Context, notebookID string) ([]NoteFeedbackSummary, error)
+}
+
+// noteFeedbackServiceImpl implements the NoteFeedbackService interface
+type noteFeedbackServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewNoteFeedbackService creates a new NoteFeedbackService instance
+func NewNoteFeedbackService() NoteFeedbackService {
+	return &noteFeedbackServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// SubmitFeedback stores a reader's anonymous reaction and/or comment on a published note. Readers are told
+// apart by a hash of their IP and the note only, so their feedback can't be linked across notes.
+func (s *noteFeedbackServiceImpl) SubmitFeedback(ctx context.Context, noteID, clientIP string, input NoteFeedbackInput) error {
+	if strings.TrimSpace(input.Website) != "" {
+		log.Debug().Str("note_id", noteID).Msg("Dropped note feedback caught by the honeypot")
+		return nil
+	}
+
+	reaction := strings.TrimSpace(input.Reaction)
+	if reaction != "" && !containsString(models.NoteReactions, reaction) {
+		return fmt.Errorf("%w: reaction must be one of %s", ErrInvalidNoteFeedback, strings.Join(models.NoteReactions, " "))
+	}
+	comment := strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' {
+			return -1
+		}
+		return r
+	}, input.Comment))
+	if utf8.RuneCountInString(comment) > maxNoteFeedbackCommentLength {
+		return fmt.Errorf("%w: comments can be at most %d characters", ErrInvalidNoteFeedback, maxNoteFeedbackCommentLength)
+	}
+	if reaction == "" && comment == "" {
+		return fmt.Errorf("%w: leave a reaction or a comment", ErrInvalidNoteFeedback)
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Table("notes").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notes.id = ? AND notes.is_public = ? AND notes.locked = ? AND chapters.is_public = ? AND notebooks.is_public = ?",
+			noteID, true, false, true, true).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if count == 0 {
+		return ErrPublicNoteNotFound
+	}
+
+	sum := sha256.Sum256([]byte(noteID + ":" + clientIP))
+	readerHash := hex.EncodeToString(sum[:])
+	now := s.now()
+	var recent int64
+	if err := s.db.WithContext(ctx).Model(&models.NoteFeedback{}).
+		Where("note_id = ? AND reader_hash = ? AND created_at > ?", noteID, readerHash, now.Add(-noteFeedbackWindow)).
+		Count(&recent).Error; err != nil {
+		return fmt.Errorf("failed to count recent feedback: %w", err)
+	}
+	if recent >= maxNoteFeedbackPerReader {
+		return ErrNoteFeedbackLimitReached
+	}
+
+	feedback := models.NoteFeedback{
+		NoteID:     noteID,
+		Reaction:   reaction,
+		Comment:    comment,
+		ReaderHash: readerHash,
+		CreatedAt:  now,
+	}
+	if err := s.db.WithContext(ctx).Create(&feedback).Error; err != nil {
+		return fmt.Errorf("failed to save feedback: %w", err)
+	}
+	return nil
+}
+
+// NotebookFeedback aggregates the feedback on a notebook's notes, the notes with the latest feedback first
+func (s *noteFeedbackServiceImpl) NotebookFeedback(ctx context.Context, notebookID string) ([]NoteFeedbackSummary, error) {
+	var rows []noteFeedbackRow
+	if err := s.db.WithContext(ctx).Table("note_feedbacks").
+		Select("note_feedbacks.note_id, notes.name AS note_name, notes.chapter_id, note_feedbacks.reaction, "+
+			"note_feedbacks.comment, note_feedbacks.created_at").
+		Joins("JOIN notes ON notes.id = note_feedbacks.note_id").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ?", notebookID).
+		Order("note_feedbacks.created_at DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch feedback: %w", err)
+	}
+
+	summaries := make(map[string]*NoteFeedbackSummary)
+	var order []string
+	for _, row := range rows {
+		summary, exists := summaries[row.NoteID]
+		if !exists {
+			summary = &NoteFeedbackSummary{
+				NoteID:         row.NoteID,
+				NoteName:       row.NoteName,
+				ChapterID:      row.ChapterID,
+				Reactions:      make(map[string]int),
+				RecentComments: []NoteFeedbackComment{},
+				LastFeedbackAt: row.CreatedAt,
+			}
+			summaries[row.NoteID] = summary
+			order = append(order, row.NoteID)
+		}
+		if row.Reaction != "" {
+			summary.Reactions[row.Reaction]++
+		}
+		if row.Comment != "" {
+			summary.CommentCount++
+			if len(summary.RecentComments) < maxRecentNoteFeedbackComments {
+				summary.RecentComments = append(summary.RecentComments, NoteFeedbackComment{
+					Comment:   row.Comment,
+					Reaction:  row.Reaction,
+					CreatedAt: row.CreatedAt,
+				})
+			}
+		}
+	}
+
+	result := make([]NoteFeedbackSummary, 0, len(order))
+	for _, noteID := range order {
+		result = append(result, *summaries[noteID])
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteFeedbackService creates a note feedback service on an in-memory database
+func setupTestNoteFeedbackService(t *testing.T) *noteFeedbackServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteFeedback{}),
+		"Failed to migrate test database")
+
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	return &noteFeedbackServiceImpl{
+		db: db,
+		now: func() time.Time {
+			now = now.Add(time.Minute)
+			return now
+		},
+	}
+}
+
+func TestSubmitFeedback(t *testing.T) {
+	service := setupTestNoteFeedbackService(t)
+	ctx := context.Background()
+	notebook, note := createPublishedNotebook(t, service.db)
+
+	require.NoError(t, service.SubmitFeedback(ctx, note.ID, "10.0.0.1", NoteFeedbackInput{Reaction: models.NoteReactionThumbsUp}))
+	require.NoError(t, service.SubmitFeedback(ctx, note.ID, "10.0.0.2", NoteFeedbackInput{
+		Reaction: models.NoteReactionThinking,
+		Comment:  "  Which checklist?\x07 ",
+	}))
+
+	err := service.SubmitFeedback(ctx, note.ID, "10.0.0.1", NoteFeedbackInput{Reaction: "🍕"})
+	assert.ErrorIs(t, err, ErrInvalidNoteFeedback)
+	err = service.SubmitFeedback(ctx, note.ID, "10.0.0.1", NoteFeedbackInput{Comment: "   "})
+	assert.ErrorIs(t, err, ErrInvalidNoteFeedback)
+	err = service.SubmitFeedback(ctx, note.ID, "10.0.0.1", NoteFeedbackInput{Comment: strings.Repeat("a", maxNoteFeedbackCommentLength+1)})
+	assert.ErrorIs(t, err, ErrInvalidNoteFeedback)
+
+	require.NoError(t, service.SubmitFeedback(ctx, note.ID, "10.0.0.3", NoteFeedbackInput{
+		Reaction: models.NoteReactionThumbsDown,
+		Website:  "http://spam.example.com",
+	}), "Bots filling in the honeypot are told it worked")
+
+	var private models.Notes
+	require.NoError(t, service.db.Where("name = ?", "Deploy drafts").First(&private).Error)
+	err = service.SubmitFeedback(ctx, private.ID, "10.0.0.1", NoteFeedbackInput{Reaction: models.NoteReactionThumbsUp})
+	assert.ErrorIs(t, err, ErrPublicNoteNotFound)
+
+	summaries, err := service.NotebookFeedback(ctx, notebook.ID)
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	summary := summaries[0]
+	assert.Equal(t, note.ID, summary.NoteID)
+	assert.Equal(t, map[string]int{models.NoteReactionThumbsUp: 1, models.NoteReactionThinking: 1}, summary.Reactions, "Honeypot feedback isn't stored")
+	assert.Equal(t, 1, summary.CommentCount)
+	require.Len(t, summary.RecentComments, 1)
+	assert.Equal(t, "Which checklist?", summary.RecentComments[0].Comment)
+}
+
+func TestSubmitFeedback_LimitsEachReader(t *testing.T) {
+	service := setupTestNoteFeedbackService(t)
+	ctx := context.Background()
+	_, note := createPublishedNotebook(t, service.db)
+
+	for i := 0; i < maxNoteFeedbackPerReader; i++ {
+		require.NoError(t, service.SubmitFeedback(ctx, note.ID, "10.0.0.1", NoteFeedbackInput{Reaction: models.NoteReactionHeart}))
+	}
+	err := service.SubmitFeedback(ctx, note.ID, "10.0.0.1", NoteFeedbackInput{Reaction: models.NoteReactionHeart})
+	assert.ErrorIs(t, err, ErrNoteFeedbackLimitReached)
+	assert.NoError(t, service.SubmitFeedback(ctx, note.ID, "10.0.0.2", NoteFeedbackInput{Reaction: models.NoteReactionHeart}),
+		"Other readers aren't limited")
+
+	later := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return later }
+	assert.NoError(t, service.SubmitFeedback(ctx, note.ID, "10.0.0.1", NoteFeedbackInput{Reaction: models.NoteReactionHeart}),
+		"The limit resets after a day")
+}
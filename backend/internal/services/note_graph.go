@@ -0,0 +1,633 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/models/dto"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrGraphClusterNotFound is returned when a cluster isn't in the graph, usually because links changed
+// since the clusters were fetched
+var ErrGraphClusterNotFound = errors.New("graph cluster not found")
+
+const (
+	// maxGraphCommunityRounds bounds the passes over the nodes at each level of community detection,
+	// which settles in a few on real graphs
+	maxGraphCommunityRounds = 20
+	// maxGraphCommunityLevels bounds how many times communities are merged and moved again
+	maxGraphCommunityLevels = 10
+	// graphClusterTopNotes is how many of its notes a cluster shows before it's expanded
+	graphClusterTopNotes = 5
+	// graphClusterIDPrefix starts cluster IDs, which are otherwise the ID of the cluster's most linked note
+	graphClusterIDPrefix = "cluster-"
+	// defaultGraphNeighborhoodDepth is how many links from a note its neighbourhood reaches by default
+	defaultGraphNeighborhoodDepth = 2
+	// maxGraphNeighborhoodDepth bounds neighbourhoods, a few links out most of a workspace is in reach
+	maxGraphNeighborhoodDepth = 5
+	// graphNoteColumns are the columns of a graphNote, selected from notes joined with their chapter and notebook
+	graphNoteColumns = "notes.id, notes.name, notes.chapter_id, chapters.name AS chapter_name, " +
+		"notebooks.id AS notebook_id, notebooks.name AS notebook_name, notes.created_at, notes.updated_at"
+)
+
+// GraphQuery selects the part of a workspace's graph to return
+type GraphQuery struct {
+	ClerkUserID    string
+	OrganizationID *string // Nil for the user's personal notebooks
+	Search         string  // Keeps the notes whose name or content contains it
+	NotebookID     string
+	ChapterID      string
+	Tags           []string  // Keeps the notes with any of the tags
+	ModifiedSince  time.Time // Keeps the notes updated since, when set
+	Origins        []string  // Keeps the links made these ways, see models.LinkOrigin constants
+	Limit          int       // Keeps the most linked notes, 0 for all
+}
+
+// graphNote is a linked note, without its content
+type graphNote struct {
+	ID           string
+	Name         string
+	ChapterID    string
+	ChapterName  string
+	NotebookID   string
+	NotebookName string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// graphLink is a link between two notes of the graph
+type graphLink struct {
+	ID           string
+	SourceNoteID string
+	TargetNoteID string
+	LinkType     string
+	Annotation   string
+	Origin       string
+	UpdatedAt    time.Time
+}
+
+// graphCluster is a community of the graph, its members most linked first
+type graphCluster struct {
+	id        string
+	members   []int
+	linkCount int
+}
+
+// noteGraph is the link graph of a query, with its notes grouped into clusters. Notes without links
+// aren't in it.
+type noteGraph struct {
+	notes     []graphNote // By ID
+	links     []graphLink
+	index     map[string]int // Note ID to its position in notes
+	degree    []int
+	adjacency [][]int // Positions of each note's linked notes, once per link
+	clusterOf []int   // Position in clusters of each note
+	clusters  []graphCluster
+}
+
+// GetGraphData returns the notes of the graph and the links between them. With a limit only the most
+// linked notes are returned, the links between them and the total to show how many were left out.
+func (s *NoteLinkService) GetGraphData(query GraphQuery) (*dto.GraphData, error) {
+	cursor := time.Now().UTC()
+	graph, err := s.loadNoteGraph(query)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]int, len(graph.notes))
+	for i := range kept {
+		kept[i] = i
+	}
+	if query.Limit > 0 && query.Limit < len(kept) {
+		slices.SortStableFunc(kept, func(a, b int) int { return cmp.Compare(graph.degree[b], graph.degree[a]) })
+		kept = kept[:query.Limit]
+		slices.Sort(kept)
+	}
+
+	keptIDs := make(map[string]bool, len(kept))
+	nodes := make([]dto.GraphNode, 0, len(kept))
+	for _, i := range kept {
+		keptIDs[graph.notes[i].ID] = true
+		nodes = append(nodes, graph.node(i))
+	}
+	links := make([]dto.GraphLink, 0, len(graph.links))
+	for _, link := range graph.links {
+		if keptIDs[link.SourceNoteID] && keptIDs[link.TargetNoteID] {
+			links = append(links, toGraphLink(link))
+		}
+	}
+
+	return &dto.GraphData{
+		Nodes:      nodes,
+		Links:      links,
+		TotalNodes: len(graph.notes),
+		Cursor:     cursor,
+	}, nil
+}
+
+// GetClusteredGraph returns the graph with closely linked notes grouped into clusters, and the links
+// between clusters added up, for graphs too large to draw note by note
+func (s *NoteLinkService) GetClusteredGraph(query GraphQuery) (*dto.ClusteredGraphData, error) {
+	cursor := time.Now().UTC()
+	graph, err := s.loadNoteGraph(query)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]dto.GraphCluster, 0, len(graph.clusters))
+	for c := range graph.clusters {
+		clusters = append(clusters, graph.cluster(c))
+	}
+
+	weights := map[[2]int]int{}
+	for _, link := range graph.links {
+		source, target := graph.clusterOf[graph.index[link.SourceNoteID]], graph.clusterOf[graph.index[link.TargetNoteID]]
+		if source == target {
+			continue
+		}
+		weights[[2]int{min(source, target), max(source, target)}]++
+	}
+	links := make([]dto.GraphClusterLink, 0, len(weights))
+	for pair, weight := range weights {
+		links = append(links, dto.GraphClusterLink{
+			Source: graph.clusters[pair[0]].id,
+			Target: graph.clusters[pair[1]].id,
+			Weight: weight,
+		})
+	}
+	sortGraphClusterLinks(links)
+
+	return &dto.ClusteredGraphData{
+		Clusters:   clusters,
+		Links:      links,
+		TotalNodes: len(graph.notes),
+		TotalLinks: len(graph.links),
+		Cursor:     cursor,
+	}, nil
+}
+
+// GetGraphCluster expands a cluster of the graph into its notes, the links between them and the links
+// from them to other clusters. The query must be the one the clusters were fetched with.
+func (s *NoteLinkService) GetGraphCluster(query GraphQuery, clusterID string) (*dto.GraphClusterData, error) {
+	graph, err := s.loadNoteGraph(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c := slices.IndexFunc(graph.clusters, func(cluster graphCluster) bool { return cluster.id == clusterID })
+	if c < 0 {
+		return nil, ErrGraphClusterNotFound
+	}
+
+	members := slices.Clone(graph.clusters[c].members)
+	slices.Sort(members)
+	nodes := make([]dto.GraphNode, 0, len(members))
+	for _, i := range members {
+		nodes = append(nodes, graph.node(i))
+	}
+
+	links := []dto.GraphLink{}
+	weights := map[[2]int]int{} // Note of the cluster and the other cluster
+	for _, link := range graph.links {
+		source, target := graph.index[link.SourceNoteID], graph.index[link.TargetNoteID]
+		sourceIn, targetIn := graph.clusterOf[source] == c, graph.clusterOf[target] == c
+		switch {
+		case sourceIn && targetIn:
+			links = append(links, toGraphLink(link))
+		case sourceIn:
+			weights[[2]int{source, graph.clusterOf[target]}]++
+		case targetIn:
+			weights[[2]int{target, graph.clusterOf[source]}]++
+		}
+	}
+	externalLinks := make([]dto.GraphClusterLink, 0, len(weights))
+	for pair, weight := range weights {
+		externalLinks = append(externalLinks, dto.GraphClusterLink{
+			Source: graph.notes[pair[0]].ID,
+			Target: graph.clusters[pair[1]].id,
+			Weight: weight,
+		})
+	}
+	sortGraphClusterLinks(externalLinks)
+
+	return &dto.GraphClusterData{
+		Cluster:       graph.cluster(c),
+		Nodes:         nodes,
+		Links:         links,
+		ExternalLinks: externalLinks,
+	}, nil
+}
+
+// GetGraphChanges returns the notes and links of the graph that changed since a cursor from an earlier
+// call, with the IDs of every note and link so the caller can drop the ones removed since
+func (s *NoteLinkService) GetGraphChanges(query GraphQuery, since time.Time) (*dto.GraphChanges, error) {
+	// Taken before reading so a change made during the reads is sent again next time, not missed
+	cursor := time.Now().UTC()
+	graph, err := s.loadNoteGraph(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// A note's link count changes with its links
+	changed := make([]bool, len(graph.notes))
+	changes := &dto.GraphChanges{
+		Cursor:  cursor,
+		Nodes:   []dto.GraphNode{},
+		Links:   []dto.GraphLink{},
+		NodeIDs: make([]string, 0, len(graph.notes)),
+		LinkIDs: make([]string, 0, len(graph.links)),
+	}
+	for _, link := range graph.links {
+		changes.LinkIDs = append(changes.LinkIDs, link.ID)
+		if !link.UpdatedAt.Before(since) {
+			changes.Links = append(changes.Links, toGraphLink(link))
+			changed[graph.index[link.SourceNoteID]] = true
+			changed[graph.index[link.TargetNoteID]] = true
+		}
+	}
+	for i, note := range graph.notes {
+		changes.NodeIDs = append(changes.NodeIDs, note.ID)
+		if changed[i] || !note.UpdatedAt.Before(since) {
+			changes.Nodes = append(changes.Nodes, graph.node(i))
+		}
+	}
+
+	return changes, nil
+}
+
+// GetGraphNeighborhood returns the notes within depth links of a note, following links both ways, and the
+// links between them. The query's filters apply, so layers left out of its origins aren't followed.
+func (s *NoteLinkService) GetGraphNeighborhood(query GraphQuery, noteID string, depth int) (*dto.GraphNeighborhood, error) {
+	if depth <= 0 {
+		depth = defaultGraphNeighborhoodDepth
+	}
+	depth = min(depth, maxGraphNeighborhoodDepth)
+
+	graph, err := s.loadNoteGraph(query)
+	if err != nil {
+		return nil, err
+	}
+
+	neighborhood := &dto.GraphNeighborhood{
+		NoteID:    noteID,
+		Depth:     depth,
+		Nodes:     []dto.GraphNode{},
+		Links:     []dto.GraphLink{},
+		Distances: map[string]int{noteID: 0},
+	}
+
+	center, ok := graph.index[noteID]
+	if !ok {
+		// A note without links is all of its neighbourhood
+		var notes []graphNote
+		if err := s.scopedGraphNotes(query).Select(graphNoteColumns).Where("notes.id = ?", noteID).Scan(&notes).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch note: %w", err)
+		}
+		if len(notes) == 0 {
+			return nil, ErrNoteNotFound
+		}
+		neighborhood.Nodes = append(neighborhood.Nodes, toGraphNode(notes[0]))
+		return neighborhood, nil
+	}
+
+	distances := map[int]int{center: 0}
+	frontier := []int{center}
+	for distance := 1; distance <= depth && len(frontier) > 0; distance++ {
+		var next []int
+		for _, i := range frontier {
+			for _, neighbour := range graph.adjacency[i] {
+				if _, seen := distances[neighbour]; !seen {
+					distances[neighbour] = distance
+					next = append(next, neighbour)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	for _, i := range slices.Sorted(maps.Keys(distances)) {
+		neighborhood.Nodes = append(neighborhood.Nodes, graph.node(i))
+		neighborhood.Distances[graph.notes[i].ID] = distances[i]
+	}
+	for _, link := range graph.links {
+		_, sourceIn := distances[graph.index[link.SourceNoteID]]
+		_, targetIn := distances[graph.index[link.TargetNoteID]]
+		if sourceIn && targetIn {
+			neighborhood.Links = append(neighborhood.Links, toGraphLink(link))
+		}
+	}
+	return neighborhood, nil
+}
+
+// loadNoteGraph loads the linked notes of a query and the links between them, and clusters them
+func (s *NoteLinkService) loadNoteGraph(query GraphQuery) (*noteGraph, error) {
+	var notes []graphNote
+	if err := s.scopedGraphNotes(query).
+		Select(graphNoteColumns).
+		Order("notes.id").
+		Scan(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch graph notes: %w", err)
+	}
+
+	// Only links between notes of the query, the subqueries keep large graphs out of IN lists
+	linkQuery := db.Replica(s.db).Model(&models.NoteLink{}).
+		Select("id, source_note_id, target_note_id, link_type, annotation, origin, updated_at").
+		Where("source_note_id IN (?) AND target_note_id IN (?)",
+			s.scopedGraphNotes(query).Select("notes.id"), s.scopedGraphNotes(query).Select("notes.id"))
+	if len(query.Origins) > 0 {
+		linkQuery = linkQuery.Where("origin IN ?", query.Origins)
+	}
+	var links []graphLink
+	if err := linkQuery.Order("id").Scan(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch graph links: %w", err)
+	}
+
+	return buildNoteGraph(notes, links), nil
+}
+
+// scopedGraphNotes selects the notes of a query's workspace that match its filters
+func (s *NoteLinkService) scopedGraphNotes(query GraphQuery) *gorm.DB {
+	tx := db.Replica(s.db).Model(&models.Notes{}).
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id")
+	if query.OrganizationID != nil {
+		tx = tx.Where("notebooks.organization_id = ?", *query.OrganizationID)
+	} else {
+		tx = tx.Where("notebooks.clerk_user_id = ? AND (notebooks.organization_id IS NULL OR notebooks.organization_id = '')", query.ClerkUserID)
+	}
+	if query.NotebookID != "" {
+		tx = tx.Where("notebooks.id = ?", query.NotebookID)
+	}
+	if query.ChapterID != "" {
+		tx = tx.Where("chapters.id = ?", query.ChapterID)
+	}
+	if query.Search != "" {
+		tx = tx.Where("(notes.name LIKE ? OR notes.content LIKE ?)", "%"+query.Search+"%", "%"+query.Search+"%")
+	}
+	if len(query.Tags) > 0 {
+		tags := make([]string, len(query.Tags))
+		for i, tag := range query.Tags {
+			tags[i] = normalizeNoteTag(tag)
+		}
+		tx = tx.Where("notes.id IN (?)", db.Replica(s.db).Model(&models.NoteTag{}).Select("note_id").Where("tag IN ?", tags))
+	}
+	if !query.ModifiedSince.IsZero() {
+		tx = tx.Where("notes.updated_at >= ?", query.ModifiedSince)
+	}
+	return tx
+}
+
+// buildNoteGraph keeps the notes with links and groups them into clusters
+func buildNoteGraph(notes []graphNote, links []graphLink) *noteGraph {
+	linked := map[string]bool{}
+	for _, link := range links {
+		linked[link.SourceNoteID] = true
+		linked[link.TargetNoteID] = true
+	}
+
+	graph := &noteGraph{index: map[string]int{}}
+	for _, note := range notes {
+		if linked[note.ID] {
+			graph.index[note.ID] = len(graph.notes)
+			graph.notes = append(graph.notes, note)
+		}
+	}
+	graph.links = links
+
+	graph.degree = make([]int, len(graph.notes))
+	graph.adjacency = make([][]int, len(graph.notes))
+	for _, link := range links {
+		source, target := graph.index[link.SourceNoteID], graph.index[link.TargetNoteID]
+		graph.degree[source]++
+		graph.degree[target]++
+		graph.adjacency[source] = append(graph.adjacency[source], target)
+		graph.adjacency[target] = append(graph.adjacency[target], source)
+	}
+
+	// Group the notes by community, most linked first, and name each cluster after its most linked note
+	communities := detectGraphCommunities(graph.adjacency)
+	members := map[int][]int{}
+	for i, community := range communities {
+		members[community] = append(members[community], i)
+	}
+	for _, notes := range members {
+		slices.SortStableFunc(notes, func(a, b int) int { return cmp.Compare(graph.degree[b], graph.degree[a]) })
+		graph.clusters = append(graph.clusters, graphCluster{
+			id:      graphClusterIDPrefix + graph.notes[notes[0]].ID,
+			members: notes,
+		})
+	}
+	slices.SortFunc(graph.clusters, func(a, b graphCluster) int {
+		return cmp.Or(cmp.Compare(len(b.members), len(a.members)), cmp.Compare(a.id, b.id))
+	})
+
+	graph.clusterOf = make([]int, len(graph.notes))
+	for c, cluster := range graph.clusters {
+		for _, i := range cluster.members {
+			graph.clusterOf[i] = c
+		}
+	}
+	for _, link := range links {
+		if c := graph.clusterOf[graph.index[link.SourceNoteID]]; c == graph.clusterOf[graph.index[link.TargetNoteID]] {
+			graph.clusters[c].linkCount++
+		}
+	}
+
+	return graph
+}
+
+// detectGraphCommunities finds communities of closely linked nodes with the Louvain method: nodes move to
+// the neighbouring community that raises modularity most, then communities are merged into nodes and
+// moved again, until nothing moves. Nodes are visited in order and ties go to the current community, then
+// the smallest, so the same graph always gives the same communities. It returns the community of each
+// node, numbered from 0.
+func detectGraphCommunities(adjacency [][]int) []int {
+	// Weighted edges of the graph at the current level, with the links inside a merged node counted in
+	// both directions on its loop
+	weights := make([]map[int]int, len(adjacency))
+	for node, neighbours := range adjacency {
+		weights[node] = map[int]int{}
+		for _, neighbour := range neighbours {
+			weights[node][neighbour]++
+		}
+	}
+
+	communities := make([]int, len(adjacency))
+	for i := range communities {
+		communities[i] = i
+	}
+	for range maxGraphCommunityLevels {
+		moved, levelCommunities := moveGraphNodes(weights)
+		if !moved {
+			break
+		}
+
+		// Merge each community into a node of the next level
+		merged := make([]map[int]int, 0)
+		for node := range weights {
+			if levelCommunities[node] == len(merged) {
+				merged = append(merged, map[int]int{})
+			}
+		}
+		for node, edges := range weights {
+			for neighbour, weight := range edges {
+				merged[levelCommunities[node]][levelCommunities[neighbour]] += weight
+			}
+		}
+		for i, community := range communities {
+			communities[i] = levelCommunities[community]
+		}
+		weights = merged
+	}
+	return communities
+}
+
+// moveGraphNodes is one level of the Louvain method. It returns whether any node moved and the community
+// of each node, numbered from 0 in node order.
+func moveGraphNodes(weights []map[int]int) (bool, []int) {
+	community := make([]int, len(weights))
+	degree := make([]int, len(weights))
+	total := make([]int, len(weights)) // Degrees of each community's nodes added up
+	twiceEdges := 0
+	for node, edges := range weights {
+		community[node] = node
+		for _, weight := range edges {
+			degree[node] += weight
+		}
+		total[node] = degree[node]
+		twiceEdges += degree[node]
+	}
+	if twiceEdges == 0 {
+		return false, community
+	}
+
+	moved := false
+	shared := map[int]int{} // Weight of the node's edges into each neighbouring community
+	for range maxGraphCommunityRounds {
+		changed := false
+		for node, edges := range weights {
+			current := community[node]
+			total[current] -= degree[node]
+			clear(shared)
+			for neighbour, weight := range edges {
+				if neighbour != node {
+					shared[community[neighbour]] += weight
+				}
+			}
+
+			gain := func(c int) float64 {
+				return float64(shared[c]) - float64(total[c])*float64(degree[node])/float64(twiceEdges)
+			}
+			best, bestGain := current, gain(current)
+			for c := range shared {
+				if g := gain(c); g > bestGain || (g == bestGain && best != current && c < best) {
+					best, bestGain = c, g
+				}
+			}
+
+			total[best] += degree[node]
+			if best != current {
+				community[node] = best
+				changed, moved = true, true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// Number the communities from 0
+	numbers := map[int]int{}
+	for node, c := range community {
+		if _, ok := numbers[c]; !ok {
+			numbers[c] = len(numbers)
+		}
+		community[node] = numbers[c]
+	}
+	return moved, community
+}
+
+// node returns a note of the graph as a graph node
+func (g *noteGraph) node(i int) dto.GraphNode {
+	node := toGraphNode(g.notes[i])
+	node.LinkCount = g.degree[i]
+	node.ClusterID = g.clusters[g.clusterOf[i]].id
+	return node
+}
+
+// toGraphNode converts a note to a graph node, without its links
+func toGraphNode(note graphNote) dto.GraphNode {
+	node := dto.GraphNode{
+		ID:           note.ID,
+		Name:         note.Name,
+		ChapterName:  note.ChapterName,
+		NotebookName: note.NotebookName,
+		CreatedAt:    note.CreatedAt,
+		UpdatedAt:    note.UpdatedAt,
+		Metadata:     map[string]string{},
+	}
+	if note.NotebookID != "" {
+		node.Metadata["notebookId"] = note.NotebookID
+	}
+	if note.ChapterID != "" {
+		node.Metadata["chapterId"] = note.ChapterID
+	}
+	return node
+}
+
+// cluster returns a cluster of the graph with its most linked notes
+func (g *noteGraph) cluster(c int) dto.GraphCluster {
+	cluster := g.clusters[c]
+	hub := g.notes[cluster.members[0]]
+
+	// The notebook most of the cluster's notes are in, the more linked notes' on a tie
+	notebookCounts := map[string]int{}
+	notebookName := ""
+	for _, i := range cluster.members {
+		name := g.notes[i].NotebookName
+		notebookCounts[name]++
+		if notebookCounts[name] > notebookCounts[notebookName] {
+			notebookName = name
+		}
+	}
+
+	topNotes := make([]dto.GraphNode, 0, graphClusterTopNotes)
+	for _, i := range cluster.members[:min(len(cluster.members), graphClusterTopNotes)] {
+		topNotes = append(topNotes, g.node(i))
+	}
+
+	return dto.GraphCluster{
+		ID:           cluster.id,
+		Label:        hub.Name,
+		NotebookName: notebookName,
+		Size:         len(cluster.members),
+		LinkCount:    cluster.linkCount,
+		TopNotes:     topNotes,
+	}
+}
+
+// toGraphLink converts a link of the graph to its response
+func toGraphLink(link graphLink) dto.GraphLink {
+	return dto.GraphLink{
+		ID:         link.ID,
+		Source:     link.SourceNoteID,
+		Target:     link.TargetNoteID,
+		LinkType:   link.LinkType,
+		Origin:     link.Origin,
+		Annotation: link.Annotation,
+	}
+}
+
+// sortGraphClusterLinks orders cluster links heaviest first, so responses are stable
+func sortGraphClusterLinks(links []dto.GraphClusterLink) {
+	slices.SortFunc(links, func(a, b dto.GraphClusterLink) int {
+		return cmp.Or(cmp.Compare(b.Weight, a.Weight), cmp.Compare(a.Source, b.Source), cmp.Compare(a.Target, b.Target))
+	})
+}
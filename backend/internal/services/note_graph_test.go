@@ -0,0 +1,212 @@
+package services
+
+import (
+	"backend/internal/models"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestGraph creates a note link service on an in-memory database holding two groups of three
+// closely linked notes, a1-a3 and b1-b3, with one AI suggested link from a1 to b1. The a notes are tagged
+// #paper. Another user has linked notes too.
+func setupTestGraph(t *testing.T) *NoteLinkService {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteLink{}, &models.NoteTag{}))
+
+	for _, owner := range []string{"user1", "user2"} {
+		require.NoError(t, db.Create(&models.Notebook{ID: "nb-" + owner, Name: "Research", ClerkUserID: owner}).Error)
+		require.NoError(t, db.Create(&models.Chapter{ID: "ch-" + owner, Name: "Papers", NotebookID: "nb-" + owner}).Error)
+	}
+	for _, id := range []string{"a1", "a2", "a3", "b1", "b2", "b3"} {
+		require.NoError(t, db.Create(&models.Notes{ID: id, Name: "Note " + id, ChapterID: "ch-user1"}).Error)
+		if id[0] == 'a' {
+			require.NoError(t, db.Create(&models.NoteTag{NoteID: id, Tag: "paper"}).Error)
+		}
+	}
+	for _, id := range []string{"x1", "x2"} {
+		require.NoError(t, db.Create(&models.Notes{ID: id, Name: "Note " + id, ChapterID: "ch-user2"}).Error)
+	}
+
+	links := [][2]string{{"a1", "a2"}, {"a2", "a3"}, {"a3", "a1"}, {"b1", "b2"}, {"b2", "b3"}, {"b3", "b1"}, {"a1", "b1"}, {"x1", "x2"}}
+	for i, link := range links {
+		origin := models.LinkOriginManual
+		if link == [2]string{"a1", "b1"} {
+			origin = models.LinkOriginAI
+		}
+		require.NoError(t, db.Create(&models.NoteLink{
+			ID:           fmt.Sprintf("link%d", i),
+			SourceNoteID: link[0],
+			TargetNoteID: link[1],
+			LinkType:     models.LinkTypeReferences,
+			Origin:       origin,
+			CreatedBy:    "user1",
+		}).Error)
+	}
+
+	return &NoteLinkService{db: db}
+}
+
+func TestDetectGraphCommunities(t *testing.T) {
+	// Two triangles joined by one edge
+	adjacency := [][]int{{1, 2, 3}, {0, 2}, {0, 1}, {0, 4, 5}, {3, 5}, {3, 4}}
+	labels := detectGraphCommunities(adjacency)
+
+	assert.Equal(t, labels[0], labels[1])
+	assert.Equal(t, labels[0], labels[2])
+	assert.Equal(t, labels[3], labels[4])
+	assert.Equal(t, labels[3], labels[5])
+	assert.NotEqual(t, labels[0], labels[3])
+	assert.Equal(t, labels, detectGraphCommunities(adjacency), "the same graph gives the same communities")
+}
+
+func TestNoteLinkService_GetGraphData(t *testing.T) {
+	service := setupTestGraph(t)
+
+	graph, err := service.GetGraphData(GraphQuery{ClerkUserID: "user1"})
+	require.NoError(t, err)
+	assert.Len(t, graph.Nodes, 6, "another user's notes are left out")
+	assert.Len(t, graph.Links, 7)
+	assert.Equal(t, 6, graph.TotalNodes)
+	for _, node := range graph.Nodes {
+		assert.NotEmpty(t, node.ClusterID)
+		assert.Equal(t, "Research", node.NotebookName)
+	}
+
+	// The most linked notes and the links between them
+	limited, err := service.GetGraphData(GraphQuery{ClerkUserID: "user1", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, limited.Nodes, 2)
+	assert.Equal(t, []string{"a1", "b1"}, []string{limited.Nodes[0].ID, limited.Nodes[1].ID})
+	require.Len(t, limited.Links, 1)
+	assert.Equal(t, "link6", limited.Links[0].ID)
+	assert.Equal(t, 6, limited.TotalNodes)
+
+	// Searching keeps the links between matching notes
+	searched, err := service.GetGraphData(GraphQuery{ClerkUserID: "user1", Search: "Note a"})
+	require.NoError(t, err)
+	assert.Len(t, searched.Nodes, 3)
+	assert.Len(t, searched.Links, 3)
+}
+
+func TestNoteLinkService_GetGraphData_Filters(t *testing.T) {
+	service := setupTestGraph(t)
+
+	tagged, err := service.GetGraphData(GraphQuery{ClerkUserID: "user1", Tags: []string{"#Paper"}})
+	require.NoError(t, err)
+	assert.Len(t, tagged.Nodes, 3)
+	assert.Len(t, tagged.Links, 3)
+
+	// Hiding the AI layer leaves the two groups apart
+	manual, err := service.GetGraphData(GraphQuery{ClerkUserID: "user1", Origins: []string{models.LinkOriginManual}})
+	require.NoError(t, err)
+	assert.Len(t, manual.Links, 6)
+	for _, link := range manual.Links {
+		assert.Equal(t, models.LinkOriginManual, link.Origin)
+	}
+
+	// Notes updated since are kept, with the links between them
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id IN ?", []string{"b1", "b2"}).
+		Update("updated_at", time.Now().Add(time.Hour)).Error)
+	recent, err := service.GetGraphData(GraphQuery{ClerkUserID: "user1", ModifiedSince: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, recent.Nodes, 2)
+	assert.Len(t, recent.Links, 1)
+}
+
+func TestNoteLinkService_GetGraphNeighborhood(t *testing.T) {
+	service := setupTestGraph(t)
+	query := GraphQuery{ClerkUserID: "user1"}
+
+	neighborhood, err := service.GetGraphNeighborhood(query, "a2", 1)
+	require.NoError(t, err)
+	assert.Len(t, neighborhood.Nodes, 3)
+	assert.Len(t, neighborhood.Links, 3)
+	assert.Equal(t, map[string]int{"a1": 1, "a2": 0, "a3": 1}, neighborhood.Distances)
+
+	// Two links out reaches across the AI suggested link, unless that layer is hidden
+	neighborhood, err = service.GetGraphNeighborhood(query, "a2", 2)
+	require.NoError(t, err)
+	assert.Len(t, neighborhood.Nodes, 4)
+	assert.Equal(t, 2, neighborhood.Distances["b1"])
+
+	neighborhood, err = service.GetGraphNeighborhood(GraphQuery{ClerkUserID: "user1", Origins: []string{models.LinkOriginManual}}, "a2", 2)
+	require.NoError(t, err)
+	assert.Len(t, neighborhood.Nodes, 3)
+
+	// A note without links is on its own, another user's isn't found
+	require.NoError(t, service.db.Create(&models.Notes{ID: "c1", Name: "Lonely", ChapterID: "ch-user1"}).Error)
+	neighborhood, err = service.GetGraphNeighborhood(query, "c1", 0)
+	require.NoError(t, err)
+	require.Len(t, neighborhood.Nodes, 1)
+	assert.Equal(t, defaultGraphNeighborhoodDepth, neighborhood.Depth)
+
+	_, err = service.GetGraphNeighborhood(query, "x1", 1)
+	assert.ErrorIs(t, err, ErrNoteNotFound)
+}
+
+func TestNoteLinkService_Clusters(t *testing.T) {
+	service := setupTestGraph(t)
+	query := GraphQuery{ClerkUserID: "user1"}
+
+	clustered, err := service.GetClusteredGraph(query)
+	require.NoError(t, err)
+	require.Len(t, clustered.Clusters, 2)
+	assert.Equal(t, 6, clustered.TotalNodes)
+	assert.Equal(t, 7, clustered.TotalLinks)
+	for _, cluster := range clustered.Clusters {
+		assert.Equal(t, 3, cluster.Size)
+		assert.Equal(t, 3, cluster.LinkCount)
+		assert.Equal(t, "Research", cluster.NotebookName)
+		assert.Len(t, cluster.TopNotes, 3)
+	}
+	assert.Equal(t, "cluster-a1", clustered.Clusters[0].ID, "clusters are named after their most linked note")
+	assert.Equal(t, "Note a1", clustered.Clusters[0].Label)
+	require.Len(t, clustered.Links, 1)
+	assert.Equal(t, 1, clustered.Links[0].Weight)
+
+	expanded, err := service.GetGraphCluster(query, "cluster-a1")
+	require.NoError(t, err)
+	assert.Len(t, expanded.Nodes, 3)
+	assert.Len(t, expanded.Links, 3)
+	require.Len(t, expanded.ExternalLinks, 1)
+	assert.Equal(t, "a1", expanded.ExternalLinks[0].Source)
+	assert.Equal(t, "cluster-b1", expanded.ExternalLinks[0].Target)
+
+	_, err = service.GetGraphCluster(query, "cluster-x1")
+	assert.ErrorIs(t, err, ErrGraphClusterNotFound, "another user's clusters can't be expanded")
+}
+
+func TestNoteLinkService_GetGraphChanges(t *testing.T) {
+	service := setupTestGraph(t)
+	query := GraphQuery{ClerkUserID: "user1"}
+
+	graph, err := service.GetGraphData(query)
+	require.NoError(t, err)
+
+	changes, err := service.GetGraphChanges(query, graph.Cursor)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Nodes)
+	assert.Empty(t, changes.Links)
+	assert.Len(t, changes.NodeIDs, 6)
+	assert.Len(t, changes.LinkIDs, 7)
+
+	// A new link brings its notes along, with their new link counts
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, service.db.Create(&models.NoteLink{ID: "link-new", SourceNoteID: "a2", TargetNoteID: "b2", CreatedBy: "user1"}).Error)
+	require.NoError(t, service.db.Delete(&models.NoteLink{}, "id = ?", "link6").Error)
+
+	changes, err = service.GetGraphChanges(query, changes.Cursor)
+	require.NoError(t, err)
+	require.Len(t, changes.Links, 1)
+	assert.Equal(t, "link-new", changes.Links[0].ID)
+	require.Len(t, changes.Nodes, 2)
+	assert.Equal(t, 3, changes.Nodes[0].LinkCount)
+	assert.NotContains(t, changes.LinkIDs, "link6", "deleted links are spotted by their missing ID")
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// minLanguageDetectionHits is how many common words of a language a note needs before it's tagged with it
+	minLanguageDetectionHits = 3
+	// maxLanguageDetectionWords caps the words of a note looked at to detect its language
+	maxLanguageDetectionWords = 2000
+	// minStemLength is the shortest a word gets from stemming, so short words aren't stripped to nothing
+	minStemLength = 3
+)
+
+// NoteLanguages are the languages notes are detected in, translated to and searched with stemming, by
+// ISO 639-1 code
+var NoteLanguages = map[string]string{
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"it": "Italian",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+}
+
+// languageStopwords are the most common words of each language. Text is detected as the language most of
+// its words are common in.
+var languageStopwords = map[string]map[string]bool{
+	"de": wordSet("der die das und ist nicht ein eine zu den mit von sich des auf für im dem auch es wir sie ich wird werden sind oder aber wie nach"),
+	"en": wordSet("the and of to is in that it for with as was on are be this have from or not by we you they will can an at which but"),
+	"es": wordSet("el la los las de que y en un una es por con para no se del al lo como más pero sus su está son este esta también"),
+	"fr": wordSet("le la les de des et est un une du en que qui pour dans pas sur au aux avec ce cette il elle nous vous sont mais ou être"),
+	"it": wordSet("il lo la gli le di che e è un una per non con del della dei sono nel nella al alla anche come più ma questo questa si"),
+	"nl": wordSet("de het een en van is dat die niet in op te voor met zijn er maar ook als aan bij om wordt door naar hij zij wij heeft dit"),
+	"pt": wordSet("o os a as de que e é um uma para com não do da dos das no na em por mais se como mas ao são está também seu"),
+}
+
+// languageSuffixes are the inflectional suffixes stemming strips in each language, longest first. Stems are
+// always a prefix of the word, so a stem matches every form of the word with a plain substring search.
+var languageSuffixes = map[string][]string{
+	"de": {"keiten", "heiten", "lichen", "ungen", "liche", "keit", "heit", "lich", "isch", "ung", "ern", "em", "en", "er", "es", "e", "s", "n"},
+	"en": {"ingly", "edly", "ings", "ing", "ied", "ies", "ed", "ly", "es", "s", "y"},
+	"es": {"amientos", "imientos", "amiento", "imiento", "aciones", "idades", "ación", "acion", "mente", "idad", "ando", "iendo", "ados", "idos", "adas", "idas", "ado", "ido", "ada", "ida", "ar", "er", "ir", "es", "as", "os", "a", "o", "e", "s"},
+	"fr": {"issements", "issement", "atrices", "ations", "ements", "ation", "ement", "ments", "ment", "euses", "euse", "eaux", "ités", "aux", "ité", "ées", "ée", "és", "er", "ez", "es", "é", "e", "s", "x"},
+	"it": {"azioni", "azione", "amenti", "amento", "imenti", "imento", "mente", "ità", "ando", "endo", "ato", "ata", "ati", "ate", "ito", "ita", "iti", "ite", "are", "ere", "ire", "i", "e", "a", "o"},
+	"nl": {"heden", "ingen", "heid", "lijk", "ing", "en", "er", "es", "e", "s"},
+	"pt": {"amentos", "imentos", "amento", "imento", "idades", "ações", "ação", "mente", "idade", "ando", "endo", "indo", "ados", "idos", "adas", "idas", "ado", "ido", "ada", "ida", "ar", "er", "ir", "es", "as", "os", "a", "o", "e", "s"},
+}
+
+// NormalizeNoteLanguage returns the language code of a supported language, empty when it isn't one
+func NormalizeNoteLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if _, ok := NoteLanguages[language]; !ok {
+		return ""
+	}
+	return language
+}
+
+// DetectLanguage guesses the language of text from its common words. It returns an empty string when the
+// text is too short to tell or isn't in a supported language.
+func DetectLanguage(text string) string {
+	scores := make(map[string]int, len(languageStopwords))
+	for i, word := range languageWords(text) {
+		if i == maxLanguageDetectionWords {
+			break
+		}
+		for language, stopwords := range languageStopwords {
+			if stopwords[word] {
+				scores[language]++
+			}
+		}
+	}
+
+	best, bestScore, runnerUp := "", 0, 0
+	for _, language := range sortedNoteLanguages() {
+		switch score := scores[language]; {
+		case score > bestScore:
+			best, bestScore, runnerUp = language, score, bestScore
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+	// A tie means the text only uses words the languages share, like "de" or "la"
+	if bestScore < minLanguageDetectionHits || bestScore == runnerUp {
+		return ""
+	}
+	return best
+}
+
+// StemWord strips the inflection of a lowercase word in a language, leaving words of unsupported
+// languages as they are
+func StemWord(language, word string) string {
+	length := utf8.RuneCountInString(word)
+	for _, suffix := range languageSuffixes[language] {
+		if strings.HasSuffix(word, suffix) && length-utf8.RuneCountInString(suffix) >= minStemLength {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// MatchNoteText restricts a notes query to the notes whose name or content contains the text. Notes
+// with a detected language match every word of the text in any inflection of that language, other
+// notes match the text as typed.
+func MatchNoteText(query *gorm.DB, text string) *gorm.DB {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return query
+	}
+	const contains = "(LOWER(notes.name) LIKE ? OR LOWER(notes.content) LIKE ?)"
+	languages := sortedNoteLanguages()
+	words := strings.Fields(text)
+
+	conditions := []string{"(notes.language NOT IN ? AND " + contains + ")"}
+	args := []interface{}{languages, "%" + text + "%", "%" + text + "%"}
+
+	// Languages that stem the words alike share a condition
+	byStems := make(map[string][]string)
+	var order []string
+	for _, language := range languages {
+		stems := make([]string, len(words))
+		for i, word := range words {
+			stems[i] = StemWord(language, word)
+		}
+		key := strings.Join(stems, " ")
+		if _, ok := byStems[key]; !ok {
+			order = append(order, key)
+		}
+		byStems[key] = append(byStems[key], language)
+	}
+	for _, key := range order {
+		condition := "(notes.language IN ?"
+		args = append(args, byStems[key])
+		for _, stem := range strings.Fields(key) {
+			condition += " AND " + contains
+			args = append(args, "%"+stem+"%", "%"+stem+"%")
+		}
+		conditions = append(conditions, condition+")")
+	}
+
+	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// languageWords splits text into lowercase words
+func languageWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+// sortedNoteLanguages returns the codes of the supported languages in order, so detection ties and
+// queries are stable
+func sortedNoteLanguages() []string {
+	languages := make([]string, 0, len(NoteLanguages))
+	for language := range NoteLanguages {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// wordSet returns the space separated words as a set
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
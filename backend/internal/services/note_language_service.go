@@ -0,0 +1,269 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnsupportedLanguage is returned when translating a note to a language notes can't be in
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	// ErrNoteAlreadyInLanguage is returned when translating a note to the language it's written in
+	ErrNoteAlreadyInLanguage = errors.New("the note is already in this language")
+)
+
+// noteLanguagesQueued holds the notes with a language detection waiting in the job queue, so bursts of
+// edits such as collaborative syncs queue one detection
+var noteLanguagesQueued sync.Map
+
+// NoteTranslation is the translated copy of a note, and whether translating created it
+type NoteTranslation struct {
+	Note    *models.Notes `json:"note"`
+	Created bool          `json:"created"`
+}
+
+// NoteLanguageService interface defines methods for the languages of notes and their translations
+type NoteLanguageService interface {
+	NoteChanged(noteID string)
+	RefreshLanguage(ctx context.Context, noteID string) (string, error)
+	TranslateNote(ctx context.Context, noteID, language, clerkUserID string) (*NoteTranslation, error)
+}
+
+// noteLanguageServiceImpl implements the NoteLanguageService interface
+type noteLanguageServiceImpl struct {
+	db        *gorm.DB
+	queue     *JobQueue
+	translate func(ctx context.Context, request TextTranslationRequest) ([]string, error)
+}
+
+// NewNoteLanguageService creates a new NoteLanguageService instance
+func NewNoteLanguageService() NoteLanguageService {
+	return &noteLanguageServiceImpl{
+		db:    db.DB,
+		queue: GetJobQueue(),
+		translate: func(ctx context.Context, request TextTranslationRequest) ([]string, error) {
+			return NewAIService().TranslateTexts(ctx, request)
+		},
+	}
+}
+
+// NoteChanged detects a note's language in the background
+func (s *noteLanguageServiceImpl) NoteChanged(noteID string) {
+	if _, queued := noteLanguagesQueued.LoadOrStore(noteID, true); queued {
+		return
+	}
+
+	err := s.queue.Enqueue(Job{
+		Name:        "note-language",
+		MaxAttempts: 2,
+		Run: func(ctx context.Context) error {
+			// Edits made from here on queue another detection
+			noteLanguagesQueued.Delete(noteID)
+			_, err := s.RefreshLanguage(ctx, noteID)
+			return err
+		},
+	})
+	if err != nil {
+		noteLanguagesQueued.Delete(noteID)
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to queue note language detection")
+	}
+}
+
+// RefreshLanguage detects a note's language from its content and stores it. Notes too short to tell
+// keep the language they had. Locked notes and notes in encrypted notebooks aren't detected.
+func (s *noteLanguageServiceImpl) RefreshLanguage(ctx context.Context, noteID string) (string, error) {
+	var note models.Notes
+	if err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.Locked || note.Chapter.Notebook.Encrypted {
+		return note.Language, nil
+	}
+
+	language := DetectLanguage(note.Name + "\n" + NoteModerationText(note.Content))
+	if language == "" || language == note.Language {
+		return note.Language, nil
+	}
+	// The language follows the content, it isn't an edit of its own
+	if err := s.db.WithContext(ctx).Model(&models.Notes{}).Where("id = ?", noteID).UpdateColumn("language", language).Error; err != nil {
+		return "", fmt.Errorf("failed to save note language: %w", err)
+	}
+	return language, nil
+}
+
+// TranslateNote translates a note with AI into a note in the same chapter linked to it. Translating a
+// note again to the same language updates its translation, and translating a translation translates
+// the original, so translations don't drift from it.
+func (s *noteLanguageServiceImpl) TranslateNote(ctx context.Context, noteID, language, clerkUserID string) (*NoteTranslation, error) {
+	target := NormalizeNoteLanguage(language)
+	if target == "" {
+		return nil, fmt.Errorf("%w: %q, use one of %s", ErrUnsupportedLanguage, language, strings.Join(sortedNoteLanguages(), ", "))
+	}
+
+	source, err := s.translationSource(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if source.Locked {
+		return nil, ErrNoteLocked
+	}
+	if source.Chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+	if source.Language == "" {
+		if source.Language, err = s.RefreshLanguage(ctx, source.ID); err != nil {
+			return nil, err
+		}
+	}
+	if source.Language == target {
+		return nil, ErrNoteAlreadyInLanguage
+	}
+
+	doc, err := translatableTipTapDoc(source.Content)
+	if err != nil {
+		return nil, err
+	}
+	texts := []*string{&source.Name}
+	texts = collectTranslatableTexts(doc.Content, texts)
+	request := TextTranslationRequest{
+		Texts:    make([]string, len(texts)),
+		Language: NoteLanguages[target],
+		UserID:   clerkUserID,
+		OrgID:    source.OrganizationID,
+	}
+	for i, text := range texts {
+		request.Texts[i] = *text
+	}
+	translated, err := s.translate(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.Join(strings.Fields(translated[0]), " ")
+	if name == "" {
+		name = source.Name
+	}
+	for i, text := range texts[1:] {
+		*text = translated[i+1]
+	}
+	content := encodeTipTapDoc(doc)
+
+	var existing []models.Notes
+	if err := s.db.WithContext(ctx).Where("translation_of_id = ? AND language = ?", source.ID, target).
+		Order("created_at ASC").Limit(1).Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch translation: %w", err)
+	}
+
+	if len(existing) == 0 {
+		translation := models.Notes{
+			Name:            name,
+			Content:         content,
+			ChapterID:       source.ChapterID,
+			OrganizationID:  source.OrganizationID,
+			Status:          models.NoteStatusDraft,
+			Language:        target,
+			TranslationOfID: &source.ID,
+		}
+		if err := s.db.WithContext(ctx).Create(&translation).Error; err != nil {
+			return nil, fmt.Errorf("failed to create translation: %w", err)
+		}
+		return &NoteTranslation{Note: &translation, Created: true}, nil
+	}
+
+	translation := existing[0]
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&translation).Updates(map[string]interface{}{"name": name, "content": content}).Error; err != nil {
+			return fmt.Errorf("failed to update translation: %w", err)
+		}
+		_, err := RefreshNoteSlug(tx, translation.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Drop the collaborative document so editors load the new translation
+	if err := NewYjsService(s.db).DeleteYjsDocument(translation.ID); err != nil {
+		log.Warn().Err(err).Str("note_id", translation.ID).Msg("Failed to reset Yjs document after translation")
+	}
+	if err := s.db.WithContext(ctx).Where("id = ?", translation.ID).First(&translation).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch translation: %w", err)
+	}
+	return &NoteTranslation{Note: &translation}, nil
+}
+
+// translationSource returns the note translations of a note are made from: the note itself, or the
+// original of a translation when it still exists
+func (s *noteLanguageServiceImpl) translationSource(ctx context.Context, noteID string) (*models.Notes, error) {
+	var note models.Notes
+	err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.TranslationOfID == nil {
+		return &note, nil
+	}
+
+	var originals []models.Notes
+	if err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", *note.TranslationOfID).
+		Limit(1).Find(&originals).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch original note: %w", err)
+	}
+	if len(originals) == 0 {
+		return &note, nil
+	}
+	return &originals[0], nil
+}
+
+// translatableTipTapDoc decodes note content as a TipTap document, converting content stored as markdown
+func translatableTipTapDoc(content string) (utils.TipTapDoc, error) {
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err == nil && doc.Type == "doc" {
+		return doc, nil
+	}
+	converted, err := utils.MarkdownToTipTap(content)
+	if err != nil {
+		return doc, fmt.Errorf("failed to read note content: %w", err)
+	}
+	if err := json.Unmarshal([]byte(converted), &doc); err != nil {
+		return doc, fmt.Errorf("failed to read note content: %w", err)
+	}
+	return doc, nil
+}
+
+// collectTranslatableTexts appends the text of the nodes to translate, leaving out code
+func collectTranslatableTexts(nodes []utils.TipTapNode, texts []*string) []*string {
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Type == "codeBlock" {
+			continue
+		}
+		if node.Type == "text" && strings.TrimSpace(node.Text) != "" && !hasTipTapMark(node.Marks, "code") {
+			texts = append(texts, &node.Text)
+		}
+		texts = collectTranslatableTexts(node.Content, texts)
+	}
+	return texts
+}
+
+// hasTipTapMark reports whether a text node has a mark
+func hasTipTapMark(marks []utils.TipTapMark, markType string) bool {
+	for _, mark := range marks {
+		if mark.Type == markType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteLanguageService creates a note language service on an in-memory database, translating
+// by upper-casing text
+func setupTestNoteLanguageService(t *testing.T) (*noteLanguageServiceImpl, *[]TextTranslationRequest) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	var requests []TextTranslationRequest
+	return &noteLanguageServiceImpl{
+		db: db,
+		translate: func(ctx context.Context, request TextTranslationRequest) ([]string, error) {
+			requests = append(requests, request)
+			translated := make([]string, len(request.Texts))
+			for i, text := range request.Texts {
+				translated[i] = strings.ToUpper(text)
+			}
+			return translated, nil
+		},
+	}, &requests
+}
+
+// tipTapParagraphs builds TipTap content with a paragraph per text
+func tipTapParagraphs(t *testing.T, texts ...string) string {
+	nodes := make([]map[string]interface{}, len(texts))
+	for i, text := range texts {
+		nodes[i] = map[string]interface{}{
+			"type":    "paragraph",
+			"content": []map[string]interface{}{{"type": "text", "text": text}},
+		}
+	}
+	content, err := json.Marshal(map[string]interface{}{"type": "doc", "content": nodes})
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestDetectLanguage(t *testing.T) {
+	assert.Equal(t, "en", DetectLanguage("The budget for the offsite is approved and we will book the venue this week."))
+	assert.Equal(t, "es", DetectLanguage("El presupuesto para la reunión está aprobado y reservamos el lugar esta semana con los equipos."))
+	assert.Equal(t, "de", DetectLanguage("Das Budget für das Treffen ist genehmigt und wir buchen den Ort in dieser Woche."))
+	assert.Equal(t, "fr", DetectLanguage("Le budget de la réunion est approuvé et nous réservons le lieu avec les équipes dans la semaine."))
+	assert.Empty(t, DetectLanguage("Budget approved"), "Short text can't be told apart")
+	assert.Empty(t, DetectLanguage("de la de la de la"), "Words shared by languages don't decide")
+}
+
+func TestStemWord(t *testing.T) {
+	assert.Equal(t, "stud", StemWord("en", "studies"))
+	assert.Equal(t, "stud", StemWord("en", "study"))
+	assert.Equal(t, "document", StemWord("es", "documentaciones"))
+	assert.Equal(t, "run", StemWord("en", "run"), "Words aren't stemmed below three letters")
+	assert.Equal(t, "studies", StemWord("ja", "studies"), "Unsupported languages aren't stemmed")
+}
+
+func TestMatchNoteText_StemsByNoteLanguage(t *testing.T) {
+	service, _ := setupTestNoteLanguageService(t)
+	note := createLifecycleNote(t, service.db, nil)
+	for _, n := range []models.Notes{
+		{Name: "Reading list", Content: "A study of remote work", Language: "en"},
+		{Name: "Archivo", Content: "Guía de documentación interna", Language: "es"},
+		{Name: "Untagged", Content: "Another study of offices"},
+	} {
+		n.ChapterID = note.ChapterID
+		require.NoError(t, service.db.Create(&n).Error)
+	}
+
+	search := func(text string) []string {
+		var names []string
+		require.NoError(t, MatchNoteText(service.db.Model(&models.Notes{}), text).Order("name").Pluck("name", &names).Error)
+		return names
+	}
+
+	assert.Equal(t, []string{"Reading list"}, search("Studies"), "Notes in a language match other forms of the words")
+	assert.Equal(t, []string{"Archivo"}, search("documentaciones internas"))
+	assert.Equal(t, []string{"Reading list", "Untagged"}, search("study of"), "Notes without a language match the text")
+	assert.Len(t, search(""), 4)
+}
+
+func TestNoteLanguage_RefreshLanguage(t *testing.T) {
+	service, _ := setupTestNoteLanguageService(t)
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	require.NoError(t, service.db.Model(&note).Update("content",
+		tipTapParagraphs(t, "Les notes de frais sont remboursées chaque mois avec le justificatif et la signature du responsable.")).Error)
+
+	language, err := service.RefreshLanguage(ctx, note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "fr", language)
+
+	require.NoError(t, service.db.Model(&note).Update("content", tipTapParagraphs(t, "OK")).Error)
+	language, err = service.RefreshLanguage(ctx, note.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "fr", language, "Notes too short to tell keep their language")
+}
+
+func TestNoteLanguage_TranslateNote(t *testing.T) {
+	service, requests := setupTestNoteLanguageService(t)
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	content := `{"type":"doc","content":[` +
+		`{"type":"paragraph","content":[{"type":"text","text":"Receipts are due with the report "},{"type":"text","text":"expense.csv","marks":[{"type":"code"}]}]},` +
+		`{"type":"codeBlock","content":[{"type":"text","text":"total = sum(receipts)"}]},` +
+		`{"type":"paragraph","content":[{"type":"text","text":"This is how we pay them back, and it is the same for all of the teams."}]}]}`
+	require.NoError(t, service.db.Model(&note).Update("content", content).Error)
+
+	translation, err := service.TranslateNote(ctx, note.ID, "ES", "user_owner")
+	require.NoError(t, err)
+	assert.True(t, translation.Created)
+	assert.Equal(t, "EXPENSES", translation.Note.Name)
+	assert.Equal(t, "es", translation.Note.Language)
+	assert.Equal(t, note.ChapterID, translation.Note.ChapterID)
+	require.NotNil(t, translation.Note.TranslationOfID)
+	assert.Equal(t, note.ID, *translation.Note.TranslationOfID)
+	assert.Contains(t, translation.Note.Content, "RECEIPTS ARE DUE WITH THE REPORT ")
+	assert.Contains(t, translation.Note.Content, `"expense.csv"`, "Inline code isn't translated")
+	assert.Contains(t, translation.Note.Content, "total = sum(receipts)", "Code blocks aren't translated")
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "Spanish", (*requests)[0].Language)
+	assert.Equal(t, "user_owner", (*requests)[0].UserID)
+
+	var source models.Notes
+	require.NoError(t, service.db.First(&source, "id = ?", note.ID).Error)
+	assert.Equal(t, "en", source.Language, "The original's language is detected before translating")
+
+	again, err := service.TranslateNote(ctx, translation.Note.ID, "es", "user_owner")
+	require.NoError(t, err)
+	assert.False(t, again.Created, "Translating again updates the translation")
+	assert.Equal(t, translation.Note.ID, again.Note.ID)
+	var count int64
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("translation_of_id = ?", note.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	_, err = service.TranslateNote(ctx, translation.Note.ID, "en", "user_owner")
+	assert.ErrorIs(t, err, ErrNoteAlreadyInLanguage, "Translations of a translation are made from the original")
+
+	_, err = service.TranslateNote(ctx, note.ID, "klingon", "user_owner")
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+
+	_, err = service.TranslateNote(ctx, "missing", "fr", "user_owner")
+	assert.ErrorIs(t, err, ErrNoteNotFound)
+
+	require.NoError(t, service.db.Model(&note).Update("locked", true).Error)
+	_, err = service.TranslateNote(ctx, note.ID, "fr", "user_owner")
+	assert.ErrorIs(t, err, ErrNoteLocked)
+}
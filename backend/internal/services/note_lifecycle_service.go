@@ -0,0 +1,368 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidNoteStatus is returned for a status that isn't a note lifecycle status
+	ErrInvalidNoteStatus = errors.New("invalid note status")
+	// ErrInvalidNoteTransition is returned when a note can't move from its status to the requested one
+	ErrInvalidNoteTransition = errors.New("invalid note status transition")
+	// ErrNoteReviewRequired is returned when an organization note is approved without a review
+	ErrNoteReviewRequired = errors.New("organization notes are approved through a review")
+	// ErrNoteNotInOrganization is returned when a personal note is sent for review
+	ErrNoteNotInOrganization = errors.New("reviews are only available for organization notes")
+	// ErrNoPendingNoteReview is returned when a note without a pending review is approved or rejected
+	ErrNoPendingNoteReview = errors.New("the note has no pending review")
+	// ErrNoteReviewCommentRequired is returned when a note is rejected without saying why
+	ErrNoteReviewCommentRequired = errors.New("a comment is required to reject a note")
+	// ErrNoteNotFound is returned when a note doesn't exist
+	ErrNoteNotFound = errors.New("note not found")
+)
+
+// noteStatusTransitions lists the statuses each status can move to
+var noteStatusTransitions = map[string][]string{
+	models.NoteStatusDraft:    {models.NoteStatusInReview, models.NoteStatusApproved, models.NoteStatusArchived},
+	models.NoteStatusInReview: {models.NoteStatusDraft, models.NoteStatusApproved, models.NoteStatusArchived},
+	models.NoteStatusApproved: {models.NoteStatusDraft, models.NoteStatusArchived},
+	models.NoteStatusArchived: {models.NoteStatusDraft},
+}
+
+// CanTransitionNoteStatus reports whether a note can move between two statuses
+func CanTransitionNoteStatus(from, to string) bool {
+	if from == "" {
+		from = models.NoteStatusDraft
+	}
+	return containsString(noteStatusTransitions[from], to)
+}
+
+// ParseNoteStatusFilter parses a comma-separated list of statuses used to filter note lists
+func ParseNoteStatusFilter(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var statuses []string
+	for _, status := range strings.Split(value, ",") {
+		status = strings.ToLower(strings.TrimSpace(status))
+		if !containsString(models.NoteStatuses, status) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidNoteStatus, status)
+		}
+		if !containsString(statuses, status) {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// NoteLifecycleService interface defines methods for moving notes through draft, review, approval and archive
+type NoteLifecycleService interface {
+	ChangeStatus(noteID, userID, status string) (*models.Notes, error)
+	RequestReview(noteID, userID, comment string) (*models.NoteReview, error)
+	Review(noteID, reviewerID string, approve bool, comment string) (*models.NoteReview, error)
+	ListReviews(noteID string) ([]models.NoteReview, error)
+	GetPublishingSettings(organizationID string) (*models.OrganizationPublishingSettings, error)
+	SavePublishingSettings(organizationID string, requireApproval bool, updatedBy string) (*models.OrganizationPublishingSettings, error)
+	UnpublishableNotes(noteIDs []string) ([]string, error)
+}
+
+// noteLifecycleServiceImpl implements the NoteLifecycleService interface
+type noteLifecycleServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewNoteLifecycleService creates a new NoteLifecycleService instance
+func NewNoteLifecycleService() NoteLifecycleService {
+	return &noteLifecycleServiceImpl{
+		db: db.DB,
+	}
+}
+
+func (s *noteLifecycleServiceImpl) findNote(tx *gorm.DB, noteID string) (*models.Notes, error) {
+	var note models.Notes
+	if err := tx.Select("id", "name", "slug", "chapter_id", "organization_id", "is_public", "status", "created_at", "updated_at").
+		Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.Status == "" {
+		note.Status = models.NoteStatusDraft
+	}
+	return &note, nil
+}
+
+// ChangeStatus moves a note to a new status. Organization notes are sent for review with RequestReview
+// and approved with Review; sending one to review here opens a review without a comment.
+func (s *noteLifecycleServiceImpl) ChangeStatus(noteID, userID, status string) (*models.Notes, error) {
+	if !containsString(models.NoteStatuses, status) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidNoteStatus, status)
+	}
+
+	note, err := s.findNote(s.db, noteID)
+	if err != nil {
+		return nil, err
+	}
+	isOrgNote := note.OrganizationID != nil && *note.OrganizationID != ""
+
+	switch {
+	case status == models.NoteStatusInReview:
+		if _, err := s.RequestReview(noteID, userID, ""); err != nil {
+			return nil, err
+		}
+		return s.findNote(s.db, noteID)
+	case status == models.NoteStatusApproved && isOrgNote:
+		return nil, ErrNoteReviewRequired
+	case !CanTransitionNoteStatus(note.Status, status):
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidNoteTransition, note.Status, status)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if note.Status == models.NoteStatusInReview {
+			if err := s.closePendingReviews(tx, noteID, userID, models.NoteReviewWithdrawn, ""); err != nil {
+				return err
+			}
+		}
+		return s.setStatus(tx, note, status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("note_id", noteID).Str("user_id", userID).Str("status", status).Msg("Note status changed")
+	return note, nil
+}
+
+// RequestReview sends an organization note for review
+func (s *noteLifecycleServiceImpl) RequestReview(noteID, userID, comment string) (*models.NoteReview, error) {
+	var review models.NoteReview
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		note, err := s.findNote(tx, noteID)
+		if err != nil {
+			return err
+		}
+		if !CanTransitionNoteStatus(note.Status, models.NoteStatusInReview) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidNoteTransition, note.Status, models.NoteStatusInReview)
+		}
+		if note.OrganizationID == nil || *note.OrganizationID == "" {
+			return ErrNoteNotInOrganization
+		}
+
+		review = models.NoteReview{
+			NoteID:         noteID,
+			OrganizationID: *note.OrganizationID,
+			RequestedBy:    userID,
+			RequestComment: strings.TrimSpace(comment),
+			Decision:       models.NoteReviewPending,
+		}
+		if err := tx.Create(&review).Error; err != nil {
+			return fmt.Errorf("failed to create note review: %w", err)
+		}
+		return s.setStatus(tx, note, models.NoteStatusInReview)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("note_id", noteID).Str("user_id", userID).Str("review_id", review.ID).Msg("Note review requested")
+	return &review, nil
+}
+
+// Review approves or rejects the pending review of a note. Rejected notes go back to draft.
+func (s *noteLifecycleServiceImpl) Review(noteID, reviewerID string, approve bool, comment string) (*models.NoteReview, error) {
+	comment = strings.TrimSpace(comment)
+	if !approve && comment == "" {
+		return nil, ErrNoteReviewCommentRequired
+	}
+
+	var review models.NoteReview
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		note, err := s.findNote(tx, noteID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("note_id = ? AND decision = ?", noteID, models.NoteReviewPending).
+			Order("created_at DESC").First(&review).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoPendingNoteReview
+			}
+			return fmt.Errorf("failed to fetch note review: %w", err)
+		}
+		if note.Status != models.NoteStatusInReview {
+			return ErrNoPendingNoteReview
+		}
+
+		decision, status := models.NoteReviewApproved, models.NoteStatusApproved
+		if !approve {
+			decision, status = models.NoteReviewRejected, models.NoteStatusDraft
+		}
+		if err := s.closePendingReviews(tx, noteID, reviewerID, decision, comment); err != nil {
+			return err
+		}
+		if err := tx.First(&review, "id = ?", review.ID).Error; err != nil {
+			return fmt.Errorf("failed to fetch note review: %w", err)
+		}
+		return s.setStatus(tx, note, status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("note_id", noteID).Str("reviewer_id", reviewerID).Str("decision", review.Decision).Msg("Note reviewed")
+	return &review, nil
+}
+
+// ListReviews returns the review history of a note, newest first
+func (s *noteLifecycleServiceImpl) ListReviews(noteID string) ([]models.NoteReview, error) {
+	reviews := []models.NoteReview{}
+	if err := s.db.Where("note_id = ?", noteID).Order("created_at DESC").Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note reviews: %w", err)
+	}
+	return reviews, nil
+}
+
+// closePendingReviews records a decision on a note's pending reviews
+func (s *noteLifecycleServiceImpl) closePendingReviews(tx *gorm.DB, noteID, reviewerID, decision, comment string) error {
+	now := time.Now()
+	if err := tx.Model(&models.NoteReview{}).
+		Where("note_id = ? AND decision = ?", noteID, models.NoteReviewPending).
+		Updates(map[string]interface{}{
+			"decision":    decision,
+			"reviewed_by": reviewerID,
+			"comment":     comment,
+			"reviewed_at": now,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update note reviews: %w", err)
+	}
+	return nil
+}
+
+// setStatus saves a note's status. Notes leaving approval are unpublished when their organization
+// only publishes approved notes.
+func (s *noteLifecycleServiceImpl) setStatus(tx *gorm.DB, note *models.Notes, status string) error {
+	if err := tx.Model(&models.Notes{}).Where("id = ?", note.ID).Update("status", status).Error; err != nil {
+		return fmt.Errorf("failed to update note status: %w", err)
+	}
+	note.Status = status
+
+	if status == models.NoteStatusApproved || !note.IsPublic || note.OrganizationID == nil {
+		return nil
+	}
+	requireApproval, err := requiresApproval(tx, *note.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if !requireApproval {
+		return nil
+	}
+
+	if err := tx.Model(&models.Notes{}).Where("id = ?", note.ID).Update("is_public", false).Error; err != nil {
+		return fmt.Errorf("failed to unpublish note: %w", err)
+	}
+	note.IsPublic = false
+	return unpublishEmptyParents(tx, "chapters.id = (SELECT chapter_id FROM notes WHERE id = ?)", note.ID)
+}
+
+// requiresApproval reports whether an organization only publishes approved notes
+func requiresApproval(tx *gorm.DB, organizationID string) (bool, error) {
+	var settings models.OrganizationPublishingSettings
+	if err := tx.Where("organization_id = ?", organizationID).Limit(1).Find(&settings).Error; err != nil {
+		return false, fmt.Errorf("failed to fetch publishing settings: %w", err)
+	}
+	return settings.RequireApproval, nil
+}
+
+// unpublishEmptyParents makes the matching chapters private when they have no public notes left,
+// then their notebooks when they have no public chapters left
+func unpublishEmptyParents(tx *gorm.DB, chapterCondition string, args ...interface{}) error {
+	var notebookIDs []string
+	if err := tx.Model(&models.Chapter{}).Where(chapterCondition, args...).Distinct().Pluck("notebook_id", &notebookIDs).Error; err != nil {
+		return fmt.Errorf("failed to fetch chapters: %w", err)
+	}
+
+	if err := tx.Model(&models.Chapter{}).
+		Where(chapterCondition, args...).
+		Where("is_public = ? AND NOT EXISTS (SELECT 1 FROM notes WHERE notes.chapter_id = chapters.id AND notes.is_public = ?)", true, true).
+		Update("is_public", false).Error; err != nil {
+		return fmt.Errorf("failed to unpublish chapters: %w", err)
+	}
+	if len(notebookIDs) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.Notebook{}).
+		Where("id IN ? AND is_public = ?", notebookIDs, true).
+		Where("NOT EXISTS (SELECT 1 FROM chapters WHERE chapters.notebook_id = notebooks.id AND chapters.is_public = ?)", true).
+		Update("is_public", false).Error; err != nil {
+		return fmt.Errorf("failed to unpublish notebooks: %w", err)
+	}
+	return nil
+}
+
+// GetPublishingSettings returns an organization's publishing settings
+func (s *noteLifecycleServiceImpl) GetPublishingSettings(organizationID string) (*models.OrganizationPublishingSettings, error) {
+	var settings models.OrganizationPublishingSettings
+	if err := s.db.Where("organization_id = ?", organizationID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch publishing settings: %w", err)
+	}
+	settings.OrganizationID = organizationID
+	return &settings, nil
+}
+
+// SavePublishingSettings updates an organization's publishing settings. Requiring approval unpublishes
+// the organization's published notes that aren't approved.
+func (s *noteLifecycleServiceImpl) SavePublishingSettings(organizationID string, requireApproval bool, updatedBy string) (*models.OrganizationPublishingSettings, error) {
+	settings := models.OrganizationPublishingSettings{OrganizationID: organizationID}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", organizationID).
+			Assign(map[string]interface{}{
+				"require_approval": requireApproval,
+				"updated_by":       updatedBy,
+			}).
+			FirstOrCreate(&settings).Error; err != nil {
+			return fmt.Errorf("failed to save publishing settings: %w", err)
+		}
+		if !requireApproval {
+			return nil
+		}
+
+		result := tx.Model(&models.Notes{}).
+			Where("organization_id = ? AND is_public = ? AND status <> ?", organizationID, true, models.NoteStatusApproved).
+			Update("is_public", false)
+		if result.Error != nil {
+			return fmt.Errorf("failed to unpublish notes: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			log.Info().Str("org_id", organizationID).Int64("notes", result.RowsAffected).Msg("Unpublished notes that aren't approved")
+		}
+		return unpublishEmptyParents(tx, "chapters.organization_id = ?", organizationID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UnpublishableNotes returns the notes that can't be published because their organization only
+// publishes approved notes and they aren't approved
+func (s *noteLifecycleServiceImpl) UnpublishableNotes(noteIDs []string) ([]string, error) {
+	blocked := []string{}
+	if len(noteIDs) == 0 {
+		return blocked, nil
+	}
+	if err := s.db.Model(&models.Notes{}).
+		Joins("JOIN organization_publishing_settings ON organization_publishing_settings.organization_id = notes.organization_id").
+		Where("notes.id IN ? AND organization_publishing_settings.require_approval = ? AND notes.status <> ?", noteIDs, true, models.NoteStatusApproved).
+		Pluck("notes.id", &blocked).Error; err != nil {
+		return nil, fmt.Errorf("failed to check note statuses: %w", err)
+	}
+	return blocked, nil
+}
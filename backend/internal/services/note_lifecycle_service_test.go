@@ -0,0 +1,169 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteLifecycleService creates a note lifecycle service on an in-memory database
+func setupTestNoteLifecycleService(t *testing.T) *noteLifecycleServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteReview{}, &models.OrganizationPublishingSettings{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &noteLifecycleServiceImpl{db: db}
+}
+
+// createLifecycleNote creates a published note, in an organization when orgID is set
+func createLifecycleNote(t *testing.T, db *gorm.DB, orgID *string) models.Notes {
+	notebook := models.Notebook{Name: "Handbook", ClerkUserID: "user_owner", OrganizationID: orgID, IsPublic: true}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Policies", NotebookID: notebook.ID, OrganizationID: orgID, IsPublic: true}
+	require.NoError(t, db.Create(&chapter).Error)
+	note := models.Notes{Name: "Expenses", ChapterID: chapter.ID, OrganizationID: orgID, IsPublic: true}
+	require.NoError(t, db.Create(&note).Error)
+	return note
+}
+
+func TestCanTransitionNoteStatus(t *testing.T) {
+	assert.True(t, CanTransitionNoteStatus("", models.NoteStatusInReview), "Notes without a status are drafts")
+	assert.True(t, CanTransitionNoteStatus(models.NoteStatusApproved, models.NoteStatusArchived))
+	assert.True(t, CanTransitionNoteStatus(models.NoteStatusArchived, models.NoteStatusDraft))
+	assert.False(t, CanTransitionNoteStatus(models.NoteStatusArchived, models.NoteStatusApproved))
+	assert.False(t, CanTransitionNoteStatus(models.NoteStatusApproved, models.NoteStatusInReview))
+}
+
+func TestParseNoteStatusFilter(t *testing.T) {
+	statuses, err := ParseNoteStatusFilter(" Draft,in_review,draft ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.NoteStatusDraft, models.NoteStatusInReview}, statuses)
+
+	_, err = ParseNoteStatusFilter("draft,published")
+	assert.ErrorIs(t, err, ErrInvalidNoteStatus)
+}
+
+func TestNoteLifecycle_PersonalNotesChangeStatusDirectly(t *testing.T) {
+	service := setupTestNoteLifecycleService(t)
+	note := createLifecycleNote(t, service.db, nil)
+
+	var created models.Notes
+	require.NoError(t, service.db.First(&created, "id = ?", note.ID).Error)
+	assert.Equal(t, models.NoteStatusDraft, created.Status, "New notes should be drafts")
+
+	updated, err := service.ChangeStatus(note.ID, "user_owner", models.NoteStatusApproved)
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteStatusApproved, updated.Status)
+
+	_, err = service.ChangeStatus(note.ID, "user_owner", models.NoteStatusInReview)
+	assert.ErrorIs(t, err, ErrInvalidNoteTransition)
+
+	_, err = service.RequestReview(note.ID, "user_owner", "")
+	assert.ErrorIs(t, err, ErrInvalidNoteTransition)
+
+	_, err = service.ChangeStatus(note.ID, "user_owner", "published")
+	assert.ErrorIs(t, err, ErrInvalidNoteStatus)
+}
+
+func TestNoteLifecycle_OrganizationReviewFlow(t *testing.T) {
+	service := setupTestNoteLifecycleService(t)
+	orgID := "org_review"
+	note := createLifecycleNote(t, service.db, &orgID)
+
+	_, err := service.ChangeStatus(note.ID, "user_member", models.NoteStatusApproved)
+	assert.ErrorIs(t, err, ErrNoteReviewRequired, "Organization notes should be approved through a review")
+
+	review, err := service.RequestReview(note.ID, "user_member", "Ready for a look")
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteReviewPending, review.Decision)
+
+	_, err = service.Review(note.ID, "user_admin", false, "  ")
+	assert.ErrorIs(t, err, ErrNoteReviewCommentRequired)
+
+	rejected, err := service.Review(note.ID, "user_admin", false, "Add the approval limits")
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteReviewRejected, rejected.Decision)
+	assert.Equal(t, "user_admin", rejected.ReviewedBy)
+	assert.Equal(t, "Add the approval limits", rejected.Comment)
+
+	var saved models.Notes
+	require.NoError(t, service.db.First(&saved, "id = ?", note.ID).Error)
+	assert.Equal(t, models.NoteStatusDraft, saved.Status, "Rejected notes should go back to draft")
+
+	_, err = service.Review(note.ID, "user_admin", true, "")
+	assert.ErrorIs(t, err, ErrNoPendingNoteReview)
+
+	// Sending to review through a status change opens a review too
+	_, err = service.ChangeStatus(note.ID, "user_member", models.NoteStatusInReview)
+	require.NoError(t, err)
+	approved, err := service.Review(note.ID, "user_admin", true, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteReviewApproved, approved.Decision)
+
+	require.NoError(t, service.db.First(&saved, "id = ?", note.ID).Error)
+	assert.Equal(t, models.NoteStatusApproved, saved.Status)
+
+	reviews, err := service.ListReviews(note.ID)
+	require.NoError(t, err)
+	assert.Len(t, reviews, 2)
+}
+
+func TestNoteLifecycle_WithdrawingClosesReview(t *testing.T) {
+	service := setupTestNoteLifecycleService(t)
+	orgID := "org_withdraw"
+	note := createLifecycleNote(t, service.db, &orgID)
+
+	_, err := service.RequestReview(note.ID, "user_member", "")
+	require.NoError(t, err)
+	_, err = service.ChangeStatus(note.ID, "user_member", models.NoteStatusDraft)
+	require.NoError(t, err)
+
+	reviews, err := service.ListReviews(note.ID)
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, models.NoteReviewWithdrawn, reviews[0].Decision)
+}
+
+func TestNoteLifecycle_RequireApprovalToPublish(t *testing.T) {
+	service := setupTestNoteLifecycleService(t)
+	orgID := "org_strict"
+	draft := createLifecycleNote(t, service.db, &orgID)
+	approved := createLifecycleNote(t, service.db, &orgID)
+	require.NoError(t, service.db.Model(&approved).Update("status", models.NoteStatusApproved).Error)
+	personal := createLifecycleNote(t, service.db, nil)
+
+	blocked, err := service.UnpublishableNotes([]string{draft.ID, approved.ID, personal.ID})
+	require.NoError(t, err)
+	assert.Empty(t, blocked, "Organizations without settings can publish any note")
+
+	settings, err := service.SavePublishingSettings(orgID, true, "user_admin")
+	require.NoError(t, err)
+	assert.True(t, settings.RequireApproval)
+
+	// Published drafts and their empty parents are unpublished
+	var saved models.Notes
+	require.NoError(t, service.db.Preload("Chapter.Notebook").First(&saved, "id = ?", draft.ID).Error)
+	assert.False(t, saved.IsPublic)
+	assert.False(t, saved.Chapter.IsPublic)
+	assert.False(t, saved.Chapter.Notebook.IsPublic)
+	var stillPublished models.Notes
+	require.NoError(t, service.db.First(&stillPublished, "id = ?", approved.ID).Error)
+	assert.True(t, stillPublished.IsPublic)
+
+	blocked, err = service.UnpublishableNotes([]string{draft.ID, approved.ID, personal.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{draft.ID}, blocked)
+
+	// Approved notes that go back to draft are unpublished
+	_, err = service.ChangeStatus(approved.ID, "user_admin", models.NoteStatusDraft)
+	require.NoError(t, err)
+	var reverted models.Notes
+	require.NoError(t, service.db.First(&reverted, "id = ?", approved.ID).Error)
+	assert.False(t, reverted.IsPublic)
+}
@@ -3,8 +3,15 @@ package services
 import (
 	"backend/db"
 	"backend/internal/models"
-	"backend/internal/models/dto"
+	"backend/internal/utils"
+	"errors"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
@@ -22,8 +29,40 @@ func NewNoteLinkService() *NoteLinkService {
 	}
 }
 
-// CreateNoteLink creates a bidirectional link between two notes
-func (s *NoteLinkService) CreateNoteLink(sourceNoteID, targetNoteID, linkType, createdBy string, organizationID *string) (*models.NoteLink, error) {
+var (
+	// ErrInvalidLinkOrigin is returned when a link is made by hand with an origin other than manual or ai.
+	// Wiki links are only made from note content.
+	ErrInvalidLinkOrigin = errors.New("link origin must be manual or ai")
+	// ErrInvalidLinkType is returned for a link type that isn't one of models.ValidLinkTypes or an alias
+	ErrInvalidLinkType = errors.New("invalid link type")
+	// ErrLinkAnnotationTooLong is returned for an annotation longer than maxLinkAnnotationLength
+	ErrLinkAnnotationTooLong = fmt.Errorf("link annotations are at most %d characters", maxLinkAnnotationLength)
+	// ErrNoteLinkNotFound is returned when a link doesn't exist
+	ErrNoteLinkNotFound = errors.New("note link not found")
+)
+
+// maxLinkAnnotationLength is the longest a link's annotation may be, in characters
+const maxLinkAnnotationLength = 500
+
+// NoteBacklink is a link pointing at a note, with the note it comes from
+type NoteBacklink struct {
+	LinkID       string    `json:"linkId"`
+	SourceNoteID string    `json:"sourceNoteId"`
+	SourceName   string    `json:"sourceName"`
+	LinkType     string    `json:"linkType"`
+	Annotation   string    `json:"annotation,omitempty"`
+	BlockRef     string    `json:"blockRef,omitempty"`
+	Origin       string    `json:"origin"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// wikiLinkPattern matches [[note]], [[chapter/note]] and [[note|label]] wiki links in Markdown
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\[\]|\n]+)(?:\|[^\[\]\n]*)?\]\]`)
+
+// CreateNoteLink creates a bidirectional link between two notes. With a block ref, the link points at
+// that block of the target note. The origin is manual for links the user made and ai for AI suggestions
+// they accepted, manual when empty. The annotation, optional, says why the notes are linked.
+func (s *NoteLinkService) CreateNoteLink(sourceNoteID, targetNoteID, linkType, blockRef, origin, annotation, createdBy string, organizationID *string) (*models.NoteLink, error) {
 	// Validation
 	if sourceNoteID == "" || targetNoteID == "" {
 		return nil, fmt.Errorf("source and target note IDs are required")
@@ -39,16 +78,21 @@ func (s *NoteLinkService) CreateNoteLink(sourceNoteID, targetNoteID, linkType, c
 	}
 
 	// Validate link type
-	validTypes := models.ValidLinkTypes()
-	isValid := false
-	for _, vt := range validTypes {
-		if vt == linkType {
-			isValid = true
-			break
-		}
+	linkType, valid := models.NormalizeLinkType(linkType)
+	if !valid {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLinkType, linkType)
 	}
-	if !isValid {
-		return nil, fmt.Errorf("invalid link type: %s", linkType)
+
+	annotation, err := normalizeLinkAnnotation(annotation)
+	if err != nil {
+		return nil, err
+	}
+
+	if origin == "" {
+		origin = models.LinkOriginManual
+	}
+	if origin != models.LinkOriginManual && origin != models.LinkOriginAI {
+		return nil, ErrInvalidLinkOrigin
 	}
 
 	// Check if both notes exist and are accessible
@@ -67,10 +111,14 @@ func (s *NoteLinkService) CreateNoteLink(sourceNoteID, targetNoteID, linkType, c
 		return nil, err
 	}
 
+	if blockRef != "" && !HasBlock(targetNote.Content, blockRef) {
+		return nil, ErrBlockNotFound
+	}
+
 	// Check for duplicate link
 	var existing models.NoteLink
-	err := s.db.Where("source_note_id = ? AND target_note_id = ? AND link_type = ?",
-		sourceNoteID, targetNoteID, linkType).First(&existing).Error
+	err = s.db.Where("source_note_id = ? AND target_note_id = ? AND link_type = ? AND block_ref = ?",
+		sourceNoteID, targetNoteID, linkType, blockRef).First(&existing).Error
 
 	if err == nil {
 		// Link already exists
@@ -85,6 +133,9 @@ func (s *NoteLinkService) CreateNoteLink(sourceNoteID, targetNoteID, linkType, c
 		SourceNoteID:   sourceNoteID,
 		TargetNoteID:   targetNoteID,
 		LinkType:       linkType,
+		BlockRef:       blockRef,
+		Annotation:     annotation,
+		Origin:         origin,
 		CreatedBy:      createdBy,
 		OrganizationID: organizationID,
 	}
@@ -123,7 +174,7 @@ func (s *NoteLinkService) DeleteNoteLink(linkID, userID string) error {
 	var link models.NoteLink
 	if err := s.db.Where("id = ?", linkID).First(&link).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("note link not found")
+			return ErrNoteLinkNotFound
 		}
 		return err
 	}
@@ -169,35 +220,43 @@ func (s *NoteLinkService) GetNoteLinksByNoteID(noteID string) ([]models.NoteLink
 	return links, nil
 }
 
-// UpdateNoteLink updates a note link's type
-func (s *NoteLinkService) UpdateNoteLink(linkID, linkType, userID string) (*models.NoteLink, error) {
+// UpdateNoteLink updates a note link's type and annotation. An empty type keeps the link's type, a nil
+// annotation keeps its annotation and an empty one clears it.
+func (s *NoteLinkService) UpdateNoteLink(linkID, linkType string, annotation *string, userID string) (*models.NoteLink, error) {
 	if linkID == "" {
 		return nil, fmt.Errorf("link ID is required")
 	}
 
 	// Validate link type
-	validTypes := models.ValidLinkTypes()
-	isValid := false
-	for _, vt := range validTypes {
-		if vt == linkType {
-			isValid = true
-			break
+	if linkType != "" {
+		var valid bool
+		if linkType, valid = models.NormalizeLinkType(linkType); !valid {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidLinkType, linkType)
 		}
 	}
-	if !isValid {
-		return nil, fmt.Errorf("invalid link type: %s", linkType)
+	if annotation != nil {
+		normalized, err := normalizeLinkAnnotation(*annotation)
+		if err != nil {
+			return nil, err
+		}
+		annotation = &normalized
 	}
 
 	var link models.NoteLink
 	if err := s.db.Where("id = ?", linkID).First(&link).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("note link not found")
+			return nil, ErrNoteLinkNotFound
 		}
 		return nil, err
 	}
 
-	// Update the link type
-	link.LinkType = linkType
+	// Update the link type and annotation
+	if linkType != "" {
+		link.LinkType = linkType
+	}
+	if annotation != nil {
+		link.Annotation = *annotation
+	}
 	if err := s.db.Save(&link).Error; err != nil {
 		log.Error().
 			Err(err).
@@ -208,7 +267,7 @@ func (s *NoteLinkService) UpdateNoteLink(linkID, linkType, userID string) (*mode
 
 	log.Info().
 		Str("link_id", linkID).
-		Str("new_link_type", linkType).
+		Str("new_link_type", link.LinkType).
 		Msg("Note link updated successfully")
 
 	return &link, nil
@@ -217,7 +276,7 @@ func (s *NoteLinkService) UpdateNoteLink(linkID, linkType, userID string) (*mode
 // GetAllLinks retrieves all links with optional organization filter
 func (s *NoteLinkService) GetAllLinks(organizationID *string) ([]models.NoteLink, error) {
 	var links []models.NoteLink
-	query := s.db.Preload("SourceNote").Preload("TargetNote")
+	query := db.Replica(s.db).Preload("SourceNote").Preload("TargetNote")
 
 	if organizationID != nil {
 		query = query.Where("organization_id = ?", *organizationID)
@@ -234,118 +293,111 @@ func (s *NoteLinkService) GetAllLinks(organizationID *string) ([]models.NoteLink
 	return links, nil
 }
 
-// GetGraphData generates graph visualization data
-func (s *NoteLinkService) GetGraphData(organizationID *string, searchQuery string) (*dto.GraphData, error) {
-	log.Info().
-		Interface("organizationID", organizationID).
-		Str("searchQuery", searchQuery).
-		Msg("GetGraphData called")
-
-	// Get all links for the organization
-	links, err := s.GetAllLinks(organizationID)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Info().
-		Int("linkCount", len(links)).
-		Msg("Fetched links for graph")
+// GetBacklinks returns the links pointing at a note, oldest first, with the notes they come from
+func (s *NoteLinkService) GetBacklinks(noteID string) ([]NoteBacklink, error) {
+	var backlinks []NoteBacklink
+	if err := db.Replica(s.db).Model(&models.NoteLink{}).
+		Select("note_links.id AS link_id, note_links.source_note_id, notes.name AS source_name, note_links.link_type, "+
+			"note_links.annotation, note_links.block_ref, note_links.origin, note_links.created_at").
+		Joins("JOIN notes ON notes.id = note_links.source_note_id").
+		Where("note_links.target_note_id = ?", noteID).
+		Order("note_links.created_at ASC").
+		Scan(&backlinks).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch backlinks: %w", err)
+	}
+	if backlinks == nil {
+		backlinks = []NoteBacklink{}
+	}
+	return backlinks, nil
+}
 
-	// Collect unique note IDs
-	noteIDMap := make(map[string]bool)
-	for _, link := range links {
-		noteIDMap[link.SourceNoteID] = true
-		noteIDMap[link.TargetNoteID] = true
+// SyncWikiLinks keeps the wiki links of a note in step with the [[wiki links]] in its content. Links to
+// notes that don't resolve, or resolve to several notes, are left out until they resolve to one.
+func (s *NoteLinkService) SyncWikiLinks(noteID, createdBy string) error {
+	var note models.Notes
+	if err := s.db.Preload("Chapter").Where("id = ?", noteID).First(&note).Error; err != nil {
+		return fmt.Errorf("failed to fetch note: %w", err)
 	}
-
-	// Fetch all notes with their notebooks and chapters
-	noteIDs := make([]string, 0, len(noteIDMap))
-	for id := range noteIDMap {
-		noteIDs = append(noteIDs, id)
+	markdown, err := utils.TipTapToMarkdown(note.Content)
+	if err != nil {
+		return fmt.Errorf("failed to convert note to markdown: %w", err)
 	}
 
-	log.Info().
-		Int("uniqueNoteCount", len(noteIDs)).
-		Strs("noteIDs", noteIDs).
-		Msg("Collected note IDs from links")
-
-	var notes []models.Notes
-	query := s.db.Preload("Chapter.Notebook").Preload("Chapter")
-
-	if organizationID != nil {
-		query = query.Where("organization_id = ?", *organizationID)
+	slugs := &slugServiceImpl{db: s.db}
+	targets := map[string]bool{}
+	for _, target := range extractWikiLinks(markdown) {
+		linked, err := slugs.ResolveNoteLink(note.Chapter.NotebookID, target)
+		if errors.Is(err, ErrNoteNotFound) || errors.Is(err, ErrAmbiguousNoteLink) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if linked.ID != note.ID {
+			targets[linked.ID] = true
+		}
 	}
 
-	if searchQuery != "" {
-		query = query.Where("name LIKE ? OR content LIKE ?",
-			"%"+searchQuery+"%", "%"+searchQuery+"%")
+	var existing []models.NoteLink
+	if err := s.db.Where("source_note_id = ? AND origin = ?", note.ID, models.LinkOriginWiki).Find(&existing).Error; err != nil {
+		return fmt.Errorf("failed to fetch wiki links: %w", err)
 	}
 
-	if len(noteIDs) > 0 {
-		err = query.Where("id IN ?", noteIDs).Find(&notes).Error
-	} else {
-		log.Warn().Msg("No note IDs to fetch - returning empty graph")
-		return &dto.GraphData{Nodes: []dto.GraphNode{}, Links: []dto.GraphLink{}}, nil
-	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, link := range existing {
+			if targets[link.TargetNoteID] {
+				delete(targets, link.TargetNoteID)
+				continue
+			}
+			if err := tx.Delete(&link).Error; err != nil {
+				return fmt.Errorf("failed to delete wiki link: %w", err)
+			}
+		}
+		for _, targetID := range slices.Sorted(maps.Keys(targets)) {
+			link := models.NoteLink{
+				ID:             uuid.New().String(),
+				SourceNoteID:   note.ID,
+				TargetNoteID:   targetID,
+				LinkType:       models.LinkTypeReferences,
+				Origin:         models.LinkOriginWiki,
+				OrganizationID: note.OrganizationID,
+				CreatedBy:      createdBy,
+			}
+			if err := tx.Create(&link).Error; err != nil {
+				return fmt.Errorf("failed to create wiki link: %w", err)
+			}
+		}
+		return nil
+	})
+}
 
-	if err != nil {
-		log.Error().
-			Err(err).
-			Msg("Failed to fetch notes for graph")
-		return nil, err
+// normalizeLinkAnnotation trims an annotation and checks its length
+func normalizeLinkAnnotation(annotation string) (string, error) {
+	annotation = strings.TrimSpace(annotation)
+	if utf8.RuneCountInString(annotation) > maxLinkAnnotationLength {
+		return "", ErrLinkAnnotationTooLong
 	}
+	return annotation, nil
+}
 
-	log.Info().
-		Int("notesFound", len(notes)).
-		Msg("Fetched notes for graph")
-
-	// Count links for each note
-	linkCountMap := make(map[string]int)
-	for _, link := range links {
-		linkCountMap[link.SourceNoteID]++
-		linkCountMap[link.TargetNoteID]++
-	}
-
-	// Build graph nodes
-	graphNodes := make([]dto.GraphNode, 0, len(notes))
-	for _, note := range notes {
-		node := dto.GraphNode{
-			ID:        note.ID,
-			Name:      note.Name,
-			CreatedAt: note.CreatedAt,
-			UpdatedAt: note.UpdatedAt,
-			LinkCount: linkCountMap[note.ID],
-			Metadata:  make(map[string]string),
+// extractWikiLinks returns the targets of the wiki links in Markdown, outside code, without a #heading
+func extractWikiLinks(markdown string) []string {
+	var targets []string
+	inFence := false
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
 		}
-
-		// Access notebook through chapter relationship
-		if note.Chapter.Notebook.Name != "" {
-			node.NotebookName = note.Chapter.Notebook.Name
-			node.Metadata["notebookId"] = note.Chapter.Notebook.ID
+		if inFence {
+			continue
 		}
-
-		// Access chapter name and ID
-		if note.Chapter.Name != "" {
-			node.ChapterName = note.Chapter.Name
-			node.Metadata["chapterId"] = note.Chapter.ID
+		for _, match := range wikiLinkPattern.FindAllStringSubmatch(stripInlineCode(line), -1) {
+			target, _, _ := strings.Cut(match[1], "#")
+			if target = strings.TrimSpace(target); target != "" && !slices.Contains(targets, target) {
+				targets = append(targets, target)
+			}
 		}
-
-		graphNodes = append(graphNodes, node)
 	}
-
-	// Build graph links
-	graphLinks := make([]dto.GraphLink, 0, len(links))
-	for _, link := range links {
-		graphLinks = append(graphLinks, dto.GraphLink{
-			ID:       link.ID,
-			Source:   link.SourceNoteID,
-			Target:   link.TargetNoteID,
-			LinkType: link.LinkType,
-		})
-	}
-
-	return &dto.GraphData{
-		Nodes: graphNodes,
-		Links: graphLinks,
-	}, nil
+	return targets
 }
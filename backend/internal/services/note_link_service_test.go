@@ -0,0 +1,107 @@
+package services
+
+import (
+	"backend/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wikiLinkContent returns TipTap content of a paragraph of text
+func wikiLinkContent(text string) string {
+	return `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"` + text + `"}]}]}`
+}
+
+func TestNoteLinkService_SyncWikiLinks(t *testing.T) {
+	service := setupTestGraph(t)
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", "a1").
+		Update("content", wikiLinkContent("See [[Note b2]], [[papers/note-b3|the third]] and [[Missing]] but not `[[Note b1]]`")).Error)
+
+	require.NoError(t, service.SyncWikiLinks("a1", "user1"))
+	var targets []string
+	require.NoError(t, service.db.Model(&models.NoteLink{}).
+		Where("source_note_id = ? AND origin = ?", "a1", models.LinkOriginWiki).
+		Order("target_note_id").Pluck("target_note_id", &targets).Error)
+	assert.Equal(t, []string{"b2", "b3"}, targets)
+
+	// Removing a wiki link from the content removes its link, links made by hand stay
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", "a1").
+		Update("content", wikiLinkContent("Only [[Note b3]] now")).Error)
+	require.NoError(t, service.SyncWikiLinks("a1", "user1"))
+	targets = nil
+	require.NoError(t, service.db.Model(&models.NoteLink{}).
+		Where("source_note_id = ? AND origin = ?", "a1", models.LinkOriginWiki).
+		Pluck("target_note_id", &targets).Error)
+	assert.Equal(t, []string{"b3"}, targets)
+
+	var manual int64
+	require.NoError(t, service.db.Model(&models.NoteLink{}).Where("source_note_id = ? AND origin <> ?", "a1", models.LinkOriginWiki).Count(&manual).Error)
+	assert.Equal(t, int64(2), manual)
+}
+
+func TestNoteLinkService_CreateNoteLink_Origin(t *testing.T) {
+	service := setupTestGraph(t)
+
+	link, err := service.CreateNoteLink("a2", "b3", models.LinkTypeRelated, "", "", "", "user1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkOriginManual, link.Origin)
+
+	link, err = service.CreateNoteLink("a3", "b3", models.LinkTypeRelated, "", models.LinkOriginAI, "", "user1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkOriginAI, link.Origin)
+
+	_, err = service.CreateNoteLink("a3", "b2", models.LinkTypeRelated, "", models.LinkOriginWiki, "", "user1", nil)
+	assert.ErrorIs(t, err, ErrInvalidLinkOrigin, "wiki links come from note content")
+}
+
+func TestNoteLinkService_LinkTypesAndAnnotations(t *testing.T) {
+	service := setupTestGraph(t)
+
+	// Aliases are stored as the type they stand for
+	link, err := service.CreateNoteLink("b2", "a2", "Depends-On", "", "", "  Needs the method from a2 ", "user1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkTypePrerequisite, link.LinkType)
+	assert.Equal(t, "Needs the method from a2", link.Annotation)
+
+	_, err = service.CreateNoteLink("b3", "a2", "cites", "", "", "", "user1", nil)
+	assert.ErrorIs(t, err, ErrInvalidLinkType)
+	_, err = service.CreateNoteLink("b3", "a2", models.LinkTypeSource, "", "", strings.Repeat("x", maxLinkAnnotationLength+1), "user1", nil)
+	assert.ErrorIs(t, err, ErrLinkAnnotationTooLong)
+
+	// Updating the annotation keeps the type, and an empty annotation clears it
+	annotation := "Uses its dataset"
+	link, err = service.UpdateNoteLink(link.ID, "", &annotation, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkTypePrerequisite, link.LinkType)
+	assert.Equal(t, annotation, link.Annotation)
+
+	link, err = service.UpdateNoteLink(link.ID, "relates-to", nil, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkTypeRelated, link.LinkType)
+	assert.Equal(t, annotation, link.Annotation)
+
+	backlinks, err := service.GetBacklinks("a2")
+	require.NoError(t, err)
+	require.Len(t, backlinks, 2)
+	assert.Equal(t, "a1", backlinks[0].SourceNoteID)
+	assert.Equal(t, "Note b2", backlinks[1].SourceName)
+	assert.Equal(t, annotation, backlinks[1].Annotation)
+
+	graph, err := service.GetGraphData(GraphQuery{ClerkUserID: "user1"})
+	require.NoError(t, err)
+	for _, graphLink := range graph.Links {
+		if graphLink.ID == link.ID {
+			assert.Equal(t, annotation, graphLink.Annotation)
+		}
+	}
+
+	empty := ""
+	link, err = service.UpdateNoteLink(link.ID, "", &empty, "user1")
+	require.NoError(t, err)
+	assert.Empty(t, link.Annotation)
+
+	_, err = service.UpdateNoteLink("missing", models.LinkTypeSource, nil, "user1")
+	assert.ErrorIs(t, err, ErrNoteLinkNotFound)
+}
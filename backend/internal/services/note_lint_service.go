@@ -0,0 +1,611 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidStyleGuide is returned, wrapped with the reason, for a style guide that can't be saved
+	ErrInvalidStyleGuide = errors.New("invalid style guide")
+	// ErrUnknownLintRule is returned when a style check that doesn't exist is asked for
+	ErrUnknownLintRule = errors.New("unknown style check")
+)
+
+// Style checks run on notes
+const (
+	LintRulePassiveVoice = "passive_voice"
+	LintRuleLongSentence = "long_sentence"
+	LintRuleBannedWord   = "banned_word"
+	LintRuleAI           = "ai"
+)
+
+// LintRules are the style checks, in the order they run
+var LintRules = []string{LintRulePassiveVoice, LintRuleLongSentence, LintRuleBannedWord, LintRuleAI}
+
+const (
+	// defaultMaxSentenceWords is the sentence length flagged in notes without a style guide
+	defaultMaxSentenceWords = 30
+	minMaxSentenceWords     = 10
+	maxMaxSentenceWords     = 100
+	maxBannedWords          = 200
+	maxBannedWordLength     = 100
+	maxStyleGuidelinesSize  = 4000
+	// maxStyleGuideDocumentSize caps the style guide document given to the assistant
+	maxStyleGuideDocumentSize = 8000
+)
+
+// tipTapLeafNodes are the node types without content, which take one position in a ProseMirror document.
+// Other nodes take a position before and after their content, even when they're empty.
+var tipTapLeafNodes = map[string]bool{
+	"hardBreak":      true,
+	"horizontalRule": true,
+	"image":          true,
+	"mention":        true,
+	"math":           true,
+	"noteEmbed":      true,
+	"embed":          true,
+}
+
+// passiveVoicePattern finds a form of "to be" followed by a past participle, e.g. "was approved" or
+// "is being written"
+var passiveVoicePattern = regexp.MustCompile(`(?i)\b(?:am|is|are|was|were|be|been|being)\s+(?:being\s+)?(?:\w+ly\s+)?` +
+	`(?:\w+ed|written|done|made|given|taken|seen|known|shown|found|held|built|sent|told|paid|kept|left|brought|bought|` +
+	`thought|chosen|driven|forgotten|hidden|broken|spoken|stolen|frozen|begun|drawn|grown|thrown|understood|set|put)\b`)
+
+// StyleGuideSettings is the API representation of an organization's style guide
+type StyleGuideSettings struct {
+	PassiveVoice     bool         `json:"passiveVoice"`
+	MaxSentenceWords int          `json:"maxSentenceWords"`
+	BannedWords      []BannedWord `json:"bannedWords"`
+	AISuggestions    bool         `json:"aiSuggestions"`
+	Guidelines       string       `json:"guidelines"`
+	DocumentNoteID   *string      `json:"documentNoteId"` // A note of the organization holding the full style guide
+	EnforceInAI      bool         `json:"enforceInAi"`    // Give the guide to AI writing and the assistant
+}
+
+// BannedWord is a word or phrase a style guide rules out, with what to write instead
+type BannedWord struct {
+	Word        string `json:"word"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// NoteLintInput is the request body for checking the style of a note
+type NoteLintInput struct {
+	Content string   `json:"content"` // The document in the editor, the saved content when empty
+	Rules   []string `json:"rules"`   // The checks to run, all the style guide turns on when empty
+}
+
+// NoteLintIssue is a style problem in a note. From and To are ProseMirror positions in the document, so
+// editors can show the issue as a decoration.
+type NoteLintIssue struct {
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"` // "warning" or "suggestion"
+	Message    string `json:"message"`
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	Text       string `json:"text"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// NoteLintReport is the result of checking the style of a note
+type NoteLintReport struct {
+	Issues  []NoteLintIssue `json:"issues"`
+	Rules   []string        `json:"rules"`             // The checks that ran
+	AIError string          `json:"aiError,omitempty"` // Why AI suggestions are missing
+}
+
+// NoteLintService interface defines methods for organization style guides and the style checks of notes
+type NoteLintService interface {
+	GetStyleGuide(ctx context.Context, organizationID string) (*StyleGuideSettings, error)
+	SaveStyleGuide(ctx context.Context, organizationID string, settings StyleGuideSettings, updatedBy string) (*StyleGuideSettings, error)
+	LintNote(ctx context.Context, noteID string, input NoteLintInput, clerkUserID string) (*NoteLintReport, error)
+	StyleGuidePrompt(ctx context.Context, organizationID *string) string
+}
+
+// noteLintServiceImpl implements the NoteLintService interface
+type noteLintServiceImpl struct {
+	db      *gorm.DB
+	suggest func(ctx context.Context, request StyleSuggestionRequest) ([]StyleSuggestion, error)
+}
+
+// NewNoteLintService creates a new NoteLintService instance
+func NewNoteLintService() NoteLintService {
+	return &noteLintServiceImpl{
+		db: db.DB,
+		suggest: func(ctx context.Context, request StyleSuggestionRequest) ([]StyleSuggestion, error) {
+			return NewAIService().SuggestStyleEdits(ctx, request)
+		},
+	}
+}
+
+// GetStyleGuide returns an organization's style guide, the default checks if none is saved
+func (s *noteLintServiceImpl) GetStyleGuide(ctx context.Context, organizationID string) (*StyleGuideSettings, error) {
+	var guides []models.OrganizationStyleGuide
+	if err := s.db.WithContext(ctx).Where("organization_id = ?", organizationID).Limit(1).Find(&guides).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch style guide: %w", err)
+	}
+	if len(guides) == 0 {
+		return defaultStyleGuide(), nil
+	}
+	return toStyleGuideSettings(&guides[0]), nil
+}
+
+// SaveStyleGuide validates and saves an organization's style guide
+func (s *noteLintServiceImpl) SaveStyleGuide(ctx context.Context, organizationID string, settings StyleGuideSettings, updatedBy string) (*StyleGuideSettings, error) {
+	if settings.MaxSentenceWords != 0 && (settings.MaxSentenceWords < minMaxSentenceWords || settings.MaxSentenceWords > maxMaxSentenceWords) {
+		return nil, fmt.Errorf("%w: sentences can be limited to between %d and %d words", ErrInvalidStyleGuide, minMaxSentenceWords, maxMaxSentenceWords)
+	}
+	if len(settings.Guidelines) > maxStyleGuidelinesSize {
+		return nil, fmt.Errorf("%w: guidelines can be at most %d characters", ErrInvalidStyleGuide, maxStyleGuidelinesSize)
+	}
+	lines := make([]string, 0, len(settings.BannedWords))
+	seen := make(map[string]bool)
+	for _, banned := range settings.BannedWords {
+		word := strings.Join(strings.Fields(banned.Word), " ")
+		replacement := strings.Join(strings.Fields(banned.Replacement), " ")
+		if word == "" || seen[strings.ToLower(word)] {
+			continue
+		}
+		if len(word) > maxBannedWordLength || len(replacement) > maxBannedWordLength || strings.Contains(word, "=>") {
+			return nil, fmt.Errorf("%w: %q can't be banned", ErrInvalidStyleGuide, word)
+		}
+		seen[strings.ToLower(word)] = true
+		if replacement != "" {
+			word += " => " + replacement
+		}
+		lines = append(lines, word)
+	}
+	if len(lines) > maxBannedWords {
+		return nil, fmt.Errorf("%w: at most %d banned words are allowed", ErrInvalidStyleGuide, maxBannedWords)
+	}
+
+	var documentNoteID *string
+	if settings.DocumentNoteID != nil && strings.TrimSpace(*settings.DocumentNoteID) != "" {
+		noteID := strings.TrimSpace(*settings.DocumentNoteID)
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.Notes{}).
+			Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+			Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+			Where("notes.id = ? AND notes.organization_id = ? AND notebooks.encrypted = ?", noteID, organizationID, false).
+			Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch style guide document: %w", err)
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%w: the style guide document must be an unencrypted note of the organization", ErrInvalidStyleGuide)
+		}
+		documentNoteID = &noteID
+	}
+
+	guide := models.OrganizationStyleGuide{OrganizationID: organizationID}
+	if err := s.db.WithContext(ctx).Where(models.OrganizationStyleGuide{OrganizationID: organizationID}).
+		Assign(map[string]interface{}{
+			"passive_voice":      settings.PassiveVoice,
+			"max_sentence_words": settings.MaxSentenceWords,
+			"banned_words":       strings.Join(lines, "\n"),
+			"ai_suggestions":     settings.AISuggestions,
+			"guidelines":         strings.TrimSpace(settings.Guidelines),
+			"document_note_id":   documentNoteID,
+			"enforce_in_ai":      settings.EnforceInAI,
+			"updated_by":         updatedBy,
+		}).
+		FirstOrCreate(&guide).Error; err != nil {
+		return nil, fmt.Errorf("failed to save style guide: %w", err)
+	}
+	return toStyleGuideSettings(&guide), nil
+}
+
+// LintNote checks the style of a note with its organization's style guide. Rule-based checks always
+// run; when AI suggestions fail the report says why instead of failing.
+func (s *noteLintServiceImpl) LintNote(ctx context.Context, noteID string, input NoteLintInput, clerkUserID string) (*NoteLintReport, error) {
+	for _, rule := range input.Rules {
+		if !containsString(LintRules, rule) {
+			return nil, fmt.Errorf("%w: %q, use %s", ErrUnknownLintRule, rule, strings.Join(LintRules, ", "))
+		}
+	}
+
+	var note models.Notes
+	err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.Locked {
+		return nil, ErrNoteLocked
+	}
+	if note.Chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	guide := defaultStyleGuide()
+	if note.OrganizationID != nil && *note.OrganizationID != "" {
+		if guide, err = s.GetStyleGuide(ctx, *note.OrganizationID); err != nil {
+			return nil, err
+		}
+	}
+
+	content := input.Content
+	if strings.TrimSpace(content) == "" {
+		content = note.Content
+	}
+	doc, err := translatableTipTapDoc(content)
+	if err != nil {
+		return nil, err
+	}
+	blocks := collectLintBlocks(doc.Content, 0, nil)
+
+	report := &NoteLintReport{Issues: []NoteLintIssue{}, Rules: []string{}}
+	for _, rule := range LintRules {
+		if len(input.Rules) > 0 && !containsString(input.Rules, rule) {
+			continue
+		}
+		switch rule {
+		case LintRulePassiveVoice:
+			// The pattern only knows English
+			if !guide.PassiveVoice || (note.Language != "" && note.Language != "en") {
+				continue
+			}
+			for _, block := range blocks {
+				report.Issues = append(report.Issues, lintPassiveVoice(block)...)
+			}
+		case LintRuleLongSentence:
+			if guide.MaxSentenceWords == 0 {
+				continue
+			}
+			for _, block := range blocks {
+				report.Issues = append(report.Issues, lintLongSentences(block, guide.MaxSentenceWords)...)
+			}
+		case LintRuleBannedWord:
+			if len(guide.BannedWords) == 0 {
+				continue
+			}
+			for _, block := range blocks {
+				report.Issues = append(report.Issues, lintBannedWords(block, guide.BannedWords)...)
+			}
+		case LintRuleAI:
+			if !guide.AISuggestions {
+				continue
+			}
+			issues, err := s.aiSuggestions(ctx, &note, blocks, guide, clerkUserID)
+			if err != nil {
+				log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to get AI style suggestions")
+				report.AIError = "AI suggestions are unavailable right now"
+				continue
+			}
+			report.Issues = append(report.Issues, issues...)
+		}
+		report.Rules = append(report.Rules, rule)
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool { return report.Issues[i].From < report.Issues[j].From })
+	return report, nil
+}
+
+// StyleGuidePrompt returns the organization's style guide as an addition to AI system prompts, so text
+// the assistant writes follows the team's tone and terminology. It's empty outside organizations and
+// when the guide isn't enforced in AI. Failures are logged and leave the prompt as it is.
+func (s *noteLintServiceImpl) StyleGuidePrompt(ctx context.Context, organizationID *string) string {
+	if organizationID == nil || *organizationID == "" {
+		return ""
+	}
+
+	var guides []models.OrganizationStyleGuide
+	if err := db.Replica(s.db).WithContext(ctx).Where("organization_id = ? AND enforce_in_ai = ?", *organizationID, true).
+		Limit(1).Find(&guides).Error; err != nil {
+		log.Warn().Err(err).Str("organization_id", *organizationID).Msg("Failed to fetch style guide for AI prompt")
+		return ""
+	}
+	if len(guides) == 0 {
+		return ""
+	}
+	guide := toStyleGuideSettings(&guides[0])
+
+	var sections []string
+	if guide.Guidelines != "" {
+		sections = append(sections, guide.Guidelines)
+	}
+	if len(guide.BannedWords) > 0 {
+		lines := make([]string, 0, len(guide.BannedWords))
+		for _, banned := range guide.BannedWords {
+			if banned.Replacement != "" {
+				lines = append(lines, fmt.Sprintf("- %q, write %q instead", banned.Word, banned.Replacement))
+			} else {
+				lines = append(lines, fmt.Sprintf("- %q", banned.Word))
+			}
+		}
+		sections = append(sections, "Never use these words:\n"+strings.Join(lines, "\n"))
+	}
+	if guide.MaxSentenceWords > 0 {
+		sections = append(sections, fmt.Sprintf("Keep sentences under %d words.", guide.MaxSentenceWords))
+	}
+	if guide.PassiveVoice {
+		sections = append(sections, "Prefer the active voice.")
+	}
+	if document := s.styleGuideDocument(ctx, guides[0].DocumentNoteID); document != "" {
+		sections = append(sections, "Style guide document:\n"+document)
+	}
+
+	return "\n\nORGANIZATION STYLE GUIDE:\nEverything you write for this organization, including notes and edits to them, " +
+		"must follow its style guide and use its terminology. The style guide doesn't change what the user asked for.\n\n" +
+		strings.Join(sections, "\n\n")
+}
+
+// styleGuideDocument returns the text of a style guide's document note as Markdown, cut to
+// maxStyleGuideDocumentSize
+func (s *noteLintServiceImpl) styleGuideDocument(ctx context.Context, noteID *string) string {
+	if noteID == nil {
+		return ""
+	}
+	var notes []models.Notes
+	if err := db.Replica(s.db).WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", *noteID).Limit(1).Find(&notes).Error; err != nil {
+		log.Warn().Err(err).Str("note_id", *noteID).Msg("Failed to fetch style guide document")
+		return ""
+	}
+	// The notebook may have been encrypted since the note was picked
+	if len(notes) == 0 || notes[0].Chapter.Notebook.Encrypted {
+		return ""
+	}
+	document, err := utils.TipTapToMarkdown(notes[0].Content)
+	if err != nil {
+		// Notes imported as Markdown keep it until they're edited
+		document = notes[0].Content
+	}
+	return truncateLinkText(strings.TrimSpace(document), maxStyleGuideDocumentSize)
+}
+
+// aiSuggestions asks the AI to review the note's text and places its suggestions in the document,
+// dropping those quoting text that isn't in it
+func (s *noteLintServiceImpl) aiSuggestions(ctx context.Context, note *models.Notes, blocks []lintBlock, guide *StyleGuideSettings, clerkUserID string) ([]NoteLintIssue, error) {
+	texts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if text := strings.TrimSpace(string(block.text)); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	suggestions, err := s.suggest(ctx, StyleSuggestionRequest{
+		Text:       strings.Join(texts, "\n\n"),
+		Guidelines: guide.Guidelines,
+		Language:   NoteLanguages[note.Language],
+		UserID:     clerkUserID,
+		OrgID:      note.OrganizationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make([]NoteLintIssue, 0, len(suggestions))
+	placed := make(map[int]bool)
+	for _, suggestion := range suggestions {
+		excerpt := []rune(strings.TrimSpace(suggestion.Text))
+		if len(excerpt) == 0 {
+			continue
+		}
+		for _, block := range blocks {
+			start := block.index(excerpt, placed)
+			if start < 0 {
+				continue
+			}
+			issue := block.issue(LintRuleAI, "suggestion", strings.TrimSpace(suggestion.Message), start, start+len(excerpt))
+			issue.Suggestion = strings.TrimSpace(suggestion.Suggestion)
+			placed[issue.From] = true
+			issues = append(issues, issue)
+			break
+		}
+	}
+	return issues, nil
+}
+
+// lintBlock is the text of a paragraph or heading, with the ProseMirror position of each of its runes.
+// Inline code and leaf nodes are blanked out so checks skip them.
+type lintBlock struct {
+	text      []rune
+	positions []int
+}
+
+// issue builds an issue over runes [start, end) of the block
+func (b lintBlock) issue(rule, severity, message string, start, end int) NoteLintIssue {
+	from, to := b.span(start, end)
+	return NoteLintIssue{
+		Rule:     rule,
+		Severity: severity,
+		Message:  message,
+		From:     from,
+		To:       to,
+		Text:     string(b.text[start:end]),
+	}
+}
+
+// span returns the ProseMirror positions of runes [start, end) of the block
+func (b lintBlock) span(start, end int) (int, int) {
+	return b.positions[start], b.positions[end-1] + len(utf16.Encode([]rune{b.text[end-1]}))
+}
+
+// index returns where text first occurs in the block at a position not taken yet, -1 if it doesn't
+func (b lintBlock) index(text []rune, taken map[int]bool) int {
+	for start := 0; start+len(text) <= len(b.text); start++ {
+		if string(b.text[start:start+len(text)]) == string(text) && !taken[b.positions[start]] {
+			return start
+		}
+	}
+	return -1
+}
+
+// collectLintBlocks appends the text blocks of nodes starting at a ProseMirror position. Code blocks are
+// skipped.
+func collectLintBlocks(nodes []utils.TipTapNode, pos int, blocks []lintBlock) []lintBlock {
+	for _, node := range nodes {
+		switch {
+		case node.Type == "paragraph" || node.Type == "heading":
+			block := lintBlock{}
+			inline := pos + 1
+			for _, child := range node.Content {
+				runes := []rune(child.Text)
+				if child.Type != "text" {
+					// Inline leaf nodes read as a space between words
+					runes = []rune{' '}
+				}
+				code := child.Type == "text" && hasTipTapMark(child.Marks, "code")
+				for _, r := range runes {
+					if code {
+						r = ' '
+					}
+					block.text = append(block.text, r)
+					block.positions = append(block.positions, inline)
+					if child.Type == "text" {
+						inline += len(utf16.Encode([]rune{r}))
+					} else {
+						inline++
+					}
+				}
+			}
+			if len(block.text) > 0 {
+				blocks = append(blocks, block)
+			}
+		case node.Type != "codeBlock" && !tipTapLeafNodes[node.Type] && node.Type != "text":
+			blocks = collectLintBlocks(node.Content, pos+1, blocks)
+		}
+		pos += tipTapNodeSize(node)
+	}
+	return blocks
+}
+
+// tipTapNodeSize returns how many ProseMirror positions a node takes
+func tipTapNodeSize(node utils.TipTapNode) int {
+	if node.Type == "text" {
+		return len(utf16.Encode([]rune(node.Text)))
+	}
+	if tipTapLeafNodes[node.Type] {
+		return 1
+	}
+	size := 2
+	for _, child := range node.Content {
+		size += tipTapNodeSize(child)
+	}
+	return size
+}
+
+// lintPassiveVoice flags passive constructions in a block
+func lintPassiveVoice(block lintBlock) []NoteLintIssue {
+	text := string(block.text)
+	var issues []NoteLintIssue
+	for _, match := range passiveVoicePattern.FindAllStringIndex(text, -1) {
+		start, end := runeIndex(text, match[0]), runeIndex(text, match[1])
+		issues = append(issues, block.issue(LintRulePassiveVoice, "suggestion",
+			"Passive voice, say who does what if you can", start, end))
+	}
+	return issues
+}
+
+// lintLongSentences flags the sentences of a block longer than the maximum number of words
+func lintLongSentences(block lintBlock, maxWords int) []NoteLintIssue {
+	var issues []NoteLintIssue
+	start := 0
+	for i := 0; i <= len(block.text); i++ {
+		end := i == len(block.text)
+		if !end && !(strings.ContainsRune(".!?", block.text[i]) && (i+1 == len(block.text) || unicode.IsSpace(block.text[i+1]))) {
+			continue
+		}
+		sentenceEnd := i
+		if !end {
+			sentenceEnd = i + 1
+		}
+		for start < sentenceEnd && unicode.IsSpace(block.text[start]) {
+			start++
+		}
+		if words := len(strings.Fields(string(block.text[start:sentenceEnd]))); words > maxWords {
+			issues = append(issues, block.issue(LintRuleLongSentence, "warning",
+				fmt.Sprintf("This sentence has %d words, try splitting it to keep under %d", words, maxWords), start, sentenceEnd))
+		}
+		start = sentenceEnd
+	}
+	return issues
+}
+
+// lintBannedWords flags the words and phrases of a block the style guide rules out, as whole words in
+// any case
+func lintBannedWords(block lintBlock, banned []BannedWord) []NoteLintIssue {
+	lower := []rune(strings.ToLower(string(block.text)))
+	if len(lower) != len(block.text) {
+		// Lowercasing changed the text's length, positions can't be matched
+		return nil
+	}
+	var issues []NoteLintIssue
+	for _, word := range banned {
+		needle := []rune(strings.ToLower(word.Word))
+		for start := 0; start+len(needle) <= len(lower); start++ {
+			end := start + len(needle)
+			if string(lower[start:end]) != string(needle) ||
+				(start > 0 && isWordRune(lower[start-1])) || (end < len(lower) && isWordRune(lower[end])) {
+				continue
+			}
+			message := fmt.Sprintf("%q is against the style guide", word.Word)
+			if word.Replacement != "" {
+				message += fmt.Sprintf(", use %q", word.Replacement)
+			}
+			issue := block.issue(LintRuleBannedWord, "warning", message, start, end)
+			issue.Suggestion = word.Replacement
+			issues = append(issues, issue)
+			start = end - 1
+		}
+	}
+	return issues
+}
+
+// isWordRune reports whether a rune is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// runeIndex converts a byte offset in text to a rune offset
+func runeIndex(text string, offset int) int {
+	return len([]rune(text[:offset]))
+}
+
+// defaultStyleGuide returns the checks run on notes without a style guide
+func defaultStyleGuide() *StyleGuideSettings {
+	return &StyleGuideSettings{
+		PassiveVoice:     true,
+		MaxSentenceWords: defaultMaxSentenceWords,
+		BannedWords:      []BannedWord{},
+		AISuggestions:    true,
+	}
+}
+
+// toStyleGuideSettings converts a stored style guide to its API representation
+func toStyleGuideSettings(guide *models.OrganizationStyleGuide) *StyleGuideSettings {
+	banned := []BannedWord{}
+	for _, line := range strings.Split(guide.BannedWords, "\n") {
+		if line == "" {
+			continue
+		}
+		word, replacement, _ := strings.Cut(line, " => ")
+		banned = append(banned, BannedWord{Word: word, Replacement: replacement})
+	}
+	return &StyleGuideSettings{
+		PassiveVoice:     guide.PassiveVoice,
+		MaxSentenceWords: guide.MaxSentenceWords,
+		BannedWords:      banned,
+		AISuggestions:    guide.AISuggestions,
+		Guidelines:       guide.Guidelines,
+		DocumentNoteID:   guide.DocumentNoteID,
+		EnforceInAI:      guide.EnforceInAI,
+	}
+}
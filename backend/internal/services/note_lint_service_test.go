@@ -0,0 +1,178 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// lintTestContent is a heading, a paragraph with inline code and a list. ProseMirror positions: the heading
+// text starts at 1, the paragraph text at 8 and the list item's paragraph text at 57.
+const lintTestContent = `{"type":"doc","content":[` +
+	`{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"Intro"}]},` +
+	`{"type":"paragraph","content":[{"type":"text","text":"The report was approved by "},{"type":"text","text":"was fixed","marks":[{"type":"code"}]},{"type":"text","text":" finance."}]},` +
+	`{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"Please utilize the shared drive."}]}]}]}]}`
+
+// setupTestNoteLintService creates a note lint service on an in-memory database with canned AI suggestions
+func setupTestNoteLintService(t *testing.T, suggestions []StyleSuggestion, err error) *noteLintServiceImpl {
+	db, openErr := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, openErr, "Failed to open test database")
+
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.OrganizationStyleGuide{}),
+		"Failed to migrate test database")
+
+	return &noteLintServiceImpl{
+		db: db,
+		suggest: func(ctx context.Context, request StyleSuggestionRequest) ([]StyleSuggestion, error) {
+			return suggestions, err
+		},
+	}
+}
+
+// lintIssues returns the issues of a report found by a rule
+func lintIssues(report *NoteLintReport, rule string) []NoteLintIssue {
+	var issues []NoteLintIssue
+	for _, issue := range report.Issues {
+		if issue.Rule == rule {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func TestNoteLint_RulesReturnDocumentPositions(t *testing.T) {
+	service := setupTestNoteLintService(t, []StyleSuggestion{
+		{Text: "shared drive", Message: "Name the drive", Suggestion: "team drive"},
+		{Text: "not in the note", Message: "Made up"},
+	}, nil)
+	ctx := context.Background()
+	orgID := "org_1"
+	note := createLifecycleNote(t, service.db, &orgID)
+	require.NoError(t, service.db.Model(&note).Update("content", lintTestContent).Error)
+
+	_, err := service.SaveStyleGuide(ctx, orgID, StyleGuideSettings{
+		PassiveVoice:     true,
+		MaxSentenceWords: 10,
+		BannedWords:      []BannedWord{{Word: "utilize", Replacement: "use"}, {Word: "Utilize"}},
+		AISuggestions:    true,
+	}, "user_admin")
+	require.NoError(t, err)
+
+	report, err := service.LintNote(ctx, note.ID, NoteLintInput{}, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, LintRules, report.Rules)
+
+	passive := lintIssues(report, LintRulePassiveVoice)
+	require.Len(t, passive, 1, "Inline code isn't checked")
+	assert.Equal(t, NoteLintIssue{Rule: LintRulePassiveVoice, Severity: "suggestion", Message: passive[0].Message, From: 19, To: 31, Text: "was approved"}, passive[0])
+
+	banned := lintIssues(report, LintRuleBannedWord)
+	require.Len(t, banned, 1)
+	assert.Equal(t, 64, banned[0].From)
+	assert.Equal(t, 71, banned[0].To)
+	assert.Equal(t, "use", banned[0].Suggestion)
+
+	ai := lintIssues(report, LintRuleAI)
+	require.Len(t, ai, 1, "Suggestions quoting text that isn't in the note are dropped")
+	assert.Equal(t, "shared drive", ai[0].Text)
+	assert.Equal(t, 76, ai[0].From)
+	assert.Equal(t, "team drive", ai[0].Suggestion)
+
+	assert.Empty(t, lintIssues(report, LintRuleLongSentence))
+	report, err = service.LintNote(ctx, note.ID, NoteLintInput{
+		Content: `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Short one. This sentence keeps going on and on well past the limit of ten words!"}]}]}`,
+		Rules:   []string{LintRuleLongSentence},
+	}, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, []string{LintRuleLongSentence}, report.Rules)
+	require.Len(t, report.Issues, 1, "Posted content is checked instead of the saved one")
+	assert.Equal(t, 12, report.Issues[0].From)
+	assert.Equal(t, "This sentence keeps going on and on well past the limit of ten words!", report.Issues[0].Text)
+
+	_, err = service.LintNote(ctx, note.ID, NoteLintInput{Rules: []string{"spelling"}}, "user_owner")
+	assert.ErrorIs(t, err, ErrUnknownLintRule)
+}
+
+func TestNoteLint_AIFailureKeepsRuleResults(t *testing.T) {
+	service := setupTestNoteLintService(t, nil, errors.New("no API key"))
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	require.NoError(t, service.db.Model(&note).Updates(map[string]interface{}{"content": lintTestContent}).Error)
+
+	report, err := service.LintNote(ctx, note.ID, NoteLintInput{}, "user_owner")
+	require.NoError(t, err)
+	assert.NotEmpty(t, report.AIError)
+	assert.NotContains(t, report.Rules, LintRuleAI)
+	assert.Len(t, lintIssues(report, LintRulePassiveVoice), 1, "Personal notes get the default checks")
+
+	require.NoError(t, service.db.Model(&note).Update("language", "de").Error)
+	report, err = service.LintNote(ctx, note.ID, NoteLintInput{}, "user_owner")
+	require.NoError(t, err)
+	assert.Empty(t, lintIssues(report, LintRulePassiveVoice), "Passive voice is only checked in English")
+
+	require.NoError(t, service.db.Model(&note).Update("locked", true).Error)
+	_, err = service.LintNote(ctx, note.ID, NoteLintInput{}, "user_owner")
+	assert.ErrorIs(t, err, ErrNoteLocked)
+}
+
+func TestNoteLint_SaveStyleGuideValidates(t *testing.T) {
+	service := setupTestNoteLintService(t, nil, nil)
+	ctx := context.Background()
+
+	guide, err := service.GetStyleGuide(ctx, "org_1")
+	require.NoError(t, err)
+	assert.Equal(t, defaultMaxSentenceWords, guide.MaxSentenceWords)
+
+	_, err = service.SaveStyleGuide(ctx, "org_1", StyleGuideSettings{MaxSentenceWords: 5}, "user_admin")
+	assert.ErrorIs(t, err, ErrInvalidStyleGuide)
+	_, err = service.SaveStyleGuide(ctx, "org_1", StyleGuideSettings{BannedWords: []BannedWord{{Word: "a => b"}}}, "user_admin")
+	assert.ErrorIs(t, err, ErrInvalidStyleGuide)
+
+	guide, err = service.SaveStyleGuide(ctx, "org_1", StyleGuideSettings{BannedWords: []BannedWord{{Word: " leverage ", Replacement: "use"}, {Word: "synergy"}}}, "user_admin")
+	require.NoError(t, err)
+	assert.Equal(t, []BannedWord{{Word: "leverage", Replacement: "use"}, {Word: "synergy"}}, guide.BannedWords)
+	assert.False(t, guide.PassiveVoice)
+	assert.Zero(t, guide.MaxSentenceWords, "0 turns the sentence length check off")
+}
+
+func TestNoteLint_StyleGuidePrompt(t *testing.T) {
+	service := setupTestNoteLintService(t, nil, nil)
+	ctx := context.Background()
+	orgID := "org_1"
+	document := createLifecycleNote(t, service.db, &orgID)
+	require.NoError(t, service.db.Model(&document).Update("content",
+		`{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Call customers members."}]}]}`).Error)
+
+	settings := StyleGuideSettings{
+		BannedWords:    []BannedWord{{Word: "utilize", Replacement: "use"}},
+		Guidelines:     "Write in a friendly tone.",
+		DocumentNoteID: &document.ID,
+	}
+	_, err := service.SaveStyleGuide(ctx, orgID, settings, "user_admin")
+	require.NoError(t, err)
+	assert.Empty(t, service.StyleGuidePrompt(ctx, &orgID), "Guides are only given to the assistant when enforced")
+
+	settings.EnforceInAI = true
+	guide, err := service.SaveStyleGuide(ctx, orgID, settings, "user_admin")
+	require.NoError(t, err)
+	require.NotNil(t, guide.DocumentNoteID)
+	assert.Equal(t, document.ID, *guide.DocumentNoteID)
+
+	prompt := service.StyleGuidePrompt(ctx, &orgID)
+	assert.Contains(t, prompt, "Write in a friendly tone.")
+	assert.Contains(t, prompt, `"utilize", write "use" instead`)
+	assert.Contains(t, prompt, "Call customers members.")
+	assert.NotContains(t, prompt, "active voice")
+	assert.Empty(t, service.StyleGuidePrompt(ctx, nil), "Personal workspaces have no style guide")
+	otherOrg := "org_2"
+	assert.Empty(t, service.StyleGuidePrompt(ctx, &otherOrg))
+
+	_, err = service.SaveStyleGuide(ctx, otherOrg, StyleGuideSettings{DocumentNoteID: &document.ID}, "user_admin")
+	assert.ErrorIs(t, err, ErrInvalidStyleGuide, "The document must belong to the organization")
+}
@@ -0,0 +1,420 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// noteLockIterations is the PBKDF2-SHA256 work factor for new locks
+	noteLockIterations = 600000
+	// noteUnlockTTL is how long an unlocked note stays readable in a session
+	noteUnlockTTL = 12 * time.Hour
+	// minNotePassphraseLength is the shortest passphrase a note can be locked with
+	minNotePassphraseLength = 8
+)
+
+var (
+	// ErrNoteLocked is returned when a locked note's content is needed before it was unlocked in the session
+	ErrNoteLocked = errors.New("this note is locked, unlock it with its passphrase first")
+	// ErrNoteNotLocked is returned for lock operations on a note without a lock
+	ErrNoteNotLocked = errors.New("this note is not locked")
+	// ErrNoteAlreadyLocked is returned when locking a note twice
+	ErrNoteAlreadyLocked = errors.New("this note is already locked")
+	// ErrWeakNotePassphrase is returned for passphrases that are too short to lock a note with
+	ErrWeakNotePassphrase = errors.New("passphrases must be at least 8 characters")
+	// ErrWrongNotePassphrase is returned when the passphrase doesn't decrypt the note
+	ErrWrongNotePassphrase = errors.New("wrong passphrase")
+)
+
+// NoteLockService interface defines methods for password-locked notes
+type NoteLockService interface {
+	Lock(noteID, clerkUserID, passphrase string) error
+	Unlock(noteID, session, passphrase string) (string, error)
+	Relock(noteID, session string)
+	RemoveLock(noteID, passphrase string) error
+	Content(noteID, session string) (string, error)
+	UpdateContent(noteID, session, content string) error
+	Locked(noteID string) (bool, error)
+	LockedNotes(noteIDs []string) ([]string, error)
+	CheckToolCall(args map[string]any) error
+}
+
+// noteLockServiceImpl implements the NoteLockService interface
+type noteLockServiceImpl struct {
+	db         *gorm.DB
+	unlocks    *NoteUnlockRegistry
+	iterations int
+}
+
+// NewNoteLockService creates a new NoteLockService instance
+func NewNoteLockService() NoteLockService {
+	return &noteLockServiceImpl{
+		db:         db.DB,
+		unlocks:    GetNoteUnlockRegistry(),
+		iterations: noteLockIterations,
+	}
+}
+
+// NoteUnlockSession identifies the session a note is unlocked in
+func NoteUnlockSession(clerkUserID, sessionID string) string {
+	return clerkUserID + ":" + sessionID
+}
+
+// Lock encrypts the note's content with a key derived from the passphrase and empties the note.
+// The note is unpublished and its collaborative editing state, which holds plain text, is deleted.
+func (s *noteLockServiceImpl) Lock(noteID, clerkUserID, passphrase string) error {
+	if utf8.RuneCountInString(passphrase) < minNotePassphraseLength {
+		return ErrWeakNotePassphrase
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var note models.Notes
+		if err := tx.Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoteNotFound
+			}
+			return fmt.Errorf("failed to fetch note: %w", err)
+		}
+		if note.Locked {
+			return ErrNoteAlreadyLocked
+		}
+		if note.Chapter.Notebook.Encrypted {
+			return ErrNotebookEncrypted
+		}
+
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		key, err := deriveNoteLockKey(passphrase, salt, s.iterations)
+		if err != nil {
+			return err
+		}
+		lock := models.NoteLock{
+			NoteID:     noteID,
+			Salt:       base64.StdEncoding.EncodeToString(salt),
+			Iterations: s.iterations,
+			LockedBy:   clerkUserID,
+		}
+		if err := sealNoteLock(&lock, key, note.Content); err != nil {
+			return err
+		}
+		if err := tx.Create(&lock).Error; err != nil {
+			return fmt.Errorf("failed to save note lock: %w", err)
+		}
+
+		if err := tx.Model(&models.Notes{}).Where("id = ?", noteID).
+			Updates(map[string]interface{}{"content": "", "locked": true, "is_public": false, "summary": "", "summary_fingerprint": "", "summary_updated_at": nil}).Error; err != nil {
+			return fmt.Errorf("failed to lock note: %w", err)
+		}
+		if err := tx.Where("note_id = ?", noteID).Delete(&models.YjsUpdate{}).Error; err != nil {
+			return fmt.Errorf("failed to delete collaboration updates: %w", err)
+		}
+		if err := tx.Where("note_id = ?", noteID).Delete(&models.YjsDocument{}).Error; err != nil {
+			return fmt.Errorf("failed to delete collaboration document: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("Note locked")
+	return nil
+}
+
+// Unlock checks the passphrase and keeps the note readable in the session until it is relocked or the unlock expires
+func (s *noteLockServiceImpl) Unlock(noteID, session, passphrase string) (string, error) {
+	lock, err := s.findLock(noteID)
+	if err != nil {
+		return "", err
+	}
+	key, err := lockKey(lock, passphrase)
+	if err != nil {
+		return "", err
+	}
+	content, err := openNoteLock(lock, key)
+	if err != nil {
+		return "", err
+	}
+
+	s.unlocks.Unlock(noteID, session, key)
+	return content, nil
+}
+
+// Relock forgets the session's unlock of the note
+func (s *noteLockServiceImpl) Relock(noteID, session string) {
+	s.unlocks.Relock(noteID, session)
+}
+
+// RemoveLock restores the note's plain content and deletes its lock
+func (s *noteLockServiceImpl) RemoveLock(noteID, passphrase string) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var lock models.NoteLock
+		if err := tx.Where("note_id = ?", noteID).First(&lock).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoteNotLocked
+			}
+			return fmt.Errorf("failed to fetch note lock: %w", err)
+		}
+		key, err := lockKey(&lock, passphrase)
+		if err != nil {
+			return err
+		}
+		content, err := openNoteLock(&lock, key)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Notes{}).Where("id = ?", noteID).
+			Updates(map[string]interface{}{"content": content, "locked": false}).Error; err != nil {
+			return fmt.Errorf("failed to unlock note: %w", err)
+		}
+		if err := tx.Delete(&lock).Error; err != nil {
+			return fmt.Errorf("failed to delete note lock: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.unlocks.Forget(noteID)
+	log.Info().Str("note_id", noteID).Msg("Note lock removed")
+	return nil
+}
+
+// Content returns the decrypted content of a note unlocked in the session
+func (s *noteLockServiceImpl) Content(noteID, session string) (string, error) {
+	key, ok := s.unlocks.Key(noteID, session)
+	if !ok {
+		return "", ErrNoteLocked
+	}
+	lock, err := s.findLock(noteID)
+	if err != nil {
+		return "", err
+	}
+	return openNoteLock(lock, key)
+}
+
+// UpdateContent encrypts new content for a note unlocked in the session
+func (s *noteLockServiceImpl) UpdateContent(noteID, session, content string) error {
+	key, ok := s.unlocks.Key(noteID, session)
+	if !ok {
+		return ErrNoteLocked
+	}
+	lock, err := s.findLock(noteID)
+	if err != nil {
+		return err
+	}
+	if err := sealNoteLock(lock, key, content); err != nil {
+		return err
+	}
+	if err := s.db.Model(lock).Select("nonce", "ciphertext").Updates(lock).Error; err != nil {
+		return fmt.Errorf("failed to save note lock: %w", err)
+	}
+	return nil
+}
+
+// Locked reports whether a note is password locked
+func (s *noteLockServiceImpl) Locked(noteID string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.Notes{}).Where("id = ? AND locked = ?", noteID, true).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check note lock: %w", err)
+	}
+	return count > 0, nil
+}
+
+// LockedNotes returns which of the notes are password locked
+func (s *noteLockServiceImpl) LockedNotes(noteIDs []string) ([]string, error) {
+	locked := []string{}
+	if len(noteIDs) == 0 {
+		return locked, nil
+	}
+	if err := s.db.Model(&models.Notes{}).Where("id IN ? AND locked = ?", noteIDs, true).Pluck("id", &locked).Error; err != nil {
+		return nil, fmt.Errorf("failed to check note locks: %w", err)
+	}
+	return locked, nil
+}
+
+// CheckToolCall returns ErrNoteLocked when an assistant tool call reads or writes a locked note
+func (s *noteLockServiceImpl) CheckToolCall(args map[string]any) error {
+	noteID, _ := args["noteId"].(string)
+	if noteID == "" {
+		return nil
+	}
+	locked, err := s.Locked(noteID)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrNoteLocked
+	}
+	return nil
+}
+
+// findLock returns the lock of a note
+func (s *noteLockServiceImpl) findLock(noteID string) (*models.NoteLock, error) {
+	var lock models.NoteLock
+	if err := s.db.Where("note_id = ?", noteID).First(&lock).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotLocked
+		}
+		return nil, fmt.Errorf("failed to fetch note lock: %w", err)
+	}
+	return &lock, nil
+}
+
+// lockKey derives the key of a lock from a passphrase
+func lockKey(lock *models.NoteLock, passphrase string) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(lock.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode note lock salt: %w", err)
+	}
+	return deriveNoteLockKey(passphrase, salt, lock.Iterations)
+}
+
+// deriveNoteLockKey derives a 256-bit AES key from a passphrase
+func deriveNoteLockKey(passphrase string, salt []byte, iterations int) ([]byte, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive note key: %w", err)
+	}
+	return key, nil
+}
+
+// sealNoteLock encrypts content into the lock with a fresh nonce
+func sealNoteLock(lock *models.NoteLock, key []byte, content string) error {
+	gcm, err := noteLockCipher(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	lock.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	lock.Ciphertext = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, []byte(content), []byte(lock.NoteID)))
+	return nil
+}
+
+// openNoteLock decrypts the lock's content. A wrong key fails authentication.
+func openNoteLock(lock *models.NoteLock, key []byte) (string, error) {
+	gcm, err := noteLockCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(lock.Nonce)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode note lock nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(lock.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode note lock content: %w", err)
+	}
+	content, err := gcm.Open(nil, nonce, ciphertext, []byte(lock.NoteID))
+	if err != nil {
+		return "", ErrWrongNotePassphrase
+	}
+	return string(content), nil
+}
+
+// noteLockCipher returns the AES-GCM cipher for a key
+func noteLockCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
+
+// noteUnlock is the key of a note unlocked in a session
+type noteUnlock struct {
+	key       []byte
+	expiresAt time.Time
+}
+
+// NoteUnlockRegistry keeps the keys of notes unlocked in each session in memory, they are never stored
+type NoteUnlockRegistry struct {
+	mu      sync.Mutex
+	unlocks map[string]map[string]noteUnlock // note ID -> session -> unlock
+}
+
+var (
+	noteUnlockRegistry     *NoteUnlockRegistry
+	noteUnlockRegistryOnce sync.Once
+)
+
+// GetNoteUnlockRegistry returns the shared note unlock registry
+func GetNoteUnlockRegistry() *NoteUnlockRegistry {
+	noteUnlockRegistryOnce.Do(func() {
+		noteUnlockRegistry = NewNoteUnlockRegistry()
+	})
+	return noteUnlockRegistry
+}
+
+// NewNoteUnlockRegistry creates an empty note unlock registry
+func NewNoteUnlockRegistry() *NoteUnlockRegistry {
+	return &NoteUnlockRegistry{unlocks: make(map[string]map[string]noteUnlock)}
+}
+
+// Unlock keeps the note's key for the session
+func (r *NoteUnlockRegistry) Unlock(noteID, session string, key []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.unlocks[noteID] == nil {
+		r.unlocks[noteID] = make(map[string]noteUnlock)
+	}
+	r.unlocks[noteID][session] = noteUnlock{key: key, expiresAt: time.Now().Add(noteUnlockTTL)}
+}
+
+// Key returns the note's key if it is unlocked in the session
+func (r *NoteUnlockRegistry) Key(noteID, session string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unlock, ok := r.unlocks[noteID][session]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(unlock.expiresAt) {
+		delete(r.unlocks[noteID], session)
+		return nil, false
+	}
+	return unlock.key, true
+}
+
+// Relock forgets the note's key for the session
+func (r *NoteUnlockRegistry) Relock(noteID, session string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.unlocks[noteID], session)
+	if len(r.unlocks[noteID]) == 0 {
+		delete(r.unlocks, noteID)
+	}
+}
+
+// Forget drops the note's key from every session, after its lock is removed
+func (r *NoteUnlockRegistry) Forget(noteID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.unlocks, noteID)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteLockService creates a note lock service on an in-memory database with a cheap key derivation
+func setupTestNoteLockService(t *testing.T) *noteLockServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteLock{},
+		&models.YjsDocument{}, &models.YjsUpdate{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &noteLockServiceImpl{db: db, unlocks: NewNoteUnlockRegistry(), iterations: 1000}
+}
+
+// createLockNote creates a published note in a personal notebook
+func createLockNote(t *testing.T, db *gorm.DB) models.Notes {
+	notebook := models.Notebook{Name: "Private", ClerkUserID: "user_owner"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Diary", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+	note := models.Notes{Name: "Secrets", Content: "my plain content", ChapterID: chapter.ID, IsPublic: true}
+	require.NoError(t, db.Create(&note).Error)
+	return note
+}
+
+func TestNoteLock_LockEncryptsContent(t *testing.T) {
+	service := setupTestNoteLockService(t)
+	note := createLockNote(t, service.db)
+
+	assert.ErrorIs(t, service.Lock(note.ID, "user_owner", "short"), ErrWeakNotePassphrase)
+	require.NoError(t, service.Lock(note.ID, "user_owner", "correct horse"))
+	assert.ErrorIs(t, service.Lock(note.ID, "user_owner", "correct horse"), ErrNoteAlreadyLocked)
+
+	var stored models.Notes
+	require.NoError(t, service.db.Where("id = ?", note.ID).First(&stored).Error)
+	assert.Empty(t, stored.Content, "The plain content should not stay on the note")
+	assert.True(t, stored.Locked)
+	assert.False(t, stored.IsPublic)
+
+	var lock models.NoteLock
+	require.NoError(t, service.db.Where("note_id = ?", note.ID).First(&lock).Error)
+	assert.NotContains(t, lock.Ciphertext, "plain")
+
+	assert.ErrorIs(t, service.CheckToolCall(map[string]any{"noteId": note.ID}), ErrNoteLocked)
+	locked, err := service.LockedNotes([]string{note.ID, "other"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{note.ID}, locked)
+}
+
+func TestNoteLock_UnlockIsPerSession(t *testing.T) {
+	service := setupTestNoteLockService(t)
+	note := createLockNote(t, service.db)
+	require.NoError(t, service.Lock(note.ID, "user_owner", "correct horse"))
+
+	session := NoteUnlockSession("user_owner", "sess_1")
+	_, err := service.Content(note.ID, session)
+	assert.ErrorIs(t, err, ErrNoteLocked)
+
+	_, err = service.Unlock(note.ID, session, "wrong horse")
+	assert.ErrorIs(t, err, ErrWrongNotePassphrase)
+
+	content, err := service.Unlock(note.ID, session, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, "my plain content", content)
+
+	_, err = service.Content(note.ID, NoteUnlockSession("user_owner", "sess_2"))
+	assert.ErrorIs(t, err, ErrNoteLocked, "Other sessions need their own unlock")
+
+	require.NoError(t, service.UpdateContent(note.ID, session, "edited content"))
+	content, err = service.Content(note.ID, session)
+	require.NoError(t, err)
+	assert.Equal(t, "edited content", content)
+
+	service.Relock(note.ID, session)
+	_, err = service.Content(note.ID, session)
+	assert.ErrorIs(t, err, ErrNoteLocked)
+	assert.ErrorIs(t, service.UpdateContent(note.ID, session, "more"), ErrNoteLocked)
+}
+
+func TestNoteLock_RemoveLockRestoresContent(t *testing.T) {
+	service := setupTestNoteLockService(t)
+	note := createLockNote(t, service.db)
+	require.NoError(t, service.Lock(note.ID, "user_owner", "correct horse"))
+
+	assert.ErrorIs(t, service.RemoveLock(note.ID, "wrong horse"), ErrWrongNotePassphrase)
+	require.NoError(t, service.RemoveLock(note.ID, "correct horse"))
+
+	var stored models.Notes
+	require.NoError(t, service.db.Where("id = ?", note.ID).First(&stored).Error)
+	assert.Equal(t, "my plain content", stored.Content)
+	assert.False(t, stored.Locked)
+
+	assert.ErrorIs(t, service.RemoveLock(note.ID, "correct horse"), ErrNoteNotLocked)
+}
+
+func TestNoteLock_RejectsEncryptedNotebooks(t *testing.T) {
+	service := setupTestNoteLockService(t)
+	note := createLockNote(t, service.db)
+	require.NoError(t, service.db.Model(&models.Notebook{}).Where("1 = 1").Update("encrypted", true).Error)
+
+	assert.ErrorIs(t, service.Lock(note.ID, "user_owner", "correct horse"), ErrNotebookEncrypted)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"backend/internal/utils"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrUnmergeableContent is returned when a version to merge isn't a TipTap document
+var ErrUnmergeableContent = errors.New("content isn't a TipTap document")
+
+// Blocks changed differently on both sides of a merge are kept from both, each side in a callout
+const (
+	ConflictCalloutType    = "callout"
+	ConflictCalloutVariant = "conflict"
+	ConflictSideLocal      = "local"
+	ConflictSideServer     = "server"
+)
+
+// MergeTipTapContent merges the local and server versions of a note, both edited from base, block by
+// block. Blocks changed on one side only take that side's change; blocks changed on both sides are
+// kept from both, in a pair of conflict callouts, and counted. Without a base every block that differs
+// is a conflict. Blocks are compared by their Markdown so formatting a side can't express, like block
+// IDs, doesn't count as a change, and unchanged blocks are taken from the server.
+func MergeTipTapContent(base, local, server string) (string, int, error) {
+	baseBlocks, err := parseMergeBlocks(base)
+	if err != nil {
+		return "", 0, err
+	}
+	localBlocks, err := parseMergeBlocks(local)
+	if err != nil {
+		return "", 0, err
+	}
+	serverBlocks, err := parseMergeBlocks(server)
+	if err != nil {
+		return "", 0, err
+	}
+
+	baseKeys, localKeys, serverKeys := mergeBlockKeys(baseBlocks), mergeBlockKeys(localBlocks), mergeBlockKeys(serverBlocks)
+	toLocal := matchMergeBlocks(baseKeys, localKeys)
+	toServer := matchMergeBlocks(baseKeys, serverKeys)
+
+	merged := []utils.TipTapNode{}
+	conflicts := 0
+	// resolve merges the blocks between two blocks unchanged on both sides
+	var resolve func(b, l, s [2]int)
+	resolve = func(b, l, s [2]int) {
+		baseChunk := baseKeys[b[0]:b[1]]
+		localChunk, serverChunk := localKeys[l[0]:l[1]], serverKeys[s[0]:s[1]]
+		// Neighbouring blocks edited in place on different sides don't conflict
+		if n := len(baseChunk); n > 1 && len(localChunk) == n && len(serverChunk) == n {
+			for offset := 0; offset < n; offset++ {
+				resolve([2]int{b[0] + offset, b[0] + offset + 1}, [2]int{l[0] + offset, l[0] + offset + 1}, [2]int{s[0] + offset, s[0] + offset + 1})
+			}
+			return
+		}
+		switch {
+		case slices.Equal(localChunk, baseChunk), slices.Equal(localChunk, serverChunk):
+			merged = append(merged, serverBlocks[s[0]:s[1]]...)
+		case slices.Equal(serverChunk, baseChunk):
+			merged = append(merged, localBlocks[l[0]:l[1]]...)
+		default:
+			merged = append(merged,
+				conflictCallout(ConflictSideLocal, "Local version", localBlocks[l[0]:l[1]]),
+				conflictCallout(ConflictSideServer, "Server version", serverBlocks[s[0]:s[1]]))
+			conflicts++
+		}
+	}
+
+	i, j, k := 0, 0, 0
+	for b := range baseKeys {
+		if toLocal[b] < 0 || toServer[b] < 0 {
+			continue
+		}
+		resolve([2]int{i, b}, [2]int{j, toLocal[b]}, [2]int{k, toServer[b]})
+		merged = append(merged, serverBlocks[toServer[b]])
+		i, j, k = b+1, toLocal[b]+1, toServer[b]+1
+	}
+	resolve([2]int{i, len(baseKeys)}, [2]int{j, len(localKeys)}, [2]int{k, len(serverKeys)})
+
+	encoded, err := json.Marshal(utils.TipTapDoc{Type: "doc", Content: merged})
+	if err != nil {
+		return "", 0, err
+	}
+	return AssignTipTapBlockIDs(server, string(encoded)), conflicts, nil
+}
+
+// parseMergeBlocks returns the blocks of a TipTap document, none for empty content
+func parseMergeBlocks(content string) ([]utils.TipTapNode, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+	var doc utils.TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" {
+		return nil, ErrUnmergeableContent
+	}
+	return doc.Content, nil
+}
+
+// mergeBlockKeys returns the Markdown of each block, to compare blocks by
+func mergeBlockKeys(blocks []utils.TipTapNode) []string {
+	keys := make([]string, len(blocks))
+	for i, block := range blocks {
+		encoded, err := json.Marshal(utils.TipTapDoc{Type: "doc", Content: []utils.TipTapNode{block}})
+		if err != nil {
+			continue
+		}
+		markdown, _ := utils.TipTapToMarkdown(string(encoded))
+		keys[i] = block.Type + ":" + strings.TrimSpace(markdown)
+	}
+	return keys
+}
+
+// matchMergeBlocks pairs the base blocks with the blocks of a side by their longest common
+// subsequence. It returns the index of each base block on the side, -1 for blocks the side changed.
+func matchMergeBlocks(base, side []string) []int {
+	lengths := make([][]int, len(base)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(side)+1)
+	}
+	for i := len(base) - 1; i >= 0; i-- {
+		for j := len(side) - 1; j >= 0; j-- {
+			if base[i] == side[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	matches := make([]int, len(base))
+	for i := range matches {
+		matches[i] = -1
+	}
+	for i, j := 0, 0; i < len(base) && j < len(side); {
+		switch {
+		case base[i] == side[j]:
+			matches[i] = j
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// conflictCallout wraps one side's blocks of a conflict. A side that deleted the blocks gets a note
+// saying so, callouts can't be empty.
+func conflictCallout(side, title string, blocks []utils.TipTapNode) utils.TipTapNode {
+	content := append([]utils.TipTapNode{}, blocks...)
+	if len(content) == 0 {
+		content = []utils.TipTapNode{{
+			Type:    "paragraph",
+			Content: []utils.TipTapNode{{Type: "text", Text: "Deleted", Marks: []utils.TipTapMark{{Type: "italic"}}}},
+		}}
+	}
+	return utils.TipTapNode{
+		Type:    ConflictCalloutType,
+		Attrs:   map[string]interface{}{"variant": ConflictCalloutVariant, "side": side, "title": title},
+		Content: content,
+	}
+}
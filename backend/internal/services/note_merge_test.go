@@ -0,0 +1,54 @@
+package services
+
+import (
+	"backend/internal/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mergeTestDoc(t *testing.T, markdown string) string {
+	content, err := utils.MarkdownToTipTap(markdown)
+	require.NoError(t, err)
+	return content
+}
+
+func TestMergeTipTapContent(t *testing.T) {
+	base := mergeTestDoc(t, "# Plan\n\nBuy seeds\n\nDig beds\n\nWater daily")
+
+	// Changes to different blocks are both kept
+	local := mergeTestDoc(t, "# Plan\n\nBuy tomato seeds\n\nDig beds\n\nWater daily")
+	server := mergeTestDoc(t, "# Plan\n\nBuy seeds\n\nDig beds\n\nWater daily\n\nHarvest in August")
+	merged, conflicts, err := MergeTipTapContent(base, local, server)
+	require.NoError(t, err)
+	assert.Equal(t, 0, conflicts)
+	markdown, err := utils.TipTapToMarkdown(merged)
+	require.NoError(t, err)
+	assert.Equal(t, "# Plan\n\nBuy tomato seeds\n\nDig beds\n\nWater daily\n\nHarvest in August\n", markdown)
+
+	// Changing the same block both ways keeps both in callouts
+	server = mergeTestDoc(t, "# Plan\n\nBuy seedlings\n\nDig beds\n\nWater daily")
+	merged, conflicts, err = MergeTipTapContent(base, local, server)
+	require.NoError(t, err)
+	assert.Equal(t, 1, conflicts)
+	markdown, err = utils.TipTapToMarkdown(merged)
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "> **Local version**\n> \n> Buy tomato seeds")
+	assert.Contains(t, markdown, "> **Server version**\n> \n> Buy seedlings")
+	assert.Contains(t, markdown, "Dig beds")
+
+	// Deleting a block on one side while the other changed it is a conflict too
+	server = mergeTestDoc(t, "# Plan\n\nDig beds\n\nWater daily")
+	_, conflicts, err = MergeTipTapContent(base, local, server)
+	require.NoError(t, err)
+	assert.Equal(t, 1, conflicts)
+
+	// Without a base only identical blocks merge
+	_, conflicts, err = MergeTipTapContent("", local, mergeTestDoc(t, "# Plan\n\nBuy seedlings\n\nDig beds\n\nWater daily"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, conflicts)
+
+	_, _, err = MergeTipTapContent(base, "not a doc", server)
+	assert.ErrorIs(t, err, ErrUnmergeableContent)
+}
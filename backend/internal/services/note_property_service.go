@@ -0,0 +1,270 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPropertyKey is returned for a property key that can't be used in frontmatter
+	ErrInvalidPropertyKey = errors.New("property keys must start with a letter and contain only letters, digits, dashes or underscores (max 100)")
+	// ErrInvalidPropertyType is returned for an unknown property type
+	ErrInvalidPropertyType = errors.New("property type must be one of text, date, number, select or person")
+	// ErrInvalidPropertyValue is returned when a value doesn't match its property type
+	ErrInvalidPropertyValue = errors.New("property value does not match its type")
+	// ErrPropertyNotFound is returned when the note has no property with the key
+	ErrPropertyNotFound = errors.New("property not found")
+	// ErrInvalidPropertyFilter is returned for a filter that can't be parsed, like "status reading"
+	ErrInvalidPropertyFilter = errors.New(`property filters look like "status = reading" or "pages >= 100"`)
+)
+
+var (
+	notePropertyKeyPattern    = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,99}$`)
+	notePropertyFilterPattern = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9_-]*)\s*(!=|>=|<=|=|>|<)\s*(.*?)\s*$`)
+)
+
+// NotePropertyFilter matches notes by a property, e.g. status = reading
+type NotePropertyFilter struct {
+	Key      string
+	Operator string // "=", "!=", ">", ">=", "<", "<="
+	Value    string
+}
+
+// NotePropertyService interface defines methods for managing typed note properties
+type NotePropertyService interface {
+	ListProperties(noteID string) ([]models.NoteProperty, error)
+	SetProperty(noteID, key, propertyType, value string) (*models.NoteProperty, error)
+	DeleteProperty(noteID, key string) error
+}
+
+// notePropertyServiceImpl implements the NotePropertyService interface
+type notePropertyServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewNotePropertyService creates a new NotePropertyService instance
+func NewNotePropertyService() NotePropertyService {
+	return &notePropertyServiceImpl{
+		db: db.DB,
+	}
+}
+
+// ListProperties returns a note's properties ordered by key
+func (s *notePropertyServiceImpl) ListProperties(noteID string) ([]models.NoteProperty, error) {
+	properties := []models.NoteProperty{}
+	if err := s.db.Where("note_id = ?", noteID).Order("key ASC").Find(&properties).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note properties: %w", err)
+	}
+	return properties, nil
+}
+
+// SetProperty creates or replaces a note property after normalizing its value for its type
+func (s *notePropertyServiceImpl) SetProperty(noteID, key, propertyType, value string) (*models.NoteProperty, error) {
+	key, err := normalizeNotePropertyKey(key)
+	if err != nil {
+		return nil, err
+	}
+	property := models.NoteProperty{NoteID: noteID, Key: key, Type: strings.ToLower(strings.TrimSpace(propertyType))}
+	if err := setNotePropertyValue(&property, value); err != nil {
+		return nil, err
+	}
+
+	var note models.Notes
+	if err := s.db.Select("id", "organization_id").Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	property.OrganizationID = note.OrganizationID
+
+	var existing models.NoteProperty
+	err = s.db.Where("note_id = ? AND key = ?", noteID, key).First(&existing).Error
+	switch {
+	case err == nil:
+		property.ID = existing.ID
+		property.CreatedAt = existing.CreatedAt
+		if err := s.db.Save(&property).Error; err != nil {
+			return nil, fmt.Errorf("failed to update note property: %w", err)
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := s.db.Create(&property).Error; err != nil {
+			return nil, fmt.Errorf("failed to create note property: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to fetch note property: %w", err)
+	}
+	return &property, nil
+}
+
+// DeleteProperty removes a property from a note
+func (s *notePropertyServiceImpl) DeleteProperty(noteID, key string) error {
+	key, err := normalizeNotePropertyKey(key)
+	if err != nil {
+		return ErrPropertyNotFound
+	}
+	result := s.db.Where("note_id = ? AND key = ?", noteID, key).Delete(&models.NoteProperty{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete note property: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPropertyNotFound
+	}
+	return nil
+}
+
+// ParseNotePropertyFilters parses filters like "status = reading" or "due < 2025-01-01"
+func ParseNotePropertyFilters(expressions []string) ([]NotePropertyFilter, error) {
+	filters := make([]NotePropertyFilter, 0, len(expressions))
+	for _, expression := range expressions {
+		if strings.TrimSpace(expression) == "" {
+			continue
+		}
+		match := notePropertyFilterPattern.FindStringSubmatch(expression)
+		if match == nil {
+			return nil, ErrInvalidPropertyFilter
+		}
+		filter := NotePropertyFilter{Key: strings.ToLower(match[1]), Operator: match[2], Value: unquotePropertyValue(match[3])}
+		if filter.Value == "" {
+			return nil, ErrInvalidPropertyFilter
+		}
+		if isRangeOperator(filter.Operator) {
+			if _, ok := parsePropertyNumber(filter.Value); !ok {
+				if _, ok := parsePropertyDate(filter.Value); !ok {
+					return nil, fmt.Errorf("%w: %s compares numbers or dates", ErrInvalidPropertyFilter, filter.Operator)
+				}
+			}
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// ApplyNotePropertyFilters restricts a notes query to the notes matching every filter
+func ApplyNotePropertyFilters(query *gorm.DB, filters []NotePropertyFilter) *gorm.DB {
+	const match = "SELECT 1 FROM note_properties WHERE note_properties.note_id = notes.id AND note_properties.key = ?"
+	for _, filter := range filters {
+		switch filter.Operator {
+		case "=":
+			query = query.Where("EXISTS ("+match+" AND LOWER(note_properties.value) = ?)", filter.Key, strings.ToLower(filter.Value))
+		case "!=":
+			query = query.Where("NOT EXISTS ("+match+" AND LOWER(note_properties.value) = ?)", filter.Key, strings.ToLower(filter.Value))
+		default:
+			if number, ok := parsePropertyNumber(filter.Value); ok {
+				query = query.Where("EXISTS ("+match+" AND note_properties.number_value "+filter.Operator+" ?)", filter.Key, number)
+			} else if date, ok := parsePropertyDate(filter.Value); ok {
+				query = query.Where("EXISTS ("+match+" AND note_properties.date_value "+filter.Operator+" ?)", filter.Key, date)
+			}
+		}
+	}
+	return query
+}
+
+// NotePropertiesFrontMatter renders properties as YAML frontmatter lines, without the --- fences
+func NotePropertiesFrontMatter(properties []models.NoteProperty) string {
+	var builder strings.Builder
+	for _, property := range properties {
+		builder.WriteString(property.Key)
+		builder.WriteString(": ")
+		switch property.Type {
+		case models.NotePropertyNumber, models.NotePropertyDate:
+			builder.WriteString(property.Value)
+		default:
+			builder.WriteString(strconv.Quote(property.Value))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// normalizeNotePropertyKey lowercases and validates a property key
+func normalizeNotePropertyKey(key string) (string, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !notePropertyKeyPattern.MatchString(key) {
+		return "", ErrInvalidPropertyKey
+	}
+	return key, nil
+}
+
+// setNotePropertyValue validates a value for the property's type and stores its normalized forms
+func setNotePropertyValue(property *models.NoteProperty, value string) error {
+	if !containsString(models.NotePropertyTypes, property.Type) {
+		return ErrInvalidPropertyType
+	}
+	value = strings.TrimSpace(value)
+	property.NumberValue = nil
+	property.DateValue = nil
+
+	switch property.Type {
+	case models.NotePropertyNumber:
+		number, ok := parsePropertyNumber(value)
+		if !ok {
+			return fmt.Errorf("%w: %q is not a number", ErrInvalidPropertyValue, value)
+		}
+		property.NumberValue = &number
+		value = strconv.FormatFloat(number, 'f', -1, 64)
+	case models.NotePropertyDate:
+		date, ok := parsePropertyDate(value)
+		if !ok {
+			return fmt.Errorf("%w: %q is not a date like 2025-01-31", ErrInvalidPropertyValue, value)
+		}
+		property.DateValue = &date
+		if len(value) > len("2006-01-02") {
+			value = date.Format(time.RFC3339)
+		} else {
+			value = date.Format("2006-01-02")
+		}
+	case models.NotePropertySelect, models.NotePropertyPerson:
+		if value == "" {
+			return fmt.Errorf("%w: %s properties need a value", ErrInvalidPropertyValue, property.Type)
+		}
+	}
+
+	if len(value) > 1000 {
+		return fmt.Errorf("%w: values are limited to 1000 characters", ErrInvalidPropertyValue)
+	}
+	property.Value = value
+	return nil
+}
+
+// parsePropertyNumber parses a finite number
+func parsePropertyNumber(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+// parsePropertyDate parses a date or an RFC 3339 timestamp
+func parsePropertyDate(value string) (time.Time, bool) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, true
+	}
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// unquotePropertyValue strips matching quotes around a filter value
+func unquotePropertyValue(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// isRangeOperator reports whether an operator compares numbers or dates
+func isRangeOperator(operator string) bool {
+	return operator == ">" || operator == ">=" || operator == "<" || operator == "<="
+}
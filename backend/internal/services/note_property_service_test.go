@@ -0,0 +1,132 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNotePropertyService creates a note property service on an in-memory database
+func setupTestNotePropertyService(t *testing.T) *notePropertyServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteProperty{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &notePropertyServiceImpl{db: db}
+}
+
+// createPropertyNote creates a note in a personal notebook
+func createPropertyNote(t *testing.T, db *gorm.DB, name string) models.Notes {
+	notebook := models.Notebook{Name: "Reading list", ClerkUserID: "user_owner"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Books", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+	note := models.Notes{Name: name, ChapterID: chapter.ID}
+	require.NoError(t, db.Create(&note).Error)
+	return note
+}
+
+func TestNoteProperty_SetValidatesAndNormalizes(t *testing.T) {
+	service := setupTestNotePropertyService(t)
+	note := createPropertyNote(t, service.db, "Dune")
+
+	property, err := service.SetProperty(note.ID, " Pages ", models.NotePropertyNumber, "412.0")
+	require.NoError(t, err)
+	assert.Equal(t, "pages", property.Key)
+	assert.Equal(t, "412", property.Value)
+	require.NotNil(t, property.NumberValue)
+	assert.Equal(t, 412.0, *property.NumberValue)
+
+	property, err = service.SetProperty(note.ID, "finished", models.NotePropertyDate, "2024-03-05")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-05", property.Value)
+	require.NotNil(t, property.DateValue)
+
+	_, err = service.SetProperty(note.ID, "pages", models.NotePropertyNumber, "many")
+	assert.ErrorIs(t, err, ErrInvalidPropertyValue)
+	_, err = service.SetProperty(note.ID, "finished", models.NotePropertyDate, "March 5th")
+	assert.ErrorIs(t, err, ErrInvalidPropertyValue)
+	_, err = service.SetProperty(note.ID, "status", "checkbox", "yes")
+	assert.ErrorIs(t, err, ErrInvalidPropertyType)
+	_, err = service.SetProperty(note.ID, "my status", models.NotePropertySelect, "reading")
+	assert.ErrorIs(t, err, ErrInvalidPropertyKey)
+	_, err = service.SetProperty("missing", "status", models.NotePropertySelect, "reading")
+	assert.ErrorIs(t, err, ErrNoteNotFound)
+
+	// Setting a key again replaces it, even with another type
+	_, err = service.SetProperty(note.ID, "pages", models.NotePropertyText, "about 400")
+	require.NoError(t, err)
+	properties, err := service.ListProperties(note.ID)
+	require.NoError(t, err)
+	require.Len(t, properties, 2)
+	assert.Equal(t, "about 400", properties[1].Value)
+	assert.Nil(t, properties[1].NumberValue)
+
+	require.NoError(t, service.DeleteProperty(note.ID, "PAGES"))
+	assert.ErrorIs(t, service.DeleteProperty(note.ID, "pages"), ErrPropertyNotFound)
+}
+
+func TestParseNotePropertyFilters(t *testing.T) {
+	filters, err := ParseNotePropertyFilters([]string{"Status = reading", `author != "Frank Herbert"`, "pages>=100", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []NotePropertyFilter{
+		{Key: "status", Operator: "=", Value: "reading"},
+		{Key: "author", Operator: "!=", Value: "Frank Herbert"},
+		{Key: "pages", Operator: ">=", Value: "100"},
+	}, filters)
+
+	_, err = ParseNotePropertyFilters([]string{"status reading"})
+	assert.ErrorIs(t, err, ErrInvalidPropertyFilter)
+	_, err = ParseNotePropertyFilters([]string{"status > reading"})
+	assert.ErrorIs(t, err, ErrInvalidPropertyFilter, "Range filters need a number or date")
+	_, err = ParseNotePropertyFilters([]string{"status ="})
+	assert.ErrorIs(t, err, ErrInvalidPropertyFilter)
+}
+
+func TestApplyNotePropertyFilters(t *testing.T) {
+	service := setupTestNotePropertyService(t)
+	dune := createPropertyNote(t, service.db, "Dune")
+	hobbit := createPropertyNote(t, service.db, "The Hobbit")
+	createPropertyNote(t, service.db, "Untagged")
+
+	for _, property := range []struct{ noteID, key, propertyType, value string }{
+		{dune.ID, "status", models.NotePropertySelect, "Reading"},
+		{dune.ID, "pages", models.NotePropertyNumber, "412"},
+		{dune.ID, "started", models.NotePropertyDate, "2024-03-01"},
+		{hobbit.ID, "status", models.NotePropertySelect, "done"},
+		{hobbit.ID, "pages", models.NotePropertyNumber, "95"},
+		{hobbit.ID, "started", models.NotePropertyDate, "2023-11-20"},
+	} {
+		_, err := service.SetProperty(property.noteID, property.key, property.propertyType, property.value)
+		require.NoError(t, err)
+	}
+
+	search := func(expressions ...string) []string {
+		filters, err := ParseNotePropertyFilters(expressions)
+		require.NoError(t, err)
+		var names []string
+		require.NoError(t, ApplyNotePropertyFilters(service.db.Model(&models.Notes{}), filters).Order("name").Pluck("name", &names).Error)
+		return names
+	}
+
+	assert.Equal(t, []string{"Dune"}, search("status = reading"), "Equality ignores case")
+	assert.Equal(t, []string{"The Hobbit", "Untagged"}, search("status != reading"))
+	assert.Equal(t, []string{"Dune"}, search("pages >= 100"))
+	assert.Equal(t, []string{"The Hobbit"}, search("started < 2024-01-01"))
+	assert.Empty(t, search("status = reading", "pages < 100"), "All filters must match")
+}
+
+func TestNotePropertiesFrontMatter(t *testing.T) {
+	frontMatter := NotePropertiesFrontMatter([]models.NoteProperty{
+		{Key: "author", Type: models.NotePropertyPerson, Value: `Frank "F" Herbert`},
+		{Key: "pages", Type: models.NotePropertyNumber, Value: "412"},
+		{Key: "started", Type: models.NotePropertyDate, Value: "2024-03-01"},
+	})
+	assert.Equal(t, "author: \"Frank \\\"F\\\" Herbert\"\npages: 412\nstarted: 2024-03-01\n", frontMatter)
+}
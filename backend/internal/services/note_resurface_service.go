@@ -0,0 +1,273 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxResurfaceCandidates caps the notes considered for review, least recently updated first
+	maxResurfaceCandidates = 2000
+	// resurfaceCooldown keeps notes seen recently out of review mode
+	resurfaceCooldown = 24 * time.Hour
+	// maxResurfaceStaleDays caps how much a note's time unseen counts, so very old notes don't always win
+	maxResurfaceStaleDays = 365
+	// resurfaceFavoriteWeight multiplies the weight of favorite notes
+	resurfaceFavoriteWeight = 3.0
+	// resurfaceTagWeight raises the weight of a note per tag, up to maxResurfaceWeightedTags tags
+	resurfaceTagWeight       = 0.25
+	maxResurfaceWeightedTags = 4
+)
+
+// ErrNothingToReview is returned when every note in scope was seen recently
+var ErrNothingToReview = errors.New("nothing to review right now")
+
+// ResurfaceScope selects the notes review mode draws from: a user's personal notes, or an organization's
+type ResurfaceScope struct {
+	ClerkUserID    string
+	OrganizationID *string
+	Tag            string // Optional, only notes with the #tag
+}
+
+// ResurfacedNote is the next note to review, with why it came up
+type ResurfacedNote struct {
+	NoteID       string     `json:"noteId"`
+	Name         string     `json:"name"`
+	Summary      string     `json:"summary,omitempty"`
+	ChapterID    string     `json:"chapterId"`
+	ChapterName  string     `json:"chapterName"`
+	NotebookID   string     `json:"notebookId"`
+	NotebookName string     `json:"notebookName"`
+	Tags         []string   `json:"tags"`
+	Favorite     bool       `json:"favorite"`
+	LastSeenAt   *time.Time `json:"lastSeenAt,omitempty"` // Last viewed, edited or reviewed by the user this past year
+	ReviewCount  int64      `json:"reviewCount"`
+	Remaining    int        `json:"remaining"` // Notes in scope not seen recently, this one included
+}
+
+// resurfaceCandidate is a note review mode can pick
+type resurfaceCandidate struct {
+	ID           string
+	Name         string
+	Summary      string
+	ChapterID    string
+	ChapterName  string
+	NotebookID   string
+	NotebookName string
+	CreatedAt    time.Time
+	lastSeenAt   *time.Time
+	favorite     bool
+	tags         []string
+}
+
+// noteSighting is a time a user saw a note
+type noteSighting struct {
+	NoteID    string
+	CreatedAt time.Time
+}
+
+// NoteResurfaceService interface defines methods for review mode, which resurfaces notes a user hasn't seen in a while
+type NoteResurfaceService interface {
+	Next(ctx context.Context, scope ResurfaceScope) (*ResurfacedNote, error)
+	Done(ctx context.Context, clerkUserID, noteID string) (*models.NoteResurfaceEvent, error)
+	SetFavorite(ctx context.Context, clerkUserID, noteID string, favorite bool) error
+}
+
+// noteResurfaceServiceImpl implements the NoteResurfaceService interface
+type noteResurfaceServiceImpl struct {
+	db     *gorm.DB
+	random func() float64
+	now    func() time.Time
+}
+
+// NewNoteResurfaceService creates a new NoteResurfaceService instance
+func NewNoteResurfaceService() NoteResurfaceService {
+	return &noteResurfaceServiceImpl{
+		db:     db.DB,
+		random: rand.Float64,
+		now:    time.Now,
+	}
+}
+
+// Next picks the next note to review. Notes are drawn at random, weighted by how long the user hasn't seen
+// them, and weighted up when they're favorites or tagged. Notes seen in the last day are left out.
+func (s *noteResurfaceServiceImpl) Next(ctx context.Context, scope ResurfaceScope) (*ResurfacedNote, error) {
+	query := s.db.WithContext(ctx).Table("notes").
+		Select("notes.id, notes.name, notes.summary, notes.chapter_id, chapters.name AS chapter_name, notebooks.id AS notebook_id, notebooks.name AS notebook_name, notes.created_at").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notebooks.encrypted = ? AND notes.locked = ? AND notes.status <> ?", false, false, models.NoteStatusArchived)
+	if scope.OrganizationID != nil && *scope.OrganizationID != "" {
+		query = query.Where("notebooks.organization_id = ?", *scope.OrganizationID)
+	} else {
+		query = query.Where("notebooks.clerk_user_id = ? AND notebooks.organization_id IS NULL", scope.ClerkUserID)
+	}
+	if tag := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(scope.Tag), "#")); tag != "" {
+		query = query.Where("notes.id IN (?)", s.db.Model(&models.NoteTag{}).Select("note_id").Where("tag = ?", tag))
+	}
+
+	var candidates []resurfaceCandidate
+	if err := query.Order("notes.updated_at ASC").Limit(maxResurfaceCandidates).Scan(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notes to review: %w", err)
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNothingToReview
+	}
+
+	noteIDs := make([]string, len(candidates))
+	byID := make(map[string]*resurfaceCandidate, len(candidates))
+	for i := range candidates {
+		noteIDs[i] = candidates[i].ID
+		byID[candidates[i].ID] = &candidates[i]
+	}
+	if err := s.loadSignals(ctx, scope.ClerkUserID, noteIDs, byID); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	var due []*resurfaceCandidate
+	var weights []float64
+	total := 0.0
+	for i := range candidates {
+		candidate := &candidates[i]
+		if candidate.lastSeenAt != nil && now.Sub(*candidate.lastSeenAt) < resurfaceCooldown {
+			continue
+		}
+		weight := resurfaceWeight(candidate, now)
+		due = append(due, candidate)
+		weights = append(weights, weight)
+		total += weight
+	}
+	if len(due) == 0 {
+		return nil, ErrNothingToReview
+	}
+
+	picked := due[len(due)-1]
+	target := s.random() * total
+	for i, weight := range weights {
+		if target < weight {
+			picked = due[i]
+			break
+		}
+		target -= weight
+	}
+
+	var reviewCount int64
+	if err := s.db.WithContext(ctx).Model(&models.NoteResurfaceEvent{}).
+		Where("note_id = ? AND clerk_user_id = ?", picked.ID, scope.ClerkUserID).
+		Count(&reviewCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count note reviews: %w", err)
+	}
+
+	tags := picked.tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return &ResurfacedNote{
+		NoteID:       picked.ID,
+		Name:         picked.Name,
+		Summary:      picked.Summary,
+		ChapterID:    picked.ChapterID,
+		ChapterName:  picked.ChapterName,
+		NotebookID:   picked.NotebookID,
+		NotebookName: picked.NotebookName,
+		Tags:         tags,
+		Favorite:     picked.favorite,
+		LastSeenAt:   picked.lastSeenAt,
+		ReviewCount:  reviewCount,
+		Remaining:    len(due),
+	}, nil
+}
+
+// Done records that the user reviewed a note, keeping it out of review mode for a while
+func (s *noteResurfaceServiceImpl) Done(ctx context.Context, clerkUserID, noteID string) (*models.NoteResurfaceEvent, error) {
+	event := models.NoteResurfaceEvent{NoteID: noteID, ClerkUserID: clerkUserID, CreatedAt: s.now()}
+	if err := s.db.WithContext(ctx).Create(&event).Error; err != nil {
+		return nil, fmt.Errorf("failed to record note review: %w", err)
+	}
+
+	log.Debug().Str("note_id", noteID).Str("user_id", clerkUserID).Msg("Recorded note review")
+	return &event, nil
+}
+
+// SetFavorite adds a note to or removes it from the user's favorites
+func (s *noteResurfaceServiceImpl) SetFavorite(ctx context.Context, clerkUserID, noteID string, favorite bool) error {
+	if !favorite {
+		if err := s.db.WithContext(ctx).Where("note_id = ? AND clerk_user_id = ?", noteID, clerkUserID).
+			Delete(&models.NoteFavorite{}).Error; err != nil {
+			return fmt.Errorf("failed to remove favorite: %w", err)
+		}
+		return nil
+	}
+
+	entry := models.NoteFavorite{NoteID: noteID, ClerkUserID: clerkUserID}
+	if err := s.db.WithContext(ctx).Where(models.NoteFavorite{NoteID: noteID, ClerkUserID: clerkUserID}).
+		FirstOrCreate(&entry).Error; err != nil {
+		return fmt.Errorf("failed to save favorite: %w", err)
+	}
+	return nil
+}
+
+// loadSignals loads when the user last saw each note, their favorites and the notes' tags
+func (s *noteResurfaceServiceImpl) loadSignals(ctx context.Context, clerkUserID string, noteIDs []string, byID map[string]*resurfaceCandidate) error {
+	// Viewing or editing a note counts as seeing it, as does reviewing it. Older sightings weigh the same
+	// as never seeing the note, so they aren't loaded.
+	since := s.now().Add(-maxResurfaceStaleDays * 24 * time.Hour)
+	for _, table := range []string{"note_access_events", "note_resurface_events"} {
+		var seen []noteSighting
+		if err := s.db.WithContext(ctx).Table(table).
+			Select("note_id, created_at").
+			Where("clerk_user_id = ? AND note_id IN ? AND created_at > ?", clerkUserID, noteIDs, since).
+			Scan(&seen).Error; err != nil {
+			return fmt.Errorf("failed to fetch when notes were last seen: %w", err)
+		}
+		for _, row := range seen {
+			candidate := byID[row.NoteID]
+			if candidate.lastSeenAt == nil || row.CreatedAt.After(*candidate.lastSeenAt) {
+				seenAt := row.CreatedAt
+				candidate.lastSeenAt = &seenAt
+			}
+		}
+	}
+
+	var favorites []string
+	if err := s.db.WithContext(ctx).Model(&models.NoteFavorite{}).
+		Where("clerk_user_id = ? AND note_id IN ?", clerkUserID, noteIDs).
+		Pluck("note_id", &favorites).Error; err != nil {
+		return fmt.Errorf("failed to fetch favorites: %w", err)
+	}
+	for _, noteID := range favorites {
+		byID[noteID].favorite = true
+	}
+
+	var tags []models.NoteTag
+	if err := s.db.WithContext(ctx).Where("note_id IN ?", noteIDs).Order("tag ASC").Find(&tags).Error; err != nil {
+		return fmt.Errorf("failed to fetch note tags: %w", err)
+	}
+	for _, tag := range tags {
+		byID[tag.NoteID].tags = append(byID[tag.NoteID].tags, tag.Tag)
+	}
+	return nil
+}
+
+// resurfaceWeight is how likely a note is to come up next. Notes the user never saw count from their creation.
+func resurfaceWeight(candidate *resurfaceCandidate, now time.Time) float64 {
+	since := candidate.CreatedAt
+	if candidate.lastSeenAt != nil {
+		since = *candidate.lastSeenAt
+	}
+	weight := 1 + min(now.Sub(since).Hours()/24, maxResurfaceStaleDays)
+	if candidate.favorite {
+		weight *= resurfaceFavoriteWeight
+	}
+	return weight * (1 + resurfaceTagWeight*float64(min(len(candidate.tags), maxResurfaceWeightedTags)))
+}
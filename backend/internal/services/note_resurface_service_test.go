@@ -0,0 +1,129 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteResurfaceService creates a review mode service on an in-memory database
+func setupTestNoteResurfaceService(t *testing.T) *noteResurfaceServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteTag{},
+		&models.NoteAccessEvent{}, &models.NoteFavorite{}, &models.NoteResurfaceEvent{}), "Failed to migrate test database")
+
+	return &noteResurfaceServiceImpl{
+		db:     db,
+		random: func() float64 { return 0 },
+		now:    func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC) },
+	}
+}
+
+// createReviewNotes creates personal notes created a month before the service's now, in update order
+func createReviewNotes(t *testing.T, service *noteResurfaceServiceImpl, names ...string) []models.Notes {
+	notebook := models.Notebook{Name: "Zettelkasten", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Ideas", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&chapter).Error)
+
+	created := service.now().AddDate(0, -1, 0)
+	notes := make([]models.Notes, 0, len(names))
+	for i, name := range names {
+		updated := created.Add(time.Duration(i) * time.Minute)
+		note := models.Notes{Name: name, ChapterID: chapter.ID, CreatedAt: created, UpdatedAt: updated}
+		require.NoError(t, service.db.Create(&note).Error)
+		notes = append(notes, note)
+	}
+	return notes
+}
+
+func TestResurfaceNext(t *testing.T) {
+	service := setupTestNoteResurfaceService(t)
+	ctx := context.Background()
+	notes := createReviewNotes(t, service, "Seen yesterday", "Never seen", "Seen today")
+	scope := ResurfaceScope{ClerkUserID: "user_1"}
+
+	require.NoError(t, service.db.Create(&models.NoteAccessEvent{NoteID: notes[0].ID, ClerkUserID: "user_1",
+		Action: models.NoteAccessView, CreatedAt: service.now().Add(-30 * time.Hour)}).Error)
+	require.NoError(t, service.db.Create(&models.NoteAccessEvent{NoteID: notes[2].ID, ClerkUserID: "user_1",
+		Action: models.NoteAccessView, CreatedAt: service.now().Add(-time.Hour)}).Error)
+
+	// With the draw at the very end, the last due note comes up; at the start, the first one
+	service.random = func() float64 { return 0.999 }
+	next, err := service.Next(ctx, scope)
+	require.NoError(t, err)
+	assert.Equal(t, notes[1].ID, next.NoteID)
+	assert.Equal(t, 2, next.Remaining, "Notes seen in the last day are left out")
+	assert.Nil(t, next.LastSeenAt)
+
+	service.random = func() float64 { return 0 }
+	next, err = service.Next(ctx, scope)
+	require.NoError(t, err)
+	assert.Equal(t, notes[0].ID, next.NoteID)
+	require.NotNil(t, next.LastSeenAt)
+
+	_, err = service.Done(ctx, "user_1", notes[0].ID)
+	require.NoError(t, err)
+	_, err = service.Done(ctx, "user_1", notes[1].ID)
+	require.NoError(t, err)
+	_, err = service.Next(ctx, scope)
+	assert.ErrorIs(t, err, ErrNothingToReview, "Reviewed notes rest before coming up again")
+
+	service.now = func() time.Time { return time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC) }
+	next, err = service.Next(ctx, scope)
+	require.NoError(t, err)
+	assert.Equal(t, 3, next.Remaining)
+	assert.EqualValues(t, 1, next.ReviewCount)
+
+	_, err = service.Next(ctx, ResurfaceScope{ClerkUserID: "user_2"})
+	assert.ErrorIs(t, err, ErrNothingToReview, "Other users' personal notes aren't reviewed")
+}
+
+func TestResurfaceNext_FavoritesAndTags(t *testing.T) {
+	service := setupTestNoteResurfaceService(t)
+	ctx := context.Background()
+	notes := createReviewNotes(t, service, "Plain", "Favorite", "Tagged")
+
+	require.NoError(t, service.SetFavorite(ctx, "user_1", notes[1].ID, true))
+	require.NoError(t, service.SetFavorite(ctx, "user_1", notes[1].ID, true), "Favoriting twice is harmless")
+	require.NoError(t, service.db.Create(&models.NoteTag{NoteID: notes[2].ID, Tag: "golang"}).Error)
+
+	next, err := service.Next(ctx, ResurfaceScope{ClerkUserID: "user_1", Tag: "#GoLang"})
+	require.NoError(t, err)
+	assert.Equal(t, notes[2].ID, next.NoteID)
+	assert.Equal(t, []string{"golang"}, next.Tags)
+	assert.Equal(t, 1, next.Remaining, "The tag filter narrows review to tagged notes")
+
+	var candidates []resurfaceCandidate
+	for _, note := range notes {
+		candidates = append(candidates, resurfaceCandidate{ID: note.ID, CreatedAt: note.CreatedAt})
+	}
+	byID := map[string]*resurfaceCandidate{}
+	for i := range candidates {
+		byID[candidates[i].ID] = &candidates[i]
+	}
+	require.NoError(t, service.loadSignals(ctx, "user_1", []string{notes[0].ID, notes[1].ID, notes[2].ID}, byID))
+	plain := resurfaceWeight(&candidates[0], service.now())
+	assert.InDelta(t, plain*resurfaceFavoriteWeight, resurfaceWeight(&candidates[1], service.now()), 0.001)
+	assert.Greater(t, resurfaceWeight(&candidates[2], service.now()), plain)
+
+	require.NoError(t, service.SetFavorite(ctx, "user_1", notes[1].ID, false))
+	var count int64
+	require.NoError(t, service.db.Model(&models.NoteFavorite{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestResurfaceWeight_CapsStaleness(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	old := resurfaceWeight(&resurfaceCandidate{CreatedAt: now.AddDate(-5, 0, 0)}, now)
+	year := resurfaceWeight(&resurfaceCandidate{CreatedAt: now.AddDate(-1, 0, -1)}, now)
+	assert.Equal(t, year, old, "Very old notes don't crowd out the rest")
+	assert.Greater(t, old, resurfaceWeight(&resurfaceCandidate{CreatedAt: now.AddDate(0, -1, 0)}, now))
+}
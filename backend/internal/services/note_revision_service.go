@@ -0,0 +1,202 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrNoPendingNoteRevision is returned when a note without a pending revision is published or discarded
+var ErrNoPendingNoteRevision = errors.New("the note has no pending revision")
+
+// NoteRevisionService interface defines methods for the draft and live versions of published notes in
+// organizations that require approval
+type NoteRevisionService interface {
+	RequiresRevision(noteID string) (bool, error)
+	SaveRevision(noteID, userID, name, content string) (*models.NoteRevision, error)
+	PendingRevision(noteID string) (*models.NoteRevision, error)
+	ListRevisions(noteID string) ([]models.NoteRevision, error)
+	PublishRevision(noteID, editorID string) (*models.Notes, *models.NoteRevision, error)
+	DiscardRevision(noteID, userID string) (*models.NoteRevision, error)
+}
+
+// noteRevisionServiceImpl implements the NoteRevisionService interface
+type noteRevisionServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewNoteRevisionService creates a new NoteRevisionService instance
+func NewNoteRevisionService() NoteRevisionService {
+	return &noteRevisionServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// RequiresRevision reports whether edits to a note wait in a revision: it's published, not locked, and
+// its organization only publishes approved notes
+func (s *noteRevisionServiceImpl) RequiresRevision(noteID string) (bool, error) {
+	var note models.Notes
+	if err := s.db.Select("id", "organization_id", "is_public", "locked").Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, ErrNoteNotFound
+		}
+		return false, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if !note.IsPublic || note.Locked || note.OrganizationID == nil || *note.OrganizationID == "" {
+		return false, nil
+	}
+	return requiresApproval(s.db, *note.OrganizationID)
+}
+
+// SaveRevision records an edit in the note's pending revision, opening one when there is none. An empty
+// name or content leaves that part of the revision as it was.
+func (s *noteRevisionServiceImpl) SaveRevision(noteID, userID, name, content string) (*models.NoteRevision, error) {
+	name = strings.TrimSpace(name)
+	var revision models.NoteRevision
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var note models.Notes
+		if err := tx.Select("id", "name", "content", "organization_id").Where("id = ?", noteID).First(&note).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoteNotFound
+			}
+			return fmt.Errorf("failed to fetch note: %w", err)
+		}
+		if note.OrganizationID == nil || *note.OrganizationID == "" {
+			return ErrNoteNotInOrganization
+		}
+
+		err := tx.Where("note_id = ? AND status = ?", noteID, models.NoteRevisionPending).First(&revision).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to fetch note revision: %w", err)
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			revision = models.NoteRevision{
+				NoteID:         noteID,
+				OrganizationID: *note.OrganizationID,
+				Status:         models.NoteRevisionPending,
+				CreatedBy:      userID,
+			}
+		}
+
+		previous := revision.Content
+		if previous == "" {
+			previous = note.Content
+		}
+		if name != "" {
+			revision.Name = name
+		}
+		if content != "" {
+			revision.Content = AssignTipTapBlockIDs(previous, content)
+		}
+		revision.UpdatedBy = userID
+		if err := tx.Save(&revision).Error; err != nil {
+			return fmt.Errorf("failed to save note revision: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// PendingRevision returns the note's pending revision, or nil when there is none
+func (s *noteRevisionServiceImpl) PendingRevision(noteID string) (*models.NoteRevision, error) {
+	var revisions []models.NoteRevision
+	if err := s.db.Where("note_id = ? AND status = ?", noteID, models.NoteRevisionPending).Limit(1).Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note revision: %w", err)
+	}
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+	return &revisions[0], nil
+}
+
+// ListRevisions returns the revision history of a note, newest first, without their content
+func (s *noteRevisionServiceImpl) ListRevisions(noteID string) ([]models.NoteRevision, error) {
+	revisions := []models.NoteRevision{}
+	if err := s.db.Omit("content").Where("note_id = ?", noteID).Order("created_at DESC").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// PublishRevision makes the note's pending revision its live version
+func (s *noteRevisionServiceImpl) PublishRevision(noteID, editorID string) (*models.Notes, *models.NoteRevision, error) {
+	var note models.Notes
+	var revision models.NoteRevision
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.closeRevision(tx, noteID, editorID, models.NoteRevisionPublished, &revision); err != nil {
+			return err
+		}
+		updates := map[string]interface{}{}
+		if revision.Name != "" {
+			updates["name"] = revision.Name
+		}
+		if revision.Content != "" {
+			updates["content"] = revision.Content
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&models.Notes{}).Where("id = ?", noteID).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to publish note revision: %w", err)
+			}
+		}
+		if revision.Name != "" {
+			if _, err := RefreshNoteSlug(tx, noteID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("id = ?", noteID).First(&note).Error; err != nil {
+			return fmt.Errorf("failed to fetch note: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Info().Str("note_id", noteID).Str("editor_id", editorID).Str("revision_id", revision.ID).Msg("Note revision published")
+	return &note, &revision, nil
+}
+
+// DiscardRevision drops the note's pending revision, leaving its live version as it is
+func (s *noteRevisionServiceImpl) DiscardRevision(noteID, userID string) (*models.NoteRevision, error) {
+	var revision models.NoteRevision
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.closeRevision(tx, noteID, userID, models.NoteRevisionDiscarded, &revision)
+	}); err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("note_id", noteID).Str("user_id", userID).Str("revision_id", revision.ID).Msg("Note revision discarded")
+	return &revision, nil
+}
+
+// closeRevision records that the note's pending revision was published or discarded
+func (s *noteRevisionServiceImpl) closeRevision(tx *gorm.DB, noteID, userID, status string, revision *models.NoteRevision) error {
+	if err := tx.Where("note_id = ? AND status = ?", noteID, models.NoteRevisionPending).First(revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNoPendingNoteRevision
+		}
+		return fmt.Errorf("failed to fetch note revision: %w", err)
+	}
+	closedAt := s.now()
+	result := tx.Model(&models.NoteRevision{}).Where("id = ? AND status = ?", revision.ID, models.NoteRevisionPending).
+		Updates(map[string]interface{}{"status": status, "closed_by": userID, "closed_at": closedAt})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update note revision: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoPendingNoteRevision
+	}
+	revision.Status, revision.ClosedBy, revision.ClosedAt = status, userID, &closedAt
+	return nil
+}
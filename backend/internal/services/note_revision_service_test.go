@@ -0,0 +1,113 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteRevisionService creates a note revision service on an in-memory database
+func setupTestNoteRevisionService(t *testing.T) *noteRevisionServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteRevision{}, &models.OrganizationPublishingSettings{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &noteRevisionServiceImpl{
+		db:  db,
+		now: func() time.Time { return time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC) },
+	}
+}
+
+func TestNoteRevisions_RequireRevisionInReviewMode(t *testing.T) {
+	service := setupTestNoteRevisionService(t)
+	orgID := "org_1"
+	note := createLifecycleNote(t, service.db, &orgID)
+	personal := createLifecycleNote(t, service.db, nil)
+
+	requires, err := service.RequiresRevision(note.ID)
+	require.NoError(t, err)
+	assert.False(t, requires, "Organizations publish edits directly until they require approval")
+
+	require.NoError(t, service.db.Create(&models.OrganizationPublishingSettings{OrganizationID: orgID, RequireApproval: true}).Error)
+	requires, err = service.RequiresRevision(note.ID)
+	require.NoError(t, err)
+	assert.True(t, requires)
+
+	requires, err = service.RequiresRevision(personal.ID)
+	require.NoError(t, err)
+	assert.False(t, requires, "Personal notes don't have revisions")
+
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", note.ID).Update("is_public", false).Error)
+	requires, err = service.RequiresRevision(note.ID)
+	require.NoError(t, err)
+	assert.False(t, requires, "Unpublished notes are edited directly")
+
+	_, err = service.RequiresRevision("missing")
+	assert.ErrorIs(t, err, ErrNoteNotFound)
+}
+
+func TestNoteRevisions_SaveAndPublish(t *testing.T) {
+	service := setupTestNoteRevisionService(t)
+	orgID := "org_1"
+	note := createLifecycleNote(t, service.db, &orgID)
+	live := `{"type":"doc","content":[{"type":"paragraph","attrs":{"id":"b1"},"content":[{"type":"text","text":"Receipts within 30 days"}]}]}`
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", note.ID).Update("content", live).Error)
+
+	draft := `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"Receipts within 30 days"}]},{"type":"paragraph","content":[{"type":"text","text":"Scans are fine"}]}]}`
+	revision, err := service.SaveRevision(note.ID, "user_writer", "", draft)
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteRevisionPending, revision.Status)
+	assert.Contains(t, revision.Content, `"id":"b1"`, "Block IDs are kept from the live version")
+
+	renamed, err := service.SaveRevision(note.ID, "user_editor", " Expense policy ", "")
+	require.NoError(t, err)
+	assert.Equal(t, revision.ID, renamed.ID, "Later edits update the pending revision")
+	assert.Equal(t, "Expense policy", renamed.Name)
+	assert.Equal(t, revision.Content, renamed.Content, "Renaming keeps the edited content")
+	assert.Equal(t, "user_writer", renamed.CreatedBy)
+	assert.Equal(t, "user_editor", renamed.UpdatedBy)
+
+	var current models.Notes
+	require.NoError(t, service.db.First(&current, "id = ?", note.ID).Error)
+	assert.Equal(t, live, current.Content, "The live version doesn't change until the revision is published")
+	assert.Equal(t, "Expenses", current.Name)
+
+	published, closed, err := service.PublishRevision(note.ID, "user_editor")
+	require.NoError(t, err)
+	assert.Equal(t, "Expense policy", published.Name)
+	assert.Equal(t, "expense-policy", published.Slug)
+	assert.Contains(t, published.Content, "Scans are fine")
+	assert.Equal(t, models.NoteRevisionPublished, closed.Status)
+	assert.Equal(t, "user_editor", closed.ClosedBy)
+
+	pending, err := service.PendingRevision(note.ID)
+	require.NoError(t, err)
+	assert.Nil(t, pending)
+	_, _, err = service.PublishRevision(note.ID, "user_editor")
+	assert.ErrorIs(t, err, ErrNoPendingNoteRevision)
+
+	// Discarded revisions leave the live version as it is
+	_, err = service.SaveRevision(note.ID, "user_writer", "", `{"type":"doc","content":[]}`)
+	require.NoError(t, err)
+	discarded, err := service.DiscardRevision(note.ID, "user_editor")
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteRevisionDiscarded, discarded.Status)
+	require.NoError(t, service.db.First(&current, "id = ?", note.ID).Error)
+	assert.Contains(t, current.Content, "Scans are fine")
+
+	revisions, err := service.ListRevisions(note.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Empty(t, revisions[0].Content, "The history leaves out the content")
+
+	personal := createLifecycleNote(t, service.db, nil)
+	_, err = service.SaveRevision(personal.ID, "user_owner", "Renamed", "")
+	assert.ErrorIs(t, err, ErrNoteNotInOrganization)
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	maxNoteSuggestionContentLength = 200000
+	maxNoteSuggestionCommentLength = 1000
+	// maxPendingNoteSuggestions caps the suggestions one reader has waiting for review on a note
+	maxPendingNoteSuggestions = 3
+)
+
+var (
+	// ErrNoteSuggestionNotFound is returned when a suggestion doesn't exist
+	ErrNoteSuggestionNotFound = errors.New("suggestion not found")
+	// ErrInvalidNoteSuggestion is returned for suggestions that can't be stored
+	ErrInvalidNoteSuggestion = errors.New("invalid suggestion")
+	// ErrNoteSuggestionStale is returned when the note changed since the reader loaded it
+	ErrNoteSuggestionStale = errors.New("the note changed since you loaded it, reload it and suggest your edit again")
+	// ErrNoteSuggestionReviewed is returned when accepting or rejecting a suggestion that was already reviewed
+	ErrNoteSuggestionReviewed = errors.New("this suggestion was already reviewed")
+	// ErrNoteSuggestionConflicts is returned when a suggestion changes blocks the author edited since
+	ErrNoteSuggestionConflicts = errors.New("the suggestion conflicts with changes made to the note since")
+	// ErrTooManyNoteSuggestions is returned when a reader has too many suggestions waiting on a note
+	ErrTooManyNoteSuggestions = errors.New("you have too many suggestions waiting for review on this note")
+)
+
+// NoteSuggestionInput is the request body for suggesting an edit to a public note
+type NoteSuggestionInput struct {
+	Content       string    `json:"content"`       // TipTap JSON of the reader's version of the public note
+	BaseUpdatedAt time.Time `json:"baseUpdatedAt"` // updatedAt of the public note the reader edited
+	Comment       string    `json:"comment"`
+}
+
+// NoteSuggestionReview is a suggestion with what the author needs to review it: the public note the
+// reader edited and their version as Markdown, and for pending suggestions the note as it would be
+// after accepting
+type NoteSuggestionReview struct {
+	models.NoteSuggestion
+	NoteName          string  `json:"noteName"`
+	OrganizationID    *string `json:"organizationId,omitempty"`
+	BaseMarkdown      string  `json:"baseMarkdown"`
+	SuggestedMarkdown string  `json:"suggestedMarkdown"`
+	MergedContent     string  `json:"mergedContent,omitempty"`
+	ConflictCount     int     `json:"conflictCount"` // Blocks the reader and the author both changed
+}
+
+// NoteSuggestionService interface defines methods for readers' suggested edits to public notes
+type NoteSuggestionService interface {
+	SubmitSuggestion(ctx context.Context, noteID, clerkUserID string, input NoteSuggestionInput) (*models.NoteSuggestion, error)
+	ListSuggestions(ctx context.Context, notebookID, status string) ([]models.NoteSuggestion, error)
+	GetSuggestion(ctx context.Context, suggestionID string) (*NoteSuggestionReview, error)
+	AcceptSuggestion(ctx context.Context, suggestionID, reviewedBy, comment string) (*models.Notes, error)
+	RejectSuggestion(ctx context.Context, suggestionID, reviewedBy, comment string) (*models.NoteSuggestion, error)
+}
+
+// noteSuggestionServiceImpl implements the NoteSuggestionService interface
+type noteSuggestionServiceImpl struct {
+	db             *gorm.DB
+	renderPublic   func(noteID, content string) (string, error)
+	validateEmbeds func(noteID, content string) error
+	now            func() time.Time
+}
+
+// NewNoteSuggestionService creates a new NoteSuggestionService instance
+func NewNoteSuggestionService() NoteSuggestionService {
+	embeds := NewNoteEmbedService()
+	return &noteSuggestionServiceImpl{
+		db: db.DB,
+		renderPublic: func(noteID, content string) (string, error) {
+			return embeds.RenderContent(noteID, content, func(note *models.Notes) bool {
+				return note.IsPublic && note.Chapter.IsPublic && note.Chapter.Notebook.IsPublic
+			})
+		},
+		validateEmbeds: embeds.ValidateEmbeds,
+		now:            time.Now,
+	}
+}
+
+// SubmitSuggestion records a reader's edit of a public note for its author to review. The public note as
+// the reader saw it is kept, so the edit can be merged with the author's later changes.
+func (s *noteSuggestionServiceImpl) SubmitSuggestion(ctx context.Context, noteID, clerkUserID string, input NoteSuggestionInput) (*models.NoteSuggestion, error) {
+	comment := strings.TrimSpace(input.Comment)
+	if utf8.RuneCountInString(comment) > maxNoteSuggestionCommentLength {
+		return nil, fmt.Errorf("%w: comments can be at most %d characters", ErrInvalidNoteSuggestion, maxNoteSuggestionCommentLength)
+	}
+	if len(input.Content) > maxNoteSuggestionContentLength {
+		return nil, fmt.Errorf("%w: the suggested content is too long", ErrInvalidNoteSuggestion)
+	}
+	if input.BaseUpdatedAt.IsZero() {
+		return nil, fmt.Errorf("%w: baseUpdatedAt is required", ErrInvalidNoteSuggestion)
+	}
+	suggestedBlocks, err := parseMergeBlocks(input.Content)
+	if err != nil || len(suggestedBlocks) == 0 {
+		return nil, fmt.Errorf("%w: content must be a TipTap document", ErrInvalidNoteSuggestion)
+	}
+
+	var note models.Notes
+	if err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPublicNoteNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if !note.IsPublic || !note.Chapter.IsPublic || !note.Chapter.Notebook.IsPublic || note.Locked || note.Chapter.Notebook.Encrypted {
+		return nil, ErrPublicNoteNotFound
+	}
+	if !note.UpdatedAt.Equal(input.BaseUpdatedAt) {
+		return nil, ErrNoteSuggestionStale
+	}
+
+	base, err := s.renderPublic(note.ID, note.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render note: %w", err)
+	}
+	baseBlocks, err := parseMergeBlocks(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read note: %w", err)
+	}
+	if slices.Equal(mergeBlockKeys(baseBlocks), mergeBlockKeys(suggestedBlocks)) {
+		return nil, fmt.Errorf("%w: the suggestion doesn't change the note", ErrInvalidNoteSuggestion)
+	}
+
+	var pending int64
+	if err := s.db.WithContext(ctx).Model(&models.NoteSuggestion{}).
+		Where("note_id = ? AND suggested_by = ? AND status = ?", noteID, clerkUserID, models.NoteSuggestionPending).
+		Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to count pending suggestions: %w", err)
+	}
+	if pending >= maxPendingNoteSuggestions {
+		return nil, ErrTooManyNoteSuggestions
+	}
+
+	suggestion := models.NoteSuggestion{
+		NoteID:           noteID,
+		NotebookID:       note.Chapter.NotebookID,
+		SuggestedBy:      clerkUserID,
+		Comment:          comment,
+		BaseContent:      base,
+		SuggestedContent: input.Content,
+		Status:           models.NoteSuggestionPending,
+	}
+	if err := s.db.WithContext(ctx).Create(&suggestion).Error; err != nil {
+		return nil, fmt.Errorf("failed to save suggestion: %w", err)
+	}
+	return &suggestion, nil
+}
+
+// ListSuggestions returns a notebook's suggestions with the given status, all of them when empty, newest first
+func (s *noteSuggestionServiceImpl) ListSuggestions(ctx context.Context, notebookID, status string) ([]models.NoteSuggestion, error) {
+	query := s.db.WithContext(ctx).Where("notebook_id = ?", notebookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	suggestions := []models.NoteSuggestion{}
+	if err := query.Order("created_at DESC").Find(&suggestions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch suggestions: %w", err)
+	}
+	return suggestions, nil
+}
+
+// GetSuggestion returns a suggestion for review. Pending suggestions are merged with the note's current
+// content to preview accepting them.
+func (s *noteSuggestionServiceImpl) GetSuggestion(ctx context.Context, suggestionID string) (*NoteSuggestionReview, error) {
+	suggestion, err := s.suggestion(s.db.WithContext(ctx), suggestionID)
+	if err != nil {
+		return nil, err
+	}
+	var note models.Notes
+	if err := s.db.WithContext(ctx).Where("id = ?", suggestion.NoteID).First(&note).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	review := &NoteSuggestionReview{NoteSuggestion: *suggestion, NoteName: note.Name, OrganizationID: note.OrganizationID}
+	review.BaseMarkdown, _ = utils.TipTapToMarkdown(suggestion.BaseContent)
+	review.SuggestedMarkdown, _ = utils.TipTapToMarkdown(suggestion.SuggestedContent)
+	if suggestion.Status == models.NoteSuggestionPending && !note.Locked {
+		review.MergedContent, review.ConflictCount, err = MergeTipTapContent(suggestion.BaseContent, suggestion.SuggestedContent, note.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge suggestion: %w", err)
+		}
+	}
+	return review, nil
+}
+
+// AcceptSuggestion applies a pending suggestion to its note, merged with the changes made to the note since
+// it was suggested, and returns the updated note. The note's previous content is kept on the suggestion.
+func (s *noteSuggestionServiceImpl) AcceptSuggestion(ctx context.Context, suggestionID, reviewedBy, comment string) (*models.Notes, error) {
+	var note models.Notes
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		suggestion, err := s.suggestion(tx, suggestionID)
+		if err != nil {
+			return err
+		}
+		if suggestion.Status != models.NoteSuggestionPending {
+			return ErrNoteSuggestionReviewed
+		}
+		if err := tx.Preload("Chapter.Notebook").Where("id = ?", suggestion.NoteID).First(&note).Error; err != nil {
+			return fmt.Errorf("failed to fetch note: %w", err)
+		}
+		if note.Locked {
+			return ErrNoteLocked
+		}
+		if note.Chapter.Notebook.Encrypted {
+			return ErrNotebookEncrypted
+		}
+
+		merged, conflicts, err := MergeTipTapContent(suggestion.BaseContent, suggestion.SuggestedContent, note.Content)
+		if err != nil {
+			return fmt.Errorf("failed to merge suggestion: %w", err)
+		}
+		if conflicts > 0 {
+			return fmt.Errorf("%w: %d blocks were changed by both", ErrNoteSuggestionConflicts, conflicts)
+		}
+		if err := s.validateEmbeds(note.ID, merged); err != nil {
+			return err
+		}
+
+		now := s.now()
+		if err := tx.Model(suggestion).Updates(map[string]interface{}{
+			"status":           models.NoteSuggestionAccepted,
+			"previous_content": note.Content,
+			"reviewed_by":      reviewedBy,
+			"review_comment":   strings.TrimSpace(comment),
+			"reviewed_at":      now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update suggestion: %w", err)
+		}
+		if err := tx.Model(&note).Update("content", merged).Error; err != nil {
+			return fmt.Errorf("failed to update note: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("suggestion_id", suggestionID).
+		Str("note_id", note.ID).
+		Str("reviewed_by", reviewedBy).
+		Msg("Accepted note suggestion")
+	return &note, nil
+}
+
+// RejectSuggestion declines a pending suggestion, leaving its note unchanged
+func (s *noteSuggestionServiceImpl) RejectSuggestion(ctx context.Context, suggestionID, reviewedBy, comment string) (*models.NoteSuggestion, error) {
+	suggestion, err := s.suggestion(s.db.WithContext(ctx), suggestionID)
+	if err != nil {
+		return nil, err
+	}
+	if suggestion.Status != models.NoteSuggestionPending {
+		return nil, ErrNoteSuggestionReviewed
+	}
+
+	now := s.now()
+	result := s.db.WithContext(ctx).Model(suggestion).Where("status = ?", models.NoteSuggestionPending).Updates(map[string]interface{}{
+		"status":         models.NoteSuggestionRejected,
+		"reviewed_by":    reviewedBy,
+		"review_comment": strings.TrimSpace(comment),
+		"reviewed_at":    now,
+	})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update suggestion: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNoteSuggestionReviewed
+	}
+	suggestion.Status = models.NoteSuggestionRejected
+	suggestion.ReviewedBy = reviewedBy
+	suggestion.ReviewComment = strings.TrimSpace(comment)
+	suggestion.ReviewedAt = &now
+	return suggestion, nil
+}
+
+func (s *noteSuggestionServiceImpl) suggestion(tx *gorm.DB, suggestionID string) (*models.NoteSuggestion, error) {
+	var suggestion models.NoteSuggestion
+	if err := tx.Where("id = ?", suggestionID).First(&suggestion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteSuggestionNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch suggestion: %w", err)
+	}
+	return &suggestion, nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteSuggestionService creates a note suggestion service on an in-memory database
+func setupTestNoteSuggestionService(t *testing.T) *noteSuggestionServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteSuggestion{}),
+		"Failed to migrate test database")
+
+	return &noteSuggestionServiceImpl{
+		db:             db,
+		renderPublic:   func(noteID, content string) (string, error) { return content, nil },
+		validateEmbeds: func(noteID, content string) error { return nil },
+		now:            func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC) },
+	}
+}
+
+func suggestionContent(t *testing.T, paragraphs ...string) string {
+	content, err := utils.MarkdownToTipTap(strings.Join(paragraphs, "\n\n"))
+	require.NoError(t, err)
+	return content
+}
+
+// createSuggestableNote creates a public note of three paragraphs
+func createSuggestableNote(t *testing.T, service *noteSuggestionServiceImpl) models.Notes {
+	notebook := models.Notebook{Name: "Handbook", ClerkUserID: "author_1", IsPublic: true}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Engineering", NotebookID: notebook.ID, IsPublic: true}
+	require.NoError(t, service.db.Create(&chapter).Error)
+	note := models.Notes{Name: "Deploys", ChapterID: chapter.ID, IsPublic: true,
+		Content: suggestionContent(t, "We deploy on Fridays.", "Deploys need a checklist.", "Ask in the channel.")}
+	require.NoError(t, service.db.Create(&note).Error)
+	require.NoError(t, service.db.Preload("Chapter").First(&note, "id = ?", note.ID).Error)
+	return note
+}
+
+func TestSubmitSuggestion(t *testing.T) {
+	service := setupTestNoteSuggestionService(t)
+	ctx := context.Background()
+	note := createSuggestableNote(t, service)
+	edited := suggestionContent(t, "We deploy on Thursdays.", "Deploys need a checklist.", "Ask in the channel.")
+
+	_, err := service.SubmitSuggestion(ctx, note.ID, "reader_1", NoteSuggestionInput{Content: note.Content, BaseUpdatedAt: note.UpdatedAt})
+	assert.ErrorIs(t, err, ErrInvalidNoteSuggestion, "Suggestions must change the note")
+	_, err = service.SubmitSuggestion(ctx, note.ID, "reader_1", NoteSuggestionInput{Content: "not json", BaseUpdatedAt: note.UpdatedAt})
+	assert.ErrorIs(t, err, ErrInvalidNoteSuggestion)
+	_, err = service.SubmitSuggestion(ctx, note.ID, "reader_1", NoteSuggestionInput{Content: edited, BaseUpdatedAt: note.UpdatedAt.Add(-time.Hour)})
+	assert.ErrorIs(t, err, ErrNoteSuggestionStale)
+
+	suggestion, err := service.SubmitSuggestion(ctx, note.ID, "reader_1", NoteSuggestionInput{Content: edited, BaseUpdatedAt: note.UpdatedAt, Comment: " Typo "})
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteSuggestionPending, suggestion.Status)
+	assert.Equal(t, "Typo", suggestion.Comment)
+
+	for i := 1; i < maxPendingNoteSuggestions; i++ {
+		_, err = service.SubmitSuggestion(ctx, note.ID, "reader_1", NoteSuggestionInput{Content: edited, BaseUpdatedAt: note.UpdatedAt})
+		require.NoError(t, err)
+	}
+	_, err = service.SubmitSuggestion(ctx, note.ID, "reader_1", NoteSuggestionInput{Content: edited, BaseUpdatedAt: note.UpdatedAt})
+	assert.ErrorIs(t, err, ErrTooManyNoteSuggestions)
+
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", note.ID).Update("is_public", false).Error)
+	_, err = service.SubmitSuggestion(ctx, note.ID, "reader_2", NoteSuggestionInput{Content: edited, BaseUpdatedAt: note.UpdatedAt})
+	assert.ErrorIs(t, err, ErrPublicNoteNotFound)
+
+	suggestions, err := service.ListSuggestions(ctx, note.Chapter.NotebookID, models.NoteSuggestionPending)
+	require.NoError(t, err)
+	assert.Len(t, suggestions, maxPendingNoteSuggestions)
+}
+
+func TestAcceptSuggestion_MergesWithLaterEdits(t *testing.T) {
+	service := setupTestNoteSuggestionService(t)
+	ctx := context.Background()
+	note := createSuggestableNote(t, service)
+
+	suggestion, err := service.SubmitSuggestion(ctx, note.ID, "reader_1", NoteSuggestionInput{
+		Content:       suggestionContent(t, "We deploy on Thursdays.", "Deploys need a checklist.", "Ask in the channel."),
+		BaseUpdatedAt: note.UpdatedAt,
+	})
+	require.NoError(t, err)
+
+	// The author edits another paragraph after the suggestion was made
+	authorEdit := suggestionContent(t, "We deploy on Fridays.", "Deploys need a signed checklist.", "Ask in the channel.")
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", note.ID).Update("content", authorEdit).Error)
+
+	review, err := service.GetSuggestion(ctx, suggestion.ID)
+	require.NoError(t, err)
+	assert.Zero(t, review.ConflictCount)
+	assert.Contains(t, review.SuggestedMarkdown, "Thursdays")
+
+	updated, err := service.AcceptSuggestion(ctx, suggestion.ID, "author_1", "Thanks")
+	require.NoError(t, err)
+	markdown, err := utils.TipTapToMarkdown(updated.Content)
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "We deploy on Thursdays.")
+	assert.Contains(t, markdown, "signed checklist", "The author's later edit is kept")
+
+	var saved models.NoteSuggestion
+	require.NoError(t, service.db.First(&saved, "id = ?", suggestion.ID).Error)
+	assert.Equal(t, models.NoteSuggestionAccepted, saved.Status)
+	assert.Equal(t, authorEdit, saved.PreviousContent, "The note's previous content is kept as its revision")
+	assert.Equal(t, "author_1", saved.ReviewedBy)
+
+	_, err = service.AcceptSuggestion(ctx, suggestion.ID, "author_1", "")
+	assert.ErrorIs(t, err, ErrNoteSuggestionReviewed)
+	_, err = service.RejectSuggestion(ctx, suggestion.ID, "author_1", "")
+	assert.ErrorIs(t, err, ErrNoteSuggestionReviewed)
+}
+
+func TestAcceptSuggestion_Conflicts(t *testing.T) {
+	service := setupTestNoteSuggestionService(t)
+	ctx := context.Background()
+	note := createSuggestableNote(t, service)
+
+	suggestion, err := service.SubmitSuggestion(ctx, note.ID, "reader_1", NoteSuggestionInput{
+		Content:       suggestionContent(t, "We deploy on Thursdays.", "Deploys need a checklist.", "Ask in the channel."),
+		BaseUpdatedAt: note.UpdatedAt,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", note.ID).
+		Update("content", suggestionContent(t, "We deploy on Mondays.", "Deploys need a checklist.", "Ask in the channel.")).Error)
+
+	_, err = service.AcceptSuggestion(ctx, suggestion.ID, "author_1", "")
+	assert.ErrorIs(t, err, ErrNoteSuggestionConflicts)
+
+	rejected, err := service.RejectSuggestion(ctx, suggestion.ID, "author_1", "Already changed")
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteSuggestionRejected, rejected.Status)
+
+	var saved models.Notes
+	require.NoError(t, service.db.First(&saved, "id = ?", note.ID).Error)
+	assert.Contains(t, saved.Content, "Mondays", "Rejected suggestions leave the note unchanged")
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// minNoteSummaryWords is the length below which a note's own text is its preview
+	minNoteSummaryWords = 40
+	// noteSummaryRefreshDistance is how many of the 64 simhash bits must differ from the summarized
+	// version before the summary is rewritten, so typo fixes and small edits don't cost an AI call
+	noteSummaryRefreshDistance = 12
+	// noteSummaryShingleSize is the number of consecutive words hashed together into the fingerprint
+	noteSummaryShingleSize = 3
+)
+
+// noteSummariesQueued holds the notes with a summary refresh waiting in the job queue, so bursts of edits
+// such as collaborative syncs queue one refresh
+var noteSummariesQueued sync.Map
+
+// NoteSummaryService interface defines methods for keeping the AI summaries of notes up to date
+type NoteSummaryService interface {
+	NoteChanged(noteID string)
+	RefreshSummary(ctx context.Context, noteID string) (bool, error)
+}
+
+// noteSummaryServiceImpl implements the NoteSummaryService interface
+type noteSummaryServiceImpl struct {
+	db        *gorm.DB
+	queue     *JobQueue
+	summarize func(ctx context.Context, request NoteSummaryRequest) (string, error)
+	now       func() time.Time
+}
+
+// NewNoteSummaryService creates a new NoteSummaryService instance
+func NewNoteSummaryService() NoteSummaryService {
+	return &noteSummaryServiceImpl{
+		db:    db.DB,
+		queue: GetJobQueue(),
+		summarize: func(ctx context.Context, request NoteSummaryRequest) (string, error) {
+			return NewAIService().SummarizeNote(ctx, request)
+		},
+		now: time.Now,
+	}
+}
+
+// NoteChanged refreshes a note's summary in the background
+func (s *noteSummaryServiceImpl) NoteChanged(noteID string) {
+	if _, queued := noteSummariesQueued.LoadOrStore(noteID, true); queued {
+		return
+	}
+
+	err := s.queue.Enqueue(Job{
+		Name:        "note-summary",
+		MaxAttempts: 2,
+		Run: func(ctx context.Context) error {
+			// Edits made from here on queue another refresh
+			noteSummariesQueued.Delete(noteID)
+			_, err := s.RefreshSummary(ctx, noteID)
+			return err
+		},
+	})
+	if err != nil {
+		noteSummariesQueued.Delete(noteID)
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to queue note summary refresh")
+	}
+}
+
+// RefreshSummary rewrites a note's summary when its content changed significantly since the summary was
+// written, and reports whether it did. Locked notes and notes in encrypted notebooks are never sent to AI
+// and lose their summary, as do notes too short to need one.
+func (s *noteSummaryServiceImpl) RefreshSummary(ctx context.Context, noteID string) (bool, error) {
+	var note models.Notes
+	if err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		return false, fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	text := NoteModerationText(note.Content)
+	words := strings.Fields(text)
+	if note.Locked || note.Chapter.Notebook.Encrypted || len(words) < minNoteSummaryWords {
+		if note.Summary == "" && note.SummaryFingerprint == "" {
+			return false, nil
+		}
+		return false, s.saveSummary(ctx, noteID, "", "", nil)
+	}
+
+	fingerprint := noteContentFingerprint(words)
+	if previous, err := strconv.ParseUint(note.SummaryFingerprint, 16, 64); err == nil && note.Summary != "" &&
+		bits.OnesCount64(previous^fingerprint) < noteSummaryRefreshDistance {
+		return false, nil
+	}
+
+	summary, err := s.summarize(ctx, NoteSummaryRequest{
+		Name:    note.Name,
+		Content: strings.Join(words, " "),
+		UserID:  note.Chapter.Notebook.ClerkUserID,
+		OrgID:   note.Chapter.Notebook.OrganizationID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	now := s.now()
+	if err := s.saveSummary(ctx, noteID, summary, fmt.Sprintf("%016x", fingerprint), &now); err != nil {
+		return false, err
+	}
+
+	log.Debug().
+		Str("note_id", noteID).
+		Int("summary_length", len(summary)).
+		Msg("Refreshed note summary")
+	return true, nil
+}
+
+// saveSummary stores a note's summary without touching its updated time, which tracks content changes
+func (s *noteSummaryServiceImpl) saveSummary(ctx context.Context, noteID, summary, fingerprint string, updatedAt *time.Time) error {
+	if err := s.db.WithContext(ctx).Model(&models.Notes{}).Where("id = ?", noteID).UpdateColumns(map[string]interface{}{
+		"summary":             summary,
+		"summary_fingerprint": fingerprint,
+		"summary_updated_at":  updatedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to save note summary: %w", err)
+	}
+	return nil
+}
+
+// noteContentFingerprint returns the simhash of a note's words. Similar content gives fingerprints that
+// differ in few bits, so the distance to the summarized version tells how much the note changed.
+func noteContentFingerprint(words []string) uint64 {
+	normalized := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}))
+		if word != "" {
+			normalized = append(normalized, word)
+		}
+	}
+
+	var weights [64]int
+	for i := 0; i+noteSummaryShingleSize <= len(normalized) || (i == 0 && len(normalized) > 0); i++ {
+		end := min(i+noteSummaryShingleSize, len(normalized))
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.Join(normalized[i:end], " ")))
+		sum := hash.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
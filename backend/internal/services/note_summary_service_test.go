@@ -0,0 +1,126 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"math/bits"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testSummaryNoteContent = `The quarterly planning meeting covered the roadmap for the mobile app, the hiring plan for the
+platform team and the budget for the offsite. Priya will draft the roadmap by Friday and share it with the
+leads. The platform team needs two backend engineers before the migration to the new billing provider can
+start. The offsite budget was approved with a cap on travel costs, and Alex will book the venue next week.`
+
+const testRewrittenNoteContent = `Incident review for the outage on Tuesday. The database failover took eleven minutes because
+the replica was lagging behind after a long running migration. We agreed to add alerting on replication lag,
+to run migrations in smaller batches and to rehearse failovers every month. Sam owns the alerting work and
+will report back at the next review, while the runbook gets updated by the on call engineer.`
+
+// setupTestNoteSummaryService creates a note summary service with a fake AI on an in-memory database
+func setupTestNoteSummaryService(t *testing.T) (*noteSummaryServiceImpl, *[]NoteSummaryRequest) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}), "Failed to migrate test database")
+
+	var requests []NoteSummaryRequest
+	return &noteSummaryServiceImpl{
+		db: db,
+		summarize: func(ctx context.Context, request NoteSummaryRequest) (string, error) {
+			requests = append(requests, request)
+			return "Summary " + strings.Fields(request.Content)[0], nil
+		},
+		now: func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC) },
+	}, &requests
+}
+
+func createSummaryNote(t *testing.T, db *gorm.DB, content string) models.Notes {
+	notebook := models.Notebook{Name: "Work", ClerkUserID: "user_1"}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Meetings", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&chapter).Error)
+	note := models.Notes{Name: "Planning", ChapterID: chapter.ID, Content: content}
+	require.NoError(t, db.Create(&note).Error)
+	return note
+}
+
+func TestNoteContentFingerprint(t *testing.T) {
+	fingerprint := noteContentFingerprint(strings.Fields(testSummaryNoteContent))
+	distance := func(content string) int {
+		return bits.OnesCount64(fingerprint ^ noteContentFingerprint(strings.Fields(content)))
+	}
+
+	assert.Zero(t, distance(strings.ToUpper(testSummaryNoteContent)), "Case and spacing don't change the fingerprint")
+	assert.Less(t, distance(strings.Replace(testSummaryNoteContent, "Friday", "Thursday", 1)), noteSummaryRefreshDistance)
+	assert.GreaterOrEqual(t, distance(testRewrittenNoteContent), noteSummaryRefreshDistance)
+}
+
+func TestRefreshSummary(t *testing.T) {
+	service, requests := setupTestNoteSummaryService(t)
+	ctx := context.Background()
+	note := createSummaryNote(t, service.db, testSummaryNoteContent)
+
+	refreshed, err := service.RefreshSummary(ctx, note.ID)
+	require.NoError(t, err)
+	assert.True(t, refreshed)
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "user_1", (*requests)[0].UserID, "The notebook owner's AI keys are used")
+
+	var saved models.Notes
+	require.NoError(t, service.db.First(&saved, "id = ?", note.ID).Error)
+	assert.Equal(t, "Summary The", saved.Summary)
+	assert.Len(t, saved.SummaryFingerprint, 16)
+	assert.True(t, saved.UpdatedAt.Equal(note.UpdatedAt), "Summaries don't count as edits")
+
+	require.NoError(t, service.db.Model(&saved).Update("content", strings.Replace(testSummaryNoteContent, "Friday", "Thursday", 1)).Error)
+	refreshed, err = service.RefreshSummary(ctx, note.ID)
+	require.NoError(t, err)
+	assert.False(t, refreshed, "Small edits keep the summary")
+
+	require.NoError(t, service.db.Model(&saved).Update("content", testRewrittenNoteContent).Error)
+	refreshed, err = service.RefreshSummary(ctx, note.ID)
+	require.NoError(t, err)
+	assert.True(t, refreshed)
+	require.NoError(t, service.db.First(&saved, "id = ?", note.ID).Error)
+	assert.Equal(t, "Summary Incident", saved.Summary)
+
+	require.NoError(t, service.db.Model(&saved).Update("content", "Just a short note now.").Error)
+	refreshed, err = service.RefreshSummary(ctx, note.ID)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+	require.NoError(t, service.db.First(&saved, "id = ?", note.ID).Error)
+	assert.Empty(t, saved.Summary, "Short notes are their own preview")
+	assert.Len(t, *requests, 2)
+}
+
+func TestRefreshSummary_SkipsPrivateNotes(t *testing.T) {
+	service, requests := setupTestNoteSummaryService(t)
+	ctx := context.Background()
+
+	note := createSummaryNote(t, service.db, testSummaryNoteContent)
+	_, err := service.RefreshSummary(ctx, note.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", note.ID).Update("locked", true).Error)
+	_, err = service.RefreshSummary(ctx, note.ID)
+	require.NoError(t, err)
+	var saved models.Notes
+	require.NoError(t, service.db.First(&saved, "id = ?", note.ID).Error)
+	assert.Empty(t, saved.Summary, "Locked notes lose their summary")
+
+	encrypted := createSummaryNote(t, service.db, testRewrittenNoteContent)
+	require.NoError(t, service.db.Model(&models.Notebook{}).Where("id = (?)",
+		service.db.Model(&models.Chapter{}).Select("notebook_id").Where("id = ?", encrypted.ChapterID)).
+		Update("encrypted", true).Error)
+	refreshed, err := service.RefreshSummary(ctx, encrypted.ID)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+	assert.Len(t, *requests, 1, "Private notes are never sent to AI")
+}
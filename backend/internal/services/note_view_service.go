@@ -0,0 +1,471 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxNoteViewRows caps the notes a view returns in one query
+const maxNoteViewRows = 500
+
+var (
+	// ErrInvalidNoteView is returned for a view definition that can't be saved
+	ErrInvalidNoteView = errors.New("invalid view")
+	// ErrNoteViewNotFound is returned when a view doesn't exist
+	ErrNoteViewNotFound = errors.New("view not found")
+)
+
+// noteViewSortColumns are the note columns a view can sort by, besides properties
+var noteViewSortColumns = map[string]string{
+	"name":       "notes.name",
+	"created_at": "notes.created_at",
+	"updated_at": "notes.updated_at",
+}
+
+// NoteViewInput defines a view's layout, scope, filters and sorting
+type NoteViewInput struct {
+	Name          string   `json:"name"`
+	Layout        string   `json:"layout"`
+	ChapterID     *string  `json:"chapterId"`
+	Filters       []string `json:"filters"`
+	Columns       []string `json:"columns"`
+	SortBy        string   `json:"sortBy"`
+	SortDirection string   `json:"sortDirection"`
+	GroupBy       string   `json:"groupBy"`
+	DateProperty  string   `json:"dateProperty"`
+}
+
+// NoteViewResponse is a view with its filters and columns as lists
+type NoteViewResponse struct {
+	models.NoteView
+	Filters []string `json:"filters"`
+	Columns []string `json:"columns"`
+}
+
+// NoteViewQuery narrows a view's results for one request, on top of the saved definition
+type NoteViewQuery struct {
+	Where         []string
+	SortBy        string
+	SortDirection string
+	Start         *time.Time // Calendar range start, inclusive
+	End           *time.Time // Calendar range end, exclusive
+}
+
+// NoteViewRow is a note in a view's results
+type NoteViewRow struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	ChapterID  string            `json:"chapterId"`
+	Status     string            `json:"status"`
+	IsPublic   bool              `json:"isPublic"`
+	Properties map[string]string `json:"properties"`
+	Date       *time.Time        `json:"date,omitempty"` // The date property of calendar rows
+	CreatedAt  time.Time         `json:"createdAt"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+}
+
+// NoteViewGroup is a board column, the notes with the same value of the group property
+type NoteViewGroup struct {
+	Value string        `json:"value"` // Empty for notes without the property
+	Notes []NoteViewRow `json:"notes"`
+}
+
+// NoteViewResult holds a view's notes laid out for its layout
+type NoteViewResult struct {
+	View      NoteViewResponse `json:"view"`
+	Rows      []NoteViewRow    `json:"rows,omitempty"`
+	Groups    []NoteViewGroup  `json:"groups,omitempty"`
+	Total     int              `json:"total"`
+	Truncated bool             `json:"truncated"`
+}
+
+// NoteViewService interface defines methods for saved table, board and calendar views over notes
+type NoteViewService interface {
+	CreateView(notebookID, createdBy string, input NoteViewInput) (*NoteViewResponse, error)
+	ListViews(notebookID string) ([]NoteViewResponse, error)
+	GetView(viewID string) (*NoteViewResponse, error)
+	UpdateView(viewID string, input NoteViewInput) (*NoteViewResponse, error)
+	DeleteView(viewID string) error
+	QueryView(viewID string, options NoteViewQuery) (*NoteViewResult, error)
+}
+
+// noteViewServiceImpl implements the NoteViewService interface
+type noteViewServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewNoteViewService creates a new NoteViewService instance
+func NewNoteViewService() NoteViewService {
+	return &noteViewServiceImpl{
+		db: db.DB,
+	}
+}
+
+// CreateView saves a new view over a notebook's notes
+func (s *noteViewServiceImpl) CreateView(notebookID, createdBy string, input NoteViewInput) (*NoteViewResponse, error) {
+	var notebook models.Notebook
+	if err := s.db.Select("id", "organization_id").Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+
+	view := models.NoteView{NotebookID: notebookID, OrganizationID: notebook.OrganizationID, CreatedBy: createdBy}
+	if err := s.applyViewInput(&view, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&view).Error; err != nil {
+		return nil, fmt.Errorf("failed to create view: %w", err)
+	}
+	return noteViewResponse(view), nil
+}
+
+// ListViews returns a notebook's views in creation order
+func (s *noteViewServiceImpl) ListViews(notebookID string) ([]NoteViewResponse, error) {
+	var views []models.NoteView
+	if err := s.db.Where("notebook_id = ?", notebookID).Order("created_at ASC").Find(&views).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch views: %w", err)
+	}
+
+	responses := make([]NoteViewResponse, len(views))
+	for i, view := range views {
+		responses[i] = *noteViewResponse(view)
+	}
+	return responses, nil
+}
+
+// GetView returns a view
+func (s *noteViewServiceImpl) GetView(viewID string) (*NoteViewResponse, error) {
+	view, err := s.view(viewID)
+	if err != nil {
+		return nil, err
+	}
+	return noteViewResponse(*view), nil
+}
+
+// UpdateView replaces a view's definition
+func (s *noteViewServiceImpl) UpdateView(viewID string, input NoteViewInput) (*NoteViewResponse, error) {
+	view, err := s.view(viewID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyViewInput(view, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(view).Error; err != nil {
+		return nil, fmt.Errorf("failed to update view: %w", err)
+	}
+	return noteViewResponse(*view), nil
+}
+
+// DeleteView removes a view
+func (s *noteViewServiceImpl) DeleteView(viewID string) error {
+	result := s.db.Where("id = ?", viewID).Delete(&models.NoteView{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete view: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoteViewNotFound
+	}
+	return nil
+}
+
+// QueryView filters and sorts the view's notes in the database and lays them out for the view
+func (s *noteViewServiceImpl) QueryView(viewID string, options NoteViewQuery) (*NoteViewResult, error) {
+	view, err := s.view(viewID)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := ParseNotePropertyFilters(append(splitNoteViewFilters(view.Filters), options.Where...))
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.Model(&models.Notes{}).
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ?", view.NotebookID)
+	if view.ChapterID != nil {
+		query = query.Where("notes.chapter_id = ?", *view.ChapterID)
+	}
+	query = ApplyNotePropertyFilters(query, filters)
+
+	if view.Layout == models.NoteViewCalendar {
+		dateFilter := "SELECT 1 FROM note_properties WHERE note_properties.note_id = notes.id AND note_properties.key = ? AND note_properties.date_value IS NOT NULL"
+		args := []interface{}{view.DateProperty}
+		if options.Start != nil {
+			dateFilter += " AND note_properties.date_value >= ?"
+			args = append(args, *options.Start)
+		}
+		if options.End != nil {
+			dateFilter += " AND note_properties.date_value < ?"
+			args = append(args, *options.End)
+		}
+		query = query.Where("EXISTS ("+dateFilter+")", args...)
+	}
+
+	sortBy, direction := view.SortBy, view.SortDirection
+	if options.SortBy != "" {
+		sortBy, direction = options.SortBy, options.SortDirection
+	}
+	if sortBy == "" && view.Layout == models.NoteViewCalendar {
+		sortBy = view.DateProperty
+	}
+	query, err = orderNoteView(query, sortBy, direction)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []models.Notes
+	if err := query.
+		Select("notes.id, notes.name, notes.slug, notes.chapter_id, notes.status, notes.is_public, notes.created_at, notes.updated_at").
+		Preload("Properties", func(db *gorm.DB) *gorm.DB {
+			if keys := noteViewPropertyKeys(view); len(keys) > 0 {
+				db = db.Where("key IN ?", keys)
+			}
+			return db
+		}).
+		Limit(maxNoteViewRows + 1).
+		Find(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to query view: %w", err)
+	}
+
+	result := &NoteViewResult{View: *noteViewResponse(*view)}
+	if len(notes) > maxNoteViewRows {
+		notes = notes[:maxNoteViewRows]
+		result.Truncated = true
+	}
+	result.Total = len(notes)
+
+	rows := make([]NoteViewRow, len(notes))
+	for i, note := range notes {
+		rows[i] = NoteViewRow{
+			ID:         note.ID,
+			Name:       note.Name,
+			ChapterID:  note.ChapterID,
+			Status:     note.Status,
+			IsPublic:   note.IsPublic,
+			Properties: make(map[string]string, len(note.Properties)),
+			CreatedAt:  note.CreatedAt,
+			UpdatedAt:  note.UpdatedAt,
+		}
+		for _, property := range note.Properties {
+			rows[i].Properties[property.Key] = property.Value
+			if view.Layout == models.NoteViewCalendar && property.Key == view.DateProperty {
+				rows[i].Date = property.DateValue
+			}
+		}
+	}
+
+	if view.Layout == models.NoteViewBoard {
+		result.Groups = groupNoteViewRows(rows, view.GroupBy)
+	} else {
+		result.Rows = rows
+	}
+	return result, nil
+}
+
+// view loads a view by ID
+func (s *noteViewServiceImpl) view(viewID string) (*models.NoteView, error) {
+	var view models.NoteView
+	if err := s.db.Where("id = ?", viewID).First(&view).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteViewNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch view: %w", err)
+	}
+	return &view, nil
+}
+
+// applyViewInput validates a view definition and copies it onto the view
+func (s *noteViewServiceImpl) applyViewInput(view *models.NoteView, input NoteViewInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("%w: name is required (max 255 characters)", ErrInvalidNoteView)
+	}
+	layout := strings.ToLower(strings.TrimSpace(input.Layout))
+	if !containsString(models.NoteViewLayouts, layout) {
+		return fmt.Errorf("%w: layout must be table, board or calendar", ErrInvalidNoteView)
+	}
+
+	var chapterID *string
+	if input.ChapterID != nil && *input.ChapterID != "" {
+		var count int64
+		if err := s.db.Model(&models.Chapter{}).Where("id = ? AND notebook_id = ?", *input.ChapterID, view.NotebookID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check chapter: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: chapter is not in this notebook", ErrInvalidNoteView)
+		}
+		chapterID = input.ChapterID
+	}
+
+	filters, err := ParseNotePropertyFilters(input.Filters)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNoteView, err)
+	}
+	filterLines := make([]string, 0, len(filters))
+	for _, filter := range input.Filters {
+		if filter = strings.TrimSpace(filter); filter != "" {
+			filterLines = append(filterLines, filter)
+		}
+	}
+
+	columns := make([]string, 0, len(input.Columns))
+	for _, column := range input.Columns {
+		key, err := normalizeNotePropertyKey(column)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidNoteView, err)
+		}
+		if !containsString(columns, key) {
+			columns = append(columns, key)
+		}
+	}
+
+	sortBy := strings.ToLower(strings.TrimSpace(input.SortBy))
+	if _, builtin := noteViewSortColumns[sortBy]; sortBy != "" && !builtin {
+		if sortBy, err = normalizeNotePropertyKey(sortBy); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidNoteView, err)
+		}
+	}
+	direction, err := normalizeNoteViewDirection(input.SortDirection)
+	if err != nil {
+		return err
+	}
+
+	groupBy, dateProperty := "", ""
+	switch layout {
+	case models.NoteViewBoard:
+		if groupBy, err = normalizeNotePropertyKey(input.GroupBy); err != nil {
+			return fmt.Errorf("%w: board views need a property to group by", ErrInvalidNoteView)
+		}
+	case models.NoteViewCalendar:
+		if dateProperty, err = normalizeNotePropertyKey(input.DateProperty); err != nil {
+			return fmt.Errorf("%w: calendar views need a date property", ErrInvalidNoteView)
+		}
+	}
+
+	view.Name = name
+	view.Layout = layout
+	view.ChapterID = chapterID
+	view.Filters = strings.Join(filterLines, "\n")
+	view.Columns = strings.Join(columns, ",")
+	view.SortBy = sortBy
+	view.SortDirection = direction
+	view.GroupBy = groupBy
+	view.DateProperty = dateProperty
+	return nil
+}
+
+// orderNoteView sorts a view query by a note column or a property. Notes without the property sort last.
+func orderNoteView(query *gorm.DB, sortBy, direction string) (*gorm.DB, error) {
+	direction, err := normalizeNoteViewDirection(direction)
+	if err != nil {
+		return nil, err
+	}
+	sortBy = strings.ToLower(strings.TrimSpace(sortBy))
+	if sortBy == "" {
+		return query.Order("notes.updated_at DESC").Order("notes.id"), nil
+	}
+	if column, ok := noteViewSortColumns[sortBy]; ok {
+		return query.Order(column + " " + direction).Order("notes.id"), nil
+	}
+
+	key, err := normalizeNotePropertyKey(sortBy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNoteView, err)
+	}
+	return query.
+		Joins("LEFT JOIN note_properties AS sort_property ON sort_property.note_id = notes.id AND sort_property.key = ?", key).
+		Order("CASE WHEN sort_property.id IS NULL THEN 1 ELSE 0 END").
+		Order("sort_property.number_value " + direction).
+		Order("sort_property.date_value " + direction).
+		Order("LOWER(sort_property.value) " + direction).
+		Order("notes.id"), nil
+}
+
+// normalizeNoteViewDirection validates a sort direction, ascending by default
+func normalizeNoteViewDirection(direction string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(direction)) {
+	case "", "asc":
+		return "ASC", nil
+	case "desc":
+		return "DESC", nil
+	default:
+		return "", fmt.Errorf("%w: sort direction must be asc or desc", ErrInvalidNoteView)
+	}
+}
+
+// noteViewPropertyKeys returns the properties a view shows, or nil for all of them
+func noteViewPropertyKeys(view *models.NoteView) []string {
+	columns := splitNoteViewColumns(view.Columns)
+	if len(columns) == 0 {
+		return nil
+	}
+	for _, key := range []string{view.GroupBy, view.DateProperty} {
+		if key != "" && !containsString(columns, key) {
+			columns = append(columns, key)
+		}
+	}
+	return columns
+}
+
+// groupNoteViewRows groups rows into board columns ordered by value, with notes missing the property last
+func groupNoteViewRows(rows []NoteViewRow, groupBy string) []NoteViewGroup {
+	groups := []NoteViewGroup{}
+	index := make(map[string]int)
+	for _, row := range rows {
+		value := row.Properties[groupBy]
+		i, ok := index[strings.ToLower(value)]
+		if !ok {
+			i = len(groups)
+			index[strings.ToLower(value)] = i
+			groups = append(groups, NoteViewGroup{Value: value, Notes: []NoteViewRow{}})
+		}
+		groups[i].Notes = append(groups[i].Notes, row)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Value == "" || groups[j].Value == "" {
+			return groups[j].Value == "" && groups[i].Value != ""
+		}
+		return strings.ToLower(groups[i].Value) < strings.ToLower(groups[j].Value)
+	})
+	return groups
+}
+
+// noteViewResponse expands a view's stored lists
+func noteViewResponse(view models.NoteView) *NoteViewResponse {
+	return &NoteViewResponse{
+		NoteView: view,
+		Filters:  splitNoteViewFilters(view.Filters),
+		Columns:  splitNoteViewColumns(view.Columns),
+	}
+}
+
+// splitNoteViewFilters splits stored filters
+func splitNoteViewFilters(filters string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(filters, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitNoteViewColumns splits stored columns
+func splitNoteViewColumns(columns string) []string {
+	keys := []string{}
+	for _, key := range strings.Split(columns, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
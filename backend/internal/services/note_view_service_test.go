@@ -0,0 +1,179 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNoteViewService creates a note view service and a notebook of books with properties
+func setupTestNoteViewService(t *testing.T) (*noteViewServiceImpl, models.Notebook, models.Chapter) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteProperty{}, &models.NoteView{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	notebook := models.Notebook{Name: "Reading list", ClerkUserID: "user_owner"}
+	require.NoError(t, db.Create(&notebook).Error)
+	books := models.Chapter{Name: "Books", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&books).Error)
+	papers := models.Chapter{Name: "Papers", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&papers).Error)
+
+	properties := &notePropertyServiceImpl{db: db}
+	for _, book := range []struct {
+		name, chapterID, status, pages, started string
+	}{
+		{"Dune", books.ID, "reading", "412", "2024-03-01"},
+		{"The Hobbit", books.ID, "done", "95", "2024-01-15"},
+		{"Neuromancer", books.ID, "reading", "271", ""},
+		{"Attention Is All You Need", papers.ID, "todo", "15", "2024-03-20"},
+	} {
+		note := models.Notes{Name: book.name, ChapterID: book.chapterID}
+		require.NoError(t, db.Create(&note).Error)
+		_, err := properties.SetProperty(note.ID, "status", models.NotePropertySelect, book.status)
+		require.NoError(t, err)
+		_, err = properties.SetProperty(note.ID, "pages", models.NotePropertyNumber, book.pages)
+		require.NoError(t, err)
+		if book.started != "" {
+			_, err = properties.SetProperty(note.ID, "started", models.NotePropertyDate, book.started)
+			require.NoError(t, err)
+		}
+	}
+
+	return &noteViewServiceImpl{db: db}, notebook, books
+}
+
+// noteViewRowNames returns the names of rows in order
+func noteViewRowNames(rows []NoteViewRow) []string {
+	names := make([]string, len(rows))
+	for i, row := range rows {
+		names[i] = row.Name
+	}
+	return names
+}
+
+func TestNoteView_ValidatesDefinition(t *testing.T) {
+	service, notebook, _ := setupTestNoteViewService(t)
+	missingChapter := "missing"
+
+	for name, input := range map[string]NoteViewInput{
+		"missing name":           {Layout: models.NoteViewTable},
+		"unknown layout":         {Name: "Gallery", Layout: "gallery"},
+		"board without group":    {Name: "Board", Layout: models.NoteViewBoard},
+		"calendar without date":  {Name: "Calendar", Layout: models.NoteViewCalendar},
+		"bad filter":             {Name: "Table", Layout: models.NoteViewTable, Filters: []string{"status reading"}},
+		"bad sort direction":     {Name: "Table", Layout: models.NoteViewTable, SortBy: "pages", SortDirection: "up"},
+		"chapter of other notes": {Name: "Table", Layout: models.NoteViewTable, ChapterID: &missingChapter},
+	} {
+		_, err := service.CreateView(notebook.ID, "user_owner", input)
+		assert.ErrorIs(t, err, ErrInvalidNoteView, name)
+	}
+
+	view, err := service.CreateView(notebook.ID, "user_owner", NoteViewInput{
+		Name:    "Reading now",
+		Layout:  "Table",
+		Filters: []string{"status = reading", " "},
+		Columns: []string{"Pages", "status", "pages"},
+		SortBy:  "Pages",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteViewTable, view.Layout)
+	assert.Equal(t, []string{"status = reading"}, view.Filters)
+	assert.Equal(t, []string{"pages", "status"}, view.Columns)
+	assert.Equal(t, "pages", view.SortBy)
+	assert.Equal(t, "ASC", view.SortDirection)
+}
+
+func TestNoteView_TableFiltersAndSorts(t *testing.T) {
+	service, notebook, books := setupTestNoteViewService(t)
+
+	view, err := service.CreateView(notebook.ID, "user_owner", NoteViewInput{
+		Name:          "Books by length",
+		Layout:        models.NoteViewTable,
+		ChapterID:     &books.ID,
+		SortBy:        "pages",
+		SortDirection: "desc",
+		Columns:       []string{"pages"},
+	})
+	require.NoError(t, err)
+
+	result, err := service.QueryView(view.ID, NoteViewQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Dune", "Neuromancer", "The Hobbit"}, noteViewRowNames(result.Rows), "Only the chapter's notes, longest first")
+	assert.Equal(t, map[string]string{"pages": "412"}, result.Rows[0].Properties, "Only the view's columns are returned")
+
+	result, err = service.QueryView(view.ID, NoteViewQuery{Where: []string{"status = reading"}, SortBy: "name"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Dune", "Neuromancer"}, noteViewRowNames(result.Rows))
+
+	result, err = service.QueryView(view.ID, NoteViewQuery{SortBy: "started"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"The Hobbit", "Dune", "Neuromancer"}, noteViewRowNames(result.Rows), "Notes without the sort property come last")
+
+	_, err = service.QueryView(view.ID, NoteViewQuery{Where: []string{"pages > many"}})
+	assert.ErrorIs(t, err, ErrInvalidPropertyFilter)
+}
+
+func TestNoteView_BoardGroupsByProperty(t *testing.T) {
+	service, notebook, _ := setupTestNoteViewService(t)
+
+	view, err := service.CreateView(notebook.ID, "user_owner", NoteViewInput{Name: "Status", Layout: models.NoteViewBoard, GroupBy: "status", SortBy: "name"})
+	require.NoError(t, err)
+
+	result, err := service.QueryView(view.ID, NoteViewQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Rows)
+	require.Len(t, result.Groups, 3)
+	assert.Equal(t, "done", result.Groups[0].Value)
+	assert.Equal(t, "reading", result.Groups[1].Value)
+	assert.Equal(t, []string{"Dune", "Neuromancer"}, noteViewRowNames(result.Groups[1].Notes))
+	assert.Equal(t, "todo", result.Groups[2].Value)
+	assert.Equal(t, 4, result.Total)
+}
+
+func TestNoteView_CalendarUsesDateRange(t *testing.T) {
+	service, notebook, _ := setupTestNoteViewService(t)
+
+	view, err := service.CreateView(notebook.ID, "user_owner", NoteViewInput{Name: "Started", Layout: models.NoteViewCalendar, DateProperty: "started"})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	result, err := service.QueryView(view.ID, NoteViewQuery{Start: &start, End: &end})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Dune", "Attention Is All You Need"}, noteViewRowNames(result.Rows), "Calendar views sort by their date")
+	require.NotNil(t, result.Rows[0].Date)
+	assert.True(t, result.Rows[0].Date.Equal(start))
+
+	result, err = service.QueryView(view.ID, NoteViewQuery{})
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 3, "Notes without the date property are left out")
+}
+
+func TestNoteView_UpdateAndDelete(t *testing.T) {
+	service, notebook, _ := setupTestNoteViewService(t)
+
+	view, err := service.CreateView(notebook.ID, "user_owner", NoteViewInput{Name: "All", Layout: models.NoteViewTable})
+	require.NoError(t, err)
+
+	updated, err := service.UpdateView(view.ID, NoteViewInput{Name: "Board", Layout: models.NoteViewBoard, GroupBy: "status"})
+	require.NoError(t, err)
+	assert.Equal(t, models.NoteViewBoard, updated.Layout)
+	assert.Equal(t, "status", updated.GroupBy)
+
+	views, err := service.ListViews(notebook.ID)
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+
+	require.NoError(t, service.DeleteView(view.ID))
+	_, err = service.GetView(view.ID)
+	assert.ErrorIs(t, err, ErrNoteViewNotFound)
+	assert.ErrorIs(t, service.DeleteView(view.ID), ErrNoteViewNotFound)
+}
@@ -0,0 +1,465 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// maxKeyEnvelopeLength caps the size of a wrapped notebook key
+const maxKeyEnvelopeLength = 8192
+
+// EncryptedNotebookExportFormat identifies encrypted notebook exports
+const EncryptedNotebookExportFormat = "atlas-encrypted-notebook"
+
+var (
+	// ErrNotebookEncrypted is returned for server-side features on a notebook whose content the server can't read
+	ErrNotebookEncrypted = errors.New("this notebook is end-to-end encrypted, so the server can't read its notes")
+	// ErrNotebookNotEncrypted is returned for encryption operations on a plain notebook
+	ErrNotebookNotEncrypted = errors.New("this notebook is not encrypted")
+	// ErrNotebookAlreadyEncrypted is returned when turning on encryption twice
+	ErrNotebookAlreadyEncrypted = errors.New("this notebook is already encrypted")
+	// ErrInvalidKeyEnvelope is returned for a missing or malformed key envelope
+	ErrInvalidKeyEnvelope = errors.New("key envelopes need an algorithm and a base64 envelope of at most 8KB")
+	// ErrEncryptedNotesMismatch is returned when re-uploaded notes don't match the notebook's notes
+	ErrEncryptedNotesMismatch = errors.New("the content of every note in the notebook, and only those, must be uploaded")
+	// ErrKeyEnvelopeNotFound is returned when the user has no envelope for the notebook
+	ErrKeyEnvelopeNotFound = errors.New("no key envelope for this user")
+	// ErrKeyEnvelopeNotHeld is returned when a user without the notebook key tries to share it
+	ErrKeyEnvelopeNotHeld = errors.New("only members who hold the notebook key can share it")
+	// ErrKeyEnvelopeExists is returned when sharing the key with a user who already has an envelope
+	ErrKeyEnvelopeExists = errors.New("this user already has a key envelope, which only they can replace")
+	// ErrNotebookHasLockedNotes is returned when encrypting a notebook with password-locked notes
+	ErrNotebookHasLockedNotes = errors.New("remove the locks of the notebook's locked notes before encrypting it")
+	// ErrNotebookNotFound is returned when the notebook doesn't exist
+	ErrNotebookNotFound = errors.New("notebook not found")
+)
+
+// KeyEnvelopeInput is a notebook key wrapped by the client for one user
+type KeyEnvelopeInput struct {
+	Algorithm  string `json:"algorithm"`
+	KeyVersion int    `json:"keyVersion"`
+	Envelope   string `json:"envelope"`
+}
+
+// NoteContentInput is the re-uploaded content of a note, encrypted or decrypted by the client
+type NoteContentInput struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
+// EnableNotebookEncryptionInput turns on encryption with the owner's key envelope and every note encrypted
+type EnableNotebookEncryptionInput struct {
+	KeyEnvelopeInput
+	Notes []NoteContentInput `json:"notes"`
+}
+
+// EncryptedNoteExport is a note of an encrypted export, with its content still encrypted
+type EncryptedNoteExport struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// EncryptedChapterExport is a chapter of an encrypted export
+type EncryptedChapterExport struct {
+	ID    string                `json:"id"`
+	Name  string                `json:"name"`
+	Notes []EncryptedNoteExport `json:"notes"`
+}
+
+// EncryptedNotebookExport is an encrypted notebook with the exporting user's key envelope, so it can
+// be decrypted offline with their key
+type EncryptedNotebookExport struct {
+	Format       string                   `json:"format"`
+	Version      int                      `json:"version"`
+	ExportedAt   time.Time                `json:"exportedAt"`
+	NotebookID   string                   `json:"notebookId"`
+	NotebookName string                   `json:"notebookName"`
+	Algorithm    string                   `json:"algorithm"`
+	KeyVersion   int                      `json:"keyVersion"`
+	Envelope     string                   `json:"envelope"`
+	Chapters     []EncryptedChapterExport `json:"chapters"`
+}
+
+// NotebookEncryptionService interface defines methods for end-to-end encrypted notebooks
+type NotebookEncryptionService interface {
+	Enable(notebookID, clerkUserID string, input EnableNotebookEncryptionInput) (*models.Notebook, error)
+	Disable(notebookID string, notes []NoteContentInput) (*models.Notebook, error)
+	GetEnvelope(notebookID, clerkUserID string) (*models.NotebookKeyEnvelope, error)
+	SaveEnvelope(notebookID, clerkUserID, createdBy string, input KeyEnvelopeInput) (*models.NotebookKeyEnvelope, error)
+	Export(notebookID, clerkUserID string) (*EncryptedNotebookExport, error)
+	NotebookEncrypted(notebookID string) (bool, error)
+	ChapterEncrypted(chapterID string) (bool, error)
+	NoteEncrypted(noteID string) (bool, error)
+	CheckToolCall(args map[string]any) error
+}
+
+// notebookEncryptionServiceImpl implements the NotebookEncryptionService interface
+type notebookEncryptionServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewNotebookEncryptionService creates a new NotebookEncryptionService instance
+func NewNotebookEncryptionService() NotebookEncryptionService {
+	return &notebookEncryptionServiceImpl{
+		db: db.DB,
+	}
+}
+
+// Enable replaces every note of the notebook with its encrypted content and stores the owner's key envelope.
+// The notebook is unpublished, and its snapshots and collaborative editing state, which hold plain text, are deleted.
+// Note, chapter and notebook names stay readable so they can be listed.
+func (s *notebookEncryptionServiceImpl) Enable(notebookID, clerkUserID string, input EnableNotebookEncryptionInput) (*models.Notebook, error) {
+	if err := validateKeyEnvelope(input.KeyEnvelopeInput); err != nil {
+		return nil, err
+	}
+
+	var notebook models.Notebook
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotebookNotFound
+			}
+			return fmt.Errorf("failed to fetch notebook: %w", err)
+		}
+		if notebook.Encrypted {
+			return ErrNotebookAlreadyEncrypted
+		}
+
+		var locked int64
+		if err := tx.Model(&models.Notes{}).
+			Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+			Where("chapters.notebook_id = ? AND notes.locked = ?", notebookID, true).
+			Count(&locked).Error; err != nil {
+			return fmt.Errorf("failed to check note locks: %w", err)
+		}
+		if locked > 0 {
+			return ErrNotebookHasLockedNotes
+		}
+
+		noteIDs, err := replaceNotebookContent(tx, notebookID, input.Notes)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Notes{}).Where("id IN ?", noteIDs).Update("is_public", false).Error; err != nil {
+			return fmt.Errorf("failed to unpublish notes: %w", err)
+		}
+		if err := tx.Model(&models.Chapter{}).Where("notebook_id = ?", notebookID).Update("is_public", false).Error; err != nil {
+			return fmt.Errorf("failed to unpublish chapters: %w", err)
+		}
+		if err := tx.Where("notebook_id = ?", notebookID).Delete(&models.NotebookSnapshot{}).Error; err != nil {
+			return fmt.Errorf("failed to delete snapshots: %w", err)
+		}
+		if len(noteIDs) > 0 {
+			if err := tx.Where("note_id IN ?", noteIDs).Delete(&models.YjsUpdate{}).Error; err != nil {
+				return fmt.Errorf("failed to delete collaboration updates: %w", err)
+			}
+			if err := tx.Where("note_id IN ?", noteIDs).Delete(&models.YjsDocument{}).Error; err != nil {
+				return fmt.Errorf("failed to delete collaboration documents: %w", err)
+			}
+		}
+
+		if _, err := saveKeyEnvelope(tx, notebookID, clerkUserID, clerkUserID, input.KeyEnvelopeInput); err != nil {
+			return err
+		}
+
+		notebook.Encrypted = true
+		notebook.IsPublic = false
+		if err := tx.Model(&notebook).Select("encrypted", "is_public").Updates(&notebook).Error; err != nil {
+			return fmt.Errorf("failed to update notebook: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("notebook_id", notebookID).Str("user_id", clerkUserID).Msg("Notebook encryption enabled")
+	return &notebook, nil
+}
+
+// Disable replaces every note of the notebook with its decrypted content and deletes the key envelopes
+func (s *notebookEncryptionServiceImpl) Disable(notebookID string, notes []NoteContentInput) (*models.Notebook, error) {
+	var notebook models.Notebook
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotebookNotFound
+			}
+			return fmt.Errorf("failed to fetch notebook: %w", err)
+		}
+		if !notebook.Encrypted {
+			return ErrNotebookNotEncrypted
+		}
+
+		if _, err := replaceNotebookContent(tx, notebookID, notes); err != nil {
+			return err
+		}
+		if err := tx.Where("notebook_id = ?", notebookID).Delete(&models.NotebookKeyEnvelope{}).Error; err != nil {
+			return fmt.Errorf("failed to delete key envelopes: %w", err)
+		}
+
+		notebook.Encrypted = false
+		if err := tx.Model(&notebook).Select("encrypted").Updates(&notebook).Error; err != nil {
+			return fmt.Errorf("failed to update notebook: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("notebook_id", notebookID).Msg("Notebook encryption disabled")
+	return &notebook, nil
+}
+
+// GetEnvelope returns the user's key envelope for an encrypted notebook
+func (s *notebookEncryptionServiceImpl) GetEnvelope(notebookID, clerkUserID string) (*models.NotebookKeyEnvelope, error) {
+	var envelope models.NotebookKeyEnvelope
+	if err := s.db.Where("notebook_id = ? AND clerk_user_id = ?", notebookID, clerkUserID).First(&envelope).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKeyEnvelopeNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch key envelope: %w", err)
+	}
+	return &envelope, nil
+}
+
+// SaveEnvelope stores the notebook key wrapped for a user, e.g. when sharing it with an organization member.
+// The key is shared by members who hold it, and an existing envelope is only replaced by its own user.
+func (s *notebookEncryptionServiceImpl) SaveEnvelope(notebookID, clerkUserID, createdBy string, input KeyEnvelopeInput) (*models.NotebookKeyEnvelope, error) {
+	if err := validateKeyEnvelope(input); err != nil {
+		return nil, err
+	}
+	encrypted, err := s.NotebookEncrypted(notebookID)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return nil, ErrNotebookNotEncrypted
+	}
+
+	var envelope *models.NotebookKeyEnvelope
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		held, err := hasKeyEnvelope(tx, notebookID, createdBy)
+		if err != nil {
+			return err
+		}
+		if !held {
+			return ErrKeyEnvelopeNotHeld
+		}
+		if clerkUserID != createdBy {
+			exists, err := hasKeyEnvelope(tx, notebookID, clerkUserID)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return ErrKeyEnvelopeExists
+			}
+		}
+		envelope, err = saveKeyEnvelope(tx, notebookID, clerkUserID, createdBy, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return envelope, nil
+}
+
+// Export returns the notebook's encrypted content with the user's key envelope
+func (s *notebookEncryptionServiceImpl) Export(notebookID, clerkUserID string) (*EncryptedNotebookExport, error) {
+	var notebook models.Notebook
+	if err := db.Replica(s.db).Where("id = ?", notebookID).
+		Preload("Chapters", func(tx *gorm.DB) *gorm.DB {
+			return db.Replica(tx).Order("created_at ASC")
+		}).
+		Preload("Chapters.Files", func(tx *gorm.DB) *gorm.DB {
+			return db.Replica(tx).Select("id, name, content, chapter_id, created_at, updated_at").Order("created_at ASC")
+		}).
+		First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotebookNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if !notebook.Encrypted {
+		return nil, ErrNotebookNotEncrypted
+	}
+
+	envelope, err := s.GetEnvelope(notebookID, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &EncryptedNotebookExport{
+		Format:       EncryptedNotebookExportFormat,
+		Version:      1,
+		ExportedAt:   time.Now().UTC(),
+		NotebookID:   notebook.ID,
+		NotebookName: notebook.Name,
+		Algorithm:    envelope.Algorithm,
+		KeyVersion:   envelope.KeyVersion,
+		Envelope:     envelope.Envelope,
+		Chapters:     make([]EncryptedChapterExport, len(notebook.Chapters)),
+	}
+	for i, chapter := range notebook.Chapters {
+		notes := make([]EncryptedNoteExport, len(chapter.Files))
+		for j, note := range chapter.Files {
+			notes[j] = EncryptedNoteExport{ID: note.ID, Name: note.Name, Content: note.Content, CreatedAt: note.CreatedAt, UpdatedAt: note.UpdatedAt}
+		}
+		export.Chapters[i] = EncryptedChapterExport{ID: chapter.ID, Name: chapter.Name, Notes: notes}
+	}
+	return export, nil
+}
+
+// NotebookEncrypted reports whether a notebook is encrypted
+func (s *notebookEncryptionServiceImpl) NotebookEncrypted(notebookID string) (bool, error) {
+	var notebook models.Notebook
+	if err := s.db.Select("id", "encrypted").Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, ErrNotebookNotFound
+		}
+		return false, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	return notebook.Encrypted, nil
+}
+
+// ChapterEncrypted reports whether a chapter's notebook is encrypted
+func (s *notebookEncryptionServiceImpl) ChapterEncrypted(chapterID string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.Chapter{}).
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("chapters.id = ? AND notebooks.encrypted = ?", chapterID, true).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check chapter encryption: %w", err)
+	}
+	return count > 0, nil
+}
+
+// NoteEncrypted reports whether a note's notebook is encrypted
+func (s *notebookEncryptionServiceImpl) NoteEncrypted(noteID string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.Notes{}).
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notes.id = ? AND notebooks.encrypted = ?", noteID, true).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check note encryption: %w", err)
+	}
+	return count > 0, nil
+}
+
+// CheckToolCall returns ErrNotebookEncrypted when an assistant tool call reads or writes an encrypted notebook
+func (s *notebookEncryptionServiceImpl) CheckToolCall(args map[string]any) error {
+	checks := []struct {
+		keys  []string
+		check func(string) (bool, error)
+	}{
+		{[]string{"notebookId", "targetNotebookId"}, s.NotebookEncrypted},
+		{[]string{"chapterId", "targetChapterId"}, s.ChapterEncrypted},
+		{[]string{"noteId"}, s.NoteEncrypted},
+	}
+	for _, c := range checks {
+		for _, key := range c.keys {
+			id, _ := args[key].(string)
+			if id == "" {
+				continue
+			}
+			encrypted, err := c.check(id)
+			if err != nil && !errors.Is(err, ErrNotebookNotFound) {
+				return err
+			}
+			if encrypted {
+				return ErrNotebookEncrypted
+			}
+		}
+	}
+	return nil
+}
+
+// replaceNotebookContent sets the content of every note in the notebook from the upload, which must
+// contain each of them exactly once, and returns the notebook's note IDs
+func replaceNotebookContent(tx *gorm.DB, notebookID string, notes []NoteContentInput) ([]string, error) {
+	var noteIDs []string
+	if err := tx.Model(&models.Notes{}).
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ?", notebookID).
+		Pluck("notes.id", &noteIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notes: %w", err)
+	}
+
+	uploaded := make([]string, len(notes))
+	for i, note := range notes {
+		uploaded[i] = note.ID
+	}
+	sort.Strings(noteIDs)
+	sort.Strings(uploaded)
+	if strings.Join(noteIDs, ",") != strings.Join(uploaded, ",") {
+		return nil, ErrEncryptedNotesMismatch
+	}
+
+	for _, note := range notes {
+		// Summaries would leak the plain text of encrypted notes, so they are rewritten once notes are edited
+		if err := tx.Model(&models.Notes{}).Where("id = ?", note.ID).Updates(map[string]interface{}{
+			"content": note.Content, "summary": "", "summary_fingerprint": "", "summary_updated_at": nil,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update note content: %w", err)
+		}
+	}
+	return noteIDs, nil
+}
+
+// hasKeyEnvelope reports whether a user has a key envelope for a notebook
+func hasKeyEnvelope(tx *gorm.DB, notebookID, clerkUserID string) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.NotebookKeyEnvelope{}).Where("notebook_id = ? AND clerk_user_id = ?", notebookID, clerkUserID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to fetch key envelope: %w", err)
+	}
+	return count > 0, nil
+}
+
+// saveKeyEnvelope creates or replaces a user's key envelope for a notebook
+func saveKeyEnvelope(tx *gorm.DB, notebookID, clerkUserID, createdBy string, input KeyEnvelopeInput) (*models.NotebookKeyEnvelope, error) {
+	envelope := models.NotebookKeyEnvelope{NotebookID: notebookID, ClerkUserID: clerkUserID}
+	if err := tx.Where("notebook_id = ? AND clerk_user_id = ?", notebookID, clerkUserID).Limit(1).Find(&envelope).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch key envelope: %w", err)
+	}
+	envelope.Algorithm = strings.TrimSpace(input.Algorithm)
+	envelope.KeyVersion = input.KeyVersion
+	if envelope.KeyVersion < 1 {
+		envelope.KeyVersion = 1
+	}
+	envelope.Envelope = strings.TrimSpace(input.Envelope)
+	envelope.CreatedBy = createdBy
+	if err := tx.Save(&envelope).Error; err != nil {
+		return nil, fmt.Errorf("failed to save key envelope: %w", err)
+	}
+	return &envelope, nil
+}
+
+// validateKeyEnvelope checks a key envelope has an algorithm and base64 content of a sensible size
+func validateKeyEnvelope(input KeyEnvelopeInput) error {
+	algorithm := strings.TrimSpace(input.Algorithm)
+	envelope := strings.TrimSpace(input.Envelope)
+	if algorithm == "" || len(algorithm) > 50 || envelope == "" || len(envelope) > maxKeyEnvelopeLength {
+		return ErrInvalidKeyEnvelope
+	}
+	if _, err := base64.StdEncoding.DecodeString(envelope); err != nil {
+		if _, err := base64.RawURLEncoding.DecodeString(envelope); err != nil {
+			return ErrInvalidKeyEnvelope
+		}
+	}
+	return nil
+}
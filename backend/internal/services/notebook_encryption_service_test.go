@@ -0,0 +1,169 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNotebookEncryptionService creates a notebook encryption service on an in-memory database
+func setupTestNotebookEncryptionService(t *testing.T) *notebookEncryptionServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NotebookSnapshot{},
+		&models.NotebookKeyEnvelope{}, &models.YjsDocument{}, &models.YjsUpdate{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &notebookEncryptionServiceImpl{db: db}
+}
+
+// createEncryptionNotebook creates a published notebook with two notes and a collaboration document
+func createEncryptionNotebook(t *testing.T, db *gorm.DB) (models.Notebook, models.Chapter, []models.Notes) {
+	notebook := models.Notebook{Name: "Journal", ClerkUserID: "user_owner", IsPublic: true}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "2026", NotebookID: notebook.ID, IsPublic: true}
+	require.NoError(t, db.Create(&chapter).Error)
+	notes := []models.Notes{
+		{Name: "Monday", Content: "plain monday", ChapterID: chapter.ID, IsPublic: true},
+		{Name: "Tuesday", Content: "plain tuesday", ChapterID: chapter.ID},
+	}
+	require.NoError(t, db.Create(&notes).Error)
+	require.NoError(t, db.Create(&models.YjsDocument{NoteID: notes[0].ID, YjsState: []byte{1}}).Error)
+	return notebook, chapter, notes
+}
+
+// testEncryptionInput returns an encryption request with ciphertext for every note
+func testEncryptionInput(notes []models.Notes) EnableNotebookEncryptionInput {
+	input := EnableNotebookEncryptionInput{
+		KeyEnvelopeInput: KeyEnvelopeInput{Algorithm: "AES-GCM-256", Envelope: "d3JhcHBlZC1rZXk="},
+	}
+	for _, note := range notes {
+		input.Notes = append(input.Notes, NoteContentInput{ID: note.ID, Content: "cipher:" + note.ID})
+	}
+	return input
+}
+
+func TestNotebookEncryption_EnableStoresCiphertext(t *testing.T) {
+	service := setupTestNotebookEncryptionService(t)
+	notebook, chapter, notes := createEncryptionNotebook(t, service.db)
+
+	encrypted, err := service.Enable(notebook.ID, "user_owner", testEncryptionInput(notes))
+	require.NoError(t, err)
+	assert.True(t, encrypted.Encrypted)
+	assert.False(t, encrypted.IsPublic)
+	assert.False(t, encrypted.Capabilities.AI)
+	assert.False(t, encrypted.Capabilities.Publish)
+
+	var stored models.Notes
+	require.NoError(t, service.db.Where("id = ?", notes[0].ID).First(&stored).Error)
+	assert.Equal(t, "cipher:"+notes[0].ID, stored.Content)
+	assert.False(t, stored.IsPublic, "Encrypted notes should be unpublished")
+
+	var documents int64
+	service.db.Model(&models.YjsDocument{}).Count(&documents)
+	assert.Zero(t, documents, "Plain text collaboration state should be deleted")
+
+	envelope, err := service.GetEnvelope(notebook.ID, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, 1, envelope.KeyVersion)
+
+	chapterEncrypted, err := service.ChapterEncrypted(chapter.ID)
+	require.NoError(t, err)
+	assert.True(t, chapterEncrypted)
+
+	_, err = service.Enable(notebook.ID, "user_owner", testEncryptionInput(notes))
+	assert.ErrorIs(t, err, ErrNotebookAlreadyEncrypted)
+}
+
+func TestNotebookEncryption_EnableRequiresEveryNote(t *testing.T) {
+	service := setupTestNotebookEncryptionService(t)
+	notebook, _, notes := createEncryptionNotebook(t, service.db)
+
+	_, err := service.Enable(notebook.ID, "user_owner", testEncryptionInput(notes[:1]))
+	assert.ErrorIs(t, err, ErrEncryptedNotesMismatch)
+
+	input := testEncryptionInput(notes)
+	input.Envelope = "not base64!"
+	_, err = service.Enable(notebook.ID, "user_owner", input)
+	assert.ErrorIs(t, err, ErrInvalidKeyEnvelope)
+
+	var stored models.Notes
+	require.NoError(t, service.db.Where("id = ?", notes[0].ID).First(&stored).Error)
+	assert.Equal(t, "plain monday", stored.Content, "A failed request should leave the notes unchanged")
+}
+
+func TestNotebookEncryption_SaveEnvelopeNeedsTheKey(t *testing.T) {
+	service := setupTestNotebookEncryptionService(t)
+	notebook, _, notes := createEncryptionNotebook(t, service.db)
+	_, err := service.Enable(notebook.ID, "user_owner", testEncryptionInput(notes))
+	require.NoError(t, err)
+
+	shared := KeyEnvelopeInput{Algorithm: "RSA-OAEP", Envelope: "c2hhcmVkLWtleQ=="}
+	_, err = service.SaveEnvelope(notebook.ID, "user_member", "user_member", shared)
+	assert.ErrorIs(t, err, ErrKeyEnvelopeNotHeld, "Members without the key can't give themselves one")
+
+	_, err = service.SaveEnvelope(notebook.ID, "user_member", "user_owner", shared)
+	require.NoError(t, err)
+
+	_, err = service.SaveEnvelope(notebook.ID, "user_owner", "user_member", shared)
+	assert.ErrorIs(t, err, ErrKeyEnvelopeExists, "Members can't replace another member's envelope")
+	envelope, err := service.GetEnvelope(notebook.ID, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, "d3JhcHBlZC1rZXk=", envelope.Envelope)
+
+	rotated := KeyEnvelopeInput{Algorithm: "RSA-OAEP", KeyVersion: 2, Envelope: "cm90YXRlZC1rZXk="}
+	envelope, err = service.SaveEnvelope(notebook.ID, "user_member", "user_member", rotated)
+	require.NoError(t, err)
+	assert.Equal(t, 2, envelope.KeyVersion)
+}
+
+func TestNotebookEncryption_DisableRestoresPlaintext(t *testing.T) {
+	service := setupTestNotebookEncryptionService(t)
+	notebook, _, notes := createEncryptionNotebook(t, service.db)
+	_, err := service.Enable(notebook.ID, "user_owner", testEncryptionInput(notes))
+	require.NoError(t, err)
+
+	plain := []NoteContentInput{{ID: notes[0].ID, Content: "monday again"}, {ID: notes[1].ID, Content: "tuesday again"}}
+	decrypted, err := service.Disable(notebook.ID, plain)
+	require.NoError(t, err)
+	assert.False(t, decrypted.Encrypted)
+	assert.True(t, decrypted.Capabilities.Search)
+
+	_, err = service.GetEnvelope(notebook.ID, "user_owner")
+	assert.ErrorIs(t, err, ErrKeyEnvelopeNotFound)
+
+	_, err = service.Disable(notebook.ID, plain)
+	assert.ErrorIs(t, err, ErrNotebookNotEncrypted)
+}
+
+func TestNotebookEncryption_ExportAndToolCalls(t *testing.T) {
+	service := setupTestNotebookEncryptionService(t)
+	notebook, chapter, notes := createEncryptionNotebook(t, service.db)
+
+	assert.NoError(t, service.CheckToolCall(map[string]any{"noteId": notes[0].ID}))
+	_, err := service.Export(notebook.ID, "user_owner")
+	assert.ErrorIs(t, err, ErrNotebookNotEncrypted)
+
+	_, err = service.Enable(notebook.ID, "user_owner", testEncryptionInput(notes))
+	require.NoError(t, err)
+
+	export, err := service.Export(notebook.ID, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, EncryptedNotebookExportFormat, export.Format)
+	assert.Equal(t, "d3JhcHBlZC1rZXk=", export.Envelope)
+	require.Len(t, export.Chapters, 1)
+	require.Len(t, export.Chapters[0].Notes, 2)
+	assert.Equal(t, "cipher:"+notes[0].ID, export.Chapters[0].Notes[0].Content)
+
+	_, err = service.Export(notebook.ID, "user_other")
+	assert.ErrorIs(t, err, ErrKeyEnvelopeNotFound)
+
+	assert.ErrorIs(t, service.CheckToolCall(map[string]any{"noteId": notes[0].ID}), ErrNotebookEncrypted)
+	assert.ErrorIs(t, service.CheckToolCall(map[string]any{"noteId": "other", "targetChapterId": chapter.ID}), ErrNotebookEncrypted)
+	assert.NoError(t, service.CheckToolCall(map[string]any{"query": "monday"}))
+}
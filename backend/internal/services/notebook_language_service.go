@@ -0,0 +1,340 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidNotebookLanguages is returned for language variants that can't be published
+var ErrInvalidNotebookLanguages = errors.New("invalid notebook languages")
+
+// NotebookLanguagesInput is the request body for setting the languages a published notebook is offered in.
+// No languages publishes the notebook in the language of its notes only.
+type NotebookLanguagesInput struct {
+	DefaultLanguage string                  `json:"defaultLanguage"`
+	Languages       []NotebookLanguageInput `json:"languages"`
+}
+
+// NotebookLanguageInput is a language a published notebook is offered in
+type NotebookLanguageInput struct {
+	Language         string `json:"language"`
+	FallbackLanguage string `json:"fallbackLanguage"`
+	HideUntranslated bool   `json:"hideUntranslated"`
+}
+
+// PublicNotebookLanguage is a language readers can switch a published notebook to
+type PublicNotebookLanguage struct {
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+}
+
+// PublicLanguageSwitcher tells a reader which language a published notebook is shown in and which
+// other languages it's offered in
+type PublicLanguageSwitcher struct {
+	Language        string                   `json:"language"`
+	DefaultLanguage string                   `json:"defaultLanguage"`
+	Languages       []PublicNotebookLanguage `json:"languages"`
+
+	variants map[string]models.NotebookLanguageVariant
+}
+
+// PublicNoteVariant is the note a reader switching to a language is shown
+type PublicNoteVariant struct {
+	Language  string `json:"language"`
+	NoteID    string `json:"noteId"`
+	ChapterID string `json:"chapterId"`
+	Fallback  bool   `json:"fallback"` // There is no translation, the note is shown in another language
+}
+
+// LocalizedPublicNote is a published note in the reader's language, with the notes to show for the
+// notebook's other languages
+type LocalizedPublicNote struct {
+	Note     *models.Notes
+	Fallback bool
+	Variants []PublicNoteVariant
+}
+
+// NotebookLanguageService interface defines methods for publishing notebooks in several languages
+type NotebookLanguageService interface {
+	GetLanguages(ctx context.Context, notebookID string) ([]models.NotebookLanguageVariant, error)
+	SaveLanguages(ctx context.Context, notebookID string, input NotebookLanguagesInput) ([]models.NotebookLanguageVariant, error)
+	ReaderLanguage(ctx context.Context, notebookID, requested, acceptLanguage string) (*PublicLanguageSwitcher, error)
+	LocalizeNotes(switcher *PublicLanguageSwitcher, notes []models.Notes) []models.Notes
+	LocalizeNote(ctx context.Context, switcher *PublicLanguageSwitcher, note *models.Notes) (*LocalizedPublicNote, error)
+}
+
+// notebookLanguageServiceImpl implements the NotebookLanguageService interface
+type notebookLanguageServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewNotebookLanguageService creates a new NotebookLanguageService instance
+func NewNotebookLanguageService() NotebookLanguageService {
+	return &notebookLanguageServiceImpl{
+		db: db.DB,
+	}
+}
+
+// GetLanguages returns the languages a notebook is offered in, the default first
+func (s *notebookLanguageServiceImpl) GetLanguages(ctx context.Context, notebookID string) ([]models.NotebookLanguageVariant, error) {
+	variants := []models.NotebookLanguageVariant{}
+	if err := db.Replica(s.db).WithContext(ctx).Where("notebook_id = ?", notebookID).
+		Order("is_default DESC, language ASC").Find(&variants).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook languages: %w", err)
+	}
+	return variants, nil
+}
+
+// SaveLanguages replaces the languages a notebook is offered in. The default language is the one its
+// original notes are written in, and is offered even when it isn't listed.
+func (s *notebookLanguageServiceImpl) SaveLanguages(ctx context.Context, notebookID string, input NotebookLanguagesInput) ([]models.NotebookLanguageVariant, error) {
+	variants, err := notebookLanguageVariants(notebookID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Notebook{}).Where("id = ?", notebookID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to fetch notebook: %w", err)
+		}
+		if count == 0 {
+			return ErrNotebookNotFound
+		}
+		if err := tx.Where("notebook_id = ?", notebookID).Delete(&models.NotebookLanguageVariant{}).Error; err != nil {
+			return fmt.Errorf("failed to clear notebook languages: %w", err)
+		}
+		if len(variants) == 0 {
+			return nil
+		}
+		if err := tx.Create(&variants).Error; err != nil {
+			return fmt.Errorf("failed to save notebook languages: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetLanguages(ctx, notebookID)
+}
+
+// ReaderLanguage picks the language to show a published notebook in: the requested one, else the best
+// of the reader's Accept-Language header, else the default. It returns nil for notebooks published in
+// one language.
+func (s *notebookLanguageServiceImpl) ReaderLanguage(ctx context.Context, notebookID, requested, acceptLanguage string) (*PublicLanguageSwitcher, error) {
+	variants, err := s.GetLanguages(ctx, notebookID)
+	if err != nil {
+		return nil, err
+	}
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	switcher := &PublicLanguageSwitcher{
+		Languages: make([]PublicNotebookLanguage, 0, len(variants)),
+		variants:  make(map[string]models.NotebookLanguageVariant, len(variants)),
+	}
+	for _, variant := range variants {
+		if variant.IsDefault {
+			switcher.DefaultLanguage = variant.Language
+		}
+		switcher.variants[variant.Language] = variant
+		switcher.Languages = append(switcher.Languages, PublicNotebookLanguage{
+			Code:    variant.Language,
+			Name:    NoteLanguages[variant.Language],
+			Default: variant.IsDefault,
+		})
+	}
+
+	switcher.Language = switcher.DefaultLanguage
+	for _, language := range append([]string{requested}, acceptedLanguages(acceptLanguage)...) {
+		if _, ok := switcher.variants[NormalizeNoteLanguage(language)]; ok {
+			switcher.Language = NormalizeNoteLanguage(language)
+			break
+		}
+	}
+	return switcher, nil
+}
+
+// LocalizeNotes lists published notes once each, in the reader's language where they're translated
+// to it. Translations listed without their original are kept as they are.
+func (s *notebookLanguageServiceImpl) LocalizeNotes(switcher *PublicLanguageSwitcher, notes []models.Notes) []models.Notes {
+	if switcher == nil {
+		return notes
+	}
+
+	listed := make(map[string]bool, len(notes))
+	for _, note := range notes {
+		listed[note.ID] = true
+	}
+	translations := make(map[string][]models.Notes)
+	for _, note := range notes {
+		if note.TranslationOfID != nil && listed[*note.TranslationOfID] {
+			translations[*note.TranslationOfID] = append(translations[*note.TranslationOfID], note)
+		}
+	}
+
+	localized := make([]models.Notes, 0, len(notes))
+	for _, note := range notes {
+		if note.TranslationOfID != nil && listed[*note.TranslationOfID] {
+			continue
+		}
+		shown, fallback := switcher.resolve(note, translations[note.ID], switcher.Language)
+		if fallback && switcher.variants[switcher.Language].HideUntranslated {
+			continue
+		}
+		localized = append(localized, shown)
+	}
+	return localized
+}
+
+// LocalizeNote returns the version of a published note in the reader's language, falling back to
+// another language when it has no translation, and the version to show for each other language. Like
+// listings, it only looks at versions in the note's chapter.
+func (s *notebookLanguageServiceImpl) LocalizeNote(ctx context.Context, switcher *PublicLanguageSwitcher, note *models.Notes) (*LocalizedPublicNote, error) {
+	if switcher == nil {
+		return &LocalizedPublicNote{Note: note}, nil
+	}
+
+	original := *note
+	if note.TranslationOfID != nil {
+		var originals []models.Notes
+		if err := db.Replica(s.db).WithContext(ctx).Where("id = ? AND chapter_id = ? AND is_public = ?", *note.TranslationOfID, note.ChapterID, true).
+			Limit(1).Find(&originals).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch original note: %w", err)
+		}
+		if len(originals) > 0 {
+			original = originals[0]
+		}
+	}
+
+	var translations []models.Notes
+	if err := db.Replica(s.db).WithContext(ctx).Where("translation_of_id = ? AND chapter_id = ? AND is_public = ?", original.ID, note.ChapterID, true).
+		Order("created_at ASC").Find(&translations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch translations: %w", err)
+	}
+
+	shown, fallback := switcher.resolve(original, translations, switcher.Language)
+	// A reader who followed a link to a translation keeps it when the notebook has no better match
+	if fallback && note.ID != original.ID {
+		shown = *note
+	}
+	localized := &LocalizedPublicNote{Note: &shown, Fallback: fallback && shown.Language != switcher.Language}
+	for _, language := range switcher.Languages {
+		variant, variantFallback := switcher.resolve(original, translations, language.Code)
+		localized.Variants = append(localized.Variants, PublicNoteVariant{
+			Language:  language.Code,
+			NoteID:    variant.ID,
+			ChapterID: variant.ChapterID,
+			Fallback:  variantFallback,
+		})
+	}
+	return localized, nil
+}
+
+// resolve picks the version of a note to show in a language: its translation, else the translation to
+// the language's fallback, else the original. It reports whether it fell back to another language.
+func (s *PublicLanguageSwitcher) resolve(original models.Notes, translations []models.Notes, language string) (models.Notes, bool) {
+	byLanguage := make(map[string]models.Notes, len(translations)+1)
+	for _, translation := range translations {
+		if _, ok := byLanguage[translation.Language]; !ok && translation.Language != "" {
+			byLanguage[translation.Language] = translation
+		}
+	}
+	originalLanguage := original.Language
+	if originalLanguage == "" {
+		originalLanguage = s.DefaultLanguage
+	}
+	byLanguage[originalLanguage] = original
+
+	if note, ok := byLanguage[language]; ok {
+		return note, false
+	}
+	if fallback := s.variants[language].FallbackLanguage; fallback != "" {
+		if note, ok := byLanguage[fallback]; ok {
+			return note, true
+		}
+	}
+	return original, true
+}
+
+// notebookLanguageVariants validates the languages a notebook is offered in
+func notebookLanguageVariants(notebookID string, input NotebookLanguagesInput) ([]models.NotebookLanguageVariant, error) {
+	if len(input.Languages) == 0 {
+		return nil, nil
+	}
+	defaultLanguage := NormalizeNoteLanguage(input.DefaultLanguage)
+	if defaultLanguage == "" {
+		return nil, fmt.Errorf("%w: the default language must be one of %s", ErrInvalidNotebookLanguages, strings.Join(sortedNoteLanguages(), ", "))
+	}
+
+	variants := []models.NotebookLanguageVariant{{NotebookID: notebookID, Language: defaultLanguage, IsDefault: true}}
+	offered := map[string]bool{defaultLanguage: true}
+	for _, language := range input.Languages {
+		code := NormalizeNoteLanguage(language.Language)
+		if code == "" {
+			return nil, fmt.Errorf("%w: %q isn't a supported language", ErrInvalidNotebookLanguages, language.Language)
+		}
+		if code == defaultLanguage {
+			continue
+		}
+		if offered[code] {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidNotebookLanguages, code)
+		}
+		offered[code] = true
+		variants = append(variants, models.NotebookLanguageVariant{
+			NotebookID:       notebookID,
+			Language:         code,
+			FallbackLanguage: strings.ToLower(strings.TrimSpace(language.FallbackLanguage)),
+			HideUntranslated: language.HideUntranslated,
+		})
+	}
+	for _, variant := range variants {
+		if variant.FallbackLanguage == "" {
+			continue
+		}
+		if variant.FallbackLanguage == variant.Language || !offered[variant.FallbackLanguage] {
+			return nil, fmt.Errorf("%w: %s can only fall back to another language of the notebook", ErrInvalidNotebookLanguages, variant.Language)
+		}
+	}
+	return variants, nil
+}
+
+// acceptedLanguages returns the languages of an Accept-Language header, most preferred first
+func acceptedLanguages(header string) []string {
+	type accepted struct {
+		language string
+		quality  float64
+	}
+	var languages []accepted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		language, _, _ := strings.Cut(tag, "-")
+		if language == "" || language == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = parsed
+			}
+		}
+		languages = append(languages, accepted{language: language, quality: quality})
+	}
+	sort.SliceStable(languages, func(i, j int) bool { return languages[i].quality > languages[j].quality })
+
+	codes := make([]string, len(languages))
+	for i, language := range languages {
+		codes[i] = language.language
+	}
+	return codes
+}
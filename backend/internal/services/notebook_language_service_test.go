@@ -0,0 +1,139 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNotebookLanguageService creates a notebook language service on an in-memory database
+func setupTestNotebookLanguageService(t *testing.T) *notebookLanguageServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NotebookLanguageVariant{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &notebookLanguageServiceImpl{db: db}
+}
+
+// createTranslatedNotes adds a Spanish translation of the lifecycle note and an untranslated note to its chapter
+func createTranslatedNotes(t *testing.T, db *gorm.DB) (models.Notes, models.Notes, models.Notes) {
+	original := createLifecycleNote(t, db, nil)
+	require.NoError(t, db.Model(&original).Update("language", "en").Error)
+	original.Language = "en"
+	translation := models.Notes{Name: "Gastos", ChapterID: original.ChapterID, IsPublic: true, Language: "es", TranslationOfID: &original.ID}
+	require.NoError(t, db.Create(&translation).Error)
+	untranslated := models.Notes{Name: "Travel", ChapterID: original.ChapterID, IsPublic: true, Language: "en"}
+	require.NoError(t, db.Create(&untranslated).Error)
+	return original, translation, untranslated
+}
+
+func TestNotebookLanguages_SaveValidates(t *testing.T) {
+	service := setupTestNotebookLanguageService(t)
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	var chapter models.Chapter
+	require.NoError(t, service.db.First(&chapter, "id = ?", note.ChapterID).Error)
+	notebookID := chapter.NotebookID
+
+	languages, err := service.SaveLanguages(ctx, notebookID, NotebookLanguagesInput{
+		DefaultLanguage: "EN",
+		Languages:       []NotebookLanguageInput{{Language: "pt", FallbackLanguage: "es"}, {Language: "es"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, languages, 3, "The default language is offered without being listed")
+	assert.Equal(t, "en", languages[0].Language)
+	assert.True(t, languages[0].IsDefault)
+	assert.Equal(t, "es", languages[2].FallbackLanguage)
+
+	_, err = service.SaveLanguages(ctx, notebookID, NotebookLanguagesInput{DefaultLanguage: "en", Languages: []NotebookLanguageInput{{Language: "es", FallbackLanguage: "de"}}})
+	assert.ErrorIs(t, err, ErrInvalidNotebookLanguages, "Fallbacks must be offered")
+	_, err = service.SaveLanguages(ctx, notebookID, NotebookLanguagesInput{DefaultLanguage: "en", Languages: []NotebookLanguageInput{{Language: "es"}, {Language: "es"}}})
+	assert.ErrorIs(t, err, ErrInvalidNotebookLanguages)
+	_, err = service.SaveLanguages(ctx, notebookID, NotebookLanguagesInput{Languages: []NotebookLanguageInput{{Language: "es"}}})
+	assert.ErrorIs(t, err, ErrInvalidNotebookLanguages, "A default language is required")
+	_, err = service.SaveLanguages(ctx, "missing", NotebookLanguagesInput{DefaultLanguage: "en", Languages: []NotebookLanguageInput{{Language: "es"}}})
+	assert.ErrorIs(t, err, ErrNotebookNotFound)
+
+	languages, err = service.SaveLanguages(ctx, notebookID, NotebookLanguagesInput{})
+	require.NoError(t, err)
+	assert.Empty(t, languages)
+	switcher, err := service.ReaderLanguage(ctx, notebookID, "es", "")
+	require.NoError(t, err)
+	assert.Nil(t, switcher, "Notebooks published in one language have no switcher")
+}
+
+func TestNotebookLanguages_ReaderLanguage(t *testing.T) {
+	service := setupTestNotebookLanguageService(t)
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	var chapter models.Chapter
+	require.NoError(t, service.db.First(&chapter, "id = ?", note.ChapterID).Error)
+	_, err := service.SaveLanguages(ctx, chapter.NotebookID, NotebookLanguagesInput{
+		DefaultLanguage: "en",
+		Languages:       []NotebookLanguageInput{{Language: "es"}, {Language: "fr"}},
+	})
+	require.NoError(t, err)
+
+	reader := func(requested, acceptLanguage string) string {
+		switcher, err := service.ReaderLanguage(ctx, chapter.NotebookID, requested, acceptLanguage)
+		require.NoError(t, err)
+		return switcher.Language
+	}
+	assert.Equal(t, "es", reader("es", "fr-FR"))
+	assert.Equal(t, "fr", reader("", "de-DE, fr;q=0.8, es;q=0.5"))
+	assert.Equal(t, "es", reader("ja", "es-MX"))
+	assert.Equal(t, "en", reader("", "ja"))
+}
+
+func TestNotebookLanguages_LocalizeNotes(t *testing.T) {
+	service := setupTestNotebookLanguageService(t)
+	ctx := context.Background()
+	original, translation, untranslated := createTranslatedNotes(t, service.db)
+	var chapter models.Chapter
+	require.NoError(t, service.db.First(&chapter, "id = ?", original.ChapterID).Error)
+	_, err := service.SaveLanguages(ctx, chapter.NotebookID, NotebookLanguagesInput{
+		DefaultLanguage: "en",
+		Languages:       []NotebookLanguageInput{{Language: "es", HideUntranslated: true}, {Language: "pt", FallbackLanguage: "es"}},
+	})
+	require.NoError(t, err)
+	var notes []models.Notes
+	require.NoError(t, service.db.Order("created_at").Find(&notes).Error)
+
+	names := func(language string) []string {
+		switcher, err := service.ReaderLanguage(ctx, chapter.NotebookID, language, "")
+		require.NoError(t, err)
+		var names []string
+		for _, note := range service.LocalizeNotes(switcher, notes) {
+			names = append(names, note.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"Expenses", "Travel"}, names("en"), "Translations aren't listed next to their original")
+	assert.Equal(t, []string{"Gastos"}, names("es"), "Untranslated notes are hidden when the language asks")
+	assert.Equal(t, []string{"Gastos", "Travel"}, names("pt"), "Notes fall back to the fallback language, then the original")
+	assert.Len(t, service.LocalizeNotes(nil, notes), 3)
+
+	switcher, err := service.ReaderLanguage(ctx, chapter.NotebookID, "pt", "")
+	require.NoError(t, err)
+	localized, err := service.LocalizeNote(ctx, switcher, &original)
+	require.NoError(t, err)
+	assert.Equal(t, translation.ID, localized.Note.ID)
+	assert.True(t, localized.Fallback)
+	require.Len(t, localized.Variants, 3)
+	assert.Equal(t, PublicNoteVariant{Language: "en", NoteID: original.ID, ChapterID: original.ChapterID}, localized.Variants[0])
+	assert.Equal(t, PublicNoteVariant{Language: "es", NoteID: translation.ID, ChapterID: original.ChapterID}, localized.Variants[1])
+
+	switcher, err = service.ReaderLanguage(ctx, chapter.NotebookID, "es", "")
+	require.NoError(t, err)
+	localized, err = service.LocalizeNote(ctx, switcher, &untranslated)
+	require.NoError(t, err)
+	assert.Equal(t, untranslated.ID, localized.Note.ID, "Notes opened directly fall back to the original")
+	assert.True(t, localized.Fallback)
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// PublicReaderCookie holds a reader's session on public sites that aren't cookie-less
+	PublicReaderCookie = "notes_reader"
+	// PublicReaderSessionTTL is how long a reader's session lasts after their last read
+	PublicReaderSessionTTL = 30 * time.Minute
+	// PublicConsentHeader is sent by public sites with "granted" once a reader consented to analytics
+	PublicConsentHeader = "X-Analytics-Consent"
+	// publicReadStatsDays is the period of the read statistics shown to authors
+	publicReadStatsDays = 30
+)
+
+// publicReaderSalt is mixed into cookie-less session hashes. It's random and replaced every day, so a
+// hash can't be traced back to an IP or linked to the reader's reads on other days.
+var publicReaderSalt struct {
+	sync.Mutex
+	day  string
+	salt []byte
+}
+
+// NotebookPrivacy is the API representation of a notebook's reader privacy settings
+type NotebookPrivacy struct {
+	AnalyticsEnabled bool             `json:"analyticsEnabled"`
+	Cookieless       bool             `json:"cookieless"`
+	RequireConsent   bool             `json:"requireConsent"`
+	Reads            *PublicReadStats `json:"reads,omitempty"`
+}
+
+// NotebookPrivacyInput is the request body for updating a notebook's reader privacy settings
+type NotebookPrivacyInput struct {
+	AnalyticsEnabled bool `json:"analyticsEnabled"`
+	Cookieless       bool `json:"cookieless"`
+	RequireConsent   bool `json:"requireConsent"`
+}
+
+// PublicReadStats counts a published notebook's guest reads
+type PublicReadStats struct {
+	Days     int   `json:"days"`
+	Reads    int64 `json:"reads"`
+	Sessions int64 `json:"sessions"`
+}
+
+// PublicPrivacyCookie is a cookie a public site sets
+type PublicPrivacyCookie struct {
+	Name          string `json:"name"`
+	Purpose       string `json:"purpose"`
+	MaxAgeSeconds int    `json:"maxAgeSeconds"`
+}
+
+// PublicPrivacyPolicy describes what a published notebook's public site records about its readers, for
+// consent banners and privacy pages
+type PublicPrivacyPolicy struct {
+	NotebookID                 string                `json:"notebookId"`
+	Analytics                  bool                  `json:"analytics"`
+	Cookieless                 bool                  `json:"cookieless"`
+	RequiresConsent            bool                  `json:"requiresConsent"`
+	ConsentHeader              string                `json:"consentHeader,omitempty"`
+	Cookies                    []PublicPrivacyCookie `json:"cookies"`
+	DataCollected              []string              `json:"dataCollected"`
+	HonorsDoNotTrack           bool                  `json:"honorsDoNotTrack"`
+	HonorsGlobalPrivacyControl bool                  `json:"honorsGlobalPrivacyControl"`
+}
+
+// PublicReader is what a public handler knows about a guest reader
+type PublicReader struct {
+	IP         string
+	UserAgent  string
+	SessionID  string // The reader's session cookie, empty without one
+	DoNotTrack bool   // Set by a DNT or Sec-GPC header
+	Consent    bool   // The site passed on the reader's consent
+}
+
+// NotebookPrivacyService interface defines methods for the reader privacy of published notebooks
+type NotebookPrivacyService interface {
+	GetSettings(ctx context.Context, notebookID string) (*NotebookPrivacy, error)
+	SaveSettings(ctx context.Context, notebookID string, input NotebookPrivacyInput, updatedBy string) (*NotebookPrivacy, error)
+	PublicPolicy(ctx context.Context, notebookID string) (*PublicPrivacyPolicy, error)
+	RecordPublicRead(ctx context.Context, notebookID, noteID string, reader PublicReader) string
+}
+
+// notebookPrivacyServiceImpl implements the NotebookPrivacyService interface
+type notebookPrivacyServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewNotebookPrivacyService creates a new NotebookPrivacyService instance
+func NewNotebookPrivacyService() NotebookPrivacyService {
+	return &notebookPrivacyServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// GetSettings returns a notebook's reader privacy settings with its guest reads of the last 30 days
+func (s *notebookPrivacyServiceImpl) GetSettings(ctx context.Context, notebookID string) (*NotebookPrivacy, error) {
+	settings, err := s.settings(ctx, notebookID)
+	if err != nil {
+		return nil, err
+	}
+	privacy := toNotebookPrivacy(settings)
+
+	stats := &PublicReadStats{Days: publicReadStatsDays}
+	since := s.now().AddDate(0, 0, -publicReadStatsDays)
+	reads := func() *gorm.DB {
+		return s.db.WithContext(ctx).Model(&models.PublicNoteRead{}).Where("notebook_id = ? AND created_at >= ?", notebookID, since)
+	}
+	if err := reads().Count(&stats.Reads).Error; err != nil {
+		return nil, fmt.Errorf("failed to count public reads: %w", err)
+	}
+	if err := reads().Distinct("session_hash").Count(&stats.Sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to count public read sessions: %w", err)
+	}
+	privacy.Reads = stats
+	return privacy, nil
+}
+
+// SaveSettings updates a notebook's reader privacy settings
+func (s *notebookPrivacyServiceImpl) SaveSettings(ctx context.Context, notebookID string, input NotebookPrivacyInput, updatedBy string) (*NotebookPrivacy, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Notebook{}).Where("id = ?", notebookID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrNotebookNotFound
+	}
+
+	settings := models.NotebookPrivacySettings{NotebookID: notebookID}
+	if err := s.db.WithContext(ctx).Where(models.NotebookPrivacySettings{NotebookID: notebookID}).
+		Assign(map[string]interface{}{
+			"analytics_enabled": input.AnalyticsEnabled,
+			"cookieless":        input.Cookieless,
+			"require_consent":   input.RequireConsent,
+			"updated_by":        updatedBy,
+		}).
+		FirstOrCreate(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to save notebook privacy settings: %w", err)
+	}
+	return toNotebookPrivacy(&settings), nil
+}
+
+// PublicPolicy describes what a published notebook's public site records about its readers
+func (s *notebookPrivacyServiceImpl) PublicPolicy(ctx context.Context, notebookID string) (*PublicPrivacyPolicy, error) {
+	var notebooks []models.Notebook
+	if err := db.Replica(s.db).WithContext(ctx).Select("id").Where("id = ? AND is_public = ?", notebookID, true).
+		Limit(1).Find(&notebooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if len(notebooks) == 0 {
+		return nil, ErrNotebookNotFound
+	}
+	settings, err := s.settings(ctx, notebookID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &PublicPrivacyPolicy{
+		NotebookID:                 notebookID,
+		Analytics:                  settings.AnalyticsEnabled,
+		Cookieless:                 settings.Cookieless,
+		RequiresConsent:            settings.AnalyticsEnabled && settings.RequireConsent,
+		Cookies:                    []PublicPrivacyCookie{},
+		DataCollected:              []string{},
+		HonorsDoNotTrack:           true,
+		HonorsGlobalPrivacyControl: true,
+	}
+	if !settings.AnalyticsEnabled {
+		return policy, nil
+	}
+	if policy.RequiresConsent {
+		policy.ConsentHeader = PublicConsentHeader
+	}
+	policy.DataCollected = append(policy.DataCollected, "note reads")
+	if settings.Cookieless {
+		policy.DataCollected = append(policy.DataCollected, "daily anonymous reader hash")
+	} else {
+		policy.DataCollected = append(policy.DataCollected, "reader session")
+		policy.Cookies = append(policy.Cookies, PublicPrivacyCookie{
+			Name:          PublicReaderCookie,
+			Purpose:       "Counts a reader's reads once per visit",
+			MaxAgeSeconds: int(PublicReaderSessionTTL.Seconds()),
+		})
+	}
+	return policy, nil
+}
+
+// RecordPublicRead counts a guest reading a published note, as the notebook's privacy settings allow. It
+// returns the session to store in the reader's cookie, empty when no cookie should be set. Failures
+// are logged, never returned, so counting can't break the read.
+func (s *notebookPrivacyServiceImpl) RecordPublicRead(ctx context.Context, notebookID, noteID string, reader PublicReader) string {
+	settings, err := s.settings(ctx, notebookID)
+	if err != nil {
+		log.Warn().Err(err).Str("notebook_id", notebookID).Msg("Failed to fetch notebook privacy settings")
+		return ""
+	}
+	if !settings.AnalyticsEnabled || reader.DoNotTrack || (settings.RequireConsent && !reader.Consent) {
+		return ""
+	}
+
+	now := s.now()
+	var sessionID, session string
+	if settings.Cookieless {
+		salt := publicReaderDailySalt(now.UTC().Format("2006-01-02"))
+		session = hex.EncodeToString(salt) + ":" + notebookID + ":" + reader.IP + ":" + reader.UserAgent
+	} else {
+		sessionID = reader.SessionID
+		if !validPublicReaderSession(sessionID) {
+			sessionID = newPublicReaderSession()
+		}
+		session = notebookID + ":" + sessionID
+	}
+	sum := sha256.Sum256([]byte(session))
+
+	read := models.PublicNoteRead{
+		NoteID:      noteID,
+		NotebookID:  notebookID,
+		SessionHash: hex.EncodeToString(sum[:]),
+		CreatedAt:   now,
+	}
+	if err := s.db.WithContext(ctx).Create(&read).Error; err != nil {
+		log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to record public read")
+	}
+	return sessionID
+}
+
+// settings returns a notebook's privacy settings, the defaults when it has none
+func (s *notebookPrivacyServiceImpl) settings(ctx context.Context, notebookID string) (*models.NotebookPrivacySettings, error) {
+	var settings models.NotebookPrivacySettings
+	err := db.Replica(s.db).WithContext(ctx).Where("notebook_id = ?", notebookID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.NotebookPrivacySettings{NotebookID: notebookID, AnalyticsEnabled: true, Cookieless: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook privacy settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// toNotebookPrivacy converts stored privacy settings to their API representation
+func toNotebookPrivacy(settings *models.NotebookPrivacySettings) *NotebookPrivacy {
+	return &NotebookPrivacy{
+		AnalyticsEnabled: settings.AnalyticsEnabled,
+		Cookieless:       settings.Cookieless,
+		RequireConsent:   settings.RequireConsent,
+	}
+}
+
+// publicReaderDailySalt returns the salt of cookie-less session hashes for a day
+func publicReaderDailySalt(day string) []byte {
+	publicReaderSalt.Lock()
+	defer publicReaderSalt.Unlock()
+	if publicReaderSalt.day != day {
+		salt := make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			log.Warn().Err(err).Msg("Failed to generate public reader salt")
+		}
+		publicReaderSalt.day, publicReaderSalt.salt = day, salt
+	}
+	return publicReaderSalt.salt
+}
+
+// newPublicReaderSession returns a random reader session
+func newPublicReaderSession() string {
+	session := make([]byte, 16)
+	if _, err := rand.Read(session); err != nil {
+		log.Warn().Err(err).Msg("Failed to generate public reader session")
+	}
+	return hex.EncodeToString(session)
+}
+
+// validPublicReaderSession reports whether a cookie holds a session this server could have set
+func validPublicReaderSession(session string) bool {
+	if len(session) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(session)
+	return err == nil
+}
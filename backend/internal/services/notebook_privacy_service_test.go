@@ -0,0 +1,107 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNotebookPrivacyService creates a notebook privacy service on an in-memory database
+func setupTestNotebookPrivacyService(t *testing.T) *notebookPrivacyServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NotebookPrivacySettings{}, &models.PublicNoteRead{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &notebookPrivacyServiceImpl{
+		db:  db,
+		now: func() time.Time { return time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC) },
+	}
+}
+
+func TestNotebookPrivacy_CookielessByDefault(t *testing.T) {
+	service := setupTestNotebookPrivacyService(t)
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	var chapter models.Chapter
+	require.NoError(t, service.db.First(&chapter, "id = ?", note.ChapterID).Error)
+	notebookID := chapter.NotebookID
+
+	policy, err := service.PublicPolicy(ctx, notebookID)
+	require.NoError(t, err)
+	assert.True(t, policy.Analytics)
+	assert.True(t, policy.Cookieless)
+	assert.Empty(t, policy.Cookies)
+
+	reader := PublicReader{IP: "203.0.113.7", UserAgent: "Firefox"}
+	assert.Empty(t, service.RecordPublicRead(ctx, notebookID, note.ID, reader), "Cookie-less reads don't set a cookie")
+	service.RecordPublicRead(ctx, notebookID, note.ID, reader)
+	service.RecordPublicRead(ctx, notebookID, note.ID, PublicReader{IP: "198.51.100.2", UserAgent: "Safari"})
+	service.RecordPublicRead(ctx, notebookID, note.ID, PublicReader{IP: "198.51.100.3", DoNotTrack: true})
+
+	settings, err := service.GetSettings(ctx, notebookID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), settings.Reads.Reads, "Readers sending Do Not Track aren't counted")
+	assert.Equal(t, int64(2), settings.Reads.Sessions)
+
+	var read models.PublicNoteRead
+	require.NoError(t, service.db.First(&read).Error)
+	assert.NotContains(t, read.SessionHash, "203.0.113.7")
+
+	require.NoError(t, service.db.Model(&models.Notebook{}).Where("id = ?", notebookID).Update("is_public", false).Error)
+	_, err = service.PublicPolicy(ctx, notebookID)
+	assert.ErrorIs(t, err, ErrNotebookNotFound)
+}
+
+func TestNotebookPrivacy_SettingsAreEnforced(t *testing.T) {
+	service := setupTestNotebookPrivacyService(t)
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	var chapter models.Chapter
+	require.NoError(t, service.db.First(&chapter, "id = ?", note.ChapterID).Error)
+	notebookID := chapter.NotebookID
+	countReads := func() int64 {
+		var count int64
+		require.NoError(t, service.db.Model(&models.PublicNoteRead{}).Count(&count).Error)
+		return count
+	}
+
+	_, err := service.SaveSettings(ctx, notebookID, NotebookPrivacyInput{AnalyticsEnabled: true, RequireConsent: true}, "user_owner")
+	require.NoError(t, err)
+	policy, err := service.PublicPolicy(ctx, notebookID)
+	require.NoError(t, err)
+	assert.True(t, policy.RequiresConsent)
+	assert.Equal(t, PublicConsentHeader, policy.ConsentHeader)
+	require.Len(t, policy.Cookies, 1)
+	assert.Equal(t, PublicReaderCookie, policy.Cookies[0].Name)
+
+	assert.Empty(t, service.RecordPublicRead(ctx, notebookID, note.ID, PublicReader{IP: "203.0.113.7"}))
+	assert.Zero(t, countReads(), "Reads aren't counted without consent")
+
+	session := service.RecordPublicRead(ctx, notebookID, note.ID, PublicReader{IP: "203.0.113.7", Consent: true})
+	assert.Len(t, session, 32)
+	assert.Equal(t, session, service.RecordPublicRead(ctx, notebookID, note.ID, PublicReader{SessionID: session, Consent: true}),
+		"The reader keeps their session")
+	assert.NotEqual(t, "forged", service.RecordPublicRead(ctx, notebookID, note.ID, PublicReader{SessionID: "forged", Consent: true}))
+
+	_, err = service.SaveSettings(ctx, notebookID, NotebookPrivacyInput{}, "user_owner")
+	require.NoError(t, err)
+	before := countReads()
+	assert.Empty(t, service.RecordPublicRead(ctx, notebookID, note.ID, PublicReader{IP: "203.0.113.7", Consent: true}))
+	assert.Equal(t, before, countReads(), "Reads aren't counted with analytics off")
+
+	policy, err = service.PublicPolicy(ctx, notebookID)
+	require.NoError(t, err)
+	assert.False(t, policy.Analytics)
+	assert.Empty(t, policy.DataCollected)
+
+	_, err = service.SaveSettings(ctx, "missing", NotebookPrivacyInput{}, "user_owner")
+	assert.ErrorIs(t, err, ErrNotebookNotFound)
+}
@@ -0,0 +1,594 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultNotebookQAMonthlyCap = 200
+	maxNotebookQAMonthlyCap     = 10000
+	minNotebookQuestionLength   = 3
+	maxNotebookQuestionLength   = 500
+	// maxNotebookQANotes caps the public notes searched for a question, most recently updated first
+	maxNotebookQANotes = 500
+	// notebookQAPassageWords is the length of the passages notes are split into for retrieval
+	notebookQAPassageWords = 150
+	// maxNotebookQAPassages is the number of best matching passages sent to the AI
+	maxNotebookQAPassages = 6
+
+	// BM25 term frequency saturation and length normalization
+	notebookQABM25K1 = 1.2
+	notebookQABM25B  = 0.75
+
+	notebookQANotCovered = "This notebook doesn't seem to cover that. Try asking in other words."
+
+	// maxNotebookWidgetOrigins caps the websites a notebook's chat widget can be embedded on
+	maxNotebookWidgetOrigins = 10
+	// maxNotebookWidgetHistory is the number of earlier turns of a widget conversation sent with a question
+	maxNotebookWidgetHistory = 6
+	// maxNotebookWidgetMessageLength caps earlier turns, which include the assistant's answers
+	maxNotebookWidgetMessageLength = 2000
+)
+
+var (
+	// ErrNotebookQADisabled is returned when readers ask a notebook that doesn't answer questions
+	ErrNotebookQADisabled = errors.New("this notebook doesn't answer questions")
+	// ErrNotebookQACapReached is returned when a notebook answered its monthly cap of questions
+	ErrNotebookQACapReached = errors.New("this notebook answered all the questions it can this month")
+	// ErrInvalidNotebookQASettings is returned for question settings that can't be stored
+	ErrInvalidNotebookQASettings = errors.New("invalid notebook question settings")
+	// ErrInvalidNotebookQuestion is returned for empty or overly long questions
+	ErrInvalidNotebookQuestion = errors.New("invalid question")
+	// ErrNotebookWidgetDisabled is returned when a notebook's chat widget is turned off
+	ErrNotebookWidgetDisabled = errors.New("this notebook's chat widget is turned off")
+	// ErrNotebookWidgetOriginNotAllowed is returned when the chat widget is used from a website the author didn't add
+	ErrNotebookWidgetOriginNotAllowed = errors.New("this website can't use the notebook's chat widget")
+)
+
+// notebookQAStopWords are left out of retrieval, as they match nearly every passage
+var notebookQAStopWords = map[string]bool{
+	"a": true, "about": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "can": true, "do": true, "does": true, "for": true, "from": true, "how": true, "i": true,
+	"in": true, "is": true, "it": true, "of": true, "on": true, "or": true, "should": true, "that": true,
+	"the": true, "there": true, "this": true, "to": true, "was": true, "what": true, "when": true,
+	"where": true, "which": true, "who": true, "why": true, "with": true, "you": true,
+}
+
+// NotebookQASettings is the API representation of a notebook's reader question settings
+type NotebookQASettings struct {
+	Enabled        bool     `json:"enabled"`
+	WidgetEnabled  bool     `json:"widgetEnabled"`
+	WidgetOrigins  []string `json:"widgetOrigins"`
+	MonthlyCap     int      `json:"monthlyCap"` // Shared by questions asked on the public site and through the widget
+	AskedThisMonth int      `json:"askedThisMonth"`
+}
+
+// NotebookQASettingsInput is the request body for updating a notebook's reader question settings
+type NotebookQASettingsInput struct {
+	Enabled       bool     `json:"enabled"`
+	WidgetEnabled bool     `json:"widgetEnabled"`
+	WidgetOrigins []string `json:"widgetOrigins"` // Websites the widget is embedded on, such as https://example.com
+	MonthlyCap    int      `json:"monthlyCap"`    // 0 for the default cap
+}
+
+// NotebookWidgetConfig is what the embeddable chat widget script needs to render on the author's website
+type NotebookWidgetConfig struct {
+	NotebookID        string `json:"notebookId"`
+	NotebookName      string `json:"notebookName"`
+	ChatPath          string `json:"chatPath"`  // Relative to the API the config was fetched from
+	PublicURL         string `json:"publicUrl"` // The published notebook, for a "read the notebook" link
+	MaxQuestionLength int    `json:"maxQuestionLength"`
+	MaxHistory        int    `json:"maxHistory"`
+}
+
+// NotebookAnswer is the answer to a reader's question with links to the public notes it cites
+type NotebookAnswer struct {
+	Answer    string                   `json:"answer"`
+	Citations []NotebookAnswerCitation `json:"citations"`
+}
+
+// NotebookAnswerCitation is a public note an answer relies on
+type NotebookAnswerCitation struct {
+	NoteID    string `json:"noteId"`
+	ChapterID string `json:"chapterId"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+}
+
+// notebookQANote is a public note searched for answers
+type notebookQANote struct {
+	ID          string
+	Name        string
+	Content     string
+	ChapterID   string
+	ChapterName string
+}
+
+// notebookQAPassage is part of a public note, scored against a question
+type notebookQAPassage struct {
+	note  *notebookQANote
+	text  string
+	terms map[string]int
+	size  int
+	score float64
+}
+
+// NotebookQAService interface defines methods for answering readers' questions about published notebooks
+type NotebookQAService interface {
+	GetSettings(ctx context.Context, notebookID string) (*NotebookQASettings, error)
+	SaveSettings(ctx context.Context, notebookID string, input NotebookQASettingsInput, updatedBy string) (*NotebookQASettings, error)
+	Ask(ctx context.Context, notebookID, question string) (*NotebookAnswer, error)
+	WidgetConfig(ctx context.Context, notebookID, origin string) (*NotebookWidgetConfig, error)
+	WidgetChat(ctx context.Context, notebookID, origin string, messages []NotebookChatMessage) (*NotebookAnswer, error)
+	WidgetOriginAllowed(ctx context.Context, notebookID, origin string) bool
+}
+
+// notebookQAServiceImpl implements the NotebookQAService interface
+type notebookQAServiceImpl struct {
+	db              *gorm.DB
+	answer          func(ctx context.Context, request NotebookQuestionRequest) (*NotebookQuestionResponse, error)
+	now             func() time.Time
+	frontendBaseURL string
+}
+
+// NewNotebookQAService creates a new NotebookQAService instance
+func NewNotebookQAService() NotebookQAService {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173"
+	}
+
+	return &notebookQAServiceImpl{
+		db: db.DB,
+		answer: func(ctx context.Context, request NotebookQuestionRequest) (*NotebookQuestionResponse, error) {
+			return NewAIService().AnswerNotebookQuestion(ctx, request)
+		},
+		now:             time.Now,
+		frontendBaseURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// GetSettings returns a notebook's reader question settings and this month's usage. Notebooks without
+// settings don't answer questions.
+func (s *notebookQAServiceImpl) GetSettings(ctx context.Context, notebookID string) (*NotebookQASettings, error) {
+	var settings models.NotebookQASettings
+	if err := s.db.WithContext(ctx).Where("notebook_id = ?", notebookID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook question settings: %w", err)
+	}
+	return s.toNotebookQASettings(ctx, notebookID, &settings)
+}
+
+// SaveSettings turns reader questions and the chat widget on or off for a notebook and sets its monthly cap
+func (s *notebookQAServiceImpl) SaveSettings(ctx context.Context, notebookID string, input NotebookQASettingsInput, updatedBy string) (*NotebookQASettings, error) {
+	monthlyCap := input.MonthlyCap
+	if monthlyCap == 0 {
+		monthlyCap = defaultNotebookQAMonthlyCap
+	}
+	if monthlyCap < 0 || monthlyCap > maxNotebookQAMonthlyCap {
+		return nil, fmt.Errorf("%w: monthlyCap must be between 1 and %d", ErrInvalidNotebookQASettings, maxNotebookQAMonthlyCap)
+	}
+
+	var origins []string
+	seen := make(map[string]bool)
+	for _, raw := range input.WidgetOrigins {
+		origin, err := normalizeWidgetOrigin(raw)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) > maxNotebookWidgetOrigins {
+		return nil, fmt.Errorf("%w: the widget can be embedded on at most %d websites", ErrInvalidNotebookQASettings, maxNotebookWidgetOrigins)
+	}
+	if input.WidgetEnabled && len(origins) == 0 {
+		return nil, fmt.Errorf("%w: add the websites the widget is embedded on", ErrInvalidNotebookQASettings)
+	}
+
+	var notebook models.Notebook
+	if err := s.db.WithContext(ctx).Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotebookNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if (input.Enabled || input.WidgetEnabled) && notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	settings := models.NotebookQASettings{NotebookID: notebookID}
+	if err := s.db.WithContext(ctx).Where(models.NotebookQASettings{NotebookID: notebookID}).
+		Assign(map[string]interface{}{
+			"enabled":        input.Enabled,
+			"widget_enabled": input.WidgetEnabled,
+			"widget_origins": strings.Join(origins, ","),
+			"monthly_cap":    monthlyCap,
+			"updated_by":     updatedBy,
+		}).
+		FirstOrCreate(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to save notebook question settings: %w", err)
+	}
+	return s.toNotebookQASettings(ctx, notebookID, &settings)
+}
+
+// Ask answers a reader's question about a published notebook from its public notes, with the author's AI
+// provider. Questions the notes don't match are answered without AI and don't count towards the cap.
+func (s *notebookQAServiceImpl) Ask(ctx context.Context, notebookID, question string) (*NotebookAnswer, error) {
+	question, err := normalizeNotebookQuestion(question)
+	if err != nil {
+		return nil, err
+	}
+
+	notebook, settings, err := s.publishedNotebook(ctx, notebookID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		return nil, ErrNotebookQADisabled
+	}
+
+	return s.answerQuestion(ctx, notebook, settings, question, nil)
+}
+
+// WidgetConfig returns the configuration of a notebook's chat widget for the website embedding it
+func (s *notebookQAServiceImpl) WidgetConfig(ctx context.Context, notebookID, origin string) (*NotebookWidgetConfig, error) {
+	notebook, _, err := s.widgetNotebook(ctx, notebookID, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotebookWidgetConfig{
+		NotebookID:        notebook.ID,
+		NotebookName:      notebook.Name,
+		ChatPath:          fmt.Sprintf("/public/%s/widget-chat", notebook.ID),
+		PublicURL:         fmt.Sprintf("%s/public/%s", s.frontendBaseURL, notebook.ID),
+		MaxQuestionLength: maxNotebookQuestionLength,
+		MaxHistory:        maxNotebookWidgetHistory,
+	}, nil
+}
+
+// WidgetChat answers the last message of a chat widget conversation from the notebook's public notes. Earlier
+// turns help with follow-up questions. Answers count towards the same monthly cap as questions asked on the
+// public site.
+func (s *notebookQAServiceImpl) WidgetChat(ctx context.Context, notebookID, origin string, messages []NotebookChatMessage) (*NotebookAnswer, error) {
+	if len(messages) == 0 || messages[len(messages)-1].Role != "user" {
+		return nil, fmt.Errorf("%w: the conversation must end with the reader's question", ErrInvalidNotebookQuestion)
+	}
+	question, err := normalizeNotebookQuestion(messages[len(messages)-1].Content)
+	if err != nil {
+		return nil, err
+	}
+
+	history := messages[:len(messages)-1]
+	if len(history) > maxNotebookWidgetHistory {
+		history = history[len(history)-maxNotebookWidgetHistory:]
+	}
+	cleaned := make([]NotebookChatMessage, 0, len(history))
+	for _, message := range history {
+		if message.Role != "user" && message.Role != "assistant" {
+			return nil, fmt.Errorf("%w: messages must be from the user or the assistant", ErrInvalidNotebookQuestion)
+		}
+		content := strings.Join(strings.Fields(message.Content), " ")
+		if content == "" {
+			continue
+		}
+		if len(content) > maxNotebookWidgetMessageLength {
+			return nil, fmt.Errorf("%w: messages must be at most %d characters", ErrInvalidNotebookQuestion, maxNotebookWidgetMessageLength)
+		}
+		cleaned = append(cleaned, NotebookChatMessage{Role: message.Role, Content: content})
+	}
+
+	notebook, settings, err := s.widgetNotebook(ctx, notebookID, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.answerQuestion(ctx, notebook, settings, question, cleaned)
+}
+
+// WidgetOriginAllowed reports whether a website can use a notebook's chat widget
+func (s *notebookQAServiceImpl) WidgetOriginAllowed(ctx context.Context, notebookID, origin string) bool {
+	_, _, err := s.widgetNotebook(ctx, notebookID, origin)
+	return err == nil
+}
+
+// widgetNotebook returns a published notebook and its settings when its chat widget is on and embedded on the origin
+func (s *notebookQAServiceImpl) widgetNotebook(ctx context.Context, notebookID, origin string) (*models.Notebook, *models.NotebookQASettings, error) {
+	notebook, settings, err := s.publishedNotebook(ctx, notebookID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !settings.WidgetEnabled {
+		return nil, nil, ErrNotebookWidgetDisabled
+	}
+
+	normalized, err := normalizeWidgetOrigin(origin)
+	if err != nil {
+		return nil, nil, ErrNotebookWidgetOriginNotAllowed
+	}
+	for _, allowed := range splitWidgetOrigins(settings.WidgetOrigins) {
+		if allowed == normalized {
+			return notebook, settings, nil
+		}
+	}
+	return nil, nil, ErrNotebookWidgetOriginNotAllowed
+}
+
+// publishedNotebook returns a public, unencrypted notebook and its question settings
+func (s *notebookQAServiceImpl) publishedNotebook(ctx context.Context, notebookID string) (*models.Notebook, *models.NotebookQASettings, error) {
+	var notebook models.Notebook
+	if err := s.db.WithContext(ctx).Where("id = ? AND is_public = ? AND encrypted = ?", notebookID, true, false).First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotebookNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+
+	var settings models.NotebookQASettings
+	if err := s.db.WithContext(ctx).Where("notebook_id = ?", notebookID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch notebook question settings: %w", err)
+	}
+	return &notebook, &settings, nil
+}
+
+// answerQuestion answers a question from the notebook's public notes. Earlier user turns of a conversation are
+// searched along with the question, so follow-up questions find the passages they refer to.
+func (s *notebookQAServiceImpl) answerQuestion(ctx context.Context, notebook *models.Notebook, settings *models.NotebookQASettings, question string, history []NotebookChatMessage) (*NotebookAnswer, error) {
+	notebookID := notebook.ID
+
+	var notes []notebookQANote
+	if err := s.db.WithContext(ctx).Table("notes").
+		Select("notes.id, notes.name, notes.content, notes.chapter_id, chapters.name AS chapter_name").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ? AND chapters.is_public = ? AND notes.is_public = ? AND notes.locked = ?", notebookID, true, true, false).
+		Order("notes.updated_at DESC").
+		Limit(maxNotebookQANotes).
+		Scan(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch public notes: %w", err)
+	}
+
+	terms := notebookQATerms(question)
+	for _, message := range history {
+		if message.Role == "user" {
+			terms = append(terms, notebookQATerms(message.Content)...)
+		}
+	}
+	passages := rankNotebookQAPassages(notes, terms)
+	if len(passages) == 0 {
+		return &NotebookAnswer{Answer: notebookQANotCovered, Citations: []NotebookAnswerCitation{}}, nil
+	}
+
+	month := s.now().UTC().Format("2006-01")
+	if err := s.reserveQuestion(ctx, notebookID, month, settings.MonthlyCap); err != nil {
+		return nil, err
+	}
+
+	// Passages are cited by reference so the answer can link back to the notes
+	refs := make(map[string]*notebookQANote)
+	sources := make([]KnowledgeSource, 0, len(passages))
+	for i, passage := range passages {
+		ref := fmt.Sprintf("N%d", i+1)
+		refs[ref] = passage.note
+		sources = append(sources, KnowledgeSource{
+			Ref:      ref,
+			Kind:     "note",
+			Title:    passage.note.Name,
+			Location: passage.note.ChapterName,
+			Content:  passage.text,
+		})
+	}
+
+	response, err := s.answer(ctx, NotebookQuestionRequest{
+		NotebookName: notebook.Name,
+		Question:     question,
+		History:      history,
+		Sources:      sources,
+		UserID:       notebook.ClerkUserID,
+		OrgID:        notebook.OrganizationID,
+	})
+	if err != nil {
+		// Failed answers don't count towards the cap
+		if releaseErr := s.db.WithContext(ctx).Model(&models.NotebookQAUsage{}).
+			Where("notebook_id = ? AND month = ? AND questions > ?", notebookID, month, 0).
+			UpdateColumn("questions", gorm.Expr("questions - 1")).Error; releaseErr != nil {
+			log.Warn().Err(releaseErr).Str("notebook_id", notebookID).Msg("Failed to release notebook question")
+		}
+		return nil, err
+	}
+
+	answer := &NotebookAnswer{Answer: response.Answer, Citations: []NotebookAnswerCitation{}}
+	cited := make(map[string]bool)
+	for _, ref := range response.Citations {
+		note, exists := refs[strings.Trim(strings.TrimSpace(ref), "[]")]
+		if !exists || cited[note.ID] {
+			continue
+		}
+		cited[note.ID] = true
+		answer.Citations = append(answer.Citations, NotebookAnswerCitation{
+			NoteID:    note.ID,
+			ChapterID: note.ChapterID,
+			Title:     note.Name,
+			URL:       fmt.Sprintf("%s/public/%s/%s/%s", s.frontendBaseURL, notebookID, note.ChapterID, note.ID),
+		})
+	}
+
+	log.Info().
+		Str("notebook_id", notebookID).
+		Int("passages", len(passages)).
+		Int("citations", len(answer.Citations)).
+		Msg("Answered notebook question")
+	return answer, nil
+}
+
+// reserveQuestion counts a question towards the notebook's monthly cap, failing when the cap is reached.
+// The count is checked and raised in one statement so concurrent questions can't exceed the cap.
+func (s *notebookQAServiceImpl) reserveQuestion(ctx context.Context, notebookID, month string, monthlyCap int) error {
+	usage := models.NotebookQAUsage{NotebookID: notebookID, Month: month}
+	if err := s.db.WithContext(ctx).Where(models.NotebookQAUsage{NotebookID: notebookID, Month: month}).FirstOrCreate(&usage).Error; err != nil {
+		return fmt.Errorf("failed to fetch notebook question usage: %w", err)
+	}
+
+	result := s.db.WithContext(ctx).Model(&models.NotebookQAUsage{}).
+		Where("notebook_id = ? AND month = ? AND questions < ?", notebookID, month, monthlyCap).
+		UpdateColumn("questions", gorm.Expr("questions + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("failed to count notebook question: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotebookQACapReached
+	}
+	return nil
+}
+
+func (s *notebookQAServiceImpl) toNotebookQASettings(ctx context.Context, notebookID string, settings *models.NotebookQASettings) (*NotebookQASettings, error) {
+	var usage models.NotebookQAUsage
+	if err := s.db.WithContext(ctx).Where("notebook_id = ? AND month = ?", notebookID, s.now().UTC().Format("2006-01")).
+		Limit(1).Find(&usage).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook question usage: %w", err)
+	}
+
+	monthlyCap := settings.MonthlyCap
+	if monthlyCap == 0 {
+		monthlyCap = defaultNotebookQAMonthlyCap
+	}
+	return &NotebookQASettings{
+		Enabled:        settings.Enabled,
+		WidgetEnabled:  settings.WidgetEnabled,
+		WidgetOrigins:  splitWidgetOrigins(settings.WidgetOrigins),
+		MonthlyCap:     monthlyCap,
+		AskedThisMonth: usage.Questions,
+	}, nil
+}
+
+// normalizeNotebookQuestion collapses whitespace in a question and checks its length
+func normalizeNotebookQuestion(question string) (string, error) {
+	question = strings.Join(strings.Fields(question), " ")
+	if len(question) < minNotebookQuestionLength || len(question) > maxNotebookQuestionLength {
+		return "", fmt.Errorf("%w: questions must be between %d and %d characters", ErrInvalidNotebookQuestion, minNotebookQuestionLength, maxNotebookQuestionLength)
+	}
+	return question, nil
+}
+
+// normalizeWidgetOrigin returns a website's origin as browsers send it, such as https://example.com:8443
+func normalizeWidgetOrigin(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" ||
+		parsed.User != nil || (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("%w: %q isn't a website origin such as https://example.com", ErrInvalidNotebookQASettings, raw)
+	}
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host), nil
+}
+
+// splitWidgetOrigins returns the stored origins of a notebook's chat widget
+func splitWidgetOrigins(origins string) []string {
+	result := []string{}
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			result = append(result, origin)
+		}
+	}
+	return result
+}
+
+// rankNotebookQAPassages splits notes into passages and returns the ones matching the question terms,
+// best first by BM25 score. A note's name counts towards each of its passages.
+func rankNotebookQAPassages(notes []notebookQANote, question []string) []*notebookQAPassage {
+	if len(question) == 0 {
+		return nil
+	}
+
+	var passages []*notebookQAPassage
+	totalSize := 0
+	for i := range notes {
+		note := &notes[i]
+		words := strings.Fields(NoteModerationText(note.Content))
+		nameTerms := notebookQATerms(note.Name)
+		for start := 0; start < len(words) || start == 0; start += notebookQAPassageWords {
+			text := strings.Join(words[start:min(start+notebookQAPassageWords, len(words))], " ")
+			passage := &notebookQAPassage{note: note, text: text, terms: make(map[string]int)}
+			for _, term := range append(notebookQATerms(text), nameTerms...) {
+				passage.terms[term]++
+				passage.size++
+			}
+			if passage.size > 0 {
+				passages = append(passages, passage)
+				totalSize += passage.size
+			}
+		}
+	}
+	if len(passages) == 0 {
+		return nil
+	}
+
+	averageSize := float64(totalSize) / float64(len(passages))
+	seen := make(map[string]bool)
+	for _, term := range question {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		matching := 0
+		for _, passage := range passages {
+			if passage.terms[term] > 0 {
+				matching++
+			}
+		}
+		if matching == 0 {
+			continue
+		}
+		idf := math.Log(1 + (float64(len(passages)-matching)+0.5)/(float64(matching)+0.5))
+		for _, passage := range passages {
+			frequency := float64(passage.terms[term])
+			if frequency == 0 {
+				continue
+			}
+			norm := notebookQABM25K1 * (1 - notebookQABM25B + notebookQABM25B*float64(passage.size)/averageSize)
+			passage.score += idf * frequency * (notebookQABM25K1 + 1) / (frequency + norm)
+		}
+	}
+
+	matched := passages[:0]
+	for _, passage := range passages {
+		if passage.score > 0 {
+			matched = append(matched, passage)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].score > matched[j].score
+	})
+	if len(matched) > maxNotebookQAPassages {
+		matched = matched[:maxNotebookQAPassages]
+	}
+	return matched
+}
+
+// notebookQATerms returns the lowercased words of a text used for retrieval, without punctuation and
+// stop words
+func notebookQATerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if !notebookQAStopWords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
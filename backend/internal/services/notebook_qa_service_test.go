@@ -0,0 +1,197 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNotebookQAService creates a notebook question service with a fake AI on an in-memory database
+func setupTestNotebookQAService(t *testing.T) (*notebookQAServiceImpl, *[]NotebookQuestionRequest) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{},
+		&models.NotebookQASettings{}, &models.NotebookQAUsage{}), "Failed to migrate test database")
+
+	var requests []NotebookQuestionRequest
+	return &notebookQAServiceImpl{
+		db: db,
+		answer: func(ctx context.Context, request NotebookQuestionRequest) (*NotebookQuestionResponse, error) {
+			requests = append(requests, request)
+			return &NotebookQuestionResponse{Answer: "Deploys run on Fridays.", Citations: []string{"[N1]", "N1", "N9"}}, nil
+		},
+		now:             func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC) },
+		frontendBaseURL: "https://notes.example.com",
+	}, &requests
+}
+
+// createPublishedNotebook creates a public notebook with a public chapter and a private one
+func createPublishedNotebook(t *testing.T, db *gorm.DB) (models.Notebook, models.Notes) {
+	notebook := models.Notebook{Name: "Handbook", ClerkUserID: "author_1", IsPublic: true}
+	require.NoError(t, db.Create(&notebook).Error)
+	public := models.Chapter{Name: "Engineering", NotebookID: notebook.ID, IsPublic: true}
+	require.NoError(t, db.Create(&public).Error)
+	private := models.Chapter{Name: "Internal", NotebookID: notebook.ID}
+	require.NoError(t, db.Create(&private).Error)
+
+	deploys := models.Notes{Name: "Deploy process", ChapterID: public.ID, IsPublic: true,
+		Content: "We deploy the backend every Friday after the release checklist is signed off."}
+	require.NoError(t, db.Create(&deploys).Error)
+	for _, note := range []models.Notes{
+		{Name: "Onboarding", ChapterID: public.ID, IsPublic: true, Content: "New hires pair with a buddy for their first two weeks."},
+		{Name: "Deploy drafts", ChapterID: public.ID, Content: "Unpublished thoughts about the deploy schedule."},
+		{Name: "Deploy incidents", ChapterID: private.ID, IsPublic: true, Content: "The deploy on Friday broke billing."},
+		{Name: "Deploy keys", ChapterID: public.ID, IsPublic: true, Locked: true, Content: "Deploy keys live in the vault."},
+	} {
+		require.NoError(t, db.Create(&note).Error)
+	}
+	return notebook, deploys
+}
+
+func TestAskNotebook(t *testing.T) {
+	service, requests := setupTestNotebookQAService(t)
+	ctx := context.Background()
+	notebook, deploys := createPublishedNotebook(t, service.db)
+
+	_, err := service.Ask(ctx, notebook.ID, "When do you deploy?")
+	assert.ErrorIs(t, err, ErrNotebookQADisabled, "Notebooks don't answer questions until the author turns it on")
+
+	_, err = service.SaveSettings(ctx, notebook.ID, NotebookQASettingsInput{Enabled: true}, "author_1")
+	require.NoError(t, err)
+
+	answer, err := service.Ask(ctx, notebook.ID, "  When do you   deploy? ")
+	require.NoError(t, err)
+	assert.Equal(t, "Deploys run on Fridays.", answer.Answer)
+	require.Len(t, answer.Citations, 1, "Citations are deduplicated and unknown references dropped")
+	assert.Equal(t, deploys.ID, answer.Citations[0].NoteID)
+	assert.Equal(t, "https://notes.example.com/public/"+notebook.ID+"/"+deploys.ChapterID+"/"+deploys.ID, answer.Citations[0].URL)
+
+	require.Len(t, *requests, 1)
+	request := (*requests)[0]
+	assert.Equal(t, "author_1", request.UserID, "The author's AI keys are used")
+	assert.Equal(t, "When do you deploy?", request.Question)
+	require.Len(t, request.Sources, 1, "Only published notes of published chapters are searched, locked ones left out")
+	assert.Equal(t, "Deploy process", request.Sources[0].Title)
+
+	answer, err = service.Ask(ctx, notebook.ID, "What's the vacation policy?")
+	require.NoError(t, err)
+	assert.Empty(t, answer.Citations)
+	assert.Len(t, *requests, 1, "Questions nothing matches aren't sent to AI")
+
+	settings, err := service.GetSettings(ctx, notebook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, settings.AskedThisMonth)
+
+	_, err = service.Ask(ctx, notebook.ID, "?")
+	assert.ErrorIs(t, err, ErrInvalidNotebookQuestion)
+
+	require.NoError(t, service.db.Model(&notebook).Update("is_public", false).Error)
+	_, err = service.Ask(ctx, notebook.ID, "When do you deploy?")
+	assert.ErrorIs(t, err, ErrNotebookNotFound)
+}
+
+func TestAskNotebook_MonthlyCap(t *testing.T) {
+	service, _ := setupTestNotebookQAService(t)
+	ctx := context.Background()
+	notebook, _ := createPublishedNotebook(t, service.db)
+
+	_, err := service.SaveSettings(ctx, notebook.ID, NotebookQASettingsInput{Enabled: true, MonthlyCap: maxNotebookQAMonthlyCap + 1}, "author_1")
+	assert.ErrorIs(t, err, ErrInvalidNotebookQASettings)
+	settings, err := service.SaveSettings(ctx, notebook.ID, NotebookQASettingsInput{Enabled: true, MonthlyCap: 2}, "author_1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, settings.MonthlyCap)
+
+	answer := service.answer
+	service.answer = func(ctx context.Context, request NotebookQuestionRequest) (*NotebookQuestionResponse, error) {
+		return nil, errors.New("provider down")
+	}
+	_, err = service.Ask(ctx, notebook.ID, "When do you deploy?")
+	require.Error(t, err)
+	service.answer = answer
+
+	for i := 0; i < 2; i++ {
+		_, err = service.Ask(ctx, notebook.ID, "When do you deploy?")
+		require.NoError(t, err, "Failed answers don't count towards the cap")
+	}
+	_, err = service.Ask(ctx, notebook.ID, "When do you deploy?")
+	assert.ErrorIs(t, err, ErrNotebookQACapReached)
+
+	service.now = func() time.Time { return time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC) }
+	_, err = service.Ask(ctx, notebook.ID, "When do you deploy?")
+	assert.NoError(t, err, "The cap resets every month")
+}
+
+func TestRankNotebookQAPassages(t *testing.T) {
+	notes := []notebookQANote{
+		{ID: "1", Name: "Billing", Content: "Invoices are sent on the first of the month. Refunds take five days."},
+		{ID: "2", Name: "Refund policy", Content: "Customers can ask for a refund within thirty days."},
+		{ID: "3", Name: "Team", Content: "The team meets every Monday."},
+	}
+
+	passages := rankNotebookQAPassages(notes, notebookQATerms("How do refunds work?"))
+	require.Len(t, passages, 1, "Stop words don't match every passage")
+	assert.Equal(t, "1", passages[0].note.ID)
+
+	passages = rankNotebookQAPassages(notes, notebookQATerms("refund policy"))
+	require.NotEmpty(t, passages)
+	assert.Equal(t, "2", passages[0].note.ID, "Note names count towards their passages")
+}
+
+func TestNotebookWidgetChat(t *testing.T) {
+	service, requests := setupTestNotebookQAService(t)
+	ctx := context.Background()
+	notebook, deploys := createPublishedNotebook(t, service.db)
+	messages := []NotebookChatMessage{
+		{Role: "user", Content: "How does the release checklist work?"},
+		{Role: "assistant", Content: "It's signed off before every deploy."},
+		{Role: "user", Content: "Which day is that?"},
+	}
+
+	_, err := service.WidgetChat(ctx, notebook.ID, "https://example.com", messages)
+	assert.ErrorIs(t, err, ErrNotebookWidgetDisabled)
+
+	_, err = service.SaveSettings(ctx, notebook.ID, NotebookQASettingsInput{WidgetEnabled: true}, "author_1")
+	assert.ErrorIs(t, err, ErrInvalidNotebookQASettings, "The widget needs the websites it's embedded on")
+	_, err = service.SaveSettings(ctx, notebook.ID, NotebookQASettingsInput{WidgetEnabled: true, WidgetOrigins: []string{"example.com"}}, "author_1")
+	assert.ErrorIs(t, err, ErrInvalidNotebookQASettings)
+	settings, err := service.SaveSettings(ctx, notebook.ID, NotebookQASettingsInput{
+		WidgetEnabled: true,
+		WidgetOrigins: []string{"https://Example.com/", "https://example.com", "http://localhost:3000"},
+	}, "author_1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com", "http://localhost:3000"}, settings.WidgetOrigins)
+
+	_, err = service.WidgetChat(ctx, notebook.ID, "https://evil.example", messages)
+	assert.ErrorIs(t, err, ErrNotebookWidgetOriginNotAllowed)
+	assert.False(t, service.WidgetOriginAllowed(ctx, notebook.ID, ""))
+	assert.True(t, service.WidgetOriginAllowed(ctx, notebook.ID, "http://localhost:3000"))
+	_, err = service.Ask(ctx, notebook.ID, "When do you deploy?")
+	assert.ErrorIs(t, err, ErrNotebookQADisabled, "The widget doesn't turn on questions on the public site")
+
+	answer, err := service.WidgetChat(ctx, notebook.ID, "https://example.com", messages)
+	require.NoError(t, err)
+	require.Len(t, answer.Citations, 1)
+	assert.Equal(t, deploys.ID, answer.Citations[0].NoteID, "Earlier questions help find the passages a follow-up refers to")
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "Which day is that?", (*requests)[0].Question)
+	assert.Len(t, (*requests)[0].History, 2)
+
+	usage, err := service.GetSettings(ctx, notebook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.AskedThisMonth, "Widget answers count towards the notebook's monthly cap")
+
+	_, err = service.WidgetChat(ctx, notebook.ID, "https://example.com", messages[:2])
+	assert.ErrorIs(t, err, ErrInvalidNotebookQuestion, "Conversations end with the reader's question")
+
+	config, err := service.WidgetConfig(ctx, notebook.ID, "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "/public/"+notebook.ID+"/widget-chat", config.ChatPath)
+	assert.Equal(t, "https://notes.example.com/public/"+notebook.ID, config.PublicURL)
+}
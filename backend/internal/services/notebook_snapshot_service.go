@@ -0,0 +1,222 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidSnapshotName is returned for a snapshot name that can't be used in a URL
+	ErrInvalidSnapshotName = errors.New("snapshot names must be 1-100 letters, digits, dots, dashes or underscores")
+	// ErrSnapshotExists is returned when the notebook already has a snapshot with the name
+	ErrSnapshotExists = errors.New("a snapshot with this name already exists")
+	// ErrSnapshotNotFound is returned when the notebook has no snapshot with the name
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+// snapshotNamePattern allows version-like names such as v1.2.0 or 2024-q3
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,99}$`)
+
+// SnapshotNote is a note as it was when the snapshot was taken
+type SnapshotNote struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Content   string    `json:"content"`
+	Status    string    `json:"status"`
+	IsPublic  bool      `json:"isPublic"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SnapshotChapter is a chapter as it was when the snapshot was taken
+type SnapshotChapter struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	IsPublic bool           `json:"isPublic"`
+	Notes    []SnapshotNote `json:"notes"`
+}
+
+// NotebookSnapshotResponse is a snapshot with its frozen chapters and notes
+type NotebookSnapshotResponse struct {
+	models.NotebookSnapshot
+	Chapters []SnapshotChapter `json:"chapters"`
+}
+
+// NotebookSnapshotService interface defines methods for freezing notebooks into named, read-only versions
+type NotebookSnapshotService interface {
+	CreateSnapshot(notebookID, name, description, createdBy string) (*models.NotebookSnapshot, error)
+	ListSnapshots(notebookID string, publishedOnly bool) ([]models.NotebookSnapshot, error)
+	GetSnapshot(notebookID, name string, publishedOnly bool) (*NotebookSnapshotResponse, error)
+	DeleteSnapshot(notebookID, name string) error
+}
+
+// notebookSnapshotServiceImpl implements the NotebookSnapshotService interface
+type notebookSnapshotServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewNotebookSnapshotService creates a new NotebookSnapshotService instance
+func NewNotebookSnapshotService() NotebookSnapshotService {
+	return &notebookSnapshotServiceImpl{
+		db: db.DB,
+	}
+}
+
+// CreateSnapshot freezes the notebook's current chapters and notes under a name
+func (s *notebookSnapshotServiceImpl) CreateSnapshot(notebookID, name, description, createdBy string) (*models.NotebookSnapshot, error) {
+	name = strings.TrimSpace(name)
+	if !snapshotNamePattern.MatchString(name) {
+		return nil, ErrInvalidSnapshotName
+	}
+
+	var snapshot models.NotebookSnapshot
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.NotebookSnapshot{}).Where("notebook_id = ? AND name = ?", notebookID, name).Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to check snapshots: %w", err)
+		}
+		if existing > 0 {
+			return ErrSnapshotExists
+		}
+
+		var notebook models.Notebook
+		if err := tx.Where("id = ?", notebookID).
+			Preload("Chapters", func(db *gorm.DB) *gorm.DB {
+				return db.Order("created_at ASC")
+			}).
+			Preload("Chapters.Files", func(db *gorm.DB) *gorm.DB {
+				return db.Select("id, name, content, chapter_id, is_public, status, created_at, updated_at").Order("created_at ASC")
+			}).
+			First(&notebook).Error; err != nil {
+			return fmt.Errorf("failed to fetch notebook: %w", err)
+		}
+
+		chapters := make([]SnapshotChapter, len(notebook.Chapters))
+		noteCount, publicNotes := 0, 0
+		for i, chapter := range notebook.Chapters {
+			notes := make([]SnapshotNote, len(chapter.Files))
+			for j, note := range chapter.Files {
+				notes[j] = SnapshotNote{
+					ID:        note.ID,
+					Name:      note.Name,
+					Content:   note.Content,
+					Status:    note.Status,
+					IsPublic:  note.IsPublic,
+					CreatedAt: note.CreatedAt,
+					UpdatedAt: note.UpdatedAt,
+				}
+				if note.IsPublic {
+					publicNotes++
+				}
+			}
+			noteCount += len(notes)
+			chapters[i] = SnapshotChapter{ID: chapter.ID, Name: chapter.Name, IsPublic: chapter.IsPublic, Notes: notes}
+		}
+
+		content, err := json.Marshal(chapters)
+		if err != nil {
+			return fmt.Errorf("failed to encode snapshot: %w", err)
+		}
+
+		snapshot = models.NotebookSnapshot{
+			NotebookID:     notebookID,
+			Name:           name,
+			Description:    strings.TrimSpace(description),
+			NotebookName:   notebook.Name,
+			OrganizationID: notebook.OrganizationID,
+			CreatedBy:      createdBy,
+			ChapterCount:   len(chapters),
+			NoteCount:      noteCount,
+			PublicNotes:    publicNotes,
+			Content:        string(content),
+		}
+		if err := tx.Create(&snapshot).Error; err != nil {
+			return fmt.Errorf("failed to create snapshot: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("notebook_id", notebookID).
+		Str("snapshot", name).
+		Int("notes", snapshot.NoteCount).
+		Msg("Notebook snapshot created")
+	return &snapshot, nil
+}
+
+// ListSnapshots returns the notebook's snapshots, newest first. With publishedOnly, only snapshots
+// that had published notes are returned.
+func (s *notebookSnapshotServiceImpl) ListSnapshots(notebookID string, publishedOnly bool) ([]models.NotebookSnapshot, error) {
+	snapshots := []models.NotebookSnapshot{}
+	query := s.db.Omit("content").Where("notebook_id = ?", notebookID)
+	if publishedOnly {
+		query = query.Where("public_notes > 0")
+	}
+	if err := query.Order("created_at DESC").Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// GetSnapshot returns a snapshot with its content. With publishedOnly, only the notes that were
+// published when the snapshot was taken are returned, for versioned published sites.
+func (s *notebookSnapshotServiceImpl) GetSnapshot(notebookID, name string, publishedOnly bool) (*NotebookSnapshotResponse, error) {
+	var snapshot models.NotebookSnapshot
+	if err := s.db.Where("notebook_id = ? AND name = ?", notebookID, name).First(&snapshot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch snapshot: %w", err)
+	}
+	if publishedOnly && snapshot.PublicNotes == 0 {
+		return nil, ErrSnapshotNotFound
+	}
+
+	var chapters []SnapshotChapter
+	if err := json.Unmarshal([]byte(snapshot.Content), &chapters); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	if publishedOnly {
+		published := []SnapshotChapter{}
+		for _, chapter := range chapters {
+			notes := []SnapshotNote{}
+			for _, note := range chapter.Notes {
+				if note.IsPublic {
+					notes = append(notes, note)
+				}
+			}
+			if len(notes) > 0 {
+				chapter.Notes = notes
+				published = append(published, chapter)
+			}
+		}
+		chapters = published
+	}
+
+	return &NotebookSnapshotResponse{NotebookSnapshot: snapshot, Chapters: chapters}, nil
+}
+
+// DeleteSnapshot removes a snapshot
+func (s *notebookSnapshotServiceImpl) DeleteSnapshot(notebookID, name string) error {
+	result := s.db.Where("notebook_id = ? AND name = ?", notebookID, name).Delete(&models.NotebookSnapshot{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSnapshotNotFound
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestNotebookSnapshotService creates a notebook snapshot service on an in-memory database
+func setupTestNotebookSnapshotService(t *testing.T) *notebookSnapshotServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NotebookSnapshot{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &notebookSnapshotServiceImpl{db: db}
+}
+
+// createSnapshotNotebook creates a notebook with a published and an unpublished note
+func createSnapshotNotebook(t *testing.T, db *gorm.DB) (models.Notebook, models.Notes) {
+	notebook := models.Notebook{Name: "API Docs", ClerkUserID: "user_owner", IsPublic: true}
+	require.NoError(t, db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Guides", NotebookID: notebook.ID, IsPublic: true}
+	require.NoError(t, db.Create(&chapter).Error)
+	published := models.Notes{Name: "Getting started", Content: "v1 content", ChapterID: chapter.ID, IsPublic: true}
+	require.NoError(t, db.Create(&published).Error)
+	draft := models.Notes{Name: "Upcoming", Content: "draft content", ChapterID: chapter.ID}
+	require.NoError(t, db.Create(&draft).Error)
+	return notebook, published
+}
+
+func TestNotebookSnapshot_FreezesContent(t *testing.T) {
+	service := setupTestNotebookSnapshotService(t)
+	notebook, note := createSnapshotNotebook(t, service.db)
+
+	snapshot, err := service.CreateSnapshot(notebook.ID, " v1.0 ", "First release", "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0", snapshot.Name)
+	assert.Equal(t, 1, snapshot.ChapterCount)
+	assert.Equal(t, 2, snapshot.NoteCount)
+	assert.Equal(t, 1, snapshot.PublicNotes)
+
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", note.ID).Update("content", "v2 content").Error)
+
+	frozen, err := service.GetSnapshot(notebook.ID, "v1.0", false)
+	require.NoError(t, err)
+	require.Len(t, frozen.Chapters, 1)
+	require.Len(t, frozen.Chapters[0].Notes, 2)
+	assert.Equal(t, "v1 content", frozen.Chapters[0].Notes[0].Content, "Later edits should not change the snapshot")
+}
+
+func TestNotebookSnapshot_RejectsInvalidAndDuplicateNames(t *testing.T) {
+	service := setupTestNotebookSnapshotService(t)
+	notebook, _ := createSnapshotNotebook(t, service.db)
+
+	_, err := service.CreateSnapshot(notebook.ID, "release 1", "", "user_owner")
+	assert.ErrorIs(t, err, ErrInvalidSnapshotName)
+	_, err = service.CreateSnapshot(notebook.ID, "", "", "user_owner")
+	assert.ErrorIs(t, err, ErrInvalidSnapshotName)
+
+	_, err = service.CreateSnapshot(notebook.ID, "v1", "", "user_owner")
+	require.NoError(t, err)
+	_, err = service.CreateSnapshot(notebook.ID, "v1", "", "user_owner")
+	assert.ErrorIs(t, err, ErrSnapshotExists)
+}
+
+func TestNotebookSnapshot_PublishedOnly(t *testing.T) {
+	service := setupTestNotebookSnapshotService(t)
+	notebook, note := createSnapshotNotebook(t, service.db)
+
+	_, err := service.CreateSnapshot(notebook.ID, "v1", "", "user_owner")
+	require.NoError(t, err)
+	require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", note.ID).Update("is_public", false).Error)
+	_, err = service.CreateSnapshot(notebook.ID, "internal", "", "user_owner")
+	require.NoError(t, err)
+
+	all, err := service.ListSnapshots(notebook.ID, false)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	published, err := service.ListSnapshots(notebook.ID, true)
+	require.NoError(t, err)
+	require.Len(t, published, 1)
+	assert.Equal(t, "v1", published[0].Name)
+
+	site, err := service.GetSnapshot(notebook.ID, "v1", true)
+	require.NoError(t, err)
+	require.Len(t, site.Chapters, 1)
+	require.Len(t, site.Chapters[0].Notes, 1, "Only published notes should be in a published snapshot")
+	assert.Equal(t, note.ID, site.Chapters[0].Notes[0].ID)
+
+	_, err = service.GetSnapshot(notebook.ID, "internal", true)
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+}
+
+func TestNotebookSnapshot_Delete(t *testing.T) {
+	service := setupTestNotebookSnapshotService(t)
+	notebook, _ := createSnapshotNotebook(t, service.db)
+
+	_, err := service.CreateSnapshot(notebook.ID, "v1", "", "user_owner")
+	require.NoError(t, err)
+
+	require.NoError(t, service.DeleteSnapshot(notebook.ID, "v1"))
+	_, err = service.GetSnapshot(notebook.ID, "v1", false)
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+	assert.ErrorIs(t, service.DeleteSnapshot(notebook.ID, "v1"), ErrSnapshotNotFound)
+}
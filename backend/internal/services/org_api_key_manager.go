@@ -60,7 +60,7 @@ func (m *orgAPIKeyManagerImpl) GetOrgAPICredentials(organizationID string) ([]Or
 		// Decrypt and mask the API key for display
 		var maskedKey *string
 		if len(cred.KeyCipher) > 0 {
-			apiKey, err := utils.Decrypt(cred.KeyCipher)
+			apiKey, err := NewSecretsService().Decrypt(cred.KeyCipher)
 			if err != nil {
 				log.Warn().
 					Err(err).
@@ -111,7 +111,7 @@ func (m *orgAPIKeyManagerImpl) SetOrgAPICredential(organizationID string, provid
 	apiKey = strings.TrimSpace(apiKey)
 
 	// Encrypt the API key
-	encryptedKey, err := utils.Encrypt(apiKey)
+	encryptedKey, err := NewSecretsService().Encrypt(apiKey)
 	if err != nil {
 		log.Error().
 			Err(err).
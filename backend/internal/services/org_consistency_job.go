@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// OrgConsistencyJob periodically repairs chapters, notes and their attached records whose organization
+// drifted from their notebook's
+type OrgConsistencyJob struct {
+	consistencyService OrgConsistencyService
+	interval           time.Duration
+	stopChan           chan struct{}
+}
+
+// NewOrgConsistencyJob creates a new scheduled organization consistency repair job
+func NewOrgConsistencyJob(consistencyService OrgConsistencyService, interval time.Duration) *OrgConsistencyJob {
+	return &OrgConsistencyJob{
+		consistencyService: consistencyService,
+		interval:           interval,
+		stopChan:           make(chan struct{}),
+	}
+}
+
+// Start begins repairing organization drift
+func (j *OrgConsistencyJob) Start(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Msg("Starting organization consistency repair scheduler")
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			report, err := j.consistencyService.RepairAll()
+			if err != nil {
+				log.Error().Err(err).Msg("Organization consistency repair failed")
+			} else if report.Fixed() > 0 {
+				log.Warn().
+					Int64("chapters", report.Chapters).
+					Int64("notes", report.Notes).
+					Int64("dependents", report.Dependents).
+					Msg("Repaired organization drift")
+			}
+		case <-ctx.Done():
+			log.Info().Msg("Stopping organization consistency repair scheduler (context cancelled)")
+			return
+		case <-j.stopChan:
+			log.Info().Msg("Stopping organization consistency repair scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (j *OrgConsistencyJob) Stop() {
+	close(j.stopChan)
+}
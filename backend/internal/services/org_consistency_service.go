@@ -0,0 +1,380 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// orgRepairBatchSize is how many notebooks or notes are repaired per query
+const orgRepairBatchSize = 200
+
+// noteOrgDependents are the records that carry the organization of the note they belong to
+var noteOrgDependents = []struct {
+	model      any
+	noteColumn string
+	conditions map[string]any
+}{
+	{&models.NoteTag{}, "note_id", nil},
+	{&models.NoteProperty{}, "note_id", nil},
+	{&models.NoteLink{}, "source_note_id", nil},
+	{&models.NoteEmbed{}, "source_note_id", nil},
+	{&models.TaskBoard{}, "note_id", map[string]any{"is_standalone": false}}, // Boards generated from the note
+}
+
+// OrgRepairReport counts the records whose organization was corrected
+type OrgRepairReport struct {
+	Notebooks  int   `json:"notebooks"`
+	Chapters   int64 `json:"chapters"`
+	Notes      int64 `json:"notes"`
+	Dependents int64 `json:"dependents"` // Tags, properties, links, embeds, note boards and their tasks
+}
+
+func (r *OrgRepairReport) add(other *OrgRepairReport) {
+	r.Notebooks += other.Notebooks
+	r.Chapters += other.Chapters
+	r.Notes += other.Notes
+	r.Dependents += other.Dependents
+}
+
+// Fixed is the number of records that were corrected
+func (r *OrgRepairReport) Fixed() int64 {
+	return r.Chapters + r.Notes + r.Dependents
+}
+
+// OrgConsistencyService keeps organization_id consistent from a notebook down to its chapters, notes and
+// the records attached to them when content moves between organizations
+type OrgConsistencyService interface {
+	MoveNote(noteID, targetChapterID string) (*models.Notes, error)
+	MoveChapter(chapterID, targetNotebookID string) (*models.Chapter, error)
+	RepairOrganization(organizationID string) (*OrgRepairReport, error)
+	RepairAll() (*OrgRepairReport, error)
+}
+
+// orgConsistencyServiceImpl implements the OrgConsistencyService interface
+type orgConsistencyServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewOrgConsistencyService creates a new organization consistency service
+func NewOrgConsistencyService() OrgConsistencyService {
+	return &orgConsistencyServiceImpl{
+		db: db.DB,
+	}
+}
+
+// MoveNote moves a note to a chapter and gives it and its attached records the organization of the
+// chapter's notebook
+func (s *orgConsistencyServiceImpl) MoveNote(noteID, targetChapterID string) (*models.Notes, error) {
+	var note models.Notes
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id", "chapter_id").Where("id = ?", noteID).First(&note).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoteNotFound
+			}
+			return fmt.Errorf("failed to fetch note: %w", err)
+		}
+
+		var chapter models.Chapter
+		if err := tx.Select("id", "notebook_id").Where("id = ?", targetChapterID).First(&chapter).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrChapterNotFound
+			}
+			return fmt.Errorf("failed to fetch chapter: %w", err)
+		}
+		// Notes of a notebook under legal hold stay in it
+		var sourceNotebookID string
+		if err := tx.Model(&models.Chapter{}).Where("id = ?", note.ChapterID).Pluck("notebook_id", &sourceNotebookID).Error; err != nil {
+			return fmt.Errorf("failed to fetch note chapter: %w", err)
+		}
+		if sourceNotebookID != chapter.NotebookID {
+			if err := CheckNotebookLegalHold(tx, sourceNotebookID); err != nil {
+				return err
+			}
+		}
+		orgID, err := notebookOrganization(tx, chapter.NotebookID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Notes{}).Where("id = ?", noteID).Update("chapter_id", targetChapterID).Error; err != nil {
+			return fmt.Errorf("failed to move note: %w", err)
+		}
+		if _, err := RefreshNoteSlug(tx, noteID); err != nil {
+			return err
+		}
+		_, err = applyNoteOrganization(tx, []string{noteID}, orgID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Where("id = ?", noteID).First(&note).Error; err != nil {
+		return nil, fmt.Errorf("failed to reload note: %w", err)
+	}
+	return &note, nil
+}
+
+// MoveChapter moves a chapter to a notebook and gives it, its notes and their attached records the
+// notebook's organization
+func (s *orgConsistencyServiceImpl) MoveChapter(chapterID, targetNotebookID string) (*models.Chapter, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var chapter models.Chapter
+		if err := tx.Select("id", "notebook_id").Where("id = ?", chapterID).First(&chapter).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrChapterNotFound
+			}
+			return fmt.Errorf("failed to fetch chapter: %w", err)
+		}
+		if chapter.NotebookID != targetNotebookID {
+			if err := CheckNotebookLegalHold(tx, chapter.NotebookID); err != nil {
+				return err
+			}
+		}
+		orgID, err := notebookOrganization(tx, targetNotebookID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Chapter{}).Where("id = ?", chapterID).
+			Select("notebook_id", "organization_id").
+			Updates(map[string]interface{}{"notebook_id": targetNotebookID, "organization_id": orgID}).Error; err != nil {
+			return fmt.Errorf("failed to move chapter: %w", err)
+		}
+		if _, err := RefreshChapterSlug(tx, chapterID); err != nil {
+			return err
+		}
+		// Saved views of the chapter go with it
+		if err := tx.Model(&models.NoteView{}).Where("chapter_id = ?", chapterID).
+			UpdateColumns(map[string]interface{}{"notebook_id": targetNotebookID, "organization_id": orgID}).Error; err != nil {
+			return fmt.Errorf("failed to move chapter views: %w", err)
+		}
+
+		var noteIDs []string
+		if err := tx.Model(&models.Notes{}).Where("chapter_id = ?", chapterID).Pluck("id", &noteIDs).Error; err != nil {
+			return fmt.Errorf("failed to fetch chapter notes: %w", err)
+		}
+		_, err = applyNoteOrganization(tx, noteIDs, orgID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var chapter models.Chapter
+	if err := s.db.Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error; err != nil {
+		return nil, fmt.Errorf("failed to reload chapter: %w", err)
+	}
+	return &chapter, nil
+}
+
+// RepairOrganization corrects the notebooks that belong to the organization and those with chapters,
+// notes or attached records that still claim it
+func (s *orgConsistencyServiceImpl) RepairOrganization(organizationID string) (*OrgRepairReport, error) {
+	notebookIDs := map[string]bool{}
+	collect := func(query *gorm.DB, column string) error {
+		var ids []string
+		if err := query.Pluck(column, &ids).Error; err != nil {
+			return fmt.Errorf("failed to find drifted notebooks: %w", err)
+		}
+		for _, id := range ids {
+			notebookIDs[id] = true
+		}
+		return nil
+	}
+
+	if err := collect(s.db.Model(&models.Notebook{}).Where("organization_id = ?", organizationID), "id"); err != nil {
+		return nil, err
+	}
+	if err := collect(s.db.Model(&models.Chapter{}).Where("organization_id = ?", organizationID), "notebook_id"); err != nil {
+		return nil, err
+	}
+	if err := collect(s.db.Model(&models.Notes{}).
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("notes.organization_id = ?", organizationID), "chapters.notebook_id"); err != nil {
+		return nil, err
+	}
+	for _, dependent := range noteOrgDependents {
+		table, err := tableName(s.db, dependent.model)
+		if err != nil {
+			return nil, err
+		}
+		query := s.db.Model(dependent.model).
+			Joins(fmt.Sprintf("JOIN notes ON notes.id = %s.%s", table, dependent.noteColumn)).
+			Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+			Where(table+".organization_id = ?", organizationID)
+		if err := collect(query, "chapters.notebook_id"); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]string, 0, len(notebookIDs))
+	for id := range notebookIDs {
+		ids = append(ids, id)
+	}
+
+	report := &OrgRepairReport{}
+	for start := 0; start < len(ids); start += orgRepairBatchSize {
+		end := min(start+orgRepairBatchSize, len(ids))
+		var notebooks []models.Notebook
+		if err := s.db.Select("id", "organization_id").Where("id IN ?", ids[start:end]).Find(&notebooks).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch notebooks: %w", err)
+		}
+		batch, err := s.repairNotebooks(notebooks)
+		if err != nil {
+			return nil, err
+		}
+		report.add(batch)
+	}
+
+	log.Info().Str("org_id", organizationID).Int("notebooks", report.Notebooks).Int64("fixed", report.Fixed()).Msg("Organization consistency repaired")
+	return report, nil
+}
+
+// RepairAll corrects every notebook, for the scheduled repair job
+func (s *orgConsistencyServiceImpl) RepairAll() (*OrgRepairReport, error) {
+	report := &OrgRepairReport{}
+	var notebooks []models.Notebook
+	var repairErr error
+	result := s.db.Select("id", "organization_id").FindInBatches(&notebooks, orgRepairBatchSize, func(tx *gorm.DB, batch int) error {
+		batchReport, err := s.repairNotebooks(notebooks)
+		if err != nil {
+			repairErr = err
+			return err
+		}
+		report.add(batchReport)
+		return nil
+	})
+	if repairErr != nil {
+		return nil, repairErr
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to fetch notebooks: %w", result.Error)
+	}
+	return report, nil
+}
+
+// repairNotebooks gives each notebook's chapters, views, notes and attached records its organization
+func (s *orgConsistencyServiceImpl) repairNotebooks(notebooks []models.Notebook) (*OrgRepairReport, error) {
+	report := &OrgRepairReport{}
+	for _, notebook := range notebooks {
+		orgID := normalizeOrganizationID(notebook.OrganizationID)
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			chapters := withOrganizationDrift(tx.Model(&models.Chapter{}).Where("notebook_id = ?", notebook.ID), orgID).
+				UpdateColumn("organization_id", orgID)
+			if chapters.Error != nil {
+				return fmt.Errorf("failed to repair chapters: %w", chapters.Error)
+			}
+			report.Chapters += chapters.RowsAffected
+
+			views := withOrganizationDrift(tx.Model(&models.NoteView{}).Where("notebook_id = ?", notebook.ID), orgID).
+				UpdateColumn("organization_id", orgID)
+			if views.Error != nil {
+				return fmt.Errorf("failed to repair note views: %w", views.Error)
+			}
+			report.Dependents += views.RowsAffected
+
+			var noteIDs []string
+			if err := tx.Model(&models.Notes{}).
+				Where("chapter_id IN (?)", tx.Model(&models.Chapter{}).Select("id").Where("notebook_id = ?", notebook.ID)).
+				Pluck("id", &noteIDs).Error; err != nil {
+				return fmt.Errorf("failed to fetch notebook notes: %w", err)
+			}
+			for start := 0; start < len(noteIDs); start += orgRepairBatchSize {
+				end := min(start+orgRepairBatchSize, len(noteIDs))
+				fixed, err := applyNoteOrganization(tx, noteIDs[start:end], orgID)
+				if err != nil {
+					return err
+				}
+				report.Notes += fixed.Notes
+				report.Dependents += fixed.Dependents
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.Notebooks++
+	}
+	return report, nil
+}
+
+// applyNoteOrganization sets the organization on the notes and their attached records that don't have it
+func applyNoteOrganization(tx *gorm.DB, noteIDs []string, orgID *string) (*OrgRepairReport, error) {
+	report := &OrgRepairReport{}
+	if len(noteIDs) == 0 {
+		return report, nil
+	}
+
+	notes := withOrganizationDrift(tx.Model(&models.Notes{}).Where("id IN ?", noteIDs), orgID).
+		UpdateColumn("organization_id", orgID)
+	if notes.Error != nil {
+		return nil, fmt.Errorf("failed to update note organization: %w", notes.Error)
+	}
+	report.Notes = notes.RowsAffected
+
+	for _, dependent := range noteOrgDependents {
+		query := tx.Model(dependent.model).Where(dependent.noteColumn+" IN ?", noteIDs)
+		if dependent.conditions != nil {
+			query = query.Where(dependent.conditions)
+		}
+		result := withOrganizationDrift(query, orgID).UpdateColumn("organization_id", orgID)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to update attached record organization: %w", result.Error)
+		}
+		report.Dependents += result.RowsAffected
+	}
+
+	// Tasks follow the board of the note they were generated from
+	tasks := withOrganizationDrift(tx.Model(&models.Task{}).
+		Where("task_board_id IN (?)", tx.Model(&models.TaskBoard{}).Select("id").Where("note_id IN ? AND is_standalone = ?", noteIDs, false)), orgID).
+		UpdateColumn("organization_id", orgID)
+	if tasks.Error != nil {
+		return nil, fmt.Errorf("failed to update task organization: %w", tasks.Error)
+	}
+	report.Dependents += tasks.RowsAffected
+
+	return report, nil
+}
+
+// notebookOrganization returns the organization a notebook belongs to, or nil for a personal notebook
+func notebookOrganization(tx *gorm.DB, notebookID string) (*string, error) {
+	var notebook models.Notebook
+	if err := tx.Select("id", "organization_id").Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotebookNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	return normalizeOrganizationID(notebook.OrganizationID), nil
+}
+
+// normalizeOrganizationID treats an empty organization ID as a personal record
+func normalizeOrganizationID(orgID *string) *string {
+	if orgID == nil || *orgID == "" {
+		return nil
+	}
+	return orgID
+}
+
+// withOrganizationDrift narrows a query to rows whose organization_id isn't orgID
+func withOrganizationDrift(query *gorm.DB, orgID *string) *gorm.DB {
+	if orgID == nil {
+		return query.Where("organization_id IS NOT NULL")
+	}
+	return query.Where("(organization_id IS NULL OR organization_id <> ?)", *orgID)
+}
+
+// tableName resolves the table of a model
+func tableName(tx *gorm.DB, model any) (string, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return "", fmt.Errorf("failed to parse model: %w", err)
+	}
+	return stmt.Schema.Table, nil
+}
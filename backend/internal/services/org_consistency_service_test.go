@@ -0,0 +1,199 @@
+package services
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// orgConsistencyFixture is a personal notebook with a chapter whose note has attached records, and an
+// organization notebook to move them to
+type orgConsistencyFixture struct {
+	service      *orgConsistencyServiceImpl
+	orgNotebook  models.Notebook
+	orgChapter   models.Chapter
+	chapter      models.Chapter
+	note         models.Notes
+	noteBoard    models.TaskBoard
+	standalone   models.TaskBoard
+	chapterView  models.NoteView
+	organization string
+}
+
+// setupTestOrgConsistencyService creates an organization consistency service on an in-memory database
+func setupTestOrgConsistencyService(t *testing.T) *orgConsistencyFixture {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteTag{}, &models.NoteProperty{},
+		&models.NoteLink{}, &models.NoteEmbed{}, &models.TaskBoard{}, &models.Task{}, &models.NoteView{}, &models.NotebookLegalHold{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	f := &orgConsistencyFixture{service: &orgConsistencyServiceImpl{db: db}, organization: "org_1"}
+
+	f.orgNotebook = models.Notebook{Name: "Team", ClerkUserID: "user_owner", OrganizationID: &f.organization}
+	require.NoError(t, db.Create(&f.orgNotebook).Error)
+	f.orgChapter = models.Chapter{Name: "Shared", NotebookID: f.orgNotebook.ID, OrganizationID: &f.organization}
+	require.NoError(t, db.Create(&f.orgChapter).Error)
+
+	personal := models.Notebook{Name: "Mine", ClerkUserID: "user_owner"}
+	require.NoError(t, db.Create(&personal).Error)
+	f.chapter = models.Chapter{Name: "Drafts", NotebookID: personal.ID}
+	require.NoError(t, db.Create(&f.chapter).Error)
+	f.note = models.Notes{Name: "Plan", ChapterID: f.chapter.ID}
+	require.NoError(t, db.Create(&f.note).Error)
+	other := models.Notes{Name: "Other", ChapterID: f.chapter.ID}
+	require.NoError(t, db.Create(&other).Error)
+
+	require.NoError(t, db.Create(&models.NoteTag{NoteID: f.note.ID, Tag: "plan"}).Error)
+	require.NoError(t, db.Create(&models.NoteProperty{NoteID: f.note.ID, Key: "status", Type: "text", Value: "draft"}).Error)
+	require.NoError(t, db.Create(&models.NoteLink{ID: "link_1", SourceNoteID: f.note.ID, TargetNoteID: other.ID, CreatedBy: "user_owner"}).Error)
+	require.NoError(t, db.Create(&models.NoteEmbed{SourceNoteID: f.note.ID, TargetNoteID: other.ID, CreatedBy: "user_owner"}).Error)
+
+	f.noteBoard = models.TaskBoard{Name: "Plan tasks", NoteID: &f.note.ID, ClerkUserID: "user_owner"}
+	require.NoError(t, db.Create(&f.noteBoard).Error)
+	require.NoError(t, db.Create(&models.Task{Title: "Write it", TaskBoardID: f.noteBoard.ID}).Error)
+	f.standalone = models.TaskBoard{Name: "Personal board", NoteID: &f.note.ID, ClerkUserID: "user_owner", IsStandalone: true}
+	require.NoError(t, db.Create(&f.standalone).Error)
+
+	f.chapterView = models.NoteView{Name: "Drafts table", Layout: models.NoteViewTable, NotebookID: personal.ID, ChapterID: &f.chapter.ID, CreatedBy: "user_owner"}
+	require.NoError(t, db.Create(&f.chapterView).Error)
+
+	return f
+}
+
+// organizationOf returns the organization of every row of the model matching the condition
+func organizationOf(t *testing.T, db *gorm.DB, model any, query string, args ...any) []*string {
+	var values []sql.NullString
+	require.NoError(t, db.Model(model).Where(query, args...).Pluck("organization_id", &values).Error)
+	require.NotEmpty(t, values)
+
+	orgIDs := make([]*string, len(values))
+	for i, value := range values {
+		if value.Valid {
+			orgIDs[i] = &value.String
+		}
+	}
+	return orgIDs
+}
+
+// assertNoteOrganization checks the note and everything attached to it belongs to the organization
+func assertNoteOrganization(t *testing.T, f *orgConsistencyFixture, orgID *string) {
+	db := f.service.db
+	checks := []struct {
+		model any
+		query string
+	}{
+		{&models.Notes{}, "id = ?"},
+		{&models.NoteTag{}, "note_id = ?"},
+		{&models.NoteProperty{}, "note_id = ?"},
+		{&models.NoteLink{}, "source_note_id = ?"},
+		{&models.NoteEmbed{}, "source_note_id = ?"},
+		{&models.TaskBoard{}, "note_id = ? AND is_standalone = false"},
+	}
+	for _, check := range checks {
+		for _, got := range organizationOf(t, db, check.model, check.query, f.note.ID) {
+			assert.Equal(t, orgID, got, "%T should follow the note", check.model)
+		}
+	}
+	for _, got := range organizationOf(t, db, &models.Task{}, "task_board_id = ?", f.noteBoard.ID) {
+		assert.Equal(t, orgID, got, "Tasks should follow their board")
+	}
+}
+
+func TestOrgConsistency_MoveChapterCascades(t *testing.T) {
+	f := setupTestOrgConsistencyService(t)
+
+	chapter, err := f.service.MoveChapter(f.chapter.ID, f.orgNotebook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.orgNotebook.ID, chapter.NotebookID)
+	assert.Equal(t, &f.organization, chapter.OrganizationID)
+
+	assertNoteOrganization(t, f, &f.organization)
+	for _, got := range organizationOf(t, f.service.db, &models.Notes{}, "chapter_id = ?", f.chapter.ID) {
+		assert.Equal(t, &f.organization, got)
+	}
+
+	var view models.NoteView
+	require.NoError(t, f.service.db.Where("id = ?", f.chapterView.ID).First(&view).Error)
+	assert.Equal(t, f.orgNotebook.ID, view.NotebookID)
+	assert.Equal(t, &f.organization, view.OrganizationID)
+
+	var standalone models.TaskBoard
+	require.NoError(t, f.service.db.Where("id = ?", f.standalone.ID).First(&standalone).Error)
+	assert.Nil(t, standalone.OrganizationID, "Standalone boards keep their own organization")
+
+	_, err = f.service.MoveChapter(f.chapter.ID, "missing")
+	assert.ErrorIs(t, err, ErrNotebookNotFound)
+}
+
+func TestOrgConsistency_MoveNoteBackToPersonal(t *testing.T) {
+	f := setupTestOrgConsistencyService(t)
+
+	note, err := f.service.MoveNote(f.note.ID, f.orgChapter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.orgChapter.ID, note.ChapterID)
+	assertNoteOrganization(t, f, &f.organization)
+
+	note, err = f.service.MoveNote(f.note.ID, f.chapter.ID)
+	require.NoError(t, err)
+	assert.Nil(t, note.OrganizationID)
+	assertNoteOrganization(t, f, nil)
+
+	_, err = f.service.MoveNote("missing", f.chapter.ID)
+	assert.ErrorIs(t, err, ErrNoteNotFound)
+	_, err = f.service.MoveNote(f.note.ID, "missing")
+	assert.ErrorIs(t, err, ErrChapterNotFound)
+}
+
+func TestOrgConsistency_LegalHoldKeepsContentInNotebook(t *testing.T) {
+	f := setupTestOrgConsistencyService(t)
+	db := f.service.db
+
+	_, err := f.service.MoveNote(f.note.ID, f.orgChapter.ID)
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.NotebookLegalHold{NotebookID: f.orgNotebook.ID, OrganizationID: f.organization, PlacedBy: "user_admin"}).Error)
+
+	_, err = f.service.MoveNote(f.note.ID, f.chapter.ID)
+	assert.ErrorIs(t, err, ErrLegalHold)
+	_, err = f.service.MoveChapter(f.orgChapter.ID, f.chapter.NotebookID)
+	assert.ErrorIs(t, err, ErrLegalHold)
+
+	// Content can still come into a notebook under hold
+	_, err = f.service.MoveChapter(f.chapter.ID, f.orgNotebook.ID)
+	require.NoError(t, err)
+}
+
+func TestOrgConsistency_RepairFixesDrift(t *testing.T) {
+	f := setupTestOrgConsistencyService(t)
+	db := f.service.db
+
+	// A chapter moved by an older path: its notebook changed but nothing under it did
+	require.NoError(t, db.Model(&models.Chapter{}).Where("id = ?", f.chapter.ID).Update("notebook_id", f.orgNotebook.ID).Error)
+
+	report, err := f.service.RepairOrganization(f.organization)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Chapters)
+	assert.Equal(t, int64(2), report.Notes)
+	assert.Equal(t, int64(6), report.Dependents, "Tag, property, link, embed, note board and task")
+	assertNoteOrganization(t, f, &f.organization)
+
+	report, err = f.service.RepairAll()
+	require.NoError(t, err)
+	assert.Zero(t, report.Fixed(), "Nothing is left to repair")
+	assert.Equal(t, 2, report.Notebooks)
+
+	// Records that still claim the organization after their note left it
+	require.NoError(t, db.Model(&models.Chapter{}).Where("id = ?", f.chapter.ID).Update("notebook_id", f.orgNotebook.ID).Error)
+	require.NoError(t, db.Model(&models.Notebook{}).Where("id = ?", f.orgNotebook.ID).Update("organization_id", nil).Error)
+
+	report, err = f.service.RepairOrganization(f.organization)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), report.Chapters)
+	assertNoteOrganization(t, f, nil)
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/organizationinvitation"
+	"github.com/clerk/clerk-sdk-go/v2/organizationmembership"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrInvalidMemberImport is returned for files that can't be read as a CSV of emails and roles
+	ErrInvalidMemberImport = errors.New("invalid member import")
+	// ErrMemberImportTooLarge is returned for files with more rows than are imported at once
+	ErrMemberImportTooLarge = errors.New("too many members to import at once")
+)
+
+const (
+	// maxMemberImportRows is the most invitations one import sends, to stay within Clerk's rate limits
+	maxMemberImportRows = 200
+	// MaxMemberImportFileSize is the largest CSV file accepted
+	MaxMemberImportFileSize = 256 << 10
+	defaultImportRole       = "org:member"
+)
+
+// Member import row statuses
+const (
+	MemberImportInvited = "invited" // An invitation was sent, or would be on a dry run
+	MemberImportSkipped = "skipped" // Already a member, already invited, or listed earlier in the file
+	MemberImportInvalid = "invalid" // The email or role can't be used
+	MemberImportFailed  = "failed"  // Clerk didn't create the invitation
+)
+
+// MemberImportOptions sets up how a member import invites people
+type MemberImportOptions struct {
+	DefaultRole string // Clerk role of rows without one, org:member when empty
+	RedirectURL string // Where invitees land after accepting
+	DryRun      bool   // Validate the rows without sending invitations
+}
+
+// MemberImportRow is the outcome of one row of an import
+type MemberImportRow struct {
+	Row          int    `json:"row"` // Line in the file, counting the header
+	Email        string `json:"email"`
+	Role         string `json:"role,omitempty"`
+	Status       string `json:"status"`
+	Message      string `json:"message,omitempty"`
+	InvitationID string `json:"invitationId,omitempty"`
+}
+
+// MemberImportResult is the outcome of a member import, row by row
+type MemberImportResult struct {
+	DryRun  bool              `json:"dryRun"`
+	Invited int               `json:"invited"`
+	Skipped int               `json:"skipped"`
+	Invalid int               `json:"invalid"`
+	Failed  int               `json:"failed"`
+	Rows    []MemberImportRow `json:"rows"`
+}
+
+// OrgMemberImportService interface defines methods for inviting people to organizations in bulk
+type OrgMemberImportService interface {
+	Import(ctx context.Context, orgID, inviterID string, file io.Reader, options MemberImportOptions) (*MemberImportResult, error)
+}
+
+// orgMemberImportServiceImpl implements the OrgMemberImportService interface
+type orgMemberImportServiceImpl struct {
+	// existing returns the lowercased emails of an organization's members and pending invitations, with
+	// MemberImportSkipped messages saying which they are
+	existing  func(ctx context.Context, orgID string) (map[string]string, error)
+	invite    func(ctx context.Context, orgID, inviterID, email, role, redirectURL string) (string, error)
+	validRole func(clerkRole string) bool
+}
+
+// NewOrgMemberImportService creates a new OrgMemberImportService instance
+func NewOrgMemberImportService() OrgMemberImportService {
+	return &orgMemberImportServiceImpl{
+		existing:  clerkOrgEmails,
+		invite:    clerkInviteMember,
+		validRole: accessLookups.ValidClerkOrgRole,
+	}
+}
+
+// Import invites the people a CSV of emails and roles lists. The file may start with a header naming
+// its email and role columns; without one the first column is the email and the second the role.
+// Rows are checked one by one, and a row that can't be imported doesn't stop the others.
+func (s *orgMemberImportServiceImpl) Import(ctx context.Context, orgID, inviterID string, file io.Reader, options MemberImportOptions) (*MemberImportResult, error) {
+	defaultRole := defaultImportRole
+	if options.DefaultRole != "" {
+		role, ok := s.importRole(options.DefaultRole)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown default role %s", ErrInvalidMemberImport, options.DefaultRole)
+		}
+		defaultRole = role
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var records [][]string
+	var lines []int // The line each record starts on, as the reader skips blank lines
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMemberImport, err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+
+	emailColumn, roleColumn, first := 0, 1, 0
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff") // Spreadsheets may start files with a byte order mark
+		if email, role, ok := memberImportHeader(records[0]); ok {
+			emailColumn, roleColumn, first = email, role, 1
+		}
+	}
+	if len(records)-first == 0 {
+		return nil, fmt.Errorf("%w: the file lists no one to invite", ErrInvalidMemberImport)
+	}
+	if len(records)-first > maxMemberImportRows {
+		return nil, fmt.Errorf("%w: at most %d rows can be imported at once", ErrMemberImportTooLarge, maxMemberImportRows)
+	}
+
+	existing, err := s.existing(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MemberImportResult{DryRun: options.DryRun, Rows: make([]MemberImportRow, 0, len(records)-first)}
+	listed := make(map[string]int)
+	for i := first; i < len(records); i++ {
+		record := records[i]
+		if len(record) == 0 || (len(record) == 1 && strings.TrimSpace(record[0]) == "") {
+			continue
+		}
+		row := MemberImportRow{Row: lines[i], Email: strings.TrimSpace(csvColumn(record, emailColumn))}
+		role := strings.TrimSpace(csvColumn(record, roleColumn))
+
+		switch address, err := mail.ParseAddress(row.Email); {
+		case row.Email == "":
+			row.Status, row.Message = MemberImportInvalid, "email is required"
+		case err != nil || address.Address != row.Email:
+			row.Status, row.Message = MemberImportInvalid, "not a valid email address"
+		}
+		if row.Status == "" {
+			row.Email = strings.ToLower(row.Email)
+			row.Role = defaultRole
+			if role != "" {
+				if clerkRole, ok := s.importRole(role); ok {
+					row.Role = clerkRole
+				} else {
+					row.Role = role
+					row.Status, row.Message = MemberImportInvalid, "role must be admin, member, commenter or viewer"
+				}
+			}
+		}
+		if row.Status == "" {
+			if earlier, ok := listed[row.Email]; ok {
+				row.Status, row.Message = MemberImportSkipped, fmt.Sprintf("already listed on row %d", earlier)
+			} else {
+				listed[row.Email] = row.Row
+				if message, ok := existing[row.Email]; ok {
+					row.Status, row.Message = MemberImportSkipped, message
+				}
+			}
+		}
+
+		if row.Status == "" {
+			row.Status = MemberImportInvited
+			if !options.DryRun {
+				invitationID, err := s.invite(ctx, orgID, inviterID, row.Email, row.Role, options.RedirectURL)
+				if err != nil {
+					log.Error().Err(err).Str("org_id", orgID).Str("email", row.Email).Msg("Failed to create imported invitation")
+					row.Status, row.Message = MemberImportFailed, "the invitation couldn't be sent"
+					if apiErr, ok := err.(*clerk.APIErrorResponse); ok && len(apiErr.Errors) > 0 {
+						row.Message = apiErr.Errors[0].Message
+					}
+				}
+				row.InvitationID = invitationID
+			}
+		}
+
+		switch row.Status {
+		case MemberImportInvited:
+			result.Invited++
+		case MemberImportSkipped:
+			result.Skipped++
+		case MemberImportInvalid:
+			result.Invalid++
+		case MemberImportFailed:
+			result.Failed++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	log.Info().Str("org_id", orgID).Str("user_id", inviterID).Bool("dry_run", options.DryRun).
+		Int("invited", result.Invited).Int("skipped", result.Skipped).Int("invalid", result.Invalid).Int("failed", result.Failed).
+		Msg("Members imported")
+	return result, nil
+}
+
+// memberImportHeader finds the email and role columns of a header row. ok is false when the row isn't
+// a header.
+func memberImportHeader(record []string) (emailColumn, roleColumn int, ok bool) {
+	emailColumn, roleColumn = -1, -1
+	for i, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "email", "email address", "e-mail":
+			emailColumn = i
+		case "role":
+			roleColumn = i
+		}
+	}
+	return emailColumn, roleColumn, emailColumn >= 0
+}
+
+// csvColumn returns a column of a record, empty when the record is shorter or the column is -1
+func csvColumn(record []string, column int) string {
+	if column < 0 || column >= len(record) {
+		return ""
+	}
+	return record[column]
+}
+
+// importRole returns the Clerk role of a role written as a Clerk role or as our simplified one
+func (s *orgMemberImportServiceImpl) importRole(role string) (string, bool) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if !strings.HasPrefix(role, "org:") {
+		role = "org:" + role
+	}
+	return role, s.validRole(role)
+}
+
+// clerkOrgEmails lists the emails of an organization's members and pending invitations from Clerk
+func clerkOrgEmails(ctx context.Context, orgID string) (map[string]string, error) {
+	emails := make(map[string]string)
+	for offset := int64(0); ; offset += 100 {
+		params := &organizationmembership.ListParams{OrganizationID: orgID}
+		params.Limit = clerk.Int64(100)
+		params.Offset = clerk.Int64(offset)
+		memberships, err := organizationmembership.List(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization members: %w", err)
+		}
+		for _, membership := range memberships.OrganizationMemberships {
+			if membership.PublicUserData != nil && membership.PublicUserData.Identifier != "" {
+				emails[strings.ToLower(membership.PublicUserData.Identifier)] = "already a member"
+			}
+		}
+		if len(memberships.OrganizationMemberships) < 100 {
+			break
+		}
+	}
+
+	for offset := int64(0); ; offset += 100 {
+		params := &organizationinvitation.ListParams{OrganizationID: orgID, Statuses: &[]string{"pending"}}
+		params.Limit = clerk.Int64(100)
+		params.Offset = clerk.Int64(offset)
+		invitations, err := organizationinvitation.List(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization invitations: %w", err)
+		}
+		for _, invitation := range invitations.OrganizationInvitations {
+			email := strings.ToLower(invitation.EmailAddress)
+			if _, ok := emails[email]; !ok {
+				emails[email] = "already invited"
+			}
+		}
+		if len(invitations.OrganizationInvitations) < 100 {
+			break
+		}
+	}
+	return emails, nil
+}
+
+// clerkInviteMember creates a Clerk organization invitation and returns its ID
+func clerkInviteMember(ctx context.Context, orgID, inviterID, email, role, redirectURL string) (string, error) {
+	params := &organizationinvitation.CreateParams{
+		OrganizationID: orgID,
+		EmailAddress:   clerk.String(email),
+		InviterUserID:  clerk.String(inviterID),
+		Role:           clerk.String(role),
+	}
+	if redirectURL != "" {
+		params.RedirectURL = clerk.String(redirectURL)
+	}
+	invitation, err := organizationinvitation.Create(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	return invitation.ID, nil
+}
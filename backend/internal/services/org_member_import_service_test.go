@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// invitedMember is an invitation the member import service sent
+type invitedMember struct {
+	email, role, redirectURL string
+}
+
+func setupTestOrgMemberImportService(t *testing.T) (*orgMemberImportServiceImpl, *[]invitedMember) {
+	var invited []invitedMember
+	return &orgMemberImportServiceImpl{
+		existing: func(ctx context.Context, orgID string) (map[string]string, error) {
+			require.Equal(t, "org_1", orgID)
+			return map[string]string{"member@example.com": "already a member", "pending@example.com": "already invited"}, nil
+		},
+		invite: func(ctx context.Context, orgID, inviterID, email, role, redirectURL string) (string, error) {
+			if email == "blocked@example.com" {
+				return "", &clerk.APIErrorResponse{Errors: []clerk.Error{{Message: "That email address is not allowed"}}}
+			}
+			invited = append(invited, invitedMember{email: email, role: role, redirectURL: redirectURL})
+			return "inv_" + email[:strings.Index(email, "@")], nil
+		},
+		validRole: func(clerkRole string) bool {
+			return slices.Contains([]string{"org:admin", "org:member", "org:commenter", "org:viewer"}, clerkRole)
+		},
+	}, &invited
+}
+
+func TestOrgMemberImportService_Import(t *testing.T) {
+	service, invited := setupTestOrgMemberImportService(t)
+	ctx := context.Background()
+
+	file := "\ufeffRole,Email Address,Team\n" +
+		"admin,Alice@Example.com,Design\n" +
+		",bob@example.com,Design\n" +
+		"org:viewer,carol@example.com\n" +
+		"owner,dave@example.com,Ops\n" +
+		"member,not an email,Ops\n" +
+		"member,Erin <erin@example.com>,Ops\n" +
+		"member,alice@example.com,Ops\n" +
+		"member,MEMBER@example.com,Ops\n" +
+		"member,pending@example.com,Ops\n" +
+		"\n" +
+		"member,blocked@example.com,Ops\n"
+	result, err := service.Import(ctx, "org_1", "user_admin", strings.NewReader(file),
+		MemberImportOptions{DefaultRole: "commenter", RedirectURL: "https://notes.example.com/welcome"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, result.Invited)
+	assert.Equal(t, 3, result.Skipped)
+	assert.Equal(t, 3, result.Invalid)
+	assert.Equal(t, 1, result.Failed)
+	require.Len(t, result.Rows, 10, "Blank lines are ignored")
+
+	assert.Equal(t, []invitedMember{
+		{email: "alice@example.com", role: "org:admin", redirectURL: "https://notes.example.com/welcome"},
+		{email: "bob@example.com", role: "org:commenter", redirectURL: "https://notes.example.com/welcome"},
+		{email: "carol@example.com", role: "org:viewer", redirectURL: "https://notes.example.com/welcome"},
+	}, *invited)
+	assert.Equal(t, MemberImportRow{Row: 2, Email: "alice@example.com", Role: "org:admin", Status: MemberImportInvited, InvitationID: "inv_alice"}, result.Rows[0])
+	assert.Equal(t, MemberImportInvalid, result.Rows[3].Status)
+	assert.Equal(t, "role must be admin, member, commenter or viewer", result.Rows[3].Message)
+	assert.Equal(t, "not a valid email address", result.Rows[4].Message)
+	assert.Equal(t, "not a valid email address", result.Rows[5].Message, "Emails are plain addresses")
+	assert.Equal(t, "already listed on row 2", result.Rows[6].Message)
+	assert.Equal(t, "already a member", result.Rows[7].Message)
+	assert.Equal(t, "already invited", result.Rows[8].Message)
+	assert.Equal(t, MemberImportRow{Row: 12, Email: "blocked@example.com", Role: "org:member", Status: MemberImportFailed,
+		Message: "That email address is not allowed"}, result.Rows[9])
+}
+
+func TestOrgMemberImportService_Import_DryRun(t *testing.T) {
+	service, invited := setupTestOrgMemberImportService(t)
+	ctx := context.Background()
+
+	result, err := service.Import(ctx, "org_1", "user_admin", strings.NewReader("alice@example.com,admin\nbob@example.com\n"),
+		MemberImportOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Empty(t, *invited, "Dry runs don't send invitations")
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 2, result.Invited)
+	assert.Equal(t, "org:member", result.Rows[1].Role, "Rows without a role get the default role")
+	assert.Equal(t, 1, result.Rows[0].Row, "Files without a header start at the first line")
+
+	result, err = service.Import(ctx, "org_1", "user_admin", strings.NewReader("carol@example.com\nCarol@example.com\ncarol@example.com\n"),
+		MemberImportOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "already listed on row 1", result.Rows[1].Message)
+	assert.Equal(t, "already listed on row 1", result.Rows[2].Message, "Duplicates point at the first row with the email")
+
+	_, err = service.Import(ctx, "org_1", "user_admin", strings.NewReader("email,role\n"), MemberImportOptions{})
+	assert.ErrorIs(t, err, ErrInvalidMemberImport)
+	_, err = service.Import(ctx, "org_1", "user_admin", strings.NewReader("a@example.com"), MemberImportOptions{DefaultRole: "owner"})
+	assert.ErrorIs(t, err, ErrInvalidMemberImport)
+	_, err = service.Import(ctx, "org_1", "user_admin", strings.NewReader("\"unterminated"), MemberImportOptions{})
+	assert.ErrorIs(t, err, ErrInvalidMemberImport)
+	_, err = service.Import(ctx, "org_1", "user_admin", strings.NewReader(strings.Repeat("a@example.com\n", maxMemberImportRows+1)), MemberImportOptions{})
+	assert.ErrorIs(t, err, ErrMemberImportTooLarge)
+}
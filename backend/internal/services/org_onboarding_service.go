@@ -0,0 +1,327 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	maxOnboardingSharedNotebooks = 10
+	maxOnboardingChapters        = 20
+	maxOnboardingNotes           = 50
+	maxOnboardingNameLength      = 255
+	maxOnboardingNoteLength      = 20000
+
+	// onboardingMemberPlaceholder is replaced by the member's name in the member workspace's name
+	onboardingMemberPlaceholder = "{member}"
+	onboardingSharedKeyPrefix   = "shared:"
+	onboardingMemberKeyPrefix   = "member:"
+)
+
+// ErrInvalidOnboardingSettings is returned when onboarding notebook templates are missing names or too large
+var ErrInvalidOnboardingSettings = errors.New("invalid onboarding settings")
+
+// OnboardingNote is a note created in an onboarding notebook, with markdown content
+type OnboardingNote struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// OnboardingChapter is a chapter created in an onboarding notebook. Its note template is the markdown
+// notes added to the chapter later start from.
+type OnboardingChapter struct {
+	Name         string           `json:"name"`
+	NoteTemplate string           `json:"noteTemplate,omitempty"`
+	Notes        []OnboardingNote `json:"notes"`
+}
+
+// OnboardingNotebook is the template of a notebook created for members joining an organization
+type OnboardingNotebook struct {
+	Name     string              `json:"name"`
+	Chapters []OnboardingChapter `json:"chapters"`
+}
+
+// OrgOnboardingSettings is the API representation of an organization's onboarding settings
+type OrgOnboardingSettings struct {
+	SharedNotebooks []OnboardingNotebook `json:"sharedNotebooks"`
+	MemberWorkspace *OnboardingNotebook  `json:"memberWorkspace"` // Name may use {member}, nil for no member workspaces
+	UpdatedBy       string               `json:"updatedBy,omitempty"`
+}
+
+// OrgOnboardingService interface defines methods for configuring and provisioning the notebooks members
+// land in when they join an organization
+type OrgOnboardingService interface {
+	GetSettings(ctx context.Context, organizationID string) (*OrgOnboardingSettings, error)
+	SaveSettings(ctx context.Context, organizationID string, input OrgOnboardingSettings, updatedBy string) (*OrgOnboardingSettings, error)
+	ProvisionMember(ctx context.Context, organizationID, clerkUserID, memberName string) ([]models.Notebook, error)
+}
+
+// orgOnboardingServiceImpl implements the OrgOnboardingService interface
+type orgOnboardingServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewOrgOnboardingService creates a new OrgOnboardingService instance
+func NewOrgOnboardingService() OrgOnboardingService {
+	return &orgOnboardingServiceImpl{
+		db: db.DB,
+	}
+}
+
+// GetSettings returns an organization's onboarding settings, with no notebooks if none are saved
+func (s *orgOnboardingServiceImpl) GetSettings(ctx context.Context, organizationID string) (*OrgOnboardingSettings, error) {
+	settings, err := s.loadSettings(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	return toOrgOnboardingSettings(settings)
+}
+
+// SaveSettings replaces an organization's onboarding notebooks. They're provisioned for members who join
+// from then on.
+func (s *orgOnboardingServiceImpl) SaveSettings(ctx context.Context, organizationID string, input OrgOnboardingSettings, updatedBy string) (*OrgOnboardingSettings, error) {
+	seen := make(map[string]bool, len(input.SharedNotebooks))
+	if len(input.SharedNotebooks) > maxOnboardingSharedNotebooks {
+		return nil, fmt.Errorf("%w: at most %d shared notebooks", ErrInvalidOnboardingSettings, maxOnboardingSharedNotebooks)
+	}
+	for i := range input.SharedNotebooks {
+		if err := normalizeOnboardingNotebook(&input.SharedNotebooks[i]); err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(input.SharedNotebooks[i].Name)
+		if seen[key] {
+			return nil, fmt.Errorf("%w: shared notebook %q is listed twice", ErrInvalidOnboardingSettings, input.SharedNotebooks[i].Name)
+		}
+		seen[key] = true
+	}
+	if input.MemberWorkspace != nil {
+		if err := normalizeOnboardingNotebook(input.MemberWorkspace); err != nil {
+			return nil, err
+		}
+	}
+
+	shared, err := json.Marshal(input.SharedNotebooks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode shared notebooks: %w", err)
+	}
+	workspace := ""
+	if input.MemberWorkspace != nil {
+		encoded, err := json.Marshal(input.MemberWorkspace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode member workspace: %w", err)
+		}
+		workspace = string(encoded)
+	}
+
+	settings := models.OrganizationOnboardingSettings{OrganizationID: organizationID}
+	if err := s.db.WithContext(ctx).Where(models.OrganizationOnboardingSettings{OrganizationID: organizationID}).
+		Assign(map[string]interface{}{
+			"shared_notebooks": string(shared),
+			"member_workspace": workspace,
+			"updated_by":       updatedBy,
+		}).
+		FirstOrCreate(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to save onboarding settings: %w", err)
+	}
+	return toOrgOnboardingSettings(&settings)
+}
+
+// ProvisionMember creates the organization's shared notebooks that don't exist yet and the member's own
+// workspace, returning the notebooks it created. Provisioning again creates nothing new, unless a
+// provisioned notebook was deleted since.
+func (s *orgOnboardingServiceImpl) ProvisionMember(ctx context.Context, organizationID, clerkUserID, memberName string) ([]models.Notebook, error) {
+	record, err := s.loadSettings(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := toOrgOnboardingSettings(record)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shared notebooks belong to the admin who set them up, so they stay put when the member leaves
+	sharedOwner := record.UpdatedBy
+	if sharedOwner == "" {
+		sharedOwner = clerkUserID
+	}
+	memberName = strings.TrimSpace(memberName)
+	if memberName == "" {
+		memberName = "Member"
+	}
+
+	var created []models.Notebook
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, template := range settings.SharedNotebooks {
+			notebook, err := provisionOnboardingNotebook(tx, organizationID, onboardingSharedKeyPrefix+strings.ToLower(template.Name), sharedOwner, template)
+			if err != nil {
+				return err
+			}
+			if notebook != nil {
+				created = append(created, *notebook)
+			}
+		}
+
+		if settings.MemberWorkspace != nil {
+			template := *settings.MemberWorkspace
+			template.Name = truncateOnboardingName(strings.ReplaceAll(template.Name, onboardingMemberPlaceholder, memberName))
+			notebook, err := provisionOnboardingNotebook(tx, organizationID, onboardingMemberKeyPrefix+clerkUserID, clerkUserID, template)
+			if err != nil {
+				return err
+			}
+			if notebook != nil {
+				created = append(created, *notebook)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(created) > 0 {
+		log.Info().Str("org_id", organizationID).Str("user_id", clerkUserID).Int("notebooks", len(created)).Msg("Provisioned onboarding notebooks")
+	}
+	return created, nil
+}
+
+// loadSettings returns an organization's saved onboarding settings, empty ones if there are none
+func (s *orgOnboardingServiceImpl) loadSettings(ctx context.Context, organizationID string) (*models.OrganizationOnboardingSettings, error) {
+	var settings models.OrganizationOnboardingSettings
+	if err := s.db.WithContext(ctx).Where("organization_id = ?", organizationID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch onboarding settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// provisionOnboardingNotebook creates a notebook from a template unless the one provisioned under the key
+// still exists, returning nil then
+func provisionOnboardingNotebook(tx *gorm.DB, organizationID, key, ownerID string, template OnboardingNotebook) (*models.Notebook, error) {
+	var count int64
+	if err := tx.Model(&models.OrganizationProvisionedNotebook{}).
+		Joins("JOIN notebooks ON notebooks.id = organization_provisioned_notebooks.notebook_id").
+		Where("organization_provisioned_notebooks.organization_id = ? AND organization_provisioned_notebooks.provision_key = ?", organizationID, key).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check provisioned notebooks: %w", err)
+	}
+	if count > 0 {
+		return nil, nil
+	}
+	// A record left behind by a notebook deleted without foreign keys is replaced
+	if err := tx.Where("organization_id = ? AND provision_key = ?", organizationID, key).Delete(&models.OrganizationProvisionedNotebook{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to clear provisioned notebook: %w", err)
+	}
+
+	orgID := organizationID
+	notebook := models.Notebook{Name: template.Name, ClerkUserID: ownerID, OrganizationID: &orgID}
+	if err := tx.Create(&notebook).Error; err != nil {
+		return nil, fmt.Errorf("failed to create onboarding notebook: %w", err)
+	}
+	for _, chapterTemplate := range template.Chapters {
+		chapter := models.Chapter{
+			Name:           chapterTemplate.Name,
+			NotebookID:     notebook.ID,
+			OrganizationID: &orgID,
+			NoteTemplate:   chapterTemplate.NoteTemplate,
+		}
+		if err := tx.Create(&chapter).Error; err != nil {
+			return nil, fmt.Errorf("failed to create onboarding chapter: %w", err)
+		}
+		for _, noteTemplate := range chapterTemplate.Notes {
+			content := ""
+			if noteTemplate.Content != "" {
+				converted, err := utils.MarkdownToTipTap(noteTemplate.Content)
+				if err != nil {
+					return nil, fmt.Errorf("failed to convert onboarding note: %w", err)
+				}
+				content = converted
+			}
+			note := models.Notes{Name: noteTemplate.Name, Content: content, ChapterID: chapter.ID, OrganizationID: &orgID}
+			if err := tx.Create(&note).Error; err != nil {
+				return nil, fmt.Errorf("failed to create onboarding note: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Create(&models.OrganizationProvisionedNotebook{OrganizationID: organizationID, ProvisionKey: key, NotebookID: notebook.ID}).Error; err != nil {
+		return nil, fmt.Errorf("failed to record provisioned notebook: %w", err)
+	}
+	return &notebook, nil
+}
+
+// normalizeOnboardingNotebook trims a notebook template's names and checks its size
+func normalizeOnboardingNotebook(notebook *OnboardingNotebook) error {
+	notebook.Name = strings.TrimSpace(notebook.Name)
+	if notebook.Name == "" || len(notebook.Name) > maxOnboardingNameLength {
+		return fmt.Errorf("%w: notebooks need a name of at most %d characters", ErrInvalidOnboardingSettings, maxOnboardingNameLength)
+	}
+	if len(notebook.Chapters) > maxOnboardingChapters {
+		return fmt.Errorf("%w: notebook %q can have at most %d chapters", ErrInvalidOnboardingSettings, notebook.Name, maxOnboardingChapters)
+	}
+	if notebook.Chapters == nil {
+		notebook.Chapters = []OnboardingChapter{}
+	}
+
+	for i := range notebook.Chapters {
+		chapter := &notebook.Chapters[i]
+		chapter.Name = strings.TrimSpace(chapter.Name)
+		chapter.NoteTemplate = strings.TrimSpace(chapter.NoteTemplate)
+		if chapter.Name == "" || len(chapter.Name) > maxOnboardingNameLength {
+			return fmt.Errorf("%w: chapters in %q need a name of at most %d characters", ErrInvalidOnboardingSettings, notebook.Name, maxOnboardingNameLength)
+		}
+		if len(chapter.NoteTemplate) > maxChapterTemplateLength {
+			return fmt.Errorf("%w: the note template of %q can be at most %d characters", ErrInvalidOnboardingSettings, chapter.Name, maxChapterTemplateLength)
+		}
+		if len(chapter.Notes) > maxOnboardingNotes {
+			return fmt.Errorf("%w: chapter %q can have at most %d notes", ErrInvalidOnboardingSettings, chapter.Name, maxOnboardingNotes)
+		}
+		if chapter.Notes == nil {
+			chapter.Notes = []OnboardingNote{}
+		}
+		for j := range chapter.Notes {
+			note := &chapter.Notes[j]
+			note.Name = strings.TrimSpace(note.Name)
+			if note.Name == "" || len(note.Name) > maxOnboardingNameLength {
+				return fmt.Errorf("%w: notes in %q need a name of at most %d characters", ErrInvalidOnboardingSettings, chapter.Name, maxOnboardingNameLength)
+			}
+			if len(note.Content) > maxOnboardingNoteLength {
+				return fmt.Errorf("%w: note %q can be at most %d characters", ErrInvalidOnboardingSettings, note.Name, maxOnboardingNoteLength)
+			}
+		}
+	}
+	return nil
+}
+
+// toOrgOnboardingSettings decodes saved onboarding settings
+func toOrgOnboardingSettings(settings *models.OrganizationOnboardingSettings) (*OrgOnboardingSettings, error) {
+	result := &OrgOnboardingSettings{SharedNotebooks: []OnboardingNotebook{}, UpdatedBy: settings.UpdatedBy}
+	if settings.SharedNotebooks != "" {
+		if err := json.Unmarshal([]byte(settings.SharedNotebooks), &result.SharedNotebooks); err != nil {
+			return nil, fmt.Errorf("failed to decode shared notebooks: %w", err)
+		}
+	}
+	if settings.MemberWorkspace != "" {
+		result.MemberWorkspace = &OnboardingNotebook{}
+		if err := json.Unmarshal([]byte(settings.MemberWorkspace), result.MemberWorkspace); err != nil {
+			return nil, fmt.Errorf("failed to decode member workspace: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// truncateOnboardingName keeps a name with the member's name filled in under the name length limit
+func truncateOnboardingName(name string) string {
+	runes := []rune(strings.TrimSpace(name))
+	if len(runes) > maxOnboardingNameLength {
+		runes = runes[:maxOnboardingNameLength]
+	}
+	return string(runes)
+}
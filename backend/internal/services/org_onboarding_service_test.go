@@ -0,0 +1,96 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestOrgOnboardingService creates an onboarding service on an in-memory database
+func setupTestOrgOnboardingService(t *testing.T) *orgOnboardingServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{},
+		&models.OrganizationOnboardingSettings{}, &models.OrganizationProvisionedNotebook{}), "Failed to migrate test database")
+
+	return &orgOnboardingServiceImpl{db: db}
+}
+
+func TestProvisionMember(t *testing.T) {
+	service := setupTestOrgOnboardingService(t)
+	ctx := context.Background()
+
+	_, err := service.SaveSettings(ctx, "org_1", OrgOnboardingSettings{
+		SharedNotebooks: []OnboardingNotebook{{
+			Name: " Handbook ",
+			Chapters: []OnboardingChapter{{
+				Name:         "Start here",
+				NoteTemplate: "## Owner",
+				Notes:        []OnboardingNote{{Name: "Welcome", Content: "# Welcome to the team"}},
+			}},
+		}},
+		MemberWorkspace: &OnboardingNotebook{Name: "{member}'s workspace", Chapters: []OnboardingChapter{{Name: "1:1s"}}},
+	}, "admin_1")
+	require.NoError(t, err)
+
+	created, err := service.ProvisionMember(ctx, "org_1", "user_1", "Ada Lovelace")
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	assert.Equal(t, "Handbook", created[0].Name)
+	assert.Equal(t, "admin_1", created[0].ClerkUserID, "Shared notebooks belong to the admin who set them up")
+	assert.Equal(t, "Ada Lovelace's workspace", created[1].Name)
+	assert.Equal(t, "user_1", created[1].ClerkUserID)
+
+	var chapter models.Chapter
+	require.NoError(t, service.db.Where("notebook_id = ?", created[0].ID).First(&chapter).Error)
+	assert.Equal(t, "## Owner", chapter.NoteTemplate)
+	require.NotNil(t, chapter.OrganizationID)
+	var note models.Notes
+	require.NoError(t, service.db.Where("chapter_id = ?", chapter.ID).First(&note).Error)
+	assert.True(t, strings.Contains(note.Content, "Welcome to the team"))
+	assert.Equal(t, "org_1", *note.OrganizationID)
+
+	// The next member only gets a workspace of their own, and provisioning twice creates nothing
+	created, err = service.ProvisionMember(ctx, "org_1", "user_2", "")
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	assert.Equal(t, "Member's workspace", created[0].Name)
+	created, err = service.ProvisionMember(ctx, "org_1", "user_2", "")
+	require.NoError(t, err)
+	assert.Empty(t, created)
+
+	var notebooks int64
+	require.NoError(t, service.db.Model(&models.Notebook{}).Where("organization_id = ?", "org_1").Count(&notebooks).Error)
+	assert.EqualValues(t, 3, notebooks)
+
+	created, err = service.ProvisionMember(ctx, "org_2", "user_1", "Ada")
+	require.NoError(t, err)
+	assert.Empty(t, created, "Organizations without settings provision nothing")
+}
+
+func TestSaveOnboardingSettings_Validation(t *testing.T) {
+	service := setupTestOrgOnboardingService(t)
+	ctx := context.Background()
+
+	for name, input := range map[string]OrgOnboardingSettings{
+		"unnamed notebook":   {SharedNotebooks: []OnboardingNotebook{{Name: " "}}},
+		"duplicate names":    {SharedNotebooks: []OnboardingNotebook{{Name: "Docs"}, {Name: "docs"}}},
+		"unnamed chapter":    {SharedNotebooks: []OnboardingNotebook{{Name: "Docs", Chapters: []OnboardingChapter{{}}}}},
+		"unnamed note":       {MemberWorkspace: &OnboardingNotebook{Name: "Mine", Chapters: []OnboardingChapter{{Name: "A", Notes: []OnboardingNote{{}}}}}},
+		"too many notebooks": {SharedNotebooks: make([]OnboardingNotebook, maxOnboardingSharedNotebooks+1)},
+	} {
+		_, err := service.SaveSettings(ctx, "org_1", input, "admin_1")
+		assert.ErrorIs(t, err, ErrInvalidOnboardingSettings, name)
+	}
+
+	settings, err := service.GetSettings(ctx, "org_1")
+	require.NoError(t, err)
+	assert.Empty(t, settings.SharedNotebooks)
+	assert.Nil(t, settings.MemberWorkspace)
+}
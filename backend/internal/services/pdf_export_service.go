@@ -0,0 +1,450 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"backend/pkg/pdf"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPDFPageSize is returned for a page size other than a4, letter or legal
+	ErrInvalidPDFPageSize = errors.New("page size must be a4, letter or legal")
+	// ErrPDFExportNotFound is returned when a notebook has no such PDF export, or it has expired
+	ErrPDFExportNotFound = errors.New("PDF export not found")
+	// ErrPDFExportTooLarge is returned for notebooks with more notes than a PDF can hold
+	ErrPDFExportTooLarge = errors.New("the notebook has too many notes to export as a PDF")
+)
+
+const (
+	// maxSyncPDFNotes is the most notes a notebook PDF is rendered with during the request, larger
+	// notebooks are rendered in the background
+	maxSyncPDFNotes     = 50
+	maxPDFExportNotes   = 2000
+	pdfExportJobTimeout = 10 * time.Minute
+	pdfExportRetention  = 24 * time.Hour
+	// pdfExportStaleAfter is when a pending or running export is assumed lost and a new one may start
+	pdfExportStaleAfter = 30 * time.Minute
+	pdfExportDateFormat = "January 2, 2006"
+
+	defaultPDFPageSize   = "a4"
+	lockedNoteExportText = "This note is locked. Unlock it in the app to read it."
+)
+
+// pdfPageSizes are the page sizes PDFs can be exported in
+var pdfPageSizes = map[string]pdf.Size{
+	"a4":     pdf.A4,
+	"letter": pdf.Letter,
+	"legal":  pdf.Legal,
+}
+
+// PDFExportOptions sets up how notes are laid out in a PDF
+type PDFExportOptions struct {
+	PageSize string // a4, letter or legal
+	Cover    bool   // Start with a cover page
+	TOC      bool   // List the chapters and notes, or a note's headings, after the cover
+}
+
+// NewPDFExportOptions validates the page size, a4 when empty, and returns the options
+func NewPDFExportOptions(pageSize string, cover, toc bool) (PDFExportOptions, error) {
+	pageSize = strings.ToLower(strings.TrimSpace(pageSize))
+	if pageSize == "" {
+		pageSize = defaultPDFPageSize
+	}
+	if _, ok := pdfPageSizes[pageSize]; !ok {
+		return PDFExportOptions{}, ErrInvalidPDFPageSize
+	}
+	return PDFExportOptions{PageSize: pageSize, Cover: cover, TOC: toc}, nil
+}
+
+// PDFExport is an exported PDF and the name of what it holds. Notebook exports rendered in the
+// background have Export set, and PDF only once completed.
+type PDFExport struct {
+	Name   string
+	PDF    []byte
+	Export *models.NotebookPDFExport
+}
+
+// PDFExportService interface defines methods for exporting notes and notebooks as PDFs
+type PDFExportService interface {
+	ExportNote(ctx context.Context, noteID string, options PDFExportOptions) (*PDFExport, error)
+	ExportNotebook(ctx context.Context, notebookID, requestedBy string, options PDFExportOptions) (*PDFExport, error)
+	GetNotebookExport(ctx context.Context, notebookID, exportID string) (*PDFExport, error)
+	Generate(ctx context.Context, exportID string) error
+}
+
+// pdfExportServiceImpl implements the PDFExportService interface
+type pdfExportServiceImpl struct {
+	db       *gorm.DB
+	queue    *JobQueue
+	diagrams DiagramService // Renders diagram code blocks as images, skipped when nil
+	now      func() time.Time
+}
+
+// NewPDFExportService creates a new PDFExportService instance
+func NewPDFExportService() PDFExportService {
+	return &pdfExportServiceImpl{
+		db:       db.DB,
+		queue:    GetJobQueue(),
+		diagrams: NewDiagramService(),
+		now:      time.Now,
+	}
+}
+
+// ExportNote renders a note as a PDF, with its headings as the table of contents
+func (s *pdfExportServiceImpl) ExportNote(ctx context.Context, noteID string, options PDFExportOptions) (*PDFExport, error) {
+	var note models.Notes
+	if err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.Chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	r := newPDFRenderer(pdfPageSizes[options.PageSize], note.Name, s.now())
+	var doc utils.TipTapDoc
+	if !note.Locked {
+		doc = exportNoteDocument(note.Content)
+		r.diagrams = s.diagramImages(ctx, doc)
+	}
+	if options.Cover {
+		r.cover(note.Name, []string{
+			note.Chapter.Notebook.Name + " / " + note.Chapter.Name,
+			"Last updated " + note.UpdatedAt.Format(pdfExportDateFormat),
+			"Exported " + s.now().Format(pdfExportDateFormat),
+		})
+	}
+	if options.TOC {
+		r.reserveTOC(tocHeadings(doc.Content))
+		r.tocHeadings = true
+	}
+	r.startContent()
+
+	r.ensure(0)
+	r.doc.AddBookmark(note.Name, r.page, r.y, -1)
+	r.title(note.Name, 20)
+	if note.Locked {
+		r.muted(lockedNoteExportText)
+	}
+	r.document(doc)
+	r.writeTOC()
+	r.footers(note.Name, options.Cover)
+
+	data, err := r.doc.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return &PDFExport{Name: note.Name, PDF: data}, nil
+}
+
+// ExportNotebook renders a notebook as a PDF, or for notebooks of more than maxSyncPDFNotes notes
+// queues an export to be rendered in the background
+func (s *pdfExportServiceImpl) ExportNotebook(ctx context.Context, notebookID, requestedBy string, options PDFExportOptions) (*PDFExport, error) {
+	notebook, err := s.notebook(ctx, notebookID)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes int64
+	if err := s.db.WithContext(ctx).Model(&models.Notes{}).
+		Joins("JOIN chapters ON notes.chapter_id = chapters.id").
+		Where("chapters.notebook_id = ?", notebookID).
+		Count(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to count notes: %w", err)
+	}
+	if notes > maxPDFExportNotes {
+		return nil, ErrPDFExportTooLarge
+	}
+	if notes <= maxSyncPDFNotes {
+		data, _, err := s.renderNotebook(ctx, notebook, options)
+		if err != nil {
+			return nil, err
+		}
+		return &PDFExport{Name: notebook.Name, PDF: data}, nil
+	}
+
+	export, err := s.queueExport(ctx, notebook.ID, requestedBy, options)
+	if err != nil {
+		return nil, err
+	}
+	return &PDFExport{Name: notebook.Name, Export: export}, nil
+}
+
+// queueExport reuses an export of the notebook with the same options that's still being rendered, or
+// records a new one and renders it in the background
+func (s *pdfExportServiceImpl) queueExport(ctx context.Context, notebookID, requestedBy string, options PDFExportOptions) (*models.NotebookPDFExport, error) {
+	if err := s.db.WithContext(ctx).Where("created_at < ?", s.now().Add(-pdfExportRetention)).
+		Delete(&models.NotebookPDFExport{}).Error; err != nil {
+		log.Warn().Err(err).Msg("Failed to delete expired PDF exports")
+	}
+
+	var active models.NotebookPDFExport
+	err := s.db.WithContext(ctx).
+		Where("notebook_id = ? AND page_size = ? AND cover = ? AND toc = ? AND status IN ? AND updated_at > ?",
+			notebookID, options.PageSize, options.Cover, options.TOC,
+			[]string{models.PDFExportStatusPending, models.PDFExportStatusRunning}, s.now().Add(-pdfExportStaleAfter)).
+		Omit("pdf").
+		First(&active).Error
+	if err == nil {
+		return &active, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check PDF exports: %w", err)
+	}
+
+	export := models.NotebookPDFExport{
+		NotebookID:  notebookID,
+		RequestedBy: requestedBy,
+		Status:      models.PDFExportStatusPending,
+		PageSize:    options.PageSize,
+		Cover:       options.Cover,
+		TOC:         options.TOC,
+	}
+	if err := s.db.WithContext(ctx).Create(&export).Error; err != nil {
+		return nil, fmt.Errorf("failed to create PDF export: %w", err)
+	}
+
+	exportID := export.ID
+	err = s.queue.Enqueue(Job{
+		Name:        "notebook-pdf-export",
+		MaxAttempts: 2,
+		Timeout:     pdfExportJobTimeout,
+		Run: func(ctx context.Context) error {
+			err := s.Generate(ctx, exportID)
+			if errors.Is(err, ErrPDFExportNotFound) || errors.Is(err, ErrNotebookNotFound) || errors.Is(err, ErrNotebookEncrypted) {
+				return nil
+			}
+			return err
+		},
+	})
+	if err != nil {
+		s.fail(exportID, err)
+		return nil, fmt.Errorf("failed to queue PDF export: %w", err)
+	}
+
+	log.Info().Str("notebook_id", notebookID).Str("export_id", exportID).Str("page_size", options.PageSize).Msg("Notebook PDF export queued")
+	return &export, nil
+}
+
+// GetNotebookExport returns a background export of the notebook, with its PDF once completed
+func (s *pdfExportServiceImpl) GetNotebookExport(ctx context.Context, notebookID, exportID string) (*PDFExport, error) {
+	notebook, err := s.notebook(ctx, notebookID)
+	if err != nil {
+		return nil, err
+	}
+
+	var export models.NotebookPDFExport
+	if err := s.db.WithContext(ctx).
+		Where("id = ? AND notebook_id = ? AND created_at > ?", exportID, notebookID, s.now().Add(-pdfExportRetention)).
+		First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPDFExportNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch PDF export: %w", err)
+	}
+
+	result := &PDFExport{Name: notebook.Name, Export: &export}
+	if export.Status == models.PDFExportStatusCompleted {
+		result.PDF = export.PDF
+	}
+	return result, nil
+}
+
+// Generate renders a background export and stores its PDF
+func (s *pdfExportServiceImpl) Generate(ctx context.Context, exportID string) error {
+	var export models.NotebookPDFExport
+	if err := s.db.WithContext(ctx).Omit("pdf").Where("id = ?", exportID).First(&export).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPDFExportNotFound
+		}
+		return fmt.Errorf("failed to fetch PDF export: %w", err)
+	}
+	if export.Status == models.PDFExportStatusCompleted {
+		return nil
+	}
+	if err := s.db.Model(&export).Updates(map[string]interface{}{"status": models.PDFExportStatusRunning, "error": ""}).Error; err != nil {
+		return fmt.Errorf("failed to update PDF export: %w", err)
+	}
+
+	data, pages, err := s.generate(ctx, &export)
+	if err != nil {
+		s.fail(export.ID, err)
+		return err
+	}
+
+	completedAt := s.now()
+	if err := s.db.Model(&export).Updates(map[string]interface{}{
+		"status":       models.PDFExportStatusCompleted,
+		"pdf":          data,
+		"pages":        pages,
+		"size":         len(data),
+		"completed_at": completedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to save PDF export: %w", err)
+	}
+
+	log.Info().Str("export_id", export.ID).Int("pages", pages).Int("bytes", len(data)).Msg("Notebook PDF export completed")
+	return nil
+}
+
+func (s *pdfExportServiceImpl) generate(ctx context.Context, export *models.NotebookPDFExport) ([]byte, int, error) {
+	notebook, err := s.notebook(ctx, export.NotebookID)
+	if err != nil {
+		return nil, 0, err
+	}
+	options, err := NewPDFExportOptions(export.PageSize, export.Cover, export.TOC)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.renderNotebook(ctx, notebook, options)
+}
+
+// fail marks an export as failed with the error
+func (s *pdfExportServiceImpl) fail(exportID string, cause error) {
+	if err := s.db.Model(&models.NotebookPDFExport{}).Where("id = ?", exportID).Updates(map[string]interface{}{
+		"status": models.PDFExportStatusFailed,
+		"error":  cause.Error(),
+	}).Error; err != nil {
+		log.Error().Err(err).Str("export_id", exportID).Msg("Failed to mark PDF export as failed")
+	}
+}
+
+// notebook fetches a notebook whose notes the server can read
+func (s *pdfExportServiceImpl) notebook(ctx context.Context, notebookID string) (models.Notebook, error) {
+	var notebook models.Notebook
+	if err := s.db.WithContext(ctx).Where("id = ?", notebookID).First(&notebook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return notebook, ErrNotebookNotFound
+		}
+		return notebook, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if notebook.Encrypted {
+		return notebook, ErrNotebookEncrypted
+	}
+	return notebook, nil
+}
+
+// renderNotebook renders every chapter of a notebook with each note starting on a new page, and
+// returns the PDF with its number of pages
+func (s *pdfExportServiceImpl) renderNotebook(ctx context.Context, notebook models.Notebook, options PDFExportOptions) ([]byte, int, error) {
+	chapters, notes, err := exportChapters(s.db.WithContext(ctx), notebook.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r := newPDFRenderer(pdfPageSizes[options.PageSize], notebook.Name, s.now())
+	if options.Cover {
+		r.cover(notebook.Name, []string{
+			fmt.Sprintf("%s · %s", pluralize(len(chapters), "chapter"), pluralize(notes, "note")),
+			"Exported " + s.now().Format(pdfExportDateFormat),
+		})
+	}
+	if options.TOC {
+		r.reserveTOC(len(chapters) + notes)
+	}
+	r.startContent()
+
+	for _, chapter := range chapters {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		r.newPage()
+		chapterBookmark := r.addTOCEntry(chapter.Name, 0, -1)
+		r.text([]pdfRun{{text: strings.ToUpper(chapter.Name), font: pdf.HelveticaBold, color: pdfAccentColor}}, 9, pdfLineSpacing)
+		r.space(6)
+		if len(chapter.Files) == 0 {
+			r.muted("This chapter has no notes.")
+		}
+
+		for i, note := range chapter.Files {
+			if i > 0 {
+				r.newPage()
+			}
+			r.addTOCEntry(note.Name, 1, chapterBookmark)
+			r.title(note.Name, 20)
+			if note.Locked {
+				r.muted(lockedNoteExportText)
+				continue
+			}
+			doc := exportNoteDocument(note.Content)
+			r.diagrams = s.diagramImages(ctx, doc)
+			r.document(doc)
+		}
+	}
+	if r.page == nil {
+		r.newPage()
+		r.muted("This notebook has no chapters.")
+	}
+
+	if options.TOC {
+		r.writeTOC()
+	}
+	r.footers(notebook.Name, options.Cover)
+
+	data, err := r.doc.Bytes()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return data, len(r.doc.Pages()), nil
+}
+
+// diagramImages returns the diagrams of a note rendered as images
+func (s *pdfExportServiceImpl) diagramImages(ctx context.Context, doc utils.TipTapDoc) map[string]image.Image {
+	if s.diagrams == nil {
+		return nil
+	}
+	return s.diagrams.Images(ctx, doc)
+}
+
+// exportChapters fetches the chapters of a notebook with their notes, both oldest first, and counts the notes
+func exportChapters(query *gorm.DB, notebookID string) ([]models.Chapter, int, error) {
+	var chapters []models.Chapter
+	if err := query.
+		Preload("Files", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, content, chapter_id, locked, created_at, updated_at").Order("created_at ASC")
+		}).
+		Where("notebook_id = ?", notebookID).
+		Order("created_at ASC").
+		Find(&chapters).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch chapters: %w", err)
+	}
+
+	notes := 0
+	for _, chapter := range chapters {
+		notes += len(chapter.Files)
+	}
+	return chapters, notes, nil
+}
+
+// exportNoteDocument parses a note's TipTap content, converting notes still stored as markdown
+func exportNoteDocument(content string) utils.TipTapDoc {
+	var doc utils.TipTapDoc
+	converted, err := utils.MarkdownToTipTap(content)
+	if err != nil {
+		return doc
+	}
+	if err := json.Unmarshal([]byte(converted), &doc); err != nil {
+		log.Warn().Err(err).Msg("Failed to parse note content for export")
+	}
+	return doc
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"backend/internal/models"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestPDFExportService creates a PDF export service whose queue isn't started, so exports are
+// generated by calling Generate
+func setupTestPDFExportService(t *testing.T) *pdfExportServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NotebookPDFExport{}),
+		"Failed to migrate test database")
+
+	return &pdfExportServiceImpl{
+		db:    db,
+		queue: NewJobQueue(1, 10),
+		now:   func() time.Time { return time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+var pdfStreamPattern = regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`)
+
+// pdfContent returns the uncompressed content streams of a PDF
+func pdfContent(t *testing.T, data []byte) string {
+	var content strings.Builder
+	for _, match := range pdfStreamPattern.FindAllSubmatch(data, -1) {
+		reader, err := zlib.NewReader(bytes.NewReader(match[1]))
+		require.NoError(t, err)
+		inflated, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		content.Write(inflated)
+	}
+	return content.String()
+}
+
+func TestExportNotePDF(t *testing.T) {
+	service := setupTestPDFExportService(t)
+	ctx := context.Background()
+
+	notebook := models.Notebook{Name: "Handbook", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Engineering", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&chapter).Error)
+	content := `{"type":"doc","content":[
+		{"type":"heading","attrs":{"level":1},"content":[{"type":"text","text":"Setup"}]},
+		{"type":"paragraph","content":[{"type":"text","text":"Install the ` + strings.Repeat("very ", 1000) + `tools"},{"type":"text","marks":[{"type":"bold"}],"text":" (carefully)"}]},
+		{"type":"bulletList","content":[{"type":"listItem","content":[{"type":"paragraph","content":[{"type":"text","text":"Go"}]}]}]},
+		{"type":"codeBlock","content":[{"type":"text","text":"make build\nmake test"}]},
+		{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Deploys"}]},
+		{"type":"heading","attrs":{"level":5},"content":[{"type":"text","text":"Too deep for the contents"}]}
+	]}`
+	note := models.Notes{Name: "Onboarding", Content: content, ChapterID: chapter.ID}
+	require.NoError(t, service.db.Create(&note).Error)
+
+	options, err := NewPDFExportOptions("", true, true)
+	require.NoError(t, err)
+	assert.Equal(t, "a4", options.PageSize)
+
+	export, err := service.ExportNote(ctx, note.ID, options)
+	require.NoError(t, err)
+	assert.Equal(t, "Onboarding", export.Name)
+	assert.True(t, bytes.HasPrefix(export.PDF, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(export.PDF, []byte("%%EOF\n")))
+	assert.Contains(t, string(export.PDF), "/MediaBox [0 0 595.28 841.89]")
+	assert.Equal(t, 2, strings.Count(string(export.PDF), "/Subtype /Link"), "Both listed headings link to their page")
+
+	text := pdfContent(t, export.PDF)
+	assert.Contains(t, text, "(Contents) Tj")
+	assert.Contains(t, text, "(Setup) Tj")
+	assert.Contains(t, text, "(make build) Tj")
+	assert.Contains(t, text, "\\(carefully\\)", "Parentheses are escaped")
+	assert.Contains(t, text, "(Page 3 of 4) Tj", "The cover and contents come before the note, whose long paragraph takes two pages")
+
+	// Locked notes keep their content out of the PDF
+	require.NoError(t, service.db.Model(&note).Update("locked", true).Error)
+	export, err = service.ExportNote(ctx, note.ID, PDFExportOptions{PageSize: "letter"})
+	require.NoError(t, err)
+	text = pdfContent(t, export.PDF)
+	assert.NotContains(t, text, "(Setup) Tj")
+	assert.Contains(t, text, "This note is locked")
+
+	require.NoError(t, service.db.Model(&notebook).Update("encrypted", true).Error)
+	_, err = service.ExportNote(ctx, note.ID, options)
+	assert.ErrorIs(t, err, ErrNotebookEncrypted)
+
+	_, err = NewPDFExportOptions("a3", true, true)
+	assert.ErrorIs(t, err, ErrInvalidPDFPageSize)
+}
+
+func TestExportNotebookPDF_LargeNotebooksRenderInBackground(t *testing.T) {
+	service := setupTestPDFExportService(t)
+	ctx := context.Background()
+
+	notebook := models.Notebook{Name: "Meetings", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "2026", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&chapter).Error)
+	createNote := func(i int) {
+		require.NoError(t, service.db.Create(&models.Notes{Name: fmt.Sprintf("Standup %d", i), Content: "Notes from standup", ChapterID: chapter.ID}).Error)
+	}
+	for i := 0; i < maxSyncPDFNotes; i++ {
+		createNote(i)
+	}
+
+	options := PDFExportOptions{PageSize: "letter", Cover: true, TOC: true}
+	export, err := service.ExportNotebook(ctx, notebook.ID, "user_1", options)
+	require.NoError(t, err)
+	require.NotNil(t, export.PDF, "Small notebooks are rendered right away")
+	assert.Nil(t, export.Export)
+
+	createNote(maxSyncPDFNotes)
+	export, err = service.ExportNotebook(ctx, notebook.ID, "user_1", options)
+	require.NoError(t, err)
+	assert.Nil(t, export.PDF)
+	require.NotNil(t, export.Export)
+	assert.Equal(t, models.PDFExportStatusPending, export.Export.Status)
+	assert.Equal(t, 1, service.queue.Len())
+
+	again, err := service.ExportNotebook(ctx, notebook.ID, "user_2", options)
+	require.NoError(t, err)
+	assert.Equal(t, export.Export.ID, again.Export.ID, "An export with the same options in progress is reused")
+
+	pending, err := service.GetNotebookExport(ctx, notebook.ID, export.Export.ID)
+	require.NoError(t, err)
+	assert.Nil(t, pending.PDF)
+
+	require.NoError(t, service.Generate(ctx, export.Export.ID))
+	completed, err := service.GetNotebookExport(ctx, notebook.ID, export.Export.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PDFExportStatusCompleted, completed.Export.Status)
+	assert.True(t, bytes.HasPrefix(completed.PDF, []byte("%PDF-1.4")))
+	// Cover, two pages of contents for the chapter and its 51 notes, and a page per note
+	assert.Equal(t, 1+2+maxSyncPDFNotes+1, completed.Export.Pages)
+	assert.Contains(t, pdfContent(t, completed.PDF), "(Standup 50) Tj")
+
+	_, err = service.GetNotebookExport(ctx, "other-notebook", export.Export.ID)
+	assert.ErrorIs(t, err, ErrNotebookNotFound)
+	service.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	_, err = service.GetNotebookExport(ctx, notebook.ID, export.Export.ID)
+	assert.ErrorIs(t, err, ErrPDFExportNotFound, "Exports expire after a day")
+}
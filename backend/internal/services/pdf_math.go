@@ -0,0 +1,393 @@
+package services
+
+import (
+	"backend/internal/utils"
+	"backend/pkg/pdf"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	pdfMathScriptScale = 0.7  // Size of sub- and superscripts, and of root indices, relative to their base
+	pdfMathFracScale   = 0.85 // Size of numerators and denominators relative to the fraction
+	pdfMathMinSize     = 5    // Nested scripts and fractions don't get smaller than this
+	pdfMathAxis        = 0.25 // Height of fraction bars and of the middle of matrices above the baseline, in em
+	pdfMathAscent      = 0.72 // Height of capitals in Helvetica, in em
+	pdfMathDescent     = 0.21
+	pdfMathRuleWidth   = 0.05
+)
+
+// pdfMathTightOperators are the operators set without the space around relations and binary operators
+var pdfMathTightOperators = map[string]bool{
+	"(": true, ")": true, "[": true, "]": true, "{": true, "}": true, "|": true, "‖": true, "⟨": true, "⟩": true,
+	"⌊": true, "⌋": true, "⌈": true, "⌉": true, ".": true, "!": true, "′": true, "/": true, "…": true, "⋯": true,
+}
+
+// pdfMathAccents are the glyphs accents are drawn with and how far above its baseline each glyph starts, in em
+var pdfMathAccents = map[string]struct {
+	text   string
+	offset float64
+}{
+	"^": {"^", 0.32},
+	"~": {"~", 0.26},
+	"˙": {".", 0},
+	"¨": {"..", 0},
+	"→": {"→", 0.1},
+}
+
+// pdfMathGlyph is text of a laid out formula. Positions are relative to the formula's origin on its
+// baseline, with y growing downwards like on the page.
+type pdfMathGlyph struct {
+	x, y float64
+	text string
+	font pdf.Font
+	size float64
+}
+
+// pdfMathRule is a line of a laid out formula, such as a fraction bar or a radical sign
+type pdfMathRule struct {
+	x1, y1, x2, y2, width float64
+}
+
+// pdfMathBox is a formula laid out for a PDF. Ascent and descent are its extent above and below the baseline.
+type pdfMathBox struct {
+	width, ascent, descent float64
+	glyphs                 []pdfMathGlyph
+	rules                  []pdfMathRule
+}
+
+// place copies a box into this one with its origin at x, y
+func (b *pdfMathBox) place(child pdfMathBox, x, y float64) {
+	for _, glyph := range child.glyphs {
+		glyph.x += x
+		glyph.y += y
+		b.glyphs = append(b.glyphs, glyph)
+	}
+	for _, rule := range child.rules {
+		rule.x1, rule.x2 = rule.x1+x, rule.x2+x
+		rule.y1, rule.y2 = rule.y1+y, rule.y2+y
+		b.rules = append(b.rules, rule)
+	}
+	b.ascent = math.Max(b.ascent, child.ascent-y)
+	b.descent = math.Max(b.descent, child.descent+y)
+}
+
+// draw draws the formula on a page with its origin at x on the baseline
+func (b *pdfMathBox) draw(page *pdf.Page, x, baseline float64, color pdf.Color) {
+	for _, glyph := range b.glyphs {
+		page.Text(x+glyph.x, baseline+glyph.y, glyph.font, glyph.size, color, glyph.text)
+	}
+	for _, rule := range b.rules {
+		page.Line(x+rule.x1, baseline+rule.y1, x+rule.x2, baseline+rule.y2, rule.width, color)
+	}
+}
+
+// layoutPDFMath lays out a formula at a font size. Formulas are drawn with Helvetica, taking the Greek
+// letters and symbols it doesn't have from the Symbol font.
+func layoutPDFMath(node utils.MathNode, size float64) pdfMathBox {
+	switch node.Kind {
+	case utils.MathRow:
+		return layoutPDFMathRow(node.Children, size)
+
+	case utils.MathIdentifier:
+		font := pdf.Helvetica
+		switch {
+		case node.Variant == "bold":
+			font = pdf.HelveticaBold
+		case node.Variant == "bold-italic":
+			font = pdf.HelveticaBoldOblique
+		case node.Variant == "" && utf8.RuneCountInString(node.Text) == 1, node.Variant == "italic":
+			// Single-letter variables are italic, function names upright
+			font = pdf.HelveticaOblique
+		}
+		return pdfMathText(node.Text, font, size)
+
+	case utils.MathNumber:
+		font := pdf.Helvetica
+		if strings.HasPrefix(node.Variant, "bold") {
+			font = pdf.HelveticaBold
+		}
+		return pdfMathText(node.Text, font, size)
+
+	case utils.MathOperator:
+		if !node.Large {
+			return pdfMathText(node.Text, pdf.Helvetica, size)
+		}
+		// Big operators are drawn larger, centered on the axis
+		large := pdfMathText(node.Text, pdf.Helvetica, size*1.5)
+		var box pdfMathBox
+		box.width = large.width
+		box.place(large, 0, (large.ascent-large.descent)/2-pdfMathAxis*size)
+		return box
+
+	case utils.MathText, utils.MathError:
+		return pdfMathText(node.Text, pdf.Helvetica, size)
+
+	case utils.MathSpace:
+		return pdfMathBox{width: math.Max(node.Width*size, 0)}
+
+	case utils.MathFraction:
+		return layoutPDFFraction(node, size)
+
+	case utils.MathRoot:
+		return layoutPDFRoot(node, size)
+
+	case utils.MathScripts:
+		return layoutPDFScripts(node, size)
+
+	case utils.MathFenced:
+		return layoutPDFFenced(layoutPDFMathRow(node.Children, size), node.Open, node.Close, size)
+
+	case utils.MathAccent:
+		return layoutPDFAccent(node, size)
+
+	case utils.MathMatrix:
+		return layoutPDFFenced(layoutPDFMatrix(node, size), node.Open, node.Close, size)
+	}
+	return pdfMathBox{}
+}
+
+// layoutPDFMathRow sets nodes side by side, with space around relations and binary operators
+func layoutPDFMathRow(nodes []utils.MathNode, size float64) pdfMathBox {
+	var row pdfMathBox
+	for i, node := range nodes {
+		child := layoutPDFMath(node, size)
+		before, after := 0.0, 0.0
+		if node.Kind == utils.MathOperator && !node.Large && !pdfMathTightOperators[node.Text] {
+			after = 0.2 * size
+			// Punctuation only has space after it, and signs starting a row none before
+			if i > 0 && node.Text != "," && node.Text != ";" {
+				before = after
+			}
+		}
+		row.place(child, row.width+before, 0)
+		row.width += before + child.width + after
+	}
+	return row
+}
+
+// layoutPDFFraction sets the numerator over the denominator, around a bar on the axis
+func layoutPDFFraction(node utils.MathNode, size float64) pdfMathBox {
+	inner := pdfMathScaled(size, pdfMathFracScale)
+	num := layoutPDFMath(node.Children[0], inner)
+	den := layoutPDFMath(node.Children[1], inner)
+	gap, axis := 0.15*size, pdfMathAxis*size
+
+	fraction := pdfMathBox{width: math.Max(num.width, den.width) + 0.2*size}
+	fraction.place(num, (fraction.width-num.width)/2, -(axis + gap + num.descent))
+	fraction.place(den, (fraction.width-den.width)/2, -axis+gap+den.ascent)
+	if !node.NoBar {
+		fraction.rules = append(fraction.rules, pdfMathRule{
+			x1: 0.05 * size, y1: -axis, x2: fraction.width - 0.05*size, y2: -axis, width: pdfMathRuleWidth * size,
+		})
+	}
+	return fraction
+}
+
+// layoutPDFRoot draws a radical sign over the radicand, with the index of nth roots in its crook
+func layoutPDFRoot(node utils.MathNode, size float64) pdfMathBox {
+	radicand := layoutPDFMath(node.Children[0], size)
+	top := math.Max(radicand.ascent, pdfMathAscent*size) + 0.15*size
+	bottom := math.Max(radicand.descent, pdfMathDescent*size)
+
+	var root pdfMathBox
+	offset := 0.0
+	if len(node.Children) > 1 {
+		index := layoutPDFMath(node.Children[1], pdfMathScaled(size, pdfMathScriptScale))
+		root.place(index, 0, -0.45*size)
+		offset = math.Max(index.width-0.2*size, 0)
+	}
+
+	width := pdfMathRuleWidth * size
+	sign := []struct{ x, y float64 }{
+		{offset, -0.4 * size},
+		{offset + 0.1*size, -0.45 * size},
+		{offset + 0.25*size, bottom},
+		{offset + 0.5*size, -top},
+		{offset + 0.6*size + radicand.width + 0.1*size, -top},
+	}
+	for i := 1; i < len(sign); i++ {
+		root.rules = append(root.rules, pdfMathRule{x1: sign[i-1].x, y1: sign[i-1].y, x2: sign[i].x, y2: sign[i].y, width: width})
+	}
+	root.place(radicand, offset+0.6*size, 0)
+	root.ascent = math.Max(root.ascent, top+width)
+	root.descent = math.Max(root.descent, bottom)
+	root.width = offset + 0.6*size + radicand.width + 0.15*size
+	return root
+}
+
+// layoutPDFScripts sets sub- and superscripts to the right of their base
+func layoutPDFScripts(node utils.MathNode, size float64) pdfMathBox {
+	base := layoutPDFMath(node.Children[0], size)
+	scriptSize := pdfMathScaled(size, pdfMathScriptScale)
+
+	scripts := pdfMathBox{width: base.width}
+	scripts.place(base, 0, 0)
+	x := base.width + 0.05*size
+	scriptWidth := 0.0
+	if node.Sup != nil {
+		sup := layoutPDFMath(*node.Sup, scriptSize)
+		shift := math.Max(0.4*size, base.ascent-0.5*sup.ascent)
+		scripts.place(sup, x, -shift)
+		scriptWidth = sup.width
+	}
+	if node.Sub != nil {
+		sub := layoutPDFMath(*node.Sub, scriptSize)
+		shift := math.Max(0.2*size, base.descent)
+		scripts.place(sub, x, shift)
+		scriptWidth = math.Max(scriptWidth, sub.width)
+	}
+	scripts.width = x + scriptWidth
+	return scripts
+}
+
+// layoutPDFFenced sets delimiters around a box, scaled to its height when it's taller than a line
+func layoutPDFFenced(body pdfMathBox, open, close string, size float64) pdfMathBox {
+	if open == "" && close == "" {
+		return body
+	}
+	height := body.ascent + body.descent
+	fontSize := size
+	if height > 1.1*size {
+		// Helvetica's parentheses are about 0.94 em high, from the descender to above the capitals
+		fontSize = height / 0.94
+	}
+	baseline := body.descent - pdfMathDescent*fontSize
+	if fontSize == size {
+		baseline = 0
+	}
+
+	var fenced pdfMathBox
+	if open != "" {
+		delimiter := pdfMathText(pdfMathDelimiter(open), pdf.Helvetica, fontSize)
+		fenced.place(delimiter, 0, baseline)
+		fenced.width = delimiter.width
+	}
+	fenced.place(body, fenced.width, 0)
+	fenced.width += body.width
+	if close != "" {
+		delimiter := pdfMathText(pdfMathDelimiter(close), pdf.Helvetica, fontSize)
+		fenced.place(delimiter, fenced.width, baseline)
+		fenced.width += delimiter.width
+	}
+	return fenced
+}
+
+// pdfMathDelimiter returns the text a delimiter is drawn with, as neither font has a double bar
+func pdfMathDelimiter(delimiter string) string {
+	if delimiter == "‖" {
+		return "||"
+	}
+	return delimiter
+}
+
+// layoutPDFAccent sets an accent centered over its base
+func layoutPDFAccent(node utils.MathNode, size float64) pdfMathBox {
+	base := layoutPDFMath(node.Children[0], size)
+	accented := pdfMathBox{width: base.width}
+	accented.place(base, 0, 0)
+	top := math.Max(base.ascent, 0.52*size) + 0.08*size
+
+	accent, ok := pdfMathAccents[node.Text]
+	if !ok {
+		// Bars are drawn as a line across the base
+		width := pdfMathRuleWidth * size
+		accented.rules = append(accented.rules, pdfMathRule{x1: 0, y1: -top, x2: base.width, y2: -top, width: width})
+		accented.ascent = math.Max(accented.ascent, top+width)
+		return accented
+	}
+	glyph := pdfMathText(accent.text, pdf.Helvetica, size*0.8)
+	accented.place(glyph, (base.width-glyph.width)/2, -(top - accent.offset*size*0.8))
+	accented.width = math.Max(base.width, glyph.width)
+	return accented
+}
+
+// layoutPDFMatrix sets the cells of a matrix in columns, centered on the axis
+func layoutPDFMatrix(node utils.MathNode, size float64) pdfMathBox {
+	cells := make([][]pdfMathBox, len(node.Rows))
+	var columns []float64
+	ascents := make([]float64, len(node.Rows))
+	descents := make([]float64, len(node.Rows))
+	for i, row := range node.Rows {
+		ascents[i], descents[i] = pdfMathAscent*size, pdfMathDescent*size
+		for j, cell := range row {
+			box := layoutPDFMath(cell, size)
+			cells[i] = append(cells[i], box)
+			if j == len(columns) {
+				columns = append(columns, 0)
+			}
+			columns[j] = math.Max(columns[j], box.width)
+			ascents[i] = math.Max(ascents[i], box.ascent)
+			descents[i] = math.Max(descents[i], box.descent)
+		}
+	}
+
+	rowGap, columnGap := 0.3*size, 0.8*size
+	height := rowGap * float64(max(len(node.Rows)-1, 0))
+	for i := range node.Rows {
+		height += ascents[i] + descents[i]
+	}
+	var matrix pdfMathBox
+	y := -pdfMathAxis*size - height/2
+	for i, row := range cells {
+		y += ascents[i]
+		x := 0.15 * size
+		for j, cell := range row {
+			offset := (columns[j] - cell.width) / 2
+			if node.Left {
+				offset = 0
+			}
+			matrix.place(cell, x+offset, y)
+			x += columns[j] + columnGap
+		}
+		y += descents[i] + rowGap
+	}
+	for _, width := range columns {
+		matrix.width += width + columnGap
+	}
+	matrix.width += 0.3*size - columnGap
+	return matrix
+}
+
+// pdfMathText lays out text in a font, drawing the characters it doesn't have with the Symbol font
+func pdfMathText(text string, font pdf.Font, size float64) pdfMathBox {
+	box := pdfMathBox{ascent: pdfMathAscent * size, descent: pdfMathDescent * size}
+	var current strings.Builder
+	currentFont := font
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		box.glyphs = append(box.glyphs, pdfMathGlyph{x: box.width, text: current.String(), font: currentFont, size: size})
+		box.width += pdf.TextWidth(currentFont, size, current.String())
+		current.Reset()
+	}
+	lastWidth := 0.0
+	for _, r := range text {
+		if r == '\u0338' {
+			// Negations are drawn as a slash over the character they negate
+			flush()
+			slash := pdf.TextWidth(pdf.Helvetica, size, "/")
+			box.glyphs = append(box.glyphs, pdfMathGlyph{x: box.width - (lastWidth+slash)/2, text: "/", font: pdf.Helvetica, size: size})
+			continue
+		}
+		glyphFont := font
+		if !pdf.Encodable(font, r) && pdf.Encodable(pdf.Symbol, r) {
+			glyphFont = pdf.Symbol
+		}
+		if glyphFont != currentFont {
+			flush()
+			currentFont = glyphFont
+		}
+		current.WriteRune(r)
+		lastWidth = pdf.TextWidth(glyphFont, size, string(r))
+	}
+	flush()
+	return box
+}
+
+// pdfMathScaled returns the size of nested parts of a formula
+func pdfMathScaled(size, scale float64) float64 {
+	return math.Max(size*scale, pdfMathMinSize)
+}
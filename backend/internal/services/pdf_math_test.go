@@ -0,0 +1,88 @@
+package services
+
+import (
+	"backend/internal/utils"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mathContent is a note with formulas in a heading, inline in text, on their own and in a table
+const mathContent = `{"type":"doc","content":[
+	{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Energy "},{"type":"math","attrs":{"latex":"E = mc^2"}}]},
+	{"type":"paragraph","content":[{"type":"text","text":"The ratio is "},{"type":"math","attrs":{"latex":"\\frac{\\alpha}{\\sqrt{x + 1}}"}},{"type":"text","text":" in general."}]},
+	{"type":"paragraph","content":[{"type":"math","attrs":{"latex":"\\begin{pmatrix} a & b \\\\ c & d \\end{pmatrix} \\neq \\mathbb{R}"}}]},
+	{"type":"table","content":[
+		{"type":"tableRow","content":[{"type":"tableCell","content":[{"type":"paragraph","content":[{"type":"math","attrs":{"latex":"\\sum_{i=1}^n i"}}]}]}]}
+	]}
+]}`
+
+func TestExportNotePDF_Math(t *testing.T) {
+	service := setupTestPDFExportService(t)
+	note := createLifecycleNote(t, service.db, nil)
+	require.NoError(t, service.db.Model(&note).Update("content", mathContent).Error)
+
+	export, err := service.ExportNote(context.Background(), note.ID, PDFExportOptions{PageSize: "a4"})
+	require.NoError(t, err)
+	assert.Contains(t, string(export.PDF), "/BaseFont /Symbol >>", "The Symbol font keeps its own encoding")
+
+	text := pdfContent(t, export.PDF)
+	assert.Contains(t, text, "(The ratio is ) Tj")
+	assert.Contains(t, text, "/F3 10.5 Tf", "Variables are italic")
+	assert.Contains(t, text, "/F6 8.92 Tf", "Greek letters of the numerator are drawn smaller with the Symbol font")
+	assert.Contains(t, text, "(a) Tj", "α is at the code of a in the Symbol font")
+	assert.Contains(t, text, "(\xb9) Tj", "≠ is drawn with the Symbol font")
+	assert.Contains(t, text, "(\xe5) Tj", "∑ is drawn with the Symbol font")
+	assert.Contains(t, text, "(2) Tj", "Exponents are drawn")
+	assert.GreaterOrEqual(t, strings.Count(text, " l S"), 6, "Fraction bars and radical signs are drawn as lines")
+	assert.NotContains(t, text, "\\\\frac", "Formulas aren't printed as LaTeX")
+}
+
+func TestExportNoteDocx_Math(t *testing.T) {
+	service := setupTestDocxExportService(t)
+	note := createLifecycleNote(t, service.db, nil)
+	require.NoError(t, service.db.Model(&note).Update("content", mathContent).Error)
+
+	export, err := service.ExportNote(context.Background(), note.ID)
+	require.NoError(t, err)
+
+	document := docxPart(t, export.Data, "word/document.xml")
+	assert.Contains(t, document, `xmlns:m="http://schemas.openxmlformats.org/officeDocument/2006/math"`)
+	assert.Contains(t, document, `<m:oMath><m:r><m:t xml:space="preserve">E</m:t></m:r>`)
+	assert.Contains(t, document, `<m:sSup><m:e><m:r><m:t xml:space="preserve">c</m:t></m:r></m:e><m:sup>`)
+	assert.Contains(t, document, `<m:f><m:num><m:r><m:t xml:space="preserve">α</m:t></m:r></m:num><m:den><m:rad>`)
+	assert.Contains(t, document, `<m:d><m:dPr><m:begChr m:val="("/><m:endChr m:val=")"/></m:dPr><m:e><m:m><m:mr>`)
+	assert.Contains(t, document, `<m:scr m:val="double-struck"/>`)
+}
+
+func TestRenderTipTapMath(t *testing.T) {
+	rendered := utils.RenderTipTapMath(mathContent)
+	var doc utils.TipTapDoc
+	require.NoError(t, json.Unmarshal([]byte(rendered), &doc))
+
+	formula := doc.Content[1].Content[1]
+	assert.Equal(t, `\frac{\alpha}{\sqrt{x + 1}}`, formula.Attrs["latex"], "The LaTeX is kept for editing")
+	assert.Equal(t, `<math xmlns="http://www.w3.org/1998/Math/MathML"><semantics><mrow><mfrac><mrow><mi>α</mi></mrow>`+
+		`<mrow><msqrt><mrow><mi>x</mi><mo>+</mo><mn>1</mn></mrow></msqrt></mrow></mfrac></mrow>`+
+		`<annotation encoding="application/x-tex">\frac{\alpha}{\sqrt{x + 1}}</annotation></semantics></math>`, formula.Attrs["mathml"])
+
+	matrix := doc.Content[2].Content[0].Attrs["mathml"]
+	assert.Contains(t, matrix, `<mo fence="true" stretchy="true">(</mo><mtable><mtr><mtd><mrow><mi>a</mi></mrow></mtd>`)
+	assert.Contains(t, matrix, `<mo>≠</mo><mrow><mi mathvariant="double-struck">R</mi></mrow>`)
+	assert.Contains(t, doc.Content[3].Content[0].Content[0].Content[0].Content[0].Attrs["mathml"],
+		`<msubsup><mo largeop="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></msubsup>`)
+
+	assert.Contains(t, utils.LaTeXToMathML(`x \unknown <y`), `<merror><mtext>\unknown</mtext></merror><mo>&lt;</mo>`)
+	assert.Equal(t, `{"type":"doc","content":[]}`, utils.RenderTipTapMath(`{"type":"doc","content":[]}`))
+	assert.Equal(t, "plain math", utils.RenderTipTapMath("plain math"), "Content that isn't a document is unchanged")
+
+	html := utils.TipTapToHTML(doc)
+	assert.Contains(t, html, `<math xmlns="http://www.w3.org/1998/Math/MathML">`, "Emails show formulas as MathML")
+	markdown, err := utils.TipTapToMarkdown(mathContent)
+	require.NoError(t, err)
+	assert.Contains(t, markdown, "The ratio is $\\frac{\\alpha}{\\sqrt{x + 1}}$ in general.")
+}
@@ -0,0 +1,633 @@
+package services
+
+import (
+	"backend/internal/utils"
+	"backend/pkg/pdf"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	pdfMargin        = 56
+	pdfFooterOffset  = 28 // Distance of the footer baseline from the bottom edge
+	pdfBodySize      = 10.5
+	pdfCodeSize      = 9
+	pdfLineSpacing   = 1.45
+	pdfListIndent    = 18
+	pdfQuoteIndent   = 14
+	pdfBlockSpacing  = 6
+	pdfTOCEntrySize  = 10.5
+	pdfTOCLineHeight = 18
+	pdfTOCTitleSpace = 60 // Height of the "Contents" title on the first TOC page
+	maxTOCHeadingLvl = 3  // Headings down to this level are listed in a note's table of contents
+)
+
+var (
+	pdfMutedColor  = pdf.Gray(0.45)
+	pdfRuleColor   = pdf.Gray(0.8)
+	pdfCodeBgColor = pdf.Gray(0.95)
+	pdfLinkColor   = pdf.Color{R: 0.1, G: 0.35, B: 0.75}
+	pdfAccentColor = pdf.Color{R: 0.25, G: 0.45, B: 0.85}
+)
+
+// pdfHeadingSizes are the font sizes of heading levels 1 to 4, deeper levels use the last
+var pdfHeadingSizes = []float64{18, 15, 13, 11.5}
+
+// pdfRun is a piece of inline text in one style, or a formula
+type pdfRun struct {
+	text  string
+	font  pdf.Font
+	color pdf.Color
+	math  *utils.MathNode
+}
+
+// pdfTOCEntry is a line of the table of contents and where it jumps to once rendered
+type pdfTOCEntry struct {
+	title string
+	level int
+	page  *pdf.Page
+	y     float64
+}
+
+// pdfRenderer lays out TipTap documents on the pages of a PDF, starting new pages as they fill up
+type pdfRenderer struct {
+	doc    *pdf.Document
+	size   pdf.Size
+	page   *pdf.Page
+	y      float64 // Top of the next line on the page
+	indent float64 // Left indent of nested lists and quotes
+
+	toc         []pdfTOCEntry
+	tocHeadings bool // Whether headings are added to the table of contents
+	tocPages    []*pdf.Page
+
+	diagrams map[string]image.Image // Rendered diagram code blocks by diagramHash
+}
+
+func newPDFRenderer(size pdf.Size, title string, created time.Time) *pdfRenderer {
+	doc := pdf.New(size)
+	doc.SetInfo(title, "")
+	doc.SetCreationDate(created)
+	return &pdfRenderer{doc: doc, size: size}
+}
+
+func (r *pdfRenderer) left() float64 {
+	return pdfMargin + r.indent
+}
+
+func (r *pdfRenderer) width() float64 {
+	return r.size.Width - 2*pdfMargin - r.indent
+}
+
+func (r *pdfRenderer) bottom() float64 {
+	return r.size.Height - pdfMargin
+}
+
+// newPage starts a new page with the cursor at the top margin
+func (r *pdfRenderer) newPage() {
+	r.page = r.doc.AddPage()
+	r.y = pdfMargin
+}
+
+// ensure starts a new page unless height fits below the cursor
+func (r *pdfRenderer) ensure(height float64) {
+	if r.page == nil || r.y+height > r.bottom() {
+		r.newPage()
+	}
+}
+
+// space moves the cursor down, dropping space at the top of a page
+func (r *pdfRenderer) space(height float64) {
+	if r.y > pdfMargin {
+		r.y = math.Min(r.y+height, r.bottom())
+	}
+}
+
+// image draws an image at its size on screen, scaled down to fit the width and height of a page
+func (r *pdfRenderer) image(img image.Image) {
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return
+	}
+	width := math.Min(float64(bounds.Dx())*0.75, r.width()) // Pixels are 0.75pt at 96 DPI
+	height := width * float64(bounds.Dy()) / float64(bounds.Dx())
+	if maxHeight := r.bottom() - pdfMargin; height > maxHeight {
+		width *= maxHeight / height
+		height = maxHeight
+	}
+	r.ensure(height)
+	r.page.Image(r.left(), r.y, width, height, img)
+	r.y += height
+}
+
+// cover draws the cover page: the title, then lines of details under it
+func (r *pdfRenderer) cover(title string, details []string) {
+	r.newPage()
+	r.y = r.size.Height / 3
+	r.text([]pdfRun{{text: title, font: pdf.HelveticaBold}}, 28, 1.25)
+	r.space(10)
+	r.page.Line(pdfMargin, r.y, r.size.Width-pdfMargin, r.y, 1.5, pdfAccentColor)
+	r.space(18)
+	for _, detail := range details {
+		r.text([]pdfRun{{text: detail, font: pdf.Helvetica, color: pdfMutedColor}}, 12, pdfLineSpacing)
+	}
+}
+
+// reserveTOC adds the blank pages the table of contents will take once the pages of its entries are known
+func (r *pdfRenderer) reserveTOC(entries int) {
+	if entries == 0 {
+		return
+	}
+	usable := r.size.Height - 2*pdfMargin
+	pages := 1
+	if remaining := entries - int((usable-pdfTOCTitleSpace)/pdfTOCLineHeight); remaining > 0 {
+		pages += int(math.Ceil(float64(remaining) / math.Floor(usable/pdfTOCLineHeight)))
+	}
+	for i := 0; i < pages; i++ {
+		r.tocPages = append(r.tocPages, r.doc.AddPage())
+	}
+}
+
+// startContent makes the content start on a new page after the cover and table of contents
+func (r *pdfRenderer) startContent() {
+	r.page = nil
+}
+
+// addTOCEntry records a table of contents entry at the cursor and bookmarks it
+func (r *pdfRenderer) addTOCEntry(title string, level int, parentBookmark int) int {
+	r.toc = append(r.toc, pdfTOCEntry{title: title, level: level, page: r.page, y: r.y})
+	return r.doc.AddBookmark(title, r.page, r.y, parentBookmark)
+}
+
+// writeTOC fills the reserved pages with the table of contents, each line linking to its entry
+func (r *pdfRenderer) writeTOC() {
+	if len(r.tocPages) == 0 {
+		return
+	}
+	pageIndex := 0
+	page := r.tocPages[0]
+	page.Text(pdfMargin, pdfMargin+22, pdf.HelveticaBold, 22, pdf.Black, "Contents")
+	y := float64(pdfMargin + pdfTOCTitleSpace)
+
+	right := r.size.Width - pdfMargin
+	for _, entry := range r.toc {
+		if y+pdfTOCLineHeight > r.bottom() {
+			pageIndex++
+			if pageIndex == len(r.tocPages) {
+				break
+			}
+			page = r.tocPages[pageIndex]
+			y = pdfMargin
+		}
+		font := pdf.Helvetica
+		if entry.level == 0 {
+			font = pdf.HelveticaBold
+		}
+		x := pdfMargin + float64(entry.level)*pdfListIndent
+		number := fmt.Sprint(entry.page.Number())
+		numberWidth := pdf.TextWidth(font, pdfTOCEntrySize, number)
+		title := truncatePDFText(font, pdfTOCEntrySize, entry.title, right-numberWidth-x-24)
+		baseline := y + pdfTOCEntrySize
+
+		page.Text(x, baseline, font, pdfTOCEntrySize, pdf.Black, title)
+		leaderStart := x + pdf.TextWidth(font, pdfTOCEntrySize, title) + 6
+		if leaderEnd := right - numberWidth - 6; leaderEnd > leaderStart {
+			page.Line(leaderStart, baseline, leaderEnd, baseline, 0.5, pdfRuleColor)
+		}
+		page.Text(right-numberWidth, baseline, font, pdfTOCEntrySize, pdf.Black, number)
+		page.Link(x, y, right-x, pdfTOCLineHeight, entry.page, entry.y)
+		y += pdfTOCLineHeight
+	}
+}
+
+// footers numbers every page after the cover and shows the document title next to the number
+func (r *pdfRenderer) footers(title string, skipFirst bool) {
+	pages := r.doc.Pages()
+	baseline := r.size.Height - pdfFooterOffset
+	for i, page := range pages {
+		if i == 0 && skipFirst {
+			continue
+		}
+		number := fmt.Sprintf("Page %d of %d", page.Number(), len(pages))
+		numberWidth := pdf.TextWidth(pdf.Helvetica, 8, number)
+		page.Text(r.size.Width-pdfMargin-numberWidth, baseline, pdf.Helvetica, 8, pdfMutedColor, number)
+		page.Text(pdfMargin, baseline, pdf.Helvetica, 8, pdfMutedColor,
+			truncatePDFText(pdf.Helvetica, 8, title, r.size.Width-2*pdfMargin-numberWidth-24))
+	}
+}
+
+// title draws a note or chapter title with a rule under it
+func (r *pdfRenderer) title(text string, size float64) {
+	r.text([]pdfRun{{text: text, font: pdf.HelveticaBold}}, size, 1.3)
+	r.space(4)
+	r.page.Line(pdfMargin, r.y, r.size.Width-pdfMargin, r.y, 0.75, pdfRuleColor)
+	r.space(14)
+}
+
+// muted draws a line of gray italic text, used for locked notes and empty chapters
+func (r *pdfRenderer) muted(text string) {
+	r.text([]pdfRun{{text: text, font: pdf.HelveticaOblique, color: pdfMutedColor}}, pdfBodySize, pdfLineSpacing)
+}
+
+// document renders the blocks of a TipTap document
+func (r *pdfRenderer) document(doc utils.TipTapDoc) {
+	r.blocks(doc.Content)
+}
+
+func (r *pdfRenderer) blocks(nodes []utils.TipTapNode) {
+	for _, node := range nodes {
+		r.block(node)
+	}
+}
+
+func (r *pdfRenderer) block(node utils.TipTapNode) {
+	switch node.Type {
+	case "paragraph":
+		runs := inlinePDFRuns(node.Content, false)
+		if len(runs) == 0 {
+			r.space(pdfBodySize * pdfLineSpacing / 2)
+			return
+		}
+		r.text(runs, pdfBodySize, pdfLineSpacing)
+		r.space(pdfBlockSpacing)
+
+	case "heading":
+		level := max(1, headingLevel(node))
+		size := pdfHeadingSizes[min(level, len(pdfHeadingSizes))-1]
+		r.space(size * 0.6)
+		// Keep the heading with the first line after it
+		r.ensure(size*1.3 + pdfBodySize*pdfLineSpacing)
+		if text := strings.TrimSpace(blockText(node)); r.tocHeadings && text != "" && level <= maxTOCHeadingLvl {
+			r.addTOCEntry(text, level-1, -1)
+		}
+		r.text(inlinePDFRuns(node.Content, true), size, 1.3)
+		r.space(pdfBlockSpacing)
+
+	case "bulletList", "orderedList", "taskList":
+		r.list(node)
+		r.space(pdfBlockSpacing)
+
+	case "codeBlock":
+		text := blockText(node)
+		if hash, _, ok := diagramHash(attrString(node.Attrs, "language"), text); ok && r.diagrams[hash] != nil {
+			r.image(r.diagrams[hash])
+		} else {
+			r.codeBlock(text)
+		}
+		r.space(pdfBlockSpacing)
+
+	case "blockquote", "callout":
+		r.quote(node)
+		r.space(pdfBlockSpacing)
+
+	case "horizontalRule":
+		r.ensure(16)
+		r.y += 8
+		r.page.Line(r.left(), r.y, r.left()+r.width(), r.y, 0.75, pdfRuleColor)
+		r.y += 8
+
+	case "table":
+		for _, row := range node.Content {
+			var runs []pdfRun
+			for i, cell := range row.Content {
+				header := cell.Type == "tableHeader"
+				if i > 0 {
+					runs = append(runs, pdfRun{text: "  |  ", font: pdfTextFont(header, false)})
+				}
+				runs = append(runs, inlinePDFRuns(cell.Content, header)...)
+			}
+			r.text(runs, pdfBodySize, pdfLineSpacing)
+		}
+		r.space(pdfBlockSpacing)
+
+	case "image":
+		label := attrString(node.Attrs, "alt")
+		if label == "" {
+			label = attrString(node.Attrs, "src")
+		}
+		r.muted("[Image: " + label + "]")
+		r.space(pdfBlockSpacing)
+
+	default:
+		if len(node.Content) > 0 {
+			r.blocks(node.Content)
+		} else if text := strings.TrimSpace(node.Text); text != "" {
+			r.text([]pdfRun{{text: text}}, pdfBodySize, pdfLineSpacing)
+		}
+	}
+}
+
+// list renders list items with their bullet, number or checkbox hanging left of the item
+func (r *pdfRenderer) list(node utils.TipTapNode) {
+	number := 1
+	if start, ok := node.Attrs["start"].(float64); ok {
+		number = int(start)
+	}
+	for _, item := range node.Content {
+		marker, font := "•", pdf.Helvetica
+		switch {
+		case node.Type == "orderedList":
+			marker = fmt.Sprintf("%d.", number)
+			number++
+		case item.Type == "taskItem":
+			marker, font = "[ ]", pdf.Courier
+			if checked, _ := item.Attrs["checked"].(bool); checked {
+				marker = "[x]"
+			}
+		}
+
+		r.ensure(pdfBodySize * pdfLineSpacing)
+		r.page.Text(r.left(), r.y+pdfBodySize*1.1, font, pdfBodySize, pdf.Black, marker)
+		r.indent += pdfListIndent
+		for i, child := range item.Content {
+			if child.Type == "paragraph" {
+				// Paragraphs in list items sit closer together than in the body
+				r.text(inlinePDFRuns(child.Content, false), pdfBodySize, pdfLineSpacing)
+				if i < len(item.Content)-1 {
+					r.space(2)
+				}
+				continue
+			}
+			r.block(child)
+		}
+		r.indent -= pdfListIndent
+	}
+}
+
+// codeBlock renders monospaced lines on a shaded background, wrapping long lines
+func (r *pdfRenderer) codeBlock(code string) {
+	lineHeight := pdfCodeSize * 1.5
+	padding := 4.0
+	r.ensure(lineHeight + 2*padding)
+	r.page.FillRect(r.left(), r.y, r.width(), padding, pdfCodeBgColor)
+	r.y += padding
+
+	textWidth := r.width() - 2*padding
+	for _, line := range strings.Split(strings.TrimRight(code, "\n"), "\n") {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		for {
+			n := pdf.FitText(pdf.Courier, pdfCodeSize, line, textWidth)
+			if r.y+lineHeight > r.bottom() {
+				r.newPage()
+			}
+			r.page.FillRect(r.left(), r.y, r.width(), lineHeight, pdfCodeBgColor)
+			r.page.Text(r.left()+padding, r.y+pdfCodeSize*1.1, pdf.Courier, pdfCodeSize, pdf.Black, line[:n])
+			r.y += lineHeight
+			if line = line[n:]; line == "" {
+				break
+			}
+		}
+	}
+	if r.y+padding <= r.bottom() {
+		r.page.FillRect(r.left(), r.y, r.width(), padding, pdfCodeBgColor)
+		r.y += padding
+	}
+}
+
+// quote renders blockquotes and callouts indented behind a bar, which continues across pages
+func (r *pdfRenderer) quote(node utils.TipTapNode) {
+	color := pdfRuleColor
+	if node.Type == "callout" {
+		color = pdfAccentColor
+	}
+	r.ensure(pdfBodySize * pdfLineSpacing)
+	startPage, startY, x := r.page, r.y, r.left()+3
+
+	r.indent += pdfQuoteIndent
+	if title := attrString(node.Attrs, "title"); node.Type == "callout" && title != "" {
+		r.text([]pdfRun{{text: title, font: pdf.HelveticaBold}}, pdfBodySize, pdfLineSpacing)
+	}
+	r.blocks(node.Content)
+	r.indent -= pdfQuoteIndent
+
+	pages := r.doc.Pages()
+	for i := startPage.Number() - 1; i < r.page.Number(); i++ {
+		top, bottom := float64(pdfMargin), r.bottom()
+		if pages[i] == startPage {
+			top = startY
+		}
+		if pages[i] == r.page {
+			bottom = r.y - pdfBlockSpacing
+		}
+		if bottom > top {
+			pages[i].Line(x, top, x, bottom, 2, color)
+		}
+	}
+}
+
+// pdfWord is a word or the space after it, in one style, or a formula set like a word
+type pdfWord struct {
+	pdfRun
+	width float64
+	space bool
+	box   *pdfMathBox
+}
+
+// text wraps runs to the width of the page, starting new pages as needed
+func (r *pdfRenderer) text(runs []pdfRun, size, spacing float64) {
+	lineHeight := size * spacing
+	maxWidth := r.width()
+
+	var line []pdfWord
+	lineWidth := 0.0
+	flush := func() {
+		for len(line) > 0 && line[len(line)-1].space {
+			line = line[:len(line)-1]
+		}
+		// Lines with formulas taller or deeper than the text grow to fit them
+		above, below := 0.0, 0.0
+		for _, word := range line {
+			if word.box != nil {
+				above = math.Max(above, word.box.ascent-size)
+				below = math.Max(below, word.box.descent-(lineHeight-size*1.1))
+			}
+		}
+		r.ensure(lineHeight + above + below)
+		// Words in the same style are drawn together
+		x, baseline := r.left(), r.y+above+size*1.1
+		for start := 0; start < len(line); {
+			if box := line[start].box; box != nil {
+				box.draw(r.page, x, baseline, line[start].color)
+				x += line[start].width
+				start++
+				continue
+			}
+			end, width := start, 0.0
+			var segment strings.Builder
+			for ; end < len(line) && line[end].box == nil && line[end].font == line[start].font && line[end].color == line[start].color; end++ {
+				segment.WriteString(line[end].text)
+				width += line[end].width
+			}
+			r.page.Text(x, baseline, line[start].font, size, line[start].color, segment.String())
+			x += width
+			start = end
+		}
+		r.y += lineHeight + above + below
+		line, lineWidth = line[:0], 0
+	}
+
+	for _, run := range runs {
+		if run.math != nil {
+			box := layoutPDFMath(*run.math, size)
+			if box.width > maxWidth {
+				// Formulas wider than the page are shrunk to fit
+				box = layoutPDFMath(*run.math, size*maxWidth/box.width)
+			}
+			if len(line) > 0 && lineWidth+box.width > maxWidth {
+				flush()
+			}
+			line = append(line, pdfWord{pdfRun: run, width: box.width, box: &box})
+			lineWidth += box.width
+			continue
+		}
+		for _, part := range splitPDFWords(run.text) {
+			if part == "\n" {
+				flush()
+				continue
+			}
+			word := pdfWord{pdfRun: pdfRun{text: part, font: run.font, color: run.color}, space: strings.TrimSpace(part) == ""}
+			word.width = pdf.TextWidth(word.font, size, part)
+			if word.space && len(line) == 0 {
+				continue
+			}
+			if !word.space && lineWidth+word.width > maxWidth {
+				if len(line) > 0 {
+					flush()
+				}
+				// Break words longer than the line between characters
+				for word.width > maxWidth {
+					n := pdf.FitText(word.font, size, word.text, maxWidth)
+					head := word
+					head.text = word.text[:n]
+					head.width = pdf.TextWidth(head.font, size, head.text)
+					line, lineWidth = append(line, head), head.width
+					flush()
+					word.text = word.text[n:]
+					word.width = pdf.TextWidth(word.font, size, word.text)
+				}
+			}
+			line = append(line, word)
+			lineWidth += word.width
+		}
+	}
+	if len(line) > 0 {
+		flush()
+	}
+}
+
+// splitPDFWords splits text into words, runs of spaces and line breaks
+func splitPDFWords(text string) []string {
+	var parts []string
+	var current strings.Builder
+	currentSpace := false
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+	for _, c := range text {
+		if c == '\n' {
+			flush()
+			parts = append(parts, "\n")
+			continue
+		}
+		space := unicode.IsSpace(c)
+		if space != currentSpace {
+			flush()
+			currentSpace = space
+		}
+		current.WriteRune(c)
+	}
+	flush()
+	return parts
+}
+
+// inlinePDFRuns turns the inline nodes of a block into styled runs
+func inlinePDFRuns(nodes []utils.TipTapNode, bold bool) []pdfRun {
+	var runs []pdfRun
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			run := pdfRun{text: node.Text, font: pdf.Helvetica}
+			isBold, isItalic := bold, false
+			for _, mark := range node.Marks {
+				switch mark.Type {
+				case "bold":
+					isBold = true
+				case "italic":
+					isItalic = true
+				case "code":
+					run.font = pdf.Courier
+				case "link":
+					run.color = pdfLinkColor
+				}
+			}
+			if run.font != pdf.Courier {
+				run.font = pdfTextFont(isBold, isItalic)
+			}
+			runs = append(runs, run)
+		case "hardBreak":
+			runs = append(runs, pdfRun{text: "\n"})
+		case "mention":
+			if label := attrString(node.Attrs, "label"); label != "" {
+				runs = append(runs, pdfRun{text: "@" + label, font: pdfTextFont(bold, false), color: pdfLinkColor})
+			}
+		case utils.MathNodeType:
+			if latex := utils.MathLaTeX(node); latex != "" {
+				formula := utils.ParseLaTeX(latex)
+				runs = append(runs, pdfRun{math: &formula})
+			}
+		default:
+			runs = append(runs, inlinePDFRuns(node.Content, bold)...)
+		}
+	}
+	return runs
+}
+
+func pdfTextFont(bold, italic bool) pdf.Font {
+	switch {
+	case bold && italic:
+		return pdf.HelveticaBoldOblique
+	case bold:
+		return pdf.HelveticaBold
+	case italic:
+		return pdf.HelveticaOblique
+	}
+	return pdf.Helvetica
+}
+
+// tocHeadings returns the headings of a document listed in its table of contents
+func tocHeadings(nodes []utils.TipTapNode) int {
+	count := 0
+	for _, node := range nodes {
+		switch node.Type {
+		case "heading":
+			if headingLevel(node) <= maxTOCHeadingLvl && strings.TrimSpace(blockText(node)) != "" {
+				count++
+			}
+		case "table", "codeBlock":
+		default:
+			count += tocHeadings(node.Content)
+		}
+	}
+	return count
+}
+
+func attrString(attrs map[string]interface{}, key string) string {
+	value, _ := attrs[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// truncatePDFText shortens text with an ellipsis to fit a width
+func truncatePDFText(font pdf.Font, size float64, text string, width float64) string {
+	if pdf.TextWidth(font, size, text) <= width {
+		return text
+	}
+	n := pdf.FitText(font, size, text, width-pdf.TextWidth(font, size, "…"))
+	return strings.TrimSpace(text[:n]) + "…"
+}
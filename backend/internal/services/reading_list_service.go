@@ -0,0 +1,719 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/internal/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrReadingListItemNotFound is returned when a reading list item doesn't exist or belongs to another user
+	ErrReadingListItemNotFound = errors.New("reading list item not found")
+	// ErrInvalidReadingListItem is returned when a clip or highlight can't be saved
+	ErrInvalidReadingListItem = errors.New("invalid reading list item")
+)
+
+const (
+	// readingWordsPerMinute is the reading speed reading times are estimated with
+	readingWordsPerMinute       = 200
+	maxReadingListContentSize   = 200 << 10
+	maxReadingListHighlightSize = 5000
+	maxReadingListHighlights    = 200
+	readingListPageMaxBytes     = 2 << 20
+)
+
+// readingListSkippedTags hold page chrome and scripts rather than the text being read
+var readingListSkippedTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true, "svg": true, "iframe": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true, "button": true,
+}
+
+// readingListBlockTags start a new paragraph of a page's text
+var readingListBlockTags = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "blockquote": true, "pre": true, "figcaption": true, "td": true, "div": true, "br": true,
+}
+
+// readingListFetchesQueued dedupes page fetches of items saved again before their fetch ran
+var readingListFetchesQueued sync.Map
+
+var (
+	readingListClient     *http.Client
+	readingListClientOnce sync.Once
+)
+
+// ReadingListClip is a page saved to the reading list, from the web clipper or the app. Content is the
+// page's text, paragraphs separated by blank lines; without it the page is fetched in the background.
+type ReadingListClip struct {
+	URL        string                      `json:"url"`
+	Title      string                      `json:"title"`
+	Excerpt    string                      `json:"excerpt"`
+	Content    string                      `json:"content"`
+	Highlights []ReadingListHighlightInput `json:"highlights"`
+}
+
+// ReadingListHighlightInput is a highlighted passage of a reading list item
+type ReadingListHighlightInput struct {
+	Text    string `json:"text"`
+	Comment string `json:"comment"`
+}
+
+// readingPage is the metadata and text of a fetched page
+type readingPage struct {
+	metadata LinkMetadata
+	text     string
+}
+
+// ReadingListService interface defines methods for the reading list
+type ReadingListService interface {
+	Save(ctx context.Context, clerkUserID string, clip ReadingListClip, source string) (*models.ReadingListItem, error)
+	SaveBookmarks(ctx context.Context, clerkUserID string, bookmarks []ParsedBookmark) (int, error)
+	List(ctx context.Context, clerkUserID, status string) ([]models.ReadingListItem, error)
+	Get(ctx context.Context, id, clerkUserID string) (*models.ReadingListItem, error)
+	SetRead(ctx context.Context, id, clerkUserID string, read bool) (*models.ReadingListItem, error)
+	Delete(ctx context.Context, id, clerkUserID string) error
+	AddHighlight(ctx context.Context, id, clerkUserID string, input ReadingListHighlightInput) (*models.ReadingListHighlight, error)
+	DeleteHighlight(ctx context.Context, id, highlightID, clerkUserID string) error
+	ConvertToNote(ctx context.Context, id, clerkUserID, chapterID string) (*models.Notes, error)
+	FetchItem(ctx context.Context, id string) error
+}
+
+// readingListServiceImpl implements the ReadingListService interface
+type readingListServiceImpl struct {
+	db        *gorm.DB
+	queue     *JobQueue
+	fetchPage func(ctx context.Context, rawURL string) (*readingPage, error)
+	now       func() time.Time
+}
+
+// NewReadingListService creates a new ReadingListService instance
+func NewReadingListService() ReadingListService {
+	readingListClientOnce.Do(func() {
+		readingListClient = newLinkPreviewClient()
+	})
+	return &readingListServiceImpl{
+		db:    db.DB,
+		queue: GetJobQueue(),
+		fetchPage: func(ctx context.Context, rawURL string) (*readingPage, error) {
+			return fetchReadingPage(ctx, readingListClient, rawURL)
+		},
+		now: time.Now,
+	}
+}
+
+// Save adds a page to the user's reading list. Saving a page that's already on the list fills in what
+// the new clip adds, including its highlights, and keeps the item's read state.
+func (s *readingListServiceImpl) Save(ctx context.Context, clerkUserID string, clip ReadingListClip, source string) (*models.ReadingListItem, error) {
+	normalized, err := normalizeLinkURL(clip.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReadingListItem, err)
+	}
+	highlights, err := normalizeReadingHighlights(clip.Highlights)
+	if err != nil {
+		return nil, err
+	}
+	title := truncateLinkText(strings.Join(strings.Fields(clip.Title), " "), 300)
+	excerpt := truncateLinkText(strings.TrimSpace(clip.Excerpt), 1000)
+	content := normalizeReadingText(clip.Content)
+
+	var item models.ReadingListItem
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("clerk_user_id = ? AND url = ?", clerkUserID, normalized).First(&item).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			item = models.ReadingListItem{
+				ClerkUserID: clerkUserID,
+				URL:         normalized,
+				Title:       title,
+				Excerpt:     excerpt,
+				Source:      source,
+			}
+			if item.Title == "" {
+				item.Title = readingListHost(normalized)
+			}
+			setReadingContent(&item, content)
+			if err := tx.Create(&item).Error; err != nil {
+				return fmt.Errorf("failed to save reading list item: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("failed to fetch reading list item: %w", err)
+		default:
+			if title != "" {
+				item.Title = title
+			}
+			if excerpt != "" {
+				item.Excerpt = excerpt
+			}
+			if content != "" {
+				setReadingContent(&item, content)
+			}
+			if err := tx.Save(&item).Error; err != nil {
+				return fmt.Errorf("failed to save reading list item: %w", err)
+			}
+		}
+
+		return s.addHighlights(tx, &item, highlights)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Content == "" && item.FetchedAt == nil {
+		s.queueFetch(item.ID)
+	}
+	return s.Get(ctx, item.ID, clerkUserID)
+}
+
+// SaveBookmarks adds imported bookmarks to the user's reading list, skipping links already on it.
+// Their pages are fetched in the background.
+func (s *readingListServiceImpl) SaveBookmarks(ctx context.Context, clerkUserID string, bookmarks []ParsedBookmark) (int, error) {
+	var existing []string
+	if err := s.db.WithContext(ctx).Model(&models.ReadingListItem{}).Where("clerk_user_id = ?", clerkUserID).
+		Pluck("url", &existing).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch reading list: %w", err)
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, link := range existing {
+		seen[link] = true
+	}
+
+	var items []models.ReadingListItem
+	for _, bookmark := range bookmarks {
+		normalized, err := normalizeLinkURL(bookmark.URL)
+		if err != nil || !isImportableBookmark(normalized) || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		items = append(items, models.ReadingListItem{
+			ClerkUserID: clerkUserID,
+			URL:         normalized,
+			Title:       bookmarkNoteName(bookmark),
+			Excerpt:     truncateLinkText(bookmark.Description, 1000),
+			Source:      models.ReadingListSourceBookmark,
+		})
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	if err := s.db.WithContext(ctx).CreateInBatches(&items, 200).Error; err != nil {
+		return 0, fmt.Errorf("failed to save reading list items: %w", err)
+	}
+	for _, item := range items {
+		s.queueFetch(item.ID)
+	}
+	return len(items), nil
+}
+
+// List returns the user's reading list, newest first, without the items' text. Status filters it to
+// "read" or "unread" items.
+func (s *readingListServiceImpl) List(ctx context.Context, clerkUserID, status string) ([]models.ReadingListItem, error) {
+	query := db.Replica(s.db).WithContext(ctx).Omit("content").
+		Preload("Highlights", func(tx *gorm.DB) *gorm.DB { return tx.Order("created_at ASC") }).
+		Where("clerk_user_id = ?", clerkUserID)
+	switch status {
+	case "":
+	case "read":
+		query = query.Where("is_read = ?", true)
+	case "unread":
+		query = query.Where("is_read = ?", false)
+	default:
+		return nil, fmt.Errorf("%w: status must be read or unread", ErrInvalidReadingListItem)
+	}
+
+	var items []models.ReadingListItem
+	if err := query.Order("created_at DESC").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch reading list: %w", err)
+	}
+	return items, nil
+}
+
+// Get returns one of the user's reading list items with its text and highlights
+func (s *readingListServiceImpl) Get(ctx context.Context, id, clerkUserID string) (*models.ReadingListItem, error) {
+	var item models.ReadingListItem
+	err := s.db.WithContext(ctx).
+		Preload("Highlights", func(tx *gorm.DB) *gorm.DB { return tx.Order("created_at ASC") }).
+		Where("id = ? AND clerk_user_id = ?", id, clerkUserID).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReadingListItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reading list item: %w", err)
+	}
+	return &item, nil
+}
+
+// SetRead marks a reading list item read or unread
+func (s *readingListServiceImpl) SetRead(ctx context.Context, id, clerkUserID string, read bool) (*models.ReadingListItem, error) {
+	item, err := s.Get(ctx, id, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	if item.IsRead == read {
+		return item, nil
+	}
+
+	var readAt *time.Time
+	if read {
+		now := s.now()
+		readAt = &now
+	}
+	if err := s.db.WithContext(ctx).Model(item).Updates(map[string]interface{}{
+		"is_read": read,
+		"read_at": readAt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update reading list item: %w", err)
+	}
+	item.IsRead = read
+	item.ReadAt = readAt
+	return item, nil
+}
+
+// Delete removes an item from the user's reading list. A note it was converted into stays.
+func (s *readingListServiceImpl) Delete(ctx context.Context, id, clerkUserID string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND clerk_user_id = ?", id, clerkUserID).Delete(&models.ReadingListItem{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete reading list item: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrReadingListItemNotFound
+		}
+		if err := tx.Where("item_id = ?", id).Delete(&models.ReadingListHighlight{}).Error; err != nil {
+			return fmt.Errorf("failed to delete highlights: %w", err)
+		}
+		return nil
+	})
+}
+
+// AddHighlight highlights a passage of a reading list item
+func (s *readingListServiceImpl) AddHighlight(ctx context.Context, id, clerkUserID string, input ReadingListHighlightInput) (*models.ReadingListHighlight, error) {
+	highlights, err := normalizeReadingHighlights([]ReadingListHighlightInput{input})
+	if err != nil {
+		return nil, err
+	}
+	if len(highlights) == 0 {
+		return nil, fmt.Errorf("%w: highlight text is required", ErrInvalidReadingListItem)
+	}
+
+	item, err := s.Get(ctx, id, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.addHighlights(s.db.WithContext(ctx), item, highlights); err != nil {
+		return nil, err
+	}
+	if highlights[0].ID == "" {
+		// The passage was already highlighted
+		for _, existing := range item.Highlights {
+			if existing.Text == highlights[0].Text {
+				return &existing, nil
+			}
+		}
+	}
+	return &highlights[0], nil
+}
+
+// DeleteHighlight removes a highlight from a reading list item
+func (s *readingListServiceImpl) DeleteHighlight(ctx context.Context, id, highlightID, clerkUserID string) error {
+	if _, err := s.Get(ctx, id, clerkUserID); err != nil {
+		return err
+	}
+	result := s.db.WithContext(ctx).Where("id = ? AND item_id = ?", highlightID, id).Delete(&models.ReadingListHighlight{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete highlight: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: highlight not found", ErrReadingListItemNotFound)
+	}
+	return nil
+}
+
+// ConvertToNote turns a reading list item into a note in the chapter, with the link, the highlights
+// and the page's text, and marks the item read
+func (s *readingListServiceImpl) ConvertToNote(ctx context.Context, id, clerkUserID, chapterID string) (*models.Notes, error) {
+	item, err := s.Get(ctx, id, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var chapter models.Chapter
+	err = s.db.WithContext(ctx).Preload("Notebook").Where("id = ?", chapterID).First(&chapter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChapterNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chapter: %w", err)
+	}
+	if chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	content, err := readingListNoteContent(item)
+	if err != nil {
+		return nil, err
+	}
+	note := models.Notes{
+		Name:           truncateLinkText(item.Title, 255),
+		Content:        AssignTipTapBlockIDs("", content),
+		ChapterID:      chapter.ID,
+		OrganizationID: chapter.OrganizationID,
+		Status:         models.NoteStatusDraft,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return fmt.Errorf("failed to create note: %w", err)
+		}
+		updates := map[string]interface{}{"note_id": note.ID}
+		if !item.IsRead {
+			updates["is_read"] = true
+			updates["read_at"] = s.now()
+		}
+		if err := tx.Model(item).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update reading list item: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("clerk_user_id", clerkUserID).Str("item_id", item.ID).Str("note_id", note.ID).Msg("Converted reading list item to note")
+	return &note, nil
+}
+
+// FetchItem fills in a reading list item's title, excerpt, text and reading time from its page.
+// Pages that can't be reached aren't retried, the item keeps what it was saved with.
+func (s *readingListServiceImpl) FetchItem(ctx context.Context, id string) error {
+	var item models.ReadingListItem
+	err := s.db.WithContext(ctx).Where("id = ?", id).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Deleted since the fetch was queued
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch reading list item: %w", err)
+	}
+
+	page, err := s.fetchPage(ctx, item.URL)
+	if err != nil && !errors.Is(err, ErrInvalidLinkURL) && !errors.Is(err, ErrLinkPreviewBlocked) {
+		return err
+	}
+
+	fetchedAt := s.now()
+	updates := map[string]interface{}{"fetched_at": fetchedAt}
+	if page != nil {
+		if page.metadata.Title != "" && (item.Title == "" || item.Title == readingListHost(item.URL)) {
+			updates["title"] = page.metadata.Title
+		}
+		if item.Excerpt == "" && page.metadata.Description != "" {
+			updates["excerpt"] = page.metadata.Description
+		}
+		if page.metadata.SiteName != "" {
+			updates["site_name"] = page.metadata.SiteName
+		}
+		if page.metadata.ImageURL != "" {
+			updates["image_url"] = page.metadata.ImageURL
+		}
+		if item.Content == "" && page.text != "" {
+			setReadingContent(&item, normalizeReadingText(page.text))
+			updates["content"] = item.Content
+			updates["word_count"] = item.WordCount
+			updates["reading_minutes"] = item.ReadingMinutes
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Model(&item).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update reading list item: %w", err)
+	}
+	return nil
+}
+
+// queueFetch fetches an item's page in the background
+func (s *readingListServiceImpl) queueFetch(id string) {
+	if _, queued := readingListFetchesQueued.LoadOrStore(id, true); queued {
+		return
+	}
+
+	err := s.queue.Enqueue(Job{
+		Name:        "reading-list-fetch",
+		MaxAttempts: 2,
+		Run: func(ctx context.Context) error {
+			defer readingListFetchesQueued.Delete(id)
+			return s.FetchItem(ctx, id)
+		},
+	})
+	if err != nil {
+		readingListFetchesQueued.Delete(id)
+		log.Warn().Err(err).Str("item_id", id).Msg("Failed to queue reading list fetch")
+	}
+}
+
+// addHighlights adds highlights to an item, skipping passages it already has
+func (s *readingListServiceImpl) addHighlights(tx *gorm.DB, item *models.ReadingListItem, highlights []models.ReadingListHighlight) error {
+	if len(highlights) == 0 {
+		return nil
+	}
+
+	var existing []string
+	if err := tx.Model(&models.ReadingListHighlight{}).Where("item_id = ?", item.ID).Pluck("text", &existing).Error; err != nil {
+		return fmt.Errorf("failed to fetch highlights: %w", err)
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, text := range existing {
+		seen[text] = true
+	}
+
+	for i := range highlights {
+		if seen[highlights[i].Text] {
+			continue
+		}
+		if len(seen) >= maxReadingListHighlights {
+			return fmt.Errorf("%w: an item can have at most %d highlights", ErrInvalidReadingListItem, maxReadingListHighlights)
+		}
+		seen[highlights[i].Text] = true
+		highlights[i].ItemID = item.ID
+		if err := tx.Create(&highlights[i]).Error; err != nil {
+			return fmt.Errorf("failed to save highlight: %w", err)
+		}
+	}
+	return nil
+}
+
+// normalizeReadingHighlights trims highlights and drops empty ones
+func normalizeReadingHighlights(inputs []ReadingListHighlightInput) ([]models.ReadingListHighlight, error) {
+	if len(inputs) > maxReadingListHighlights {
+		return nil, fmt.Errorf("%w: an item can have at most %d highlights", ErrInvalidReadingListItem, maxReadingListHighlights)
+	}
+
+	highlights := make([]models.ReadingListHighlight, 0, len(inputs))
+	for _, input := range inputs {
+		text := strings.Join(strings.Fields(input.Text), " ")
+		if text == "" {
+			continue
+		}
+		if len(text) > maxReadingListHighlightSize || len(input.Comment) > maxReadingListHighlightSize {
+			return nil, fmt.Errorf("%w: highlights and comments can be at most %d characters", ErrInvalidReadingListItem, maxReadingListHighlightSize)
+		}
+		highlights = append(highlights, models.ReadingListHighlight{Text: text, Comment: strings.TrimSpace(input.Comment)})
+	}
+	return highlights, nil
+}
+
+// setReadingContent stores a page's text on an item with its estimated reading time
+func setReadingContent(item *models.ReadingListItem, content string) {
+	item.Content = content
+	item.WordCount = len(strings.Fields(content))
+	item.ReadingMinutes = (item.WordCount + readingWordsPerMinute - 1) / readingWordsPerMinute
+}
+
+// normalizeReadingText collapses the whitespace of each paragraph and separates paragraphs with a blank line
+func normalizeReadingText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var paragraphs []string
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if paragraph = strings.Join(strings.Fields(paragraph), " "); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return truncateLinkText(strings.Join(paragraphs, "\n\n"), maxReadingListContentSize)
+}
+
+// readingListHost is the title of items whose page has no title
+func readingListHost(rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Hostname() != "" {
+		return parsed.Hostname()
+	}
+	return truncateLinkText(rawURL, 300)
+}
+
+// readingListNoteContent builds the TipTap document of a note converted from a reading list item
+func readingListNoteContent(item *models.ReadingListItem) (string, error) {
+	paragraph := func(text string, marks ...utils.TipTapMark) utils.TipTapNode {
+		return utils.TipTapNode{Type: "paragraph", Content: []utils.TipTapNode{{Type: "text", Text: text, Marks: marks}}}
+	}
+	heading := func(text string) utils.TipTapNode {
+		return utils.TipTapNode{Type: "heading", Attrs: map[string]interface{}{"level": 2}, Content: []utils.TipTapNode{{Type: "text", Text: text}}}
+	}
+
+	nodes := []utils.TipTapNode{
+		paragraph(item.URL, utils.TipTapMark{Type: "link", Attrs: map[string]interface{}{"href": item.URL}}),
+	}
+	var details []string
+	if item.SiteName != "" {
+		details = append(details, item.SiteName)
+	}
+	if item.ReadingMinutes > 0 {
+		details = append(details, fmt.Sprintf("%d min read", item.ReadingMinutes))
+	}
+	details = append(details, "Saved on "+item.CreatedAt.Format("January 2, 2006"))
+	nodes = append(nodes, paragraph(strings.Join(details, " · "), utils.TipTapMark{Type: "italic"}))
+	if item.Excerpt != "" {
+		nodes = append(nodes, paragraph(item.Excerpt))
+	}
+
+	if len(item.Highlights) > 0 {
+		nodes = append(nodes, heading("Highlights"))
+		for _, highlight := range item.Highlights {
+			nodes = append(nodes, utils.TipTapNode{Type: "blockquote", Content: []utils.TipTapNode{paragraph(highlight.Text)}})
+			if highlight.Comment != "" {
+				nodes = append(nodes, paragraph(highlight.Comment))
+			}
+		}
+	}
+
+	if item.Content != "" {
+		nodes = append(nodes, heading("Article"))
+		for _, text := range strings.Split(item.Content, "\n\n") {
+			nodes = append(nodes, paragraph(text))
+		}
+	}
+
+	content, err := json.Marshal(utils.TipTapDoc{Type: "doc", Content: nodes})
+	if err != nil {
+		return "", fmt.Errorf("failed to build note content: %w", err)
+	}
+	return string(content), nil
+}
+
+// fetchReadingPage downloads a page and extracts its preview metadata and text
+func fetchReadingPage(ctx context.Context, client *http.Client, rawURL string) (*readingPage, error) {
+	normalized, err := normalizeLinkURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, normalized, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLinkURL, err)
+	}
+	req.Header.Set("User-Agent", linkPreviewUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrLinkPreviewBlocked) {
+			return nil, ErrLinkPreviewBlocked
+		}
+		return nil, fmt.Errorf("%w: %v", ErrLinkPreviewFetchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: status %d", ErrLinkPreviewFetchFailed, resp.StatusCode)
+	}
+
+	finalURL := resp.Request.URL
+	contentType := resp.Header.Get("Content-Type")
+	page := &readingPage{metadata: LinkMetadata{
+		URL:         finalURL.String(),
+		SiteName:    finalURL.Hostname(),
+		ContentType: strings.TrimSpace(strings.Split(contentType, ";")[0]),
+	}}
+	if page.metadata.ContentType != "" && page.metadata.ContentType != "text/html" && page.metadata.ContentType != "application/xhtml+xml" {
+		return page, nil
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, readingListPageMaxBytes), contentType)
+	if err != nil {
+		body = io.LimitReader(resp.Body, readingListPageMaxBytes)
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLinkPreviewFetchFailed, err)
+	}
+
+	parseLinkMetadata(bytes.NewReader(raw), finalURL, &page.metadata)
+	page.text = extractReadingText(bytes.NewReader(raw))
+	return page, nil
+}
+
+// extractReadingText returns the readable text of an HTML page, paragraphs separated by blank lines.
+// Navigation, scripts and forms are left out, and when the page marks its article only that is kept.
+func extractReadingText(body io.Reader) string {
+	var (
+		page, article []string
+		current       strings.Builder
+		skipped       int
+		articles      int
+	)
+	flush := func() {
+		text := strings.Join(strings.Fields(current.String()), " ")
+		current.Reset()
+		if text == "" {
+			return
+		}
+		page = append(page, text)
+		if articles > 0 {
+			article = append(article, text)
+		}
+	}
+
+	tokenizer := html.NewTokenizer(body)
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch {
+			case readingListSkippedTags[tag]:
+				if tokenType == html.StartTagToken {
+					skipped++
+				}
+			case tag == "article" || tag == "main":
+				flush()
+				articles++
+			case readingListBlockTags[tag]:
+				flush()
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch {
+			case readingListSkippedTags[tag]:
+				if skipped > 0 {
+					skipped--
+				}
+			case tag == "article" || tag == "main":
+				flush()
+				if articles > 0 {
+					articles--
+				}
+			case readingListBlockTags[tag]:
+				flush()
+			}
+		case html.TextToken:
+			if skipped == 0 {
+				current.WriteString(" ")
+				current.Write(tokenizer.Text())
+			}
+		}
+	}
+	flush()
+
+	if len(article) > 0 {
+		return strings.Join(article, "\n\n")
+	}
+	return strings.Join(page, "\n\n")
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestReadingListService creates a reading list service on an in-memory database. Pages are
+// served from the given map instead of being fetched, and queued fetches don't run.
+func setupTestReadingListService(t *testing.T, pages map[string]*readingPage) *readingListServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{},
+		&models.ReadingListItem{}, &models.ReadingListHighlight{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	now := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	return &readingListServiceImpl{
+		db:    db,
+		queue: NewJobQueue(1, 10),
+		fetchPage: func(ctx context.Context, rawURL string) (*readingPage, error) {
+			if page, ok := pages[rawURL]; ok {
+				return page, nil
+			}
+			return nil, ErrLinkPreviewBlocked
+		},
+		now: func() time.Time { return now },
+	}
+}
+
+func TestReadingList_SaveClipAndConvertToNote(t *testing.T) {
+	service := setupTestReadingListService(t, nil)
+	ctx := context.Background()
+	article := strings.Repeat("word ", 450)
+
+	item, err := service.Save(ctx, "user_1", ReadingListClip{
+		URL:        "https://Example.com/post#comments",
+		Title:      "  A   long post ",
+		Content:    "First  paragraph.\r\n\r\n\n" + article,
+		Highlights: []ReadingListHighlightInput{{Text: "First paragraph.", Comment: "Good start"}, {Text: "  "}},
+	}, models.ReadingListSourceClip)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/post", item.URL)
+	assert.Equal(t, "A long post", item.Title)
+	assert.Equal(t, 452, item.WordCount)
+	assert.Equal(t, 3, item.ReadingMinutes)
+	require.Len(t, item.Highlights, 1, "Empty highlights are dropped")
+
+	_, err = service.SetRead(ctx, item.ID, "user_1", true)
+	require.NoError(t, err)
+	again, err := service.Save(ctx, "user_1", ReadingListClip{
+		URL:        "https://example.com/post",
+		Highlights: []ReadingListHighlightInput{{Text: "First paragraph."}, {Text: "word word"}},
+	}, models.ReadingListSourceClip)
+	require.NoError(t, err)
+	assert.Equal(t, item.ID, again.ID, "Saving a page again updates its item")
+	assert.True(t, again.IsRead)
+	assert.Equal(t, "A long post", again.Title)
+	assert.Len(t, again.Highlights, 2, "Highlighted passages are only kept once")
+
+	_, err = service.Get(ctx, item.ID, "user_2")
+	assert.ErrorIs(t, err, ErrReadingListItemNotFound)
+	_, err = service.Save(ctx, "user_1", ReadingListClip{URL: "ftp://example.com/file"}, models.ReadingListSourceClip)
+	assert.ErrorIs(t, err, ErrInvalidReadingListItem)
+
+	note := createLifecycleNote(t, service.db, nil)
+	converted, err := service.ConvertToNote(ctx, item.ID, "user_1", note.ChapterID)
+	require.NoError(t, err)
+	assert.Equal(t, "A long post", converted.Name)
+	assert.Contains(t, converted.Content, `"type":"blockquote"`)
+	assert.Contains(t, converted.Content, "Good start")
+	assert.Contains(t, converted.Content, "3 min read")
+	assert.Contains(t, converted.Content, `"href":"https://example.com/post"`)
+
+	stored, err := service.Get(ctx, item.ID, "user_1")
+	require.NoError(t, err)
+	require.NotNil(t, stored.NoteID)
+	assert.Equal(t, converted.ID, *stored.NoteID)
+
+	require.NoError(t, service.db.Model(&models.Notebook{}).Where("1 = 1").Update("encrypted", true).Error)
+	_, err = service.ConvertToNote(ctx, item.ID, "user_1", note.ChapterID)
+	assert.ErrorIs(t, err, ErrNotebookEncrypted)
+	_, err = service.ConvertToNote(ctx, item.ID, "user_1", "missing")
+	assert.ErrorIs(t, err, ErrChapterNotFound)
+}
+
+func TestReadingList_BookmarksAreFetched(t *testing.T) {
+	service := setupTestReadingListService(t, map[string]*readingPage{
+		"https://example.com/article": {
+			metadata: LinkMetadata{Title: "An article, in full", Description: "What it's about", SiteName: "Example"},
+			text:     strings.Repeat("word ", 250),
+		},
+	})
+	ctx := context.Background()
+
+	bookmarks, err := ParseNetscapeBookmarks(strings.NewReader(testBookmarksExport))
+	require.NoError(t, err)
+	added, err := service.SaveBookmarks(ctx, "user_1", bookmarks)
+	require.NoError(t, err)
+	assert.Equal(t, 3, added, "Bookmarklets and repeated links are skipped")
+	added, err = service.SaveBookmarks(ctx, "user_1", bookmarks)
+	require.NoError(t, err)
+	assert.Zero(t, added, "Links already on the reading list are skipped")
+
+	items, err := service.List(ctx, "user_1", "unread")
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	for _, item := range items {
+		require.NoError(t, service.FetchItem(ctx, item.ID))
+	}
+
+	var fetched models.ReadingListItem
+	require.NoError(t, service.db.Where("url = ?", "https://example.com/article").First(&fetched).Error)
+	assert.Equal(t, "An article", fetched.Title, "Bookmark titles are kept")
+	assert.Equal(t, "What it's about", fetched.Excerpt)
+	assert.Equal(t, "Example", fetched.SiteName)
+	assert.Equal(t, 2, fetched.ReadingMinutes)
+	assert.NotNil(t, fetched.FetchedAt)
+
+	var blocked models.ReadingListItem
+	require.NoError(t, service.db.Where("url = ?", "https://example.com/untitled").First(&blocked).Error)
+	assert.Equal(t, "example.com", blocked.Title)
+	assert.NotNil(t, blocked.FetchedAt, "Pages that can't be fetched aren't retried")
+	assert.Zero(t, blocked.ReadingMinutes)
+
+	_, err = service.SetRead(ctx, fetched.ID, "user_1", true)
+	require.NoError(t, err)
+	read, err := service.List(ctx, "user_1", "read")
+	require.NoError(t, err)
+	require.Len(t, read, 1)
+	assert.Empty(t, read[0].Content, "Lists leave out the items' text")
+	_, err = service.List(ctx, "user_1", "archived")
+	assert.ErrorIs(t, err, ErrInvalidReadingListItem)
+}
+
+func TestExtractReadingText(t *testing.T) {
+	page := `<html><head><title>Post</title><script>var x = 1;</script></head><body>
+		<nav><a href="/">Home</a></nav>
+		<p>Sign up for the newsletter</p>
+		<article><h1>The title</h1><p>First <em>paragraph</em> here.</p><div>Second<br>line</div></article>
+		<footer>Copyright</footer></body></html>`
+
+	assert.Equal(t, "The title\n\nFirst paragraph here.\n\nSecond\n\nline", extractReadingText(strings.NewReader(page)))
+	assert.Equal(t, "Intro\n\nMore", extractReadingText(strings.NewReader("<body><p>Intro</p><header>Menu</header><p>More</body>")),
+		"Pages without an article keep all their text")
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetentionJob periodically applies organization retention policies
+type RetentionJob struct {
+	retentionService RetentionService
+	interval         time.Duration
+	stopChan         chan struct{}
+}
+
+// NewRetentionJob creates a new scheduled retention job
+func NewRetentionJob(retentionService RetentionService, interval time.Duration) *RetentionJob {
+	return &RetentionJob{
+		retentionService: retentionService,
+		interval:         interval,
+		stopChan:         make(chan struct{}),
+	}
+}
+
+// Start begins applying retention policies
+func (j *RetentionJob) Start(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Msg("Starting retention policy scheduler")
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			report, err := j.retentionService.ApplyAll()
+			if err != nil {
+				log.Error().Err(err).Msg("Applying retention policies failed")
+			} else if report.Archived > 0 {
+				log.Info().
+					Int("organizations", report.Organizations).
+					Int("archived", report.Archived).
+					Msg("Applied retention policies")
+			}
+		case <-ctx.Done():
+			log.Info().Msg("Stopping retention policy scheduler (context cancelled)")
+			return
+		case <-j.stopChan:
+			log.Info().Msg("Stopping retention policy scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (j *RetentionJob) Stop() {
+	close(j.stopChan)
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxArchiveAfterMonths caps how long a retention policy waits before archiving untouched notes
+	maxArchiveAfterMonths = 120
+	// retentionBatchSize is how many notes are archived per query
+	retentionBatchSize = 200
+	// retentionUserID is recorded as the user who archived notes for a retention policy
+	retentionUserID = "system:retention"
+)
+
+var (
+	// ErrLegalHold is returned when content of a notebook under legal hold is deleted or moved out of it
+	ErrLegalHold = errors.New("the notebook is under legal hold")
+	// ErrInvalidRetentionPolicy is returned for retention settings out of range
+	ErrInvalidRetentionPolicy = errors.New("invalid retention policy")
+	// ErrNoLegalHold is returned when a hold is released from a notebook that isn't under one
+	ErrNoLegalHold = errors.New("the notebook isn't under legal hold")
+)
+
+// RetentionReport counts what applying retention policies changed
+type RetentionReport struct {
+	Organizations int `json:"organizations"`
+	Archived      int `json:"archived"`
+}
+
+// RetentionService interface defines methods for organization retention policies and legal holds
+type RetentionService interface {
+	GetPolicy(organizationID string) (*models.OrganizationRetentionPolicy, error)
+	SavePolicy(organizationID string, archiveAfterMonths int, updatedBy string) (*models.OrganizationRetentionPolicy, error)
+	ListLegalHolds(organizationID string) ([]models.NotebookLegalHold, error)
+	PlaceLegalHold(organizationID, notebookID, reason, userID string) (*models.NotebookLegalHold, error)
+	ReleaseLegalHold(organizationID, notebookID, userID string) (*models.NotebookLegalHold, error)
+	ApplyPolicy(organizationID string) (int, error)
+	ApplyAll() (*RetentionReport, error)
+}
+
+// retentionServiceImpl implements the RetentionService interface
+type retentionServiceImpl struct {
+	db        *gorm.DB
+	lifecycle *noteLifecycleServiceImpl
+	now       func() time.Time
+}
+
+// NewRetentionService creates a new RetentionService instance
+func NewRetentionService() RetentionService {
+	return &retentionServiceImpl{
+		db:        db.DB,
+		lifecycle: &noteLifecycleServiceImpl{db: db.DB},
+		now:       time.Now,
+	}
+}
+
+// GetPolicy returns an organization's retention policy
+func (s *retentionServiceImpl) GetPolicy(organizationID string) (*models.OrganizationRetentionPolicy, error) {
+	var policy models.OrganizationRetentionPolicy
+	if err := s.db.Where("organization_id = ?", organizationID).Limit(1).Find(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch retention policy: %w", err)
+	}
+	policy.OrganizationID = organizationID
+	return &policy, nil
+}
+
+// SavePolicy updates an organization's retention policy. It's applied by the retention job.
+func (s *retentionServiceImpl) SavePolicy(organizationID string, archiveAfterMonths int, updatedBy string) (*models.OrganizationRetentionPolicy, error) {
+	if archiveAfterMonths < 0 || archiveAfterMonths > maxArchiveAfterMonths {
+		return nil, fmt.Errorf("%w: archiveAfterMonths must be between 0 and %d", ErrInvalidRetentionPolicy, maxArchiveAfterMonths)
+	}
+
+	policy := models.OrganizationRetentionPolicy{OrganizationID: organizationID}
+	if err := s.db.Where("organization_id = ?", organizationID).
+		Assign(map[string]interface{}{
+			"archive_after_months": archiveAfterMonths,
+			"updated_by":           updatedBy,
+		}).
+		FirstOrCreate(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save retention policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// ListLegalHolds returns the organization's active legal holds, newest first
+func (s *retentionServiceImpl) ListLegalHolds(organizationID string) ([]models.NotebookLegalHold, error) {
+	holds := []models.NotebookLegalHold{}
+	if err := s.db.Where("organization_id = ? AND released_at IS NULL", organizationID).
+		Order("created_at DESC").Find(&holds).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch legal holds: %w", err)
+	}
+	return holds, nil
+}
+
+// PlaceLegalHold puts an organization notebook under legal hold. A notebook already under hold keeps
+// its hold.
+func (s *retentionServiceImpl) PlaceLegalHold(organizationID, notebookID, reason, userID string) (*models.NotebookLegalHold, error) {
+	var hold models.NotebookLegalHold
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Notebook{}).Where("id = ? AND organization_id = ?", notebookID, organizationID).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to fetch notebook: %w", err)
+		}
+		if count == 0 {
+			return ErrNotebookNotFound
+		}
+
+		err := tx.Where("notebook_id = ? AND released_at IS NULL", notebookID).First(&hold).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to fetch legal hold: %w", err)
+		}
+		hold = models.NotebookLegalHold{
+			NotebookID:     notebookID,
+			OrganizationID: organizationID,
+			Reason:         strings.TrimSpace(reason),
+			PlacedBy:       userID,
+		}
+		if err := tx.Create(&hold).Error; err != nil {
+			return fmt.Errorf("failed to place legal hold: %w", err)
+		}
+		log.Info().Str("org_id", organizationID).Str("notebook_id", notebookID).Str("user_id", userID).Msg("Legal hold placed")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ReleaseLegalHold ends a notebook's legal hold
+func (s *retentionServiceImpl) ReleaseLegalHold(organizationID, notebookID, userID string) (*models.NotebookLegalHold, error) {
+	var hold models.NotebookLegalHold
+	if err := s.db.Where("organization_id = ? AND notebook_id = ? AND released_at IS NULL", organizationID, notebookID).
+		First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoLegalHold
+		}
+		return nil, fmt.Errorf("failed to fetch legal hold: %w", err)
+	}
+
+	releasedAt := s.now()
+	if err := s.db.Model(&hold).Updates(map[string]interface{}{"released_by": userID, "released_at": releasedAt}).Error; err != nil {
+		return nil, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+	hold.ReleasedBy, hold.ReleasedAt = userID, &releasedAt
+
+	log.Info().Str("org_id", organizationID).Str("notebook_id", notebookID).Str("user_id", userID).Msg("Legal hold released")
+	return &hold, nil
+}
+
+// ApplyPolicy archives the organization's notes that weren't changed for as long as its policy says,
+// through the note lifecycle so their reviews are withdrawn and they're unpublished when required
+func (s *retentionServiceImpl) ApplyPolicy(organizationID string) (int, error) {
+	policy, err := s.GetPolicy(organizationID)
+	if err != nil {
+		return 0, err
+	}
+	if policy.ArchiveAfterMonths == 0 {
+		return 0, nil
+	}
+
+	now := s.now()
+	cutoff := now.AddDate(0, -policy.ArchiveAfterMonths, 0)
+	archived := 0
+	lastID := ""
+	for {
+		var noteIDs []string
+		if err := s.db.Model(&models.Notes{}).
+			Where("organization_id = ? AND status <> ? AND updated_at < ? AND id > ?", organizationID, models.NoteStatusArchived, cutoff, lastID).
+			Order("id ASC").Limit(retentionBatchSize).Pluck("id", &noteIDs).Error; err != nil {
+			return archived, fmt.Errorf("failed to fetch untouched notes: %w", err)
+		}
+		for _, noteID := range noteIDs {
+			if _, err := s.lifecycle.ChangeStatus(noteID, retentionUserID, models.NoteStatusArchived); err != nil {
+				log.Warn().Err(err).Str("org_id", organizationID).Str("note_id", noteID).Msg("Failed to archive untouched note")
+				continue
+			}
+			archived++
+		}
+		if len(noteIDs) < retentionBatchSize {
+			break
+		}
+		lastID = noteIDs[len(noteIDs)-1]
+	}
+
+	if policy.ID != 0 {
+		if err := s.db.Model(policy).Update("last_applied_at", now).Error; err != nil {
+			log.Warn().Err(err).Str("org_id", organizationID).Msg("Failed to record when the retention policy was applied")
+		}
+	}
+	if archived > 0 {
+		log.Info().Str("org_id", organizationID).Int("notes", archived).Msg("Archived untouched notes")
+	}
+	return archived, nil
+}
+
+// ApplyAll applies the retention policy of every organization that has one
+func (s *retentionServiceImpl) ApplyAll() (*RetentionReport, error) {
+	var organizationIDs []string
+	if err := s.db.Model(&models.OrganizationRetentionPolicy{}).Where("archive_after_months > ?", 0).
+		Pluck("organization_id", &organizationIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch retention policies: %w", err)
+	}
+
+	report := &RetentionReport{}
+	for _, organizationID := range organizationIDs {
+		archived, err := s.ApplyPolicy(organizationID)
+		report.Archived += archived
+		if err != nil {
+			log.Error().Err(err).Str("org_id", organizationID).Msg("Failed to apply retention policy")
+			continue
+		}
+		report.Organizations++
+	}
+	return report, nil
+}
+
+// CheckNotebookLegalHold returns ErrLegalHold when a notebook is under legal hold
+func CheckNotebookLegalHold(tx *gorm.DB, notebookID string) error {
+	return checkLegalHold(tx, "?", notebookID)
+}
+
+// CheckChapterLegalHold returns ErrLegalHold when a chapter's notebook is under legal hold
+func CheckChapterLegalHold(tx *gorm.DB, chapterID string) error {
+	return checkLegalHold(tx, "(SELECT notebook_id FROM chapters WHERE id = ?)", chapterID)
+}
+
+// CheckNoteLegalHold returns ErrLegalHold when a note's notebook is under legal hold
+func CheckNoteLegalHold(tx *gorm.DB, noteID string) error {
+	return checkLegalHold(tx, "(SELECT chapters.notebook_id FROM notes JOIN chapters ON chapters.id = notes.chapter_id WHERE notes.id = ?)", noteID)
+}
+
+// checkLegalHold returns ErrLegalHold when the notebook the expression selects is under legal hold
+func checkLegalHold(tx *gorm.DB, notebookExpr string, arg string) error {
+	var count int64
+	if err := tx.Model(&models.NotebookLegalHold{}).
+		Where("notebook_id = "+notebookExpr+" AND released_at IS NULL", arg).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check legal hold: %w", err)
+	}
+	if count > 0 {
+		return ErrLegalHold
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestRetentionService creates a retention service on an in-memory database
+func setupTestRetentionService(t *testing.T) *retentionServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.NoteReview{},
+		&models.OrganizationPublishingSettings{}, &models.OrganizationRetentionPolicy{}, &models.NotebookLegalHold{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &retentionServiceImpl{
+		db:        db,
+		lifecycle: &noteLifecycleServiceImpl{db: db},
+		now:       func() time.Time { return time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC) },
+	}
+}
+
+func TestRetention_ArchivesUntouchedNotes(t *testing.T) {
+	service := setupTestRetentionService(t)
+	orgID := "org_1"
+	stale := createLifecycleNote(t, service.db, &orgID)
+	recent := createLifecycleNote(t, service.db, &orgID)
+	personal := createLifecycleNote(t, service.db, nil)
+
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, id := range []string{stale.ID, personal.ID} {
+		require.NoError(t, service.db.Model(&models.Notes{}).Where("id = ?", id).UpdateColumn("updated_at", old).Error)
+	}
+
+	archived, err := service.ApplyPolicy(orgID)
+	require.NoError(t, err)
+	assert.Zero(t, archived, "Organizations without a policy keep their notes")
+
+	_, err = service.SavePolicy(orgID, maxArchiveAfterMonths+1, "user_admin")
+	assert.ErrorIs(t, err, ErrInvalidRetentionPolicy)
+	policy, err := service.SavePolicy(orgID, 12, "user_admin")
+	require.NoError(t, err)
+	assert.Equal(t, 12, policy.ArchiveAfterMonths)
+
+	report, err := service.ApplyAll()
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Organizations)
+	assert.Equal(t, 1, report.Archived)
+
+	var statuses []models.Notes
+	require.NoError(t, service.db.Select("id", "status").Find(&statuses).Error)
+	for _, note := range statuses {
+		if note.ID == stale.ID {
+			assert.Equal(t, models.NoteStatusArchived, note.Status)
+		} else {
+			assert.NotEqual(t, models.NoteStatusArchived, note.Status, "Only untouched organization notes are archived")
+		}
+	}
+	assert.NotEqual(t, recent.ID, stale.ID)
+
+	saved, err := service.GetPolicy(orgID)
+	require.NoError(t, err)
+	require.NotNil(t, saved.LastAppliedAt)
+}
+
+func TestRetention_LegalHolds(t *testing.T) {
+	service := setupTestRetentionService(t)
+	orgID := "org_1"
+	note := createLifecycleNote(t, service.db, &orgID)
+	var chapter models.Chapter
+	require.NoError(t, service.db.First(&chapter, "id = ?", note.ChapterID).Error)
+
+	_, err := service.PlaceLegalHold("org_2", chapter.NotebookID, "", "user_admin")
+	assert.ErrorIs(t, err, ErrNotebookNotFound, "Holds are only placed on the organization's notebooks")
+
+	hold, err := service.PlaceLegalHold(orgID, chapter.NotebookID, " Audit 2026 ", "user_admin")
+	require.NoError(t, err)
+	assert.Equal(t, "Audit 2026", hold.Reason)
+	again, err := service.PlaceLegalHold(orgID, chapter.NotebookID, "Another", "user_admin")
+	require.NoError(t, err)
+	assert.Equal(t, hold.ID, again.ID, "A notebook has one active hold")
+
+	assert.ErrorIs(t, CheckNotebookLegalHold(service.db, chapter.NotebookID), ErrLegalHold)
+	assert.ErrorIs(t, CheckChapterLegalHold(service.db, chapter.ID), ErrLegalHold)
+	assert.ErrorIs(t, CheckNoteLegalHold(service.db, note.ID), ErrLegalHold)
+
+	holds, err := service.ListLegalHolds(orgID)
+	require.NoError(t, err)
+	assert.Len(t, holds, 1)
+
+	released, err := service.ReleaseLegalHold(orgID, chapter.NotebookID, "user_admin")
+	require.NoError(t, err)
+	assert.Equal(t, "user_admin", released.ReleasedBy)
+	assert.NoError(t, CheckNoteLegalHold(service.db, note.ID))
+
+	_, err = service.ReleaseLegalHold(orgID, chapter.NotebookID, "user_admin")
+	assert.ErrorIs(t, err, ErrNoLegalHold)
+}
@@ -0,0 +1,669 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Scheduling link time zones shouldn't depend on the host's zoneinfo
+
+	"github.com/lucsky/cuid"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidAvailabilityRange is returned for an availability range that's empty or too long
+	ErrInvalidAvailabilityRange = errors.New("invalid availability range")
+	// ErrSchedulingLinkNotFound is returned when a scheduling link doesn't exist, isn't the user's or,
+	// for bookings, isn't active
+	ErrSchedulingLinkNotFound = errors.New("scheduling link not found")
+	// ErrInvalidSchedulingLink is returned, wrapped with the reason, for invalid scheduling link settings
+	ErrInvalidSchedulingLink = errors.New("invalid scheduling link")
+	// ErrSchedulingSlugTaken is returned when another scheduling link uses the slug
+	ErrSchedulingSlugTaken = errors.New("scheduling link slug is taken")
+	// ErrInvalidBookingRequest is returned, wrapped with the reason, for an invalid booking request
+	ErrInvalidBookingRequest = errors.New("invalid booking request")
+	// ErrBookingSlotUnavailable is returned when the requested time isn't one of the link's free slots
+	ErrBookingSlotUnavailable = errors.New("the requested time is not available")
+	// ErrTooManyBookingRequests is returned when a link, or one email on it, has too many pending requests
+	ErrTooManyBookingRequests = errors.New("too many pending booking requests")
+	// ErrBookingRequestNotFound is returned when a booking request doesn't exist or isn't the user's
+	ErrBookingRequestNotFound = errors.New("booking request not found")
+	// ErrBookingRequestAnswered is returned when confirming or declining a request that isn't pending
+	ErrBookingRequestAnswered = errors.New("booking request was already answered")
+)
+
+const (
+	defaultAvailabilityRange   = 7 * 24 * time.Hour
+	maxAvailabilityRange       = 62 * 24 * time.Hour
+	maxSchedulingDaysAhead     = 60
+	maxSchedulingDuration      = 8 * 60
+	maxBookingSlots            = 500
+	maxPendingBookingsPerLink  = 50
+	maxPendingBookingsPerEmail = 3
+	maxBookingNameLength       = 100
+	maxBookingMessageLength    = 2000
+	minSchedulingSlugLength    = 3
+)
+
+// TimeWindow is a span of time, its end excluded
+type TimeWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Availability is when the user is busy and free between two times, by their synced calendar events
+// and confirmed bookings
+type Availability struct {
+	From time.Time    `json:"from"`
+	To   time.Time    `json:"to"`
+	Busy []TimeWindow `json:"busy"`
+	Free []TimeWindow `json:"free"`
+}
+
+// SchedulingLinkRequest creates or updates a scheduling link. Fields left out keep their value, or the
+// default for a new link.
+type SchedulingLinkRequest struct {
+	Slug             *string `json:"slug"`
+	Title            *string `json:"title"`
+	Description      *string `json:"description"`
+	DurationMinutes  *int    `json:"durationMinutes"`
+	Timezone         *string `json:"timezone"`
+	DayStart         *string `json:"dayStart"` // HH:MM
+	DayEnd           *string `json:"dayEnd"`   // HH:MM
+	Weekdays         *string `json:"weekdays"` // Comma separated, 0 is Sunday
+	MinNoticeMinutes *int    `json:"minNoticeMinutes"`
+	DaysAhead        *int    `json:"daysAhead"`
+	Active           *bool   `json:"active"`
+}
+
+// PublicSchedulingLink is what the booking page shows of a scheduling link: its free slots and none of
+// the owner's events
+type PublicSchedulingLink struct {
+	Slug            string       `json:"slug"`
+	Title           string       `json:"title"`
+	Description     string       `json:"description,omitempty"`
+	DurationMinutes int          `json:"durationMinutes"`
+	Timezone        string       `json:"timezone"`
+	Slots           []TimeWindow `json:"slots"`
+}
+
+// BookingInput is a meeting requested on a booking page
+type BookingInput struct {
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Message   string    `json:"message"`
+	StartTime time.Time `json:"startTime"`
+}
+
+// SchedulingService interface defines methods for calendar availability and public scheduling links
+type SchedulingService interface {
+	GetAvailability(ctx context.Context, clerkUserID string, from, to time.Time) (*Availability, error)
+	ListLinks(ctx context.Context, clerkUserID string) ([]models.SchedulingLink, error)
+	CreateLink(ctx context.Context, clerkUserID string, req SchedulingLinkRequest) (*models.SchedulingLink, error)
+	UpdateLink(ctx context.Context, clerkUserID, linkID string, req SchedulingLinkRequest) (*models.SchedulingLink, error)
+	DeleteLink(ctx context.Context, clerkUserID, linkID string) error
+	GetPublicLink(ctx context.Context, slug string) (*PublicSchedulingLink, error)
+	RequestBooking(ctx context.Context, slug string, input BookingInput) (*models.BookingRequest, error)
+	ListBookingRequests(ctx context.Context, clerkUserID, status string) ([]models.BookingRequest, error)
+	RespondToBookingRequest(ctx context.Context, clerkUserID, requestID string, confirm bool) (*models.BookingRequest, error)
+}
+
+// schedulingServiceImpl implements the SchedulingService interface
+type schedulingServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewSchedulingService creates a new SchedulingService instance
+func NewSchedulingService() SchedulingService {
+	return &schedulingServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// GetAvailability returns the user's busy and free windows between two times, from now for a week by
+// default
+func (s *schedulingServiceImpl) GetAvailability(ctx context.Context, clerkUserID string, from, to time.Time) (*Availability, error) {
+	if from.IsZero() {
+		from = s.now()
+	}
+	if to.IsZero() {
+		to = from.Add(defaultAvailabilityRange)
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidAvailabilityRange)
+	}
+	if to.Sub(from) > maxAvailabilityRange {
+		return nil, fmt.Errorf("%w: the range can be at most %d days", ErrInvalidAvailabilityRange, int(maxAvailabilityRange.Hours()/24))
+	}
+
+	busy, err := s.busyWindows(ctx, clerkUserID, from, to, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Availability{From: from, To: to, Busy: busy, Free: freeWindows(from, to, busy)}, nil
+}
+
+// ListLinks returns the user's scheduling links, newest first
+func (s *schedulingServiceImpl) ListLinks(ctx context.Context, clerkUserID string) ([]models.SchedulingLink, error) {
+	var links []models.SchedulingLink
+	if err := s.db.WithContext(ctx).Where("clerk_user_id = ?", clerkUserID).Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch scheduling links: %w", err)
+	}
+	return links, nil
+}
+
+// CreateLink creates a scheduling link. Without a slug, one is made from the title with a random suffix.
+func (s *schedulingServiceImpl) CreateLink(ctx context.Context, clerkUserID string, req SchedulingLinkRequest) (*models.SchedulingLink, error) {
+	if req.Title == nil || strings.TrimSpace(*req.Title) == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidSchedulingLink)
+	}
+
+	// Day hours are in the user's timezone unless the link gives one
+	timezone := "UTC"
+	if preferences, err := findUserPreferences(ctx, s.db, clerkUserID); err == nil && preferences.Timezone != "" {
+		timezone = preferences.Timezone
+	}
+	link := models.SchedulingLink{
+		ClerkUserID:      clerkUserID,
+		DurationMinutes:  30,
+		Timezone:         timezone,
+		DayStart:         "09:00",
+		DayEnd:           "17:00",
+		Weekdays:         "1,2,3,4,5",
+		MinNoticeMinutes: 240,
+		DaysAhead:        14,
+		Active:           true,
+	}
+	if err := applySchedulingLinkRequest(&link, req); err != nil {
+		return nil, err
+	}
+	if link.Slug == "" {
+		link.Slug = models.Slugify(link.Title) + "-" + strings.ToLower(cuid.Slug())
+	}
+	if err := s.checkSlugAvailable(ctx, link.Slug, ""); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Create(&link).Error; err != nil {
+		return nil, fmt.Errorf("failed to create scheduling link: %w", err)
+	}
+	return &link, nil
+}
+
+// UpdateLink changes the settings of one of the user's scheduling links
+func (s *schedulingServiceImpl) UpdateLink(ctx context.Context, clerkUserID, linkID string, req SchedulingLinkRequest) (*models.SchedulingLink, error) {
+	link, err := s.findLink(ctx, clerkUserID, linkID)
+	if err != nil {
+		return nil, err
+	}
+	slug := link.Slug
+	if err := applySchedulingLinkRequest(link, req); err != nil {
+		return nil, err
+	}
+	if link.Slug != slug {
+		if err := s.checkSlugAvailable(ctx, link.Slug, link.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Save(link).Error; err != nil {
+		return nil, fmt.Errorf("failed to update scheduling link: %w", err)
+	}
+	return link, nil
+}
+
+// DeleteLink deletes one of the user's scheduling links with its booking requests
+func (s *schedulingServiceImpl) DeleteLink(ctx context.Context, clerkUserID, linkID string) error {
+	link, err := s.findLink(ctx, clerkUserID, linkID)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("scheduling_link_id = ?", link.ID).Delete(&models.BookingRequest{}).Error; err != nil {
+			return fmt.Errorf("failed to delete booking requests: %w", err)
+		}
+		if err := tx.Delete(link).Error; err != nil {
+			return fmt.Errorf("failed to delete scheduling link: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetPublicLink returns an active scheduling link with its free slots
+func (s *schedulingServiceImpl) GetPublicLink(ctx context.Context, slug string) (*PublicSchedulingLink, error) {
+	link, err := s.findActiveLink(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	slots, err := s.bookableSlots(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	return &PublicSchedulingLink{
+		Slug:            link.Slug,
+		Title:           link.Title,
+		Description:     link.Description,
+		DurationMinutes: link.DurationMinutes,
+		Timezone:        link.Timezone,
+		Slots:           slots,
+	}, nil
+}
+
+// RequestBooking records a request for one of a link's free slots, for the link's owner to confirm. The
+// slot is held while the request is pending.
+func (s *schedulingServiceImpl) RequestBooking(ctx context.Context, slug string, input BookingInput) (*models.BookingRequest, error) {
+	link, err := s.findActiveLink(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > maxBookingNameLength {
+		return nil, fmt.Errorf("%w: name is required and can be at most %d characters", ErrInvalidBookingRequest, maxBookingNameLength)
+	}
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return nil, fmt.Errorf("%w: a valid email is required", ErrInvalidBookingRequest)
+	}
+	message := strings.TrimSpace(input.Message)
+	if len(message) > maxBookingMessageLength {
+		return nil, fmt.Errorf("%w: message can be at most %d characters", ErrInvalidBookingRequest, maxBookingMessageLength)
+	}
+
+	var pending, pendingForEmail int64
+	if err := s.db.WithContext(ctx).Model(&models.BookingRequest{}).
+		Where("scheduling_link_id = ? AND status = ?", link.ID, models.BookingRequestStatusPending).
+		Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to count booking requests: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&models.BookingRequest{}).
+		Where("scheduling_link_id = ? AND status = ? AND email = ?", link.ID, models.BookingRequestStatusPending, email).
+		Count(&pendingForEmail).Error; err != nil {
+		return nil, fmt.Errorf("failed to count booking requests: %w", err)
+	}
+	if pending >= maxPendingBookingsPerLink || pendingForEmail >= maxPendingBookingsPerEmail {
+		return nil, ErrTooManyBookingRequests
+	}
+
+	slots, err := s.bookableSlots(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	var slot *TimeWindow
+	for i := range slots {
+		if slots[i].Start.Equal(input.StartTime) {
+			slot = &slots[i]
+			break
+		}
+	}
+	if slot == nil {
+		return nil, ErrBookingSlotUnavailable
+	}
+
+	request := models.BookingRequest{
+		SchedulingLinkID: link.ID,
+		ClerkUserID:      link.ClerkUserID,
+		Name:             name,
+		Email:            email,
+		Message:          message,
+		StartTime:        slot.Start.UTC(),
+		EndTime:          slot.End.UTC(),
+		Status:           models.BookingRequestStatusPending,
+	}
+	if err := s.db.WithContext(ctx).Create(&request).Error; err != nil {
+		return nil, fmt.Errorf("failed to create booking request: %w", err)
+	}
+
+	log.Info().
+		Str("scheduling_link_id", link.ID).
+		Str("booking_request_id", request.ID).
+		Time("start_time", request.StartTime).
+		Msg("Booking requested")
+
+	return &request, nil
+}
+
+// ListBookingRequests returns the booking requests on the user's links, soonest first, only those with
+// the status when given
+func (s *schedulingServiceImpl) ListBookingRequests(ctx context.Context, clerkUserID, status string) ([]models.BookingRequest, error) {
+	query := s.db.WithContext(ctx).Preload("SchedulingLink").Where("clerk_user_id = ?", clerkUserID)
+	if status != "" {
+		switch status {
+		case models.BookingRequestStatusPending, models.BookingRequestStatusConfirmed, models.BookingRequestStatusDeclined:
+			query = query.Where("status = ?", status)
+		default:
+			return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidBookingRequest, status)
+		}
+	}
+
+	var requests []models.BookingRequest
+	if err := query.Order("start_time ASC").Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch booking requests: %w", err)
+	}
+	return requests, nil
+}
+
+// RespondToBookingRequest confirms or declines a pending booking request on one of the user's links.
+// Declining frees the slot.
+func (s *schedulingServiceImpl) RespondToBookingRequest(ctx context.Context, clerkUserID, requestID string, confirm bool) (*models.BookingRequest, error) {
+	var request models.BookingRequest
+	if err := s.db.WithContext(ctx).Where("id = ? AND clerk_user_id = ?", requestID, clerkUserID).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch booking request: %w", err)
+	}
+	if request.Status != models.BookingRequestStatusPending {
+		return nil, ErrBookingRequestAnswered
+	}
+
+	status := models.BookingRequestStatusDeclined
+	if confirm {
+		status = models.BookingRequestStatusConfirmed
+	}
+	now := s.now()
+	result := s.db.WithContext(ctx).Model(&models.BookingRequest{}).
+		Where("id = ? AND status = ?", request.ID, models.BookingRequestStatusPending).
+		Updates(map[string]interface{}{"status": status, "responded_at": now})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update booking request: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrBookingRequestAnswered
+	}
+
+	request.Status = status
+	request.RespondedAt = &now
+	return &request, nil
+}
+
+// findLink returns one of the user's scheduling links
+func (s *schedulingServiceImpl) findLink(ctx context.Context, clerkUserID, linkID string) (*models.SchedulingLink, error) {
+	var link models.SchedulingLink
+	if err := s.db.WithContext(ctx).Where("id = ? AND clerk_user_id = ?", linkID, clerkUserID).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSchedulingLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch scheduling link: %w", err)
+	}
+	return &link, nil
+}
+
+// findActiveLink returns the active scheduling link with the slug
+func (s *schedulingServiceImpl) findActiveLink(ctx context.Context, slug string) (*models.SchedulingLink, error) {
+	var link models.SchedulingLink
+	if err := s.db.WithContext(ctx).Where("slug = ? AND active = ?", strings.ToLower(slug), true).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSchedulingLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch scheduling link: %w", err)
+	}
+	return &link, nil
+}
+
+// checkSlugAvailable returns ErrSchedulingSlugTaken when a link other than the excluded one uses the slug
+func (s *schedulingServiceImpl) checkSlugAvailable(ctx context.Context, slug, excludeID string) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.SchedulingLink{}).
+		Where("slug = ? AND id <> ?", slug, excludeID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check scheduling link slug: %w", err)
+	}
+	if count > 0 {
+		return ErrSchedulingSlugTaken
+	}
+	return nil
+}
+
+// bookableSlots returns a link's free slots in its time zone: its day hours on its weekdays, from its
+// minimum notice to its days ahead, that overlap no event, confirmed booking or pending request
+func (s *schedulingServiceImpl) bookableSlots(ctx context.Context, link *models.SchedulingLink) ([]TimeWindow, error) {
+	location, err := time.LoadLocation(link.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	weekdays, err := parseWeekdays(link.Weekdays)
+	if err != nil {
+		return nil, err
+	}
+	dayStart, err := parseTimeOfDay(link.DayStart)
+	if err != nil {
+		return nil, err
+	}
+	dayEnd, err := parseTimeOfDay(link.DayEnd)
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Duration(link.DurationMinutes) * time.Minute
+
+	now := s.now()
+	earliest := now.Add(time.Duration(link.MinNoticeMinutes) * time.Minute)
+	horizon := now.AddDate(0, 0, link.DaysAhead)
+	if !horizon.After(earliest) {
+		return []TimeWindow{}, nil
+	}
+	busy, err := s.busyWindows(ctx, link.ClerkUserID, earliest, horizon, true)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := []TimeWindow{}
+	local := earliest.In(location)
+	for day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location); day.Before(horizon); day = day.AddDate(0, 0, 1) {
+		if !weekdays[day.Weekday()] {
+			continue
+		}
+		end := day.Add(dayEnd)
+		for start := day.Add(dayStart); !start.Add(duration).After(end); start = start.Add(duration) {
+			slot := TimeWindow{Start: start, End: start.Add(duration)}
+			if slot.Start.Before(earliest) || slot.End.After(horizon) || overlapsAny(busy, slot) {
+				continue
+			}
+			slots = append(slots, slot)
+			if len(slots) >= maxBookingSlots {
+				return slots, nil
+			}
+		}
+	}
+	return slots, nil
+}
+
+// busyWindows returns the merged windows the user's calendar events and confirmed bookings take between
+// two times, and pending booking requests when they hold their slots
+func (s *schedulingServiceImpl) busyWindows(ctx context.Context, clerkUserID string, from, to time.Time, holdPending bool) ([]TimeWindow, error) {
+	var spans []struct {
+		StartTime time.Time
+		EndTime   time.Time
+	}
+	if err := s.db.WithContext(ctx).Model(&models.CalendarEvent{}).
+		Select("calendar_events.start_time, calendar_events.end_time").
+		Joins("JOIN calendars ON calendars.id = calendar_events.calendar_id").
+		Where("calendars.clerk_user_id = ? AND calendar_events.is_deleted = ?", clerkUserID, false).
+		Where("calendar_events.start_time < ? AND calendar_events.end_time > ?", to, from).
+		Scan(&spans).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar events: %w", err)
+	}
+	windows := make([]TimeWindow, len(spans))
+	for i, span := range spans {
+		windows[i] = TimeWindow{Start: span.StartTime, End: span.EndTime}
+	}
+
+	statuses := []string{models.BookingRequestStatusConfirmed}
+	if holdPending {
+		statuses = append(statuses, models.BookingRequestStatusPending)
+	}
+	var bookings []models.BookingRequest
+	if err := s.db.WithContext(ctx).Select("start_time", "end_time").
+		Where("clerk_user_id = ? AND status IN ?", clerkUserID, statuses).
+		Where("start_time < ? AND end_time > ?", to, from).
+		Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch booking requests: %w", err)
+	}
+	for _, booking := range bookings {
+		windows = append(windows, TimeWindow{Start: booking.StartTime, End: booking.EndTime})
+	}
+
+	return mergeWindows(windows, from, to), nil
+}
+
+// mergeWindows clips windows to a range and merges the ones that overlap or touch, in order
+func mergeWindows(windows []TimeWindow, from, to time.Time) []TimeWindow {
+	clipped := make([]TimeWindow, 0, len(windows))
+	for _, window := range windows {
+		if window.Start.Before(from) {
+			window.Start = from
+		}
+		if window.End.After(to) {
+			window.End = to
+		}
+		if window.End.After(window.Start) {
+			clipped = append(clipped, window)
+		}
+	}
+	sort.Slice(clipped, func(i, j int) bool { return clipped[i].Start.Before(clipped[j].Start) })
+
+	merged := []TimeWindow{}
+	for _, window := range clipped {
+		if last := len(merged) - 1; last >= 0 && !window.Start.After(merged[last].End) {
+			if window.End.After(merged[last].End) {
+				merged[last].End = window.End
+			}
+			continue
+		}
+		merged = append(merged, window)
+	}
+	return merged
+}
+
+// freeWindows returns the gaps between merged busy windows in a range
+func freeWindows(from, to time.Time, busy []TimeWindow) []TimeWindow {
+	free := []TimeWindow{}
+	cursor := from
+	for _, window := range busy {
+		if window.Start.After(cursor) {
+			free = append(free, TimeWindow{Start: cursor, End: window.Start})
+		}
+		if window.End.After(cursor) {
+			cursor = window.End
+		}
+	}
+	if to.After(cursor) {
+		free = append(free, TimeWindow{Start: cursor, End: to})
+	}
+	return free
+}
+
+// overlapsAny reports whether a window overlaps any of the busy windows
+func overlapsAny(busy []TimeWindow, window TimeWindow) bool {
+	for _, b := range busy {
+		if b.Start.Before(window.End) && window.Start.Before(b.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// applySchedulingLinkRequest validates a request and sets the fields it gives on a link
+func applySchedulingLinkRequest(link *models.SchedulingLink, req SchedulingLinkRequest) error {
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" || len(title) > 200 {
+			return fmt.Errorf("%w: title is required and can be at most 200 characters", ErrInvalidSchedulingLink)
+		}
+		link.Title = title
+	}
+	if req.Description != nil {
+		link.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Slug != nil {
+		slug := strings.ToLower(strings.TrimSpace(*req.Slug))
+		if len(slug) < minSchedulingSlugLength || models.Slugify(slug) != slug {
+			return fmt.Errorf("%w: slug must be at least %d lowercase letters, digits and dashes", ErrInvalidSchedulingLink, minSchedulingSlugLength)
+		}
+		link.Slug = slug
+	}
+	if req.DurationMinutes != nil {
+		if *req.DurationMinutes < 5 || *req.DurationMinutes > maxSchedulingDuration {
+			return fmt.Errorf("%w: duration must be between 5 and %d minutes", ErrInvalidSchedulingLink, maxSchedulingDuration)
+		}
+		link.DurationMinutes = *req.DurationMinutes
+	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			return fmt.Errorf("%w: unknown time zone %q", ErrInvalidSchedulingLink, *req.Timezone)
+		}
+		link.Timezone = *req.Timezone
+	}
+	if req.DayStart != nil {
+		link.DayStart = strings.TrimSpace(*req.DayStart)
+	}
+	if req.DayEnd != nil {
+		link.DayEnd = strings.TrimSpace(*req.DayEnd)
+	}
+	if req.Weekdays != nil {
+		link.Weekdays = strings.ReplaceAll(*req.Weekdays, " ", "")
+	}
+	if req.MinNoticeMinutes != nil {
+		if *req.MinNoticeMinutes < 0 {
+			return fmt.Errorf("%w: minimum notice can't be negative", ErrInvalidSchedulingLink)
+		}
+		link.MinNoticeMinutes = *req.MinNoticeMinutes
+	}
+	if req.DaysAhead != nil {
+		if *req.DaysAhead < 1 || *req.DaysAhead > maxSchedulingDaysAhead {
+			return fmt.Errorf("%w: days ahead must be between 1 and %d", ErrInvalidSchedulingLink, maxSchedulingDaysAhead)
+		}
+		link.DaysAhead = *req.DaysAhead
+	}
+	if req.Active != nil {
+		link.Active = *req.Active
+	}
+
+	dayStart, err := parseTimeOfDay(link.DayStart)
+	if err != nil {
+		return err
+	}
+	dayEnd, err := parseTimeOfDay(link.DayEnd)
+	if err != nil {
+		return err
+	}
+	if dayEnd-dayStart < time.Duration(link.DurationMinutes)*time.Minute {
+		return fmt.Errorf("%w: the day must be at least one meeting long", ErrInvalidSchedulingLink)
+	}
+	if _, err := parseWeekdays(link.Weekdays); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseTimeOfDay parses an HH:MM time of day as the time since midnight
+func parseTimeOfDay(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q isn't an HH:MM time", ErrInvalidSchedulingLink, value)
+	}
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// parseWeekdays parses comma separated weekday numbers, 0 being Sunday
+func parseWeekdays(value string) (map[time.Weekday]bool, error) {
+	weekdays := map[time.Weekday]bool{}
+	for _, part := range strings.Split(value, ",") {
+		day, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || day < 0 || day > 6 {
+			return nil, fmt.Errorf("%w: weekdays must be numbers from 0 (Sunday) to 6", ErrInvalidSchedulingLink)
+		}
+		weekdays[time.Weekday(day)] = true
+	}
+	return weekdays, nil
+}
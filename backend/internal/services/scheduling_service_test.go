@@ -0,0 +1,170 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// schedulingTestNow is a Monday morning
+var schedulingTestNow = time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+
+// setupTestSchedulingService creates a scheduling service where user_1 has meetings on Monday from 10 to
+// 11 and 10:30 to 12, and user_2 is busy all Monday afternoon
+func setupTestSchedulingService(t *testing.T) *schedulingServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Calendar{}, &models.CalendarEvent{}, &models.SchedulingLink{}, &models.BookingRequest{}, &models.UserPreferences{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	calendars := []models.Calendar{
+		{ID: "cal_1", ClerkUserID: "user_1", RecallCalendarID: "recall_cal_1", Platform: "google_calendar"},
+		{ID: "cal_2", ClerkUserID: "user_2", RecallCalendarID: "recall_cal_2", Platform: "google_calendar"},
+	}
+	require.NoError(t, db.Create(&calendars).Error)
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 3, 10, hour, minute, 0, 0, time.UTC)
+	}
+	events := []models.CalendarEvent{
+		{CalendarID: "cal_1", RecallEventID: "re_1", Title: "Planning", StartTime: at(10, 0), EndTime: at(11, 0)},
+		{CalendarID: "cal_1", RecallEventID: "re_2", Title: "Review", StartTime: at(10, 30), EndTime: at(12, 0)},
+		{CalendarID: "cal_1", RecallEventID: "re_3", Title: "Cancelled", StartTime: at(14, 0), EndTime: at(15, 0), IsDeleted: true},
+		{CalendarID: "cal_2", RecallEventID: "re_4", Title: "Offsite", StartTime: at(12, 0), EndTime: at(18, 0)},
+	}
+	require.NoError(t, db.Create(&events).Error)
+
+	return &schedulingServiceImpl{db: db, now: func() time.Time { return schedulingTestNow }}
+}
+
+func TestGetAvailability_MergesBusyWindows(t *testing.T) {
+	service := setupTestSchedulingService(t)
+
+	from := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 10, 17, 0, 0, 0, time.UTC)
+	availability, err := service.GetAvailability(context.Background(), "user_1", from, to)
+	require.NoError(t, err)
+
+	require.Len(t, availability.Busy, 1, "Overlapping events should merge, deleted ones don't count")
+	assert.Equal(t, time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC), availability.Busy[0].Start.UTC())
+	assert.Equal(t, time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC), availability.Busy[0].End.UTC())
+
+	require.Len(t, availability.Free, 2)
+	assert.Equal(t, from, availability.Free[0].Start)
+	assert.Equal(t, to, availability.Free[1].End)
+
+	_, err = service.GetAvailability(context.Background(), "user_1", to, from)
+	assert.ErrorIs(t, err, ErrInvalidAvailabilityRange)
+	_, err = service.GetAvailability(context.Background(), "user_1", from, from.AddDate(0, 3, 0))
+	assert.ErrorIs(t, err, ErrInvalidAvailabilityRange)
+}
+
+func TestSchedulingLink_Validation(t *testing.T) {
+	service := setupTestSchedulingService(t)
+	ctx := context.Background()
+
+	title, slug := "Intro call", "intro-call"
+	link, err := service.CreateLink(ctx, "user_1", SchedulingLinkRequest{Title: &title, Slug: &slug})
+	require.NoError(t, err)
+	assert.Equal(t, "intro-call", link.Slug)
+	assert.Equal(t, 30, link.DurationMinutes)
+
+	_, err = service.CreateLink(ctx, "user_2", SchedulingLinkRequest{Title: &title, Slug: &slug})
+	assert.ErrorIs(t, err, ErrSchedulingSlugTaken)
+
+	require.NoError(t, service.db.Create(&models.UserPreferences{ClerkUserID: "user_2", Timezone: "Asia/Kolkata"}).Error)
+	generated, err := service.CreateLink(ctx, "user_2", SchedulingLinkRequest{Title: &title})
+	require.NoError(t, err)
+	assert.NotEqual(t, "intro-call", generated.Slug)
+	assert.Equal(t, "Asia/Kolkata", generated.Timezone, "New links should default to the user's timezone")
+
+	badSlug, badZone, badWeekdays := "Intro Call!", "Mars/Olympus", "1,9"
+	_, err = service.CreateLink(ctx, "user_1", SchedulingLinkRequest{Title: &title, Slug: &badSlug})
+	assert.ErrorIs(t, err, ErrInvalidSchedulingLink)
+	_, err = service.CreateLink(ctx, "user_1", SchedulingLinkRequest{Title: &title, Timezone: &badZone})
+	assert.ErrorIs(t, err, ErrInvalidSchedulingLink)
+	_, err = service.CreateLink(ctx, "user_1", SchedulingLinkRequest{Title: &title, Weekdays: &badWeekdays})
+	assert.ErrorIs(t, err, ErrInvalidSchedulingLink)
+
+	_, err = service.UpdateLink(ctx, "user_2", link.ID, SchedulingLinkRequest{Title: &title})
+	assert.ErrorIs(t, err, ErrSchedulingLinkNotFound, "Other users' links can't be changed")
+}
+
+func TestSchedulingLink_SlotsAndBookings(t *testing.T) {
+	service := setupTestSchedulingService(t)
+	ctx := context.Background()
+
+	title, slug, duration, notice, days := "Intro call", "intro-call", 60, 0, 1
+	link, err := service.CreateLink(ctx, "user_1", SchedulingLinkRequest{
+		Title: &title, Slug: &slug, DurationMinutes: &duration, MinNoticeMinutes: &notice, DaysAhead: &days,
+	})
+	require.NoError(t, err)
+
+	public, err := service.GetPublicLink(ctx, "intro-call")
+	require.NoError(t, err)
+	var starts []int
+	for _, slot := range public.Slots {
+		starts = append(starts, slot.Start.Hour())
+	}
+	assert.Equal(t, []int{9, 12, 13, 14, 15, 16}, starts, "Slots should skip the user's meetings and keep to the day hours")
+
+	slot := time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC)
+	request, err := service.RequestBooking(ctx, "intro-call", BookingInput{Name: "Ada", Email: "Ada@Example.com", StartTime: slot})
+	require.NoError(t, err)
+	assert.Equal(t, models.BookingRequestStatusPending, request.Status)
+	assert.Equal(t, "ada@example.com", request.Email)
+	assert.Equal(t, slot.Add(time.Hour), request.EndTime.UTC())
+
+	_, err = service.RequestBooking(ctx, "intro-call", BookingInput{Name: "Bob", Email: "bob@example.com", StartTime: slot})
+	assert.ErrorIs(t, err, ErrBookingSlotUnavailable, "A pending request should hold its slot")
+	_, err = service.RequestBooking(ctx, "intro-call", BookingInput{Name: "Bob", Email: "bob@example.com", StartTime: slot.Add(30 * time.Minute)})
+	assert.ErrorIs(t, err, ErrBookingSlotUnavailable, "Only the link's slots can be booked")
+	_, err = service.RequestBooking(ctx, "intro-call", BookingInput{Name: "Bob", Email: "not an email", StartTime: slot.Add(time.Hour)})
+	assert.ErrorIs(t, err, ErrInvalidBookingRequest)
+
+	_, err = service.RespondToBookingRequest(ctx, "user_2", request.ID, true)
+	assert.ErrorIs(t, err, ErrBookingRequestNotFound)
+	confirmed, err := service.RespondToBookingRequest(ctx, "user_1", request.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, models.BookingRequestStatusConfirmed, confirmed.Status)
+	_, err = service.RespondToBookingRequest(ctx, "user_1", request.ID, false)
+	assert.ErrorIs(t, err, ErrBookingRequestAnswered)
+
+	from := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	availability, err := service.GetAvailability(ctx, "user_1", from, from.Add(8*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, availability.Busy, 2, "Confirmed bookings should count as busy")
+
+	inactive := false
+	_, err = service.UpdateLink(ctx, "user_1", link.ID, SchedulingLinkRequest{Active: &inactive})
+	require.NoError(t, err)
+	_, err = service.GetPublicLink(ctx, "intro-call")
+	assert.ErrorIs(t, err, ErrSchedulingLinkNotFound)
+}
+
+func TestRequestBooking_LimitsPendingPerEmail(t *testing.T) {
+	service := setupTestSchedulingService(t)
+	ctx := context.Background()
+
+	title, slug, notice := "Intro call", "intro-call", 0
+	_, err := service.CreateLink(ctx, "user_2", SchedulingLinkRequest{Title: &title, Slug: &slug, MinNoticeMinutes: &notice})
+	require.NoError(t, err)
+
+	public, err := service.GetPublicLink(ctx, "intro-call")
+	require.NoError(t, err)
+	require.Greater(t, len(public.Slots), maxPendingBookingsPerEmail)
+
+	for i := 0; i < maxPendingBookingsPerEmail; i++ {
+		_, err := service.RequestBooking(ctx, "intro-call", BookingInput{Name: "Ada", Email: "ada@example.com", StartTime: public.Slots[i].Start})
+		require.NoError(t, err)
+	}
+	_, err = service.RequestBooking(ctx, "intro-call", BookingInput{Name: "Ada", Email: "ada@example.com", StartTime: public.Slots[maxPendingBookingsPerEmail].Start})
+	assert.ErrorIs(t, err, ErrTooManyBookingRequests)
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"backend/internal/config"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const secretKeyProviderTimeout = 10 * time.Second
+
+// SecretKeyProvider unwraps the key material configured for a key version into the AES-256 key values are
+// encrypted with. With a KMS, each version is a data key wrapped by the KMS, so the key itself is never
+// stored in the environment.
+type SecretKeyProvider interface {
+	Name() string
+	UnwrapKey(ctx context.Context, material string) ([]byte, error)
+}
+
+// newSecretKeyProvider creates the key provider the configuration selects
+func newSecretKeyProvider(cfg *config.SecretsConfig) (SecretKeyProvider, error) {
+	client := &http.Client{Timeout: secretKeyProviderTimeout}
+
+	switch cfg.Provider {
+	case config.SecretsProviderLocal:
+		return localSecretKeyProvider{}, nil
+	case config.SecretsProviderAWSKMS:
+		if cfg.AWSRegion == "" || cfg.AWSAccessKeyID == "" || cfg.AWSSecretAccessKey == "" {
+			return nil, fmt.Errorf("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for the aws-kms key provider")
+		}
+		endpoint := cfg.AWSKMSEndpoint
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com/", cfg.AWSRegion)
+		}
+		return &awsKMSKeyProvider{
+			endpoint:        endpoint,
+			region:          cfg.AWSRegion,
+			accessKeyID:     cfg.AWSAccessKeyID,
+			secretAccessKey: cfg.AWSSecretAccessKey,
+			sessionToken:    cfg.AWSSessionToken,
+			client:          client,
+			now:             time.Now,
+		}, nil
+	case config.SecretsProviderVault:
+		if cfg.VaultAddr == "" || cfg.VaultToken == "" || cfg.VaultTransitKey == "" {
+			return nil, fmt.Errorf("VAULT_ADDR, VAULT_TOKEN and VAULT_TRANSIT_KEY are required for the vault key provider")
+		}
+		return &vaultKeyProvider{
+			addr:      cfg.VaultAddr,
+			token:     cfg.VaultToken,
+			namespace: cfg.VaultNamespace,
+			mount:     strings.Trim(cfg.VaultTransitMount, "/"),
+			key:       cfg.VaultTransitKey,
+			client:    client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown secrets key provider %q, use local, aws-kms or vault", cfg.Provider)
+	}
+}
+
+// localSecretKeyProvider uses the configured key as is
+type localSecretKeyProvider struct{}
+
+func (localSecretKeyProvider) Name() string {
+	return config.SecretsProviderLocal
+}
+
+func (localSecretKeyProvider) UnwrapKey(_ context.Context, material string) ([]byte, error) {
+	return localSecretKey(material), nil
+}
+
+// awsKMSKeyProvider decrypts data keys from `aws kms generate-data-key --key-spec AES_256`, configured
+// as their base64 CiphertextBlob
+type awsKMSKeyProvider struct {
+	endpoint        string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	client          *http.Client
+	now             func() time.Time
+}
+
+func (p *awsKMSKeyProvider) Name() string {
+	return config.SecretsProviderAWSKMS
+}
+
+func (p *awsKMSKeyProvider) UnwrapKey(ctx context.Context, material string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"CiphertextBlob": strings.TrimSpace(material)})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	p.sign(req, body)
+
+	var result struct {
+		Plaintext string `json:"Plaintext"`
+		Type      string `json:"__type"`
+		Message   string `json:"message"`
+	}
+	status, err := doSecretKeyRequest(p.client, req, &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("KMS decrypt failed with status %d: %s %s", status, result.Type, result.Message)
+	}
+	return base64.StdEncoding.DecodeString(result.Plaintext)
+}
+
+// sign signs a KMS request with AWS Signature Version 4
+func (p *awsKMSKeyProvider) sign(req *http.Request, body []byte) {
+	now := p.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, sha256Hex(body),
+	}, "\n")
+	scope := strings.Join([]string{date, p.region, "kms", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+p.secretAccessKey), date)
+	for _, part := range []string{p.region, "kms", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.accessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(signingKey, stringToSign))))
+}
+
+// vaultKeyProvider decrypts data keys from Vault's transit engine, as created with
+// `vault write -f transit/datakey/wrapped/<key>`
+type vaultKeyProvider struct {
+	addr      string
+	token     string
+	namespace string
+	mount     string
+	key       string
+	client    *http.Client
+}
+
+func (p *vaultKeyProvider) Name() string {
+	return config.SecretsProviderVault
+}
+
+func (p *vaultKeyProvider) UnwrapKey(ctx context.Context, material string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"ciphertext": strings.TrimSpace(material)})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s/decrypt/%s", p.addr, p.mount, url.PathEscape(p.key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	var result struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}
+	status, err := doSecretKeyRequest(p.client, req, &result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("vault decrypt failed with status %d: %s", status, strings.Join(result.Errors, "; "))
+	}
+	return base64.StdEncoding.DecodeString(result.Data.Plaintext)
+}
+
+// doSecretKeyRequest sends a key provider request and decodes its JSON response, returning the status
+func doSecretKeyRequest(client *http.Client, req *http.Request, result interface{}) (int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, result); err != nil && resp.StatusCode == http.StatusOK {
+			return 0, fmt.Errorf("failed to decode key provider response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
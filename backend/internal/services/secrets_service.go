@@ -0,0 +1,329 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"backend/internal/models"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// secretsCiphertextPrefix starts values encrypted with a versioned key, as in "enc:v2:". Values
+	// without it were encrypted with the legacy key before keys were versioned.
+	secretsCiphertextPrefix = "enc:v"
+	defaultSecretsKey       = "MyDefaultEncryptionKey32BytesLong"
+	secretsKeySize          = 32
+)
+
+var (
+	ErrSecretKeyUnavailable = errors.New("secret key version is not configured")
+	ErrInvalidCiphertext    = errors.New("invalid ciphertext")
+)
+
+// storedSecret is a column holding values encrypted by the secrets service
+type storedSecret struct {
+	model  interface{}
+	column string
+	binary bool // Raw ciphertext bytes rather than a base64 string
+}
+
+// storedSecrets are every column holding encrypted values, re-encrypted with the current key on rotation.
+// Webhook signing secrets, like CLERK_WEBHOOK_SIGNING_SECRET, aren't stored in the database: they're
+// server configuration read from the environment or settings file, so there's nothing here to encrypt.
+var storedSecrets = []storedSecret{
+	{model: &models.AICredential{}, column: "key_cipher", binary: true},
+	{model: &models.OrganizationAPICredential{}, column: "key_cipher", binary: true},
+	{model: &models.Calendar{}, column: "oauth_client_secret"},
+	{model: &models.Calendar{}, column: "oauth_refresh_token"},
+	{model: &models.GitHubIntegration{}, column: "access_token"},
+	{model: &models.GoogleDriveConnection{}, column: "oauth_refresh_token"},
+	{model: &models.TaskSyncIntegration{}, column: "access_token"},
+}
+
+// secretKeyring holds the unwrapped keys secrets are encrypted with
+type secretKeyring struct {
+	current int
+	keys    map[int][]byte
+	legacy  []byte
+}
+
+// secretKeys is the process-wide keyring, loaded from the environment with the local provider until
+// InitSecrets runs
+var secretKeys atomic.Pointer[secretKeyring]
+
+func init() {
+	legacy := localSecretKey(os.Getenv("AI_CREDENTIALS_ENC_KEY"))
+	secretKeys.Store(&secretKeyring{current: 1, keys: map[int][]byte{1: legacy}, legacy: legacy})
+}
+
+// InitSecrets loads the keyring from the configuration, unwrapping the data keys with the KMS when one
+// is the key provider
+func InitSecrets(ctx context.Context, cfg *config.SecretsConfig) error {
+	provider, err := newSecretKeyProvider(cfg)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Keys[cfg.KeyVersion]; !ok {
+		return fmt.Errorf("%w: SECRETS_KEY_V%d is required for the current version", ErrSecretKeyUnavailable, cfg.KeyVersion)
+	}
+
+	keyring := &secretKeyring{current: cfg.KeyVersion, keys: make(map[int][]byte, len(cfg.Keys)), legacy: localSecretKey(cfg.LegacyKey)}
+	for version, material := range cfg.Keys {
+		key, err := provider.UnwrapKey(ctx, material)
+		if err != nil {
+			return fmt.Errorf("failed to load secret key version %d from %s: %w", version, provider.Name(), err)
+		}
+		if len(key) != secretsKeySize {
+			return fmt.Errorf("secret key version %d from %s must be %d bytes, got %d", version, provider.Name(), secretsKeySize, len(key))
+		}
+		keyring.keys[version] = key
+	}
+	secretKeys.Store(keyring)
+
+	log.Info().Str("provider", provider.Name()).Int("key_version", cfg.KeyVersion).Int("key_versions", len(keyring.keys)).Msg("Loaded secret keys")
+	return nil
+}
+
+// ReloadSecrets reloads the keyring from the environment, like after first-run setup generated a key
+func ReloadSecrets(ctx context.Context) error {
+	return InitSecrets(ctx, config.LoadSecretsConfig())
+}
+
+// localSecretKey turns a configured key into an AES-256 key, padding or truncating it to 32 bytes as
+// credentials have always been encrypted with
+func localSecretKey(key string) []byte {
+	if key == "" {
+		key = defaultSecretsKey
+	}
+	result := make([]byte, secretsKeySize)
+	copy(result, key)
+	return result
+}
+
+// SecretRotationReport counts the stored secrets a rotation re-encrypted with the current key
+type SecretRotationReport struct {
+	Rotated int `json:"rotated"`
+	Failed  int `json:"failed"`
+}
+
+// SecretsService interface defines methods for encrypting stored credentials, OAuth secrets and access
+// tokens, and for rotating them to the current key
+type SecretsService interface {
+	Encrypt(plaintext string) ([]byte, error)
+	Decrypt(ciphertext []byte) (string, error)
+	EncryptString(plaintext string) (string, error)
+	DecryptString(ciphertext string) (string, error)
+	KeyVersion(ciphertext []byte) int
+	Rotate(ctx context.Context) (*SecretRotationReport, error)
+}
+
+// secretsServiceImpl implements the SecretsService interface
+type secretsServiceImpl struct {
+	db   *gorm.DB
+	keys func() *secretKeyring
+}
+
+// NewSecretsService creates a new SecretsService instance
+func NewSecretsService() SecretsService {
+	return &secretsServiceImpl{
+		db:   db.DB,
+		keys: secretKeys.Load,
+	}
+}
+
+// Encrypt encrypts plaintext with AES-GCM under the current key version
+func (s *secretsServiceImpl) Encrypt(plaintext string) ([]byte, error) {
+	keyring := s.keys()
+	prefix := secretsVersionPrefix(keyring.current)
+	sealed, err := sealSecret(keyring.keys[keyring.current], plaintext, []byte(prefix))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(prefix), sealed...), nil
+}
+
+// Decrypt decrypts a value from Encrypt, or one encrypted with the legacy key
+func (s *secretsServiceImpl) Decrypt(ciphertext []byte) (string, error) {
+	keyring := s.keys()
+	version, sealed, err := splitSecretVersion(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if version == 0 {
+		return openSecret(keyring.legacy, sealed, nil)
+	}
+
+	key, ok := keyring.keys[version]
+	if !ok {
+		return "", fmt.Errorf("%w: version %d", ErrSecretKeyUnavailable, version)
+	}
+	return openSecret(key, sealed, []byte(secretsVersionPrefix(version)))
+}
+
+// EncryptString encrypts plaintext into a string safe to store in text columns
+func (s *secretsServiceImpl) EncryptString(plaintext string) (string, error) {
+	ciphertext, err := s.Encrypt(plaintext)
+	if err != nil {
+		return "", err
+	}
+	version, sealed, _ := splitSecretVersion(ciphertext)
+	return secretsVersionPrefix(version) + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString decrypts a value from EncryptString, or a base64 value encrypted with the legacy key
+func (s *secretsServiceImpl) DecryptString(ciphertext string) (string, error) {
+	version, encoded, err := splitSecretVersion([]byte(ciphertext))
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	if version == 0 {
+		return s.Decrypt(sealed)
+	}
+	return s.Decrypt(append([]byte(secretsVersionPrefix(version)), sealed...))
+}
+
+// KeyVersion returns the key version a value was encrypted with, 0 for the legacy key. String values
+// from EncryptString carry their version the same way.
+func (s *secretsServiceImpl) KeyVersion(ciphertext []byte) int {
+	version, _, err := splitSecretVersion(ciphertext)
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// Rotate re-encrypts every stored secret that isn't encrypted with the current key version. Values that
+// can't be decrypted are counted and left alone.
+func (s *secretsServiceImpl) Rotate(ctx context.Context) (*SecretRotationReport, error) {
+	current := s.keys().current
+	report := &SecretRotationReport{}
+
+	for _, secret := range storedSecrets {
+		rows, err := s.db.WithContext(ctx).Unscoped().Model(secret.model).Select("id", secret.column).Rows()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch stored secrets: %w", err)
+		}
+		type storedValue struct {
+			id    interface{}
+			value []byte
+		}
+		var stale []storedValue
+		for rows.Next() {
+			var value storedValue
+			if err := rows.Scan(&value.id, &value.value); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read stored secrets: %w", err)
+			}
+			if len(value.value) > 0 && s.KeyVersion(value.value) != current {
+				stale = append(stale, value)
+			}
+		}
+		rows.Close()
+
+		for _, value := range stale {
+			rotated, err := s.reencrypt(value.value, secret.binary)
+			if err == nil {
+				err = s.db.WithContext(ctx).Unscoped().Model(secret.model).Where("id = ?", value.id).
+					UpdateColumn(secret.column, rotated).Error
+			}
+			if err != nil {
+				log.Warn().Err(err).Interface("id", value.id).Str("column", secret.column).Msg("Failed to rotate stored secret")
+				report.Failed++
+				continue
+			}
+			report.Rotated++
+		}
+	}
+	return report, nil
+}
+
+// reencrypt decrypts a stored value and encrypts it again with the current key, in the same encoding
+func (s *secretsServiceImpl) reencrypt(value []byte, binary bool) (interface{}, error) {
+	if binary {
+		plaintext, err := s.Decrypt(value)
+		if err != nil {
+			return nil, err
+		}
+		return s.Encrypt(plaintext)
+	}
+	plaintext, err := s.DecryptString(string(value))
+	if err != nil {
+		return nil, err
+	}
+	return s.EncryptString(plaintext)
+}
+
+// secretsVersionPrefix returns the prefix of values encrypted with a key version
+func secretsVersionPrefix(version int) string {
+	return secretsCiphertextPrefix + strconv.Itoa(version) + ":"
+}
+
+// splitSecretVersion splits the version prefix off a stored value, returning version 0 and the value
+// as is for legacy values
+func splitSecretVersion(value []byte) (int, []byte, error) {
+	rest, ok := bytes.CutPrefix(value, []byte(secretsCiphertextPrefix))
+	if !ok {
+		return 0, value, nil
+	}
+	digits, sealed, ok := bytes.Cut(rest, []byte(":"))
+	version, err := strconv.Atoi(string(digits))
+	if !ok || err != nil || version <= 0 {
+		return 0, nil, fmt.Errorf("%w: malformed key version", ErrInvalidCiphertext)
+	}
+	return version, sealed, nil
+}
+
+// sealSecret encrypts plaintext with AES-GCM, returning the nonce followed by the ciphertext
+func sealSecret(key []byte, plaintext string, additionalData []byte) ([]byte, error) {
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(plaintext), additionalData), nil
+}
+
+// openSecret decrypts a nonce and ciphertext from sealSecret
+func openSecret(key, sealed, additionalData []byte) (string, error) {
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("%w: ciphertext too short", ErrInvalidCiphertext)
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func secretCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
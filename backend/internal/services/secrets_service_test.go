@@ -0,0 +1,169 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/models"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestSecretsService creates a secrets service on an in-memory database, encrypting with key version 2
+// and still holding version 1 and the legacy key
+func setupTestSecretsService(t *testing.T) (*secretsServiceImpl, *secretKeyring) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(encryptedModels()...), "Failed to migrate test database")
+
+	keyring := &secretKeyring{
+		current: 2,
+		keys:    map[int][]byte{1: localSecretKey("first-key"), 2: localSecretKey("second-key")},
+		legacy:  localSecretKey(""),
+	}
+	return &secretsServiceImpl{db: db, keys: func() *secretKeyring { return keyring }}, keyring
+}
+
+// legacySecret encrypts a value the way credentials were encrypted before keys were versioned
+func legacySecret(t *testing.T, key []byte, plaintext string) []byte {
+	sealed, err := sealSecret(key, plaintext, nil)
+	require.NoError(t, err)
+	return sealed
+}
+
+func TestSecrets_EncryptDecrypt(t *testing.T) {
+	service, keyring := setupTestSecretsService(t)
+
+	ciphertext, err := service.Encrypt("sk-live")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(ciphertext), "enc:v2:"))
+	assert.Equal(t, 2, service.KeyVersion(ciphertext))
+	plaintext, err := service.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-live", plaintext)
+
+	encoded, err := service.EncryptString("refresh-token")
+	require.NoError(t, err)
+	assert.Equal(t, 2, service.KeyVersion([]byte(encoded)))
+	plaintext, err = service.DecryptString(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-token", plaintext)
+
+	legacy := legacySecret(t, keyring.legacy, "old-key")
+	assert.Zero(t, service.KeyVersion(legacy))
+	plaintext, err = service.Decrypt(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, "old-key", plaintext, "Values from before key versions still decrypt")
+	plaintext, err = service.DecryptString(base64.StdEncoding.EncodeToString(legacy))
+	require.NoError(t, err)
+	assert.Equal(t, "old-key", plaintext)
+
+	// The version is authenticated, so relabeling a value fails
+	relabeled := append([]byte("enc:v1:"), ciphertext[len("enc:v2:"):]...)
+	_, err = service.Decrypt(relabeled)
+	assert.Error(t, err)
+
+	delete(keyring.keys, 1)
+	_, err = service.Decrypt(relabeled)
+	assert.ErrorIs(t, err, ErrSecretKeyUnavailable)
+	_, err = service.DecryptString("enc:vX:abc")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestSecrets_Rotate(t *testing.T) {
+	service, keyring := setupTestSecretsService(t)
+	ctx := context.Background()
+
+	current, err := service.Encrypt("current")
+	require.NoError(t, err)
+	keyring.current = 1
+	previous, err := service.EncryptString("previous")
+	require.NoError(t, err)
+	keyring.current = 2
+
+	require.NoError(t, service.db.Create(&models.AICredential{ClerkUserID: "user_1", Provider: "openai", KeyCipher: legacySecret(t, keyring.legacy, "legacy")}).Error)
+	require.NoError(t, service.db.Create(&models.AICredential{ClerkUserID: "user_2", Provider: "openai", KeyCipher: current}).Error)
+	require.NoError(t, service.db.Create(&models.GitHubIntegration{ID: "gh_1", ClerkUserID: "user_1", AccessToken: previous}).Error)
+	require.NoError(t, service.db.Create(&models.GitHubIntegration{ID: "gh_2", ClerkUserID: "user_2", AccessToken: "not encrypted"}).Error)
+
+	report, err := service.Rotate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Rotated)
+	assert.Equal(t, 1, report.Failed, "Values that don't decrypt are left alone")
+
+	var credential models.AICredential
+	require.NoError(t, service.db.Where("clerk_user_id = ?", "user_1").First(&credential).Error)
+	assert.Equal(t, 2, service.KeyVersion(credential.KeyCipher))
+	plaintext, err := service.Decrypt(credential.KeyCipher)
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", plaintext)
+
+	var integration models.GitHubIntegration
+	require.NoError(t, service.db.First(&integration, "id = ?", "gh_1").Error)
+	plaintext, err = service.DecryptString(integration.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "previous", plaintext)
+	assert.Equal(t, 2, service.KeyVersion([]byte(integration.AccessToken)))
+
+	report, err = service.Rotate(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.Rotated, "Rotating again has nothing left to do")
+}
+
+func TestSecretKeyProviders(t *testing.T) {
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+	ctx := context.Background()
+
+	kms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "TrentService.Decrypt", r.Header.Get("X-Amz-Target"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=AKID/20260310/eu-west-1/kms/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, Signature="))
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["CiphertextBlob"] != "d3JhcHBlZA==" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"__type": "InvalidCiphertextException", "message": "bad blob"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"Plaintext": base64.StdEncoding.EncodeToString(dataKey)})
+	}))
+	defer kms.Close()
+
+	provider, err := newSecretKeyProvider(&config.SecretsConfig{Provider: config.SecretsProviderAWSKMS, AWSRegion: "eu-west-1",
+		AWSAccessKeyID: "AKID", AWSSecretAccessKey: "secret", AWSSessionToken: "session", AWSKMSEndpoint: kms.URL})
+	require.NoError(t, err)
+	provider.(*awsKMSKeyProvider).now = func() time.Time { return time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC) }
+	key, err := provider.UnwrapKey(ctx, "d3JhcHBlZA==")
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, key)
+	_, err = provider.UnwrapKey(ctx, "b3RoZXI=")
+	assert.ErrorContains(t, err, "InvalidCiphertextException")
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/transit/decrypt/notes", r.URL.Path)
+		assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}})
+	}))
+	defer vault.Close()
+
+	provider, err = newSecretKeyProvider(&config.SecretsConfig{Provider: config.SecretsProviderVault, VaultAddr: vault.URL,
+		VaultToken: "token", VaultTransitMount: "transit", VaultTransitKey: "notes"})
+	require.NoError(t, err)
+	key, err = provider.UnwrapKey(ctx, "vault:v1:wrapped")
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, key)
+
+	_, err = newSecretKeyProvider(&config.SecretsConfig{Provider: config.SecretsProviderVault})
+	assert.Error(t, err, "Vault needs an address, token and transit key")
+	_, err = newSecretKeyProvider(&config.SecretsConfig{Provider: "gcp-kms"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/pkg/recallai"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Kinds of connected sessions
+const (
+	SessionKindAPIKey      = "api_key"
+	SessionKindWhatsApp    = "whatsapp"
+	SessionKindCalendar    = "calendar"
+	SessionKindGoogleDrive = "google_drive"
+)
+
+// Connected session statuses
+const (
+	SessionStatusActive  = "active"
+	SessionStatusExpired = "expired" // Still connected but needs the user to sign in again
+	SessionStatusError   = "error"
+)
+
+// ErrSessionNotFound is returned when the user has no connected session with the ID
+var ErrSessionNotFound = errors.New("session not found")
+
+// ConnectedSession is a token, link or connection that acts on the user's behalf
+type ConnectedSession struct {
+	ID             string     `json:"id"` // <kind>:<source ID>
+	Kind           string     `json:"kind"`
+	Name           string     `json:"name"`
+	Detail         string     `json:"detail,omitempty"`
+	OrganizationID *string    `json:"organizationId,omitempty"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	LastUsedAt     *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// SessionSource lists and revokes one kind of connected session
+type SessionSource interface {
+	Kind() string
+	List(clerkUserID string) ([]ConnectedSession, error)
+	Revoke(clerkUserID, sourceID string) error
+}
+
+// SessionRegistry brings together every kind of connected session so they can be listed and revoked in one place
+type SessionRegistry struct {
+	mu      sync.RWMutex
+	sources map[string]SessionSource
+	kinds   []string
+}
+
+var (
+	sessionRegistry     *SessionRegistry
+	sessionRegistryOnce sync.Once
+)
+
+// GetSessionRegistry returns the shared session registry with the built-in sources
+func GetSessionRegistry() *SessionRegistry {
+	sessionRegistryOnce.Do(func() {
+		sessionRegistry = NewSessionRegistry()
+		sessionRegistry.Register(&apiKeySessionSource{db: db.DB})
+		sessionRegistry.Register(&whatsAppSessionSource{db: db.DB})
+		sessionRegistry.Register(&calendarSessionSource{db: db.DB, deleteRemote: func(recallCalendarID string) error {
+			return recallai.NewClient().DeleteCalendar(recallCalendarID)
+		}})
+		sessionRegistry.Register(&googleDriveSessionSource{db: db.DB})
+	})
+	return sessionRegistry
+}
+
+// NewSessionRegistry creates an empty session registry
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{sources: make(map[string]SessionSource)}
+}
+
+// Register adds a source of connected sessions
+func (r *SessionRegistry) Register(source SessionSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.sources[source.Kind()]; !exists {
+		r.kinds = append(r.kinds, source.Kind())
+	}
+	r.sources[source.Kind()] = source
+}
+
+// List returns the user's connected sessions, most recently used first. A failing source is logged and
+// skipped so the others can still be managed.
+func (r *SessionRegistry) List(clerkUserID string) []ConnectedSession {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sessions := []ConnectedSession{}
+	for _, kind := range r.kinds {
+		found, err := r.sources[kind].List(clerkUserID)
+		if err != nil {
+			log.Error().Err(err).Str("kind", kind).Str("user_id", clerkUserID).Msg("Failed to list connected sessions")
+			continue
+		}
+		for _, session := range found {
+			session.Kind = kind
+			session.ID = kind + ":" + session.ID
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessionActivity(sessions[i]).After(sessionActivity(sessions[j]))
+	})
+	return sessions
+}
+
+// Revoke disconnects one of the user's sessions by its registry ID
+func (r *SessionRegistry) Revoke(clerkUserID, sessionID string) error {
+	kind, sourceID, ok := strings.Cut(sessionID, ":")
+	if !ok || sourceID == "" {
+		return ErrSessionNotFound
+	}
+
+	r.mu.RLock()
+	source, exists := r.sources[kind]
+	r.mu.RUnlock()
+	if !exists {
+		return ErrSessionNotFound
+	}
+
+	if err := source.Revoke(clerkUserID, sourceID); err != nil {
+		return err
+	}
+	log.Info().Str("kind", kind).Str("user_id", clerkUserID).Msg("Connected session revoked")
+	return nil
+}
+
+// sessionActivity is when a session was last used, or created if it never was
+func sessionActivity(session ConnectedSession) time.Time {
+	if session.LastUsedAt != nil {
+		return *session.LastUsedAt
+	}
+	return session.CreatedAt
+}
+
+// apiKeySessionSource lists the automation API keys the user created
+type apiKeySessionSource struct {
+	db *gorm.DB
+}
+
+func (s *apiKeySessionSource) Kind() string {
+	return SessionKindAPIKey
+}
+
+func (s *apiKeySessionSource) List(clerkUserID string) ([]ConnectedSession, error) {
+	var keys []models.AutomationAPIKey
+	if err := s.db.Where("clerk_user_id = ?", clerkUserID).Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+
+	sessions := make([]ConnectedSession, len(keys))
+	for i, key := range keys {
+		sessions[i] = ConnectedSession{
+			ID:             key.ID,
+			Name:           key.Name,
+			Detail:         key.KeyPrefix + "…",
+			OrganizationID: key.OrganizationID,
+			Status:         SessionStatusActive,
+			CreatedAt:      key.CreatedAt,
+			LastUsedAt:     key.LastUsedAt,
+		}
+	}
+	return sessions, nil
+}
+
+func (s *apiKeySessionSource) Revoke(clerkUserID, sourceID string) error {
+	var key models.AutomationAPIKey
+	if err := s.db.Where("id = ? AND clerk_user_id = ?", sourceID, clerkUserID).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to fetch API key: %w", err)
+	}
+
+	automation := &automationServiceImpl{db: s.db}
+	return automation.RevokeAPIKey(AutomationScope{ClerkUserID: key.ClerkUserID, OrganizationID: key.OrganizationID}, key.ID)
+}
+
+// whatsAppSessionSource lists the phone numbers linked to the user
+type whatsAppSessionSource struct {
+	db *gorm.DB
+}
+
+func (s *whatsAppSessionSource) Kind() string {
+	return SessionKindWhatsApp
+}
+
+func (s *whatsAppSessionSource) List(clerkUserID string) ([]ConnectedSession, error) {
+	var users []models.WhatsAppUser
+	if err := s.db.Where("clerk_user_id = ? AND is_authenticated = ?", clerkUserID, true).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch WhatsApp links: %w", err)
+	}
+
+	auth := &whatsAppAuthService{db: s.db}
+	sessions := make([]ConnectedSession, len(users))
+	for i, user := range users {
+		status := SessionStatusActive
+		if auth.IsSessionExpired(&users[i]) {
+			status = SessionStatusExpired
+		}
+		lastActive := user.LastActiveAt
+		sessions[i] = ConnectedSession{
+			ID:             user.ID,
+			Name:           "WhatsApp",
+			Detail:         maskPhoneNumber(user.PhoneNumber),
+			OrganizationID: user.OrganizationID,
+			Status:         status,
+			CreatedAt:      user.CreatedAt,
+			LastUsedAt:     &lastActive,
+		}
+	}
+	return sessions, nil
+}
+
+// Revoke signs the phone number out. Its next message asks to link the account again.
+func (s *whatsAppSessionSource) Revoke(clerkUserID, sourceID string) error {
+	result := s.db.Model(&models.WhatsAppUser{}).
+		Where("id = ? AND clerk_user_id = ? AND is_authenticated = ?", sourceID, clerkUserID, true).
+		Updates(map[string]interface{}{"is_authenticated": false, "auth_token": ""})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unlink WhatsApp: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// calendarSessionSource lists the user's connected calendars
+type calendarSessionSource struct {
+	db           *gorm.DB
+	deleteRemote func(recallCalendarID string) error
+}
+
+func (s *calendarSessionSource) Kind() string {
+	return SessionKindCalendar
+}
+
+func (s *calendarSessionSource) List(clerkUserID string) ([]ConnectedSession, error) {
+	var calendars []models.Calendar
+	if err := s.db.Where("clerk_user_id = ?", clerkUserID).Find(&calendars).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch calendars: %w", err)
+	}
+
+	sessions := make([]ConnectedSession, len(calendars))
+	for i, calendar := range calendars {
+		status := SessionStatusActive
+		if calendar.Status == "error" {
+			status = SessionStatusError
+		}
+		name := "Google Calendar"
+		if calendar.Platform == "microsoft_outlook" {
+			name = "Outlook Calendar"
+		}
+		sessions[i] = ConnectedSession{
+			ID:         calendar.ID,
+			Name:       name,
+			Detail:     calendar.PlatformEmail,
+			Status:     status,
+			CreatedAt:  calendar.CreatedAt,
+			LastUsedAt: calendar.LastSyncedAt,
+		}
+	}
+	return sessions, nil
+}
+
+// Revoke disconnects the calendar from Recall and deletes it with its events
+func (s *calendarSessionSource) Revoke(clerkUserID, sourceID string) error {
+	var calendar models.Calendar
+	if err := s.db.Where("id = ? AND clerk_user_id = ?", sourceID, clerkUserID).First(&calendar).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to fetch calendar: %w", err)
+	}
+
+	if err := s.deleteRemote(calendar.RecallCalendarID); err != nil {
+		// The local connection is removed even if Recall can't be reached
+		log.Error().Err(err).Str("recall_calendar_id", calendar.RecallCalendarID).Msg("Error deleting calendar from Recall")
+	}
+	if err := s.db.Delete(&calendar).Error; err != nil {
+		return fmt.Errorf("failed to delete calendar: %w", err)
+	}
+	return nil
+}
+
+// googleDriveSessionSource lists the user's Google Drive connection
+type googleDriveSessionSource struct {
+	db *gorm.DB
+}
+
+func (s *googleDriveSessionSource) Kind() string {
+	return SessionKindGoogleDrive
+}
+
+func (s *googleDriveSessionSource) List(clerkUserID string) ([]ConnectedSession, error) {
+	var connections []models.GoogleDriveConnection
+	if err := s.db.Where("clerk_user_id = ?", clerkUserID).Find(&connections).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch drive connection: %w", err)
+	}
+
+	sessions := make([]ConnectedSession, len(connections))
+	for i, connection := range connections {
+		sessions[i] = ConnectedSession{
+			ID:        connection.ID,
+			Name:      "Google Drive",
+			Detail:    connection.Email,
+			Status:    SessionStatusActive,
+			CreatedAt: connection.CreatedAt,
+		}
+	}
+	return sessions, nil
+}
+
+func (s *googleDriveSessionSource) Revoke(clerkUserID, sourceID string) error {
+	result := s.db.Where("id = ? AND clerk_user_id = ?", sourceID, clerkUserID).Delete(&models.GoogleDriveConnection{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete drive connection: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// maskPhoneNumber keeps the country code and last four digits of a phone number
+func maskPhoneNumber(phone string) string {
+	if len(phone) <= 6 {
+		return phone
+	}
+	prefix := phone[:2]
+	if !strings.HasPrefix(phone, "+") {
+		prefix = ""
+	}
+	return prefix + strings.Repeat("•", 3) + phone[len(phone)-4:]
+}
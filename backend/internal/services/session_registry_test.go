@@ -0,0 +1,86 @@
+package services
+
+import (
+	"backend/internal/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestSessionRegistry creates a session registry with every built-in source on an in-memory database
+func setupTestSessionRegistry(t *testing.T) (*SessionRegistry, *gorm.DB, *[]string) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.AutomationAPIKey{}, &models.AutomationWebhook{}, &models.WhatsAppUser{},
+		&models.Calendar{}, &models.GoogleDriveConnection{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	deleted := []string{}
+	registry := NewSessionRegistry()
+	registry.Register(&apiKeySessionSource{db: db})
+	registry.Register(&whatsAppSessionSource{db: db})
+	registry.Register(&calendarSessionSource{db: db, deleteRemote: func(recallCalendarID string) error {
+		deleted = append(deleted, recallCalendarID)
+		return errors.New("recall unavailable")
+	}})
+	registry.Register(&googleDriveSessionSource{db: db})
+	return registry, db, &deleted
+}
+
+func TestSessionRegistry_ListsEveryKindByLastUse(t *testing.T) {
+	registry, db, _ := setupTestSessionRegistry(t)
+	now := time.Now()
+	keyUsed := now.Add(-time.Hour)
+
+	require.NoError(t, db.Create(&models.AutomationAPIKey{ClerkUserID: "user_1", Name: "Zapier", KeyPrefix: "nk_12345678", KeyHash: "h1", LastUsedAt: &keyUsed}).Error)
+	require.NoError(t, db.Create(&models.AutomationAPIKey{ClerkUserID: "user_2", Name: "Other", KeyPrefix: "nk_87654321", KeyHash: "h2"}).Error)
+	require.NoError(t, db.Create(&models.WhatsAppUser{PhoneNumber: "+15551234567", ClerkUserID: "user_1", IsAuthenticated: true, LastActiveAt: now}).Error)
+	require.NoError(t, db.Create(&models.WhatsAppUser{PhoneNumber: "+15557654321", ClerkUserID: "user_1", IsAuthenticated: false, LastActiveAt: now}).Error)
+	require.NoError(t, db.Create(&models.Calendar{ClerkUserID: "user_1", RecallCalendarID: "rc_1", Platform: "microsoft_outlook", PlatformEmail: "me@example.com", OAuthClientID: "client", CreatedAt: now.Add(-2 * time.Hour)}).Error)
+
+	sessions := registry.List("user_1")
+	require.Len(t, sessions, 3, "Other users' keys and unlinked phones should not be listed")
+
+	assert.Equal(t, SessionKindWhatsApp, sessions[0].Kind)
+	assert.Equal(t, "+1•••4567", sessions[0].Detail)
+	assert.Equal(t, SessionKindAPIKey, sessions[1].Kind)
+	assert.Equal(t, "Zapier", sessions[1].Name)
+	assert.Equal(t, SessionKindCalendar, sessions[2].Kind)
+	assert.Equal(t, "Outlook Calendar", sessions[2].Name)
+	assert.Nil(t, sessions[2].LastUsedAt)
+}
+
+func TestSessionRegistry_Revoke(t *testing.T) {
+	registry, db, deleted := setupTestSessionRegistry(t)
+
+	key := models.AutomationAPIKey{ClerkUserID: "user_1", Name: "Zapier", KeyPrefix: "nk_12345678", KeyHash: "h1"}
+	require.NoError(t, db.Create(&key).Error)
+	phone := models.WhatsAppUser{PhoneNumber: "+15551234567", ClerkUserID: "user_1", IsAuthenticated: true, AuthToken: "token", LastActiveAt: time.Now()}
+	require.NoError(t, db.Create(&phone).Error)
+	calendar := models.Calendar{ClerkUserID: "user_1", RecallCalendarID: "rc_1", Platform: "google_calendar", OAuthClientID: "client"}
+	require.NoError(t, db.Create(&calendar).Error)
+
+	assert.ErrorIs(t, registry.Revoke("user_2", "api_key:"+key.ID), ErrSessionNotFound, "Users can only revoke their own sessions")
+	assert.ErrorIs(t, registry.Revoke("user_1", "unknown:"+key.ID), ErrSessionNotFound)
+	assert.ErrorIs(t, registry.Revoke("user_1", key.ID), ErrSessionNotFound)
+
+	require.NoError(t, registry.Revoke("user_1", "api_key:"+key.ID))
+	require.NoError(t, registry.Revoke("user_1", "whatsapp:"+phone.ID))
+	require.NoError(t, registry.Revoke("user_1", "calendar:"+calendar.ID), "A Recall failure should not block the disconnect")
+
+	assert.Empty(t, registry.List("user_1"))
+	assert.Equal(t, []string{"rc_1"}, *deleted)
+
+	var stored models.WhatsAppUser
+	require.NoError(t, db.Where("id = ?", phone.ID).First(&stored).Error)
+	assert.False(t, stored.IsAuthenticated)
+	assert.Empty(t, stored.AuthToken)
+
+	assert.ErrorIs(t, registry.Revoke("user_1", "whatsapp:"+phone.ID), ErrSessionNotFound)
+}
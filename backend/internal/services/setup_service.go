@@ -0,0 +1,287 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSetupCompleted is returned when setup runs on a server that already has authentication configured
+	ErrSetupCompleted = errors.New("setup has already been completed")
+	// ErrInvalidSetup is returned when setup settings are missing or malformed
+	ErrInvalidSetup = errors.New("invalid setup settings")
+)
+
+// setupAPIKeys are the external API keys first-run setup accepts, by their setting name
+var setupAPIKeys = []string{
+	"CLERK_SECRET_KEY",
+	"CLERK_WEBHOOK_SIGNING_SECRET",
+	"OPENAI_API_KEY",
+	"ANTHROPIC_API_KEY",
+	"GOOGLE_API_KEY",
+	"RECALL_AI_API_KEY",
+	"GOOGLE_CALENDAR_CLIENT_ID",
+	"GOOGLE_CALENDAR_CLIENT_SECRET",
+	"MICROSOFT_CALENDAR_CLIENT_ID",
+	"MICROSOFT_CALENDAR_CLIENT_SECRET",
+	"GOOGLE_DRIVE_CLIENT_ID",
+	"GOOGLE_DRIVE_CLIENT_SECRET",
+}
+
+// encryptedModels returns the models holding values encrypted by the secrets service, which a new key
+// would make unreadable
+func encryptedModels() []interface{} {
+	seen := make(map[reflect.Type]bool, len(storedSecrets))
+	var encrypted []interface{}
+	for _, secret := range storedSecrets {
+		if modelType := reflect.TypeOf(secret.model); !seen[modelType] {
+			seen[modelType] = true
+			encrypted = append(encrypted, secret.model)
+		}
+	}
+	return encrypted
+}
+
+// secretsKeyConfigured reports whether secrets are encrypted with a configured key rather than the
+// built-in default: the legacy key, versioned keys, or data keys wrapped by a KMS
+func secretsKeyConfigured() bool {
+	cfg := config.LoadSecretsConfig()
+	if cfg.Provider != config.SecretsProviderLocal || cfg.LegacyKey != "" {
+		return true
+	}
+	for _, key := range cfg.Keys {
+		if key != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// setupMu keeps two setup requests from both passing the completed check
+var setupMu sync.Mutex
+
+// SetupStatus reports what a self-hosted server has configured
+type SetupStatus struct {
+	Completed               bool            `json:"completed"`
+	SingleUser              bool            `json:"singleUser"`
+	DatabaseDriver          string          `json:"databaseDriver"`
+	EncryptionKeyConfigured bool            `json:"encryptionKeyConfigured"`
+	APIKeys                 map[string]bool `json:"apiKeys"` // Whether each accepted API key is set
+	SettingsFile            string          `json:"settingsFile"`
+}
+
+// SetupAdmin is the server's administrator. In single-user mode it's the local user; with Clerk, users
+// sign up through Clerk and setup only needs CLERK_SECRET_KEY.
+type SetupAdmin struct {
+	SingleUser bool   `json:"singleUser"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+}
+
+// SetupStorage picks the database, applied on the next start
+type SetupStorage struct {
+	Driver string `json:"driver"` // postgres or sqlite
+	URL    string `json:"url"`    // PostgreSQL DSN or SQLite file
+}
+
+// SetupRequest holds the settings first-run setup persists
+type SetupRequest struct {
+	Admin   SetupAdmin        `json:"admin"`
+	Storage *SetupStorage     `json:"storage"`
+	APIKeys map[string]string `json:"apiKeys"` // Keyed by setting name, like OPENAI_API_KEY
+}
+
+// SetupResult is the outcome of first-run setup
+type SetupResult struct {
+	Status                 SetupStatus `json:"status"`
+	AdminToken             string      `json:"adminToken,omitempty"` // Generated single-user bearer token, only shown once
+	EncryptionKeyGenerated bool        `json:"encryptionKeyGenerated"`
+	RestartRequired        bool        `json:"restartRequired"`
+}
+
+// SetupService configures a self-hosted server on its first run
+type SetupService interface {
+	Status() SetupStatus
+	Setup(req SetupRequest) (*SetupResult, error)
+}
+
+// setupServiceImpl implements the SetupService interface
+type setupServiceImpl struct {
+	db         *gorm.DB
+	singleUser func() bool
+}
+
+// NewSetupService creates a new setup service
+func NewSetupService() SetupService {
+	return &setupServiceImpl{
+		db:         db.DB,
+		singleUser: accessLookups.SingleUser,
+	}
+}
+
+// Status reports what is configured. Setup counts as completed once it has run or once the server has
+// authentication configured some other way, so it can't be used to take over a running deployment.
+func (s *setupServiceImpl) Status() SetupStatus {
+	apiKeys := make(map[string]bool, len(setupAPIKeys))
+	for _, key := range setupAPIKeys {
+		apiKeys[key] = os.Getenv(key) != ""
+	}
+
+	return SetupStatus{
+		Completed: os.Getenv(config.SetupCompletedKey) == "true" ||
+			os.Getenv("CLERK_SECRET_KEY") != "" ||
+			s.singleUser(),
+		SingleUser:              s.singleUser(),
+		DatabaseDriver:          db.ActiveDriver(),
+		EncryptionKeyConfigured: secretsKeyConfigured(),
+		APIKeys:                 apiKeys,
+		SettingsFile:            config.SettingsFilePath(),
+	}
+}
+
+// Setup validates and persists the first-run settings. API keys apply right away; authentication and
+// storage changes apply on the next start.
+func (s *setupServiceImpl) Setup(req SetupRequest) (*SetupResult, error) {
+	setupMu.Lock()
+	defer setupMu.Unlock()
+
+	if s.Status().Completed {
+		return nil, ErrSetupCompleted
+	}
+
+	settings := map[string]string{config.SetupCompletedKey: "true"}
+	result := &SetupResult{}
+
+	for key, value := range req.APIKeys {
+		if !isSetupAPIKey(key) {
+			return nil, fmt.Errorf("%w: unknown API key %s", ErrInvalidSetup, key)
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			settings[key] = value
+		}
+	}
+
+	if req.Admin.SingleUser {
+		name := strings.TrimSpace(req.Admin.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: the admin needs a name", ErrInvalidSetup)
+		}
+		email := strings.TrimSpace(req.Admin.Email)
+		if _, err := mail.ParseAddress(email); err != nil {
+			return nil, fmt.Errorf("%w: the admin email is invalid", ErrInvalidSetup)
+		}
+		settings["SINGLE_USER"] = "true"
+		settings["SINGLE_USER_NAME"] = name
+		settings["SINGLE_USER_EMAIL"] = email
+		if os.Getenv("SINGLE_USER_TOKEN") == "" {
+			token, err := randomSecret(32)
+			if err != nil {
+				return nil, err
+			}
+			settings["SINGLE_USER_TOKEN"] = token
+			result.AdminToken = token
+		}
+	} else if settings["CLERK_SECRET_KEY"] == "" {
+		return nil, fmt.Errorf("%w: CLERK_SECRET_KEY is required unless the server runs in single-user mode", ErrInvalidSetup)
+	}
+
+	if req.Storage != nil {
+		driver := strings.ToLower(strings.TrimSpace(req.Storage.Driver))
+		url := strings.TrimSpace(req.Storage.URL)
+		switch {
+		case driver != db.DriverPostgres && driver != db.DriverSQLite:
+			return nil, fmt.Errorf("%w: the storage driver must be %s or %s", ErrInvalidSetup, db.DriverPostgres, db.DriverSQLite)
+		case driver == db.DriverPostgres && url == "":
+			return nil, fmt.Errorf("%w: PostgreSQL storage needs a connection URL", ErrInvalidSetup)
+		}
+		settings["DB_DRIVER"] = driver
+		if url != "" {
+			settings["DB_URL"] = url
+		}
+	}
+
+	generated, err := s.encryptionKey(settings)
+	if err != nil {
+		return nil, err
+	}
+	result.EncryptionKeyGenerated = generated
+
+	if err := config.SaveSettings(settings); err != nil {
+		return nil, err
+	}
+	if generated {
+		if err := ReloadSecrets(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("Failed to load the generated encryption key, it applies after a restart")
+		}
+	}
+
+	// The auth middleware and database are chosen at startup
+	result.RestartRequired = true
+	result.Status = s.Status()
+
+	log.Info().
+		Bool("single_user", req.Admin.SingleUser).
+		Bool("storage", req.Storage != nil).
+		Int("api_keys", len(req.APIKeys)).
+		Bool("encryption_key_generated", generated).
+		Msg("First-run setup completed")
+	return result, nil
+}
+
+// encryptionKey adds a generated credentials encryption key to the settings when none is configured.
+// It leaves the built-in key alone when credentials were already encrypted with it, since a new key
+// would make them unreadable.
+func (s *setupServiceImpl) encryptionKey(settings map[string]string) (bool, error) {
+	if secretsKeyConfigured() {
+		return false, nil
+	}
+	for _, model := range encryptedModels() {
+		var count int64
+		if err := s.db.Model(model).Count(&count).Error; err != nil {
+			return false, fmt.Errorf("failed to check for encrypted credentials: %w", err)
+		}
+		if count > 0 {
+			log.Warn().Msg("Credentials are already encrypted with the default key, set AI_CREDENTIALS_ENC_KEY and re-enter them to change it")
+			return false, nil
+		}
+	}
+
+	// 24 random bytes encode to exactly the 32 characters of an AES-256 key
+	key, err := randomSecret(24)
+	if err != nil {
+		return false, err
+	}
+	settings["AI_CREDENTIALS_ENC_KEY"] = key
+	return true, nil
+}
+
+func isSetupAPIKey(key string) bool {
+	for _, allowed := range setupAPIKeys {
+		if key == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// randomSecret returns n random bytes, URL-safe base64 encoded
+func randomSecret(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
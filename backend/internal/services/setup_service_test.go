@@ -0,0 +1,132 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/models"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestSetupService creates a setup service on an in-memory database, with a fresh settings file
+// and the settings setup writes cleared from the environment
+func setupTestSetupService(t *testing.T) *setupServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(encryptedModels()...)
+	require.NoError(t, err, "Failed to migrate test database")
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "settings.env"))
+	for _, key := range append([]string{
+		config.SetupCompletedKey, "AI_CREDENTIALS_ENC_KEY", "SECRETS_KEY_PROVIDER", "SECRETS_KEY_VERSION", "SECRETS_KEY_V1",
+		"SINGLE_USER", "SINGLE_USER_NAME", "SINGLE_USER_EMAIL", "SINGLE_USER_TOKEN", "DB_DRIVER", "DB_URL",
+	}, setupAPIKeys...) {
+		t.Setenv(key, "")
+	}
+	t.Cleanup(func() { require.NoError(t, ReloadSecrets(context.Background())) })
+
+	return &setupServiceImpl{db: db, singleUser: func() bool { return false }}
+}
+
+func TestSetup_SingleUser(t *testing.T) {
+	service := setupTestSetupService(t)
+	assert.False(t, service.Status().Completed)
+
+	result, err := service.Setup(SetupRequest{
+		Admin:   SetupAdmin{SingleUser: true, Name: "Ada", Email: "ada@example.com"},
+		Storage: &SetupStorage{Driver: "sqlite", URL: "/data/notes.db"},
+		APIKeys: map[string]string{"OPENAI_API_KEY": " sk-test "},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.RestartRequired)
+	assert.True(t, result.EncryptionKeyGenerated)
+	assert.NotEmpty(t, result.AdminToken, "A single-user token should be generated")
+	assert.True(t, result.Status.Completed)
+	assert.True(t, result.Status.APIKeys["OPENAI_API_KEY"])
+
+	settings, err := config.ReadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, "true", settings["SINGLE_USER"])
+	assert.Equal(t, "ada@example.com", settings["SINGLE_USER_EMAIL"])
+	assert.Equal(t, result.AdminToken, settings["SINGLE_USER_TOKEN"])
+	assert.Equal(t, "sqlite", settings["DB_DRIVER"])
+	assert.Equal(t, "/data/notes.db", settings["DB_URL"])
+	assert.Equal(t, "sk-test", settings["OPENAI_API_KEY"])
+	assert.Len(t, settings["AI_CREDENTIALS_ENC_KEY"], 32)
+	assert.Equal(t, "sk-test", os.Getenv("OPENAI_API_KEY"), "API keys should apply without a restart")
+
+	info, err := os.Stat(config.SettingsFilePath())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "The settings file holds secrets")
+
+	_, err = service.Setup(SetupRequest{Admin: SetupAdmin{SingleUser: true, Name: "Eve", Email: "eve@example.com"}})
+	assert.ErrorIs(t, err, ErrSetupCompleted, "Setup should only run once")
+}
+
+func TestSetup_Validation(t *testing.T) {
+	service := setupTestSetupService(t)
+
+	_, err := service.Setup(SetupRequest{})
+	assert.ErrorIs(t, err, ErrInvalidSetup, "Clerk mode needs CLERK_SECRET_KEY")
+
+	_, err = service.Setup(SetupRequest{APIKeys: map[string]string{"CLERK_SECRET_KEY": "sk", "DB_URL": "postgres://"}})
+	assert.ErrorIs(t, err, ErrInvalidSetup, "Only API keys should be accepted as API keys")
+
+	_, err = service.Setup(SetupRequest{Admin: SetupAdmin{SingleUser: true, Name: "Ada", Email: "not an email"}})
+	assert.ErrorIs(t, err, ErrInvalidSetup)
+
+	_, err = service.Setup(SetupRequest{
+		APIKeys: map[string]string{"CLERK_SECRET_KEY": "sk"},
+		Storage: &SetupStorage{Driver: "postgres"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidSetup, "PostgreSQL storage needs a URL")
+
+	assert.False(t, service.Status().Completed, "Rejected setups should save nothing")
+}
+
+func TestSetup_KeepsKeyOfExistingCredentials(t *testing.T) {
+	service := setupTestSetupService(t)
+	require.NoError(t, service.db.Create(&models.AICredential{ClerkUserID: "user_1", Provider: "openai"}).Error)
+
+	result, err := service.Setup(SetupRequest{APIKeys: map[string]string{"CLERK_SECRET_KEY": "sk"}})
+	require.NoError(t, err)
+	assert.False(t, result.EncryptionKeyGenerated, "A new key would make stored credentials unreadable")
+	assert.Empty(t, result.AdminToken)
+
+	settings, err := config.ReadSettings()
+	require.NoError(t, err)
+	assert.NotContains(t, settings, "AI_CREDENTIALS_ENC_KEY")
+	assert.Equal(t, "sk", settings["CLERK_SECRET_KEY"])
+}
+
+func TestSetup_UsesConfiguredSecretKeys(t *testing.T) {
+	service := setupTestSetupService(t)
+	assert.False(t, service.Status().EncryptionKeyConfigured)
+
+	t.Setenv("SECRETS_KEY_V1", "versioned-key-material-32-bytes!")
+	assert.True(t, service.Status().EncryptionKeyConfigured, "Versioned keys count as configured")
+
+	result, err := service.Setup(SetupRequest{APIKeys: map[string]string{"CLERK_SECRET_KEY": "sk"}})
+	require.NoError(t, err)
+	assert.False(t, result.EncryptionKeyGenerated)
+	settings, err := config.ReadSettings()
+	require.NoError(t, err)
+	assert.NotContains(t, settings, "AI_CREDENTIALS_ENC_KEY", "No legacy key next to versioned ones")
+
+	t.Setenv("SECRETS_KEY_V1", "")
+	t.Setenv("SECRETS_KEY_PROVIDER", "vault")
+	assert.True(t, secretsKeyConfigured(), "Keys wrapped by a KMS count as configured")
+}
+
+func TestEncryptedModels(t *testing.T) {
+	encrypted := encryptedModels()
+	assert.Len(t, encrypted, 6, "Each model with encrypted columns is listed once")
+	assert.Contains(t, encrypted, &models.Calendar{})
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SlugRepairJob periodically slugs chapters and notes created before slugs were assigned and renumbers
+// slugs that were taken twice by concurrent creates
+type SlugRepairJob struct {
+	slugService SlugService
+	interval    time.Duration
+	stopChan    chan struct{}
+}
+
+// NewSlugRepairJob creates a new scheduled slug repair job
+func NewSlugRepairJob(slugService SlugService, interval time.Duration) *SlugRepairJob {
+	return &SlugRepairJob{
+		slugService: slugService,
+		interval:    interval,
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start begins repairing slugs
+func (j *SlugRepairJob) Start(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Msg("Starting slug repair scheduler")
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			report, err := j.slugService.RepairSlugs()
+			if err != nil {
+				log.Error().Err(err).Msg("Slug repair failed")
+			} else if report.Chapters+report.Notes > 0 {
+				log.Info().
+					Int64("chapters", report.Chapters).
+					Int64("notes", report.Notes).
+					Msg("Repaired slugs")
+			}
+		case <-ctx.Done():
+			log.Info().Msg("Stopping slug repair scheduler (context cancelled)")
+			return
+		case <-j.stopChan:
+			log.Info().Msg("Stopping slug repair scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (j *SlugRepairJob) Stop() {
+	close(j.stopChan)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrAmbiguousNoteLink is returned when a link without a chapter matches notes in several chapters
+var ErrAmbiguousNoteLink = errors.New("more than one note matches the link")
+
+// SlugRepairReport counts the chapters and notes given a new slug by a repair
+type SlugRepairReport struct {
+	Chapters int64 `json:"chapters"`
+	Notes    int64 `json:"notes"`
+}
+
+// SlugService resolves note links by slug and repairs missing or duplicate slugs
+type SlugService interface {
+	ResolveNoteLink(notebookID, target string) (*models.Notes, error)
+	RepairSlugs() (*SlugRepairReport, error)
+}
+
+// slugServiceImpl implements the SlugService interface
+type slugServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewSlugService creates a new slug service
+func NewSlugService() SlugService {
+	return &slugServiceImpl{
+		db: db.DB,
+	}
+}
+
+// ResolveNoteLink finds the note a wiki link in a notebook points at. The target is "note" or
+// "chapter/note", matched by slug so case, punctuation and spacing don't matter.
+func (s *slugServiceImpl) ResolveNoteLink(notebookID, target string) (*models.Notes, error) {
+	target = strings.Trim(strings.TrimSpace(target), "/")
+	if target == "" {
+		return nil, ErrNoteNotFound
+	}
+
+	query := s.db.Model(&models.Notes{}).
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ?", notebookID)
+	if chapterName, noteName, ok := strings.Cut(target, "/"); ok {
+		query = query.Where("chapters.slug = ? AND notes.slug = ?", models.Slugify(chapterName), models.Slugify(noteName))
+	} else {
+		query = query.Where("notes.slug = ?", models.Slugify(target))
+	}
+
+	var notes []models.Notes
+	if err := query.Select("notes.id, notes.name, notes.slug, notes.chapter_id, notes.organization_id").Limit(2).Find(&notes).Error; err != nil {
+		return nil, fmt.Errorf("failed to resolve note link: %w", err)
+	}
+	switch len(notes) {
+	case 0:
+		return nil, ErrNoteNotFound
+	case 1:
+		return &notes[0], nil
+	default:
+		return nil, ErrAmbiguousNoteLink
+	}
+}
+
+// RepairSlugs gives chapters and notes without a slug one, and renumbers slugs that are used twice in
+// a notebook or chapter. The oldest chapter or note keeps a duplicated slug.
+func (s *slugServiceImpl) RepairSlugs() (*SlugRepairReport, error) {
+	report := &SlugRepairReport{}
+	var err error
+	if report.Chapters, err = s.repairSlugs(&models.Chapter{}, "notebook_id", RefreshChapterSlug); err != nil {
+		return report, err
+	}
+	if report.Notes, err = s.repairSlugs(&models.Notes{}, "chapter_id", RefreshNoteSlug); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// repairSlugs refreshes the slugs of a model's rows that have none or share one with an older row in their scope
+func (s *slugServiceImpl) repairSlugs(model any, scopeColumn string, refresh func(tx *gorm.DB, id string) (string, error)) (int64, error) {
+	var ids []string
+	if err := s.db.Model(model).Where("slug = '' OR slug IS NULL").Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch rows without a slug: %w", err)
+	}
+
+	var duplicates []struct {
+		ScopeID string
+		Slug    string
+	}
+	if err := s.db.Model(model).
+		Select(scopeColumn + " AS scope_id, slug").
+		Where("slug <> ''").
+		Group(scopeColumn + ", slug").
+		Having("COUNT(*) > 1").
+		Scan(&duplicates).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch duplicate slugs: %w", err)
+	}
+	for _, duplicate := range duplicates {
+		var duplicateIDs []string
+		if err := s.db.Model(model).
+			Where(scopeColumn+" = ? AND slug = ?", duplicate.ScopeID, duplicate.Slug).
+			Order("created_at, id").
+			Pluck("id", &duplicateIDs).Error; err != nil {
+			return 0, fmt.Errorf("failed to fetch duplicate slugs: %w", err)
+		}
+		ids = append(ids, duplicateIDs[1:]...)
+	}
+
+	for _, id := range ids {
+		if _, err := refresh(s.db, id); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(ids)), nil
+}
+
+// RefreshNoteSlug gives a note the slug of its name that's unique in its chapter and returns it. Call it
+// after a note is renamed or moved.
+func RefreshNoteSlug(tx *gorm.DB, noteID string) (string, error) {
+	var note models.Notes
+	if err := tx.Select("id", "name", "chapter_id").Where("id = ?", noteID).First(&note).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrNoteNotFound
+		}
+		return "", fmt.Errorf("failed to fetch note: %w", err)
+	}
+	slug, err := models.UniqueSlug(tx, "notes", "chapter_id", note.ChapterID, note.Name, note.ID)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Model(&models.Notes{}).Where("id = ?", noteID).UpdateColumn("slug", slug).Error; err != nil {
+		return "", fmt.Errorf("failed to update note slug: %w", err)
+	}
+	return slug, nil
+}
+
+// RefreshChapterSlug gives a chapter the slug of its name that's unique in its notebook and returns it.
+// Call it after a chapter is renamed or moved.
+func RefreshChapterSlug(tx *gorm.DB, chapterID string) (string, error) {
+	var chapter models.Chapter
+	if err := tx.Select("id", "name", "notebook_id").Where("id = ?", chapterID).First(&chapter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrChapterNotFound
+		}
+		return "", fmt.Errorf("failed to fetch chapter: %w", err)
+	}
+	slug, err := models.UniqueSlug(tx, "chapters", "notebook_id", chapter.NotebookID, chapter.Name, chapter.ID)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Model(&models.Chapter{}).Where("id = ?", chapterID).UpdateColumn("slug", slug).Error; err != nil {
+		return "", fmt.Errorf("failed to update chapter slug: %w", err)
+	}
+	return slug, nil
+}
+
+// slugOrName returns a slug, or the slug of the name for rows created before slugs were assigned
+func slugOrName(slug, name string) string {
+	if slug != "" {
+		return slug
+	}
+	return models.Slugify(name)
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestSlugService creates a slug service on an in-memory database
+func setupTestSlugService(t *testing.T) *slugServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	return &slugServiceImpl{db: db}
+}
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "kickoff-q3", models.Slugify("  Kickoff: Q3!"))
+	assert.Equal(t, "café-notes", models.Slugify("Café Notes"))
+	assert.Equal(t, "untitled", models.Slugify("???"))
+	assert.Len(t, models.Slugify(string(make([]byte, 200))+"a"), len("a"))
+}
+
+func TestSlugs_UniqueWithinChapter(t *testing.T) {
+	service := setupTestSlugService(t)
+	notebook := models.Notebook{Name: "Work", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	meetings := models.Chapter{Name: "Meetings", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&meetings).Error)
+	duplicate := models.Chapter{Name: "meetings!", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&duplicate).Error)
+	assert.Equal(t, "meetings", meetings.Slug)
+	assert.Equal(t, "meetings-2", duplicate.Slug, "Chapters in a notebook should get unique slugs")
+
+	first := models.Notes{Name: "Kickoff", ChapterID: meetings.ID}
+	second := models.Notes{Name: "Kickoff", ChapterID: meetings.ID}
+	elsewhere := models.Notes{Name: "Kickoff", ChapterID: duplicate.ID}
+	for _, note := range []*models.Notes{&first, &second, &elsewhere} {
+		require.NoError(t, service.db.Create(note).Error)
+	}
+	assert.Equal(t, "kickoff", first.Slug)
+	assert.Equal(t, "kickoff-2", second.Slug)
+	assert.Equal(t, "kickoff", elsewhere.Slug, "Slugs only need to be unique within a chapter")
+
+	// Renaming keeps the slug when it still fits and moving takes the next free slug
+	require.NoError(t, service.db.Model(&first).Update("name", "Kickoff!").Error)
+	slug, err := RefreshNoteSlug(service.db, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "kickoff", slug)
+
+	require.NoError(t, service.db.Model(&second).Update("chapter_id", duplicate.ID).Error)
+	slug, err = RefreshNoteSlug(service.db, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "kickoff-2", slug)
+}
+
+func TestSlugs_ResolveNoteLink(t *testing.T) {
+	service := setupTestSlugService(t)
+	notebook := models.Notebook{Name: "Work", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	meetings := models.Chapter{Name: "Meetings", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&meetings).Error)
+	projects := models.Chapter{Name: "Projects", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&projects).Error)
+
+	kickoff := models.Notes{Name: "Kickoff", ChapterID: meetings.ID}
+	require.NoError(t, service.db.Create(&kickoff).Error)
+	projectKickoff := models.Notes{Name: "Kickoff", ChapterID: projects.ID}
+	require.NoError(t, service.db.Create(&projectKickoff).Error)
+	roadmap := models.Notes{Name: "Q3 Roadmap", ChapterID: projects.ID}
+	require.NoError(t, service.db.Create(&roadmap).Error)
+
+	note, err := service.ResolveNoteLink(notebook.ID, "q3 roadmap")
+	require.NoError(t, err)
+	assert.Equal(t, roadmap.ID, note.ID, "Links should match by slug, not exact title")
+
+	_, err = service.ResolveNoteLink(notebook.ID, "Kickoff")
+	assert.ErrorIs(t, err, ErrAmbiguousNoteLink)
+
+	note, err = service.ResolveNoteLink(notebook.ID, "Projects/Kickoff")
+	require.NoError(t, err)
+	assert.Equal(t, projectKickoff.ID, note.ID)
+
+	_, err = service.ResolveNoteLink(notebook.ID, "Missing")
+	assert.ErrorIs(t, err, ErrNoteNotFound)
+	_, err = service.ResolveNoteLink("other_notebook", "Q3 Roadmap")
+	assert.ErrorIs(t, err, ErrNoteNotFound)
+}
+
+func TestSlugs_RepairMissingAndDuplicateSlugs(t *testing.T) {
+	service := setupTestSlugService(t)
+	notebook := models.Notebook{Name: "Work", ClerkUserID: "user_1"}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Meetings", NotebookID: notebook.ID}
+	require.NoError(t, service.db.Create(&chapter).Error)
+
+	oldest := models.Notes{Name: "Standup", ChapterID: chapter.ID}
+	require.NoError(t, service.db.Create(&oldest).Error)
+	racing := models.Notes{Name: "Standup", ChapterID: chapter.ID}
+	require.NoError(t, service.db.Create(&racing).Error)
+	legacy := models.Notes{Name: "Retro", ChapterID: chapter.ID}
+	require.NoError(t, service.db.Create(&legacy).Error)
+
+	// Simulate a concurrent create and a note from before slugs
+	require.NoError(t, service.db.Model(&racing).UpdateColumn("slug", "standup").Error)
+	require.NoError(t, service.db.Model(&legacy).UpdateColumn("slug", "").Error)
+	require.NoError(t, service.db.Model(&chapter).UpdateColumn("slug", "").Error)
+
+	report, err := service.RepairSlugs()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Chapters)
+	assert.Equal(t, int64(2), report.Notes)
+
+	slugs := map[string]string{}
+	var notes []models.Notes
+	require.NoError(t, service.db.Find(&notes).Error)
+	for _, note := range notes {
+		slugs[note.ID] = note.Slug
+	}
+	assert.Equal(t, "standup", slugs[oldest.ID], "The oldest note should keep a duplicated slug")
+	assert.Equal(t, "standup-2", slugs[racing.ID])
+	assert.Equal(t, "retro", slugs[legacy.ID])
+
+	report, err = service.RepairSlugs()
+	require.NoError(t, err)
+	assert.Zero(t, report.Chapters+report.Notes, "A second repair should have nothing to fix")
+}
@@ -0,0 +1,951 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSourceNotFound is returned when a source doesn't exist on the note
+	ErrSourceNotFound = errors.New("source not found")
+	// ErrInvalidSource is returned when a source's fields, DOI or ISBN are invalid
+	ErrInvalidSource = errors.New("invalid source")
+	// ErrSourceMetadataNotFound is returned when no work is registered under a DOI or ISBN
+	ErrSourceMetadataNotFound = errors.New("no work found for the identifier")
+	// ErrSourceLookupFailed is returned when the DOI or ISBN registry can't be reached
+	ErrSourceLookupFailed = errors.New("failed to look up source")
+)
+
+// Bibliography styles
+const (
+	BibliographyStyleAPA = "apa" // APA, 7th edition
+	BibliographyStyleMLA = "mla" // MLA, 9th edition
+)
+
+const (
+	maxSourcesPerNote    = 200
+	maxSourceAuthors     = 100
+	maxSourceTitle       = 1000
+	sourceLookupMaxBytes = 1 << 20
+	crossrefWorksURL     = "https://api.crossref.org/works/"
+	openLibraryBooksURL  = "https://openlibrary.org/api/books"
+	doiURLPrefix         = "https://doi.org/"
+	apaMaxListedAuthors  = 20
+	apaUndated           = "n.d."
+	mlaMaxListedAuthors  = 2
+)
+
+var (
+	// doiPattern matches a DOI once its resolver prefix is removed
+	doiPattern = regexp.MustCompile(`^10\.\d{4,9}/\S+$`)
+	// doiPrefixPattern matches the ways a DOI is written as a link or with a label
+	doiPrefixPattern = regexp.MustCompile(`(?i)^(https?://(dx\.)?doi\.org/|doi:\s*)`)
+	// sourceYearPattern finds the year in free-form publication dates such as "March 2004"
+	sourceYearPattern = regexp.MustCompile(`\b\d{4}\b`)
+)
+
+// mlaMonths are the month abbreviations MLA uses in access dates
+var mlaMonths = [...]string{"Jan.", "Feb.", "Mar.", "Apr.", "May", "June", "July", "Aug.", "Sept.", "Oct.", "Nov.", "Dec."}
+
+// sourceLookupClient fetches work metadata from Crossref and Open Library
+var sourceLookupClient = &http.Client{Timeout: linkPreviewTimeout}
+
+// SourceInput is a new source or the changes to one. Authors are written "Family, Given", or as an
+// organization's name. When a new source only has a DOI or ISBN the rest is looked up.
+type SourceInput struct {
+	Type       string     `json:"type"`
+	Title      string     `json:"title"`
+	Authors    []string   `json:"authors"`
+	Year       int        `json:"year,omitempty"`
+	Publisher  string     `json:"publisher,omitempty"`
+	Container  string     `json:"container,omitempty"`
+	Volume     string     `json:"volume,omitempty"`
+	Issue      string     `json:"issue,omitempty"`
+	Pages      string     `json:"pages,omitempty"`
+	DOI        string     `json:"doi,omitempty"`
+	ISBN       string     `json:"isbn,omitempty"`
+	URL        string     `json:"url,omitempty"`
+	AccessedAt *time.Time `json:"accessedAt,omitempty"`
+}
+
+// BibliographyEntry is a formatted work of a bibliography, with the sources citing it
+type BibliographyEntry struct {
+	Text      string   `json:"text"`
+	Markdown  string   `json:"markdown"` // The entry with its italics
+	SourceIDs []string `json:"sourceIds"`
+	NoteIDs   []string `json:"noteIds"`
+}
+
+// Bibliography is the works cited by a notebook's notes, in alphabetical order
+type Bibliography struct {
+	Style   string              `json:"style"`
+	Entries []BibliographyEntry `json:"entries"`
+}
+
+// crossrefWork is the part of a Crossref works response sources are made from
+type crossrefWork struct {
+	Message struct {
+		Type           string   `json:"type"`
+		Title          []string `json:"title"`
+		Subtitle       []string `json:"subtitle"`
+		ContainerTitle []string `json:"container-title"`
+		Publisher      string   `json:"publisher"`
+		Volume         string   `json:"volume"`
+		Issue          string   `json:"issue"`
+		Page           string   `json:"page"`
+		DOI            string   `json:"DOI"`
+		Author         []struct {
+			Given  string `json:"given"`
+			Family string `json:"family"`
+			Name   string `json:"name"`
+		} `json:"author"`
+		Issued struct {
+			DateParts [][]int `json:"date-parts"`
+		} `json:"issued"`
+	} `json:"message"`
+}
+
+// openLibraryBook is the part of an Open Library books response sources are made from
+type openLibraryBook struct {
+	Title       string `json:"title"`
+	Subtitle    string `json:"subtitle"`
+	PublishDate string `json:"publish_date"`
+	Authors     []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Publishers []struct {
+		Name string `json:"name"`
+	} `json:"publishers"`
+}
+
+// SourceService interface defines methods for the sources notes cite and the bibliographies made from them
+type SourceService interface {
+	List(ctx context.Context, noteID string) ([]models.Source, error)
+	Create(ctx context.Context, noteID string, input SourceInput, clerkUserID string) (*models.Source, error)
+	Update(ctx context.Context, noteID, sourceID string, input SourceInput) (*models.Source, error)
+	Delete(ctx context.Context, noteID, sourceID string) error
+	LookupDOI(ctx context.Context, doi string) (*SourceInput, error)
+	LookupISBN(ctx context.Context, isbn string) (*SourceInput, error)
+	Bibliography(ctx context.Context, notebookID, style string) (*Bibliography, error)
+}
+
+// sourceServiceImpl implements the SourceService interface
+type sourceServiceImpl struct {
+	db        *gorm.DB
+	fetchJSON func(ctx context.Context, rawURL string, out interface{}) error
+	now       func() time.Time
+}
+
+// NewSourceService creates a new SourceService instance
+func NewSourceService() SourceService {
+	return &sourceServiceImpl{
+		db: db.DB,
+		fetchJSON: func(ctx context.Context, rawURL string, out interface{}) error {
+			return fetchSourceJSON(ctx, sourceLookupClient, rawURL, out)
+		},
+		now: time.Now,
+	}
+}
+
+// List returns the sources a note cites, in the order they were added
+func (s *sourceServiceImpl) List(ctx context.Context, noteID string) ([]models.Source, error) {
+	var note models.Notes
+	err := db.Replica(s.db).WithContext(ctx).Select("id").Where("id = ?", noteID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+
+	sources := []models.Source{}
+	if err := db.Replica(s.db).WithContext(ctx).Where("note_id = ?", noteID).
+		Order("created_at ASC").Find(&sources).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch sources: %w", err)
+	}
+	return sources, nil
+}
+
+// Create cites a work from a note. A source with only a DOI or ISBN is filled in from Crossref or Open
+// Library, and web pages are marked as accessed now unless the input says when.
+func (s *sourceServiceImpl) Create(ctx context.Context, noteID string, input SourceInput, clerkUserID string) (*models.Source, error) {
+	if _, err := s.editableNote(ctx, noteID); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(input.Title) == "" {
+		var found *SourceInput
+		var err error
+		switch {
+		case strings.TrimSpace(input.DOI) != "":
+			found, err = s.LookupDOI(ctx, input.DOI)
+		case strings.TrimSpace(input.ISBN) != "":
+			found, err = s.LookupISBN(ctx, input.ISBN)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			input = mergeSourceInput(*found, input)
+		}
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Source{}).Where("note_id = ?", noteID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count sources: %w", err)
+	}
+	if count >= maxSourcesPerNote {
+		return nil, fmt.Errorf("%w: a note can cite at most %d sources", ErrInvalidSource, maxSourcesPerNote)
+	}
+
+	source := &models.Source{NoteID: noteID, CreatedBy: clerkUserID}
+	if err := s.applySourceInput(source, input); err != nil {
+		return nil, err
+	}
+	if source.Type == models.SourceTypeWebpage && source.AccessedAt == nil {
+		accessed := s.now()
+		source.AccessedAt = &accessed
+	}
+	if err := s.db.WithContext(ctx).Create(source).Error; err != nil {
+		return nil, fmt.Errorf("failed to save source: %w", err)
+	}
+	return source, nil
+}
+
+// Update replaces a source's details
+func (s *sourceServiceImpl) Update(ctx context.Context, noteID, sourceID string, input SourceInput) (*models.Source, error) {
+	if _, err := s.editableNote(ctx, noteID); err != nil {
+		return nil, err
+	}
+	source, err := s.source(ctx, noteID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applySourceInput(source, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Save(source).Error; err != nil {
+		return nil, fmt.Errorf("failed to update source: %w", err)
+	}
+	return source, nil
+}
+
+// Delete removes a source from a note
+func (s *sourceServiceImpl) Delete(ctx context.Context, noteID, sourceID string) error {
+	if _, err := s.editableNote(ctx, noteID); err != nil {
+		return err
+	}
+	result := s.db.WithContext(ctx).Where("id = ? AND note_id = ?", sourceID, noteID).Delete(&models.Source{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete source: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSourceNotFound
+	}
+	return nil
+}
+
+// LookupDOI returns the details of the work registered under a DOI at Crossref
+func (s *sourceServiceImpl) LookupDOI(ctx context.Context, doi string) (*SourceInput, error) {
+	normalized, err := normalizeDOI(doi)
+	if err != nil {
+		return nil, err
+	}
+
+	var work crossrefWork
+	if err := s.fetchJSON(ctx, crossrefWorksURL+url.PathEscape(normalized), &work); err != nil {
+		return nil, err
+	}
+	message := work.Message
+	if len(message.Title) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSourceMetadataNotFound, normalized)
+	}
+
+	found := &SourceInput{
+		Type:      models.SourceTypeArticle,
+		Title:     joinSourceTitle(message.Title[0], firstString(message.Subtitle)),
+		Publisher: message.Publisher,
+		Container: firstString(message.ContainerTitle),
+		Volume:    message.Volume,
+		Issue:     message.Issue,
+		Pages:     message.Page,
+		DOI:       normalized,
+	}
+	switch message.Type {
+	case "book", "monograph", "edited-book", "reference-book":
+		found.Type = models.SourceTypeBook
+		found.Container = ""
+	}
+	for _, author := range message.Author {
+		switch {
+		case strings.TrimSpace(author.Family) != "" && strings.TrimSpace(author.Given) != "":
+			found.Authors = append(found.Authors, strings.TrimSpace(author.Family)+", "+strings.TrimSpace(author.Given))
+		case strings.TrimSpace(author.Family) != "":
+			found.Authors = append(found.Authors, strings.TrimSpace(author.Family))
+		case strings.TrimSpace(author.Name) != "":
+			found.Authors = append(found.Authors, strings.TrimSpace(author.Name))
+		}
+	}
+	if len(message.Issued.DateParts) > 0 && len(message.Issued.DateParts[0]) > 0 {
+		found.Year = message.Issued.DateParts[0][0]
+	}
+	return found, nil
+}
+
+// LookupISBN returns the details of the book registered under an ISBN at Open Library
+func (s *sourceServiceImpl) LookupISBN(ctx context.Context, isbn string) (*SourceInput, error) {
+	normalized, err := normalizeISBN(isbn)
+	if err != nil {
+		return nil, err
+	}
+
+	key := "ISBN:" + normalized
+	query := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"data"}}
+	var books map[string]openLibraryBook
+	if err := s.fetchJSON(ctx, openLibraryBooksURL+"?"+query.Encode(), &books); err != nil {
+		return nil, err
+	}
+	book, ok := books[key]
+	if !ok || strings.TrimSpace(book.Title) == "" {
+		return nil, fmt.Errorf("%w: %s", ErrSourceMetadataNotFound, normalized)
+	}
+
+	found := &SourceInput{
+		Type:  models.SourceTypeBook,
+		Title: joinSourceTitle(book.Title, book.Subtitle),
+		ISBN:  normalized,
+	}
+	for _, author := range book.Authors {
+		if name := sourceAuthorFromName(author.Name); name != "" {
+			found.Authors = append(found.Authors, name)
+		}
+	}
+	if len(book.Publishers) > 0 {
+		found.Publisher = strings.TrimSpace(book.Publishers[0].Name)
+	}
+	if year := sourceYearPattern.FindString(book.PublishDate); year != "" {
+		found.Year, _ = strconv.Atoi(year)
+	}
+	return found, nil
+}
+
+// Bibliography formats the works cited by a notebook's notes. A work cited from several notes, found by
+// its DOI, ISBN or URL, is listed once.
+func (s *sourceServiceImpl) Bibliography(ctx context.Context, notebookID, style string) (*Bibliography, error) {
+	style = strings.ToLower(strings.TrimSpace(style))
+	if style == "" {
+		style = BibliographyStyleAPA
+	}
+	if style != BibliographyStyleAPA && style != BibliographyStyleMLA {
+		return nil, fmt.Errorf("%w: unknown bibliography style %q", ErrInvalidSource, style)
+	}
+
+	var notebook models.Notebook
+	err := db.Replica(s.db).WithContext(ctx).Where("id = ?", notebookID).First(&notebook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotebookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+
+	var sources []models.Source
+	if err := db.Replica(s.db).WithContext(ctx).Select("sources.*").
+		Joins("JOIN notes ON notes.id = sources.note_id").
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ?", notebookID).
+		Order("sources.created_at ASC").
+		Find(&sources).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch sources: %w", err)
+	}
+
+	bibliography := &Bibliography{Style: style, Entries: []BibliographyEntry{}}
+	works := make(map[string]int)
+	for _, source := range sources {
+		key := sourceWorkKey(&source)
+		if i, ok := works[key]; ok {
+			entry := &bibliography.Entries[i]
+			entry.SourceIDs = append(entry.SourceIDs, source.ID)
+			if !containsString(entry.NoteIDs, source.NoteID) {
+				entry.NoteIDs = append(entry.NoteIDs, source.NoteID)
+			}
+			continue
+		}
+
+		var citation *sourceCitation
+		if style == BibliographyStyleMLA {
+			citation = formatMLACitation(&source)
+		} else {
+			citation = formatAPACitation(&source)
+		}
+		works[key] = len(bibliography.Entries)
+		bibliography.Entries = append(bibliography.Entries, BibliographyEntry{
+			Text:      citation.text.String(),
+			Markdown:  citation.markdown.String(),
+			SourceIDs: []string{source.ID},
+			NoteIDs:   []string{source.NoteID},
+		})
+	}
+
+	sort.SliceStable(bibliography.Entries, func(i, j int) bool {
+		return bibliographySortKey(bibliography.Entries[i].Text) < bibliographySortKey(bibliography.Entries[j].Text)
+	})
+	return bibliography, nil
+}
+
+// editableNote returns a note sources can be added to
+func (s *sourceServiceImpl) editableNote(ctx context.Context, noteID string) (*models.Notes, error) {
+	var note models.Notes
+	err := s.db.WithContext(ctx).Preload("Chapter.Notebook").Where("id = ?", noteID).First(&note).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch note: %w", err)
+	}
+	if note.Locked {
+		return nil, ErrNoteLocked
+	}
+	// Sources are stored in plain text, so they would give away what an encrypted notebook is about
+	if note.Chapter.Notebook.Encrypted {
+		return nil, ErrNotebookEncrypted
+	}
+	return &note, nil
+}
+
+// source returns one of a note's sources
+func (s *sourceServiceImpl) source(ctx context.Context, noteID, sourceID string) (*models.Source, error) {
+	var source models.Source
+	err := s.db.WithContext(ctx).Where("id = ? AND note_id = ?", sourceID, noteID).First(&source).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSourceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch source: %w", err)
+	}
+	return &source, nil
+}
+
+// applySourceInput validates and sets a source's details. Without a type, sources with a DOI are
+// articles, those with an ISBN books and the rest web pages.
+func (s *sourceServiceImpl) applySourceInput(source *models.Source, input SourceInput) error {
+	title := strings.Join(strings.Fields(input.Title), " ")
+	if title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidSource)
+	}
+	if utf8.RuneCountInString(title) > maxSourceTitle {
+		return fmt.Errorf("%w: titles can be at most %d characters", ErrInvalidSource, maxSourceTitle)
+	}
+
+	var authors []string
+	for _, author := range input.Authors {
+		if author = strings.Join(strings.Fields(author), " "); author != "" {
+			authors = append(authors, author)
+		}
+	}
+	if len(authors) > maxSourceAuthors {
+		return fmt.Errorf("%w: a source can have at most %d authors", ErrInvalidSource, maxSourceAuthors)
+	}
+
+	if input.Year < 0 || input.Year > s.now().Year()+1 {
+		return fmt.Errorf("%w: invalid year %d", ErrInvalidSource, input.Year)
+	}
+
+	var doi, isbn, link string
+	var err error
+	if strings.TrimSpace(input.DOI) != "" {
+		if doi, err = normalizeDOI(input.DOI); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(input.ISBN) != "" {
+		if isbn, err = normalizeISBN(input.ISBN); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(input.URL) != "" {
+		if link, err = normalizeLinkURL(input.URL); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSource, err)
+		}
+	}
+
+	sourceType := strings.ToLower(strings.TrimSpace(input.Type))
+	switch {
+	case sourceType != "":
+	case doi != "":
+		sourceType = models.SourceTypeArticle
+	case isbn != "":
+		sourceType = models.SourceTypeBook
+	default:
+		sourceType = models.SourceTypeWebpage
+	}
+	if !models.ValidSourceTypes[sourceType] {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidSource, input.Type)
+	}
+	if sourceType == models.SourceTypeWebpage && link == "" {
+		return fmt.Errorf("%w: web pages need a URL", ErrInvalidSource)
+	}
+
+	fields := []struct {
+		name  string
+		value *string
+		input string
+		max   int
+	}{
+		{"publisher", &source.Publisher, input.Publisher, 255},
+		{"container", &source.Container, input.Container, 500},
+		{"volume", &source.Volume, input.Volume, 50},
+		{"issue", &source.Issue, input.Issue, 50},
+		{"pages", &source.Pages, input.Pages, 50},
+	}
+	for _, field := range fields {
+		value := strings.Join(strings.Fields(field.input), " ")
+		if utf8.RuneCountInString(value) > field.max {
+			return fmt.Errorf("%w: %s can be at most %d characters", ErrInvalidSource, field.name, field.max)
+		}
+		*field.value = value
+	}
+
+	source.Type = sourceType
+	source.Title = title
+	source.Authors = strings.Join(authors, "\n")
+	source.AuthorList = authors
+	source.Year = input.Year
+	source.DOI = doi
+	source.ISBN = isbn
+	source.URL = link
+	if input.AccessedAt != nil {
+		source.AccessedAt = input.AccessedAt
+	}
+	return nil
+}
+
+// mergeSourceInput fills the details missing from a source with those looked up for it
+func mergeSourceInput(found, input SourceInput) SourceInput {
+	merged := input
+	for _, field := range []struct{ value, found *string }{
+		{&merged.Type, &found.Type},
+		{&merged.Title, &found.Title},
+		{&merged.Publisher, &found.Publisher},
+		{&merged.Container, &found.Container},
+		{&merged.Volume, &found.Volume},
+		{&merged.Issue, &found.Issue},
+		{&merged.Pages, &found.Pages},
+		{&merged.DOI, &found.DOI},
+		{&merged.ISBN, &found.ISBN},
+	} {
+		if strings.TrimSpace(*field.value) == "" {
+			*field.value = *field.found
+		}
+	}
+	if len(merged.Authors) == 0 {
+		merged.Authors = found.Authors
+	}
+	if merged.Year == 0 {
+		merged.Year = found.Year
+	}
+	return merged
+}
+
+// normalizeDOI returns a DOI without its resolver prefix. DOIs are case-insensitive, so they're lowercased.
+func normalizeDOI(doi string) (string, error) {
+	normalized := strings.TrimSpace(doiPrefixPattern.ReplaceAllString(strings.TrimSpace(doi), ""))
+	if unescaped, err := url.PathUnescape(normalized); err == nil {
+		normalized = unescaped
+	}
+	normalized = strings.ToLower(normalized)
+	if !doiPattern.MatchString(normalized) || len(normalized) > 255 {
+		return "", fmt.Errorf("%w: %q is not a DOI", ErrInvalidSource, doi)
+	}
+	return normalized, nil
+}
+
+// normalizeISBN checks an ISBN's check digit and returns it as an ISBN-13, so a book is found by
+// either of its ISBNs
+func normalizeISBN(isbn string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(isbn)), "ISBN"))
+	digits = strings.TrimLeft(digits, ":")
+	invalid := fmt.Errorf("%w: %q is not an ISBN", ErrInvalidSource, isbn)
+
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, r := range digits {
+			switch {
+			case r >= '0' && r <= '9':
+				sum += (10 - i) * int(r-'0')
+			case r == 'X' && i == 9:
+				sum += 10
+			default:
+				return "", invalid
+			}
+		}
+		if sum%11 != 0 {
+			return "", invalid
+		}
+		return isbn13("978" + digits[:9]), nil
+	case 13:
+		for _, r := range digits {
+			if r < '0' || r > '9' {
+				return "", invalid
+			}
+		}
+		if isbn13(digits[:12]) != digits {
+			return "", invalid
+		}
+		return digits, nil
+	}
+	return "", invalid
+}
+
+// isbn13 appends the check digit to the first twelve digits of an ISBN-13
+func isbn13(digits string) string {
+	sum := 0
+	for i, r := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(r-'0')
+	}
+	return digits + strconv.Itoa((10-sum%10)%10)
+}
+
+// fetchSourceJSON fetches and decodes a JSON response of a metadata registry
+func fetchSourceJSON(ctx context.Context, client *http.Client, rawURL string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSourceLookupFailed, err)
+	}
+	req.Header.Set("User-Agent", linkPreviewUserAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSourceLookupFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrSourceMetadataNotFound
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%w: status %d", ErrSourceLookupFailed, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, sourceLookupMaxBytes)).Decode(out); err != nil {
+		return fmt.Errorf("%w: %v", ErrSourceLookupFailed, err)
+	}
+	return nil
+}
+
+// sourceWorkKey identifies the work a source cites, so citations of it from several notes are listed once
+func sourceWorkKey(source *models.Source) string {
+	switch {
+	case source.DOI != "":
+		return "doi:" + source.DOI
+	case source.ISBN != "":
+		return "isbn:" + source.ISBN
+	case source.URL != "":
+		return "url:" + source.URL
+	}
+	return fmt.Sprintf("title:%s|%d", strings.ToLower(source.Title), source.Year)
+}
+
+// bibliographySortKey orders entries alphabetically, ignoring the quotes around MLA titles
+func bibliographySortKey(entry string) string {
+	return strings.ToLower(strings.TrimLeftFunc(entry, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+}
+
+// sourceCitation is a bibliography entry written as plain text and as markdown
+type sourceCitation struct {
+	text     strings.Builder
+	markdown strings.Builder
+}
+
+// plain adds text to the entry
+func (c *sourceCitation) plain(s string) {
+	c.text.WriteString(s)
+	c.markdown.WriteString(s)
+}
+
+// italic adds text to the entry, in italics in the markdown
+func (c *sourceCitation) italic(s string) {
+	c.text.WriteString(s)
+	c.markdown.WriteString("*" + s + "*")
+}
+
+// formatAPACitation formats a source as an APA reference list entry
+func formatAPACitation(source *models.Source) *sourceCitation {
+	c := &sourceCitation{}
+	year := "(" + apaUndated + ")."
+	if source.Year > 0 {
+		year = fmt.Sprintf("(%d).", source.Year)
+	}
+
+	// The title takes the place of missing authors
+	titled := false
+	if authors := apaAuthors(source.AuthorList); authors != "" {
+		c.plain(withPeriod(authors) + " " + year + " ")
+	} else {
+		apaTitle(c, source)
+		c.plain(" " + year)
+		titled = true
+	}
+
+	switch source.Type {
+	case models.SourceTypeArticle:
+		if !titled {
+			apaTitle(c, source)
+		}
+		if source.Container != "" {
+			c.plain(" ")
+			c.italic(source.Container)
+			if source.Volume != "" {
+				c.plain(", ")
+				c.italic(source.Volume)
+				if source.Issue != "" {
+					c.plain("(" + source.Issue + ")")
+				}
+			}
+			if source.Pages != "" {
+				c.plain(", " + source.Pages)
+			}
+			c.plain(".")
+		}
+	case models.SourceTypeBook:
+		if !titled {
+			apaTitle(c, source)
+		}
+		if source.Publisher != "" {
+			c.plain(" " + withPeriod(source.Publisher))
+		}
+	default:
+		if !titled {
+			apaTitle(c, source)
+		}
+		if source.Container != "" {
+			c.plain(" " + withPeriod(source.Container))
+		}
+	}
+
+	if link := sourceLink(source); link != "" {
+		c.plain(" " + link)
+	}
+	return c
+}
+
+// apaTitle adds a source's title as APA writes it: articles in plain text, books and pages in italics
+func apaTitle(c *sourceCitation, source *models.Source) {
+	if source.Type == models.SourceTypeArticle {
+		c.plain(withPeriod(source.Title))
+		return
+	}
+	c.italic(source.Title)
+	if !endsSentence(source.Title) {
+		c.plain(".")
+	}
+}
+
+// apaAuthors lists authors as "Family, G. G.", joined with an ampersand before the last
+func apaAuthors(authors []string) string {
+	names := make([]string, len(authors))
+	for i, author := range authors {
+		family, given := splitSourceAuthor(author)
+		names[i] = family
+		if initials := apaInitials(given); initials != "" {
+			names[i] += ", " + initials
+		}
+	}
+
+	switch {
+	case len(names) == 0:
+		return ""
+	case len(names) == 1:
+		return names[0]
+	case len(names) == 2:
+		return names[0] + ", & " + names[1]
+	case len(names) > apaMaxListedAuthors:
+		// APA lists the first nineteen authors, an ellipsis and the last author
+		return strings.Join(names[:apaMaxListedAuthors-1], ", ") + ", . . . " + names[len(names)-1]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + ", & " + names[len(names)-1]
+}
+
+// apaInitials shortens given names to initials, keeping hyphens: "Mary-Jane Ann" becomes "M.-J. A."
+func apaInitials(given string) string {
+	var initials []string
+	for _, name := range strings.Fields(given) {
+		var parts []string
+		for _, part := range strings.Split(name, "-") {
+			if r, _ := utf8.DecodeRuneInString(part); r != utf8.RuneError {
+				parts = append(parts, string(unicode.ToUpper(r))+".")
+			}
+		}
+		if len(parts) > 0 {
+			initials = append(initials, strings.Join(parts, "-"))
+		}
+	}
+	return strings.Join(initials, " ")
+}
+
+// formatMLACitation formats a source as an MLA works cited entry
+func formatMLACitation(source *models.Source) *sourceCitation {
+	c := &sourceCitation{}
+	if authors := mlaAuthors(source.AuthorList); authors != "" {
+		c.plain(withPeriod(authors) + " ")
+	}
+
+	// Books are titled in italics, the parts of larger works in quotes and followed by the larger work
+	inContainer := false
+	if source.Type == models.SourceTypeBook {
+		c.italic(source.Title)
+		if !endsSentence(source.Title) {
+			c.plain(".")
+		}
+	} else {
+		c.plain(`"` + withPeriod(source.Title) + `"`)
+		if source.Container != "" {
+			c.plain(" ")
+			c.italic(source.Container)
+			inContainer = true
+		}
+	}
+
+	var details []string
+	if source.Volume != "" {
+		details = append(details, "vol. "+source.Volume)
+	}
+	if source.Issue != "" {
+		details = append(details, "no. "+source.Issue)
+	}
+	if source.Publisher != "" && source.Type != models.SourceTypeArticle {
+		details = append(details, source.Publisher)
+	}
+	if source.Year > 0 {
+		details = append(details, strconv.Itoa(source.Year))
+	}
+	if source.Pages != "" {
+		if strings.ContainsAny(source.Pages, "-–") {
+			details = append(details, "pp. "+source.Pages)
+		} else {
+			details = append(details, "p. "+source.Pages)
+		}
+	}
+	if link := sourceLink(source); link != "" {
+		details = append(details, link)
+	}
+
+	// A container's details follow it after a comma, a title's after its period
+	switch {
+	case inContainer && len(details) > 0:
+		c.plain(", " + strings.Join(details, ", ") + ".")
+	case inContainer:
+		c.plain(".")
+	case len(details) > 0:
+		c.plain(" " + strings.Join(details, ", ") + ".")
+	}
+
+	if source.Type == models.SourceTypeWebpage && source.AccessedAt != nil {
+		accessed := source.AccessedAt.UTC()
+		c.plain(fmt.Sprintf(" Accessed %d %s %d.", accessed.Day(), mlaMonths[accessed.Month()-1], accessed.Year()))
+	}
+	return c
+}
+
+// mlaAuthors names the first author "Family, Given", a second "Given Family" and more as "et al."
+func mlaAuthors(authors []string) string {
+	switch {
+	case len(authors) == 0:
+		return ""
+	case len(authors) > mlaMaxListedAuthors:
+		return authors[0] + ", et al."
+	case len(authors) == 2:
+		family, given := splitSourceAuthor(authors[1])
+		return authors[0] + ", and " + strings.TrimSpace(given+" "+family)
+	}
+	return authors[0]
+}
+
+// splitSourceAuthor splits an author written "Family, Given". Organizations have no given name.
+func splitSourceAuthor(author string) (family, given string) {
+	family, given, _ = strings.Cut(author, ",")
+	return strings.TrimSpace(family), strings.TrimSpace(given)
+}
+
+// sourceAuthorFromName turns a name written "Given Family" into "Family, Given"
+func sourceAuthorFromName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if strings.Contains(name, ",") {
+		return name
+	}
+	i := strings.LastIndex(name, " ")
+	if i < 0 {
+		return name
+	}
+	return name[i+1:] + ", " + name[:i]
+}
+
+// sourceLink is the link a bibliography gives for a source: its DOI when it has one
+func sourceLink(source *models.Source) string {
+	if source.DOI != "" {
+		return doiURLPrefix + source.DOI
+	}
+	return source.URL
+}
+
+// joinSourceTitle joins a title and its subtitle with a colon
+func joinSourceTitle(title, subtitle string) string {
+	title = strings.TrimSpace(title)
+	if subtitle = strings.TrimSpace(subtitle); subtitle == "" || title == "" {
+		return title
+	}
+	return title + ": " + subtitle
+}
+
+// withPeriod ends text with a period unless it ends a sentence already
+func withPeriod(s string) string {
+	if endsSentence(s) {
+		return s
+	}
+	return s + "."
+}
+
+// endsSentence reports whether text ends with a period, question mark or exclamation mark
+func endsSentence(s string) bool {
+	return strings.HasSuffix(s, ".") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "!")
+}
+
+// firstString returns the first of a list of strings, or "" when it's empty
+func firstString(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	testCrossrefWork = `{"message": {
+		"type": "journal-article",
+		"title": ["Attention is all you need"],
+		"container-title": ["Journal of Machine Learning"],
+		"publisher": "ML Press",
+		"volume": "12", "issue": "3", "page": "45-67",
+		"DOI": "10.1000/XYZ123",
+		"author": [
+			{"given": "Ada Maria", "family": "Lovelace"},
+			{"given": "Alan", "family": "Turing"},
+			{"name": "The Analytical Society"}
+		],
+		"issued": {"date-parts": [[2017, 6]]}
+	}}`
+	testOpenLibraryBook = `{"ISBN:9780306406157": {
+		"title": "The Art of Notes",
+		"subtitle": "A Field Guide",
+		"publish_date": "March 2004",
+		"authors": [{"name": "Grace Brewster Hopper"}],
+		"publishers": [{"name": "Plenum Press"}]
+	}}`
+)
+
+// setupTestSourceService creates a source service on an in-memory database. Registry responses are
+// served from the given map of URLs to JSON instead of being fetched.
+func setupTestSourceService(t *testing.T, responses map[string]string) *sourceServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.Source{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	now := time.Date(2025, 7, 3, 9, 0, 0, 0, time.UTC)
+	return &sourceServiceImpl{
+		db: db,
+		fetchJSON: func(ctx context.Context, rawURL string, out interface{}) error {
+			response, ok := responses[rawURL]
+			if !ok {
+				return ErrSourceMetadataNotFound
+			}
+			return json.Unmarshal([]byte(response), out)
+		},
+		now: func() time.Time { return now },
+	}
+}
+
+func TestSources_LookupAndCite(t *testing.T) {
+	service := setupTestSourceService(t, map[string]string{
+		"https://api.crossref.org/works/10.1000%2Fxyz123":                                       testCrossrefWork,
+		"https://openlibrary.org/api/books?bibkeys=ISBN%3A9780306406157&format=json&jscmd=data": testOpenLibraryBook,
+	})
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+
+	article, err := service.Create(ctx, note.ID, SourceInput{DOI: "https://doi.org/10.1000/XYZ123", Pages: "50"}, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, models.SourceTypeArticle, article.Type)
+	assert.Equal(t, "Attention is all you need", article.Title)
+	assert.Equal(t, []string{"Lovelace, Ada Maria", "Turing, Alan", "The Analytical Society"}, article.AuthorList)
+	assert.Equal(t, 2017, article.Year)
+	assert.Equal(t, "10.1000/xyz123", article.DOI)
+	assert.Equal(t, "50", article.Pages, "Details given with the DOI are kept")
+
+	book, err := service.Create(ctx, note.ID, SourceInput{ISBN: "0-306-40615-2"}, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, models.SourceTypeBook, book.Type)
+	assert.Equal(t, "The Art of Notes: A Field Guide", book.Title)
+	assert.Equal(t, []string{"Hopper, Grace Brewster"}, book.AuthorList)
+	assert.Equal(t, "9780306406157", book.ISBN, "ISBN-10s are stored as ISBN-13s")
+	assert.Equal(t, 2004, book.Year)
+
+	page, err := service.Create(ctx, note.ID, SourceInput{Title: "Team handbook", URL: "example.com/handbook"}, "user_owner")
+	require.NoError(t, err)
+	assert.Equal(t, models.SourceTypeWebpage, page.Type)
+	require.NotNil(t, page.AccessedAt)
+	assert.Equal(t, 2025, page.AccessedAt.Year())
+
+	_, err = service.Create(ctx, note.ID, SourceInput{DOI: "10.1000/missing"}, "user_owner")
+	assert.ErrorIs(t, err, ErrSourceMetadataNotFound)
+	for name, input := range map[string]SourceInput{
+		"bad ISBN check digit": {Title: "Book", ISBN: "0-306-40615-3"},
+		"not a DOI":            {Title: "Article", DOI: "11.1000/xyz"},
+		"page without URL":     {Title: "Page"},
+		"unknown type":         {Title: "Talk", Type: "podcast", URL: "https://example.com"},
+		"future year":          {Title: "Page", URL: "https://example.com", Year: 2030},
+	} {
+		_, err := service.Create(ctx, note.ID, input, "user_owner")
+		assert.ErrorIs(t, err, ErrInvalidSource, name)
+	}
+
+	sources, err := service.List(ctx, note.ID)
+	require.NoError(t, err)
+	require.Len(t, sources, 3)
+	assert.Equal(t, article.AuthorList, sources[0].AuthorList)
+
+	updated, err := service.Update(ctx, note.ID, page.ID, SourceInput{Title: "Team handbook", URL: "https://example.com/handbook", Container: "Example"})
+	require.NoError(t, err)
+	assert.Equal(t, "Example", updated.Container)
+	assert.NotNil(t, updated.AccessedAt, "Updates keep when the page was read")
+	require.NoError(t, service.Delete(ctx, note.ID, page.ID))
+	assert.ErrorIs(t, service.Delete(ctx, note.ID, page.ID), ErrSourceNotFound)
+
+	require.NoError(t, service.db.Model(&note).Update("locked", true).Error)
+	_, err = service.Create(ctx, note.ID, SourceInput{Title: "Page", URL: "https://example.com"}, "user_owner")
+	assert.ErrorIs(t, err, ErrNoteLocked)
+}
+
+func TestSources_Bibliography(t *testing.T) {
+	service := setupTestSourceService(t, nil)
+	ctx := context.Background()
+	note := createLifecycleNote(t, service.db, nil)
+	other := models.Notes{Name: "Travel", ChapterID: note.ChapterID}
+	require.NoError(t, service.db.Create(&other).Error)
+	accessed := time.Date(2025, 9, 14, 0, 0, 0, 0, time.UTC)
+
+	article := SourceInput{
+		Title: "Attention is all you need", Authors: []string{"Lovelace, Ada Maria", "Turing, Alan", "Hopper, Grace"},
+		Year: 2017, Container: "Journal of Machine Learning", Volume: "12", Issue: "3", Pages: "45-67", DOI: "10.1000/xyz123",
+	}
+	for _, input := range []SourceInput{
+		article,
+		{Type: models.SourceTypeBook, Title: "Notes on notes", Authors: []string{"Brown, Sam"}, Year: 2004, Publisher: "Plenum Press"},
+		{Title: "Team handbook", URL: "https://example.com/handbook", Container: "Example", AccessedAt: &accessed},
+	} {
+		_, err := service.Create(ctx, note.ID, input, "user_owner")
+		require.NoError(t, err)
+	}
+	_, err := service.Create(ctx, other.ID, article, "user_owner")
+	require.NoError(t, err)
+
+	var chapter models.Chapter
+	require.NoError(t, service.db.First(&chapter, "id = ?", note.ChapterID).Error)
+
+	apa, err := service.Bibliography(ctx, chapter.NotebookID, "")
+	require.NoError(t, err)
+	assert.Equal(t, BibliographyStyleAPA, apa.Style)
+	require.Len(t, apa.Entries, 3, "Works cited from several notes are listed once")
+	assert.Equal(t, "Brown, S. (2004). Notes on notes. Plenum Press.", apa.Entries[0].Text)
+	assert.Equal(t, "Brown, S. (2004). *Notes on notes*. Plenum Press.", apa.Entries[0].Markdown)
+	assert.Equal(t, "Lovelace, A. M., Turing, A., & Hopper, G. (2017). Attention is all you need. "+
+		"Journal of Machine Learning, 12(3), 45-67. https://doi.org/10.1000/xyz123", apa.Entries[1].Text)
+	assert.ElementsMatch(t, []string{note.ID, other.ID}, apa.Entries[1].NoteIDs)
+	assert.Len(t, apa.Entries[1].SourceIDs, 2)
+	assert.Equal(t, "Team handbook. (n.d.). Example. https://example.com/handbook", apa.Entries[2].Text)
+
+	mla, err := service.Bibliography(ctx, chapter.NotebookID, "MLA")
+	require.NoError(t, err)
+	require.Len(t, mla.Entries, 3)
+	assert.Equal(t, "Brown, Sam. Notes on notes. Plenum Press, 2004.", mla.Entries[0].Text)
+	assert.Equal(t, `Lovelace, Ada Maria, et al. "Attention is all you need." Journal of Machine Learning, `+
+		`vol. 12, no. 3, 2017, pp. 45-67, https://doi.org/10.1000/xyz123.`, mla.Entries[1].Text)
+	assert.Equal(t, `"Team handbook." Example, https://example.com/handbook. Accessed 14 Sept. 2025.`, mla.Entries[2].Text)
+	assert.Equal(t, `"Team handbook." *Example*, https://example.com/handbook. Accessed 14 Sept. 2025.`, mla.Entries[2].Markdown)
+
+	_, err = service.Bibliography(ctx, chapter.NotebookID, "chicago")
+	assert.ErrorIs(t, err, ErrInvalidSource)
+	_, err = service.Bibliography(ctx, "missing", "apa")
+	assert.ErrorIs(t, err, ErrNotebookNotFound)
+}
+
+func TestSourceAuthorFormats(t *testing.T) {
+	assert.Equal(t, "Curie, M.-S. A.", apaAuthors([]string{"Curie, Marie-Salome Ann"}))
+	assert.Equal(t, "Curie, M., & World Health Organization", apaAuthors([]string{"Curie, Marie", "World Health Organization"}))
+	assert.Equal(t, "Curie, Marie, and Pierre Curie", mlaAuthors([]string{"Curie, Marie", "Curie, Pierre"}))
+	assert.Equal(t, "Hopper, Grace Brewster", sourceAuthorFromName(" Grace  Brewster Hopper "))
+	assert.Equal(t, "Plato", sourceAuthorFromName("Plato"))
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSystemPromptNotFound is returned for prompts the app doesn't have and versions that don't exist
+	ErrSystemPromptNotFound = errors.New("system prompt not found")
+	// ErrInvalidSystemPrompt is returned when a prompt version is empty, too long or not a valid template
+	ErrInvalidSystemPrompt = errors.New("invalid system prompt")
+)
+
+// maxSystemPromptLength caps the length of a prompt version, in bytes
+const maxSystemPromptLength = 50000
+
+// builtinSystemPrompts are the prompts shipped with the app, by name. They seed the default prompts,
+// and are used as they are if the store can't be read.
+var builtinSystemPrompts = map[string]string{
+	models.SystemPromptChat: defaultChatSystemPrompt,
+}
+
+// SystemPromptData is what a prompt template can use
+type SystemPromptData struct {
+	Workspace string // "personal workspace" or "organization workspace"
+}
+
+// systemPromptPreviewData fills prompt templates when validating and previewing them
+var systemPromptPreviewData = SystemPromptData{Workspace: "personal workspace"}
+
+// SystemPromptInput is a new version of a prompt
+type SystemPromptInput struct {
+	Content  string `json:"content"`
+	Note     string `json:"note"`
+	Activate bool   `json:"activate"` // Put the version in use right away
+}
+
+// SystemPromptDetails is a prompt with the version in effect and the versions that can be activated
+type SystemPromptDetails struct {
+	Name      string                `json:"name"`
+	Active    *models.SystemPrompt  `json:"active"`    // In effect, nil while the built-in prompt is
+	Inherited bool                  `json:"inherited"` // The organization uses the default prompt
+	Variables []string              `json:"variables"` // Template fields, e.g. {{.Workspace}}
+	Versions  []models.SystemPrompt `json:"versions"`  // Of the organization, or of the default; newest first
+}
+
+// SystemPromptPreviewRequest asks for a prompt rendered as a chat would see it. Without content the
+// version in effect is rendered.
+type SystemPromptPreviewRequest struct {
+	Content   *string `json:"content,omitempty"`
+	Workspace string  `json:"workspace,omitempty"`
+}
+
+// SystemPromptPreview is a rendered prompt. Chats append the current time, tool restrictions and the
+// notebook or chapter a conversation is limited to.
+type SystemPromptPreview struct {
+	Prompt  string `json:"prompt"`
+	Tokens  int    `json:"tokens"`            // Estimated
+	Version *int   `json:"version,omitempty"` // The rendered version, unset for unsaved content
+}
+
+// SystemPromptService interface defines methods for managing and rendering versioned system prompts.
+// A nil organization ID means the default prompt.
+type SystemPromptService interface {
+	Seed() error
+	Render(name string, organizationID *string, data SystemPromptData) string
+	Get(name string, organizationID *string) (*SystemPromptDetails, error)
+	CreateVersion(name string, organizationID *string, input SystemPromptInput, createdBy string) (*models.SystemPrompt, error)
+	ActivateVersion(name string, organizationID *string, version int) (*models.SystemPrompt, error)
+	Reset(name string, organizationID string) error
+	Preview(name string, organizationID *string, req SystemPromptPreviewRequest) (*SystemPromptPreview, error)
+}
+
+// systemPromptServiceImpl implements the SystemPromptService interface
+type systemPromptServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewSystemPromptService creates a new SystemPromptService instance
+func NewSystemPromptService() SystemPromptService {
+	return &systemPromptServiceImpl{db: db.DB}
+}
+
+// Seed adds the built-in prompts as versions of the default prompts. A built-in prompt that changed
+// since it was last seeded becomes a new version, activated unless an admin activated one of their own.
+func (s *systemPromptServiceImpl) Seed() error {
+	for name, content := range builtinSystemPrompts {
+		var seeded models.SystemPrompt
+		err := s.scoped(name, nil).Where("created_by = ?", models.SystemPromptSeeder).Order("version DESC").First(&seeded).Error
+		if err == nil && seeded.Content == content {
+			continue
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to fetch seeded %s prompt: %w", name, err)
+		}
+
+		active, err := s.activeVersion(name, nil)
+		if err != nil && !errors.Is(err, ErrSystemPromptNotFound) {
+			return err
+		}
+		activate := active == nil || active.CreatedBy == models.SystemPromptSeeder
+
+		input := SystemPromptInput{Content: content, Note: "Built-in prompt", Activate: activate}
+		prompt, err := s.CreateVersion(name, nil, input, models.SystemPromptSeeder)
+		if err != nil {
+			return err
+		}
+		log.Info().Str("prompt", name).Int("version", prompt.Version).Bool("active", activate).Msg("Seeded built-in system prompt")
+	}
+	return nil
+}
+
+// Render returns a prompt for a workspace: the organization's active version, else the default's, else
+// the built-in prompt. Chats shouldn't stop because prompts can't be read, so failures fall back to the
+// built-in prompt.
+func (s *systemPromptServiceImpl) Render(name string, organizationID *string, data SystemPromptData) string {
+	content := builtinSystemPrompts[name]
+	prompt, err := s.effectiveVersion(name, organizationID)
+	switch {
+	case err == nil:
+		content = prompt.Content
+	case !errors.Is(err, ErrSystemPromptNotFound):
+		log.Warn().Err(err).Str("prompt", name).Msg("Failed to fetch system prompt, using the built-in prompt")
+	}
+
+	rendered, err := renderSystemPrompt(name, content, data)
+	if err != nil {
+		log.Warn().Err(err).Str("prompt", name).Msg("Failed to render system prompt, using the built-in prompt")
+		rendered, _ = renderSystemPrompt(name, builtinSystemPrompts[name], data)
+	}
+	return rendered
+}
+
+// Get returns the version of a prompt in effect and the versions of the organization, or of the default
+func (s *systemPromptServiceImpl) Get(name string, organizationID *string) (*SystemPromptDetails, error) {
+	if _, ok := builtinSystemPrompts[name]; !ok {
+		return nil, ErrSystemPromptNotFound
+	}
+
+	details := &SystemPromptDetails{Name: name, Variables: []string{"Workspace"}}
+	active, err := s.effectiveVersion(name, organizationID)
+	if err != nil && !errors.Is(err, ErrSystemPromptNotFound) {
+		return nil, err
+	}
+	if active != nil {
+		details.Active = active
+		details.Inherited = organizationID != nil && active.OrganizationID == nil
+	}
+
+	if err := s.scoped(name, organizationID).Order("version DESC").Find(&details.Versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch system prompt versions: %w", err)
+	}
+	return details, nil
+}
+
+// CreateVersion adds a version of a prompt, validated by rendering it
+func (s *systemPromptServiceImpl) CreateVersion(name string, organizationID *string, input SystemPromptInput, createdBy string) (*models.SystemPrompt, error) {
+	if _, ok := builtinSystemPrompts[name]; !ok {
+		return nil, ErrSystemPromptNotFound
+	}
+	if err := validateSystemPrompt(name, input.Content); err != nil {
+		return nil, err
+	}
+
+	prompt := &models.SystemPrompt{
+		Name:           name,
+		OrganizationID: organizationID,
+		Content:        input.Content,
+		Note:           truncateLinkText(strings.TrimSpace(input.Note), 500),
+		CreatedBy:      createdBy,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := s.scopedTx(tx, name, organizationID).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return fmt.Errorf("failed to fetch latest version: %w", err)
+		}
+		prompt.Version = latest + 1
+
+		if input.Activate {
+			if err := s.scopedTx(tx, name, organizationID).Where("active = ?", true).Update("active", false).Error; err != nil {
+				return fmt.Errorf("failed to deactivate system prompt: %w", err)
+			}
+			prompt.Active = true
+		}
+		if err := tx.Create(prompt).Error; err != nil {
+			return fmt.Errorf("failed to create system prompt version: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prompt, nil
+}
+
+// ActivateVersion puts a version of a prompt in use, to roll out a new one or roll back to an old one
+func (s *systemPromptServiceImpl) ActivateVersion(name string, organizationID *string, version int) (*models.SystemPrompt, error) {
+	var prompt models.SystemPrompt
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.scopedTx(tx, name, organizationID).Where("version = ?", version).First(&prompt).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSystemPromptNotFound
+			}
+			return fmt.Errorf("failed to fetch system prompt version: %w", err)
+		}
+		if err := s.scopedTx(tx, name, organizationID).Where("active = ? AND id <> ?", true, prompt.ID).Update("active", false).Error; err != nil {
+			return fmt.Errorf("failed to deactivate system prompt: %w", err)
+		}
+		if err := tx.Model(&prompt).Update("active", true).Error; err != nil {
+			return fmt.Errorf("failed to activate system prompt: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// Reset returns an organization to the default prompt. Its versions are kept to activate again later.
+func (s *systemPromptServiceImpl) Reset(name string, organizationID string) error {
+	if _, ok := builtinSystemPrompts[name]; !ok {
+		return ErrSystemPromptNotFound
+	}
+	if err := s.scoped(name, &organizationID).Where("active = ?", true).Update("active", false).Error; err != nil {
+		return fmt.Errorf("failed to reset system prompt: %w", err)
+	}
+	return nil
+}
+
+// Preview renders unsaved content, or the version in effect, with sample workspace data
+func (s *systemPromptServiceImpl) Preview(name string, organizationID *string, req SystemPromptPreviewRequest) (*SystemPromptPreview, error) {
+	builtin, ok := builtinSystemPrompts[name]
+	if !ok {
+		return nil, ErrSystemPromptNotFound
+	}
+
+	data := systemPromptPreviewData
+	if workspace := strings.TrimSpace(req.Workspace); workspace != "" {
+		data.Workspace = workspace
+	}
+
+	preview := &SystemPromptPreview{}
+	content := builtin
+	if req.Content != nil {
+		if err := validateSystemPrompt(name, *req.Content); err != nil {
+			return nil, err
+		}
+		content = *req.Content
+	} else {
+		prompt, err := s.effectiveVersion(name, organizationID)
+		if err != nil && !errors.Is(err, ErrSystemPromptNotFound) {
+			return nil, err
+		}
+		if prompt != nil {
+			content = prompt.Content
+			preview.Version = &prompt.Version
+		}
+	}
+
+	rendered, err := renderSystemPrompt(name, content, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSystemPrompt, err)
+	}
+	preview.Prompt = rendered
+	preview.Tokens = EstimateTokens(rendered)
+	return preview, nil
+}
+
+// effectiveVersion returns the version of a prompt in effect for a workspace: the organization's active
+// version, else the default's
+func (s *systemPromptServiceImpl) effectiveVersion(name string, organizationID *string) (*models.SystemPrompt, error) {
+	if organizationID != nil && *organizationID != "" {
+		prompt, err := s.activeVersion(name, organizationID)
+		if !errors.Is(err, ErrSystemPromptNotFound) {
+			return prompt, err
+		}
+	}
+	return s.activeVersion(name, nil)
+}
+
+func (s *systemPromptServiceImpl) activeVersion(name string, organizationID *string) (*models.SystemPrompt, error) {
+	var prompt models.SystemPrompt
+	if err := s.scoped(name, organizationID).Where("active = ?", true).First(&prompt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSystemPromptNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch system prompt: %w", err)
+	}
+	return &prompt, nil
+}
+
+// scoped returns a query for the versions of a prompt of an organization, or of the default
+func (s *systemPromptServiceImpl) scoped(name string, organizationID *string) *gorm.DB {
+	return s.scopedTx(s.db, name, organizationID)
+}
+
+func (s *systemPromptServiceImpl) scopedTx(tx *gorm.DB, name string, organizationID *string) *gorm.DB {
+	query := tx.Model(&models.SystemPrompt{}).Where("name = ?", name)
+	if organizationID == nil {
+		return query.Where("organization_id IS NULL")
+	}
+	return query.Where("organization_id = ?", *organizationID)
+}
+
+// validateSystemPrompt checks a prompt isn't empty or too long and renders as a template
+func validateSystemPrompt(name, content string) error {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("%w: content is required", ErrInvalidSystemPrompt)
+	}
+	if len(content) > maxSystemPromptLength {
+		return fmt.Errorf("%w: content is longer than %d characters", ErrInvalidSystemPrompt, maxSystemPromptLength)
+	}
+	if _, err := renderSystemPrompt(name, content, systemPromptPreviewData); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSystemPrompt, err)
+	}
+	return nil
+}
+
+func renderSystemPrompt(name, content string, data SystemPromptData) (string, error) {
+	tmpl, err := template.New(name).Parse(content)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
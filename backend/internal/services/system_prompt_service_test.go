@@ -0,0 +1,135 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestSystemPromptService creates a system prompt service on an in-memory database, with a short
+// built-in chat prompt
+func setupTestSystemPromptService(t *testing.T) *systemPromptServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.SystemPrompt{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	setBuiltinChatPrompt(t, "Built-in prompt for the {{.Workspace}}.")
+	return &systemPromptServiceImpl{db: db}
+}
+
+func setBuiltinChatPrompt(t *testing.T, content string) {
+	previous := builtinSystemPrompts[models.SystemPromptChat]
+	builtinSystemPrompts[models.SystemPromptChat] = content
+	t.Cleanup(func() { builtinSystemPrompts[models.SystemPromptChat] = previous })
+}
+
+func TestSystemPrompt_BuiltinPromptRenders(t *testing.T) {
+	_, err := renderSystemPrompt(models.SystemPromptChat, defaultChatSystemPrompt, systemPromptPreviewData)
+	assert.NoError(t, err)
+}
+
+func TestSystemPrompt_SeedAndRender(t *testing.T) {
+	service := setupTestSystemPromptService(t)
+	data := SystemPromptData{Workspace: "personal workspace"}
+
+	assert.Equal(t, "Built-in prompt for the personal workspace.", service.Render(models.SystemPromptChat, nil, data),
+		"The built-in prompt is used before seeding")
+
+	require.NoError(t, service.Seed())
+	require.NoError(t, service.Seed(), "Seeding again should add nothing")
+	details, err := service.Get(models.SystemPromptChat, nil)
+	require.NoError(t, err)
+	require.Len(t, details.Versions, 1)
+	require.NotNil(t, details.Active)
+	assert.Equal(t, models.SystemPromptSeeder, details.Active.CreatedBy)
+
+	// A changed built-in prompt is seeded as a new active version
+	setBuiltinChatPrompt(t, "New built-in prompt.")
+	require.NoError(t, service.Seed())
+	assert.Equal(t, "New built-in prompt.", service.Render(models.SystemPromptChat, nil, data))
+
+	// Unless an admin activated a version of their own
+	_, err = service.CreateVersion(models.SystemPromptChat, nil, SystemPromptInput{Content: "Admin prompt.", Activate: true}, "admin_1")
+	require.NoError(t, err)
+	setBuiltinChatPrompt(t, "Newer built-in prompt.")
+	require.NoError(t, service.Seed())
+	assert.Equal(t, "Admin prompt.", service.Render(models.SystemPromptChat, nil, data))
+
+	details, err = service.Get(models.SystemPromptChat, nil)
+	require.NoError(t, err)
+	assert.Len(t, details.Versions, 4)
+	assert.Equal(t, 4, details.Versions[0].Version, "Newest versions first")
+}
+
+func TestSystemPrompt_OrganizationVersions(t *testing.T) {
+	service := setupTestSystemPromptService(t)
+	require.NoError(t, service.Seed())
+	orgID := "org_1"
+	data := SystemPromptData{Workspace: "organization workspace"}
+
+	details, err := service.Get(models.SystemPromptChat, &orgID)
+	require.NoError(t, err)
+	assert.True(t, details.Inherited)
+	assert.Empty(t, details.Versions)
+
+	first, err := service.CreateVersion(models.SystemPromptChat, &orgID, SystemPromptInput{Content: "Acme prompt for the {{.Workspace}}.", Activate: true}, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Version)
+	assert.Equal(t, "Acme prompt for the organization workspace.", service.Render(models.SystemPromptChat, &orgID, data))
+	assert.Equal(t, "Built-in prompt for the personal workspace.", service.Render(models.SystemPromptChat, nil, SystemPromptData{Workspace: "personal workspace"}),
+		"Other workspaces keep the default")
+
+	// Drafts aren't used until activated, and old versions can be rolled back to
+	second, err := service.CreateVersion(models.SystemPromptChat, &orgID, SystemPromptInput{Content: "Acme draft."}, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Version)
+	assert.Contains(t, service.Render(models.SystemPromptChat, &orgID, data), "Acme prompt")
+	_, err = service.ActivateVersion(models.SystemPromptChat, &orgID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Acme draft.", service.Render(models.SystemPromptChat, &orgID, data))
+	_, err = service.ActivateVersion(models.SystemPromptChat, &orgID, 1)
+	require.NoError(t, err)
+	assert.Contains(t, service.Render(models.SystemPromptChat, &orgID, data), "Acme prompt")
+	_, err = service.ActivateVersion(models.SystemPromptChat, &orgID, 9)
+	assert.ErrorIs(t, err, ErrSystemPromptNotFound)
+
+	require.NoError(t, service.Reset(models.SystemPromptChat, orgID))
+	assert.Equal(t, "Built-in prompt for the organization workspace.", service.Render(models.SystemPromptChat, &orgID, data))
+	details, err = service.Get(models.SystemPromptChat, &orgID)
+	require.NoError(t, err)
+	assert.True(t, details.Inherited)
+	assert.Len(t, details.Versions, 2, "Resetting keeps the versions")
+}
+
+func TestSystemPrompt_ValidationAndPreview(t *testing.T) {
+	service := setupTestSystemPromptService(t)
+
+	_, err := service.CreateVersion(models.SystemPromptChat, nil, SystemPromptInput{Content: "  "}, "admin_1")
+	assert.ErrorIs(t, err, ErrInvalidSystemPrompt)
+	_, err = service.CreateVersion(models.SystemPromptChat, nil, SystemPromptInput{Content: "Hello {{.Workspace"}, "admin_1")
+	assert.ErrorIs(t, err, ErrInvalidSystemPrompt, "Templates must parse")
+	_, err = service.CreateVersion(models.SystemPromptChat, nil, SystemPromptInput{Content: "Hello {{.User}}"}, "admin_1")
+	assert.ErrorIs(t, err, ErrInvalidSystemPrompt, "Templates may only use known fields")
+	_, err = service.CreateVersion("unknown", nil, SystemPromptInput{Content: "Hello"}, "admin_1")
+	assert.ErrorIs(t, err, ErrSystemPromptNotFound)
+
+	draft := "Draft for the {{.Workspace}}."
+	preview, err := service.Preview(models.SystemPromptChat, nil, SystemPromptPreviewRequest{Content: &draft, Workspace: "organization workspace"})
+	require.NoError(t, err)
+	assert.Equal(t, "Draft for the organization workspace.", preview.Prompt)
+	assert.Positive(t, preview.Tokens)
+	assert.Nil(t, preview.Version)
+
+	require.NoError(t, service.Seed())
+	preview, err = service.Preview(models.SystemPromptChat, nil, SystemPromptPreviewRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "Built-in prompt for the personal workspace.", preview.Prompt)
+	require.NotNil(t, preview.Version)
+	assert.Equal(t, 1, *preview.Version)
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"backend/pkg/recallai"
+	whatsappclient "backend/pkg/whatsapp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/clerk/clerk-sdk-go/v2/jwks"
+	"github.com/rs/zerolog/log"
+)
+
+// Dependency states
+const (
+	DependencyOK            = "ok"
+	DependencyNotConfigured = "not_configured" // Features needing it are off, nothing is broken
+	DependencyDegraded      = "degraded"
+	DependencyDown          = "down"
+)
+
+// Dependencies the system status reports on
+const (
+	DependencyDatabase = "database"
+	DependencyClerk    = "clerk"
+	DependencyRecallAI = "recall_ai"
+	DependencyStorage  = "storage"
+	DependencyWhatsApp = "whatsapp"
+)
+
+const (
+	systemStatusCacheTTL     = time.Minute
+	systemStatusProbeTimeout = 5 * time.Second
+)
+
+// DependencyStatus is the last probe of an external dependency. Impact says what users lose while it
+// isn't ok, for the frontend to show as a banner.
+type DependencyStatus struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+	Impact    string    `json:"impact,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// AIProviderStatus is whether an AI provider can serve the user: a key they may use and a closed circuit
+type AIProviderStatus struct {
+	Provider  string       `json:"provider"`
+	Status    string       `json:"status"`
+	KeySource APIKeySource `json:"keySource,omitempty"`
+	InChain   bool         `json:"inChain"` // Tried when the preferred provider fails
+	Impact    string       `json:"impact,omitempty"`
+}
+
+// SystemStatus is the health of the server's dependencies. Status is down when the database is, and
+// degraded when any other dependency is; ones that aren't configured don't count.
+type SystemStatus struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+	AIProviders  []AIProviderStatus `json:"aiProviders"`
+}
+
+// SystemStatusService reports the health of external dependencies
+type SystemStatusService interface {
+	GetStatus(ctx context.Context, clerkUserID string, organizationID *string) (*SystemStatus, error)
+}
+
+// dependencyProbe checks one dependency. A nil check means the dependency isn't configured.
+type dependencyProbe struct {
+	name   string
+	impact string
+	check  func(ctx context.Context) error
+}
+
+// cachedProbe is a probe with its last result. The lock keeps concurrent requests from probing the
+// same dependency at once.
+type cachedProbe struct {
+	probe  func() dependencyProbe
+	mu     sync.Mutex
+	result *DependencyStatus
+}
+
+type systemStatusServiceImpl struct {
+	probes         []*cachedProbe
+	ttl            time.Duration
+	timeout        time.Duration
+	apiKeyResolver APIKeyResolver
+	policy         *AIProviderPolicy
+}
+
+var (
+	systemStatusService     SystemStatusService
+	systemStatusServiceOnce sync.Once
+)
+
+// GetSystemStatusService returns the shared system status service, so probe results are cached for all
+// requests
+func GetSystemStatusService() SystemStatusService {
+	systemStatusServiceOnce.Do(func() {
+		systemStatusService = newSystemStatusService(
+			defaultDependencyProbes(),
+			systemStatusCacheTTL,
+			systemStatusProbeTimeout,
+			NewAPIKeyResolver(),
+			GetAIProviderPolicy(),
+		)
+	})
+	return systemStatusService
+}
+
+func newSystemStatusService(probes []func() dependencyProbe, ttl, timeout time.Duration, apiKeyResolver APIKeyResolver, policy *AIProviderPolicy) *systemStatusServiceImpl {
+	cached := make([]*cachedProbe, len(probes))
+	for i, probe := range probes {
+		cached[i] = &cachedProbe{probe: probe}
+	}
+	return &systemStatusServiceImpl{
+		probes:         cached,
+		ttl:            ttl,
+		timeout:        timeout,
+		apiKeyResolver: apiKeyResolver,
+		policy:         policy,
+	}
+}
+
+// defaultDependencyProbes builds the probes when they run, since settings saved by setup apply without
+// a restart
+func defaultDependencyProbes() []func() dependencyProbe {
+	whatsappConfig, whatsappErr := config.LoadWhatsAppConfig()
+	return []func() dependencyProbe{
+		func() dependencyProbe {
+			return dependencyProbe{name: DependencyDatabase, impact: "Notes can't be loaded or saved", check: pingDatabase}
+		},
+		func() dependencyProbe {
+			probe := dependencyProbe{name: DependencyClerk}
+			if accessLookups.SingleUser() {
+				// Single-user mode doesn't use Clerk, nothing is missing without it
+				return probe
+			}
+			probe.impact = "Sign-in and organizations are unavailable"
+			if os.Getenv("CLERK_SECRET_KEY") != "" {
+				probe.check = func(ctx context.Context) error {
+					_, err := jwks.Get(ctx, &jwks.GetParams{})
+					return err
+				}
+			}
+			return probe
+		},
+		func() dependencyProbe {
+			probe := dependencyProbe{name: DependencyRecallAI, impact: "Meeting bots are unavailable"}
+			if client := recallai.NewClient(); client.APIKey != "" {
+				probe.check = client.Ping
+			}
+			return probe
+		},
+		func() dependencyProbe {
+			return dependencyProbe{name: DependencyStorage, impact: "Settings can't be saved", check: checkSettingsWritable}
+		},
+		func() dependencyProbe {
+			probe := dependencyProbe{name: DependencyWhatsApp, impact: "WhatsApp messages aren't being answered"}
+			if whatsappErr == nil {
+				probe.check = whatsappclient.NewClient(whatsappConfig).Ping
+			}
+			return probe
+		},
+	}
+}
+
+// GetStatus returns the dependencies' cached probe results, probing the stale ones in parallel, and
+// the AI providers the user can use
+func (s *systemStatusServiceImpl) GetStatus(ctx context.Context, clerkUserID string, organizationID *string) (*SystemStatus, error) {
+	status := &SystemStatus{
+		Status:       DependencyOK,
+		Dependencies: make([]DependencyStatus, len(s.probes)),
+	}
+
+	var wg sync.WaitGroup
+	for i, probe := range s.probes {
+		wg.Add(1)
+		go func(i int, probe *cachedProbe) {
+			defer wg.Done()
+			status.Dependencies[i] = s.check(ctx, probe)
+		}(i, probe)
+	}
+	wg.Wait()
+
+	for _, dependency := range status.Dependencies {
+		switch {
+		case dependency.Status == DependencyDown && dependency.Name == DependencyDatabase:
+			status.Status = DependencyDown
+		case dependency.Status == DependencyDown, dependency.Status == DependencyDegraded:
+			if status.Status == DependencyOK {
+				status.Status = DependencyDegraded
+			}
+		}
+	}
+	status.AIProviders = s.aiProviderStatuses(clerkUserID, organizationID)
+	return status, nil
+}
+
+// check returns a probe's last result, probing again once it's older than the cache TTL
+func (s *systemStatusServiceImpl) check(ctx context.Context, cached *cachedProbe) DependencyStatus {
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+	if cached.result != nil && time.Since(cached.result.CheckedAt) < s.ttl {
+		return *cached.result
+	}
+
+	probe := cached.probe()
+	result := DependencyStatus{Name: probe.name, Status: DependencyOK, CheckedAt: time.Now()}
+	if probe.check == nil {
+		result.Status = DependencyNotConfigured
+		result.Impact = probe.impact
+	} else {
+		// A request that goes away mid-probe shouldn't leave a failure cached for everyone
+		probeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+		err := probe.check(probeCtx)
+		cancel()
+		result.LatencyMs = time.Since(result.CheckedAt).Milliseconds()
+		if err != nil {
+			// The error stays in the logs, it can name internal hosts
+			log.Warn().Err(err).Str("dependency", probe.name).Msg("Dependency health check failed")
+			result.Status = DependencyDown
+			result.Message = "Health check failed"
+			if errors.Is(err, context.DeadlineExceeded) {
+				result.Message = "Health check timed out"
+			}
+			result.Impact = probe.impact
+		} else if result.LatencyMs > (s.timeout / 2).Milliseconds() {
+			result.Status = DependencyDegraded
+			result.Message = "Responding slowly"
+			result.Impact = probe.impact
+		}
+	}
+	cached.result = &result
+	return result
+}
+
+// aiProviderStatuses reports each supported provider for the user. Keys come from the organization,
+// the user or the server's environment, like AI calls resolve them.
+func (s *systemStatusServiceImpl) aiProviderStatuses(clerkUserID string, organizationID *string) []AIProviderStatus {
+	inChain := map[string]bool{}
+	for _, provider := range s.policy.ProviderChain("") {
+		inChain[provider] = true
+	}
+
+	providers := []string{config.AIProviderOpenAI, config.AIProviderAnthropic, config.AIProviderGoogle}
+	statuses := make([]AIProviderStatus, len(providers))
+	for i, provider := range providers {
+		status := AIProviderStatus{Provider: provider, Status: DependencyOK, InChain: inChain[provider]}
+		if source, err := s.apiKeyResolver.GetAPIKeySource(clerkUserID, organizationID, provider); err == nil {
+			status.KeySource = source
+		} else if os.Getenv(providerEnvAPIKeys[provider]) != "" {
+			status.KeySource = APIKeySourceEnvironment
+		}
+
+		switch {
+		case status.KeySource == "":
+			status.Status = DependencyNotConfigured
+			status.Impact = fmt.Sprintf("No %s API key is configured", provider)
+		case !s.policy.Allow(provider):
+			status.Status = DependencyDown
+			status.Impact = fmt.Sprintf("%s is failing, AI features fall back to other providers", provider)
+		}
+		statuses[i] = status
+	}
+	return statuses
+}
+
+// pingDatabase checks the primary database answers
+func pingDatabase(ctx context.Context) error {
+	if db.DB == nil {
+		return fmt.Errorf("database isn't initialized")
+	}
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// checkSettingsWritable checks settings saved through setup can be written next to the settings file
+func checkSettingsWritable(_ context.Context) error {
+	file, err := os.CreateTemp(filepath.Dir(config.SettingsFilePath()), ".status-check-*")
+	if err != nil {
+		return fmt.Errorf("settings directory isn't writable: %w", err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"backend/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubKeyResolver reports keys for the providers it's given, from the given source
+type stubKeyResolver struct {
+	source    APIKeySource
+	providers map[string]bool
+}
+
+func (r *stubKeyResolver) GetAPIKey(clerkUserID string, organizationID *string, provider string) (*APIKeyResult, error) {
+	source, err := r.GetAPIKeySource(clerkUserID, organizationID, provider)
+	if err != nil {
+		return nil, err
+	}
+	return &APIKeyResult{APIKey: "key", Source: source}, nil
+}
+
+func (r *stubKeyResolver) GetAPIKeySource(_ string, _ *string, provider string) (APIKeySource, error) {
+	if !r.providers[provider] {
+		return "", fmt.Errorf("no API key configured for provider %s", provider)
+	}
+	return r.source, nil
+}
+
+// countingProbe returns a probe that counts its checks and fails with err
+func countingProbe(name string, calls *int32, err error) func() dependencyProbe {
+	return func() dependencyProbe {
+		return dependencyProbe{name: name, impact: name + " is unavailable", check: func(context.Context) error {
+			atomic.AddInt32(calls, 1)
+			return err
+		}}
+	}
+}
+
+// setupTestSystemStatusService creates a status service with the given probes, no AI keys and a policy
+// whose circuits open on the first failure
+func setupTestSystemStatusService(t *testing.T, probes ...func() dependencyProbe) *systemStatusServiceImpl {
+	for _, key := range providerEnvAPIKeys {
+		t.Setenv(key, "")
+	}
+	policy := NewAIProviderPolicy(&config.AIConfig{
+		ProviderChain:           []string{config.AIProviderOpenAI, config.AIProviderAnthropic},
+		CircuitFailureThreshold: 1,
+		CircuitCooldown:         time.Minute,
+	})
+	return newSystemStatusService(probes, time.Minute, time.Second, &stubKeyResolver{providers: map[string]bool{}}, policy)
+}
+
+func TestSystemStatus_CachesProbes(t *testing.T) {
+	var databaseCalls, recallCalls int32
+	service := setupTestSystemStatusService(t,
+		countingProbe(DependencyDatabase, &databaseCalls, nil),
+		countingProbe(DependencyRecallAI, &recallCalls, errors.New("dial tcp 10.0.0.5:443: connection refused")),
+		func() dependencyProbe { return dependencyProbe{name: DependencyWhatsApp, impact: "WhatsApp is off"} },
+	)
+
+	status, err := service.GetStatus(context.Background(), "user_1", nil)
+	require.NoError(t, err)
+	require.Len(t, status.Dependencies, 3)
+	assert.Equal(t, DependencyDegraded, status.Status, "A failing dependency other than the database only degrades the server")
+
+	assert.Equal(t, DependencyOK, status.Dependencies[0].Status)
+	assert.Empty(t, status.Dependencies[0].Impact)
+	assert.Equal(t, DependencyDown, status.Dependencies[1].Status)
+	assert.Equal(t, "Health check failed", status.Dependencies[1].Message, "Probe errors shouldn't reach users")
+	assert.Equal(t, "recall_ai is unavailable", status.Dependencies[1].Impact)
+	assert.Equal(t, DependencyNotConfigured, status.Dependencies[2].Status)
+
+	_, err = service.GetStatus(context.Background(), "user_1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&databaseCalls), "Fresh results should come from the cache")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&recallCalls), "Failures should be cached too")
+
+	service.ttl = 0
+	_, err = service.GetStatus(context.Background(), "user_1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&databaseCalls), "Stale results should be probed again")
+}
+
+func TestSystemStatus_DatabaseDown(t *testing.T) {
+	var calls int32
+	service := setupTestSystemStatusService(t, countingProbe(DependencyDatabase, &calls, errors.New("connection refused")))
+
+	status, err := service.GetStatus(context.Background(), "user_1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, DependencyDown, status.Status)
+}
+
+func TestSystemStatus_ProbeTimeout(t *testing.T) {
+	service := setupTestSystemStatusService(t, func() dependencyProbe {
+		return dependencyProbe{name: DependencyClerk, check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}}
+	})
+	service.timeout = 20 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	status, err := service.GetStatus(ctx, "user_1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, DependencyDown, status.Dependencies[0].Status)
+	assert.Equal(t, "Health check timed out", status.Dependencies[0].Message, "Probes should outlive a cancelled request, up to their timeout")
+}
+
+func TestSystemStatus_AIProviders(t *testing.T) {
+	service := setupTestSystemStatusService(t)
+	service.apiKeyResolver = &stubKeyResolver{source: APIKeySourceOrganization, providers: map[string]bool{config.AIProviderOpenAI: true}}
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant")
+	service.policy.RecordFailure(config.AIProviderAnthropic)
+
+	orgID := "org_1"
+	status, err := service.GetStatus(context.Background(), "user_1", &orgID)
+	require.NoError(t, err)
+	require.Len(t, status.AIProviders, 3)
+
+	openAI, anthropic, google := status.AIProviders[0], status.AIProviders[1], status.AIProviders[2]
+	assert.Equal(t, DependencyOK, openAI.Status)
+	assert.Equal(t, APIKeySourceOrganization, openAI.KeySource)
+	assert.True(t, openAI.InChain)
+
+	assert.Equal(t, DependencyDown, anthropic.Status, "A provider with an open circuit is down")
+	assert.Equal(t, APIKeySourceEnvironment, anthropic.KeySource)
+	assert.NotEmpty(t, anthropic.Impact)
+
+	assert.Equal(t, DependencyNotConfigured, google.Status)
+	assert.Empty(t, google.KeySource)
+	assert.False(t, google.InChain)
+}
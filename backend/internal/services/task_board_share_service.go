@@ -0,0 +1,307 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTaskBoardNotFound is returned when publishing a board that doesn't exist
+	ErrTaskBoardNotFound = errors.New("task board not found")
+	// ErrBoardShareNotFound is returned for boards that aren't published and for unknown links
+	ErrBoardShareNotFound = errors.New("shared board not found")
+	// ErrBoardShareExpired is returned for links past their expiry
+	ErrBoardShareExpired = errors.New("this link has expired")
+	// ErrBoardSharePasswordRequired is returned when a password protected board is opened without one
+	ErrBoardSharePasswordRequired = errors.New("this board is password protected")
+	// ErrBoardSharePasswordIncorrect is returned when the password doesn't match
+	ErrBoardSharePasswordIncorrect = errors.New("incorrect password")
+	// ErrInvalidBoardShare is returned for a password or expiry that can't be used
+	ErrInvalidBoardShare = errors.New("invalid share settings")
+)
+
+const (
+	// boardSharePasswordIterations is the PBKDF2-SHA256 work factor of share passwords. It's lower than a
+	// note lock's since every view of a protected board checks the password.
+	boardSharePasswordIterations = 100000
+	minBoardSharePasswordLength  = 4
+	maxBoardSharePasswordLength  = 128
+	maxBoardShareLifetime        = 365 * 24 * time.Hour
+)
+
+// boardColumns are the columns of a shared board in order, with the statuses of the tasks in them
+var boardColumns = []struct{ status, name string }{
+	{"backlog", "Backlog"},
+	{"todo", "To Do"},
+	{"in_progress", "In Progress"},
+	{"done", "Done"},
+}
+
+// BoardShareInput is the request body for publishing a board. Both settings are optional.
+type BoardShareInput struct {
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// BoardShare is the API representation of a board's public link, for the board's members
+type BoardShare struct {
+	Token             string     `json:"token"`
+	URL               string     `json:"url"`
+	PasswordProtected bool       `json:"passwordProtected"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
+	CreatedBy         string     `json:"createdBy"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// PublicTaskBoard is what a shared board's link shows: its columns and cards, without who is assigned
+// to them or where they link to
+type PublicTaskBoard struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Columns     []PublicBoardColumn `json:"columns"`
+	UpdatedAt   time.Time           `json:"updatedAt"`
+	ExpiresAt   *time.Time          `json:"expiresAt,omitempty"`
+}
+
+// PublicBoardColumn is a column of a shared board
+type PublicBoardColumn struct {
+	Status string            `json:"status"`
+	Name   string            `json:"name"`
+	Tasks  []PublicBoardTask `json:"tasks"`
+}
+
+// PublicBoardTask is a task card of a shared board
+type PublicBoardTask struct {
+	ID            string     `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Priority      string     `json:"priority"`
+	StartDate     *time.Time `json:"startDate,omitempty"`
+	DurationDays  int        `json:"durationDays"`
+	CompletedAt   *time.Time `json:"completedAt,omitempty"`
+	AssigneeCount int        `json:"assigneeCount"`
+}
+
+// TaskBoardShareService interface defines methods for publishing read-only views of task boards
+type TaskBoardShareService interface {
+	Publish(ctx context.Context, boardID, createdBy string, input BoardShareInput) (*BoardShare, error)
+	GetShare(ctx context.Context, boardID string) (*BoardShare, error)
+	Revoke(ctx context.Context, boardID string) error
+	GetPublicBoard(ctx context.Context, token, password string) (*PublicTaskBoard, error)
+}
+
+// taskBoardShareServiceImpl implements the TaskBoardShareService interface
+type taskBoardShareServiceImpl struct {
+	db              *gorm.DB
+	now             func() time.Time
+	iterations      int
+	frontendBaseURL string
+}
+
+// NewTaskBoardShareService creates a new TaskBoardShareService instance
+func NewTaskBoardShareService() TaskBoardShareService {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173"
+	}
+
+	return &taskBoardShareServiceImpl{
+		db:              db.DB,
+		now:             time.Now,
+		iterations:      boardSharePasswordIterations,
+		frontendBaseURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// Publish creates the board's public link, replacing the one it had so the old link stops working
+func (s *taskBoardShareServiceImpl) Publish(ctx context.Context, boardID, createdBy string, input BoardShareInput) (*BoardShare, error) {
+	share := models.TaskBoardShare{TaskBoardID: boardID, CreatedBy: createdBy}
+	if input.ExpiresAt != nil {
+		expiresAt := input.ExpiresAt.UTC()
+		if !expiresAt.After(s.now()) || expiresAt.Sub(s.now()) > maxBoardShareLifetime {
+			return nil, fmt.Errorf("%w: links must expire in the future and within a year", ErrInvalidBoardShare)
+		}
+		share.ExpiresAt = &expiresAt
+	}
+	if input.Password != "" {
+		length := utf8.RuneCountInString(input.Password)
+		if length < minBoardSharePasswordLength || length > maxBoardSharePasswordLength {
+			return nil, fmt.Errorf("%w: passwords must be %d to %d characters", ErrInvalidBoardShare,
+				minBoardSharePasswordLength, maxBoardSharePasswordLength)
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		hash, err := pbkdf2.Key(sha256.New, input.Password, salt, s.iterations, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		share.PasswordHash = base64.StdEncoding.EncodeToString(hash)
+		share.PasswordSalt = base64.StdEncoding.EncodeToString(salt)
+		share.PasswordIterations = s.iterations
+	}
+	token, err := randomSecret(24)
+	if err != nil {
+		return nil, err
+	}
+	share.Token = token
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var board models.TaskBoard
+		if err := tx.Select("id").Where("id = ?", boardID).First(&board).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTaskBoardNotFound
+			}
+			return fmt.Errorf("failed to fetch task board: %w", err)
+		}
+		if err := tx.Where("task_board_id = ?", boardID).Delete(&models.TaskBoardShare{}).Error; err != nil {
+			return fmt.Errorf("failed to replace board link: %w", err)
+		}
+		if err := tx.Create(&share).Error; err != nil {
+			return fmt.Errorf("failed to save board link: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("board_id", boardID).Str("user_id", createdBy).Bool("password", share.PasswordHash != "").Msg("Task board published")
+	return s.toBoardShare(share), nil
+}
+
+// GetShare returns the board's public link
+func (s *taskBoardShareServiceImpl) GetShare(ctx context.Context, boardID string) (*BoardShare, error) {
+	var share models.TaskBoardShare
+	if err := s.db.WithContext(ctx).Where("task_board_id = ?", boardID).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBoardShareNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch board link: %w", err)
+	}
+	return s.toBoardShare(share), nil
+}
+
+// Revoke deletes the board's public link
+func (s *taskBoardShareServiceImpl) Revoke(ctx context.Context, boardID string) error {
+	result := s.db.WithContext(ctx).Where("task_board_id = ?", boardID).Delete(&models.TaskBoardShare{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke board link: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrBoardShareNotFound
+	}
+	return nil
+}
+
+// GetPublicBoard returns the board a link shares, checking its password when it has one
+func (s *taskBoardShareServiceImpl) GetPublicBoard(ctx context.Context, token, password string) (*PublicTaskBoard, error) {
+	if token == "" {
+		return nil, ErrBoardShareNotFound
+	}
+	var share models.TaskBoardShare
+	if err := s.db.WithContext(ctx).Where("token = ?", token).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBoardShareNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch board link: %w", err)
+	}
+	if share.ExpiresAt != nil && !share.ExpiresAt.After(s.now()) {
+		return nil, ErrBoardShareExpired
+	}
+	if err := checkBoardSharePassword(share, password); err != nil {
+		return nil, err
+	}
+
+	var board models.TaskBoard
+	if err := s.db.WithContext(ctx).Preload("Tasks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC, created_at ASC")
+	}).Preload("Tasks.Assignments").Where("id = ?", share.TaskBoardID).First(&board).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBoardShareNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch task board: %w", err)
+	}
+
+	public := &PublicTaskBoard{
+		Name:        board.Name,
+		Description: board.Description,
+		Columns:     make([]PublicBoardColumn, 0, len(boardColumns)),
+		UpdatedAt:   board.UpdatedAt,
+		ExpiresAt:   share.ExpiresAt,
+	}
+	for _, column := range boardColumns {
+		tasks := []PublicBoardTask{}
+		for _, task := range board.Tasks {
+			if task.Status != column.status {
+				continue
+			}
+			tasks = append(tasks, PublicBoardTask{
+				ID:            task.ID,
+				Title:         task.Title,
+				Description:   task.Description,
+				Priority:      task.Priority,
+				StartDate:     task.StartDate,
+				DurationDays:  task.DurationDays,
+				CompletedAt:   task.CompletedAt,
+				AssigneeCount: len(task.Assignments),
+			})
+		}
+		public.Columns = append(public.Columns, PublicBoardColumn{Status: column.status, Name: column.name, Tasks: tasks})
+	}
+	return public, nil
+}
+
+// checkBoardSharePassword checks a password against a link's, in constant time
+func checkBoardSharePassword(share models.TaskBoardShare, password string) error {
+	if share.PasswordHash == "" {
+		return nil
+	}
+	if password == "" {
+		return ErrBoardSharePasswordRequired
+	}
+	salt, err := base64.StdEncoding.DecodeString(share.PasswordSalt)
+	if err != nil {
+		return fmt.Errorf("failed to decode password salt: %w", err)
+	}
+	want, err := base64.StdEncoding.DecodeString(share.PasswordHash)
+	if err != nil {
+		return fmt.Errorf("failed to decode password hash: %w", err)
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, share.PasswordIterations, len(want))
+	if err != nil {
+		return ErrBoardSharePasswordIncorrect
+	}
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return ErrBoardSharePasswordIncorrect
+	}
+	return nil
+}
+
+// toBoardShare returns the API representation of a board's link
+func (s *taskBoardShareServiceImpl) toBoardShare(share models.TaskBoardShare) *BoardShare {
+	return &BoardShare{
+		Token:             share.Token,
+		URL:               fmt.Sprintf("%s/shared/boards/%s", s.frontendBaseURL, share.Token),
+		PasswordProtected: share.PasswordHash != "",
+		ExpiresAt:         share.ExpiresAt,
+		CreatedBy:         share.CreatedBy,
+		CreatedAt:         share.CreatedAt,
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestTaskBoardShareService creates a board share service with a board of assigned tasks
+func setupTestTaskBoardShareService(t *testing.T) (*taskBoardShareServiceImpl, models.TaskBoard) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.TaskBoard{}, &models.Task{}, &models.TaskAssignment{}, &models.TaskBoardShare{}),
+		"Failed to migrate test database")
+
+	board := models.TaskBoard{Name: "Launch", Description: "Website relaunch", ClerkUserID: "user_owner", IsStandalone: true}
+	require.NoError(t, db.Create(&board).Error)
+	tasks := []models.Task{
+		{Title: "Write copy", Status: "todo", Priority: "high", TaskBoardID: board.ID, Position: 1, BlockRef: "note_1#block_1"},
+		{Title: "Pick fonts", Status: "todo", Priority: "low", TaskBoardID: board.ID, Position: 0},
+		{Title: "Wireframes", Status: "done", Priority: "medium", TaskBoardID: board.ID},
+	}
+	require.NoError(t, db.Create(&tasks).Error)
+	require.NoError(t, db.Create(&models.TaskAssignment{TaskID: tasks[0].ID, UserID: "user_alice"}).Error)
+
+	return &taskBoardShareServiceImpl{
+		db:              db,
+		now:             func() time.Time { return time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC) },
+		iterations:      1000,
+		frontendBaseURL: "https://notes.example.com",
+	}, board
+}
+
+func TestTaskBoardShareService_Publish(t *testing.T) {
+	service, board := setupTestTaskBoardShareService(t)
+	ctx := context.Background()
+
+	share, err := service.Publish(ctx, board.ID, "user_owner", BoardShareInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://notes.example.com/shared/boards/"+share.Token, share.URL)
+	assert.False(t, share.PasswordProtected)
+
+	public, err := service.GetPublicBoard(ctx, share.Token, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Launch", public.Name)
+	require.Len(t, public.Columns, 4)
+	assert.Equal(t, "To Do", public.Columns[1].Name)
+	require.Len(t, public.Columns[1].Tasks, 2)
+	assert.Equal(t, "Pick fonts", public.Columns[1].Tasks[0].Title, "Cards are in board order")
+	assert.Equal(t, 1, public.Columns[1].Tasks[1].AssigneeCount)
+	assert.Empty(t, public.Columns[2].Tasks)
+	assert.Equal(t, "Wireframes", public.Columns[3].Tasks[0].Title)
+
+	encoded, err := json.Marshal(public)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "user_alice", "Assignees aren't shown")
+	assert.NotContains(t, string(encoded), "user_owner")
+	assert.NotContains(t, string(encoded), "note_1", "Links into notes aren't shown")
+
+	again, err := service.Publish(ctx, board.ID, "user_owner", BoardShareInput{})
+	require.NoError(t, err)
+	assert.NotEqual(t, share.Token, again.Token)
+	_, err = service.GetPublicBoard(ctx, share.Token, "")
+	assert.ErrorIs(t, err, ErrBoardShareNotFound, "Publishing again replaces the link")
+
+	current, err := service.GetShare(ctx, board.ID)
+	require.NoError(t, err)
+	assert.Equal(t, again.Token, current.Token)
+
+	require.NoError(t, service.Revoke(ctx, board.ID))
+	_, err = service.GetPublicBoard(ctx, again.Token, "")
+	assert.ErrorIs(t, err, ErrBoardShareNotFound)
+	assert.ErrorIs(t, service.Revoke(ctx, board.ID), ErrBoardShareNotFound)
+	_, err = service.GetShare(ctx, board.ID)
+	assert.ErrorIs(t, err, ErrBoardShareNotFound)
+
+	_, err = service.Publish(ctx, "missing", "user_owner", BoardShareInput{})
+	assert.ErrorIs(t, err, ErrTaskBoardNotFound)
+}
+
+func TestTaskBoardShareService_PasswordAndExpiry(t *testing.T) {
+	service, board := setupTestTaskBoardShareService(t)
+	ctx := context.Background()
+	expiresAt := service.now().Add(48 * time.Hour)
+
+	share, err := service.Publish(ctx, board.ID, "user_owner", BoardShareInput{Password: "open sesame", ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	assert.True(t, share.PasswordProtected)
+	assert.Equal(t, expiresAt, *share.ExpiresAt)
+
+	_, err = service.GetPublicBoard(ctx, share.Token, "")
+	assert.ErrorIs(t, err, ErrBoardSharePasswordRequired)
+	_, err = service.GetPublicBoard(ctx, share.Token, "open sesame!")
+	assert.ErrorIs(t, err, ErrBoardSharePasswordIncorrect)
+	public, err := service.GetPublicBoard(ctx, share.Token, "open sesame")
+	require.NoError(t, err)
+	assert.Equal(t, expiresAt, *public.ExpiresAt)
+
+	service.now = func() time.Time { return expiresAt }
+	_, err = service.GetPublicBoard(ctx, share.Token, "open sesame")
+	assert.ErrorIs(t, err, ErrBoardShareExpired)
+
+	past := expiresAt.Add(-time.Hour)
+	_, err = service.Publish(ctx, board.ID, "user_owner", BoardShareInput{ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrInvalidBoardShare)
+	tooLate := expiresAt.Add(400 * 24 * time.Hour)
+	_, err = service.Publish(ctx, board.ID, "user_owner", BoardShareInput{ExpiresAt: &tooLate})
+	assert.ErrorIs(t, err, ErrInvalidBoardShare)
+	_, err = service.Publish(ctx, board.ID, "user_owner", BoardShareInput{Password: "abc"})
+	assert.ErrorIs(t, err, ErrInvalidBoardShare)
+
+	_, err = service.GetPublicBoard(ctx, share.Token, "open sesame")
+	assert.ErrorIs(t, err, ErrBoardShareExpired, "Invalid settings keep the current link")
+}
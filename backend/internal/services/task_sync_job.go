@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TaskSyncJob periodically queues syncs for task boards mirrored with Jira or Linear
+type TaskSyncJob struct {
+	syncService TaskSyncService
+	interval    time.Duration
+	stopChan    chan struct{}
+}
+
+// NewTaskSyncJob creates a new scheduled sync job
+func NewTaskSyncJob(syncService TaskSyncService, interval time.Duration) *TaskSyncJob {
+	return &TaskSyncJob{
+		syncService: syncService,
+		interval:    interval,
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start begins checking for due syncs
+func (j *TaskSyncJob) Start(ctx context.Context) {
+	log.Info().Dur("interval", j.interval).Msg("Starting task sync scheduler")
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if queued := j.syncService.QueueDueSyncs(time.Now()); queued > 0 {
+				log.Info().Int("queued", queued).Msg("Queued scheduled task syncs")
+			}
+		case <-ctx.Done():
+			log.Info().Msg("Stopping task sync scheduler (context cancelled)")
+			return
+		case <-j.stopChan:
+			log.Info().Msg("Stopping task sync scheduler")
+			return
+		}
+	}
+}
+
+// Stop stops the scheduler
+func (j *TaskSyncJob) Stop() {
+	close(j.stopChan)
+}
@@ -0,0 +1,791 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidTaskSyncIntegration is returned when task sync settings are incomplete or can't be used
+	ErrInvalidTaskSyncIntegration = errors.New("invalid task sync integration")
+	// ErrTaskSyncIntegrationNotFound is returned when a board has no task sync configured
+	ErrTaskSyncIntegrationNotFound = errors.New("task sync integration not found")
+	// ErrTaskSyncInProgress is returned when the board is already syncing
+	ErrTaskSyncInProgress = errors.New("task sync already in progress")
+
+	// errTrackerNotFound is returned by trackers when a project or issue doesn't exist
+	errTrackerNotFound = errors.New("not found in issue tracker")
+)
+
+const (
+	defaultTaskSyncInterval = 15
+	minTaskSyncInterval     = 5
+	maxTaskSyncInterval     = 24 * 60
+	taskSyncJobTimeout      = 10 * time.Minute
+	// taskSyncImportLimit caps how many issues one sync imports from the tracker
+	taskSyncImportLimit = 200
+)
+
+var (
+	// taskSyncLocks prevents two syncs of the same board from running at once
+	taskSyncLocks sync.Map
+	// taskSyncRunning tracks integrations that are syncing right now
+	taskSyncRunning sync.Map
+	// taskSyncQueued tracks integrations with a sync waiting in the job queue
+	taskSyncQueued sync.Map
+)
+
+// trackerIssue is an issue in the tracker with its status in task board terms
+type trackerIssue struct {
+	ID          string
+	Key         string
+	URL         string
+	Title       string
+	Description string
+	Status      string
+	UpdatedAt   time.Time
+}
+
+// trackerComment is a comment on a tracker issue
+type trackerComment struct {
+	ID        string
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// issueTracker is the part of the Jira and Linear APIs used for syncing
+type issueTracker interface {
+	CheckProject(ctx context.Context) error
+	ListIssues(ctx context.Context, updatedSince time.Time, limit int) ([]trackerIssue, error)
+	GetIssue(ctx context.Context, issueID string) (*trackerIssue, error)
+	CreateIssue(ctx context.Context, title, description, status string) (*trackerIssue, error)
+	UpdateIssue(ctx context.Context, issue *trackerIssue, title, status string) (*trackerIssue, error)
+	ListComments(ctx context.Context, issueID string) ([]trackerComment, error)
+	AddComment(ctx context.Context, issueID, body string) (*trackerComment, error)
+	// SameStatus reports whether two task statuses map to the same status in the tracker
+	SameStatus(a, b string) bool
+}
+
+// TaskSyncSettings is the API representation of a board's task sync settings.
+// The access token is write-only and kept when omitted.
+type TaskSyncSettings struct {
+	Provider            string `json:"provider"`
+	BaseURL             string `json:"baseUrl"`
+	Email               string `json:"email"`
+	AccessToken         string `json:"accessToken,omitempty"`
+	ProjectKey          string `json:"projectKey"`
+	IssueType           string `json:"issueType"`
+	ConflictPolicy      string `json:"conflictPolicy"`
+	SyncIntervalMinutes int    `json:"syncIntervalMinutes"`
+	Enabled             *bool  `json:"enabled"`
+}
+
+// TaskSyncIntegrationResponse is a task sync integration as returned by the API
+type TaskSyncIntegrationResponse struct {
+	*models.TaskSyncIntegration
+	HasAccessToken bool  `json:"hasAccessToken"`
+	LinkedTasks    int64 `json:"linkedTasks"`
+}
+
+// TaskSyncStatus summarizes where a board's sync stands
+type TaskSyncStatus struct {
+	TaskBoardID    string     `json:"taskBoardId"`
+	Provider       string     `json:"provider"`
+	Enabled        bool       `json:"enabled"`
+	Syncing        bool       `json:"syncing"`
+	LastSyncedAt   *time.Time `json:"lastSyncedAt,omitempty"`
+	LastSyncStatus string     `json:"lastSyncStatus,omitempty"`
+	LastSyncError  string     `json:"lastSyncError,omitempty"`
+	LastConflicts  int        `json:"lastConflicts"`
+	LinkedTasks    int64      `json:"linkedTasks"`
+	NextSyncAt     *time.Time `json:"nextSyncAt,omitempty"`
+}
+
+// TaskSyncResult summarizes a sync
+type TaskSyncResult struct {
+	Created           int      `json:"created"`
+	Imported          int      `json:"imported"`
+	Pushed            int      `json:"pushed"`
+	Pulled            int      `json:"pulled"`
+	Unlinked          int      `json:"unlinked"`
+	CommentsPushed    int      `json:"commentsPushed"`
+	CommentsPulled    int      `json:"commentsPulled"`
+	Conflicts         int      `json:"conflicts"`
+	ConflictedTaskIDs []string `json:"conflictedTaskIds,omitempty"`
+}
+
+// TaskSyncService interface defines methods for mirroring task boards with Jira and Linear
+type TaskSyncService interface {
+	GetIntegration(boardID string) (*TaskSyncIntegrationResponse, error)
+	SaveIntegration(ctx context.Context, boardID string, settings TaskSyncSettings, updatedBy string) (*TaskSyncIntegrationResponse, error)
+	DeleteIntegration(boardID string) error
+	GetStatus(boardID string) (*TaskSyncStatus, error)
+	Sync(ctx context.Context, integrationID string) (*TaskSyncResult, error)
+	QueueSync(integrationID string) error
+	QueueSyncForBoard(boardID string)
+	QueueDueSyncs(now time.Time) int
+}
+
+// taskSyncServiceImpl implements the TaskSyncService interface
+type taskSyncServiceImpl struct {
+	db              *gorm.DB
+	queue           *JobQueue
+	newTracker      func(integration *models.TaskSyncIntegration, token string) issueTracker
+	statusChanged   func(taskID, oldStatus string)
+	frontendBaseURL string
+}
+
+// NewTaskSyncService creates a new TaskSyncService instance
+func NewTaskSyncService() TaskSyncService {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:5173"
+	}
+
+	return &taskSyncServiceImpl{
+		db:         db.DB,
+		queue:      GetJobQueue(),
+		newTracker: newIssueTracker,
+		statusChanged: func(taskID, oldStatus string) {
+			NewAutomationService().TaskStatusChanged(taskID, oldStatus)
+		},
+		frontendBaseURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// GetIntegration returns the board's integration, or nil if none is configured
+func (s *taskSyncServiceImpl) GetIntegration(boardID string) (*TaskSyncIntegrationResponse, error) {
+	integration, err := s.findIntegration(boardID)
+	if err != nil || integration == nil {
+		return nil, err
+	}
+
+	var linked int64
+	if err := s.db.Model(&models.TaskExternalLink{}).Where("integration_id = ?", integration.ID).Count(&linked).Error; err != nil {
+		return nil, fmt.Errorf("failed to count linked tasks: %w", err)
+	}
+
+	return &TaskSyncIntegrationResponse{
+		TaskSyncIntegration: integration,
+		HasAccessToken:      integration.AccessToken != "",
+		LinkedTasks:         linked,
+	}, nil
+}
+
+// SaveIntegration validates and saves the board's integration.
+// The credentials are checked against the project whenever they or the project change.
+func (s *taskSyncServiceImpl) SaveIntegration(ctx context.Context, boardID string, settings TaskSyncSettings, updatedBy string) (*TaskSyncIntegrationResponse, error) {
+	var board models.TaskBoard
+	if err := s.db.Select("id", "organization_id").Where("id = ?", boardID).First(&board).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch task board: %w", err)
+	}
+
+	existing, err := s.findIntegration(boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	integration := models.TaskSyncIntegration{
+		Provider:       strings.ToLower(strings.TrimSpace(settings.Provider)),
+		ProjectKey:     strings.TrimSpace(settings.ProjectKey),
+		ConflictPolicy: strings.TrimSpace(settings.ConflictPolicy),
+	}
+	if integration.ProjectKey == "" {
+		return nil, fmt.Errorf("%w: a project is required", ErrInvalidTaskSyncIntegration)
+	}
+
+	switch integration.Provider {
+	case models.TaskSyncProviderJira:
+		baseURL := strings.TrimRight(strings.TrimSpace(settings.BaseURL), "/")
+		parsed, err := url.Parse(baseURL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return nil, fmt.Errorf("%w: the Jira site must be an https URL", ErrInvalidTaskSyncIntegration)
+		}
+		integration.BaseURL = baseURL
+		integration.Email = strings.TrimSpace(settings.Email)
+		if integration.Email == "" {
+			return nil, fmt.Errorf("%w: the Jira account email is required", ErrInvalidTaskSyncIntegration)
+		}
+		integration.ProjectKey = strings.ToUpper(integration.ProjectKey)
+		integration.IssueType = strings.TrimSpace(settings.IssueType)
+		if integration.IssueType == "" {
+			integration.IssueType = "Task"
+		}
+	case models.TaskSyncProviderLinear:
+	default:
+		return nil, fmt.Errorf("%w: provider must be jira or linear", ErrInvalidTaskSyncIntegration)
+	}
+
+	switch integration.ConflictPolicy {
+	case "":
+		integration.ConflictPolicy = models.TaskSyncConflictNewestWins
+	case models.TaskSyncConflictNewestWins, models.TaskSyncConflictRemoteWins, models.TaskSyncConflictLocalWins:
+	default:
+		return nil, fmt.Errorf("%w: unknown conflict policy %s", ErrInvalidTaskSyncIntegration, integration.ConflictPolicy)
+	}
+
+	interval := settings.SyncIntervalMinutes
+	if interval == 0 {
+		interval = defaultTaskSyncInterval
+	}
+	if interval < minTaskSyncInterval || interval > maxTaskSyncInterval {
+		return nil, fmt.Errorf("%w: sync interval must be between %d and %d minutes", ErrInvalidTaskSyncIntegration, minTaskSyncInterval, maxTaskSyncInterval)
+	}
+
+	// The stored token only carries over while it's used with the same account
+	sameAccount := existing != nil && existing.Provider == integration.Provider &&
+		existing.BaseURL == integration.BaseURL && existing.Email == integration.Email
+
+	token := strings.TrimSpace(settings.AccessToken)
+	encryptedToken := ""
+	if token != "" {
+		if encryptedToken, err = NewSecretsService().EncryptString(token); err != nil {
+			return nil, fmt.Errorf("failed to encrypt access token: %w", err)
+		}
+	} else if sameAccount {
+		encryptedToken = existing.AccessToken
+		if token, err = NewSecretsService().DecryptString(existing.AccessToken); err != nil {
+			return nil, fmt.Errorf("failed to decrypt access token: %w", err)
+		}
+	} else {
+		return nil, fmt.Errorf("%w: an access token is required", ErrInvalidTaskSyncIntegration)
+	}
+
+	sameProject := sameAccount && existing.ProjectKey == integration.ProjectKey
+	if !sameProject || settings.AccessToken != "" {
+		if err := s.newTracker(&integration, token).CheckProject(ctx); err != nil {
+			if errors.Is(err, errTrackerNotFound) {
+				return nil, fmt.Errorf("%w: project not found or not accessible with these credentials", ErrInvalidTaskSyncIntegration)
+			}
+			return nil, fmt.Errorf("%w: couldn't reach the project: %v", ErrInvalidTaskSyncIntegration, err)
+		}
+	}
+
+	enabled := true
+	if settings.Enabled != nil {
+		enabled = *settings.Enabled
+	} else if existing != nil {
+		enabled = existing.Enabled
+	}
+
+	columns := map[string]interface{}{
+		"provider":              integration.Provider,
+		"base_url":              integration.BaseURL,
+		"email":                 integration.Email,
+		"access_token":          encryptedToken,
+		"project_key":           integration.ProjectKey,
+		"issue_type":            integration.IssueType,
+		"conflict_policy":       integration.ConflictPolicy,
+		"sync_interval_minutes": interval,
+		"enabled":               enabled,
+		"updated_by":            updatedBy,
+	}
+
+	if existing != nil {
+		if err := s.db.Model(existing).Updates(columns).Error; err != nil {
+			return nil, fmt.Errorf("failed to save task sync integration: %w", err)
+		}
+		// Links point at issues in the old project, so tasks have to be mirrored again from scratch
+		if !sameProject {
+			s.db.Where("integration_id = ?", existing.ID).Delete(&models.TaskExternalLink{})
+			s.db.Model(existing).Update("last_synced_at", nil)
+		}
+	} else {
+		created := models.TaskSyncIntegration{
+			TaskBoardID:    boardID,
+			OrganizationID: board.OrganizationID,
+			Provider:       integration.Provider,
+			ProjectKey:     integration.ProjectKey,
+			AccessToken:    encryptedToken,
+		}
+		if err := s.db.Create(&created).Error; err != nil {
+			return nil, fmt.Errorf("failed to save task sync integration: %w", err)
+		}
+		if err := s.db.Model(&created).Updates(columns).Error; err != nil {
+			return nil, fmt.Errorf("failed to save task sync integration: %w", err)
+		}
+	}
+
+	return s.GetIntegration(boardID)
+}
+
+// DeleteIntegration stops syncing the board. Tasks and issues are left in place.
+func (s *taskSyncServiceImpl) DeleteIntegration(boardID string) error {
+	integration, err := s.findIntegration(boardID)
+	if err != nil {
+		return err
+	}
+	if integration == nil {
+		return ErrTaskSyncIntegrationNotFound
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("integration_id = ?", integration.ID).Delete(&models.TaskExternalLink{}).Error; err != nil {
+			return fmt.Errorf("failed to delete task links: %w", err)
+		}
+		if err := tx.Delete(integration).Error; err != nil {
+			return fmt.Errorf("failed to delete task sync integration: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetStatus returns the board's sync status
+func (s *taskSyncServiceImpl) GetStatus(boardID string) (*TaskSyncStatus, error) {
+	integration, err := s.GetIntegration(boardID)
+	if err != nil {
+		return nil, err
+	}
+	if integration == nil {
+		return nil, ErrTaskSyncIntegrationNotFound
+	}
+
+	_, queued := taskSyncQueued.Load(integration.ID)
+	_, running := taskSyncRunning.Load(integration.ID)
+
+	status := &TaskSyncStatus{
+		TaskBoardID:    boardID,
+		Provider:       integration.Provider,
+		Enabled:        integration.Enabled,
+		Syncing:        queued || running,
+		LastSyncedAt:   integration.LastSyncedAt,
+		LastSyncStatus: integration.LastSyncStatus,
+		LastSyncError:  integration.LastSyncError,
+		LastConflicts:  integration.LastConflicts,
+		LinkedTasks:    integration.LinkedTasks,
+	}
+	if integration.Enabled {
+		next := time.Now()
+		if integration.LastSyncedAt != nil {
+			next = integration.LastSyncedAt.Add(time.Duration(integration.SyncIntervalMinutes) * time.Minute)
+		}
+		status.NextSyncAt = &next
+	}
+	return status, nil
+}
+
+// Sync mirrors the board with its tracker: issues are imported as tasks, tasks are created as issues,
+// and titles, statuses and comments of linked tasks are synced both ways
+func (s *taskSyncServiceImpl) Sync(ctx context.Context, integrationID string) (*TaskSyncResult, error) {
+	lock, _ := taskSyncLocks.LoadOrStore(integrationID, &sync.Mutex{})
+	mutex := lock.(*sync.Mutex)
+	if !mutex.TryLock() {
+		return nil, ErrTaskSyncInProgress
+	}
+	defer mutex.Unlock()
+	taskSyncRunning.Store(integrationID, true)
+	defer taskSyncRunning.Delete(integrationID)
+
+	var integration models.TaskSyncIntegration
+	if err := s.db.Where("id = ?", integrationID).First(&integration).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskSyncIntegrationNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch task sync integration: %w", err)
+	}
+	if !integration.Enabled {
+		return nil, fmt.Errorf("%w: integration is disabled", ErrInvalidTaskSyncIntegration)
+	}
+
+	token, err := NewSecretsService().DecryptString(integration.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt access token: %w", err)
+	}
+
+	startedAt := time.Now()
+	result, syncErr := s.syncBoard(ctx, s.newTracker(&integration, token), &integration)
+
+	status := models.TaskSyncStatusSuccess
+	errorMessage := ""
+	if syncErr != nil {
+		status = models.TaskSyncStatusFailed
+		errorMessage = syncErr.Error()
+	}
+	columns := map[string]interface{}{
+		"last_sync_status": status,
+		"last_sync_error":  errorMessage,
+		"last_conflicts":   result.Conflicts,
+	}
+	// Only a complete sync moves the import window forward
+	if syncErr == nil {
+		columns["last_synced_at"] = startedAt
+	}
+	if err := s.db.Model(&integration).Updates(columns).Error; err != nil {
+		log.Error().Err(err).Str("integration_id", integration.ID).Msg("Failed to record task sync status")
+	}
+
+	logEvent := log.Info()
+	if syncErr != nil {
+		logEvent = log.Error().Err(syncErr)
+	}
+	logEvent.
+		Str("integration_id", integration.ID).
+		Str("board_id", integration.TaskBoardID).
+		Str("provider", integration.Provider).
+		Int("created", result.Created).
+		Int("imported", result.Imported).
+		Int("pushed", result.Pushed).
+		Int("pulled", result.Pulled).
+		Int("conflicts", result.Conflicts).
+		Msg("Task sync finished")
+
+	if syncErr != nil {
+		return result, syncErr
+	}
+	return result, nil
+}
+
+// QueueSync runs a sync in the background. Syncs already waiting in the queue aren't queued twice.
+func (s *taskSyncServiceImpl) QueueSync(integrationID string) error {
+	if _, queued := taskSyncQueued.LoadOrStore(integrationID, true); queued {
+		return nil
+	}
+
+	err := s.queue.Enqueue(Job{
+		Name:        "task-sync",
+		MaxAttempts: 2,
+		Timeout:     taskSyncJobTimeout,
+		Run: func(ctx context.Context) error {
+			taskSyncQueued.Delete(integrationID)
+			_, err := s.Sync(ctx, integrationID)
+			if errors.Is(err, ErrTaskSyncInProgress) || errors.Is(err, ErrInvalidTaskSyncIntegration) || errors.Is(err, ErrTaskSyncIntegrationNotFound) {
+				return nil
+			}
+			return err
+		},
+	})
+	if err != nil {
+		taskSyncQueued.Delete(integrationID)
+	}
+	return err
+}
+
+// QueueSyncForBoard queues a sync after a task on the board changed, if the board is synced
+func (s *taskSyncServiceImpl) QueueSyncForBoard(boardID string) {
+	integration, err := s.findIntegration(boardID)
+	if err != nil {
+		log.Error().Err(err).Str("board_id", boardID).Msg("Failed to find task sync integration for board")
+		return
+	}
+	if integration == nil || !integration.Enabled {
+		return
+	}
+
+	if err := s.QueueSync(integration.ID); err != nil {
+		log.Warn().Err(err).Str("integration_id", integration.ID).Msg("Failed to queue task sync")
+	}
+}
+
+// QueueDueSyncs queues integrations whose interval has elapsed and returns how many were queued
+func (s *taskSyncServiceImpl) QueueDueSyncs(now time.Time) int {
+	var integrations []models.TaskSyncIntegration
+	if err := s.db.Where("enabled = ?", true).Find(&integrations).Error; err != nil {
+		log.Error().Err(err).Msg("Failed to find task sync integrations")
+		return 0
+	}
+
+	queued := 0
+	for _, integration := range integrations {
+		interval := time.Duration(integration.SyncIntervalMinutes) * time.Minute
+		if integration.LastSyncedAt != nil && now.Before(integration.LastSyncedAt.Add(interval)) {
+			continue
+		}
+		if err := s.QueueSync(integration.ID); err != nil {
+			log.Warn().Err(err).Str("integration_id", integration.ID).Msg("Failed to queue scheduled task sync")
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+// syncBoard runs the import, two-way and export phases of a sync
+func (s *taskSyncServiceImpl) syncBoard(ctx context.Context, tracker issueTracker, integration *models.TaskSyncIntegration) (*TaskSyncResult, error) {
+	result := &TaskSyncResult{}
+
+	var tasks []models.Task
+	if err := s.db.Preload("ExternalLink").
+		Where("task_board_id = ?", integration.TaskBoardID).
+		Order("position, created_at").
+		Find(&tasks).Error; err != nil {
+		return result, fmt.Errorf("failed to fetch tasks: %w", err)
+	}
+
+	var linkedIDs []string
+	if err := s.db.Model(&models.TaskExternalLink{}).Where("integration_id = ?", integration.ID).Pluck("external_id", &linkedIDs).Error; err != nil {
+		return result, fmt.Errorf("failed to fetch task links: %w", err)
+	}
+	linked := make(map[string]bool, len(linkedIDs))
+	for _, id := range linkedIDs {
+		linked[id] = true
+	}
+
+	// Import: issues created or updated in the tracker since the last sync that have no task yet
+	var since time.Time
+	if integration.LastSyncedAt != nil {
+		// Jira searches have minute precision
+		since = integration.LastSyncedAt.Add(-time.Minute)
+	}
+	issues, err := tracker.ListIssues(ctx, since, taskSyncImportLimit)
+	if err != nil {
+		return result, fmt.Errorf("failed to list issues: %w", err)
+	}
+	position := len(tasks)
+	for i := range issues {
+		issue := &issues[i]
+		if linked[issue.ID] {
+			continue
+		}
+
+		task := models.Task{
+			Title:          issue.Title,
+			Description:    stripTaskSyncLinkBack(issue.Description),
+			Status:         issue.Status,
+			TaskBoardID:    integration.TaskBoardID,
+			OrganizationID: integration.OrganizationID,
+			Position:       position,
+		}
+		if err := s.db.Create(&task).Error; err != nil {
+			return result, fmt.Errorf("failed to import %s: %w", issue.Key, err)
+		}
+		position++
+		if task.Status == "done" {
+			s.statusChanged(task.ID, "")
+		}
+
+		link, err := s.saveLink(integration, nil, &task, issue)
+		if err != nil {
+			return result, err
+		}
+		linked[issue.ID] = true
+		result.Imported++
+
+		if err := s.syncComments(ctx, tracker, integration, link, true, result); err != nil {
+			return result, err
+		}
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+
+		// Export: tasks that aren't in the tracker yet
+		if task.ExternalLink == nil || task.ExternalLink.IntegrationID != integration.ID {
+			issue, err := tracker.CreateIssue(ctx, task.Title, s.issueDescription(task), task.Status)
+			if err != nil {
+				return result, fmt.Errorf("failed to create issue for task %s: %w", task.ID, err)
+			}
+			link, err := s.saveLink(integration, task.ExternalLink, task, issue)
+			if err != nil {
+				return result, err
+			}
+			result.Created++
+
+			if err := s.syncComments(ctx, tracker, integration, link, false, result); err != nil {
+				return result, err
+			}
+			continue
+		}
+
+		// Two-way: sync linked tasks with their issues
+		link := task.ExternalLink
+		issue, err := tracker.GetIssue(ctx, link.ExternalID)
+		if errors.Is(err, errTrackerNotFound) {
+			if err := s.db.Delete(link).Error; err != nil {
+				return result, fmt.Errorf("failed to unlink task: %w", err)
+			}
+			result.Unlinked++
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to fetch %s: %w", link.ExternalKey, err)
+		}
+
+		pullComments := link.RemoteUpdatedAt.IsZero() || issue.UpdatedAt.After(link.RemoteUpdatedAt)
+		if issue, err = s.syncTask(ctx, tracker, integration, task, link, issue, result); err != nil {
+			return result, err
+		}
+		if link, err = s.saveLink(integration, link, task, issue); err != nil {
+			return result, err
+		}
+		if err := s.syncComments(ctx, tracker, integration, link, pullComments, result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// syncTask reconciles a task's title and status with its issue, applying the conflict policy
+// when both changed since the last sync. It returns the issue as it stands afterwards.
+func (s *taskSyncServiceImpl) syncTask(ctx context.Context, tracker issueTracker, integration *models.TaskSyncIntegration, task *models.Task, link *models.TaskExternalLink, issue *trackerIssue, result *TaskSyncResult) (*trackerIssue, error) {
+	localChanged := task.Title != link.SyncedTitle || task.Status != link.SyncedStatus
+	remoteChanged := issue.Title != link.SyncedTitle || !tracker.SameStatus(issue.Status, link.SyncedStatus)
+	if task.Title == issue.Title && tracker.SameStatus(task.Status, issue.Status) {
+		return issue, nil
+	}
+
+	useLocal := localChanged && !remoteChanged
+	if localChanged && remoteChanged {
+		result.Conflicts++
+		result.ConflictedTaskIDs = append(result.ConflictedTaskIDs, task.ID)
+		switch integration.ConflictPolicy {
+		case models.TaskSyncConflictLocalWins:
+			useLocal = true
+		case models.TaskSyncConflictRemoteWins:
+			useLocal = false
+		default:
+			useLocal = task.UpdatedAt.After(issue.UpdatedAt)
+		}
+	}
+
+	if useLocal {
+		updated, err := tracker.UpdateIssue(ctx, issue, task.Title, task.Status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", issue.Key, err)
+		}
+		result.Pushed++
+		return updated, nil
+	}
+
+	oldStatus := task.Status
+	task.Title = issue.Title
+	// Keep the local status when the tracker can't tell it apart, e.g. backlog and todo in Jira
+	if !tracker.SameStatus(task.Status, issue.Status) {
+		task.Status = issue.Status
+	}
+	updates := map[string]interface{}{"title": task.Title, "status": task.Status}
+	if err := s.db.Model(task).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update task: %w", err)
+	}
+	if task.Status != oldStatus {
+		s.statusChanged(task.ID, oldStatus)
+	}
+	result.Pulled++
+	return issue, nil
+}
+
+// syncComments pulls new issue comments (when the issue changed) and pushes task comments that aren't in the tracker yet
+func (s *taskSyncServiceImpl) syncComments(ctx context.Context, tracker issueTracker, integration *models.TaskSyncIntegration, link *models.TaskExternalLink, pull bool, result *TaskSyncResult) error {
+	var comments []models.TaskComment
+	if err := s.db.Where("task_id = ?", link.TaskID).Order("created_at").Find(&comments).Error; err != nil {
+		return fmt.Errorf("failed to fetch task comments: %w", err)
+	}
+	mirrored := make(map[string]bool, len(comments))
+	for _, comment := range comments {
+		if comment.ExternalID != "" {
+			mirrored[comment.ExternalID] = true
+		}
+	}
+
+	if pull {
+		remoteComments, err := tracker.ListComments(ctx, link.ExternalID)
+		if err != nil && !errors.Is(err, errTrackerNotFound) {
+			return fmt.Errorf("failed to fetch comments of %s: %w", link.ExternalKey, err)
+		}
+		for _, remote := range remoteComments {
+			if mirrored[remote.ID] {
+				continue
+			}
+			comment := models.TaskComment{
+				TaskID:     link.TaskID,
+				AuthorName: remote.Author,
+				Body:       remote.Body,
+				Source:     integration.Provider,
+				ExternalID: remote.ID,
+				CreatedAt:  remote.CreatedAt,
+			}
+			if err := s.db.Create(&comment).Error; err != nil {
+				return fmt.Errorf("failed to save comment: %w", err)
+			}
+			mirrored[remote.ID] = true
+			result.CommentsPulled++
+		}
+	}
+
+	for i := range comments {
+		comment := &comments[i]
+		if comment.ExternalID != "" || comment.Source != models.TaskCommentSourceLocal {
+			continue
+		}
+
+		body := comment.Body
+		if comment.AuthorName != "" {
+			body = comment.AuthorName + " commented:\n\n" + body
+		}
+		remote, err := tracker.AddComment(ctx, link.ExternalID, body)
+		if err != nil {
+			return fmt.Errorf("failed to add comment to %s: %w", link.ExternalKey, err)
+		}
+		if err := s.db.Model(comment).Update("external_id", remote.ID).Error; err != nil {
+			return fmt.Errorf("failed to save comment: %w", err)
+		}
+		result.CommentsPushed++
+	}
+	return nil
+}
+
+// saveLink records the state a task and its issue agreed on
+func (s *taskSyncServiceImpl) saveLink(integration *models.TaskSyncIntegration, link *models.TaskExternalLink, task *models.Task, issue *trackerIssue) (*models.TaskExternalLink, error) {
+	if link == nil {
+		link = &models.TaskExternalLink{TaskID: task.ID}
+	}
+	link.IntegrationID = integration.ID
+	link.Provider = integration.Provider
+	link.ExternalID = issue.ID
+	link.ExternalKey = issue.Key
+	link.ExternalURL = issue.URL
+	link.SyncedTitle = task.Title
+	link.SyncedStatus = task.Status
+	link.RemoteUpdatedAt = issue.UpdatedAt
+	link.LastSyncedAt = time.Now()
+
+	if err := s.db.Save(link).Error; err != nil {
+		return nil, fmt.Errorf("failed to save task link: %w", err)
+	}
+	task.ExternalLink = link
+	return link, nil
+}
+
+// issueDescription is the description of an issue created for a task, with a link back to the board
+func (s *taskSyncServiceImpl) issueDescription(task *models.Task) string {
+	linkBack := fmt.Sprintf("%s%s/kanban/%s?task=%s", taskSyncLinkBackPrefix, s.frontendBaseURL, task.TaskBoardID, task.ID)
+	if strings.TrimSpace(task.Description) == "" {
+		return linkBack
+	}
+	return strings.TrimSpace(task.Description) + "\n\n" + linkBack
+}
+
+// taskSyncLinkBackPrefix starts the link back to the board in issue descriptions
+const taskSyncLinkBackPrefix = "Synced from Notes: "
+
+// stripTaskSyncLinkBack removes the link back to the board from an issue description
+func stripTaskSyncLinkBack(description string) string {
+	if index := strings.LastIndex(description, taskSyncLinkBackPrefix); index >= 0 {
+		description = description[:index]
+	}
+	return strings.TrimSpace(description)
+}
+
+// findIntegration returns the board's integration, or nil if none is configured
+func (s *taskSyncServiceImpl) findIntegration(boardID string) (*models.TaskSyncIntegration, error) {
+	var integration models.TaskSyncIntegration
+	if err := s.db.Where("task_board_id = ?", boardID).Limit(1).Find(&integration).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch task sync integration: %w", err)
+	}
+	if integration.ID == "" {
+		return nil, nil
+	}
+	return &integration, nil
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeIssueTracker keeps issues and comments in memory
+type fakeIssueTracker struct {
+	issues   map[string]*trackerIssue
+	comments map[string][]trackerComment
+	nextID   int
+}
+
+func (f *fakeIssueTracker) CheckProject(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeIssueTracker) ListIssues(ctx context.Context, updatedSince time.Time, limit int) ([]trackerIssue, error) {
+	var issues []trackerIssue
+	for _, issue := range f.issues {
+		if !issue.UpdatedAt.Before(updatedSince) {
+			issues = append(issues, *issue)
+		}
+	}
+	return issues, nil
+}
+
+func (f *fakeIssueTracker) GetIssue(ctx context.Context, issueID string) (*trackerIssue, error) {
+	issue, exists := f.issues[issueID]
+	if !exists {
+		return nil, errTrackerNotFound
+	}
+	copied := *issue
+	return &copied, nil
+}
+
+func (f *fakeIssueTracker) CreateIssue(ctx context.Context, title, description, status string) (*trackerIssue, error) {
+	f.nextID++
+	issue := f.put(fmt.Sprintf("issue_%d", f.nextID), title, status)
+	issue.Description = description
+	return f.GetIssue(ctx, issue.ID)
+}
+
+func (f *fakeIssueTracker) UpdateIssue(ctx context.Context, issue *trackerIssue, title, status string) (*trackerIssue, error) {
+	stored := f.issues[issue.ID]
+	stored.Title = title
+	stored.Status = status
+	stored.UpdatedAt = time.Now()
+	return f.GetIssue(ctx, issue.ID)
+}
+
+func (f *fakeIssueTracker) ListComments(ctx context.Context, issueID string) ([]trackerComment, error) {
+	return f.comments[issueID], nil
+}
+
+func (f *fakeIssueTracker) AddComment(ctx context.Context, issueID, body string) (*trackerComment, error) {
+	f.nextID++
+	comment := trackerComment{ID: fmt.Sprintf("comment_%d", f.nextID), Author: "Sync Bot", Body: body, CreatedAt: time.Now()}
+	f.comments[issueID] = append(f.comments[issueID], comment)
+	f.issues[issueID].UpdatedAt = time.Now()
+	return &comment, nil
+}
+
+func (f *fakeIssueTracker) SameStatus(a, b string) bool {
+	return a == b
+}
+
+// put stores an issue as if it was edited directly in the tracker
+func (f *fakeIssueTracker) put(id, title, status string) *trackerIssue {
+	issue := &trackerIssue{ID: id, Key: "ENG-" + id, URL: "https://tracker.example.com/" + id, Title: title, Status: status, UpdatedAt: time.Now()}
+	f.issues[id] = issue
+	return issue
+}
+
+// setupTestTaskSyncService creates a sync service and a board with one task
+func setupTestTaskSyncService(t *testing.T) (*taskSyncServiceImpl, *fakeIssueTracker, *models.TaskBoard, *models.Task) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(
+		&models.TaskBoard{},
+		&models.Task{},
+		&models.TaskComment{},
+		&models.TaskSyncIntegration{},
+		&models.TaskExternalLink{},
+	)
+	require.NoError(t, err, "Failed to migrate test database")
+
+	tracker := &fakeIssueTracker{issues: make(map[string]*trackerIssue), comments: make(map[string][]trackerComment)}
+	service := &taskSyncServiceImpl{
+		db: db,
+		newTracker: func(integration *models.TaskSyncIntegration, token string) issueTracker {
+			return tracker
+		},
+		statusChanged:   func(taskID, oldStatus string) {},
+		frontendBaseURL: "https://notes.example.com",
+	}
+
+	board := models.TaskBoard{Name: "Launch", ClerkUserID: "user_1", IsStandalone: true}
+	require.NoError(t, db.Create(&board).Error)
+	task := models.Task{Title: "Write launch post", Status: "todo", TaskBoardID: board.ID}
+	require.NoError(t, db.Create(&task).Error)
+
+	return service, tracker, &board, &task
+}
+
+// saveTestTaskSyncIntegration configures a Linear integration for the board
+func saveTestTaskSyncIntegration(t *testing.T, service *taskSyncServiceImpl, boardID, conflictPolicy string) *TaskSyncIntegrationResponse {
+	integration, err := service.SaveIntegration(context.Background(), boardID, TaskSyncSettings{
+		Provider:       " Linear ",
+		AccessToken:    "lin_api_token",
+		ProjectKey:     "team_1",
+		ConflictPolicy: conflictPolicy,
+	}, "user_1")
+	require.NoError(t, err)
+	return integration
+}
+
+func TestSaveTaskSyncIntegration_ValidatesSettings(t *testing.T) {
+	service, _, board, _ := setupTestTaskSyncService(t)
+	ctx := context.Background()
+
+	_, err := service.SaveIntegration(ctx, board.ID, TaskSyncSettings{Provider: "trello", AccessToken: "token", ProjectKey: "x"}, "user_1")
+	assert.ErrorIs(t, err, ErrInvalidTaskSyncIntegration)
+
+	_, err = service.SaveIntegration(ctx, board.ID, TaskSyncSettings{Provider: "jira", BaseURL: "http://acme.atlassian.net", Email: "a@example.com", AccessToken: "token", ProjectKey: "ENG"}, "user_1")
+	assert.ErrorIs(t, err, ErrInvalidTaskSyncIntegration, "Jira sites must use https")
+
+	_, err = service.SaveIntegration(ctx, board.ID, TaskSyncSettings{Provider: "linear", ProjectKey: "team_1"}, "user_1")
+	assert.ErrorIs(t, err, ErrInvalidTaskSyncIntegration, "A token is required for new integrations")
+
+	_, err = service.SaveIntegration(ctx, board.ID, TaskSyncSettings{Provider: "linear", AccessToken: "token", ProjectKey: "team_1", ConflictPolicy: "coin_flip"}, "user_1")
+	assert.ErrorIs(t, err, ErrInvalidTaskSyncIntegration)
+
+	integration := saveTestTaskSyncIntegration(t, service, board.ID, "")
+	assert.Equal(t, models.TaskSyncProviderLinear, integration.Provider)
+	assert.Equal(t, models.TaskSyncConflictNewestWins, integration.ConflictPolicy)
+	assert.Equal(t, defaultTaskSyncInterval, integration.SyncIntervalMinutes)
+	assert.True(t, integration.HasAccessToken)
+	assert.True(t, integration.Enabled)
+
+	// The token is kept when updating other settings
+	updated, err := service.SaveIntegration(ctx, board.ID, TaskSyncSettings{Provider: "linear", ProjectKey: "team_1", ConflictPolicy: models.TaskSyncConflictLocalWins}, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, integration.ID, updated.ID)
+	assert.Equal(t, models.TaskSyncConflictLocalWins, updated.ConflictPolicy)
+}
+
+func TestTaskSync_MirrorsTasksAndIssues(t *testing.T) {
+	service, tracker, board, task := setupTestTaskSyncService(t)
+	integration := saveTestTaskSyncIntegration(t, service, board.ID, "")
+	tracker.put("remote_1", "Fix signup bug", "in_progress")
+
+	result, err := service.Sync(context.Background(), integration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Imported)
+
+	// The local task was created in the tracker with a link back to the board
+	var link models.TaskExternalLink
+	require.NoError(t, service.db.Where("task_id = ?", task.ID).First(&link).Error)
+	created := tracker.issues[link.ExternalID]
+	require.NotNil(t, created)
+	assert.Equal(t, "Write launch post", created.Title)
+	assert.Contains(t, created.Description, "https://notes.example.com/kanban/"+board.ID+"?task="+task.ID)
+	assert.Equal(t, created.URL, link.ExternalURL)
+
+	// The remote issue was imported as a task
+	var imported models.Task
+	require.NoError(t, service.db.Preload("ExternalLink").Where("title = ?", "Fix signup bug").First(&imported).Error)
+	assert.Equal(t, "in_progress", imported.Status)
+	require.NotNil(t, imported.ExternalLink)
+	assert.Equal(t, "ENG-remote_1", imported.ExternalLink.ExternalKey)
+
+	// Status changes flow both ways
+	require.NoError(t, service.db.Model(task).Update("status", "in_progress").Error)
+	tracker.issues["remote_1"].Status = "done"
+	tracker.issues["remote_1"].UpdatedAt = time.Now()
+
+	result, err = service.Sync(context.Background(), integration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Pushed)
+	assert.Equal(t, 1, result.Pulled)
+	assert.Zero(t, result.Created)
+	assert.Zero(t, result.Imported)
+	assert.Equal(t, "in_progress", tracker.issues[link.ExternalID].Status)
+	require.NoError(t, service.db.First(&imported, "id = ?", imported.ID).Error)
+	assert.Equal(t, "done", imported.Status)
+
+	// Issues deleted in the tracker are unlinked
+	delete(tracker.issues, link.ExternalID)
+	result, err = service.Sync(context.Background(), integration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Unlinked)
+
+	status, err := service.GetStatus(board.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TaskSyncStatusSuccess, status.LastSyncStatus)
+	assert.Equal(t, int64(1), status.LinkedTasks)
+	assert.NotNil(t, status.NextSyncAt)
+}
+
+func TestTaskSync_AppliesConflictPolicy(t *testing.T) {
+	service, tracker, board, task := setupTestTaskSyncService(t)
+	integration := saveTestTaskSyncIntegration(t, service, board.ID, models.TaskSyncConflictRemoteWins)
+
+	_, err := service.Sync(context.Background(), integration.ID)
+	require.NoError(t, err)
+	var link models.TaskExternalLink
+	require.NoError(t, service.db.Where("task_id = ?", task.ID).First(&link).Error)
+
+	// Both sides changed the status since the last sync
+	require.NoError(t, service.db.Model(task).Updates(map[string]interface{}{"status": "done", "updated_at": time.Now().Add(time.Hour)}).Error)
+	tracker.issues[link.ExternalID].Status = "in_progress"
+
+	result, err := service.Sync(context.Background(), integration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Conflicts)
+	assert.Equal(t, []string{task.ID}, result.ConflictedTaskIDs)
+	require.NoError(t, service.db.First(task, "id = ?", task.ID).Error)
+	assert.Equal(t, "in_progress", task.Status, "The tracker wins with remote_wins")
+
+	status, err := service.GetStatus(board.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.LastConflicts)
+
+	// With newest_wins the more recent edit is kept
+	require.NoError(t, service.db.Model(&models.TaskSyncIntegration{}).Where("id = ?", integration.ID).Update("conflict_policy", models.TaskSyncConflictNewestWins).Error)
+	require.NoError(t, service.db.Model(task).Updates(map[string]interface{}{"status": "done", "updated_at": time.Now().Add(time.Hour)}).Error)
+	tracker.issues[link.ExternalID].Status = "backlog"
+
+	result, err = service.Sync(context.Background(), integration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Conflicts)
+	assert.Equal(t, "done", tracker.issues[link.ExternalID].Status)
+}
+
+func TestTaskSync_MirrorsComments(t *testing.T) {
+	service, tracker, board, task := setupTestTaskSyncService(t)
+	integration := saveTestTaskSyncIntegration(t, service, board.ID, "")
+
+	local := models.TaskComment{TaskID: task.ID, ClerkUserID: "user_1", AuthorName: "Ada Lovelace", Body: "Drafted the intro"}
+	require.NoError(t, service.db.Create(&local).Error)
+
+	result, err := service.Sync(context.Background(), integration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.CommentsPushed)
+
+	var link models.TaskExternalLink
+	require.NoError(t, service.db.Where("task_id = ?", task.ID).First(&link).Error)
+	require.Len(t, tracker.comments[link.ExternalID], 1)
+	assert.Contains(t, tracker.comments[link.ExternalID][0].Body, "Ada Lovelace")
+
+	// A reply in the tracker is pulled in, and pushed comments aren't echoed back
+	tracker.comments[link.ExternalID] = append(tracker.comments[link.ExternalID], trackerComment{ID: "reply_1", Author: "Grace", Body: "Looks good", CreatedAt: time.Now()})
+	tracker.issues[link.ExternalID].UpdatedAt = time.Now().Add(time.Minute)
+
+	result, err = service.Sync(context.Background(), integration.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.CommentsPulled)
+	assert.Zero(t, result.CommentsPushed)
+
+	var comments []models.TaskComment
+	require.NoError(t, service.db.Where("task_id = ?", task.ID).Order("created_at").Find(&comments).Error)
+	require.Len(t, comments, 2)
+	assert.Equal(t, "Grace", comments[1].AuthorName)
+	assert.Equal(t, models.TaskSyncProviderLinear, comments[1].Source)
+}
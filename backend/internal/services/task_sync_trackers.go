@@ -0,0 +1,337 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/pkg/jira"
+	"backend/pkg/linear"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// newIssueTracker creates the tracker client for an integration's provider
+func newIssueTracker(integration *models.TaskSyncIntegration, token string) issueTracker {
+	if integration.Provider == models.TaskSyncProviderLinear {
+		return &linearTracker{client: linear.NewClient(token), teamID: integration.ProjectKey}
+	}
+	return &jiraTracker{
+		client:     jira.NewClient(integration.BaseURL, integration.Email, token),
+		projectKey: integration.ProjectKey,
+		issueType:  integration.IssueType,
+	}
+}
+
+// jiraTracker syncs with a Jira project. Statuses are mapped through Jira's status categories,
+// so backlog and todo both map to "To Do".
+type jiraTracker struct {
+	client     *jira.Client
+	projectKey string
+	issueType  string
+}
+
+// jiraStatusCategory maps a task status to a Jira status category
+func jiraStatusCategory(status string) string {
+	switch status {
+	case "in_progress":
+		return "indeterminate"
+	case "done":
+		return "done"
+	default:
+		return "new"
+	}
+}
+
+// jiraTaskStatus maps a Jira status category to a task status
+func jiraTaskStatus(category string) string {
+	switch category {
+	case "indeterminate":
+		return "in_progress"
+	case "done":
+		return "done"
+	default:
+		return "todo"
+	}
+}
+
+func jiraError(err error) error {
+	if errors.Is(err, jira.ErrNotFound) {
+		return errTrackerNotFound
+	}
+	return err
+}
+
+func (t *jiraTracker) toIssue(issue *jira.Issue) *trackerIssue {
+	return &trackerIssue{
+		ID:          issue.ID,
+		Key:         issue.Key,
+		URL:         t.client.IssueURL(issue.Key),
+		Title:       issue.Summary,
+		Description: issue.Description,
+		Status:      jiraTaskStatus(issue.StatusCategory),
+		UpdatedAt:   issue.Updated,
+	}
+}
+
+func (t *jiraTracker) CheckProject(ctx context.Context) error {
+	_, err := t.client.GetProject(ctx, t.projectKey)
+	return jiraError(err)
+}
+
+func (t *jiraTracker) ListIssues(ctx context.Context, updatedSince time.Time, limit int) ([]trackerIssue, error) {
+	issues, err := t.client.SearchIssues(ctx, t.projectKey, updatedSince, limit)
+	if err != nil {
+		return nil, jiraError(err)
+	}
+	result := make([]trackerIssue, len(issues))
+	for i := range issues {
+		result[i] = *t.toIssue(&issues[i])
+	}
+	return result, nil
+}
+
+func (t *jiraTracker) GetIssue(ctx context.Context, issueID string) (*trackerIssue, error) {
+	issue, err := t.client.GetIssue(ctx, issueID)
+	if err != nil {
+		return nil, jiraError(err)
+	}
+	return t.toIssue(issue), nil
+}
+
+func (t *jiraTracker) CreateIssue(ctx context.Context, title, description, status string) (*trackerIssue, error) {
+	issue, err := t.client.CreateIssue(ctx, t.projectKey, t.issueType, jira.IssueFields{Summary: title, Description: description})
+	if err != nil {
+		return nil, jiraError(err)
+	}
+	created := t.toIssue(issue)
+	if t.SameStatus(created.Status, status) {
+		return created, nil
+	}
+	return t.UpdateIssue(ctx, created, title, status)
+}
+
+func (t *jiraTracker) UpdateIssue(ctx context.Context, issue *trackerIssue, title, status string) (*trackerIssue, error) {
+	if title != issue.Title {
+		if err := t.client.UpdateIssue(ctx, issue.ID, jira.IssueFields{Summary: title, Description: issue.Description}); err != nil {
+			return nil, jiraError(err)
+		}
+	}
+
+	if !t.SameStatus(issue.Status, status) {
+		transitions, err := t.client.ListTransitions(ctx, issue.ID)
+		if err != nil {
+			return nil, jiraError(err)
+		}
+		transitionID := ""
+		for _, transition := range transitions {
+			if transition.StatusCategory == jiraStatusCategory(status) {
+				transitionID = transition.ID
+				break
+			}
+		}
+		if transitionID == "" {
+			return nil, fmt.Errorf("the workflow of %s has no transition for status %s", issue.Key, status)
+		}
+		if err := t.client.TransitionIssue(ctx, issue.ID, transitionID); err != nil {
+			return nil, jiraError(err)
+		}
+	}
+
+	return t.GetIssue(ctx, issue.ID)
+}
+
+func (t *jiraTracker) ListComments(ctx context.Context, issueID string) ([]trackerComment, error) {
+	comments, err := t.client.ListComments(ctx, issueID)
+	if err != nil {
+		return nil, jiraError(err)
+	}
+	result := make([]trackerComment, len(comments))
+	for i, comment := range comments {
+		result[i] = trackerComment{ID: comment.ID, Author: comment.Author, Body: comment.Body, CreatedAt: comment.Created}
+	}
+	return result, nil
+}
+
+func (t *jiraTracker) AddComment(ctx context.Context, issueID, body string) (*trackerComment, error) {
+	comment, err := t.client.AddComment(ctx, issueID, body)
+	if err != nil {
+		return nil, jiraError(err)
+	}
+	return &trackerComment{ID: comment.ID, Author: comment.Author, Body: comment.Body, CreatedAt: comment.Created}, nil
+}
+
+func (t *jiraTracker) SameStatus(a, b string) bool {
+	return jiraStatusCategory(a) == jiraStatusCategory(b)
+}
+
+// linearTracker syncs with a Linear team. Statuses are mapped through workflow state types.
+type linearTracker struct {
+	client *linear.Client
+	teamID string
+	states []linear.WorkflowState
+}
+
+// linearStateType maps a task status to a Linear workflow state type
+func linearStateType(status string) string {
+	switch status {
+	case "backlog":
+		return "backlog"
+	case "in_progress":
+		return "started"
+	case "done":
+		return "completed"
+	default:
+		return "unstarted"
+	}
+}
+
+// linearTaskStatus maps a Linear workflow state type to a task status
+func linearTaskStatus(stateType string) string {
+	switch stateType {
+	case "backlog", "triage":
+		return "backlog"
+	case "started":
+		return "in_progress"
+	case "completed", "canceled":
+		return "done"
+	default:
+		return "todo"
+	}
+}
+
+func linearError(err error) error {
+	if errors.Is(err, linear.ErrNotFound) {
+		return errTrackerNotFound
+	}
+	return err
+}
+
+func toLinearIssue(issue *linear.Issue) *trackerIssue {
+	return &trackerIssue{
+		ID:          issue.ID,
+		Key:         issue.Identifier,
+		URL:         issue.URL,
+		Title:       issue.Title,
+		Description: issue.Description,
+		Status:      linearTaskStatus(issue.State.Type),
+		UpdatedAt:   issue.UpdatedAt,
+	}
+}
+
+// stateFor returns the team's first workflow state for a task status
+func (t *linearTracker) stateFor(ctx context.Context, status string) (string, error) {
+	if t.states == nil {
+		states, err := t.client.ListWorkflowStates(ctx, t.teamID)
+		if err != nil {
+			return "", linearError(err)
+		}
+		t.states = states
+	}
+
+	for _, stateType := range []string{linearStateType(status), "unstarted"} {
+		var match *linear.WorkflowState
+		for i := range t.states {
+			state := &t.states[i]
+			if state.Type == stateType && (match == nil || state.Position < match.Position) {
+				match = state
+			}
+		}
+		if match != nil {
+			return match.ID, nil
+		}
+	}
+	return "", fmt.Errorf("the team has no workflow state for status %s", status)
+}
+
+func (t *linearTracker) CheckProject(ctx context.Context) error {
+	_, err := t.client.GetTeam(ctx, t.teamID)
+	return linearError(err)
+}
+
+func (t *linearTracker) ListIssues(ctx context.Context, updatedSince time.Time, limit int) ([]trackerIssue, error) {
+	issues, err := t.client.ListIssues(ctx, t.teamID, updatedSince, limit)
+	if err != nil {
+		return nil, linearError(err)
+	}
+	result := make([]trackerIssue, len(issues))
+	for i := range issues {
+		result[i] = *toLinearIssue(&issues[i])
+	}
+	return result, nil
+}
+
+func (t *linearTracker) GetIssue(ctx context.Context, issueID string) (*trackerIssue, error) {
+	issue, err := t.client.GetIssue(ctx, issueID)
+	if err != nil {
+		return nil, linearError(err)
+	}
+	return toLinearIssue(issue), nil
+}
+
+func (t *linearTracker) CreateIssue(ctx context.Context, title, description, status string) (*trackerIssue, error) {
+	stateID, err := t.stateFor(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	issue, err := t.client.CreateIssue(ctx, t.teamID, linear.IssueInput{Title: title, Description: description, StateID: stateID})
+	if err != nil {
+		return nil, linearError(err)
+	}
+	return toLinearIssue(issue), nil
+}
+
+func (t *linearTracker) UpdateIssue(ctx context.Context, issue *trackerIssue, title, status string) (*trackerIssue, error) {
+	var input linear.IssueInput
+	if title != issue.Title {
+		input.Title = title
+	}
+	if !t.SameStatus(issue.Status, status) {
+		stateID, err := t.stateFor(ctx, status)
+		if err != nil {
+			return nil, err
+		}
+		input.StateID = stateID
+	}
+	if input == (linear.IssueInput{}) {
+		return issue, nil
+	}
+
+	updated, err := t.client.UpdateIssue(ctx, issue.ID, input)
+	if err != nil {
+		return nil, linearError(err)
+	}
+	return toLinearIssue(updated), nil
+}
+
+func (t *linearTracker) ListComments(ctx context.Context, issueID string) ([]trackerComment, error) {
+	comments, err := t.client.ListComments(ctx, issueID)
+	if err != nil {
+		return nil, linearError(err)
+	}
+	result := make([]trackerComment, len(comments))
+	for i, comment := range comments {
+		result[i] = toLinearComment(&comment)
+	}
+	return result, nil
+}
+
+func (t *linearTracker) AddComment(ctx context.Context, issueID, body string) (*trackerComment, error) {
+	comment, err := t.client.AddComment(ctx, issueID, body)
+	if err != nil {
+		return nil, linearError(err)
+	}
+	result := toLinearComment(comment)
+	return &result, nil
+}
+
+func (t *linearTracker) SameStatus(a, b string) bool {
+	return linearStateType(a) == linearStateType(b)
+}
+
+func toLinearComment(comment *linear.Comment) trackerComment {
+	author := ""
+	if comment.User != nil {
+		author = comment.User.Name
+	}
+	return trackerComment{ID: comment.ID, Author: author, Body: comment.Body, CreatedAt: comment.CreatedAt}
+}
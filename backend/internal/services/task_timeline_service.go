@@ -0,0 +1,329 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxTaskDurationDays caps a task's estimated duration
+const maxTaskDurationDays = 3650
+
+var (
+	// ErrInvalidTaskDependency is returned for a dependency on the task itself or on a task of another board
+	ErrInvalidTaskDependency = errors.New("tasks can only depend on other tasks of the same board")
+	// ErrTaskDependencyCycle is returned when a dependency would make a task wait on itself
+	ErrTaskDependencyCycle = errors.New("dependency would create a cycle")
+	// ErrTaskDependencyExists is returned when the task already depends on the other task
+	ErrTaskDependencyExists = errors.New("task already depends on this task")
+	// ErrTaskDependencyNotFound is returned when the task doesn't depend on the other task
+	ErrTaskDependencyNotFound = errors.New("task dependency not found")
+	// ErrInvalidTaskDuration is returned for a duration outside 1 to maxTaskDurationDays days
+	ErrInvalidTaskDuration = fmt.Errorf("durationDays must be between 1 and %d", maxTaskDurationDays)
+)
+
+// TimelineTask is a task with its dependency-aware schedule. Days are counted from the timeline's start.
+type TimelineTask struct {
+	ID             string     `json:"id"`
+	Title          string     `json:"title"`
+	Status         string     `json:"status"`
+	Priority       string     `json:"priority"`
+	StartDate      *time.Time `json:"startDate,omitempty"`
+	DurationDays   int        `json:"durationDays"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+	DependsOn      []string   `json:"dependsOn"`
+	EarliestStart  time.Time  `json:"earliestStart"`
+	EarliestFinish time.Time  `json:"earliestFinish"`
+	LatestStart    time.Time  `json:"latestStart"`
+	LatestFinish   time.Time  `json:"latestFinish"`
+	SlackDays      int        `json:"slackDays"`
+	Critical       bool       `json:"critical"`
+}
+
+// BoardTimeline is the scheduling data of a board for a timeline view
+type BoardTimeline struct {
+	BoardID      string                  `json:"boardId"`
+	Start        time.Time               `json:"start"`
+	End          time.Time               `json:"end"`
+	Tasks        []TimelineTask          `json:"tasks"`
+	Dependencies []models.TaskDependency `json:"dependencies"`
+	CriticalPath []string                `json:"criticalPath"`
+}
+
+// TaskTimelineService interface defines methods for task dependencies and board timelines
+type TaskTimelineService interface {
+	AddDependency(taskID, dependsOnID string) (*models.TaskDependency, error)
+	RemoveDependency(taskID, dependsOnID string) error
+	GetTimeline(boardID string, today time.Time) (*BoardTimeline, error)
+}
+
+// taskTimelineServiceImpl implements the TaskTimelineService interface
+type taskTimelineServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewTaskTimelineService creates a new TaskTimelineService instance
+func NewTaskTimelineService() TaskTimelineService {
+	return &taskTimelineServiceImpl{
+		db: db.DB,
+	}
+}
+
+// AddDependency makes a task wait on another task of its board, rejecting dependencies that form a cycle
+func (s *taskTimelineServiceImpl) AddDependency(taskID, dependsOnID string) (*models.TaskDependency, error) {
+	if taskID == dependsOnID {
+		return nil, ErrInvalidTaskDependency
+	}
+
+	var tasks []models.Task
+	if err := s.db.Select("id", "task_board_id").Where("id IN ?", []string{taskID, dependsOnID}).Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch tasks: %w", err)
+	}
+	if len(tasks) != 2 || tasks[0].TaskBoardID != tasks[1].TaskBoardID {
+		return nil, ErrInvalidTaskDependency
+	}
+	boardID := tasks[0].TaskBoardID
+
+	var dependencies []models.TaskDependency
+	if err := s.db.Where("task_board_id = ?", boardID).Find(&dependencies).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch task dependencies: %w", err)
+	}
+	dependsOn := make(map[string][]string)
+	for _, dependency := range dependencies {
+		if dependency.TaskID == taskID && dependency.DependsOnID == dependsOnID {
+			return nil, ErrTaskDependencyExists
+		}
+		dependsOn[dependency.TaskID] = append(dependsOn[dependency.TaskID], dependency.DependsOnID)
+	}
+
+	// The new dependency closes a cycle if the other task already waits on this one
+	visited := map[string]bool{dependsOnID: true}
+	queue := []string{dependsOnID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range dependsOn[current] {
+			if next == taskID {
+				return nil, ErrTaskDependencyCycle
+			}
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	dependency := models.TaskDependency{TaskID: taskID, DependsOnID: dependsOnID, TaskBoardID: boardID}
+	if err := s.db.Create(&dependency).Error; err != nil {
+		return nil, fmt.Errorf("failed to create task dependency: %w", err)
+	}
+	return &dependency, nil
+}
+
+// RemoveDependency removes a task's dependency on another task
+func (s *taskTimelineServiceImpl) RemoveDependency(taskID, dependsOnID string) error {
+	result := s.db.Where("task_id = ? AND depends_on_id = ?", taskID, dependsOnID).Delete(&models.TaskDependency{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete task dependency: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTaskDependencyNotFound
+	}
+	return nil
+}
+
+// GetTimeline schedules a board's tasks as early as their start dates and dependencies allow, and
+// finds the critical path: the chain of tasks where any delay pushes back the end of the board.
+// Boards without start dates are scheduled from today.
+func (s *taskTimelineServiceImpl) GetTimeline(boardID string, today time.Time) (*BoardTimeline, error) {
+	var tasks []models.Task
+	if err := s.db.Where("task_board_id = ?", boardID).Order("position ASC, created_at ASC").Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch tasks: %w", err)
+	}
+	dependencies := []models.TaskDependency{}
+	if err := s.db.Where("task_board_id = ?", boardID).Order("created_at ASC").Find(&dependencies).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch task dependencies: %w", err)
+	}
+
+	start := truncateToDay(today)
+	for _, task := range tasks {
+		if task.StartDate != nil && truncateToDay(*task.StartDate).Before(start) {
+			start = truncateToDay(*task.StartDate)
+		}
+	}
+
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+	}
+	predecessors := make([][]int, len(tasks))
+	successors := make([][]int, len(tasks))
+	for _, dependency := range dependencies {
+		from, okFrom := index[dependency.DependsOnID]
+		to, okTo := index[dependency.TaskID]
+		if okFrom && okTo {
+			predecessors[to] = append(predecessors[to], from)
+			successors[from] = append(successors[from], to)
+		}
+	}
+	order := topologicalTaskOrder(predecessors, successors)
+
+	// Forward pass: earliest start and finish, in days from the start
+	durations := make([]int, len(tasks))
+	earliestStart := make([]int, len(tasks))
+	earliestFinish := make([]int, len(tasks))
+	end := 0
+	for _, i := range order {
+		durations[i] = tasks[i].DurationDays
+		if durations[i] < 1 {
+			durations[i] = 1
+		}
+		if tasks[i].StartDate != nil {
+			earliestStart[i] = daysBetween(start, *tasks[i].StartDate)
+		}
+		for _, p := range predecessors[i] {
+			if earliestFinish[p] > earliestStart[i] {
+				earliestStart[i] = earliestFinish[p]
+			}
+		}
+		earliestFinish[i] = earliestStart[i] + durations[i]
+		if earliestFinish[i] > end {
+			end = earliestFinish[i]
+		}
+	}
+
+	// Backward pass: latest finish and start that don't delay the end
+	latestFinish := make([]int, len(tasks))
+	latestStart := make([]int, len(tasks))
+	for k := len(order) - 1; k >= 0; k-- {
+		i := order[k]
+		latestFinish[i] = end
+		for _, next := range successors[i] {
+			if latestStart[next] < latestFinish[i] {
+				latestFinish[i] = latestStart[next]
+			}
+		}
+		latestStart[i] = latestFinish[i] - durations[i]
+	}
+
+	dependsOn := make(map[string][]string)
+	for _, dependency := range dependencies {
+		dependsOn[dependency.TaskID] = append(dependsOn[dependency.TaskID], dependency.DependsOnID)
+	}
+	day := func(offset int) time.Time { return start.AddDate(0, 0, offset) }
+	timeline := &BoardTimeline{
+		BoardID:      boardID,
+		Start:        start,
+		End:          day(end),
+		Tasks:        make([]TimelineTask, len(tasks)),
+		Dependencies: dependencies,
+		CriticalPath: []string{},
+	}
+	for i, task := range tasks {
+		taskDependsOn := dependsOn[task.ID]
+		if taskDependsOn == nil {
+			taskDependsOn = []string{}
+		}
+		slack := latestStart[i] - earliestStart[i]
+		timeline.Tasks[i] = TimelineTask{
+			ID:             task.ID,
+			Title:          task.Title,
+			Status:         task.Status,
+			Priority:       task.Priority,
+			StartDate:      task.StartDate,
+			DurationDays:   durations[i],
+			CompletedAt:    task.CompletedAt,
+			DependsOn:      taskDependsOn,
+			EarliestStart:  day(earliestStart[i]),
+			EarliestFinish: day(earliestFinish[i]),
+			LatestStart:    day(latestStart[i]),
+			LatestFinish:   day(latestFinish[i]),
+			SlackDays:      slack,
+			Critical:       slack == 0,
+		}
+	}
+
+	// Walk back from the critical task that finishes last through critical predecessors that finish
+	// right when it starts
+	current := -1
+	for _, i := range order {
+		if timeline.Tasks[i].Critical && earliestFinish[i] == end && current == -1 {
+			current = i
+		}
+	}
+	for current != -1 {
+		timeline.CriticalPath = append(timeline.CriticalPath, tasks[current].ID)
+		next := -1
+		for _, p := range predecessors[current] {
+			if timeline.Tasks[p].Critical && earliestFinish[p] == earliestStart[current] {
+				next = p
+				break
+			}
+		}
+		current = next
+	}
+	for i, j := 0, len(timeline.CriticalPath)-1; i < j; i, j = i+1, j-1 {
+		timeline.CriticalPath[i], timeline.CriticalPath[j] = timeline.CriticalPath[j], timeline.CriticalPath[i]
+	}
+
+	return timeline, nil
+}
+
+// ValidateTaskDuration checks a task's estimated duration, where zero means the default of one day
+func ValidateTaskDuration(days int) error {
+	if days < 0 || days > maxTaskDurationDays {
+		return ErrInvalidTaskDuration
+	}
+	return nil
+}
+
+// topologicalTaskOrder orders tasks so every task comes after the tasks it depends on, keeping the
+// board order among independent tasks. Tasks left in a cycle are appended in board order.
+func topologicalTaskOrder(predecessors, successors [][]int) []int {
+	remaining := make([]int, len(predecessors))
+	var ready []int
+	for i := range predecessors {
+		remaining[i] = len(predecessors[i])
+		if remaining[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	order := make([]int, 0, len(predecessors))
+	placed := make([]bool, len(predecessors))
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, i)
+		placed[i] = true
+		for _, next := range successors[i] {
+			remaining[next]--
+			if remaining[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	for i := range placed {
+		if !placed[i] {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// truncateToDay returns midnight UTC of a time's day
+func truncateToDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween returns the whole days from start to a time's day
+func daysBetween(start, t time.Time) int {
+	return int(truncateToDay(t).Sub(start).Hours() / 24)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestTaskTimelineService creates a task timeline service with a board on an in-memory database
+func setupTestTaskTimelineService(t *testing.T) (*taskTimelineServiceImpl, models.TaskBoard) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+
+	err = db.AutoMigrate(&models.TaskBoard{}, &models.Task{}, &models.TaskDependency{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	board := models.TaskBoard{Name: "Launch", ClerkUserID: "user_owner", IsStandalone: true}
+	require.NoError(t, db.Create(&board).Error)
+
+	return &taskTimelineServiceImpl{db: db}, board
+}
+
+// createTimelineTask creates a task on a board
+func createTimelineTask(t *testing.T, db *gorm.DB, boardID, title string, position, days int) models.Task {
+	task := models.Task{Title: title, TaskBoardID: boardID, Position: position, DurationDays: days}
+	require.NoError(t, db.Create(&task).Error)
+	return task
+}
+
+func TestTaskTimelineService_AddDependency(t *testing.T) {
+	service, board := setupTestTaskTimelineService(t)
+	design := createTimelineTask(t, service.db, board.ID, "Design", 0, 2)
+	build := createTimelineTask(t, service.db, board.ID, "Build", 1, 3)
+	ship := createTimelineTask(t, service.db, board.ID, "Ship", 2, 1)
+
+	_, err := service.AddDependency(build.ID, design.ID)
+	require.NoError(t, err)
+	_, err = service.AddDependency(ship.ID, build.ID)
+	require.NoError(t, err)
+
+	_, err = service.AddDependency(build.ID, design.ID)
+	assert.ErrorIs(t, err, ErrTaskDependencyExists)
+	_, err = service.AddDependency(design.ID, ship.ID)
+	assert.ErrorIs(t, err, ErrTaskDependencyCycle)
+	_, err = service.AddDependency(design.ID, design.ID)
+	assert.ErrorIs(t, err, ErrInvalidTaskDependency)
+
+	other := models.TaskBoard{Name: "Other", ClerkUserID: "user_owner", IsStandalone: true}
+	require.NoError(t, service.db.Create(&other).Error)
+	elsewhere := createTimelineTask(t, service.db, other.ID, "Elsewhere", 0, 1)
+	_, err = service.AddDependency(design.ID, elsewhere.ID)
+	assert.ErrorIs(t, err, ErrInvalidTaskDependency)
+
+	require.NoError(t, service.RemoveDependency(ship.ID, build.ID))
+	assert.ErrorIs(t, service.RemoveDependency(ship.ID, build.ID), ErrTaskDependencyNotFound)
+	_, err = service.AddDependency(design.ID, ship.ID)
+	assert.NoError(t, err, "the cycle is gone once the dependency is removed")
+}
+
+func TestTaskTimelineService_GetTimeline(t *testing.T) {
+	service, board := setupTestTaskTimelineService(t)
+	today := time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	// Design (2d) -> Build (3d) -> Ship (1d), with Docs (1d) after Design off the critical path
+	design := createTimelineTask(t, service.db, board.ID, "Design", 0, 2)
+	build := createTimelineTask(t, service.db, board.ID, "Build", 1, 3)
+	docs := createTimelineTask(t, service.db, board.ID, "Docs", 2, 1)
+	ship := createTimelineTask(t, service.db, board.ID, "Ship", 3, 1)
+	for _, pair := range [][2]string{{build.ID, design.ID}, {docs.ID, design.ID}, {ship.ID, build.ID}, {ship.ID, docs.ID}} {
+		_, err := service.AddDependency(pair[0], pair[1])
+		require.NoError(t, err)
+	}
+
+	timeline, err := service.GetTimeline(board.ID, today)
+	require.NoError(t, err)
+
+	start := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, start, timeline.Start)
+	assert.Equal(t, start.AddDate(0, 0, 6), timeline.End)
+	assert.Equal(t, []string{design.ID, build.ID, ship.ID}, timeline.CriticalPath)
+	assert.Len(t, timeline.Dependencies, 4)
+
+	byID := make(map[string]TimelineTask)
+	for _, task := range timeline.Tasks {
+		byID[task.ID] = task
+	}
+	assert.Equal(t, start.AddDate(0, 0, 2), byID[build.ID].EarliestStart)
+	assert.Equal(t, start.AddDate(0, 0, 5), byID[ship.ID].EarliestStart)
+	assert.Equal(t, 2, byID[docs.ID].SlackDays)
+	assert.False(t, byID[docs.ID].Critical)
+	assert.ElementsMatch(t, []string{build.ID, docs.ID}, byID[ship.ID].DependsOn)
+}
+
+func TestTaskTimelineService_GetTimeline_StartDates(t *testing.T) {
+	service, board := setupTestTaskTimelineService(t)
+	today := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	// A task that can't start before a date pushes back the tasks waiting on it
+	kickoff := today.AddDate(0, 0, 3)
+	prep := models.Task{Title: "Prep", TaskBoardID: board.ID, DurationDays: 1, StartDate: &kickoff}
+	require.NoError(t, service.db.Create(&prep).Error)
+	follow := createTimelineTask(t, service.db, board.ID, "Follow-up", 1, 2)
+	_, err := service.AddDependency(follow.ID, prep.ID)
+	require.NoError(t, err)
+
+	timeline, err := service.GetTimeline(board.ID, today)
+	require.NoError(t, err)
+	assert.Equal(t, today.AddDate(0, 0, 4), timeline.Tasks[1].EarliestStart)
+	assert.Equal(t, today.AddDate(0, 0, 6), timeline.End)
+	assert.Equal(t, []string{prep.ID, follow.ID}, timeline.CriticalPath)
+}
+
+func TestValidateTaskDuration(t *testing.T) {
+	assert.NoError(t, ValidateTaskDuration(0))
+	assert.NoError(t, ValidateTaskDuration(5))
+	assert.ErrorIs(t, ValidateTaskDuration(-1), ErrInvalidTaskDuration)
+	assert.ErrorIs(t, ValidateTaskDuration(maxTaskDurationDays+1), ErrInvalidTaskDuration)
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"backend/pkg/recallai"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/organizationmembership"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidRedactionPolicy is returned, wrapped with the reason, for a redaction policy with an
+	// invalid custom pattern
+	ErrInvalidRedactionPolicy = errors.New("invalid redaction policy")
+	// ErrMeetingNotFound is returned when a meeting recording doesn't exist or isn't the user's
+	ErrMeetingNotFound = errors.New("meeting not found")
+)
+
+const (
+	maxRedactionPatterns      = 20
+	maxRedactionPatternLength = 200
+)
+
+// Placeholders redacted text is replaced with. Names are replaced with the participant's speaker label.
+const (
+	redactedEmail   = "[EMAIL]"
+	redactedNumber  = "[NUMBER]"
+	redactedPattern = "[REDACTED]"
+)
+
+var (
+	redactionEmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Seven or more digits with single separators between them: phone, card and account numbers, but not
+	// years, times or counts
+	redactionNumberPattern = regexp.MustCompile(`\+?\d(?:[ ().-]?\d){6,}`)
+)
+
+// RedactionPolicySettings is the API representation of an organization's redaction policy
+type RedactionPolicySettings struct {
+	Enabled        bool     `json:"enabled"`
+	RedactNames    bool     `json:"redactNames"`
+	RedactEmails   bool     `json:"redactEmails"`
+	RedactNumbers  bool     `json:"redactNumbers"`
+	CustomPatterns []string `json:"customPatterns"`
+}
+
+// TranscriptRedactionService interface defines methods for organization redaction policies and the
+// redaction reports of meetings
+type TranscriptRedactionService interface {
+	GetPolicy(ctx context.Context, organizationID string) (*RedactionPolicySettings, error)
+	SavePolicy(ctx context.Context, organizationID string, settings RedactionPolicySettings, updatedBy string) (*RedactionPolicySettings, error)
+	GetMeetingRedaction(ctx context.Context, clerkUserID, recordingID string) (*models.TranscriptRedaction, error)
+}
+
+// transcriptRedactionServiceImpl implements the TranscriptRedactionService interface
+type transcriptRedactionServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewTranscriptRedactionService creates a new TranscriptRedactionService instance
+func NewTranscriptRedactionService() TranscriptRedactionService {
+	return &transcriptRedactionServiceImpl{db: db.DB}
+}
+
+// GetPolicy returns an organization's redaction policy, disabled if none is saved
+func (s *transcriptRedactionServiceImpl) GetPolicy(ctx context.Context, organizationID string) (*RedactionPolicySettings, error) {
+	var policy models.OrganizationRedactionPolicy
+	if err := s.db.WithContext(ctx).Where("organization_id = ?", organizationID).Limit(1).Find(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch redaction policy: %w", err)
+	}
+	return toRedactionPolicySettings(&policy), nil
+}
+
+// SavePolicy validates and saves an organization's redaction policy
+func (s *transcriptRedactionServiceImpl) SavePolicy(ctx context.Context, organizationID string, settings RedactionPolicySettings, updatedBy string) (*RedactionPolicySettings, error) {
+	patterns := make([]string, 0, len(settings.CustomPatterns))
+	for _, pattern := range settings.CustomPatterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) > maxRedactionPatterns {
+		return nil, fmt.Errorf("%w: at most %d custom patterns are allowed", ErrInvalidRedactionPolicy, maxRedactionPatterns)
+	}
+	for _, pattern := range patterns {
+		if len(pattern) > maxRedactionPatternLength {
+			return nil, fmt.Errorf("%w: patterns can be at most %d characters", ErrInvalidRedactionPolicy, maxRedactionPatternLength)
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q isn't a valid regular expression", ErrInvalidRedactionPolicy, pattern)
+		}
+		if compiled.MatchString("") {
+			return nil, fmt.Errorf("%w: %q matches empty text", ErrInvalidRedactionPolicy, pattern)
+		}
+	}
+
+	policy := models.OrganizationRedactionPolicy{OrganizationID: organizationID}
+	if err := s.db.WithContext(ctx).Where(models.OrganizationRedactionPolicy{OrganizationID: organizationID}).
+		Assign(map[string]interface{}{
+			"enabled":         settings.Enabled,
+			"redact_names":    settings.RedactNames,
+			"redact_emails":   settings.RedactEmails,
+			"redact_numbers":  settings.RedactNumbers,
+			"custom_patterns": strings.Join(patterns, "\n"),
+			"updated_by":      updatedBy,
+		}).
+		FirstOrCreate(&policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save redaction policy: %w", err)
+	}
+	return toRedactionPolicySettings(&policy), nil
+}
+
+// GetMeetingRedaction returns what was redacted from one of the user's meetings, nil if its transcript
+// was sent to AI as it was
+func (s *transcriptRedactionServiceImpl) GetMeetingRedaction(ctx context.Context, clerkUserID, recordingID string) (*models.TranscriptRedaction, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.MeetingRecording{}).
+		Where("id = ? AND clerk_user_id = ?", recordingID, clerkUserID).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch meeting: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrMeetingNotFound
+	}
+
+	var redactions []models.TranscriptRedaction
+	if err := s.db.WithContext(ctx).Where("meeting_recording_id = ?", recordingID).Limit(1).Find(&redactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch redaction report: %w", err)
+	}
+	if len(redactions) == 0 {
+		return nil, nil
+	}
+	return &redactions[0], nil
+}
+
+// transcriptRedactor prepares meeting transcripts for AI providers, redacting what the policies of the
+// owner's organizations require
+type transcriptRedactor struct {
+	db              *gorm.DB
+	organizationIDs func(ctx context.Context, clerkUserID string) ([]string, error)
+}
+
+func newTranscriptRedactor(tx *gorm.DB) *transcriptRedactor {
+	return &transcriptRedactor{db: tx, organizationIDs: clerkUserOrganizationIDs}
+}
+
+// TranscriptForAI returns the transcript as plain text for AI analysis. When any of the owner's
+// organizations has redaction enabled, their policies are combined, the text is redacted and a report is
+// saved with the meeting. An error means the transcript mustn't be sent.
+func (r *transcriptRedactor) TranscriptForAI(ctx context.Context, recording *models.MeetingRecording, transcript []recallai.TranscriptEntry) (string, error) {
+	organizationIDs, err := r.organizationIDs(ctx, recording.ClerkUserID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch organizations: %w", err)
+	}
+
+	var policies []models.OrganizationRedactionPolicy
+	if len(organizationIDs) > 0 {
+		if err := r.db.WithContext(ctx).
+			Where("organization_id IN ? AND enabled = ?", organizationIDs, true).
+			Order("organization_id").
+			Find(&policies).Error; err != nil {
+			return "", fmt.Errorf("failed to fetch redaction policies: %w", err)
+		}
+	}
+	if len(policies) == 0 {
+		text, _ := redactTranscript(transcript, transcriptRedactionRules{})
+		return text, nil
+	}
+
+	rules := transcriptRedactionRules{}
+	applied := make([]string, len(policies))
+	for i, policy := range policies {
+		applied[i] = policy.OrganizationID
+		rules.names = rules.names || policy.RedactNames
+		rules.emails = rules.emails || policy.RedactEmails
+		rules.numbers = rules.numbers || policy.RedactNumbers
+		for _, pattern := range strings.Split(policy.CustomPatterns, "\n") {
+			if pattern == "" {
+				continue
+			}
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				return "", fmt.Errorf("invalid redaction pattern of organization %s: %w", policy.OrganizationID, err)
+			}
+			rules.patterns = append(rules.patterns, compiled)
+		}
+	}
+
+	text, report := redactTranscript(transcript, rules)
+	report.MeetingRecordingID = recording.ID
+	report.OrganizationIDs = strings.Join(applied, ",")
+	if err := r.db.WithContext(ctx).Where(models.TranscriptRedaction{MeetingRecordingID: recording.ID}).
+		Assign(map[string]interface{}{
+			"organization_ids": report.OrganizationIDs,
+			"names":            report.Names,
+			"emails":           report.Emails,
+			"numbers":          report.Numbers,
+			"custom_matches":   report.CustomMatches,
+		}).
+		FirstOrCreate(&models.TranscriptRedaction{MeetingRecordingID: recording.ID}).Error; err != nil {
+		return "", fmt.Errorf("failed to save redaction report: %w", err)
+	}
+
+	log.Info().
+		Str("recording_id", recording.ID).
+		Int("names", report.Names).
+		Int("emails", report.Emails).
+		Int("numbers", report.Numbers).
+		Int("custom_matches", report.CustomMatches).
+		Msg("Redacted meeting transcript before AI analysis")
+
+	return text, nil
+}
+
+// transcriptRedactionRules is what to redact from a transcript
+type transcriptRedactionRules struct {
+	names    bool
+	emails   bool
+	numbers  bool
+	patterns []*regexp.Regexp
+}
+
+// redactTranscript converts a transcript to plain text like TranscriptToPlainText, redacting it by the
+// rules. Participants are labeled "Speaker 1", "Speaker 2" and so on in order of speaking, and their
+// names, first or last, are replaced with their label wherever they're mentioned. Each labeled
+// participant and each mention counts as a redacted name.
+func redactTranscript(transcript []recallai.TranscriptEntry, rules transcriptRedactionRules) (string, models.TranscriptRedaction) {
+	var report models.TranscriptRedaction
+
+	labels := map[int]string{}
+	nameLabels := map[string]string{}
+	for _, entry := range transcript {
+		if _, seen := labels[entry.Participant.ID]; seen {
+			continue
+		}
+		label := fmt.Sprintf("Speaker %d", len(labels)+1)
+		labels[entry.Participant.ID] = label
+		name := strings.TrimSpace(entry.Participant.Name)
+		if !rules.names || name == "" {
+			continue
+		}
+		report.Names++
+		parts := append([]string{name}, strings.Fields(name)...)
+		for _, part := range parts {
+			key := strings.ToLower(part)
+			// Initials and short words would redact too much of the conversation
+			if len([]rune(part)) < 3 {
+				continue
+			}
+			if _, taken := nameLabels[key]; !taken {
+				nameLabels[key] = label
+			}
+		}
+	}
+
+	var namePattern *regexp.Regexp
+	if len(nameLabels) > 0 {
+		// Longest first, so a full name is replaced once rather than by its parts
+		names := make([]string, 0, len(nameLabels))
+		for name := range nameLabels {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+		sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+		namePattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(names, "|") + `)\b`)
+	}
+
+	var text strings.Builder
+	for _, entry := range transcript {
+		speaker := entry.Participant.Name
+		if rules.names {
+			speaker = labels[entry.Participant.ID]
+		}
+		words := make([]string, len(entry.Words))
+		for i, word := range entry.Words {
+			words[i] = word.Text
+		}
+		text.WriteString(speaker)
+		text.WriteString(": ")
+		text.WriteString(redactText(strings.Join(words, " "), rules, namePattern, nameLabels, &report))
+		text.WriteString("\n")
+	}
+	return text.String(), report
+}
+
+// redactText redacts one line of a transcript, counting what it replaced in the report. Emails go first,
+// so their digits and names aren't redacted on their own.
+func redactText(line string, rules transcriptRedactionRules, namePattern *regexp.Regexp, nameLabels map[string]string, report *models.TranscriptRedaction) string {
+	replace := func(pattern *regexp.Regexp, placeholder string, count *int) {
+		line = pattern.ReplaceAllStringFunc(line, func(string) string {
+			*count++
+			return placeholder
+		})
+	}
+
+	if rules.emails {
+		replace(redactionEmailPattern, redactedEmail, &report.Emails)
+	}
+	if rules.numbers {
+		replace(redactionNumberPattern, redactedNumber, &report.Numbers)
+	}
+	for _, pattern := range rules.patterns {
+		replace(pattern, redactedPattern, &report.CustomMatches)
+	}
+	if namePattern != nil {
+		line = namePattern.ReplaceAllStringFunc(line, func(match string) string {
+			report.Names++
+			return nameLabels[strings.ToLower(match)]
+		})
+	}
+	return line
+}
+
+// clerkUserOrganizationIDs lists the organizations the user is a member of. Single-user servers have
+// none.
+func clerkUserOrganizationIDs(ctx context.Context, clerkUserID string) ([]string, error) {
+	if accessLookups.SingleUser() {
+		return nil, nil
+	}
+	params := &organizationmembership.ListParams{}
+	params.Limit = clerk.Int64(100)
+	params.UserIDs = []string{clerkUserID}
+	memberships, err := organizationmembership.List(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(memberships.OrganizationMemberships))
+	for _, membership := range memberships.OrganizationMemberships {
+		if membership.Organization != nil {
+			ids = append(ids, membership.Organization.ID)
+		}
+	}
+	return ids, nil
+}
+
+func toRedactionPolicySettings(policy *models.OrganizationRedactionPolicy) *RedactionPolicySettings {
+	patterns := []string{}
+	if policy.CustomPatterns != "" {
+		patterns = strings.Split(policy.CustomPatterns, "\n")
+	}
+	return &RedactionPolicySettings{
+		Enabled:        policy.Enabled,
+		RedactNames:    policy.RedactNames,
+		RedactEmails:   policy.RedactEmails,
+		RedactNumbers:  policy.RedactNumbers,
+		CustomPatterns: patterns,
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/pkg/recallai"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func transcriptLine(id int, name string, text string) recallai.TranscriptEntry {
+	entry := recallai.TranscriptEntry{Participant: recallai.ParticipantInfo{ID: id, Name: name}}
+	for _, word := range strings.Fields(text) {
+		entry.Words = append(entry.Words, recallai.WordInfo{Text: word})
+	}
+	return entry
+}
+
+var testRedactionTranscript = []recallai.TranscriptEntry{
+	transcriptLine(1, "Priya Sharma", "Thanks everyone. Alex, can you send the invoice to billing@acme.com?"),
+	transcriptLine(2, "Alex Chen", "Sure Priya. Call me on +1 415-555-0134 if it bounces, it's PRJ-4521."),
+	transcriptLine(1, "Priya Sharma", "We ship 3 features in 2025."),
+}
+
+func setupTestTranscriptRedactor(t *testing.T) *transcriptRedactor {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	err = db.AutoMigrate(&models.MeetingRecording{}, &models.OrganizationRedactionPolicy{}, &models.TranscriptRedaction{})
+	require.NoError(t, err, "Failed to migrate test database")
+
+	require.NoError(t, db.Create(&models.MeetingRecording{ID: "rec_1", ClerkUserID: "user_1", BotID: "bot_1", MeetingURL: "https://zoom.us/j/1"}).Error)
+
+	return &transcriptRedactor{
+		db: db,
+		organizationIDs: func(ctx context.Context, clerkUserID string) ([]string, error) {
+			return []string{"org_1", "org_2"}, nil
+		},
+	}
+}
+
+func TestRedactTranscript(t *testing.T) {
+	text, report := redactTranscript(testRedactionTranscript, transcriptRedactionRules{names: true, emails: true, numbers: true})
+
+	assert.NotContains(t, text, "Priya")
+	assert.NotContains(t, text, "Alex")
+	assert.NotContains(t, text, "acme.com")
+	assert.NotContains(t, text, "555")
+	assert.Contains(t, text, "Speaker 1: Thanks everyone. Speaker 2, can you send the invoice to [EMAIL]?")
+	assert.Contains(t, text, "Speaker 2: Sure Speaker 1. Call me on [NUMBER] if it bounces, it's PRJ-4521.")
+	assert.Contains(t, text, "We ship 3 features in 2025.", "Short numbers and years aren't redacted")
+
+	assert.Equal(t, 4, report.Names, "Two participants and two mentions")
+	assert.Equal(t, 1, report.Emails)
+	assert.Equal(t, 1, report.Numbers)
+
+	plain, report := redactTranscript(testRedactionTranscript, transcriptRedactionRules{})
+	assert.Contains(t, plain, "Priya Sharma: Thanks everyone. Alex,")
+	assert.Zero(t, report.Names+report.Emails+report.Numbers+report.CustomMatches)
+}
+
+func TestTranscriptForAI_CombinesOrganizationPolicies(t *testing.T) {
+	redactor := setupTestTranscriptRedactor(t)
+	ctx := context.Background()
+	service := &transcriptRedactionServiceImpl{db: redactor.db}
+
+	_, err := service.SavePolicy(ctx, "org_1", RedactionPolicySettings{Enabled: true, RedactEmails: true}, "admin_1")
+	require.NoError(t, err)
+	_, err = service.SavePolicy(ctx, "org_2", RedactionPolicySettings{Enabled: true, CustomPatterns: []string{`PRJ-\d+`, " "}}, "admin_2")
+	require.NoError(t, err)
+	_, err = service.SavePolicy(ctx, "org_3", RedactionPolicySettings{Enabled: true, RedactNames: true}, "admin_3")
+	require.NoError(t, err)
+
+	recording := &models.MeetingRecording{ID: "rec_1", ClerkUserID: "user_1"}
+	text, err := redactor.TranscriptForAI(ctx, recording, testRedactionTranscript)
+	require.NoError(t, err)
+	assert.Contains(t, text, "[EMAIL]")
+	assert.Contains(t, text, "it's [REDACTED].")
+	assert.Contains(t, text, "Priya Sharma:", "Only the policies of the user's organizations apply")
+
+	redaction, err := service.GetMeetingRedaction(ctx, "user_1", "rec_1")
+	require.NoError(t, err)
+	require.NotNil(t, redaction)
+	assert.Equal(t, "org_1,org_2", redaction.OrganizationIDs)
+	assert.Equal(t, 1, redaction.Emails)
+	assert.Equal(t, 1, redaction.CustomMatches)
+
+	_, err = service.GetMeetingRedaction(ctx, "user_2", "rec_1")
+	assert.ErrorIs(t, err, ErrMeetingNotFound)
+}
+
+func TestTranscriptForAI_DisabledPolicy(t *testing.T) {
+	redactor := setupTestTranscriptRedactor(t)
+	ctx := context.Background()
+	service := &transcriptRedactionServiceImpl{db: redactor.db}
+
+	_, err := service.SavePolicy(ctx, "org_1", RedactionPolicySettings{Enabled: false, RedactNames: true}, "admin_1")
+	require.NoError(t, err)
+
+	text, err := redactor.TranscriptForAI(ctx, &models.MeetingRecording{ID: "rec_1", ClerkUserID: "user_1"}, testRedactionTranscript)
+	require.NoError(t, err)
+	assert.Contains(t, text, "Priya Sharma:")
+
+	redaction, err := service.GetMeetingRedaction(ctx, "user_1", "rec_1")
+	require.NoError(t, err)
+	assert.Nil(t, redaction, "No report is stored when nothing was redacted")
+}
+
+func TestSaveRedactionPolicy_Validation(t *testing.T) {
+	service := &transcriptRedactionServiceImpl{db: setupTestTranscriptRedactor(t).db}
+	ctx := context.Background()
+
+	_, err := service.SavePolicy(ctx, "org_1", RedactionPolicySettings{CustomPatterns: []string{"("}}, "admin_1")
+	assert.ErrorIs(t, err, ErrInvalidRedactionPolicy)
+	_, err = service.SavePolicy(ctx, "org_1", RedactionPolicySettings{CustomPatterns: []string{"a*"}}, "admin_1")
+	assert.ErrorIs(t, err, ErrInvalidRedactionPolicy, "Patterns matching empty text are refused")
+
+	policy, err := service.GetPolicy(ctx, "org_1")
+	require.NoError(t, err)
+	assert.False(t, policy.Enabled)
+	assert.Empty(t, policy.CustomPatterns)
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/pkg/recallai"
+	"backend/pkg/whisper"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidTranscriptionProvider is returned when saving an unknown transcription provider
+	ErrInvalidTranscriptionProvider = errors.New("invalid transcription provider")
+	// ErrWhisperNotConfigured is returned when Whisper transcription is chosen but the server has no
+	// Whisper endpoint
+	ErrWhisperNotConfigured = errors.New("whisper transcription is not configured on this server")
+)
+
+// TranscriptionSettings is the API representation of an organization's transcription settings
+type TranscriptionSettings struct {
+	Provider         string `json:"provider"`
+	WhisperAvailable bool   `json:"whisperAvailable"`
+}
+
+// TranscriptionSettingsService interface defines methods for choosing how organization members' meetings
+// are transcribed
+type TranscriptionSettingsService interface {
+	GetSettings(ctx context.Context, organizationID string) (*TranscriptionSettings, error)
+	SaveSettings(ctx context.Context, organizationID string, provider string, updatedBy string) (*TranscriptionSettings, error)
+	ProviderForUser(ctx context.Context, clerkUserID string) (string, error)
+}
+
+// transcriptionSettingsServiceImpl implements the TranscriptionSettingsService interface
+type transcriptionSettingsServiceImpl struct {
+	db               *gorm.DB
+	organizationIDs  func(ctx context.Context, clerkUserID string) ([]string, error)
+	whisperAvailable func() bool
+}
+
+// NewTranscriptionSettingsService creates a new TranscriptionSettingsService instance
+func NewTranscriptionSettingsService() TranscriptionSettingsService {
+	return newTranscriptionSettingsService(db.DB)
+}
+
+func newTranscriptionSettingsService(tx *gorm.DB) *transcriptionSettingsServiceImpl {
+	return &transcriptionSettingsServiceImpl{
+		db:              tx,
+		organizationIDs: clerkUserOrganizationIDs,
+		whisperAvailable: func() bool {
+			return config.LoadTranscriptionConfig().IsWhisperConfigured()
+		},
+	}
+}
+
+// GetSettings returns an organization's transcription settings, Recall.ai if none are saved
+func (s *transcriptionSettingsServiceImpl) GetSettings(ctx context.Context, organizationID string) (*TranscriptionSettings, error) {
+	var settings models.OrganizationTranscriptionSettings
+	if err := s.db.WithContext(ctx).Where("organization_id = ?", organizationID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch transcription settings: %w", err)
+	}
+	return s.toTranscriptionSettings(settings.Provider), nil
+}
+
+// SaveSettings sets how an organization's meetings are transcribed. It applies to meetings recorded from
+// then on.
+func (s *transcriptionSettingsServiceImpl) SaveSettings(ctx context.Context, organizationID string, provider string, updatedBy string) (*TranscriptionSettings, error) {
+	switch provider {
+	case models.TranscriptionProviderRecall:
+	case models.TranscriptionProviderWhisper:
+		if !s.whisperAvailable() {
+			return nil, ErrWhisperNotConfigured
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTranscriptionProvider, provider)
+	}
+
+	settings := models.OrganizationTranscriptionSettings{OrganizationID: organizationID}
+	if err := s.db.WithContext(ctx).Where(models.OrganizationTranscriptionSettings{OrganizationID: organizationID}).
+		Assign(map[string]interface{}{
+			"provider":   provider,
+			"updated_by": updatedBy,
+		}).
+		FirstOrCreate(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to save transcription settings: %w", err)
+	}
+	return s.toTranscriptionSettings(settings.Provider), nil
+}
+
+// ProviderForUser returns the transcription provider for a new recording of the user's. Whisper is used
+// when any of their organizations opted out of Recall.ai transcription, and an error is returned rather
+// than falling back to Recall.ai when that can't be honoured.
+func (s *transcriptionSettingsServiceImpl) ProviderForUser(ctx context.Context, clerkUserID string) (string, error) {
+	organizationIDs, err := s.organizationIDs(ctx, clerkUserID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch organizations: %w", err)
+	}
+	if len(organizationIDs) == 0 {
+		return models.TranscriptionProviderRecall, nil
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.OrganizationTranscriptionSettings{}).
+		Where("organization_id IN ? AND provider = ?", organizationIDs, models.TranscriptionProviderWhisper).
+		Count(&count).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch transcription settings: %w", err)
+	}
+	if count == 0 {
+		return models.TranscriptionProviderRecall, nil
+	}
+	if !s.whisperAvailable() {
+		return "", ErrWhisperNotConfigured
+	}
+	return models.TranscriptionProviderWhisper, nil
+}
+
+func (s *transcriptionSettingsServiceImpl) toTranscriptionSettings(provider string) *TranscriptionSettings {
+	if provider == "" {
+		provider = models.TranscriptionProviderRecall
+	}
+	return &TranscriptionSettings{Provider: provider, WhisperAvailable: s.whisperAvailable()}
+}
+
+// TranscriptionProvider produces the transcript of a finished meeting recording
+type TranscriptionProvider interface {
+	Name() string
+	Transcribe(ctx context.Context, recording *models.MeetingRecording) ([]recallai.TranscriptEntry, error)
+}
+
+// newTranscriptionProviders returns the available transcription providers by name. Whisper is only
+// available when the server has an endpoint for it.
+func newTranscriptionProviders(recallClient *recallai.Client) map[string]TranscriptionProvider {
+	providers := map[string]TranscriptionProvider{
+		models.TranscriptionProviderRecall: &recallTranscriptionProvider{client: recallClient},
+	}
+	if cfg := config.LoadTranscriptionConfig(); cfg.IsWhisperConfigured() {
+		providers[models.TranscriptionProviderWhisper] = &whisperTranscriptionProvider{
+			recallClient:  recallClient,
+			whisperClient: whisper.NewClient(cfg.WhisperURL, cfg.WhisperAPIKey, cfg.WhisperModel, cfg.WhisperTimeout),
+		}
+	}
+	return providers
+}
+
+// transcribeRecording transcribes a recording with the provider it was recorded for
+func transcribeRecording(ctx context.Context, providers map[string]TranscriptionProvider, recording *models.MeetingRecording) ([]recallai.TranscriptEntry, error) {
+	name := recording.TranscriptionProvider
+	if name == "" {
+		name = models.TranscriptionProviderRecall
+	}
+	provider, ok := providers[name]
+	if !ok {
+		if name == models.TranscriptionProviderWhisper {
+			return nil, ErrWhisperNotConfigured
+		}
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTranscriptionProvider, name)
+	}
+
+	log.Info().
+		Str("meeting_id", recording.ID).
+		Str("provider", provider.Name()).
+		Msg("Transcribing meeting recording")
+	return provider.Transcribe(ctx, recording)
+}
+
+// recallTranscriptionProvider downloads the transcript Recall.ai made during the meeting
+type recallTranscriptionProvider struct {
+	client *recallai.Client
+}
+
+func (p *recallTranscriptionProvider) Name() string {
+	return models.TranscriptionProviderRecall
+}
+
+// Transcribe downloads the transcript, fetching a fresh download URL when the recording has none
+func (p *recallTranscriptionProvider) Transcribe(ctx context.Context, recording *models.MeetingRecording) ([]recallai.TranscriptEntry, error) {
+	downloadURL := recording.TranscriptDownloadURL
+	if downloadURL == "" {
+		botDetails, err := p.client.GetBot(recording.BotID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bot details: %w", err)
+		}
+		if len(botDetails.Recordings) > 0 {
+			downloadURL = botDetails.Recordings[0].MediaShortcuts.Transcript.Data.DownloadURL
+		}
+		if downloadURL == "" {
+			return nil, fmt.Errorf("transcript download URL not available")
+		}
+	}
+
+	transcript, err := p.client.DownloadTranscript(downloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download transcript: %w", err)
+	}
+	return transcript, nil
+}
+
+// whisperSpeaker is the participant name of Whisper transcripts, which don't tell speakers apart
+const whisperSpeaker = "Speaker"
+
+// whisperTranscriptionProvider transcribes the recorded meeting audio with the self-hosted Whisper server
+type whisperTranscriptionProvider struct {
+	recallClient  *recallai.Client
+	whisperClient *whisper.Client
+}
+
+func (p *whisperTranscriptionProvider) Name() string {
+	return models.TranscriptionProviderWhisper
+}
+
+// Transcribe streams the recording's mixed audio, or its video for bots recorded without audio, to Whisper
+func (p *whisperTranscriptionProvider) Transcribe(ctx context.Context, recording *models.MeetingRecording) ([]recallai.TranscriptEntry, error) {
+	botDetails, err := p.recallClient.GetBot(recording.BotID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot details: %w", err)
+	}
+	if len(botDetails.Recordings) == 0 {
+		return nil, fmt.Errorf("no recordings found for bot")
+	}
+
+	media := botDetails.Recordings[0].MediaShortcuts
+	downloadURL, filename := media.AudioMixed.Data.DownloadURL, "meeting.mp3"
+	if downloadURL == "" {
+		downloadURL, filename = media.VideoMixed.Data.DownloadURL, "meeting.mp4"
+	}
+	if downloadURL == "" {
+		return nil, fmt.Errorf("meeting audio not available")
+	}
+
+	audio, err := p.recallClient.DownloadMedia(ctx, downloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer audio.Close()
+
+	transcription, err := p.whisperClient.Transcribe(ctx, audio, filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe meeting audio: %w", err)
+	}
+	return whisperTranscriptEntries(transcription), nil
+}
+
+// whisperTranscriptEntries converts Whisper segments to transcript entries, timing each word with its
+// segment
+func whisperTranscriptEntries(transcription *whisper.Transcription) []recallai.TranscriptEntry {
+	segments := transcription.Segments
+	if len(segments) == 0 && strings.TrimSpace(transcription.Text) != "" {
+		segments = []whisper.Segment{{End: transcription.Duration, Text: transcription.Text}}
+	}
+
+	entries := make([]recallai.TranscriptEntry, 0, len(segments))
+	for _, segment := range segments {
+		words := strings.Fields(segment.Text)
+		if len(words) == 0 {
+			continue
+		}
+		entry := recallai.TranscriptEntry{
+			Participant: recallai.ParticipantInfo{ID: 1, Name: whisperSpeaker},
+			Words:       make([]recallai.WordInfo, len(words)),
+		}
+		for i, word := range words {
+			entry.Words[i] = recallai.WordInfo{
+				Text:           word,
+				StartTimestamp: recallai.Timestamp{Relative: segment.Start},
+				EndTimestamp:   recallai.Timestamp{Relative: segment.End},
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/pkg/recallai"
+	"backend/pkg/whisper"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestTranscriptionSettingsService(t *testing.T) *transcriptionSettingsServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.OrganizationTranscriptionSettings{}), "Failed to migrate test database")
+
+	return &transcriptionSettingsServiceImpl{
+		db: db,
+		organizationIDs: func(ctx context.Context, clerkUserID string) ([]string, error) {
+			switch clerkUserID {
+			case "user_1":
+				return []string{"org_1", "org_2"}, nil
+			case "user_broken":
+				return nil, errors.New("clerk unavailable")
+			}
+			return nil, nil
+		},
+		whisperAvailable: func() bool { return true },
+	}
+}
+
+func TestSaveTranscriptionSettings(t *testing.T) {
+	service := setupTestTranscriptionSettingsService(t)
+	ctx := context.Background()
+
+	settings, err := service.GetSettings(ctx, "org_1")
+	require.NoError(t, err)
+	assert.Equal(t, models.TranscriptionProviderRecall, settings.Provider, "Organizations use Recall.ai by default")
+
+	_, err = service.SaveSettings(ctx, "org_1", "deepgram", "admin_1")
+	assert.ErrorIs(t, err, ErrInvalidTranscriptionProvider)
+
+	settings, err = service.SaveSettings(ctx, "org_1", models.TranscriptionProviderWhisper, "admin_1")
+	require.NoError(t, err)
+	assert.Equal(t, models.TranscriptionProviderWhisper, settings.Provider)
+
+	service.whisperAvailable = func() bool { return false }
+	_, err = service.SaveSettings(ctx, "org_2", models.TranscriptionProviderWhisper, "admin_2")
+	assert.ErrorIs(t, err, ErrWhisperNotConfigured)
+}
+
+func TestProviderForUser(t *testing.T) {
+	service := setupTestTranscriptionSettingsService(t)
+	ctx := context.Background()
+
+	provider, err := service.ProviderForUser(ctx, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, models.TranscriptionProviderRecall, provider)
+
+	_, err = service.SaveSettings(ctx, "org_2", models.TranscriptionProviderWhisper, "admin_2")
+	require.NoError(t, err)
+
+	provider, err = service.ProviderForUser(ctx, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, models.TranscriptionProviderWhisper, provider, "Any organization opting out of Recall.ai wins")
+
+	provider, err = service.ProviderForUser(ctx, "user_2")
+	require.NoError(t, err)
+	assert.Equal(t, models.TranscriptionProviderRecall, provider)
+
+	_, err = service.ProviderForUser(ctx, "user_broken")
+	assert.Error(t, err, "Recall.ai isn't used when the organizations can't be checked")
+
+	service.whisperAvailable = func() bool { return false }
+	_, err = service.ProviderForUser(ctx, "user_1")
+	assert.ErrorIs(t, err, ErrWhisperNotConfigured)
+}
+
+// rewriteTransport sends every request to a test server, keeping the path
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWhisperTranscriptionProvider(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/bot/bot_1/":
+			bot := recallai.BotDetails{ID: "bot_1", Recordings: []recallai.Recording{{ID: "rec"}}}
+			bot.Recordings[0].MediaShortcuts.AudioMixed.Data.DownloadURL = server.URL + "/media/audio.mp3"
+			json.NewEncoder(w).Encode(bot)
+		case "/media/audio.mp3":
+			w.Write([]byte("mp3 audio"))
+		case "/v1/audio/transcriptions":
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			assert.Equal(t, "verbose_json", r.FormValue("response_format"))
+			file, header, err := r.FormFile("file")
+			require.NoError(t, err)
+			audio, _ := io.ReadAll(file)
+			assert.Equal(t, "mp3 audio", string(audio))
+			assert.Equal(t, "meeting.mp3", header.Filename)
+			json.NewEncoder(w).Encode(whisper.Transcription{
+				Text: "Hello everyone. Let's start.",
+				Segments: []whisper.Segment{
+					{Start: 0, End: 1.5, Text: " Hello everyone."},
+					{Start: 1.5, End: 1.6, Text: " "},
+					{Start: 1.6, End: 3, Text: " Let's start."},
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	recallClient := &recallai.Client{APIKey: "key", Region: "us-east-1", HTTPClient: &http.Client{Transport: rewriteTransport{target: target}}}
+	providers := map[string]TranscriptionProvider{
+		models.TranscriptionProviderWhisper: &whisperTranscriptionProvider{
+			recallClient:  recallClient,
+			whisperClient: whisper.NewClient(server.URL+"/v1/audio/transcriptions", "secret", "whisper-1", time.Minute),
+		},
+	}
+
+	recording := &models.MeetingRecording{ID: "rec_1", BotID: "bot_1", TranscriptionProvider: models.TranscriptionProviderWhisper}
+	transcript, err := transcribeRecording(context.Background(), providers, recording)
+	require.NoError(t, err)
+	require.Len(t, transcript, 2, "Blank segments are dropped")
+	assert.Equal(t, whisperSpeaker, transcript[0].Participant.Name)
+	assert.Equal(t, "everyone.", transcript[0].Words[1].Text)
+	assert.Equal(t, 1.6, transcript[1].Words[0].StartTimestamp.Relative)
+
+	_, err = transcribeRecording(context.Background(), map[string]TranscriptionProvider{}, recording)
+	assert.ErrorIs(t, err, ErrWhisperNotConfigured, "Whisper recordings aren't sent to Recall.ai when Whisper is gone")
+}
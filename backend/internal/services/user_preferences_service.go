@@ -0,0 +1,204 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidTimezone is returned for a timezone that isn't an IANA name like Europe/Berlin
+	ErrInvalidTimezone = errors.New("invalid timezone")
+	// ErrInvalidMeetingFiling is returned for a meeting filing mode other than suggest, auto or off
+	ErrInvalidMeetingFiling = errors.New("invalid meeting filing mode")
+)
+
+// TimezoneSettings is the user's timezone setting. Timezone is empty when none is set, and Effective is
+// the timezone dates and times are shown in either way.
+type TimezoneSettings struct {
+	Timezone  string `json:"timezone"`
+	Source    string `json:"source,omitempty"`
+	Effective string `json:"effectiveTimezone"`
+}
+
+// UserPreferencesService interface defines methods for per-user preferences
+type UserPreferencesService interface {
+	GetTimezone(ctx context.Context, clerkUserID string) (*TimezoneSettings, error)
+	SetTimezone(ctx context.Context, clerkUserID, timezone string) (*TimezoneSettings, error)
+	SyncClerkTimezone(ctx context.Context, clerkUserID, timezone string) error
+	GetMeetingFiling(ctx context.Context, clerkUserID string) (string, error)
+	SetMeetingFiling(ctx context.Context, clerkUserID, mode string) (string, error)
+}
+
+// userPreferencesServiceImpl implements the UserPreferencesService interface
+type userPreferencesServiceImpl struct {
+	db *gorm.DB
+}
+
+// NewUserPreferencesService creates a new UserPreferencesService instance
+func NewUserPreferencesService() UserPreferencesService {
+	return &userPreferencesServiceImpl{db: db.DB}
+}
+
+// GetTimezone returns the user's timezone setting
+func (s *userPreferencesServiceImpl) GetTimezone(ctx context.Context, clerkUserID string) (*TimezoneSettings, error) {
+	preferences, err := findUserPreferences(ctx, s.db, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	return toTimezoneSettings(preferences), nil
+}
+
+// SetTimezone saves the timezone the user chose. An empty timezone clears it, so the server's timezone
+// is used again until Clerk syncs one.
+func (s *userPreferencesServiceImpl) SetTimezone(ctx context.Context, clerkUserID, timezone string) (*TimezoneSettings, error) {
+	timezone = strings.TrimSpace(timezone)
+	source := models.TimezoneSourceSettings
+	if timezone == "" {
+		source = ""
+	} else if _, err := loadTimezone(timezone); err != nil {
+		return nil, err
+	}
+
+	preferences := models.UserPreferences{ClerkUserID: clerkUserID}
+	if err := s.db.WithContext(ctx).Where(models.UserPreferences{ClerkUserID: clerkUserID}).
+		Assign(map[string]interface{}{"timezone": timezone, "timezone_source": source}).
+		FirstOrCreate(&preferences).Error; err != nil {
+		return nil, fmt.Errorf("failed to save timezone: %w", err)
+	}
+	return toTimezoneSettings(&preferences), nil
+}
+
+// SyncClerkTimezone saves the timezone from the user's Clerk metadata, unless the user chose one in
+// settings. Invalid timezones are ignored.
+func (s *userPreferencesServiceImpl) SyncClerkTimezone(ctx context.Context, clerkUserID, timezone string) error {
+	timezone = strings.TrimSpace(timezone)
+	if timezone == "" {
+		return nil
+	}
+	if _, err := loadTimezone(timezone); err != nil {
+		log.Warn().Str("user_id", clerkUserID).Str("timezone", timezone).Msg("Ignoring invalid timezone from Clerk")
+		return nil
+	}
+
+	preferences, err := findUserPreferences(ctx, s.db, clerkUserID)
+	if err != nil {
+		return err
+	}
+	if preferences.TimezoneSource == models.TimezoneSourceSettings || preferences.Timezone == timezone {
+		return nil
+	}
+
+	if err := s.db.WithContext(ctx).Where(models.UserPreferences{ClerkUserID: clerkUserID}).
+		Assign(map[string]interface{}{"timezone": timezone, "timezone_source": models.TimezoneSourceClerk}).
+		FirstOrCreate(&models.UserPreferences{ClerkUserID: clerkUserID}).Error; err != nil {
+		return fmt.Errorf("failed to save timezone: %w", err)
+	}
+	return nil
+}
+
+// GetMeetingFiling returns how the user's generated meeting notes are filed into project chapters
+func (s *userPreferencesServiceImpl) GetMeetingFiling(ctx context.Context, clerkUserID string) (string, error) {
+	preferences, err := findUserPreferences(ctx, s.db, clerkUserID)
+	if err != nil {
+		return "", err
+	}
+	return meetingFilingMode(preferences), nil
+}
+
+// SetMeetingFiling sets whether the user's generated meeting notes are filed automatically, suggested
+// for review or left where they're generated
+func (s *userPreferencesServiceImpl) SetMeetingFiling(ctx context.Context, clerkUserID, mode string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode != models.MeetingFilingSuggest && mode != models.MeetingFilingAuto && mode != models.MeetingFilingOff {
+		return "", fmt.Errorf("%w: %q", ErrInvalidMeetingFiling, mode)
+	}
+
+	if err := s.db.WithContext(ctx).Where(models.UserPreferences{ClerkUserID: clerkUserID}).
+		Assign(map[string]interface{}{"meeting_filing": mode}).
+		FirstOrCreate(&models.UserPreferences{ClerkUserID: clerkUserID}).Error; err != nil {
+		return "", fmt.Errorf("failed to save meeting filing: %w", err)
+	}
+	return mode, nil
+}
+
+// UserLocation returns the timezone to show the user's dates and times in, the server's when they
+// haven't set one
+func UserLocation(ctx context.Context, tx *gorm.DB, clerkUserID string) *time.Location {
+	return userLocationOr(ctx, tx, clerkUserID, time.Local)
+}
+
+// userLocationOr returns the user's timezone, the fallback when they haven't set one
+func userLocationOr(ctx context.Context, tx *gorm.DB, clerkUserID string, fallback *time.Location) *time.Location {
+	preferences, err := findUserPreferences(ctx, tx, clerkUserID)
+	if err != nil {
+		log.Warn().Err(err).Str("user_id", clerkUserID).Str("fallback", fallback.String()).Msg("Failed to fetch user timezone")
+		return fallback
+	}
+	if preferences.Timezone == "" {
+		return fallback
+	}
+	location, err := loadTimezone(preferences.Timezone)
+	if err != nil {
+		return fallback
+	}
+	return location
+}
+
+// TimezoneName names a location for API responses. The server's timezone is named by its abbreviation,
+// since Go only knows it as "Local".
+func TimezoneName(location *time.Location) string {
+	if location == time.Local {
+		name, _ := time.Now().In(location).Zone()
+		return name
+	}
+	return location.String()
+}
+
+// findUserPreferences returns the user's preferences, empty ones if none are saved
+func findUserPreferences(ctx context.Context, tx *gorm.DB, clerkUserID string) (*models.UserPreferences, error) {
+	preferences := models.UserPreferences{ClerkUserID: clerkUserID}
+	if err := tx.WithContext(ctx).Where("clerk_user_id = ?", clerkUserID).Limit(1).Find(&preferences).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch user preferences: %w", err)
+	}
+	return &preferences, nil
+}
+
+// loadTimezone loads an IANA timezone. "Local" is refused, it's the server's timezone and not a real one.
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "Local" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	return location, nil
+}
+
+// meetingFilingMode returns how the user's meeting notes are filed, suggesting when they haven't chosen
+func meetingFilingMode(preferences *models.UserPreferences) string {
+	if preferences.MeetingFiling == "" {
+		return models.MeetingFilingSuggest
+	}
+	return preferences.MeetingFiling
+}
+
+func toTimezoneSettings(preferences *models.UserPreferences) *TimezoneSettings {
+	settings := &TimezoneSettings{
+		Timezone:  preferences.Timezone,
+		Source:    preferences.TimezoneSource,
+		Effective: preferences.Timezone,
+	}
+	if settings.Effective == "" {
+		settings.Effective = TimezoneName(time.Local)
+	}
+	return settings
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestUserPreferencesService(t *testing.T) *userPreferencesServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.UserPreferences{}), "Failed to migrate test database")
+	return &userPreferencesServiceImpl{db: db}
+}
+
+func TestSetTimezone(t *testing.T) {
+	service := setupTestUserPreferencesService(t)
+	ctx := context.Background()
+
+	assert.Equal(t, time.Local, UserLocation(ctx, service.db, "user_1"), "Users without a timezone get the server's")
+	assert.Equal(t, time.UTC, userLocationOr(ctx, service.db, "user_1", time.UTC), "Or the fallback, like UTC on WhatsApp")
+
+	settings, err := service.SetTimezone(ctx, "user_1", "Asia/Kolkata")
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Kolkata", settings.Effective)
+	assert.Equal(t, models.TimezoneSourceSettings, settings.Source)
+	assert.Equal(t, "Asia/Kolkata", UserLocation(ctx, service.db, "user_1").String())
+	assert.Equal(t, "Asia/Kolkata", userLocationOr(ctx, service.db, "user_1", time.UTC).String())
+
+	_, err = service.SetTimezone(ctx, "user_1", "Mars/Olympus")
+	assert.ErrorIs(t, err, ErrInvalidTimezone)
+	_, err = service.SetTimezone(ctx, "user_1", "Local")
+	assert.ErrorIs(t, err, ErrInvalidTimezone)
+
+	settings, err = service.SetTimezone(ctx, "user_1", "")
+	require.NoError(t, err)
+	assert.Empty(t, settings.Timezone)
+	assert.Equal(t, time.Local, UserLocation(ctx, service.db, "user_1"))
+}
+
+func TestSyncClerkTimezone(t *testing.T) {
+	service := setupTestUserPreferencesService(t)
+	ctx := context.Background()
+
+	require.NoError(t, service.SyncClerkTimezone(ctx, "user_1", "Europe/Berlin"))
+	settings, err := service.GetTimezone(ctx, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", settings.Timezone)
+	assert.Equal(t, models.TimezoneSourceClerk, settings.Source)
+
+	require.NoError(t, service.SyncClerkTimezone(ctx, "user_1", "Not/AZone"), "Invalid timezones from Clerk are ignored")
+	_, err = service.SetTimezone(ctx, "user_1", "America/New_York")
+	require.NoError(t, err)
+	require.NoError(t, service.SyncClerkTimezone(ctx, "user_1", "Europe/Berlin"))
+
+	settings, err = service.GetTimezone(ctx, "user_1")
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", settings.Timezone, "Clerk sync shouldn't overwrite the user's choice")
+}
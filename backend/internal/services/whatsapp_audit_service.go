@@ -20,11 +20,12 @@ func NewWhatsAppAuditService(db *gorm.DB) *WhatsAppAuditService {
 	}
 }
 
-// LogInboundMessage logs an inbound message to the audit table
-func (s *WhatsAppAuditService) LogInboundMessage(messageID, phoneNumber, messageType, content string, timestamp time.Time) error {
+// LogInboundMessage logs an inbound message to the audit table. groupID is nil for direct messages.
+func (s *WhatsAppAuditService) LogInboundMessage(messageID, phoneNumber string, groupID *string, messageType, content string, timestamp time.Time) error {
 	auditMsg := models.WhatsAppMessage{
 		MessageID:   messageID,
 		PhoneNumber: phoneNumber,
+		GroupID:     groupID,
 		Direction:   "inbound",
 		MessageType: messageType,
 		Content:     content,
@@ -30,7 +30,7 @@ func TestLogInboundMessage(t *testing.T) {
 	content := "Hello, bot!"
 	timestamp := time.Now()
 
-	err := service.LogInboundMessage(messageID, phoneNumber, "text", content, timestamp)
+	err := service.LogInboundMessage(messageID, phoneNumber, nil, "text", content, timestamp)
 	assert.NoError(t, err)
 
 	// Verify the message was logged
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidWhatsAppGroup is returned, wrapped with the reason, for a group link that can't be saved
+	ErrInvalidWhatsAppGroup = errors.New("invalid WhatsApp group")
+	// ErrWhatsAppGroupNotFound is returned when a group isn't linked to the organization
+	ErrWhatsAppGroupNotFound = errors.New("WhatsApp group not found")
+	// ErrWhatsAppGroupLinked is returned when a group is already linked to another organization
+	ErrWhatsAppGroupLinked = errors.New("WhatsApp group is linked to another organization")
+)
+
+const (
+	maxWhatsAppGroupIDLength = 255
+	// DefaultWhatsAppGroupVolumeDays is how far back recent message volume is counted by default
+	DefaultWhatsAppGroupVolumeDays = 30
+	maxWhatsAppGroupVolumeDays     = 365
+)
+
+// WhatsAppGroupInput is the request body for linking a group or changing its capture notebook
+type WhatsAppGroupInput struct {
+	GroupID           string  `json:"groupId"`
+	DefaultNotebookID *string `json:"defaultNotebookId"` // nil or empty to let AI pick notebooks
+}
+
+// WhatsAppGroup is a group linked to an organization with how many messages its members send
+type WhatsAppGroup struct {
+	models.WhatsAppGroupLink
+	DefaultNotebookName string     `json:"defaultNotebookName,omitempty"`
+	Messages            int64      `json:"messages"`       // Messages received from the group still in the audit log
+	RecentMessages      int64      `json:"recentMessages"` // Messages received in the last days asked for
+	LastMessageAt       *time.Time `json:"lastMessageAt,omitempty"`
+}
+
+// WhatsAppGroupService interface defines methods for organization admins to manage the WhatsApp groups
+// linked to their organization
+type WhatsAppGroupService interface {
+	ListGroups(ctx context.Context, organizationID string, days int) ([]WhatsAppGroup, error)
+	LinkGroup(ctx context.Context, organizationID string, input WhatsAppGroupInput, clerkUserID string) (*WhatsAppGroup, error)
+	UpdateGroup(ctx context.Context, organizationID, groupID string, input WhatsAppGroupInput) (*WhatsAppGroup, error)
+	UnlinkGroup(ctx context.Context, organizationID, groupID string) error
+}
+
+// whatsAppGroupServiceImpl implements the WhatsAppGroupService interface
+type whatsAppGroupServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewWhatsAppGroupService creates a new WhatsAppGroupService instance
+func NewWhatsAppGroupService() WhatsAppGroupService {
+	return &whatsAppGroupServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// ListGroups returns the groups linked to an organization, most recently linked first, with their
+// message volume over all time and the last days
+func (s *whatsAppGroupServiceImpl) ListGroups(ctx context.Context, organizationID string, days int) ([]WhatsAppGroup, error) {
+	if days <= 0 || days > maxWhatsAppGroupVolumeDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidWhatsAppGroup, maxWhatsAppGroupVolumeDays)
+	}
+
+	var links []models.WhatsAppGroupLink
+	if err := s.db.WithContext(ctx).Where("organization_id = ? AND is_active = ?", organizationID, true).
+		Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch WhatsApp groups: %w", err)
+	}
+	return s.groups(ctx, links, days)
+}
+
+// LinkGroup links a group to an organization, so commands sent in it work on the organization's
+// notebooks. A group unlinked earlier is linked again.
+func (s *whatsAppGroupServiceImpl) LinkGroup(ctx context.Context, organizationID string, input WhatsAppGroupInput, clerkUserID string) (*WhatsAppGroup, error) {
+	groupID := strings.TrimSpace(input.GroupID)
+	if groupID == "" || len(groupID) > maxWhatsAppGroupIDLength || strings.ContainsAny(groupID, " /\t\n") {
+		return nil, fmt.Errorf("%w: groupId must be a WhatsApp group ID", ErrInvalidWhatsAppGroup)
+	}
+	defaultNotebookID, err := s.defaultNotebook(ctx, organizationID, input.DefaultNotebookID)
+	if err != nil {
+		return nil, err
+	}
+
+	var link models.WhatsAppGroupLink
+	err = s.db.WithContext(ctx).Where("group_id = ?", groupID).First(&link).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		link = models.WhatsAppGroupLink{GroupID: groupID}
+	case err != nil:
+		return nil, fmt.Errorf("failed to fetch WhatsApp group: %w", err)
+	case link.IsActive && link.OrganizationID != organizationID:
+		return nil, ErrWhatsAppGroupLinked
+	}
+
+	// Group IDs are unique, so relinking reuses the row the group had
+	link.OrganizationID = organizationID
+	link.LinkedBy = clerkUserID
+	link.IsActive = true
+	link.DefaultNotebookID = defaultNotebookID
+	if err := s.db.WithContext(ctx).Save(&link).Error; err != nil {
+		return nil, fmt.Errorf("failed to link WhatsApp group: %w", err)
+	}
+
+	log.Info().Str("org_id", organizationID).Str("group_id", groupID).Str("user_id", clerkUserID).Msg("WhatsApp group linked")
+	return s.group(ctx, link)
+}
+
+// UpdateGroup sets the notebook notes captured in a group are saved to
+func (s *whatsAppGroupServiceImpl) UpdateGroup(ctx context.Context, organizationID, groupID string, input WhatsAppGroupInput) (*WhatsAppGroup, error) {
+	link, err := s.link(ctx, organizationID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defaultNotebookID, err := s.defaultNotebook(ctx, organizationID, input.DefaultNotebookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Model(link).Update("default_notebook_id", defaultNotebookID).Error; err != nil {
+		return nil, fmt.Errorf("failed to update WhatsApp group: %w", err)
+	}
+	link.DefaultNotebookID = defaultNotebookID
+	return s.group(ctx, *link)
+}
+
+// UnlinkGroup stops a group's commands working on the organization's notebooks. Its messages are kept.
+func (s *whatsAppGroupServiceImpl) UnlinkGroup(ctx context.Context, organizationID, groupID string) error {
+	link, err := s.link(ctx, organizationID, groupID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.WithContext(ctx).Model(link).Update("is_active", false).Error; err != nil {
+		return fmt.Errorf("failed to unlink WhatsApp group: %w", err)
+	}
+
+	log.Info().Str("org_id", organizationID).Str("group_id", groupID).Msg("WhatsApp group unlinked")
+	return nil
+}
+
+// link returns a group's active link to an organization
+func (s *whatsAppGroupServiceImpl) link(ctx context.Context, organizationID, groupID string) (*models.WhatsAppGroupLink, error) {
+	var link models.WhatsAppGroupLink
+	if err := s.db.WithContext(ctx).Where("group_id = ? AND organization_id = ? AND is_active = ?", groupID, organizationID, true).
+		First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWhatsAppGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch WhatsApp group: %w", err)
+	}
+	return &link, nil
+}
+
+// defaultNotebook checks a capture notebook belongs to the organization, returning nil when none is set
+func (s *whatsAppGroupServiceImpl) defaultNotebook(ctx context.Context, organizationID string, notebookID *string) (*string, error) {
+	if notebookID == nil || strings.TrimSpace(*notebookID) == "" {
+		return nil, nil
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Notebook{}).
+		Where("id = ? AND organization_id = ?", *notebookID, organizationID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notebook: %w", err)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("%w: defaultNotebookId must be one of the organization's notebooks", ErrInvalidWhatsAppGroup)
+	}
+	return notebookID, nil
+}
+
+// group returns a single group with its message volume over the default number of days
+func (s *whatsAppGroupServiceImpl) group(ctx context.Context, link models.WhatsAppGroupLink) (*WhatsAppGroup, error) {
+	groups, err := s.groups(ctx, []models.WhatsAppGroupLink{link}, DefaultWhatsAppGroupVolumeDays)
+	if err != nil {
+		return nil, err
+	}
+	return &groups[0], nil
+}
+
+// groups adds capture notebook names and inbound message counts to group links
+func (s *whatsAppGroupServiceImpl) groups(ctx context.Context, links []models.WhatsAppGroupLink, days int) ([]WhatsAppGroup, error) {
+	groups := make([]WhatsAppGroup, len(links))
+	if len(links) == 0 {
+		return groups, nil
+	}
+
+	groupIDs := make([]string, len(links))
+	var notebookIDs []string
+	for i, link := range links {
+		groupIDs[i] = link.GroupID
+		if link.DefaultNotebookID != nil {
+			notebookIDs = append(notebookIDs, *link.DefaultNotebookID)
+		}
+	}
+
+	notebookNames := make(map[string]string)
+	if len(notebookIDs) > 0 {
+		var notebooks []models.Notebook
+		if err := s.db.WithContext(ctx).Select("id, name").Where("id IN ?", notebookIDs).Find(&notebooks).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch notebooks: %w", err)
+		}
+		for _, notebook := range notebooks {
+			notebookNames[notebook.ID] = notebook.Name
+		}
+	}
+
+	var volumes []struct {
+		GroupID  string
+		Messages int64
+		Recent   int64
+	}
+	since := s.now().AddDate(0, 0, -days)
+	if err := s.db.WithContext(ctx).Model(&models.WhatsAppMessage{}).
+		Select("group_id, COUNT(*) AS messages, SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END) AS recent", since).
+		Where("group_id IN ? AND direction = ?", groupIDs, "inbound").
+		Group("group_id").Scan(&volumes).Error; err != nil {
+		return nil, fmt.Errorf("failed to count WhatsApp group messages: %w", err)
+	}
+
+	for i, link := range links {
+		groups[i] = WhatsAppGroup{WhatsAppGroupLink: link}
+		if link.DefaultNotebookID != nil {
+			groups[i].DefaultNotebookName = notebookNames[*link.DefaultNotebookID]
+		}
+		for _, volume := range volumes {
+			if volume.GroupID == link.GroupID {
+				groups[i].Messages, groups[i].RecentMessages = volume.Messages, volume.Recent
+			}
+		}
+		if groups[i].Messages == 0 {
+			continue
+		}
+
+		var last models.WhatsAppMessage
+		if err := s.db.WithContext(ctx).Select("created_at").Where("group_id = ? AND direction = ?", link.GroupID, "inbound").
+			Order("created_at DESC").First(&last).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch last WhatsApp group message: %w", err)
+		}
+		groups[i].LastMessageAt = &last.CreatedAt
+	}
+	return groups, nil
+}
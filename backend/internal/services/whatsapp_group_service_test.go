@@ -0,0 +1,135 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestWhatsAppGroupService(t *testing.T, now time.Time) *whatsAppGroupServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.WhatsAppGroupLink{}, &models.WhatsAppMessage{}),
+		"Failed to migrate test database")
+
+	return &whatsAppGroupServiceImpl{
+		db:  db,
+		now: func() time.Time { return now },
+	}
+}
+
+func TestWhatsAppGroupService_LinkGroup(t *testing.T) {
+	service := setupTestWhatsAppGroupService(t, time.Now())
+	ctx := context.Background()
+
+	orgID := "org_1"
+	notebook := models.Notebook{Name: "Team Inbox", ClerkUserID: "user_admin", OrganizationID: &orgID}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	otherOrg := "org_2"
+	otherNotebook := models.Notebook{Name: "Elsewhere", ClerkUserID: "user_admin", OrganizationID: &otherOrg}
+	require.NoError(t, service.db.Create(&otherNotebook).Error)
+
+	group, err := service.LinkGroup(ctx, "org_1", WhatsAppGroupInput{GroupID: " 120363@g.us ", DefaultNotebookID: &notebook.ID}, "user_admin")
+	require.NoError(t, err)
+	assert.Equal(t, "120363@g.us", group.GroupID)
+	assert.True(t, group.IsActive)
+	assert.Equal(t, "user_admin", group.LinkedBy)
+	assert.Equal(t, "Team Inbox", group.DefaultNotebookName)
+
+	_, err = service.LinkGroup(ctx, "org_2", WhatsAppGroupInput{GroupID: "120363@g.us"}, "user_other")
+	assert.ErrorIs(t, err, ErrWhatsAppGroupLinked)
+	_, err = service.LinkGroup(ctx, "org_1", WhatsAppGroupInput{GroupID: "777@g.us", DefaultNotebookID: &otherNotebook.ID}, "user_admin")
+	assert.ErrorIs(t, err, ErrInvalidWhatsAppGroup, "Capture notebooks must belong to the organization")
+	_, err = service.LinkGroup(ctx, "org_1", WhatsAppGroupInput{GroupID: "  "}, "user_admin")
+	assert.ErrorIs(t, err, ErrInvalidWhatsAppGroup)
+
+	// Unlinked groups can be linked to another organization, reusing their row
+	require.NoError(t, service.UnlinkGroup(ctx, "org_1", "120363@g.us"))
+	assert.ErrorIs(t, service.UnlinkGroup(ctx, "org_1", "120363@g.us"), ErrWhatsAppGroupNotFound)
+	relinked, err := service.LinkGroup(ctx, "org_2", WhatsAppGroupInput{GroupID: "120363@g.us"}, "user_other")
+	require.NoError(t, err)
+	assert.Equal(t, group.ID, relinked.ID)
+	assert.Equal(t, "org_2", relinked.OrganizationID)
+	assert.Nil(t, relinked.DefaultNotebookID)
+
+	groups, err := service.ListGroups(ctx, "org_1", DefaultWhatsAppGroupVolumeDays)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestWhatsAppGroupService_UpdateGroup(t *testing.T) {
+	service := setupTestWhatsAppGroupService(t, time.Now())
+	ctx := context.Background()
+
+	orgID := "org_1"
+	notebook := models.Notebook{Name: "Standups", ClerkUserID: "user_admin", OrganizationID: &orgID}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	_, err := service.LinkGroup(ctx, "org_1", WhatsAppGroupInput{GroupID: "120363@g.us"}, "user_admin")
+	require.NoError(t, err)
+
+	group, err := service.UpdateGroup(ctx, "org_1", "120363@g.us", WhatsAppGroupInput{DefaultNotebookID: &notebook.ID})
+	require.NoError(t, err)
+	require.NotNil(t, group.DefaultNotebookID)
+	assert.Equal(t, notebook.ID, *group.DefaultNotebookID)
+	assert.Equal(t, "Standups", group.DefaultNotebookName)
+
+	empty := ""
+	group, err = service.UpdateGroup(ctx, "org_1", "120363@g.us", WhatsAppGroupInput{DefaultNotebookID: &empty})
+	require.NoError(t, err)
+	assert.Nil(t, group.DefaultNotebookID, "An empty notebook lets AI pick notebooks again")
+
+	_, err = service.UpdateGroup(ctx, "org_2", "120363@g.us", WhatsAppGroupInput{})
+	assert.ErrorIs(t, err, ErrWhatsAppGroupNotFound)
+}
+
+func TestWhatsAppGroupService_ListGroups(t *testing.T) {
+	now := time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC)
+	service := setupTestWhatsAppGroupService(t, now)
+	ctx := context.Background()
+
+	_, err := service.LinkGroup(ctx, "org_1", WhatsAppGroupInput{GroupID: "busy@g.us"}, "user_admin")
+	require.NoError(t, err)
+	_, err = service.LinkGroup(ctx, "org_1", WhatsAppGroupInput{GroupID: "quiet@g.us"}, "user_admin")
+	require.NoError(t, err)
+
+	busy, other := "busy@g.us", "other@g.us"
+	require.NoError(t, service.db.Create(&[]models.WhatsAppMessage{
+		{MessageID: "m1", PhoneNumber: "+15550001", GroupID: &busy, Direction: "inbound", CreatedAt: now.AddDate(0, 0, -40)},
+		{MessageID: "m2", PhoneNumber: "+15550001", GroupID: &busy, Direction: "inbound", CreatedAt: now.AddDate(0, 0, -5)},
+		{MessageID: "m3", PhoneNumber: "+15550002", GroupID: &busy, Direction: "inbound", CreatedAt: now.Add(-time.Hour)},
+		{MessageID: "m4", PhoneNumber: "+15550002", Direction: "inbound", CreatedAt: now},
+		{MessageID: "m5", PhoneNumber: "+15550002", GroupID: &other, Direction: "inbound", CreatedAt: now},
+	}).Error)
+
+	groups, err := service.ListGroups(ctx, "org_1", 30)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	volumes := make(map[string]WhatsAppGroup)
+	for _, group := range groups {
+		volumes[group.GroupID] = group
+	}
+
+	assert.Equal(t, int64(3), volumes["busy@g.us"].Messages)
+	assert.Equal(t, int64(2), volumes["busy@g.us"].RecentMessages)
+	require.NotNil(t, volumes["busy@g.us"].LastMessageAt)
+	assert.True(t, now.Add(-time.Hour).Equal(*volumes["busy@g.us"].LastMessageAt))
+	assert.Equal(t, int64(0), volumes["quiet@g.us"].Messages)
+	assert.Nil(t, volumes["quiet@g.us"].LastMessageAt)
+
+	groups, err = service.ListGroups(ctx, "org_1", 1)
+	require.NoError(t, err)
+	for _, group := range groups {
+		if group.GroupID == "busy@g.us" {
+			assert.Equal(t, int64(1), group.RecentMessages)
+		}
+	}
+
+	_, err = service.ListGroups(ctx, "org_1", 0)
+	assert.ErrorIs(t, err, ErrInvalidWhatsAppGroup)
+}
@@ -15,6 +15,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// whatsAppTodoBoardName is the standalone board that receives to-dos captured from WhatsApp
+const whatsAppTodoBoardName = "WhatsApp To-Dos"
+
 // WhatsAppMessageProcessor handles incoming WhatsApp messages and routes them to appropriate handlers
 type WhatsAppMessageProcessor struct {
 	db             *gorm.DB
@@ -85,7 +88,7 @@ func (p *WhatsAppMessageProcessor) ProcessMessage(msg *IncomingMessage) error {
 	msg.Content = sanitizedContent
 
 	// Log the incoming message using audit service
-	if err := p.auditService.LogInboundMessage(msg.MessageID, msg.PhoneNumber, "text", msg.Content, msg.Timestamp); err != nil {
+	if err := p.auditService.LogInboundMessage(msg.MessageID, msg.PhoneNumber, msg.GroupID, "text", msg.Content, msg.Timestamp); err != nil {
 		log.Warn().Err(err).Msg("Failed to log incoming message")
 	}
 
@@ -112,13 +115,14 @@ func (p *WhatsAppMessageProcessor) ProcessMessage(msg *IncomingMessage) error {
 	}
 
 	// Check for organization context (group message)
-	var organizationID *string
+	var organizationID, defaultNotebookID *string
 	if msg.GroupID != nil {
-		orgID, err := p.getOrganizationForGroup(*msg.GroupID)
+		groupLink, err := p.getGroupLink(*msg.GroupID)
 		if err != nil {
 			log.Error().Err(err).Str("group_id", *msg.GroupID).Msg("Failed to get organization for group")
-		} else if orgID != nil {
-			organizationID = orgID
+		} else if groupLink != nil {
+			organizationID = &groupLink.OrganizationID
+			defaultNotebookID = groupLink.DefaultNotebookID
 		}
 	}
 
@@ -131,14 +135,15 @@ func (p *WhatsAppMessageProcessor) ProcessMessage(msg *IncomingMessage) error {
 
 	// Build command context
 	cmdCtx := &whatsapp.CommandContext{
-		PhoneNumber:     msg.PhoneNumber,
-		Message:         msg.Content,
-		User:            user,
-		ConversationCtx: conversationCtx,
-		Client:          p.client,
-		DB:              p.db,
-		GroupID:         msg.GroupID,
-		OrganizationID:  organizationID,
+		PhoneNumber:       msg.PhoneNumber,
+		Message:           msg.Content,
+		User:              user,
+		ConversationCtx:   conversationCtx,
+		Client:            p.client,
+		DB:                p.db,
+		GroupID:           msg.GroupID,
+		OrganizationID:    organizationID,
+		DefaultNotebookID: defaultNotebookID,
 	}
 
 	// If we have an active context, continue the flow
@@ -286,9 +291,8 @@ func (p *WhatsAppMessageProcessor) executeCommand(ctx *whatsapp.CommandContext)
 			return p.executeNaturalLanguageCommand(ctx, nlCmd)
 		}
 
-		// Regular message, not a command
-		return p.sendErrorMessage(ctx.PhoneNumber,
-			"Please use commands to interact with the bot. Type /help to see available commands.")
+		// Free-text message, let AI figure out what the user wants
+		return p.routeNaturalLanguageIntent(ctx)
 	}
 
 	// Check if command requires authentication
@@ -336,6 +340,250 @@ func (p *WhatsAppMessageProcessor) executeNaturalLanguageCommand(ctx *whatsapp.C
 	return p.createNoteWithAI(ctx, nlCmd.NoteTitle)
 }
 
+// routeNaturalLanguageIntent classifies a free-text message with AI and routes it to the matching handler
+func (p *WhatsAppMessageProcessor) routeNaturalLanguageIntent(ctx *whatsapp.CommandContext) error {
+	// Intent routing acts on the user's data, so it requires authentication
+	if ctx.User == nil || !ctx.User.IsAuthenticated {
+		return p.sendErrorMessage(ctx.PhoneNumber,
+			"Please use commands to interact with the bot. Type /help to see available commands.")
+	}
+
+	aiService := NewAIService()
+	intent, err := aiService.ClassifyMessageIntent(context.Background(), MessageIntentRequest{
+		Message: ctx.Message,
+		UserID:  ctx.User.ClerkUserID,
+		OrgID:   ctx.OrganizationID,
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("phone", ctx.PhoneNumber).Msg("Failed to classify message intent, falling back to help")
+		p.metricsService.RecordCommandExecution("ai_intent_routing", "failed")
+		return p.sendHelpFallback(ctx)
+	}
+
+	return p.routeIntent(ctx, intent)
+}
+
+// routeIntent runs the handler of a classified intent, or shows help when it isn't confident or complete
+// enough to act on
+func (p *WhatsAppMessageProcessor) routeIntent(ctx *whatsapp.CommandContext, intent *whatsapp.NaturalLanguageIntent) error {
+	if !intent.IsActionable() {
+		log.Info().
+			Str("intent", intent.Intent).
+			Float64("confidence", intent.Confidence).
+			Str("phone", ctx.PhoneNumber).
+			Msg("Low confidence intent, falling back to help")
+		p.metricsService.RecordCommandExecution("ai_intent_routing", "low_confidence")
+		return p.sendHelpFallback(ctx)
+	}
+
+	p.metricsService.RecordCommandExecution("ai_intent_routing", intent.Intent)
+
+	switch intent.Intent {
+	case whatsapp.IntentAdd:
+		title := intent.Slot("title")
+		if title == "" {
+			return p.sendHelpFallback(ctx)
+		}
+		return p.createNoteWithAI(ctx, title)
+
+	case whatsapp.IntentSearch:
+		query := intent.Slot("query")
+		if query == "" {
+			return p.sendHelpFallback(ctx)
+		}
+		return p.executeRoutedCommand(ctx, "retrieve", strings.Fields(query))
+
+	case whatsapp.IntentSummarize:
+		args := []string{}
+		if entityType := intent.Slot("type"); entityType != "" {
+			args = append(args, entityType)
+		}
+		args = append(args, strings.Fields(intent.Slot("name"))...)
+		return p.executeRoutedCommand(ctx, "summarize", args)
+
+	case whatsapp.IntentTodo:
+		task := intent.Slot("task")
+		if task == "" {
+			return p.sendHelpFallback(ctx)
+		}
+		return p.createTodoFromIntent(ctx, task, intent.Slot("priority"))
+
+	case whatsapp.IntentSchedule:
+		return p.showScheduleFromIntent(ctx, intent.Slot("date"))
+	}
+
+	return p.sendHelpFallback(ctx)
+}
+
+// executeRoutedCommand runs a registered command on behalf of an AI-routed intent
+func (p *WhatsAppMessageProcessor) executeRoutedCommand(ctx *whatsapp.CommandContext, commandName string, args []string) error {
+	cmd, exists := p.registry.Get(commandName)
+	if !exists {
+		log.Warn().Str("command", commandName).Msg("Routed intent has no registered command")
+		return p.sendHelpFallback(ctx)
+	}
+
+	ctx.Args = args
+
+	cmdTimer := p.metricsService.CommandTimer(cmd.Name())
+	err := cmd.Execute(ctx)
+	cmdTimer.ObserveDuration()
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("command", cmd.Name()).
+			Str("phone", ctx.PhoneNumber).
+			Msg("Routed command execution failed")
+
+		p.metricsService.RecordCommandExecution(cmd.Name(), "failed")
+		p.metricsService.RecordCommandError(cmd.Name(), "execution_error")
+		p.metricsService.RecordErrorByCategory("command_execution")
+
+		return p.sendErrorMessage(ctx.PhoneNumber,
+			"❌ Failed to process your request. Please try again or use /help for assistance.")
+	}
+
+	p.metricsService.RecordCommandExecution(cmd.Name(), "success")
+	return nil
+}
+
+// createTodoFromIntent adds a task to the user's WhatsApp to-do board, creating the board if needed
+func (p *WhatsAppMessageProcessor) createTodoFromIntent(ctx *whatsapp.CommandContext, title, priority string) error {
+	// Viewers and commenters can't add to-dos to the organization
+	if err := whatsapp.VerifyOrganizationEditAccess(ctx); err != nil {
+		return p.sendErrorMessage(ctx.PhoneNumber, fmt.Sprintf("❌ %s", err.Error()))
+	}
+
+	if priority != "low" && priority != "medium" && priority != "high" {
+		priority = "medium"
+	}
+
+	// Find or create the standalone to-do board for this workspace
+	var board models.TaskBoard
+	boardQuery := p.db.Where("name = ? AND clerk_user_id = ? AND is_standalone = ?",
+		whatsAppTodoBoardName, ctx.User.ClerkUserID, true)
+	if ctx.OrganizationID != nil {
+		boardQuery = boardQuery.Where("organization_id = ?", *ctx.OrganizationID)
+	} else {
+		boardQuery = boardQuery.Where("organization_id IS NULL")
+	}
+
+	if err := boardQuery.First(&board).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			log.Error().Err(err).Msg("Failed to look up to-do board")
+			return p.sendErrorMessage(ctx.PhoneNumber, "❌ Failed to add your to-do. Please try again.")
+		}
+
+		board = models.TaskBoard{
+			Name:           whatsAppTodoBoardName,
+			Description:    "To-dos captured from WhatsApp",
+			ClerkUserID:    ctx.User.ClerkUserID,
+			OrganizationID: ctx.OrganizationID,
+			IsStandalone:   true,
+		}
+		if err := p.db.Create(&board).Error; err != nil {
+			log.Error().Err(err).Msg("Failed to create to-do board")
+			return p.sendErrorMessage(ctx.PhoneNumber, "❌ Failed to add your to-do. Please try again.")
+		}
+	}
+
+	// Append the task at the end of the todo column
+	var position int64
+	p.db.Model(&models.Task{}).Where("task_board_id = ? AND status = ?", board.ID, "todo").Count(&position)
+
+	task := models.Task{
+		Title:          title,
+		Status:         "todo",
+		Priority:       priority,
+		TaskBoardID:    board.ID,
+		Position:       int(position),
+		OrganizationID: ctx.OrganizationID,
+	}
+	if err := p.db.Create(&task).Error; err != nil {
+		log.Error().Err(err).Msg("Failed to create to-do task")
+		return p.sendErrorMessage(ctx.PhoneNumber, "❌ Failed to add your to-do. Please try again.")
+	}
+
+	message := fmt.Sprintf("✅ *To-do added!*\n\n☑️ %s\n🏷️ *Priority:* %s\n📋 *Board:* %s",
+		task.Title, task.Priority, board.Name)
+	return p.client.SendTextMessage(ctx.PhoneNumber, message)
+}
+
+// showScheduleFromIntent lists the user's calendar events for the requested day (today by default), in
+// the user's timezone
+func (p *WhatsAppMessageProcessor) showScheduleFromIntent(ctx *whatsapp.CommandContext, date string) error {
+	// The server's timezone means nothing on the user's phone, so days are UTC until they set theirs
+	location := userLocationOr(context.Background(), p.db, ctx.User.ClerkUserID, time.UTC)
+	day := time.Now().In(location)
+	if date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, location)
+		if err != nil {
+			log.Debug().Err(err).Str("date", date).Msg("Invalid schedule date slot, using today")
+		} else {
+			day = parsed
+		}
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.Add(24 * time.Hour)
+
+	var events []models.CalendarEvent
+	if err := p.db.
+		Joins("JOIN calendars ON calendars.id = calendar_events.calendar_id").
+		Where("calendars.clerk_user_id = ? AND calendar_events.is_deleted = ?", ctx.User.ClerkUserID, false).
+		Where("calendar_events.start_time >= ? AND calendar_events.start_time < ?", start, end).
+		Preload("AgendaNote", SelectAgendaNote).
+		Order("calendar_events.start_time ASC").
+		Limit(20).
+		Find(&events).Error; err != nil {
+		log.Error().Err(err).Msg("Failed to load calendar events")
+		return p.sendErrorMessage(ctx.PhoneNumber, "❌ Failed to load your schedule. Please try again.")
+	}
+
+	dayLabel := start.Format("Monday, Jan 2")
+	if len(events) == 0 {
+		return p.client.SendTextMessage(ctx.PhoneNumber,
+			fmt.Sprintf("📅 Nothing on your calendar for *%s*.", dayLabel))
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("📅 *Schedule for %s*\n\n", dayLabel))
+	for _, event := range events {
+		title := event.Title
+		if title == "" {
+			title = "Untitled event"
+		}
+		botIndicator := ""
+		if event.BotScheduled {
+			botIndicator = " 🤖"
+		}
+		message.WriteString(fmt.Sprintf("• %s - %s *%s*%s\n",
+			event.StartTime.In(location).Format("3:04 PM"), event.EndTime.In(location).Format("3:04 PM"), title, botIndicator))
+		if event.AgendaNote != nil {
+			message.WriteString(fmt.Sprintf("   📝 Agenda: %s\n", event.AgendaNote.Name))
+		}
+	}
+
+	return p.client.SendTextMessage(ctx.PhoneNumber, message.String())
+}
+
+// sendHelpFallback shows the help message when a free-text message can't be understood
+func (p *WhatsAppMessageProcessor) sendHelpFallback(ctx *whatsapp.CommandContext) error {
+	if err := p.client.SendTextMessage(ctx.PhoneNumber,
+		"🤔 Sorry, I couldn't understand that. Here's what I can help with:"); err != nil {
+		return err
+	}
+
+	helpCmd, exists := p.registry.Get("help")
+	if !exists {
+		return p.sendErrorMessage(ctx.PhoneNumber,
+			"Please use commands to interact with the bot. Type /help to see available commands.")
+	}
+
+	ctx.Args = nil
+	return helpCmd.Execute(ctx)
+}
+
 // createNoteWithAI creates a note using AI to organize and generate content
 func (p *WhatsAppMessageProcessor) createNoteWithAI(ctx *whatsapp.CommandContext, noteTitle string) error {
 	cmdTimer := p.metricsService.CommandTimer("ai_note_creation")
@@ -346,6 +594,11 @@ func (p *WhatsAppMessageProcessor) createNoteWithAI(ctx *whatsapp.CommandContext
 		log.Debug().Err(err).Msg("No context to clear (expected for AI commands)")
 	}
 
+	// Viewers and commenters can't add notes to the organization
+	if err := whatsapp.VerifyOrganizationEditAccess(ctx); err != nil {
+		return p.sendErrorMessage(ctx.PhoneNumber, fmt.Sprintf("❌ %s", err.Error()))
+	}
+
 	// Send initial processing message
 	p.client.SendTextMessage(ctx.PhoneNumber, "🤖 Creating your note with AI...\n\n_This may take a moment_")
 
@@ -386,31 +639,41 @@ func (p *WhatsAppMessageProcessor) createNoteWithAI(ctx *whatsapp.CommandContext
 		return p.sendErrorMessage(ctx.PhoneNumber, errorMsg)
 	}
 
-	// Find or create notebook
+	// Groups with a capture notebook save notes there instead of the notebook AI picked
 	var notebook models.Notebook
-	notebookQuery := p.db.Where("LOWER(name) = ? AND clerk_user_id = ?",
-		strings.ToLower(organization.NotebookName), ctx.User.ClerkUserID)
-	if ctx.OrganizationID != nil {
-		notebookQuery = notebookQuery.Where("organization_id = ?", *ctx.OrganizationID)
-	} else {
-		notebookQuery = notebookQuery.Where("organization_id IS NULL")
+	if ctx.DefaultNotebookID != nil && ctx.OrganizationID != nil {
+		if err := p.db.Where("id = ? AND organization_id = ?", *ctx.DefaultNotebookID, *ctx.OrganizationID).
+			First(&notebook).Error; err != nil {
+			log.Warn().Err(err).Str("notebook_id", *ctx.DefaultNotebookID).Msg("Group capture notebook not found")
+		}
 	}
 
-	err = notebookQuery.First(&notebook).Error
-	if err != nil {
-		// Notebook doesn't exist, create it
-		notebook = models.Notebook{
-			Name:        organization.NotebookName,
-			ClerkUserID: ctx.User.ClerkUserID,
-		}
+	// Find or create notebook
+	if notebook.ID == "" {
+		notebookQuery := p.db.Where("LOWER(name) = ? AND clerk_user_id = ?",
+			strings.ToLower(organization.NotebookName), ctx.User.ClerkUserID)
 		if ctx.OrganizationID != nil {
-			notebook.OrganizationID = ctx.OrganizationID
+			notebookQuery = notebookQuery.Where("organization_id = ?", *ctx.OrganizationID)
+		} else {
+			notebookQuery = notebookQuery.Where("organization_id IS NULL")
 		}
-		if err := p.db.Create(&notebook).Error; err != nil {
-			log.Error().Err(err).Msg("Failed to create notebook")
-			p.metricsService.RecordCommandExecution("ai_note_creation", "failed")
-			return p.sendErrorMessage(ctx.PhoneNumber,
-				"❌ Failed to create notebook. Please try again.")
+
+		err = notebookQuery.First(&notebook).Error
+		if err != nil {
+			// Notebook doesn't exist, create it
+			notebook = models.Notebook{
+				Name:        organization.NotebookName,
+				ClerkUserID: ctx.User.ClerkUserID,
+			}
+			if ctx.OrganizationID != nil {
+				notebook.OrganizationID = ctx.OrganizationID
+			}
+			if err := p.db.Create(&notebook).Error; err != nil {
+				log.Error().Err(err).Msg("Failed to create notebook")
+				p.metricsService.RecordCommandExecution("ai_note_creation", "failed")
+				return p.sendErrorMessage(ctx.PhoneNumber,
+					"❌ Failed to create notebook. Please try again.")
+			}
 		}
 	}
 
@@ -437,12 +700,14 @@ func (p *WhatsAppMessageProcessor) createNoteWithAI(ctx *whatsapp.CommandContext
 		}
 	}
 
-	// Generate AI content
-	markdownContent, err := aiService.GenerateNoteContent(context.Background(), NoteContentGenerationRequest{
+	// Generate AI content in the chapter's style
+	generationRequest := NoteContentGenerationRequest{
 		NoteTitle: noteTitle,
 		UserID:    ctx.User.ClerkUserID,
 		OrgID:     ctx.OrganizationID,
-	})
+	}
+	ApplyChapterGenerationSettings(&chapter, &generationRequest)
+	markdownContent, err := aiService.GenerateNoteContent(context.Background(), generationRequest)
 
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate AI content")
@@ -465,7 +730,7 @@ func (p *WhatsAppMessageProcessor) createNoteWithAI(ctx *whatsapp.CommandContext
 	// Create the note
 	note := models.Notes{
 		Name:      noteTitle,
-		Content:   content,
+		Content:   ApplyChapterNoteDefaults(&chapter, content),
 		ChapterID: chapter.ID,
 	}
 	if ctx.OrganizationID != nil {
@@ -492,8 +757,8 @@ func (p *WhatsAppMessageProcessor) createNoteWithAI(ctx *whatsapp.CommandContext
 	return p.client.SendTextMessage(ctx.PhoneNumber, successMessage)
 }
 
-// getOrganizationForGroup retrieves the organization ID for a WhatsApp group
-func (p *WhatsAppMessageProcessor) getOrganizationForGroup(groupID string) (*string, error) {
+// getGroupLink retrieves the organization link of a WhatsApp group
+func (p *WhatsAppMessageProcessor) getGroupLink(groupID string) (*models.WhatsAppGroupLink, error) {
 	var groupLink models.WhatsAppGroupLink
 	err := p.db.Where("group_id = ? AND is_active = ?", groupID, true).First(&groupLink).Error
 	if err != nil {
@@ -503,7 +768,7 @@ func (p *WhatsAppMessageProcessor) getOrganizationForGroup(groupID string) (*str
 		return nil, err
 	}
 
-	return &groupLink.OrganizationID, nil
+	return &groupLink, nil
 }
 
 // sendErrorMessage sends an error message to the user
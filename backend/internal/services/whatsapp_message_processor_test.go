@@ -0,0 +1,157 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/whatsapp"
+	whatsappclient "backend/pkg/whatsapp"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Metrics are registered with Prometheus once per process
+var testWhatsAppMetrics = sync.OnceValue(NewWhatsAppMetricsService)
+
+// recordingWhatsAppClient records the text messages sent through it
+type recordingWhatsAppClient struct {
+	messages []string
+}
+
+func (c *recordingWhatsAppClient) SendTextMessage(phoneNumber, message string) error {
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func (c *recordingWhatsAppClient) SendInteractiveMessage(phoneNumber string, message whatsappclient.InteractiveMessage) error {
+	return nil
+}
+
+func (c *recordingWhatsAppClient) VerifyWebhookSignature(payload []byte, signature string) bool {
+	return true
+}
+
+func (c *recordingWhatsAppClient) GetPhoneNumberID() string {
+	return "phone_number_id"
+}
+
+func (c *recordingWhatsAppClient) Ping(ctx context.Context) error {
+	return nil
+}
+
+// recordingCommand records the arguments it's run with
+type recordingCommand struct {
+	name string
+	runs [][]string
+}
+
+func (c *recordingCommand) Name() string        { return c.name }
+func (c *recordingCommand) Description() string { return c.name }
+func (c *recordingCommand) Usage() string       { return "/" + c.name }
+func (c *recordingCommand) RequiresAuth() bool  { return false }
+
+func (c *recordingCommand) Execute(ctx *whatsapp.CommandContext) error {
+	c.runs = append(c.runs, ctx.Args)
+	return nil
+}
+
+type intentRoutingFixture struct {
+	processor *WhatsAppMessageProcessor
+	client    *recordingWhatsAppClient
+	commands  map[string]*recordingCommand
+}
+
+// setupTestIntentRouting creates a message processor over an in-memory database, with commands that
+// record how they're run
+func setupTestIntentRouting(t *testing.T) intentRoutingFixture {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.TaskBoard{}, &models.Task{}), "Failed to migrate test database")
+
+	f := intentRoutingFixture{client: &recordingWhatsAppClient{}, commands: map[string]*recordingCommand{}}
+	registry := whatsapp.NewCommandRegistry()
+	for _, name := range []string{"help", "retrieve", "summarize"} {
+		f.commands[name] = &recordingCommand{name: name}
+		registry.Register(f.commands[name])
+	}
+	f.processor = NewWhatsAppMessageProcessor(db, nil, f.client, nil, nil, registry, nil, testWhatsAppMetrics())
+	return f
+}
+
+func (f intentRoutingFixture) commandContext() *whatsapp.CommandContext {
+	return &whatsapp.CommandContext{
+		PhoneNumber: "+15550001111",
+		User:        &models.WhatsAppUser{ClerkUserID: "user_1", IsAuthenticated: true},
+		Client:      f.client,
+		DB:          f.processor.db,
+	}
+}
+
+func TestRouteIntent_RoutesToCommands(t *testing.T) {
+	f := setupTestIntentRouting(t)
+
+	err := f.processor.routeIntent(f.commandContext(), &whatsapp.NaturalLanguageIntent{
+		Intent: whatsapp.IntentSearch, Confidence: 0.9, Slots: map[string]string{"query": "quarterly  planning"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"quarterly", "planning"}}, f.commands["retrieve"].runs)
+
+	err = f.processor.routeIntent(f.commandContext(), &whatsapp.NaturalLanguageIntent{
+		Intent: whatsapp.IntentSummarize, Confidence: 0.8, Slots: map[string]string{"type": "chapter", "name": "Week 1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"chapter", "Week", "1"}}, f.commands["summarize"].runs)
+	assert.Empty(t, f.commands["help"].runs)
+}
+
+func TestRouteIntent_FallsBackToHelp(t *testing.T) {
+	for name, intent := range map[string]*whatsapp.NaturalLanguageIntent{
+		"low confidence":     {Intent: whatsapp.IntentSearch, Confidence: 0.3, Slots: map[string]string{"query": "planning"}},
+		"unknown intent":     {Intent: whatsapp.IntentUnknown, Confidence: 0.9},
+		"search of nothing":  {Intent: whatsapp.IntentSearch, Confidence: 0.9},
+		"to-do with no task": {Intent: whatsapp.IntentTodo, Confidence: 0.9, Slots: map[string]string{"task": "  "}},
+		"note with no title": {Intent: whatsapp.IntentAdd, Confidence: 0.9},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := setupTestIntentRouting(t)
+
+			require.NoError(t, f.processor.routeIntent(f.commandContext(), intent))
+			assert.Len(t, f.commands["help"].runs, 1)
+			assert.Empty(t, f.commands["retrieve"].runs)
+			assert.Contains(t, f.client.messages[0], "couldn't understand")
+		})
+	}
+}
+
+func TestRouteIntent_AddsTodos(t *testing.T) {
+	f := setupTestIntentRouting(t)
+
+	for _, slots := range []map[string]string{
+		{"task": "Call the dentist", "priority": "high"},
+		{"task": "Renew passport", "priority": "urgent"},
+	} {
+		err := f.processor.routeIntent(f.commandContext(), &whatsapp.NaturalLanguageIntent{
+			Intent: whatsapp.IntentTodo, Confidence: 0.9, Slots: slots,
+		})
+		require.NoError(t, err)
+	}
+
+	var boards []models.TaskBoard
+	require.NoError(t, f.processor.db.Find(&boards).Error)
+	require.Len(t, boards, 1, "Both to-dos go on the same board")
+	assert.Equal(t, whatsAppTodoBoardName, boards[0].Name)
+	assert.Nil(t, boards[0].OrganizationID)
+
+	var tasks []models.Task
+	require.NoError(t, f.processor.db.Order("position").Find(&tasks).Error)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "Call the dentist", tasks[0].Title)
+	assert.Equal(t, "high", tasks[0].Priority)
+	assert.Equal(t, "medium", tasks[1].Priority, "Unknown priorities default to medium")
+	assert.Equal(t, 1, tasks[1].Position)
+	assert.Contains(t, f.client.messages[1], "To-do added")
+}
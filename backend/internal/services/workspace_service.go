@@ -0,0 +1,199 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/organizationmembership"
+	"gorm.io/gorm"
+)
+
+const (
+	// WorkspaceTypePersonal is a user's own notes, outside any organization
+	WorkspaceTypePersonal = "personal"
+	// WorkspaceTypeOrganization is an organization's notes
+	WorkspaceTypeOrganization = "organization"
+
+	workspaceRecentItems = 5
+)
+
+// Workspace is a place a user's notes live: their personal space or one of their organizations
+type Workspace struct {
+	Type           string  `json:"type"`
+	OrganizationID *string `json:"organizationId,omitempty"`
+	Name           string  `json:"name"`
+	Role           string  `json:"role,omitempty"` // The user's role in the organization
+}
+
+// WorkspaceCounts counts what a workspace holds
+type WorkspaceCounts struct {
+	Notebooks  int64 `json:"notebooks"`
+	Notes      int64 `json:"notes"`
+	TaskBoards int64 `json:"taskBoards"`
+	OpenTasks  int64 `json:"openTasks"`
+}
+
+// WorkspaceRecentNote is a recently updated note in a workspace
+type WorkspaceRecentNote struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Slug         string    `json:"slug"`
+	NotebookID   string    `json:"notebookId"`
+	NotebookName string    `json:"notebookName"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// WorkspaceOverview is a workspace with its counts and recently updated notes
+type WorkspaceOverview struct {
+	Workspace
+	Counts      WorkspaceCounts       `json:"counts"`
+	RecentNotes []WorkspaceRecentNote `json:"recentNotes"`
+}
+
+// WorkspaceService interface defines methods for working across every workspace a user belongs to
+type WorkspaceService interface {
+	ListWorkspaces(ctx context.Context, clerkUserID string) ([]Workspace, error)
+	Overview(ctx context.Context, clerkUserID string) ([]WorkspaceOverview, error)
+}
+
+// workspaceServiceImpl implements the WorkspaceService interface
+type workspaceServiceImpl struct {
+	db            *gorm.DB
+	organizations func(ctx context.Context, clerkUserID string) ([]Workspace, error)
+}
+
+// NewWorkspaceService creates a new WorkspaceService instance
+func NewWorkspaceService() WorkspaceService {
+	return &workspaceServiceImpl{
+		db:            db.Replica(db.DB),
+		organizations: clerkUserWorkspaces,
+	}
+}
+
+// ListWorkspaces returns the user's personal workspace followed by their organizations
+func (s *workspaceServiceImpl) ListWorkspaces(ctx context.Context, clerkUserID string) ([]Workspace, error) {
+	organizations, err := s.organizations(ctx, clerkUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organizations: %w", err)
+	}
+	return append([]Workspace{{Type: WorkspaceTypePersonal, Name: "Personal"}}, organizations...), nil
+}
+
+// Overview returns every workspace of the user with what it holds and its most recently updated notes
+func (s *workspaceServiceImpl) Overview(ctx context.Context, clerkUserID string) ([]WorkspaceOverview, error) {
+	workspaces, err := s.ListWorkspaces(ctx, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	overviews := make([]WorkspaceOverview, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		overview := WorkspaceOverview{Workspace: workspace}
+		if err := s.countWorkspace(ctx, clerkUserID, workspace, &overview.Counts); err != nil {
+			return nil, err
+		}
+
+		overview.RecentNotes = []WorkspaceRecentNote{}
+		if err := scopeWorkspaceNotebooks(s.db.WithContext(ctx).Table("notes"), clerkUserID, workspace).
+			Select("notes.id, notes.name, notes.slug, notebooks.id AS notebook_id, notebooks.name AS notebook_name, notes.updated_at").
+			Joins("JOIN chapters ON notes.chapter_id = chapters.id").
+			Joins("JOIN notebooks ON chapters.notebook_id = notebooks.id").
+			Order("notes.updated_at DESC").
+			Limit(workspaceRecentItems).
+			Scan(&overview.RecentNotes).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch recent notes: %w", err)
+		}
+		overviews = append(overviews, overview)
+	}
+	return overviews, nil
+}
+
+// countWorkspace counts the notebooks, notes, task boards and open tasks in a workspace
+func (s *workspaceServiceImpl) countWorkspace(ctx context.Context, clerkUserID string, workspace Workspace, counts *WorkspaceCounts) error {
+	query := s.db.WithContext(ctx)
+	if err := scopeWorkspaceNotebooks(query.Model(&models.Notebook{}), clerkUserID, workspace).
+		Count(&counts.Notebooks).Error; err != nil {
+		return fmt.Errorf("failed to count notebooks: %w", err)
+	}
+	if err := scopeWorkspaceNotebooks(query.Model(&models.Notes{}), clerkUserID, workspace).
+		Joins("JOIN chapters ON notes.chapter_id = chapters.id").
+		Joins("JOIN notebooks ON chapters.notebook_id = notebooks.id").
+		Count(&counts.Notes).Error; err != nil {
+		return fmt.Errorf("failed to count notes: %w", err)
+	}
+
+	boards := query.Model(&models.TaskBoard{})
+	tasks := query.Model(&models.Task{}).Joins("JOIN task_boards ON tasks.task_board_id = task_boards.id").
+		Where("tasks.status <> ?", "done")
+	if workspace.OrganizationID != nil {
+		boards = boards.Where("task_boards.organization_id = ?", *workspace.OrganizationID)
+		tasks = tasks.Where("task_boards.organization_id = ?", *workspace.OrganizationID)
+	} else {
+		boards = boards.Where("task_boards.clerk_user_id = ? AND task_boards.organization_id IS NULL", clerkUserID)
+		tasks = tasks.Where("task_boards.clerk_user_id = ? AND task_boards.organization_id IS NULL", clerkUserID)
+	}
+	if err := boards.Count(&counts.TaskBoards).Error; err != nil {
+		return fmt.Errorf("failed to count task boards: %w", err)
+	}
+	if err := tasks.Count(&counts.OpenTasks).Error; err != nil {
+		return fmt.Errorf("failed to count open tasks: %w", err)
+	}
+	return nil
+}
+
+// scopeWorkspaceNotebooks limits a query joined with notebooks to one workspace's notebooks
+func scopeWorkspaceNotebooks(query *gorm.DB, clerkUserID string, workspace Workspace) *gorm.DB {
+	if workspace.OrganizationID != nil {
+		return query.Where("notebooks.organization_id = ?", *workspace.OrganizationID)
+	}
+	return query.Where("notebooks.clerk_user_id = ? AND notebooks.organization_id IS NULL", clerkUserID)
+}
+
+// ScopeAllWorkspaces limits a query joined with notebooks to the user's personal notebooks and those of
+// the given organizations
+func ScopeAllWorkspaces(query *gorm.DB, clerkUserID string, workspaces []Workspace) *gorm.DB {
+	organizationIDs := make([]string, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		if workspace.OrganizationID != nil {
+			organizationIDs = append(organizationIDs, *workspace.OrganizationID)
+		}
+	}
+	if len(organizationIDs) == 0 {
+		return query.Where("notebooks.clerk_user_id = ? AND notebooks.organization_id IS NULL", clerkUserID)
+	}
+	return query.Where("((notebooks.clerk_user_id = ? AND notebooks.organization_id IS NULL) OR notebooks.organization_id IN ?)",
+		clerkUserID, organizationIDs)
+}
+
+// clerkUserWorkspaces lists the organizations the user is a member of, with their names and the user's
+// role. Single-user servers have none.
+func clerkUserWorkspaces(ctx context.Context, clerkUserID string) ([]Workspace, error) {
+	if accessLookups.SingleUser() {
+		return nil, nil
+	}
+	params := &organizationmembership.ListParams{}
+	params.Limit = clerk.Int64(100)
+	params.UserIDs = []string{clerkUserID}
+	memberships, err := organizationmembership.List(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	workspaces := make([]Workspace, 0, len(memberships.OrganizationMemberships))
+	for _, membership := range memberships.OrganizationMemberships {
+		if membership.Organization == nil {
+			continue
+		}
+		orgID := membership.Organization.ID
+		workspaces = append(workspaces, Workspace{
+			Type:           WorkspaceTypeOrganization,
+			OrganizationID: &orgID,
+			Name:           membership.Organization.Name,
+			Role:           accessLookups.OrgRoleFromClerk(membership.Role),
+		})
+	}
+	return workspaces, nil
+}
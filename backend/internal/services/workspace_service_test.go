@@ -0,0 +1,97 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestWorkspaceService creates a workspace service on an in-memory database, for a user who is a
+// member of org_1
+func setupTestWorkspaceService(t *testing.T) *workspaceServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Notebook{}, &models.Chapter{}, &models.Notes{}, &models.TaskBoard{}, &models.Task{}),
+		"Failed to migrate test database")
+
+	return &workspaceServiceImpl{
+		db: db,
+		organizations: func(ctx context.Context, clerkUserID string) ([]Workspace, error) {
+			orgID := "org_1"
+			return []Workspace{{Type: WorkspaceTypeOrganization, OrganizationID: &orgID, Name: "Acme", Role: "member"}}, nil
+		},
+	}
+}
+
+// createWorkspaceNotes creates a notebook with one note per name, updated in order
+func createWorkspaceNotes(t *testing.T, service *workspaceServiceImpl, ownerID string, orgID *string, names ...string) {
+	notebook := models.Notebook{Name: "Notebook", ClerkUserID: ownerID, OrganizationID: orgID}
+	require.NoError(t, service.db.Create(&notebook).Error)
+	chapter := models.Chapter{Name: "Chapter", NotebookID: notebook.ID, OrganizationID: orgID}
+	require.NoError(t, service.db.Create(&chapter).Error)
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, name := range names {
+		updated := start.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, service.db.Create(&models.Notes{Name: name, ChapterID: chapter.ID, OrganizationID: orgID, UpdatedAt: updated}).Error)
+	}
+}
+
+func TestWorkspacesOverview(t *testing.T) {
+	service := setupTestWorkspaceService(t)
+	ctx := context.Background()
+	orgID, otherOrgID := "org_1", "org_2"
+
+	createWorkspaceNotes(t, service, "user_1", nil, "Journal", "Recipes")
+	createWorkspaceNotes(t, service, "user_2", &orgID, "Roadmap", "Standup", "Retro", "Hiring", "Budget", "Offsite")
+	createWorkspaceNotes(t, service, "user_2", &otherOrgID, "Secret plans")
+	createWorkspaceNotes(t, service, "user_2", nil, "Someone else's diary")
+
+	board := models.TaskBoard{Name: "Sprint", ClerkUserID: "user_2", OrganizationID: &orgID}
+	require.NoError(t, service.db.Create(&board).Error)
+	require.NoError(t, service.db.Create(&models.Task{Title: "Ship", TaskBoardID: board.ID, Status: "todo"}).Error)
+	require.NoError(t, service.db.Create(&models.Task{Title: "Plan", TaskBoardID: board.ID, Status: "done"}).Error)
+
+	overview, err := service.Overview(ctx, "user_1")
+	require.NoError(t, err)
+	require.Len(t, overview, 2)
+
+	personal := overview[0]
+	assert.Equal(t, WorkspaceTypePersonal, personal.Type)
+	assert.Equal(t, WorkspaceCounts{Notebooks: 1, Notes: 2}, personal.Counts)
+	require.Len(t, personal.RecentNotes, 2)
+	assert.Equal(t, "Recipes", personal.RecentNotes[0].Name)
+	assert.Equal(t, "Notebook", personal.RecentNotes[0].NotebookName)
+
+	org := overview[1]
+	assert.Equal(t, "Acme", org.Name)
+	assert.Equal(t, WorkspaceCounts{Notebooks: 1, Notes: 6, TaskBoards: 1, OpenTasks: 1}, org.Counts)
+	require.Len(t, org.RecentNotes, workspaceRecentItems)
+	assert.Equal(t, "Offsite", org.RecentNotes[0].Name)
+}
+
+func TestScopeAllWorkspaces(t *testing.T) {
+	service := setupTestWorkspaceService(t)
+	ctx := context.Background()
+	orgID, otherOrgID := "org_1", "org_2"
+
+	createWorkspaceNotes(t, service, "user_1", nil, "Journal")
+	createWorkspaceNotes(t, service, "user_2", &orgID, "Roadmap")
+	createWorkspaceNotes(t, service, "user_2", &otherOrgID, "Secret plans")
+
+	workspaces, err := service.ListWorkspaces(ctx, "user_1")
+	require.NoError(t, err)
+
+	var names []string
+	require.NoError(t, ScopeAllWorkspaces(service.db.Model(&models.Notes{}).
+		Joins("JOIN chapters ON notes.chapter_id = chapters.id").
+		Joins("JOIN notebooks ON chapters.notebook_id = notebooks.id"), "user_1", workspaces).
+		Order("notes.name ASC").
+		Pluck("notes.name", &names).Error)
+	assert.Equal(t, []string{"Journal", "Roadmap"}, names, "Organizations the user isn't in are left out")
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"backend/db"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultWritingStatsRange = "30d"
+	writingDayLayout         = "2006-01-02"
+)
+
+// writingStatsRanges are the ranges stats can be asked for, in days
+var writingStatsRanges = map[string]int{"7d": 7, "30d": 30, "90d": 90, "365d": 365}
+
+// writingStreakMilestones are the streak lengths, in days, recorded as milestones
+var writingStreakMilestones = []int{3, 7, 14, 30, 60, 100, 180, 365}
+
+// ErrInvalidWritingStatsRange is returned for ranges other than 7d, 30d, 90d and 365d
+var ErrInvalidWritingStatsRange = errors.New("range must be 7d, 30d, 90d or 365d")
+
+// WritingStatsScope selects whose writing stats are computed: a user's personal notes, or their notes in an organization
+type WritingStatsScope struct {
+	ClerkUserID    string
+	OrganizationID *string
+}
+
+// WritingDay is a user's writing on one day
+type WritingDay struct {
+	Day          string `json:"day"`
+	WordsWritten int    `json:"wordsWritten"`
+	NotesCreated int    `json:"notesCreated"`
+	Edits        int    `json:"edits"`
+}
+
+// WritingStats summarizes a user's writing over a range of days, with their streaks
+type WritingStats struct {
+	Range             string                    `json:"range"`
+	Timezone          string                    `json:"timezone"`
+	Days              []WritingDay              `json:"days"` // Every day of the range, oldest first
+	TotalWords        int                       `json:"totalWords"`
+	TotalNotesCreated int                       `json:"totalNotesCreated"`
+	TotalEdits        int                       `json:"totalEdits"`
+	ActiveDays        int                       `json:"activeDays"`
+	CurrentStreak     int                       `json:"currentStreak"` // Days in a row with edits, up to today or yesterday
+	LongestStreak     int                       `json:"longestStreak"`
+	Milestones        []models.WritingMilestone `json:"milestones"`
+}
+
+// WritingStatsService interface defines methods for tracking writing activity and reporting stats and streaks
+type WritingStatsService interface {
+	RecordWriting(clerkUserID string, organizationID *string, previousContent, content string, created bool)
+	GetStats(ctx context.Context, scope WritingStatsScope, statsRange string) (*WritingStats, error)
+}
+
+// writingStatsServiceImpl implements the WritingStatsService interface
+type writingStatsServiceImpl struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewWritingStatsService creates a new WritingStatsService instance
+func NewWritingStatsService() WritingStatsService {
+	return &writingStatsServiceImpl{
+		db:  db.DB,
+		now: time.Now,
+	}
+}
+
+// RecordWriting adds a note save to the user's writing activity for today, counting the words it added.
+// Content is empty when the server can't read it, so only the edit is counted. Failures are logged, never
+// returned, so tracking can't break the request it's attached to.
+func (s *writingStatsServiceImpl) RecordWriting(clerkUserID string, organizationID *string, previousContent, content string, created bool) {
+	if clerkUserID == "" {
+		return
+	}
+
+	ctx := context.Background()
+	orgID := ""
+	if organizationID != nil {
+		orgID = *organizationID
+	}
+	day := s.now().In(UserLocation(ctx, s.db, clerkUserID)).Format(writingDayLayout)
+	words := max(noteWordCount(content)-noteWordCount(previousContent), 0)
+	notesCreated := 0
+	if created {
+		notesCreated = 1
+	}
+
+	activity := models.WritingActivity{ClerkUserID: clerkUserID, OrganizationID: orgID, Day: day}
+	if err := s.db.Where(models.WritingActivity{ClerkUserID: clerkUserID, OrganizationID: orgID, Day: day}).
+		FirstOrCreate(&activity).Error; err != nil {
+		log.Warn().Err(err).Str("user_id", clerkUserID).Msg("Failed to record writing activity")
+		return
+	}
+	if err := s.db.Model(&models.WritingActivity{}).Where("id = ?", activity.ID).
+		UpdateColumns(map[string]interface{}{
+			"words_written": gorm.Expr("words_written + ?", words),
+			"notes_created": gorm.Expr("notes_created + ?", notesCreated),
+			"edits":         gorm.Expr("edits + 1"),
+		}).Error; err != nil {
+		log.Warn().Err(err).Str("user_id", clerkUserID).Msg("Failed to record writing activity")
+		return
+	}
+
+	// Streaks only grow with the first edit of a day
+	if activity.Edits == 0 {
+		s.recordStreakMilestones(ctx, clerkUserID, orgID, day)
+	}
+}
+
+// GetStats returns the user's writing stats over the range, 30 days by default, and their streaks
+func (s *writingStatsServiceImpl) GetStats(ctx context.Context, scope WritingStatsScope, statsRange string) (*WritingStats, error) {
+	if statsRange == "" {
+		statsRange = defaultWritingStatsRange
+	}
+	days, ok := writingStatsRanges[statsRange]
+	if !ok {
+		return nil, ErrInvalidWritingStatsRange
+	}
+
+	orgID := ""
+	if scope.OrganizationID != nil {
+		orgID = *scope.OrganizationID
+	}
+	location := UserLocation(ctx, s.db, scope.ClerkUserID)
+	today := s.now().In(location)
+	from := today.AddDate(0, 0, -(days - 1)).Format(writingDayLayout)
+
+	var activity []models.WritingActivity
+	if err := s.db.WithContext(ctx).
+		Where("clerk_user_id = ? AND organization_id = ? AND day >= ?", scope.ClerkUserID, orgID, from).
+		Find(&activity).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch writing activity: %w", err)
+	}
+	byDay := make(map[string]models.WritingActivity, len(activity))
+	for _, entry := range activity {
+		byDay[entry.Day] = entry
+	}
+
+	stats := &WritingStats{Range: statsRange, Timezone: TimezoneName(location), Days: make([]WritingDay, 0, days)}
+	for i := days - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i).Format(writingDayLayout)
+		entry := byDay[day]
+		stats.Days = append(stats.Days, WritingDay{Day: day, WordsWritten: entry.WordsWritten, NotesCreated: entry.NotesCreated, Edits: entry.Edits})
+		stats.TotalWords += entry.WordsWritten
+		stats.TotalNotesCreated += entry.NotesCreated
+		stats.TotalEdits += entry.Edits
+		if entry.Edits > 0 {
+			stats.ActiveDays++
+		}
+	}
+
+	activeDays, err := s.activeDays(ctx, scope.ClerkUserID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	stats.CurrentStreak, stats.LongestStreak = writingStreaks(activeDays, today.Format(writingDayLayout))
+
+	stats.Milestones = []models.WritingMilestone{}
+	if err := s.db.WithContext(ctx).
+		Where("clerk_user_id = ? AND organization_id = ?", scope.ClerkUserID, orgID).
+		Order("kind ASC, value ASC").
+		Find(&stats.Milestones).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch writing milestones: %w", err)
+	}
+	return stats, nil
+}
+
+// recordStreakMilestones records the streak milestones the user's current streak reached. Milestones are
+// recorded once, the first time they're reached.
+func (s *writingStatsServiceImpl) recordStreakMilestones(ctx context.Context, clerkUserID, orgID, today string) {
+	activeDays, err := s.activeDays(ctx, clerkUserID, orgID)
+	if err != nil {
+		log.Warn().Err(err).Str("user_id", clerkUserID).Msg("Failed to check writing streak")
+		return
+	}
+
+	streak, _ := writingStreaks(activeDays, today)
+	for _, milestone := range writingStreakMilestones {
+		if milestone > streak {
+			break
+		}
+		entry := models.WritingMilestone{ClerkUserID: clerkUserID, OrganizationID: orgID, Kind: models.WritingMilestoneStreak, Value: milestone}
+		result := s.db.WithContext(ctx).
+			Where(models.WritingMilestone{ClerkUserID: clerkUserID, OrganizationID: orgID, Kind: models.WritingMilestoneStreak, Value: milestone}).
+			Attrs(models.WritingMilestone{Day: today}).
+			FirstOrCreate(&entry)
+		if result.Error != nil {
+			log.Warn().Err(result.Error).Str("user_id", clerkUserID).Int("streak", milestone).Msg("Failed to record writing milestone")
+			return
+		}
+		if result.RowsAffected > 0 {
+			log.Info().Str("user_id", clerkUserID).Int("streak", milestone).Msg("Writing streak milestone reached")
+		}
+	}
+}
+
+// activeDays returns the days the user edited notes in the scope, oldest first
+func (s *writingStatsServiceImpl) activeDays(ctx context.Context, clerkUserID, orgID string) ([]string, error) {
+	var days []string
+	if err := s.db.WithContext(ctx).Model(&models.WritingActivity{}).
+		Where("clerk_user_id = ? AND organization_id = ? AND edits > 0", clerkUserID, orgID).
+		Order("day ASC").
+		Pluck("day", &days).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch writing days: %w", err)
+	}
+	return days, nil
+}
+
+// writingStreaks returns the current and longest runs of consecutive days in the sorted active days. The
+// current streak still counts when today has no edits yet, as long as yesterday had some.
+func writingStreaks(activeDays []string, today string) (int, int) {
+	current, longest, run := 0, 0, 0
+	var previous time.Time
+	for _, value := range activeDays {
+		day, err := time.Parse(writingDayLayout, value)
+		if err != nil {
+			continue
+		}
+		if run > 0 && day.Equal(previous.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		previous = day
+		longest = max(longest, run)
+	}
+
+	todayDay, err := time.Parse(writingDayLayout, today)
+	if err == nil && run > 0 && (previous.Equal(todayDay) || previous.Equal(todayDay.AddDate(0, 0, -1))) {
+		current = run
+	}
+	return current, longest
+}
+
+// noteWordCount counts the words of a note's content, leaving out markdown syntax
+func noteWordCount(content string) int {
+	if strings.TrimSpace(content) == "" {
+		return 0
+	}
+
+	count := 0
+	for _, field := range strings.Fields(NoteModerationText(content)) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) >= 0 {
+			count++
+		}
+	}
+	return count
+}
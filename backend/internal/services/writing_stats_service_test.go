@@ -0,0 +1,112 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestWritingStatsService creates a writing stats service on an in-memory database, for a user in UTC
+func setupTestWritingStatsService(t *testing.T) *writingStatsServiceImpl {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.UserPreferences{}, &models.WritingActivity{}, &models.WritingMilestone{}),
+		"Failed to migrate test database")
+	require.NoError(t, db.Create(&models.UserPreferences{ClerkUserID: "user_1", Timezone: "UTC"}).Error)
+
+	return &writingStatsServiceImpl{
+		db:  db,
+		now: func() time.Time { return time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+func writingContent(t *testing.T, words ...string) string {
+	content, err := utils.MarkdownToTipTap("# " + strings.Join(words, " "))
+	require.NoError(t, err)
+	return content
+}
+
+func TestRecordWriting(t *testing.T) {
+	service := setupTestWritingStatsService(t)
+	ctx := context.Background()
+	orgID := "org_1"
+
+	first := writingContent(t, "Release", "notes")
+	service.RecordWriting("user_1", nil, "", first, true)
+	service.RecordWriting("user_1", nil, first, writingContent(t, "Release", "notes", "for", "March"), false)
+	service.RecordWriting("user_1", nil, first, writingContent(t, "Release"), false)
+	service.RecordWriting("user_1", nil, "", "", false)
+	service.RecordWriting("user_1", &orgID, "", first, true)
+
+	stats, err := service.GetStats(ctx, WritingStatsScope{ClerkUserID: "user_1"}, "7d")
+	require.NoError(t, err)
+	require.Len(t, stats.Days, 7)
+	today := stats.Days[6]
+	assert.Equal(t, "2026-03-10", today.Day)
+	assert.Equal(t, 4, today.WordsWritten, "Deleting words doesn't count against words written")
+	assert.Equal(t, 1, today.NotesCreated)
+	assert.Equal(t, 4, today.Edits, "Edits the server can't read still count")
+	assert.Equal(t, 1, stats.ActiveDays)
+	assert.Equal(t, 1, stats.CurrentStreak)
+
+	orgStats, err := service.GetStats(ctx, WritingStatsScope{ClerkUserID: "user_1", OrganizationID: &orgID}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "30d", orgStats.Range)
+	assert.Len(t, orgStats.Days, 30)
+	assert.Equal(t, 2, orgStats.TotalWords, "Organization writing is counted apart from personal writing")
+
+	_, err = service.GetStats(ctx, WritingStatsScope{ClerkUserID: "user_1"}, "forever")
+	assert.ErrorIs(t, err, ErrInvalidWritingStatsRange)
+}
+
+func TestRecordWriting_StreakMilestones(t *testing.T) {
+	service := setupTestWritingStatsService(t)
+	ctx := context.Background()
+	start := service.now()
+
+	for i := 0; i < 7; i++ {
+		service.now = func() time.Time { return start.AddDate(0, 0, i) }
+		service.RecordWriting("user_1", nil, "", "", false)
+		service.RecordWriting("user_1", nil, "", "", false)
+	}
+
+	stats, err := service.GetStats(ctx, WritingStatsScope{ClerkUserID: "user_1"}, "30d")
+	require.NoError(t, err)
+	assert.Equal(t, 7, stats.CurrentStreak)
+	assert.Equal(t, 7, stats.LongestStreak)
+	require.Len(t, stats.Milestones, 2)
+	assert.Equal(t, 3, stats.Milestones[0].Value)
+	assert.Equal(t, "2026-03-12", stats.Milestones[0].Day)
+	assert.Equal(t, 7, stats.Milestones[1].Value)
+
+	// A day without writing keeps the streak until the end of the next day
+	service.now = func() time.Time { return start.AddDate(0, 0, 7) }
+	stats, err = service.GetStats(ctx, WritingStatsScope{ClerkUserID: "user_1"}, "7d")
+	require.NoError(t, err)
+	assert.Equal(t, 7, stats.CurrentStreak)
+
+	service.now = func() time.Time { return start.AddDate(0, 0, 8) }
+	service.RecordWriting("user_1", nil, "", "", false)
+	stats, err = service.GetStats(ctx, WritingStatsScope{ClerkUserID: "user_1"}, "7d")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.CurrentStreak)
+	assert.Equal(t, 7, stats.LongestStreak)
+}
+
+func TestWritingStreaks(t *testing.T) {
+	current, longest := writingStreaks([]string{"2026-02-27", "2026-02-28", "2026-03-01", "2026-03-05", "2026-03-06"}, "2026-03-07")
+	assert.Equal(t, 2, current)
+	assert.Equal(t, 3, longest, "Streaks run across month ends")
+
+	current, longest = writingStreaks(nil, "2026-03-07")
+	assert.Zero(t, current)
+	assert.Zero(t, longest)
+}
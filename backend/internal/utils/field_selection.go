@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSelectedFields caps the fields a client can ask for
+const maxSelectedFields = 100
+
+// FieldSelection is the JSON fields a client asked for with ?fields=, as dotted paths like
+// "id,name,tasks.title,note.chapter.name". Selecting a field keeps everything under it, and a path
+// through an array applies to each of its elements. An empty selection keeps every field.
+type FieldSelection []string
+
+// ParseFieldSelection reads a comma-separated ?fields= value, ignoring empty and repeated paths
+func ParseFieldSelection(raw string) FieldSelection {
+	var fields FieldSelection
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.Trim(strings.TrimSpace(field), ".")
+		if field == "" || len(fields) >= maxSelectedFields {
+			continue
+		}
+		duplicate := false
+		for _, existing := range fields {
+			if existing == field {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Includes reports whether any part of the value at a path is selected, so handlers can skip loading
+// what the client didn't ask for
+func (f FieldSelection) Includes(path string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, field := range f {
+		if field == path || strings.HasPrefix(path, field+".") || strings.HasPrefix(field, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply returns the JSON encoding of value with only the selected fields. Fields that don't exist are
+// ignored.
+func (f FieldSelection) Apply(value interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil || len(f) == 0 {
+		return encoded, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(pruneFields(decoded, f.tree()))
+}
+
+// fieldTree is a selection as nested field names. A nil subtree keeps the whole value.
+type fieldTree map[string]fieldTree
+
+func (f FieldSelection) tree() fieldTree {
+	root := fieldTree{}
+	for _, field := range f {
+		node := root
+		parts := strings.Split(field, ".")
+		for i, part := range parts {
+			child, exists := node[part]
+			if exists && child == nil {
+				// A parent is already selected whole
+				break
+			}
+			if i == len(parts)-1 {
+				node[part] = nil
+				break
+			}
+			if !exists {
+				child = fieldTree{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+// pruneFields keeps the fields of a decoded JSON value that are in the tree
+func pruneFields(value interface{}, tree fieldTree) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		pruned := make(map[string]interface{}, len(tree))
+		for name, subtree := range tree {
+			child, ok := v[name]
+			if !ok {
+				continue
+			}
+			if subtree == nil {
+				pruned[name] = child
+			} else {
+				pruned[name] = pruneFields(child, subtree)
+			}
+		}
+		return pruned
+	case []interface{}:
+		pruned := make([]interface{}, len(v))
+		for i, element := range v {
+			pruned[i] = pruneFields(element, tree)
+		}
+		return pruned
+	default:
+		return value
+	}
+}
+
+// SendSelectedFields sends value as JSON with only the fields the request's ?fields= asks for
+func SendSelectedFields(c *gin.Context, status int, value interface{}) {
+	fields := ParseFieldSelection(c.Query("fields"))
+	if len(fields) == 0 {
+		c.JSON(status, value)
+		return
+	}
+	encoded, err := fields.Apply(value)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", encoded)
+}
@@ -0,0 +1,577 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MathKind is the kind of a node of a parsed formula
+type MathKind string
+
+// Kinds of formula nodes, after the MathML elements they become
+const (
+	MathRow        MathKind = "row"        // Children in sequence
+	MathIdentifier MathKind = "identifier" // Variables, italic unless they have a variant, and function names
+	MathNumber     MathKind = "number"
+	MathOperator   MathKind = "operator"
+	MathText       MathKind = "text"     // Text written with \text
+	MathFraction   MathKind = "fraction" // Children are the numerator and the denominator
+	MathRoot       MathKind = "root"     // Children are the radicand and, for nth roots, the index
+	MathScripts    MathKind = "scripts"  // Children[0] is the base of Sub and Sup
+	MathFenced     MathKind = "fenced"   // Children are set between the Open and Close delimiters
+	MathAccent     MathKind = "accent"   // Text is the accent over the base in Children[0]
+	MathMatrix     MathKind = "matrix"   // Rows of cells, between the Open and Close delimiters
+	MathSpace      MathKind = "space"    // Width em wide
+	MathError      MathKind = "error"    // A command that isn't supported, shown as written
+)
+
+// MathNode is a node of a formula parsed from LaTeX
+type MathNode struct {
+	Kind        MathKind
+	Text        string
+	Variant     string // MathML mathvariant of identifiers and numbers, such as "bold" or "double-struck"
+	Large       bool   // Big operators such as ∑ and ∫
+	NoBar       bool   // Fractions without a bar, for binomial coefficients
+	Children    []MathNode
+	Sub, Sup    *MathNode
+	Open, Close string
+	Rows        [][]MathNode // Matrix cells, each a row node
+	Left        bool         // Whether matrix columns are aligned left, as in cases
+	Width       float64
+}
+
+const (
+	// maxLaTeXDepth bounds the nesting of groups so formulas can't exhaust the stack
+	maxLaTeXDepth = 50
+	maxLaTeXRunes = 10000
+)
+
+// latexGreek are the Greek letters. Capitals are upright, lowercase letters italic like other variables.
+var latexGreek = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ", "varepsilon": "ε", "zeta": "ζ",
+	"eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν",
+	"xi": "ξ", "omicron": "ο", "pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ", "varsigma": "ς",
+	"tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π", "Sigma": "Σ",
+	"Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+}
+
+// latexLetterlike are symbols set like variables
+var latexLetterlike = map[string]string{
+	"infty": "∞", "partial": "∂", "nabla": "∇", "hbar": "ℏ", "ell": "ℓ", "aleph": "ℵ", "Re": "ℜ", "Im": "ℑ",
+	"wp": "℘", "emptyset": "∅", "varnothing": "∅", "prime": "′", "angle": "∠", "degree": "°",
+}
+
+// latexOperators are the binary operators, relations, arrows and punctuation written as commands
+var latexOperators = map[string]string{
+	"times": "×", "cdot": "⋅", "pm": "±", "mp": "∓", "div": "÷", "ast": "∗", "star": "⋆", "circ": "∘",
+	"bullet": "•", "oplus": "⊕", "otimes": "⊗", "cup": "∪", "cap": "∩", "setminus": "∖", "wedge": "∧",
+	"land": "∧", "vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠", "approx": "≈", "equiv": "≡",
+	"sim": "∼", "simeq": "≃", "cong": "≅", "propto": "∝", "ll": "≪", "gg": "≫", "in": "∈", "notin": "∉",
+	"ni": "∋", "subset": "⊂", "subseteq": "⊆", "supset": "⊃", "supseteq": "⊇", "perp": "⊥", "parallel": "∥",
+	"mid": "∣", "forall": "∀", "exists": "∃", "therefore": "∴", "because": "∵",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔", "Rightarrow": "⇒",
+	"Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⇒", "iff": "⇔", "mapsto": "↦", "uparrow": "↑",
+	"downarrow": "↓", "ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱", "colon": ":",
+	"vert": "|", "Vert": "‖", "lvert": "|", "rvert": "|", "lVert": "‖", "rVert": "‖", "langle": "⟨",
+	"rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋", "lceil": "⌈", "rceil": "⌉", "bmod": "mod",
+}
+
+// latexLargeOperators are the operators drawn larger than the text around them
+var latexLargeOperators = map[string]string{
+	"sum": "∑", "prod": "∏", "coprod": "∐", "int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+	"bigcup": "⋃", "bigcap": "⋂", "bigoplus": "⨁", "bigotimes": "⨂", "bigvee": "⋁", "bigwedge": "⋀",
+}
+
+// latexFunctions are the function names set upright
+var latexFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true, "arcsin": true, "arccos": true,
+	"arctan": true, "sinh": true, "cosh": true, "tanh": true, "log": true, "ln": true, "lg": true, "exp": true,
+	"lim": true, "limsup": true, "liminf": true, "max": true, "min": true, "sup": true, "inf": true, "det": true,
+	"gcd": true, "deg": true, "dim": true, "ker": true, "arg": true, "Pr": true, "hom": true,
+}
+
+// latexSpaces are the widths of spacing commands in em
+var latexSpaces = map[string]float64{
+	",": 3.0 / 18, "thinspace": 3.0 / 18, ":": 4.0 / 18, ">": 4.0 / 18, "medspace": 4.0 / 18, ";": 5.0 / 18,
+	"thickspace": 5.0 / 18, "!": -3.0 / 18, " ": 0.25, "quad": 1, "qquad": 2,
+}
+
+// latexAccents are the accents set over their argument
+var latexAccents = map[string]string{
+	"hat": "^", "widehat": "^", "bar": "¯", "overline": "¯", "vec": "→", "overrightarrow": "→",
+	"dot": "˙", "ddot": "¨", "tilde": "~", "widetilde": "~",
+}
+
+// latexVariants are the font commands and the mathvariant they give identifiers
+var latexVariants = map[string]string{
+	"mathrm": "normal", "mathbf": "bold", "mathit": "italic", "mathbb": "double-struck", "mathcal": "script",
+	"mathscr": "script", "mathfrak": "fraktur", "mathsf": "sans-serif", "mathtt": "monospace",
+	"boldsymbol": "bold-italic", "bm": "bold-italic",
+}
+
+// latexEnvironments are the matrix environments and their delimiters
+var latexEnvironments = map[string][2]string{
+	"matrix": {"", ""}, "smallmatrix": {"", ""}, "pmatrix": {"(", ")"}, "bmatrix": {"[", "]"},
+	"Bmatrix": {"{", "}"}, "vmatrix": {"|", "|"}, "Vmatrix": {"‖", "‖"}, "cases": {"{", ""},
+	"aligned": {"", ""}, "align": {"", ""}, "align*": {"", ""}, "gathered": {"", ""}, "array": {"", ""},
+}
+
+// latexIgnored are the style and sizing commands that don't change how a formula is rendered here
+var latexIgnored = map[string]bool{
+	"displaystyle": true, "textstyle": true, "scriptstyle": true, "scriptscriptstyle": true, "limits": true,
+	"nolimits": true, "big": true, "Big": true, "bigg": true, "Bigg": true, "bigl": true, "bigr": true,
+	"Bigl": true, "Bigr": true, "biggl": true, "biggr": true, "Biggl": true, "Biggr": true, "left": true,
+	"right": true, "middle": true, "underline": true, "nonumber": true, "notag": true,
+}
+
+// ParseLaTeX parses a TeX formula as written in math nodes into a row of nodes. Parsing doesn't fail:
+// commands that aren't supported become error nodes and unbalanced braces are closed.
+func ParseLaTeX(latex string) MathNode {
+	src := []rune(latex)
+	if len(src) > maxLaTeXRunes {
+		src = src[:maxLaTeXRunes]
+	}
+	p := &latexParser{src: src}
+	row := p.row(false)
+	for p.pos < len(p.src) {
+		// A stray closing brace or \right ends the row early, the rest follows it
+		p.skipStray()
+		row.Children = append(row.Children, p.row(false).Children...)
+	}
+	return row
+}
+
+// skipStray skips a closing brace or a \right without a matching opening
+func (p *latexParser) skipStray() {
+	if p.peekCommand() == "right" {
+		p.pos += len(`\right`)
+		p.delimiter()
+		return
+	}
+	p.pos++
+}
+
+// latexParser reads a formula. variant is the font of the command being parsed, if any.
+type latexParser struct {
+	src     []rune
+	pos     int
+	depth   int
+	variant string
+}
+
+// row parses nodes up to the end of the group. In matrices rows also end at & and \\.
+func (p *latexParser) row(inMatrix bool) MathNode {
+	row := MathNode{Kind: MathRow}
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '}':
+			return row
+		case inMatrix && (c == '&' || p.peekCommand() == "\\" || p.peekCommand() == "end"):
+			return row
+		case p.peekCommand() == "right":
+			return row
+		case unicode.IsSpace(c):
+			p.pos++
+		case c == '^' || c == '_':
+			p.pos++
+			script := p.argument()
+			if len(row.Children) == 0 {
+				row.Children = append(row.Children, MathNode{Kind: MathRow})
+			}
+			last := &row.Children[len(row.Children)-1]
+			if last.Kind != MathScripts || (c == '^' && last.Sup != nil) || (c == '_' && last.Sub != nil) {
+				*last = MathNode{Kind: MathScripts, Children: []MathNode{*last}}
+			}
+			if c == '^' {
+				last.Sup = &script
+			} else {
+				last.Sub = &script
+			}
+		case c == '\'':
+			// Primes are superscripts of what they follow
+			p.pos++
+			prime := MathNode{Kind: MathOperator, Text: "′"}
+			if n := len(row.Children); n > 0 && row.Children[n-1].Kind != MathScripts {
+				row.Children[n-1] = MathNode{Kind: MathScripts, Children: []MathNode{row.Children[n-1]}, Sup: &prime}
+			} else {
+				row.Children = append(row.Children, prime)
+			}
+		default:
+			if node, ok := p.atom(); ok {
+				row.Children = append(row.Children, node)
+			}
+		}
+	}
+	return row
+}
+
+// atom parses one node: a group, a command, a number or a character
+func (p *latexParser) atom() (MathNode, bool) {
+	c := p.src[p.pos]
+	switch {
+	case c == '{':
+		return p.group(), true
+	case c == '\\':
+		return p.command()
+	case c == '&':
+		p.pos++
+		return MathNode{}, false
+	case c == '~':
+		p.pos++
+		return MathNode{Kind: MathSpace, Width: 0.25}, true
+	case unicode.IsDigit(c) || c == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1]):
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) ||
+			p.src[p.pos] == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1])) {
+			p.pos++
+		}
+		return MathNode{Kind: MathNumber, Text: string(p.src[start:p.pos]), Variant: p.numberVariant()}, true
+	case unicode.IsLetter(c):
+		p.pos++
+		return MathNode{Kind: MathIdentifier, Text: string(c), Variant: p.variant}, true
+	case c == '-':
+		p.pos++
+		return MathNode{Kind: MathOperator, Text: "−"}, true
+	case c == '*':
+		p.pos++
+		return MathNode{Kind: MathOperator, Text: "∗"}, true
+	}
+	p.pos++
+	return MathNode{Kind: MathOperator, Text: string(c)}, true
+}
+
+// group parses a braced group into a row
+func (p *latexParser) group() MathNode {
+	p.pos++ // {
+	if p.depth >= maxLaTeXDepth {
+		p.skipGroup()
+		return MathNode{Kind: MathError, Text: "…"}
+	}
+	p.depth++
+	row := p.row(false)
+	p.depth--
+	if p.pos < len(p.src) && p.src[p.pos] == '}' {
+		p.pos++
+	}
+	return row
+}
+
+// skipGroup skips to the end of a group too deeply nested to parse
+func (p *latexParser) skipGroup() {
+	for open := 1; p.pos < len(p.src) && open > 0; p.pos++ {
+		switch p.src[p.pos] {
+		case '{':
+			open++
+		case '}':
+			open--
+		case '\\':
+			p.pos++
+		}
+	}
+}
+
+// argument parses the argument of a command or script: a group, or a single token
+func (p *latexParser) argument() MathNode {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] == '}' || p.src[p.pos] == '&' {
+		return MathNode{Kind: MathRow}
+	}
+	if p.src[p.pos] == '{' {
+		return p.group()
+	}
+	if p.src[p.pos] == '\\' {
+		if node, ok := p.command(); ok {
+			return node
+		}
+		return MathNode{Kind: MathRow}
+	}
+	// A bare argument is a single character, so x^10 is x to the 1, then 0
+	c := p.src[p.pos]
+	p.pos++
+	switch {
+	case unicode.IsDigit(c):
+		return MathNode{Kind: MathNumber, Text: string(c), Variant: p.numberVariant()}
+	case unicode.IsLetter(c):
+		return MathNode{Kind: MathIdentifier, Text: string(c), Variant: p.variant}
+	case c == '-':
+		return MathNode{Kind: MathOperator, Text: "−"}
+	}
+	return MathNode{Kind: MathOperator, Text: string(c)}
+}
+
+// rawArgument returns the text of a braced argument as written, for \text and environment names
+func (p *latexParser) rawArgument() string {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		return ""
+	}
+	p.pos++
+	var text strings.Builder
+	for open := 1; p.pos < len(p.src); p.pos++ {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src) && strings.ContainsRune(`{}\$%&#_ `, p.src[p.pos+1]):
+			p.pos++
+			c = p.src[p.pos]
+		case c == '{':
+			open++
+		case c == '}':
+			if open--; open == 0 {
+				p.pos++
+				return text.String()
+			}
+		}
+		text.WriteRune(c)
+	}
+	return text.String()
+}
+
+// peekCommand returns the name of the command at the cursor, or "" when there's none
+func (p *latexParser) peekCommand() string {
+	if p.pos >= len(p.src) || p.src[p.pos] != '\\' {
+		return ""
+	}
+	end := p.pos + 1
+	if end >= len(p.src) {
+		return ""
+	}
+	if !unicode.IsLetter(p.src[end]) {
+		return string(p.src[end])
+	}
+	for end < len(p.src) && unicode.IsLetter(p.src[end]) {
+		end++
+	}
+	if end < len(p.src) && p.src[end] == '*' {
+		end++
+	}
+	return string(p.src[p.pos+1 : end])
+}
+
+// command parses a command and its arguments
+func (p *latexParser) command() (MathNode, bool) {
+	name := p.peekCommand()
+	if name == "" {
+		p.pos++
+		return MathNode{}, false
+	}
+	p.pos += 1 + len([]rune(name))
+	// Starred environments are only known as environment names
+	if strings.HasSuffix(name, "*") && len(name) > 1 {
+		p.pos--
+		name = strings.TrimSuffix(name, "*")
+	}
+
+	if symbol, ok := latexGreek[name]; ok {
+		variant := p.variant
+		if variant == "" && unicode.IsUpper([]rune(symbol)[0]) {
+			variant = "normal"
+		}
+		return MathNode{Kind: MathIdentifier, Text: symbol, Variant: variant}, true
+	}
+	if symbol, ok := latexLetterlike[name]; ok {
+		return MathNode{Kind: MathIdentifier, Text: symbol, Variant: "normal"}, true
+	}
+	if symbol, ok := latexOperators[name]; ok {
+		return MathNode{Kind: MathOperator, Text: symbol}, true
+	}
+	if symbol, ok := latexLargeOperators[name]; ok {
+		return MathNode{Kind: MathOperator, Text: symbol, Large: true}, true
+	}
+	if latexFunctions[name] {
+		return MathNode{Kind: MathIdentifier, Text: name, Variant: "normal"}, true
+	}
+	if width, ok := latexSpaces[name]; ok {
+		return MathNode{Kind: MathSpace, Width: width}, true
+	}
+	if accent, ok := latexAccents[name]; ok {
+		base := p.argument()
+		return MathNode{Kind: MathAccent, Text: accent, Children: []MathNode{base}}, true
+	}
+	if variant, ok := latexVariants[name]; ok {
+		outer := p.variant
+		p.variant = variant
+		arg := p.argument()
+		p.variant = outer
+		return arg, true
+	}
+
+	switch name {
+	case "{", "}", "|", "%", "$", "#", "&", "_":
+		symbol := name
+		if name == "|" {
+			symbol = "‖"
+		}
+		return MathNode{Kind: MathOperator, Text: symbol}, true
+	case "\\":
+		// Line breaks outside matrices don't break inline formulas
+		return MathNode{}, false
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num := p.argument()
+		den := p.argument()
+		return MathNode{Kind: MathFraction, Children: []MathNode{num, den}}, true
+	case "binom", "dbinom", "tbinom":
+		top := p.argument()
+		bottom := p.argument()
+		fraction := MathNode{Kind: MathFraction, NoBar: true, Children: []MathNode{top, bottom}}
+		return MathNode{Kind: MathFenced, Open: "(", Close: ")", Children: []MathNode{fraction}}, true
+	case "sqrt":
+		var index *MathNode
+		if p.pos < len(p.src) && p.src[p.pos] == '[' {
+			p.pos++
+			start := p.pos
+			for p.pos < len(p.src) && p.src[p.pos] != ']' {
+				p.pos++
+			}
+			parsed := ParseLaTeX(string(p.src[start:p.pos]))
+			index = &parsed
+			if p.pos < len(p.src) {
+				p.pos++
+			}
+		}
+		root := MathNode{Kind: MathRoot, Children: []MathNode{p.argument()}}
+		if index != nil {
+			root.Children = append(root.Children, *index)
+		}
+		return root, true
+	case "text", "textrm", "textit", "textbf", "textnormal", "mbox":
+		return MathNode{Kind: MathText, Text: p.rawArgument()}, true
+	case "operatorname":
+		return MathNode{Kind: MathIdentifier, Text: strings.TrimSpace(p.rawArgument()), Variant: "normal"}, true
+	case "pmod":
+		arg := p.argument()
+		body := MathNode{Kind: MathRow, Children: []MathNode{
+			{Kind: MathIdentifier, Text: "mod", Variant: "normal"}, {Kind: MathSpace, Width: 4.0 / 18}, arg,
+		}}
+		return MathNode{Kind: MathFenced, Open: "(", Close: ")", Children: []MathNode{body}}, true
+	case "not":
+		next := p.argument()
+		switch next.Text {
+		case "=":
+			return MathNode{Kind: MathOperator, Text: "≠"}, true
+		case "∈":
+			return MathNode{Kind: MathOperator, Text: "∉"}, true
+		}
+		if next.Kind == MathOperator || next.Kind == MathIdentifier {
+			next.Text += "\u0338" // A combining slash
+		}
+		return next, true
+	case "left":
+		return p.fenced(), true
+	case "begin":
+		return p.environment(), true
+	}
+	if latexIgnored[name] {
+		return MathNode{}, false
+	}
+	return MathNode{Kind: MathError, Text: "\\" + name}, true
+}
+
+// fenced parses \left( ... \right) into the body between its delimiters
+func (p *latexParser) fenced() MathNode {
+	node := MathNode{Kind: MathFenced, Open: p.delimiter()}
+	if p.depth >= maxLaTeXDepth {
+		return MathNode{Kind: MathError, Text: "…"}
+	}
+	p.depth++
+	body := p.row(false)
+	p.depth--
+	node.Children = []MathNode{body}
+	if p.peekCommand() == "right" {
+		p.pos += len(`\right`)
+		node.Close = p.delimiter()
+	}
+	return node
+}
+
+// delimiter reads the delimiter after \left or \right. A period is an invisible delimiter.
+func (p *latexParser) delimiter() string {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	if p.src[p.pos] == '\\' {
+		name := p.peekCommand()
+		p.pos += 1 + len([]rune(name))
+		switch name {
+		case "{", "}":
+			return name
+		case "|":
+			return "‖"
+		}
+		return latexOperators[name]
+	}
+	c := p.src[p.pos]
+	p.pos++
+	if c == '.' {
+		return ""
+	}
+	return string(c)
+}
+
+// environment parses \begin{matrix} ... \end{matrix} and the other matrix environments
+func (p *latexParser) environment() MathNode {
+	name := p.rawArgument()
+	fences, ok := latexEnvironments[name]
+	if !ok {
+		return MathNode{Kind: MathError, Text: `\begin{` + name + `}`}
+	}
+	if name == "array" {
+		p.rawArgument() // Column alignment
+	}
+	if p.depth >= maxLaTeXDepth {
+		return MathNode{Kind: MathError, Text: "…"}
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+
+	matrix := MathNode{Kind: MathMatrix, Open: fences[0], Close: fences[1], Left: name == "cases"}
+	var cells []MathNode
+	for {
+		cells = append(cells, p.row(true))
+		command := p.peekCommand()
+		switch {
+		case p.pos >= len(p.src):
+			matrix.Rows = appendMatrixRow(matrix.Rows, cells)
+			return matrix
+		case p.src[p.pos] == '&':
+			p.pos++
+		case command == "\\":
+			p.pos += 2
+			matrix.Rows = appendMatrixRow(matrix.Rows, cells)
+			cells = nil
+		case command == "end":
+			p.pos += len(`\end`)
+			p.rawArgument()
+			matrix.Rows = appendMatrixRow(matrix.Rows, cells)
+			return matrix
+		default:
+			// A stray closing brace or \right inside the matrix
+			p.skipStray()
+		}
+	}
+}
+
+// appendMatrixRow adds a row of cells to a matrix, dropping the empty row after a trailing \\
+func appendMatrixRow(rows [][]MathNode, cells []MathNode) [][]MathNode {
+	if len(cells) == 1 && len(cells[0].Children) == 0 {
+		return rows
+	}
+	return append(rows, cells)
+}
+
+// numberVariant is the variant of numbers: only the bold fonts change them
+func (p *latexParser) numberVariant() string {
+	if strings.HasPrefix(p.variant, "bold") || p.variant == "double-struck" {
+		return p.variant
+	}
+	return ""
+}
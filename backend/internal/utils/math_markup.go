@@ -0,0 +1,337 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// MathNodeType is the type of the inline TipTap nodes holding a LaTeX formula in their latex attribute
+const MathNodeType = "math"
+
+// MathLaTeX returns the formula of a math node
+func MathLaTeX(node TipTapNode) string {
+	latex, _ := node.Attrs["latex"].(string)
+	return strings.TrimSpace(latex)
+}
+
+// RenderTipTapMath sets the mathml attribute of the math nodes of TipTap content to their formula rendered
+// as MathML, so pages can show formulas without running KaTeX. Content that isn't a TipTap document or has
+// no formulas is returned unchanged.
+func RenderTipTapMath(content string) string {
+	if !strings.Contains(content, `"`+MathNodeType+`"`) {
+		return content
+	}
+	var doc TipTapDoc
+	if err := json.Unmarshal([]byte(content), &doc); err != nil || doc.Type != "doc" {
+		return content
+	}
+	doc.Content = renderMathNodes(doc.Content)
+	rendered, err := json.Marshal(doc)
+	if err != nil {
+		return content
+	}
+	return string(rendered)
+}
+
+// renderMathNodes sets the mathml attribute of the math nodes among nodes
+func renderMathNodes(nodes []TipTapNode) []TipTapNode {
+	for i, node := range nodes {
+		if node.Type != MathNodeType {
+			if len(node.Content) > 0 {
+				nodes[i].Content = renderMathNodes(node.Content)
+			}
+			continue
+		}
+		attrs := make(map[string]interface{}, len(node.Attrs)+1)
+		for key, value := range node.Attrs {
+			attrs[key] = value
+		}
+		attrs["mathml"] = LaTeXToMathML(MathLaTeX(node))
+		nodes[i].Attrs = attrs
+	}
+	return nodes
+}
+
+// LaTeXToMathML renders a formula as an inline MathML element, keeping the LaTeX as an annotation
+func LaTeXToMathML(latex string) string {
+	var b strings.Builder
+	b.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"><semantics>`)
+	writeMathML(&b, ParseLaTeX(latex))
+	fmt.Fprintf(&b, `<annotation encoding="application/x-tex">%s</annotation></semantics></math>`, html.EscapeString(latex))
+	return b.String()
+}
+
+// writeMathML writes a formula node as MathML
+func writeMathML(b *strings.Builder, node MathNode) {
+	switch node.Kind {
+	case MathRow:
+		b.WriteString("<mrow>")
+		for _, child := range node.Children {
+			writeMathML(b, child)
+		}
+		b.WriteString("</mrow>")
+
+	case MathIdentifier, MathNumber:
+		tag := "mi"
+		if node.Kind == MathNumber {
+			tag = "mn"
+		}
+		b.WriteString("<" + tag)
+		if node.Variant != "" {
+			fmt.Fprintf(b, ` mathvariant="%s"`, node.Variant)
+		}
+		fmt.Fprintf(b, ">%s</%s>", html.EscapeString(node.Text), tag)
+
+	case MathOperator:
+		if node.Large {
+			fmt.Fprintf(b, `<mo largeop="true">%s</mo>`, html.EscapeString(node.Text))
+		} else {
+			fmt.Fprintf(b, "<mo>%s</mo>", html.EscapeString(node.Text))
+		}
+
+	case MathText:
+		fmt.Fprintf(b, "<mtext>%s</mtext>", html.EscapeString(node.Text))
+
+	case MathFraction:
+		if node.NoBar {
+			b.WriteString(`<mfrac linethickness="0">`)
+		} else {
+			b.WriteString("<mfrac>")
+		}
+		writeMathML(b, node.Children[0])
+		writeMathML(b, node.Children[1])
+		b.WriteString("</mfrac>")
+
+	case MathRoot:
+		if len(node.Children) == 1 {
+			b.WriteString("<msqrt>")
+			writeMathML(b, node.Children[0])
+			b.WriteString("</msqrt>")
+			return
+		}
+		b.WriteString("<mroot>")
+		writeMathML(b, node.Children[0])
+		writeMathML(b, node.Children[1])
+		b.WriteString("</mroot>")
+
+	case MathScripts:
+		tag := "msubsup"
+		switch {
+		case node.Sup == nil:
+			tag = "msub"
+		case node.Sub == nil:
+			tag = "msup"
+		}
+		b.WriteString("<" + tag + ">")
+		writeMathML(b, node.Children[0])
+		if node.Sub != nil {
+			writeMathML(b, *node.Sub)
+		}
+		if node.Sup != nil {
+			writeMathML(b, *node.Sup)
+		}
+		b.WriteString("</" + tag + ">")
+
+	case MathFenced:
+		b.WriteString("<mrow>")
+		writeMathMLFence(b, node.Open)
+		for _, child := range node.Children {
+			writeMathML(b, child)
+		}
+		writeMathMLFence(b, node.Close)
+		b.WriteString("</mrow>")
+
+	case MathAccent:
+		b.WriteString(`<mover accent="true">`)
+		writeMathML(b, node.Children[0])
+		fmt.Fprintf(b, "<mo>%s</mo></mover>", html.EscapeString(node.Text))
+
+	case MathMatrix:
+		b.WriteString("<mrow>")
+		writeMathMLFence(b, node.Open)
+		if node.Left {
+			b.WriteString(`<mtable columnalign="left">`)
+		} else {
+			b.WriteString("<mtable>")
+		}
+		for _, row := range node.Rows {
+			b.WriteString("<mtr>")
+			for _, cell := range row {
+				b.WriteString("<mtd>")
+				writeMathML(b, cell)
+				b.WriteString("</mtd>")
+			}
+			b.WriteString("</mtr>")
+		}
+		b.WriteString("</mtable>")
+		writeMathMLFence(b, node.Close)
+		b.WriteString("</mrow>")
+
+	case MathSpace:
+		fmt.Fprintf(b, `<mspace width="%.3gem"/>`, node.Width)
+
+	case MathError:
+		fmt.Fprintf(b, "<merror><mtext>%s</mtext></merror>", html.EscapeString(node.Text))
+	}
+}
+
+// writeMathMLFence writes a delimiter that stretches to the height of what it encloses
+func writeMathMLFence(b *strings.Builder, delimiter string) {
+	if delimiter != "" {
+		fmt.Fprintf(b, `<mo fence="true" stretchy="true">%s</mo>`, html.EscapeString(delimiter))
+	}
+}
+
+// ommlAccents are the combining characters Word draws accents with
+var ommlAccents = map[string]string{
+	"^": "\u0302", "¯": "\u0305", "→": "\u20d7", "˙": "\u0307", "¨": "\u0308", "~": "\u0303",
+}
+
+// ommlStyles are the run properties of mathvariants
+var ommlStyles = map[string]string{
+	"normal":        `<m:sty m:val="p"/>`,
+	"bold":          `<m:sty m:val="b"/>`,
+	"bold-italic":   `<m:sty m:val="bi"/>`,
+	"italic":        `<m:sty m:val="i"/>`,
+	"double-struck": `<m:scr m:val="double-struck"/><m:sty m:val="p"/>`,
+	"script":        `<m:scr m:val="script"/><m:sty m:val="p"/>`,
+	"fraktur":       `<m:scr m:val="fraktur"/><m:sty m:val="p"/>`,
+	"sans-serif":    `<m:scr m:val="sans-serif"/><m:sty m:val="p"/>`,
+	"monospace":     `<m:scr m:val="monospace"/><m:sty m:val="p"/>`,
+}
+
+// LaTeXToOMML renders a formula as an Office Math element, which Word shows as an editable equation
+func LaTeXToOMML(latex string) string {
+	var b strings.Builder
+	b.WriteString("<m:oMath>")
+	writeOMML(&b, ParseLaTeX(latex))
+	b.WriteString("</m:oMath>")
+	return b.String()
+}
+
+// writeOMML writes a formula node as Office Math
+func writeOMML(b *strings.Builder, node MathNode) {
+	switch node.Kind {
+	case MathRow:
+		for _, child := range node.Children {
+			writeOMML(b, child)
+		}
+
+	case MathIdentifier, MathNumber, MathOperator:
+		writeOMMLRun(b, node.Text, ommlStyles[node.Variant])
+
+	case MathText:
+		writeOMMLRun(b, node.Text, "<m:nor/>")
+
+	case MathError:
+		writeOMMLRun(b, node.Text, "<m:nor/>")
+
+	case MathSpace:
+		switch {
+		case node.Width >= 1:
+			writeOMMLRun(b, strings.Repeat("\u2003", int(node.Width)), "") // Em spaces
+		case node.Width > 0:
+			writeOMMLRun(b, "\u2009", "") // A thin space
+		}
+
+	case MathFraction:
+		b.WriteString("<m:f>")
+		if node.NoBar {
+			b.WriteString(`<m:fPr><m:type m:val="noBar"/></m:fPr>`)
+		}
+		writeOMMLArgument(b, "m:num", node.Children[0])
+		writeOMMLArgument(b, "m:den", node.Children[1])
+		b.WriteString("</m:f>")
+
+	case MathRoot:
+		b.WriteString("<m:rad>")
+		if len(node.Children) == 1 {
+			b.WriteString(`<m:radPr><m:degHide m:val="1"/></m:radPr><m:deg/>`)
+		} else {
+			writeOMMLArgument(b, "m:deg", node.Children[1])
+		}
+		writeOMMLArgument(b, "m:e", node.Children[0])
+		b.WriteString("</m:rad>")
+
+	case MathScripts:
+		tag := "m:sSubSup"
+		switch {
+		case node.Sup == nil:
+			tag = "m:sSub"
+		case node.Sub == nil:
+			tag = "m:sSup"
+		}
+		b.WriteString("<" + tag + ">")
+		writeOMMLArgument(b, "m:e", node.Children[0])
+		if node.Sub != nil {
+			writeOMMLArgument(b, "m:sub", *node.Sub)
+		}
+		if node.Sup != nil {
+			writeOMMLArgument(b, "m:sup", *node.Sup)
+		}
+		b.WriteString("</" + tag + ">")
+
+	case MathFenced:
+		writeOMMLDelimiters(b, node.Open, node.Close)
+		for _, child := range node.Children {
+			writeOMMLArgument(b, "m:e", child)
+		}
+		b.WriteString("</m:d>")
+
+	case MathAccent:
+		accent, ok := ommlAccents[node.Text]
+		if !ok {
+			accent = node.Text
+		}
+		fmt.Fprintf(b, `<m:acc><m:accPr><m:chr m:val="%s"/></m:accPr>`, html.EscapeString(accent))
+		writeOMMLArgument(b, "m:e", node.Children[0])
+		b.WriteString("</m:acc>")
+
+	case MathMatrix:
+		fenced := node.Open != "" || node.Close != ""
+		if fenced {
+			writeOMMLDelimiters(b, node.Open, node.Close)
+			b.WriteString("<m:e>")
+		}
+		b.WriteString("<m:m>")
+		if node.Left && len(node.Rows) > 0 {
+			fmt.Fprintf(b, `<m:mPr><m:mcs><m:mc><m:mcPr><m:count m:val="%d"/><m:mcJc m:val="left"/></m:mcPr></m:mc></m:mcs></m:mPr>`,
+				len(node.Rows[0]))
+		}
+		for _, row := range node.Rows {
+			b.WriteString("<m:mr>")
+			for _, cell := range row {
+				writeOMMLArgument(b, "m:e", cell)
+			}
+			b.WriteString("</m:mr>")
+		}
+		b.WriteString("</m:m>")
+		if fenced {
+			b.WriteString("</m:e></m:d>")
+		}
+	}
+}
+
+// writeOMMLArgument writes a node as the argument of an Office Math structure, such as m:num or m:e
+func writeOMMLArgument(b *strings.Builder, tag string, node MathNode) {
+	b.WriteString("<" + tag + ">")
+	writeOMML(b, node)
+	b.WriteString("</" + tag + ">")
+}
+
+// writeOMMLDelimiters opens an m:d element with the given delimiters, which may be empty
+func writeOMMLDelimiters(b *strings.Builder, open, close string) {
+	fmt.Fprintf(b, `<m:d><m:dPr><m:begChr m:val="%s"/><m:endChr m:val="%s"/></m:dPr>`,
+		html.EscapeString(open), html.EscapeString(close))
+}
+
+// writeOMMLRun writes text as a math run with the given run properties
+func writeOMMLRun(b *strings.Builder, text, properties string) {
+	b.WriteString("<m:r>")
+	if properties != "" {
+		b.WriteString("<m:rPr>" + properties + "</m:rPr>")
+	}
+	fmt.Fprintf(b, `<m:t xml:space="preserve">%s</m:t></m:r>`, html.EscapeString(text))
+}
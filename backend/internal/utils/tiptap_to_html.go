@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// TipTapToHTML renders a TipTap document as HTML for email clients, styled inline since they drop
+// stylesheets. Links and images are kept only with http(s) addresses (and mailto for links).
+func TipTapToHTML(doc TipTapDoc) string {
+	var b strings.Builder
+	nodesToHTML(&b, doc.Content)
+	return b.String()
+}
+
+func nodesToHTML(b *strings.Builder, nodes []TipTapNode) {
+	for _, node := range nodes {
+		nodeToHTML(b, node)
+	}
+}
+
+func nodeToHTML(b *strings.Builder, node TipTapNode) {
+	switch node.Type {
+	case "paragraph":
+		b.WriteString(`<p style="margin:0 0 12px">`)
+		nodesToHTML(b, node.Content)
+		b.WriteString("</p>")
+
+	case "heading":
+		level := 1
+		if value, ok := node.Attrs["level"].(float64); ok && value >= 1 && value <= 6 {
+			level = int(value)
+		}
+		fmt.Fprintf(b, `<h%d style="margin:20px 0 8px">`, level)
+		nodesToHTML(b, node.Content)
+		fmt.Fprintf(b, "</h%d>", level)
+
+	case "bulletList":
+		b.WriteString(`<ul style="margin:0 0 12px;padding-left:24px">`)
+		nodesToHTML(b, node.Content)
+		b.WriteString("</ul>")
+
+	case "orderedList":
+		start := 1
+		if value, ok := node.Attrs["start"].(float64); ok {
+			start = int(value)
+		}
+		fmt.Fprintf(b, `<ol start="%d" style="margin:0 0 12px;padding-left:24px">`, start)
+		nodesToHTML(b, node.Content)
+		b.WriteString("</ol>")
+
+	case "taskList":
+		b.WriteString(`<ul style="margin:0 0 12px;padding-left:0;list-style:none">`)
+		nodesToHTML(b, node.Content)
+		b.WriteString("</ul>")
+
+	case "listItem", "taskItem":
+		b.WriteString("<li>")
+		if node.Type == "taskItem" {
+			if checked, _ := node.Attrs["checked"].(bool); checked {
+				b.WriteString("&#9746; ")
+			} else {
+				b.WriteString("&#9744; ")
+			}
+		}
+		// Paragraphs in list items don't get margins of their own
+		for _, child := range node.Content {
+			if child.Type == "paragraph" {
+				nodesToHTML(b, child.Content)
+				continue
+			}
+			nodeToHTML(b, child)
+		}
+		b.WriteString("</li>")
+
+	case "codeBlock":
+		if svg, ok := node.Attrs["svg"].(string); ok && svg != "" {
+			// Set by the diagram renderer, which sanitizes it
+			fmt.Fprintf(b, `<div style="margin:0 0 12px;overflow-x:auto">%s</div>`, svg)
+			return
+		}
+		b.WriteString(`<pre style="margin:0 0 12px;padding:12px;background:#f4f4f5;border-radius:4px;overflow-x:auto"><code>`)
+		for _, child := range node.Content {
+			b.WriteString(html.EscapeString(child.Text))
+		}
+		b.WriteString("</code></pre>")
+
+	case "blockquote":
+		b.WriteString(`<blockquote style="margin:0 0 12px;padding-left:12px;border-left:3px solid #d4d4d8;color:#52525b">`)
+		nodesToHTML(b, node.Content)
+		b.WriteString("</blockquote>")
+
+	case "callout":
+		b.WriteString(`<div style="margin:0 0 12px;padding:12px;background:#f4f4f5;border-radius:4px">`)
+		if title, ok := node.Attrs["title"].(string); ok && title != "" {
+			fmt.Fprintf(b, `<p style="margin:0 0 8px"><strong>%s</strong></p>`, html.EscapeString(title))
+		}
+		nodesToHTML(b, node.Content)
+		b.WriteString("</div>")
+
+	case "horizontalRule":
+		b.WriteString(`<hr style="border:none;border-top:1px solid #e4e4e7;margin:16px 0">`)
+
+	case "table":
+		b.WriteString(`<table style="border-collapse:collapse;margin:0 0 12px">`)
+		nodesToHTML(b, node.Content)
+		b.WriteString("</table>")
+
+	case "tableRow":
+		b.WriteString("<tr>")
+		nodesToHTML(b, node.Content)
+		b.WriteString("</tr>")
+
+	case "tableHeader", "tableCell":
+		tag := "td"
+		if node.Type == "tableHeader" {
+			tag = "th"
+		}
+		fmt.Fprintf(b, `<%s style="border:1px solid #d4d4d8;padding:6px 8px;text-align:left;vertical-align:top">`, tag)
+		for _, child := range node.Content {
+			if child.Type == "paragraph" {
+				nodesToHTML(b, child.Content)
+				continue
+			}
+			nodeToHTML(b, child)
+		}
+		fmt.Fprintf(b, "</%s>", tag)
+
+	case "image":
+		src, _ := node.Attrs["src"].(string)
+		alt, _ := node.Attrs["alt"].(string)
+		if isHTTPURL(src) {
+			fmt.Fprintf(b, `<p style="margin:0 0 12px"><img src="%s" alt="%s" style="max-width:100%%;height:auto"></p>`,
+				html.EscapeString(src), html.EscapeString(alt))
+		}
+
+	case "hardBreak":
+		b.WriteString("<br>")
+
+	case "mention":
+		if label, ok := node.Attrs["label"].(string); ok && label != "" {
+			b.WriteString("@" + html.EscapeString(label))
+		}
+
+	case MathNodeType:
+		if latex := MathLaTeX(node); latex != "" {
+			b.WriteString(LaTeXToMathML(latex))
+		}
+
+	case "text":
+		textToHTML(b, node)
+
+	default:
+		nodesToHTML(b, node.Content)
+	}
+}
+
+// textToHTML writes text wrapped in the tags of its marks
+func textToHTML(b *strings.Builder, node TipTapNode) {
+	var closing []string
+	for _, mark := range node.Marks {
+		switch mark.Type {
+		case "bold":
+			b.WriteString("<strong>")
+			closing = append(closing, "</strong>")
+		case "italic":
+			b.WriteString("<em>")
+			closing = append(closing, "</em>")
+		case "strike":
+			b.WriteString("<s>")
+			closing = append(closing, "</s>")
+		case "code":
+			b.WriteString(`<code style="background:#f4f4f5;padding:1px 4px;border-radius:3px">`)
+			closing = append(closing, "</code>")
+		case "link":
+			href, _ := mark.Attrs["href"].(string)
+			if isHTTPURL(href) || strings.HasPrefix(href, "mailto:") {
+				fmt.Fprintf(b, `<a href="%s">`, html.EscapeString(href))
+				closing = append(closing, "</a>")
+			}
+		}
+	}
+	b.WriteString(html.EscapeString(node.Text))
+	for i := len(closing) - 1; i >= 0; i-- {
+		b.WriteString(closing[i])
+	}
+}
+
+func isHTTPURL(value string) bool {
+	return strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://")
+}
@@ -73,12 +73,21 @@ func nodeToMarkdown(node TipTapNode, depth int) string {
 	case "blockquote":
 		return blockquoteToMarkdown(node, depth)
 
+	case "callout":
+		return calloutToMarkdown(node, depth)
+
 	case "text":
 		return textToMarkdown(node)
 
 	case "hardBreak":
 		return "  \n"
 
+	case MathNodeType:
+		if latex := MathLaTeX(node); latex != "" {
+			return "$" + latex + "$"
+		}
+		return ""
+
 	default:
 		// For unknown types, try to extract text content
 		if len(node.Content) > 0 {
@@ -196,6 +205,18 @@ func blockquoteToMarkdown(node TipTapNode, depth int) string {
 	return result.String()
 }
 
+// calloutToMarkdown renders a callout as a blockquote, led by its title in bold
+func calloutToMarkdown(node TipTapNode, depth int) string {
+	title, _ := node.Attrs["title"].(string)
+	if title == "" {
+		return blockquoteToMarkdown(node, depth)
+	}
+	return blockquoteToMarkdown(TipTapNode{
+		Type:    "blockquote",
+		Content: append([]TipTapNode{{Type: "paragraph", Content: []TipTapNode{{Type: "text", Text: title, Marks: []TipTapMark{{Type: "bold"}}}}}}, node.Content...),
+	}, depth)
+}
+
 func textToMarkdown(node TipTapNode) string {
 	text := node.Text
 
@@ -233,6 +254,7 @@ func shouldAddSpacing(currentType, nextType string) bool {
 		"orderedList":    true,
 		"horizontalRule": true,
 		"blockquote":     true,
+		"callout":        true,
 	}
 
 	return blockElements[currentType] && blockElements[nextType]
@@ -55,6 +55,9 @@ type CommandContext struct {
 
 	// OrganizationID is the organization ID (for organization mode)
 	OrganizationID *string
+
+	// DefaultNotebookID is the notebook the linked group captures notes into (nil to let AI pick one)
+	DefaultNotebookID *string
 }
 
 // CommandRegistry manages registration and lookup of commands
@@ -49,8 +49,8 @@ func (c *AddNoteCommand) RequiresAuth() bool {
 
 // Execute runs the add note command
 func (c *AddNoteCommand) Execute(ctx *whatsapp.CommandContext) error {
-	// Verify organization access if in organization mode
-	if err := whatsapp.VerifyOrganizationAccess(ctx); err != nil {
+	// Verify the user may change organization notes if in organization mode
+	if err := whatsapp.VerifyOrganizationEditAccess(ctx); err != nil {
 		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
 			fmt.Sprintf("❌ %s", err.Error()))
 	}
@@ -435,10 +435,10 @@ func (c *AddNoteCommand) createNote(ctx *whatsapp.CommandContext, contextData ma
 			"❌ Failed to create note. Please try again.")
 	}
 
-	// Create the note
+	// Create the note, starting from the chapter's template and default tags
 	note := models.Notes{
 		Name:      noteTitle,
-		Content:   "", // Empty content initially
+		Content:   services.ApplyChapterNoteDefaults(&chapter, ""),
 		ChapterID: chapterID,
 	}
 
@@ -599,12 +599,20 @@ func (c *AddNoteCommand) handleAIContentResponse(ctx *whatsapp.CommandContext, c
 	}
 
 	if response == "yes" || response == "y" {
+		// The note's chapter sets the tone, length and structure of generated content
+		var chapter models.Chapter
+		if err := ctx.DB.Where("id = (SELECT chapter_id FROM notes WHERE id = ?)", noteID).First(&chapter).Error; err != nil {
+			log.Warn().Err(err).Str("note_id", noteID).Msg("Failed to load chapter settings for AI content")
+		}
+
 		// Generate AI content (returns markdown)
-		markdownContent, err := c.aiService.GenerateNoteContent(context.Background(), services.NoteContentGenerationRequest{
+		generationRequest := services.NoteContentGenerationRequest{
 			NoteTitle: noteTitle,
 			UserID:    ctx.User.ClerkUserID,
 			OrgID:     ctx.OrganizationID,
-		})
+		}
+		services.ApplyChapterGenerationSettings(&chapter, &generationRequest)
+		markdownContent, err := c.aiService.GenerateNoteContent(context.Background(), generationRequest)
 
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to generate AI content")
@@ -621,6 +629,7 @@ func (c *AddNoteCommand) handleAIContentResponse(ctx *whatsapp.CommandContext, c
 				"⚠️ Failed to convert content. Your note has been created but remains empty.")
 			return c.sendSuccessMessage(ctx, noteTitle, notebookName, chapterName, false)
 		}
+		tiptapContent = services.ApplyChapterNoteDefaults(&chapter, tiptapContent)
 
 		// Update note with generated content in TipTap format
 		if err := ctx.DB.Model(&models.Notes{}).Where("id = ?", noteID).Update("content", tiptapContent).Error; err != nil {
@@ -47,8 +47,8 @@ func (c *CreateCommand) RequiresAuth() bool {
 
 // Execute runs the create command
 func (c *CreateCommand) Execute(ctx *whatsapp.CommandContext) error {
-	// Verify organization access if in organization mode
-	if err := whatsapp.VerifyOrganizationAccess(ctx); err != nil {
+	// Verify the user may change organization notes if in organization mode
+	if err := whatsapp.VerifyOrganizationEditAccess(ctx); err != nil {
 		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
 			fmt.Sprintf("❌ %s", err.Error()))
 	}
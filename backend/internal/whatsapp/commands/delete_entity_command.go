@@ -5,6 +5,7 @@ import (
 	"backend/internal/services"
 	"backend/internal/whatsapp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -46,8 +47,8 @@ func (c *DeleteEntityCommand) RequiresAuth() bool {
 
 // Execute runs the delete entity command
 func (c *DeleteEntityCommand) Execute(ctx *whatsapp.CommandContext) error {
-	// Verify organization access if in organization mode
-	if err := whatsapp.VerifyOrganizationAccess(ctx); err != nil {
+	// Verify the user may change organization notes if in organization mode
+	if err := whatsapp.VerifyOrganizationEditAccess(ctx); err != nil {
 		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
 			fmt.Sprintf("❌ %s", err.Error()))
 	}
@@ -395,9 +396,23 @@ func (c *DeleteEntityCommand) handleConfirmation(ctx *whatsapp.CommandContext, c
 
 		var err error
 		if entityType == "notebook" {
-			err = ctx.DB.Delete(&models.Notebook{}, "id = ?", entityID).Error
+			err = services.CheckNotebookLegalHold(ctx.DB, entityID)
 		} else {
-			err = ctx.DB.Delete(&models.Chapter{}, "id = ?", entityID).Error
+			err = services.CheckChapterLegalHold(ctx.DB, entityID)
+		}
+		if errors.Is(err, services.ErrLegalHold) {
+			if err := c.contextService.ClearContext(ctx.PhoneNumber); err != nil {
+				log.Error().Err(err).Msg("Failed to clear context")
+			}
+			return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+				fmt.Sprintf("🔒 This %s is under legal hold and can't be deleted.", entityType))
+		}
+		if err == nil {
+			if entityType == "notebook" {
+				err = ctx.DB.Delete(&models.Notebook{}, "id = ?", entityID).Error
+			} else {
+				err = ctx.DB.Delete(&models.Chapter{}, "id = ?", entityID).Error
+			}
 		}
 
 		if err != nil {
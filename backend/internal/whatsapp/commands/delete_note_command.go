@@ -5,6 +5,7 @@ import (
 	"backend/internal/services"
 	"backend/internal/whatsapp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -46,8 +47,8 @@ func (c *DeleteNoteCommand) RequiresAuth() bool {
 
 // Execute runs the delete note command
 func (c *DeleteNoteCommand) Execute(ctx *whatsapp.CommandContext) error {
-	// Verify organization access if in organization mode
-	if err := whatsapp.VerifyOrganizationAccess(ctx); err != nil {
+	// Verify the user may change organization notes if in organization mode
+	if err := whatsapp.VerifyOrganizationEditAccess(ctx); err != nil {
 		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
 			fmt.Sprintf("❌ %s", err.Error()))
 	}
@@ -274,6 +275,14 @@ func (c *DeleteNoteCommand) handleConfirmation(ctx *whatsapp.CommandContext, con
 		noteID, _ := contextData["note_id"].(string)
 		noteName, _ := contextData["note_name"].(string)
 
+		if errors.Is(services.CheckNoteLegalHold(ctx.DB, noteID), services.ErrLegalHold) {
+			if err := c.contextService.ClearContext(ctx.PhoneNumber); err != nil {
+				log.Error().Err(err).Msg("Failed to clear context")
+			}
+			return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+				"🔒 This note's notebook is under legal hold, so the note can't be deleted.")
+		}
+
 		// Delete the note
 		if err := ctx.DB.Delete(&models.Notes{}, "id = ?", noteID).Error; err != nil {
 			log.Error().Err(err).Str("note_id", noteID).Msg("Failed to delete note")
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/internal/whatsapp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// readLaterListLimit is how many unread items /readlater shows without a link
+const readLaterListLimit = 5
+
+// ReadLaterCommand handles the /readlater command for saving links to the reading list
+type ReadLaterCommand struct {
+	readingListService services.ReadingListService
+}
+
+// NewReadLaterCommand creates a new read later command
+func NewReadLaterCommand() *ReadLaterCommand {
+	return &ReadLaterCommand{
+		readingListService: services.NewReadingListService(),
+	}
+}
+
+// Name returns the command name
+func (c *ReadLaterCommand) Name() string {
+	return "readlater"
+}
+
+// Description returns the command description
+func (c *ReadLaterCommand) Description() string {
+	return "Save a link to your reading list"
+}
+
+// Usage returns usage instructions
+func (c *ReadLaterCommand) Usage() string {
+	return "/readlater [url] - Save a link to your reading list, or show your unread items without a link"
+}
+
+// RequiresAuth returns whether authentication is required
+func (c *ReadLaterCommand) RequiresAuth() bool {
+	return true
+}
+
+// Execute runs the read later command
+func (c *ReadLaterCommand) Execute(ctx *whatsapp.CommandContext) error {
+	if len(ctx.Args) == 0 {
+		return c.listUnread(ctx)
+	}
+
+	item, err := c.readingListService.Save(context.Background(), ctx.User.ClerkUserID,
+		services.ReadingListClip{URL: ctx.Args[0]}, models.ReadingListSourceWhatsApp)
+	if errors.Is(err, services.ErrInvalidReadingListItem) {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"❌ That doesn't look like a link.\n\n*Usage:* /readlater [url]")
+	}
+	if err != nil {
+		log.Error().Err(err).Str("user_id", ctx.User.ClerkUserID).Msg("Failed to save link to reading list")
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"❌ Failed to save the link. Please try again.")
+	}
+
+	return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+		fmt.Sprintf("📚 *Saved to your reading list!*\n\n🔗 %s\n\n_Send /readlater to see what's unread._", item.URL))
+}
+
+// listUnread shows the newest unread items of the reading list
+func (c *ReadLaterCommand) listUnread(ctx *whatsapp.CommandContext) error {
+	items, err := c.readingListService.List(context.Background(), ctx.User.ClerkUserID, "unread")
+	if err != nil {
+		log.Error().Err(err).Str("user_id", ctx.User.ClerkUserID).Msg("Failed to list reading list")
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"❌ An error occurred while loading your reading list. Please try again.")
+	}
+	if len(items) == 0 {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"📭 Your reading list is empty.\n\n*Usage:* /readlater [url]")
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("📚 *Unread (%d)*\n\n", len(items)))
+	for i, item := range items {
+		if i == readLaterListLimit {
+			message.WriteString(fmt.Sprintf("_...and %d more in the app_\n", len(items)-readLaterListLimit))
+			break
+		}
+		message.WriteString(fmt.Sprintf("%d. *%s*", i+1, item.Title))
+		if item.ReadingMinutes > 0 {
+			message.WriteString(fmt.Sprintf(" (%d min)", item.ReadingMinutes))
+		}
+		message.WriteString(fmt.Sprintf("\n%s\n\n", item.URL))
+	}
+
+	return ctx.Client.SendTextMessage(ctx.PhoneNumber, strings.TrimSpace(message.String()))
+}
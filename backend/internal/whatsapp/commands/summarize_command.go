@@ -0,0 +1,389 @@
+package commands
+
+import (
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/internal/utils"
+	"backend/internal/whatsapp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// summaryChunkLength keeps each summary message comfortably under WhatsApp's message length limit
+const summaryChunkLength = 3500
+
+// summariesChapterName is the chapter notebook summaries are saved into
+const summariesChapterName = "Summaries"
+
+// SummarizeCommand handles the /summarize command for summarizing notebooks and chapters with AI
+type SummarizeCommand struct {
+	contextService *services.WhatsAppContextService
+	aiService      *services.AIService
+}
+
+// NewSummarizeCommand creates a new summarize command
+func NewSummarizeCommand(contextService *services.WhatsAppContextService) *SummarizeCommand {
+	return &SummarizeCommand{
+		contextService: contextService,
+		aiService:      services.NewAIService(),
+	}
+}
+
+// Name returns the command name
+func (c *SummarizeCommand) Name() string {
+	return "summarize"
+}
+
+// Description returns the command description
+func (c *SummarizeCommand) Description() string {
+	return "Get an AI summary of a notebook or chapter"
+}
+
+// Usage returns usage instructions
+func (c *SummarizeCommand) Usage() string {
+	return "/summarize [notebook|chapter] [name] - Summarize all notes in a notebook or chapter, with the option to save the summary as a note"
+}
+
+// RequiresAuth returns whether authentication is required
+func (c *SummarizeCommand) RequiresAuth() bool {
+	return true
+}
+
+// Execute runs the summarize command
+func (c *SummarizeCommand) Execute(ctx *whatsapp.CommandContext) error {
+	// Verify organization access if in organization mode
+	if err := whatsapp.VerifyOrganizationAccess(ctx); err != nil {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			fmt.Sprintf("❌ %s", err.Error()))
+	}
+
+	// Check if we have an active context (user is deciding whether to save the summary)
+	if ctx.ConversationCtx != nil && ctx.ConversationCtx.Command == "summarize" {
+		return c.handleSaveReply(ctx)
+	}
+
+	return c.summarize(ctx)
+}
+
+// summarize resolves the target notebook or chapter and sends its summary
+func (c *SummarizeCommand) summarize(ctx *whatsapp.CommandContext) error {
+	if len(ctx.Args) == 0 {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"❌ Please provide a notebook or chapter name.\n\n*Usage:* /summarize [notebook|chapter] [name]")
+	}
+
+	// The entity type is optional; without it notebooks are matched before chapters
+	entityType := ""
+	args := ctx.Args
+	switch strings.ToLower(args[0]) {
+	case "notebook", "chapter":
+		entityType = strings.ToLower(args[0])
+		args = args[1:]
+	}
+
+	name := strings.TrimSpace(strings.Join(args, " "))
+	if name == "" {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"❌ Please provide a name to summarize.\n\n*Usage:* /summarize [notebook|chapter] [name]")
+	}
+
+	if err := utils.ValidateCommandArgument("name", name); err != nil {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			fmt.Sprintf("❌ Invalid name: %s", err.Error()))
+	}
+	name = utils.SanitizeCommandArgument(name)
+
+	if entityType == "" || entityType == "notebook" {
+		notebook, err := c.findNotebook(ctx, name)
+		if err == nil {
+			return c.summarizeNotebook(ctx, notebook)
+		}
+		if entityType == "notebook" {
+			return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+				fmt.Sprintf("❌ No notebook found matching: *%s*\n\nUse /list notebooks to see your notebooks.", name))
+		}
+	}
+
+	chapter, err := c.findChapter(ctx, name)
+	if err != nil {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			fmt.Sprintf("❌ No notebook or chapter found matching: *%s*\n\nUse /list notebooks or /list chapters to see what's available.", name))
+	}
+
+	return c.summarizeChapter(ctx, chapter)
+}
+
+// findNotebook looks up a notebook by name in the current workspace, preferring exact matches
+func (c *SummarizeCommand) findNotebook(ctx *whatsapp.CommandContext, name string) (*models.Notebook, error) {
+	query := ctx.DB.Where("clerk_user_id = ?", ctx.User.ClerkUserID)
+	if ctx.OrganizationID != nil {
+		query = query.Where("organization_id = ?", *ctx.OrganizationID)
+	} else {
+		query = query.Where("organization_id IS NULL")
+	}
+
+	var notebook models.Notebook
+	if err := query.Session(&gorm.Session{}).Where("LOWER(name) = ?", strings.ToLower(name)).First(&notebook).Error; err == nil {
+		return &notebook, nil
+	}
+
+	if err := query.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(name)+"%").
+		Order("updated_at DESC").First(&notebook).Error; err != nil {
+		return nil, err
+	}
+	return &notebook, nil
+}
+
+// findChapter looks up a chapter by name in the current workspace, preferring exact matches
+func (c *SummarizeCommand) findChapter(ctx *whatsapp.CommandContext, name string) (*models.Chapter, error) {
+	query := ctx.DB.Preload("Notebook").
+		Joins("JOIN notebooks ON notebooks.id = chapters.notebook_id").
+		Where("notebooks.clerk_user_id = ?", ctx.User.ClerkUserID)
+	if ctx.OrganizationID != nil {
+		query = query.Where("chapters.organization_id = ?", *ctx.OrganizationID)
+	} else {
+		query = query.Where("chapters.organization_id IS NULL")
+	}
+
+	var chapter models.Chapter
+	if err := query.Session(&gorm.Session{}).Where("LOWER(chapters.name) = ?", strings.ToLower(name)).First(&chapter).Error; err == nil {
+		return &chapter, nil
+	}
+
+	if err := query.Where("LOWER(chapters.name) LIKE ?", "%"+strings.ToLower(name)+"%").
+		Order("chapters.updated_at DESC").First(&chapter).Error; err != nil {
+		return nil, err
+	}
+	return &chapter, nil
+}
+
+// summarizeNotebook summarizes every note across the chapters of a notebook
+func (c *SummarizeCommand) summarizeNotebook(ctx *whatsapp.CommandContext, notebook *models.Notebook) error {
+	var notes []models.Notes
+	if err := ctx.DB.
+		Joins("JOIN chapters ON chapters.id = notes.chapter_id").
+		Where("chapters.notebook_id = ?", notebook.ID).
+		Order("chapters.created_at ASC, notes.created_at ASC").
+		Find(&notes).Error; err != nil {
+		log.Error().Err(err).Str("notebook_id", notebook.ID).Msg("Failed to load notebook notes for summary")
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"❌ An error occurred while loading your notes. Please try again.")
+	}
+
+	return c.sendSummary(ctx, "notebook", notebook.ID, notebook.Name, notebook.ID, notes)
+}
+
+// summarizeChapter summarizes every note in a chapter
+func (c *SummarizeCommand) summarizeChapter(ctx *whatsapp.CommandContext, chapter *models.Chapter) error {
+	var notes []models.Notes
+	if err := ctx.DB.Where("chapter_id = ?", chapter.ID).
+		Order("created_at ASC").
+		Find(&notes).Error; err != nil {
+		log.Error().Err(err).Str("chapter_id", chapter.ID).Msg("Failed to load chapter notes for summary")
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"❌ An error occurred while loading your notes. Please try again.")
+	}
+
+	return c.sendSummary(ctx, "chapter", chapter.ID, chapter.Name, chapter.NotebookID, notes)
+}
+
+// sendSummary generates the AI summary, sends it in chunks and offers to save it as a note
+func (c *SummarizeCommand) sendSummary(ctx *whatsapp.CommandContext, entityType, entityID, entityName, notebookID string, notes []models.Notes) error {
+	if len(notes) == 0 {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			fmt.Sprintf("📭 The %s *%s* has no notes to summarize yet.", entityType, entityName))
+	}
+
+	ctx.Client.SendTextMessage(ctx.PhoneNumber,
+		fmt.Sprintf("🤖 Summarizing %d notes in *%s*...\n\n_This may take a moment_", len(notes), entityName))
+
+	// Render note content to plain markdown before handing it to the AI
+	summaryNotes := make([]services.SummarizedNote, 0, len(notes))
+	for _, note := range notes {
+		content, err := utils.TipTapToMarkdown(note.Content)
+		if err != nil {
+			log.Warn().Err(err).Str("note_id", note.ID).Msg("Failed to convert TipTap to markdown for summary")
+			content = note.Content
+		}
+		summaryNotes = append(summaryNotes, services.SummarizedNote{
+			Name:    note.Name,
+			Content: content,
+		})
+	}
+
+	summary, err := c.aiService.SummarizeNotes(context.Background(), services.NotesSummaryRequest{
+		Title:      entityName,
+		EntityType: entityType,
+		Notes:      summaryNotes,
+		UserID:     ctx.User.ClerkUserID,
+		OrgID:      ctx.OrganizationID,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("entity_id", entityID).Msg("Failed to summarize notes with AI")
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			fmt.Sprintf("❌ Failed to summarize your notes.\n\n*Error:* %s\n\nPlease check your API key and try again.", err.Error()))
+	}
+
+	header := fmt.Sprintf("📝 *Summary of %s*\n\n", entityName)
+	for i, chunk := range chunkMessage(header+summary, summaryChunkLength) {
+		if err := ctx.Client.SendTextMessage(ctx.PhoneNumber, chunk); err != nil {
+			log.Error().Err(err).Int("chunk", i).Msg("Failed to send summary chunk")
+			return err
+		}
+	}
+
+	// Remember the summary so the user can choose to save it
+	contextData := map[string]interface{}{
+		"step":        "awaiting_save",
+		"entity_type": entityType,
+		"entity_id":   entityID,
+		"entity_name": entityName,
+		"notebook_id": notebookID,
+		"summary":     summary,
+	}
+	if err := c.contextService.SetContext(ctx.PhoneNumber, "summarize", contextData); err != nil {
+		log.Error().Err(err).Msg("Failed to set context for summarize command")
+		return nil
+	}
+
+	return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+		"💾 _Reply 'save' to save this summary as a note, or anything else to skip_")
+}
+
+// handleSaveReply saves the summary as a note if the user asked for it
+func (c *SummarizeCommand) handleSaveReply(ctx *whatsapp.CommandContext) error {
+	response := strings.ToLower(strings.TrimSpace(ctx.Message))
+
+	var contextData map[string]interface{}
+	if err := json.Unmarshal([]byte(ctx.ConversationCtx.Data), &contextData); err != nil {
+		log.Error().Err(err).Msg("Failed to unmarshal context data")
+		if clearErr := c.contextService.ClearContext(ctx.PhoneNumber); clearErr != nil {
+			log.Error().Err(clearErr).Msg("Failed to clear context")
+		}
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"❌ An error occurred. Please start over with /summarize")
+	}
+
+	// Clear context regardless of the answer, the summary is only offered once
+	if err := c.contextService.ClearContext(ctx.PhoneNumber); err != nil {
+		log.Error().Err(err).Msg("Failed to clear context")
+	}
+
+	if response != "save" && response != "yes" && response != "y" {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber, "👍 Summary not saved.")
+	}
+
+	// Anyone in the organization can read a summary, saving it needs a role that can add notes
+	if err := whatsapp.VerifyOrganizationEditAccess(ctx); err != nil {
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			fmt.Sprintf("❌ %s", err.Error()))
+	}
+
+	entityType, _ := contextData["entity_type"].(string)
+	entityID, _ := contextData["entity_id"].(string)
+	entityName, _ := contextData["entity_name"].(string)
+	notebookID, _ := contextData["notebook_id"].(string)
+	summary, _ := contextData["summary"].(string)
+
+	// Chapter summaries live alongside the chapter's notes, notebook summaries get their own chapter
+	chapterID := entityID
+	chapterName := entityName
+	if entityType == "notebook" {
+		chapter, err := c.findOrCreateSummariesChapter(ctx, notebookID)
+		if err != nil {
+			log.Error().Err(err).Str("notebook_id", notebookID).Msg("Failed to prepare summaries chapter")
+			return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+				"❌ Failed to save the summary. Please try again.")
+		}
+		chapterID = chapter.ID
+		chapterName = chapter.Name
+	}
+
+	content, err := utils.MarkdownToTipTap(summary)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to convert summary markdown to TipTap")
+		content = ""
+	}
+
+	note := models.Notes{
+		Name:      fmt.Sprintf("Summary: %s - %s", entityName, time.Now().Format("Jan 2, 2006")),
+		Content:   content,
+		ChapterID: chapterID,
+	}
+	if ctx.OrganizationID != nil {
+		note.OrganizationID = ctx.OrganizationID
+	}
+
+	if err := ctx.DB.Create(&note).Error; err != nil {
+		log.Error().Err(err).Msg("Failed to save summary note")
+		return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+			"❌ Failed to save the summary. Please try again.")
+	}
+
+	return ctx.Client.SendTextMessage(ctx.PhoneNumber,
+		fmt.Sprintf("✅ *Summary saved!*\n\n📝 *%s*\n📑 *Chapter:* %s\n\nYou can view it in the application.", note.Name, chapterName))
+}
+
+// findOrCreateSummariesChapter returns the notebook's summaries chapter, creating it if needed
+func (c *SummarizeCommand) findOrCreateSummariesChapter(ctx *whatsapp.CommandContext, notebookID string) (*models.Chapter, error) {
+	var chapter models.Chapter
+	err := ctx.DB.Where("notebook_id = ? AND LOWER(name) = ?", notebookID, strings.ToLower(summariesChapterName)).
+		First(&chapter).Error
+	if err == nil {
+		return &chapter, nil
+	}
+
+	chapter = models.Chapter{
+		Name:       summariesChapterName,
+		NotebookID: notebookID,
+	}
+	if ctx.OrganizationID != nil {
+		chapter.OrganizationID = ctx.OrganizationID
+	}
+	if err := ctx.DB.Create(&chapter).Error; err != nil {
+		return nil, err
+	}
+	return &chapter, nil
+}
+
+// chunkMessage splits a long message into parts no longer than maxLength,
+// breaking on paragraph or line boundaries where possible
+func chunkMessage(message string, maxLength int) []string {
+	var chunks []string
+
+	for len(message) > maxLength {
+		cut := strings.LastIndex(message[:maxLength], "\n\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(message[:maxLength], "\n")
+		}
+		if cut <= 0 {
+			cut = strings.LastIndex(message[:maxLength], " ")
+		}
+		if cut <= 0 {
+			// Without a space to break on, cut at the last whole character, not inside a multi-byte one
+			cut = maxLength
+			for cut > 0 && !utf8.RuneStart(message[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				_, cut = utf8.DecodeRuneInString(message)
+			}
+		}
+
+		chunks = append(chunks, strings.TrimSpace(message[:cut]))
+		message = strings.TrimSpace(message[cut:])
+	}
+
+	if message != "" {
+		chunks = append(chunks, message)
+	}
+
+	return chunks
+}
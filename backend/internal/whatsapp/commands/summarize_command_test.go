@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkMessage(t *testing.T) {
+	chunks := chunkMessage("First paragraph.\n\nSecond paragraph that is longer.", 40)
+	assert.Equal(t, []string{"First paragraph.", "Second paragraph that is longer."}, chunks)
+
+	// Scripts without spaces are cut between characters, never inside one
+	for _, text := range []string{strings.Repeat("日本語の要約", 200), strings.Repeat("🎉👍", 300), "a" + strings.Repeat("é", 500)} {
+		chunks = chunkMessage(text, 100)
+		assert.Greater(t, len(chunks), 1)
+		for _, chunk := range chunks {
+			assert.True(t, utf8.ValidString(chunk), "chunk %q isn't valid UTF-8", chunk)
+			assert.LessOrEqual(t, len(chunk), 100)
+		}
+		assert.Equal(t, text, strings.Join(chunks, ""))
+	}
+
+	// A limit smaller than a character still makes progress
+	assert.Equal(t, []string{"🎉", "👍"}, chunkMessage("🎉👍", 2))
+}
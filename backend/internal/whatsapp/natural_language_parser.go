@@ -48,3 +48,57 @@ func (nlc *NaturalLanguageCommand) ConvertToCommandFormat() (string, []string) {
 		return "", nil
 	}
 }
+
+// Intent names produced by the AI intent classifier for free-text messages
+const (
+	IntentAdd       = "add"
+	IntentSearch    = "search"
+	IntentTodo      = "todo"
+	IntentSummarize = "summarize"
+	IntentSchedule  = "schedule"
+	IntentUnknown   = "unknown"
+)
+
+// MinIntentConfidence is the minimum classifier confidence required before acting on an intent.
+// Anything below this falls back to the help message.
+const MinIntentConfidence = 0.6
+
+// ValidIntents returns the intents the message processor knows how to route
+func ValidIntents() []string {
+	return []string{
+		IntentAdd,
+		IntentSearch,
+		IntentTodo,
+		IntentSummarize,
+		IntentSchedule,
+	}
+}
+
+// NaturalLanguageIntent represents a free-text message classified by AI
+type NaturalLanguageIntent struct {
+	Intent     string            `json:"intent"`
+	Confidence float64           `json:"confidence"`
+	Slots      map[string]string `json:"slots"`
+}
+
+// IsActionable returns true if the intent is known and confident enough to be routed
+func (i *NaturalLanguageIntent) IsActionable() bool {
+	if i == nil || i.Confidence < MinIntentConfidence {
+		return false
+	}
+
+	for _, intent := range ValidIntents() {
+		if i.Intent == intent {
+			return true
+		}
+	}
+	return false
+}
+
+// Slot returns the trimmed value of an extracted slot, or an empty string if it is missing
+func (i *NaturalLanguageIntent) Slot(name string) string {
+	if i == nil || i.Slots == nil {
+		return ""
+	}
+	return strings.TrimSpace(i.Slots[name])
+}
@@ -0,0 +1,41 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNaturalLanguageIntent_IsActionable(t *testing.T) {
+	tests := []struct {
+		name   string
+		intent *NaturalLanguageIntent
+		want   bool
+	}{
+		{"confident known intent", &NaturalLanguageIntent{Intent: IntentTodo, Confidence: 0.9}, true},
+		{"exactly the minimum confidence", &NaturalLanguageIntent{Intent: IntentSearch, Confidence: MinIntentConfidence}, true},
+		{"below the minimum confidence", &NaturalLanguageIntent{Intent: IntentAdd, Confidence: 0.59}, false},
+		{"unknown intent", &NaturalLanguageIntent{Intent: IntentUnknown, Confidence: 1}, false},
+		{"intent the processor can't route", &NaturalLanguageIntent{Intent: "delete", Confidence: 1}, false},
+		{"no intent", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.intent.IsActionable())
+		})
+	}
+}
+
+func TestNaturalLanguageIntent_Slot(t *testing.T) {
+	intent := &NaturalLanguageIntent{
+		Intent: IntentTodo,
+		Slots:  map[string]string{"task": "  call the dentist \n", "priority": ""},
+	}
+	assert.Equal(t, "call the dentist", intent.Slot("task"))
+	assert.Equal(t, "", intent.Slot("priority"))
+	assert.Equal(t, "", intent.Slot("date"), "Missing slots are empty")
+
+	assert.Equal(t, "", (&NaturalLanguageIntent{Intent: IntentTodo}).Slot("task"), "Intents without slots have none")
+	var missing *NaturalLanguageIntent
+	assert.Equal(t, "", missing.Slot("task"))
+}
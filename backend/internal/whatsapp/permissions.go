@@ -1,6 +1,7 @@
 package whatsapp
 
 import (
+	"backend/internal/middleware"
 	"context"
 	"fmt"
 
@@ -125,3 +126,39 @@ func VerifyOrganizationAdminAccess(cmdCtx *CommandContext) error {
 
 	return nil
 }
+
+// VerifyOrganizationEditAccess checks if a user can add, change or delete organization notes. Viewers and
+// commenters only read them.
+func VerifyOrganizationEditAccess(cmdCtx *CommandContext) error {
+	if cmdCtx.OrganizationID == nil {
+		// No organization context, personal mode
+		return nil
+	}
+
+	isMember, role, err := CheckOrganizationPermission(
+		context.Background(),
+		cmdCtx.User.ClerkUserID,
+		*cmdCtx.OrganizationID,
+		false,
+	)
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("user_id", cmdCtx.User.ClerkUserID).
+			Str("org_id", *cmdCtx.OrganizationID).
+			Msg("Failed to verify organization edit access")
+		return fmt.Errorf("failed to verify organization access")
+	}
+
+	if !isMember {
+		return fmt.Errorf("you are not a member of this organization")
+	}
+
+	switch middleware.OrgRoleFromClerk(role) {
+	case middleware.OrgRoleViewer, middleware.OrgRoleCommenter:
+		return fmt.Errorf("your role in this organization can read notes but not change them")
+	}
+
+	return nil
+}
@@ -0,0 +1,446 @@
+// Package docx writes Word documents (Office Open XML) of styled paragraphs, lists, tables and
+// images. Styles are the built-in Word ones, so documents pasted into other documents take on their look.
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Paragraph styles
+const (
+	StyleNormal   = ""
+	StyleTitle    = "Title"
+	StyleSubtitle = "Subtitle"
+	StyleQuote    = "Quote"
+	StyleCode     = "Code"
+	StyleCaption  = "Caption"
+)
+
+// Heading returns the paragraph style of a heading level from 1 to 6
+func Heading(level int) string {
+	return fmt.Sprintf("Heading%d", min(max(level, 1), 6))
+}
+
+// Page sizes in twentieths of a point
+var (
+	A4     = Size{Width: 11906, Height: 16838}
+	Letter = Size{Width: 12240, Height: 15840}
+	Legal  = Size{Width: 12240, Height: 20160}
+)
+
+// Size is a page size in twentieths of a point
+type Size struct {
+	Width  int
+	Height int
+}
+
+const (
+	pageMargin = 1440 // One inch
+	emuPerPx   = 9525 // English Metric Units per pixel at 96 DPI
+	emuPerTwip = 635
+)
+
+// Run is a piece of text in one style
+type Run struct {
+	Text   string // Line breaks start a new line in the same paragraph
+	Bold   bool
+	Italic bool
+	Strike bool
+	Code   bool
+	Link   string // URL the text links to
+	Math   string // An Office Math element (m:oMath) written in place of the text
+}
+
+// Cell is a table cell of one or more paragraphs
+type Cell [][]Run
+
+// List is a bulleted or numbered list. Items of the same list continue its numbering.
+type List struct {
+	id      int
+	ordered bool
+}
+
+// Document is a Word document being written
+type Document struct {
+	size    Size
+	title   string
+	created time.Time
+	body    bytes.Buffer
+	rels    []relationship
+	media   []media
+	lists   []listInstance
+	drawing int
+}
+
+type relationship struct {
+	id, kind, target string
+	external         bool
+}
+
+type media struct {
+	name string
+	data []byte
+}
+
+type listInstance struct {
+	ordered bool
+	start   int
+}
+
+// New creates an empty document with pages of the given size
+func New(size Size) *Document {
+	return &Document{size: size, created: time.Now()}
+}
+
+// SetInfo sets the title and creation date shown in the document properties
+func (d *Document) SetInfo(title string, created time.Time) {
+	d.title = title
+	d.created = created
+}
+
+// Paragraph appends a paragraph in a style
+func (d *Document) Paragraph(style string, runs []Run) {
+	d.body.WriteString("<w:p>")
+	if style != StyleNormal {
+		fmt.Fprintf(&d.body, `<w:pPr><w:pStyle w:val="%s"/></w:pPr>`, style)
+	}
+	d.runs(runs)
+	d.body.WriteString("</w:p>")
+}
+
+// NewList starts a list, numbered from start when ordered. Nested lists are lists of their own whose
+// items are at a deeper level.
+func (d *Document) NewList(ordered bool, start int) *List {
+	d.lists = append(d.lists, listInstance{ordered: ordered, start: max(start, 1)})
+	return &List{id: len(d.lists), ordered: ordered}
+}
+
+// ListItem appends an item to a list, nested at a level from 0 to 8
+func (d *Document) ListItem(list *List, level int, runs []Run) {
+	fmt.Fprintf(&d.body, `<w:p><w:pPr><w:pStyle w:val="ListParagraph"/><w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr></w:pPr>`,
+		min(max(level, 0), 8), list.id)
+	d.runs(runs)
+	d.body.WriteString("</w:p>")
+}
+
+// Rule appends a horizontal line
+func (d *Document) Rule() {
+	d.body.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="BFBFBF"/></w:pBdr></w:pPr></w:p>`)
+}
+
+// PageBreak starts a new page
+func (d *Document) PageBreak() {
+	d.body.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+}
+
+// Table appends a table whose first row is a header repeated on every page when header is set
+func (d *Document) Table(rows [][]Cell, header bool) {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return
+	}
+
+	d.body.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/></w:tblPr><w:tblGrid>`)
+	width := (d.size.Width - 2*pageMargin) / columns
+	for i := 0; i < columns; i++ {
+		fmt.Fprintf(&d.body, `<w:gridCol w:w="%d"/>`, width)
+	}
+	d.body.WriteString("</w:tblGrid>")
+	for i, row := range rows {
+		d.body.WriteString("<w:tr>")
+		if i == 0 && header {
+			d.body.WriteString("<w:trPr><w:tblHeader/></w:trPr>")
+		}
+		for j := 0; j < columns; j++ {
+			fmt.Fprintf(&d.body, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, width)
+			if i == 0 && header {
+				d.body.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="F2F2F2"/>`)
+			}
+			d.body.WriteString("</w:tcPr>")
+			var paragraphs [][]Run
+			if j < len(row) {
+				paragraphs = row[j]
+			}
+			if len(paragraphs) == 0 {
+				// Every cell needs a paragraph
+				paragraphs = [][]Run{nil}
+			}
+			for _, runs := range paragraphs {
+				if i == 0 && header {
+					runs = boldRuns(runs)
+				}
+				d.body.WriteString("<w:p>")
+				d.runs(runs)
+				d.body.WriteString("</w:p>")
+			}
+			d.body.WriteString("</w:tc>")
+		}
+		d.body.WriteString("</w:tr>")
+	}
+	// Word merges tables that follow each other, an empty paragraph keeps them apart
+	d.body.WriteString("</w:tbl><w:p/>")
+}
+
+// Image appends a PNG, JPEG or GIF image in its own paragraph, scaled down to the width of the page.
+// The size is in pixels.
+func (d *Document) Image(data []byte, extension string, width, height int, description string) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	d.drawing++
+	name := fmt.Sprintf("image%d.%s", len(d.media)+1, extension)
+	d.media = append(d.media, media{name: name, data: data})
+	relID := d.relationship("http://schemas.openxmlformats.org/officeDocument/2006/relationships/image", "media/"+name, false)
+
+	cx, cy := int64(width)*emuPerPx, int64(height)*emuPerPx
+	if maxWidth := int64(d.size.Width-2*pageMargin) * emuPerTwip; cx > maxWidth {
+		cy = cy * maxWidth / cx
+		cx = maxWidth
+	}
+	fmt.Fprintf(&d.body, `<w:p><w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%d" cy="%d"/><wp:docPr id="%d" name="Picture %d" descr="%s"/>`+
+		`<wp:cNvGraphicFramePr><a:graphicFrameLocks noChangeAspect="1"/></wp:cNvGraphicFramePr>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture"><pic:pic>`+
+		`<pic:nvPicPr><pic:cNvPr id="%d" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>`,
+		cx, cy, d.drawing, d.drawing, escape(description), d.drawing, name, relID, cx, cy)
+}
+
+// runs writes runs, linking those with a URL
+func (d *Document) runs(runs []Run) {
+	for _, run := range runs {
+		if run.Math != "" {
+			d.body.WriteString(run.Math)
+			continue
+		}
+		if run.Text == "" {
+			continue
+		}
+		if run.Link != "" {
+			relID := d.relationship("http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink", run.Link, true)
+			fmt.Fprintf(&d.body, `<w:hyperlink r:id="%s">`, relID)
+		}
+		d.body.WriteString("<w:r>")
+		var props strings.Builder
+		if run.Link != "" {
+			props.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+		}
+		if run.Code {
+			props.WriteString(`<w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/>`)
+		}
+		if run.Bold {
+			props.WriteString("<w:b/>")
+		}
+		if run.Italic {
+			props.WriteString("<w:i/>")
+		}
+		if run.Strike {
+			props.WriteString("<w:strike/>")
+		}
+		if props.Len() > 0 {
+			d.body.WriteString("<w:rPr>" + props.String() + "</w:rPr>")
+		}
+		for i, line := range strings.Split(run.Text, "\n") {
+			if i > 0 {
+				d.body.WriteString("<w:br/>")
+			}
+			for j, part := range strings.Split(line, "\t") {
+				if j > 0 {
+					d.body.WriteString("<w:tab/>")
+				}
+				if part != "" {
+					fmt.Fprintf(&d.body, `<w:t xml:space="preserve">%s</w:t>`, escape(part))
+				}
+			}
+		}
+		d.body.WriteString("</w:r>")
+		if run.Link != "" {
+			d.body.WriteString("</w:hyperlink>")
+		}
+	}
+}
+
+// relationship adds a relationship from the document to a part or URL and returns its ID
+func (d *Document) relationship(kind, target string, external bool) string {
+	id := fmt.Sprintf("rId%d", len(d.rels)+firstRelID)
+	d.rels = append(d.rels, relationship{id: id, kind: kind, target: target, external: external})
+	return id
+}
+
+// firstRelID follows the relationships of the styles and numbering parts
+const firstRelID = 3
+
+// WriteTo writes the document as a .docx file
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	archive := zip.NewWriter(counter)
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", packageRelsXML},
+		{"docProps/core.xml", d.coreXML()},
+		{"word/_rels/document.xml.rels", d.documentRelsXML()},
+		{"word/document.xml", d.documentXML()},
+		{"word/styles.xml", stylesXML},
+		{"word/numbering.xml", d.numberingXML()},
+	}
+	for _, part := range parts {
+		if err := writeZipFile(archive, part.name, []byte(part.content)); err != nil {
+			return counter.n, err
+		}
+	}
+	for _, image := range d.media {
+		if err := writeZipFile(archive, "word/media/"+image.name, image.data); err != nil {
+			return counter.n, err
+		}
+	}
+	err := archive.Close()
+	return counter.n, err
+}
+
+// Bytes returns the document as a .docx file
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *Document) documentXML() string {
+	var doc strings.Builder
+	doc.WriteString(xml.Header)
+	doc.WriteString(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+		`xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing" ` +
+		`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+		`xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture" ` +
+		`xmlns:m="http://schemas.openxmlformats.org/officeDocument/2006/math"><w:body>`)
+	doc.Write(d.body.Bytes())
+	fmt.Fprintf(&doc, `<w:sectPr><w:pgSz w:w="%d" w:h="%d"/><w:pgMar w:top="%d" w:right="%d" w:bottom="%d" w:left="%d" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr>`,
+		d.size.Width, d.size.Height, pageMargin, pageMargin, pageMargin, pageMargin)
+	doc.WriteString("</w:body></w:document>")
+	return doc.String()
+}
+
+func (d *Document) documentRelsXML() string {
+	var rels strings.Builder
+	rels.WriteString(xml.Header)
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	rels.WriteString(`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`)
+	rels.WriteString(`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>`)
+	for _, rel := range d.rels {
+		mode := ""
+		if rel.external {
+			mode = ` TargetMode="External"`
+		}
+		fmt.Fprintf(&rels, `<Relationship Id="%s" Type="%s" Target="%s"%s/>`, rel.id, rel.kind, escape(rel.target), mode)
+	}
+	rels.WriteString("</Relationships>")
+	return rels.String()
+}
+
+func (d *Document) coreXML() string {
+	return xml.Header + `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" ` +
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<dc:title>` + escape(d.title) + `</dc:title>` +
+		`<dcterms:created xsi:type="dcterms:W3CDTF">` + d.created.UTC().Format(time.RFC3339) + `</dcterms:created>` +
+		`</cp:coreProperties>`
+}
+
+// numberingXML defines a bullet and a numbered list format, and a numbering instance per list so
+// every numbered list starts over
+func (d *Document) numberingXML() string {
+	var numbering strings.Builder
+	numbering.WriteString(xml.Header)
+	numbering.WriteString(`<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
+
+	bullets := []string{"•", "◦", "▪"}
+	numbering.WriteString(`<w:abstractNum w:abstractNumId="0"><w:multiLevelType w:val="hybridMultilevel"/>`)
+	for level := 0; level < 9; level++ {
+		fmt.Fprintf(&numbering, `<w:lvl w:ilvl="%d"><w:start w:val="1"/><w:numFmt w:val="bullet"/><w:lvlText w:val="%s"/><w:lvlJc w:val="left"/>`+
+			`<w:pPr><w:ind w:left="%d" w:hanging="360"/></w:pPr></w:lvl>`, level, bullets[level%len(bullets)], 720*(level+1))
+	}
+	numbering.WriteString("</w:abstractNum>")
+
+	formats := []string{"decimal", "lowerLetter", "lowerRoman"}
+	numbering.WriteString(`<w:abstractNum w:abstractNumId="1"><w:multiLevelType w:val="hybridMultilevel"/>`)
+	for level := 0; level < 9; level++ {
+		fmt.Fprintf(&numbering, `<w:lvl w:ilvl="%d"><w:start w:val="1"/><w:numFmt w:val="%s"/><w:lvlText w:val="%%%d."/><w:lvlJc w:val="left"/>`+
+			`<w:pPr><w:ind w:left="%d" w:hanging="360"/></w:pPr></w:lvl>`, level, formats[level%len(formats)], level+1, 720*(level+1))
+	}
+	numbering.WriteString("</w:abstractNum>")
+
+	for i, list := range d.lists {
+		if !list.ordered {
+			fmt.Fprintf(&numbering, `<w:num w:numId="%d"><w:abstractNumId w:val="0"/></w:num>`, i+1)
+			continue
+		}
+		fmt.Fprintf(&numbering, `<w:num w:numId="%d"><w:abstractNumId w:val="1"/>`, i+1)
+		for level := 0; level < 9; level++ {
+			fmt.Fprintf(&numbering, `<w:lvlOverride w:ilvl="%d"><w:startOverride w:val="%d"/></w:lvlOverride>`, level, list.start)
+		}
+		numbering.WriteString("</w:num>")
+	}
+	numbering.WriteString("</w:numbering>")
+	return numbering.String()
+}
+
+func boldRuns(runs []Run) []Run {
+	bold := make([]Run, len(runs))
+	for i, run := range runs {
+		run.Bold = true
+		bold[i] = run
+	}
+	return bold
+}
+
+// escape escapes text for XML, dropping characters XML can't hold
+func escape(text string) string {
+	var buf bytes.Buffer
+	clean := strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r != 0xFFFE && r != 0xFFFF {
+			return r
+		}
+		return -1
+	}, text)
+	if err := xml.EscapeText(&buf, []byte(clean)); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
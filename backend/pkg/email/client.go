@@ -0,0 +1,232 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is a minimal SMTP client sending HTML emails with attachments
+type Client struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// NewClient creates a new SMTP client. Port 465 connects over TLS, other ports upgrade with STARTTLS
+// when the server supports it. Username and password are optional for relays without authentication.
+func NewClient(host string, port int, username, password string, timeout time.Duration) *Client {
+	return &Client{Host: host, Port: port, Username: username, Password: password, Timeout: timeout}
+}
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Message is an email with an HTML body and its plain text alternative
+type Message struct {
+	From        mail.Address
+	ReplyTo     *mail.Address
+	To          []string
+	Subject     string
+	HTML        string
+	Text        string
+	Attachments []Attachment
+}
+
+// Send delivers a message to its recipients
+func (c *Client) Send(ctx context.Context, msg *Message) error {
+	if c.Host == "" {
+		return errors.New("SMTP host is not configured")
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	address := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	if c.Port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: c.Host}}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && c.Port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(msg.From.Address); err != nil {
+		return fmt.Errorf("SMTP server refused the sender: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP server refused recipient %s: %w", to, err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+// Bytes returns the message in MIME format: the text and HTML bodies as alternatives, followed by
+// the attachments
+func (m *Message) Bytes() ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, errors.New("email has no recipients")
+	}
+	for _, value := range append([]string{m.From.Address, m.Subject}, m.To...) {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, errors.New("email headers can't contain line breaks")
+		}
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", m.From.String())
+	header("To", strings.Join(m.To, ", "))
+	if m.ReplyTo != nil {
+		header("Reply-To", m.ReplyTo.String())
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(m.From.Address))
+	header("MIME-Version", "1.0")
+
+	mixed := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/mixed; boundary="`+mixed.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	var bodies bytes.Buffer
+	alternative := multipart.NewWriter(&bodies)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		writer, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return nil, err
+	}
+	body, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {`multipart/alternative; boundary="` + alternative.Boundary() + `"`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := body.Write(bodies.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range m.Attachments {
+		writer, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64Lines(writer, attachment.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// messageID returns a unique Message-ID on the sender's domain
+func messageID(from string) string {
+	domain := "localhost"
+	if _, host, ok := strings.Cut(from, "@"); ok && host != "" {
+		domain = host
+	}
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">"
+}
+
+// writeBase64Lines writes data base64 encoded in lines of 76 characters
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		line := encoded[:min(76, len(encoded))]
+		encoded = encoded[len(line):]
+		if _, err := io.WriteString(w, line+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
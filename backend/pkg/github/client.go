@@ -0,0 +1,205 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://api.github.com"
+
+// ErrNotFound is returned when a repository or file doesn't exist or the token can't see it
+var ErrNotFound = errors.New("github resource not found")
+
+// Client is a minimal GitHub REST API client for reading and writing repository files
+type Client struct {
+	Token      string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new GitHub API client for an access token
+func NewClient(token string) *Client {
+	return &Client{
+		Token:   token,
+		BaseURL: defaultBaseURL,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Repository is the metadata of a repository
+type Repository struct {
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+	Private       bool   `json:"private"`
+	Permissions   struct {
+		Push bool `json:"push"`
+	} `json:"permissions"`
+}
+
+// File is a file's content at a ref
+type File struct {
+	Path    string
+	SHA     string
+	Content string
+}
+
+// CommitAuthor attributes a commit to a person
+type CommitAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// PutFileRequest creates or updates a file in a single commit
+type PutFileRequest struct {
+	Message string
+	Content string
+	Branch  string
+	// SHA is the blob being replaced; empty when creating a file
+	SHA    string
+	Author *CommitAuthor
+}
+
+// DeleteFileRequest deletes a file in a single commit
+type DeleteFileRequest struct {
+	Message string
+	Branch  string
+	SHA     string
+	Author  *CommitAuthor
+}
+
+// GetRepository returns a repository's metadata, including whether the token can push to it
+func (c *Client) GetRepository(ctx context.Context, repo string) (*Repository, error) {
+	var repository Repository
+	if err := c.request(ctx, http.MethodGet, "/repos/"+repo, nil, &repository); err != nil {
+		return nil, err
+	}
+	return &repository, nil
+}
+
+// GetFile returns a file's content on a branch
+func (c *Client) GetFile(ctx context.Context, repo, path, branch string) (*File, error) {
+	var response struct {
+		Path     string `json:"path"`
+		SHA      string `json:"sha"`
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	endpoint := contentsPath(repo, path) + "?ref=" + url.QueryEscape(branch)
+	if err := c.request(ctx, http.MethodGet, endpoint, nil, &response); err != nil {
+		return nil, err
+	}
+
+	content := response.Content
+	if response.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(content, "\n", ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode file content: %w", err)
+		}
+		content = string(decoded)
+	}
+
+	return &File{Path: response.Path, SHA: response.SHA, Content: content}, nil
+}
+
+// PutFile creates or updates a file and returns the new blob SHA
+func (c *Client) PutFile(ctx context.Context, repo, path string, req PutFileRequest) (string, error) {
+	payload := map[string]interface{}{
+		"message": req.Message,
+		"content": base64.StdEncoding.EncodeToString([]byte(req.Content)),
+		"branch":  req.Branch,
+	}
+	if req.SHA != "" {
+		payload["sha"] = req.SHA
+	}
+	if req.Author != nil {
+		payload["author"] = req.Author
+	}
+
+	var response struct {
+		Content struct {
+			SHA string `json:"sha"`
+		} `json:"content"`
+	}
+	if err := c.request(ctx, http.MethodPut, contentsPath(repo, path), payload, &response); err != nil {
+		return "", err
+	}
+	return response.Content.SHA, nil
+}
+
+// DeleteFile deletes a file
+func (c *Client) DeleteFile(ctx context.Context, repo, path string, req DeleteFileRequest) error {
+	payload := map[string]interface{}{
+		"message": req.Message,
+		"sha":     req.SHA,
+		"branch":  req.Branch,
+	}
+	if req.Author != nil {
+		payload["author"] = req.Author
+	}
+	return c.request(ctx, http.MethodDelete, contentsPath(repo, path), payload, nil)
+}
+
+// request sends an authenticated API request and decodes the JSON response
+func (c *Client) request(ctx context.Context, method, endpoint string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("github API error (status %d): %s", resp.StatusCode, string(responseBody))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// contentsPath builds the contents API path for a file, escaping each path segment
+func contentsPath(repo, path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/repos/" + repo + "/contents/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,238 @@
+package googledrive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// MimeTypeDocument is the MIME type of Google Docs
+	MimeTypeDocument = "application/vnd.google-apps.document"
+	// MimeTypeFolder is the MIME type of Drive folders
+	MimeTypeFolder = "application/vnd.google-apps.folder"
+	// ExportMimeTypeMarkdown exports a Google Doc as Markdown
+	ExportMimeTypeMarkdown = "text/markdown"
+
+	// Scope grants read-only access to the user's Drive files
+	Scope = "https://www.googleapis.com/auth/drive.readonly"
+
+	defaultBaseURL  = "https://www.googleapis.com/drive/v3"
+	tokenURL        = "https://oauth2.googleapis.com/token"
+	maxExportBytes  = 10 << 20
+	fileFields      = "id,name,mimeType,modifiedTime,webViewLink,parents"
+	listFieldsQuery = "nextPageToken,files(" + fileFields + ")"
+)
+
+// ErrNotFound is returned when a file doesn't exist or the user can no longer access it
+var ErrNotFound = errors.New("drive file not found")
+
+// Client is a minimal Google Drive API client
+type Client struct {
+	AccessToken string
+	BaseURL     string
+	HTTPClient  *http.Client
+}
+
+// NewClient creates a new Drive API client for an access token
+func NewClient(accessToken string) *Client {
+	return &Client{
+		AccessToken: accessToken,
+		BaseURL:     defaultBaseURL,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// File is the metadata of a Drive file
+type File struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	MimeType     string    `json:"mimeType"`
+	ModifiedTime time.Time `json:"modifiedTime"`
+	WebViewLink  string    `json:"webViewLink"`
+	Parents      []string  `json:"parents,omitempty"`
+}
+
+// IsFolder reports whether the file is a folder
+func (f *File) IsFolder() bool {
+	return f.MimeType == MimeTypeFolder
+}
+
+// IsDocument reports whether the file is a Google Doc
+func (f *File) IsDocument() bool {
+	return f.MimeType == MimeTypeDocument
+}
+
+// FileList is a page of files
+type FileList struct {
+	Files         []File `json:"files"`
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
+
+// ListFilesOptions filters the files returned by ListFiles
+type ListFilesOptions struct {
+	FolderID  string
+	Query     string
+	PageToken string
+	PageSize  int
+}
+
+// ListFiles lists the Docs and folders the user can import
+func (c *Client) ListFiles(ctx context.Context, options ListFilesOptions) (*FileList, error) {
+	conditions := []string{
+		"trashed = false",
+		fmt.Sprintf("(mimeType = '%s' or mimeType = '%s')", MimeTypeDocument, MimeTypeFolder),
+	}
+	if options.FolderID != "" {
+		conditions = append(conditions, fmt.Sprintf("'%s' in parents", escapeQuery(options.FolderID)))
+	}
+	if options.Query != "" {
+		conditions = append(conditions, fmt.Sprintf("name contains '%s'", escapeQuery(options.Query)))
+	}
+
+	pageSize := options.PageSize
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 50
+	}
+
+	params := url.Values{}
+	params.Set("q", strings.Join(conditions, " and "))
+	params.Set("fields", listFieldsQuery)
+	params.Set("orderBy", "folder,modifiedTime desc")
+	params.Set("pageSize", fmt.Sprintf("%d", pageSize))
+	params.Set("supportsAllDrives", "true")
+	params.Set("includeItemsFromAllDrives", "true")
+	if options.PageToken != "" {
+		params.Set("pageToken", options.PageToken)
+	}
+
+	var list FileList
+	if err := c.getJSON(ctx, "/files?"+params.Encode(), &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetFile returns a file's metadata
+func (c *Client) GetFile(ctx context.Context, fileID string) (*File, error) {
+	params := url.Values{}
+	params.Set("fields", fileFields)
+	params.Set("supportsAllDrives", "true")
+
+	var file File
+	if err := c.getJSON(ctx, "/files/"+url.PathEscape(fileID)+"?"+params.Encode(), &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ExportFile exports a Google Workspace file in the given format
+func (c *Client) ExportFile(ctx context.Context, fileID, mimeType string) (string, error) {
+	params := url.Values{}
+	params.Set("mimeType", mimeType)
+
+	resp, err := c.do(ctx, "/files/"+url.PathEscape(fileID)+"/export?"+params.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxExportBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read export: %w", err)
+	}
+	return string(body), nil
+}
+
+// getJSON performs a GET request and decodes the JSON response
+func (c *Client) getJSON(ctx context.Context, path string, out interface{}) error {
+	resp, err := c.do(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// do performs an authenticated GET request, turning error statuses into errors
+func (c *Client) do(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("drive API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return resp, nil
+}
+
+// Token is an OAuth access token
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+// RefreshAccessToken exchanges a refresh token for a new access token
+func RefreshAccessToken(ctx context.Context, clientID, clientSecret, refreshToken string) (*Token, error) {
+	data := url.Values{}
+	data.Set("client_id", clientID)
+	data.Set("client_secret", clientSecret)
+	data.Set("refresh_token", refreshToken)
+	data.Set("grant_type", "refresh_token")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := (&http.Client{Timeout: 15 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token refresh failed with status: %d", resp.StatusCode)
+	}
+
+	var token Token
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode token: %w", err)
+	}
+	return &token, nil
+}
+
+// escapeQuery escapes a value for use inside a quoted Drive query string
+func escapeQuery(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, `'`, `\'`)
+}
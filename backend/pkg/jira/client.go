@@ -0,0 +1,328 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// timeLayout is the timestamp format used by the Jira REST API
+const timeLayout = "2006-01-02T15:04:05.000-0700"
+
+// ErrNotFound is returned when an issue or project doesn't exist or the credentials can't see it
+var ErrNotFound = errors.New("jira resource not found")
+
+// Client is a minimal Jira Cloud REST API client for syncing issues
+type Client struct {
+	BaseURL    string
+	Email      string
+	APIToken   string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new Jira API client for a site (https://your-site.atlassian.net)
+func NewClient(baseURL, email, apiToken string) *Client {
+	return &Client{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Email:    email,
+		APIToken: apiToken,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Project is the metadata of a project
+type Project struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// Issue is an issue with the fields used for syncing
+type Issue struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+	// StatusCategory is "new", "indeterminate" or "done"
+	StatusCategory string
+	StatusName     string
+	Summary        string
+	Description    string
+	Updated        time.Time
+}
+
+// Comment is a comment on an issue
+type Comment struct {
+	ID      string
+	Author  string
+	Body    string
+	Created time.Time
+}
+
+// Transition moves an issue to another status
+type Transition struct {
+	ID             string
+	Name           string
+	StatusCategory string
+}
+
+// IssueFields are the editable fields of an issue
+type IssueFields struct {
+	Summary     string
+	Description string
+}
+
+// issueResponse is an issue as returned by the API
+type issueResponse struct {
+	ID     string `json:"id"`
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Updated     string `json:"updated"`
+		Status      struct {
+			Name           string `json:"name"`
+			StatusCategory struct {
+				Key string `json:"key"`
+			} `json:"statusCategory"`
+		} `json:"status"`
+	} `json:"fields"`
+}
+
+// toIssue converts an API issue
+func (r *issueResponse) toIssue() Issue {
+	updated, _ := time.Parse(timeLayout, r.Fields.Updated)
+	return Issue{
+		ID:             r.ID,
+		Key:            r.Key,
+		StatusCategory: r.Fields.Status.StatusCategory.Key,
+		StatusName:     r.Fields.Status.Name,
+		Summary:        r.Fields.Summary,
+		Description:    r.Fields.Description,
+		Updated:        updated,
+	}
+}
+
+const issueFields = "summary,description,status,updated"
+
+// GetProject returns a project's metadata
+func (c *Client) GetProject(ctx context.Context, projectKey string) (*Project, error) {
+	var project Project
+	if err := c.request(ctx, http.MethodGet, "/rest/api/2/project/"+url.PathEscape(projectKey), nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// SearchIssues returns the project's issues updated since a time, most recently updated first
+func (c *Client) SearchIssues(ctx context.Context, projectKey string, updatedSince time.Time, limit int) ([]Issue, error) {
+	jql := fmt.Sprintf(`project = "%s"`, strings.ReplaceAll(projectKey, `"`, ""))
+	if !updatedSince.IsZero() {
+		jql += fmt.Sprintf(` AND updated >= "%s"`, updatedSince.UTC().Format("2006/01/02 15:04"))
+	}
+	jql += " ORDER BY updated DESC"
+
+	var issues []Issue
+	pageToken := ""
+	for len(issues) < limit {
+		params := url.Values{}
+		params.Set("jql", jql)
+		params.Set("fields", issueFields)
+		params.Set("maxResults", "100")
+		if pageToken != "" {
+			params.Set("nextPageToken", pageToken)
+		}
+
+		var response struct {
+			Issues        []issueResponse `json:"issues"`
+			NextPageToken string          `json:"nextPageToken"`
+		}
+		if err := c.request(ctx, http.MethodGet, "/rest/api/2/search/jql?"+params.Encode(), nil, &response); err != nil {
+			return nil, err
+		}
+		for i := range response.Issues {
+			issues = append(issues, response.Issues[i].toIssue())
+		}
+		if response.NextPageToken == "" || len(response.Issues) == 0 {
+			break
+		}
+		pageToken = response.NextPageToken
+	}
+
+	if len(issues) > limit {
+		issues = issues[:limit]
+	}
+	return issues, nil
+}
+
+// GetIssue returns an issue by ID or key
+func (c *Client) GetIssue(ctx context.Context, issueKey string) (*Issue, error) {
+	var response issueResponse
+	if err := c.request(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(issueKey)+"?fields="+issueFields, nil, &response); err != nil {
+		return nil, err
+	}
+	issue := response.toIssue()
+	return &issue, nil
+}
+
+// CreateIssue creates an issue and returns it
+func (c *Client) CreateIssue(ctx context.Context, projectKey, issueType string, fields IssueFields) (*Issue, error) {
+	payload := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": projectKey},
+			"issuetype":   map[string]string{"name": issueType},
+			"summary":     fields.Summary,
+			"description": fields.Description,
+		},
+	}
+
+	var created struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	}
+	if err := c.request(ctx, http.MethodPost, "/rest/api/2/issue", payload, &created); err != nil {
+		return nil, err
+	}
+	return c.GetIssue(ctx, created.Key)
+}
+
+// UpdateIssue updates an issue's summary and description
+func (c *Client) UpdateIssue(ctx context.Context, issueKey string, fields IssueFields) error {
+	payload := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary":     fields.Summary,
+			"description": fields.Description,
+		},
+	}
+	return c.request(ctx, http.MethodPut, "/rest/api/2/issue/"+url.PathEscape(issueKey), payload, nil)
+}
+
+// ListTransitions returns the transitions currently available for an issue
+func (c *Client) ListTransitions(ctx context.Context, issueKey string) ([]Transition, error) {
+	var response struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := c.request(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(issueKey)+"/transitions", nil, &response); err != nil {
+		return nil, err
+	}
+
+	transitions := make([]Transition, len(response.Transitions))
+	for i, t := range response.Transitions {
+		transitions[i] = Transition{ID: t.ID, Name: t.Name, StatusCategory: t.To.StatusCategory.Key}
+	}
+	return transitions, nil
+}
+
+// TransitionIssue moves an issue through a transition
+func (c *Client) TransitionIssue(ctx context.Context, issueKey, transitionID string) error {
+	payload := map[string]interface{}{
+		"transition": map[string]string{"id": transitionID},
+	}
+	return c.request(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(issueKey)+"/transitions", payload, nil)
+}
+
+// ListComments returns an issue's comments, oldest first
+func (c *Client) ListComments(ctx context.Context, issueKey string) ([]Comment, error) {
+	var response struct {
+		Comments []commentResponse `json:"comments"`
+	}
+	if err := c.request(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(issueKey)+"/comment?orderBy=created&maxResults=100", nil, &response); err != nil {
+		return nil, err
+	}
+
+	comments := make([]Comment, len(response.Comments))
+	for i := range response.Comments {
+		comments[i] = response.Comments[i].toComment()
+	}
+	return comments, nil
+}
+
+// AddComment adds a comment to an issue
+func (c *Client) AddComment(ctx context.Context, issueKey, body string) (*Comment, error) {
+	var response commentResponse
+	if err := c.request(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(issueKey)+"/comment", map[string]string{"body": body}, &response); err != nil {
+		return nil, err
+	}
+	comment := response.toComment()
+	return &comment, nil
+}
+
+// IssueURL returns the browser URL of an issue
+func (c *Client) IssueURL(issueKey string) string {
+	return c.BaseURL + "/browse/" + issueKey
+}
+
+// commentResponse is a comment as returned by the API
+type commentResponse struct {
+	ID     string `json:"id"`
+	Body   string `json:"body"`
+	Author struct {
+		DisplayName string `json:"displayName"`
+	} `json:"author"`
+	Created string `json:"created"`
+}
+
+// toComment converts an API comment
+func (r *commentResponse) toComment() Comment {
+	created, _ := time.Parse(timeLayout, r.Created)
+	return Comment{ID: r.ID, Author: r.Author.DisplayName, Body: r.Body, Created: created}
+}
+
+// request sends an authenticated API request and decodes the JSON response
+func (c *Client) request(ctx context.Context, method, endpoint string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(c.Email, c.APIToken)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("jira API error (status %d): %s", resp.StatusCode, string(responseBody))
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
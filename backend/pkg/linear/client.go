@@ -0,0 +1,336 @@
+package linear
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultEndpoint = "https://api.linear.app/graphql"
+
+// ErrNotFound is returned when a team or issue doesn't exist or the API key can't see it
+var ErrNotFound = errors.New("linear resource not found")
+
+// Client is a minimal Linear GraphQL API client for syncing issues
+type Client struct {
+	APIKey     string
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new Linear API client for a personal API key
+func NewClient(apiKey string) *Client {
+	return &Client{
+		APIKey:   apiKey,
+		Endpoint: defaultEndpoint,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Team is the metadata of a team
+type Team struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// WorkflowState is a status in a team's workflow
+type WorkflowState struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Type is "triage", "backlog", "unstarted", "started", "completed" or "canceled"
+	Type     string  `json:"type"`
+	Position float64 `json:"position"`
+}
+
+// Issue is an issue with the fields used for syncing
+type Issue struct {
+	ID          string        `json:"id"`
+	Identifier  string        `json:"identifier"`
+	URL         string        `json:"url"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+	State       WorkflowState `json:"state"`
+}
+
+// Comment is a comment on an issue
+type Comment struct {
+	ID        string    `json:"id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	User      *struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+// IssueInput are the editable fields of an issue. Empty fields are left unchanged on update.
+type IssueInput struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	StateID     string `json:"stateId,omitempty"`
+}
+
+const issueFields = `id identifier url title description updatedAt state { id name type position }`
+
+// GetTeam returns a team's metadata
+func (c *Client) GetTeam(ctx context.Context, teamID string) (*Team, error) {
+	var response struct {
+		Team *Team `json:"team"`
+	}
+	query := `query($id: String!) { team(id: $id) { id key name } }`
+	if err := c.query(ctx, query, map[string]interface{}{"id": teamID}, &response); err != nil {
+		return nil, err
+	}
+	if response.Team == nil {
+		return nil, ErrNotFound
+	}
+	return response.Team, nil
+}
+
+// ListWorkflowStates returns a team's workflow states
+func (c *Client) ListWorkflowStates(ctx context.Context, teamID string) ([]WorkflowState, error) {
+	var response struct {
+		WorkflowStates struct {
+			Nodes []WorkflowState `json:"nodes"`
+		} `json:"workflowStates"`
+	}
+	query := `query($teamId: ID!) { workflowStates(filter: { team: { id: { eq: $teamId } } }) { nodes { id name type position } } }`
+	if err := c.query(ctx, query, map[string]interface{}{"teamId": teamID}, &response); err != nil {
+		return nil, err
+	}
+	return response.WorkflowStates.Nodes, nil
+}
+
+// ListIssues returns the team's issues updated since a time
+func (c *Client) ListIssues(ctx context.Context, teamID string, updatedSince time.Time, limit int) ([]Issue, error) {
+	filter := map[string]interface{}{
+		"team": map[string]interface{}{"id": map[string]string{"eq": teamID}},
+	}
+	if !updatedSince.IsZero() {
+		filter["updatedAt"] = map[string]string{"gte": updatedSince.UTC().Format(time.RFC3339)}
+	}
+
+	query := `query($filter: IssueFilter, $after: String) {
+		issues(filter: $filter, first: 100, after: $after, orderBy: updatedAt) {
+			nodes { ` + issueFields + ` }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	var issues []Issue
+	var after interface{}
+	for len(issues) < limit {
+		var response struct {
+			Issues struct {
+				Nodes    []Issue `json:"nodes"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"issues"`
+		}
+		if err := c.query(ctx, query, map[string]interface{}{"filter": filter, "after": after}, &response); err != nil {
+			return nil, err
+		}
+		issues = append(issues, response.Issues.Nodes...)
+		if !response.Issues.PageInfo.HasNextPage {
+			break
+		}
+		after = response.Issues.PageInfo.EndCursor
+	}
+
+	if len(issues) > limit {
+		issues = issues[:limit]
+	}
+	return issues, nil
+}
+
+// GetIssue returns an issue by ID
+func (c *Client) GetIssue(ctx context.Context, issueID string) (*Issue, error) {
+	var response struct {
+		Issue *Issue `json:"issue"`
+	}
+	query := `query($id: String!) { issue(id: $id) { ` + issueFields + ` } }`
+	if err := c.query(ctx, query, map[string]interface{}{"id": issueID}, &response); err != nil {
+		return nil, err
+	}
+	if response.Issue == nil {
+		return nil, ErrNotFound
+	}
+	return response.Issue, nil
+}
+
+// CreateIssue creates an issue in a team
+func (c *Client) CreateIssue(ctx context.Context, teamID string, input IssueInput) (*Issue, error) {
+	variables := map[string]interface{}{
+		"input": map[string]interface{}{
+			"teamId":      teamID,
+			"title":       input.Title,
+			"description": input.Description,
+			"stateId":     nullable(input.StateID),
+		},
+	}
+	var response struct {
+		IssueCreate struct {
+			Success bool   `json:"success"`
+			Issue   *Issue `json:"issue"`
+		} `json:"issueCreate"`
+	}
+	query := `mutation($input: IssueCreateInput!) { issueCreate(input: $input) { success issue { ` + issueFields + ` } } }`
+	if err := c.query(ctx, query, variables, &response); err != nil {
+		return nil, err
+	}
+	if !response.IssueCreate.Success || response.IssueCreate.Issue == nil {
+		return nil, errors.New("linear API error: issue was not created")
+	}
+	return response.IssueCreate.Issue, nil
+}
+
+// UpdateIssue updates an issue and returns it
+func (c *Client) UpdateIssue(ctx context.Context, issueID string, input IssueInput) (*Issue, error) {
+	var response struct {
+		IssueUpdate struct {
+			Success bool   `json:"success"`
+			Issue   *Issue `json:"issue"`
+		} `json:"issueUpdate"`
+	}
+	query := `mutation($id: String!, $input: IssueUpdateInput!) { issueUpdate(id: $id, input: $input) { success issue { ` + issueFields + ` } } }`
+	if err := c.query(ctx, query, map[string]interface{}{"id": issueID, "input": input}, &response); err != nil {
+		return nil, err
+	}
+	if !response.IssueUpdate.Success || response.IssueUpdate.Issue == nil {
+		return nil, errors.New("linear API error: issue was not updated")
+	}
+	return response.IssueUpdate.Issue, nil
+}
+
+// ListComments returns an issue's comments, oldest first
+func (c *Client) ListComments(ctx context.Context, issueID string) ([]Comment, error) {
+	var response struct {
+		Issue *struct {
+			Comments struct {
+				Nodes []Comment `json:"nodes"`
+			} `json:"comments"`
+		} `json:"issue"`
+	}
+	query := `query($id: String!) { issue(id: $id) { comments(first: 100, orderBy: createdAt) { nodes { id body createdAt user { name } } } } }`
+	if err := c.query(ctx, query, map[string]interface{}{"id": issueID}, &response); err != nil {
+		return nil, err
+	}
+	if response.Issue == nil {
+		return nil, ErrNotFound
+	}
+
+	comments := response.Issue.Comments.Nodes
+	// orderBy only supports descending order, so reverse to oldest first
+	for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
+		comments[i], comments[j] = comments[j], comments[i]
+	}
+	return comments, nil
+}
+
+// AddComment adds a comment to an issue
+func (c *Client) AddComment(ctx context.Context, issueID, body string) (*Comment, error) {
+	var response struct {
+		CommentCreate struct {
+			Success bool     `json:"success"`
+			Comment *Comment `json:"comment"`
+		} `json:"commentCreate"`
+	}
+	query := `mutation($input: CommentCreateInput!) { commentCreate(input: $input) { success comment { id body createdAt user { name } } } }`
+	variables := map[string]interface{}{
+		"input": map[string]string{"issueId": issueID, "body": body},
+	}
+	if err := c.query(ctx, query, variables, &response); err != nil {
+		return nil, err
+	}
+	if !response.CommentCreate.Success || response.CommentCreate.Comment == nil {
+		return nil, errors.New("linear API error: comment was not created")
+	}
+	return response.CommentCreate.Comment, nil
+}
+
+// query sends a GraphQL request and decodes its data
+func (c *Client) query(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	encoded, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", c.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("linear API error (status %d): %s", resp.StatusCode, truncate(string(body), 4096))
+	}
+	if len(response.Errors) > 0 {
+		messages := make([]string, len(response.Errors))
+		for i, e := range response.Errors {
+			if strings.Contains(strings.ToLower(e.Message), "not found") || e.Extensions.Code == "NOT_FOUND" {
+				return ErrNotFound
+			}
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("linear API error (status %d): %s", resp.StatusCode, strings.Join(messages, "; "))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("linear API error (status %d): %s", resp.StatusCode, truncate(string(body), 4096))
+	}
+
+	if err := json.Unmarshal(response.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// nullable sends empty strings as null
+func nullable(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// truncate shortens error bodies
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	return text[:max]
+}
@@ -0,0 +1,256 @@
+package notesapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a note or chapter doesn't exist or the API key can't reach it
+	ErrNotFound = errors.New("notes resource not found")
+	// ErrConflict is returned when a note changed on the server since it was last read
+	ErrConflict = errors.New("note changed on the server")
+)
+
+// ConflictError is ErrConflict with the conflict the server kept, to fetch its merge with GetConflict
+type ConflictError struct {
+	ConflictID    string
+	ConflictCount int
+}
+
+func (e *ConflictError) Error() string {
+	return ErrConflict.Error()
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// Client is a client for the notes automation API, authenticated with an API key created in the
+// app's settings
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new notes API client for a server (http://localhost:8080)
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		HTTPClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// Note is a note with its content as Markdown
+type Note struct {
+	NoteID       string    `json:"noteId"`
+	Name         string    `json:"name"`
+	Slug         string    `json:"slug"`
+	Content      string    `json:"content"`
+	ChapterID    string    `json:"chapterId"`
+	ChapterName  string    `json:"chapterName"`
+	NotebookID   string    `json:"notebookId"`
+	NotebookName string    `json:"notebookName"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// Chapter is a chapter notes can be created in
+type Chapter struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	NotebookID   string `json:"notebookId"`
+	NotebookName string `json:"notebookName"`
+	NotebookSlug string `json:"notebookSlug"`
+}
+
+// Export is every note of the API key's workspace with its chapters
+type Export struct {
+	Chapters []Chapter `json:"chapters"`
+	Notes    []Note    `json:"notes"`
+}
+
+// Changes is what changed in the workspace since a cursor
+type Changes struct {
+	Cursor   time.Time `json:"cursor"` // Pass back as since for the next changes
+	Chapters []Chapter `json:"chapters"`
+	Notes    []Note    `json:"notes"`   // Updated since the cursor, or all without one
+	NoteIDs  []string  `json:"noteIds"` // Every note, to spot deleted ones
+}
+
+// NoteUpdate renames a note or replaces its content. When UpdatedAt is set the server refuses the
+// update with ErrConflict if the note changed since, and merges the content with its version from
+// BaseContent, the content last synced.
+type NoteUpdate struct {
+	Name        *string    `json:"name,omitempty"`
+	Content     *string    `json:"content,omitempty"`
+	BaseContent *string    `json:"baseContent,omitempty"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Conflict is an update the server refused because the note changed, with both versions and their merge
+type Conflict struct {
+	ID              string    `json:"id"`
+	NoteID          string    `json:"noteId"`
+	Name            string    `json:"name"`
+	Base            string    `json:"base"`
+	Local           string    `json:"local"`
+	Server          string    `json:"server"`
+	Merged          string    `json:"merged"`          // Blocks changed on both sides are kept from both, quoted
+	ConflictCount   int       `json:"conflictCount"`   // Blocks changed on both sides
+	ServerUpdatedAt time.Time `json:"serverUpdatedAt"` // Upload the resolution with this as UpdatedAt
+}
+
+// Me describes the API key the client authenticates with
+type Me struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	UserID         string  `json:"userId"`
+	OrganizationID *string `json:"organizationId,omitempty"`
+}
+
+// GetMe returns the API key the client authenticates with, to check it works
+func (c *Client) GetMe(ctx context.Context) (*Me, error) {
+	var me Me
+	if err := c.request(ctx, http.MethodGet, "/api/automation/me", nil, &me); err != nil {
+		return nil, err
+	}
+	return &me, nil
+}
+
+// CreateNote creates a note from Markdown in a chapter
+func (c *Client) CreateNote(ctx context.Context, chapterID, name, content string) (*Note, error) {
+	payload := map[string]string{"chapterId": chapterID, "name": name, "content": content}
+	var note Note
+	if err := c.request(ctx, http.MethodPost, "/api/automation/actions/notes", payload, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// UpdateNote renames a note or replaces its content
+func (c *Client) UpdateNote(ctx context.Context, noteID string, update NoteUpdate) (*Note, error) {
+	var note Note
+	if err := c.request(ctx, http.MethodPut, "/api/automation/notes/"+url.PathEscape(noteID), update, &note); err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// GetConflict returns a conflict from a refused update, with the merge of both versions
+func (c *Client) GetConflict(ctx context.Context, conflictID string) (*Conflict, error) {
+	var conflict Conflict
+	if err := c.request(ctx, http.MethodGet, "/api/automation/conflicts/"+url.PathEscape(conflictID), nil, &conflict); err != nil {
+		return nil, err
+	}
+	return &conflict, nil
+}
+
+// SearchNotes returns the notes whose name or content contains the query, most recently updated first
+func (c *Client) SearchNotes(ctx context.Context, query string, limit int) ([]Note, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	var notes []Note
+	if err := c.request(ctx, http.MethodGet, "/api/automation/notes?"+params.Encode(), nil, &notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// Export returns every note of the workspace with its chapters
+func (c *Client) Export(ctx context.Context) (*Export, error) {
+	var export Export
+	if err := c.request(ctx, http.MethodGet, "/api/automation/export", nil, &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetChanges returns the notes updated since a cursor from an earlier call, or all notes for a zero cursor
+func (c *Client) GetChanges(ctx context.Context, since time.Time) (*Changes, error) {
+	endpoint := "/api/automation/changes"
+	if !since.IsZero() {
+		endpoint += "?since=" + url.QueryEscape(since.UTC().Format(time.RFC3339Nano))
+	}
+	var changes Changes
+	if err := c.request(ctx, http.MethodGet, endpoint, nil, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+func (c *Client) request(ctx context.Context, method, endpoint string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		var conflict struct {
+			ConflictID    string `json:"conflictId"`
+			ConflictCount int    `json:"conflictCount"`
+		}
+		if json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&conflict) == nil && conflict.ConflictID != "" {
+			return &ConflictError{ConflictID: conflict.ConflictID, ConflictCount: conflict.ConflictCount}
+		}
+		return ErrConflict
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiError struct {
+			Error string `json:"error"`
+		}
+		responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(responseBody, &apiError) == nil && apiError.Error != "" {
+			return fmt.Errorf("notes API error (status %d): %s", resp.StatusCode, apiError.Error)
+		}
+		return fmt.Errorf("notes API error (status %d): %s", resp.StatusCode, string(responseBody))
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
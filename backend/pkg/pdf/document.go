@@ -0,0 +1,448 @@
+// Package pdf writes simple PDF documents of text, lines, filled rectangles and images using the
+// standard fonts, with internal links and bookmarks. Coordinates are in points from the top-left corner.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+// Page sizes in points
+var (
+	A4     = Size{Width: 595.28, Height: 841.89}
+	Letter = Size{Width: 612, Height: 792}
+	Legal  = Size{Width: 612, Height: 1008}
+)
+
+// Size is a page size in points
+type Size struct {
+	Width  float64
+	Height float64
+}
+
+// Color is an RGB color with components from 0 to 1
+type Color struct {
+	R, G, B float64
+}
+
+// Gray returns a shade of gray from 0 (black) to 1 (white)
+func Gray(level float64) Color {
+	return Color{R: level, G: level, B: level}
+}
+
+// Black is the default text color
+var Black = Color{}
+
+// Document is a PDF being written. Pages are kept in memory until WriteTo.
+type Document struct {
+	size      Size
+	title     string
+	author    string
+	created   time.Time
+	pages     []*Page
+	bookmarks []bookmark
+}
+
+// Page is a page of a document
+type Page struct {
+	index   int
+	size    Size
+	content bytes.Buffer
+	links   []link
+	images  []image.Image
+}
+
+// link is a clickable area of a page that jumps to a position on another page
+type link struct {
+	x, y, width, height float64
+	target              *Page
+	targetY             float64
+}
+
+// bookmark is an entry of the document outline readers show next to the pages
+type bookmark struct {
+	title  string
+	page   *Page
+	y      float64
+	parent int // Index of the parent bookmark, -1 at the top level
+}
+
+// New creates a document whose pages are the given size
+func New(size Size) *Document {
+	return &Document{size: size, created: time.Now()}
+}
+
+// SetInfo sets the title and author shown in the reader's document properties
+func (d *Document) SetInfo(title, author string) {
+	d.title = title
+	d.author = author
+}
+
+// SetCreationDate sets the creation date of the document, now by default
+func (d *Document) SetCreationDate(created time.Time) {
+	d.created = created
+}
+
+// AddPage appends a blank page
+func (d *Document) AddPage() *Page {
+	page := &Page{index: len(d.pages), size: d.size}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages of the document in order
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// AddBookmark adds an outline entry that jumps to a position on a page and returns its index, to be used
+// as the parent of nested entries. Top-level entries have a parent of -1.
+func (d *Document) AddBookmark(title string, page *Page, y float64, parent int) int {
+	d.bookmarks = append(d.bookmarks, bookmark{title: title, page: page, y: y, parent: parent})
+	return len(d.bookmarks) - 1
+}
+
+// Number returns the 1-based page number
+func (p *Page) Number() int {
+	return p.index + 1
+}
+
+// Text draws text with its baseline at y
+func (p *Page) Text(x, y float64, font Font, size float64, color Color, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(&p.content, "%s rg BT /F%d %s Tf %s %s Td ", color.operands(), int(font)+1, num(size), num(x), num(p.size.Height-y))
+	writeString(&p.content, encode(font, text))
+	p.content.WriteString(" Tj ET\n")
+}
+
+// Line draws a straight line
+func (p *Page) Line(x1, y1, x2, y2, width float64, color Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n", color.operands(), num(width),
+		num(x1), num(p.size.Height-y1), num(x2), num(p.size.Height-y2))
+}
+
+// FillRect fills a rectangle whose top-left corner is at x, y
+func (p *Page) FillRect(x, y, width, height float64, color Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n", color.operands(), num(x), num(p.size.Height-y-height), num(width), num(height))
+}
+
+// Image draws an image scaled to a rectangle whose top-left corner is at x, y. Transparent parts of the
+// image are drawn over white.
+func (p *Page) Image(x, y, width, height float64, img image.Image) {
+	p.images = append(p.images, img)
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(width), num(height), num(x), num(p.size.Height-y-height), len(p.images))
+}
+
+// Link makes a rectangle whose top-left corner is at x, y jump to a position on another page
+func (p *Page) Link(x, y, width, height float64, target *Page, targetY float64) {
+	p.links = append(p.links, link{x: x, y: y, width: width, height: height, target: target, targetY: targetY})
+}
+
+// WriteTo writes the document as a PDF
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &writer{w: w}
+	out.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// The catalog, page tree, fonts and document info come first, then every page takes two objects,
+	// followed by images, links and bookmarks
+	const catalogObj, pagesObj, firstFontObj = 1, 2, 3
+	infoObj := firstFontObj + len(fontNames)
+	firstPageObj := infoObj + 1
+	pageObj := func(page *Page) int { return firstPageObj + 2*page.index }
+	next := firstPageObj + 2*len(d.pages)
+	imageObjs := make([][]int, len(d.pages))
+	linkObjs := make([][]int, len(d.pages))
+	for i, page := range d.pages {
+		for range page.images {
+			imageObjs[i] = append(imageObjs[i], next)
+			next++
+		}
+	}
+	for i, page := range d.pages {
+		for range page.links {
+			linkObjs[i] = append(linkObjs[i], next)
+			next++
+		}
+	}
+	outlinesObj := 0
+	if len(d.bookmarks) > 0 {
+		outlinesObj = next
+		next += 1 + len(d.bookmarks)
+	}
+	bookmarkObj := func(i int) int { return outlinesObj + 1 + i }
+
+	catalog := fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R", pagesObj)
+	if outlinesObj > 0 {
+		catalog += fmt.Sprintf(" /Outlines %d 0 R /PageMode /UseOutlines", outlinesObj)
+	}
+	out.object(catalogObj, catalog+" >>")
+
+	kids := new(bytes.Buffer)
+	for _, page := range d.pages {
+		fmt.Fprintf(kids, "%d 0 R ", pageObj(page))
+	}
+	out.object(pagesObj, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] >>",
+		bytes.TrimSpace(kids.Bytes()), len(d.pages), num(d.size.Width), num(d.size.Height)))
+
+	for i, name := range fontNames {
+		encoding := " /Encoding /WinAnsiEncoding"
+		if Font(i) == Symbol {
+			// The Symbol font only has its own glyphs, in its built-in encoding
+			encoding = ""
+		}
+		out.object(firstFontObj+i, fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s%s >>", name, encoding))
+	}
+
+	info := new(bytes.Buffer)
+	info.WriteString("<< /Producer ")
+	writeTextString(info, "notes-app")
+	if d.title != "" {
+		info.WriteString(" /Title ")
+		writeTextString(info, d.title)
+	}
+	if d.author != "" {
+		info.WriteString(" /Author ")
+		writeTextString(info, d.author)
+	}
+	fmt.Fprintf(info, " /CreationDate (D:%s) >>", d.created.UTC().Format("20060102150405Z"))
+	out.object(infoObj, info.String())
+
+	var fonts bytes.Buffer
+	for i := range fontNames {
+		fmt.Fprintf(&fonts, "/F%d %d 0 R ", i+1, firstFontObj+i)
+	}
+	for i, page := range d.pages {
+		annots := ""
+		if len(linkObjs[i]) > 0 {
+			refs := new(bytes.Buffer)
+			for _, obj := range linkObjs[i] {
+				fmt.Fprintf(refs, "%d 0 R ", obj)
+			}
+			annots = fmt.Sprintf(" /Annots [%s]", bytes.TrimSpace(refs.Bytes()))
+		}
+		xObjects := ""
+		if len(imageObjs[i]) > 0 {
+			refs := new(bytes.Buffer)
+			for j, obj := range imageObjs[i] {
+				fmt.Fprintf(refs, "/Im%d %d 0 R ", j+1, obj)
+			}
+			xObjects = fmt.Sprintf(" /XObject << %s>>", refs.String())
+		}
+		out.object(pageObj(page), fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Resources << /Font << %s>>%s >> /Contents %d 0 R%s >>",
+			pagesObj, fonts.String(), xObjects, pageObj(page)+1, annots))
+
+		compressed, err := deflate(page.content.Bytes())
+		if err != nil {
+			return out.n, err
+		}
+		out.stream(pageObj(page)+1, "", compressed)
+	}
+
+	for i, page := range d.pages {
+		for j, img := range page.images {
+			bounds := img.Bounds()
+			compressed, err := deflate(rgbSamples(img))
+			if err != nil {
+				return out.n, err
+			}
+			out.stream(imageObjs[i][j], fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 ",
+				bounds.Dx(), bounds.Dy()), compressed)
+		}
+	}
+
+	for i, page := range d.pages {
+		for j, l := range page.links {
+			bottom := page.size.Height - l.y - l.height
+			out.object(linkObjs[i][j], fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect [%s %s %s %s] /Border [0 0 0] /Dest [%d 0 R /XYZ 0 %s 0] >>",
+				num(l.x), num(bottom), num(l.x+l.width), num(bottom+l.height), pageObj(l.target), num(page.size.Height-l.targetY)))
+		}
+	}
+
+	if outlinesObj > 0 {
+		d.writeOutline(out, outlinesObj, bookmarkObj, pageObj)
+	}
+
+	if out.err != nil {
+		return out.n, out.err
+	}
+	xref := out.n
+	out.printf("xref\n0 %d\n0000000000 65535 f \n", next)
+	for obj := 1; obj < next; obj++ {
+		out.printf("%010d 00000 n \n", out.offsets[obj])
+	}
+	out.printf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", next, catalogObj, infoObj, xref)
+	return out.n, out.err
+}
+
+// Bytes returns the document as a PDF
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeOutline writes the outline root and its bookmarks, linked to their parents and siblings
+func (d *Document) writeOutline(out *writer, outlinesObj int, bookmarkObj func(int) int, pageObj func(*Page) int) {
+	children := map[int][]int{}
+	for i, b := range d.bookmarks {
+		children[b.parent] = append(children[b.parent], i)
+	}
+	// descendants counts the open entries under a bookmark, which readers use to draw the tree
+	var descendants func(parent int) int
+	descendants = func(parent int) int {
+		count := 0
+		for _, child := range children[parent] {
+			count += 1 + descendants(child)
+		}
+		return count
+	}
+
+	top := children[-1]
+	out.object(outlinesObj, fmt.Sprintf("<< /Type /Outlines /First %d 0 R /Last %d 0 R /Count %d >>",
+		bookmarkObj(top[0]), bookmarkObj(top[len(top)-1]), descendants(-1)))
+
+	for i, b := range d.bookmarks {
+		entry := new(bytes.Buffer)
+		entry.WriteString("<< /Title ")
+		writeTextString(entry, b.title)
+		parent := outlinesObj
+		if b.parent >= 0 {
+			parent = bookmarkObj(b.parent)
+		}
+		fmt.Fprintf(entry, " /Parent %d 0 R", parent)
+		siblings := children[b.parent]
+		for k, sibling := range siblings {
+			if sibling != i {
+				continue
+			}
+			if k > 0 {
+				fmt.Fprintf(entry, " /Prev %d 0 R", bookmarkObj(siblings[k-1]))
+			}
+			if k < len(siblings)-1 {
+				fmt.Fprintf(entry, " /Next %d 0 R", bookmarkObj(siblings[k+1]))
+			}
+		}
+		if kids := children[i]; len(kids) > 0 {
+			fmt.Fprintf(entry, " /First %d 0 R /Last %d 0 R /Count %d", bookmarkObj(kids[0]), bookmarkObj(kids[len(kids)-1]), descendants(i))
+		}
+		fmt.Fprintf(entry, " /Dest [%d 0 R /XYZ 0 %s 0] >>", pageObj(b.page), num(b.page.size.Height-b.y))
+		out.object(bookmarkObj(i), entry.String())
+	}
+}
+
+// writer tracks the byte offset of every object for the cross-reference table
+type writer struct {
+	w       io.Writer
+	n       int64
+	err     error
+	offsets map[int]int64
+}
+
+func (w *writer) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *writer) object(obj int, body string) {
+	w.begin(obj)
+	w.printf("%s\nendobj\n", body)
+}
+
+// stream writes a compressed stream object, whose dictionary holds the given entries besides its length
+func (w *writer) stream(obj int, entries string, data []byte) {
+	w.begin(obj)
+	w.printf("<< %s/Length %d /Filter /FlateDecode >>\nstream\n", entries, len(data))
+	if w.err == nil {
+		n, err := w.w.Write(data)
+		w.n += int64(n)
+		w.err = err
+	}
+	w.printf("\nendstream\nendobj\n")
+}
+
+func (w *writer) begin(obj int) {
+	if w.offsets == nil {
+		w.offsets = map[int]int64{}
+	}
+	w.offsets[obj] = w.n
+	w.printf("%d 0 obj\n", obj)
+}
+
+// deflate compresses the data of a stream
+func deflate(data []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// rgbSamples returns the pixels of an image as 8-bit RGB samples, row by row, blending transparent pixels
+// with white
+func rgbSamples(img image.Image) []byte {
+	bounds := img.Bounds()
+	samples := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// Colors are premultiplied by alpha, so adding the missing coverage as white blends them
+			r, g, b, a := img.At(x, y).RGBA()
+			white := 0xffff - a
+			samples = append(samples, byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8))
+		}
+	}
+	return samples
+}
+
+// operands returns the color as the operands of a color operator
+func (c Color) operands() string {
+	return num(c.R) + " " + num(c.G) + " " + num(c.B)
+}
+
+// num formats a number to two decimals the way PDF content streams expect, without exponents or
+// trailing zeros
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// writeString writes bytes as a PDF literal string
+func writeString(buf *bytes.Buffer, s []byte) {
+	buf.WriteByte('(')
+	for _, b := range s {
+		switch b {
+		case '(', ')', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		default:
+			buf.WriteByte(b)
+		}
+	}
+	buf.WriteByte(')')
+}
+
+// writeTextString writes text outside content streams, such as titles, as UTF-16 so any language shows
+func writeTextString(buf *bytes.Buffer, text string) {
+	buf.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(buf, "%04X", unit)
+	}
+	buf.WriteByte('>')
+}
@@ -0,0 +1,189 @@
+package pdf
+
+import "unicode/utf8"
+
+// Font is one of the standard PDF fonts, which every reader has and which aren't embedded
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+	HelveticaOblique
+	HelveticaBoldOblique
+	Courier
+	Symbol // Greek letters and mathematical symbols, in its own encoding
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Helvetica-BoldOblique", "Courier", "Symbol"}
+
+// helveticaWidths and helveticaBoldWidths are the widths of characters 32 to 126 in thousandths of
+// the font size, from the Adobe font metrics. The oblique fonts share the widths of their upright ones.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// winAnsiSpecials maps the typographic characters of WinAnsiEncoding outside Latin-1 to their codes,
+// with their regular and bold widths
+var winAnsiSpecials = map[rune]struct {
+	code          byte
+	regular, bold int
+}{
+	'€': {0x80, 556, 556},
+	'…': {0x85, 1000, 1000},
+	'‘': {0x91, 222, 278},
+	'’': {0x92, 222, 278},
+	'“': {0x93, 333, 500},
+	'”': {0x94, 333, 500},
+	'•': {0x95, 350, 350},
+	'–': {0x96, 556, 556},
+	'—': {0x97, 1000, 1000},
+	'™': {0x99, 1000, 1000},
+}
+
+// symbolGlyphs maps characters to their codes in the Symbol font's built-in encoding, with their widths
+var symbolGlyphs = map[rune]struct {
+	code  byte
+	width int
+}{
+	' ': {32, 250}, '!': {33, 333}, '∀': {34, 713}, '#': {35, 500}, '∃': {36, 549}, '%': {37, 833}, '&': {38, 778},
+	'∋': {39, 439}, '(': {40, 333}, ')': {41, 333}, '∗': {42, 500}, '+': {43, 549}, ',': {44, 250}, '−': {45, 549},
+	'.': {46, 250}, '/': {47, 278}, '0': {48, 500}, '1': {49, 500}, '2': {50, 500}, '3': {51, 500}, '4': {52, 500},
+	'5': {53, 500}, '6': {54, 500}, '7': {55, 500}, '8': {56, 500}, '9': {57, 500}, ':': {58, 278}, ';': {59, 278},
+	'<': {60, 549}, '=': {61, 549}, '>': {62, 549}, '?': {63, 444}, '≅': {64, 549},
+	'Α': {65, 722}, 'Β': {66, 667}, 'Χ': {67, 722}, 'Δ': {68, 612}, 'Ε': {69, 611}, 'Φ': {70, 763}, 'Γ': {71, 603},
+	'Η': {72, 722}, 'Ι': {73, 333}, 'ϑ': {74, 631}, 'Κ': {75, 722}, 'Λ': {76, 686}, 'Μ': {77, 889}, 'Ν': {78, 722},
+	'Ο': {79, 722}, 'Π': {80, 768}, 'Θ': {81, 741}, 'Ρ': {82, 556}, 'Σ': {83, 592}, 'Τ': {84, 611}, 'Υ': {85, 690},
+	'ς': {86, 439}, 'Ω': {87, 768}, 'Ξ': {88, 645}, 'Ψ': {89, 795}, 'Ζ': {90, 611}, '[': {91, 333}, '∴': {92, 863},
+	']': {93, 333}, '⊥': {94, 658}, '_': {95, 500},
+	'α': {97, 631}, 'β': {98, 549}, 'χ': {99, 549}, 'δ': {100, 494}, 'ε': {101, 439}, 'ϵ': {101, 439},
+	'φ': {102, 521}, 'γ': {103, 411}, 'η': {104, 603}, 'ι': {105, 329}, 'ϕ': {106, 603}, 'κ': {107, 549},
+	'λ': {108, 549}, 'μ': {109, 576}, 'ν': {110, 521}, 'ο': {111, 549}, 'π': {112, 549}, 'θ': {113, 521},
+	'ρ': {114, 549}, 'σ': {115, 603}, 'τ': {116, 439}, 'υ': {117, 576}, 'ϖ': {118, 713}, 'ω': {119, 686},
+	'ξ': {120, 493}, 'ψ': {121, 686}, 'ζ': {122, 494}, '{': {123, 480}, '|': {124, 200}, '∣': {124, 200},
+	'}': {125, 480}, '∼': {126, 549},
+	'ϒ': {161, 620}, '′': {162, 247}, '≤': {163, 549}, '∞': {165, 713}, '↔': {171, 1042}, '←': {172, 987},
+	'↑': {173, 603}, '→': {174, 987}, '↓': {175, 603}, '°': {176, 400}, '±': {177, 549}, '″': {178, 411},
+	'≥': {179, 549}, '×': {180, 549}, '∝': {181, 713}, '∂': {182, 494}, '•': {183, 460}, '÷': {184, 549},
+	'≠': {185, 549}, '≡': {186, 549}, '≈': {187, 549}, '…': {188, 1000}, 'ℵ': {192, 823}, 'ℑ': {193, 686},
+	'ℜ': {194, 795}, '℘': {195, 987}, '⊗': {196, 768}, '⊕': {197, 768}, '∅': {198, 823}, '∩': {199, 768},
+	'∪': {200, 768}, '⊃': {201, 713}, '⊇': {202, 713}, '⊄': {203, 713}, '⊂': {204, 713}, '⊆': {205, 713},
+	'∈': {206, 713}, '∉': {207, 713}, '∠': {208, 768}, '∇': {209, 713}, '∏': {213, 823}, '√': {214, 549},
+	'⋅': {215, 250}, '¬': {216, 713}, '∧': {217, 603}, '∨': {218, 603}, '⇔': {219, 1042}, '⇐': {220, 987},
+	'⇑': {221, 603}, '⇒': {222, 987}, '⇓': {223, 603}, '◊': {224, 494}, '⟨': {225, 329}, '∑': {229, 713},
+	'⌈': {233, 384}, '⌊': {235, 384}, '⟩': {241, 329}, '∫': {242, 274}, '⌉': {249, 384}, '⌋': {251, 384},
+}
+
+// encode converts text to the encoding of a font: the Symbol font's own, or WinAnsiEncoding for the others.
+// Characters the font doesn't have are replaced with "?".
+func encode(font Font, text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		if font == Symbol {
+			if glyph, ok := symbolGlyphs[r]; ok {
+				out = append(out, glyph.code)
+			} else {
+				out = append(out, symbolGlyphs['?'].code)
+			}
+			continue
+		}
+		out = append(out, encodeRune(r))
+	}
+	return out
+}
+
+// Encodable reports whether a font has a character
+func Encodable(font Font, r rune) bool {
+	if font == Symbol {
+		_, ok := symbolGlyphs[r]
+		return ok
+	}
+	_, special := winAnsiSpecials[r]
+	return r >= 32 && r <= 126 || r >= 0xA0 && r <= 0xFF || special
+}
+
+func encodeRune(r rune) byte {
+	switch {
+	case r == '\t':
+		return ' '
+	case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+		return byte(r)
+	}
+	if special, ok := winAnsiSpecials[r]; ok {
+		return special.code
+	}
+	return '?'
+}
+
+// runeWidth returns the width of a character in thousandths of the font size
+func runeWidth(font Font, r rune) int {
+	switch font {
+	case Courier:
+		return 600
+	case Symbol:
+		if glyph, ok := symbolGlyphs[r]; ok {
+			return glyph.width
+		}
+		return symbolGlyphs['?'].width
+	}
+	bold := font == HelveticaBold || font == HelveticaBoldOblique
+	if special, ok := winAnsiSpecials[r]; ok {
+		if bold {
+			return special.bold
+		}
+		return special.regular
+	}
+	if r == '\t' || r >= 0xA0 && r <= 0xFF {
+		// Latin-1 letters are about as wide as the average lowercase letter
+		if bold {
+			return 611
+		}
+		return 556
+	}
+	if r < 32 || r > 126 {
+		r = '?'
+	}
+	if bold {
+		return helveticaBoldWidths[r-32]
+	}
+	return helveticaWidths[r-32]
+}
+
+// TextWidth returns the width of text set in a font at a size, in points
+func TextWidth(font Font, size float64, text string) float64 {
+	total := 0
+	for _, r := range text {
+		total += runeWidth(font, r)
+	}
+	return float64(total) * size / 1000
+}
+
+// FitText returns how many bytes of text fit in a width, breaking between characters
+func FitText(font Font, size float64, text string, width float64) int {
+	used := 0.0
+	for i, r := range text {
+		used += float64(runeWidth(font, r)) * size / 1000
+		if used > width {
+			if i == 0 {
+				// Always fit at least one character so callers make progress
+				_, n := utf8.DecodeRuneInString(text)
+				return n
+			}
+			return i
+		}
+	}
+	return len(text)
+}
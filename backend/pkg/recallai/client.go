@@ -2,6 +2,7 @@ package recallai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -47,15 +48,54 @@ func (c *Client) getCalendarBaseURL() string {
 
 // CreateBotRequest represents the request payload for creating a bot
 type CreateBotRequest struct {
-	MeetingURL      string          `json:"meeting_url"`
-	RecordingConfig RecordingConfig `json:"recording_config"`
+	MeetingURL      string                `json:"meeting_url"`
+	BotName         string                `json:"bot_name,omitempty"`
+	RecordingConfig RecordingConfig       `json:"recording_config"`
+	Chat            *ChatConfig           `json:"chat,omitempty"`
+	AutomaticLeave  *AutomaticLeaveConfig `json:"automatic_leave,omitempty"`
+}
+
+// BotOptions are how the bot presents itself in a meeting
+type BotOptions struct {
+	BotName                string // Shown as the bot's participant name, Recall.ai's default when empty
+	JoinMessage            string // Sent to the meeting chat when the bot joins, nothing when empty
+	LeaveIfRecordingDenied bool   // Leave as soon as the host denies recording permission
+}
+
+// ChatConfig defines the messages the bot sends to the meeting chat
+type ChatConfig struct {
+	OnBotJoin *ChatMessage `json:"on_bot_join,omitempty"`
+}
+
+// ChatMessage is a message sent to the meeting chat
+type ChatMessage struct {
+	SendTo  string `json:"send_to"` // "everyone" or "host"
+	Message string `json:"message"`
+}
+
+// AutomaticLeaveConfig defines when the bot leaves the meeting on its own
+type AutomaticLeaveConfig struct {
+	RecordingPermissionDeniedTimeout *int `json:"recording_permission_denied_timeout,omitempty"` // Seconds
+}
+
+// Apply sets the options on a bot creation request
+func (o BotOptions) Apply(req *CreateBotRequest) {
+	req.BotName = o.BotName
+	if o.JoinMessage != "" {
+		req.Chat = &ChatConfig{OnBotJoin: &ChatMessage{SendTo: "everyone", Message: o.JoinMessage}}
+	}
+	if o.LeaveIfRecordingDenied {
+		timeout := 0
+		req.AutomaticLeave = &AutomaticLeaveConfig{RecordingPermissionDeniedTimeout: &timeout}
+	}
 }
 
 // RecordingConfig defines the recording configuration for the bot
 type RecordingConfig struct {
-	Transcript       TranscriptConfig `json:"transcript"`
-	VideoMixedLayout string           `json:"video_mixed_layout,omitempty"` // e.g., "gallery_view_v2"
-	VideoSeparateMP4 *struct{}        `json:"video_separate_mp4,omitempty"` // Empty object to enable
+	Transcript       *TranscriptConfig `json:"transcript,omitempty"`         // Omitted when transcribing elsewhere
+	VideoMixedLayout string            `json:"video_mixed_layout,omitempty"` // e.g., "gallery_view_v2"
+	VideoSeparateMP4 *struct{}         `json:"video_separate_mp4,omitempty"` // Empty object to enable
+	AudioMixedMP3    *struct{}         `json:"audio_mixed_mp3,omitempty"`    // Empty object to enable
 }
 
 // TranscriptConfig defines the transcript provider configuration
@@ -96,6 +136,7 @@ type Recording struct {
 type MediaShortcuts struct {
 	Transcript TranscriptShortcut `json:"transcript"`
 	VideoMixed VideoMixedShortcut `json:"video_mixed"`
+	AudioMixed AudioMixedShortcut `json:"audio_mixed"`
 }
 
 // TranscriptShortcut contains transcript-specific shortcuts
@@ -120,6 +161,17 @@ type VideoData struct {
 	DownloadURL string `json:"download_url"`
 }
 
+// AudioMixedShortcut contains audio recording shortcuts
+type AudioMixedShortcut struct {
+	ID   string    `json:"id"`
+	Data AudioData `json:"data"`
+}
+
+// AudioData contains the actual audio data and download URL
+type AudioData struct {
+	DownloadURL string `json:"download_url"`
+}
+
 // TranscriptEntry represents a single entry in the transcript
 type TranscriptEntry struct {
 	Participant ParticipantInfo `json:"participant"`
@@ -322,8 +374,9 @@ func (c *Client) handleAPIError(resp *http.Response) error {
 	return fmt.Errorf("recall.ai API error: %d - %s", resp.StatusCode, string(body))
 }
 
-// CreateBot creates a new bot to join and record a meeting
-func (c *Client) CreateBot(meetingURL string) (*CreateBotResponse, error) {
+// CreateBot creates a new bot to join and record a meeting. Without transcription, the bot records mixed
+// audio instead so the meeting can be transcribed elsewhere.
+func (c *Client) CreateBot(meetingURL string, transcribe bool, options BotOptions) (*CreateBotResponse, error) {
 	if c.APIKey == "" {
 		return nil, fmt.Errorf("RECALL_AI_API_KEY environment variable is not set")
 	}
@@ -331,20 +384,26 @@ func (c *Client) CreateBot(meetingURL string) (*CreateBotResponse, error) {
 	reqBody := CreateBotRequest{
 		MeetingURL: meetingURL,
 		RecordingConfig: RecordingConfig{
-			Transcript: TranscriptConfig{
-				Provider: TranscriptProvider{
-					RecallAIStreaming: RecallAIStreamingConfig{
-						Mode: "prioritize_accuracy",
-					},
-				},
-			},
 			VideoMixedLayout: "gallery_view_v2", // Enable video recording with gallery view
 			VideoSeparateMP4: &struct{}{},       // Enable separate MP4 streams
 		},
 	}
+	if transcribe {
+		reqBody.RecordingConfig.Transcript = &TranscriptConfig{
+			Provider: TranscriptProvider{
+				RecallAIStreaming: RecallAIStreamingConfig{
+					Mode: "prioritize_accuracy",
+				},
+			},
+		}
+	} else {
+		reqBody.RecordingConfig.AudioMixedMP3 = &struct{}{}
+	}
+	options.Apply(&reqBody)
 
 	log.Info().
 		Str("meeting_url", meetingURL).
+		Bool("transcribe", transcribe).
 		Msg("Creating Recall.ai bot")
 
 	resp, err := c.makeRequest("POST", "/bot/", reqBody)
@@ -408,6 +467,31 @@ func (c *Client) GetBot(botID string) (*BotDetails, error) {
 	return &botDetails, nil
 }
 
+// Ping checks that the API is reachable and accepts the API key, listing at most one bot
+func (c *Client) Ping(ctx context.Context) error {
+	if c.APIKey == "" {
+		return fmt.Errorf("RECALL_AI_API_KEY environment variable is not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.getBaseURL()+"/bot/?page_size=1", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Token "+c.APIKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Recall.ai: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("recall.ai API error: %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // DownloadTranscript downloads and parses the transcript from the provided URL
 func (c *Client) DownloadTranscript(downloadURL string) ([]TranscriptEntry, error) {
 	if downloadURL == "" {
@@ -450,6 +534,31 @@ func (c *Client) DownloadTranscript(downloadURL string) ([]TranscriptEntry, erro
 	return transcript, nil
 }
 
+// DownloadMedia opens a recording's media file from the provided URL. The caller must close it.
+func (c *Client) DownloadMedia(ctx context.Context, downloadURL string) (io.ReadCloser, error) {
+	if downloadURL == "" {
+		return nil, fmt.Errorf("download URL cannot be empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create media download request: %w", err)
+	}
+
+	// Recordings can take longer to download than API calls are allowed
+	client := *c.HTTPClient
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download media: status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
 // ===== Calendar V2 Integration Methods =====
 
 // CreateCalendar creates a new calendar in Recall for a user
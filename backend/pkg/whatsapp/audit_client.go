@@ -1,6 +1,7 @@
 package whatsapp
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
@@ -133,6 +134,11 @@ func (a *AuditClient) GetPhoneNumberID() string {
 	return a.client.GetPhoneNumberID()
 }
 
+// Ping delegates to the underlying client
+func (a *AuditClient) Ping(ctx context.Context) error {
+	return a.client.Ping(ctx)
+}
+
 // generateMessageID generates a unique message ID for tracking
 func generateMessageID() string {
 	bytes := make([]byte, 16)
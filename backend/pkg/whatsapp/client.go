@@ -2,6 +2,7 @@ package whatsapp
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -22,6 +23,7 @@ type WhatsAppClient interface {
 	SendInteractiveMessage(phoneNumber string, message InteractiveMessage) error
 	VerifyWebhookSignature(payload []byte, signature string) bool
 	GetPhoneNumberID() string
+	Ping(ctx context.Context) error
 }
 
 // Client implements WhatsAppClient interface
@@ -206,6 +208,27 @@ func (c *Client) GetPhoneNumberID() string {
 	return c.config.PhoneNumberID
 }
 
+// Ping checks that the API is reachable and the access token can read the configured phone number
+func (c *Client) Ping(ctx context.Context) error {
+	url := fmt.Sprintf("%s/%s?fields=id", c.config.APIURL, c.config.PhoneNumberID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach WhatsApp API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("WhatsApp API error: %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // min returns the minimum of two integers
 func min(a, b int) int {
 	if a < b {
@@ -0,0 +1,110 @@
+package whisper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client is a minimal client for a self-hosted Whisper server with an OpenAI-compatible
+// /v1/audio/transcriptions endpoint
+type Client struct {
+	Endpoint   string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new Whisper client. The API key is optional for servers without authentication.
+func NewClient(endpoint, apiKey, model string, timeout time.Duration) *Client {
+	return &Client{
+		Endpoint: endpoint,
+		APIKey:   apiKey,
+		Model:    model,
+		HTTPClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Segment is a span of speech with its offsets in seconds from the start of the audio
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Transcription is the verbose JSON result of transcribing audio
+type Transcription struct {
+	Text     string    `json:"text"`
+	Language string    `json:"language"`
+	Duration float64   `json:"duration"`
+	Segments []Segment `json:"segments"`
+}
+
+// Transcribe uploads audio and returns its transcription. The audio is streamed to the server, so it
+// can be a download of any size.
+func (c *Client) Transcribe(ctx context.Context, audio io.Reader, filename string) (*Transcription, error) {
+	if c.Endpoint == "" {
+		return nil, fmt.Errorf("whisper endpoint is not configured")
+	}
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeTranscriptionForm(form, c.Model, audio, filename))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Endpoint, body)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to reach whisper server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("whisper API error: %d - %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var transcription Transcription
+	if err := json.NewDecoder(resp.Body).Decode(&transcription); err != nil {
+		return nil, fmt.Errorf("failed to decode transcription: %w", err)
+	}
+	return &transcription, nil
+}
+
+func writeTranscriptionForm(form *multipart.Writer, model string, audio io.Reader, filename string) error {
+	if model != "" {
+		if err := form.WriteField("model", model); err != nil {
+			return err
+		}
+	}
+	if err := form.WriteField("response_format", "verbose_json"); err != nil {
+		return err
+	}
+	file, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, audio); err != nil {
+		return fmt.Errorf("failed to upload audio: %w", err)
+	}
+	return form.Close()
+}